@@ -1,23 +1,340 @@
 package main
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
 	"flag"
+	"fmt"
+	"io"
 	"log"
+	"os"
+	"os/signal"
+	"slices"
+	"strings"
+	"syscall"
+	"time"
+	_ "time/tzdata" // schedule time zones on images without zoneinfo
 
+	"github.com/katalabut/openclaw-relay/internal/bundle"
 	"github.com/katalabut/openclaw-relay/internal/config"
+	"github.com/katalabut/openclaw-relay/internal/gmail"
+	"github.com/katalabut/openclaw-relay/internal/rules"
+	"github.com/katalabut/openclaw-relay/internal/sealed"
 	"github.com/katalabut/openclaw-relay/internal/server"
+	"github.com/katalabut/openclaw-relay/internal/simulate"
 )
 
 func main() {
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "simulate":
+			runSimulate(os.Args[2:])
+			return
+		case "export":
+			runExport(os.Args[2:])
+			return
+		case "import":
+			runImport(os.Args[2:])
+			return
+		case "gmail":
+			runGmail(os.Args[2:])
+			return
+		case "seal":
+			runSeal(os.Args[2:])
+			return
+		case "rules":
+			runRules(os.Args[2:])
+			return
+		}
+	}
+
 	configPath := flag.String("config", "config.yaml", "path to config file")
+	inMemory := flag.Bool("memory", false, "in-memory mode for load tests: no disk state, jobs go to a local sink")
 	flag.Parse()
 
 	cfg, err := config.Load(*configPath)
 	if err != nil {
 		log.Fatalf("Failed to load config: %v", err)
 	}
+	cfg.InMemory = *inMemory
 
 	if err := server.Run(cfg); err != nil {
 		log.Fatalf("Server error: %v", err)
 	}
 }
+
+// runSimulate posts a signed synthetic webhook to a running relay:
+//
+//	relay simulate --source trello --event card_moved --card "X" --list ready
+func runSimulate(args []string) {
+	fs := flag.NewFlagSet("simulate", flag.ExitOnError)
+	configPath := fs.String("config", "config.yaml", "path to config file (for secrets and list IDs)")
+	var opts simulate.Options
+	fs.StringVar(&opts.Source, "source", "", "trello, github, slack or jira")
+	fs.StringVar(&opts.Event, "event", "", "event to simulate (e.g. card_moved, check_run, app_mention, issue_updated)")
+	fs.StringVar(&opts.URL, "url", "", "relay base URL (default http://localhost:<server.port>)")
+	fs.StringVar(&opts.Card, "card", "Simulated card", "trello card name or jira issue summary")
+	fs.StringVar(&opts.List, "list", "", "trello target list name")
+	fs.StringVar(&opts.From, "from", "", "trello previous list or jira previous status")
+	fs.StringVar(&opts.Text, "text", "", "comment or message text")
+	fs.StringVar(&opts.User, "user", "", "trello member, slack user ID or jira account ID")
+	fs.StringVar(&opts.Repo, "repo", "", "github repository (owner/name)")
+	fs.IntVar(&opts.PR, "pr", 1, "github pull request number")
+	fs.StringVar(&opts.Conclusion, "conclusion", "failure", "github check/workflow conclusion")
+	fs.StringVar(&opts.Channel, "channel", "", "slack channel ID")
+	fs.StringVar(&opts.Issue, "issue", "SIM-1", "jira issue key")
+	fs.StringVar(&opts.Status, "status", "", "jira status")
+	count := fs.Int("count", 1, "number of deliveries to send (load test when > 1)")
+	concurrency := fs.Int("concurrency", 1, "parallel senders when --count > 1")
+	fs.Parse(args)
+
+	cfg, err := config.Load(*configPath)
+	if err != nil {
+		log.Fatalf("Failed to load config: %v", err)
+	}
+	if opts.URL == "" {
+		opts.URL = fmt.Sprintf("http://localhost:%d", cfg.Server.Port)
+	}
+
+	if *count > 1 {
+		res, err := simulate.Load(cfg, opts, *count, *concurrency)
+		if err != nil {
+			log.Fatalf("Simulate failed: %v", err)
+		}
+		fmt.Printf("sent=%d failed=%d duration=%s rate=%.1f/s\n", res.Sent, res.Failed, res.Duration.Round(time.Millisecond), res.PerSecond())
+		if res.Failed > 0 {
+			os.Exit(1)
+		}
+		return
+	}
+
+	status, body, err := simulate.Send(cfg, opts)
+	if err != nil {
+		log.Fatalf("Simulate failed: %v", err)
+	}
+	fmt.Printf("%d %s\n", status, body)
+	if status >= 300 {
+		os.Exit(1)
+	}
+}
+
+// runExport writes the data directory to an encrypted bundle:
+//
+//	relay export --out relay-bundle.enc
+func runExport(args []string) {
+	fs := flag.NewFlagSet("export", flag.ExitOnError)
+	out := fs.String("out", "relay-bundle.enc", "bundle file to write")
+	dataDir := fs.String("data", "data", "relay data directory")
+	fs.Parse(args)
+
+	f, err := os.OpenFile(*out, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
+	if err != nil {
+		log.Fatalf("Export failed: %v", err)
+	}
+	m, err := bundle.Export(f, *dataDir, os.Getenv("RELAY_ENCRYPTION_KEY"), time.Now())
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(*out)
+		log.Fatalf("Export failed: %v", err)
+	}
+	fmt.Printf("exported %d file(s) to %s\n", len(m.Files), *out)
+}
+
+// runImport restores an encrypted bundle into the data directory. The relay
+// must be stopped, or it will overwrite the imported state.
+//
+//	relay import --in relay-bundle.enc
+func runImport(args []string) {
+	fs := flag.NewFlagSet("import", flag.ExitOnError)
+	in := fs.String("in", "relay-bundle.enc", "bundle file to read")
+	dataDir := fs.String("data", "data", "relay data directory")
+	force := fs.Bool("force", false, "overwrite files that already exist")
+	fs.Parse(args)
+
+	f, err := os.Open(*in)
+	if err != nil {
+		log.Fatalf("Import failed: %v", err)
+	}
+	defer f.Close()
+	m, err := bundle.Import(f, *dataDir, os.Getenv("RELAY_ENCRYPTION_KEY"), *force)
+	if errors.Is(err, bundle.ErrExists) {
+		log.Fatalf("Import failed: %v (use --force to replace existing state)", err)
+	}
+	if err != nil {
+		log.Fatalf("Import failed: %v", err)
+	}
+	fmt.Printf("imported %d file(s) exported at %s into %s\n", len(m.Files), m.CreatedAt.Format(time.RFC3339), *dataDir)
+}
+
+// runSeal seals a secret read from stdin with RELAY_ENCRYPTION_KEY, for use
+// as a config value:
+//
+//	printf %s "$SECRET" | relay seal
+func runSeal(args []string) {
+	fs := flag.NewFlagSet("seal", flag.ExitOnError)
+	fs.Parse(args)
+
+	key, err := sealed.ParseKey(os.Getenv("RELAY_ENCRYPTION_KEY"))
+	if err != nil {
+		log.Fatalf("Seal failed: %v", err)
+	}
+	data, err := io.ReadAll(io.LimitReader(os.Stdin, 64<<10))
+	if err != nil {
+		log.Fatalf("Seal failed: %v", err)
+	}
+	value := strings.TrimRight(string(data), "\r\n")
+	if value == "" {
+		log.Fatalf("Seal failed: no secret on stdin")
+	}
+	v, err := sealed.Seal(key, value)
+	if err != nil {
+		log.Fatalf("Seal failed: %v", err)
+	}
+	fmt.Println(v)
+}
+
+// runRules exports the config's rules as JSON, or imports such a file into
+// the config. Imports merge by rule name unless --replace; the relay reads
+// the changed file on its next restart.
+//
+//	relay rules export --config config.yaml --out rules.json
+//	relay rules import --config config.yaml --dry-run rules.json
+func runRules(args []string) {
+	const usage = "usage: relay rules export [--config FILE] [--out FILE] | relay rules import [--config FILE] [--replace] [--overwrite] [--dry-run] FILE"
+	if len(args) == 0 {
+		log.Fatal(usage)
+	}
+	switch args[0] {
+	case "export":
+		fs := flag.NewFlagSet("rules export", flag.ExitOnError)
+		configPath := fs.String("config", "config.yaml", "path to config file")
+		out := fs.String("out", "", "file to write (default stdout)")
+		fs.Parse(args[1:])
+
+		content, err := os.ReadFile(*configPath)
+		if err != nil {
+			log.Fatalf("Export failed: %v", err)
+		}
+		doc, err := rules.Export(content)
+		if err != nil {
+			log.Fatalf("Export failed: %v", err)
+		}
+		data, err := json.MarshalIndent(doc, "", "  ")
+		if err != nil {
+			log.Fatalf("Export failed: %v", err)
+		}
+		data = append(data, '\n')
+		if *out == "" {
+			os.Stdout.Write(data)
+			return
+		}
+		if err := os.WriteFile(*out, data, 0600); err != nil {
+			log.Fatalf("Export failed: %v", err)
+		}
+		fmt.Fprintf(os.Stderr, "exported the rules of %d source(s) to %s\n", len(doc.Sources), *out)
+	case "import":
+		fs := flag.NewFlagSet("rules import", flag.ExitOnError)
+		configPath := fs.String("config", "config.yaml", "path to config file")
+		var opts rules.Options
+		fs.BoolVar(&opts.Replace, "replace", false, "replace each source's rules instead of merging")
+		fs.BoolVar(&opts.Overwrite, "overwrite", false, "let a rule replace a different rule of the same name")
+		dryRun := fs.Bool("dry-run", false, "report the changes without writing the config")
+		fs.Parse(args[1:])
+		if fs.NArg() != 1 {
+			log.Fatal(usage)
+		}
+
+		data, err := os.ReadFile(fs.Arg(0))
+		if err != nil {
+			log.Fatalf("Import failed: %v", err)
+		}
+		var doc rules.Document
+		if err := json.Unmarshal(data, &doc); err != nil {
+			log.Fatalf("Import failed: %s: %v", fs.Arg(0), err)
+		}
+		current, err := os.ReadFile(*configPath)
+		if err != nil {
+			log.Fatalf("Import failed: %v", err)
+		}
+		out, report, err := rules.Import(current, &doc, opts)
+		if report != nil {
+			for _, c := range report.Conflicts {
+				if c.Rule != "" {
+					fmt.Printf("conflict  %s: %s: %s\n", c.Source, c.Rule, c.Reason)
+				} else {
+					fmt.Printf("conflict  %s: %s\n", c.Source, c.Reason)
+				}
+			}
+			fmt.Printf("added=%d replaced=%d removed=%d unchanged=%d conflicts=%d\n",
+				report.Added, report.Replaced, report.Removed, report.Unchanged, len(report.Conflicts))
+		}
+		if errors.Is(err, rules.ErrConflict) {
+			log.Fatalf("Import failed: %v (use --overwrite to replace rules of the same name)", err)
+		}
+		if err != nil {
+			log.Fatalf("Import failed: %v", err)
+		}
+		if *dryRun || !report.Changed() {
+			return
+		}
+		if err := config.WriteFile(*configPath, out); err != nil {
+			log.Fatalf("Import failed: %v", err)
+		}
+		fmt.Printf("wrote %s; restart the relay to apply\n", *configPath)
+	default:
+		log.Fatal(usage)
+	}
+}
+
+// runGmail runs Gmail maintenance commands:
+//
+//	relay gmail backfill --account a@b.com --query "newer_than:7d" --rule invoices --dry-run
+func runGmail(args []string) {
+	if len(args) == 0 || args[0] != "backfill" {
+		log.Fatalf("usage: relay gmail backfill --account EMAIL --query QUERY [--rule NAME] [--max N] [--dry-run]")
+	}
+	fs := flag.NewFlagSet("gmail backfill", flag.ExitOnError)
+	configPath := fs.String("config", "config.yaml", "path to config file")
+	dataDir := fs.String("data", "data", "relay data directory (tokens, backfill log)")
+	account := fs.String("account", "", "Gmail account from gmail.accounts")
+	var opts gmail.BackfillOptions
+	fs.StringVar(&opts.Query, "query", "", "Gmail search for the messages to run, e.g. newer_than:7d")
+	fs.StringVar(&opts.Rule, "rule", "", "run only this rule (default all rules)")
+	fs.IntVar(&opts.Max, "max", 100, "most messages to scan")
+	fs.BoolVar(&opts.DryRun, "dry-run", false, "list matches without creating jobs")
+	fs.Parse(args[1:])
+	if *account == "" || opts.Query == "" {
+		log.Fatalf("Backfill failed: --account and --query are required")
+	}
+
+	cfg, err := config.Load(*configPath)
+	if err != nil {
+		log.Fatalf("Failed to load config: %v", err)
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	res, err := server.GmailBackfill(ctx, cfg, *dataDir, *account, opts)
+	if res != nil {
+		verb := "ran"
+		if opts.DryRun {
+			verb = "would run"
+		}
+		for _, m := range res.Matches {
+			fmt.Printf("%s  %s: %s\n", m.MessageID, m.From, m.Subject)
+			for _, r := range m.Rules {
+				if slices.Contains(m.Done, r) {
+					fmt.Printf("    %s: already backfilled\n", r)
+				} else {
+					fmt.Printf("    %s: %s\n", r, verb)
+				}
+			}
+		}
+		fmt.Printf("scanned=%d matched=%d\n", res.Scanned, len(res.Matches))
+	}
+	if err != nil {
+		log.Fatalf("Backfill failed: %v", err)
+	}
+}
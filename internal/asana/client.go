@@ -0,0 +1,126 @@
+// Package asana reads tasks, stories and sections from the Asana API, since
+// Asana's webhook events carry only resource IDs.
+package asana
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Client authenticates with a personal access token.
+type Client struct {
+	token   string
+	baseURL string
+	HTTP    *http.Client
+}
+
+func NewClient(token, apiURL string) *Client {
+	baseURL := strings.TrimRight(apiURL, "/")
+	if baseURL == "" {
+		baseURL = "https://app.asana.com/api/1.0"
+	}
+	return &Client{
+		token:   token,
+		baseURL: baseURL,
+		HTTP:    &http.Client{Timeout: 15 * time.Second},
+	}
+}
+
+// APIError is a non-2xx response from Asana.
+type APIError struct {
+	Status int
+	Body   string
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("asana api: status %d: %s", e.Status, e.Body)
+}
+
+type User struct {
+	GID  string `json:"gid"`
+	Name string `json:"name"`
+}
+
+type Task struct {
+	GID          string `json:"gid"`
+	Name         string `json:"name"`
+	PermalinkURL string `json:"permalink_url"`
+	Completed    bool   `json:"completed"`
+	Assignee     *User  `json:"assignee"`
+}
+
+// Story is an entry in a task's activity feed; comments are stories with
+// resource_subtype "comment_added".
+type Story struct {
+	GID             string `json:"gid"`
+	ResourceSubtype string `json:"resource_subtype"`
+	Text            string `json:"text"`
+	CreatedBy       *User  `json:"created_by"`
+}
+
+type Section struct {
+	GID  string `json:"gid"`
+	Name string `json:"name"`
+}
+
+// GetTask fetches a task's name, link, completion and assignee.
+func (c *Client) GetTask(ctx context.Context, gid string) (*Task, error) {
+	var t Task
+	err := c.get(ctx, "/tasks/"+url.PathEscape(gid), "name,permalink_url,completed,assignee.name", &t)
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+// GetStory fetches a story, e.g. a comment's text and author.
+func (c *Client) GetStory(ctx context.Context, gid string) (*Story, error) {
+	var s Story
+	err := c.get(ctx, "/stories/"+url.PathEscape(gid), "resource_subtype,text,created_by.name", &s)
+	if err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// GetSection fetches a section's name.
+func (c *Client) GetSection(ctx context.Context, gid string) (*Section, error) {
+	var s Section
+	if err := c.get(ctx, "/sections/"+url.PathEscape(gid), "name", &s); err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// get reads one resource; Asana wraps it in {"data": ...}.
+func (c *Client) get(ctx context.Context, path, fields string, out any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.baseURL+path+"?opt_fields="+url.QueryEscape(fields), nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+c.token)
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := c.HTTP.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode >= 300 {
+		return &APIError{Status: resp.StatusCode, Body: strings.TrimSpace(string(body))}
+	}
+	envelope := struct {
+		Data any `json:"data"`
+	}{Data: out}
+	if err := json.Unmarshal(body, &envelope); err != nil {
+		return fmt.Errorf("decode %s: %w", path, err)
+	}
+	return nil
+}
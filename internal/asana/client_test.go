@@ -0,0 +1,61 @@
+package asana
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestClient(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer pat" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if r.URL.Query().Get("opt_fields") == "" {
+			t.Errorf("expected opt_fields on %s", r.URL.Path)
+		}
+		switch r.URL.Path {
+		case "/tasks/11":
+			w.Write([]byte(`{"data":{"gid":"11","name":"Fix login","permalink_url":"https://app.asana.com/0/1/11","assignee":{"gid":"u1","name":"Alice"}}}`))
+		case "/stories/22":
+			w.Write([]byte(`{"data":{"gid":"22","resource_subtype":"comment_added","text":"looks good","created_by":{"gid":"u2","name":"Bob"}}}`))
+		case "/sections/33":
+			w.Write([]byte(`{"data":{"gid":"33","name":"Review"}}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"errors":[{"message":"Not Found"}]}`))
+		}
+	}))
+	defer srv.Close()
+
+	c := NewClient("pat", srv.URL)
+	ctx := context.Background()
+
+	task, err := c.GetTask(ctx, "11")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if task.Name != "Fix login" || task.Assignee == nil || task.Assignee.Name != "Alice" {
+		t.Errorf("unexpected task %+v", task)
+	}
+	story, err := c.GetStory(ctx, "22")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if story.Text != "looks good" || story.CreatedBy.GID != "u2" {
+		t.Errorf("unexpected story %+v", story)
+	}
+	section, err := c.GetSection(ctx, "33")
+	if err != nil || section.Name != "Review" {
+		t.Errorf("unexpected section %+v, %v", section, err)
+	}
+
+	_, err = c.GetTask(ctx, "404")
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.Status != http.StatusNotFound {
+		t.Errorf("expected 404 APIError, got %v", err)
+	}
+}
@@ -0,0 +1,52 @@
+// Package atomicfile replaces files through a synced temp file and a rename,
+// so a crash mid-write leaves the previous contents rather than a truncated
+// file. The relay's state files under data/, config.yaml and pruned audit
+// logs are replaced this way (logs are otherwise only appended to); stores
+// that change on every webhook (event history, delivery IDs) batch their
+// writes with Debounced.
+package atomicfile
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+)
+
+// WriteFile replaces path with data, creating its directory (0700) when
+// missing. The file gets perm.
+func WriteFile(path string, data []byte, perm os.FileMode) error {
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(dir, "."+filepath.Base(path)+"-*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Chmod(perm); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// WriteJSON replaces path with v encoded as JSON, readable by the owner only.
+func WriteJSON(path string, v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return WriteFile(path, data, 0600)
+}
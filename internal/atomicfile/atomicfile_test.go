@@ -0,0 +1,96 @@
+package atomicfile
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestWriteFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state", "drive-state.json")
+	if err := WriteJSON(path, map[string]string{"a@example.com": "token-1"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := WriteFile(path, []byte(`{"version":2}`), 0640); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil || string(data) != `{"version":2}` {
+		t.Fatalf("read: %s %v", data, err)
+	}
+	info, _ := os.Stat(path)
+	if info.Mode().Perm() != 0640 {
+		t.Errorf("mode = %v", info.Mode())
+	}
+	// No temp files are left behind
+	entries, _ := os.ReadDir(filepath.Dir(path))
+	if len(entries) != 1 {
+		t.Errorf("dir has %d entries", len(entries))
+	}
+
+	if err := WriteJSON(path, func() {}); err == nil {
+		t.Error("expected an error for a value JSON can't encode")
+	}
+	if err := WriteFile(filepath.Join(path, "sub", "x"), nil, 0600); err == nil {
+		t.Error("expected an error for a path under a file")
+	}
+}
+
+func TestDebounced(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ids.json")
+	var mu sync.Mutex
+	ids := []string{}
+	d := NewDebounced(path, time.Hour, func() any {
+		mu.Lock()
+		defer mu.Unlock()
+		return slices.Clone(ids)
+	})
+	add := func(id string) {
+		mu.Lock()
+		ids = append(ids, id)
+		mu.Unlock()
+		d.Mark()
+	}
+	add("a")
+	add("b")
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Fatal("expected no write before the interval")
+	}
+	if err := d.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := os.ReadFile(path); string(data) != `["a","b"]` {
+		t.Errorf("flushed %s", data)
+	}
+	// Nothing scheduled: Flush leaves the file alone
+	os.Remove(path)
+	if err := d.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Error("expected no write without a change")
+	}
+
+	// The timer writes on its own
+	d.interval = time.Millisecond
+	add("c")
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		if data, _ := os.ReadFile(path); string(data) == `["a","b","c"]` {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("scheduled write never happened")
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	var none *Debounced
+	none.Mark()
+	if err := none.Flush(); err != nil || NewDebounced("", time.Second, nil) != nil {
+		t.Error("expected a nil Debounced for an in-memory store")
+	}
+}
@@ -0,0 +1,65 @@
+package atomicfile
+
+import (
+	"log"
+	"sync"
+	"time"
+)
+
+// Debounced writes a JSON file at most once per interval, for stores that
+// change on every request. Mark schedules a write of what snapshot returns;
+// Flush writes a scheduled one at once, e.g. on shutdown. A nil Debounced
+// (a store kept in memory) writes nothing.
+type Debounced struct {
+	path     string
+	interval time.Duration
+	snapshot func() any // called without the Debounced's locks; returns a copy
+
+	mu      sync.Mutex
+	timer   *time.Timer // set while a write is scheduled
+	writeMu sync.Mutex  // one write at a time, so a later snapshot always lands last
+}
+
+// NewDebounced returns a Debounced writing path, or nil when path is empty.
+func NewDebounced(path string, interval time.Duration, snapshot func() any) *Debounced {
+	if path == "" {
+		return nil
+	}
+	return &Debounced{path: path, interval: interval, snapshot: snapshot}
+}
+
+// Mark schedules a write within interval, unless one already is.
+func (d *Debounced) Mark() {
+	if d == nil {
+		return
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.timer == nil {
+		d.timer = time.AfterFunc(d.interval, func() {
+			if err := d.Flush(); err != nil {
+				log.Printf("Warning: failed to write %s: %v", d.path, err)
+			}
+		})
+	}
+}
+
+// Flush writes a scheduled write now. It does nothing when none is.
+func (d *Debounced) Flush() error {
+	if d == nil {
+		return nil
+	}
+	d.mu.Lock()
+	pending := d.timer != nil
+	if pending {
+		d.timer.Stop()
+		d.timer = nil
+	}
+	d.mu.Unlock()
+	if !pending {
+		return nil
+	}
+	d.writeMu.Lock()
+	defer d.writeMu.Unlock()
+	return WriteJSON(d.path, d.snapshot())
+}
@@ -1,15 +1,18 @@
 package audit
 
 import (
+	"context"
 	"encoding/json"
+	"fmt"
 	"log"
 	"net"
 	"net/http"
 	"os"
-	"path/filepath"
 	"strings"
 	"sync"
 	"time"
+
+	"github.com/katalabut/openclaw-relay/internal/config"
 )
 
 type Entry struct {
@@ -19,29 +22,130 @@ type Entry struct {
 	Status    int    `json:"status"`
 	SourceIP  string `json:"source_ip"`
 	LatencyMs int64  `json:"latency_ms"`
+	// Source is the webhook source of /webhook/{source} requests.
+	Source string `json:"source,omitempty"`
+	// SampleRate is N when the path logs 1 in N successful requests.
+	SampleRate int `json:"sample_rate,omitempty"`
+	// Handshake is the kind of provider verification the request was, e.g.
+	// "ping"; see MarkHandshake.
+	Handshake string `json:"handshake,omitempty"`
 }
 
 type Logger struct {
-	mu   sync.Mutex
-	file *os.File
+	// ExcludePaths are never logged: exact paths, or prefixes ending in "/".
+	ExcludePaths []string
+	// Sample logs 1 in N successful (status < 400) requests under a path
+	// prefix; the longest matching prefix applies. Failures are always logged.
+	Sample map[string]int
+
+	mu      sync.Mutex
+	out     output
+	sources map[string]output // per-source files; other sources go to out
+	files   []*fileOutput     // files with a retention, pruned hourly
+	stop    chan struct{}
+	seen    map[string]int // successful requests per Sample prefix
 }
 
+// pruneInterval is how often files with a retention are pruned.
+var pruneInterval = time.Hour
+
+// NewLogger appends entries as JSON lines to the file at path.
 func NewLogger(path string) (*Logger, error) {
-	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
-		return nil, err
-	}
-	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	f, err := openFile(path, 0)
 	if err != nil {
 		return nil, err
 	}
-	return &Logger{file: f}, nil
+	return &Logger{out: f}, nil
+}
+
+// FromConfig opens the output selected by cfg.Output and applies the path
+// exclusions and sampling. With the file output, sources listed in
+// cfg.Sources get their own files, and files with a retention are pruned.
+func FromConfig(cfg config.AuditConfig) (*Logger, error) {
+	var l *Logger
+	switch cfg.Output {
+	case "", "file":
+		main, err := openFile(cfg.LogPath, cfg.ResolvedRetention())
+		if err != nil {
+			return nil, err
+		}
+		l = &Logger{out: main, sources: make(map[string]output)}
+		all := []*fileOutput{main}
+		for source := range cfg.Sources {
+			f, err := openFile(cfg.SourceLogPath(source), cfg.SourceRetention(source))
+			if err != nil {
+				l.Close()
+				return nil, fmt.Errorf("audit.sources.%s: %w", source, err)
+			}
+			l.sources[source] = f
+			all = append(all, f)
+		}
+		for _, f := range all {
+			if f.retention > 0 {
+				l.files = append(l.files, f)
+			}
+		}
+		if len(l.files) > 0 {
+			l.prune(time.Now())
+			l.stop = make(chan struct{})
+			go l.pruneLoop(l.stop)
+		}
+	case "stdout":
+		l = &Logger{out: &writerOutput{w: os.Stdout}}
+	case "syslog":
+		l = &Logger{out: newSyslogOutput(cfg.Syslog)}
+	case "http":
+		l = &Logger{out: newHTTPOutput(cfg.HTTP)}
+	default:
+		return nil, fmt.Errorf("unknown audit output %q", cfg.Output)
+	}
+	l.ExcludePaths = cfg.ExcludePaths
+	l.Sample = cfg.Sample
+	return l, nil
 }
 
-// Close closes the audit log file.
+// Close flushes and closes the outputs.
 func (l *Logger) Close() error {
+	if l.stop != nil {
+		close(l.stop)
+		l.stop = nil
+	}
 	l.mu.Lock()
 	defer l.mu.Unlock()
-	return l.file.Close()
+	err := l.out.Close()
+	for _, o := range l.sources {
+		if cerr := o.Close(); err == nil {
+			err = cerr
+		}
+	}
+	return err
+}
+
+func (l *Logger) pruneLoop(stop <-chan struct{}) {
+	ticker := time.NewTicker(pruneInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case now := <-ticker.C:
+			l.prune(now)
+		}
+	}
+}
+
+// prune drops entries past their file's retention.
+func (l *Logger) prune(now time.Time) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for _, f := range l.files {
+		n, err := f.prune(now.Add(-f.retention))
+		if err != nil {
+			log.Printf("audit prune %s: %v", f.path, err)
+		} else if n > 0 {
+			log.Printf("Audit: dropped %d entries older than %s from %s", n, f.retention, f.path)
+		}
+	}
 }
 
 func (l *Logger) Log(e Entry) {
@@ -52,7 +156,57 @@ func (l *Logger) Log(e Entry) {
 	}
 	l.mu.Lock()
 	defer l.mu.Unlock()
-	l.file.Write(append(data, '\n'))
+	out := l.out
+	if o, ok := l.sources[e.Source]; ok {
+		out = o
+	}
+	if err := out.write(e, data); err != nil {
+		log.Printf("audit write error: %v", err)
+	}
+}
+
+// sampleRate returns N for the longest Sample prefix of path, or 0.
+func (l *Logger) sampleRate(path string) (prefix string, rate int) {
+	for p, n := range l.Sample {
+		if strings.HasPrefix(path, p) && len(p) > len(prefix) {
+			prefix, rate = p, n
+		}
+	}
+	if rate <= 1 {
+		return "", 0
+	}
+	return prefix, rate
+}
+
+func (l *Logger) excluded(path string) bool {
+	for _, p := range l.ExcludePaths {
+		if path == p || (strings.HasSuffix(p, "/") && strings.HasPrefix(path, p)) {
+			return true
+		}
+	}
+	return false
+}
+
+// filter reports whether a request should be logged, and its sample rate.
+func (l *Logger) filter(path string, status int) (keep bool, rate int) {
+	if l.excluded(path) {
+		return false, 0
+	}
+	prefix, rate := l.sampleRate(path)
+	if rate == 0 {
+		return true, 0
+	}
+	if status >= 400 {
+		return true, 0
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.seen == nil {
+		l.seen = make(map[string]int)
+	}
+	n := l.seen[prefix]
+	l.seen[prefix] = n + 1
+	return n%rate == 0, rate
 }
 
 type responseWriter struct {
@@ -65,6 +219,16 @@ func (rw *responseWriter) WriteHeader(code int) {
 	rw.ResponseWriter.WriteHeader(code)
 }
 
+// sourceOf returns the webhook source of a /webhook/{source}[/...] path.
+func sourceOf(path string) string {
+	rest, ok := strings.CutPrefix(path, "/webhook/")
+	if !ok {
+		return ""
+	}
+	source, _, _ := strings.Cut(rest, "/")
+	return source
+}
+
 // extractClientIP returns the client IP from X-Forwarded-For or RemoteAddr.
 func extractClientIP(r *http.Request) string {
 	if xff := r.Header.Get("X-Forwarded-For"); xff != "" {
@@ -80,18 +244,41 @@ func extractClientIP(r *http.Request) string {
 	return host
 }
 
+type handshakeKey struct{}
+
+// MarkHandshake records in the audit entry of the request ctx belongs to
+// that it was a provider's verification handshake of the given kind.
+// Outside Middleware it does nothing.
+func MarkHandshake(ctx context.Context, kind string) {
+	if p, ok := ctx.Value(handshakeKey{}).(*string); ok {
+		*p = kind
+	}
+}
+
 func Middleware(logger *Logger, next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		start := time.Now()
 		rw := &responseWriter{ResponseWriter: w, status: 200}
-		next.ServeHTTP(rw, r)
+		var handshake string
+		next.ServeHTTP(rw, r.WithContext(context.WithValue(r.Context(), handshakeKey{}, &handshake)))
+		keep, rate := logger.filter(r.URL.Path, rw.status)
+		if handshake != "" {
+			// Rare and worth keeping: never sampled out
+			keep, rate = !logger.excluded(r.URL.Path), 0
+		}
+		if !keep {
+			return
+		}
 		logger.Log(Entry{
-			Timestamp: start.UTC().Format(time.RFC3339),
-			Method:    r.Method,
-			Path:      r.URL.Path,
-			Status:    rw.status,
-			SourceIP:  extractClientIP(r),
-			LatencyMs: time.Since(start).Milliseconds(),
+			Timestamp:  start.UTC().Format(time.RFC3339),
+			Method:     r.Method,
+			Path:       r.URL.Path,
+			Status:     rw.status,
+			SourceIP:   extractClientIP(r),
+			LatencyMs:  time.Since(start).Milliseconds(),
+			Source:     sourceOf(r.URL.Path),
+			SampleRate: rate,
+			Handshake:  handshake,
 		})
 	})
 }
@@ -2,10 +2,13 @@ package audit
 
 import (
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"os"
 	"path/filepath"
+	"slices"
+	"strings"
 	"testing"
 )
 
@@ -172,3 +175,76 @@ func TestClose(t *testing.T) {
 		t.Errorf("expected no error on close, got %v", err)
 	}
 }
+
+func TestMiddleware_ExcludeAndSample(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+	l, _ := NewLogger(path)
+	defer l.Close()
+	l.ExcludePaths = []string{"/health", "/metrics/"}
+	l.Sample = map[string]int{"/webhook/": 3, "/webhook/gmail": 1}
+
+	handler := Middleware(l, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("fail") != "" {
+			w.WriteHeader(http.StatusUnauthorized)
+		}
+	}))
+	for _, target := range []string{
+		"/health", "/metrics/gateway", "/healthz",
+		"/webhook/github", "/webhook/github", "/webhook/trello", "/webhook/github",
+		"/webhook/github?fail=1",
+		"/webhook/gmail", "/webhook/gmail",
+	} {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("POST", target, nil))
+	}
+
+	data, _ := os.ReadFile(path)
+	var got []string
+	for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+		var e Entry
+		json.Unmarshal([]byte(line), &e)
+		got = append(got, fmt.Sprintf("%s %d %d", e.Path, e.Status, e.SampleRate))
+	}
+	want := []string{
+		"/healthz 200 0",
+		"/webhook/github 200 3", // 1st of 3 under /webhook/
+		"/webhook/github 200 3", // 4th
+		"/webhook/github 401 0", // failures always logged
+		"/webhook/gmail 200 0",  // rate 1 logs everything
+		"/webhook/gmail 200 0",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("logged:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestMiddleware_Handshake(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+	l, _ := NewLogger(path)
+	defer l.Close()
+	l.Sample = map[string]int{"/webhook/": 100}
+
+	inner := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodHead {
+			MarkHandshake(r.Context(), "head")
+		}
+	})
+	handler := Middleware(l, inner)
+	// The first request of a sampled prefix is kept; handshakes always are
+	for _, method := range []string{"POST", "HEAD", "POST", "HEAD"} {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(method, "/webhook/trello", nil))
+	}
+
+	data, _ := os.ReadFile(path)
+	var got []string
+	for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+		var e Entry
+		json.Unmarshal([]byte(line), &e)
+		got = append(got, e.Method+" "+e.Handshake)
+	}
+	if want := []string{"POST ", "HEAD head", "HEAD head"}; !slices.Equal(got, want) {
+		t.Errorf("entries = %q, want %q", got, want)
+	}
+
+	// Outside the middleware it does nothing
+	MarkHandshake(httptest.NewRequest("GET", "/", nil).Context(), "ping")
+}
@@ -0,0 +1,335 @@
+package audit
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"maps"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/katalabut/openclaw-relay/internal/atomicfile"
+	"github.com/katalabut/openclaw-relay/internal/config"
+)
+
+// output receives each entry with its JSON encoding. Calls are serialized by
+// Logger.mu.
+type output interface {
+	write(e Entry, data []byte) error
+	Close() error
+}
+
+// writerOutput writes JSON lines to stdout, which it never closes.
+type writerOutput struct {
+	w io.Writer
+}
+
+func (o *writerOutput) write(_ Entry, data []byte) error {
+	_, err := o.w.Write(append(data, '\n'))
+	return err
+}
+
+func (o *writerOutput) Close() error {
+	return nil
+}
+
+// logPerm is the mode of audit log files.
+const logPerm = 0644
+
+// fileOutput appends JSON lines to a file, dropping entries older than
+// retention when pruned.
+type fileOutput struct {
+	path      string
+	retention time.Duration
+	f         *os.File
+}
+
+func openFile(path string, retention time.Duration) (*fileOutput, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, err
+	}
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, logPerm)
+	if err != nil {
+		return nil, err
+	}
+	return &fileOutput{path: path, retention: retention, f: f}, nil
+}
+
+func (o *fileOutput) write(_ Entry, data []byte) error {
+	_, err := o.f.Write(append(data, '\n'))
+	return err
+}
+
+func (o *fileOutput) Close() error {
+	return o.f.Close()
+}
+
+// prune rewrites the file without the entries before cutoff and returns how
+// many were dropped. Entries are appended in time order, so it keeps
+// everything from the first entry at or after cutoff.
+func (o *fileOutput) prune(cutoff time.Time) (int, error) {
+	data, err := os.ReadFile(o.path)
+	if err != nil {
+		return 0, err
+	}
+	dropped, offset := 0, 0
+	for offset < len(data) {
+		line, _, _ := bytes.Cut(data[offset:], []byte("\n"))
+		var e struct {
+			Timestamp string `json:"timestamp"`
+		}
+		if json.Unmarshal(line, &e) == nil {
+			if ts, err := time.Parse(time.RFC3339, e.Timestamp); err == nil && !ts.Before(cutoff) {
+				break
+			}
+		}
+		dropped++
+		offset += len(line) + 1
+	}
+	if dropped == 0 {
+		return 0, nil
+	}
+	if err := atomicfile.WriteFile(o.path, data[min(offset, len(data)):], logPerm); err != nil {
+		return 0, err
+	}
+	f, err := os.OpenFile(o.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, logPerm)
+	if err != nil {
+		return 0, err
+	}
+	o.f.Close()
+	o.f = f
+	return dropped, nil
+}
+
+// syslogOutput sends RFC 5424 messages over UDP, or over TCP with octet-counting
+// framing (RFC 6587). The connection is dialed on first use and redialed after
+// a failed write.
+type syslogOutput struct {
+	network, addr string
+	appName       string
+	facility      int
+	hostname      string
+	conn          net.Conn
+}
+
+func newSyslogOutput(cfg config.AuditSyslogConfig) *syslogOutput {
+	u, _ := url.Parse(cfg.Address) // validated with the config
+	o := &syslogOutput{network: u.Scheme, addr: u.Host, appName: cfg.AppName, facility: 16}
+	if o.appName == "" {
+		o.appName = "openclaw-relay"
+	}
+	if f, ok := config.SyslogFacilities[cfg.Facility]; ok {
+		o.facility = f
+	}
+	o.hostname, _ = os.Hostname()
+	if o.hostname == "" {
+		o.hostname = "-"
+	}
+	return o
+}
+
+// format renders e as an RFC 5424 message with the JSON entry as its body.
+// Server errors are logged at warning severity, everything else at info.
+func (o *syslogOutput) format(e Entry, data []byte) []byte {
+	severity := 6
+	if e.Status >= 500 {
+		severity = 4
+	}
+	msg := fmt.Sprintf("<%d>1 %s %s %s %d audit - %s", o.facility*8+severity, e.Timestamp, o.hostname, o.appName, os.Getpid(), data)
+	if o.network == "tcp" {
+		msg = strconv.Itoa(len(msg)) + " " + msg
+	}
+	return []byte(msg)
+}
+
+func (o *syslogOutput) write(e Entry, data []byte) error {
+	msg := o.format(e, data)
+	for attempt := 0; ; attempt++ {
+		if o.conn == nil {
+			conn, err := net.DialTimeout(o.network, o.addr, 5*time.Second)
+			if err != nil {
+				return fmt.Errorf("syslog dial: %w", err)
+			}
+			o.conn = conn
+		}
+		o.conn.SetWriteDeadline(time.Now().Add(5 * time.Second))
+		_, err := o.conn.Write(msg)
+		if err == nil {
+			return nil
+		}
+		o.conn.Close()
+		o.conn = nil
+		if attempt > 0 {
+			return fmt.Errorf("syslog write: %w", err)
+		}
+	}
+}
+
+func (o *syslogOutput) Close() error {
+	if o.conn == nil {
+		return nil
+	}
+	return o.conn.Close()
+}
+
+// maxHTTPBuffer bounds how many batches are held while the collector is down;
+// older entries are dropped beyond it.
+const maxHTTPBuffer = 10
+
+// httpOutput posts entries in batches, when a batch fills or every flush
+// interval, as newline-delimited JSON or a Loki push request.
+type httpOutput struct {
+	cfg    config.AuditHTTPConfig
+	client *http.Client
+	size   int
+
+	mu      sync.Mutex
+	pending []Entry
+	lines   [][]byte
+	kick    chan struct{}
+	done    chan struct{}
+	stopped chan struct{}
+}
+
+func newHTTPOutput(cfg config.AuditHTTPConfig) *httpOutput {
+	o := &httpOutput{
+		cfg:     cfg,
+		client:  &http.Client{Timeout: 10 * time.Second},
+		size:    cfg.ResolvedBatchSize(),
+		kick:    make(chan struct{}, 1),
+		done:    make(chan struct{}),
+		stopped: make(chan struct{}),
+	}
+	go o.run(cfg.ResolvedFlushInterval())
+	return o
+}
+
+func (o *httpOutput) write(e Entry, data []byte) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if max := o.size * maxHTTPBuffer; len(o.pending) >= max {
+		o.pending, o.lines = o.pending[1:], o.lines[1:]
+	}
+	o.pending = append(o.pending, e)
+	o.lines = append(o.lines, data)
+	if len(o.pending) >= o.size {
+		select {
+		case o.kick <- struct{}{}:
+		default:
+		}
+	}
+	return nil
+}
+
+func (o *httpOutput) run(interval time.Duration) {
+	defer close(o.stopped)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-o.done:
+			o.flush()
+			return
+		case <-ticker.C:
+		case <-o.kick:
+		}
+		o.flush()
+	}
+}
+
+// flush sends pending entries a batch at a time. A failed batch goes back to
+// the front of the queue for the next flush.
+func (o *httpOutput) flush() {
+	for {
+		o.mu.Lock()
+		n := min(len(o.pending), o.size)
+		if n == 0 {
+			o.mu.Unlock()
+			return
+		}
+		entries, lines := o.pending[:n:n], o.lines[:n:n]
+		o.pending, o.lines = o.pending[n:], o.lines[n:]
+		o.mu.Unlock()
+
+		if err := o.send(entries, lines); err != nil {
+			log.Printf("audit http output: %v", err)
+			o.mu.Lock()
+			o.pending = append(entries, o.pending...)
+			o.lines = append(lines, o.lines...)
+			o.mu.Unlock()
+			return
+		}
+	}
+}
+
+func (o *httpOutput) send(entries []Entry, lines [][]byte) error {
+	body, contentType, err := o.encode(entries, lines)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(http.MethodPost, o.cfg.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", contentType)
+	for k, v := range o.cfg.Headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := o.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("%s returned %d", o.cfg.URL, resp.StatusCode)
+	}
+	return nil
+}
+
+func (o *httpOutput) encode(entries []Entry, lines [][]byte) ([]byte, string, error) {
+	if o.cfg.Format != "loki" {
+		return append(bytes.Join(lines, []byte("\n")), '\n'), "application/x-ndjson", nil
+	}
+	labels := o.cfg.Labels
+	if len(labels) == 0 {
+		labels = map[string]string{"job": "openclaw-relay"}
+	}
+	// one stream per source, labelled source=<name> when there is one
+	var streams []map[string]any
+	bySource := make(map[string]int)
+	for i, e := range entries {
+		ts := time.Now()
+		if t, err := time.Parse(time.RFC3339, e.Timestamp); err == nil {
+			ts = t
+		}
+		idx, ok := bySource[e.Source]
+		if !ok {
+			stream := maps.Clone(labels)
+			if e.Source != "" {
+				stream["source"] = e.Source
+			}
+			idx = len(streams)
+			bySource[e.Source] = idx
+			streams = append(streams, map[string]any{"stream": stream, "values": [][2]string{}})
+		}
+		streams[idx]["values"] = append(streams[idx]["values"].([][2]string), [2]string{strconv.FormatInt(ts.UnixNano(), 10), string(lines[i])})
+	}
+	body, err := json.Marshal(map[string]any{"streams": streams})
+	return body, "application/json", err
+}
+
+// Close sends what is still pending.
+func (o *httpOutput) Close() error {
+	close(o.done)
+	<-o.stopped
+	return nil
+}
@@ -0,0 +1,288 @@
+package audit
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/katalabut/openclaw-relay/internal/config"
+)
+
+var testEntry = Entry{Timestamp: "2025-01-01T00:00:00Z", Method: "POST", Path: "/webhook/github", Status: 200, SourceIP: "1.2.3.4"}
+
+func TestFromConfig(t *testing.T) {
+	l, err := FromConfig(config.AuditConfig{LogPath: filepath.Join(t.TempDir(), "audit.log"), ExcludePaths: []string{"/health"}, Sample: map[string]int{"/webhook/": 5}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	if _, ok := l.out.(*fileOutput); !ok || l.ExcludePaths[0] != "/health" || l.Sample["/webhook/"] != 5 {
+		t.Errorf("unexpected logger %+v", l)
+	}
+
+	stdout, err := FromConfig(config.AuditConfig{Output: "stdout"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := stdout.out.(*writerOutput); !ok {
+		t.Errorf("expected stdout output, got %T", stdout.out)
+	}
+	if _, err := FromConfig(config.AuditConfig{Output: "kafka"}); err == nil {
+		t.Error("expected error for unknown output")
+	}
+}
+
+func TestFromConfig_Sources(t *testing.T) {
+	dir := t.TempDir()
+	main := filepath.Join(dir, "audit.log")
+	old := `{"timestamp":"2020-01-01T00:00:00Z","path":"/old"}` + "\n"
+	recent := fmt.Sprintf(`{"timestamp":%q,"path":"/recent"}`, time.Now().UTC().Format(time.RFC3339)) + "\n"
+	os.WriteFile(main, []byte(old+recent), 0644)
+	os.WriteFile(filepath.Join(dir, "audit-github.log"), []byte(old+old+recent), 0644)
+
+	l, err := FromConfig(config.AuditConfig{
+		LogPath:   main,
+		Retention: "30d",
+		Sources: map[string]config.AuditSourceConfig{
+			"github": {},
+			"trello": {LogPath: filepath.Join(dir, "trello", "audit.log"), Retention: "1h"},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	handler := Middleware(l, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	for _, path := range []string{"/webhook/github", "/webhook/trello", "/webhook/custom/deploy", "/api/links"} {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("POST", path, nil))
+	}
+	l.Close()
+
+	read := func(path string) []string {
+		data, _ := os.ReadFile(path)
+		var paths []string
+		for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+			var e Entry
+			json.Unmarshal([]byte(line), &e)
+			paths = append(paths, e.Path+"@"+e.Source)
+		}
+		return paths
+	}
+	// startup pruning dropped the 2020 entries; unlisted sources go to the main file
+	if got := strings.Join(read(main), ","); got != "/recent@,/webhook/custom/deploy@custom,/api/links@" {
+		t.Errorf("main file: %s", got)
+	}
+	if got := strings.Join(read(filepath.Join(dir, "audit-github.log")), ","); got != "/recent@,/webhook/github@github" {
+		t.Errorf("github file: %s", got)
+	}
+	if got := strings.Join(read(filepath.Join(dir, "trello", "audit.log")), ","); got != "/webhook/trello@trello" {
+		t.Errorf("trello file: %s", got)
+	}
+}
+
+func TestFileOutput_Prune(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+	f, err := openFile(path, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	for _, ts := range []time.Time{now.Add(-3 * time.Hour), now.Add(-2 * time.Hour), now.Add(-30 * time.Minute)} {
+		f.write(Entry{}, []byte(fmt.Sprintf(`{"timestamp":%q}`, ts.Format(time.RFC3339))))
+	}
+	n, err := f.prune(now.Add(-time.Hour))
+	if err != nil || n != 2 {
+		t.Fatalf("prune = %d, %v; want 2 dropped", n, err)
+	}
+	if n, _ := f.prune(now.Add(-time.Hour)); n != 0 {
+		t.Errorf("second prune dropped %d", n)
+	}
+	// writes after pruning go to the rewritten file
+	f.write(Entry{}, []byte(`{"timestamp":"after"}`))
+	data, _ := os.ReadFile(path)
+	if lines := strings.Split(strings.TrimSpace(string(data)), "\n"); len(lines) != 2 || lines[1] != `{"timestamp":"after"}` {
+		t.Errorf("unexpected file after prune:\n%s", data)
+	}
+}
+
+func TestSyslogOutput_UDP(t *testing.T) {
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pc.Close()
+
+	o := newSyslogOutput(config.AuditSyslogConfig{Address: "udp://" + pc.LocalAddr().String(), Facility: "daemon"})
+	defer o.Close()
+	failed := testEntry
+	failed.Status = 502
+	data, _ := json.Marshal(failed)
+	if err := o.write(failed, data); err != nil {
+		t.Fatal(err)
+	}
+
+	buf := make([]byte, 2048)
+	pc.SetReadDeadline(time.Now().Add(2 * time.Second))
+	n, _, err := pc.ReadFrom(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := string(buf[:n])
+	// daemon (3) * 8 + warning (4)
+	if !strings.HasPrefix(got, "<28>1 2025-01-01T00:00:00Z ") || !strings.Contains(got, " openclaw-relay ") || !strings.HasSuffix(got, " audit - "+string(data)) {
+		t.Errorf("unexpected syslog message %q", got)
+	}
+}
+
+func TestSyslogOutput_TCPFraming(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	received := make(chan string, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		r := bufio.NewReader(conn)
+		length, _ := r.ReadString(' ')
+		var n int
+		json.Unmarshal([]byte(strings.TrimSpace(length)), &n)
+		msg := make([]byte, n)
+		io.ReadFull(r, msg)
+		received <- string(msg)
+	}()
+
+	o := newSyslogOutput(config.AuditSyslogConfig{Address: "tcp://" + ln.Addr().String(), AppName: "relay-prod"})
+	defer o.Close()
+	data, _ := json.Marshal(testEntry)
+	if err := o.write(testEntry, data); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case msg := <-received:
+		// local0 (16) * 8 + info (6)
+		if !strings.HasPrefix(msg, "<134>1 ") || !strings.Contains(msg, " relay-prod ") || !strings.HasSuffix(msg, string(data)) {
+			t.Errorf("unexpected framed message %q", msg)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("no message received")
+	}
+}
+
+type collector struct {
+	mu       sync.Mutex
+	bodies   []string
+	types    []string
+	auth     string
+	failNext bool
+}
+
+func (c *collector) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.failNext {
+		c.failNext = false
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
+	body, _ := io.ReadAll(r.Body)
+	c.bodies = append(c.bodies, string(body))
+	c.types = append(c.types, r.Header.Get("Content-Type"))
+	c.auth = r.Header.Get("Authorization")
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func TestHTTPOutput_NDJSONBatches(t *testing.T) {
+	c := &collector{failNext: true}
+	srv := httptest.NewServer(c)
+	defer srv.Close()
+
+	o := newHTTPOutput(config.AuditHTTPConfig{URL: srv.URL, BatchSize: 2, FlushInterval: "1h", Headers: map[string]string{"Authorization": "Bearer t"}})
+	for _, path := range []string{"/a", "/b", "/c"} {
+		e := testEntry
+		e.Path = path
+		data, _ := json.Marshal(e)
+		o.write(e, data)
+	}
+	// the first full batch is rejected and kept; Close sends everything
+	time.Sleep(50 * time.Millisecond)
+	o.Close()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	joined := strings.Join(c.bodies, "")
+	if len(c.bodies) != 2 || strings.Count(joined, "\n") != 3 {
+		t.Fatalf("expected 3 entries in 2 batches, got %q", c.bodies)
+	}
+	if !strings.Contains(c.bodies[0], `"path":"/a"`) || !strings.Contains(c.bodies[1], `"path":"/c"`) {
+		t.Errorf("entries out of order: %q", c.bodies)
+	}
+	if c.types[0] != "application/x-ndjson" || c.auth != "Bearer t" {
+		t.Errorf("content type %q, auth %q", c.types[0], c.auth)
+	}
+}
+
+func TestHTTPOutput_Loki(t *testing.T) {
+	c := &collector{}
+	srv := httptest.NewServer(c)
+	defer srv.Close()
+
+	o := newHTTPOutput(config.AuditHTTPConfig{URL: srv.URL, Format: "loki", Labels: map[string]string{"app": "relay"}})
+	data, _ := json.Marshal(testEntry)
+	o.write(testEntry, data)
+	github := testEntry
+	github.Source = "github"
+	o.write(github, []byte(`{"source":"github"}`))
+	o.write(testEntry, data)
+	o.Close()
+
+	var push struct {
+		Streams []struct {
+			Stream map[string]string `json:"stream"`
+			Values [][2]string       `json:"values"`
+		} `json:"streams"`
+	}
+	if len(c.bodies) != 1 {
+		t.Fatalf("expected one push, got %d", len(c.bodies))
+	}
+	if err := json.Unmarshal([]byte(c.bodies[0]), &push); err != nil {
+		t.Fatal(err)
+	}
+	if len(push.Streams) != 2 || push.Streams[0].Stream["app"] != "relay" || len(push.Streams[0].Values) != 2 {
+		t.Fatalf("unexpected push %s", c.bodies[0])
+	}
+	if s := push.Streams[1].Stream; s["source"] != "github" || s["app"] != "relay" || len(push.Streams[1].Values) != 1 {
+		t.Errorf("expected a github stream, got %+v", push.Streams[1])
+	}
+	if _, ok := push.Streams[0].Stream["source"]; ok {
+		t.Error("entries without a source should not get a source label")
+	}
+	v := push.Streams[0].Values[0]
+	if v[0] != "1735689600000000000" || v[1] != string(data) {
+		t.Errorf("unexpected value %q", v)
+	}
+}
+
+func TestHTTPOutput_BufferBound(t *testing.T) {
+	o := &httpOutput{size: 1, kick: make(chan struct{}, 1)}
+	for i := 0; i < maxHTTPBuffer+5; i++ {
+		o.write(testEntry, []byte{byte('a' + i)})
+	}
+	if len(o.pending) != maxHTTPBuffer || o.lines[0][0] != 'f' {
+		t.Errorf("expected the oldest entries dropped, kept %d starting at %q", len(o.pending), o.lines[0])
+	}
+}
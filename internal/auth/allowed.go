@@ -0,0 +1,162 @@
+package auth
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/katalabut/openclaw-relay/internal/atomicfile"
+)
+
+// allowedOverrides are runtime changes to google.allowed_emails, persisted next to other relay state.
+type allowedOverrides struct {
+	Added   []string `json:"added,omitempty"`
+	Removed []string `json:"removed,omitempty"`
+}
+
+// LoadAllowedOverrides applies runtime allow-list changes from path (if it exists)
+// and persists future changes made through the admin API there.
+func (g *GoogleAuth) LoadAllowedOverrides(path string) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.overridesPath = path
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	var o allowedOverrides
+	if err := json.Unmarshal(data, &o); err != nil {
+		return fmt.Errorf("parse allowed-email overrides: %w", err)
+	}
+	g.overrides = o
+	g.rebuildAllowedLocked()
+	return nil
+}
+
+// rebuildAllowedLocked recomputes the effective allow-list: config + added - removed.
+func (g *GoogleAuth) rebuildAllowedLocked() {
+	allowed := make(map[string]bool, len(g.configAllowed)+len(g.overrides.Added))
+	for e := range g.configAllowed {
+		allowed[e] = true
+	}
+	for _, e := range g.overrides.Added {
+		allowed[e] = true
+	}
+	for _, e := range g.overrides.Removed {
+		delete(allowed, e)
+	}
+	g.allowedEmails = allowed
+}
+
+func (g *GoogleAuth) saveOverridesLocked() error {
+	if g.overridesPath == "" {
+		return nil
+	}
+	return atomicfile.WriteJSON(g.overridesPath, g.overrides)
+}
+
+func (g *GoogleAuth) isAllowed(email string) bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.allowedEmails[email]
+}
+
+// AllowedEmails returns the effective allow-list, sorted.
+func (g *GoogleAuth) AllowedEmails() []string {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	out := make([]string, 0, len(g.allowedEmails))
+	for e := range g.allowedEmails {
+		out = append(out, e)
+	}
+	sort.Strings(out)
+	return out
+}
+
+func removeString(list []string, s string) []string {
+	out := list[:0]
+	for _, v := range list {
+		if v != s {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+// AllowEmail adds an email to the allow-list at runtime.
+func (g *GoogleAuth) AllowEmail(email string) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.overrides.Removed = removeString(g.overrides.Removed, email)
+	if !g.configAllowed[email] {
+		g.overrides.Added = append(removeString(g.overrides.Added, email), email)
+	}
+	g.rebuildAllowedLocked()
+	return g.saveOverridesLocked()
+}
+
+// DisallowEmail removes an email from the allow-list at runtime.
+// Existing tokens are kept; use DELETE /api/auth/accounts/{email} to off-board fully.
+func (g *GoogleAuth) DisallowEmail(email string) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.overrides.Added = removeString(g.overrides.Added, email)
+	if g.configAllowed[email] {
+		g.overrides.Removed = append(removeString(g.overrides.Removed, email), email)
+	}
+	g.rebuildAllowedLocked()
+	return g.saveOverridesLocked()
+}
+
+// HandleAllowedEmails serves the allow-list admin API:
+//
+//	GET    /api/auth/allowed-emails          list effective emails
+//	POST   /api/auth/allowed-emails          {"email": "..."} add
+//	DELETE /api/auth/allowed-emails/{email}  remove
+func (g *GoogleAuth) HandleAllowedEmails(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	writeErr := func(msg string, code int) {
+		w.WriteHeader(code)
+		json.NewEncoder(w).Encode(map[string]string{"error": msg})
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		json.NewEncoder(w).Encode(map[string]any{"allowed_emails": g.AllowedEmails()})
+	case http.MethodPost:
+		var req struct {
+			Email string `json:"email"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || !strings.Contains(req.Email, "@") {
+			writeErr("invalid email", http.StatusBadRequest)
+			return
+		}
+		if err := g.AllowEmail(req.Email); err != nil {
+			writeErr(err.Error(), http.StatusInternalServerError)
+			return
+		}
+		log.Printf("Allowed email added at runtime: %s", req.Email)
+		json.NewEncoder(w).Encode(map[string]any{"ok": true, "allowed_emails": g.AllowedEmails()})
+	case http.MethodDelete:
+		email := strings.TrimPrefix(r.URL.Path, "/api/auth/allowed-emails/")
+		if email == "" || email == r.URL.Path {
+			writeErr("missing email", http.StatusBadRequest)
+			return
+		}
+		if err := g.DisallowEmail(email); err != nil {
+			writeErr(err.Error(), http.StatusInternalServerError)
+			return
+		}
+		log.Printf("Allowed email removed at runtime: %s", email)
+		json.NewEncoder(w).Encode(map[string]any{"ok": true, "allowed_emails": g.AllowedEmails()})
+	default:
+		writeErr("method not allowed", http.StatusMethodNotAllowed)
+	}
+}
@@ -0,0 +1,126 @@
+package auth
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestAllowEmail_PersistsAndReloads(t *testing.T) {
+	ga, _ := newTestGoogleAuth(t)
+	fp := filepath.Join(t.TempDir(), "allowed-emails.json")
+	if err := ga.LoadAllowedOverrides(fp); err != nil {
+		t.Fatal(err)
+	}
+	if err := ga.AllowEmail("new@example.com"); err != nil {
+		t.Fatal(err)
+	}
+	if !ga.isAllowed("new@example.com") {
+		t.Error("expected runtime-added email to be allowed")
+	}
+
+	ga2, _ := newTestGoogleAuth(t)
+	if err := ga2.LoadAllowedOverrides(fp); err != nil {
+		t.Fatal(err)
+	}
+	got := ga2.AllowedEmails()
+	if len(got) != 2 || got[0] != "new@example.com" || got[1] != "test@example.com" {
+		t.Errorf("unexpected allow-list after reload: %v", got)
+	}
+}
+
+func TestDisallowEmail_ConfigEmail(t *testing.T) {
+	ga, _ := newTestGoogleAuth(t)
+	fp := filepath.Join(t.TempDir(), "allowed-emails.json")
+	ga.LoadAllowedOverrides(fp)
+
+	if err := ga.DisallowEmail("test@example.com"); err != nil {
+		t.Fatal(err)
+	}
+	if ga.isAllowed("test@example.com") {
+		t.Error("expected config email to be removed")
+	}
+
+	// Re-adding a config email clears the removal instead of duplicating it
+	ga.AllowEmail("test@example.com")
+	data, _ := os.ReadFile(fp)
+	var o allowedOverrides
+	json.Unmarshal(data, &o)
+	if len(o.Added) != 0 || len(o.Removed) != 0 {
+		t.Errorf("expected empty overrides, got %+v", o)
+	}
+}
+
+func TestLoadAllowedOverrides_Corrupt(t *testing.T) {
+	ga, _ := newTestGoogleAuth(t)
+	fp := filepath.Join(t.TempDir(), "allowed-emails.json")
+	os.WriteFile(fp, []byte("{"), 0600)
+	if err := ga.LoadAllowedOverrides(fp); err == nil {
+		t.Error("expected error for corrupt overrides")
+	}
+	if !ga.isAllowed("test@example.com") {
+		t.Error("config emails should stay allowed")
+	}
+}
+
+func TestHandleAllowedEmails(t *testing.T) {
+	ga, _ := newTestGoogleAuth(t)
+	ga.LoadAllowedOverrides(filepath.Join(t.TempDir(), "allowed-emails.json"))
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/auth/allowed-emails", ga.HandleAllowedEmails)
+	mux.HandleFunc("/api/auth/allowed-emails/", ga.HandleAllowedEmails)
+
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest("POST", "/api/auth/allowed-emails", strings.NewReader(`{"email":"new@example.com"}`)))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("add: expected 200, got %d", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest("GET", "/api/auth/allowed-emails", nil))
+	var resp map[string][]string
+	json.NewDecoder(rec.Body).Decode(&resp)
+	if len(resp["allowed_emails"]) != 2 {
+		t.Errorf("expected 2 emails, got %v", resp["allowed_emails"])
+	}
+
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest("DELETE", "/api/auth/allowed-emails/new@example.com", nil))
+	if rec.Code != http.StatusOK || ga.isAllowed("new@example.com") {
+		t.Errorf("delete: expected email removed, code %d", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest("POST", "/api/auth/allowed-emails", strings.NewReader(`{"email":"nope"}`)))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("invalid email: expected 400, got %d", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest("DELETE", "/api/auth/allowed-emails", nil))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("missing email: expected 400, got %d", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest("PUT", "/api/auth/allowed-emails", nil))
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("expected 405, got %d", rec.Code)
+	}
+}
+
+func TestHandleLogin_RuntimeAllowedAccount(t *testing.T) {
+	ga, _ := newTestGoogleAuth(t)
+	ga.AllowEmail("new@example.com")
+
+	req := httptest.NewRequest("GET", "/auth/google/login?account=new@example.com", nil)
+	rec := httptest.NewRecorder()
+	ga.handleLogin(rec, req)
+	if rec.Code != http.StatusTemporaryRedirect {
+		t.Errorf("expected redirect for runtime-allowed account, got %d", rec.Code)
+	}
+}
@@ -0,0 +1,78 @@
+package auth
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/katalabut/openclaw-relay/internal/config"
+)
+
+// CORS adds CORS headers for allowed origins on the paths the rules cover and
+// answers their preflight requests itself, before the token check: browsers
+// send preflights without credentials. Requests without an Origin header, or
+// on paths no rule covers, pass through unchanged.
+func CORS(rules []config.CORSRule, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		origin := r.Header.Get("Origin")
+		rule := matchCORSRule(rules, r.URL.Path)
+		if origin == "" || rule == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+		preflight := r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != ""
+
+		allowed := corsOrigin(rule, origin)
+		w.Header().Add("Vary", "Origin")
+		if allowed == "" {
+			if preflight {
+				http.Error(w, `{"error":"origin not allowed"}`, http.StatusForbidden)
+				return
+			}
+			// No CORS headers: the browser withholds the response
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		w.Header().Set("Access-Control-Allow-Origin", allowed)
+		if rule.Credentials {
+			w.Header().Set("Access-Control-Allow-Credentials", "true")
+		}
+		if !preflight {
+			next.ServeHTTP(w, r)
+			return
+		}
+		w.Header().Set("Access-Control-Allow-Methods", strings.Join(rule.ResolvedMethods(), ", "))
+		w.Header().Set("Access-Control-Allow-Headers", strings.Join(rule.ResolvedHeaders(), ", "))
+		w.Header().Set("Access-Control-Max-Age", strconv.Itoa(int(rule.ResolvedMaxAge().Seconds())))
+		w.WriteHeader(http.StatusNoContent)
+	})
+}
+
+func matchCORSRule(rules []config.CORSRule, path string) *config.CORSRule {
+	for i := range rules {
+		if strings.HasPrefix(path, rules[i].ResolvedPath()) {
+			return &rules[i]
+		}
+	}
+	return nil
+}
+
+// corsOrigin returns the Access-Control-Allow-Origin value for origin, or ""
+// when the rule does not allow it. Browsers reject "*" on a response that
+// allows credentials, and config validation refuses the combination; should
+// a rule still have both, "*" allows no origin rather than every one.
+func corsOrigin(rule *config.CORSRule, origin string) string {
+	for _, o := range rule.Origins {
+		if o == "*" {
+			if rule.Credentials {
+				continue
+			}
+			return "*"
+		}
+		if strings.EqualFold(strings.TrimSuffix(o, "/"), origin) {
+			return origin
+		}
+	}
+	return ""
+}
@@ -0,0 +1,102 @@
+package auth
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/katalabut/openclaw-relay/internal/config"
+)
+
+func TestCORS(t *testing.T) {
+	inner := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	rules := []config.CORSRule{
+		{Path: "/api/gmail/", Origins: []string{"*"}},
+		{Origins: []string{"https://dash.example.com/"}, Credentials: true, MaxAge: "1h"},
+	}
+	// Preflights are answered before the token check
+	handler := CORS(rules, Middleware("secret", inner))
+
+	tests := []struct {
+		name        string
+		method      string
+		path        string
+		origin      string
+		preflight   bool
+		token       string
+		wantCode    int
+		wantOrigin  string
+		wantHeaders string
+	}{
+		{"preflight allowed", "OPTIONS", "/api/events", "https://dash.example.com", true, "", http.StatusNoContent, "https://dash.example.com", "X-Relay-Token, Content-Type"},
+		{"preflight other origin", "OPTIONS", "/api/events", "https://evil.example", true, "", http.StatusForbidden, "", ""},
+		{"request allowed", "GET", "/api/events", "https://dash.example.com", false, "secret", http.StatusOK, "https://dash.example.com", ""},
+		{"request still needs token", "GET", "/api/events", "https://dash.example.com", false, "", http.StatusUnauthorized, "https://dash.example.com", ""},
+		{"request other origin", "GET", "/api/events", "https://evil.example", false, "secret", http.StatusOK, "", ""},
+		{"wildcard rule", "GET", "/api/gmail/messages", "https://anything.example", false, "secret", http.StatusOK, "*", ""},
+		{"not covered", "OPTIONS", "/auth/login", "https://dash.example.com", true, "", http.StatusOK, "", ""},
+		{"no origin", "GET", "/api/events", "", false, "secret", http.StatusOK, "", ""},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(tt.method, tt.path, nil)
+		if tt.origin != "" {
+			req.Header.Set("Origin", tt.origin)
+		}
+		if tt.preflight {
+			req.Header.Set("Access-Control-Request-Method", "POST")
+		}
+		if tt.token != "" {
+			req.Header.Set("X-Relay-Token", tt.token)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		if rec.Code != tt.wantCode {
+			t.Errorf("%s: expected %d, got %d", tt.name, tt.wantCode, rec.Code)
+		}
+		if got := rec.Header().Get("Access-Control-Allow-Origin"); got != tt.wantOrigin {
+			t.Errorf("%s: expected origin %q, got %q", tt.name, tt.wantOrigin, got)
+		}
+		if got := rec.Header().Get("Access-Control-Allow-Headers"); got != tt.wantHeaders {
+			t.Errorf("%s: expected headers %q, got %q", tt.name, tt.wantHeaders, got)
+		}
+	}
+
+	req := httptest.NewRequest("OPTIONS", "/api/events", nil)
+	req.Header.Set("Origin", "https://dash.example.com")
+	req.Header.Set("Access-Control-Request-Method", "DELETE")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Header().Get("Access-Control-Allow-Credentials") != "true" || rec.Header().Get("Access-Control-Max-Age") != "3600" {
+		t.Errorf("unexpected preflight headers %v", rec.Header())
+	}
+}
+
+func TestCORS_WildcardWithCredentials(t *testing.T) {
+	inner := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	// Config validation refuses this rule; the middleware must not answer "*"
+	// with credentials either
+	rules := []config.CORSRule{{Origins: []string{"*", "https://dash.example.com"}, Credentials: true}}
+	handler := CORS(rules, inner)
+
+	for origin, want := range map[string]string{
+		"https://dash.example.com": "https://dash.example.com",
+		"https://evil.example":     "",
+	} {
+		req := httptest.NewRequest("GET", "/api/events", nil)
+		req.Header.Set("Origin", origin)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		if got := rec.Header().Get("Access-Control-Allow-Origin"); got != want {
+			t.Errorf("%s: expected origin %q, got %q", origin, want, got)
+		}
+		if got := rec.Header().Get("Vary"); got != "Origin" {
+			t.Errorf("%s: expected Vary: Origin, got %q", origin, got)
+		}
+	}
+	cfg := &config.Config{InMemory: true, Server: config.ServerConfig{CORS: rules}}
+	if err := cfg.Validate(); err == nil {
+		t.Error("expected config validation to refuse \"*\" with credentials")
+	}
+}
@@ -9,10 +9,13 @@ import (
 	"html"
 	"log"
 	"net/http"
+	"net/url"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/katalabut/openclaw-relay/internal/config"
+	"github.com/katalabut/openclaw-relay/internal/health"
 	"github.com/katalabut/openclaw-relay/internal/tokens"
 	"golang.org/x/oauth2"
 	"golang.org/x/oauth2/google"
@@ -20,15 +23,10 @@ import (
 	"google.golang.org/api/option"
 )
 
-var (
-	oauthScopes = []string{
-		"https://www.googleapis.com/auth/gmail.modify",
-		"https://www.googleapis.com/auth/calendar.readonly",
-		"https://www.googleapis.com/auth/userinfo.email",
-	}
+var stateTTL = 10 * time.Minute
 
-	stateTTL = 10 * time.Minute
-)
+// tokenPurgeInterval is how often tokens past their grace period are purged.
+var tokenPurgeInterval = time.Hour
 
 type stateEntry struct {
 	email     string
@@ -38,37 +36,73 @@ type stateEntry struct {
 // GoogleAuth handles OAuth web flow.
 type GoogleAuth struct {
 	oauthCfg      *oauth2.Config
+	configAllowed map[string]bool
 	allowedEmails map[string]bool
+	overrides     allowedOverrides
+	overridesPath string
 	store         *tokens.Store
+	gracePeriod   time.Duration // how long a disconnected account can be restored
 	encKey        string
 	appCfg        *config.Config
 	mu            sync.Mutex
 	stateToEmail  map[string]stateEntry
+	offboardHooks []OffboardHook
+	degraded      func() []health.Feature
 }
 
+// OffboardHook removes per-account state owned by another subsystem.
+// It returns a short label describing what was removed (empty if nothing).
+type OffboardHook func(email string) (string, error)
+
 func NewGoogleAuth(ctx context.Context, cfg *config.GoogleConfig, store *tokens.Store, encKey string, appCfg *config.Config) *GoogleAuth {
 	allowed := make(map[string]bool, len(cfg.AllowedEmails))
+	effective := make(map[string]bool, len(cfg.AllowedEmails))
 	for _, e := range cfg.AllowedEmails {
 		allowed[e] = true
+		effective[e] = true
 	}
 	ga := &GoogleAuth{
-		oauthCfg: &oauth2.Config{
-			ClientID:     cfg.ClientID,
-			ClientSecret: cfg.ClientSecret,
-			RedirectURL:  cfg.RedirectURL,
-			Scopes:       oauthScopes,
-			Endpoint:     google.Endpoint,
-		},
-		allowedEmails: allowed,
+		oauthCfg:      NewOAuthConfig(cfg),
+		configAllowed: allowed,
+		allowedEmails: effective,
 		store:         store,
+		gracePeriod:   cfg.ResolvedTokenGracePeriod(),
 		encKey:        encKey,
 		appCfg:        appCfg,
 		stateToEmail:  map[string]stateEntry{},
 	}
+	for email, t := range store.ListGoogle() {
+		if missing := t.MissingScopes(ga.oauthCfg.Scopes); len(missing) > 0 {
+			log.Printf("Warning: Google account %s was connected without %s from google.scopes; reconnect at /auth/google/login?account=%s",
+				email, strings.Join(shortScopes(missing), ", "), email)
+		}
+	}
 	go ga.cleanupStates(ctx)
+	go ga.purgeTokens(ctx)
 	return ga
 }
 
+// shortScopes strips the common Google prefix from scope URLs for display.
+func shortScopes(scopes []string) []string {
+	out := make([]string, len(scopes))
+	for i, s := range scopes {
+		out[i] = strings.TrimPrefix(s, "https://www.googleapis.com/auth/")
+	}
+	return out
+}
+
+// NewOAuthConfig returns the oauth2 config for the google section, for
+// refreshing tokens outside the server, e.g. in CLI commands.
+func NewOAuthConfig(cfg *config.GoogleConfig) *oauth2.Config {
+	return &oauth2.Config{
+		ClientID:     cfg.ClientID,
+		ClientSecret: cfg.ClientSecret,
+		RedirectURL:  cfg.RedirectURL,
+		Scopes:       cfg.ResolvedScopes(),
+		Endpoint:     google.Endpoint,
+	}
+}
+
 // OAuthConfig returns the oauth2 config for token refresh.
 func (g *GoogleAuth) OAuthConfig() *oauth2.Config {
 	return g.oauthCfg
@@ -129,12 +163,34 @@ func (g *GoogleAuth) cleanupStates(ctx context.Context) {
 	}
 }
 
+// purgeTokens deletes disconnected account tokens once their grace period has
+// passed, at startup and then every tokenPurgeInterval.
+func (g *GoogleAuth) purgeTokens(ctx context.Context) {
+	ticker := time.NewTicker(tokenPurgeInterval)
+	defer ticker.Stop()
+	for {
+		purged, err := g.store.PurgeDeactivatedGoogle(g.gracePeriod)
+		if err != nil {
+			log.Printf("Token purge error: %v", err)
+		}
+		for _, email := range purged {
+			log.Printf("Purged Google token for %s (disconnected more than %s ago)", email, g.gracePeriod)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
 // RegisterRoutes adds OAuth routes to the mux.
 func (g *GoogleAuth) RegisterRoutes(mux *http.ServeMux) {
 	mux.HandleFunc("/", g.handleRoot)
 	mux.HandleFunc("/auth/google/login", g.handleLogin)
 	mux.HandleFunc("/auth/google/callback", g.handleCallback)
 	mux.HandleFunc("/auth/logout", g.handleLogout)
+	mux.HandleFunc("/auth/restore", g.handleRestore)
 }
 
 func (g *GoogleAuth) handleRoot(w http.ResponseWriter, r *http.Request) {
@@ -151,7 +207,7 @@ func (g *GoogleAuth) handleRoot(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	// Session exists but email not allowed → clear and show login
-	if !g.allowedEmails[sessionEmail] {
+	if !g.isAllowed(sessionEmail) {
 		clearSessionCookie(w)
 		g.renderLoginPage(w)
 		return
@@ -189,6 +245,7 @@ Sign in with Google
 func (g *GoogleAuth) renderDashboard(w http.ResponseWriter, sessionEmail string) {
 	w.Header().Set("Content-Type", "text/html; charset=utf-8")
 	accounts := g.store.ListGoogle()
+	disconnected := g.store.ListDeactivatedGoogle()
 
 	fmt.Fprint(w, `<!DOCTYPE html>
 <html lang="en"><head><meta charset="utf-8"><meta name="viewport" content="width=device-width,initial-scale=1">
@@ -229,11 +286,22 @@ body{background:#0d1117;color:#c9d1d9;font-family:-apple-system,BlinkMacSystemFo
 
 	// Google Accounts section
 	fmt.Fprint(w, `<div class="section"><h2>Google Accounts</h2>`)
-	for email := range g.allowedEmails {
+	for _, email := range g.AllowedEmails() {
 		fmt.Fprint(w, `<div class="card"><div class="card-row">`)
-		if _, ok := accounts[email]; ok {
-			fmt.Fprintf(w, `<div><span class="badge badge-ok">connected</span> <span>%s</span></div>`, html.EscapeString(email))
-			fmt.Fprintf(w, `<a class="btn-sm" href="/auth/logout?account=%s">Disconnect</a>`, html.EscapeString(email))
+		if t, ok := accounts[email]; ok {
+			if missing := t.MissingScopes(g.oauthCfg.Scopes); len(missing) > 0 {
+				// google.scopes changed since the account was connected
+				fmt.Fprintf(w, `<div><span class="badge badge-warn">reconnect needed</span> <span>%s</span> <span class="info">missing %s</span></div>`,
+					html.EscapeString(email), html.EscapeString(strings.Join(shortScopes(missing), ", ")))
+				fmt.Fprintf(w, `<a class="btn-sm" href="/auth/google/login?account=%s">Reconnect</a>`, html.EscapeString(email))
+			} else {
+				fmt.Fprintf(w, `<div><span class="badge badge-ok">connected</span> <span>%s</span></div>`, html.EscapeString(email))
+				fmt.Fprintf(w, `<a class="btn-sm" href="/auth/logout?account=%s">Disconnect</a>`, html.EscapeString(email))
+			}
+		} else if t, ok := disconnected[email]; ok {
+			purgeAt := t.DeactivatedAt.Add(g.gracePeriod).UTC().Format("2006-01-02 15:04 MST")
+			fmt.Fprintf(w, `<div><span class="badge badge-warn">disconnected</span> <span>%s</span> <span class="info">token kept until %s</span></div>`, html.EscapeString(email), purgeAt)
+			fmt.Fprintf(w, `<a class="btn-sm" href="/auth/restore?account=%s">Restore</a>`, html.EscapeString(email))
 		} else {
 			fmt.Fprintf(w, `<div><span class="badge badge-off">not connected</span> <span>%s</span></div>`, html.EscapeString(email))
 			fmt.Fprintf(w, `<a class="btn-sm" href="/auth/google/login?account=%s">Connect</a>`, html.EscapeString(email))
@@ -242,6 +310,18 @@ body{background:#0d1117;color:#c9d1d9;font-family:-apple-system,BlinkMacSystemFo
 	}
 	fmt.Fprint(w, `</div>`)
 
+	// Degraded features section
+	if g.degraded != nil {
+		if degraded := g.degraded(); len(degraded) > 0 {
+			fmt.Fprint(w, `<div class="section"><h2>Degraded Features</h2>`)
+			for _, f := range degraded {
+				fmt.Fprintf(w, `<div class="card"><div class="card-row"><div><span class="badge badge-warn">disabled</span> <strong>%s</strong></div></div><div class="info">%s</div></div>`,
+					html.EscapeString(f.Name), html.EscapeString(f.Reason))
+			}
+			fmt.Fprint(w, `</div>`)
+		}
+	}
+
 	// Integrations section
 	fmt.Fprint(w, `<div class="section"><h2>Integrations</h2>`)
 	if g.appCfg != nil {
@@ -292,7 +372,7 @@ body{background:#0d1117;color:#c9d1d9;font-family:-apple-system,BlinkMacSystemFo
 
 func (g *GoogleAuth) handleLogin(w http.ResponseWriter, r *http.Request) {
 	account := r.URL.Query().Get("account")
-	if account != "" && !g.allowedEmails[account] {
+	if account != "" && !g.isAllowed(account) {
 		http.Error(w, "account is not allowed", http.StatusForbidden)
 		return
 	}
@@ -341,7 +421,7 @@ func (g *GoogleAuth) handleCallback(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "authenticated with different account", http.StatusForbidden)
 		return
 	}
-	if !g.allowedEmails[email] {
+	if !g.isAllowed(email) {
 		log.Printf("Rejected email: %s", email)
 		w.Header().Set("Content-Type", "text/html; charset=utf-8")
 		w.WriteHeader(http.StatusForbidden)
@@ -353,7 +433,7 @@ func (g *GoogleAuth) handleCallback(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := g.store.SaveGoogle(token, email); err != nil {
+	if err := g.store.SaveGoogleScopes(token, email, tokens.GrantedScopes(token, g.oauthCfg.Scopes)); err != nil {
 		log.Printf("Token save error: %v", err)
 		http.Error(w, "Failed to save token", http.StatusInternalServerError)
 		return
@@ -367,9 +447,12 @@ func (g *GoogleAuth) handleCallback(w http.ResponseWriter, r *http.Request) {
 func (g *GoogleAuth) handleLogout(w http.ResponseWriter, r *http.Request) {
 	account := r.URL.Query().Get("account")
 	if account != "" {
-		// Disconnecting a specific Google account (not ending user session)
-		if err := g.store.ClearGoogle(account); err != nil {
-			log.Printf("Clear token error: %v", err)
+		// Disconnecting a specific Google account (not ending user session).
+		// The token is kept for the grace period so the disconnect can be undone.
+		if err := g.store.DeactivateGoogle(account); err != nil {
+			log.Printf("Deactivate token error: %v", err)
+		} else {
+			log.Printf("Disconnected Google account %s; restorable for %s", account, g.gracePeriod)
 		}
 	} else {
 		// Full logout — clear session cookie
@@ -378,6 +461,106 @@ func (g *GoogleAuth) handleLogout(w http.ResponseWriter, r *http.Request) {
 	http.Redirect(w, r, "/", http.StatusTemporaryRedirect)
 }
 
+// handleRestore reactivates a disconnected account from the dashboard.
+func (g *GoogleAuth) handleRestore(w http.ResponseWriter, r *http.Request) {
+	sessionEmail := getSessionEmail(r, g.encKey)
+	if sessionEmail == "" || !g.isAllowed(sessionEmail) {
+		http.Error(w, "sign in first", http.StatusForbidden)
+		return
+	}
+	account := r.URL.Query().Get("account")
+	if err := g.store.RestoreGoogle(account); err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	log.Printf("Restored Google account %s (by %s)", account, sessionEmail)
+	http.Redirect(w, r, "/", http.StatusTemporaryRedirect)
+}
+
+// HandleAccount serves /api/auth/accounts/{email}: DELETE off-boards the
+// account and POST .../restore reactivates a disconnected one.
+func (g *GoogleAuth) HandleAccount(w http.ResponseWriter, r *http.Request) {
+	if strings.HasSuffix(r.URL.Path, "/restore") {
+		g.HandleRestoreAccount(w, r)
+		return
+	}
+	g.HandleDeleteAccount(w, r)
+}
+
+// HandleRestoreAccount reactivates a disconnected account's token within its
+// grace period (for POST /api/auth/accounts/{email}/restore).
+func (g *GoogleAuth) HandleRestoreAccount(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		json.NewEncoder(w).Encode(map[string]string{"error": "method not allowed"})
+		return
+	}
+	email := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/api/auth/accounts/"), "/restore")
+	if err := g.store.RestoreGoogle(email); err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+	log.Printf("Restored Google account %s", email)
+	json.NewEncoder(w).Encode(map[string]any{"ok": true, "email": email})
+}
+
+// SetDegraded makes the dashboard list the features degraded reports.
+func (g *GoogleAuth) SetDegraded(degraded func() []health.Feature) {
+	g.degraded = degraded
+}
+
+// OnOffboard registers a hook run by HandleDeleteAccount after the account's tokens are removed.
+func (g *GoogleAuth) OnOffboard(hook OffboardHook) {
+	g.offboardHooks = append(g.offboardHooks, hook)
+}
+
+// HandleDeleteAccount off-boards a Google account (for DELETE /api/auth/accounts/{email}):
+// removes its stored tokens and runs all registered offboard hooks.
+func (g *GoogleAuth) HandleDeleteAccount(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if r.Method != http.MethodDelete {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		json.NewEncoder(w).Encode(map[string]string{"error": "method not allowed"})
+		return
+	}
+	email := strings.TrimPrefix(r.URL.Path, "/api/auth/accounts/")
+	if email == "" {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "missing account email"})
+		return
+	}
+
+	removed := []string{}
+	var errs []string
+	if _, disconnected := g.store.ListDeactivatedGoogle()[email]; disconnected || g.store.GetGoogle(email) != nil {
+		if err := g.store.ClearGoogle(email); err != nil {
+			errs = append(errs, fmt.Sprintf("tokens: %v", err))
+		} else {
+			removed = append(removed, "tokens")
+		}
+	}
+	for _, hook := range g.offboardHooks {
+		what, err := hook(email)
+		if err != nil {
+			errs = append(errs, err.Error())
+			continue
+		}
+		if what != "" {
+			removed = append(removed, what)
+		}
+	}
+
+	log.Printf("Off-boarded Google account %s (removed: %s)", email, strings.Join(removed, ", "))
+	resp := map[string]any{"ok": len(errs) == 0, "email": email, "removed": removed}
+	if len(errs) > 0 {
+		resp["errors"] = errs
+		w.WriteHeader(http.StatusInternalServerError)
+	}
+	json.NewEncoder(w).Encode(resp)
+}
+
 // HandleAuthStatus returns auth status as JSON (for /api/auth/status).
 func (g *GoogleAuth) HandleAuthStatus(w http.ResponseWriter, r *http.Request) {
 	accounts := g.store.ListGoogle()
@@ -386,11 +569,27 @@ func (g *GoogleAuth) HandleAuthStatus(w http.ResponseWriter, r *http.Request) {
 	googleMap := resp["google"].(map[string]any)
 	list := make([]map[string]any, 0, len(accounts))
 	for _, gt := range accounts {
-		list = append(list, map[string]any{
+		acc := map[string]any{
 			"email":      gt.Email,
 			"expires_at": gt.Expiry,
-		})
+			"scopes":     gt.Scopes,
+		}
+		if missing := gt.MissingScopes(g.oauthCfg.Scopes); len(missing) > 0 {
+			acc["missing_scopes"] = missing
+			acc["reconnect_url"] = "/auth/google/login?account=" + url.QueryEscape(gt.Email)
+		}
+		list = append(list, acc)
 	}
 	googleMap["accounts"] = list
+	disconnected := g.store.ListDeactivatedGoogle()
+	dlist := make([]map[string]any, 0, len(disconnected))
+	for _, gt := range disconnected {
+		dlist = append(dlist, map[string]any{
+			"email":          gt.Email,
+			"deactivated_at": gt.DeactivatedAt,
+			"purge_at":       gt.DeactivatedAt.Add(g.gracePeriod),
+		})
+	}
+	googleMap["disconnected"] = dlist
 	json.NewEncoder(w).Encode(resp)
 }
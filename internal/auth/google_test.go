@@ -3,6 +3,7 @@ package auth
 import (
 	"context"
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"path/filepath"
@@ -11,6 +12,7 @@ import (
 	"time"
 
 	"github.com/katalabut/openclaw-relay/internal/config"
+	"github.com/katalabut/openclaw-relay/internal/health"
 	"github.com/katalabut/openclaw-relay/internal/tokens"
 	"golang.org/x/oauth2"
 )
@@ -142,6 +144,77 @@ func TestOAuthConfig(t *testing.T) {
 	if cfg.ClientID != "test-client-id" {
 		t.Errorf("expected test-client-id, got %s", cfg.ClientID)
 	}
+	if len(cfg.Scopes) != 3 {
+		t.Errorf("expected default scopes, got %v", cfg.Scopes)
+	}
+}
+
+func TestOAuthConfig_ConfiguredScopes(t *testing.T) {
+	store, _ := tokens.NewStore(filepath.Join(t.TempDir(), "tokens.json.enc"), testKey)
+	cfg := &config.GoogleConfig{ClientID: "id", Scopes: []string{"gmail.readonly"}}
+	ga := NewGoogleAuth(context.Background(), cfg, store, testKey, nil)
+	scopes := strings.Join(ga.OAuthConfig().Scopes, " ")
+	if strings.Contains(scopes, "gmail.modify") || !strings.Contains(scopes, "gmail.readonly") {
+		t.Errorf("unexpected scopes: %s", scopes)
+	}
+}
+
+func TestScopesChanged(t *testing.T) {
+	ga, store := newTestGoogleAuth(t)
+	tok := &oauth2.Token{AccessToken: "a", RefreshToken: "r", Expiry: time.Now().Add(time.Hour)}
+	// Connected while google.scopes was read-only; the relay now asks for modify
+	store.SaveGoogleScopes(tok, "test@example.com", []string{
+		"https://www.googleapis.com/auth/gmail.readonly",
+		"https://www.googleapis.com/auth/calendar.readonly",
+		"https://www.googleapis.com/auth/userinfo.email",
+	})
+
+	rec := httptest.NewRecorder()
+	ga.HandleAuthStatus(rec, httptest.NewRequest("GET", "/api/auth/status", nil))
+	var resp struct {
+		Google struct {
+			Accounts []struct {
+				Email         string   `json:"email"`
+				Scopes        []string `json:"scopes"`
+				MissingScopes []string `json:"missing_scopes"`
+				ReconnectURL  string   `json:"reconnect_url"`
+			} `json:"accounts"`
+		} `json:"google"`
+	}
+	json.NewDecoder(rec.Body).Decode(&resp)
+	if len(resp.Google.Accounts) != 1 {
+		t.Fatalf("status = %+v", resp)
+	}
+	acc := resp.Google.Accounts[0]
+	if len(acc.Scopes) != 3 || len(acc.MissingScopes) != 1 || acc.MissingScopes[0] != "https://www.googleapis.com/auth/gmail.modify" ||
+		acc.ReconnectURL != "/auth/google/login?account=test%40example.com" {
+		t.Errorf("account = %+v", acc)
+	}
+
+	ga.SetDegraded(func() []health.Feature {
+		return []health.Feature{{Name: "audit_log", Reason: "open audit.log: permission denied"}}
+	})
+	mux := http.NewServeMux()
+	ga.RegisterRoutes(mux)
+	rec0 := httptest.NewRecorder()
+	setSessionCookie(rec0, "test@example.com", testKey)
+	req := httptest.NewRequest("GET", "/", nil)
+	req.AddCookie(rec0.Result().Cookies()[0])
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+	body := rec.Body.String()
+	if !strings.Contains(body, "reconnect needed") || !strings.Contains(body, "missing gmail.modify") || !strings.Contains(body, `href="/auth/google/login?account=test@example.com">Reconnect`) {
+		t.Errorf("dashboard lacks the reconnect prompt:\n%s", body)
+	}
+	if !strings.Contains(body, "Degraded Features") || !strings.Contains(body, "<strong>audit_log</strong>") {
+		t.Errorf("dashboard lacks the degraded features:\n%s", body)
+	}
+
+	// Reconnecting with the current scopes clears it
+	store.SaveGoogleScopes(tok, "test@example.com", ga.OAuthConfig().Scopes)
+	if missing := store.GetGoogle("test@example.com").MissingScopes(ga.OAuthConfig().Scopes); missing != nil {
+		t.Errorf("missing after reconnect = %v", missing)
+	}
 }
 
 func TestHandleLogin(t *testing.T) {
@@ -226,6 +299,100 @@ func TestHandleLogout_Account(t *testing.T) {
 	if store.GetGoogle() != nil {
 		t.Error("expected token cleared after account logout")
 	}
+	if _, ok := store.ListDeactivatedGoogle()["test@example.com"]; !ok {
+		t.Error("expected token kept as deactivated for the grace period")
+	}
+}
+
+func TestHandleRestore(t *testing.T) {
+	ga, store := newTestGoogleAuth(t)
+	tok := &oauth2.Token{AccessToken: "a", RefreshToken: "r", Expiry: time.Now().Add(time.Hour)}
+	store.SaveGoogle(tok, "test@example.com")
+	store.DeactivateGoogle("test@example.com")
+
+	mux := http.NewServeMux()
+	ga.RegisterRoutes(mux)
+
+	rec0 := httptest.NewRecorder()
+	setSessionCookie(rec0, "test@example.com", testKey)
+	sessionCookie := rec0.Result().Cookies()[0]
+
+	// Dashboard offers the restore
+	req := httptest.NewRequest("GET", "/", nil)
+	req.AddCookie(sessionCookie)
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+	if !strings.Contains(rec.Body.String(), "/auth/restore?account=test@example.com") {
+		t.Error("expected restore link for disconnected account")
+	}
+
+	// Without a session the restore is refused
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest("GET", "/auth/restore?account=test@example.com", nil))
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("expected 403 without session, got %d", rec.Code)
+	}
+
+	req = httptest.NewRequest("GET", "/auth/restore?account=test@example.com", nil)
+	req.AddCookie(sessionCookie)
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+	if rec.Code != http.StatusTemporaryRedirect {
+		t.Errorf("expected 307, got %d", rec.Code)
+	}
+	if store.GetGoogle("test@example.com") == nil {
+		t.Error("expected token restored")
+	}
+}
+
+func TestHandleRestoreAccount(t *testing.T) {
+	ga, store := newTestGoogleAuth(t)
+	tok := &oauth2.Token{AccessToken: "a", RefreshToken: "r", Expiry: time.Now().Add(time.Hour)}
+	store.SaveGoogle(tok, "test@example.com")
+	store.DeactivateGoogle("test@example.com")
+
+	rec := httptest.NewRecorder()
+	ga.HandleAuthStatus(rec, httptest.NewRequest("GET", "/api/auth/status", nil))
+	if !strings.Contains(rec.Body.String(), `"disconnected":[{`) {
+		t.Errorf("expected disconnected account in status, got %s", rec.Body.String())
+	}
+
+	rec = httptest.NewRecorder()
+	ga.HandleAccount(rec, httptest.NewRequest("GET", "/api/auth/accounts/test@example.com/restore", nil))
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("expected 405, got %d", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	ga.HandleAccount(rec, httptest.NewRequest("POST", "/api/auth/accounts/test@example.com/restore", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if store.GetGoogle("test@example.com") == nil {
+		t.Error("expected token restored")
+	}
+
+	rec = httptest.NewRecorder()
+	ga.HandleAccount(rec, httptest.NewRequest("POST", "/api/auth/accounts/test@example.com/restore", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("expected 404 for an active account, got %d", rec.Code)
+	}
+}
+
+func TestHandleDeleteAccount_Disconnected(t *testing.T) {
+	ga, store := newTestGoogleAuth(t)
+	tok := &oauth2.Token{AccessToken: "a", RefreshToken: "r", Expiry: time.Now().Add(time.Hour)}
+	store.SaveGoogle(tok, "test@example.com")
+	store.DeactivateGoogle("test@example.com")
+
+	rec := httptest.NewRecorder()
+	ga.HandleAccount(rec, httptest.NewRequest("DELETE", "/api/auth/accounts/test@example.com", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rec.Code)
+	}
+	if len(store.ListDeactivatedGoogle()) != 0 {
+		t.Error("expected off-boarding to remove the deactivated token")
+	}
 }
 
 func TestHandleLogout_Session(t *testing.T) {
@@ -277,3 +444,67 @@ func TestVerifyState(t *testing.T) {
 		t.Error("should reject invalid state")
 	}
 }
+
+func TestHandleDeleteAccount(t *testing.T) {
+	ga, store := newTestGoogleAuth(t)
+	tok := &oauth2.Token{AccessToken: "a", RefreshToken: "r", Expiry: time.Now().Add(time.Hour)}
+	store.SaveGoogle(tok, "test@example.com")
+
+	var hooked string
+	ga.OnOffboard(func(email string) (string, error) {
+		hooked = email
+		return "poller_state", nil
+	})
+
+	req := httptest.NewRequest("DELETE", "/api/auth/accounts/test@example.com", nil)
+	rec := httptest.NewRecorder()
+	ga.HandleDeleteAccount(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rec.Code)
+	}
+	if store.GetGoogle("test@example.com") != nil {
+		t.Error("expected token to be removed")
+	}
+	if hooked != "test@example.com" {
+		t.Errorf("expected hook to run for account, got %q", hooked)
+	}
+	var resp struct {
+		Removed []string `json:"removed"`
+	}
+	json.NewDecoder(rec.Body).Decode(&resp)
+	if len(resp.Removed) != 2 || resp.Removed[0] != "tokens" || resp.Removed[1] != "poller_state" {
+		t.Errorf("unexpected removed list: %v", resp.Removed)
+	}
+}
+
+func TestHandleDeleteAccount_HookError(t *testing.T) {
+	ga, _ := newTestGoogleAuth(t)
+	ga.OnOffboard(func(email string) (string, error) {
+		return "", fmt.Errorf("poller state: permission denied")
+	})
+
+	req := httptest.NewRequest("DELETE", "/api/auth/accounts/test@example.com", nil)
+	rec := httptest.NewRecorder()
+	ga.HandleDeleteAccount(rec, req)
+
+	if rec.Code != http.StatusInternalServerError {
+		t.Errorf("expected 500, got %d", rec.Code)
+	}
+}
+
+func TestHandleDeleteAccount_BadRequests(t *testing.T) {
+	ga, _ := newTestGoogleAuth(t)
+
+	rec := httptest.NewRecorder()
+	ga.HandleDeleteAccount(rec, httptest.NewRequest("GET", "/api/auth/accounts/test@example.com", nil))
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("expected 405, got %d", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	ga.HandleDeleteAccount(rec, httptest.NewRequest("DELETE", "/api/auth/accounts/", nil))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("expected 400, got %d", rec.Code)
+	}
+}
@@ -1,12 +1,36 @@
 package auth
 
 import (
+	"context"
 	"crypto/subtle"
 	"net/http"
 	"strings"
+
+	"github.com/katalabut/openclaw-relay/internal/config"
 )
 
 func Middleware(internalToken string, next http.Handler) http.Handler {
+	return KeyMiddleware(internalToken, nil, next)
+}
+
+type keyCtx struct{}
+
+// KeyFrom returns the API key a request was authorized with, or nil for the
+// internal token.
+func KeyFrom(ctx context.Context) *config.APIKey {
+	k, _ := ctx.Value(keyCtx{}).(*config.APIKey)
+	return k
+}
+
+// keyRoute reports whether an API key may call path: the Gmail API, but not
+// the poller status and quota usage.
+func keyRoute(path string) bool {
+	return strings.HasPrefix(path, "/api/gmail/") && path != "/api/gmail/pollers" && path != "/api/gmail/quota"
+}
+
+// KeyMiddleware is Middleware that also accepts the restricted server.api_keys.
+// A key only reaches the Gmail API, whose handlers read its scope with KeyFrom.
+func KeyMiddleware(internalToken string, keys []config.APIKey, next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		path := r.URL.Path
 		// Public routes
@@ -17,10 +41,52 @@ func Middleware(internalToken string, next http.Handler) http.Handler {
 		// Protected routes require token
 		if strings.HasPrefix(path, "/api/") {
 			token := r.Header.Get("X-Relay-Token")
-			if token == "" || subtle.ConstantTimeCompare([]byte(token), []byte(internalToken)) != 1 {
+			if token == "" {
 				http.Error(w, `{"error":"unauthorized"}`, http.StatusUnauthorized)
 				return
 			}
+			if subtle.ConstantTimeCompare([]byte(token), []byte(internalToken)) != 1 {
+				key := matchKey(keys, token)
+				if key == nil {
+					http.Error(w, `{"error":"unauthorized"}`, http.StatusUnauthorized)
+					return
+				}
+				if !keyRoute(path) {
+					http.Error(w, `{"error":"not allowed for this API key"}`, http.StatusForbidden)
+					return
+				}
+				r = r.WithContext(context.WithValue(r.Context(), keyCtx{}, key))
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// matchKey returns the key whose value is token, comparing every key in
+// constant time.
+func matchKey(keys []config.APIKey, token string) *config.APIKey {
+	var found *config.APIKey
+	for i := range keys {
+		if subtle.ConstantTimeCompare([]byte(token), []byte(keys[i].Key)) == 1 {
+			found = &keys[i]
+		}
+	}
+	return found
+}
+
+// ReadOnly rejects mutating /api/* requests with 403 while read-only mode is on.
+// Webhooks, OAuth routes and read requests are unaffected.
+func ReadOnly(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasPrefix(r.URL.Path, "/api/") {
+			switch r.Method {
+			case http.MethodGet, http.MethodHead, http.MethodOptions:
+			default:
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(http.StatusForbidden)
+				w.Write([]byte(`{"error":"relay is in read-only mode"}`))
+				return
+			}
 		}
 		next.ServeHTTP(w, r)
 	})
@@ -4,6 +4,8 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"testing"
+
+	"github.com/katalabut/openclaw-relay/internal/config"
 )
 
 func TestMiddleware_NoToken(t *testing.T) {
@@ -68,3 +70,69 @@ func TestMiddleware_PublicRoutes(t *testing.T) {
 		}
 	}
 }
+
+func TestReadOnly(t *testing.T) {
+	inner := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	handler := ReadOnly(inner)
+
+	tests := []struct {
+		method string
+		path   string
+		want   int
+	}{
+		{"POST", "/api/gmail/modify/m1", http.StatusForbidden},
+		{"DELETE", "/api/auth/accounts/a@b.com", http.StatusForbidden},
+		{"POST", "/api/events/replay/e1", http.StatusForbidden},
+		{"GET", "/api/gmail/messages", http.StatusOK},
+		{"POST", "/webhook/trello", http.StatusOK},
+		{"GET", "/auth/google/callback", http.StatusOK},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(tt.method, tt.path, nil)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		if rec.Code != tt.want {
+			t.Errorf("%s %s: expected %d, got %d", tt.method, tt.path, tt.want, rec.Code)
+		}
+	}
+}
+
+func TestKeyMiddleware(t *testing.T) {
+	var got *config.APIKey
+	inner := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = KeyFrom(r.Context())
+		w.WriteHeader(http.StatusOK)
+	})
+	keys := []config.APIKey{{Name: "reader", Key: "reader-key"}}
+	handler := KeyMiddleware("secret", keys, inner)
+
+	tests := []struct {
+		token   string
+		path    string
+		want    int
+		wantKey string
+	}{
+		{"reader-key", "/api/gmail/messages", http.StatusOK, "reader"},
+		{"reader-key", "/api/gmail/pollers", http.StatusForbidden, ""},
+		{"reader-key", "/api/gmail/quota", http.StatusForbidden, ""},
+		{"reader-key", "/api/events", http.StatusForbidden, ""},
+		{"secret", "/api/events", http.StatusOK, ""},
+		{"other-key", "/api/gmail/messages", http.StatusUnauthorized, ""},
+		{"", "/api/gmail/messages", http.StatusUnauthorized, ""},
+	}
+	for _, tt := range tests {
+		got = nil
+		req := httptest.NewRequest("GET", tt.path, nil)
+		req.Header.Set("X-Relay-Token", tt.token)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		if rec.Code != tt.want {
+			t.Errorf("%q %s: expected %d, got %d", tt.token, tt.path, tt.want, rec.Code)
+		}
+		if (got != nil && got.Name != tt.wantKey) || (got == nil && tt.wantKey != "") {
+			t.Errorf("%q %s: unexpected key %+v", tt.token, tt.path, got)
+		}
+	}
+}
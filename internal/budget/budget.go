@@ -0,0 +1,239 @@
+// Package budget counts the agent jobs each source and rule creates per
+// calendar month (UTC) and stops a source or rule at its monthly cap, so a
+// runaway automation cannot burn through the agent budget.
+package budget
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/katalabut/openclaw-relay/internal/atomicfile"
+	"github.com/katalabut/openclaw-relay/internal/gateway"
+)
+
+// ErrOverCap is returned for jobs dropped because their source or rule is at its cap.
+var ErrOverCap = errors.New("monthly job cap reached")
+
+// Caps are monthly job limits. Rules are keyed "<source>/<rule>", where rule
+// is the job's rule tag (see gateway.JobSpec).
+type Caps struct {
+	Sources map[string]int
+	Rules   map[string]int
+}
+
+// Alert is the job sent once per month when a cap is first reached.
+type Alert struct {
+	AgentID string
+	Timeout int
+}
+
+type usage struct {
+	Month   string          `json:"month"` // "2006-01"
+	Counts  map[string]int  `json:"counts"`
+	Alerted map[string]bool `json:"alerted,omitempty"`
+}
+
+// Budget is a GatewayClient that counts jobs and drops those over a cap
+// before they reach the wrapped client.
+type Budget struct {
+	next     gateway.GatewayClient
+	caps     Caps
+	alert    Alert
+	filePath string
+
+	mu    sync.Mutex
+	usage usage
+	now   func() time.Time
+}
+
+// New wraps next. Counts persist to filePath; an empty filePath keeps them in memory only.
+func New(next gateway.GatewayClient, caps Caps, alert Alert, filePath string) (*Budget, error) {
+	b := &Budget{next: next, caps: caps, alert: alert, filePath: filePath, now: time.Now}
+	b.usage = usage{Month: b.month(), Counts: map[string]int{}}
+	if filePath == "" {
+		return b, nil
+	}
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return b, nil
+		}
+		return nil, err
+	}
+	var u usage
+	if err := json.Unmarshal(data, &u); err != nil {
+		return nil, fmt.Errorf("parse budget: %w", err)
+	}
+	if u.Month == b.usage.Month && u.Counts != nil {
+		b.usage = u
+	}
+	return b, nil
+}
+
+func (b *Budget) month() string {
+	return b.now().UTC().Format("2006-01")
+}
+
+func (b *Budget) save() error {
+	if b.filePath == "" {
+		return nil
+	}
+	return atomicfile.WriteJSON(b.filePath, b.usage)
+}
+
+func (b *Budget) CreateOneShotJob(name, message string, timeoutSeconds, delaySeconds int) error {
+	return b.CreateOneShotJobForAgent(name, message, "", timeoutSeconds, delaySeconds)
+}
+
+func (b *Budget) CreateOneShotJobForAgent(name, message, agentID string, timeoutSeconds, delaySeconds int) error {
+	return b.CreateJob(gateway.JobSpec{Name: name, Message: message, AgentID: agentID, TimeoutSeconds: timeoutSeconds, DelaySeconds: delaySeconds})
+}
+
+// CreateJob counts spec against its source and rule, and forwards it unless
+// either is at its cap.
+func (b *Budget) CreateJob(spec gateway.JobSpec) error {
+	keys := jobKeys(spec)
+
+	b.mu.Lock()
+	if m := b.month(); b.usage.Month != m {
+		b.usage = usage{Month: m, Counts: map[string]int{}}
+	}
+	var over []string
+	for _, k := range keys {
+		if limit, ok := b.limit(k); ok && b.usage.Counts[k] >= limit {
+			over = append(over, k)
+		}
+	}
+	var alerts []string
+	if len(over) == 0 {
+		for _, k := range keys {
+			b.usage.Counts[k]++
+		}
+	} else {
+		for _, k := range over {
+			if !b.usage.Alerted[k] {
+				if b.usage.Alerted == nil {
+					b.usage.Alerted = map[string]bool{}
+				}
+				b.usage.Alerted[k] = true
+				alerts = append(alerts, k)
+			}
+		}
+	}
+	if err := b.save(); err != nil {
+		log.Printf("Budget: failed to save usage: %v", err)
+	}
+	month := b.usage.Month
+	b.mu.Unlock()
+
+	for _, k := range alerts {
+		b.sendAlert(k, month)
+	}
+	if len(over) > 0 {
+		log.Printf("Budget: dropping job %q: %s at its monthly cap", spec.Name, over[0])
+		return fmt.Errorf("%s: %w", over[0], ErrOverCap)
+	}
+	return gateway.CreateJob(b.next, spec)
+}
+
+// jobKeys returns the counters a job adds to: its source and its source/rule.
+func jobKeys(spec gateway.JobSpec) []string {
+	if spec.Source == "" {
+		return nil
+	}
+	keys := []string{spec.Source}
+	rule := spec.Rule
+	if r := spec.Tags["rule"]; r != "" {
+		rule = r
+	}
+	if rule != "" {
+		keys = append(keys, spec.Source+"/"+rule)
+	}
+	return keys
+}
+
+func (b *Budget) limit(key string) (int, bool) {
+	if n, ok := b.caps.Rules[key]; ok {
+		return n, true
+	}
+	n, ok := b.caps.Sources[key]
+	return n, ok
+}
+
+func (b *Budget) sendAlert(key, month string) {
+	limit, _ := b.limit(key)
+	timeout := b.alert.Timeout
+	if timeout == 0 {
+		timeout = 90
+	}
+	msg := fmt.Sprintf("[Relay Alert] %s reached its monthly cap of %d agent jobs for %s. "+
+		"Further jobs are dropped until next month or until the cap is raised in the relay config.", key, limit, month)
+	err := gateway.CreateJob(b.next, gateway.JobSpec{
+		Name:           "budget/" + key,
+		Message:        msg,
+		AgentID:        b.alert.AgentID,
+		TimeoutSeconds: timeout,
+		Source:         "budget",
+	})
+	if err != nil {
+		log.Printf("Budget: failed to send cap alert for %s: %v", key, err)
+	}
+}
+
+// Usage is one counter in the GET /api/budget response.
+type Usage struct {
+	Key    string `json:"key"`
+	Jobs   int    `json:"jobs"`
+	Cap    int    `json:"cap,omitempty"`
+	Capped bool   `json:"capped"`
+}
+
+// Usage returns this month and its counters, capped ones included, sorted by key.
+func (b *Budget) Usage() (string, []Usage) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	month := b.month()
+	counts := b.usage.Counts
+	if b.usage.Month != month {
+		counts = nil
+	}
+	keys := map[string]bool{}
+	for k := range counts {
+		keys[k] = true
+	}
+	for k := range b.caps.Sources {
+		keys[k] = true
+	}
+	for k := range b.caps.Rules {
+		keys[k] = true
+	}
+	out := make([]Usage, 0, len(keys))
+	for k := range keys {
+		u := Usage{Key: k, Jobs: counts[k]}
+		if limit, ok := b.limit(k); ok {
+			u.Cap = limit
+			u.Capped = u.Jobs >= limit
+		}
+		out = append(out, u)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Key < out[j].Key })
+	return month, out
+}
+
+// HandleUsage serves GET /api/budget.
+func (b *Budget) HandleUsage(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	month, usage := b.Usage()
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{"month": month, "usage": usage})
+}
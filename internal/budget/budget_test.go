@@ -0,0 +1,102 @@
+package budget
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/katalabut/openclaw-relay/internal/gateway"
+)
+
+func TestBudget_Caps(t *testing.T) {
+	sink := gateway.NewSink()
+	b, err := New(sink, Caps{
+		Sources: map[string]int{"github": 3},
+		Rules:   map[string]int{"gmail/newsletter": 1},
+	}, Alert{AgentID: "ops"}, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 3; i++ {
+		if err := b.CreateJob(gateway.JobSpec{Name: "ci", Source: "github"}); err != nil {
+			t.Fatalf("job %d: %v", i, err)
+		}
+	}
+	err = b.CreateJob(gateway.JobSpec{Name: "ci", Source: "github"})
+	if !errors.Is(err, ErrOverCap) {
+		t.Fatalf("expected ErrOverCap, got %v", err)
+	}
+	// 3 jobs and one alert
+	if sink.Count() != 4 || sink.Last().AgentID != "ops" || !strings.Contains(sink.Last().Message, "github reached its monthly cap of 3") {
+		t.Fatalf("expected cap alert, got %d jobs, last %+v", sink.Count(), sink.Last())
+	}
+	// The alert is sent once a month
+	b.CreateJob(gateway.JobSpec{Name: "ci", Source: "github"})
+	if sink.Count() != 4 {
+		t.Errorf("expected a single alert, got %d jobs", sink.Count())
+	}
+
+	// Rule caps apply to the rule only; the rule tag names rules without names
+	b.CreateJob(gateway.JobSpec{Name: "a", Source: "gmail", Rule: "newsletter"})
+	if err := b.CreateJob(gateway.JobSpec{Name: "b", Source: "gmail", Rule: "newsletter"}); !errors.Is(err, ErrOverCap) {
+		t.Errorf("expected rule cap, got %v", err)
+	}
+	if err := b.CreateJob(gateway.JobSpec{Name: "c", Source: "gmail", Rule: "invoices"}); err != nil {
+		t.Errorf("other rule should pass: %v", err)
+	}
+	if err := b.CreateJob(gateway.JobSpec{Name: "d", Source: "slack", Tags: map[string]string{"rule": "newsletter"}}); err != nil {
+		t.Errorf("other source should pass: %v", err)
+	}
+
+	// A new month starts from zero
+	b.now = func() time.Time { return time.Now().AddDate(0, 1, 0) }
+	if err := b.CreateJob(gateway.JobSpec{Name: "ci", Source: "github"}); err != nil {
+		t.Errorf("expected new month to reset caps: %v", err)
+	}
+}
+
+func TestBudget_Persist(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "budget.json")
+	caps := Caps{Sources: map[string]int{"trello": 2}}
+	b, err := New(gateway.NewSink(), caps, Alert{}, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b.CreateJob(gateway.JobSpec{Name: "x", Source: "trello"})
+	b.CreateJob(gateway.JobSpec{Name: "x", Source: "trello"})
+
+	reopened, err := New(gateway.NewSink(), caps, Alert{}, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := reopened.CreateJob(gateway.JobSpec{Name: "x", Source: "trello"}); !errors.Is(err, ErrOverCap) {
+		t.Errorf("expected counts to survive a restart, got %v", err)
+	}
+}
+
+func TestBudget_HandleUsage(t *testing.T) {
+	b, _ := New(gateway.NewSink(), Caps{Sources: map[string]int{"jira": 1}}, Alert{}, "")
+	b.CreateJob(gateway.JobSpec{Name: "x", Source: "jira"})
+	b.CreateJob(gateway.JobSpec{Name: "x", Source: "slack"})
+
+	rec := httptest.NewRecorder()
+	b.HandleUsage(rec, httptest.NewRequest("GET", "/api/budget", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rec.Code)
+	}
+	body := rec.Body.String()
+	if !strings.Contains(body, `{"key":"jira","jobs":1,"cap":1,"capped":true}`) || !strings.Contains(body, `{"key":"slack","jobs":1,"capped":false}`) {
+		t.Errorf("unexpected usage %s", body)
+	}
+
+	rec = httptest.NewRecorder()
+	b.HandleUsage(rec, httptest.NewRequest("POST", "/api/budget", nil))
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("expected 405, got %d", rec.Code)
+	}
+}
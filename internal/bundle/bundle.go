@@ -0,0 +1,263 @@
+// Package bundle packs the relay's data directory (tokens, poller state,
+// allow-list overrides, event history, links and shared state) into one
+// encrypted file, so the relay can move hosts without re-authenticating or
+// losing dedup state.
+package bundle
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/katalabut/openclaw-relay/internal/atomicfile"
+	"github.com/katalabut/openclaw-relay/internal/sealed"
+)
+
+// magic prefixes every bundle so a wrong file fails before decryption. It
+// is also the additional data the archive is sealed with.
+const magic = "OCRELAY-BUNDLE-2\n"
+
+// legacyMagic prefixes bundles sealed with the raw key, before bundles had
+// a key of their own.
+const legacyMagic = "OCRELAY-BUNDLE-1\n"
+
+// keyPurpose derives the bundle key from RELAY_ENCRYPTION_KEY, so a bundle
+// is never encrypted with the key the token store and sealed values use.
+const keyPurpose = "relay-bundle-v2"
+
+const manifestName = "manifest.json"
+
+// ErrExists is returned by Import when a bundled file is already present and
+// overwriting was not requested.
+var ErrExists = errors.New("file already exists")
+
+// Manifest describes a bundle's contents.
+type Manifest struct {
+	CreatedAt time.Time `json:"created_at"`
+	Files     []string  `json:"files"` // slash-separated, relative to the data directory
+}
+
+// skipped reports whether a data file stays behind: audit logs are host-local
+// and can grow large, and temp files are half-written.
+func skipped(rel string) bool {
+	base := path.Base(rel)
+	return strings.HasSuffix(base, ".log") || strings.HasSuffix(base, ".tmp")
+}
+
+// Export writes an encrypted bundle of every file under dataDir to w.
+// keyHex is the relay's RELAY_ENCRYPTION_KEY.
+func Export(w io.Writer, dataDir, keyHex string, now time.Time) (*Manifest, error) {
+	key, err := bundleKey(keyHex)
+	if err != nil {
+		return nil, err
+	}
+
+	m := &Manifest{CreatedAt: now.UTC()}
+	contents := map[string][]byte{}
+	err = filepath.WalkDir(dataDir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		rel, err := filepath.Rel(dataDir, p)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		if skipped(rel) {
+			return nil
+		}
+		b, err := os.ReadFile(p)
+		if err != nil {
+			return err
+		}
+		contents[rel] = b
+		m.Files = append(m.Files, rel)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("read %s: %w", dataDir, err)
+	}
+	sort.Strings(m.Files)
+
+	var archive bytes.Buffer
+	gz := gzip.NewWriter(&archive)
+	tw := tar.NewWriter(gz)
+	manifest, _ := json.MarshalIndent(m, "", "  ")
+	if err := writeEntry(tw, manifestName, manifest, now); err != nil {
+		return nil, err
+	}
+	for _, name := range m.Files {
+		if err := writeEntry(tw, "data/"+name, contents[name], now); err != nil {
+			return nil, err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return nil, err
+	}
+	if err := gz.Close(); err != nil {
+		return nil, err
+	}
+
+	ciphertext, err := seal(key, archive.Bytes())
+	if err != nil {
+		return nil, err
+	}
+	if _, err := io.WriteString(w, magic); err != nil {
+		return nil, err
+	}
+	if _, err := w.Write(ciphertext); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func writeEntry(tw *tar.Writer, name string, b []byte, now time.Time) error {
+	hdr := &tar.Header{Name: name, Mode: 0600, Size: int64(len(b)), ModTime: now, Typeflag: tar.TypeReg}
+	if err := tw.WriteHeader(hdr); err != nil {
+		return err
+	}
+	_, err := tw.Write(b)
+	return err
+}
+
+// Import decrypts a bundle from r and writes its files under dataDir. It
+// refuses to replace existing files unless overwrite is set, and checks every
+// file before writing any, so a refused import leaves dataDir untouched.
+func Import(r io.Reader, dataDir, keyHex string, overwrite bool) (*Manifest, error) {
+	key, err := bundleKey(keyHex)
+	if err != nil {
+		return nil, err
+	}
+	raw, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	if bytes.HasPrefix(raw, []byte(legacyMagic)) {
+		return nil, fmt.Errorf("bundle was exported by an older relay; export it again with this version")
+	}
+	if !bytes.HasPrefix(raw, []byte(magic)) {
+		return nil, fmt.Errorf("not a relay bundle")
+	}
+	archive, err := open(key, raw[len(magic):])
+	if err != nil {
+		return nil, fmt.Errorf("decrypt bundle (is RELAY_ENCRYPTION_KEY the one it was exported with?): %w", err)
+	}
+
+	gz, err := gzip.NewReader(bytes.NewReader(archive))
+	if err != nil {
+		return nil, err
+	}
+	tr := tar.NewReader(gz)
+	var m *Manifest
+	files := map[string][]byte{}
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("read bundle: %w", err)
+		}
+		b, err := io.ReadAll(tr)
+		if err != nil {
+			return nil, err
+		}
+		if hdr.Name == manifestName {
+			m = &Manifest{}
+			if err := json.Unmarshal(b, m); err != nil {
+				return nil, fmt.Errorf("bad manifest: %w", err)
+			}
+			continue
+		}
+		rel, ok := strings.CutPrefix(hdr.Name, "data/")
+		if !ok || hdr.Typeflag != tar.TypeReg || !safePath(rel) {
+			return nil, fmt.Errorf("unexpected bundle entry %q", hdr.Name)
+		}
+		files[rel] = b
+	}
+	if m == nil {
+		return nil, fmt.Errorf("bundle has no manifest")
+	}
+
+	for _, rel := range m.Files {
+		if _, ok := files[rel]; !ok {
+			return nil, fmt.Errorf("bundle is missing %s", rel)
+		}
+		if _, err := os.Stat(filepath.Join(dataDir, filepath.FromSlash(rel))); err == nil && !overwrite {
+			return nil, fmt.Errorf("%s: %w", rel, ErrExists)
+		}
+	}
+	for _, rel := range m.Files {
+		dst := filepath.Join(dataDir, filepath.FromSlash(rel))
+		if err := atomicfile.WriteFile(dst, files[rel], 0600); err != nil {
+			return nil, err
+		}
+	}
+	return m, nil
+}
+
+// safePath rejects entries that would escape the data directory.
+func safePath(rel string) bool {
+	return rel != "" && !path.IsAbs(rel) && path.Clean(rel) == rel && rel != ".." && !strings.HasPrefix(rel, "../")
+}
+
+// bundleKey derives the bundle key from the hex RELAY_ENCRYPTION_KEY.
+func bundleKey(keyHex string) ([]byte, error) {
+	key, err := sealed.ParseKey(keyHex)
+	if err != nil {
+		return nil, err
+	}
+	if key == nil {
+		return nil, sealed.ErrNoKey
+	}
+	return sealed.DeriveKey(key, keyPurpose)
+}
+
+func seal(key, plaintext []byte) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+	return gcm.Seal(nonce, nonce, plaintext, []byte(magic)), nil
+}
+
+func open(key, ciphertext []byte) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	ns := gcm.NonceSize()
+	if len(ciphertext) < ns {
+		return nil, fmt.Errorf("ciphertext too short")
+	}
+	return gcm.Open(nil, ciphertext[:ns], ciphertext[ns:], []byte(magic))
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,133 @@
+package bundle
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testKey = "0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef"
+
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		p := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(p), 0700); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestExportImportRoundTrip(t *testing.T) {
+	src := t.TempDir()
+	writeFiles(t, src, map[string]string{
+		"tokens.json.enc":                  "\x00encrypted",
+		"gmail-state-you_example_com.json": `{"history_id":"42"}`,
+		"events.json":                      `[]`,
+		"nested/state.json":                `{}`,
+		"audit.log":                        "left behind",
+	})
+
+	var buf bytes.Buffer
+	m, err := Export(&buf, src, testKey, time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"events.json", "gmail-state-you_example_com.json", "nested/state.json", "tokens.json.enc"}
+	if strings.Join(m.Files, ",") != strings.Join(want, ",") {
+		t.Errorf("files = %v, want %v", m.Files, want)
+	}
+	if bytes.Contains(buf.Bytes(), []byte("history_id")) {
+		t.Error("bundle must not contain plaintext state")
+	}
+
+	dst := t.TempDir()
+	got, err := Import(bytes.NewReader(buf.Bytes()), dst, testKey, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !got.CreatedAt.Equal(m.CreatedAt) {
+		t.Errorf("created_at = %v, want %v", got.CreatedAt, m.CreatedAt)
+	}
+	b, err := os.ReadFile(filepath.Join(dst, "nested", "state.json"))
+	if err != nil || string(b) != "{}" {
+		t.Errorf("nested file = %q, %v", b, err)
+	}
+	if fi, err := os.Stat(filepath.Join(dst, "tokens.json.enc")); err != nil || fi.Mode().Perm() != 0600 {
+		t.Errorf("expected 0600 token file, got %v %v", fi, err)
+	}
+	if _, err := os.Stat(filepath.Join(dst, "audit.log")); !os.IsNotExist(err) {
+		t.Error("audit log should not be bundled")
+	}
+
+	// A second import collides with the files just written
+	_, err = Import(bytes.NewReader(buf.Bytes()), dst, testKey, false)
+	if !errors.Is(err, ErrExists) {
+		t.Errorf("expected ErrExists, got %v", err)
+	}
+	if _, err := Import(bytes.NewReader(buf.Bytes()), dst, testKey, true); err != nil {
+		t.Errorf("overwrite import failed: %v", err)
+	}
+}
+
+func TestImportRejects(t *testing.T) {
+	src := t.TempDir()
+	writeFiles(t, src, map[string]string{"events.json": "[]"})
+	var buf bytes.Buffer
+	if _, err := Export(&buf, src, testKey, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	otherKey := strings.Repeat("ab", 32)
+	// The same archive sealed with the raw key, as bundles were before they
+	// had a key of their own
+	rawKey, _ := hex.DecodeString(testKey)
+	rawSealed, err := seal(rawKey, []byte("archive"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		data []byte
+		key  string
+		want string
+	}{
+		{"wrong key", buf.Bytes(), otherKey, "decrypt bundle"},
+		{"not a bundle", []byte("hello"), testKey, "not a relay bundle"},
+		{"truncated", buf.Bytes()[:len(magic)+4], testKey, "decrypt bundle"},
+		{"bad key", buf.Bytes(), "short", "RELAY_ENCRYPTION_KEY"},
+		{"no key", buf.Bytes(), "", "RELAY_ENCRYPTION_KEY"},
+		{"raw key", append([]byte(magic), rawSealed...), testKey, "decrypt bundle"},
+		{"older bundle", append([]byte(legacyMagic), rawSealed...), testKey, "older relay"},
+	}
+	for _, tt := range tests {
+		_, err := Import(bytes.NewReader(tt.data), t.TempDir(), tt.key, false)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: expected error containing %q, got %v", tt.name, tt.want, err)
+		}
+	}
+}
+
+func TestSafePath(t *testing.T) {
+	tests := map[string]bool{
+		"events.json":       true,
+		"nested/state.json": true,
+		"../etc/passwd":     false,
+		"/etc/passwd":       false,
+		"a/../../b":         false,
+		"":                  false,
+	}
+	for p, want := range tests {
+		if got := safePath(p); got != want {
+			t.Errorf("safePath(%q) = %v, want %v", p, got, want)
+		}
+	}
+}
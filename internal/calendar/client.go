@@ -0,0 +1,232 @@
+// Package calendar reads Google Calendar for the accounts signed in with
+// Google OAuth: the /api/calendar/* routes and a poller that creates jobs
+// shortly before matching events start.
+package calendar
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/katalabut/openclaw-relay/internal/tokens"
+	"golang.org/x/oauth2"
+	cal "google.golang.org/api/calendar/v3"
+	"google.golang.org/api/option"
+)
+
+// CalendarClient is the interface the API handler and poller use, so tests
+// can fake Google Calendar.
+type CalendarClient interface {
+	ListCalendars(ctx context.Context) ([]Calendar, error)
+	ListEvents(ctx context.Context, calendarID string, q EventQuery) ([]Event, string, error)
+	GetEvent(ctx context.Context, calendarID, id string) (*Event, error)
+	CreateEvent(ctx context.Context, calendarID string, in EventInput) (*Event, error)
+	UpdateEvent(ctx context.Context, calendarID, id string, in EventInput) (*Event, error)
+	RespondEvent(ctx context.Context, calendarID, id string, rsvp RSVP) (*Event, error)
+}
+
+// Client wraps Google Calendar API v3 for one account.
+type Client struct {
+	store    *tokens.Store
+	oauthCfg *oauth2.Config
+	email    string
+	endpoint string
+}
+
+func NewClientForAccount(store *tokens.Store, oauthCfg *oauth2.Config, email string) *Client {
+	return &Client{store: store, oauthCfg: oauthCfg, email: email}
+}
+
+// SetEndpoint points the client at another Calendar API base URL, e.g. a
+// fake server in tests. Empty means Google's default.
+func (c *Client) SetEndpoint(url string) {
+	c.endpoint = url
+}
+
+func (c *Client) getService(ctx context.Context) (*cal.Service, error) {
+	ts, err := c.store.GoogleTokenSource(ctx, c.oauthCfg, c.email)
+	if err != nil {
+		return nil, err
+	}
+	// Calls refused for a missing scope fail with a *tokens.ScopeError
+	opts := []option.ClientOption{option.WithHTTPClient(&http.Client{
+		Transport: &oauth2.Transport{Source: ts, Base: c.store.ScopeTransport(c.email, nil)},
+	})}
+	if c.endpoint != "" {
+		opts = append(opts, option.WithEndpoint(c.endpoint))
+	}
+	return cal.NewService(ctx, opts...)
+}
+
+// Calendar is an entry of the account's calendar list.
+type Calendar struct {
+	ID         string `json:"id"`
+	Summary    string `json:"summary"`
+	Primary    bool   `json:"primary,omitempty"`
+	TimeZone   string `json:"timeZone,omitempty"`
+	AccessRole string `json:"accessRole"` // owner, writer, reader or freeBusyReader
+}
+
+// Event is a calendar event. Start and End are instants; all-day events
+// start and end at midnight in the calendar's time zone.
+type Event struct {
+	ID          string     `json:"id"`
+	CalendarID  string     `json:"calendarId"`
+	Status      string     `json:"status"` // confirmed, tentative or cancelled
+	Summary     string     `json:"summary"`
+	Description string     `json:"description,omitempty"`
+	Location    string     `json:"location,omitempty"`
+	Start       time.Time  `json:"start"`
+	End         time.Time  `json:"end"`
+	AllDay      bool       `json:"allDay,omitempty"`
+	Organizer   string     `json:"organizer,omitempty"`
+	Attendees   []Attendee `json:"attendees,omitempty"`
+	URL         string     `json:"url,omitempty"`     // the event in the Calendar web UI
+	MeetURL     string     `json:"meetUrl,omitempty"` // Google Meet link, if any
+	Recurring   bool       `json:"recurring,omitempty"`
+}
+
+// Attendee is an event guest and their response: needsAction, declined,
+// tentative or accepted.
+type Attendee struct {
+	Email    string `json:"email"`
+	Name     string `json:"name,omitempty"`
+	Response string `json:"response,omitempty"`
+}
+
+// EventQuery narrows ListEvents. Zero fields are unbounded.
+type EventQuery struct {
+	From       time.Time // events ending after From
+	To         time.Time // events starting before To
+	Query      string    // free text over summary, description, location and attendees
+	MaxResults int64
+	PageToken  string
+}
+
+// ListCalendars lists the calendars on the account's calendar list.
+func (c *Client) ListCalendars(ctx context.Context) ([]Calendar, error) {
+	svc, err := c.getService(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var out []Calendar
+	err = svc.CalendarList.List().Pages(ctx, func(resp *cal.CalendarList) error {
+		for _, e := range resp.Items {
+			out = append(out, Calendar{ID: e.Id, Summary: e.Summary, Primary: e.Primary, TimeZone: e.TimeZone, AccessRole: e.AccessRole})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// ListEvents lists a calendar's events matching q in start order, with
+// recurring events expanded into their instances, and the next page token.
+func (c *Client) ListEvents(ctx context.Context, calendarID string, q EventQuery) ([]Event, string, error) {
+	svc, err := c.getService(ctx)
+	if err != nil {
+		return nil, "", err
+	}
+	call := svc.Events.List(calendarID).SingleEvents(true).OrderBy("startTime").Context(ctx)
+	if !q.From.IsZero() {
+		call = call.TimeMin(q.From.Format(time.RFC3339))
+	}
+	if !q.To.IsZero() {
+		call = call.TimeMax(q.To.Format(time.RFC3339))
+	}
+	if q.Query != "" {
+		call = call.Q(q.Query)
+	}
+	if q.MaxResults > 0 {
+		call = call.MaxResults(q.MaxResults)
+	}
+	if q.PageToken != "" {
+		call = call.PageToken(q.PageToken)
+	}
+	resp, err := call.Do()
+	if err != nil {
+		return nil, "", err
+	}
+	loc := location(resp.TimeZone)
+	events := make([]Event, 0, len(resp.Items))
+	for _, e := range resp.Items {
+		events = append(events, convertEvent(calendarID, e, loc))
+	}
+	return events, resp.NextPageToken, nil
+}
+
+// GetEvent gets one event of a calendar.
+func (c *Client) GetEvent(ctx context.Context, calendarID, id string) (*Event, error) {
+	svc, err := c.getService(ctx)
+	if err != nil {
+		return nil, err
+	}
+	e, err := svc.Events.Get(calendarID, id).Context(ctx).Do()
+	if err != nil {
+		return nil, err
+	}
+	// All-day events carry no zone of their own; the calendar's is needed
+	loc := time.UTC
+	if e.Start != nil && e.Start.DateTime == "" {
+		if entry, err := svc.CalendarList.Get(calendarID).Context(ctx).Do(); err == nil {
+			loc = location(entry.TimeZone)
+		}
+	}
+	ev := convertEvent(calendarID, e, loc)
+	return &ev, nil
+}
+
+func location(tz string) *time.Location {
+	if loc, err := time.LoadLocation(tz); err == nil && tz != "" {
+		return loc
+	}
+	return time.UTC
+}
+
+func convertEvent(calendarID string, e *cal.Event, loc *time.Location) Event {
+	ev := Event{
+		ID:          e.Id,
+		CalendarID:  calendarID,
+		Status:      e.Status,
+		Summary:     e.Summary,
+		Description: e.Description,
+		Location:    e.Location,
+		URL:         e.HtmlLink,
+		MeetURL:     e.HangoutLink,
+		Recurring:   e.RecurringEventId != "",
+	}
+	ev.Start, ev.AllDay = eventTime(e.Start, loc)
+	ev.End, _ = eventTime(e.End, loc)
+	if e.Organizer != nil {
+		ev.Organizer = e.Organizer.Email
+	}
+	for _, a := range e.Attendees {
+		ev.Attendees = append(ev.Attendees, Attendee{Email: a.Email, Name: a.DisplayName, Response: a.ResponseStatus})
+	}
+	return ev
+}
+
+// eventTime converts an event's start or end: a dateTime, or the date of an
+// all-day event at midnight in loc.
+func eventTime(t *cal.EventDateTime, loc *time.Location) (time.Time, bool) {
+	if t == nil {
+		return time.Time{}, false
+	}
+	if t.DateTime != "" {
+		v, err := time.Parse(time.RFC3339, t.DateTime)
+		if err != nil {
+			return time.Time{}, false
+		}
+		return v, false
+	}
+	if t.TimeZone != "" {
+		loc = location(t.TimeZone)
+	}
+	v, err := time.ParseInLocation(time.DateOnly, t.Date, loc)
+	if err != nil {
+		return time.Time{}, true
+	}
+	return v, true
+}
@@ -0,0 +1,123 @@
+package calendar
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/katalabut/openclaw-relay/internal/tokens"
+	"golang.org/x/oauth2"
+)
+
+// newTestClient returns a client for me@example.com against a fake Calendar
+// API served by h.
+func newTestClient(t *testing.T, h http.HandlerFunc) *Client {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		h(w, r)
+	}))
+	t.Cleanup(srv.Close)
+	store, err := tokens.NewStore(filepath.Join(t.TempDir(), "tokens.json.enc"), strings.Repeat("ab", 32))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := store.SaveGoogle(&oauth2.Token{AccessToken: "tok", RefreshToken: "r", Expiry: time.Now().Add(time.Hour)}, "me@example.com"); err != nil {
+		t.Fatal(err)
+	}
+	c := NewClientForAccount(store, &oauth2.Config{}, "me@example.com")
+	c.SetEndpoint(srv.URL + "/")
+	return c
+}
+
+func TestListCalendars(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/users/me/calendarList" {
+			t.Errorf("path = %s", r.URL.Path)
+		}
+		if r.URL.Query().Get("pageToken") == "" {
+			fmt.Fprint(w, `{"items":[{"id":"me@example.com","summary":"Me","primary":true,"timeZone":"Europe/Berlin","accessRole":"owner"}],"nextPageToken":"p2"}`)
+			return
+		}
+		fmt.Fprint(w, `{"items":[{"id":"team@group.calendar.google.com","summary":"Team","accessRole":"reader"}]}`)
+	})
+	cals, err := c.ListCalendars(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cals) != 2 || !cals[0].Primary || cals[0].TimeZone != "Europe/Berlin" || cals[1].ID != "team@group.calendar.google.com" {
+		t.Errorf("calendars = %+v", cals)
+	}
+}
+
+func TestListEvents(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		if r.URL.Path != "/calendars/primary/events" || q.Get("singleEvents") != "true" || q.Get("orderBy") != "startTime" ||
+			q.Get("timeMin") != "2026-03-02T09:00:00Z" || q.Get("q") != "standup" || q.Get("pageToken") != "p1" {
+			t.Errorf("request = %s?%s", r.URL.Path, r.URL.RawQuery)
+		}
+		fmt.Fprint(w, `{"timeZone":"Europe/Berlin","nextPageToken":"p2","items":[
+			{"id":"e1","status":"confirmed","summary":"Standup","start":{"dateTime":"2026-03-02T10:00:00+01:00"},"end":{"dateTime":"2026-03-02T10:15:00+01:00"},
+			 "organizer":{"email":"lead@example.com"},"attendees":[{"email":"me@example.com","displayName":"Me","responseStatus":"accepted"}],
+			 "hangoutLink":"https://meet.google.com/abc-defg-hij","htmlLink":"https://calendar.google.com/event?eid=e1","recurringEventId":"r1"},
+			{"id":"e2","summary":"Offsite","start":{"date":"2026-03-03"},"end":{"date":"2026-03-04"}}]}`)
+	})
+	events, next, err := c.ListEvents(context.Background(), "primary", EventQuery{
+		From:      time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC),
+		Query:     "standup",
+		PageToken: "p1",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 2 || next != "p2" {
+		t.Fatalf("events = %+v, next %q", events, next)
+	}
+	e := events[0]
+	if !e.Start.Equal(time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC)) || e.AllDay || !e.Recurring || e.Organizer != "lead@example.com" ||
+		e.MeetURL != "https://meet.google.com/abc-defg-hij" || len(e.Attendees) != 1 || e.Attendees[0].Response != "accepted" || e.CalendarID != "primary" {
+		t.Errorf("timed event = %+v", e)
+	}
+	berlin, _ := time.LoadLocation("Europe/Berlin")
+	if e := events[1]; !e.AllDay || !e.Start.Equal(time.Date(2026, 3, 3, 0, 0, 0, 0, berlin)) {
+		t.Errorf("all-day event = %+v", e)
+	}
+}
+
+func TestGetEvent(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/calendars/team@group.calendar.google.com/events/e2":
+			fmt.Fprint(w, `{"id":"e2","summary":"Offsite","start":{"date":"2026-03-03"},"end":{"date":"2026-03-04"}}`)
+		case "/users/me/calendarList/team@group.calendar.google.com":
+			fmt.Fprint(w, `{"id":"team@group.calendar.google.com","timeZone":"America/New_York"}`)
+		default:
+			http.Error(w, `{"error":{"code":404,"message":"Not Found"}}`, http.StatusNotFound)
+		}
+	})
+	ev, err := c.GetEvent(context.Background(), "team@group.calendar.google.com", "e2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ny, _ := time.LoadLocation("America/New_York")
+	if !ev.AllDay || !ev.Start.Equal(time.Date(2026, 3, 3, 0, 0, 0, 0, ny)) || ev.CalendarID != "team@group.calendar.google.com" {
+		t.Errorf("event = %+v", ev)
+	}
+	if _, err := c.GetEvent(context.Background(), "primary", "missing"); err == nil {
+		t.Error("expected an error for a missing event")
+	}
+}
+
+func TestClient_NotAuthenticated(t *testing.T) {
+	store, _ := tokens.NewStore(filepath.Join(t.TempDir(), "tokens.json.enc"), strings.Repeat("ab", 32))
+	c := NewClientForAccount(store, &oauth2.Config{}, "other@example.com")
+	if _, err := c.ListCalendars(context.Background()); err == nil || !strings.Contains(err.Error(), "not authenticated") {
+		t.Errorf("expected not authenticated, got %v", err)
+	}
+}
@@ -0,0 +1,226 @@
+package calendar
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/katalabut/openclaw-relay/internal/tokens"
+)
+
+// maxEvents caps ?max= on /api/calendar/events, Google's page size limit.
+const maxEvents = 2500
+
+// Handler serves the Calendar API for the agent.
+type Handler struct {
+	clients        map[string]CalendarClient
+	defaultAccount string
+	write          bool // calendar.write: create, update and RSVP routes
+	now            func() time.Time
+}
+
+// NewHandler serves clients, keyed by account; ?account= defaults to the
+// first of accounts that has one.
+func NewHandler(accounts []string, clients map[string]CalendarClient) *Handler {
+	h := &Handler{clients: clients, now: time.Now}
+	for _, acc := range accounts {
+		if _, ok := clients[acc]; ok {
+			h.defaultAccount = acc
+			break
+		}
+	}
+	return h
+}
+
+// SetWrite enables creating, updating and responding to events.
+func (h *Handler) SetWrite(enabled bool) {
+	h.write = enabled
+}
+
+// writeAllowed answers 403 and returns false unless calendar.write is on.
+func (h *Handler) writeAllowed(w http.ResponseWriter) bool {
+	if !h.write {
+		jsonError(w, "calendar.write is disabled", http.StatusForbidden)
+		return false
+	}
+	return true
+}
+
+// RegisterRoutes adds the Calendar API routes to the mux.
+func (h *Handler) RegisterRoutes(mux *http.ServeMux) {
+	mux.HandleFunc("/api/calendar/calendars", h.handleListCalendars)
+	mux.HandleFunc("/api/calendar/events", h.handleEvents)
+	mux.HandleFunc("/api/calendar/events/", h.handleEvent)
+}
+
+// resolveClient returns the client for ?account=, the default account when
+// it's unset.
+func (h *Handler) resolveClient(r *http.Request) (CalendarClient, bool) {
+	account := r.URL.Query().Get("account")
+	if account == "" {
+		account = h.defaultAccount
+	}
+	client, ok := h.clients[account]
+	return client, ok
+}
+
+func jsonResponse(w http.ResponseWriter, data any) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(data)
+}
+
+func jsonError(w http.ResponseWriter, msg string, code int) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(map[string]string{"error": msg})
+}
+
+// apiError answers a failed Google API call: 403 with the page that grants
+// the missing scope when the account's token lacks one, 500 otherwise.
+func apiError(w http.ResponseWriter, err error) {
+	var scopeErr *tokens.ScopeError
+	if !errors.As(err, &scopeErr) {
+		jsonError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusForbidden)
+	json.NewEncoder(w).Encode(map[string]any{
+		"error":           "insufficient_scope",
+		"message":         scopeErr.Error(),
+		"account":         scopeErr.Account,
+		"required_scopes": scopeErr.Required,
+		"granted_scopes":  scopeErr.Granted,
+		"reauth_url":      scopeErr.ReauthURL(),
+	})
+}
+
+// handleListCalendars serves GET /api/calendar/calendars.
+func (h *Handler) handleListCalendars(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		jsonError(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	client, ok := h.resolveClient(r)
+	if !ok {
+		jsonError(w, "unknown account", http.StatusBadRequest)
+		return
+	}
+	cals, err := client.ListCalendars(r.Context())
+	if err != nil {
+		apiError(w, err)
+		return
+	}
+	jsonResponse(w, map[string]any{"calendars": cals})
+}
+
+// handleEvents serves GET (list) and POST (create) on /api/calendar/events.
+func (h *Handler) handleEvents(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodPost {
+		jsonError(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if r.Method == http.MethodPost && !h.writeAllowed(w) {
+		return
+	}
+	client, ok := h.resolveClient(r)
+	if !ok {
+		jsonError(w, "unknown account", http.StatusBadRequest)
+		return
+	}
+	if r.Method == http.MethodPost {
+		h.handleCreateEvent(w, r, client)
+		return
+	}
+	h.handleListEvents(w, r, client)
+}
+
+// handleListEvents serves GET /api/calendar/events: ?calendar= (default
+// primary), from and to as RFC 3339 times (from defaults to now), q, max
+// (default 50) and pageToken.
+func (h *Handler) handleListEvents(w http.ResponseWriter, r *http.Request, client CalendarClient) {
+	params := r.URL.Query()
+	q := EventQuery{From: h.now(), Query: params.Get("q"), MaxResults: 50, PageToken: params.Get("pageToken")}
+	for name, t := range map[string]*time.Time{"from": &q.From, "to": &q.To} {
+		if s := params.Get(name); s != "" {
+			v, err := time.Parse(time.RFC3339, s)
+			if err != nil {
+				jsonError(w, name+" must be an RFC 3339 time", http.StatusBadRequest)
+				return
+			}
+			*t = v
+		}
+	}
+	if s := params.Get("max"); s != "" {
+		if v, err := strconv.ParseInt(s, 10, 64); err == nil && v > 0 {
+			q.MaxResults = min(v, maxEvents)
+		}
+	}
+	events, next, err := client.ListEvents(r.Context(), calendarParam(r), q)
+	if err != nil {
+		apiError(w, err)
+		return
+	}
+	resp := map[string]any{"events": events}
+	if next != "" {
+		resp["nextPageToken"] = next
+	}
+	jsonResponse(w, resp)
+}
+
+// handleEvent serves GET (get) and PATCH (update) on
+// /api/calendar/events/{id}, and POST on /api/calendar/events/{id}/respond.
+func (h *Handler) handleEvent(w http.ResponseWriter, r *http.Request) {
+	id, action, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/api/calendar/events/"), "/")
+	if id == "" || (action != "" && action != "respond") {
+		jsonError(w, "not found", http.StatusNotFound)
+		return
+	}
+	method := http.MethodGet
+	if action == "respond" {
+		method = http.MethodPost
+	} else if r.Method == http.MethodPatch {
+		method = http.MethodPatch
+	}
+	if r.Method != method {
+		jsonError(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if method != http.MethodGet && !h.writeAllowed(w) {
+		return
+	}
+	client, ok := h.resolveClient(r)
+	if !ok {
+		jsonError(w, "unknown account", http.StatusBadRequest)
+		return
+	}
+	switch {
+	case action == "respond":
+		h.handleRespondEvent(w, r, client, id)
+	case method == http.MethodPatch:
+		h.handleUpdateEvent(w, r, client, id)
+	default:
+		h.handleGetEvent(w, r, client, id)
+	}
+}
+
+// handleGetEvent serves GET /api/calendar/events/{id}?calendar=.
+func (h *Handler) handleGetEvent(w http.ResponseWriter, r *http.Request, client CalendarClient, id string) {
+	ev, err := client.GetEvent(r.Context(), calendarParam(r), id)
+	if err != nil {
+		apiError(w, err)
+		return
+	}
+	jsonResponse(w, ev)
+}
+
+// calendarParam returns ?calendar=, or primary.
+func calendarParam(r *http.Request) string {
+	if c := r.URL.Query().Get("calendar"); c != "" {
+		return c
+	}
+	return "primary"
+}
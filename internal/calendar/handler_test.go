@@ -0,0 +1,173 @@
+package calendar
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/katalabut/openclaw-relay/internal/tokens"
+)
+
+// fakeClient serves events from memory and records the last query.
+type fakeClient struct {
+	calendars []Calendar
+	events    map[string][]Event // calendar ID -> events
+	pages     map[string]string  // page token -> next page token
+	err       error
+
+	lastCalendar string
+	lastQuery    EventQuery
+	lastInput    EventInput
+	lastRSVP     RSVP
+	calls        int
+}
+
+func (f *fakeClient) ListCalendars(ctx context.Context) ([]Calendar, error) {
+	return f.calendars, f.err
+}
+
+func (f *fakeClient) ListEvents(ctx context.Context, calendarID string, q EventQuery) ([]Event, string, error) {
+	f.calls++
+	f.lastCalendar, f.lastQuery = calendarID, q
+	if f.err != nil {
+		return nil, "", f.err
+	}
+	var out []Event
+	for _, ev := range f.events[calendarID] {
+		if (q.To.IsZero() || ev.Start.Before(q.To)) && ev.End.After(q.From) {
+			out = append(out, ev)
+		}
+	}
+	return out, f.pages[q.PageToken], nil
+}
+
+func (f *fakeClient) GetEvent(ctx context.Context, calendarID, id string) (*Event, error) {
+	for _, ev := range f.events[calendarID] {
+		if ev.ID == id {
+			return &ev, nil
+		}
+	}
+	return nil, errors.New("not found")
+}
+
+func (f *fakeClient) CreateEvent(ctx context.Context, calendarID string, in EventInput) (*Event, error) {
+	f.lastCalendar, f.lastInput = calendarID, in
+	return &Event{ID: "new", CalendarID: calendarID, Summary: in.Summary}, f.err
+}
+
+func (f *fakeClient) UpdateEvent(ctx context.Context, calendarID, id string, in EventInput) (*Event, error) {
+	f.lastCalendar, f.lastInput = calendarID, in
+	return &Event{ID: id, CalendarID: calendarID, Summary: in.Summary}, f.err
+}
+
+func (f *fakeClient) RespondEvent(ctx context.Context, calendarID, id string, rsvp RSVP) (*Event, error) {
+	f.lastCalendar, f.lastRSVP = calendarID, rsvp
+	if f.err != nil {
+		return nil, f.err
+	}
+	return &Event{ID: id, CalendarID: calendarID}, nil
+}
+
+func newTestHandler(clients map[string]CalendarClient) *Handler {
+	h := NewHandler([]string{"missing@example.com", "me@example.com", "team@example.com"}, clients)
+	h.now = func() time.Time { return time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC) }
+	return h
+}
+
+func serve(h *Handler, method, url string) *httptest.ResponseRecorder {
+	mux := http.NewServeMux()
+	h.RegisterRoutes(mux)
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(method, url, nil))
+	return rec
+}
+
+func TestHandler_ListCalendars(t *testing.T) {
+	me := &fakeClient{calendars: []Calendar{{ID: "me@example.com", Primary: true}}}
+	team := &fakeClient{calendars: []Calendar{{ID: "team"}}}
+	h := newTestHandler(map[string]CalendarClient{"me@example.com": me, "team@example.com": team})
+
+	var resp struct{ Calendars []Calendar }
+	rec := serve(h, "GET", "/api/calendar/calendars")
+	json.NewDecoder(rec.Body).Decode(&resp)
+	if rec.Code != http.StatusOK || len(resp.Calendars) != 1 || resp.Calendars[0].ID != "me@example.com" {
+		t.Errorf("default account: %d %+v", rec.Code, resp)
+	}
+	rec = serve(h, "GET", "/api/calendar/calendars?account=team@example.com")
+	json.NewDecoder(rec.Body).Decode(&resp)
+	if resp.Calendars[0].ID != "team" {
+		t.Errorf("team account: %+v", resp)
+	}
+	if rec := serve(h, "GET", "/api/calendar/calendars?account=nobody@example.com"); rec.Code != http.StatusBadRequest {
+		t.Errorf("unknown account: %d", rec.Code)
+	}
+	if rec := serve(h, "POST", "/api/calendar/calendars"); rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("POST: %d", rec.Code)
+	}
+	me.err = errors.New("token refresh: revoked")
+	if rec := serve(h, "GET", "/api/calendar/calendars"); rec.Code != http.StatusInternalServerError {
+		t.Errorf("client error: %d", rec.Code)
+	}
+	me.err = fmt.Errorf("calendarList.list: %w", &tokens.ScopeError{Account: "me@example.com"})
+	rec = serve(h, "GET", "/api/calendar/calendars")
+	var scopeResp map[string]any
+	json.NewDecoder(rec.Body).Decode(&scopeResp)
+	if rec.Code != http.StatusForbidden || scopeResp["error"] != "insufficient_scope" || scopeResp["reauth_url"] != "/auth/google/login?account=me%40example.com" {
+		t.Errorf("scope error: %d %v", rec.Code, scopeResp)
+	}
+}
+
+func TestHandler_ListEvents(t *testing.T) {
+	start := time.Date(2026, 3, 2, 10, 0, 0, 0, time.UTC)
+	fc := &fakeClient{
+		events: map[string][]Event{"team": {{ID: "e1", Summary: "Standup", Start: start, End: start.Add(15 * time.Minute)}}},
+		pages:  map[string]string{"": "p2"},
+	}
+	h := newTestHandler(map[string]CalendarClient{"me@example.com": fc})
+
+	rec := serve(h, "GET", "/api/calendar/events?calendar=team&q=standup&max=5000&to=2026-03-03T00:00:00Z")
+	var resp struct {
+		Events        []Event
+		NextPageToken string
+	}
+	json.NewDecoder(rec.Body).Decode(&resp)
+	if rec.Code != http.StatusOK || len(resp.Events) != 1 || resp.NextPageToken != "p2" {
+		t.Fatalf("response: %d %+v", rec.Code, resp)
+	}
+	q := fc.lastQuery
+	if fc.lastCalendar != "team" || q.Query != "standup" || q.MaxResults != maxEvents ||
+		!q.From.Equal(h.now()) || !q.To.Equal(time.Date(2026, 3, 3, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("query: %s %+v", fc.lastCalendar, q)
+	}
+
+	serve(h, "GET", "/api/calendar/events?from=2026-03-01T00:00:00Z")
+	if fc.lastCalendar != "primary" || fc.lastQuery.MaxResults != 50 || fc.lastQuery.From.Day() != 1 {
+		t.Errorf("defaults: %s %+v", fc.lastCalendar, fc.lastQuery)
+	}
+	if rec := serve(h, "GET", "/api/calendar/events?from=tomorrow"); rec.Code != http.StatusBadRequest {
+		t.Errorf("bad from: %d", rec.Code)
+	}
+}
+
+func TestHandler_GetEvent(t *testing.T) {
+	fc := &fakeClient{events: map[string][]Event{"primary": {{ID: "e1", Summary: "Standup"}}}}
+	h := newTestHandler(map[string]CalendarClient{"me@example.com": fc})
+
+	rec := serve(h, "GET", "/api/calendar/events/e1")
+	var ev Event
+	json.NewDecoder(rec.Body).Decode(&ev)
+	if rec.Code != http.StatusOK || ev.Summary != "Standup" {
+		t.Errorf("get: %d %+v", rec.Code, ev)
+	}
+	if rec := serve(h, "GET", "/api/calendar/events/e2?calendar=primary"); rec.Code != http.StatusInternalServerError {
+		t.Errorf("missing event: %d", rec.Code)
+	}
+	if rec := serve(h, "GET", "/api/calendar/events/e1/x"); rec.Code != http.StatusNotFound {
+		t.Errorf("nested path: %d", rec.Code)
+	}
+}
@@ -0,0 +1,351 @@
+package calendar
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"maps"
+	"math"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/katalabut/openclaw-relay/internal/atomicfile"
+	"github.com/katalabut/openclaw-relay/internal/config"
+	"github.com/katalabut/openclaw-relay/internal/gateway"
+	"github.com/katalabut/openclaw-relay/internal/migrate"
+)
+
+// stateFileName is the file in the state dir holding the reminders already
+// sent, so a restart does not send them again.
+const stateFileName = "calendar-state.json"
+
+// Poller creates the jobs of calendar rules. Each poll lists the events
+// starting within the rules' before, and creates a job for a matching event
+// once it is within its rule's before of the start, at most once per event,
+// start time and rule.
+type Poller struct {
+	clients  map[string]CalendarClient
+	accounts []string
+	rules    []config.CalendarRule
+	interval time.Duration
+	gateway  gateway.GatewayClient
+	stateDir string
+	timezone *time.Location // for the times in job messages (server.timezone)
+	now      func() time.Time
+
+	// mu serializes polls and RemoveAccount. fired maps the key of each job
+	// created to the event's start; entries are dropped once the event has
+	// started. removed holds off-boarded accounts.
+	mu      sync.Mutex
+	fired   map[string]time.Time
+	removed map[string]bool
+}
+
+// calendarState is the state file.
+type calendarState struct {
+	Version int                  `json:"version"`
+	Fired   map[string]time.Time `json:"fired"`
+}
+
+// stateSchema versions the state file. Add a migrate.Step when calendarState
+// changes shape.
+var stateSchema = migrate.Schema{Name: "calendar"}
+
+func NewPoller(clients map[string]CalendarClient, accounts []string, rules []config.CalendarRule, interval time.Duration, gw gateway.GatewayClient, stateDir string) *Poller {
+	return &Poller{
+		clients:  clients,
+		accounts: accounts,
+		rules:    rules,
+		interval: interval,
+		gateway:  gw,
+		stateDir: stateDir,
+		timezone: time.UTC,
+		now:      time.Now,
+	}
+}
+
+// SetTimezone sets the IANA zone event times are shown in; empty means UTC.
+func (p *Poller) SetTimezone(tz string) {
+	p.timezone = location(tz)
+}
+
+// Start polls in a goroutine, right away and then every interval, until ctx
+// is cancelled.
+func (p *Poller) Start(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(p.interval)
+		defer ticker.Stop()
+		for {
+			if err := p.Poll(ctx); err != nil {
+				log.Printf("Calendar: poll: %v", err)
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// Poll creates the jobs that are due. An account or calendar that fails to
+// list is logged and retried on the next poll; its reminders are still sent
+// if the event has not started by then.
+func (p *Poller) Poll(ctx context.Context) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	now := p.now()
+	if p.fired == nil {
+		fired, err := loadState(p.stateDir)
+		if err != nil {
+			return err
+		}
+		p.fired = fired
+	}
+	changed := false
+	for _, account := range p.accounts {
+		client, ok := p.clients[account]
+		if !ok || p.removed[account] {
+			continue
+		}
+		for _, calendarID := range p.calendars(account) {
+			events, err := p.upcoming(ctx, client, calendarID, now, now.Add(p.lead(account, calendarID)))
+			if err != nil {
+				log.Printf("Calendar: %s: list %s: %v", account, calendarID, err)
+				continue
+			}
+			for _, ev := range events {
+				if p.remind(account, ev, now) {
+					changed = true
+				}
+			}
+		}
+	}
+	for key, start := range p.fired {
+		if !start.After(now) {
+			delete(p.fired, key)
+			changed = true
+		}
+	}
+	if !changed {
+		return nil
+	}
+	return saveState(p.stateDir, p.fired)
+}
+
+// RemoveAccount stops polling account and drops the reminders it sent,
+// waiting for a poll in progress to finish. It reports whether there were
+// any.
+func (p *Poller) RemoveAccount(account string) (bool, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.removed == nil {
+		p.removed = map[string]bool{}
+	}
+	p.removed[account] = true
+	maps.DeleteFunc(p.fired, firedFor(account))
+	return RemoveState(p.stateDir, account)
+}
+
+// RemoveState drops the reminders sent for account from the state file in
+// stateDir. It reports whether there were any.
+func RemoveState(stateDir, account string) (bool, error) {
+	fired, err := loadState(stateDir)
+	if err != nil {
+		return false, err
+	}
+	n := len(fired)
+	maps.DeleteFunc(fired, firedFor(account))
+	if len(fired) == n {
+		return false, nil
+	}
+	return true, saveState(stateDir, fired)
+}
+
+// firedFor matches the fired keys of account's reminders.
+func firedFor(account string) func(string, time.Time) bool {
+	return func(key string, _ time.Time) bool {
+		return strings.HasPrefix(key, account+"|")
+	}
+}
+
+// calendars returns the calendars the account's rules read.
+func (p *Poller) calendars(account string) []string {
+	var out []string
+	for _, r := range p.rules {
+		if len(r.Accounts) > 0 && !slices.Contains(r.Accounts, account) {
+			continue
+		}
+		for _, c := range r.ResolvedCalendars() {
+			if !slices.Contains(out, c) {
+				out = append(out, c)
+			}
+		}
+	}
+	return out
+}
+
+// lead is the longest before of the rules reading the calendar.
+func (p *Poller) lead(account, calendarID string) time.Duration {
+	var d time.Duration
+	for _, r := range p.rules {
+		if p.reads(r, account, calendarID) {
+			d = max(d, r.ResolvedBefore())
+		}
+	}
+	return d
+}
+
+func (p *Poller) reads(r config.CalendarRule, account, calendarID string) bool {
+	return (len(r.Accounts) == 0 || slices.Contains(r.Accounts, account)) && slices.Contains(r.ResolvedCalendars(), calendarID)
+}
+
+// upcoming lists the events of a calendar starting in (from, to], all pages.
+func (p *Poller) upcoming(ctx context.Context, client CalendarClient, calendarID string, from, to time.Time) ([]Event, error) {
+	var out []Event
+	q := EventQuery{From: from, To: to, MaxResults: 250}
+	for {
+		events, next, err := client.ListEvents(ctx, calendarID, q)
+		if err != nil {
+			return nil, err
+		}
+		for _, ev := range events {
+			if ev.Status != "cancelled" && ev.Start.After(from) {
+				out = append(out, ev)
+			}
+		}
+		if next == "" {
+			return out, nil
+		}
+		q.PageToken = next
+	}
+}
+
+// remind creates the due jobs of the rules ev matches and reports whether
+// it created any.
+func (p *Poller) remind(account string, ev Event, now time.Time) bool {
+	created := false
+	for i, n := range p.findRules(account, ev) {
+		rule := p.rules[n]
+		if now.Before(ev.Start.Add(-rule.ResolvedBefore())) {
+			continue
+		}
+		ref := fmt.Sprintf("calendar.rules[%d]", n)
+		key := fmt.Sprintf("%s|%s|%s|%d|%s", account, ev.CalendarID, ev.ID, ev.Start.Unix(), ref)
+		if _, ok := p.fired[key]; ok {
+			continue
+		}
+		name := "calendar: " + ev.Summary
+		if i > 0 {
+			name = fmt.Sprintf("%s [%s]", name, ref)
+		}
+		if err := p.createJob(account, rule, name, p.templateData(account, ev, rule, now)); err != nil {
+			log.Printf("Calendar: job for %q (%s): %v", ev.Summary, ref, err)
+			continue
+		}
+		log.Printf("Calendar: %s: job for %q starting %s (%s)", account, ev.Summary, ev.Start.Format(time.RFC3339), ref)
+		p.fired[key] = ev.Start
+		created = true
+	}
+	return created
+}
+
+// findRules returns the indexes of the rules ev matches: the first, and
+// more while the matched rule sets continue.
+func (p *Poller) findRules(account string, ev Event) []int {
+	var out []int
+	for i, r := range p.rules {
+		if !p.reads(r, account, ev.CalendarID) {
+			continue
+		}
+		if len(r.Attendees) > 0 && !matchAttendees(r.Attendees, ev) {
+			continue
+		}
+		if len(r.Title) > 0 && !config.MatchPattern(r.Title, ev.Summary) {
+			continue
+		}
+		out = append(out, i)
+		if !r.Continue {
+			break
+		}
+	}
+	return out
+}
+
+// matchAttendees reports whether a pattern matches the organizer or any
+// attendee, by address or name, like gmail match.from.
+func matchAttendees(patterns []string, ev Event) bool {
+	candidates := []string{ev.Organizer}
+	for _, a := range ev.Attendees {
+		candidates = append(candidates, a.Email, a.Name)
+	}
+	return slices.ContainsFunc(candidates, func(c string) bool {
+		return c != "" && config.MatchAddress(patterns, c)
+	})
+}
+
+func (p *Poller) templateData(account string, ev Event, rule config.CalendarRule, now time.Time) map[string]any {
+	layout := "2006-01-02 15:04 MST"
+	if ev.AllDay {
+		layout = time.DateOnly
+	}
+	attendees := make([]string, 0, len(ev.Attendees))
+	for _, a := range ev.Attendees {
+		attendees = append(attendees, a.Email)
+	}
+	return map[string]any{
+		"Account":     account,
+		"Calendar":    ev.CalendarID,
+		"EventID":     ev.ID,
+		"Title":       ev.Summary,
+		"Description": ev.Description,
+		"Location":    ev.Location,
+		"Start":       ev.Start.In(p.timezone).Format(layout),
+		"End":         ev.End.In(p.timezone).Format(layout),
+		"AllDay":      ev.AllDay,
+		"StartsIn":    int(math.Ceil(ev.Start.Sub(now).Minutes())),
+		"Organizer":   ev.Organizer,
+		"Attendees":   strings.Join(attendees, ", "),
+		"URL":         ev.URL,
+		"MeetURL":     ev.MeetURL,
+		"Rule":        rule.Name,
+	}
+}
+
+func (p *Poller) createJob(account string, rule config.CalendarRule, name string, data map[string]any) error {
+	job := gateway.ActionJob("calendar", rule.Name, name, rule.Action, config.DefaultCalendarMessageTemplate(), data, p.timezone.String())
+	job.Account = account
+	return gateway.CreateJob(p.gateway, job)
+}
+
+func loadState(stateDir string) (map[string]time.Time, error) {
+	fired := map[string]time.Time{}
+	data, err := os.ReadFile(filepath.Join(stateDir, stateFileName))
+	if os.IsNotExist(err) {
+		return fired, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if data, _, err = stateSchema.Apply(data); err != nil {
+		return nil, err
+	}
+	var st calendarState
+	if err := json.Unmarshal(data, &st); err != nil {
+		return nil, fmt.Errorf("%s: %w", stateFileName, err)
+	}
+	for k, v := range st.Fired {
+		fired[k] = v
+	}
+	return fired, nil
+}
+
+// saveState replaces the state file.
+func saveState(stateDir string, fired map[string]time.Time) error {
+	return atomicfile.WriteJSON(filepath.Join(stateDir, stateFileName), calendarState{Version: stateSchema.Current(), Fired: fired})
+}
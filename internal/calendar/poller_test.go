@@ -0,0 +1,214 @@
+package calendar
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/katalabut/openclaw-relay/internal/config"
+	"github.com/katalabut/openclaw-relay/internal/gateway"
+)
+
+type recordingGateway struct {
+	jobs []gateway.JobSpec
+	err  error
+}
+
+func (g *recordingGateway) CreateOneShotJob(name, message string, timeoutSeconds, delaySeconds int) error {
+	return g.CreateOneShotJobForAgent(name, message, "", timeoutSeconds, delaySeconds)
+}
+
+func (g *recordingGateway) CreateOneShotJobForAgent(name, message, agentID string, timeoutSeconds, delaySeconds int) error {
+	return g.CreateJob(gateway.JobSpec{Name: name, Message: message, AgentID: agentID, TimeoutSeconds: timeoutSeconds, DelaySeconds: delaySeconds})
+}
+
+func (g *recordingGateway) CreateJob(spec gateway.JobSpec) error {
+	if g.err != nil {
+		return g.err
+	}
+	g.jobs = append(g.jobs, spec)
+	return nil
+}
+
+func (g *recordingGateway) names() string {
+	var names []string
+	for _, j := range g.jobs {
+		names = append(names, j.Name)
+	}
+	return strings.Join(names, "; ")
+}
+
+var pollStart = time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC)
+
+func event(id, summary string, in time.Duration, attendees ...string) Event {
+	ev := Event{ID: id, CalendarID: "primary", Status: "confirmed", Summary: summary, Start: pollStart.Add(in), End: pollStart.Add(in + 30*time.Minute), Organizer: "lead@example.com"}
+	for _, a := range attendees {
+		ev.Attendees = append(ev.Attendees, Attendee{Email: a})
+	}
+	return ev
+}
+
+func newTestPoller(t *testing.T, fc *fakeClient, gw *recordingGateway, rules ...config.CalendarRule) (*Poller, *time.Time) {
+	t.Helper()
+	now := pollStart
+	p := NewPoller(map[string]CalendarClient{"me@example.com": fc}, []string{"me@example.com"}, rules, time.Minute, gw, t.TempDir())
+	p.now = func() time.Time { return now }
+	return p, &now
+}
+
+func TestPoller_RemindsOnceBeforeStart(t *testing.T) {
+	fc := &fakeClient{events: map[string][]Event{"primary": {
+		event("e1", "Standup", 12*time.Minute),
+		event("e2", "Planning", 40*time.Minute),
+	}}}
+	gw := &recordingGateway{}
+	p, now := newTestPoller(t, fc, gw, config.CalendarRule{Name: "soon", Action: config.RuleAction{AgentID: "pa", Tags: map[string]string{"kind": "meeting"}}})
+	p.SetTimezone("Europe/Berlin")
+
+	// 12 minutes out: not yet within the default 10m
+	if err := p.Poll(context.Background()); err != nil || len(gw.jobs) != 0 {
+		t.Fatalf("first poll: %v, jobs %s", err, gw.names())
+	}
+	if fc.lastQuery.To != pollStart.Add(10*time.Minute) {
+		t.Errorf("lookahead = %v", fc.lastQuery.To)
+	}
+
+	*now = pollStart.Add(3 * time.Minute)
+	p.Poll(context.Background())
+	*now = pollStart.Add(4 * time.Minute)
+	p.Poll(context.Background())
+	if gw.names() != "calendar: Standup" {
+		t.Fatalf("jobs = %s", gw.names())
+	}
+	job := gw.jobs[0]
+	if job.Source != "calendar" || job.Rule != "soon" || job.AgentID != "pa" || job.Tags["kind"] != "meeting" || job.TimeoutSeconds != 120 || job.DelaySeconds != 2 {
+		t.Errorf("job = %+v", job)
+	}
+	if !strings.Contains(job.Message, "Standup starts in 9 min") || !strings.Contains(job.Message, "Start: 2026-03-02 10:12 CET") {
+		t.Errorf("message = %q", job.Message)
+	}
+
+	// A restarted poller remembers the reminder
+	p2 := NewPoller(p.clients, p.accounts, p.rules, time.Minute, gw, p.stateDir)
+	p2.now = p.now
+	p2.Poll(context.Background())
+	if len(gw.jobs) != 1 {
+		t.Errorf("restart resent: %s", gw.names())
+	}
+
+	// Once the event has started, its entry is dropped from the state file
+	*now = pollStart.Add(13 * time.Minute)
+	p.Poll(context.Background())
+	data, _ := os.ReadFile(filepath.Join(p.stateDir, stateFileName))
+	if strings.Contains(string(data), "e1") {
+		t.Errorf("state still has e1: %s", data)
+	}
+}
+
+func TestPoller_RemoveAccount(t *testing.T) {
+	fc := &fakeClient{events: map[string][]Event{"primary": {event("e1", "Standup", 5*time.Minute)}}}
+	gw := &recordingGateway{}
+	p, _ := newTestPoller(t, fc, gw, config.CalendarRule{Name: "soon"})
+	saveState(p.stateDir, map[string]time.Time{"other@example.com|primary|e9|1|calendar.rules[0]": pollStart.Add(time.Hour)})
+	if err := p.Poll(context.Background()); err != nil || len(gw.jobs) != 1 || gw.jobs[0].Account != "me@example.com" {
+		t.Fatalf("poll: %v, jobs %+v", err, gw.jobs)
+	}
+
+	if removed, err := p.RemoveAccount("me@example.com"); err != nil || !removed {
+		t.Fatalf("RemoveAccount = %v, %v", removed, err)
+	}
+	fired, _ := loadState(p.stateDir)
+	if len(fired) != 1 || len(p.fired) != 1 {
+		t.Errorf("expected only the other account's reminder kept, state %v, memory %v", fired, p.fired)
+	}
+	// Not polled again, so the reminder isn't sent a second time
+	if err := p.Poll(context.Background()); err != nil || len(gw.jobs) != 1 {
+		t.Errorf("poll after removal: %v, jobs %s", err, gw.names())
+	}
+	if removed, err := RemoveState(p.stateDir, "me@example.com"); err != nil || removed {
+		t.Errorf("second removal = %v, %v", removed, err)
+	}
+}
+
+func TestPoller_RescheduledEventFiresAgain(t *testing.T) {
+	ev := event("e1", "Standup", 5*time.Minute)
+	fc := &fakeClient{events: map[string][]Event{"primary": {ev}}}
+	gw := &recordingGateway{}
+	p, now := newTestPoller(t, fc, gw, config.CalendarRule{})
+
+	p.Poll(context.Background())
+	fc.events["primary"][0] = event("e1", "Standup", 8*time.Minute)
+	*now = pollStart.Add(time.Minute)
+	p.Poll(context.Background())
+	if len(gw.jobs) != 2 {
+		t.Errorf("jobs = %s", gw.names())
+	}
+}
+
+func TestPoller_Rules(t *testing.T) {
+	fc := &fakeClient{events: map[string][]Event{
+		"primary": {
+			event("e1", "1:1 with Dana", 5*time.Minute, "me@example.com", "dana@example.com"),
+			event("e2", "Interview: backend", 5*time.Minute, "candidate@gmail.com"),
+			event("e3", "Lunch", 5*time.Minute),
+			func() Event { e := event("e4", "Cancelled sync", 5*time.Minute); e.Status = "cancelled"; return e }(),
+			event("e5", "Already started", -5*time.Minute),
+		},
+		"team": {func() Event { e := event("t1", "Release", 30*time.Minute); e.CalendarID = "team"; return e }()},
+	}}
+	gw := &recordingGateway{}
+	p, _ := newTestPoller(t, fc, gw,
+		config.CalendarRule{Name: "one-on-one", Attendees: []string{"dana@"}, Continue: true},
+		config.CalendarRule{Name: "prep", Title: []string{"1:1 *", "interview*"}},
+		config.CalendarRule{Name: "release", Calendars: []string{"team"}, Before: "1h", Attendees: []string{"*@example.com"}},
+	)
+	p.Poll(context.Background())
+	want := "calendar: 1:1 with Dana; calendar: 1:1 with Dana [calendar.rules[1]]; calendar: Interview: backend; calendar: Release"
+	if gw.names() != want {
+		t.Errorf("jobs = %s\nwant %s", gw.names(), want)
+	}
+	if fc.lastCalendar != "team" || fc.lastQuery.To != pollStart.Add(time.Hour) {
+		t.Errorf("team calendar read with %+v", fc.lastQuery)
+	}
+}
+
+func TestPoller_Errors(t *testing.T) {
+	fc := &fakeClient{events: map[string][]Event{"primary": {event("e1", "Standup", 5*time.Minute)}}, err: errors.New("token refresh: revoked")}
+	gw := &recordingGateway{}
+	p, _ := newTestPoller(t, fc, gw, config.CalendarRule{})
+
+	// A failing account is retried on the next poll
+	if err := p.Poll(context.Background()); err != nil || len(gw.jobs) != 0 {
+		t.Fatalf("poll: %v, jobs %s", err, gw.names())
+	}
+	fc.err = nil
+	gw.err = errors.New("gateway down")
+	p.Poll(context.Background())
+	gw.err = nil
+	p.Poll(context.Background())
+	if len(gw.jobs) != 1 {
+		t.Errorf("jobs after recovery = %s", gw.names())
+	}
+
+	os.WriteFile(filepath.Join(p.stateDir, stateFileName), []byte("{"), 0600)
+	p.fired = nil
+	if err := p.Poll(context.Background()); err == nil {
+		t.Error("expected an error for a corrupt state file")
+	}
+}
+
+func TestPoller_Pages(t *testing.T) {
+	fc := &fakeClient{
+		events: map[string][]Event{"primary": {event("e1", "Standup", 5*time.Minute)}},
+		pages:  map[string]string{"": "p2"},
+	}
+	p, _ := newTestPoller(t, fc, &recordingGateway{}, config.CalendarRule{})
+	events, err := p.upcoming(context.Background(), fc, "primary", pollStart, pollStart.Add(time.Hour))
+	if err != nil || len(events) != 2 || fc.calls != 2 {
+		t.Errorf("events = %+v, calls %d, err %v", events, fc.calls, err)
+	}
+}
@@ -0,0 +1,270 @@
+package calendar
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	cal "google.golang.org/api/calendar/v3"
+)
+
+// ErrNotAttendee is returned by RespondEvent for an event the account is not
+// invited to.
+var ErrNotAttendee = errors.New("the account is not an attendee of this event")
+
+// EventInput is the content of a new event, or the fields of an event to
+// change. Empty fields are left unchanged by UpdateEvent; a set attendees
+// list replaces the event's.
+type EventInput struct {
+	Summary     string   `json:"summary"`
+	Description string   `json:"description"`
+	Location    string   `json:"location"`
+	Start       string   `json:"start"`    // RFC 3339 time, or a date (2006-01-02) for an all-day event
+	End         string   `json:"end"`      // exclusive: the day after the last for all-day events
+	TimeZone    string   `json:"timeZone"` // IANA zone of start and end; default the calendar's
+	Attendees   []string `json:"attendees"`
+	// SendUpdates is whom Google emails about the change: all,
+	// externalOnly or none (the default).
+	SendUpdates string `json:"sendUpdates"`
+}
+
+// RSVP is an answer to an event invitation.
+type RSVP struct {
+	Response    string `json:"response"` // accepted, declined or tentative
+	Comment     string `json:"comment"`
+	SendUpdates string `json:"sendUpdates"` // as in EventInput
+}
+
+var (
+	sendUpdatesValues = []string{"", "all", "externalOnly", "none"}
+	rsvpResponses     = []string{"accepted", "declined", "tentative"}
+)
+
+// validate checks in for a new event (create) or a change. Start and end
+// must both be times or both be dates, with end after start.
+func (in EventInput) validate(create bool) error {
+	if create && (in.Summary == "" || in.Start == "" || in.End == "") {
+		return errors.New("summary, start and end are required")
+	}
+	if (in.Start == "") != (in.End == "") {
+		return errors.New("start and end must be changed together")
+	}
+	if in.Start != "" {
+		start, startDate, err := parseEventTime(in.Start)
+		if err != nil {
+			return fmt.Errorf("start: %w", err)
+		}
+		end, endDate, err := parseEventTime(in.End)
+		if err != nil {
+			return fmt.Errorf("end: %w", err)
+		}
+		if startDate != endDate {
+			return errors.New("start and end must both be times or both be dates")
+		}
+		if !end.After(start) {
+			return errors.New("end must be after start")
+		}
+	}
+	if in.TimeZone != "" {
+		if _, err := time.LoadLocation(in.TimeZone); err != nil {
+			return fmt.Errorf("unknown timeZone %q", in.TimeZone)
+		}
+	}
+	for _, a := range in.Attendees {
+		if !strings.Contains(a, "@") {
+			return fmt.Errorf("attendee %q is not an email address", a)
+		}
+	}
+	if !slices.Contains(sendUpdatesValues, in.SendUpdates) {
+		return fmt.Errorf("sendUpdates must be all, externalOnly or none")
+	}
+	return nil
+}
+
+func (r RSVP) validate() error {
+	if !slices.Contains(rsvpResponses, r.Response) {
+		return errors.New("response must be accepted, declined or tentative")
+	}
+	if !slices.Contains(sendUpdatesValues, r.SendUpdates) {
+		return fmt.Errorf("sendUpdates must be all, externalOnly or none")
+	}
+	return nil
+}
+
+// parseEventTime parses an RFC 3339 time, or a date, and reports which.
+func parseEventTime(s string) (time.Time, bool, error) {
+	if t, err := time.Parse(time.DateOnly, s); err == nil {
+		return t, true, nil
+	}
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return time.Time{}, false, errors.New("must be an RFC 3339 time or a date")
+	}
+	return t, false, nil
+}
+
+// apiEvent converts in to the API's event; fields for Patch are only those
+// set.
+func (in EventInput) apiEvent() *cal.Event {
+	e := &cal.Event{Summary: in.Summary, Description: in.Description, Location: in.Location}
+	if in.Start != "" {
+		e.Start, e.End = apiEventTime(in.Start, in.TimeZone), apiEventTime(in.End, in.TimeZone)
+	}
+	if in.Attendees != nil {
+		e.Attendees = []*cal.EventAttendee{}
+		for _, a := range in.Attendees {
+			e.Attendees = append(e.Attendees, &cal.EventAttendee{Email: a})
+		}
+		e.ForceSendFields = append(e.ForceSendFields, "Attendees")
+	}
+	return e
+}
+
+func apiEventTime(s, tz string) *cal.EventDateTime {
+	if _, date, _ := parseEventTime(s); date {
+		return &cal.EventDateTime{Date: s, TimeZone: tz}
+	}
+	return &cal.EventDateTime{DateTime: s, TimeZone: tz}
+}
+
+// CreateEvent adds an event to a calendar.
+func (c *Client) CreateEvent(ctx context.Context, calendarID string, in EventInput) (*Event, error) {
+	svc, err := c.getService(ctx)
+	if err != nil {
+		return nil, err
+	}
+	call := svc.Events.Insert(calendarID, in.apiEvent()).Context(ctx)
+	if in.SendUpdates != "" {
+		call = call.SendUpdates(in.SendUpdates)
+	}
+	e, err := call.Do()
+	if err != nil {
+		return nil, fmt.Errorf("create event: %w", err)
+	}
+	ev := convertEvent(calendarID, e, location(in.TimeZone))
+	return &ev, nil
+}
+
+// UpdateEvent changes the fields of an event set in in.
+func (c *Client) UpdateEvent(ctx context.Context, calendarID, id string, in EventInput) (*Event, error) {
+	svc, err := c.getService(ctx)
+	if err != nil {
+		return nil, err
+	}
+	call := svc.Events.Patch(calendarID, id, in.apiEvent()).Context(ctx)
+	if in.SendUpdates != "" {
+		call = call.SendUpdates(in.SendUpdates)
+	}
+	e, err := call.Do()
+	if err != nil {
+		return nil, fmt.Errorf("update event: %w", err)
+	}
+	ev := convertEvent(calendarID, e, location(in.TimeZone))
+	return &ev, nil
+}
+
+// RespondEvent sets the account's response to an event it is invited to.
+func (c *Client) RespondEvent(ctx context.Context, calendarID, id string, rsvp RSVP) (*Event, error) {
+	svc, err := c.getService(ctx)
+	if err != nil {
+		return nil, err
+	}
+	e, err := svc.Events.Get(calendarID, id).Context(ctx).Do()
+	if err != nil {
+		return nil, err
+	}
+	found := false
+	for _, a := range e.Attendees {
+		if a.Self {
+			a.ResponseStatus, a.Comment = rsvp.Response, rsvp.Comment
+			found = true
+		}
+	}
+	if !found {
+		return nil, ErrNotAttendee
+	}
+	// The attendee list is replaced as a whole
+	call := svc.Events.Patch(calendarID, id, &cal.Event{Attendees: e.Attendees}).Context(ctx)
+	if rsvp.SendUpdates != "" {
+		call = call.SendUpdates(rsvp.SendUpdates)
+	}
+	e, err = call.Do()
+	if err != nil {
+		return nil, fmt.Errorf("respond to event: %w", err)
+	}
+	ev := convertEvent(calendarID, e, time.UTC)
+	return &ev, nil
+}
+
+// handleCreateEvent serves POST /api/calendar/events?calendar=.
+func (h *Handler) handleCreateEvent(w http.ResponseWriter, r *http.Request, client CalendarClient) {
+	var in EventInput
+	if !decodeBody(w, r, &in) {
+		return
+	}
+	if err := in.validate(true); err != nil {
+		jsonError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	ev, err := client.CreateEvent(r.Context(), calendarParam(r), in)
+	if err != nil {
+		apiError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(ev)
+}
+
+// handleUpdateEvent serves PATCH /api/calendar/events/{id}?calendar=.
+func (h *Handler) handleUpdateEvent(w http.ResponseWriter, r *http.Request, client CalendarClient, id string) {
+	var in EventInput
+	if !decodeBody(w, r, &in) {
+		return
+	}
+	if err := in.validate(false); err != nil {
+		jsonError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	ev, err := client.UpdateEvent(r.Context(), calendarParam(r), id, in)
+	if err != nil {
+		apiError(w, err)
+		return
+	}
+	jsonResponse(w, ev)
+}
+
+// handleRespondEvent serves POST /api/calendar/events/{id}/respond?calendar=.
+func (h *Handler) handleRespondEvent(w http.ResponseWriter, r *http.Request, client CalendarClient, id string) {
+	var rsvp RSVP
+	if !decodeBody(w, r, &rsvp) {
+		return
+	}
+	if err := rsvp.validate(); err != nil {
+		jsonError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	ev, err := client.RespondEvent(r.Context(), calendarParam(r), id, rsvp)
+	if errors.Is(err, ErrNotAttendee) {
+		jsonError(w, err.Error(), http.StatusConflict)
+		return
+	}
+	if err != nil {
+		apiError(w, err)
+		return
+	}
+	jsonResponse(w, ev)
+}
+
+func decodeBody(w http.ResponseWriter, r *http.Request, v any) bool {
+	if err := json.NewDecoder(r.Body).Decode(v); err != nil {
+		jsonError(w, "invalid request body", http.StatusBadRequest)
+		return false
+	}
+	return true
+}
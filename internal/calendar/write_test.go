@@ -0,0 +1,174 @@
+package calendar
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func serveBody(h *Handler, method, url, body string) *httptest.ResponseRecorder {
+	mux := http.NewServeMux()
+	h.RegisterRoutes(mux)
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(method, url, strings.NewReader(body)))
+	return rec
+}
+
+func TestEventInput_Validate(t *testing.T) {
+	tests := []struct {
+		name   string
+		in     EventInput
+		create bool
+		err    string
+	}{
+		{"timed", EventInput{Summary: "Sync", Start: "2026-03-02T10:00:00Z", End: "2026-03-02T10:30:00Z"}, true, ""},
+		{"all day", EventInput{Summary: "Offsite", Start: "2026-03-02", End: "2026-03-04", TimeZone: "Europe/Berlin"}, true, ""},
+		{"missing summary", EventInput{Start: "2026-03-02", End: "2026-03-03"}, true, "required"},
+		{"update without times", EventInput{Location: "Room 4"}, false, ""},
+		{"only start", EventInput{Start: "2026-03-02T10:00:00Z"}, false, "together"},
+		{"mixed", EventInput{Start: "2026-03-02", End: "2026-03-02T10:00:00Z"}, false, "both"},
+		{"bad time", EventInput{Start: "tomorrow", End: "2026-03-02"}, false, "start:"},
+		{"end before start", EventInput{Start: "2026-03-02T10:00:00Z", End: "2026-03-02T09:00:00Z"}, false, "after start"},
+		{"bad zone", EventInput{TimeZone: "Mars/Base"}, false, "timeZone"},
+		{"bad attendee", EventInput{Attendees: []string{"dana"}}, false, "email"},
+		{"bad sendUpdates", EventInput{SendUpdates: "everyone"}, false, "sendUpdates"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.in.validate(tt.create)
+			if tt.err == "" && err != nil || tt.err != "" && (err == nil || !strings.Contains(err.Error(), tt.err)) {
+				t.Errorf("validate() = %v, want %q", err, tt.err)
+			}
+		})
+	}
+}
+
+func TestHandler_Write(t *testing.T) {
+	fc := &fakeClient{}
+	h := newTestHandler(map[string]CalendarClient{"me@example.com": fc})
+	create := `{"summary":"Follow-up","start":"2026-03-02T10:00:00Z","end":"2026-03-02T10:30:00Z","attendees":["dana@example.com"]}`
+
+	// Off unless calendar.write
+	if rec := serveBody(h, "POST", "/api/calendar/events", create); rec.Code != http.StatusForbidden {
+		t.Errorf("write disabled: %d", rec.Code)
+	}
+	if rec := serveBody(h, "PATCH", "/api/calendar/events/e1", `{}`); rec.Code != http.StatusForbidden {
+		t.Errorf("write disabled patch: %d", rec.Code)
+	}
+	h.SetWrite(true)
+
+	rec := serveBody(h, "POST", "/api/calendar/events?calendar=team", create)
+	var ev Event
+	json.NewDecoder(rec.Body).Decode(&ev)
+	if rec.Code != http.StatusCreated || ev.ID != "new" || fc.lastCalendar != "team" || fc.lastInput.Attendees[0] != "dana@example.com" {
+		t.Errorf("create: %d %+v %+v", rec.Code, ev, fc.lastInput)
+	}
+	if rec := serveBody(h, "POST", "/api/calendar/events", `{"summary":"x"}`); rec.Code != http.StatusBadRequest {
+		t.Errorf("invalid create: %d", rec.Code)
+	}
+	if rec := serveBody(h, "POST", "/api/calendar/events", `{`); rec.Code != http.StatusBadRequest {
+		t.Errorf("bad JSON: %d", rec.Code)
+	}
+
+	rec = serveBody(h, "PATCH", "/api/calendar/events/e1", `{"location":"Room 4","sendUpdates":"all"}`)
+	if rec.Code != http.StatusOK || fc.lastCalendar != "primary" || fc.lastInput.Location != "Room 4" || fc.lastInput.SendUpdates != "all" {
+		t.Errorf("update: %d %+v", rec.Code, fc.lastInput)
+	}
+
+	rec = serveBody(h, "POST", "/api/calendar/events/e1/respond", `{"response":"declined","comment":"Out that day"}`)
+	if rec.Code != http.StatusOK || fc.lastRSVP.Response != "declined" || fc.lastRSVP.Comment != "Out that day" {
+		t.Errorf("respond: %d %+v", rec.Code, fc.lastRSVP)
+	}
+	if rec := serveBody(h, "POST", "/api/calendar/events/e1/respond", `{"response":"maybe"}`); rec.Code != http.StatusBadRequest {
+		t.Errorf("bad response: %d", rec.Code)
+	}
+	if rec := serveBody(h, "GET", "/api/calendar/events/e1/respond", ""); rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("GET respond: %d", rec.Code)
+	}
+	if rec := serveBody(h, "DELETE", "/api/calendar/events/e1", ""); rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("DELETE: %d", rec.Code)
+	}
+
+	fc.err = ErrNotAttendee
+	if rec := serveBody(h, "POST", "/api/calendar/events/e1/respond", `{"response":"accepted"}`); rec.Code != http.StatusConflict {
+		t.Errorf("not invited: %d", rec.Code)
+	}
+	fc.err = errors.New("insufficient permissions")
+	if rec := serveBody(h, "POST", "/api/calendar/events", create); rec.Code != http.StatusInternalServerError {
+		t.Errorf("client error: %d", rec.Code)
+	}
+}
+
+func TestClient_CreateEvent(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if r.Method != "POST" || r.URL.Path != "/calendars/primary/events" || r.URL.Query().Get("sendUpdates") != "all" {
+			t.Errorf("request = %s %s?%s", r.Method, r.URL.Path, r.URL.RawQuery)
+		}
+		if !strings.Contains(string(body), `"date":"2026-03-03"`) || !strings.Contains(string(body), `"email":"dana@example.com"`) {
+			t.Errorf("body = %s", body)
+		}
+		fmt.Fprint(w, `{"id":"n1","summary":"Offsite","start":{"date":"2026-03-03"},"end":{"date":"2026-03-04"}}`)
+	})
+	ev, err := c.CreateEvent(context.Background(), "primary", EventInput{
+		Summary: "Offsite", Start: "2026-03-03", End: "2026-03-04", Attendees: []string{"dana@example.com"}, SendUpdates: "all",
+	})
+	if err != nil || ev.ID != "n1" || !ev.AllDay {
+		t.Errorf("event = %+v, err %v", ev, err)
+	}
+}
+
+func TestClient_UpdateEvent(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if r.Method != "PATCH" || r.URL.Path != "/calendars/primary/events/e1" {
+			t.Errorf("request = %s %s", r.Method, r.URL.Path)
+		}
+		// Only the fields set are sent; an empty attendee list clears them
+		if got := strings.TrimSpace(string(body)); got != `{"attendees":[],"location":"Room 4"}` {
+			t.Errorf("body = %s", body)
+		}
+		fmt.Fprint(w, `{"id":"e1","location":"Room 4"}`)
+	})
+	ev, err := c.UpdateEvent(context.Background(), "primary", "e1", EventInput{Location: "Room 4", Attendees: []string{}})
+	if err != nil || ev.Location != "Room 4" {
+		t.Errorf("event = %+v, err %v", ev, err)
+	}
+}
+
+func TestClient_RespondEvent(t *testing.T) {
+	var patched string
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == "GET" && r.URL.Path == "/calendars/primary/events/e1":
+			fmt.Fprint(w, `{"id":"e1","attendees":[{"email":"lead@example.com","organizer":true,"responseStatus":"accepted"},{"email":"me@example.com","self":true,"responseStatus":"needsAction"}]}`)
+		case r.Method == "GET" && r.URL.Path == "/calendars/primary/events/e2":
+			fmt.Fprint(w, `{"id":"e2","attendees":[{"email":"lead@example.com"}]}`)
+		case r.Method == "PATCH":
+			body, _ := io.ReadAll(r.Body)
+			patched = string(body)
+			fmt.Fprint(w, `{"id":"e1","attendees":[{"email":"me@example.com","self":true,"responseStatus":"tentative"}]}`)
+		default:
+			http.Error(w, `{"error":{"code":404,"message":"Not Found"}}`, http.StatusNotFound)
+		}
+	})
+	ev, err := c.RespondEvent(context.Background(), "primary", "e1", RSVP{Response: "tentative", Comment: "May be late"})
+	if err != nil || ev.Attendees[0].Response != "tentative" {
+		t.Fatalf("event = %+v, err %v", ev, err)
+	}
+	if !strings.Contains(patched, `"lead@example.com"`) || !strings.Contains(patched, `"comment":"May be late"`) || !strings.Contains(patched, `"responseStatus":"tentative"`) {
+		t.Errorf("patch = %s", patched)
+	}
+	if _, err := c.RespondEvent(context.Background(), "primary", "e2", RSVP{Response: "accepted"}); !errors.Is(err, ErrNotAttendee) {
+		t.Errorf("not invited: %v", err)
+	}
+	if _, err := c.RespondEvent(context.Background(), "primary", "missing", RSVP{Response: "accepted"}); err == nil {
+		t.Error("expected an error for a missing event")
+	}
+}
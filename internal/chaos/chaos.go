@@ -0,0 +1,181 @@
+// Package chaos injects simulated failures for staging and testing.
+// It is inert unless the RELAY_CHAOS env var is set.
+package chaos
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"math/rand"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Fault names accepted in RELAY_CHAOS and /api/chaos.
+const (
+	GatewayError   = "gateway_error"   // gateway responds 500
+	GatewayTimeout = "gateway_timeout" // gateway request hangs until the client times out
+	GmailQuota     = "gmail_quota"     // Gmail calls fail with 429 rateLimitExceeded
+)
+
+var knownFaults = map[string]bool{GatewayError: true, GatewayTimeout: true, GmailQuota: true}
+
+// Injector decides, per call, whether a fault should be simulated.
+type Injector struct {
+	mu    sync.Mutex
+	rates map[string]float64
+	rand  func() float64
+}
+
+// Parse builds an injector from a spec like "gateway_error=0.5,gmail_quota=1".
+// Rates are probabilities between 0 and 1.
+func Parse(spec string) (*Injector, error) {
+	inj := &Injector{rates: map[string]float64{}, rand: rand.Float64}
+	for _, part := range strings.Split(spec, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" || part == "1" || part == "true" {
+			continue
+		}
+		name, val, ok := strings.Cut(part, "=")
+		if !ok {
+			return nil, fmt.Errorf("chaos: expected fault=rate, got %q", part)
+		}
+		rate, err := strconv.ParseFloat(strings.TrimSpace(val), 64)
+		if err != nil {
+			return nil, fmt.Errorf("chaos: invalid rate for %s: %w", name, err)
+		}
+		if err := inj.Set(strings.TrimSpace(name), rate); err != nil {
+			return nil, err
+		}
+	}
+	return inj, nil
+}
+
+// FromEnv returns an injector configured by RELAY_CHAOS, or nil when unset.
+func FromEnv() (*Injector, error) {
+	spec := os.Getenv("RELAY_CHAOS")
+	if spec == "" {
+		return nil, nil
+	}
+	return Parse(spec)
+}
+
+// Set changes the rate for a fault (0 disables it).
+func (i *Injector) Set(fault string, rate float64) error {
+	if !knownFaults[fault] {
+		return fmt.Errorf("chaos: unknown fault %q", fault)
+	}
+	if rate < 0 || rate > 1 {
+		return fmt.Errorf("chaos: rate for %s must be between 0 and 1", fault)
+	}
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	if rate == 0 {
+		delete(i.rates, fault)
+	} else {
+		i.rates[fault] = rate
+	}
+	return nil
+}
+
+// Rates returns a copy of the active fault rates.
+func (i *Injector) Rates() map[string]float64 {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	out := make(map[string]float64, len(i.rates))
+	for k, v := range i.rates {
+		out[k] = v
+	}
+	return out
+}
+
+// Should reports whether the fault should fire for this call. Safe on a nil injector.
+func (i *Injector) Should(fault string) bool {
+	if i == nil {
+		return false
+	}
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	rate := i.rates[fault]
+	return rate > 0 && i.rand() < rate
+}
+
+// String describes the active faults for logging.
+func (i *Injector) String() string {
+	rates := i.Rates()
+	parts := make([]string, 0, len(rates))
+	for k, v := range rates {
+		parts = append(parts, fmt.Sprintf("%s=%g", k, v))
+	}
+	sort.Strings(parts)
+	return strings.Join(parts, ",")
+}
+
+type transport struct {
+	inj  *Injector
+	base http.RoundTripper
+}
+
+// Transport wraps base so gateway requests can fail with 500s or hang until timeout.
+func (i *Injector) Transport(base http.RoundTripper) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &transport{inj: i, base: base}
+}
+
+func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if t.inj.Should(GatewayTimeout) {
+		log.Printf("Chaos: simulating gateway timeout for %s", req.URL.Path)
+		<-req.Context().Done()
+		return nil, req.Context().Err()
+	}
+	if t.inj.Should(GatewayError) {
+		log.Printf("Chaos: simulating gateway 500 for %s", req.URL.Path)
+		if req.Body != nil {
+			req.Body.Close()
+		}
+		return &http.Response{
+			StatusCode: http.StatusInternalServerError,
+			Status:     "500 Internal Server Error",
+			Header:     http.Header{},
+			Body:       io.NopCloser(strings.NewReader(`{"error":"chaos: simulated gateway failure"}`)),
+			Request:    req,
+		}, nil
+	}
+	return t.base.RoundTrip(req)
+}
+
+// HandleChaos serves GET/PUT /api/chaos to inspect or change fault rates at runtime.
+// PUT body: {"gateway_error": 0.5, "gmail_quota": 0}
+func (i *Injector) HandleChaos(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPut:
+		var req map[string]float64
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "invalid request body"})
+			return
+		}
+		for fault, rate := range req {
+			if err := i.Set(fault, rate); err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+				return
+			}
+		}
+		log.Printf("Chaos: faults updated: %s", i)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		json.NewEncoder(w).Encode(map[string]string{"error": "method not allowed"})
+		return
+	}
+	json.NewEncoder(w).Encode(map[string]any{"faults": i.Rates()})
+}
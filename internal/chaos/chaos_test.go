@@ -0,0 +1,157 @@
+package chaos
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParse(t *testing.T) {
+	inj, err := Parse("gateway_error=0.5, gmail_quota=1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	rates := inj.Rates()
+	if rates[GatewayError] != 0.5 || rates[GmailQuota] != 1 || len(rates) != 2 {
+		t.Errorf("unexpected rates: %v", rates)
+	}
+	if inj.String() != "gateway_error=0.5,gmail_quota=1" {
+		t.Errorf("unexpected string: %s", inj)
+	}
+
+	// Bare "1" enables the injector with no faults, for runtime control via /api/chaos
+	inj, err = Parse("1")
+	if err != nil || len(inj.Rates()) != 0 {
+		t.Errorf("expected empty injector, got %v, %v", inj.Rates(), err)
+	}
+
+	for _, bad := range []string{"gateway_error", "gateway_error=x", "disk_full=1", "gmail_quota=2"} {
+		if _, err := Parse(bad); err == nil {
+			t.Errorf("Parse(%q): expected error", bad)
+		}
+	}
+}
+
+func TestFromEnv(t *testing.T) {
+	t.Setenv("RELAY_CHAOS", "")
+	if inj, err := FromEnv(); inj != nil || err != nil {
+		t.Errorf("expected nil injector when unset, got %v, %v", inj, err)
+	}
+	t.Setenv("RELAY_CHAOS", "gateway_timeout=0.1")
+	inj, err := FromEnv()
+	if err != nil || inj.Rates()[GatewayTimeout] != 0.1 {
+		t.Errorf("unexpected injector: %v, %v", inj, err)
+	}
+}
+
+func TestShould(t *testing.T) {
+	var nilInj *Injector
+	if nilInj.Should(GatewayError) {
+		t.Error("nil injector should never fire")
+	}
+
+	inj, _ := Parse("gateway_error=0.5")
+	inj.rand = func() float64 { return 0.4 }
+	if !inj.Should(GatewayError) {
+		t.Error("expected fault below rate to fire")
+	}
+	inj.rand = func() float64 { return 0.6 }
+	if inj.Should(GatewayError) {
+		t.Error("expected fault above rate not to fire")
+	}
+	if inj.Should(GmailQuota) {
+		t.Error("unset fault should not fire")
+	}
+}
+
+func TestTransport_GatewayError(t *testing.T) {
+	hits := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits++
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	inj, _ := Parse("gateway_error=1")
+	client := &http.Client{Transport: inj.Transport(nil)}
+
+	resp, err := client.Post(srv.URL, "application/json", strings.NewReader(`{}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusInternalServerError || hits != 0 {
+		t.Errorf("expected simulated 500 without reaching server, got %d (hits=%d)", resp.StatusCode, hits)
+	}
+
+	inj.Set(GatewayError, 0)
+	resp, err = client.Post(srv.URL, "application/json", strings.NewReader(`{}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || hits != 1 {
+		t.Errorf("expected pass-through once disabled, got %d (hits=%d)", resp.StatusCode, hits)
+	}
+}
+
+func TestTransport_GatewayTimeout(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+
+	inj, _ := Parse("gateway_timeout=1")
+	client := &http.Client{Transport: inj.Transport(nil), Timeout: 50 * time.Millisecond}
+
+	start := time.Now()
+	_, err := client.Get(srv.URL)
+	if err == nil {
+		t.Fatal("expected timeout error")
+	}
+	if time.Since(start) < 50*time.Millisecond {
+		t.Error("expected request to hang until the client timeout")
+	}
+}
+
+func TestHandleChaos(t *testing.T) {
+	inj, _ := Parse("gateway_error=0.5")
+
+	rec := httptest.NewRecorder()
+	inj.HandleChaos(rec, httptest.NewRequest("PUT", "/api/chaos", strings.NewReader(`{"gateway_error":0,"gmail_quota":0.25}`)))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rec.Code)
+	}
+	var resp struct {
+		Faults map[string]float64 `json:"faults"`
+	}
+	json.NewDecoder(rec.Body).Decode(&resp)
+	if len(resp.Faults) != 1 || resp.Faults[GmailQuota] != 0.25 {
+		t.Errorf("unexpected faults: %v", resp.Faults)
+	}
+
+	rec = httptest.NewRecorder()
+	inj.HandleChaos(rec, httptest.NewRequest("GET", "/api/chaos", nil))
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "gmail_quota") {
+		t.Errorf("unexpected GET response: %d %s", rec.Code, rec.Body.String())
+	}
+
+	rec = httptest.NewRecorder()
+	inj.HandleChaos(rec, httptest.NewRequest("PUT", "/api/chaos", strings.NewReader(`{"disk_full":1}`)))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for unknown fault, got %d", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	inj.HandleChaos(rec, httptest.NewRequest("PUT", "/api/chaos", strings.NewReader(`nope`)))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for bad body, got %d", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	inj.HandleChaos(rec, httptest.NewRequest("DELETE", "/api/chaos", nil))
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("expected 405, got %d", rec.Code)
+	}
+}
@@ -0,0 +1,110 @@
+package chaos
+
+import (
+	"context"
+	"log"
+	"net/http"
+
+	"google.golang.org/api/googleapi"
+
+	"github.com/katalabut/openclaw-relay/internal/gmail"
+)
+
+// gmailClient fails calls with a simulated quota error before reaching the wrapped client.
+// Methods not overridden here pass straight through.
+type gmailClient struct {
+	gmail.GmailClient
+	inj *Injector
+}
+
+// WrapGmail returns a client that injects gmail_quota faults into c.
+func (i *Injector) WrapGmail(c gmail.GmailClient) gmail.GmailClient {
+	return &gmailClient{GmailClient: c, inj: i}
+}
+
+// QuotaError is the error returned for simulated Gmail quota exhaustion.
+func QuotaError() error {
+	return &googleapi.Error{
+		Code:    http.StatusTooManyRequests,
+		Message: "chaos: simulated quota exceeded",
+		Errors:  []googleapi.ErrorItem{{Reason: "rateLimitExceeded", Message: "chaos: simulated quota exceeded"}},
+	}
+}
+
+func (c *gmailClient) fault(op string) error {
+	if c.inj.Should(GmailQuota) {
+		log.Printf("Chaos: simulating Gmail quota error for %s", op)
+		return QuotaError()
+	}
+	return nil
+}
+
+func (c *gmailClient) ListMessages(ctx context.Context, query string, maxResults int64, pageToken string) ([]gmail.MessageMeta, string, error) {
+	if err := c.fault("ListMessages"); err != nil {
+		return nil, "", err
+	}
+	return c.GmailClient.ListMessages(ctx, query, maxResults, pageToken)
+}
+
+func (c *gmailClient) ListThreads(ctx context.Context, query string, maxResults int64, pageToken string) ([]gmail.ThreadMeta, string, error) {
+	if err := c.fault("ListThreads"); err != nil {
+		return nil, "", err
+	}
+	return c.GmailClient.ListThreads(ctx, query, maxResults, pageToken)
+}
+
+func (c *gmailClient) GetMessage(ctx context.Context, id string) (*gmail.MessageFull, error) {
+	if err := c.fault("GetMessage"); err != nil {
+		return nil, err
+	}
+	return c.GmailClient.GetMessage(ctx, id)
+}
+
+func (c *gmailClient) ModifyMessage(ctx context.Context, id string, req gmail.ModifyRequest) error {
+	if err := c.fault("ModifyMessage"); err != nil {
+		return err
+	}
+	return c.GmailClient.ModifyMessage(ctx, id, req)
+}
+
+func (c *gmailClient) ListLabels(ctx context.Context) ([]gmail.LabelInfo, error) {
+	if err := c.fault("ListLabels"); err != nil {
+		return nil, err
+	}
+	return c.GmailClient.ListLabels(ctx)
+}
+
+func (c *gmailClient) GetThread(ctx context.Context, threadID string) ([]gmail.MessageFull, error) {
+	if err := c.fault("GetThread"); err != nil {
+		return nil, err
+	}
+	return c.GmailClient.GetThread(ctx, threadID)
+}
+
+func (c *gmailClient) GetAttachment(ctx context.Context, messageID, attachmentID string) ([]byte, error) {
+	if err := c.fault("GetAttachment"); err != nil {
+		return nil, err
+	}
+	return c.GmailClient.GetAttachment(ctx, messageID, attachmentID)
+}
+
+func (c *gmailClient) GetCurrentHistoryID(ctx context.Context) (uint64, error) {
+	if err := c.fault("GetCurrentHistoryID"); err != nil {
+		return 0, err
+	}
+	return c.GmailClient.GetCurrentHistoryID(ctx)
+}
+
+func (c *gmailClient) GetHistory(ctx context.Context, startHistoryID uint64) ([]gmail.HistoryMessage, uint64, error) {
+	if err := c.fault("GetHistory"); err != nil {
+		return nil, 0, err
+	}
+	return c.GmailClient.GetHistory(ctx, startHistoryID)
+}
+
+func (c *gmailClient) ForwardMessage(ctx context.Context, id string, req gmail.ForwardRequest) error {
+	if err := c.fault("ForwardMessage"); err != nil {
+		return err
+	}
+	return c.GmailClient.ForwardMessage(ctx, id, req)
+}
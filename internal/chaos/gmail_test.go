@@ -0,0 +1,56 @@
+package chaos
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"google.golang.org/api/googleapi"
+
+	"github.com/katalabut/openclaw-relay/internal/gmail"
+)
+
+type stubGmail struct {
+	gmail.GmailClient
+	calls int
+}
+
+func (s *stubGmail) GetHistory(ctx context.Context, startHistoryID uint64) ([]gmail.HistoryMessage, uint64, error) {
+	s.calls++
+	return nil, startHistoryID + 1, nil
+}
+
+func (s *stubGmail) ListLabels(ctx context.Context) ([]gmail.LabelInfo, error) {
+	s.calls++
+	return []gmail.LabelInfo{{ID: "INBOX"}}, nil
+}
+
+func TestWrapGmail_Quota(t *testing.T) {
+	stub := &stubGmail{}
+	inj, _ := Parse("gmail_quota=1")
+	c := inj.WrapGmail(stub)
+
+	_, _, err := c.GetHistory(context.Background(), 10)
+	var apiErr *googleapi.Error
+	if !errors.As(err, &apiErr) || apiErr.Code != http.StatusTooManyRequests {
+		t.Fatalf("expected 429 googleapi error, got %v", err)
+	}
+	if apiErr.Errors[0].Reason != "rateLimitExceeded" {
+		t.Errorf("unexpected reason: %s", apiErr.Errors[0].Reason)
+	}
+	if stub.calls != 0 {
+		t.Error("wrapped client should not be called when a fault fires")
+	}
+
+	inj.Set(GmailQuota, 0)
+	if _, next, err := c.GetHistory(context.Background(), 10); err != nil || next != 11 {
+		t.Errorf("expected pass-through, got %d, %v", next, err)
+	}
+	if labels, err := c.ListLabels(context.Background()); err != nil || len(labels) != 1 {
+		t.Errorf("expected pass-through, got %v, %v", labels, err)
+	}
+	if stub.calls != 2 {
+		t.Errorf("expected 2 calls to wrapped client, got %d", stub.calls)
+	}
+}
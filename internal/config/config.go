@@ -1,23 +1,64 @@
 package config
 
 import (
+	"crypto/ed25519"
+	"encoding/hex"
 	"fmt"
 	"log"
+	"net"
+	"net/mail"
+	"net/url"
 	"os"
+	"path/filepath"
 	"regexp"
+	"slices"
 	"strings"
+	"sync"
+	"text/template"
+	"time"
 
+	"github.com/katalabut/openclaw-relay/internal/expr"
+	"github.com/katalabut/openclaw-relay/internal/schedule"
+	"github.com/katalabut/openclaw-relay/internal/sealed"
 	"gopkg.in/yaml.v3"
 )
 
 type Config struct {
-	Server  ServerConfig  `yaml:"server"`
-	Gateway GatewayConfig `yaml:"gateway"`
-	Trello  TrelloConfig  `yaml:"trello"`
-	GitHub  GitHubConfig  `yaml:"github"`
-	Google  GoogleConfig  `yaml:"google"`
-	Gmail   GmailConfig   `yaml:"gmail"`
-	Audit   AuditConfig   `yaml:"audit"`
+	// ReadOnly blocks mutating /api/* requests and skips rule actions that change external state.
+	ReadOnly bool `yaml:"read_only"`
+
+	// InMemory is set by the -memory flag: no disk state, jobs go to an in-process sink.
+	InMemory bool `yaml:"-"`
+
+	// Path and Source are the file Load read and its content before ${VAR}
+	// substitution, kept for config version history.
+	Path   string `yaml:"-"`
+	Source []byte `yaml:"-"`
+
+	Server   ServerConfig   `yaml:"server"`
+	Gateway  GatewayConfig  `yaml:"gateway"`
+	Trello   TrelloConfig   `yaml:"trello"`
+	GitHub   GitHubConfig   `yaml:"github"`
+	Slack    SlackConfig    `yaml:"slack"`
+	Discord  DiscordConfig  `yaml:"discord"`
+	Notion   NotionConfig   `yaml:"notion"`
+	Jira     JiraConfig     `yaml:"jira"`
+	Sentry   SentryConfig   `yaml:"sentry"`
+	Google   GoogleConfig   `yaml:"google"`
+	Gmail    GmailConfig    `yaml:"gmail"`
+	Calendar CalendarConfig `yaml:"calendar"`
+	Drive    DriveConfig    `yaml:"drive"`
+	Tasks    TasksConfig    `yaml:"tasks"`
+	Audit    AuditConfig    `yaml:"audit"`
+	Budget   BudgetConfig   `yaml:"budget"`
+	Dataset  DatasetConfig  `yaml:"dataset"`
+	GC       GCConfig       `yaml:"gc"`
+
+	Alertmanager AlertmanagerConfig `yaml:"alertmanager"`
+	Asana        AsanaConfig        `yaml:"asana"`
+	Bitbucket    BitbucketConfig    `yaml:"bitbucket"`
+
+	GenericWebhooks []GenericWebhookConfig `yaml:"generic_webhooks"`
 }
 
 type GoogleConfig struct {
@@ -25,6 +66,58 @@ type GoogleConfig struct {
 	ClientSecret  string   `yaml:"client_secret"`
 	RedirectURL   string   `yaml:"redirect_url"`
 	AllowedEmails []string `yaml:"allowed_emails"`
+	Scopes        []string `yaml:"scopes"` // OAuth scopes; short names like "gmail.readonly" are expanded
+	// TokenGracePeriod is how long a disconnected account's token is kept for
+	// restore before it is purged (default 168h).
+	TokenGracePeriod string `yaml:"token_grace_period"`
+}
+
+// ResolvedTokenGracePeriod returns token_grace_period with default 7 days.
+func (g GoogleConfig) ResolvedTokenGracePeriod() time.Duration {
+	if d, err := time.ParseDuration(g.TokenGracePeriod); err == nil && d > 0 {
+		return d
+	}
+	return 7 * 24 * time.Hour
+}
+
+const googleScopePrefix = "https://www.googleapis.com/auth/"
+
+// DefaultGoogleScopes returns the OAuth scopes requested when google.scopes is empty.
+func DefaultGoogleScopes() []string {
+	return []string{
+		googleScopePrefix + "gmail.modify",
+		googleScopePrefix + "calendar.readonly",
+		googleScopePrefix + "userinfo.email",
+	}
+}
+
+// ResolvedScopes returns the configured OAuth scopes as full URLs, or the defaults.
+// userinfo.email is always included because the callback needs the account email.
+func (g GoogleConfig) ResolvedScopes() []string {
+	if len(g.Scopes) == 0 {
+		return DefaultGoogleScopes()
+	}
+	emailScope := googleScopePrefix + "userinfo.email"
+	out := make([]string, 0, len(g.Scopes)+1)
+	seen := make(map[string]bool, len(g.Scopes)+1)
+	for _, s := range g.Scopes {
+		s = strings.TrimSpace(s)
+		if s == "" {
+			continue
+		}
+		if !strings.HasPrefix(s, "https://") && s != "openid" && s != "email" && s != "profile" {
+			s = googleScopePrefix + s
+		}
+		if seen[s] {
+			continue
+		}
+		seen[s] = true
+		out = append(out, s)
+	}
+	if !seen[emailScope] && !seen["email"] {
+		out = append(out, emailScope)
+	}
+	return out
 }
 
 type GmailConfig struct {
@@ -32,6 +125,205 @@ type GmailConfig struct {
 	PollInterval string                `yaml:"poll_interval"`
 	Accounts     []GmailAccountConf    `yaml:"accounts"`
 	AuthAlert    *GmailAuthAlertConfig `yaml:"auth_alert"`
+	Watchdog     *GmailWatchdogConfig  `yaml:"watchdog"`
+	APIURL       string                `yaml:"api_url"` // default https://gmail.googleapis.com
+
+	// Per-poll caps so a large backlog is worked off over several cycles
+	MaxHistoryPages    int `yaml:"max_history_pages"`
+	MaxHistoryMessages int `yaml:"max_history_messages"`
+
+	// Retry is the backoff for rate-limited and failing Gmail API calls
+	Retry GmailRetryConfig `yaml:"retry"`
+
+	// Quota is the daily Gmail API unit budget per account
+	Quota GmailQuotaConfig `yaml:"quota"`
+
+	// APICache is how long GET /api/gmail/messages and /api/gmail/labels
+	// responses are reused for the same account and query; empty = no cache
+	APICache string `yaml:"api_cache"`
+
+	Attachments    GmailAttachmentsConfig    `yaml:"attachments"`
+	TextExtraction GmailTextExtractionConfig `yaml:"text_extraction"`
+}
+
+// GmailRetryConfig is how Gmail API calls that hit a rate limit (429, or 403
+// rateLimitExceeded) or a server error are retried, and how many calls one
+// account has in flight.
+type GmailRetryConfig struct {
+	MaxRetries    int    `yaml:"max_retries"`    // retries after the first attempt (default 4); -1 = none
+	BaseDelay     string `yaml:"base_delay"`     // first backoff, doubled per retry with jitter (default 1s)
+	MaxDelay      string `yaml:"max_delay"`      // longest backoff or Retry-After waited for (default 30s)
+	MaxConcurrent int    `yaml:"max_concurrent"` // requests in flight per account (default 10); -1 = unlimited
+}
+
+// ResolvedMaxRetries returns max_retries with default 4, 0 when disabled.
+func (r GmailRetryConfig) ResolvedMaxRetries() int {
+	switch {
+	case r.MaxRetries == 0:
+		return 4
+	case r.MaxRetries < 0:
+		return 0
+	}
+	return r.MaxRetries
+}
+
+// ResolvedDelays returns base_delay (default 1s) and max_delay (default 30s).
+func (r GmailRetryConfig) ResolvedDelays() (base, max time.Duration) {
+	base, max = time.Second, 30*time.Second
+	if d, err := time.ParseDuration(r.BaseDelay); err == nil && d > 0 {
+		base = d
+	}
+	if d, err := time.ParseDuration(r.MaxDelay); err == nil && d > 0 {
+		max = d
+	}
+	return base, max
+}
+
+// ResolvedMaxConcurrent returns max_concurrent with default 10, 0 when unlimited.
+func (r GmailRetryConfig) ResolvedMaxConcurrent() int {
+	switch {
+	case r.MaxConcurrent == 0:
+		return 10
+	case r.MaxConcurrent < 0:
+		return 0
+	}
+	return r.MaxConcurrent
+}
+
+func (r GmailRetryConfig) validate() error {
+	for field, v := range map[string]string{"base_delay": r.BaseDelay, "max_delay": r.MaxDelay} {
+		if v == "" {
+			continue
+		}
+		if d, err := time.ParseDuration(v); err != nil || d <= 0 {
+			return fmt.Errorf("gmail.retry.%s %q is not a positive duration", field, v)
+		}
+	}
+	if base, max := r.ResolvedDelays(); base > max {
+		return fmt.Errorf("gmail.retry.base_delay must not exceed max_delay")
+	}
+	return nil
+}
+
+// GmailQuotaConfig budgets the Gmail API units each account spends per day
+// (UTC), so one busy mailbox can't use up the project's quota.
+type GmailQuotaConfig struct {
+	DailyUnits int `yaml:"daily_units"` // per account; 0 = unlimited
+	ThrottleAt int `yaml:"throttle_at"` // percent of the budget where the relay slows down (default 80)
+}
+
+// ResolvedThrottleAt returns throttle_at with default 80.
+func (q GmailQuotaConfig) ResolvedThrottleAt() int {
+	if q.ThrottleAt == 0 {
+		return 80
+	}
+	return q.ThrottleAt
+}
+
+// ResolvedDailyQuota returns the daily unit budget for acc, 0 when unlimited.
+func (g GmailConfig) ResolvedDailyQuota(acc GmailAccountConf) int {
+	switch {
+	case acc.DailyQuota < 0:
+		return 0
+	case acc.DailyQuota > 0:
+		return acc.DailyQuota
+	}
+	return g.Quota.DailyUnits
+}
+
+// GmailTextExtractionConfig is the service that turns attachments into text
+// for rules with action.extract_text, e.g. an Apache Tika server.
+type GmailTextExtractionConfig struct {
+	URL       string            `yaml:"url"`
+	Headers   map[string]string `yaml:"headers"`    // sent with every request, e.g. Authorization
+	Timeout   string            `yaml:"timeout"`    // per attachment (default 60s)
+	MimeTypes []string          `yaml:"mime_types"` // default application/pdf and image/*
+	MaxChars  int               `yaml:"max_chars"`  // text kept per message (default 20000)
+}
+
+// ResolvedTimeout returns timeout (default 60s).
+func (t GmailTextExtractionConfig) ResolvedTimeout() time.Duration {
+	if d, err := time.ParseDuration(t.Timeout); err == nil && d > 0 {
+		return d
+	}
+	return 60 * time.Second
+}
+
+// ResolvedMaxChars returns max_chars (default 20000).
+func (t GmailTextExtractionConfig) ResolvedMaxChars() int {
+	if t.MaxChars > 0 {
+		return t.MaxChars
+	}
+	return 20000
+}
+
+// GmailAttachmentsConfig screens attachments before the API or a job
+// exposes them.
+type GmailAttachmentsConfig struct {
+	MaxSizeMB      int      `yaml:"max_size_mb"`     // default 25; -1 = no cap
+	DenyExtensions []string `yaml:"deny_extensions"` // unset = built-in list; [] = none
+	ClamAV         string   `yaml:"clamav"`          // clamd host:port or unix socket path
+	ClamAVTimeout  string   `yaml:"clamav_timeout"`
+}
+
+// ResolvedMaxBytes returns max_size_mb in bytes (default 25 MB, Gmail's own
+// limit); 0 means no cap.
+func (a GmailAttachmentsConfig) ResolvedMaxBytes() int64 {
+	switch {
+	case a.MaxSizeMB < 0:
+		return 0
+	case a.MaxSizeMB == 0:
+		return 25 << 20
+	}
+	return int64(a.MaxSizeMB) << 20
+}
+
+// ResolvedDenyExtensions returns deny_extensions lower-cased with a leading
+// dot, or nil when unset so the caller applies its default list.
+func (a GmailAttachmentsConfig) ResolvedDenyExtensions() []string {
+	if a.DenyExtensions == nil {
+		return nil
+	}
+	out := make([]string, 0, len(a.DenyExtensions))
+	for _, ext := range a.DenyExtensions {
+		out = append(out, "."+strings.TrimPrefix(strings.ToLower(strings.TrimSpace(ext)), "."))
+	}
+	return out
+}
+
+// ResolvedClamAVTimeout returns clamav_timeout (default 30s).
+func (a GmailAttachmentsConfig) ResolvedClamAVTimeout() time.Duration {
+	if d, err := time.ParseDuration(a.ClamAVTimeout); err == nil && d > 0 {
+		return d
+	}
+	return 30 * time.Second
+}
+
+// ResolvedAPICache returns api_cache, or 0 when unset (no cache).
+func (g GmailConfig) ResolvedAPICache() time.Duration {
+	if d, err := time.ParseDuration(g.APICache); err == nil && d > 0 {
+		return d
+	}
+	return 0
+}
+
+// ResolvedHistoryLimits returns max_history_pages (default 10) and
+// max_history_messages (default 500). A negative value disables the cap.
+func (g GmailConfig) ResolvedHistoryLimits() (pages, messages int) {
+	pages, messages = g.MaxHistoryPages, g.MaxHistoryMessages
+	if pages == 0 {
+		pages = 10
+	}
+	if messages == 0 {
+		messages = 500
+	}
+	if pages < 0 {
+		pages = 0
+	}
+	if messages < 0 {
+		messages = 0
+	}
+	return pages, messages
 }
 
 type GmailAuthAlertConfig struct {
@@ -43,31 +335,289 @@ type GmailAuthAlertConfig struct {
 	MessageTemplate string `yaml:"message_template"`
 }
 
+// GmailWatchdogConfig alerts on (and optionally restarts) pollers that stopped completing polls.
+type GmailWatchdogConfig struct {
+	StallIntervals  int    `yaml:"stall_intervals"` // default 3
+	Restart         bool   `yaml:"restart"`
+	Alert           bool   `yaml:"alert"`
+	AgentID         string `yaml:"agent_id"`
+	Timeout         int    `yaml:"timeout"`
+	Delay           int    `yaml:"delay"`
+	MessageTemplate string `yaml:"message_template"`
+}
+
 type GmailAccountConf struct {
-	Email        string      `yaml:"email"`
-	PollInterval string      `yaml:"poll_interval"`
-	Rules        []GmailRule `yaml:"rules"`
+	Email        string              `yaml:"email"`
+	Provider     string              `yaml:"provider"` // gmail (default) or imap
+	PollInterval string              `yaml:"poll_interval"`
+	DailyQuota   int                 `yaml:"daily_quota"` // overrides gmail.quota.daily_units; -1 = unlimited
+	IMAP         MailIMAPConfig      `yaml:"imap"`        // provider: imap
+	Rules        RuleList[GmailRule] `yaml:"rules"`
+}
+
+// Mail providers of a gmail.accounts entry.
+const (
+	ProviderGmail = "gmail"
+	ProviderIMAP  = "imap"
+)
+
+// IsIMAP reports whether the account is read over IMAP instead of the Gmail API.
+func (a GmailAccountConf) IsIMAP() bool {
+	return a.Provider == ProviderIMAP
+}
+
+// MailIMAPConfig is the IMAP mailbox, and the SMTP server forward actions
+// send through, of an account with provider: imap. Both are reached over
+// TLS. See docs/gmail-api.md#imap-accounts.
+type MailIMAPConfig struct {
+	Host           string `yaml:"host"`
+	Port           int    `yaml:"port"`     // default 993
+	Username       string `yaml:"username"` // default the account email
+	Password       string `yaml:"password"`
+	Mailbox        string `yaml:"mailbox"`         // polled for new mail (default INBOX)
+	ArchiveMailbox string `yaml:"archive_mailbox"` // where archive moves mail (default Archive)
+
+	SMTP MailSMTPConfig `yaml:"smtp"`
+}
+
+// MailSMTPConfig is the SMTP submission server of an IMAP account. Port 465
+// uses TLS from the start, any other STARTTLS.
+type MailSMTPConfig struct {
+	Host     string `yaml:"host"`
+	Port     int    `yaml:"port"`     // default 465
+	Username string `yaml:"username"` // default imap.username
+	Password string `yaml:"password"` // default imap.password
+	From     string `yaml:"from"`     // default the account email
+}
+
+// Resolved returns the config with defaults filled in for the account email.
+func (m MailIMAPConfig) Resolved(email string) MailIMAPConfig {
+	if m.Port == 0 {
+		m.Port = 993
+	}
+	if m.Username == "" {
+		m.Username = email
+	}
+	if m.Mailbox == "" {
+		m.Mailbox = "INBOX"
+	}
+	if m.ArchiveMailbox == "" {
+		m.ArchiveMailbox = "Archive"
+	}
+	if m.SMTP.Host != "" {
+		if m.SMTP.Port == 0 {
+			m.SMTP.Port = 465
+		}
+		if m.SMTP.Username == "" {
+			m.SMTP.Username = m.Username
+		}
+		if m.SMTP.Password == "" {
+			m.SMTP.Password = m.Password
+		}
+		if m.SMTP.From == "" {
+			m.SMTP.From = email
+		}
+	}
+	return m
+}
+
+func (a GmailAccountConf) validateProvider(path string) error {
+	switch a.Provider {
+	case "", ProviderGmail:
+		if a.IMAP.Host != "" {
+			return fmt.Errorf("%s.imap needs provider: imap", path)
+		}
+		return nil
+	case ProviderIMAP:
+	default:
+		return fmt.Errorf("%s.provider %q must be gmail or imap", path, a.Provider)
+	}
+	m := a.IMAP
+	if m.Host == "" || m.Password == "" {
+		return fmt.Errorf("%s.imap needs host and password", path)
+	}
+	if m.Port < 0 || m.Port > 65535 || m.SMTP.Port < 0 || m.SMTP.Port > 65535 {
+		return fmt.Errorf("%s.imap: port out of range", path)
+	}
+	for j, rule := range a.Rules {
+		for _, e := range rule.Match.Events {
+			if e != "message_added" {
+				return fmt.Errorf("%s.rules[%d].match.events: IMAP accounts only have message_added", path, j)
+			}
+		}
+		for _, act := range rule.ResolvedActions() {
+			if act.Kind == "forward" && m.SMTP.Host == "" {
+				return fmt.Errorf("%s.rules[%d]: kind forward needs imap.smtp.host", path, j)
+			}
+		}
+	}
+	return nil
 }
 
 type GmailRule struct {
-	Name   string      `yaml:"name"`
-	Match  GmailMatch  `yaml:"match"`
-	Action GmailAction `yaml:"action"`
+	Name     string        `yaml:"name"`
+	Match    GmailMatch    `yaml:"match"`
+	Sample   Sample        `yaml:"sample"`
+	Continue bool          `yaml:"continue"` // keep evaluating later rules after this one matches
+	Action   GmailAction   `yaml:"action"`
+	Actions  []GmailAction `yaml:"-"` // action given as a list: one job per entry
+}
+
+// UnmarshalYAML lets action be a list of actions.
+func (r *GmailRule) UnmarshalYAML(node *yaml.Node) error {
+	type plain GmailRule
+	return decodeActionList(node, (*plain)(r), &r.Actions)
+}
+
+// ResolvedActions returns actions, or action alone when actions is empty.
+func (r GmailRule) ResolvedActions() []GmailAction {
+	if len(r.Actions) > 0 {
+		return r.Actions
+	}
+	return []GmailAction{r.Action}
 }
 
 type GmailMatch struct {
-	From   []string `yaml:"from"`
-	Labels []string `yaml:"labels"`
-	Query  string   `yaml:"query"`
+	// Events are the history events the rule fires on, from GmailEvents
+	// (default message_added).
+	Events []string `yaml:"events"`
+	// ChangedLabels matches label_added and label_removed events that added
+	// or removed any of these labels.
+	ChangedLabels []string `yaml:"changed_labels"`
+	From          []string `yaml:"from"`
+	To            []string `yaml:"to"` // any recipient matches, same patterns as from
+	Cc            []string `yaml:"cc"`
+	Labels        []string `yaml:"labels"`
+	// Subject patterns: a case-insensitive glob (* and ?) over the whole
+	// subject, or a regular expression written as /expr/.
+	Subject []string `yaml:"subject"`
+	// BodyContains matches when the plain-text body contains any of the
+	// strings (case-insensitive). The body is fetched only for such rules.
+	BodyContains []string `yaml:"body_contains"`
+	// Exclusions: a message matching any of them never matches the rule.
+	ExcludeFrom   []string `yaml:"exclude_from"`   // same patterns as from
+	ExcludeLabels []string `yaml:"exclude_labels"` // any label present excludes
+	NotQuery      string   `yaml:"not_query"`      // Gmail search query, e.g. "category:promotions"
+	Query         string   `yaml:"query"`          // Gmail search query the message must match, e.g. "has:attachment larger:5M"
+	Condition     string   `yaml:"condition"`      // expression over from, to, cc, subject, snippet, labels, account, event, changed_labels
+}
+
+// GmailEvents are the valid values of a Gmail rule's match.events.
+var GmailEvents = []string{"message_added", "label_added", "label_removed", "message_deleted"}
+
+// ResolvedEvents returns events, or message_added when empty.
+func (m GmailMatch) ResolvedEvents() []string {
+	if len(m.Events) == 0 {
+		return []string{"message_added"}
+	}
+	return m.Events
+}
+
+// HistoryEvents returns the history events any of the account's rules fire
+// on, in GmailEvents order.
+func (a GmailAccountConf) HistoryEvents() []string {
+	var events []string
+	for _, e := range GmailEvents {
+		for _, r := range a.Rules {
+			if slices.Contains(r.Match.ResolvedEvents(), e) {
+				events = append(events, e)
+				break
+			}
+		}
+	}
+	return events
+}
+
+var patternCache sync.Map // pattern -> *regexp.Regexp
+
+// SubjectRegexp compiles a match.subject pattern: /expr/ is a regular
+// expression, anything else a case-insensitive glob over the whole subject.
+// Patterns are compiled once, when the config is validated, and cached.
+func SubjectRegexp(pattern string) (*regexp.Regexp, error) {
+	if re, ok := patternCache.Load(pattern); ok {
+		return re.(*regexp.Regexp), nil
+	}
+	var re *regexp.Regexp
+	var err error
+	if len(pattern) >= 2 && strings.HasPrefix(pattern, "/") && strings.HasSuffix(pattern, "/") {
+		re, err = regexp.Compile(pattern[1 : len(pattern)-1])
+	} else {
+		glob := regexp.QuoteMeta(pattern)
+		glob = strings.NewReplacer(`\*`, ".*", `\?`, ".").Replace(glob)
+		re, err = regexp.Compile("(?is)^" + glob + "$")
+	}
+	if err != nil {
+		return nil, err
+	}
+	patternCache.Store(pattern, re)
+	return re, nil
+}
+
+// MatchPattern reports whether any of the SubjectRegexp patterns matches s,
+// as match.subject (Gmail), title (Calendar) and mime_types (Drive) do. An
+// invalid pattern, which validation refuses, never matches.
+func MatchPattern(patterns []string, s string) bool {
+	for _, pattern := range patterns {
+		re, err := SubjectRegexp(pattern)
+		if err != nil {
+			log.Printf("Invalid pattern %q: %v", pattern, err)
+			continue
+		}
+		if re.MatchString(s) {
+			return true
+		}
+	}
+	return false
+}
+
+// MatchAddress reports whether any of the patterns of a match.from-style
+// list matches addr: a pattern starting with * matches as a suffix, anything
+// else as a substring (case-insensitive).
+func MatchAddress(patterns []string, addr string) bool {
+	addr = strings.ToLower(addr)
+	for _, pattern := range patterns {
+		pattern = strings.ToLower(pattern)
+		if suffix, ok := strings.CutPrefix(pattern, "*"); ok {
+			if strings.HasSuffix(addr, suffix) {
+				return true
+			}
+		} else if strings.Contains(addr, pattern) {
+			return true
+		}
+	}
+	return false
 }
 
 type GmailAction struct {
-	// Cron-style action (flat format, like Trello rules)
+	// Kind is cron (the default for flat actions), modify, forward or
+	// agent_turn, which is a cron job with the full message body.
 	Kind            string `yaml:"kind"`
 	AgentID         string `yaml:"agent_id"`
 	Timeout         int    `yaml:"timeout"`
 	Delay           int    `yaml:"delay"`
+	Schedule        string `yaml:"schedule"` // e.g. "tomorrow 9am"; overrides delay
+	Timezone        string `yaml:"timezone"` // IANA zone for schedule; default server.timezone
 	MessageTemplate string `yaml:"message_template"`
+	// MessageTemplates are per-language variants of message_template, keyed by
+	// ISO 639-1 code (e.g. ru, de); the detected language picks one
+	MessageTemplates map[string]string `yaml:"message_templates"`
+
+	Tags map[string]string `yaml:"tags"` // job tags; override gateway.tags
+
+	// ExtractText fills {{.AttachmentText}} with text extracted from the
+	// message's PDF and image attachments (needs gmail.text_extraction)
+	ExtractText bool `yaml:"extract_text"`
+
+	// kind: modify changes the matched message's labels; labels are IDs as in
+	// match.labels (system labels like STARRED, or Label_... from /api/gmail/labels)
+	AddLabels    []string `yaml:"add_labels"`
+	RemoveLabels []string `yaml:"remove_labels"`
+	Archive      bool     `yaml:"archive"`   // remove INBOX
+	MarkRead     bool     `yaml:"mark_read"` // remove UNREAD
+
+	// kind: forward sends the message on to these addresses; message_template,
+	// when set, is a note above the forwarded text
+	To []string `yaml:"to"`
 
 	// Legacy notify sub-action (kept for backward compat)
 	Notify *GmailNotifyAction `yaml:"notify"`
@@ -84,6 +634,20 @@ func (a GmailAction) ResolvedTemplate() string {
 	return ""
 }
 
+// LocalizedTemplate returns the variant of the message template for lang,
+// else ResolvedTemplate.
+func (a GmailAction) LocalizedTemplate(lang string) string {
+	if t := a.MessageTemplates[lang]; lang != "" && t != "" {
+		return t
+	}
+	if a.MessageTemplate == "" && a.Notify != nil {
+		if t := a.Notify.Templates[lang]; lang != "" && t != "" {
+			return t
+		}
+	}
+	return a.ResolvedTemplate()
+}
+
 // ResolvedAgentID returns agent_id from either flat or notify format.
 func (a GmailAction) ResolvedAgentID() string {
 	if a.AgentID != "" {
@@ -109,8 +673,63 @@ func (a GmailAction) ResolvedDelay() int {
 }
 
 // IsCron returns true if this is a direct cron-style action (not legacy notify).
+// agent_turn actions are cron jobs too.
 func (a GmailAction) IsCron() bool {
-	return a.Kind == "cron" || a.MessageTemplate != ""
+	switch a.Kind {
+	case "cron", "agent_turn":
+		return true
+	case "":
+		return a.MessageTemplate != "" || len(a.MessageTemplates) > 0
+	}
+	return false
+}
+
+// GmailActionKinds are the valid values of a Gmail rule's action.kind.
+var GmailActionKinds = []string{"cron", "modify", "forward", "agent_turn"}
+
+func (m GmailMatch) validate(path string) error {
+	for _, e := range m.Events {
+		if !slices.Contains(GmailEvents, e) {
+			return fmt.Errorf("%s.events %q must be one of %s", path, e, strings.Join(GmailEvents, ", "))
+		}
+	}
+	if len(m.ChangedLabels) > 0 && !slices.Contains(m.Events, "label_added") && !slices.Contains(m.Events, "label_removed") {
+		return fmt.Errorf("%s.changed_labels needs events label_added or label_removed", path)
+	}
+	return nil
+}
+
+func (a GmailAction) validate(path string) error {
+	if a.Kind != "" && !slices.Contains(GmailActionKinds, a.Kind) {
+		return fmt.Errorf("%s.kind %q must be one of %s", path, a.Kind, strings.Join(GmailActionKinds, ", "))
+	}
+	modifies := len(a.AddLabels) > 0 || len(a.RemoveLabels) > 0 || a.Archive || a.MarkRead
+	if a.Kind == "modify" && !modifies {
+		return fmt.Errorf("%s: kind modify needs add_labels, remove_labels, archive or mark_read", path)
+	}
+	if a.Kind != "modify" && modifies {
+		return fmt.Errorf("%s: add_labels, remove_labels, archive and mark_read need kind modify", path)
+	}
+	if a.Kind == "forward" && len(a.To) == 0 {
+		return fmt.Errorf("%s.to is required for kind forward", path)
+	}
+	if a.Kind != "forward" && len(a.To) > 0 {
+		return fmt.Errorf("%s.to needs kind forward", path)
+	}
+	for i, to := range a.To {
+		if _, err := mail.ParseAddress(to); err != nil || strings.ContainsAny(to, "\r\n") {
+			return fmt.Errorf("%s.to[%d] %q is not an email address", path, i, to)
+		}
+	}
+	if n := a.Notify; n != nil {
+		if n.MaxBodyChars < 0 {
+			return fmt.Errorf("%s.notify.max_body_chars must not be negative", path)
+		}
+		if n.MaxBodyChars > 0 && !n.IncludeBody {
+			return fmt.Errorf("%s.notify.max_body_chars needs include_body", path)
+		}
+	}
+	return nil
 }
 
 type GmailNotifyAction struct {
@@ -118,114 +737,2258 @@ type GmailNotifyAction struct {
 	Channel  string `yaml:"channel"`
 	Template string `yaml:"template"`
 	AgentID  string `yaml:"agent_id"` // optional: which agent sends the notification (default: global)
-}
 
-type ServerConfig struct {
-	Port          int    `yaml:"port"`
-	InternalToken string `yaml:"internal_token"`
+	Templates map[string]string `yaml:"templates"` // per-language variants of template, keyed by ISO 639-1 code
+
+	IncludeBody  bool `yaml:"include_body"`   // fetch the message and expose its plain-text body as .Body
+	MaxBodyChars int  `yaml:"max_body_chars"` // characters of .Body kept (default 1000)
 }
 
-type GatewayConfig struct {
-	URL     string `yaml:"url"`
-	Token   string `yaml:"token"`
-	AgentID string `yaml:"agent_id"`
-	Model   string `yaml:"model"`
+// ResolvedMaxBodyChars returns max_body_chars (default 1000).
+func (n GmailNotifyAction) ResolvedMaxBodyChars() int {
+	if n.MaxBodyChars > 0 {
+		return n.MaxBodyChars
+	}
+	return 1000
 }
 
-type TrelloConfig struct {
-	Secret        string            `yaml:"secret"`
-	Lists         map[string]string `yaml:"lists"`
-	IgnoreMembers []string          `yaml:"ignore_members"` // member IDs or usernames to ignore (e.g. bot accounts)
-	Rules         []TrelloRule      `yaml:"rules"`
+// LocalizedTemplate returns the template variant for lang, else template.
+func (n GmailNotifyAction) LocalizedTemplate(lang string) string {
+	if t := n.Templates[lang]; lang != "" && t != "" {
+		return t
+	}
+	return n.Template
 }
 
-type TrelloRule struct {
-	Event     string     `yaml:"event"`
-	Condition string     `yaml:"condition"`
-	Action    RuleAction `yaml:"action"`
+type ServerConfig struct {
+	Port          int            `yaml:"port"`
+	InternalToken string         `yaml:"internal_token"`
+	MaxInFlight   map[string]int `yaml:"max_in_flight"`  // per webhook source (trello, github, slack, jira, custom); 0 = unlimited
+	InFlightWait  string         `yaml:"in_flight_wait"` // how long a delivery waits for a slot before 503 (default 5s)
+	EventTTL      string         `yaml:"event_ttl"`      // max event age before a delivery or replay is dropped; empty = no limit
+	Timezone      string         `yaml:"timezone"`       // default IANA zone for action schedules (default UTC)
+	RateLimit     string         `yaml:"rate_limit"`     // default dedup window for webhook events (default 5m)
+
+	// DeliveryHistory is how many GitHub and Trello delivery IDs are kept to
+	// drop redeliveries (default 5000)
+	DeliveryHistory int `yaml:"delivery_history"`
+
+	// EventHistory is how many webhook deliveries are kept in
+	// data/history.json for GET /api/events (default 1000)
+	EventHistory int `yaml:"event_history"`
+
+	// DevSkipSignatures skips webhook signature checks for requests made directly
+	// from a loopback address. For local development only.
+	DevSkipSignatures bool `yaml:"dev_skip_signatures"`
+
+	// Strict refuses to start when a configured feature can't run, e.g.
+	// Gmail without RELAY_ENCRYPTION_KEY, instead of starting without it.
+	Strict bool `yaml:"strict"`
+
+	IPAllowlist IPAllowlistConfig `yaml:"ip_allowlist"`
+	ClockSkew   ClockSkewConfig   `yaml:"clock_skew"`
+
+	// CORS lets browser apps on other origins call the API, e.g. a separately
+	// hosted dashboard. The first rule whose path prefix matches applies.
+	CORS []CORSRule `yaml:"cors"`
+
+	// APIKeys are tokens for less-trusted agents, accepted in X-Relay-Token
+	// in place of internal_token. A key only reaches the Gmail API, narrowed
+	// by its gmail scope.
+	APIKeys []APIKey `yaml:"api_keys"`
 }
 
-type RuleAction struct {
-	Kind            string `yaml:"kind"`
-	Timeout         int    `yaml:"timeout"`
-	Delay           int    `yaml:"delay"`
-	AgentID         string `yaml:"agent_id"`
-	MessageTemplate string `yaml:"message_template"`
+// APIKey is a restricted token for the Gmail API.
+type APIKey struct {
+	Name  string        `yaml:"name"` // shown in logs and errors
+	Key   string        `yaml:"key"`
+	Gmail GmailKeyScope `yaml:"gmail"`
 }
 
-type GitHubConfig struct {
-	Secret          string `yaml:"secret"`
-	NotifyMode      string `yaml:"notify_mode"` // "all" (default) or "failures"
-	MessageTemplate string `yaml:"message_template"`
-	AgentID         string `yaml:"agent_id"`
-	Timeout         int    `yaml:"timeout"`
-	Delay           int    `yaml:"delay"`
+// GmailKeyScope narrows what an API key can do through /api/gmail/*.
+type GmailKeyScope struct {
+	Accounts []string `yaml:"accounts"` // accounts the key may use; empty = all
+	Query    string   `yaml:"query"`    // Gmail search every message read or modified must match; empty = any
+	Labels   []string `yaml:"labels"`   // label IDs the key may add or remove; empty = no modify
+	Drafts   bool     `yaml:"drafts"`   // may list, create, update and delete drafts
 }
 
-type AuditConfig struct {
-	LogPath string `yaml:"log_path"`
+// AllowsAccount reports whether the key may use the account.
+func (s GmailKeyScope) AllowsAccount(email string) bool {
+	return len(s.Accounts) == 0 || slices.Contains(s.Accounts, email)
 }
 
-var envRegex = regexp.MustCompile(`\$\{([^}]+)\}`)
+// AllowsLabel reports whether the key may add or remove the label.
+func (s GmailKeyScope) AllowsLabel(label string) bool {
+	return slices.Contains(s.Labels, label)
+}
 
-func envSubst(s string) string {
-	return envRegex.ReplaceAllStringFunc(s, func(match string) string {
-		key := envRegex.FindStringSubmatch(match)[1]
-		if v := os.Getenv(key); v != "" {
-			return v
-		}
-		return match
-	})
+// CORSRule is the CORS policy for one path prefix.
+type CORSRule struct {
+	Path        string   `yaml:"path"`        // path prefix (default /api/)
+	Origins     []string `yaml:"origins"`     // e.g. https://dash.example.com, or "*"
+	Headers     []string `yaml:"headers"`     // request headers allowed (default X-Relay-Token, Content-Type)
+	Methods     []string `yaml:"methods"`     // default GET, POST, PUT, PATCH, DELETE
+	Credentials bool     `yaml:"credentials"` // allow the session cookie; not with "*"
+	MaxAge      string   `yaml:"max_age"`     // how long browsers cache a preflight (default 10m)
 }
 
-func Load(path string) (*Config, error) {
-	data, err := os.ReadFile(path)
-	if err != nil {
-		return nil, err
+// ResolvedPath returns path with default /api/.
+func (c CORSRule) ResolvedPath() string {
+	if c.Path == "" {
+		return "/api/"
 	}
-	expanded := envSubst(string(data))
-	var cfg Config
-	if err := yaml.Unmarshal([]byte(expanded), &cfg); err != nil {
-		return nil, err
+	return c.Path
+}
+
+// ResolvedHeaders returns headers with default X-Relay-Token, Content-Type.
+func (c CORSRule) ResolvedHeaders() []string {
+	if len(c.Headers) == 0 {
+		return []string{"X-Relay-Token", "Content-Type"}
 	}
-	if cfg.Server.Port == 0 {
-		cfg.Server.Port = 8080
+	return c.Headers
+}
+
+// ResolvedMethods returns methods with default GET, POST, PUT, PATCH, DELETE.
+func (c CORSRule) ResolvedMethods() []string {
+	if len(c.Methods) == 0 {
+		return []string{"GET", "POST", "PUT", "PATCH", "DELETE"}
 	}
-	if cfg.Gateway.AgentID == "" {
-		cfg.Gateway.AgentID = "main"
+	return c.Methods
+}
+
+// ResolvedMaxAge returns max_age with default 10m.
+func (c CORSRule) ResolvedMaxAge() time.Duration {
+	if d, err := time.ParseDuration(c.MaxAge); err == nil && d >= 0 {
+		return d
 	}
-	if cfg.Audit.LogPath == "" {
-		cfg.Audit.LogPath = "data/audit.log"
+	return 10 * time.Minute
+}
+
+// ClockSkewConfig bounds the timestamps of signed Slack and Discord requests.
+type ClockSkewConfig struct {
+	Tolerance string `yaml:"tolerance"` // max distance from the local clock (default 5m)
+	Warn      string `yaml:"warn"`      // log a warning above this skew (default 1m)
+}
+
+// ResolvedTolerance returns tolerance with default 5m.
+func (c ClockSkewConfig) ResolvedTolerance() time.Duration {
+	if d, err := time.ParseDuration(c.Tolerance); err == nil && d > 0 {
+		return d
 	}
-	return &cfg, nil
+	return 5 * time.Minute
 }
 
-// Validate checks config for common misconfigurations.
-func (c *Config) Validate() error {
-	hasRules := len(c.Trello.Rules) > 0 || c.GitHub.Secret != "" || c.Gmail.Enabled
-	if hasRules && c.Gateway.URL == "" {
-		return fmt.Errorf("gateway.url is required when trello/github/gmail rules are configured")
+// ResolvedWarn returns warn with default 1m.
+func (c ClockSkewConfig) ResolvedWarn() time.Duration {
+	if d, err := time.ParseDuration(c.Warn); err == nil && d > 0 {
+		return d
 	}
+	return time.Minute
+}
 
-	if c.Gmail.Enabled {
-		allowedSet := make(map[string]bool, len(c.Google.AllowedEmails))
+// IPAllowlistConfig restricts /webhook/* to the address ranges providers send
+// from. Ranges are CIDRs or single addresses.
+type IPAllowlistConfig struct {
+	Default        []string            `yaml:"default"`         // ranges for sources without their own; empty leaves them open
+	Sources        map[string][]string `yaml:"sources"`         // per webhook source: trello, github, slack, custom, ...
+	GitHubMeta     bool                `yaml:"github_meta"`     // add GitHub's hook ranges from GET /meta to github
+	Refresh        string              `yaml:"refresh"`         // how often github_meta ranges are re-fetched (default 24h)
+	TrustedProxies []string            `yaml:"trusted_proxies"` // proxies whose X-Forwarded-For names the client
+}
+
+// Enabled reports whether any webhook source is restricted.
+func (a IPAllowlistConfig) Enabled() bool {
+	return len(a.Default) > 0 || len(a.Sources) > 0 || a.GitHubMeta
+}
+
+// ResolvedRefresh returns refresh with default 24h.
+func (a IPAllowlistConfig) ResolvedRefresh() time.Duration {
+	if d, err := time.ParseDuration(a.Refresh); err == nil && d > 0 {
+		return d
+	}
+	return 24 * time.Hour
+}
+
+// ResolvedEventTTL returns event_ttl, or 0 when unset (no expiry).
+func (s ServerConfig) ResolvedEventTTL() time.Duration {
+	if d, err := time.ParseDuration(s.EventTTL); err == nil && d > 0 {
+		return d
+	}
+	return 0
+}
+
+// ResolvedRateLimit returns rate_limit with default 5m.
+func (s ServerConfig) ResolvedRateLimit() time.Duration {
+	if d, err := time.ParseDuration(s.RateLimit); err == nil && d > 0 {
+		return d
+	}
+	return 5 * time.Minute
+}
+
+// RateWindow returns the first set window of a rule's and its source's
+// rate_limit, or 0 for the limiter default (server.rate_limit).
+func RateWindow(windows ...string) time.Duration {
+	for _, w := range windows {
+		if d, err := time.ParseDuration(w); err == nil && d > 0 {
+			return d
+		}
+	}
+	return 0
+}
+
+// ResolvedInFlightWait returns in_flight_wait with default 5s.
+func (s ServerConfig) ResolvedInFlightWait() time.Duration {
+	if d, err := time.ParseDuration(s.InFlightWait); err == nil && d > 0 {
+		return d
+	}
+	return 5 * time.Second
+}
+
+type GatewayConfig struct {
+	URL     string `yaml:"url"`
+	Token   string `yaml:"token"`
+	AgentID string `yaml:"agent_id"`
+	Model   string `yaml:"model"`
+
+	// SigningSecret signs job requests (X-Relay-Signature) so the gateway can
+	// verify they come from the relay; shared with the gateway
+	SigningSecret string `yaml:"signing_secret"`
+
+	// Control channel: the relay long-polls the gateway for commands
+	Control GatewayControlConfig `yaml:"control"`
+
+	// JobName is a Go template for job names with .Name, .Source, .Rule,
+	// .Agent and .Tags (default "webhook: {{.Name}}")
+	JobName string `yaml:"job_name"`
+	// Tags are added to every job, e.g. tenant: acme
+	Tags map[string]string `yaml:"tags"`
+
+	// Jobs the gateway does not accept are kept in data/deadletter.json and retried
+	DeadLetter GatewayDeadLetterConfig `yaml:"dead_letter"`
+
+	// Heartbeat periodically tells the agent the relay is alive
+	Heartbeat GatewayHeartbeatConfig `yaml:"heartbeat"`
+}
+
+// GatewayHeartbeatConfig is a periodic job confirming the relay is alive and
+// summarizing its pending work, so the agent can notice when it goes quiet.
+// With channel and target the agent just relays the message there.
+type GatewayHeartbeatConfig struct {
+	Interval        string `yaml:"interval"` // e.g. 6h; empty disables the heartbeat
+	AgentID         string `yaml:"agent_id"`
+	Timeout         int    `yaml:"timeout"` // default 60
+	Channel         string `yaml:"channel"`
+	Target          string `yaml:"target"`
+	MessageTemplate string `yaml:"message_template"`
+}
+
+// ResolvedInterval returns interval, or 0 when the heartbeat is off.
+func (c GatewayHeartbeatConfig) ResolvedInterval() time.Duration {
+	d, _ := time.ParseDuration(c.Interval)
+	return max(d, 0)
+}
+
+// ResolvedTimeout returns timeout with default 60.
+func (c GatewayHeartbeatConfig) ResolvedTimeout() int {
+	if c.Timeout > 0 {
+		return c.Timeout
+	}
+	return 60
+}
+
+type GatewayDeadLetterConfig struct {
+	RetryInterval string `yaml:"retry_interval"` // first retry delay, doubling up to 1h (default 1m)
+	MaxAttempts   int    `yaml:"max_attempts"`   // attempts before retries stop (default 10)
+}
+
+// ResolvedRetryInterval returns retry_interval with default 1m.
+func (c GatewayDeadLetterConfig) ResolvedRetryInterval() time.Duration {
+	if d, err := time.ParseDuration(c.RetryInterval); err == nil && d > 0 {
+		return d
+	}
+	return time.Minute
+}
+
+// ResolvedMaxAttempts returns max_attempts with default 10.
+func (c GatewayDeadLetterConfig) ResolvedMaxAttempts() int {
+	if c.MaxAttempts > 0 {
+		return c.MaxAttempts
+	}
+	return 10
+}
+
+type GatewayControlConfig struct {
+	Enabled bool   `yaml:"enabled"`
+	Wait    string `yaml:"wait"` // how long one poll may be held open (default 30s)
+}
+
+// ResolvedWait returns wait with default 30s.
+func (c GatewayControlConfig) ResolvedWait() time.Duration {
+	if d, err := time.ParseDuration(c.Wait); err == nil && d > 0 {
+		return d
+	}
+	return 30 * time.Second
+}
+
+type TrelloConfig struct {
+	Secret        string               `yaml:"secret"`
+	Lists         map[string]string    `yaml:"lists"`
+	ListNames     map[string]string    `yaml:"list_names"`     // alias -> list name on trello.boards, resolved to IDs via the API
+	IgnoreMembers []string             `yaml:"ignore_members"` // member IDs or usernames to ignore (e.g. bot accounts)
+	Rules         RuleList[TrelloRule] `yaml:"rules"`
+	RateLimit     string               `yaml:"rate_limit"` // dedup window for this source; default server.rate_limit
+
+	// REST API credentials for /api/trello/*; both are required to enable it
+	APIKey string `yaml:"api_key"`
+	Token  string `yaml:"token"`
+	APIURL string `yaml:"api_url"` // default https://api.trello.com
+
+	// Boards to register webhooks for on startup (and delete on shutdown)
+	Boards      []string `yaml:"boards"`
+	CallbackURL string   `yaml:"callback_url"` // public URL of /webhook/trello
+
+	// How often list, label and member names of the boards are re-fetched for templates (default 15m)
+	BoardRefresh string `yaml:"board_refresh"`
+
+	// List aliases whose cards are watched: comments on a card get its list
+	// while it stays there, since comment payloads carry none
+	WatchLists []string `yaml:"watch_lists"`
+	WatchTTL   string   `yaml:"watch_ttl"` // how long a watch lasts without a move out (default 720h)
+}
+
+// ResolvedWatchTTL returns watch_ttl with default 720h.
+func (t TrelloConfig) ResolvedWatchTTL() time.Duration {
+	if d, err := time.ParseDuration(t.WatchTTL); err == nil && d > 0 {
+		return d
+	}
+	return 720 * time.Hour
+}
+
+// ResolvedBoardRefresh returns board_refresh with default 15m.
+func (t TrelloConfig) ResolvedBoardRefresh() time.Duration {
+	if d, err := time.ParseDuration(t.BoardRefresh); err == nil && d > 0 {
+		return d
+	}
+	return 15 * time.Minute
+}
+
+type TrelloRule struct {
+	Event     string       `yaml:"event"`
+	Condition string       `yaml:"condition"`
+	Sample    Sample       `yaml:"sample"`
+	RateLimit string       `yaml:"rate_limit"` // dedup window for this rule; default the source's rate_limit
+	DedupKey  string       `yaml:"dedup_key"`  // template over the message data, e.g. "{{.CardID}}"; default card and action type
+	Continue  bool         `yaml:"continue"`   // keep evaluating later rules after this one matches
+	Action    RuleAction   `yaml:"action"`
+	Actions   []RuleAction `yaml:"-"` // action given as a list: one job per entry
+}
+
+// UnmarshalYAML lets action be a list of actions.
+func (r *TrelloRule) UnmarshalYAML(node *yaml.Node) error {
+	type plain TrelloRule
+	return decodeActionList(node, (*plain)(r), &r.Actions)
+}
+
+// ResolvedActions returns actions, or action alone when actions is empty.
+func (r TrelloRule) ResolvedActions() []RuleAction {
+	return resolveActions(r.Action, r.Actions)
+}
+
+// Sample is the fraction (0-1] of a rule's matching events that trigger its
+// action, for trialing noisy automations. Unset (0) means every event.
+type Sample float64
+
+// Keep reports whether an event with the random roll in [0, 1) is acted on.
+func (s Sample) Keep(roll float64) bool {
+	return s <= 0 || s >= 1 || roll < float64(s)
+}
+
+type RuleAction struct {
+	Kind            string `yaml:"kind"`
+	Timeout         int    `yaml:"timeout"`
+	Delay           int    `yaml:"delay"`
+	Schedule        string `yaml:"schedule"` // e.g. "next business day 9am"; overrides delay
+	Timezone        string `yaml:"timezone"` // IANA zone for schedule; default server.timezone
+	AgentID         string `yaml:"agent_id"`
+	MessageTemplate string `yaml:"message_template"`
+	// MessageTemplates are per-language variants of message_template, keyed by
+	// ISO 639-1 code; used by Trello rules, where the card's language picks one
+	MessageTemplates map[string]string `yaml:"message_templates"`
+
+	Tags map[string]string `yaml:"tags"` // job tags; override gateway.tags
+}
+
+// decodeActionList decodes a rule mapping into rule, except that an action
+// given as a list is decoded into actions instead of rule's action.
+func decodeActionList[T any](node *yaml.Node, rule any, actions *[]T) error {
+	if node.Kind == yaml.MappingNode {
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			if node.Content[i].Value != "action" || node.Content[i+1].Kind != yaml.SequenceNode {
+				continue
+			}
+			if err := node.Content[i+1].Decode(actions); err != nil {
+				return err
+			}
+			rest := *node
+			rest.Content = append(append([]*yaml.Node{}, node.Content[:i]...), node.Content[i+2:]...)
+			return rest.Decode(rule)
+		}
+	}
+	return node.Decode(rule)
+}
+
+// RuleList is a rules: list whose entries may also be rule groups:
+//
+//   - when: "repo in ['acme/api', 'acme/web']"
+//     rules:
+//   - event: workflow_run
+//   - event: pull_request_review
+//
+// A group's rules are flattened in place, with when ANDed into each member's
+// condition, so handlers and rules[N] positions see an ordinary list. Groups
+// may nest.
+type RuleList[T any] []T
+
+// conditioned is implemented by rule types with a condition a group's when
+// can be added to.
+type conditioned interface {
+	condition() *string
+}
+
+func (l *RuleList[T]) UnmarshalYAML(node *yaml.Node) error {
+	rules, err := decodeRuleList[T](node, "")
+	if err != nil {
+		return err
+	}
+	*l = rules
+	return nil
+}
+
+func decodeRuleList[T any](node *yaml.Node, when string) ([]T, error) {
+	if node.Kind != yaml.SequenceNode {
+		return nil, fmt.Errorf("line %d: rules must be a list", node.Line)
+	}
+	var out []T
+	for _, item := range node.Content {
+		if members, groupWhen, ok, err := ruleGroup(item); ok {
+			if err != nil {
+				return nil, err
+			}
+			rules, err := decodeRuleList[T](members, joinConditions(when, groupWhen))
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, rules...)
+			continue
+		}
+		var rule T
+		if err := item.Decode(&rule); err != nil {
+			return nil, err
+		}
+		if when != "" {
+			c, ok := any(&rule).(conditioned)
+			if !ok {
+				return nil, fmt.Errorf("line %d: these rules have no condition for a group's when", item.Line)
+			}
+			*c.condition() = joinConditions(when, *c.condition())
+		}
+		out = append(out, rule)
+	}
+	return out, nil
+}
+
+// ruleGroup reports whether node is a group (a mapping with when or rules)
+// and returns its member list and when condition.
+func ruleGroup(node *yaml.Node) (members *yaml.Node, when string, ok bool, err error) {
+	if node.Kind != yaml.MappingNode {
+		return nil, "", false, nil
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if k := node.Content[i].Value; k == "when" || k == "rules" {
+			ok = true
+		}
+	}
+	if !ok {
+		return nil, "", false, nil
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key, value := node.Content[i], node.Content[i+1]
+		switch key.Value {
+		case "when":
+			if err := value.Decode(&when); err != nil {
+				return nil, "", true, err
+			}
+		case "rules":
+			members = value
+		default:
+			return nil, "", true, fmt.Errorf("line %d: rule group has unknown field %q (a group has when and rules)", key.Line, key.Value)
+		}
+	}
+	if members == nil {
+		return nil, "", true, fmt.Errorf("line %d: rule group has no rules", node.Line)
+	}
+	return members, when, true, nil
+}
+
+// joinConditions ANDs two conditions; an empty one is left out.
+func joinConditions(a, b string) string {
+	a, b = strings.TrimSpace(a), strings.TrimSpace(b)
+	switch {
+	case a == "":
+		return b
+	case b == "":
+		return a
+	}
+	return "(" + a + ") && (" + b + ")"
+}
+
+func (r *GmailRule) condition() *string        { return &r.Match.Condition }
+func (r *TrelloRule) condition() *string       { return &r.Condition }
+func (r *GitHubRule) condition() *string       { return &r.Condition }
+func (r *NotionRule) condition() *string       { return &r.Condition }
+func (r *JiraRule) condition() *string         { return &r.Condition }
+func (r *SentryRule) condition() *string       { return &r.Condition }
+func (r *AlertmanagerRule) condition() *string { return &r.Condition }
+func (r *BitbucketRule) condition() *string    { return &r.Condition }
+func (r *AsanaRule) condition() *string        { return &r.Condition }
+func (r *GenericRule) condition() *string      { return &r.Condition }
+
+// resolveActions returns actions, or action alone when actions is empty.
+func resolveActions(action RuleAction, actions []RuleAction) []RuleAction {
+	if len(actions) > 0 {
+		return actions
+	}
+	return []RuleAction{action}
+}
+
+// LocalizedTemplate returns the message template variant for lang, else message_template.
+func (a RuleAction) LocalizedTemplate(lang string) string {
+	if t := a.MessageTemplates[lang]; lang != "" && t != "" {
+		return t
+	}
+	return a.MessageTemplate
+}
+
+// ResolvedTimeout returns timeout with default 120.
+func (a RuleAction) ResolvedTimeout() int {
+	if a.Timeout > 0 {
+		return a.Timeout
+	}
+	return 120
+}
+
+// ResolvedDelay returns delay with default 2.
+func (a RuleAction) ResolvedDelay() int {
+	if a.Delay > 0 {
+		return a.Delay
+	}
+	return 2
+}
+
+type GitHubConfig struct {
+	Secret          string               `yaml:"secret"`
+	NotifyMode      string               `yaml:"notify_mode"` // "all" (default) or "failures"; ignored when rules are set
+	MessageTemplate string               `yaml:"message_template"`
+	AgentID         string               `yaml:"agent_id"`
+	Timeout         int                  `yaml:"timeout"`
+	Delay           int                  `yaml:"delay"`
+	Rules           RuleList[GitHubRule] `yaml:"rules"` // when set, replaces the built-in event handling
+	RateLimit       string               `yaml:"rate_limit"`
+
+	// Events enables optional built-in events without rules: issues, issue_comment, pull_request.
+	Events []string `yaml:"events"`
+	// Templates overrides message_template per event type, e.g. templates.issue_comment.
+	Templates map[string]string `yaml:"templates"`
+
+	App *GitHubAppConfig `yaml:"app"` // enables /api/github/* via a GitHub App
+}
+
+// GitHubAppConfig authenticates the relay as a GitHub App for API calls.
+type GitHubAppConfig struct {
+	AppID          int64  `yaml:"app_id"`
+	PrivateKeyFile string `yaml:"private_key_file"` // PEM key downloaded from the App settings
+	InstallationID int64  `yaml:"installation_id"`  // optional; looked up per repository when empty
+	APIURL         string `yaml:"api_url"`          // default https://api.github.com (set for GitHub Enterprise)
+}
+
+// OptionalGitHubEvents are built-in events that must be enabled via github.events.
+var OptionalGitHubEvents = map[string][]string{
+	"issues":        {"opened", "closed", "reopened"},
+	"issue_comment": {"created"},
+	"pull_request":  {"opened", "synchronize", "closed"},
+}
+
+// EventEnabled reports whether an optional built-in event is listed in github.events.
+func (g GitHubConfig) EventEnabled(event string) bool {
+	for _, e := range g.Events {
+		if e == event {
+			return true
+		}
+	}
+	return false
+}
+
+// ResolvedTemplate returns the template for event: templates[event], then message_template,
+// then the built-in default for that event.
+func (g GitHubConfig) ResolvedTemplate(event string) string {
+	if t := g.Templates[event]; t != "" {
+		return t
+	}
+	if g.MessageTemplate != "" {
+		return g.MessageTemplate
+	}
+	if event == "issues" || event == "issue_comment" {
+		return DefaultGitHubIssueTemplate()
+	}
+	return DefaultGitHubMessageTemplate()
+}
+
+// GitHubRule matches a GitHub delivery. Empty filters match anything.
+type GitHubRule struct {
+	Event       string     `yaml:"event"`       // X-GitHub-Event, e.g. "workflow_run"
+	Actions     []string   `yaml:"actions"`     // payload action, e.g. ["completed"]
+	Repos       []string   `yaml:"repos"`       // "owner/name" or globs like "owner/*"
+	Conclusions []string   `yaml:"conclusions"` // check/workflow conclusion, e.g. ["failure", "timed_out"]
+	Condition   string     `yaml:"condition"`   // expression over the event, e.g. "branch == 'main' && !sender_bot"
+	Sample      Sample     `yaml:"sample"`      // fraction of matches to act on; unset = all
+	RateLimit   string     `yaml:"rate_limit"`  // dedup window, e.g. "30s"; default github.rate_limit
+	DedupKey    string     `yaml:"dedup_key"`   // template over the message data, e.g. "{{.Repository}}#{{.PRNumber}}"
+	Continue    bool       `yaml:"continue"`    // keep evaluating later rules after this one matches
+	Action      RuleAction `yaml:"action"`      // empty fields fall back to the github.* defaults
+
+	// Jobs is action given as a list, creating several jobs for one event,
+	// e.g. a failed CI run notifying one agent and opening a follow-up on
+	// another (Actions is taken by the payload filter)
+	Jobs []RuleAction `yaml:"-"`
+}
+
+// UnmarshalYAML lets action be a list of actions.
+func (r *GitHubRule) UnmarshalYAML(node *yaml.Node) error {
+	type plain GitHubRule
+	return decodeActionList(node, (*plain)(r), &r.Jobs)
+}
+
+// ResolvedActions returns actions, or action alone when actions is empty.
+func (r GitHubRule) ResolvedActions() []RuleAction {
+	return resolveActions(r.Action, r.Jobs)
+}
+
+type SlackConfig struct {
+	SigningSecret string      `yaml:"signing_secret"`
+	IgnoreUsers   []string    `yaml:"ignore_users"` // user IDs to ignore; bot messages are always ignored
+	Rules         []SlackRule `yaml:"rules"`
+	RateLimit     string      `yaml:"rate_limit"`
+}
+
+type SlackRule struct {
+	Event     string     `yaml:"event"`    // Slack event type, e.g. "app_mention" or "message"
+	Channels  []string   `yaml:"channels"` // channel IDs; empty matches any channel
+	Sample    Sample     `yaml:"sample"`
+	RateLimit string     `yaml:"rate_limit"`
+	DedupKey  string     `yaml:"dedup_key"`
+	Continue  bool       `yaml:"continue"`
+	Action    RuleAction `yaml:"action"`
+}
+
+type DiscordConfig struct {
+	PublicKey   string        `yaml:"public_key"`   // application public key (hex) for Ed25519 signatures
+	IgnoreUsers []string      `yaml:"ignore_users"` // user IDs to ignore; bot users are always ignored
+	Rules       []DiscordRule `yaml:"rules"`
+	RateLimit   string        `yaml:"rate_limit"`
+}
+
+type DiscordRule struct {
+	Command   string     `yaml:"command"`  // slash or message command name, e.g. "ask"
+	Channels  []string   `yaml:"channels"` // channel IDs; empty matches any channel
+	Reply     string     `yaml:"reply"`    // ephemeral reply to the user; default "Sent to the agent."
+	Sample    Sample     `yaml:"sample"`
+	RateLimit string     `yaml:"rate_limit"`
+	DedupKey  string     `yaml:"dedup_key"`
+	Continue  bool       `yaml:"continue"`
+	Action    RuleAction `yaml:"action"`
+}
+
+type NotionConfig struct {
+	VerificationToken string               `yaml:"verification_token"` // from the subscription handshake; signs deliveries (X-Notion-Signature)
+	APIToken          string               `yaml:"api_token"`          // integration token, needed to read property values
+	APIURL            string               `yaml:"api_url"`            // default https://api.notion.com
+	IgnoreUsers       []string             `yaml:"ignore_users"`       // user IDs to ignore; events authored by bots are always ignored
+	Rules             RuleList[NotionRule] `yaml:"rules"`
+	RateLimit         string               `yaml:"rate_limit"`
+}
+
+type NotionRule struct {
+	Event     string     `yaml:"event"`     // event type, e.g. "page.properties_updated" or "page.created"
+	Database  string     `yaml:"database"`  // parent database ID; empty matches any
+	Property  string     `yaml:"property"`  // property that must have changed, e.g. "Status"
+	From      string     `yaml:"from"`      // previous value of property; empty matches any
+	To        string     `yaml:"to"`        // new value of property; empty matches any
+	Condition string     `yaml:"condition"` // expression over event, database, page, title, props, prev, changed
+	Sample    Sample     `yaml:"sample"`
+	RateLimit string     `yaml:"rate_limit"`
+	DedupKey  string     `yaml:"dedup_key"`
+	Continue  bool       `yaml:"continue"`
+	Action    RuleAction `yaml:"action"`
+}
+
+type JiraConfig struct {
+	Secret      string             `yaml:"secret"`       // HMAC-SHA256 secret sent as X-Hub-Signature
+	IgnoreUsers []string           `yaml:"ignore_users"` // account IDs to ignore (e.g. the agent's own Jira user)
+	Rules       RuleList[JiraRule] `yaml:"rules"`
+	RateLimit   string             `yaml:"rate_limit"`
+}
+
+type JiraRule struct {
+	Event     string     `yaml:"event"`     // issue_created, issue_updated (status transitions) or comment_created
+	Project   string     `yaml:"project"`   // project key; empty matches any project
+	Condition string     `yaml:"condition"` // e.g. "status == 'In Review'"
+	Sample    Sample     `yaml:"sample"`
+	RateLimit string     `yaml:"rate_limit"`
+	DedupKey  string     `yaml:"dedup_key"`
+	Continue  bool       `yaml:"continue"`
+	Action    RuleAction `yaml:"action"`
+}
+
+type SentryConfig struct {
+	ClientSecret string               `yaml:"client_secret"` // integration client secret, verifies Sentry-Hook-Signature
+	Rules        RuleList[SentryRule] `yaml:"rules"`
+	RateLimit    string               `yaml:"rate_limit"`
+}
+
+type SentryRule struct {
+	Project   string     `yaml:"project"`   // project slug; empty matches any project
+	Condition string     `yaml:"condition"` // e.g. "level == 'fatal' || count > 100"
+	Sample    Sample     `yaml:"sample"`
+	RateLimit string     `yaml:"rate_limit"`
+	DedupKey  string     `yaml:"dedup_key"`
+	Continue  bool       `yaml:"continue"`
+	Action    RuleAction `yaml:"action"`
+}
+
+type AlertmanagerConfig struct {
+	Token     string                     `yaml:"token"` // bearer token from Alertmanager's http_config; empty disables the check
+	Rules     RuleList[AlertmanagerRule] `yaml:"rules"`
+	RateLimit string                     `yaml:"rate_limit"`
+}
+
+type BitbucketConfig struct {
+	Token       string                  `yaml:"token"`        // shared token expected as ?token= on the webhook URL; empty disables the check
+	IgnoreUsers []string                `yaml:"ignore_users"` // account UUIDs or nicknames to ignore (e.g. the agent's own user)
+	Rules       RuleList[BitbucketRule] `yaml:"rules"`
+	RateLimit   string                  `yaml:"rate_limit"`
+}
+
+type BitbucketRule struct {
+	Event     string     `yaml:"event"`     // pullrequest:created, pullrequest:approved or repo:push
+	Repos     []string   `yaml:"repos"`     // "workspace/repo" or globs like "workspace/*"
+	Branches  []string   `yaml:"branches"`  // pushed branch, or PR destination branch; globs allowed
+	Condition string     `yaml:"condition"` // expression over event, repo, actor, branch, source_branch, pr, title, author, commits
+	Sample    Sample     `yaml:"sample"`
+	RateLimit string     `yaml:"rate_limit"`
+	DedupKey  string     `yaml:"dedup_key"`
+	Continue  bool       `yaml:"continue"`
+	Action    RuleAction `yaml:"action"`
+}
+
+type AsanaConfig struct {
+	APIToken    string              `yaml:"api_token"`    // personal access token, to read task names, comments and section names
+	APIURL      string              `yaml:"api_url"`      // default https://app.asana.com/api/1.0
+	Sections    map[string]string   `yaml:"sections"`     // section name -> GID, like trello.lists
+	IgnoreUsers []string            `yaml:"ignore_users"` // user GIDs to ignore (e.g. the agent's own Asana user)
+	Rules       RuleList[AsanaRule] `yaml:"rules"`
+	RateLimit   string              `yaml:"rate_limit"`
+}
+
+type AsanaRule struct {
+	Event     string     `yaml:"event"`     // section_moved or comment_added
+	Section   string     `yaml:"section"`   // section name from asana.sections, or a GID; empty matches any
+	Condition string     `yaml:"condition"` // expression over event, section, task, comment, user
+	Sample    Sample     `yaml:"sample"`
+	RateLimit string     `yaml:"rate_limit"`
+	DedupKey  string     `yaml:"dedup_key"`
+	Continue  bool       `yaml:"continue"`
+	Action    RuleAction `yaml:"action"`
+}
+
+type AlertmanagerRule struct {
+	Status    string     `yaml:"status"`    // firing or resolved; empty matches both
+	Condition string     `yaml:"condition"` // e.g. "severity == 'critical'"
+	Sample    Sample     `yaml:"sample"`
+	RateLimit string     `yaml:"rate_limit"`
+	DedupKey  string     `yaml:"dedup_key"`
+	Continue  bool       `yaml:"continue"`
+	Action    RuleAction `yaml:"action"`
+}
+
+// GenericWebhookConfig describes a config-driven webhook mounted at /webhook/custom/<name>.
+type GenericWebhookConfig struct {
+	Name            string                `yaml:"name"`
+	Secret          string                `yaml:"secret"`           // HMAC-SHA256 secret; empty disables verification
+	SignatureHeader string                `yaml:"signature_header"` // header carrying the hex HMAC (default X-Signature-256)
+	SignaturePrefix string                `yaml:"signature_prefix"` // e.g. "sha256="
+	Fields          map[string]string     `yaml:"fields"`           // template/condition name -> dot path into the JSON payload
+	DedupField      string                `yaml:"dedup_field"`      // optional field used as rate-limit key
+	Rules           RuleList[GenericRule] `yaml:"rules"`
+	RateLimit       string                `yaml:"rate_limit"`
+}
+
+type GenericRule struct {
+	Name      string     `yaml:"name"`
+	Condition string     `yaml:"condition"`
+	Sample    Sample     `yaml:"sample"`
+	RateLimit string     `yaml:"rate_limit"`
+	DedupKey  string     `yaml:"dedup_key"`
+	Continue  bool       `yaml:"continue"`
+	Action    RuleAction `yaml:"action"`
+}
+
+type AuditConfig struct {
+	// Output is where entries go: file (default, at log_path), stdout, syslog or http.
+	Output  string            `yaml:"output"`
+	LogPath string            `yaml:"log_path"`
+	Syslog  AuditSyslogConfig `yaml:"syslog"`
+	HTTP    AuditHTTPConfig   `yaml:"http"`
+	// Retention drops entries older than this from log_path ("30d", "12h");
+	// empty keeps everything.
+	Retention string `yaml:"retention"`
+	// Sources give a webhook source (trello, github, custom, ...) its own
+	// file and retention. Only with the file output.
+	Sources map[string]AuditSourceConfig `yaml:"sources"`
+	// ExcludePaths are never logged: exact paths, or prefixes ending in "/",
+	// e.g. /health or /metrics.
+	ExcludePaths []string `yaml:"exclude_paths"`
+	// Sample maps a path prefix to N: 1 in N successful requests under it are
+	// logged, all failures are.
+	Sample map[string]int `yaml:"sample"`
+}
+
+// AuditSourceConfig is the audit file of one webhook source.
+type AuditSourceConfig struct {
+	LogPath   string `yaml:"log_path"`  // default audit-<source>.log next to audit.log_path
+	Retention string `yaml:"retention"` // default audit.retention
+}
+
+// SourceLogPath returns the audit file for source.
+func (a AuditConfig) SourceLogPath(source string) string {
+	if p := a.Sources[source].LogPath; p != "" {
+		return p
+	}
+	return filepath.Join(filepath.Dir(a.LogPath), "audit-"+source+".log")
+}
+
+// ResolvedRetention returns retention, or 0 when unset (keep everything).
+func (a AuditConfig) ResolvedRetention() time.Duration {
+	d, _ := parseRetention(a.Retention)
+	return d
+}
+
+// SourceRetention returns the retention of source's file, defaulting to
+// audit.retention.
+func (a AuditConfig) SourceRetention(source string) time.Duration {
+	if r := a.Sources[source].Retention; r != "" {
+		d, _ := parseRetention(r)
+		return d
+	}
+	return a.ResolvedRetention()
+}
+
+// parseRetention parses a positive retention such as 12h or 30d.
+func parseRetention(s string) (time.Duration, error) {
+	if s == "" {
+		return 0, nil
+	}
+	d, err := schedule.ParseDuration(s)
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("invalid retention %q (use e.g. 12h or 30d)", s)
+	}
+	return d, nil
+}
+
+// AuditSyslogConfig sends audit entries as RFC 5424 syslog messages.
+type AuditSyslogConfig struct {
+	Address  string `yaml:"address"`  // udp://host:514 or tcp://host:601
+	AppName  string `yaml:"app_name"` // default openclaw-relay
+	Facility string `yaml:"facility"` // e.g. daemon, local0 (default local0)
+}
+
+// AuditHTTPConfig posts audit entries in batches to a log collector.
+type AuditHTTPConfig struct {
+	URL           string            `yaml:"url"`
+	Format        string            `yaml:"format"`         // ndjson (default) or loki
+	Headers       map[string]string `yaml:"headers"`        // e.g. Authorization
+	Labels        map[string]string `yaml:"labels"`         // loki stream labels (default job: openclaw-relay)
+	BatchSize     int               `yaml:"batch_size"`     // entries per request (default 100)
+	FlushInterval string            `yaml:"flush_interval"` // longest an entry waits (default 5s)
+}
+
+// ResolvedBatchSize returns batch_size with default 100.
+func (c AuditHTTPConfig) ResolvedBatchSize() int {
+	if c.BatchSize <= 0 {
+		return 100
+	}
+	return c.BatchSize
+}
+
+// ResolvedFlushInterval returns flush_interval with default 5s.
+func (c AuditHTTPConfig) ResolvedFlushInterval() time.Duration {
+	if d, err := time.ParseDuration(c.FlushInterval); err == nil && d > 0 {
+		return d
+	}
+	return 5 * time.Second
+}
+
+// SyslogFacilities maps audit.syslog.facility names to their codes.
+var SyslogFacilities = map[string]int{
+	"kern": 0, "user": 1, "mail": 2, "daemon": 3, "auth": 4, "syslog": 5, "authpriv": 10,
+	"local0": 16, "local1": 17, "local2": 18, "local3": 19,
+	"local4": 20, "local5": 21, "local6": 22, "local7": 23,
+}
+
+func (a AuditConfig) validate() error {
+	switch a.Output {
+	case "", "file", "stdout":
+	case "syslog":
+		u, err := url.Parse(a.Syslog.Address)
+		if err != nil || (u.Scheme != "udp" && u.Scheme != "tcp") || u.Port() == "" {
+			return fmt.Errorf("audit.syslog.address must be udp://host:port or tcp://host:port")
+		}
+		if _, ok := SyslogFacilities[a.Syslog.Facility]; a.Syslog.Facility != "" && !ok {
+			return fmt.Errorf("audit.syslog.facility %q is not a syslog facility", a.Syslog.Facility)
+		}
+	case "http":
+		if u, err := url.Parse(a.HTTP.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("audit.http.url must be an http(s) URL")
+		}
+		if a.HTTP.Format != "" && a.HTTP.Format != "ndjson" && a.HTTP.Format != "loki" {
+			return fmt.Errorf("audit.http.format must be ndjson or loki")
+		}
+		if a.HTTP.FlushInterval != "" {
+			if d, err := time.ParseDuration(a.HTTP.FlushInterval); err != nil || d <= 0 {
+				return fmt.Errorf("audit.http.flush_interval must be a positive duration")
+			}
+		}
+	default:
+		return fmt.Errorf("audit.output must be file, stdout, syslog or http")
+	}
+	for prefix, n := range a.Sample {
+		if n < 1 {
+			return fmt.Errorf("audit.sample.%s must be at least 1", prefix)
+		}
+	}
+	if _, err := parseRetention(a.Retention); err != nil {
+		return fmt.Errorf("audit.retention: %w", err)
+	}
+	if len(a.Sources) > 0 && a.Output != "" && a.Output != "file" {
+		return fmt.Errorf("audit.sources needs output: file; other outputs label entries with source instead")
+	}
+	for source, sc := range a.Sources {
+		if source == "" || strings.ContainsAny(source, `/\`) {
+			return fmt.Errorf("audit.sources: invalid source name %q", source)
+		}
+		if _, err := parseRetention(sc.Retention); err != nil {
+			return fmt.Errorf("audit.sources.%s.retention: %w", source, err)
+		}
+	}
+	return nil
+}
+
+// BudgetConfig caps the agent jobs created per calendar month (UTC).
+type BudgetConfig struct {
+	Sources map[string]int `yaml:"sources"`  // source -> monthly cap, e.g. github: 500
+	Rules   map[string]int `yaml:"rules"`    // "<source>/<rule>" -> monthly cap, e.g. "trello/trello.rules[2]"
+	AgentID string         `yaml:"agent_id"` // agent that gets the cap alert
+	Timeout int            `yaml:"timeout"`  // alert job timeout (default 90)
+}
+
+// Enabled reports whether any cap is set.
+func (b BudgetConfig) Enabled() bool {
+	return len(b.Sources) > 0 || len(b.Rules) > 0
+}
+
+// DatasetConfig records anonymized event → prompt pairs for prompt
+// evaluation. See docs/configuration.md#dataset.
+type DatasetConfig struct {
+	Path         string   `yaml:"path"`          // JSONL file to append to; empty disables recording
+	Sample       Sample   `yaml:"sample"`        // fraction of jobs recorded (default all)
+	Sources      []string `yaml:"sources"`       // only these sources (default all)
+	RedactFields []string `yaml:"redact_fields"` // payload keys whose values are dropped, e.g. login
+	Redact       []string `yaml:"redact"`        // extra regular expressions masked in prompts and payloads
+}
+
+// GCConfig cleans up the data directory: poller state of removed Gmail
+// accounts, dead letters given up on and old event records. See
+// docs/configuration.md#gc.
+type GCConfig struct {
+	Interval            string `yaml:"interval"`              // how often to collect; empty = only on POST /api/gc
+	EventRetention      string `yaml:"event_retention"`       // stored deliveries and history entries (default 30d)
+	DeadLetterRetention string `yaml:"dead_letter_retention"` // dead letters out of retries (default 30d)
+}
+
+// ResolvedInterval returns interval, 0 when collection only runs on request.
+func (g GCConfig) ResolvedInterval() time.Duration {
+	d, _ := parseRetention(g.Interval)
+	return d
+}
+
+// ResolvedRetention returns event_retention and dead_letter_retention, each
+// 30 days by default.
+func (g GCConfig) ResolvedRetention() (events, deadLetters time.Duration) {
+	events, deadLetters = 30*24*time.Hour, 30*24*time.Hour
+	if d, _ := parseRetention(g.EventRetention); d > 0 {
+		events = d
+	}
+	if d, _ := parseRetention(g.DeadLetterRetention); d > 0 {
+		deadLetters = d
+	}
+	return events, deadLetters
+}
+
+func (g GCConfig) validate() error {
+	fields := []struct{ name, value string }{
+		{"interval", g.Interval},
+		{"event_retention", g.EventRetention},
+		{"dead_letter_retention", g.DeadLetterRetention},
+	}
+	for _, f := range fields {
+		if _, err := parseRetention(f.value); err != nil {
+			return fmt.Errorf("gc.%s: %w", f.name, err)
+		}
+	}
+	return nil
+}
+
+// CalendarConfig reads Google Calendar for the accounts signed in with
+// Google OAuth: /api/calendar/* for the agent, and rules that create a job
+// shortly before matching events start.
+type CalendarConfig struct {
+	Enabled      bool           `yaml:"enabled"`
+	Accounts     []string       `yaml:"accounts"`      // Google accounts whose calendars are read; the first is the API default
+	PollInterval string         `yaml:"poll_interval"` // how often rules look for upcoming events (default 1m)
+	APIURL       string         `yaml:"api_url"`       // default https://www.googleapis.com/calendar/v3/
+	Rules        []CalendarRule `yaml:"rules"`
+	// Write enables creating, updating and responding to events through the
+	// API, and adds the calendar.events scope to google.scopes.
+	Write bool `yaml:"write"`
+}
+
+// CalendarRule creates a job Before an event starts. Empty filters match
+// any event; the first matching rule wins unless it sets continue.
+type CalendarRule struct {
+	Name      string     `yaml:"name"`
+	Accounts  []string   `yaml:"accounts"`  // calendar.accounts the rule reads; empty = all
+	Calendars []string   `yaml:"calendars"` // calendar IDs from /api/calendar/calendars (default primary)
+	Attendees []string   `yaml:"attendees"` // any attendee or the organizer matches; same patterns as gmail match.from
+	Title     []string   `yaml:"title"`     // same patterns as gmail match.subject
+	Before    string     `yaml:"before"`    // how long before the start the job is created (default 10m)
+	Continue  bool       `yaml:"continue"`  // keep evaluating later rules after this one matches
+	Action    RuleAction `yaml:"action"`
+}
+
+// ResolvedPollInterval returns poll_interval with default 1m.
+func (c CalendarConfig) ResolvedPollInterval() time.Duration {
+	if d, err := time.ParseDuration(c.PollInterval); err == nil && d > 0 {
+		return d
+	}
+	return time.Minute
+}
+
+// ResolvedCalendars returns calendars, or primary when empty.
+func (r CalendarRule) ResolvedCalendars() []string {
+	if len(r.Calendars) == 0 {
+		return []string{"primary"}
+	}
+	return r.Calendars
+}
+
+// ResolvedBefore returns before with default 10m.
+func (r CalendarRule) ResolvedBefore() time.Duration {
+	if d, err := time.ParseDuration(r.Before); err == nil && d > 0 {
+		return d
+	}
+	return 10 * time.Minute
+}
+
+func (c CalendarConfig) validate() error {
+	if !c.Enabled {
+		return nil
+	}
+	if len(c.Accounts) == 0 {
+		return fmt.Errorf("calendar.accounts is required when calendar is enabled")
+	}
+	if c.PollInterval != "" {
+		if d, err := time.ParseDuration(c.PollInterval); err != nil || d <= 0 {
+			return fmt.Errorf("calendar.poll_interval %q is not a positive duration", c.PollInterval)
+		}
+	}
+	for i, r := range c.Rules {
+		path := fmt.Sprintf("calendar.rules[%d]", i)
+		for _, acc := range r.Accounts {
+			if !slices.Contains(c.Accounts, acc) {
+				return fmt.Errorf("%s.accounts: %s is not in calendar.accounts", path, acc)
+			}
+		}
+		if r.Before != "" {
+			if d, err := time.ParseDuration(r.Before); err != nil || d <= 0 {
+				return fmt.Errorf("%s.before %q is not a positive duration", path, r.Before)
+			}
+		}
+		for _, t := range r.Title {
+			if _, err := SubjectRegexp(t); err != nil {
+				return fmt.Errorf("%s.title %q: %w", path, t, err)
+			}
+		}
+		// The job is timed by before; a schedule would move it past the start
+		if r.Action.Schedule != "" {
+			return fmt.Errorf("%s.action.schedule is not supported; use before", path)
+		}
+		if r.Action.MessageTemplate != "" {
+			if _, err := template.New("calendar").Parse(r.Action.MessageTemplate); err != nil {
+				return fmt.Errorf("%s.action.message_template: %w", path, err)
+			}
+		}
+	}
+	return nil
+}
+
+// DriveConfig reads Google Drive for the accounts signed in with Google
+// OAuth: /api/drive/file/{id} for the agent, and rules that create a job
+// when a matching file changes. Enabling it adds the drive.readonly scope to
+// google.scopes.
+type DriveConfig struct {
+	Enabled       bool        `yaml:"enabled"`
+	Accounts      []string    `yaml:"accounts"`        // Google accounts whose Drive is read; the first is the API default
+	PollInterval  string      `yaml:"poll_interval"`   // how often the change feed is read (default 1m)
+	APIURL        string      `yaml:"api_url"`         // default https://www.googleapis.com/drive/v3/
+	RateLimit     string      `yaml:"rate_limit"`      // per file and rule; default server.rate_limit
+	MaxDownloadMB int         `yaml:"max_download_mb"` // largest content /api/drive/file/{id} returns (default 25)
+	Rules         []DriveRule `yaml:"rules"`
+}
+
+// DriveRule creates a job when a file it matches is created or changed.
+// Empty filters match any file; the first matching rule wins unless it sets
+// continue.
+type DriveRule struct {
+	Name      string     `yaml:"name"`
+	Accounts  []string   `yaml:"accounts"`   // drive.accounts the rule reads; empty = all
+	Folders   []string   `yaml:"folders"`    // folder IDs the file is directly in
+	Owners    []string   `yaml:"owners"`     // same patterns as gmail match.from
+	MimeTypes []string   `yaml:"mime_types"` // same patterns as gmail match.subject
+	RateLimit string     `yaml:"rate_limit"` // overrides drive.rate_limit
+	Continue  bool       `yaml:"continue"`   // keep evaluating later rules after this one matches
+	Action    RuleAction `yaml:"action"`
+}
+
+// ResolvedPollInterval returns poll_interval with default 1m.
+func (c DriveConfig) ResolvedPollInterval() time.Duration {
+	if d, err := time.ParseDuration(c.PollInterval); err == nil && d > 0 {
+		return d
+	}
+	return time.Minute
+}
+
+// ResolvedMaxDownloadBytes returns max_download_mb in bytes, default 25 MB.
+func (c DriveConfig) ResolvedMaxDownloadBytes() int64 {
+	if c.MaxDownloadMB > 0 {
+		return int64(c.MaxDownloadMB) << 20
+	}
+	return 25 << 20
+}
+
+func (c DriveConfig) validate() error {
+	if !c.Enabled {
+		return nil
+	}
+	if len(c.Accounts) == 0 {
+		return fmt.Errorf("drive.accounts is required when drive is enabled")
+	}
+	if c.PollInterval != "" {
+		if d, err := time.ParseDuration(c.PollInterval); err != nil || d <= 0 {
+			return fmt.Errorf("drive.poll_interval %q is not a positive duration", c.PollInterval)
+		}
+	}
+	if c.MaxDownloadMB < 0 {
+		return fmt.Errorf("drive.max_download_mb must not be negative")
+	}
+	if c.RateLimit != "" {
+		if _, err := time.ParseDuration(c.RateLimit); err != nil {
+			return fmt.Errorf("drive.rate_limit: %w", err)
+		}
+	}
+	for i, r := range c.Rules {
+		path := fmt.Sprintf("drive.rules[%d]", i)
+		for _, acc := range r.Accounts {
+			if !slices.Contains(c.Accounts, acc) {
+				return fmt.Errorf("%s.accounts: %s is not in drive.accounts", path, acc)
+			}
+		}
+		for _, m := range r.MimeTypes {
+			if _, err := SubjectRegexp(m); err != nil {
+				return fmt.Errorf("%s.mime_types %q: %w", path, m, err)
+			}
+		}
+		if r.RateLimit != "" {
+			if _, err := time.ParseDuration(r.RateLimit); err != nil {
+				return fmt.Errorf("%s.rate_limit: %w", path, err)
+			}
+		}
+		if r.Action.MessageTemplate != "" {
+			if _, err := template.New("drive").Parse(r.Action.MessageTemplate); err != nil {
+				return fmt.Errorf("%s.action.message_template: %w", path, err)
+			}
+		}
+	}
+	return nil
+}
+
+// TasksConfig reads Google Tasks for the accounts signed in with Google
+// OAuth: /api/tasks/* for the agent. Enabling it adds the tasks.readonly
+// scope to google.scopes.
+type TasksConfig struct {
+	Enabled  bool     `yaml:"enabled"`
+	Accounts []string `yaml:"accounts"` // Google accounts whose tasks are read; the first is the API default
+	APIURL   string   `yaml:"api_url"`  // default https://tasks.googleapis.com/
+}
+
+func (c TasksConfig) validate() error {
+	if c.Enabled && len(c.Accounts) == 0 {
+		return fmt.Errorf("tasks.accounts is required when tasks is enabled")
+	}
+	return nil
+}
+
+var envRegex = regexp.MustCompile(`\$\{([^}]+)\}`)
+
+func envSubst(s string) string {
+	return envRegex.ReplaceAllStringFunc(s, func(match string) string {
+		key := envRegex.FindStringSubmatch(match)[1]
+		if v := os.Getenv(key); v != "" {
+			return v
+		}
+		return match
+	})
+}
+
+func Load(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	cfg, err := Parse(data)
+	if err != nil {
+		return nil, err
+	}
+	cfg.Path = path
+	return cfg, nil
+}
+
+// Parse decodes config file content, substituting ${VAR} references, opening
+// sealed values with RELAY_ENCRYPTION_KEY and applying defaults.
+func Parse(data []byte) (*Config, error) {
+	expanded := envSubst(string(data))
+	var root yaml.Node
+	if err := yaml.Unmarshal([]byte(expanded), &root); err != nil {
+		return nil, err
+	}
+	if err := sealed.OpenConfig(&root, func() string { return os.Getenv("RELAY_ENCRYPTION_KEY") }); err != nil {
+		return nil, fmt.Errorf("sealed value: %w", err)
+	}
+	var cfg Config
+	if root.Kind != 0 {
+		if err := root.Decode(&cfg); err != nil {
+			return nil, err
+		}
+	}
+	if cfg.Server.Port == 0 {
+		cfg.Server.Port = 8080
+	}
+	if cfg.Gateway.AgentID == "" {
+		cfg.Gateway.AgentID = "main"
+	}
+	if cfg.Audit.LogPath == "" {
+		cfg.Audit.LogPath = "data/audit.log"
+	}
+	if cfg.Calendar.Enabled && cfg.Calendar.Write {
+		cfg.Google.Scopes = append(cfg.Google.ResolvedScopes(), googleScopePrefix+"calendar.events")
+	}
+	if cfg.Drive.Enabled {
+		cfg.Google.Scopes = append(cfg.Google.ResolvedScopes(), googleScopePrefix+"drive.readonly")
+	}
+	if cfg.Tasks.Enabled {
+		cfg.Google.Scopes = append(cfg.Google.ResolvedScopes(), googleScopePrefix+"tasks.readonly")
+	}
+	cfg.Source = data
+	return &cfg, nil
+}
+
+// Validate checks config for common misconfigurations.
+func (c *Config) Validate() error {
+	hasRules := len(c.Trello.Rules) > 0 || c.GitHub.Secret != "" || len(c.GitHub.Rules) > 0 || c.Gmail.Enabled || len(c.GenericWebhooks) > 0 || len(c.Slack.Rules) > 0 || len(c.Discord.Rules) > 0 || len(c.Notion.Rules) > 0 || len(c.Jira.Rules) > 0 || len(c.Sentry.Rules) > 0 || len(c.Alertmanager.Rules) > 0 || len(c.Asana.Rules) > 0 || len(c.Bitbucket.Rules) > 0 || len(c.Calendar.Rules) > 0 || len(c.Drive.Rules) > 0
+	if hasRules && c.Gateway.URL == "" && !c.InMemory {
+		return fmt.Errorf("gateway.url is required when trello/github/gmail/generic/slack/jira rules are configured")
+	}
+
+	for source, n := range c.Server.MaxInFlight {
+		if n < 0 {
+			return fmt.Errorf("server.max_in_flight.%s must not be negative", source)
+		}
+	}
+	if c.Server.InFlightWait != "" {
+		if _, err := time.ParseDuration(c.Server.InFlightWait); err != nil {
+			return fmt.Errorf("server.in_flight_wait: %w", err)
+		}
+	}
+	if err := c.Audit.validate(); err != nil {
+		return err
+	}
+	if err := c.GC.validate(); err != nil {
+		return err
+	}
+	if err := c.Calendar.validate(); err != nil {
+		return err
+	}
+	if err := c.Drive.validate(); err != nil {
+		return err
+	}
+	if err := c.Tasks.validate(); err != nil {
+		return err
+	}
+	if err := c.validateGoogleScopes(); err != nil {
+		return err
+	}
+	if err := c.Server.IPAllowlist.validate(); err != nil {
+		return err
+	}
+	for i, rule := range c.Server.CORS {
+		if err := rule.validate(); err != nil {
+			return fmt.Errorf("server.cors[%d].%w", i, err)
+		}
+	}
+	if err := c.validateAPIKeys(); err != nil {
+		return err
+	}
+	for field, v := range map[string]string{"tolerance": c.Server.ClockSkew.Tolerance, "warn": c.Server.ClockSkew.Warn} {
+		if v == "" {
+			continue
+		}
+		if d, err := time.ParseDuration(v); err != nil || d <= 0 {
+			return fmt.Errorf("server.clock_skew.%s %q is not a positive duration", field, v)
+		}
+	}
+	if c.Server.DeliveryHistory < 0 {
+		return fmt.Errorf("server.delivery_history must not be negative")
+	}
+	if c.Server.EventHistory < 0 {
+		return fmt.Errorf("server.event_history must not be negative")
+	}
+	if c.Server.EventTTL != "" {
+		if _, err := time.ParseDuration(c.Server.EventTTL); err != nil {
+			return fmt.Errorf("server.event_ttl: %w", err)
+		}
+	}
+	if c.Gateway.Control.Enabled && (c.Gateway.URL == "" || c.Gateway.Token == "") {
+		return fmt.Errorf("gateway.control requires gateway.url and gateway.token")
+	}
+	if c.Gateway.Control.Wait != "" {
+		if _, err := time.ParseDuration(c.Gateway.Control.Wait); err != nil {
+			return fmt.Errorf("gateway.control.wait: %w", err)
+		}
+	}
+	if c.Gateway.DeadLetter.RetryInterval != "" {
+		if _, err := time.ParseDuration(c.Gateway.DeadLetter.RetryInterval); err != nil {
+			return fmt.Errorf("gateway.dead_letter.retry_interval: %w", err)
+		}
+	}
+	if c.Gateway.DeadLetter.MaxAttempts < 0 {
+		return fmt.Errorf("gateway.dead_letter.max_attempts must not be negative")
+	}
+	if hb := c.Gateway.Heartbeat; hb.Interval != "" {
+		if d, err := time.ParseDuration(hb.Interval); err != nil || d < time.Minute {
+			return fmt.Errorf("gateway.heartbeat.interval %q must be a duration of at least 1m", hb.Interval)
+		}
+		if (hb.Channel == "") != (hb.Target == "") {
+			return fmt.Errorf("gateway.heartbeat: channel and target go together")
+		}
+		if _, err := template.New("heartbeat").Parse(hb.MessageTemplate); err != nil {
+			return fmt.Errorf("gateway.heartbeat.message_template: %w", err)
+		}
+	}
+	for source, n := range c.Budget.Sources {
+		if n <= 0 {
+			return fmt.Errorf("budget.sources.%s: cap must be positive", source)
+		}
+	}
+	for key, n := range c.Budget.Rules {
+		if !strings.Contains(key, "/") {
+			return fmt.Errorf("budget.rules.%s: key must be <source>/<rule>", key)
+		}
+		if n <= 0 {
+			return fmt.Errorf("budget.rules.%s: cap must be positive", key)
+		}
+	}
+	if c.Dataset.Sample < 0 || c.Dataset.Sample > 1 {
+		return fmt.Errorf("dataset.sample must be between 0 and 1")
+	}
+	for _, p := range c.Dataset.Redact {
+		if _, err := regexp.Compile(p); err != nil {
+			return fmt.Errorf("dataset.redact %q: %w", p, err)
+		}
+	}
+	if c.Gateway.JobName != "" {
+		if _, err := template.New("job_name").Parse(c.Gateway.JobName); err != nil {
+			return fmt.Errorf("gateway.job_name: %w", err)
+		}
+	}
+	if c.Google.TokenGracePeriod != "" {
+		if _, err := time.ParseDuration(c.Google.TokenGracePeriod); err != nil {
+			return fmt.Errorf("google.token_grace_period: %w", err)
+		}
+	}
+
+	if _, err := schedule.LoadLocation(c.Server.Timezone); err != nil {
+		return fmt.Errorf("server.timezone: %w", err)
+	}
+	if err := c.validateSchedules(); err != nil {
+		return err
+	}
+	if err := c.validateRateLimits(); err != nil {
+		return err
+	}
+	if err := c.validateDedupKeys(); err != nil {
+		return err
+	}
+	if err := c.validateSamples(); err != nil {
+		return err
+	}
+	if err := c.validateConditions(); err != nil {
+		return err
+	}
+	if err := c.validateLanguages(); err != nil {
+		return err
+	}
+
+	if (c.Trello.APIKey == "") != (c.Trello.Token == "") {
+		return fmt.Errorf("trello.api_key and trello.token must be set together")
+	}
+	if len(c.Trello.Boards) > 0 && (c.Trello.APIKey == "" || c.Trello.CallbackURL == "") {
+		return fmt.Errorf("trello.boards requires trello.api_key, trello.token and trello.callback_url")
+	}
+	if len(c.Trello.ListNames) > 0 && len(c.Trello.Boards) == 0 {
+		return fmt.Errorf("trello.list_names requires trello.boards")
+	}
+	for alias := range c.Trello.ListNames {
+		if _, ok := c.Trello.Lists[alias]; ok {
+			return fmt.Errorf("trello.list_names.%s is also set in trello.lists", alias)
+		}
+	}
+	if c.Trello.BoardRefresh != "" {
+		if d, err := time.ParseDuration(c.Trello.BoardRefresh); err != nil || d <= 0 {
+			return fmt.Errorf("trello.board_refresh %q is not a positive duration", c.Trello.BoardRefresh)
+		}
+	}
+	for _, alias := range c.Trello.WatchLists {
+		_, inLists := c.Trello.Lists[alias]
+		_, inNames := c.Trello.ListNames[alias]
+		if !inLists && !inNames {
+			return fmt.Errorf("trello.watch_lists: %q is not in trello.lists or trello.list_names", alias)
+		}
+	}
+	if c.Trello.WatchTTL != "" {
+		if d, err := time.ParseDuration(c.Trello.WatchTTL); err != nil || d <= 0 {
+			return fmt.Errorf("trello.watch_ttl %q is not a positive duration", c.Trello.WatchTTL)
+		}
+	}
+
+	if app := c.GitHub.App; app != nil {
+		if app.AppID == 0 || app.PrivateKeyFile == "" {
+			return fmt.Errorf("github.app requires app_id and private_key_file")
+		}
+	}
+
+	for _, e := range c.GitHub.Events {
+		if _, ok := OptionalGitHubEvents[e]; !ok {
+			return fmt.Errorf("github.events: unknown event %q (issues, issue_comment, pull_request)", e)
+		}
+	}
+
+	for i, r := range c.GitHub.Rules {
+		if r.Event == "" {
+			return fmt.Errorf("github.rules[%d].event must not be empty", i)
+		}
+	}
+
+	for i, r := range c.Slack.Rules {
+		if r.Event == "" {
+			return fmt.Errorf("slack.rules[%d].event must not be empty", i)
+		}
+	}
+
+	if len(c.Discord.Rules) > 0 {
+		if key, err := hex.DecodeString(c.Discord.PublicKey); err != nil || len(key) != ed25519.PublicKeySize {
+			return fmt.Errorf("discord.public_key must be the application's 64-character hex public key")
+		}
+	}
+	for i, r := range c.Discord.Rules {
+		if r.Command == "" {
+			return fmt.Errorf("discord.rules[%d].command must not be empty", i)
+		}
+	}
+
+	for i, r := range c.Notion.Rules {
+		if r.Event == "" {
+			return fmt.Errorf("notion.rules[%d].event must not be empty", i)
+		}
+		if (r.From != "" || r.To != "") && r.Property == "" {
+			return fmt.Errorf("notion.rules[%d]: from and to require property", i)
+		}
+		if (r.Property != "" || r.Condition != "") && c.Notion.APIToken == "" {
+			return fmt.Errorf("notion.rules[%d]: property and condition need notion.api_token to read page properties", i)
+		}
+	}
+
+	for i, r := range c.Jira.Rules {
+		switch r.Event {
+		case "issue_created", "issue_updated", "comment_created":
+		default:
+			return fmt.Errorf("jira.rules[%d].event %q must be issue_created, issue_updated or comment_created", i, r.Event)
+		}
+	}
+
+	for i, r := range c.Bitbucket.Rules {
+		switch r.Event {
+		case "pullrequest:created", "pullrequest:approved", "repo:push":
+		default:
+			return fmt.Errorf("bitbucket.rules[%d].event %q must be pullrequest:created, pullrequest:approved or repo:push", i, r.Event)
+		}
+	}
+
+	for i, r := range c.Asana.Rules {
+		switch r.Event {
+		case "section_moved", "comment_added":
+		default:
+			return fmt.Errorf("asana.rules[%d].event %q must be section_moved or comment_added", i, r.Event)
+		}
+		if r.Section != "" && r.Event != "section_moved" {
+			return fmt.Errorf("asana.rules[%d]: section only applies to section_moved", i)
+		}
+	}
+
+	for i, r := range c.Alertmanager.Rules {
+		switch r.Status {
+		case "", "firing", "resolved":
+		default:
+			return fmt.Errorf("alertmanager.rules[%d].status %q must be firing or resolved", i, r.Status)
+		}
+	}
+
+	genericNames := make(map[string]bool, len(c.GenericWebhooks))
+	for i, g := range c.GenericWebhooks {
+		if g.Name == "" || strings.Contains(g.Name, "/") {
+			return fmt.Errorf("generic_webhooks[%d].name must be non-empty and must not contain '/'", i)
+		}
+		if genericNames[g.Name] {
+			return fmt.Errorf("generic_webhooks[%d].name %q is duplicated", i, g.Name)
+		}
+		genericNames[g.Name] = true
+	}
+
+	if c.Gmail.Enabled {
+		allowedSet := make(map[string]bool, len(c.Google.AllowedEmails))
 		for _, e := range c.Google.AllowedEmails {
 			allowedSet[e] = true
 		}
-		for i, acc := range c.Gmail.Accounts {
-			if acc.Email == "" {
-				return fmt.Errorf("gmail.accounts[%d].email must not be empty", i)
+		for i, acc := range c.Gmail.Accounts {
+			if acc.Email == "" {
+				return fmt.Errorf("gmail.accounts[%d].email must not be empty", i)
+			}
+			if err := acc.validateProvider(fmt.Sprintf("gmail.accounts[%d]", i)); err != nil {
+				return err
+			}
+			if len(c.Google.AllowedEmails) > 0 && !acc.IsIMAP() && !allowedSet[acc.Email] {
+				return fmt.Errorf("gmail.accounts[%d].email %q is not in google.allowed_emails", i, acc.Email)
+			}
+		}
+	}
+	if c.Gmail.Attachments.MaxSizeMB < -1 {
+		return fmt.Errorf("gmail.attachments.max_size_mb must be positive, or -1 for no cap")
+	}
+	for i, ext := range c.Gmail.Attachments.DenyExtensions {
+		if strings.Trim(ext, ". ") == "" {
+			return fmt.Errorf("gmail.attachments.deny_extensions[%d] must not be empty", i)
+		}
+	}
+	if te := c.Gmail.TextExtraction; te.URL != "" {
+		if !strings.HasPrefix(te.URL, "http://") && !strings.HasPrefix(te.URL, "https://") {
+			return fmt.Errorf("gmail.text_extraction.url must be an http(s) URL")
+		}
+		if te.Timeout != "" {
+			if d, err := time.ParseDuration(te.Timeout); err != nil || d <= 0 {
+				return fmt.Errorf("gmail.text_extraction.timeout %q is not a positive duration", te.Timeout)
+			}
+		}
+		if te.MaxChars < 0 {
+			return fmt.Errorf("gmail.text_extraction.max_chars must not be negative")
+		}
+	}
+	for i, acc := range c.Gmail.Accounts {
+		for j, rule := range acc.Rules {
+			if err := rule.Match.validate(fmt.Sprintf("gmail.accounts[%d].rules[%d].match", i, j)); err != nil {
+				return err
+			}
+			deleted := slices.Contains(rule.Match.Events, "message_deleted")
+			for n, a := range rule.ResolvedActions() {
+				path := actionPath(fmt.Sprintf("gmail.accounts[%d].rules[%d]", i, j), n, len(rule.Actions) > 0)
+				if a.ExtractText && c.Gmail.TextExtraction.URL == "" {
+					return fmt.Errorf("%s.extract_text needs gmail.text_extraction.url", path)
+				}
+				if err := a.validate(path); err != nil {
+					return err
+				}
+				if deleted && (a.Kind == "modify" || a.Kind == "forward") {
+					return fmt.Errorf("%s: kind %s can't act on message_deleted events", path, a.Kind)
+				}
+			}
+		}
+	}
+	if t := c.Gmail.Attachments.ClamAVTimeout; t != "" {
+		if d, err := time.ParseDuration(t); err != nil || d <= 0 {
+			return fmt.Errorf("gmail.attachments.clamav_timeout %q is not a positive duration", t)
+		}
+	}
+	if err := c.Gmail.Retry.validate(); err != nil {
+		return err
+	}
+	if c.Gmail.Quota.DailyUnits < 0 {
+		return fmt.Errorf("gmail.quota.daily_units must not be negative")
+	}
+	if t := c.Gmail.Quota.ThrottleAt; t < 0 || t > 100 {
+		return fmt.Errorf("gmail.quota.throttle_at must be a percentage between 1 and 100")
+	}
+	for i, acc := range c.Gmail.Accounts {
+		if acc.DailyQuota < -1 {
+			return fmt.Errorf("gmail.accounts[%d].daily_quota must be -1 (unlimited), 0 (gmail.quota.daily_units) or positive", i)
+		}
+	}
+	if t := c.Gmail.APICache; t != "" {
+		if d, err := time.ParseDuration(t); err != nil || d <= 0 {
+			return fmt.Errorf("gmail.api_cache %q is not a positive duration", t)
+		}
+	}
+
+	if c.Server.InternalToken == "" {
+		log.Println("Warning: server.internal_token is empty, /api/* routes are unprotected")
+	}
+
+	return nil
+}
+
+// actionPath is the config path of the n-th resolved action of the rule at
+// path: "<path>.action", or "<path>.action[n]" when action is a list.
+func actionPath(path string, n int, list bool) string {
+	if !list {
+		return path + ".action"
+	}
+	return fmt.Sprintf("%s.action[%d]", path, n)
+}
+
+// validateSchedules checks every rule action's schedule expression and time zone.
+func (c *Config) validateSchedules() error {
+	check := func(path, expr, tz string) error {
+		if expr == "" && tz == "" {
+			return nil
+		}
+		if expr != "" {
+			if _, err := schedule.Parse(expr); err != nil {
+				return fmt.Errorf("%s.schedule: %w", path, err)
+			}
+		}
+		if _, err := schedule.LoadLocation(tz); err != nil {
+			return fmt.Errorf("%s.timezone: %w", path, err)
+		}
+		return nil
+	}
+	for i, r := range c.Trello.Rules {
+		for n, a := range r.ResolvedActions() {
+			if err := check(actionPath(fmt.Sprintf("trello.rules[%d]", i), n, len(r.Actions) > 0), a.Schedule, a.Timezone); err != nil {
+				return err
+			}
+		}
+	}
+	for i, r := range c.GitHub.Rules {
+		for n, a := range r.ResolvedActions() {
+			if err := check(actionPath(fmt.Sprintf("github.rules[%d]", i), n, len(r.Jobs) > 0), a.Schedule, a.Timezone); err != nil {
+				return err
+			}
+		}
+	}
+	for i, r := range c.Slack.Rules {
+		if err := check(fmt.Sprintf("slack.rules[%d].action", i), r.Action.Schedule, r.Action.Timezone); err != nil {
+			return err
+		}
+	}
+	for i, r := range c.Discord.Rules {
+		if err := check(fmt.Sprintf("discord.rules[%d].action", i), r.Action.Schedule, r.Action.Timezone); err != nil {
+			return err
+		}
+	}
+	for i, r := range c.Notion.Rules {
+		if err := check(fmt.Sprintf("notion.rules[%d].action", i), r.Action.Schedule, r.Action.Timezone); err != nil {
+			return err
+		}
+	}
+	for i, r := range c.Jira.Rules {
+		if err := check(fmt.Sprintf("jira.rules[%d].action", i), r.Action.Schedule, r.Action.Timezone); err != nil {
+			return err
+		}
+	}
+	for i, r := range c.Sentry.Rules {
+		if err := check(fmt.Sprintf("sentry.rules[%d].action", i), r.Action.Schedule, r.Action.Timezone); err != nil {
+			return err
+		}
+	}
+	for i, r := range c.Bitbucket.Rules {
+		if err := check(fmt.Sprintf("bitbucket.rules[%d].action", i), r.Action.Schedule, r.Action.Timezone); err != nil {
+			return err
+		}
+	}
+	for i, r := range c.Asana.Rules {
+		if err := check(fmt.Sprintf("asana.rules[%d].action", i), r.Action.Schedule, r.Action.Timezone); err != nil {
+			return err
+		}
+	}
+	for i, r := range c.Alertmanager.Rules {
+		if err := check(fmt.Sprintf("alertmanager.rules[%d].action", i), r.Action.Schedule, r.Action.Timezone); err != nil {
+			return err
+		}
+	}
+	for i, g := range c.GenericWebhooks {
+		for j, r := range g.Rules {
+			if err := check(fmt.Sprintf("generic_webhooks[%d].rules[%d].action", i, j), r.Action.Schedule, r.Action.Timezone); err != nil {
+				return err
+			}
+		}
+	}
+	for i, r := range c.Drive.Rules {
+		if err := check(fmt.Sprintf("drive.rules[%d].action", i), r.Action.Schedule, r.Action.Timezone); err != nil {
+			return err
+		}
+	}
+	for i, acc := range c.Gmail.Accounts {
+		for j, r := range acc.Rules {
+			for n, a := range r.ResolvedActions() {
+				if err := check(actionPath(fmt.Sprintf("gmail.accounts[%d].rules[%d]", i, j), n, len(r.Actions) > 0), a.Schedule, a.Timezone); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+var langCodeRegex = regexp.MustCompile(`^[a-z]{2}$`)
+
+// validateLanguages checks that per-language template variants are keyed by
+// ISO 639-1 codes, the form language detection returns.
+func (c *Config) validateLanguages() error {
+	check := func(path string, templates map[string]string) error {
+		for code := range templates {
+			if !langCodeRegex.MatchString(code) {
+				return fmt.Errorf("%s: %q is not a two-letter ISO 639-1 code like \"de\"", path, code)
+			}
+		}
+		return nil
+	}
+	for i, r := range c.Trello.Rules {
+		for n, a := range r.ResolvedActions() {
+			if err := check(actionPath(fmt.Sprintf("trello.rules[%d]", i), n, len(r.Actions) > 0)+".message_templates", a.MessageTemplates); err != nil {
+				return err
+			}
+		}
+	}
+	for i, acc := range c.Gmail.Accounts {
+		for j, r := range acc.Rules {
+			for n, a := range r.ResolvedActions() {
+				path := actionPath(fmt.Sprintf("gmail.accounts[%d].rules[%d]", i, j), n, len(r.Actions) > 0)
+				if err := check(path+".message_templates", a.MessageTemplates); err != nil {
+					return err
+				}
+				if a.Notify != nil {
+					if err := check(path+".notify.templates", a.Notify.Templates); err != nil {
+						return err
+					}
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func (a IPAllowlistConfig) validate() error {
+	check := func(path string, ranges []string) error {
+		for _, r := range ranges {
+			if _, _, err := net.ParseCIDR(r); err != nil && net.ParseIP(r) == nil {
+				return fmt.Errorf("server.ip_allowlist.%s: invalid IP range %q", path, r)
+			}
+		}
+		return nil
+	}
+	if err := check("default", a.Default); err != nil {
+		return err
+	}
+	if err := check("trusted_proxies", a.TrustedProxies); err != nil {
+		return err
+	}
+	for source, ranges := range a.Sources {
+		if err := check("sources."+source, ranges); err != nil {
+			return err
+		}
+	}
+	if a.Refresh != "" {
+		if d, err := time.ParseDuration(a.Refresh); err != nil || d <= 0 {
+			return fmt.Errorf("server.ip_allowlist.refresh %q is not a positive duration", a.Refresh)
+		}
+	}
+	return nil
+}
+
+func (c CORSRule) validate() error {
+	if c.Path != "" && !strings.HasPrefix(c.Path, "/") {
+		return fmt.Errorf("path %q must start with /", c.Path)
+	}
+	if len(c.Origins) == 0 {
+		return fmt.Errorf("origins: at least one origin is required")
+	}
+	for _, o := range c.Origins {
+		if o == "*" {
+			if c.Credentials {
+				return fmt.Errorf("origins: \"*\" cannot be combined with credentials")
+			}
+			continue
+		}
+		u, err := url.Parse(o)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" || (u.Path != "" && u.Path != "/") || u.RawQuery != "" {
+			return fmt.Errorf("origins: %q is not an origin like https://dash.example.com", o)
+		}
+	}
+	if c.MaxAge != "" {
+		if d, err := time.ParseDuration(c.MaxAge); err != nil || d < 0 {
+			return fmt.Errorf("max_age %q is not a duration", c.MaxAge)
+		}
+	}
+	return nil
+}
+
+// googleScopeName matches the short scope names google.scopes expands, like
+// gmail.readonly.
+var googleScopeName = regexp.MustCompile(`^[a-z0-9]+(\.[a-z0-9_]+)*$`)
+
+// validateGoogleScopes checks google.scopes is well formed and, with Google
+// OAuth configured, covers what the enabled Google features use, so a
+// deployment that narrows the scopes finds out at startup rather than from
+// failing API calls.
+func (c *Config) validateGoogleScopes() error {
+	for i, s := range c.Google.Scopes {
+		s = strings.TrimSpace(s)
+		if strings.HasPrefix(s, "https://") && !strings.ContainsAny(s, " \t") {
+			continue
+		}
+		if !googleScopeName.MatchString(s) {
+			return fmt.Errorf("google.scopes[%d] %q is not a scope name or https:// URL", i, s)
+		}
+	}
+	if c.Google.ClientID == "" {
+		return nil
+	}
+	scopes := c.Google.ResolvedScopes()
+	has := func(names ...string) bool {
+		for _, n := range names {
+			if slices.Contains(scopes, googleScopePrefix+n) {
+				return true
+			}
+		}
+		return slices.Contains(scopes, "https://mail.google.com/")
+	}
+	if c.Gmail.Enabled {
+		for i, acc := range c.Gmail.Accounts {
+			if acc.IsIMAP() {
+				continue
+			}
+			path := fmt.Sprintf("gmail.accounts[%d]", i)
+			if !has("gmail.modify", "gmail.readonly") {
+				return fmt.Errorf("%s: google.scopes needs gmail.readonly or gmail.modify to read mail", path)
+			}
+			for j, r := range acc.Rules {
+				for _, a := range r.ResolvedActions() {
+					if a.Kind == "modify" && !has("gmail.modify") {
+						return fmt.Errorf("%s.rules[%d]: kind modify needs the gmail.modify scope in google.scopes", path, j)
+					}
+					if a.Kind == "forward" && !has("gmail.modify", "gmail.send") {
+						return fmt.Errorf("%s.rules[%d]: kind forward needs the gmail.send or gmail.modify scope in google.scopes", path, j)
+					}
+				}
+			}
+		}
+	}
+	if c.Calendar.Enabled && !slices.ContainsFunc(scopes, func(s string) bool {
+		return s == googleScopePrefix+"calendar" || s == googleScopePrefix+"calendar.readonly"
+	}) {
+		return fmt.Errorf("calendar: google.scopes needs calendar.readonly or calendar")
+	}
+	return nil
+}
+
+// validateAPIKeys checks server.api_keys: names and keys are unique, and keys
+// only work behind internal_token.
+func (c *Config) validateAPIKeys() error {
+	if len(c.Server.APIKeys) > 0 && c.Server.InternalToken == "" {
+		return fmt.Errorf("server.api_keys requires server.internal_token")
+	}
+	accounts := map[string]bool{}
+	for _, acc := range c.Gmail.Accounts {
+		accounts[acc.Email] = true
+	}
+	names, keys := map[string]bool{}, map[string]bool{}
+	for i, k := range c.Server.APIKeys {
+		switch {
+		case k.Name == "":
+			return fmt.Errorf("server.api_keys[%d].name is required", i)
+		case names[k.Name]:
+			return fmt.Errorf("server.api_keys[%d].name %q is used twice", i, k.Name)
+		case k.Key == "":
+			return fmt.Errorf("server.api_keys[%d].key is required", i)
+		case keys[k.Key] || k.Key == c.Server.InternalToken:
+			return fmt.Errorf("server.api_keys[%d].key must differ from internal_token and the other keys", i)
+		}
+		names[k.Name], keys[k.Key] = true, true
+		for _, email := range k.Gmail.Accounts {
+			if !accounts[email] {
+				return fmt.Errorf("server.api_keys[%d].gmail.accounts: %q is not in gmail.accounts", i, email)
 			}
-			if len(c.Google.AllowedEmails) > 0 && !allowedSet[acc.Email] {
-				return fmt.Errorf("gmail.accounts[%d].email %q is not in google.allowed_emails", i, acc.Email)
+		}
+	}
+	return nil
+}
+
+// validateRateLimits checks every source's and rule's rate_limit window.
+func (c *Config) validateRateLimits() error {
+	check := func(path, w string) error {
+		if w == "" {
+			return nil
+		}
+		if d, err := time.ParseDuration(w); err != nil || d <= 0 {
+			return fmt.Errorf("%s.rate_limit %q is not a positive duration", path, w)
+		}
+		return nil
+	}
+	if err := check("server", c.Server.RateLimit); err != nil {
+		return err
+	}
+	if err := check("trello", c.Trello.RateLimit); err != nil {
+		return err
+	}
+	for i, r := range c.Trello.Rules {
+		if err := check(fmt.Sprintf("trello.rules[%d]", i), r.RateLimit); err != nil {
+			return err
+		}
+	}
+	if err := check("github", c.GitHub.RateLimit); err != nil {
+		return err
+	}
+	for i, r := range c.GitHub.Rules {
+		if err := check(fmt.Sprintf("github.rules[%d]", i), r.RateLimit); err != nil {
+			return err
+		}
+	}
+	if err := check("slack", c.Slack.RateLimit); err != nil {
+		return err
+	}
+	for i, r := range c.Slack.Rules {
+		if err := check(fmt.Sprintf("slack.rules[%d]", i), r.RateLimit); err != nil {
+			return err
+		}
+	}
+	if err := check("discord", c.Discord.RateLimit); err != nil {
+		return err
+	}
+	for i, r := range c.Discord.Rules {
+		if err := check(fmt.Sprintf("discord.rules[%d]", i), r.RateLimit); err != nil {
+			return err
+		}
+	}
+	if err := check("notion", c.Notion.RateLimit); err != nil {
+		return err
+	}
+	for i, r := range c.Notion.Rules {
+		if err := check(fmt.Sprintf("notion.rules[%d]", i), r.RateLimit); err != nil {
+			return err
+		}
+	}
+	if err := check("jira", c.Jira.RateLimit); err != nil {
+		return err
+	}
+	for i, r := range c.Jira.Rules {
+		if err := check(fmt.Sprintf("jira.rules[%d]", i), r.RateLimit); err != nil {
+			return err
+		}
+	}
+	if err := check("sentry", c.Sentry.RateLimit); err != nil {
+		return err
+	}
+	for i, r := range c.Sentry.Rules {
+		if err := check(fmt.Sprintf("sentry.rules[%d]", i), r.RateLimit); err != nil {
+			return err
+		}
+	}
+	if err := check("bitbucket", c.Bitbucket.RateLimit); err != nil {
+		return err
+	}
+	for i, r := range c.Bitbucket.Rules {
+		if err := check(fmt.Sprintf("bitbucket.rules[%d]", i), r.RateLimit); err != nil {
+			return err
+		}
+	}
+	if err := check("asana", c.Asana.RateLimit); err != nil {
+		return err
+	}
+	for i, r := range c.Asana.Rules {
+		if err := check(fmt.Sprintf("asana.rules[%d]", i), r.RateLimit); err != nil {
+			return err
+		}
+	}
+	if err := check("alertmanager", c.Alertmanager.RateLimit); err != nil {
+		return err
+	}
+	for i, r := range c.Alertmanager.Rules {
+		if err := check(fmt.Sprintf("alertmanager.rules[%d]", i), r.RateLimit); err != nil {
+			return err
+		}
+	}
+	for i, g := range c.GenericWebhooks {
+		if err := check(fmt.Sprintf("generic_webhooks[%d]", i), g.RateLimit); err != nil {
+			return err
+		}
+		for j, r := range g.Rules {
+			if err := check(fmt.Sprintf("generic_webhooks[%d].rules[%d]", i, j), r.RateLimit); err != nil {
+				return err
 			}
 		}
 	}
+	return nil
+}
 
-	if c.Server.InternalToken == "" {
-		log.Println("Warning: server.internal_token is empty, /api/* routes are unprotected")
+// validateDedupKeys checks that every rule's dedup_key template parses.
+func (c *Config) validateDedupKeys() error {
+	check := func(path, key string) error {
+		if key == "" {
+			return nil
+		}
+		if _, err := template.New("dedup_key").Parse(key); err != nil {
+			return fmt.Errorf("%s.dedup_key: %w", path, err)
+		}
+		return nil
+	}
+	for i, r := range c.Trello.Rules {
+		if err := check(fmt.Sprintf("trello.rules[%d]", i), r.DedupKey); err != nil {
+			return err
+		}
+	}
+	for i, r := range c.GitHub.Rules {
+		if err := check(fmt.Sprintf("github.rules[%d]", i), r.DedupKey); err != nil {
+			return err
+		}
+	}
+	for i, r := range c.Slack.Rules {
+		if err := check(fmt.Sprintf("slack.rules[%d]", i), r.DedupKey); err != nil {
+			return err
+		}
+	}
+	for i, r := range c.Discord.Rules {
+		if err := check(fmt.Sprintf("discord.rules[%d]", i), r.DedupKey); err != nil {
+			return err
+		}
+	}
+	for i, r := range c.Notion.Rules {
+		if err := check(fmt.Sprintf("notion.rules[%d]", i), r.DedupKey); err != nil {
+			return err
+		}
+	}
+	for i, r := range c.Jira.Rules {
+		if err := check(fmt.Sprintf("jira.rules[%d]", i), r.DedupKey); err != nil {
+			return err
+		}
+	}
+	for i, r := range c.Sentry.Rules {
+		if err := check(fmt.Sprintf("sentry.rules[%d]", i), r.DedupKey); err != nil {
+			return err
+		}
+	}
+	for i, r := range c.Bitbucket.Rules {
+		if err := check(fmt.Sprintf("bitbucket.rules[%d]", i), r.DedupKey); err != nil {
+			return err
+		}
+	}
+	for i, r := range c.Asana.Rules {
+		if err := check(fmt.Sprintf("asana.rules[%d]", i), r.DedupKey); err != nil {
+			return err
+		}
+	}
+	for i, r := range c.Alertmanager.Rules {
+		if err := check(fmt.Sprintf("alertmanager.rules[%d]", i), r.DedupKey); err != nil {
+			return err
+		}
+	}
+	for i, g := range c.GenericWebhooks {
+		for j, r := range g.Rules {
+			if err := check(fmt.Sprintf("generic_webhooks[%d].rules[%d]", i, j), r.DedupKey); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// validateSamples checks that every rule's sample is a fraction in [0, 1].
+func (c *Config) validateSamples() error {
+	check := func(path string, s Sample) error {
+		if s < 0 || s > 1 {
+			return fmt.Errorf("%s.sample must be between 0 and 1, got %v", path, float64(s))
+		}
+		return nil
+	}
+	for i, r := range c.Trello.Rules {
+		if err := check(fmt.Sprintf("trello.rules[%d]", i), r.Sample); err != nil {
+			return err
+		}
+	}
+	for i, r := range c.GitHub.Rules {
+		if err := check(fmt.Sprintf("github.rules[%d]", i), r.Sample); err != nil {
+			return err
+		}
+	}
+	for i, r := range c.Slack.Rules {
+		if err := check(fmt.Sprintf("slack.rules[%d]", i), r.Sample); err != nil {
+			return err
+		}
+	}
+	for i, r := range c.Discord.Rules {
+		if err := check(fmt.Sprintf("discord.rules[%d]", i), r.Sample); err != nil {
+			return err
+		}
+	}
+	for i, r := range c.Notion.Rules {
+		if err := check(fmt.Sprintf("notion.rules[%d]", i), r.Sample); err != nil {
+			return err
+		}
+	}
+	for i, r := range c.Jira.Rules {
+		if err := check(fmt.Sprintf("jira.rules[%d]", i), r.Sample); err != nil {
+			return err
+		}
+	}
+	for i, r := range c.Sentry.Rules {
+		if err := check(fmt.Sprintf("sentry.rules[%d]", i), r.Sample); err != nil {
+			return err
+		}
+	}
+	for i, r := range c.Bitbucket.Rules {
+		if err := check(fmt.Sprintf("bitbucket.rules[%d]", i), r.Sample); err != nil {
+			return err
+		}
+	}
+	for i, r := range c.Asana.Rules {
+		if err := check(fmt.Sprintf("asana.rules[%d]", i), r.Sample); err != nil {
+			return err
+		}
+	}
+	for i, r := range c.Alertmanager.Rules {
+		if err := check(fmt.Sprintf("alertmanager.rules[%d]", i), r.Sample); err != nil {
+			return err
+		}
+	}
+	for i, g := range c.GenericWebhooks {
+		for j, r := range g.Rules {
+			if err := check(fmt.Sprintf("generic_webhooks[%d].rules[%d]", i, j), r.Sample); err != nil {
+				return err
+			}
+		}
+	}
+	for i, acc := range c.Gmail.Accounts {
+		for j, r := range acc.Rules {
+			if err := check(fmt.Sprintf("gmail.accounts[%d].rules[%d]", i, j), r.Sample); err != nil {
+				return err
+			}
+		}
 	}
+	return nil
+}
 
+// validateConditions compiles every rule condition so syntax errors fail at load.
+func (c *Config) validateConditions() error {
+	check := func(path, cond string) error {
+		if _, err := expr.Compile(cond); err != nil {
+			return fmt.Errorf("%s.condition: %w", path, err)
+		}
+		return nil
+	}
+	for i, r := range c.Trello.Rules {
+		if err := check(fmt.Sprintf("trello.rules[%d]", i), r.Condition); err != nil {
+			return err
+		}
+	}
+	for i, r := range c.GitHub.Rules {
+		if err := check(fmt.Sprintf("github.rules[%d]", i), r.Condition); err != nil {
+			return err
+		}
+	}
+	for i, r := range c.Notion.Rules {
+		if err := check(fmt.Sprintf("notion.rules[%d]", i), r.Condition); err != nil {
+			return err
+		}
+	}
+	for i, r := range c.Jira.Rules {
+		if err := check(fmt.Sprintf("jira.rules[%d]", i), r.Condition); err != nil {
+			return err
+		}
+	}
+	for i, r := range c.Sentry.Rules {
+		if err := check(fmt.Sprintf("sentry.rules[%d]", i), r.Condition); err != nil {
+			return err
+		}
+	}
+	for i, r := range c.Bitbucket.Rules {
+		if err := check(fmt.Sprintf("bitbucket.rules[%d]", i), r.Condition); err != nil {
+			return err
+		}
+	}
+	for i, r := range c.Asana.Rules {
+		if err := check(fmt.Sprintf("asana.rules[%d]", i), r.Condition); err != nil {
+			return err
+		}
+	}
+	for i, r := range c.Alertmanager.Rules {
+		if err := check(fmt.Sprintf("alertmanager.rules[%d]", i), r.Condition); err != nil {
+			return err
+		}
+	}
+	for i, g := range c.GenericWebhooks {
+		for j, r := range g.Rules {
+			if err := check(fmt.Sprintf("generic_webhooks[%d].rules[%d]", i, j), r.Condition); err != nil {
+				return err
+			}
+		}
+	}
+	for i, acc := range c.Gmail.Accounts {
+		for j, r := range acc.Rules {
+			if err := check(fmt.Sprintf("gmail.accounts[%d].rules[%d].match", i, j), r.Match.Condition); err != nil {
+				return err
+			}
+			for _, s := range r.Match.Subject {
+				if _, err := SubjectRegexp(s); err != nil {
+					return fmt.Errorf("gmail.accounts[%d].rules[%d].match.subject %q: %w", i, j, s, err)
+				}
+			}
+		}
+	}
 	return nil
 }
 
@@ -239,6 +3002,26 @@ func (c *Config) ListIDToName(id string) string {
 	return ""
 }
 
+// AsanaSectionName returns the asana.sections name for a section GID, or "".
+func (c *Config) AsanaSectionName(gid string) string {
+	for name, id := range c.Asana.Sections {
+		if id == gid {
+			return name
+		}
+	}
+	return ""
+}
+
+// GenericWebhook returns the generic webhook config with the given name, or nil.
+func (c *Config) GenericWebhook(name string) *GenericWebhookConfig {
+	for i := range c.GenericWebhooks {
+		if c.GenericWebhooks[i].Name == name {
+			return &c.GenericWebhooks[i]
+		}
+	}
+	return nil
+}
+
 // ResolvedAccounts returns Gmail account configs with inherited poll interval.
 func (g GmailConfig) ResolvedAccounts() []GmailAccountConf {
 	out := make([]GmailAccountConf, 0, len(g.Accounts))
@@ -251,6 +3034,220 @@ func (g GmailConfig) ResolvedAccounts() []GmailAccountConf {
 	return out
 }
 
+// DefaultGitHubIssueTemplate returns the default template for issues and issue_comment events.
+func DefaultGitHubIssueTemplate() string {
+	return strings.TrimSpace(`
+[Webhook Event] GitHub event detected.
+
+Source: github
+Event: {{.Event}}
+Action: {{.Action}}
+Repository: {{.Repository}}
+Issue: #{{.IssueNumber}} {{.IssueTitle}}
+{{- if .URL}}
+URL: {{.URL}}
+{{- end}}
+{{- if .CardID}}
+Trello card: {{.CardName}} ({{.CardID}}){{if .CardList}} in {{.CardList}}{{end}}
+{{- end}}
+{{- if .Comment}}
+
+Comment by {{.CommentAuthor}}:
+{{.Comment}}
+{{- end}}
+`)
+}
+
+// DefaultSlackMessageTemplate returns the default template for Slack events.
+func DefaultSlackMessageTemplate() string {
+	return strings.TrimSpace(`
+[Webhook Event] Slack event detected.
+
+Source: slack
+Event: {{.EventType}}
+Channel: {{.Channel}}
+User: {{.User}}
+{{- if .ThreadTS}}
+Thread: {{.ThreadTS}}
+{{- end}}
+
+{{.Text}}
+`)
+}
+
+// DefaultDiscordMessageTemplate returns the default template for Discord commands.
+func DefaultDiscordMessageTemplate() string {
+	return strings.TrimSpace(`
+[Webhook Event] Discord command received.
+
+Source: discord
+Command: /{{.Command}}{{if .Subcommand}} {{.Subcommand}}{{end}}
+Guild: {{.Guild}}
+Channel: {{.Channel}}
+User: {{.Username}} ({{.User}})
+{{- if .TargetAuthor}}
+Message by: {{.TargetAuthor}}
+{{- end}}
+
+{{.Text}}
+`)
+}
+
+// DefaultNotionMessageTemplate returns the default template for Notion events.
+func DefaultNotionMessageTemplate() string {
+	return strings.TrimSpace(`
+[Webhook Event] Notion event detected.
+
+Source: notion
+Event: {{.Event}}
+Page: {{.Title}} ({{.PageID}})
+{{- if .PageURL}}
+URL: {{.PageURL}}
+{{- end}}
+{{- if .Property}}
+{{.Property}}: {{if .From}}{{.From}} → {{end}}{{.To}}
+{{- else if .Changed}}
+Changed: {{.Changed}}
+{{- end}}
+`)
+}
+
+// DefaultCalendarMessageTemplate returns the default template for calendar
+// rules.
+func DefaultCalendarMessageTemplate() string {
+	return strings.TrimSpace(`
+[Calendar] {{.Title}} starts in {{.StartsIn}} min.
+
+Source: calendar
+Account: {{.Account}}
+Calendar: {{.Calendar}}
+Start: {{.Start}}
+{{- if .Location}}
+Location: {{.Location}}
+{{- end}}
+{{- if .MeetURL}}
+Meet: {{.MeetURL}}
+{{- end}}
+{{- if .Attendees}}
+Attendees: {{.Attendees}}
+{{- end}}
+{{- if .URL}}
+URL: {{.URL}}
+{{- end}}
+`)
+}
+
+// DefaultSentryMessageTemplate returns the default template for Sentry alerts.
+func DefaultSentryMessageTemplate() string {
+	return strings.TrimSpace(`
+[Webhook Event] Sentry alert.
+
+Source: sentry
+Project: {{.Project}}
+Issue: {{.Title}}
+{{- if .ShortID}} ({{.ShortID}}){{end}}
+Level: {{.Level}}
+{{- if .Culprit}}
+Culprit: {{.Culprit}}
+{{- end}}
+{{- if .Environment}}
+Environment: {{.Environment}}
+{{- end}}
+{{- if .Count}}
+Events: {{.Count}}{{if .Users}} ({{.Users}} users){{end}}
+{{- end}}
+{{- if .URL}}
+URL: {{.URL}}
+{{- end}}
+`)
+}
+
+// DefaultAlertmanagerMessageTemplate returns the default template for Alertmanager notifications.
+func DefaultAlertmanagerMessageTemplate() string {
+	return strings.TrimSpace(`
+[Webhook Event] Alertmanager {{.Status}}.
+
+Source: alertmanager
+Alert: {{.AlertName}}
+{{- if .Severity}}
+Severity: {{.Severity}}
+{{- end}}
+{{- if .Summary}}
+Summary: {{.Summary}}
+{{- end}}
+Alerts: {{.Count}}
+{{- range .Alerts}}
+- [{{.Status}}] {{range $k, $v := .Labels}}{{$k}}={{$v}} {{end}}
+{{- end}}
+`)
+}
+
+// DefaultBitbucketMessageTemplate returns the default template for Bitbucket events.
+func DefaultBitbucketMessageTemplate() string {
+	return strings.TrimSpace(`
+[Webhook Event] Bitbucket {{.Event}}.
+
+Source: bitbucket
+Repository: {{.Repo}}
+{{- if .PRID}}
+Pull request: #{{.PRID}} {{.Title}} ({{.SourceBranch}} → {{.Branch}})
+Author: {{.Author}}
+{{- else}}
+Branch: {{.Branch}} @ {{.Commit}}
+{{- range .Commits}}
+- {{.Hash}} {{.Message}}
+{{- end}}
+{{- end}}
+By: {{.Actor}}
+{{- if .URL}}
+URL: {{.URL}}
+{{- end}}
+`)
+}
+
+// DefaultAsanaMessageTemplate returns the default template for Asana events.
+func DefaultAsanaMessageTemplate() string {
+	return strings.TrimSpace(`
+[Webhook Event] Asana {{.Event}}.
+
+Source: asana
+Task: {{if .TaskName}}{{.TaskName}}{{else}}{{.TaskID}}{{end}}
+{{- if .Section}}
+Section: {{.Section}}
+{{- end}}
+{{- if .Comment}}
+Comment: {{.Comment}}
+{{- end}}
+{{- if .UserName}}
+By: {{.UserName}}
+{{- end}}
+URL: {{.TaskURL}}
+`)
+}
+
+// DefaultJiraMessageTemplate returns the default template for Jira events.
+func DefaultJiraMessageTemplate() string {
+	return strings.TrimSpace(`
+[Webhook Event] Jira event detected.
+
+Source: jira
+Event: {{.Event}}
+Issue: {{.IssueKey}} {{.Summary}}
+Status: {{.Status}}
+{{- if .FromStatus}}
+From: {{.FromStatus}}
+{{- end}}
+{{- if .URL}}
+URL: {{.URL}}
+{{- end}}
+{{- if .Comment}}
+
+Comment by {{.CommentAuthor}}:
+{{.Comment}}
+{{- end}}
+`)
+}
+
 // DefaultGitHubMessageTemplate returns the default template for GitHub events.
 func DefaultGitHubMessageTemplate() string {
 	return strings.TrimSpace(`
@@ -267,5 +3264,50 @@ Title: {{.PRTitle}}
 {{- if .Conclusion}}
 Conclusion: {{.Conclusion}}
 {{- end}}
+{{- if .ReviewState}}
+Review: {{.ReviewState}} by {{.ReviewAuthor}}
+{{- end}}
+{{- if .Artifacts}}
+Artifacts:
+{{- range .Artifacts}}
+- {{.Name}}{{if .URL}}: {{.URL}}{{end}}
+{{- end}}
+{{- end}}
+{{- if .CardID}}
+Trello card: {{.CardName}} ({{.CardID}}){{if .CardList}} in {{.CardList}}{{end}}
+{{- end}}
+{{- if .ReviewBody}}
+
+{{.ReviewBody}}
+{{- end}}
+{{- if .Comment}}
+
+Comment by {{.CommentAuthor}}{{if .CommentPath}} on {{.CommentPath}}{{end}}:
+{{.Comment}}
+{{- end}}
+`)
+}
+
+// DefaultDriveMessageTemplate returns the default template for drive rules.
+func DefaultDriveMessageTemplate() string {
+	return strings.TrimSpace(`
+[Drive] {{.Name}} changed
+
+Source: drive
+Account: {{.Account}}
+File: {{.Name}} ({{.FileID}})
+Type: {{.MimeType}}
+Modified: {{.Modified}}
+{{- if .ModifiedBy}}
+Modified by: {{.ModifiedBy}}
+{{- end}}
+{{- if .Owners}}
+Owners: {{.Owners}}
+{{- end}}
+{{- if .URL}}
+URL: {{.URL}}
+{{- end}}
+
+Read it with GET /api/drive/file/{{.FileID}}
 `)
 }
@@ -3,8 +3,13 @@ package config
 import (
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 	"testing"
+	"time"
+
+	"github.com/katalabut/openclaw-relay/internal/sealed"
+	"gopkg.in/yaml.v3"
 )
 
 func TestLoad(t *testing.T) {
@@ -38,6 +43,30 @@ trello:
 	if cfg.ListIDToName("abc123") != "ready" {
 		t.Errorf("ListIDToName = %s, want ready", cfg.ListIDToName("abc123"))
 	}
+	if cfg.Path != cfgPath || !strings.Contains(string(cfg.Source), "${TEST_TOKEN}") {
+		t.Errorf("Path = %q, Source should keep ${TEST_TOKEN} unexpanded:\n%s", cfg.Path, cfg.Source)
+	}
+}
+
+func TestParse_Sealed(t *testing.T) {
+	key := strings.Repeat("ab", 32)
+	k, _ := sealed.ParseKey(key)
+	token, _ := sealed.Seal(k, "s3cret")
+	data := []byte("server:\n  internal_token: " + token + "\n")
+
+	t.Setenv("RELAY_ENCRYPTION_KEY", key)
+	cfg, err := Parse(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Server.InternalToken != "s3cret" {
+		t.Errorf("internal_token = %q", cfg.Server.InternalToken)
+	}
+
+	t.Setenv("RELAY_ENCRYPTION_KEY", "")
+	if _, err := Parse(data); err == nil || !strings.Contains(err.Error(), "sealed value") {
+		t.Errorf("expected an error without the key, got %v", err)
+	}
 }
 
 func TestLoad_MissingFile(t *testing.T) {
@@ -241,3 +270,1427 @@ gateway:
 		t.Errorf("expected my-model, got %s", cfg.Gateway.Model)
 	}
 }
+
+func TestResolvedScopes_Default(t *testing.T) {
+	got := GoogleConfig{}.ResolvedScopes()
+	want := DefaultGoogleScopes()
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("ResolvedScopes() = %v, want %v", got, want)
+	}
+}
+
+func TestResolvedScopes_ShortNamesAndEmail(t *testing.T) {
+	g := GoogleConfig{Scopes: []string{"gmail.readonly", "https://www.googleapis.com/auth/calendar.readonly", "gmail.readonly"}}
+	got := g.ResolvedScopes()
+	want := []string{
+		"https://www.googleapis.com/auth/gmail.readonly",
+		"https://www.googleapis.com/auth/calendar.readonly",
+		"https://www.googleapis.com/auth/userinfo.email",
+	}
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("ResolvedScopes() = %v, want %v", got, want)
+	}
+}
+
+func TestResolvedScopes_EmailAliasNotDuplicated(t *testing.T) {
+	g := GoogleConfig{Scopes: []string{"calendar.readonly", "email"}}
+	got := g.ResolvedScopes()
+	if len(got) != 2 || got[1] != "email" {
+		t.Errorf("unexpected scopes: %v", got)
+	}
+}
+
+func TestParse_CalendarWriteScope(t *testing.T) {
+	cfg, err := Parse([]byte("calendar:\n  enabled: true\n  write: true\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := cfg.Google.ResolvedScopes()
+	want := append(DefaultGoogleScopes(), "https://www.googleapis.com/auth/calendar.events")
+	if !slices.Equal(got, want) {
+		t.Errorf("scopes = %v, want %v", got, want)
+	}
+	cfg, _ = Parse([]byte("google:\n  scopes: [gmail.readonly]\ncalendar:\n  enabled: true\n"))
+	if got := cfg.Google.ResolvedScopes(); len(got) != 2 {
+		t.Errorf("read-only calendar added scopes: %v", got)
+	}
+	cfg, _ = Parse([]byte("google:\n  scopes: [gmail.readonly]\ndrive:\n  enabled: true\n"))
+	if got := cfg.Google.ResolvedScopes(); !slices.Contains(got, "https://www.googleapis.com/auth/drive.readonly") {
+		t.Errorf("drive scope missing: %v", got)
+	}
+	cfg, _ = Parse([]byte("google:\n  scopes: [gmail.readonly]\ntasks:\n  enabled: true\n  accounts: [me@example.com]\n"))
+	if got := cfg.Google.ResolvedScopes(); !slices.Contains(got, "https://www.googleapis.com/auth/tasks.readonly") {
+		t.Errorf("tasks scope missing: %v", got)
+	}
+	cfg.InMemory = true
+	cfg.Tasks.Accounts = nil
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "tasks.accounts is required") {
+		t.Errorf("tasks without accounts: %v", err)
+	}
+}
+
+func TestValidate_GoogleScopes(t *testing.T) {
+	gmail := func(actions ...GmailAction) GmailConfig {
+		var rules RuleList[GmailRule]
+		for _, a := range actions {
+			rules = append(rules, GmailRule{Name: "r", Action: a})
+		}
+		return GmailConfig{Enabled: true, Accounts: []GmailAccountConf{{Email: "me@example.com", Rules: rules}}}
+	}
+	tests := []struct {
+		name     string
+		clientID string
+		scopes   []string
+		gmail    GmailConfig
+		calendar bool
+		wantErr  string
+	}{
+		{"defaults", "id", nil, gmail(GmailAction{Kind: "modify", Archive: true}), true, ""},
+		{"read-only mail", "id", []string{"gmail.readonly"}, gmail(GmailAction{}), false, ""},
+		{"full mail", "id", []string{"https://mail.google.com/"}, gmail(GmailAction{Kind: "modify", Archive: true}), false, ""},
+		{"malformed", "id", []string{"gmail readonly"}, GmailConfig{}, false, "google.scopes[0]"},
+		{"malformed without oauth", "", []string{"Gmail.Modify"}, GmailConfig{}, false, "google.scopes[0]"},
+		{"no mail scope", "id", []string{"calendar.readonly"}, gmail(GmailAction{}), false, "gmail.accounts[0]: google.scopes needs gmail.readonly"},
+		{"modify on read-only", "id", []string{"gmail.readonly"}, gmail(GmailAction{}, GmailAction{Kind: "modify", MarkRead: true}), false, "gmail.accounts[0].rules[1]: kind modify"},
+		{"forward with send", "id", []string{"gmail.readonly", "gmail.send"}, gmail(GmailAction{Kind: "forward", To: []string{"a@example.com"}}), false, ""},
+		{"forward on read-only", "id", []string{"gmail.readonly"}, gmail(GmailAction{Kind: "forward", To: []string{"a@example.com"}}), false, "kind forward"},
+		{"calendar", "id", []string{"gmail.readonly"}, GmailConfig{}, true, "calendar: google.scopes needs calendar.readonly"},
+		{"no oauth", "", []string{"gmail.readonly"}, gmail(GmailAction{Kind: "modify", Archive: true}), false, ""},
+	}
+	for _, tt := range tests {
+		cfg := Config{InMemory: true, Google: GoogleConfig{ClientID: tt.clientID, Scopes: tt.scopes}, Gmail: tt.gmail}
+		if tt.calendar {
+			cfg.Calendar = CalendarConfig{Enabled: true, Accounts: []string{"me@example.com"}}
+		}
+		err := cfg.Validate()
+		if tt.wantErr == "" && err != nil {
+			t.Errorf("%s: unexpected error %v", tt.name, err)
+		}
+		if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+			t.Errorf("%s: expected error containing %q, got %v", tt.name, tt.wantErr, err)
+		}
+	}
+}
+
+func TestValidate_GenericWebhooks(t *testing.T) {
+	cfg := &Config{
+		Gateway:         GatewayConfig{URL: "http://gw"},
+		GenericWebhooks: []GenericWebhookConfig{{Name: "sentry"}, {Name: "sentry"}},
+	}
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "duplicated") {
+		t.Errorf("expected duplicate name error, got %v", err)
+	}
+
+	cfg.GenericWebhooks = []GenericWebhookConfig{{Name: "a/b"}}
+	if err := cfg.Validate(); err == nil {
+		t.Error("expected error for name with slash")
+	}
+
+	cfg.GenericWebhooks = []GenericWebhookConfig{{Name: "sentry"}}
+	cfg.Gateway.URL = ""
+	if err := cfg.Validate(); err == nil {
+		t.Error("expected gateway.url to be required")
+	}
+}
+
+func TestGenericWebhookLookup(t *testing.T) {
+	cfg := &Config{GenericWebhooks: []GenericWebhookConfig{{Name: "sentry"}}}
+	if cfg.GenericWebhook("sentry") == nil {
+		t.Error("expected sentry webhook")
+	}
+	if cfg.GenericWebhook("stripe") != nil {
+		t.Error("expected nil for unknown webhook")
+	}
+}
+
+func TestLoad_ReadOnly(t *testing.T) {
+	dir := t.TempDir()
+	cfgPath := filepath.Join(dir, "config.yaml")
+	os.WriteFile(cfgPath, []byte("read_only: true\n"), 0644)
+
+	cfg, err := Load(cfgPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !cfg.ReadOnly {
+		t.Error("expected read_only to be parsed")
+	}
+}
+
+func TestValidate_SlackRules(t *testing.T) {
+	cfg := &Config{Slack: SlackConfig{Rules: []SlackRule{{Event: "app_mention"}}}}
+	if err := cfg.Validate(); err == nil {
+		t.Error("expected gateway.url to be required for slack rules")
+	}
+
+	cfg.Gateway.URL = "http://gw"
+	cfg.Slack.Rules = append(cfg.Slack.Rules, SlackRule{})
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "slack.rules[1].event") {
+		t.Errorf("expected empty event error, got %v", err)
+	}
+}
+
+func TestValidate_JiraRules(t *testing.T) {
+	cfg := &Config{
+		Gateway: GatewayConfig{URL: "http://gw"},
+		Jira:    JiraConfig{Rules: []JiraRule{{Event: "issue_created"}, {Event: "issue_deleted"}}},
+	}
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "jira.rules[1].event") {
+		t.Errorf("expected unknown event error, got %v", err)
+	}
+
+	cfg.Jira.Rules = cfg.Jira.Rules[:1]
+	cfg.Gateway.URL = ""
+	if err := cfg.Validate(); err == nil {
+		t.Error("expected gateway.url to be required for jira rules")
+	}
+}
+
+func TestValidate_InMemoryWithoutGateway(t *testing.T) {
+	cfg := &Config{InMemory: true, Trello: TrelloConfig{Rules: []TrelloRule{{Event: "card_moved"}}}}
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("expected in-memory mode to skip gateway.url check, got %v", err)
+	}
+}
+
+func TestValidate_MaxInFlight(t *testing.T) {
+	cfg := &Config{Server: ServerConfig{MaxInFlight: map[string]int{"trello": -1}}}
+	if err := cfg.Validate(); err == nil {
+		t.Error("expected error for negative max_in_flight")
+	}
+	cfg.Server.MaxInFlight = map[string]int{"trello": 4}
+	cfg.Server.InFlightWait = "soon"
+	if err := cfg.Validate(); err == nil {
+		t.Error("expected error for invalid in_flight_wait")
+	}
+}
+
+func TestValidate_AuditSample(t *testing.T) {
+	cfg := &Config{Audit: AuditConfig{Sample: map[string]int{"/webhook/github": 0}}}
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "audit.sample./webhook/github") {
+		t.Errorf("expected audit.sample error, got %v", err)
+	}
+	cfg.Audit.Sample["/webhook/github"] = 10
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestValidate_AuditOutput(t *testing.T) {
+	tests := []struct {
+		audit AuditConfig
+		want  string
+	}{
+		{AuditConfig{Output: "stdout"}, ""},
+		{AuditConfig{Output: "syslog", Syslog: AuditSyslogConfig{Address: "udp://logs:514", Facility: "local3"}}, ""},
+		{AuditConfig{Output: "syslog", Syslog: AuditSyslogConfig{Address: "logs:514"}}, "audit.syslog.address"},
+		{AuditConfig{Output: "syslog", Syslog: AuditSyslogConfig{Address: "tcp://logs:601", Facility: "local9"}}, "audit.syslog.facility"},
+		{AuditConfig{Output: "http", HTTP: AuditHTTPConfig{URL: "https://loki:3100/loki/api/v1/push", Format: "loki", FlushInterval: "2s"}}, ""},
+		{AuditConfig{Output: "http"}, "audit.http.url"},
+		{AuditConfig{Output: "http", HTTP: AuditHTTPConfig{URL: "http://c", Format: "gelf"}}, "audit.http.format"},
+		{AuditConfig{Output: "http", HTTP: AuditHTTPConfig{URL: "http://c", FlushInterval: "0s"}}, "audit.http.flush_interval"},
+		{AuditConfig{Output: "kafka"}, "audit.output"},
+		{AuditConfig{Retention: "30d", Sources: map[string]AuditSourceConfig{"github": {Retention: "12h"}}}, ""},
+		{AuditConfig{Retention: "a month"}, "audit.retention"},
+		{AuditConfig{Sources: map[string]AuditSourceConfig{"github": {Retention: "0d"}}}, "audit.sources.github.retention"},
+		{AuditConfig{Sources: map[string]AuditSourceConfig{"../etc": {}}}, "invalid source name"},
+		{AuditConfig{Output: "stdout", Sources: map[string]AuditSourceConfig{"github": {}}}, "audit.sources needs output: file"},
+	}
+	for _, tt := range tests {
+		err := (&Config{Audit: tt.audit}).Validate()
+		if tt.want == "" && err != nil {
+			t.Errorf("%+v: unexpected error %v", tt.audit, err)
+		}
+		if tt.want != "" && (err == nil || !strings.Contains(err.Error(), tt.want)) {
+			t.Errorf("%+v: expected %s error, got %v", tt.audit, tt.want, err)
+		}
+	}
+	a := AuditConfig{LogPath: "/data/audit.log", Retention: "7d", Sources: map[string]AuditSourceConfig{
+		"github": {Retention: "12h"},
+		"trello": {LogPath: "/logs/trello.log"},
+	}}
+	if a.SourceLogPath("github") != "/data/audit-github.log" || a.SourceLogPath("trello") != "/logs/trello.log" {
+		t.Errorf("unexpected source paths %q %q", a.SourceLogPath("github"), a.SourceLogPath("trello"))
+	}
+	if a.SourceRetention("github") != 12*time.Hour || a.SourceRetention("trello") != 7*24*time.Hour || (AuditConfig{}).ResolvedRetention() != 0 {
+		t.Errorf("unexpected retentions %v %v", a.SourceRetention("github"), a.SourceRetention("trello"))
+	}
+	h := AuditHTTPConfig{}
+	if h.ResolvedBatchSize() != 100 || h.ResolvedFlushInterval() != 5*time.Second {
+		t.Errorf("unexpected defaults %d %v", h.ResolvedBatchSize(), h.ResolvedFlushInterval())
+	}
+}
+
+func TestResolvedInFlightWait(t *testing.T) {
+	if got := (ServerConfig{}).ResolvedInFlightWait(); got != 5*time.Second {
+		t.Errorf("expected default 5s, got %v", got)
+	}
+	if got := (ServerConfig{InFlightWait: "250ms"}).ResolvedInFlightWait(); got != 250*time.Millisecond {
+		t.Errorf("expected 250ms, got %v", got)
+	}
+}
+
+func TestValidate_GitHubRules(t *testing.T) {
+	cfg := &Config{GitHub: GitHubConfig{Rules: []GitHubRule{{Event: "workflow_run"}}}}
+	if err := cfg.Validate(); err == nil {
+		t.Error("expected gateway.url to be required for github rules")
+	}
+	cfg.Gateway.URL = "http://gw"
+	cfg.GitHub.Rules = append(cfg.GitHub.Rules, GitHubRule{})
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "github.rules[1].event") {
+		t.Errorf("expected empty event error, got %v", err)
+	}
+}
+
+func TestValidate_GitHubEvents(t *testing.T) {
+	cfg := &Config{GitHub: GitHubConfig{Events: []string{"issues", "issue_comment", "pull_request"}}}
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	cfg.GitHub.Events = append(cfg.GitHub.Events, "push")
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "push") {
+		t.Errorf("expected unknown event error, got %v", err)
+	}
+}
+
+func TestGitHubConfig_ResolvedTemplate(t *testing.T) {
+	g := GitHubConfig{}
+	if g.ResolvedTemplate("issues") != DefaultGitHubIssueTemplate() {
+		t.Error("expected issue default for issues")
+	}
+	if g.ResolvedTemplate("check_run") != DefaultGitHubMessageTemplate() {
+		t.Error("expected PR default for check_run")
+	}
+	g.MessageTemplate = "all"
+	g.Templates = map[string]string{"issue_comment": "comment"}
+	if got := g.ResolvedTemplate("issue_comment"); got != "comment" {
+		t.Errorf("expected per-event template, got %q", got)
+	}
+	if got := g.ResolvedTemplate("issues"); got != "all" {
+		t.Errorf("expected message_template fallback, got %q", got)
+	}
+}
+
+func TestResolvedHistoryLimits(t *testing.T) {
+	if p, m := (GmailConfig{}).ResolvedHistoryLimits(); p != 10 || m != 500 {
+		t.Errorf("defaults = %d/%d, want 10/500", p, m)
+	}
+	if p, m := (GmailConfig{MaxHistoryPages: 3, MaxHistoryMessages: -1}).ResolvedHistoryLimits(); p != 3 || m != 0 {
+		t.Errorf("got %d/%d, want 3/0 (unlimited)", p, m)
+	}
+}
+
+func TestValidate_GitHubApp(t *testing.T) {
+	cfg := &Config{GitHub: GitHubConfig{App: &GitHubAppConfig{AppID: 1}}}
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "github.app") {
+		t.Errorf("expected missing key error, got %v", err)
+	}
+	cfg.GitHub.App.PrivateKeyFile = "/run/secrets/github-app.pem"
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestValidate_EventTTL(t *testing.T) {
+	cfg := &Config{Server: ServerConfig{EventTTL: "soon"}}
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "event_ttl") {
+		t.Errorf("expected event_ttl error, got %v", err)
+	}
+	cfg.Server.EventTTL = "2h"
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if got := cfg.Server.ResolvedEventTTL(); got != 2*time.Hour {
+		t.Errorf("ResolvedEventTTL() = %v, want 2h", got)
+	}
+	if got := (ServerConfig{}).ResolvedEventTTL(); got != 0 {
+		t.Errorf("expected no TTL by default, got %v", got)
+	}
+}
+
+func TestValidate_TrelloAPICredentials(t *testing.T) {
+	cfg := &Config{Trello: TrelloConfig{APIKey: "key"}}
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "trello.token") {
+		t.Errorf("expected missing token error, got %v", err)
+	}
+	cfg.Trello.Token = "token"
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestValidate_Schedules(t *testing.T) {
+	cfg := &Config{InMemory: true, Server: ServerConfig{Timezone: "Nowhere/City"}}
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "server.timezone") {
+		t.Errorf("expected server.timezone error, got %v", err)
+	}
+	cfg.Server.Timezone = "Europe/Berlin"
+	cfg.Jira.Rules = []JiraRule{{Event: "issue_created", Action: RuleAction{Schedule: "next business day 9am"}}}
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	cfg.Jira.Rules[0].Action.Schedule = "whenever"
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "jira.rules[0].action.schedule") {
+		t.Errorf("expected schedule error, got %v", err)
+	}
+	cfg.Jira.Rules[0].Action.Schedule = "9am"
+	cfg.Gmail.Accounts = []GmailAccountConf{{Email: "a@example.com", Rules: []GmailRule{{Action: GmailAction{Schedule: "9am", Timezone: "Bad/Zone"}}}}}
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "gmail.accounts[0].rules[0].action.timezone") {
+		t.Errorf("expected timezone error, got %v", err)
+	}
+}
+
+func TestValidate_TrelloBoards(t *testing.T) {
+	cfg := &Config{Trello: TrelloConfig{Boards: []string{"5f00"}, APIKey: "key", Token: "token"}}
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "trello.boards") {
+		t.Errorf("expected missing callback_url error, got %v", err)
+	}
+	cfg.Trello.CallbackURL = "https://relay.example.com/webhook/trello"
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if got := cfg.Trello.ResolvedBoardRefresh(); got != 15*time.Minute {
+		t.Errorf("expected default board refresh 15m, got %v", got)
+	}
+	cfg.Trello.BoardRefresh = "-1m"
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "trello.board_refresh") {
+		t.Errorf("expected board_refresh error, got %v", err)
+	}
+	cfg.Trello.BoardRefresh = ""
+
+	cfg.Trello.Lists = map[string]string{"ready": "5f01"}
+	cfg.Trello.ListNames = map[string]string{"doing": "In Progress", "ready": "Ready"}
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "trello.list_names.ready") {
+		t.Errorf("expected duplicate alias error, got %v", err)
+	}
+	delete(cfg.Trello.ListNames, "ready")
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	cfg.Trello.Boards = nil
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "trello.list_names requires") {
+		t.Errorf("expected list_names without boards error, got %v", err)
+	}
+}
+
+func TestValidate_TrelloWatchLists(t *testing.T) {
+	cfg := &Config{InMemory: true, Trello: TrelloConfig{Lists: map[string]string{"questions": "5f01"}, WatchLists: []string{"questions", "review"}}}
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), `trello.watch_lists: "review"`) {
+		t.Errorf("expected unknown alias error, got %v", err)
+	}
+	cfg.Trello.WatchLists = []string{"questions"}
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if got := cfg.Trello.ResolvedWatchTTL(); got != 720*time.Hour {
+		t.Errorf("expected default watch ttl 720h, got %v", got)
+	}
+	cfg.Trello.WatchTTL = "0s"
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "trello.watch_ttl") {
+		t.Errorf("expected watch_ttl error, got %v", err)
+	}
+}
+
+func TestValidate_Sample(t *testing.T) {
+	cfg := &Config{InMemory: true, Slack: SlackConfig{Rules: []SlackRule{{Event: "message", Sample: 1.5}}}}
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "slack.rules[0].sample") {
+		t.Errorf("expected sample range error, got %v", err)
+	}
+	cfg.Slack.Rules[0].Sample = 0.1
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	var r TrelloRule
+	if err := yaml.Unmarshal([]byte("event: card_moved\nsample: 0.25\n"), &r); err != nil || r.Sample != 0.25 {
+		t.Errorf("expected sample 0.25 from YAML, got %v (%v)", r.Sample, err)
+	}
+}
+
+func TestValidate_Calendar(t *testing.T) {
+	enabled := func(rules ...CalendarRule) CalendarConfig {
+		return CalendarConfig{Enabled: true, Accounts: []string{"me@example.com"}, Rules: rules}
+	}
+	tests := []struct {
+		name    string
+		cal     CalendarConfig
+		wantErr string
+	}{
+		{"disabled", CalendarConfig{Rules: []CalendarRule{{Before: "soon"}}}, ""},
+		{"ok", enabled(CalendarRule{Accounts: []string{"me@example.com"}, Title: []string{"/^1:1/"}, Before: "15m"}), ""},
+		{"no accounts", CalendarConfig{Enabled: true}, "calendar.accounts is required"},
+		{"poll interval", CalendarConfig{Enabled: true, Accounts: []string{"me@example.com"}, PollInterval: "0s"}, "calendar.poll_interval"},
+		{"rule account", enabled(CalendarRule{Accounts: []string{"other@example.com"}}), "calendar.rules[0].accounts"},
+		{"before", enabled(CalendarRule{}, CalendarRule{Before: "-5m"}), "calendar.rules[1].before"},
+		{"title", enabled(CalendarRule{Title: []string{"/(/"}}), "calendar.rules[0].title"},
+		{"schedule", enabled(CalendarRule{Action: RuleAction{Schedule: "tomorrow 9am"}}), "calendar.rules[0].action.schedule"},
+		{"template", enabled(CalendarRule{Action: RuleAction{MessageTemplate: "{{.Title"}}), "calendar.rules[0].action.message_template"},
+	}
+	for _, tt := range tests {
+		cfg := Config{InMemory: true, Calendar: tt.cal}
+		err := cfg.Validate()
+		if tt.wantErr == "" && err != nil {
+			t.Errorf("%s: unexpected error %v", tt.name, err)
+		}
+		if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+			t.Errorf("%s: expected error containing %q, got %v", tt.name, tt.wantErr, err)
+		}
+	}
+
+	r := CalendarRule{}
+	if r.ResolvedBefore() != 10*time.Minute || r.ResolvedCalendars()[0] != "primary" || (CalendarConfig{}).ResolvedPollInterval() != time.Minute {
+		t.Error("unexpected calendar defaults")
+	}
+}
+
+func TestValidate_Drive(t *testing.T) {
+	enabled := func(rules ...DriveRule) DriveConfig {
+		return DriveConfig{Enabled: true, Accounts: []string{"me@example.com"}, Rules: rules}
+	}
+	tests := []struct {
+		name    string
+		drive   DriveConfig
+		wantErr string
+	}{
+		{"disabled", DriveConfig{PollInterval: "soon"}, ""},
+		{"ok", enabled(DriveRule{Accounts: []string{"me@example.com"}, MimeTypes: []string{"application/vnd.google-apps.*"}, RateLimit: "1h", Action: RuleAction{Schedule: "tomorrow 9am"}}), ""},
+		{"no accounts", DriveConfig{Enabled: true}, "drive.accounts is required"},
+		{"poll interval", DriveConfig{Enabled: true, Accounts: []string{"me@example.com"}, PollInterval: "0s"}, "drive.poll_interval"},
+		{"max download", DriveConfig{Enabled: true, Accounts: []string{"me@example.com"}, MaxDownloadMB: -1}, "drive.max_download_mb"},
+		{"rate limit", DriveConfig{Enabled: true, Accounts: []string{"me@example.com"}, RateLimit: "often"}, "drive.rate_limit"},
+		{"rule account", enabled(DriveRule{Accounts: []string{"other@example.com"}}), "drive.rules[0].accounts"},
+		{"mime type", enabled(DriveRule{}, DriveRule{MimeTypes: []string{"/(/"}}), "drive.rules[1].mime_types"},
+		{"rule rate limit", enabled(DriveRule{RateLimit: "often"}), "drive.rules[0].rate_limit"},
+		{"schedule", enabled(DriveRule{Action: RuleAction{Schedule: "whenever"}}), "drive.rules[0].action"},
+		{"template", enabled(DriveRule{Action: RuleAction{MessageTemplate: "{{.Name"}}), "drive.rules[0].action.message_template"},
+	}
+	for _, tt := range tests {
+		cfg := Config{InMemory: true, Drive: tt.drive}
+		err := cfg.Validate()
+		if tt.wantErr == "" && err != nil {
+			t.Errorf("%s: unexpected error %v", tt.name, err)
+		}
+		if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+			t.Errorf("%s: expected error containing %q, got %v", tt.name, tt.wantErr, err)
+		}
+	}
+
+	if (DriveConfig{}).ResolvedMaxDownloadBytes() != 25<<20 || (DriveConfig{MaxDownloadMB: 2}).ResolvedMaxDownloadBytes() != 2<<20 || (DriveConfig{}).ResolvedPollInterval() != time.Minute {
+		t.Error("unexpected drive defaults")
+	}
+}
+
+func TestValidate_DedupKeys(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     Config
+		wantErr string
+	}{
+		{"ok", Config{Trello: TrelloConfig{Rules: []TrelloRule{{Event: "card_moved", DedupKey: "{{.CardID}}"}}}}, ""},
+		{"rule", Config{GitHub: GitHubConfig{Rules: []GitHubRule{{Event: "push", DedupKey: "{{.Repository"}}}}, "github.rules[0].dedup_key"},
+		{"generic", Config{GenericWebhooks: []GenericWebhookConfig{{Name: "ci", Rules: []GenericRule{{DedupKey: "{{end}}"}}}}}, "generic_webhooks[0].rules[0].dedup_key"},
+	}
+	for _, tt := range tests {
+		tt.cfg.InMemory = true
+		err := tt.cfg.Validate()
+		if tt.wantErr == "" && err != nil {
+			t.Errorf("%s: unexpected error %v", tt.name, err)
+		}
+		if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+			t.Errorf("%s: expected error containing %q, got %v", tt.name, tt.wantErr, err)
+		}
+	}
+}
+
+func TestValidate_RateLimits(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     Config
+		wantErr string
+	}{
+		{"ok", Config{Server: ServerConfig{RateLimit: "10m"}, GitHub: GitHubConfig{RateLimit: "30s", Rules: []GitHubRule{{Event: "push", RateLimit: "1m"}}}}, ""},
+		{"server", Config{Server: ServerConfig{RateLimit: "soon"}}, "server.rate_limit"},
+		{"source", Config{Trello: TrelloConfig{RateLimit: "0s"}}, "trello.rate_limit"},
+		{"rule", Config{Sentry: SentryConfig{Rules: []SentryRule{{RateLimit: "-1m"}}}}, "sentry.rules[0].rate_limit"},
+	}
+	for _, tt := range tests {
+		tt.cfg.InMemory = true
+		err := tt.cfg.Validate()
+		if tt.wantErr == "" && err != nil {
+			t.Errorf("%s: unexpected error %v", tt.name, err)
+		}
+		if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+			t.Errorf("%s: expected error containing %q, got %v", tt.name, tt.wantErr, err)
+		}
+	}
+
+	var s ServerConfig
+	if s.ResolvedRateLimit() != 5*time.Minute {
+		t.Errorf("expected 5m default, got %v", s.ResolvedRateLimit())
+	}
+	if got := RateWindow("", "2m"); got != 2*time.Minute {
+		t.Errorf("expected the source window, got %v", got)
+	}
+	if got := RateWindow("30s", "2m"); got != 30*time.Second {
+		t.Errorf("expected the rule window, got %v", got)
+	}
+	if got := RateWindow("", ""); got != 0 {
+		t.Errorf("expected 0 for the limiter default, got %v", got)
+	}
+}
+
+func TestValidate_Dataset(t *testing.T) {
+	cfg := &Config{InMemory: true, Dataset: DatasetConfig{Path: "data/dataset.jsonl", Sample: 2}}
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "dataset.sample") {
+		t.Errorf("expected sample error, got %v", err)
+	}
+	cfg.Dataset.Sample = 0.2
+	cfg.Dataset.Redact = []string{"[a-"}
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "dataset.redact") {
+		t.Errorf("expected redact error, got %v", err)
+	}
+	cfg.Dataset.Redact = []string{`INV-\d+`}
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestValidate_IPAllowlist(t *testing.T) {
+	tests := []struct {
+		name    string
+		allow   IPAllowlistConfig
+		wantErr string
+	}{
+		{"ok", IPAllowlistConfig{Default: []string{"10.0.0.0/8", "192.168.1.5"}, Sources: map[string][]string{"github": {"2a0a:a440::/29"}}, GitHubMeta: true, Refresh: "6h"}, ""},
+		{"bad default", IPAllowlistConfig{Default: []string{"10.0.0.0/33"}}, "server.ip_allowlist.default"},
+		{"bad source", IPAllowlistConfig{Sources: map[string][]string{"trello": {"trello.com"}}}, "server.ip_allowlist.sources.trello"},
+		{"bad proxy", IPAllowlistConfig{TrustedProxies: []string{"proxy"}}, "server.ip_allowlist.trusted_proxies"},
+		{"bad refresh", IPAllowlistConfig{GitHubMeta: true, Refresh: "0s"}, "server.ip_allowlist.refresh"},
+	}
+	for _, tt := range tests {
+		cfg := &Config{InMemory: true, Server: ServerConfig{IPAllowlist: tt.allow}}
+		err := cfg.Validate()
+		if tt.wantErr == "" && err != nil {
+			t.Errorf("%s: unexpected error %v", tt.name, err)
+		}
+		if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+			t.Errorf("%s: expected error containing %q, got %v", tt.name, tt.wantErr, err)
+		}
+	}
+
+	var a IPAllowlistConfig
+	if a.Enabled() || a.ResolvedRefresh() != 24*time.Hour {
+		t.Error("unexpected defaults")
+	}
+}
+
+func TestValidate_ClockSkew(t *testing.T) {
+	cfg := &Config{InMemory: true, Server: ServerConfig{ClockSkew: ClockSkewConfig{Tolerance: "10m", Warn: "soon"}}}
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "server.clock_skew.warn") {
+		t.Errorf("expected warn error, got %v", err)
+	}
+	cfg.Server.ClockSkew.Warn = "30s"
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if cfg.Server.ClockSkew.ResolvedTolerance() != 10*time.Minute || cfg.Server.ClockSkew.ResolvedWarn() != 30*time.Second {
+		t.Error("unexpected resolved values")
+	}
+	var c ClockSkewConfig
+	if c.ResolvedTolerance() != 5*time.Minute || c.ResolvedWarn() != time.Minute {
+		t.Error("unexpected defaults")
+	}
+}
+
+func TestValidate_CORS(t *testing.T) {
+	tests := []struct {
+		name    string
+		rule    CORSRule
+		wantErr string
+	}{
+		{"ok", CORSRule{Origins: []string{"https://dash.example.com", "http://localhost:5173"}, Credentials: true, MaxAge: "1h"}, ""},
+		{"wildcard", CORSRule{Path: "/api/gmail/", Origins: []string{"*"}}, ""},
+		{"no origins", CORSRule{}, "server.cors[0].origins"},
+		{"wildcard with credentials", CORSRule{Origins: []string{"*"}, Credentials: true}, "cannot be combined with credentials"},
+		{"not an origin", CORSRule{Origins: []string{"https://dash.example.com/app"}}, "is not an origin"},
+		{"bad path", CORSRule{Path: "api", Origins: []string{"*"}}, "server.cors[0].path"},
+		{"bad max_age", CORSRule{Origins: []string{"*"}, MaxAge: "forever"}, "server.cors[0].max_age"},
+	}
+	for _, tt := range tests {
+		cfg := &Config{InMemory: true, Server: ServerConfig{CORS: []CORSRule{tt.rule}}}
+		err := cfg.Validate()
+		if tt.wantErr == "" && err != nil {
+			t.Errorf("%s: unexpected error %v", tt.name, err)
+		}
+		if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+			t.Errorf("%s: expected error containing %q, got %v", tt.name, tt.wantErr, err)
+		}
+	}
+
+	var r CORSRule
+	if r.ResolvedPath() != "/api/" || len(r.ResolvedHeaders()) != 2 || len(r.ResolvedMethods()) != 5 || r.ResolvedMaxAge() != 10*time.Minute {
+		t.Error("unexpected defaults")
+	}
+}
+
+func TestGmailConfig_APICache(t *testing.T) {
+	if d := (GmailConfig{}).ResolvedAPICache(); d != 0 {
+		t.Errorf("default = %v, want 0", d)
+	}
+	if d := (GmailConfig{APICache: "15s"}).ResolvedAPICache(); d != 15*time.Second {
+		t.Errorf("got %v", d)
+	}
+	for _, v := range []string{"soon", "-5s"} {
+		cfg := &Config{InMemory: true, Gmail: GmailConfig{APICache: v}}
+		if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "gmail.api_cache") {
+			t.Errorf("%q: expected error, got %v", v, err)
+		}
+	}
+}
+
+func TestGmailRetryConfig(t *testing.T) {
+	var r GmailRetryConfig
+	base, max := r.ResolvedDelays()
+	if r.ResolvedMaxRetries() != 4 || r.ResolvedMaxConcurrent() != 10 || base != time.Second || max != 30*time.Second {
+		t.Errorf("defaults: %d retries, %d concurrent, %v..%v", r.ResolvedMaxRetries(), r.ResolvedMaxConcurrent(), base, max)
+	}
+	r = GmailRetryConfig{MaxRetries: -1, MaxConcurrent: -1, BaseDelay: "200ms", MaxDelay: "5s"}
+	base, max = r.ResolvedDelays()
+	if r.ResolvedMaxRetries() != 0 || r.ResolvedMaxConcurrent() != 0 || base != 200*time.Millisecond || max != 5*time.Second {
+		t.Errorf("got %d retries, %d concurrent, %v..%v", r.ResolvedMaxRetries(), r.ResolvedMaxConcurrent(), base, max)
+	}
+
+	tests := []struct {
+		retry   GmailRetryConfig
+		wantErr string
+	}{
+		{GmailRetryConfig{BaseDelay: "soon"}, "gmail.retry.base_delay"},
+		{GmailRetryConfig{MaxDelay: "-1s"}, "gmail.retry.max_delay"},
+		{GmailRetryConfig{BaseDelay: "1m", MaxDelay: "10s"}, "must not exceed"},
+	}
+	for _, tt := range tests {
+		cfg := &Config{InMemory: true, Gmail: GmailConfig{Retry: tt.retry}}
+		if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("%+v: expected %q, got %v", tt.retry, tt.wantErr, err)
+		}
+	}
+}
+
+func TestGmailConfig_DailyQuota(t *testing.T) {
+	g := GmailConfig{Quota: GmailQuotaConfig{DailyUnits: 100000}}
+	tests := []struct {
+		acc  GmailAccountConf
+		want int
+	}{
+		{GmailAccountConf{}, 100000},
+		{GmailAccountConf{DailyQuota: 5000}, 5000},
+		{GmailAccountConf{DailyQuota: -1}, 0},
+	}
+	for _, tt := range tests {
+		if got := g.ResolvedDailyQuota(tt.acc); got != tt.want {
+			t.Errorf("daily_quota %d: got %d, want %d", tt.acc.DailyQuota, got, tt.want)
+		}
+	}
+	if g.Quota.ResolvedThrottleAt() != 80 {
+		t.Errorf("throttle_at default = %d", g.Quota.ResolvedThrottleAt())
+	}
+
+	for _, tt := range []struct {
+		gmail   GmailConfig
+		wantErr string
+	}{
+		{GmailConfig{Quota: GmailQuotaConfig{DailyUnits: -1}}, "gmail.quota.daily_units"},
+		{GmailConfig{Quota: GmailQuotaConfig{ThrottleAt: 120}}, "gmail.quota.throttle_at"},
+		{GmailConfig{Accounts: []GmailAccountConf{{Email: "me@example.com", DailyQuota: -2}}}, "gmail.accounts[0].daily_quota"},
+	} {
+		cfg := &Config{InMemory: true, Gmail: tt.gmail}
+		if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("expected %q, got %v", tt.wantErr, err)
+		}
+	}
+}
+
+func TestValidate_APIKeys(t *testing.T) {
+	gmail := GmailConfig{Accounts: []GmailAccountConf{{Email: "me@example.com"}}}
+	tests := []struct {
+		name    string
+		token   string
+		keys    []APIKey
+		wantErr string
+	}{
+		{"ok", "secret", []APIKey{{Name: "a", Key: "k1", Gmail: GmailKeyScope{Accounts: []string{"me@example.com"}}}, {Name: "b", Key: "k2"}}, ""},
+		{"no internal token", "", []APIKey{{Name: "a", Key: "k1"}}, "requires server.internal_token"},
+		{"no name", "secret", []APIKey{{Key: "k1"}}, "server.api_keys[0].name is required"},
+		{"duplicate name", "secret", []APIKey{{Name: "a", Key: "k1"}, {Name: "a", Key: "k2"}}, "server.api_keys[1].name"},
+		{"no key", "secret", []APIKey{{Name: "a"}}, "server.api_keys[0].key is required"},
+		{"duplicate key", "secret", []APIKey{{Name: "a", Key: "k1"}, {Name: "b", Key: "k1"}}, "must differ"},
+		{"internal token", "secret", []APIKey{{Name: "a", Key: "secret"}}, "must differ"},
+		{"unknown account", "secret", []APIKey{{Name: "a", Key: "k1", Gmail: GmailKeyScope{Accounts: []string{"you@example.com"}}}}, "not in gmail.accounts"},
+	}
+	for _, tt := range tests {
+		cfg := &Config{InMemory: true, Gmail: gmail, Server: ServerConfig{InternalToken: tt.token, APIKeys: tt.keys}}
+		err := cfg.validateAPIKeys()
+		if tt.wantErr == "" && err != nil {
+			t.Errorf("%s: unexpected error %v", tt.name, err)
+		}
+		if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+			t.Errorf("%s: expected error containing %q, got %v", tt.name, tt.wantErr, err)
+		}
+	}
+
+	scope := GmailKeyScope{Labels: []string{"UNREAD"}}
+	if !scope.AllowsAccount("any@example.com") || !scope.AllowsLabel("UNREAD") || scope.AllowsLabel("INBOX") {
+		t.Error("unexpected scope checks")
+	}
+}
+
+func TestLoad_ActionList(t *testing.T) {
+	dir := t.TempDir()
+	cfgPath := filepath.Join(dir, "config.yaml")
+	os.WriteFile(cfgPath, []byte(`
+gateway:
+  url: http://gateway
+github:
+  rules:
+    - event: workflow_run
+      actions: [completed]
+      action:
+        - agent_id: ci
+        - agent_id: followup
+          delay: 60
+trello:
+  rules:
+    - event: card_moved
+      action:
+        agent_id: dev
+gmail:
+  accounts:
+    - email: a@example.com
+      rules:
+        - name: invoices
+          action:
+            - message_template: "one"
+            - message_template: "two"
+              schedule: "at some point"
+`), 0644)
+
+	cfg, err := Load(cfgPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "gmail.accounts[0].rules[0].action[1].schedule") {
+		t.Fatalf("expected the list entry's schedule rejected, got %v", err)
+	}
+
+	cfg.Gmail.Accounts[0].Rules[0].Actions[1].Schedule = "tomorrow 9am"
+	if err := cfg.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	gh := cfg.GitHub.Rules[0]
+	if got := gh.ResolvedActions(); len(got) != 2 || got[0].AgentID != "ci" || got[1].Delay != 60 || gh.Actions[0] != "completed" {
+		t.Errorf("unexpected github actions %+v (payload actions %v)", got, gh.Actions)
+	}
+	if got := cfg.Trello.Rules[0].ResolvedActions(); len(got) != 1 || got[0].AgentID != "dev" {
+		t.Errorf("expected a single action, got %+v", got)
+	}
+	if got := cfg.Gmail.Accounts[0].Rules[0].ResolvedActions(); len(got) != 2 || got[1].MessageTemplate != "two" {
+		t.Errorf("unexpected gmail actions %+v", got)
+	}
+}
+
+func TestLoad_RuleGroups(t *testing.T) {
+	var cfg Config
+	err := yaml.Unmarshal([]byte(`
+github:
+  rules:
+    - event: push
+    - when: "repo in ['acme/api', 'acme/web']"
+      rules:
+        - event: workflow_run
+          condition: "conclusion == 'failure'"
+        - when: "now.hour >= 9"
+          rules:
+            - event: pull_request_review
+    - event: issues
+gmail:
+  accounts:
+    - email: a@example.com
+      rules:
+        - when: "account == 'a@example.com'"
+          rules:
+            - name: invoices
+              match:
+                condition: "subject =~ 'invoice'"
+`), &cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []struct{ event, condition string }{
+		{"push", ""},
+		{"workflow_run", "(repo in ['acme/api', 'acme/web']) && (conclusion == 'failure')"},
+		{"pull_request_review", "(repo in ['acme/api', 'acme/web']) && (now.hour >= 9)"},
+		{"issues", ""},
+	}
+	if len(cfg.GitHub.Rules) != len(want) {
+		t.Fatalf("expected %d flattened rules, got %+v", len(want), cfg.GitHub.Rules)
+	}
+	for i, w := range want {
+		if r := cfg.GitHub.Rules[i]; r.Event != w.event || r.Condition != w.condition {
+			t.Errorf("rules[%d] = %s %q, want %s %q", i, r.Event, r.Condition, w.event, w.condition)
+		}
+	}
+	if got := cfg.Gmail.Accounts[0].Rules[0].Match.Condition; got != "(account == 'a@example.com') && (subject =~ 'invoice')" {
+		t.Errorf("unexpected gmail condition %q", got)
+	}
+
+	for _, bad := range []string{
+		"trello:\n  rules:\n    - when: \"x\"\n      name: oops\n      rules: []\n",
+		"trello:\n  rules:\n    - when: \"x\"\n",
+		"trello:\n  rules:\n    - when: \"x\"\n      rules: {event: card_moved}\n",
+	} {
+		if err := yaml.Unmarshal([]byte(bad), &Config{}); err == nil {
+			t.Errorf("expected an error for %q", bad)
+		}
+	}
+}
+
+func TestValidate_Conditions(t *testing.T) {
+	cfg := &Config{InMemory: true, GitHub: GitHubConfig{Rules: []GitHubRule{{Event: "push", Condition: "branch == 'main' &&"}}}}
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "github.rules[0].condition") {
+		t.Errorf("expected condition error, got %v", err)
+	}
+	cfg.GitHub.Rules[0].Condition = "branch == 'main' && !sender_bot"
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	cfg.Trello.Rules = []TrelloRule{{Event: "card_moved", Condition: "(list == 'ready'"}}
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "trello.rules[0].condition") {
+		t.Errorf("expected trello condition error, got %v", err)
+	}
+}
+
+func TestValidate_GmailSubject(t *testing.T) {
+	cfg := &Config{InMemory: true, Gmail: GmailConfig{Enabled: true, Accounts: []GmailAccountConf{{
+		Email: "me@example.com",
+		Rules: RuleList[GmailRule]{{Name: "invoices", Match: GmailMatch{Subject: []string{"invoice*", "/(unclosed/"}}}},
+	}}}}
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "gmail.accounts[0].rules[0].match.subject") {
+		t.Errorf("expected subject error, got %v", err)
+	}
+	cfg.Gmail.Accounts[0].Rules[0].Match.Subject[1] = "/(?i)^re: invoice/"
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestValidate_GmailActionKinds(t *testing.T) {
+	tests := []struct {
+		action GmailAction
+		err    string
+	}{
+		{GmailAction{Kind: "modify", AddLabels: []string{"Label_1"}, Archive: true}, ""},
+		{GmailAction{Kind: "forward", To: []string{"Books <books@example.com>"}}, ""},
+		{GmailAction{Kind: "agent_turn"}, ""},
+		{GmailAction{Kind: "label"}, "rules[0].action.kind"},
+		{GmailAction{Kind: "modify"}, "kind modify needs"},
+		{GmailAction{MessageTemplate: "x", Archive: true}, "need kind modify"},
+		{GmailAction{Kind: "forward"}, "rules[0].action.to is required"},
+		{GmailAction{Kind: "forward", To: []string{"not an address"}}, "action.to[0]"},
+		{GmailAction{Kind: "cron", To: []string{"a@example.com"}}, "to needs kind forward"},
+		{GmailAction{Notify: &GmailNotifyAction{IncludeBody: true, MaxBodyChars: 500}}, ""},
+		{GmailAction{Notify: &GmailNotifyAction{MaxBodyChars: 500}}, "max_body_chars needs include_body"},
+		{GmailAction{Notify: &GmailNotifyAction{IncludeBody: true, MaxBodyChars: -1}}, "must not be negative"},
+	}
+	for _, tt := range tests {
+		cfg := &Config{InMemory: true, Gmail: GmailConfig{Enabled: true, Accounts: []GmailAccountConf{{
+			Email: "me@example.com",
+			Rules: RuleList[GmailRule]{{Name: "r", Action: tt.action}},
+		}}}}
+		err := cfg.Validate()
+		if tt.err == "" && err != nil {
+			t.Errorf("%+v: unexpected error %v", tt.action, err)
+		}
+		if tt.err != "" && (err == nil || !strings.Contains(err.Error(), tt.err)) {
+			t.Errorf("%+v: expected error containing %q, got %v", tt.action, tt.err, err)
+		}
+	}
+}
+
+func TestGmailAction_IsCron(t *testing.T) {
+	for _, tt := range []struct {
+		action GmailAction
+		want   bool
+	}{
+		{GmailAction{MessageTemplate: "x"}, true},
+		{GmailAction{Kind: "agent_turn"}, true},
+		{GmailAction{Kind: "forward", To: []string{"a@example.com"}, MessageTemplate: "note"}, false},
+		{GmailAction{Kind: "modify", Archive: true}, false},
+		{GmailAction{Notify: &GmailNotifyAction{Target: "1"}}, false},
+	} {
+		if got := tt.action.IsCron(); got != tt.want {
+			t.Errorf("%+v: IsCron = %v, want %v", tt.action, got, tt.want)
+		}
+	}
+}
+
+func TestSubjectRegexp(t *testing.T) {
+	tests := []struct {
+		pattern, subject string
+		want             bool
+	}{
+		{"invoice*", "Invoice #42", true},
+		{"*[urgent]*", "Re: [URGENT] server down", true},
+		{"a?c", "abc", true},
+		{"a?c", "abbc", false},
+		{"/^Re: /", "Re: hello", true},
+		{"/^Re: /", "re: hello", false},
+		{"/", "/", true}, // too short for a regexp: a literal glob
+	}
+	for _, tt := range tests {
+		re, err := SubjectRegexp(tt.pattern)
+		if err != nil {
+			t.Fatalf("SubjectRegexp(%q): %v", tt.pattern, err)
+		}
+		if got := re.MatchString(tt.subject); got != tt.want {
+			t.Errorf("SubjectRegexp(%q).MatchString(%q) = %v, want %v", tt.pattern, tt.subject, got, tt.want)
+		}
+	}
+}
+
+func TestMatchPattern(t *testing.T) {
+	patterns := []string{"invoice*", "/^re: .*urgent/"}
+	for subject, want := range map[string]bool{
+		"Invoice #12":          true,
+		"re: something urgent": true,
+		"Receipt":              false,
+	} {
+		if got := MatchPattern(patterns, subject); got != want {
+			t.Errorf("MatchPattern(%q) = %v, want %v", subject, got, want)
+		}
+	}
+	if MatchPattern([]string{"/(/"}, "(") {
+		t.Error("an invalid pattern must not match")
+	}
+	// Compiled once and reused
+	a, _ := SubjectRegexp("invoice*")
+	b, _ := SubjectRegexp("invoice*")
+	if a != b {
+		t.Error("expected the compiled pattern cached")
+	}
+}
+
+func TestMatchAddress(t *testing.T) {
+	tests := []struct {
+		patterns []string
+		addr     string
+		want     bool
+	}{
+		{[]string{"*@Example.com"}, "alice@example.COM", true},
+		{[]string{"*@example.com"}, "alice@example.com.evil.io", false},
+		{[]string{"billing"}, "Billing Team <billing@acme.io>", true},
+		{[]string{"nobody", "*@acme.io"}, "bob@acme.io", true},
+		{nil, "bob@acme.io", false},
+	}
+	for _, tt := range tests {
+		if got := MatchAddress(tt.patterns, tt.addr); got != tt.want {
+			t.Errorf("MatchAddress(%q, %q) = %v, want %v", tt.patterns, tt.addr, got, tt.want)
+		}
+	}
+}
+
+func TestValidate_DiscordRules(t *testing.T) {
+	cfg := &Config{InMemory: true, Discord: DiscordConfig{Rules: []DiscordRule{{Command: "ask"}}}}
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "discord.public_key") {
+		t.Errorf("expected public key error, got %v", err)
+	}
+	cfg.Discord.PublicKey = strings.Repeat("ab", 32)
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	cfg.Discord.Rules = append(cfg.Discord.Rules, DiscordRule{})
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "discord.rules[1].command") {
+		t.Errorf("expected command error, got %v", err)
+	}
+}
+
+func TestValidate_NotionRules(t *testing.T) {
+	tests := []struct {
+		name   string
+		notion NotionConfig
+		want   string
+	}{
+		{"valid event only", NotionConfig{Rules: []NotionRule{{Event: "page.created"}}}, ""},
+		{"missing event", NotionConfig{Rules: []NotionRule{{}}}, "notion.rules[0].event"},
+		{"to without property", NotionConfig{APIToken: "t", Rules: []NotionRule{{Event: "page.properties_updated", To: "Done"}}}, "require property"},
+		{"property without api token", NotionConfig{Rules: []NotionRule{{Event: "page.properties_updated", Property: "Status"}}}, "notion.api_token"},
+		{"property with api token", NotionConfig{APIToken: "t", Rules: []NotionRule{{Event: "page.properties_updated", Property: "Status", To: "Done"}}}, ""},
+	}
+	for _, tt := range tests {
+		cfg := &Config{InMemory: true, Notion: tt.notion}
+		err := cfg.Validate()
+		if tt.want == "" {
+			if err != nil {
+				t.Errorf("%s: unexpected error: %v", tt.name, err)
+			}
+		} else if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: expected error containing %q, got %v", tt.name, tt.want, err)
+		}
+	}
+}
+
+func TestValidate_AlertRules(t *testing.T) {
+	cfg := &Config{InMemory: true,
+		Sentry:       SentryConfig{Rules: []SentryRule{{Project: "api", Condition: "level == 'fatal'"}}},
+		Alertmanager: AlertmanagerConfig{Rules: []AlertmanagerRule{{Status: "firing"}, {}}},
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	cfg.Alertmanager.Rules[1].Status = "pending"
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "alertmanager.rules[1].status") {
+		t.Errorf("expected status error, got %v", err)
+	}
+	cfg.Alertmanager.Rules[1].Status = ""
+	cfg.Sentry.Rules[0].Condition = "level =="
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "sentry.rules[0].condition") {
+		t.Errorf("expected condition error, got %v", err)
+	}
+}
+
+func TestLoad_DevSkipSignatures(t *testing.T) {
+	var cfg Config
+	if err := yaml.Unmarshal([]byte("server:\n  dev_skip_signatures: true\n"), &cfg); err != nil || !cfg.Server.DevSkipSignatures {
+		t.Errorf("expected dev_skip_signatures from YAML, got %v (%v)", cfg.Server.DevSkipSignatures, err)
+	}
+}
+
+func TestGoogleTokenGracePeriod(t *testing.T) {
+	if d := (GoogleConfig{}).ResolvedTokenGracePeriod(); d != 7*24*time.Hour {
+		t.Errorf("default = %v, want 168h", d)
+	}
+	if d := (GoogleConfig{TokenGracePeriod: "48h"}).ResolvedTokenGracePeriod(); d != 48*time.Hour {
+		t.Errorf("got %v, want 48h", d)
+	}
+	cfg := &Config{InMemory: true, Google: GoogleConfig{TokenGracePeriod: "a week"}}
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "google.token_grace_period") {
+		t.Errorf("expected grace period error, got %v", err)
+	}
+}
+
+func TestGatewayControl(t *testing.T) {
+	if d := (GatewayControlConfig{}).ResolvedWait(); d != 30*time.Second {
+		t.Errorf("default = %v, want 30s", d)
+	}
+	if dl := (GatewayDeadLetterConfig{}); dl.ResolvedRetryInterval() != time.Minute || dl.ResolvedMaxAttempts() != 10 {
+		t.Errorf("unexpected dead letter defaults %v/%d", dl.ResolvedRetryInterval(), dl.ResolvedMaxAttempts())
+	}
+	if hb := (GatewayHeartbeatConfig{}); hb.ResolvedInterval() != 0 || hb.ResolvedTimeout() != 60 {
+		t.Errorf("unexpected heartbeat defaults %v/%d", hb.ResolvedInterval(), hb.ResolvedTimeout())
+	}
+	tests := []struct {
+		name    string
+		gateway GatewayConfig
+		wantErr string
+	}{
+		{"disabled", GatewayConfig{}, ""},
+		{"ok", GatewayConfig{URL: "http://gw", Token: "t", Control: GatewayControlConfig{Enabled: true, Wait: "1m"}}, ""},
+		{"no token", GatewayConfig{URL: "http://gw", Control: GatewayControlConfig{Enabled: true}}, "gateway.control requires"},
+		{"bad wait", GatewayConfig{URL: "http://gw", Token: "t", Control: GatewayControlConfig{Enabled: true, Wait: "soon"}}, "gateway.control.wait"},
+		{"job name", GatewayConfig{JobName: "{{.Source}}: {{.Name}}", Tags: map[string]string{"tenant": "acme"}}, ""},
+		{"bad job name", GatewayConfig{JobName: "{{.Name"}, "gateway.job_name"},
+		{"dead letter", GatewayConfig{DeadLetter: GatewayDeadLetterConfig{RetryInterval: "30s", MaxAttempts: 5}}, ""},
+		{"bad retry interval", GatewayConfig{DeadLetter: GatewayDeadLetterConfig{RetryInterval: "often"}}, "gateway.dead_letter.retry_interval"},
+		{"negative attempts", GatewayConfig{DeadLetter: GatewayDeadLetterConfig{MaxAttempts: -1}}, "gateway.dead_letter.max_attempts"},
+		{"heartbeat", GatewayConfig{Heartbeat: GatewayHeartbeatConfig{Interval: "6h", Channel: "telegram", Target: "123"}}, ""},
+		{"heartbeat too often", GatewayConfig{Heartbeat: GatewayHeartbeatConfig{Interval: "30s"}}, "gateway.heartbeat.interval"},
+		{"heartbeat channel only", GatewayConfig{Heartbeat: GatewayHeartbeatConfig{Interval: "6h", Channel: "telegram"}}, "channel and target go together"},
+		{"heartbeat bad template", GatewayConfig{Heartbeat: GatewayHeartbeatConfig{Interval: "6h", MessageTemplate: "{{.Uptime"}}, "gateway.heartbeat.message_template"},
+	}
+	for _, tt := range tests {
+		cfg := &Config{InMemory: true, Gateway: tt.gateway}
+		err := cfg.Validate()
+		if tt.wantErr == "" && err != nil {
+			t.Errorf("%s: unexpected error %v", tt.name, err)
+		}
+		if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+			t.Errorf("%s: expected error containing %q, got %v", tt.name, tt.wantErr, err)
+		}
+	}
+}
+
+func TestValidate_AsanaRules(t *testing.T) {
+	tests := []struct {
+		name    string
+		rule    AsanaRule
+		wantErr string
+	}{
+		{"move", AsanaRule{Event: "section_moved", Section: "review"}, ""},
+		{"comment", AsanaRule{Event: "comment_added", Condition: "user != 'U1'"}, ""},
+		{"bad event", AsanaRule{Event: "task_created"}, "must be section_moved or comment_added"},
+		{"section on comment", AsanaRule{Event: "comment_added", Section: "review"}, "section only applies"},
+		{"bad condition", AsanaRule{Event: "comment_added", Condition: "comment =="}, "asana.rules[0]"},
+	}
+	for _, tt := range tests {
+		cfg := &Config{Gateway: GatewayConfig{URL: "http://gw"}, Asana: AsanaConfig{Rules: []AsanaRule{tt.rule}}}
+		err := cfg.Validate()
+		if tt.wantErr == "" && err != nil {
+			t.Errorf("%s: unexpected error %v", tt.name, err)
+		}
+		if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+			t.Errorf("%s: expected error containing %q, got %v", tt.name, tt.wantErr, err)
+		}
+	}
+}
+
+func TestValidate_BitbucketRules(t *testing.T) {
+	cfg := &Config{Gateway: GatewayConfig{URL: "http://gw"}, Bitbucket: BitbucketConfig{Rules: []BitbucketRule{{Event: "repo:push", Branches: []string{"main"}}}}}
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("unexpected error %v", err)
+	}
+	cfg.Bitbucket.Rules[0].Event = "pullrequest:merged"
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "bitbucket.rules[0].event") {
+		t.Errorf("expected event error, got %v", err)
+	}
+	cfg.Bitbucket.Rules[0] = BitbucketRule{Event: "repo:push", Sample: 2}
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "bitbucket.rules[0]") {
+		t.Errorf("expected sample error, got %v", err)
+	}
+}
+
+func TestValidate_Budget(t *testing.T) {
+	tests := []struct {
+		name    string
+		budget  BudgetConfig
+		wantErr string
+	}{
+		{"ok", BudgetConfig{Sources: map[string]int{"github": 500}, Rules: map[string]int{"gmail/newsletter": 20}}, ""},
+		{"zero source cap", BudgetConfig{Sources: map[string]int{"github": 0}}, "budget.sources.github"},
+		{"rule key", BudgetConfig{Rules: map[string]int{"newsletter": 20}}, "<source>/<rule>"},
+		{"negative rule cap", BudgetConfig{Rules: map[string]int{"gmail/newsletter": -1}}, "cap must be positive"},
+	}
+	for _, tt := range tests {
+		cfg := &Config{InMemory: true, Budget: tt.budget}
+		err := cfg.Validate()
+		if tt.wantErr == "" && err != nil {
+			t.Errorf("%s: unexpected error %v", tt.name, err)
+		}
+		if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+			t.Errorf("%s: expected error containing %q, got %v", tt.name, tt.wantErr, err)
+		}
+	}
+	if (BudgetConfig{}).Enabled() {
+		t.Error("empty budget should be disabled")
+	}
+}
+
+func TestLocalizedTemplates(t *testing.T) {
+	a := RuleAction{MessageTemplate: "default", MessageTemplates: map[string]string{"ru": "русский"}}
+	if a.LocalizedTemplate("ru") != "русский" || a.LocalizedTemplate("de") != "default" || a.LocalizedTemplate("") != "default" {
+		t.Error("unexpected RuleAction.LocalizedTemplate")
+	}
+	notify := &GmailNotifyAction{Template: "mail", Templates: map[string]string{"de": "Post"}}
+	ga := GmailAction{Notify: notify}
+	if ga.LocalizedTemplate("de") != "Post" || ga.LocalizedTemplate("fr") != "mail" {
+		t.Error("unexpected notify variant")
+	}
+	ga.MessageTemplate = "flat"
+	if ga.LocalizedTemplate("de") != "flat" {
+		t.Error("the flat template wins over notify variants")
+	}
+
+	cfg := &Config{InMemory: true, Trello: TrelloConfig{Rules: []TrelloRule{{Event: "card_moved", Action: a}}}}
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("unexpected error %v", err)
+	}
+	cfg.Trello.Rules[0].Action.MessageTemplates = map[string]string{"russian": "x"}
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "trello.rules[0].action.message_templates") {
+		t.Errorf("expected language code error, got %v", err)
+	}
+}
+
+func TestValidate_GmailEvents(t *testing.T) {
+	tests := []struct {
+		match  GmailMatch
+		action GmailAction
+		err    string
+	}{
+		{GmailMatch{Events: []string{"label_added"}, ChangedLabels: []string{"IMPORTANT"}}, GmailAction{}, ""},
+		{GmailMatch{Events: []string{"message_added", "message_deleted"}}, GmailAction{}, ""},
+		{GmailMatch{Events: []string{"labelAdded"}}, GmailAction{}, "match.events \"labelAdded\" must be one of"},
+		{GmailMatch{ChangedLabels: []string{"IMPORTANT"}}, GmailAction{}, "changed_labels needs events label_added or label_removed"},
+		{GmailMatch{Events: []string{"message_deleted"}}, GmailAction{Kind: "modify", Archive: true}, "can't act on message_deleted"},
+	}
+	for _, tt := range tests {
+		cfg := &Config{InMemory: true, Gmail: GmailConfig{Enabled: true, Accounts: []GmailAccountConf{{
+			Email: "me@example.com",
+			Rules: RuleList[GmailRule]{{Name: "r", Match: tt.match, Action: tt.action}},
+		}}}}
+		err := cfg.Validate()
+		if tt.err == "" && err != nil {
+			t.Errorf("%+v: unexpected error %v", tt.match, err)
+		}
+		if tt.err != "" && (err == nil || !strings.Contains(err.Error(), tt.err)) {
+			t.Errorf("%+v: expected error containing %q, got %v", tt.match, tt.err, err)
+		}
+	}
+}
+
+func TestGmailAccountConf_HistoryEvents(t *testing.T) {
+	acc := GmailAccountConf{Rules: RuleList[GmailRule]{
+		{Match: GmailMatch{Events: []string{"message_deleted", "label_added"}}},
+		{Match: GmailMatch{Events: []string{"label_added"}}},
+	}}
+	if got := acc.HistoryEvents(); !slices.Equal(got, []string{"label_added", "message_deleted"}) {
+		t.Errorf("HistoryEvents = %v", got)
+	}
+	acc.Rules = append(acc.Rules, GmailRule{})
+	if got := acc.HistoryEvents(); !slices.Equal(got, []string{"message_added", "label_added", "message_deleted"}) {
+		t.Errorf("HistoryEvents with a default rule = %v", got)
+	}
+}
+
+func TestGmailAttachments(t *testing.T) {
+	tests := []struct {
+		name    string
+		att     GmailAttachmentsConfig
+		wantErr string
+	}{
+		{"defaults", GmailAttachmentsConfig{}, ""},
+		{"ok", GmailAttachmentsConfig{MaxSizeMB: -1, DenyExtensions: []string{"exe"}, ClamAV: "127.0.0.1:3310", ClamAVTimeout: "10s"}, ""},
+		{"bad size", GmailAttachmentsConfig{MaxSizeMB: -2}, "max_size_mb"},
+		{"empty extension", GmailAttachmentsConfig{DenyExtensions: []string{"."}}, "deny_extensions[0]"},
+		{"bad timeout", GmailAttachmentsConfig{ClamAVTimeout: "soon"}, "clamav_timeout"},
+	}
+	for _, tt := range tests {
+		cfg := &Config{InMemory: true, Gmail: GmailConfig{Attachments: tt.att}}
+		err := cfg.Validate()
+		if tt.wantErr == "" && err != nil {
+			t.Errorf("%s: unexpected error %v", tt.name, err)
+		}
+		if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+			t.Errorf("%s: expected error containing %q, got %v", tt.name, tt.wantErr, err)
+		}
+	}
+
+	var a GmailAttachmentsConfig
+	if a.ResolvedMaxBytes() != 25<<20 || a.ResolvedDenyExtensions() != nil || a.ResolvedClamAVTimeout() != 30*time.Second {
+		t.Error("unexpected defaults")
+	}
+	a = GmailAttachmentsConfig{MaxSizeMB: -1, DenyExtensions: []string{"EXE", ".js"}}
+	if a.ResolvedMaxBytes() != 0 {
+		t.Error("-1 should disable the size cap")
+	}
+	if got := a.ResolvedDenyExtensions(); len(got) != 2 || got[0] != ".exe" || got[1] != ".js" {
+		t.Errorf("unexpected extensions %v", got)
+	}
+	if got := (GmailAttachmentsConfig{DenyExtensions: []string{}}).ResolvedDenyExtensions(); got == nil || len(got) != 0 {
+		t.Error("an empty list should disable the denylist")
+	}
+}
+
+func TestValidate_TextExtraction(t *testing.T) {
+	rules := []GmailRule{{Name: "invoices", Action: GmailAction{ExtractText: true, MessageTemplate: "{{.AttachmentText}}"}}}
+	tests := []struct {
+		name    string
+		te      GmailTextExtractionConfig
+		wantErr string
+	}{
+		{"ok", GmailTextExtractionConfig{URL: "http://tika:9998/tika", Timeout: "2m"}, ""},
+		{"missing url", GmailTextExtractionConfig{}, "action.extract_text needs gmail.text_extraction.url"},
+		{"bad url", GmailTextExtractionConfig{URL: "tika:9998"}, "http(s) URL"},
+		{"bad timeout", GmailTextExtractionConfig{URL: "http://tika:9998/tika", Timeout: "-1s"}, "gmail.text_extraction.timeout"},
+	}
+	for _, tt := range tests {
+		cfg := &Config{InMemory: true, Gmail: GmailConfig{
+			Accounts:       []GmailAccountConf{{Email: "me@example.com", Rules: rules}},
+			TextExtraction: tt.te,
+		}}
+		err := cfg.Validate()
+		if tt.wantErr == "" && err != nil {
+			t.Errorf("%s: unexpected error %v", tt.name, err)
+		}
+		if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+			t.Errorf("%s: expected error containing %q, got %v", tt.name, tt.wantErr, err)
+		}
+	}
+	var te GmailTextExtractionConfig
+	if te.ResolvedTimeout() != time.Minute || te.ResolvedMaxChars() != 20000 {
+		t.Error("unexpected defaults")
+	}
+}
+
+func TestGCConfig(t *testing.T) {
+	var g GCConfig
+	events, deadLetters := g.ResolvedRetention()
+	if g.ResolvedInterval() != 0 || events != 30*24*time.Hour || deadLetters != 30*24*time.Hour {
+		t.Errorf("defaults: every %v, keep %v/%v", g.ResolvedInterval(), events, deadLetters)
+	}
+	g = GCConfig{Interval: "6h", EventRetention: "7d", DeadLetterRetention: "90d"}
+	events, deadLetters = g.ResolvedRetention()
+	if g.ResolvedInterval() != 6*time.Hour || events != 7*24*time.Hour || deadLetters != 90*24*time.Hour {
+		t.Errorf("got every %v, keep %v/%v", g.ResolvedInterval(), events, deadLetters)
+	}
+
+	cfg := &Config{InMemory: true, GC: GCConfig{EventRetention: "a month"}}
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "gc.event_retention") {
+		t.Errorf("expected a gc.event_retention error, got %v", err)
+	}
+}
+
+func TestGmailAccountConf_Provider(t *testing.T) {
+	imap := MailIMAPConfig{Host: "imap.example.com", Password: "secret"}
+	tests := []struct {
+		acc GmailAccountConf
+		err string
+	}{
+		{GmailAccountConf{Provider: "imap", IMAP: imap}, ""},
+		{GmailAccountConf{Provider: "gmail"}, ""},
+		{GmailAccountConf{Provider: "outlook"}, "must be gmail or imap"},
+		{GmailAccountConf{IMAP: imap}, "imap needs provider: imap"},
+		{GmailAccountConf{Provider: "imap", IMAP: MailIMAPConfig{Host: "imap.example.com"}}, "needs host and password"},
+		{GmailAccountConf{Provider: "imap", IMAP: MailIMAPConfig{Host: "h", Password: "p", Port: 70000}}, "port out of range"},
+		{GmailAccountConf{Provider: "imap", IMAP: imap, Rules: RuleList[GmailRule]{
+			{Name: "r", Match: GmailMatch{Events: []string{"label_added"}, ChangedLabels: []string{"X"}}},
+		}}, "IMAP accounts only have message_added"},
+		{GmailAccountConf{Provider: "imap", IMAP: imap, Rules: RuleList[GmailRule]{
+			{Name: "r", Action: GmailAction{Kind: "forward", To: []string{"a@example.com"}}},
+		}}, "kind forward needs imap.smtp.host"},
+	}
+	for _, tt := range tests {
+		tt.acc.Email = "me@example.com"
+		cfg := &Config{
+			InMemory: true,
+			Google:   GoogleConfig{AllowedEmails: []string{"me@example.com"}},
+			Gmail:    GmailConfig{Enabled: true, Accounts: []GmailAccountConf{tt.acc}},
+		}
+		err := cfg.Validate()
+		if tt.err == "" && err != nil {
+			t.Errorf("%+v: unexpected error %v", tt.acc, err)
+		}
+		if tt.err != "" && (err == nil || !strings.Contains(err.Error(), tt.err)) {
+			t.Errorf("%+v: expected error containing %q, got %v", tt.acc, tt.err, err)
+		}
+	}
+
+	// IMAP accounts don't sign in with Google
+	cfg := &Config{
+		InMemory: true,
+		Google:   GoogleConfig{AllowedEmails: []string{"someone@example.com"}},
+		Gmail:    GmailConfig{Enabled: true, Accounts: []GmailAccountConf{{Email: "me@fastmail.example", Provider: "imap", IMAP: imap}}},
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("IMAP account outside allowed_emails: %v", err)
+	}
+
+	r := MailIMAPConfig{Host: "imap.example.com", Password: "secret", SMTP: MailSMTPConfig{Host: "smtp.example.com"}}.Resolved("me@example.com")
+	if r.Port != 993 || r.Username != "me@example.com" || r.Mailbox != "INBOX" || r.ArchiveMailbox != "Archive" {
+		t.Errorf("IMAP defaults = %+v", r)
+	}
+	if s := r.SMTP; s.Port != 465 || s.Username != "me@example.com" || s.Password != "secret" || s.From != "me@example.com" {
+		t.Errorf("SMTP defaults = %+v", s)
+	}
+	if r := imap.Resolved("me@example.com"); r.SMTP != (MailSMTPConfig{}) {
+		t.Errorf("SMTP defaults without a host = %+v", r.SMTP)
+	}
+}
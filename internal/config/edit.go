@@ -0,0 +1,86 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/katalabut/openclaw-relay/internal/atomicfile"
+	"gopkg.in/yaml.v3"
+)
+
+// ErrInvalid is returned by Edit and WriteFile for content that does not
+// parse or validate as a config.
+var ErrInvalid = errors.New("invalid config")
+
+// ParseNode parses config file content, as written, into its yaml.Node
+// document. Empty content is an empty mapping.
+func ParseNode(content []byte) (*yaml.Node, error) {
+	var root yaml.Node
+	if err := yaml.Unmarshal(content, &root); err != nil {
+		return nil, err
+	}
+	if root.Kind == 0 {
+		root = yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{{Kind: yaml.MappingNode, Tag: "!!map"}}}
+	}
+	if root.Content[0].Kind != yaml.MappingNode {
+		return nil, errors.New("config is not a mapping")
+	}
+	return &root, nil
+}
+
+// Edit applies fn to the top-level mapping of content, a config file, and
+// returns the file rendered again. Going through the yaml.Node tree rather
+// than Config keeps the hand-written file's comments, anchors, key order and
+// ${VAR} references; indentation becomes two spaces and blank lines may go.
+// An error from fn is returned as is; a result that does not validate wraps
+// ErrInvalid.
+func Edit(content []byte, fn func(top *yaml.Node) error) ([]byte, error) {
+	root, err := ParseNode(content)
+	if err != nil {
+		return nil, err
+	}
+	if err := fn(root.Content[0]); err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(root); err != nil {
+		return nil, err
+	}
+	enc.Close()
+	if err := ValidateContent(buf.Bytes()); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// WriteFile replaces the config file at path with content, atomically and
+// keeping the file's permissions. Content that does not validate is refused
+// with ErrInvalid and the file left alone. Rule import and version rollback
+// write config.yaml through it; both take effect on the next restart.
+func WriteFile(path string, content []byte) error {
+	if err := ValidateContent(content); err != nil {
+		return err
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	return atomicfile.WriteFile(path, content, info.Mode().Perm())
+}
+
+// ValidateContent reports, wrapping ErrInvalid, whether content parses and
+// validates as a config.
+func ValidateContent(content []byte) error {
+	cfg, err := Parse(content)
+	if err == nil {
+		err = cfg.Validate()
+	}
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalid, err)
+	}
+	return nil
+}
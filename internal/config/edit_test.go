@@ -0,0 +1,89 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+const editConfig = `# relay config
+server:
+  port: 8080 # public port
+gateway:
+  url: http://localhost:18789
+trello:
+  rules:
+    - name: ready
+      list: Ready
+`
+
+func TestParseNode(t *testing.T) {
+	root, err := ParseNode(nil)
+	if err != nil || root.Content[0].Kind != yaml.MappingNode {
+		t.Fatalf("empty: %v %v", root, err)
+	}
+	if _, err := ParseNode([]byte("- a\n")); err == nil {
+		t.Error("sequence should fail")
+	}
+}
+
+func TestEdit(t *testing.T) {
+	out, err := Edit([]byte(editConfig), func(top *yaml.Node) error {
+		top.Content = append(top.Content,
+			&yaml.Node{Kind: yaml.ScalarNode, Value: "read_only"},
+			&yaml.Node{Kind: yaml.ScalarNode, Value: "true", Tag: "!!bool"})
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"# relay config", "# public port", "read_only: true"} {
+		if !strings.Contains(string(out), want) {
+			t.Errorf("missing %q in:\n%s", want, out)
+		}
+	}
+
+	stop := errors.New("stop")
+	if _, err := Edit([]byte(editConfig), func(*yaml.Node) error { return stop }); err != stop {
+		t.Errorf("fn error: %v", err)
+	}
+	_, err = Edit([]byte(editConfig), func(top *yaml.Node) error {
+		top.Content = append(top.Content,
+			&yaml.Node{Kind: yaml.ScalarNode, Value: "server"},
+			&yaml.Node{Kind: yaml.ScalarNode, Value: "x"})
+		return nil
+	})
+	if !errors.Is(err, ErrInvalid) {
+		t.Errorf("invalid result: %v", err)
+	}
+}
+
+func TestWriteFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte(editConfig), 0640); err != nil {
+		t.Fatal(err)
+	}
+	if err := WriteFile(path, []byte("server: [\n")); !errors.Is(err, ErrInvalid) {
+		t.Errorf("invalid content: %v", err)
+	}
+	if got, _ := os.ReadFile(path); string(got) != editConfig {
+		t.Error("invalid content was written")
+	}
+	if err := WriteFile(path, []byte("# new\nserver:\n  port: 9090\n")); err != nil {
+		t.Fatal(err)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0640 {
+		t.Errorf("perm = %v", info.Mode().Perm())
+	}
+	if got, _ := os.ReadFile(path); !strings.HasPrefix(string(got), "# new") {
+		t.Errorf("content = %q", got)
+	}
+}
@@ -0,0 +1,337 @@
+// Package control runs the relay's end of the gateway control channel. The
+// relay long-polls the gateway for commands (pause a source, replay an event,
+// fetch a message) and posts each result back, so the agent can act on the
+// relay without the relay being reachable from the gateway.
+package control
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/katalabut/openclaw-relay/internal/events"
+	"github.com/katalabut/openclaw-relay/internal/gmail"
+)
+
+const maxBackoff = 5 * time.Minute
+
+// Command is one instruction from the gateway.
+type Command struct {
+	ID   string          `json:"id"`
+	Type string          `json:"type"`
+	Args json.RawMessage `json:"args"`
+}
+
+// Result is posted back to the gateway for every command.
+type Result struct {
+	OK     bool   `json:"ok"`
+	Error  string `json:"error,omitempty"`
+	Result any    `json:"result,omitempty"`
+}
+
+// Status describes the channel for /api/control.
+type Status struct {
+	Connected bool      `json:"connected"`
+	LastPoll  time.Time `json:"last_poll,omitzero"`
+	LastError string    `json:"last_error,omitempty"`
+	Commands  int       `json:"commands"`
+}
+
+// Channel polls GET <url>/relay/commands?wait=<wait> and answers each command
+// with POST <url>/relay/commands/<id>/result, using the gateway token.
+type Channel struct {
+	URL   string
+	Token string
+	Wait  time.Duration
+	HTTP  *http.Client
+
+	Pauses   *events.Pauses
+	Events   *events.Store
+	Recovery *events.Recovery
+	Gmail    map[string]gmail.GmailClient // by account; nil disables fetch_message
+	ReadOnly bool                         // rejects commands that change state, as read_only does for the API
+
+	mu     sync.Mutex
+	status Status
+}
+
+func NewChannel(gatewayURL, token string, wait time.Duration) *Channel {
+	return &Channel{
+		URL:   strings.TrimRight(gatewayURL, "/"),
+		Token: token,
+		Wait:  wait,
+		// The gateway holds a poll open for up to wait
+		HTTP: &http.Client{Timeout: wait + 15*time.Second},
+	}
+}
+
+// Start runs the poll loop until ctx is done.
+func (c *Channel) Start(ctx context.Context) {
+	go c.run(ctx)
+}
+
+func (c *Channel) run(ctx context.Context) {
+	log.Printf("Control channel: polling %s/relay/commands", c.URL)
+	backoff := time.Second
+	for {
+		cmds, err := c.poll(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+		if err != nil {
+			c.setStatus(false, err)
+			log.Printf("Control channel: %v (retrying in %s)", err, backoff)
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(backoff):
+			}
+			backoff = min(backoff*2, maxBackoff)
+			continue
+		}
+		c.setStatus(true, nil)
+		backoff = time.Second
+		for _, cmd := range cmds {
+			res := c.Execute(ctx, cmd)
+			if err := c.report(ctx, cmd.ID, res); err != nil {
+				log.Printf("Control channel: reporting %s %s: %v", cmd.Type, cmd.ID, err)
+			}
+		}
+	}
+}
+
+func (c *Channel) setStatus(connected bool, err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.status.Connected = connected
+	c.status.LastPoll = time.Now().UTC()
+	c.status.LastError = ""
+	if err != nil {
+		c.status.LastError = err.Error()
+	}
+}
+
+// Status returns the channel's connection state.
+func (c *Channel) Status() Status {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.status
+}
+
+func (c *Channel) poll(ctx context.Context) ([]Command, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.URL+"/relay/commands?wait="+url.QueryEscape(c.Wait.String()), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+c.Token)
+	resp, err := c.HTTP.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusNoContent:
+		return nil, nil
+	case http.StatusOK:
+	default:
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return nil, fmt.Errorf("gateway returned %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	var out struct {
+		Commands []Command `json:"commands"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return nil, fmt.Errorf("parse commands: %w", err)
+	}
+	return out.Commands, nil
+}
+
+func (c *Channel) report(ctx context.Context, id string, res Result) error {
+	body, err := json.Marshal(res)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.URL+"/relay/commands/"+url.PathEscape(id)+"/result", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+c.Token)
+	resp, err := c.HTTP.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("gateway returned %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// Execute runs one command.
+func (c *Channel) Execute(ctx context.Context, cmd Command) Result {
+	c.mu.Lock()
+	c.status.Commands++
+	c.mu.Unlock()
+
+	out, err := c.execute(ctx, cmd)
+	if err != nil {
+		log.Printf("Control channel: %s %s failed: %v", cmd.Type, cmd.ID, err)
+		return Result{Error: err.Error()}
+	}
+	log.Printf("Control channel: %s %s done", cmd.Type, cmd.ID)
+	return Result{OK: true, Result: out}
+}
+
+// mutating are the commands read-only mode rejects.
+var mutating = map[string]bool{"pause_source": true, "resume_source": true, "replay": true}
+
+func (c *Channel) execute(ctx context.Context, cmd Command) (any, error) {
+	if c.ReadOnly && mutating[cmd.Type] {
+		return nil, fmt.Errorf("relay is in read-only mode")
+	}
+	switch cmd.Type {
+	case "pause_source":
+		var args struct {
+			Source   string `json:"source"`
+			Duration string `json:"duration"`
+		}
+		if err := decodeArgs(cmd, &args); err != nil {
+			return nil, err
+		}
+		if args.Source == "" {
+			return nil, fmt.Errorf("source is required")
+		}
+		var d time.Duration
+		if args.Duration != "" {
+			var err error
+			if d, err = time.ParseDuration(args.Duration); err != nil || d < 0 {
+				return nil, fmt.Errorf("invalid duration %q", args.Duration)
+			}
+		}
+		return c.Pauses.Pause(args.Source, d), nil
+
+	case "resume_source":
+		var args struct {
+			Source string `json:"source"`
+			Replay bool   `json:"replay"`
+		}
+		if err := decodeArgs(cmd, &args); err != nil {
+			return nil, err
+		}
+		if args.Source == "" {
+			return nil, fmt.Errorf("source is required")
+		}
+		out := map[string]any{"resumed": c.Pauses.Resume(args.Source)}
+		if args.Replay {
+			replayed, failed := c.replayPaused(args.Source)
+			out["replayed"] = replayed
+			out["failed"] = failed
+		}
+		return out, nil
+
+	case "replay":
+		var args struct {
+			EventID string `json:"event_id"`
+			Force   bool   `json:"force"`
+		}
+		if err := decodeArgs(cmd, &args); err != nil {
+			return nil, err
+		}
+		if args.EventID == "" {
+			return nil, fmt.Errorf("event_id is required")
+		}
+		status, err := c.Recovery.Replay(args.EventID, args.Force)
+		if err != nil {
+			return nil, err
+		}
+		return map[string]any{"status": status}, nil
+
+	case "fetch_message":
+		var args struct {
+			Account string `json:"account"`
+			ID      string `json:"id"`
+		}
+		if err := decodeArgs(cmd, &args); err != nil {
+			return nil, err
+		}
+		if args.ID == "" {
+			return nil, fmt.Errorf("id is required")
+		}
+		client, err := c.gmailClient(args.Account)
+		if err != nil {
+			return nil, err
+		}
+		return client.GetMessage(ctx, args.ID)
+
+	default:
+		return nil, fmt.Errorf("unknown command %q", cmd.Type)
+	}
+}
+
+func decodeArgs(cmd Command, v any) error {
+	if len(cmd.Args) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(cmd.Args, v); err != nil {
+		return fmt.Errorf("bad args: %w", err)
+	}
+	return nil
+}
+
+// replayPaused replays source's held deliveries oldest first.
+func (c *Channel) replayPaused(source string) (int, []string) {
+	held := c.Events.List(events.StatusPaused)
+	replayed, failed := 0, []string{}
+	for i := len(held) - 1; i >= 0; i-- {
+		if held[i].Source != source {
+			continue
+		}
+		if status, err := c.Recovery.Replay(held[i].ID, false); err != nil || status >= 500 {
+			failed = append(failed, held[i].ID)
+			continue
+		}
+		replayed++
+	}
+	return replayed, failed
+}
+
+// gmailClient resolves account, which may be omitted when only one is configured.
+func (c *Channel) gmailClient(account string) (gmail.GmailClient, error) {
+	if len(c.Gmail) == 0 {
+		return nil, fmt.Errorf("gmail is not configured")
+	}
+	if account == "" {
+		if len(c.Gmail) > 1 {
+			return nil, fmt.Errorf("account is required when several Gmail accounts are configured")
+		}
+		for _, client := range c.Gmail {
+			return client, nil
+		}
+	}
+	client, ok := c.Gmail[account]
+	if !ok {
+		return nil, fmt.Errorf("unknown account %s", account)
+	}
+	return client, nil
+}
+
+// HandleStatus serves GET /api/control: channel state and paused sources.
+func (c *Channel) HandleStatus(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		json.NewEncoder(w).Encode(map[string]string{"error": "method not allowed"})
+		return
+	}
+	json.NewEncoder(w).Encode(map[string]any{"channel": c.Status(), "paused": c.Pauses.List()})
+}
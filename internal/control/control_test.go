@@ -0,0 +1,251 @@
+package control
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/katalabut/openclaw-relay/internal/events"
+	"github.com/katalabut/openclaw-relay/internal/gmail"
+)
+
+type fakeGmail struct {
+	gmail.GmailClient
+	msgs map[string]*gmail.MessageFull
+}
+
+func (f *fakeGmail) GetMessage(ctx context.Context, id string) (*gmail.MessageFull, error) {
+	if m, ok := f.msgs[id]; ok {
+		return m, nil
+	}
+	return nil, errors.New("not found")
+}
+
+func newTestChannel(t *testing.T, gatewayURL string) (*Channel, *[]string) {
+	t.Helper()
+	store, err := events.NewStore("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := NewChannel(gatewayURL, "gw-token", time.Second)
+	c.Events = store
+	c.Recovery = events.NewRecovery(store)
+	c.Pauses = events.NewPauses(store)
+
+	handled := &[]string{}
+	c.Recovery.Wrap("github", c.Pauses.Wrap("github", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		*handled = append(*handled, string(body))
+	})))
+	return c, handled
+}
+
+func cmd(typ, args string) Command {
+	return Command{ID: "c1", Type: typ, Args: json.RawMessage(args)}
+}
+
+func TestExecute_PauseResumeReplay(t *testing.T) {
+	c, handled := newTestChannel(t, "")
+	h := c.Pauses.Wrap("github", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("paused delivery reached the handler")
+	}))
+
+	res := c.Execute(context.Background(), cmd("pause_source", `{"source":"github","duration":"1h"}`))
+	if !res.OK || !c.Pauses.Paused("github") {
+		t.Fatalf("pause failed: %+v", res)
+	}
+	for _, body := range []string{"one", "two"} {
+		h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("POST", "/webhook/github", strings.NewReader(body)))
+	}
+
+	res = c.Execute(context.Background(), cmd("resume_source", `{"source":"github","replay":true}`))
+	out, _ := res.Result.(map[string]any)
+	if !res.OK || out["resumed"] != true || out["replayed"] != 2 {
+		t.Fatalf("unexpected resume result %+v", res)
+	}
+	if strings.Join(*handled, ",") != "one,two" {
+		t.Errorf("expected held deliveries replayed oldest first, got %v", *handled)
+	}
+
+	res = c.Execute(context.Background(), cmd("replay", `{"event_id":"missing"}`))
+	if res.OK || !strings.Contains(res.Error, "not found") {
+		t.Errorf("expected not found error, got %+v", res)
+	}
+}
+
+func TestExecute_FetchMessage(t *testing.T) {
+	c, _ := newTestChannel(t, "")
+	res := c.Execute(context.Background(), cmd("fetch_message", `{"id":"m1"}`))
+	if res.OK || !strings.Contains(res.Error, "gmail is not configured") {
+		t.Errorf("expected gmail error, got %+v", res)
+	}
+
+	c.Gmail = map[string]gmail.GmailClient{
+		"a@example.com": &fakeGmail{msgs: map[string]*gmail.MessageFull{"m1": {ID: "m1", Subject: "Hi"}}},
+	}
+	res = c.Execute(context.Background(), cmd("fetch_message", `{"id":"m1"}`))
+	if m, ok := res.Result.(*gmail.MessageFull); !res.OK || !ok || m.Subject != "Hi" {
+		t.Errorf("unexpected fetch result %+v", res)
+	}
+
+	c.Gmail["b@example.com"] = &fakeGmail{}
+	tests := []struct {
+		args string
+		want string
+	}{
+		{`{"id":"m1"}`, "account is required"},
+		{`{"account":"c@example.com","id":"m1"}`, "unknown account"},
+		{`{"account":"b@example.com","id":"m1"}`, "not found"},
+		{`{"account":"b@example.com"}`, "id is required"},
+	}
+	for _, tt := range tests {
+		res := c.Execute(context.Background(), cmd("fetch_message", tt.args))
+		if res.OK || !strings.Contains(res.Error, tt.want) {
+			t.Errorf("%s: expected error containing %q, got %+v", tt.args, tt.want, res)
+		}
+	}
+}
+
+func TestExecute_BadCommands(t *testing.T) {
+	c, _ := newTestChannel(t, "")
+	tests := []struct {
+		cmd  Command
+		want string
+	}{
+		{cmd("reboot", `{}`), `unknown command "reboot"`},
+		{cmd("pause_source", `{}`), "source is required"},
+		{cmd("pause_source", `{"source":"github","duration":"soon"}`), "invalid duration"},
+		{cmd("resume_source", `[]`), "bad args"},
+		{cmd("replay", ``), "event_id is required"},
+	}
+	for _, tt := range tests {
+		res := c.Execute(context.Background(), tt.cmd)
+		if res.OK || !strings.Contains(res.Error, tt.want) {
+			t.Errorf("%s: expected error containing %q, got %+v", tt.cmd.Type, tt.want, res)
+		}
+	}
+}
+
+func TestExecute_ReadOnly(t *testing.T) {
+	c, _ := newTestChannel(t, "")
+	c.ReadOnly = true
+	c.Gmail = map[string]gmail.GmailClient{"me@example.com": &fakeGmail{msgs: map[string]*gmail.MessageFull{"m1": {}}}}
+	for _, tt := range []Command{
+		cmd("pause_source", `{"source":"github"}`),
+		cmd("resume_source", `{"source":"github","replay":true}`),
+		cmd("replay", `{"event_id":"e1"}`),
+	} {
+		if res := c.Execute(context.Background(), tt); res.OK || res.Error != "relay is in read-only mode" {
+			t.Errorf("%s: %+v", tt.Type, res)
+		}
+	}
+	if c.Pauses.Paused("github") {
+		t.Error("source paused in read-only mode")
+	}
+	if res := c.Execute(context.Background(), cmd("fetch_message", `{"id":"m1"}`)); !res.OK {
+		t.Errorf("fetch_message: %+v", res)
+	}
+}
+
+func TestChannel_PollsAndReports(t *testing.T) {
+	var mu sync.Mutex
+	polls := 0
+	results := map[string]Result{}
+	done := make(chan struct{})
+	gw := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer gw-token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		switch {
+		case r.Method == "GET" && r.URL.Path == "/relay/commands":
+			if r.URL.Query().Get("wait") != "1s" {
+				t.Errorf("wait = %q", r.URL.Query().Get("wait"))
+			}
+			polls++
+			if polls > 1 {
+				w.WriteHeader(http.StatusNoContent)
+				return
+			}
+			w.Write([]byte(`{"commands":[{"id":"p1","type":"pause_source","args":{"source":"github"}},{"id":"x1","type":"nope"}]}`))
+		case r.Method == "POST" && strings.HasPrefix(r.URL.Path, "/relay/commands/"):
+			id := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/relay/commands/"), "/result")
+			var res Result
+			json.NewDecoder(r.Body).Decode(&res)
+			results[id] = res
+			if len(results) == 2 {
+				close(done)
+			}
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer gw.Close()
+
+	c, _ := newTestChannel(t, gw.URL)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	c.Start(ctx)
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for results")
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if !results["p1"].OK || results["x1"].OK || !strings.Contains(results["x1"].Error, "unknown command") {
+		t.Errorf("unexpected results %+v", results)
+	}
+	if !c.Pauses.Paused("github") {
+		t.Error("expected github to be paused")
+	}
+	if st := c.Status(); !st.Connected || st.Commands != 2 {
+		t.Errorf("unexpected status %+v", st)
+	}
+}
+
+func TestChannel_PollError(t *testing.T) {
+	gw := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "no control channel here", http.StatusNotFound)
+	}))
+	defer gw.Close()
+
+	c, _ := newTestChannel(t, gw.URL)
+	_, err := c.poll(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "gateway returned 404: no control channel here") {
+		t.Errorf("expected 404 error, got %v", err)
+	}
+}
+
+func TestHandleStatus(t *testing.T) {
+	c, _ := newTestChannel(t, "")
+	c.Pauses.Pause("slack", 0)
+	rec := httptest.NewRecorder()
+	c.HandleStatus(rec, httptest.NewRequest("GET", "/api/control", nil))
+	var out struct {
+		Channel Status         `json:"channel"`
+		Paused  []events.Pause `json:"paused"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &out); err != nil {
+		t.Fatal(err)
+	}
+	if len(out.Paused) != 1 || out.Paused[0].Source != "slack" || out.Channel.Connected {
+		t.Errorf("unexpected status %s", rec.Body.String())
+	}
+
+	rec = httptest.NewRecorder()
+	c.HandleStatus(rec, httptest.NewRequest("POST", "/api/control", nil))
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("expected 405, got %d", rec.Code)
+	}
+}
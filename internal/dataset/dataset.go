@@ -0,0 +1,130 @@
+// Package dataset records a sample of the jobs rules create as anonymized
+// event → prompt pairs in a JSONL file, for evaluating and regression-testing
+// the prompts rule templates render.
+package dataset
+
+import (
+	"encoding/json"
+	"log"
+	"math/rand/v2"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/katalabut/openclaw-relay/internal/gateway"
+)
+
+// Record is one line of the dataset file.
+type Record struct {
+	Time    time.Time       `json:"time"`
+	Source  string          `json:"source"`
+	Rule    string          `json:"rule,omitempty"`
+	Name    string          `json:"name"`
+	AgentID string          `json:"agent_id,omitempty"`
+	Event   json.RawMessage `json:"event,omitempty"` // the redacted webhook delivery
+	Prompt  string          `json:"prompt"`          // the redacted rendered message
+}
+
+// Options select and anonymize the recorded jobs.
+type Options struct {
+	Sample   float64  // fraction of jobs recorded; <= 0 or >= 1 records all
+	Sources  []string // only jobs from these sources; empty records every source
+	Redactor *Redactor
+}
+
+// Recorder is a GatewayClient that appends a sample of the jobs passing
+// through it to a JSONL file, then forwards every job to the wrapped client.
+// Recording never fails a job.
+type Recorder struct {
+	next gateway.GatewayClient
+	path string
+	opts Options
+
+	mu   sync.Mutex
+	now  func() time.Time
+	roll func() float64
+}
+
+// New wraps next, appending records to the file at path.
+func New(next gateway.GatewayClient, path string, opts Options) *Recorder {
+	if opts.Redactor == nil {
+		opts.Redactor, _ = NewRedactor(nil, nil)
+	}
+	return &Recorder{next: next, path: path, opts: opts, now: time.Now, roll: rand.Float64}
+}
+
+func (d *Recorder) CreateOneShotJob(name, message string, timeoutSeconds, delaySeconds int) error {
+	return d.CreateOneShotJobForAgent(name, message, "", timeoutSeconds, delaySeconds)
+}
+
+func (d *Recorder) CreateOneShotJobForAgent(name, message, agentID string, timeoutSeconds, delaySeconds int) error {
+	return d.CreateJob(gateway.JobSpec{Name: name, Message: message, AgentID: agentID, TimeoutSeconds: timeoutSeconds, DelaySeconds: delaySeconds})
+}
+
+// CreateJob records spec when it is sampled, and forwards it.
+func (d *Recorder) CreateJob(spec gateway.JobSpec) error {
+	if d.wants(spec) {
+		if err := d.record(spec); err != nil {
+			log.Printf("Dataset: failed to record %q: %v", spec.Name, err)
+		}
+	}
+	return gateway.CreateJob(d.next, spec)
+}
+
+// wants reports whether spec is recorded. Jobs without a source are the
+// relay's own notices, not rule output.
+func (d *Recorder) wants(spec gateway.JobSpec) bool {
+	if spec.Source == "" {
+		return false
+	}
+	if len(d.opts.Sources) > 0 && !contains(d.opts.Sources, spec.Source) {
+		return false
+	}
+	s := d.opts.Sample
+	return s <= 0 || s >= 1 || d.roll() < s
+}
+
+func (d *Recorder) record(spec gateway.JobSpec) error {
+	rule := spec.Rule
+	if r := spec.Tags["rule"]; r != "" {
+		rule = r
+	}
+	rec := Record{
+		Time:    d.now().UTC(),
+		Source:  spec.Source,
+		Rule:    rule,
+		Name:    d.opts.Redactor.String(spec.Name),
+		AgentID: spec.AgentID,
+		Event:   d.opts.Redactor.Payload(spec.Payload),
+		Prompt:  d.opts.Redactor.String(spec.Message),
+	}
+	line, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if err := os.MkdirAll(filepath.Dir(d.path), 0700); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(d.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
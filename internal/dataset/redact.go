@@ -0,0 +1,98 @@
+package dataset
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// builtinPatterns mask personal data and credentials wherever they appear.
+// Order matters: URLs go before emails so basic-auth userinfo is masked whole.
+var builtinPatterns = []struct {
+	re   *regexp.Regexp
+	mask string
+}{
+	{regexp.MustCompile(`https?://[^\s"'<>]+`), "[url]"},
+	{regexp.MustCompile(`[A-Za-z0-9._%+\-]+@[A-Za-z0-9.\-]+\.[A-Za-z]{2,}`), "[email]"},
+	{regexp.MustCompile(`\b(?:\d{1,3}\.){3}\d{1,3}\b`), "[ip]"},
+	// International format only, so dates and amounts survive
+	{regexp.MustCompile(`\+\d[\d ()\-]{7,}\d`), "[phone]"},
+	// API keys, tokens and hashes: long runs of letters, digits, - and _
+	{regexp.MustCompile(`\b[A-Za-z0-9_\-]{32,}\b`), "[token]"},
+}
+
+// Redactor anonymizes prompts and event payloads before they are written.
+type Redactor struct {
+	patterns []*regexp.Regexp
+	fields   map[string]bool
+}
+
+// NewRedactor masks the built-in patterns plus the extra regular expressions
+// in patterns, and replaces the values of payload keys named in fields
+// (case-insensitive) outright.
+func NewRedactor(fields, patterns []string) (*Redactor, error) {
+	r := &Redactor{fields: make(map[string]bool, len(fields))}
+	for _, f := range fields {
+		r.fields[strings.ToLower(f)] = true
+	}
+	for _, p := range patterns {
+		re, err := regexp.Compile(p)
+		if err != nil {
+			return nil, fmt.Errorf("redact pattern %q: %w", p, err)
+		}
+		r.patterns = append(r.patterns, re)
+	}
+	return r, nil
+}
+
+// String returns s with every match masked.
+func (r *Redactor) String(s string) string {
+	for _, re := range r.patterns {
+		s = re.ReplaceAllString(s, "[redacted]")
+	}
+	for _, p := range builtinPatterns {
+		s = p.re.ReplaceAllString(s, p.mask)
+	}
+	return s
+}
+
+// Payload redacts a JSON event body value by value. A body that is not JSON
+// is redacted as a single string.
+func (r *Redactor) Payload(body []byte) json.RawMessage {
+	if len(body) == 0 {
+		return nil
+	}
+	var v any
+	if err := json.Unmarshal(body, &v); err != nil {
+		out, _ := json.Marshal(r.String(string(body)))
+		return out
+	}
+	out, err := json.Marshal(r.value(v))
+	if err != nil {
+		return nil
+	}
+	return out
+}
+
+func (r *Redactor) value(v any) any {
+	switch v := v.(type) {
+	case map[string]any:
+		for k, val := range v {
+			if r.fields[strings.ToLower(k)] {
+				v[k] = "[redacted]"
+				continue
+			}
+			v[k] = r.value(val)
+		}
+		return v
+	case []any:
+		for i, val := range v {
+			v[i] = r.value(val)
+		}
+		return v
+	case string:
+		return r.String(v)
+	}
+	return v
+}
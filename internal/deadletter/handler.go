@@ -0,0 +1,81 @@
+package deadletter
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+)
+
+func newID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+func writeJSON(w http.ResponseWriter, code int, data any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(data)
+}
+
+// RegisterRoutes adds the dead letter routes to the mux.
+func (q *Queue) RegisterRoutes(mux *http.ServeMux) {
+	mux.HandleFunc("/api/deadletter", q.handleList)
+	mux.HandleFunc("/api/deadletter/", q.handleEntry)
+}
+
+// handleList serves GET /api/deadletter.
+func (q *Queue) handleList(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"entries": q.List()})
+}
+
+// handleEntry routes:
+//
+//	POST   /api/deadletter/replay       replay every entry now
+//	POST   /api/deadletter/{id}/replay  replay one entry now
+//	DELETE /api/deadletter/{id}         drop an entry
+func (q *Queue) handleEntry(w http.ResponseWriter, r *http.Request) {
+	rest := strings.TrimPrefix(r.URL.Path, "/api/deadletter/")
+	id, action, _ := strings.Cut(rest, "/")
+
+	switch {
+	case id == "replay" && action == "" && r.Method == http.MethodPost:
+		replayed, failed := 0, 0
+		for _, e := range q.List() {
+			if q.Replay(e.ID) == nil {
+				replayed++
+			} else {
+				failed++
+			}
+		}
+		writeJSON(w, http.StatusOK, map[string]int{"replayed": replayed, "failed": failed})
+	case id != "" && action == "replay" && r.Method == http.MethodPost:
+		err := q.Replay(id)
+		if errors.Is(err, ErrNotFound) {
+			writeJSON(w, http.StatusNotFound, map[string]string{"error": err.Error()})
+			return
+		}
+		if err != nil {
+			writeJSON(w, http.StatusBadGateway, map[string]string{"error": err.Error()})
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]bool{"ok": true})
+	case id != "" && action == "" && r.Method == http.MethodDelete:
+		if err := q.Delete(id); err != nil {
+			writeJSON(w, http.StatusNotFound, map[string]string{"error": err.Error()})
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]bool{"ok": true})
+	case id == "" || (action != "" && action != "replay"):
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "not found"})
+	default:
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+	}
+}
@@ -0,0 +1,65 @@
+package deadletter
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/katalabut/openclaw-relay/internal/gateway"
+)
+
+func TestHandler(t *testing.T) {
+	gw := &flakyGateway{down: true}
+	q, _ := New(gw, "")
+	q.CreateJob(gateway.JobSpec{Name: "a", Source: "slack"})
+	q.CreateJob(gateway.JobSpec{Name: "b", Source: "slack"})
+	q.CreateJob(gateway.JobSpec{Name: "c", Source: "slack"})
+	mux := http.NewServeMux()
+	q.RegisterRoutes(mux)
+
+	do := func(method, path string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest(method, path, nil))
+		return rec
+	}
+
+	rec := do("GET", "/api/deadletter")
+	var list struct {
+		Entries []Entry `json:"entries"`
+	}
+	json.Unmarshal(rec.Body.Bytes(), &list)
+	if rec.Code != http.StatusOK || len(list.Entries) != 3 {
+		t.Fatalf("unexpected list %d %s", rec.Code, rec.Body)
+	}
+	a, b := list.Entries[0].ID, list.Entries[1].ID
+
+	if rec := do("POST", "/api/deadletter/"+a+"/replay"); rec.Code != http.StatusBadGateway {
+		t.Errorf("expected 502 while the gateway is down, got %d", rec.Code)
+	}
+	if rec := do("DELETE", "/api/deadletter/"+b); rec.Code != http.StatusOK {
+		t.Errorf("expected delete, got %d", rec.Code)
+	}
+	if rec := do("DELETE", "/api/deadletter/"+b); rec.Code != http.StatusNotFound {
+		t.Errorf("expected 404 for a deleted entry, got %d", rec.Code)
+	}
+	if rec := do("POST", "/api/deadletter/nope/replay"); rec.Code != http.StatusNotFound {
+		t.Errorf("expected 404, got %d", rec.Code)
+	}
+
+	gw.down = false
+	rec = do("POST", "/api/deadletter/replay")
+	if rec.Code != http.StatusOK || rec.Body.String() != "{\"failed\":0,\"replayed\":2}\n" {
+		t.Errorf("unexpected replay all %d %s", rec.Code, rec.Body)
+	}
+	if len(q.List()) != 0 || len(gw.jobs) != 2 {
+		t.Errorf("expected queue drained, left %d, sent %d", len(q.List()), len(gw.jobs))
+	}
+
+	if rec := do("POST", "/api/deadletter"); rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("expected 405, got %d", rec.Code)
+	}
+	if rec := do("GET", "/api/deadletter/x/y"); rec.Code != http.StatusNotFound {
+		t.Errorf("expected 404, got %d", rec.Code)
+	}
+}
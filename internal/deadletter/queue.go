@@ -0,0 +1,345 @@
+// Package deadletter keeps gateway jobs that failed to be created, retries them
+// in the background and serves /api/deadletter to list, replay and drop them,
+// so a gateway outage does not silently lose webhook events.
+package deadletter
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/katalabut/openclaw-relay/internal/atomicfile"
+	"github.com/katalabut/openclaw-relay/internal/gateway"
+)
+
+const (
+	defaultRetryInterval = time.Minute
+	maxRetryInterval     = time.Hour
+	defaultMaxAttempts   = 10
+	defaultMaxEntries    = 500
+)
+
+// ErrNotFound is returned for unknown entry IDs.
+var ErrNotFound = errors.New("dead letter not found")
+
+// Entry is a job the gateway did not accept.
+type Entry struct {
+	ID      string            `json:"id"`
+	JobID   string            `json:"job_id,omitempty"` // the job's gateway.JobSpec ID, kept across retries
+	Source  string            `json:"source,omitempty"`
+	Rule    string            `json:"rule,omitempty"`
+	Account string            `json:"account,omitempty"` // the job's gateway.JobSpec Account
+	Name    string            `json:"name"`
+	Message string            `json:"message"` // the rendered agent message
+	AgentID string            `json:"agent_id,omitempty"`
+	Timeout int               `json:"timeout_seconds"`
+	Tags    map[string]string `json:"tags,omitempty"`
+	Payload json.RawMessage   `json:"payload,omitempty"` // the webhook delivery, when it is JSON
+
+	FireAt      time.Time `json:"fire_at"` // when the job was meant to run
+	FailedAt    time.Time `json:"failed_at"`
+	Error       string    `json:"error"`
+	Attempts    int       `json:"attempts"`
+	NextAttempt time.Time `json:"next_attempt,omitzero"` // zero once retries are exhausted
+}
+
+func (e Entry) spec(now time.Time) gateway.JobSpec {
+	delay := 0
+	if d := e.FireAt.Sub(now); d > 0 {
+		delay = int(d.Round(time.Second) / time.Second)
+	}
+	return gateway.JobSpec{
+		ID:             e.JobID,
+		Name:           e.Name,
+		Message:        e.Message,
+		AgentID:        e.AgentID,
+		TimeoutSeconds: e.Timeout,
+		DelaySeconds:   delay,
+		Source:         e.Source,
+		Rule:           e.Rule,
+		Tags:           e.Tags,
+		Account:        e.Account,
+	}
+}
+
+// Queue is a GatewayClient that stores jobs the wrapped client fails to
+// create. Entries are retried with a doubling interval until they go through
+// or MaxAttempts is reached; exhausted entries stay until replayed or deleted.
+type Queue struct {
+	next     gateway.GatewayClient
+	filePath string
+
+	RetryInterval time.Duration // first retry delay, doubling up to an hour
+	MaxAttempts   int
+	MaxEntries    int // oldest entries are dropped beyond this
+
+	mu      sync.Mutex
+	entries []Entry
+	sending map[string]bool // entries with an attempt in progress
+	now     func() time.Time
+}
+
+// New wraps next. Entries persist to filePath; an empty filePath keeps them in memory only.
+func New(next gateway.GatewayClient, filePath string) (*Queue, error) {
+	q := &Queue{
+		next:          next,
+		filePath:      filePath,
+		RetryInterval: defaultRetryInterval,
+		MaxAttempts:   defaultMaxAttempts,
+		MaxEntries:    defaultMaxEntries,
+		sending:       map[string]bool{},
+		now:           time.Now,
+	}
+	if filePath == "" {
+		return q, nil
+	}
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return q, nil
+		}
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &q.entries); err != nil {
+		return nil, fmt.Errorf("parse dead letters: %w", err)
+	}
+	return q, nil
+}
+
+func (q *Queue) save() error {
+	if q.filePath == "" {
+		return nil
+	}
+	return atomicfile.WriteJSON(q.filePath, q.entries)
+}
+
+func (q *Queue) CreateOneShotJob(name, message string, timeoutSeconds, delaySeconds int) error {
+	return q.CreateOneShotJobForAgent(name, message, "", timeoutSeconds, delaySeconds)
+}
+
+func (q *Queue) CreateOneShotJobForAgent(name, message, agentID string, timeoutSeconds, delaySeconds int) error {
+	return q.CreateJob(gateway.JobSpec{Name: name, Message: message, AgentID: agentID, TimeoutSeconds: timeoutSeconds, DelaySeconds: delaySeconds})
+}
+
+// CreateJob forwards spec and keeps it for retry when that fails.
+func (q *Queue) CreateJob(spec gateway.JobSpec) error {
+	err := gateway.CreateJob(q.next, spec)
+	if err == nil {
+		return err
+	}
+	now := q.now()
+	e := Entry{
+		ID:          newID(),
+		JobID:       spec.ID,
+		Source:      spec.Source,
+		Rule:        spec.Rule,
+		Account:     spec.Account,
+		Name:        spec.Name,
+		Message:     spec.Message,
+		AgentID:     spec.AgentID,
+		Timeout:     spec.TimeoutSeconds,
+		Tags:        spec.Tags,
+		FireAt:      now.Add(time.Duration(spec.DelaySeconds) * time.Second).UTC(),
+		FailedAt:    now.UTC(),
+		Error:       err.Error(),
+		Attempts:    1,
+		NextAttempt: now.Add(q.RetryInterval).UTC(),
+	}
+	if json.Valid(spec.Payload) {
+		e.Payload = spec.Payload
+	}
+	q.mu.Lock()
+	q.entries = append(q.entries, e)
+	if len(q.entries) > q.MaxEntries {
+		q.entries = q.entries[len(q.entries)-q.MaxEntries:]
+	}
+	if saveErr := q.save(); saveErr != nil {
+		log.Printf("Dead letter: failed to save: %v", saveErr)
+	}
+	q.mu.Unlock()
+	log.Printf("Dead letter: kept job %q (%s) for retry: %v", spec.Name, e.ID, err)
+	return err
+}
+
+// List returns the queued entries, oldest first.
+func (q *Queue) List() []Entry {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	out := make([]Entry, len(q.entries))
+	copy(out, q.entries)
+	return out
+}
+
+// Delete drops an entry.
+func (q *Queue) Delete(id string) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for i := range q.entries {
+		if q.entries[i].ID == id {
+			q.entries = append(q.entries[:i], q.entries[i+1:]...)
+			return q.save()
+		}
+	}
+	return ErrNotFound
+}
+
+// RemoveAccount drops the entries of jobs about account, as off-boarding
+// does, and returns how many there were.
+func (q *Queue) RemoveAccount(account string) (int, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	n := len(q.entries)
+	q.entries = slices.DeleteFunc(q.entries, func(e Entry) bool { return e.Account == account })
+	if removed := n - len(q.entries); removed > 0 {
+		return removed, q.save()
+	}
+	return 0, nil
+}
+
+// Prune drops the entries out of retries that failed before cutoff and
+// returns them. With dryRun it only returns them.
+func (q *Queue) Prune(cutoff time.Time, dryRun bool) []Entry {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	var pruned []Entry
+	kept := q.entries[:0:0]
+	for _, e := range q.entries {
+		if e.NextAttempt.IsZero() && e.FailedAt.Before(cutoff) && !q.sending[e.ID] {
+			pruned = append(pruned, e)
+			continue
+		}
+		kept = append(kept, e)
+	}
+	if !dryRun && len(pruned) > 0 {
+		q.entries = kept
+		q.saveLocked()
+	}
+	return pruned
+}
+
+// Replay sends an entry now, whatever its schedule, and removes it on success.
+// On failure it counts as an attempt.
+func (q *Queue) Replay(id string) error {
+	q.mu.Lock()
+	var e Entry
+	found := false
+	for _, x := range q.entries {
+		if x.ID == id {
+			e, found = x, true
+			break
+		}
+	}
+	q.mu.Unlock()
+	if !found {
+		return ErrNotFound
+	}
+	return q.attempt(e)
+}
+
+// Retry sends every entry whose next attempt is due and returns how many went through.
+func (q *Queue) Retry() int {
+	now := q.now()
+	q.mu.Lock()
+	var due []Entry
+	for _, e := range q.entries {
+		if !e.NextAttempt.IsZero() && !e.NextAttempt.After(now) {
+			due = append(due, e)
+		}
+	}
+	q.mu.Unlock()
+
+	sent := 0
+	for _, e := range due {
+		if q.attempt(e) == nil {
+			sent++
+		}
+	}
+	return sent
+}
+
+// errInProgress is returned by attempt while another attempt at the entry runs.
+var errInProgress = errors.New("attempt in progress")
+
+func (q *Queue) attempt(e Entry) error {
+	q.mu.Lock()
+	if q.sending[e.ID] {
+		q.mu.Unlock()
+		return errInProgress
+	}
+	q.sending[e.ID] = true
+	q.mu.Unlock()
+
+	err := gateway.CreateJob(q.next, e.spec(q.now()))
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	delete(q.sending, e.ID)
+	i := q.index(e.ID)
+	if i < 0 {
+		return err // deleted meanwhile
+	}
+	if err == nil {
+		log.Printf("Dead letter: delivered job %q (%s) after %d attempts", e.Name, e.ID, e.Attempts+1)
+		q.entries = append(q.entries[:i], q.entries[i+1:]...)
+		q.saveLocked()
+		return nil
+	}
+	x := &q.entries[i]
+	x.Attempts++
+	x.Error = err.Error()
+	x.NextAttempt = time.Time{}
+	if x.Attempts < q.MaxAttempts {
+		x.NextAttempt = q.now().Add(q.backoff(x.Attempts)).UTC()
+	} else {
+		log.Printf("Dead letter: giving up on job %q (%s) after %d attempts; replay it via /api/deadletter", x.Name, x.ID, x.Attempts)
+	}
+	q.saveLocked()
+	return err
+}
+
+func (q *Queue) saveLocked() {
+	if err := q.save(); err != nil {
+		log.Printf("Dead letter: failed to save: %v", err)
+	}
+}
+
+func (q *Queue) index(id string) int {
+	for i := range q.entries {
+		if q.entries[i].ID == id {
+			return i
+		}
+	}
+	return -1
+}
+
+// backoff is the wait after the given number of failed attempts.
+func (q *Queue) backoff(attempts int) time.Duration {
+	d := q.RetryInterval
+	for i := 1; i < attempts && d < maxRetryInterval; i++ {
+		d *= 2
+	}
+	return min(d, maxRetryInterval)
+}
+
+// Start retries due entries in the background until ctx is done.
+func (q *Queue) Start(ctx context.Context) {
+	tick := min(q.RetryInterval, 30*time.Second)
+	go func() {
+		t := time.NewTicker(tick)
+		defer t.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-t.C:
+				q.Retry()
+			}
+		}
+	}()
+}
@@ -0,0 +1,200 @@
+package deadletter
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/katalabut/openclaw-relay/internal/gateway"
+)
+
+// flakyGateway fails while down is set and records the jobs it accepts.
+type flakyGateway struct {
+	down bool
+	err  error
+	jobs []gateway.JobSpec
+}
+
+func (g *flakyGateway) CreateOneShotJob(name, message string, timeoutSeconds, delaySeconds int) error {
+	return g.CreateOneShotJobForAgent(name, message, "", timeoutSeconds, delaySeconds)
+}
+
+func (g *flakyGateway) CreateOneShotJobForAgent(name, message, agentID string, timeoutSeconds, delaySeconds int) error {
+	return g.CreateJob(gateway.JobSpec{Name: name, Message: message, AgentID: agentID, TimeoutSeconds: timeoutSeconds, DelaySeconds: delaySeconds})
+}
+
+func (g *flakyGateway) CreateJob(spec gateway.JobSpec) error {
+	if g.err != nil {
+		return g.err
+	}
+	if g.down {
+		return errors.New("gateway returned 502")
+	}
+	g.jobs = append(g.jobs, spec)
+	return nil
+}
+
+func TestQueue_KeepsAndRetries(t *testing.T) {
+	gw := &flakyGateway{down: true}
+	q, err := New(gw, filepath.Join(t.TempDir(), "deadletter.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	q.now = func() time.Time { return now }
+
+	spec := gateway.JobSpec{
+		ID: "job-1", Name: "trello card_moved: Fix login", Message: "msg", AgentID: "work",
+		TimeoutSeconds: 120, DelaySeconds: 600, Source: "trello",
+		Payload: []byte(`{"action":{"type":"updateCard"}}`),
+	}
+	if err := q.CreateJob(spec); err == nil {
+		t.Fatal("expected the gateway error to be returned")
+	}
+	entries := q.List()
+	if len(entries) != 1 {
+		t.Fatalf("expected 1 entry, got %d", len(entries))
+	}
+	e := entries[0]
+	if e.Source != "trello" || e.Message != "msg" || string(e.Payload) != `{"action":{"type":"updateCard"}}` || e.Attempts != 1 {
+		t.Errorf("unexpected entry %+v", e)
+	}
+
+	// Not due yet
+	if q.Retry() != 0 {
+		t.Error("expected nothing due")
+	}
+	// Due, gateway still down: attempts grow and the wait doubles
+	now = now.Add(time.Minute)
+	q.Retry()
+	if e := q.List()[0]; e.Attempts != 2 || !e.NextAttempt.Equal(now.Add(2*time.Minute)) {
+		t.Errorf("unexpected entry after failed retry %+v", e)
+	}
+
+	// Back up: the job goes through with what is left of its delay
+	gw.down = false
+	now = now.Add(2 * time.Minute)
+	if q.Retry() != 1 || len(q.List()) != 0 {
+		t.Fatalf("expected the entry delivered, left %+v", q.List())
+	}
+	if got := gw.jobs[0]; got.DelaySeconds != 420 || got.ID != "job-1" || got.Source != "trello" || got.AgentID != "work" {
+		t.Errorf("unexpected retried job %+v", got)
+	}
+}
+
+func TestQueue_Exhausted(t *testing.T) {
+	gw := &flakyGateway{down: true}
+	q, _ := New(gw, "")
+	q.MaxAttempts = 2
+	now := time.Now()
+	q.now = func() time.Time { return now }
+
+	q.CreateJob(gateway.JobSpec{Name: "job", Source: "slack"})
+	now = now.Add(time.Hour)
+	q.Retry()
+	e := q.List()[0]
+	if e.Attempts != 2 || !e.NextAttempt.IsZero() {
+		t.Fatalf("expected retries to stop, got %+v", e)
+	}
+	now = now.Add(24 * time.Hour)
+	if q.Retry() != 0 {
+		t.Error("exhausted entries are not retried automatically")
+	}
+
+	gw.down = false
+	if err := q.Replay(e.ID); err != nil || len(q.List()) != 0 {
+		t.Errorf("expected manual replay to deliver, got %v", err)
+	}
+	if err := q.Replay(e.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestQueue_Persist(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "deadletter.json")
+	q, _ := New(&flakyGateway{down: true}, path)
+	q.CreateJob(gateway.JobSpec{Name: "job", Source: "jira", Payload: []byte("not json")})
+
+	reopened, err := New(&flakyGateway{}, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	entries := reopened.List()
+	if len(entries) != 1 || entries[0].Name != "job" || entries[0].Payload != nil {
+		t.Fatalf("unexpected entries after reopen %+v", entries)
+	}
+	if err := reopened.Delete(entries[0].ID); err != nil || len(reopened.List()) != 0 {
+		t.Errorf("expected delete, got %v", err)
+	}
+}
+
+func TestQueue_Prune(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "deadletter.json")
+	q, _ := New(&flakyGateway{down: true}, path)
+	q.MaxAttempts = 2
+	now := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	q.now = func() time.Time { return now }
+	q.CreateJob(gateway.JobSpec{Name: "old", Source: "slack"})
+	now = now.Add(time.Hour)
+	q.Retry()
+	now = now.Add(48 * time.Hour)
+	q.CreateJob(gateway.JobSpec{Name: "new", Source: "slack"})
+	now = now.Add(time.Hour)
+	q.Retry()
+	q.MaxAttempts = 5
+	q.CreateJob(gateway.JobSpec{Name: "retrying", Source: "slack"})
+
+	cutoff := now.Add(-24 * time.Hour)
+	if pruned := q.Prune(cutoff, true); len(pruned) != 1 || pruned[0].Name != "old" || len(q.List()) != 3 {
+		t.Fatalf("dry run: pruned %+v, %d left", pruned, len(q.List()))
+	}
+	if pruned := q.Prune(cutoff, false); len(pruned) != 1 || pruned[0].Name != "old" {
+		t.Fatalf("pruned %+v", pruned)
+	}
+	reopened, _ := New(&flakyGateway{}, path)
+	if entries := reopened.List(); len(entries) != 2 {
+		t.Errorf("expected the prune saved, got %+v", entries)
+	}
+	if pruned := q.Prune(now.Add(time.Hour), false); len(pruned) != 1 || pruned[0].Name != "new" {
+		t.Errorf("entries still retrying must be kept, pruned %+v", pruned)
+	}
+}
+
+func TestQueue_RemoveAccount(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "deadletter.json")
+	q, _ := New(&flakyGateway{down: true}, path)
+	q.CreateJob(gateway.JobSpec{Name: "mail", Source: "gmail", Account: "gone@example.com"})
+	q.CreateJob(gateway.JobSpec{Name: "kept", Source: "gmail", Account: "me@example.com"})
+	q.CreateJob(gateway.JobSpec{Name: "webhook", Source: "slack"})
+
+	if n, err := q.RemoveAccount("gone@example.com"); err != nil || n != 1 {
+		t.Fatalf("RemoveAccount = %d, %v", n, err)
+	}
+	reopened, _ := New(&flakyGateway{}, path)
+	entries := reopened.List()
+	if len(entries) != 2 || entries[0].Name != "kept" || entries[0].Account != "me@example.com" {
+		t.Errorf("entries = %+v", entries)
+	}
+	if n, err := q.RemoveAccount("gone@example.com"); err != nil || n != 0 {
+		t.Errorf("second removal = %d, %v", n, err)
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	q, _ := New(&flakyGateway{}, "")
+	tests := []struct {
+		attempts int
+		want     time.Duration
+	}{
+		{1, time.Minute},
+		{2, 2 * time.Minute},
+		{4, 8 * time.Minute},
+		{20, time.Hour},
+	}
+	for _, tt := range tests {
+		if got := q.backoff(tt.attempts); got != tt.want {
+			t.Errorf("backoff(%d) = %v, want %v", tt.attempts, got, tt.want)
+		}
+	}
+}
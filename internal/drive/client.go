@@ -0,0 +1,219 @@
+// Package drive reads Google Drive for the accounts signed in with Google
+// OAuth: /api/drive/file/{id} for the agent, and a poller that follows the
+// account's change feed and creates jobs for files matching drive rules.
+package drive
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/katalabut/openclaw-relay/internal/tokens"
+	"golang.org/x/oauth2"
+	dr "google.golang.org/api/drive/v3"
+	"google.golang.org/api/googleapi"
+	"google.golang.org/api/option"
+)
+
+// ErrTooLarge is returned by Export and Download when the content is over
+// the caller's limit.
+var ErrTooLarge = errors.New("file content is over drive.max_download_mb")
+
+// DriveClient is the interface the API handler and poller use, so tests can
+// fake Google Drive.
+type DriveClient interface {
+	StartPageToken(ctx context.Context) (string, error)
+	// ListChanges returns a page of changes since pageToken, and either the
+	// next page's token or, on the last page, the token to resume from.
+	ListChanges(ctx context.Context, pageToken string) (changes []Change, next, newStart string, err error)
+	GetFile(ctx context.Context, id string) (*File, error)
+	Export(ctx context.Context, id, mimeType string, maxBytes int64) ([]byte, error)
+	Download(ctx context.Context, id string, maxBytes int64) ([]byte, error)
+}
+
+// Client wraps Google Drive API v3 for one account.
+type Client struct {
+	store    *tokens.Store
+	oauthCfg *oauth2.Config
+	email    string
+	endpoint string
+}
+
+func NewClientForAccount(store *tokens.Store, oauthCfg *oauth2.Config, email string) *Client {
+	return &Client{store: store, oauthCfg: oauthCfg, email: email}
+}
+
+// SetEndpoint points the client at another Drive API base URL, e.g. a fake
+// server in tests. Empty means Google's default.
+func (c *Client) SetEndpoint(url string) {
+	c.endpoint = url
+}
+
+func (c *Client) getService(ctx context.Context) (*dr.Service, error) {
+	ts, err := c.store.GoogleTokenSource(ctx, c.oauthCfg, c.email)
+	if err != nil {
+		return nil, err
+	}
+	opts := []option.ClientOption{option.WithTokenSource(ts)}
+	if c.endpoint != "" {
+		opts = append(opts, option.WithEndpoint(c.endpoint))
+	}
+	return dr.NewService(ctx, opts...)
+}
+
+// File is a Drive file's metadata.
+type File struct {
+	ID         string    `json:"id"`
+	Name       string    `json:"name"`
+	MimeType   string    `json:"mimeType"`
+	Parents    []string  `json:"parents,omitempty"` // folder IDs
+	Owners     []string  `json:"owners,omitempty"`  // email addresses; none for shared drive files
+	ModifiedBy string    `json:"modifiedBy,omitempty"`
+	Modified   time.Time `json:"modified"`
+	Created    time.Time `json:"created"`
+	Size       int64     `json:"size,omitempty"` // bytes; 0 for Google Docs, Sheets and Slides
+	URL        string    `json:"url,omitempty"`  // the file in the Drive web UI
+	Trashed    bool      `json:"trashed,omitempty"`
+}
+
+// Change is an entry of the account's change feed. File is nil when the
+// file was removed or the account lost access to it.
+type Change struct {
+	FileID  string
+	Removed bool
+	Time    time.Time
+	File    *File
+}
+
+// fileFields are the file fields File is built from.
+const fileFields = "id,name,mimeType,parents,owners(emailAddress),lastModifyingUser(emailAddress),modifiedTime,createdTime,size,webViewLink,trashed"
+
+// StartPageToken returns the token of the change feed's current end.
+func (c *Client) StartPageToken(ctx context.Context) (string, error) {
+	svc, err := c.getService(ctx)
+	if err != nil {
+		return "", err
+	}
+	resp, err := svc.Changes.GetStartPageToken().SupportsAllDrives(true).Context(ctx).Do()
+	if err != nil {
+		return "", err
+	}
+	return resp.StartPageToken, nil
+}
+
+// ListChanges lists the file changes in My Drive and shared drives since
+// pageToken.
+func (c *Client) ListChanges(ctx context.Context, pageToken string) ([]Change, string, string, error) {
+	svc, err := c.getService(ctx)
+	if err != nil {
+		return nil, "", "", err
+	}
+	resp, err := svc.Changes.List(pageToken).
+		IncludeRemoved(true).
+		IncludeItemsFromAllDrives(true).
+		SupportsAllDrives(true).
+		Spaces("drive").
+		PageSize(100).
+		Fields(googleapi.Field("nextPageToken,newStartPageToken,changes(fileId,removed,time,changeType,file(" + fileFields + "))")).
+		Context(ctx).
+		Do()
+	if err != nil {
+		return nil, "", "", err
+	}
+	changes := make([]Change, 0, len(resp.Changes))
+	for _, ch := range resp.Changes {
+		if ch.ChangeType != "" && ch.ChangeType != "file" {
+			continue
+		}
+		out := Change{FileID: ch.FileId, Removed: ch.Removed, Time: parseTime(ch.Time)}
+		if ch.File != nil {
+			f := convertFile(ch.File)
+			out.File = &f
+		}
+		changes = append(changes, out)
+	}
+	return changes, resp.NextPageToken, resp.NewStartPageToken, nil
+}
+
+// GetFile returns a file's metadata.
+func (c *Client) GetFile(ctx context.Context, id string) (*File, error) {
+	svc, err := c.getService(ctx)
+	if err != nil {
+		return nil, err
+	}
+	f, err := svc.Files.Get(id).SupportsAllDrives(true).Fields(googleapi.Field(fileFields)).Context(ctx).Do()
+	if err != nil {
+		return nil, err
+	}
+	out := convertFile(f)
+	return &out, nil
+}
+
+// Export converts a Google Docs, Sheets or Slides file to mimeType. Google
+// caps exports at 10 MB.
+func (c *Client) Export(ctx context.Context, id, mimeType string, maxBytes int64) ([]byte, error) {
+	svc, err := c.getService(ctx)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := svc.Files.Export(id, mimeType).Context(ctx).Download()
+	if err != nil {
+		return nil, fmt.Errorf("export file: %w", err)
+	}
+	defer resp.Body.Close()
+	return readLimited(resp.Body, maxBytes)
+}
+
+// Download returns a file's content as stored.
+func (c *Client) Download(ctx context.Context, id string, maxBytes int64) ([]byte, error) {
+	svc, err := c.getService(ctx)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := svc.Files.Get(id).SupportsAllDrives(true).Context(ctx).Download()
+	if err != nil {
+		return nil, fmt.Errorf("download file: %w", err)
+	}
+	defer resp.Body.Close()
+	return readLimited(resp.Body, maxBytes)
+}
+
+// readLimited reads r, or fails with ErrTooLarge past maxBytes.
+func readLimited(r io.Reader, maxBytes int64) ([]byte, error) {
+	data, err := io.ReadAll(io.LimitReader(r, maxBytes+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > maxBytes {
+		return nil, ErrTooLarge
+	}
+	return data, nil
+}
+
+func convertFile(f *dr.File) File {
+	out := File{
+		ID:       f.Id,
+		Name:     f.Name,
+		MimeType: f.MimeType,
+		Parents:  f.Parents,
+		Modified: parseTime(f.ModifiedTime),
+		Created:  parseTime(f.CreatedTime),
+		Size:     f.Size,
+		URL:      f.WebViewLink,
+		Trashed:  f.Trashed,
+	}
+	for _, o := range f.Owners {
+		out.Owners = append(out.Owners, o.EmailAddress)
+	}
+	if f.LastModifyingUser != nil {
+		out.ModifiedBy = f.LastModifyingUser.EmailAddress
+	}
+	return out
+}
+
+func parseTime(s string) time.Time {
+	t, _ := time.Parse(time.RFC3339, s)
+	return t
+}
@@ -0,0 +1,106 @@
+package drive
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/katalabut/openclaw-relay/internal/tokens"
+	"golang.org/x/oauth2"
+)
+
+// newTestClient returns a client for me@example.com against a fake Drive
+// API served by h.
+func newTestClient(t *testing.T, h http.HandlerFunc) *Client {
+	t.Helper()
+	srv := httptest.NewServer(h)
+	t.Cleanup(srv.Close)
+	store, err := tokens.NewStore(filepath.Join(t.TempDir(), "tokens.json.enc"), strings.Repeat("ab", 32))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := store.SaveGoogle(&oauth2.Token{AccessToken: "tok", RefreshToken: "r", Expiry: time.Now().Add(time.Hour)}, "me@example.com"); err != nil {
+		t.Fatal(err)
+	}
+	c := NewClientForAccount(store, &oauth2.Config{}, "me@example.com")
+	c.SetEndpoint(srv.URL + "/")
+	return c
+}
+
+func TestClient_Changes(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/changes/startPageToken":
+			fmt.Fprint(w, `{"startPageToken":"100"}`)
+		case "/changes":
+			q := r.URL.Query()
+			if q.Get("pageToken") != "100" || q.Get("includeItemsFromAllDrives") != "true" || q.Get("includeRemoved") != "true" {
+				t.Errorf("query = %s", r.URL.RawQuery)
+			}
+			fmt.Fprint(w, `{"newStartPageToken":"104","changes":[
+				{"changeType":"file","fileId":"f1","time":"2026-03-02T09:00:00Z","file":{"id":"f1","name":"Plan","mimeType":"application/vnd.google-apps.document","parents":["fold"],"owners":[{"emailAddress":"dana@example.com"}],"lastModifyingUser":{"emailAddress":"lee@example.com"},"modifiedTime":"2026-03-02T08:59:00Z","webViewLink":"https://docs.example/f1"}},
+				{"changeType":"file","fileId":"f2","removed":true},
+				{"changeType":"drive","driveId":"d1"}]}`)
+		default:
+			t.Errorf("path = %s", r.URL.Path)
+		}
+	})
+	ctx := context.Background()
+	start, err := c.StartPageToken(ctx)
+	if err != nil || start != "100" {
+		t.Fatalf("start = %q, err %v", start, err)
+	}
+	changes, next, newStart, err := c.ListChanges(ctx, start)
+	if err != nil || next != "" || newStart != "104" {
+		t.Fatalf("next %q, newStart %q, err %v", next, newStart, err)
+	}
+	if len(changes) != 2 {
+		t.Fatalf("changes = %+v", changes)
+	}
+	f := changes[0].File
+	if f.Name != "Plan" || f.Parents[0] != "fold" || f.Owners[0] != "dana@example.com" || f.ModifiedBy != "lee@example.com" || f.Modified.IsZero() {
+		t.Errorf("file = %+v", f)
+	}
+	if !changes[1].Removed || changes[1].File != nil {
+		t.Errorf("removed = %+v", changes[1])
+	}
+}
+
+func TestClient_Content(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/files/doc/export" && r.URL.Query().Get("mimeType") == "text/plain":
+			fmt.Fprint(w, "Plan for Q3")
+		case r.URL.Path == "/files/pdf" && r.URL.Query().Get("alt") == "media":
+			fmt.Fprint(w, "%PDF-1.7 ...")
+		case r.URL.Path == "/files/pdf":
+			w.Header().Set("Content-Type", "application/json")
+			fmt.Fprint(w, `{"id":"pdf","name":"report.pdf","mimeType":"application/pdf","size":"12"}`)
+		default:
+			http.Error(w, `{"error":{"code":404,"message":"File not found"}}`, http.StatusNotFound)
+		}
+	})
+	ctx := context.Background()
+	if data, err := c.Export(ctx, "doc", "text/plain", 1<<20); err != nil || string(data) != "Plan for Q3" {
+		t.Errorf("export = %q, err %v", data, err)
+	}
+	if f, err := c.GetFile(ctx, "pdf"); err != nil || f.Size != 12 || f.MimeType != "application/pdf" {
+		t.Errorf("file = %+v, err %v", f, err)
+	}
+	if data, err := c.Download(ctx, "pdf", 1<<20); err != nil || string(data) != "%PDF-1.7 ..." {
+		t.Errorf("download = %q, err %v", data, err)
+	}
+	if _, err := c.Download(ctx, "pdf", 4); !errors.Is(err, ErrTooLarge) {
+		t.Errorf("over the cap: %v", err)
+	}
+	if _, err := c.Export(ctx, "missing", "text/plain", 1<<20); err == nil {
+		t.Error("expected an error for a missing file")
+	}
+}
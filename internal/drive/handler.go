@@ -0,0 +1,165 @@
+package drive
+
+import (
+	"encoding/json"
+	"errors"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/katalabut/openclaw-relay/internal/screening"
+)
+
+const folderMimeType = "application/vnd.google-apps.folder"
+
+// defaultExports are the formats Google files are exported to when
+// ?format= is unset: text the agent can read.
+var defaultExports = map[string]string{
+	"application/vnd.google-apps.document":     "text/plain",
+	"application/vnd.google-apps.spreadsheet":  "text/csv", // the first sheet only
+	"application/vnd.google-apps.presentation": "text/plain",
+}
+
+// Handler serves the Drive API for the agent.
+type Handler struct {
+	clients        map[string]DriveClient
+	defaultAccount string
+	maxBytes       int64
+	screener       *screening.Screener
+}
+
+// NewHandler serves clients, keyed by account; ?account= defaults to the
+// first of accounts that has one. Content over maxBytes is refused.
+func NewHandler(accounts []string, clients map[string]DriveClient, maxBytes int64) *Handler {
+	h := &Handler{clients: clients, maxBytes: maxBytes}
+	for _, acc := range accounts {
+		if _, ok := clients[acc]; ok {
+			h.defaultAccount = acc
+			break
+		}
+	}
+	return h
+}
+
+// SetScreener screens downloaded files like mail attachments. Exports of
+// Google files are text and are not screened.
+func (h *Handler) SetScreener(s *screening.Screener) {
+	h.screener = s
+}
+
+// RegisterRoutes adds the Drive API routes to the mux.
+func (h *Handler) RegisterRoutes(mux *http.ServeMux) {
+	mux.HandleFunc("/api/drive/file/", h.handleFile)
+}
+
+// resolveClient returns the client for ?account=, the default account when
+// it's unset.
+func (h *Handler) resolveClient(r *http.Request) (DriveClient, bool) {
+	account := r.URL.Query().Get("account")
+	if account == "" {
+		account = h.defaultAccount
+	}
+	client, ok := h.clients[account]
+	return client, ok
+}
+
+func jsonResponse(w http.ResponseWriter, data any) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(data)
+}
+
+func jsonError(w http.ResponseWriter, msg string, code int) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(map[string]string{"error": msg})
+}
+
+// handleFile serves GET /api/drive/file/{id} with the file's content and
+// GET /api/drive/file/{id}/metadata with its metadata.
+func (h *Handler) handleFile(w http.ResponseWriter, r *http.Request) {
+	id, action, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/api/drive/file/"), "/")
+	if id == "" || (action != "" && action != "metadata") {
+		jsonError(w, "not found", http.StatusNotFound)
+		return
+	}
+	if r.Method != http.MethodGet {
+		jsonError(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	client, ok := h.resolveClient(r)
+	if !ok {
+		jsonError(w, "unknown account", http.StatusBadRequest)
+		return
+	}
+	f, err := client.GetFile(r.Context(), id)
+	if err != nil {
+		jsonError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if action == "metadata" {
+		jsonResponse(w, f)
+		return
+	}
+	h.serveContent(w, r, client, f)
+}
+
+// serveContent writes a file's content: Google files exported to ?format=
+// (a MIME type, default per defaultExports), other files as stored after
+// screening.
+func (h *Handler) serveContent(w http.ResponseWriter, r *http.Request, client DriveClient, f *File) {
+	format := r.URL.Query().Get("format")
+	var (
+		data     []byte
+		mimeType string
+		err      error
+	)
+	switch {
+	case f.MimeType == folderMimeType:
+		jsonError(w, "file is a folder", http.StatusBadRequest)
+		return
+	case strings.HasPrefix(f.MimeType, "application/vnd.google-apps."):
+		mimeType = format
+		if mimeType == "" {
+			mimeType = defaultExports[f.MimeType]
+		}
+		if mimeType == "" {
+			jsonError(w, "no default export for "+f.MimeType+"; set format", http.StatusUnsupportedMediaType)
+			return
+		}
+		data, err = client.Export(r.Context(), f.ID, mimeType, h.maxBytes)
+	default:
+		if format != "" {
+			jsonError(w, "format applies to Google Docs, Sheets and Slides only", http.StatusBadRequest)
+			return
+		}
+		if res := h.screener.Check(f.Name, f.Size); !res.Allowed() {
+			jsonError(w, "file blocked: "+res.Reason, http.StatusForbidden)
+			return
+		}
+		mimeType = f.MimeType
+		data, err = client.Download(r.Context(), f.ID, h.maxBytes)
+		if err == nil {
+			if res := h.screener.Scan(r.Context(), f.Name, data); !res.Allowed() {
+				jsonError(w, "file blocked: "+res.Status+" "+res.Reason, http.StatusForbidden)
+				return
+			}
+		}
+	}
+	if errors.Is(err, ErrTooLarge) {
+		jsonError(w, err.Error(), http.StatusRequestEntityTooLarge)
+		return
+	}
+	if err != nil {
+		jsonError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if mimeType == "" {
+		mimeType = "application/octet-stream"
+	}
+	w.Header().Set("Content-Type", mimeType)
+	w.Header().Set("Content-Length", strconv.Itoa(len(data)))
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": f.Name}))
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Write(data)
+}
@@ -0,0 +1,151 @@
+package drive
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/katalabut/openclaw-relay/internal/screening"
+)
+
+// fakeClient serves files and changes from memory.
+type fakeClient struct {
+	files   map[string]File
+	content map[string]string // file ID (or ID/export MIME type) -> content
+	changes map[string][]Change
+	pages   map[string]string // page token -> next page token; the last page's entry is the new start
+	start   string
+	err     error
+
+	lastExport string
+}
+
+func (f *fakeClient) StartPageToken(ctx context.Context) (string, error) {
+	return f.start, f.err
+}
+
+func (f *fakeClient) ListChanges(ctx context.Context, pageToken string) ([]Change, string, string, error) {
+	if f.err != nil {
+		return nil, "", "", f.err
+	}
+	next := f.pages[pageToken]
+	if _, more := f.changes[next]; more {
+		return f.changes[pageToken], next, "", nil
+	}
+	return f.changes[pageToken], "", next, nil
+}
+
+func (f *fakeClient) GetFile(ctx context.Context, id string) (*File, error) {
+	if file, ok := f.files[id]; ok {
+		return &file, nil
+	}
+	return nil, errors.New("not found")
+}
+
+func (f *fakeClient) Export(ctx context.Context, id, mimeType string, maxBytes int64) ([]byte, error) {
+	f.lastExport = mimeType
+	return f.read(id+"/"+mimeType, maxBytes)
+}
+
+func (f *fakeClient) Download(ctx context.Context, id string, maxBytes int64) ([]byte, error) {
+	return f.read(id, maxBytes)
+}
+
+func (f *fakeClient) read(key string, maxBytes int64) ([]byte, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	data, ok := f.content[key]
+	if !ok {
+		return nil, errors.New("not found")
+	}
+	if int64(len(data)) > maxBytes {
+		return nil, ErrTooLarge
+	}
+	return []byte(data), nil
+}
+
+func serve(h *Handler, url string) *httptest.ResponseRecorder {
+	mux := http.NewServeMux()
+	h.RegisterRoutes(mux)
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest("GET", url, nil))
+	return rec
+}
+
+func TestHandler_File(t *testing.T) {
+	fc := &fakeClient{
+		files: map[string]File{
+			"doc":   {ID: "doc", Name: "Plan", MimeType: "application/vnd.google-apps.document"},
+			"sheet": {ID: "sheet", Name: "Budget", MimeType: "application/vnd.google-apps.spreadsheet"},
+			"form":  {ID: "form", Name: "Survey", MimeType: "application/vnd.google-apps.form"},
+			"dir":   {ID: "dir", Name: "Team", MimeType: folderMimeType},
+			"pdf":   {ID: "pdf", Name: "report.pdf", MimeType: "application/pdf", Size: 12},
+			"exe":   {ID: "exe", Name: "setup.exe", MimeType: "application/octet-stream", Size: 10},
+			"big":   {ID: "big", Name: "video.mp4", MimeType: "video/mp4", Size: 100},
+		},
+		content: map[string]string{
+			"doc/text/plain":      "Plan for Q3",
+			"doc/application/pdf": "%PDF doc",
+			"sheet/text/csv":      "a,b\n1,2\n",
+			"pdf":                 "%PDF-1.7 ...",
+			"exe":                 "MZ........",
+			"big":                 string(make([]byte, 100)),
+		},
+	}
+	h := NewHandler([]string{"me@example.com"}, map[string]DriveClient{"me@example.com": fc}, 64)
+	h.SetScreener(&screening.Screener{DenyExtensions: screening.DefaultDenyExtensions})
+
+	tests := []struct {
+		url  string
+		code int
+		body string
+		mime string
+	}{
+		{"/api/drive/file/doc", http.StatusOK, "Plan for Q3", "text/plain"},
+		{"/api/drive/file/doc?format=application/pdf", http.StatusOK, "%PDF doc", "application/pdf"},
+		{"/api/drive/file/sheet", http.StatusOK, "a,b\n1,2\n", "text/csv"},
+		{"/api/drive/file/pdf", http.StatusOK, "%PDF-1.7 ...", "application/pdf"},
+		{"/api/drive/file/form", http.StatusUnsupportedMediaType, "", ""},
+		{"/api/drive/file/dir", http.StatusBadRequest, "", ""},
+		{"/api/drive/file/pdf?format=text/plain", http.StatusBadRequest, "", ""},
+		{"/api/drive/file/exe", http.StatusForbidden, "", ""},
+		{"/api/drive/file/big", http.StatusRequestEntityTooLarge, "", ""},
+		{"/api/drive/file/missing", http.StatusInternalServerError, "", ""},
+		{"/api/drive/file/pdf?account=other@example.com", http.StatusBadRequest, "", ""},
+		{"/api/drive/file/pdf/revisions", http.StatusNotFound, "", ""},
+	}
+	for _, tt := range tests {
+		rec := serve(h, tt.url)
+		if rec.Code != tt.code {
+			t.Errorf("%s: code %d, want %d (%s)", tt.url, rec.Code, tt.code, rec.Body)
+			continue
+		}
+		if tt.body != "" && (rec.Body.String() != tt.body || rec.Header().Get("Content-Type") != tt.mime) {
+			t.Errorf("%s: %q (%s)", tt.url, rec.Body, rec.Header().Get("Content-Type"))
+		}
+	}
+}
+
+func TestHandler_Metadata(t *testing.T) {
+	fc := &fakeClient{files: map[string]File{"doc": {ID: "doc", Name: "Plan", Owners: []string{"dana@example.com"}}}}
+	h := NewHandler([]string{"me@example.com"}, map[string]DriveClient{"me@example.com": fc}, 64)
+
+	rec := serve(h, "/api/drive/file/doc/metadata")
+	var f File
+	json.NewDecoder(rec.Body).Decode(&f)
+	if rec.Code != http.StatusOK || f.Name != "Plan" || f.Owners[0] != "dana@example.com" {
+		t.Errorf("metadata: %d %+v", rec.Code, f)
+	}
+
+	mux := http.NewServeMux()
+	h.RegisterRoutes(mux)
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest("DELETE", "/api/drive/file/doc", nil))
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("DELETE: %d", rec.Code)
+	}
+}
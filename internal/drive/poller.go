@@ -0,0 +1,294 @@
+package drive
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/katalabut/openclaw-relay/internal/atomicfile"
+	"github.com/katalabut/openclaw-relay/internal/config"
+	"github.com/katalabut/openclaw-relay/internal/gateway"
+	"github.com/katalabut/openclaw-relay/internal/migrate"
+	"github.com/katalabut/openclaw-relay/internal/ratelimit"
+)
+
+// stateFileName is the file in the state dir holding each account's change
+// feed position, so a restart resumes where the last poll stopped.
+const stateFileName = "drive-state.json"
+
+// Poller creates the jobs of drive rules. Each poll reads the accounts'
+// change feeds from the saved position and creates a job for each changed
+// file a rule matches. The first poll of an account only records the feed's
+// end: files changed before the relay started are not reported.
+type Poller struct {
+	clients  map[string]DriveClient
+	accounts []string
+	cfg      config.DriveConfig
+	interval time.Duration
+	gateway  gateway.GatewayClient
+	limiter  *ratelimit.Limiter
+	stateDir string
+	timezone string // server.timezone, for action schedules and job times
+
+	// mu serializes polls and RemoveAccount. tokens maps each account to its
+	// change feed position; removed holds off-boarded accounts.
+	mu      sync.Mutex
+	tokens  map[string]string
+	removed map[string]bool
+}
+
+// driveState is the state file.
+type driveState struct {
+	Version  int               `json:"version"`
+	Accounts map[string]string `json:"accounts"` // account → page token
+}
+
+// stateSchema versions the state file. Add a migrate.Step when driveState
+// changes shape.
+var stateSchema = migrate.Schema{Name: "drive"}
+
+func NewPoller(clients map[string]DriveClient, cfg config.DriveConfig, gw gateway.GatewayClient, limiter *ratelimit.Limiter, stateDir string) *Poller {
+	return &Poller{
+		clients:  clients,
+		accounts: cfg.Accounts,
+		cfg:      cfg,
+		interval: cfg.ResolvedPollInterval(),
+		gateway:  gw,
+		limiter:  limiter,
+		stateDir: stateDir,
+	}
+}
+
+// SetTimezone sets the IANA zone of action schedules and the times in job
+// messages; empty means UTC.
+func (p *Poller) SetTimezone(tz string) {
+	p.timezone = tz
+}
+
+// Start polls in a goroutine, right away and then every interval, until ctx
+// is cancelled.
+func (p *Poller) Start(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(p.interval)
+		defer ticker.Stop()
+		for {
+			if err := p.Poll(ctx); err != nil {
+				log.Printf("Drive: poll: %v", err)
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// Poll reads each account's new changes. An account whose feed fails to
+// list is logged and keeps its position, so its changes are read on the
+// next poll; the rate limit keeps a file read twice from creating a second
+// job.
+func (p *Poller) Poll(ctx context.Context) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.tokens == nil {
+		tokens, err := loadState(p.stateDir)
+		if err != nil {
+			return err
+		}
+		p.tokens = tokens
+	}
+	changed := false
+	for _, account := range p.accounts {
+		client, ok := p.clients[account]
+		if !ok || p.removed[account] {
+			continue
+		}
+		token, err := p.pollAccount(ctx, account, client, p.tokens[account])
+		if err != nil {
+			log.Printf("Drive: %s: %v", account, err)
+			continue
+		}
+		if token != p.tokens[account] {
+			p.tokens[account] = token
+			changed = true
+		}
+	}
+	if !changed {
+		return nil
+	}
+	return saveState(p.stateDir, p.tokens)
+}
+
+// RemoveAccount stops polling account and drops its feed position, waiting
+// for a poll in progress to finish. It reports whether there was one.
+func (p *Poller) RemoveAccount(account string) (bool, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.removed == nil {
+		p.removed = map[string]bool{}
+	}
+	p.removed[account] = true
+	delete(p.tokens, account)
+	return RemoveState(p.stateDir, account)
+}
+
+// RemoveState drops account's feed position from the state file in
+// stateDir. It reports whether there was one.
+func RemoveState(stateDir, account string) (bool, error) {
+	tokens, err := loadState(stateDir)
+	if err != nil {
+		return false, err
+	}
+	if _, ok := tokens[account]; !ok {
+		return false, nil
+	}
+	delete(tokens, account)
+	return true, saveState(stateDir, tokens)
+}
+
+// pollAccount handles the changes since token, all pages, and returns the
+// position to resume from.
+func (p *Poller) pollAccount(ctx context.Context, account string, client DriveClient, token string) (string, error) {
+	if token == "" {
+		start, err := client.StartPageToken(ctx)
+		if err != nil {
+			return "", fmt.Errorf("start page token: %w", err)
+		}
+		log.Printf("Drive: %s: watching changes from now", account)
+		return start, nil
+	}
+	for {
+		changes, next, newStart, err := client.ListChanges(ctx, token)
+		if err != nil {
+			return "", fmt.Errorf("list changes: %w", err)
+		}
+		for _, ch := range changes {
+			if ch.Removed || ch.File == nil || ch.File.Trashed || ch.File.MimeType == folderMimeType {
+				continue
+			}
+			p.handle(account, *ch.File)
+		}
+		if next == "" && newStart == "" {
+			// Not expected from Google; rereading the last page is safe
+			return token, nil
+		}
+		if next == "" {
+			return newStart, nil
+		}
+		token = next
+	}
+}
+
+// handle creates the jobs of the rules f matches.
+func (p *Poller) handle(account string, f File) {
+	for i, n := range p.findRules(account, f) {
+		rule := p.cfg.Rules[n]
+		ref := fmt.Sprintf("drive.rules[%d]", n)
+		key := fmt.Sprintf("drive:%s:%s:%s", account, f.ID, ref)
+		if p.limiter != nil && !p.limiter.AllowWithin(key, config.RateWindow(rule.RateLimit, p.cfg.RateLimit)) {
+			log.Printf("Drive: rate limited %q (%s)", f.Name, ref)
+			continue
+		}
+		name := "drive: " + f.Name
+		if i > 0 {
+			name = fmt.Sprintf("%s [%s]", name, ref)
+		}
+		if err := p.createJob(account, rule, name, p.templateData(account, f, rule)); err != nil {
+			log.Printf("Drive: job for %q (%s): %v", f.Name, ref, err)
+			continue
+		}
+		log.Printf("Drive: %s: job for %q (%s)", account, f.Name, ref)
+	}
+}
+
+// findRules returns the indexes of the rules f matches: the first, and
+// more while the matched rule sets continue.
+func (p *Poller) findRules(account string, f File) []int {
+	var out []int
+	for i, r := range p.cfg.Rules {
+		if len(r.Accounts) > 0 && !slices.Contains(r.Accounts, account) {
+			continue
+		}
+		if len(r.Folders) > 0 && !slices.ContainsFunc(f.Parents, func(id string) bool { return slices.Contains(r.Folders, id) }) {
+			continue
+		}
+		if len(r.Owners) > 0 && !slices.ContainsFunc(f.Owners, func(o string) bool { return config.MatchAddress(r.Owners, o) }) {
+			continue
+		}
+		if len(r.MimeTypes) > 0 && !config.MatchPattern(r.MimeTypes, f.MimeType) {
+			continue
+		}
+		out = append(out, i)
+		if !r.Continue {
+			break
+		}
+	}
+	return out
+}
+
+func (p *Poller) templateData(account string, f File, rule config.DriveRule) map[string]any {
+	loc, err := time.LoadLocation(p.timezone)
+	if err != nil {
+		loc = time.UTC
+	}
+	layout := "2006-01-02 15:04 MST"
+	folder := ""
+	if len(f.Parents) > 0 {
+		folder = f.Parents[0]
+	}
+	return map[string]any{
+		"Account":    account,
+		"FileID":     f.ID,
+		"Name":       f.Name,
+		"MimeType":   f.MimeType,
+		"Owners":     strings.Join(f.Owners, ", "),
+		"ModifiedBy": f.ModifiedBy,
+		"Modified":   f.Modified.In(loc).Format(layout),
+		"Created":    f.Created.In(loc).Format(layout),
+		"Size":       f.Size,
+		"URL":        f.URL,
+		"Folder":     folder,
+		"Rule":       rule.Name,
+	}
+}
+
+func (p *Poller) createJob(account string, rule config.DriveRule, name string, data map[string]any) error {
+	job := gateway.ActionJob("drive", rule.Name, name, rule.Action, config.DefaultDriveMessageTemplate(), data, p.timezone)
+	job.Account = account
+	return gateway.CreateJob(p.gateway, job)
+}
+
+func loadState(stateDir string) (map[string]string, error) {
+	tokens := map[string]string{}
+	data, err := os.ReadFile(filepath.Join(stateDir, stateFileName))
+	if os.IsNotExist(err) {
+		return tokens, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if data, _, err = stateSchema.Apply(data); err != nil {
+		return nil, err
+	}
+	var st driveState
+	if err := json.Unmarshal(data, &st); err != nil {
+		return nil, fmt.Errorf("%s: %w", stateFileName, err)
+	}
+	for k, v := range st.Accounts {
+		tokens[k] = v
+	}
+	return tokens, nil
+}
+
+// saveState replaces the state file.
+func saveState(stateDir string, tokens map[string]string) error {
+	return atomicfile.WriteJSON(filepath.Join(stateDir, stateFileName), driveState{Version: stateSchema.Current(), Accounts: tokens})
+}
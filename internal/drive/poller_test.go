@@ -0,0 +1,165 @@
+package drive
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/katalabut/openclaw-relay/internal/config"
+	"github.com/katalabut/openclaw-relay/internal/gateway"
+	"github.com/katalabut/openclaw-relay/internal/ratelimit"
+)
+
+type recordingGateway struct {
+	jobs []gateway.JobSpec
+	err  error
+}
+
+func (g *recordingGateway) CreateOneShotJob(name, message string, timeoutSeconds, delaySeconds int) error {
+	return g.CreateOneShotJobForAgent(name, message, "", timeoutSeconds, delaySeconds)
+}
+
+func (g *recordingGateway) CreateOneShotJobForAgent(name, message, agentID string, timeoutSeconds, delaySeconds int) error {
+	return g.CreateJob(gateway.JobSpec{Name: name, Message: message, AgentID: agentID, TimeoutSeconds: timeoutSeconds, DelaySeconds: delaySeconds})
+}
+
+func (g *recordingGateway) CreateJob(spec gateway.JobSpec) error {
+	if g.err != nil {
+		return g.err
+	}
+	g.jobs = append(g.jobs, spec)
+	return nil
+}
+
+func (g *recordingGateway) names() string {
+	var names []string
+	for _, j := range g.jobs {
+		names = append(names, j.Name)
+	}
+	return strings.Join(names, "; ")
+}
+
+func file(id, name, mimeType, folder, owner string) *File {
+	return &File{ID: id, Name: name, MimeType: mimeType, Parents: []string{folder}, Owners: []string{owner}, Modified: time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC)}
+}
+
+func newTestPoller(t *testing.T, fc *fakeClient, gw *recordingGateway, rules ...config.DriveRule) *Poller {
+	t.Helper()
+	cfg := config.DriveConfig{Accounts: []string{"me@example.com"}, Rules: rules}
+	return NewPoller(map[string]DriveClient{"me@example.com": fc}, cfg, gw, ratelimit.New(t.Context(), time.Hour), t.TempDir())
+}
+
+func TestPoller_Changes(t *testing.T) {
+	fc := &fakeClient{
+		start: "1",
+		changes: map[string][]Change{
+			"1": {
+				{FileID: "f1", File: file("f1", "Contract", "application/pdf", "legal", "dana@partner.example")},
+				{FileID: "f2", File: file("f2", "Notes", "application/vnd.google-apps.document", "team", "me@example.com")},
+			},
+			"2": {
+				{FileID: "f3", Removed: true},
+				{FileID: "f4", File: file("f4", "Legal", folderMimeType, "legal", "me@example.com")},
+				{FileID: "f5", File: &File{ID: "f5", Name: "Old", MimeType: "application/pdf", Parents: []string{"legal"}, Trashed: true}},
+			},
+		},
+		pages: map[string]string{"1": "2", "2": "3"},
+	}
+	gw := &recordingGateway{}
+	p := newTestPoller(t, fc, gw,
+		config.DriveRule{Name: "legal", Folders: []string{"legal"}, Continue: true, Action: config.RuleAction{AgentID: "lawyer", Tags: map[string]string{"kind": "contract"}}},
+		config.DriveRule{Name: "partners", Owners: []string{"*@partner.example"}},
+		config.DriveRule{Name: "docs", MimeTypes: []string{"application/vnd.google-apps.*"}},
+	)
+	p.SetTimezone("Europe/Berlin")
+
+	// The first poll only records where the feed ends
+	if err := p.Poll(context.Background()); err != nil || len(gw.jobs) != 0 || p.tokens["me@example.com"] != "1" {
+		t.Fatalf("first poll: %v, jobs %s, tokens %v", err, gw.names(), p.tokens)
+	}
+
+	if err := p.Poll(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if got := gw.names(); got != "drive: Contract; drive: Contract [drive.rules[1]]; drive: Notes" {
+		t.Fatalf("jobs = %s", got)
+	}
+	j := gw.jobs[0]
+	if j.AgentID != "lawyer" || j.Source != "drive" || j.Rule != "legal" || j.Account != "me@example.com" || j.Tags["kind"] != "contract" || j.DelaySeconds != 2 || j.TimeoutSeconds != 120 {
+		t.Errorf("job = %+v", j)
+	}
+	if !strings.Contains(j.Message, "File: Contract (f1)") || !strings.Contains(j.Message, "Modified: 2026-03-02 10:00 CET") || !strings.Contains(j.Message, "/api/drive/file/f1") {
+		t.Errorf("message = %s", j.Message)
+	}
+	if p.tokens["me@example.com"] != "3" {
+		t.Errorf("token = %q", p.tokens["me@example.com"])
+	}
+
+	// Resumed from the state file after a restart
+	p2 := newTestPoller(t, fc, gw)
+	p2.stateDir = p.stateDir
+	if err := p2.Poll(context.Background()); err != nil || p2.tokens["me@example.com"] != "3" {
+		t.Errorf("restart: %v, tokens %v", err, p2.tokens)
+	}
+	data, _ := os.ReadFile(filepath.Join(p.stateDir, stateFileName))
+	if !strings.Contains(string(data), `"version":0`) {
+		t.Errorf("state = %s", data)
+	}
+}
+
+func TestPoller_RateLimitAndErrors(t *testing.T) {
+	fc := &fakeClient{
+		changes: map[string][]Change{"1": {{FileID: "f1", File: file("f1", "Contract", "application/pdf", "legal", "dana@example.com")}}},
+		pages:   map[string]string{"1": "2"},
+	}
+	gw := &recordingGateway{}
+	p := newTestPoller(t, fc, gw, config.DriveRule{Name: "all"})
+	p.tokens = map[string]string{"me@example.com": "1"}
+
+	// The same change read again, as after a failed save, within the rate
+	// limit creates no second job
+	p.Poll(context.Background())
+	p.tokens["me@example.com"] = "1"
+	p.Poll(context.Background())
+	if len(gw.jobs) != 1 {
+		t.Errorf("jobs = %s", gw.names())
+	}
+
+	// A failed listing keeps the position
+	fc.err = errors.New("quota exceeded")
+	if err := p.Poll(context.Background()); err != nil || p.tokens["me@example.com"] != "2" {
+		t.Errorf("error poll: %v, tokens %v", err, p.tokens)
+	}
+}
+
+func TestPoller_RemoveAccount(t *testing.T) {
+	fc := &fakeClient{start: "1"}
+	p := newTestPoller(t, fc, &recordingGateway{})
+	saveState(p.stateDir, map[string]string{"me@example.com": "5", "other@example.com": "7"})
+
+	if removed, err := p.RemoveAccount("me@example.com"); err != nil || !removed {
+		t.Fatalf("RemoveAccount = %v, %v", removed, err)
+	}
+	if err := p.Poll(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	tokens, _ := loadState(p.stateDir)
+	if _, ok := tokens["me@example.com"]; ok || tokens["other@example.com"] != "7" {
+		t.Errorf("expected the removed account not polled again, state %v", tokens)
+	}
+	if removed, err := RemoveState(p.stateDir, "me@example.com"); err != nil || removed {
+		t.Errorf("second removal = %v, %v", removed, err)
+	}
+}
+
+func TestLoadState_Corrupt(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, stateFileName), []byte("{"), 0600)
+	if _, err := loadState(dir); err == nil {
+		t.Error("expected an error for a corrupt state file")
+	}
+}
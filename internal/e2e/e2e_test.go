@@ -0,0 +1,160 @@
+package e2e
+
+import (
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTrelloCardMoved(t *testing.T) {
+	h := New(t)
+	h.Trello.Handle("GET", "/1/boards/board-e2e", "trello/board.json")
+	h.Trello.Handle("GET", "/1/tokens/trello-token/webhooks", "trello/webhooks.json")
+	h.Trello.Handle("POST", "/1/webhooks", "trello/webhook.json")
+	h.Start(`
+gateway:
+  url: "[[.Gateway.URL]]"
+  token: gw-token
+trello:
+  secret: trello-secret
+  api_key: trello-key
+  token: trello-token
+  api_url: "[[.Trello.URL]]"
+  boards: [board-e2e]
+  callback_url: https://relay.example.com/webhook/trello
+  lists:
+    ready: list-ready
+  rules:
+    - event: card_moved
+      condition: "list == 'ready'"
+      action:
+        agent_id: dev
+        message_template: "Card {{.CardName}} is ready"
+`)
+
+	if code := h.Deliver("trello", "", "trello/card_moved.json"); code != 200 {
+		t.Fatalf("delivery status %d", code)
+	}
+	jobs := h.WaitJobs(1, 5*time.Second)
+	if jobs[0].AgentID != "dev" || jobs[0].Message != "Card Fix login redirect is ready" {
+		t.Errorf("unexpected job %+v", jobs[0])
+	}
+	if !h.Trello.Received("GET /1/boards/board-e2e") {
+		t.Errorf("board metadata not fetched: %v", h.Trello.Requests())
+	}
+	waitFor(t, func() bool { return h.Trello.Received("POST /1/webhooks") }, "webhook registration")
+}
+
+func TestTrelloBadSignature(t *testing.T) {
+	h := New(t)
+	h.Start(`
+gateway:
+  url: "[[.Gateway.URL]]"
+  token: gw-token
+trello:
+  secret: trello-secret
+  lists:
+    ready: list-ready
+  rules:
+    - event: card_moved
+`)
+	h.cfg.Trello.Secret = "wrong-secret"
+	if code := h.Deliver("trello", "", "trello/card_moved.json"); code != 403 {
+		t.Errorf("status %d, want 403 for a bad signature", code)
+	}
+	if jobs := h.Gateway.Jobs(); len(jobs) != 0 {
+		t.Errorf("rejected delivery created jobs: %+v", jobs)
+	}
+}
+
+func TestGitHubWorkflowRunArtifacts(t *testing.T) {
+	h := New(t)
+	h.GitHub.Handle("POST", "/app/installations/77/access_tokens", "github/access_token.json")
+	h.GitHub.Handle("GET", "/repos/acme/api/actions/runs/42/artifacts", "github/artifacts.json")
+	h.Start(`
+gateway:
+  url: "[[.Gateway.URL]]"
+  token: gw-token
+github:
+  secret: github-secret
+  app:
+    app_id: 1
+    installation_id: 77
+    private_key_file: "[[.GitHubAppKey]]"
+    api_url: "[[.GitHub.URL]]"
+  rules:
+    - event: workflow_run
+      actions: [completed]
+      repos: ["acme/*"]
+      conclusions: [failure]
+      action:
+        agent_id: ci
+        message_template: "{{.Repository}} PR#{{.PRNumber}}{{range .Artifacts}} {{.Name}}{{end}}"
+`)
+
+	if code := h.Deliver("github", "workflow_run", "github/workflow_run_failure.json"); code != 200 {
+		t.Fatalf("delivery status %d", code)
+	}
+	jobs := h.WaitJobs(1, 5*time.Second)
+	if jobs[0].AgentID != "ci" || jobs[0].Message != "acme/api PR#7 test-report" {
+		t.Errorf("unexpected job %+v", jobs[0])
+	}
+}
+
+func TestGmailPollerRule(t *testing.T) {
+	h := New(t)
+	h.Start(`
+gateway:
+  url: "[[.Gateway.URL]]"
+  token: gw-token
+google:
+  client_id: e2e-client
+  client_secret: e2e-secret
+  redirect_url: http://localhost/auth/google/callback
+gmail:
+  enabled: true
+  api_url: "[[.Gmail.URL]]/"
+  accounts:
+    - email: e2e@example.com
+      poll_interval: 50ms
+      rules:
+        - name: invoices
+          match:
+            from: ["@vendor.example"]
+            labels: [INBOX]
+          action:
+            agent_id: finance
+            message_template: "{{.Subject}} from {{.From}}"
+`)
+	// the poller starts from the current historyId, so wait for it before mail arrives
+	waitFor(t, func() bool { return fileExists("data/gmail-state.json") }, "poller initialization")
+	h.Gmail.Deliver("gmail/newsletter.json")
+	h.Gmail.Deliver("gmail/invoice.json")
+
+	jobs := h.WaitJobs(1, 5*time.Second)
+	time.Sleep(200 * time.Millisecond) // a few more polls must not repeat the job
+	jobs = h.Gateway.Jobs()
+	if len(jobs) != 1 {
+		t.Fatalf("expected one job, got %+v", jobs)
+	}
+	if jobs[0].AgentID != "finance" || !strings.HasPrefix(jobs[0].Message, "Invoice 1042 from Billing") {
+		t.Errorf("unexpected job %+v", jobs[0])
+	}
+}
+
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+func waitFor(t *testing.T, cond func() bool, what string) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
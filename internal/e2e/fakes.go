@@ -0,0 +1,221 @@
+package e2e
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// Job is a cron job the relay created on the fake gateway.
+type Job struct {
+	Name    string            `json:"name"`
+	AgentID string            `json:"agentId"`
+	Message string            `json:"message"`
+	Tags    map[string]string `json:"tags"`
+}
+
+// Gateway is a fake OpenClaw gateway that records the jobs it receives on
+// /tools/invoke.
+type Gateway struct {
+	*httptest.Server
+
+	mu   sync.Mutex
+	jobs []Job
+}
+
+func newGateway() *Gateway {
+	g := &Gateway{}
+	g.Server = httptest.NewServer(http.HandlerFunc(g.serve))
+	return g
+}
+
+func (g *Gateway) serve(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/tools/invoke" || r.Method != http.MethodPost {
+		http.NotFound(w, r)
+		return
+	}
+	var req struct {
+		Args json.RawMessage `json:"args"`
+	}
+	var args struct {
+		Job struct {
+			Name    string            `json:"name"`
+			AgentID string            `json:"agentId"`
+			Tags    map[string]string `json:"tags"`
+			Payload struct {
+				Message string `json:"message"`
+			} `json:"payload"`
+		} `json:"job"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || json.Unmarshal(req.Args, &args) != nil {
+		http.Error(w, "bad request", http.StatusBadRequest)
+		return
+	}
+	g.mu.Lock()
+	g.jobs = append(g.jobs, Job{Name: args.Job.Name, AgentID: args.Job.AgentID, Message: args.Job.Payload.Message, Tags: args.Job.Tags})
+	g.mu.Unlock()
+	w.Header().Set("Content-Type", "application/json")
+	fmt.Fprint(w, `{"ok":true}`)
+}
+
+// Jobs returns the jobs received so far.
+func (g *Gateway) Jobs() []Job {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return append([]Job(nil), g.jobs...)
+}
+
+// Provider is a fake REST API that answers with canned fixtures and records
+// every request as "METHOD /path". Requests without a route get a 404.
+type Provider struct {
+	*httptest.Server
+
+	t        testing.TB
+	dir      string
+	mu       sync.Mutex
+	routes   map[string][]byte
+	requests []string
+}
+
+func newProvider(t testing.TB, dir string) *Provider {
+	p := &Provider{t: t, dir: dir, routes: make(map[string][]byte)}
+	p.Server = httptest.NewServer(http.HandlerFunc(p.serve))
+	return p
+}
+
+// Handle answers method and path (without query) with a fixture file.
+func (p *Provider) Handle(method, path, fixture string) {
+	body := readFixture(p.t, p.dir, fixture)
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.routes[method+" "+path] = body
+}
+
+func (p *Provider) serve(w http.ResponseWriter, r *http.Request) {
+	key := r.Method + " " + r.URL.Path
+	io.Copy(io.Discard, r.Body)
+	p.mu.Lock()
+	p.requests = append(p.requests, key)
+	body, ok := p.routes[key]
+	p.mu.Unlock()
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(body)
+}
+
+// Requests returns the requests received so far.
+func (p *Provider) Requests() []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]string(nil), p.requests...)
+}
+
+// Received reports whether a request matching "METHOD /path" was made.
+func (p *Provider) Received(key string) bool {
+	for _, r := range p.Requests() {
+		if r == key {
+			return true
+		}
+	}
+	return false
+}
+
+// Gmail is a fake Gmail API mailbox. Messages added with Deliver show up in
+// history after the historyId the relay started from, so pollers pick them up
+// like new mail.
+type Gmail struct {
+	*httptest.Server
+
+	t         testing.TB
+	dir       string
+	mu        sync.Mutex
+	historyID uint64
+	added     []gmailRecord
+	messages  map[string][]byte
+}
+
+type gmailRecord struct {
+	historyID uint64
+	id        string
+	threadID  string
+	labels    []string
+}
+
+func newGmail(t testing.TB, dir string) *Gmail {
+	g := &Gmail{t: t, dir: dir, historyID: 1000, messages: make(map[string][]byte)}
+	g.Server = httptest.NewServer(http.HandlerFunc(g.serve))
+	return g
+}
+
+// Deliver adds the message in a fixture file, a users.messages resource, to
+// the mailbox.
+func (g *Gmail) Deliver(fixture string) {
+	body := readFixture(g.t, g.dir, fixture)
+	var msg struct {
+		ID       string   `json:"id"`
+		ThreadID string   `json:"threadId"`
+		LabelIDs []string `json:"labelIds"`
+	}
+	if err := json.Unmarshal(body, &msg); err != nil || msg.ID == "" {
+		g.t.Fatalf("gmail fixture %s: not a message with an id", fixture)
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.historyID++
+	g.added = append(g.added, gmailRecord{historyID: g.historyID, id: msg.ID, threadID: msg.ThreadID, labels: msg.LabelIDs})
+	g.messages[msg.ID] = body
+}
+
+func (g *Gmail) serve(w http.ResponseWriter, r *http.Request) {
+	path := strings.TrimPrefix(r.URL.Path, "/gmail/v1/users/me")
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	w.Header().Set("Content-Type", "application/json")
+	switch {
+	case path == "/profile":
+		json.NewEncoder(w).Encode(map[string]any{"emailAddress": "e2e@example.com", "historyId": strconv.FormatUint(g.historyID, 10)})
+	case path == "/history":
+		start, _ := strconv.ParseUint(r.URL.Query().Get("startHistoryId"), 10, 64)
+		var history []map[string]any
+		for _, rec := range g.added {
+			if rec.historyID <= start {
+				continue
+			}
+			msg := map[string]any{"id": rec.id, "threadId": rec.threadID, "labelIds": rec.labels}
+			history = append(history, map[string]any{
+				"id":            strconv.FormatUint(rec.historyID, 10),
+				"messagesAdded": []map[string]any{{"message": msg}},
+			})
+		}
+		json.NewEncoder(w).Encode(map[string]any{"history": history, "historyId": strconv.FormatUint(g.historyID, 10)})
+	case strings.HasPrefix(path, "/messages/"):
+		body, ok := g.messages[strings.TrimPrefix(path, "/messages/")]
+		if !ok {
+			http.Error(w, `{"error":{"code":404,"message":"Not Found"}}`, http.StatusNotFound)
+			return
+		}
+		w.Write(body)
+	default:
+		http.Error(w, `{"error":{"code":404,"message":"Not Found"}}`, http.StatusNotFound)
+	}
+}
+
+func readFixture(t testing.TB, dir, name string) []byte {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join(dir, name))
+	if err != nil {
+		t.Fatalf("fixture: %v", err)
+	}
+	return data
+}
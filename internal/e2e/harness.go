@@ -0,0 +1,243 @@
+// Package e2e runs the whole relay, as server.Run does, against fake Trello,
+// GitHub, Gmail and gateway servers, so changes that cut across packages can
+// be checked from webhook delivery or new mail through to the created job.
+package e2e
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"text/template"
+	"time"
+
+	"github.com/katalabut/openclaw-relay/internal/config"
+	"github.com/katalabut/openclaw-relay/internal/server"
+	"github.com/katalabut/openclaw-relay/internal/tokens"
+	"golang.org/x/oauth2"
+)
+
+// EncryptionKey is the RELAY_ENCRYPTION_KEY the harness runs the relay with.
+const EncryptionKey = "000102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1f"
+
+// Harness is one relay under test and the fakes it talks to.
+type Harness struct {
+	Gateway *Gateway
+	Trello  *Provider
+	GitHub  *Provider
+	Gmail   *Gmail
+
+	// URL is the relay's base URL once Start returns.
+	URL string
+	// Dir is the relay's working directory, holding its data/ state.
+	Dir string
+
+	t        testing.TB
+	cfg      *config.Config
+	fixtures string
+	keyDir   string
+}
+
+// New starts the fake servers, serving fixtures from the test's testdata
+// directory, and changes into a fresh working directory for the relay's
+// state. Everything is torn down when the test ends.
+func New(t testing.TB) *Harness {
+	t.Helper()
+	fixtures, err := filepath.Abs("testdata")
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := &Harness{
+		Gateway:  newGateway(),
+		Trello:   newProvider(t, fixtures),
+		GitHub:   newProvider(t, fixtures),
+		Gmail:    newGmail(t, fixtures),
+		Dir:      t.TempDir(),
+		t:        t,
+		fixtures: fixtures,
+		keyDir:   t.TempDir(),
+	}
+	t.Cleanup(h.Gateway.Close)
+	t.Cleanup(h.Trello.Close)
+	t.Cleanup(h.GitHub.Close)
+	t.Cleanup(h.Gmail.Close)
+	t.Chdir(h.Dir)
+	return h
+}
+
+// GitHubAppKey writes a fresh RSA key for github.app.private_key_file and
+// returns its path.
+func (h *Harness) GitHubAppKey() string {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		h.t.Fatal(err)
+	}
+	path := filepath.Join(h.keyDir, "github-app.pem")
+	data := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		h.t.Fatal(err)
+	}
+	return path
+}
+
+// Start renders configYAML as a text/template over the harness, with [[ ]]
+// delimiters so relay templates pass through, so it can refer to
+// [[.Gateway.URL]], [[.Trello.URL]], [[.GitHub.URL]], [[.Gmail.URL]] and
+// [[.GitHubAppKey]]. It then runs the relay on a free port
+// until the test ends. Gmail accounts get a valid stored token, so their
+// pollers start right away.
+func (h *Harness) Start(configYAML string) *config.Config {
+	h.t.Helper()
+	tmpl, err := template.New("config").Delims("[[", "]]").Parse(configYAML)
+	if err != nil {
+		h.t.Fatalf("config template: %v", err)
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, h); err != nil {
+		h.t.Fatalf("config template: %v", err)
+	}
+	cfg, err := config.Parse(buf.Bytes())
+	if err != nil {
+		h.t.Fatalf("config: %v", err)
+	}
+	cfg.Server.Port = freePort(h.t)
+	h.URL = fmt.Sprintf("http://127.0.0.1:%d", cfg.Server.Port)
+	// Deliver signs with its own copy, so a test can sign with a wrong secret
+	h.cfg, _ = config.Parse(buf.Bytes())
+
+	h.t.Setenv("RELAY_ENCRYPTION_KEY", EncryptionKey)
+	if cfg.Gmail.Enabled {
+		h.seedTokens(cfg)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- server.RunContext(ctx, cfg) }()
+	h.t.Cleanup(func() {
+		cancel()
+		select {
+		case err := <-done:
+			if err != nil {
+				h.t.Errorf("relay: %v", err)
+			}
+		case <-time.After(15 * time.Second):
+			h.t.Error("relay did not shut down")
+		}
+	})
+
+	deadline := time.Now().Add(10 * time.Second)
+	for {
+		select {
+		case err := <-done:
+			h.t.Fatalf("relay exited: %v", err)
+		default:
+		}
+		if resp, err := http.Get(h.URL + "/health"); err == nil {
+			resp.Body.Close()
+			if resp.StatusCode == http.StatusOK {
+				return cfg
+			}
+		}
+		if time.Now().After(deadline) {
+			h.t.Fatal("relay did not become healthy")
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
+
+func (h *Harness) seedTokens(cfg *config.Config) {
+	store, err := tokens.NewStore("data/tokens.json.enc", EncryptionKey)
+	if err != nil {
+		h.t.Fatal(err)
+	}
+	for _, acc := range cfg.Gmail.ResolvedAccounts() {
+		tok := &oauth2.Token{AccessToken: "e2e-" + acc.Email, TokenType: "Bearer", RefreshToken: "e2e", Expiry: time.Now().Add(time.Hour)}
+		if err := store.SaveGoogle(tok, acc.Email); err != nil {
+			h.t.Fatal(err)
+		}
+	}
+}
+
+// Deliver posts the payload in fixture to /webhook/<source>, signed the way
+// the provider signs it when the source has a secret configured, and returns
+// the response status. event is GitHub's X-GitHub-Event; other sources
+// ignore it.
+func (h *Harness) Deliver(source, event, fixture string) int {
+	h.t.Helper()
+	body := readFixture(h.t, h.fixtures, fixture)
+	req, err := http.NewRequest(http.MethodPost, h.URL+"/webhook/"+source, bytes.NewReader(body))
+	if err != nil {
+		h.t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	switch source {
+	case "trello":
+		if secret := h.cfg.Trello.Secret; secret != "" {
+			// signed against the callback URL the relay derives from Host and path
+			mac := hmac.New(sha1.New, []byte(secret))
+			mac.Write(body)
+			mac.Write([]byte("https://" + req.Host + "/webhook/trello"))
+			req.Header.Set("X-Trello-Webhook", base64.StdEncoding.EncodeToString(mac.Sum(nil)))
+		}
+	case "github":
+		req.Header.Set("X-GitHub-Event", event)
+		req.Header.Set("X-GitHub-Delivery", fmt.Sprintf("e2e-%d", time.Now().UnixNano()))
+		if secret := h.cfg.GitHub.Secret; secret != "" {
+			mac := hmac.New(sha256.New, []byte(secret))
+			mac.Write(body)
+			req.Header.Set("X-Hub-Signature-256", "sha256="+hex.EncodeToString(mac.Sum(nil)))
+		}
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		h.t.Fatalf("deliver %s: %v", fixture, err)
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+	return resp.StatusCode
+}
+
+// WaitJobs waits until the gateway has received at least n jobs and returns
+// them, failing the test after timeout.
+func (h *Harness) WaitJobs(n int, timeout time.Duration) []Job {
+	h.t.Helper()
+	deadline := time.Now().Add(timeout)
+	for {
+		jobs := h.Gateway.Jobs()
+		if len(jobs) >= n {
+			return jobs
+		}
+		if time.Now().After(deadline) {
+			names := make([]string, len(jobs))
+			for i, j := range jobs {
+				names[i] = j.Name
+			}
+			h.t.Fatalf("got %d jobs (%s), want %d", len(jobs), strings.Join(names, ", "), n)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
+
+func freePort(t testing.TB) int {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	return ln.Addr().(*net.TCPAddr).Port
+}
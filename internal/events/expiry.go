@@ -0,0 +1,96 @@
+package events
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"time"
+)
+
+// StatusExpired marks a delivery dropped because it was older than server.event_ttl.
+const StatusExpired = "expired"
+
+type forceKey struct{}
+
+// withForce marks a replay request that should bypass the TTL.
+func withForce(ctx context.Context) context.Context {
+	return context.WithValue(ctx, forceKey{}, true)
+}
+
+func isForced(ctx context.Context) bool {
+	forced, _ := ctx.Value(forceKey{}).(bool)
+	return forced
+}
+
+// EventTimeFunc extracts when the source produced a delivery, if the payload says.
+type EventTimeFunc func(source string, body []byte) (time.Time, bool)
+
+// Expiry drops deliveries whose source timestamp is older than ttl, e.g. a CI
+// failure redelivered hours after an outage. Dropped deliveries are kept in the
+// store as "expired" so they remain visible and can be force-replayed.
+type Expiry struct {
+	store     *Store
+	ttl       time.Duration
+	eventTime EventTimeFunc
+	now       func() time.Time
+}
+
+// NewExpiry returns a guard; a zero ttl disables it.
+func NewExpiry(store *Store, ttl time.Duration, eventTime EventTimeFunc) *Expiry {
+	return &Expiry{store: store, ttl: ttl, eventTime: eventTime, now: time.Now}
+}
+
+// Wrap returns next guarded by the TTL for source.
+func (e *Expiry) Wrap(source string, next http.Handler) http.Handler {
+	if e.ttl <= 0 || e.eventTime == nil {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if isForced(r.Context()) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+
+		at, ok := e.eventTime(source, body)
+		if !ok {
+			next.ServeHTTP(w, r)
+			return
+		}
+		age := e.now().Sub(at)
+		if age <= e.ttl {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		reason := fmt.Sprintf("event age %s exceeds event_ttl %s", age.Round(time.Second), e.ttl)
+		log.Printf("Dropping expired %s delivery: %s", source, reason)
+		if e.store != nil {
+			if ev, err := e.store.Add(Event{
+				Source: source,
+				Status: StatusExpired,
+				Error:  reason,
+				Method: r.Method,
+				Host:   r.Host,
+				Path:   r.URL.RequestURI(),
+				Header: r.Header.Clone(),
+				Body:   body,
+			}); err != nil {
+				log.Printf("Failed to record expired %s event: %v", source, err)
+			} else {
+				annotateStored(r.Context(), OutcomeExpired, ev.ID)
+			}
+		}
+		// 200 so the source doesn't retry a delivery we will never act on
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"ok":true,"expired":true}`))
+	})
+}
@@ -0,0 +1,98 @@
+package events
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fixedEventTime treats the body as an RFC3339 timestamp; empty means unknown.
+func fixedEventTime(source string, body []byte) (time.Time, bool) {
+	t, err := time.Parse(time.RFC3339, string(body))
+	return t, err == nil
+}
+
+func TestExpiry_DropsOldDeliveries(t *testing.T) {
+	rc, s := newTestRecovery(t)
+	exp := NewExpiry(s, time.Hour, fixedEventTime)
+	calls := 0
+	h := rc.Wrap("github", exp.Wrap("github", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if len(body) == 0 {
+			t.Error("expected body to be readable after the guard")
+		}
+		calls++
+	})))
+
+	post := func(body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest("POST", "/webhook/github", strings.NewReader(body)))
+		return rec
+	}
+
+	post(time.Now().Add(-10 * time.Minute).Format(time.RFC3339)) // fresh
+	post("no timestamp")                                         // unknown age passes
+	rec := post(time.Now().Add(-6 * time.Hour).Format(time.RFC3339))
+
+	if calls != 2 {
+		t.Errorf("expected 2 deliveries handled, got %d", calls)
+	}
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"expired":true`) {
+		t.Errorf("expected 200 expired response, got %d %s", rec.Code, rec.Body.String())
+	}
+	list := s.List(StatusExpired)
+	if len(list) != 1 || list[0].Source != "github" || !strings.Contains(list[0].Error, "exceeds event_ttl 1h0m0s") {
+		t.Fatalf("expected an expired record, got %+v", list)
+	}
+
+	// Expired records are only replayed when forced, and then bypass the guard
+	if status, err := rc.Replay(list[0].ID, false); status != http.StatusGone || err != ErrExpired {
+		t.Errorf("expected 410/ErrExpired, got %d/%v", status, err)
+	}
+	if status, err := rc.Replay(list[0].ID, true); err != nil || status != http.StatusOK {
+		t.Fatalf("forced replay: %d/%v", status, err)
+	}
+	if calls != 3 {
+		t.Errorf("expected forced replay to reach the handler, got %d calls", calls)
+	}
+}
+
+func TestExpiry_DisabledPassesThrough(t *testing.T) {
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	if h := NewExpiry(nil, 0, fixedEventTime).Wrap("trello", next); h == nil {
+		t.Fatal("expected handler")
+	}
+	rec := httptest.NewRecorder()
+	NewExpiry(nil, time.Minute, fixedEventTime).Wrap("trello", next).ServeHTTP(rec,
+		httptest.NewRequest("POST", "/webhook/trello", strings.NewReader("2000-01-01T00:00:00Z")))
+	if !strings.Contains(rec.Body.String(), "expired") {
+		t.Errorf("expected expiry without a store, got %q", rec.Body.String())
+	}
+}
+
+func TestReplay_TTLOnReceivedAt(t *testing.T) {
+	rc, s := newTestRecovery(t)
+	rc.SetTTL(time.Hour)
+	rc.Wrap("trello", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	s.Add(Event{ID: "old", Source: "trello", Status: StatusErrored, Method: "POST", Path: "/webhook/trello", ReceivedAt: time.Now().Add(-2 * time.Hour)})
+	mux := http.NewServeMux()
+	rc.RegisterRoutes(mux)
+
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest("POST", "/api/events/replay/old", nil))
+	if rec.Code != http.StatusGone || !strings.Contains(rec.Body.String(), "force=true") {
+		t.Errorf("expected 410 with force hint, got %d %s", rec.Code, rec.Body.String())
+	}
+	if got, _ := s.Get("old"); got.Status != StatusExpired {
+		t.Errorf("expected event marked expired, got %s", got.Status)
+	}
+
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest("POST", "/api/events/replay/old?force=true", nil))
+	if rec.Code != http.StatusOK {
+		t.Errorf("expected forced replay to succeed, got %d %s", rec.Code, rec.Body.String())
+	}
+}
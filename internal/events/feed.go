@@ -0,0 +1,188 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/katalabut/openclaw-relay/internal/gateway"
+)
+
+const (
+	defaultFeedSize = 200
+	defaultWait     = 30 * time.Second
+	maxWait         = 2 * time.Minute
+)
+
+// Matched is an event that matched a rule and created a gateway job.
+type Matched struct {
+	Seq      uint64          `json:"seq"`
+	Time     time.Time       `json:"time"`
+	Source   string          `json:"source"`
+	Rule     string          `json:"rule,omitempty"`
+	Job      string          `json:"job"` // job name
+	AgentID  string          `json:"agent_id,omitempty"`
+	JobError string          `json:"job_error,omitempty"` // set when the gateway did not take the job
+	Payload  json.RawMessage `json:"payload,omitempty"`   // the webhook delivery, when it is JSON
+}
+
+// Feed keeps the most recent matched events in memory, numbered by a
+// sequence that clients use as a cursor to wait for newer ones.
+type Feed struct {
+	mu     sync.Mutex
+	size   int
+	seq    uint64
+	items  []Matched
+	notify chan struct{} // closed and replaced on every Publish
+}
+
+// NewFeed keeps up to size events (200 when size <= 0).
+func NewFeed(size int) *Feed {
+	if size <= 0 {
+		size = defaultFeedSize
+	}
+	return &Feed{size: size, notify: make(chan struct{})}
+}
+
+// Publish numbers m, stores it and wakes waiting clients.
+func (f *Feed) Publish(m Matched) Matched {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.seq++
+	m.Seq = f.seq
+	if m.Time.IsZero() {
+		m.Time = time.Now().UTC()
+	}
+	f.items = append(f.items, m)
+	if len(f.items) > f.size {
+		f.items = f.items[len(f.items)-f.size:]
+	}
+	close(f.notify)
+	f.notify = make(chan struct{})
+	return m
+}
+
+// Cursor returns the sequence of the latest event.
+func (f *Feed) Cursor() uint64 {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.seq
+}
+
+// since returns the stored events after seq and the channel closed by the
+// next Publish. A seq ahead of the feed comes from before a restart and
+// starts over.
+func (f *Feed) since(seq uint64) ([]Matched, <-chan struct{}) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if seq > f.seq {
+		seq = 0
+	}
+	var out []Matched
+	for _, m := range f.items {
+		if m.Seq > seq {
+			out = append(out, m)
+		}
+	}
+	return out, f.notify
+}
+
+// Wait returns the events after seq, blocking until there is one or ctx ends.
+func (f *Feed) Wait(ctx context.Context, seq uint64) []Matched {
+	for {
+		out, notify := f.since(seq)
+		if len(out) > 0 {
+			return out
+		}
+		select {
+		case <-notify:
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
+
+// HandleWait serves GET /api/events/wait?since=<cursor>&timeout=30s. Without
+// since it waits for events newer than the request. The response carries the
+// cursor to pass as since next time; on timeout, events is empty.
+func (f *Feed) HandleWait(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+		return
+	}
+	q := r.URL.Query()
+	seq := f.Cursor()
+	if s := q.Get("since"); s != "" {
+		n, err := strconv.ParseUint(s, 10, 64)
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "since must be a cursor from a previous response"})
+			return
+		}
+		seq = n
+	}
+	timeout := defaultWait
+	if s := q.Get("timeout"); s != "" {
+		d, err := time.ParseDuration(s)
+		if err != nil || d < 0 {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid timeout"})
+			return
+		}
+		timeout = min(d, maxWait)
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), timeout)
+	defer cancel()
+	out := f.Wait(ctx, seq)
+	cursor := seq
+	if len(out) > 0 {
+		cursor = out[len(out)-1].Seq
+	} else if cursor > f.Cursor() {
+		cursor = f.Cursor()
+	}
+	if out == nil {
+		out = []Matched{}
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"events": out, "cursor": cursor})
+}
+
+// Gateway wraps next so every job created through it is published as a
+// matched event. Jobs without a source are the relay's own notices and are
+// not published.
+func (f *Feed) Gateway(next gateway.GatewayClient) gateway.GatewayClient {
+	return &feedGateway{feed: f, next: next}
+}
+
+type feedGateway struct {
+	feed *Feed
+	next gateway.GatewayClient
+}
+
+func (g *feedGateway) CreateOneShotJob(name, message string, timeoutSeconds, delaySeconds int) error {
+	return g.CreateOneShotJobForAgent(name, message, "", timeoutSeconds, delaySeconds)
+}
+
+func (g *feedGateway) CreateOneShotJobForAgent(name, message, agentID string, timeoutSeconds, delaySeconds int) error {
+	return g.CreateJob(gateway.JobSpec{Name: name, Message: message, AgentID: agentID, TimeoutSeconds: timeoutSeconds, DelaySeconds: delaySeconds})
+}
+
+func (g *feedGateway) CreateJob(spec gateway.JobSpec) error {
+	err := gateway.CreateJob(g.next, spec)
+	if spec.Source == "" {
+		return err
+	}
+	m := Matched{Source: spec.Source, Rule: spec.Rule, Job: spec.Name, AgentID: spec.AgentID}
+	if r := spec.Tags["rule"]; r != "" {
+		m.Rule = r
+	}
+	if err != nil {
+		m.JobError = err.Error()
+	}
+	if json.Valid(spec.Payload) {
+		m.Payload = spec.Payload
+	}
+	g.feed.Publish(m)
+	return err
+}
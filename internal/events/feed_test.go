@@ -0,0 +1,109 @@
+package events
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/katalabut/openclaw-relay/internal/gateway"
+)
+
+type waitResponse struct {
+	Events []Matched `json:"events"`
+	Cursor uint64    `json:"cursor"`
+}
+
+func getWait(t *testing.T, f *Feed, query string) (int, waitResponse) {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	f.HandleWait(rec, httptest.NewRequest("GET", "/api/events/wait"+query, nil))
+	var resp waitResponse
+	if rec.Code == http.StatusOK {
+		if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return rec.Code, resp
+}
+
+func TestFeed_HandleWait(t *testing.T) {
+	f := NewFeed(2)
+	f.Publish(Matched{Source: "github", Job: "a"})
+	f.Publish(Matched{Source: "trello", Job: "b"})
+	f.Publish(Matched{Source: "slack", Job: "c"})
+
+	// Buffered events after the cursor return at once; the oldest was dropped
+	code, resp := getWait(t, f, "?since=0")
+	if code != http.StatusOK || len(resp.Events) != 2 || resp.Events[0].Job != "b" || resp.Cursor != 3 {
+		t.Fatalf("unexpected response %d %+v", code, resp)
+	}
+
+	// Without since, only events after the request count
+	done := make(chan waitResponse)
+	go func() {
+		_, resp := getWait(t, f, "?timeout=5s")
+		done <- resp
+	}()
+	time.Sleep(20 * time.Millisecond)
+	f.Publish(Matched{Source: "jira", Job: "d"})
+	select {
+	case resp := <-done:
+		if len(resp.Events) != 1 || resp.Events[0].Job != "d" || resp.Cursor != 4 {
+			t.Errorf("unexpected woken response %+v", resp)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("wait did not return after publish")
+	}
+
+	// Timeout returns no events and the same cursor
+	code, resp = getWait(t, f, "?since=4&timeout=10ms")
+	if code != http.StatusOK || len(resp.Events) != 0 || resp.Cursor != 4 {
+		t.Errorf("unexpected timeout response %d %+v", code, resp)
+	}
+
+	// A cursor from before a restart starts over
+	_, resp = getWait(t, f, "?since=99&timeout=10ms")
+	if len(resp.Events) != 2 || resp.Cursor != 4 {
+		t.Errorf("unexpected stale-cursor response %+v", resp)
+	}
+
+	if code, _ := getWait(t, f, "?since=abc"); code != http.StatusBadRequest {
+		t.Errorf("expected 400 for a bad cursor, got %d", code)
+	}
+	if code, _ := getWait(t, f, "?timeout=soon"); code != http.StatusBadRequest {
+		t.Errorf("expected 400 for a bad timeout, got %d", code)
+	}
+}
+
+type failingGateway struct{ gateway.GatewayClient }
+
+func (failingGateway) CreateJob(spec gateway.JobSpec) error { return errors.New("gateway down") }
+
+func TestFeed_Gateway(t *testing.T) {
+	f := NewFeed(0)
+	sink := gateway.NewSink()
+	gw := f.Gateway(sink)
+
+	gateway.CreateJob(gw, gateway.JobSpec{Name: "github ci", Source: "github", AgentID: "ci", Tags: map[string]string{"rule": "ci"}, Payload: []byte(`{"action":"completed"}`)})
+	gateway.CreateJob(gw, gateway.JobSpec{Name: "budget/github", Message: "cap"})
+	gw.CreateOneShotJob("plain", "m", 60, 0)
+	if sink.Count() != 3 {
+		t.Fatalf("expected every job forwarded, got %d", sink.Count())
+	}
+	got, _ := f.since(0)
+	if len(got) != 1 || got[0].Rule != "ci" || got[0].AgentID != "ci" || string(got[0].Payload) != `{"action":"completed"}` {
+		t.Errorf("unexpected matched events %+v", got)
+	}
+
+	err := gateway.CreateJob(f.Gateway(failingGateway{}), gateway.JobSpec{Name: "x", Source: "trello", Payload: []byte("not json")})
+	if err == nil {
+		t.Fatal("expected the gateway error returned")
+	}
+	got, _ = f.since(1)
+	if len(got) != 1 || got[0].JobError != "gateway down" || got[0].Payload != nil {
+		t.Errorf("unexpected failed-job event %+v", got)
+	}
+}
@@ -0,0 +1,351 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/katalabut/openclaw-relay/internal/atomicfile"
+)
+
+// Outcomes recorded for a delivery in the history.
+const (
+	OutcomeMatched     = "matched"      // a rule matched and a job was created
+	OutcomeJobFailed   = "job_failed"   // a rule matched but the gateway refused the job
+	OutcomeNoRule      = "no_rule"      // no rule matched
+	OutcomeRateLimited = "rate_limited" // the rule's dedup window dropped it
+	OutcomeSampledOut  = "sampled_out"  // the rule's sample rate skipped it
+	OutcomeIgnored     = "ignored"      // filtered before rule matching (ignored user, unhandled event, redelivery)
+	OutcomeHandshake   = "handshake"    // a provider's endpoint verification (HEAD, ping, challenge) was answered
+	OutcomeRejected    = "rejected"     // signature or token check failed
+	OutcomeInvalid     = "invalid"      // malformed request
+	OutcomeErrored     = "errored"      // the handler failed or panicked
+	OutcomePaused      = "paused"
+	OutcomeExpired     = "expired"
+
+	// DefaultHistory is how many deliveries the history keeps by default.
+	DefaultHistory = 1000
+
+	defaultHistoryLimit = 100
+
+	// historyFlush is how long a new delivery may wait before the file is
+	// rewritten, so a burst of webhooks shares one write.
+	historyFlush = time.Second
+)
+
+// Delivery is one webhook request as the relay handled it.
+type Delivery struct {
+	ID         string    `json:"id"`
+	Time       time.Time `json:"time"`
+	Source     string    `json:"source"`
+	EventType  string    `json:"event_type,omitempty"`
+	Rule       string    `json:"rule,omitempty"`
+	Outcome    string    `json:"outcome"`
+	Status     int       `json:"status"`             // HTTP status the relay answered with
+	EventID    string    `json:"event_id,omitempty"` // stored copy for replay (errored, paused, expired)
+	DurationMs int64     `json:"duration_ms"`
+	Timeline   []Step    `json:"timeline,omitempty"`
+}
+
+// History persists the most recent webhook deliveries to a JSON file, oldest
+// dropped first. Unlike Store it keeps no headers or bodies, so it can hold
+// every delivery rather than only the ones worth replaying. The file is
+// written at most once a second; call Flush before exiting.
+type History struct {
+	mu    sync.RWMutex
+	size  int
+	items []Delivery // oldest first
+	now   func() time.Time
+	file  *atomicfile.Debounced
+}
+
+// NewHistory loads the history saved at path, keeping up to size deliveries
+// (DefaultHistory when size <= 0). An empty path keeps it in memory only.
+func NewHistory(path string, size int) (*History, error) {
+	if size <= 0 {
+		size = DefaultHistory
+	}
+	h := &History{size: size, now: time.Now}
+	h.file = atomicfile.NewDebounced(path, historyFlush, h.snapshot)
+	if path == "" {
+		return h, nil
+	}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return h, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read history: %w", err)
+	}
+	if err := json.Unmarshal(data, &h.items); err != nil {
+		return nil, fmt.Errorf("parse history: %w", err)
+	}
+	if len(h.items) > size {
+		h.items = h.items[len(h.items)-size:]
+	}
+	return h, nil
+}
+
+// snapshot returns a copy of the deliveries for the file.
+func (h *History) snapshot() any {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return slices.Clone(h.items)
+}
+
+// Flush writes deliveries recorded since the last write.
+func (h *History) Flush() error {
+	return h.file.Flush()
+}
+
+// Add records d, assigning its ID and time when missing.
+func (h *History) Add(d Delivery) (Delivery, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if d.ID == "" {
+		d.ID = newID()
+	}
+	if d.Time.IsZero() {
+		d.Time = h.now().UTC()
+	}
+	h.items = append(h.items, d)
+	if len(h.items) > h.size {
+		h.items = h.items[len(h.items)-h.size:]
+	}
+	h.file.Mark()
+	return d, nil
+}
+
+// Prune drops the deliveries received before cutoff and returns them. With
+// dryRun it only returns them.
+func (h *History) Prune(cutoff time.Time, dryRun bool) ([]Delivery, error) {
+	h.mu.Lock()
+	n := 0
+	for n < len(h.items) && h.items[n].Time.Before(cutoff) {
+		n++
+	}
+	pruned := slices.Clone(h.items[:n])
+	if dryRun || n == 0 {
+		h.mu.Unlock()
+		return pruned, nil
+	}
+	h.items = slices.Clone(h.items[n:])
+	h.mu.Unlock()
+	h.file.Mark()
+	return pruned, h.file.Flush()
+}
+
+// Get returns the delivery with the given ID, or the one whose stored copy
+// has that event ID.
+func (h *History) Get(id string) (Delivery, bool) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	for i := len(h.items) - 1; i >= 0; i-- {
+		if d := h.items[i]; d.ID == id || d.EventID == id {
+			return d, true
+		}
+	}
+	return Delivery{}, false
+}
+
+// Query returns up to limit deliveries from source (all when empty) received
+// after since, newest first.
+func (h *History) Query(source string, since time.Time, limit int) []Delivery {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	out := []Delivery{}
+	for i := len(h.items) - 1; i >= 0 && (limit <= 0 || len(out) < limit); i-- {
+		d := h.items[i]
+		if !d.Time.After(since) {
+			break
+		}
+		if source == "" || d.Source == source {
+			out = append(out, d)
+		}
+	}
+	return out
+}
+
+// Wrap records every delivery to source that next handles. It goes outside
+// Recovery, which turns panics into 500s. Replays are not recorded again:
+// they update the stored event instead.
+func (h *History) Wrap(source string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if IsReplay(r.Context()) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		start := h.now()
+		note := &annotation{now: h.now, steps: []Step{{Time: start.UTC(), Stage: StageReceived}}}
+		sw := &statusWriter{ResponseWriter: w}
+		defer func() {
+			status := sw.status
+			if status == 0 {
+				status = http.StatusOK
+			}
+			note.responded(status)
+			d := note.delivery()
+			d.Source = source
+			d.Status = status
+			d.DurationMs = h.now().Sub(start).Milliseconds()
+			if d.Outcome == "" {
+				d.Outcome = statusOutcome(status)
+			}
+			if _, err := h.Add(d); err != nil {
+				log.Printf("Failed to record %s delivery in history: %v", source, err)
+			}
+		}()
+		next.ServeHTTP(sw, r.WithContext(context.WithValue(r.Context(), annotationKey{}, note)))
+	})
+}
+
+// statusOutcome is the outcome of a delivery whose handler did not report one.
+func statusOutcome(status int) string {
+	switch {
+	case status >= 500:
+		return OutcomeErrored
+	case status == http.StatusUnauthorized || status == http.StatusForbidden:
+		return OutcomeRejected
+	case status >= 400:
+		return OutcomeInvalid
+	default:
+		return OutcomeIgnored
+	}
+}
+
+// HandleList serves GET /api/events?source=&since=&limit=. since is an
+// RFC 3339 time or a duration back from now (e.g. 1h); limit defaults to 100.
+func (h *History) HandleList(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+		return
+	}
+	q := r.URL.Query()
+	var since time.Time
+	if s := q.Get("since"); s != "" {
+		if d, err := time.ParseDuration(s); err == nil && d >= 0 {
+			since = h.now().Add(-d)
+		} else if t, err := time.Parse(time.RFC3339, s); err == nil {
+			since = t
+		} else {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "since must be an RFC 3339 time or a duration like 1h"})
+			return
+		}
+	}
+	limit := defaultHistoryLimit
+	if s := q.Get("limit"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n <= 0 {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "limit must be a positive number"})
+			return
+		}
+		limit = min(n, h.size)
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"events": h.Query(q.Get("source"), since, limit)})
+}
+
+// annotation collects what a handler reports about the delivery it is handling.
+type annotation struct {
+	mu        sync.Mutex
+	eventType string
+	rule      string
+	outcome   string
+	eventID   string
+	steps     []Step
+	now       func() time.Time
+}
+
+type annotationKey struct{}
+
+func (a *annotation) delivery() Delivery {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return Delivery{EventType: a.eventType, Rule: a.rule, Outcome: a.outcome, EventID: a.eventID, Timeline: a.steps}
+}
+
+// responded ends the timeline with the status the relay answered with.
+func (a *annotation) responded(status int) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.step(Step{Stage: StageResponded, Detail: strconv.Itoa(status)})
+}
+
+// Annotate reports the event type, matched rule and outcome of the delivery
+// ctx belongs to; empty values leave what was reported earlier. A delivery
+// carrying several events (Asana, Bitbucket) keeps its first match: once an
+// outcome is "matched", later events do not overwrite it. A matched rule or
+// an outcome other than "matched" and "job_failed" (which Queued and
+// Delivered record) is added to the timeline. Outside a delivery recorded by
+// History it does nothing.
+func Annotate(ctx context.Context, eventType, rule, outcome string) {
+	a, _ := ctx.Value(annotationKey{}).(*annotation)
+	if a == nil {
+		return
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	switch outcome {
+	case "":
+		if rule != "" && !a.matched(rule) {
+			a.step(Step{Stage: StageMatched, Rule: rule})
+		}
+	case OutcomeMatched, OutcomeJobFailed:
+	default:
+		a.step(Step{Stage: outcome, Rule: rule})
+	}
+	if a.outcome == OutcomeMatched {
+		return
+	}
+	if eventType != "" {
+		a.eventType = eventType
+	}
+	if rule != "" {
+		a.rule = rule
+	}
+	if outcome != "" {
+		a.outcome = outcome
+	}
+}
+
+// annotateStored links the delivery to the copy kept in the event store.
+func annotateStored(ctx context.Context, outcome, eventID string) {
+	a, _ := ctx.Value(annotationKey{}).(*annotation)
+	if a == nil {
+		return
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.outcome = outcome
+	a.eventID = eventID
+	a.step(Step{Stage: outcome, Detail: "stored as " + eventID})
+}
+
+// statusWriter remembers the status code written through it.
+type statusWriter struct {
+	http.ResponseWriter
+	status int
+}
+
+func (s *statusWriter) WriteHeader(code int) {
+	if s.status == 0 {
+		s.status = code
+	}
+	s.ResponseWriter.WriteHeader(code)
+}
+
+func (s *statusWriter) Write(b []byte) (int, error) {
+	if s.status == 0 {
+		s.status = http.StatusOK
+	}
+	return s.ResponseWriter.Write(b)
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer.
+func (s *statusWriter) Unwrap() http.ResponseWriter { return s.ResponseWriter }
@@ -0,0 +1,172 @@
+package events
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHistory_Wrap(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history.json")
+	h, err := NewHistory(path, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rc, s := newTestRecovery(t)
+	pauses := NewPauses(s)
+
+	handler := func(source string, next http.HandlerFunc) http.Handler {
+		return h.Wrap(source, rc.Wrap(source, pauses.Wrap(source, next)))
+	}
+	matched := handler("trello", func(w http.ResponseWriter, r *http.Request) {
+		Annotate(r.Context(), "card_moved", "", "")
+		Annotate(r.Context(), "", "trello.rules[1]", OutcomeMatched)
+		Annotate(r.Context(), "comment_added", "", OutcomeNoRule) // a later event keeps the match
+	})
+	plain := handler("github", func(w http.ResponseWriter, r *http.Request) {})
+	rejected := handler("slack", func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "forbidden", http.StatusForbidden)
+	})
+	panics := handler("jira", func(w http.ResponseWriter, r *http.Request) {
+		io.ReadAll(r.Body)
+		panic("boom")
+	})
+
+	post := func(hh http.Handler) {
+		hh.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("POST", "/webhook", strings.NewReader("{}")))
+	}
+	post(matched)
+	post(plain)
+	post(rejected)
+	post(panics)
+	pauses.Pause("github", 0)
+	post(plain)
+	// Replays update the stored event instead of adding a delivery
+	req := httptest.NewRequest("POST", "/webhook", nil).WithContext(withReplay(t.Context()))
+	plain.ServeHTTP(httptest.NewRecorder(), req)
+
+	got := h.Query("", time.Time{}, 0)
+	want := []struct {
+		source, eventType, rule, outcome string
+		status                           int
+		stored                           bool
+	}{
+		{"github", "", "", OutcomePaused, http.StatusOK, true},
+		{"jira", "", "", OutcomeErrored, http.StatusInternalServerError, true},
+		{"slack", "", "", OutcomeRejected, http.StatusForbidden, false},
+		{"github", "", "", OutcomeIgnored, http.StatusOK, false},
+		{"trello", "card_moved", "trello.rules[1]", OutcomeMatched, http.StatusOK, false},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("expected %d deliveries, got %+v", len(want), got)
+	}
+	for i, w := range want {
+		d := got[i]
+		if d.Source != w.source || d.EventType != w.eventType || d.Rule != w.rule || d.Outcome != w.outcome || d.Status != w.status || (d.EventID != "") != w.stored {
+			t.Errorf("delivery %d: unexpected %+v", i, d)
+		}
+	}
+	if ev, ok := s.Get(got[1].EventID); !ok || ev.Status != StatusErrored {
+		t.Errorf("expected the errored delivery linked to its stored event, got %+v", ev)
+	}
+
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Fatal("expected the write batched")
+	}
+	if err := h.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	reloaded, err := NewHistory(path, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := reloaded.Query("", time.Time{}, 0); len(got) != 2 || got[0].Outcome != OutcomePaused {
+		t.Errorf("expected the latest 2 deliveries reloaded, got %+v", got)
+	}
+}
+
+func TestHistory_HandleList(t *testing.T) {
+	h, _ := NewHistory("", 0)
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	h.now = func() time.Time { return now }
+	h.Add(Delivery{Source: "github", Outcome: OutcomeMatched, Time: now.Add(-3 * time.Hour)})
+	h.Add(Delivery{Source: "trello", Outcome: OutcomeNoRule, Time: now.Add(-2 * time.Hour)})
+	h.Add(Delivery{Source: "github", Outcome: OutcomeRateLimited, Time: now.Add(-time.Hour)})
+	h.Add(Delivery{Source: "github", Outcome: OutcomeMatched, Time: now.Add(-time.Minute)})
+
+	rc, s := newTestRecovery(t)
+	s.Add(Event{ID: "e1", Source: "github", Status: StatusErrored})
+	rc.SetHistory(h)
+	mux := http.NewServeMux()
+	rc.RegisterRoutes(mux)
+
+	tests := []struct {
+		query    string
+		wantCode int
+		want     []string // outcomes, newest first
+	}{
+		{"", http.StatusOK, []string{OutcomeMatched, OutcomeRateLimited, OutcomeNoRule, OutcomeMatched}},
+		{"?source=github&limit=2", http.StatusOK, []string{OutcomeMatched, OutcomeRateLimited}},
+		{"?since=90m", http.StatusOK, []string{OutcomeMatched, OutcomeRateLimited}},
+		{"?source=trello&since=2026-03-01T09:30:00Z", http.StatusOK, []string{OutcomeNoRule}},
+		{"?since=yesterday", http.StatusBadRequest, nil},
+		{"?limit=0", http.StatusBadRequest, nil},
+	}
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest("GET", "/api/events"+tt.query, nil))
+		if rec.Code != tt.wantCode {
+			t.Errorf("%q: expected %d, got %d", tt.query, tt.wantCode, rec.Code)
+			continue
+		}
+		if tt.wantCode != http.StatusOK {
+			continue
+		}
+		var resp struct{ Events []Delivery }
+		json.NewDecoder(rec.Body).Decode(&resp)
+		var outcomes []string
+		for _, d := range resp.Events {
+			outcomes = append(outcomes, d.Outcome)
+		}
+		if strings.Join(outcomes, ",") != strings.Join(tt.want, ",") {
+			t.Errorf("%q: expected %v, got %v", tt.query, tt.want, outcomes)
+		}
+	}
+
+	// ?status= still lists the stored deliveries
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest("GET", "/api/events?status=errored", nil))
+	var resp map[string][]Event
+	json.NewDecoder(rec.Body).Decode(&resp)
+	if len(resp["events"]) != 1 || resp["events"][0].ID != "e1" {
+		t.Errorf("expected the errored event, got %+v", resp)
+	}
+}
+
+func TestHistory_Prune(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history.json")
+	h, _ := NewHistory(path, 0)
+	now := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	h.Add(Delivery{ID: "a", Time: now.Add(-72 * time.Hour), Source: "trello"})
+	h.Add(Delivery{ID: "b", Time: now.Add(-48 * time.Hour), Source: "trello"})
+	h.Add(Delivery{ID: "c", Time: now, Source: "trello"})
+
+	cutoff := now.Add(-24 * time.Hour)
+	pruned, err := h.Prune(cutoff, true)
+	if err != nil || len(pruned) != 2 || len(h.Query("", time.Time{}, 0)) != 3 {
+		t.Fatalf("dry run: pruned %+v, err %v", pruned, err)
+	}
+	if pruned, err = h.Prune(cutoff, false); err != nil || len(pruned) != 2 || pruned[0].ID != "a" {
+		t.Fatalf("pruned %+v, err %v", pruned, err)
+	}
+	reopened, _ := NewHistory(path, 0)
+	if left := reopened.Query("", time.Time{}, 0); len(left) != 1 || left[0].ID != "c" {
+		t.Errorf("expected only c kept, got %+v", left)
+	}
+}
@@ -0,0 +1,137 @@
+package events
+
+import (
+	"context"
+	"io"
+	"log"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+// StatusPaused marks a delivery that arrived while its source was paused.
+const StatusPaused = "paused"
+
+type replayKey struct{}
+
+// withReplay marks a request re-run by Recovery.Replay.
+func withReplay(ctx context.Context) context.Context {
+	return context.WithValue(ctx, replayKey{}, true)
+}
+
+// IsReplay reports whether ctx belongs to a delivery re-run by Recovery.Replay.
+func IsReplay(ctx context.Context) bool {
+	replay, _ := ctx.Value(replayKey{}).(bool)
+	return replay
+}
+
+// Pause is an active pause of one source.
+type Pause struct {
+	Source string    `json:"source"`
+	Since  time.Time `json:"since"`
+	Until  time.Time `json:"until,omitzero"` // zero means until resumed
+}
+
+// Pauses holds sources that should not dispatch jobs for now, e.g. while the
+// agent works through a backlog. Deliveries for a paused source are kept in
+// the store as "paused" and can be replayed once it resumes.
+type Pauses struct {
+	store  *Store
+	mu     sync.Mutex
+	paused map[string]Pause
+	now    func() time.Time
+}
+
+func NewPauses(store *Store) *Pauses {
+	return &Pauses{store: store, paused: map[string]Pause{}, now: time.Now}
+}
+
+// Pause pauses source for d; zero d pauses it until Resume.
+func (p *Pauses) Pause(source string, d time.Duration) Pause {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	now := p.now().UTC()
+	pause := Pause{Source: source, Since: now}
+	if d > 0 {
+		pause.Until = now.Add(d)
+	}
+	p.paused[source] = pause
+	return pause
+}
+
+// Resume lifts a pause and reports whether source was paused.
+func (p *Pauses) Resume(source string) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	_, ok := p.active(source)
+	delete(p.paused, source)
+	return ok
+}
+
+// Paused reports whether source is currently paused.
+func (p *Pauses) Paused(source string) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	_, ok := p.active(source)
+	return ok
+}
+
+// List returns the active pauses sorted by source.
+func (p *Pauses) List() []Pause {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	out := make([]Pause, 0, len(p.paused))
+	for source := range p.paused {
+		if pause, ok := p.active(source); ok {
+			out = append(out, pause)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Source < out[j].Source })
+	return out
+}
+
+// active returns source's pause, dropping it once it has run out. p.mu must be held.
+func (p *Pauses) active(source string) (Pause, bool) {
+	pause, ok := p.paused[source]
+	if ok && !pause.Until.IsZero() && !p.now().Before(pause.Until) {
+		delete(p.paused, source)
+		return Pause{}, false
+	}
+	return pause, ok
+}
+
+// Wrap returns next held back while source is paused. Replays always pass,
+// so paused deliveries can be worked off before resuming.
+func (p *Pauses) Wrap(source string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if IsReplay(r.Context()) || !p.Paused(source) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+		log.Printf("Holding %s delivery: source is paused", source)
+		if p.store != nil {
+			if ev, err := p.store.Add(Event{
+				Source: source,
+				Status: StatusPaused,
+				Method: r.Method,
+				Host:   r.Host,
+				Path:   r.URL.RequestURI(),
+				Header: r.Header.Clone(),
+				Body:   body,
+			}); err != nil {
+				log.Printf("Failed to record paused %s event: %v", source, err)
+			} else {
+				annotateStored(r.Context(), OutcomePaused, ev.ID)
+			}
+		}
+		// 200 so the source doesn't retry; the stored copy is replayed instead
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"ok":true,"paused":true}`))
+	})
+}
@@ -0,0 +1,84 @@
+package events
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPauses_HoldAndReplay(t *testing.T) {
+	rc, s := newTestRecovery(t)
+	p := NewPauses(s)
+	var got []string
+	h := rc.Wrap("jira", p.Wrap("jira", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		got = append(got, string(body))
+	})))
+	post := func(body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest("POST", "/webhook/jira", strings.NewReader(body)))
+		return rec
+	}
+
+	post("before")
+	p.Pause("jira", 0)
+	rec := post("held")
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"paused":true`) {
+		t.Errorf("expected 200 paused response, got %d %s", rec.Code, rec.Body.String())
+	}
+	if len(got) != 1 {
+		t.Fatalf("expected the held delivery not to reach the handler, got %v", got)
+	}
+	held := s.List(StatusPaused)
+	if len(held) != 1 || string(held[0].Body) != "held" {
+		t.Fatalf("expected one paused record, got %+v", held)
+	}
+
+	// Replays pass while the source is still paused
+	if status, err := rc.Replay(held[0].ID, false); err != nil || status != http.StatusOK {
+		t.Fatalf("replay: %d/%v", status, err)
+	}
+	if len(got) != 2 || got[1] != "held" {
+		t.Errorf("expected replay to reach the handler, got %v", got)
+	}
+	if ev, _ := s.Get(held[0].ID); ev.Status != StatusReplayed {
+		t.Errorf("status = %s, want replayed", ev.Status)
+	}
+
+	if !p.Resume("jira") || p.Resume("jira") {
+		t.Error("expected Resume to report the pause once")
+	}
+	post("after")
+	if len(got) != 3 {
+		t.Errorf("expected delivery after resume, got %v", got)
+	}
+}
+
+func TestPauses_Expire(t *testing.T) {
+	p := NewPauses(nil)
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	p.now = func() time.Time { return now }
+
+	pause := p.Pause("github", time.Hour)
+	if !pause.Until.Equal(now.Add(time.Hour)) {
+		t.Errorf("until = %v", pause.Until)
+	}
+	p.Pause("slack", 0)
+	if list := p.List(); len(list) != 2 || list[0].Source != "github" || list[1].Source != "slack" {
+		t.Errorf("unexpected pauses %+v", list)
+	}
+
+	now = now.Add(time.Hour)
+	if p.Paused("github") {
+		t.Error("expected github pause to run out")
+	}
+	if !p.Paused("slack") {
+		t.Error("expected open-ended pause to stay")
+	}
+	if p.Resume("github") {
+		t.Error("expected an expired pause not to count as resumed")
+	}
+}
@@ -0,0 +1,259 @@
+package events
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"runtime/debug"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Recovery catches panics in webhook handlers and keeps the raw delivery in the
+// event store marked "errored", so it can be replayed once the bug is fixed.
+type Recovery struct {
+	store    *Store
+	mu       sync.RWMutex
+	handlers map[string]http.Handler
+	ttl      time.Duration
+	history  *History
+}
+
+// ErrExpired is returned by Replay for events older than the TTL; force overrides it.
+var ErrExpired = errors.New("event expired")
+
+// SetTTL makes Replay refuse events received more than ttl ago unless forced.
+func (rc *Recovery) SetTTL(ttl time.Duration) {
+	rc.ttl = ttl
+}
+
+// SetHistory makes GET /api/events without ?status= list the delivery history.
+func (rc *Recovery) SetHistory(h *History) {
+	rc.history = h
+}
+
+func NewRecovery(store *Store) *Recovery {
+	return &Recovery{store: store, handlers: map[string]http.Handler{}}
+}
+
+// Wrap registers next as the handler for source and returns it wrapped with panic recovery.
+func (rc *Recovery) Wrap(source string, next http.Handler) http.Handler {
+	rc.mu.Lock()
+	rc.handlers[source] = next
+	rc.mu.Unlock()
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+
+		defer func() {
+			rec := recover()
+			if rec == nil {
+				return
+			}
+			log.Printf("Panic in %s webhook handler: %v\n%s", source, rec, debug.Stack())
+			if rc.store != nil {
+				ev, err := rc.store.Add(Event{
+					Source: source,
+					Status: StatusErrored,
+					Error:  fmt.Sprint(rec),
+					Method: r.Method,
+					Host:   r.Host,
+					Path:   r.URL.RequestURI(),
+					Header: r.Header.Clone(),
+					Body:   body,
+				})
+				if err != nil {
+					log.Printf("Failed to preserve errored %s event: %v", source, err)
+				} else {
+					log.Printf("Preserved errored %s event %s for replay", source, ev.ID)
+					annotateStored(r.Context(), OutcomeErrored, ev.ID)
+				}
+			}
+			http.Error(w, "internal error", http.StatusInternalServerError)
+		}()
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// Replay re-runs a stored delivery through its source handler and returns the response status.
+// The event is marked "replayed" on a non-5xx response and stays "errored" otherwise.
+// Expired events, or events older than the TTL, are only replayed when force is set.
+func (rc *Recovery) Replay(id string, force bool) (int, error) {
+	if rc.store == nil {
+		return 0, fmt.Errorf("event store not configured")
+	}
+	ev, ok := rc.store.Get(id)
+	if !ok {
+		return 0, fmt.Errorf("event %s not found", id)
+	}
+	if !force {
+		if ev.Status == StatusExpired {
+			return http.StatusGone, ErrExpired
+		}
+		if rc.ttl > 0 && time.Since(ev.ReceivedAt) > rc.ttl {
+			reason := fmt.Sprintf("received %s ago, exceeds event_ttl %s", time.Since(ev.ReceivedAt).Round(time.Second), rc.ttl)
+			log.Printf("Not replaying %s event %s: %s", ev.Source, id, reason)
+			if err := rc.store.SetStatus(id, StatusExpired, reason); err != nil {
+				return 0, err
+			}
+			return http.StatusGone, ErrExpired
+		}
+	}
+	rc.mu.RLock()
+	h := rc.handlers[ev.Source]
+	rc.mu.RUnlock()
+	if h == nil {
+		return 0, fmt.Errorf("no handler for source %q", ev.Source)
+	}
+
+	req, err := http.NewRequest(ev.Method, ev.Path, bytes.NewReader(ev.Body))
+	if err != nil {
+		return 0, err
+	}
+	req.Host = ev.Host
+	req.Header = ev.Header.Clone()
+	if req.Header == nil {
+		req.Header = http.Header{}
+	}
+	req = req.WithContext(withReplay(req.Context()))
+	if force {
+		req = req.WithContext(withForce(req.Context()))
+	}
+
+	status, panicMsg := serveRecovered(h, req)
+	if panicMsg != "" {
+		log.Printf("Replay of event %s panicked again: %s", id, panicMsg)
+		return status, rc.store.SetStatus(id, StatusErrored, panicMsg)
+	}
+	if status >= 500 {
+		return status, rc.store.SetStatus(id, StatusErrored, fmt.Sprintf("replay returned %d", status))
+	}
+	log.Printf("Replayed %s event %s (status %d)", ev.Source, id, status)
+	return status, rc.store.SetStatus(id, StatusReplayed, "")
+}
+
+// statusRecorder is a minimal ResponseWriter that only keeps the status code.
+type statusRecorder struct {
+	header http.Header
+	status int
+}
+
+func (s *statusRecorder) Header() http.Header         { return s.header }
+func (s *statusRecorder) Write(b []byte) (int, error) { return len(b), nil }
+func (s *statusRecorder) WriteHeader(code int) {
+	if s.status == 0 {
+		s.status = code
+	}
+}
+
+func serveRecovered(h http.Handler, req *http.Request) (status int, panicMsg string) {
+	rec := &statusRecorder{header: http.Header{}}
+	defer func() {
+		if r := recover(); r != nil {
+			status = http.StatusInternalServerError
+			panicMsg = fmt.Sprint(r)
+		}
+	}()
+	h.ServeHTTP(rec, req)
+	if rec.status == 0 {
+		rec.status = http.StatusOK
+	}
+	return rec.status, ""
+}
+
+// RegisterRoutes adds event API routes to the mux.
+func (rc *Recovery) RegisterRoutes(mux *http.ServeMux) {
+	mux.HandleFunc("/api/events", rc.handleList)
+	mux.HandleFunc("/api/events/", rc.handleGet)
+	mux.HandleFunc("/api/events/replay/", rc.handleReplay)
+}
+
+func writeJSON(w http.ResponseWriter, code int, data any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(data)
+}
+
+func (rc *Recovery) handleList(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+		return
+	}
+	status := r.URL.Query().Get("status")
+	if status == "" && rc.history != nil {
+		rc.history.HandleList(w, r)
+		return
+	}
+	if rc.store == nil {
+		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": "event store not configured"})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"events": rc.store.List(status)})
+}
+
+// handleGet serves GET /api/events/{id}: the delivery with that ID or stored
+// event ID, with its timeline, and its stored copy when there is one.
+func (rc *Recovery) handleGet(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+		return
+	}
+	id := strings.TrimPrefix(r.URL.Path, "/api/events/")
+	if id == "" || strings.Contains(id, "/") {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "not found"})
+		return
+	}
+	if rc.history == nil {
+		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": "event history not configured"})
+		return
+	}
+	d, ok := rc.history.Get(id)
+	if !ok {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": fmt.Sprintf("event %s not found", id)})
+		return
+	}
+	resp := map[string]any{"event": d}
+	if d.EventID != "" && rc.store != nil {
+		if ev, ok := rc.store.Get(d.EventID); ok {
+			resp["stored"] = ev
+		}
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func (rc *Recovery) handleReplay(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+		return
+	}
+	id := strings.TrimPrefix(r.URL.Path, "/api/events/replay/")
+	if id == "" {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "missing event id"})
+		return
+	}
+	status, err := rc.Replay(id, r.URL.Query().Get("force") == "true")
+	if errors.Is(err, ErrExpired) {
+		writeJSON(w, http.StatusGone, map[string]string{"error": err.Error() + "; add ?force=true to replay anyway"})
+		return
+	}
+	if err != nil && status == 0 {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"ok": status < 500, "status": status})
+}
@@ -0,0 +1,174 @@
+package events
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func newTestRecovery(t *testing.T) (*Recovery, *Store) {
+	s, err := NewStore(filepath.Join(t.TempDir(), "events.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return NewRecovery(s), s
+}
+
+func TestWrap_PanicPreservesEvent(t *testing.T) {
+	rc, s := newTestRecovery(t)
+	h := rc.Wrap("trello", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.ReadAll(r.Body)
+		panic("nil map")
+	}))
+
+	req := httptest.NewRequest("POST", "/webhook/trello", strings.NewReader(`{"action":{}}`))
+	req.Header.Set("X-Trello-Webhook", "sig")
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusInternalServerError {
+		t.Errorf("expected 500, got %d", rec.Code)
+	}
+	list := s.List(StatusErrored)
+	if len(list) != 1 {
+		t.Fatalf("expected 1 errored event, got %d", len(list))
+	}
+	ev := list[0]
+	if ev.Source != "trello" || string(ev.Body) != `{"action":{}}` || ev.Error != "nil map" {
+		t.Errorf("unexpected event: %+v", ev)
+	}
+	if ev.Header.Get("X-Trello-Webhook") != "sig" {
+		t.Error("expected signature header to be preserved")
+	}
+}
+
+func TestWrap_NoPanicPassesThrough(t *testing.T) {
+	rc, s := newTestRecovery(t)
+	h := rc.Wrap("github", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		w.Write(body)
+	}))
+
+	req := httptest.NewRequest("POST", "/webhook/github", strings.NewReader("hello"))
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK || rec.Body.String() != "hello" {
+		t.Errorf("unexpected response: %d %q", rec.Code, rec.Body.String())
+	}
+	if len(s.List("")) != 0 {
+		t.Error("expected no stored events")
+	}
+}
+
+func TestWrap_NilStoreStillRecovers(t *testing.T) {
+	rc := NewRecovery(nil)
+	h := rc.Wrap("trello", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic("boom")
+	}))
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest("POST", "/webhook/trello", nil))
+	if rec.Code != http.StatusInternalServerError {
+		t.Errorf("expected 500, got %d", rec.Code)
+	}
+}
+
+func TestReplay_MarksReplayed(t *testing.T) {
+	rc, s := newTestRecovery(t)
+	fixed := false
+	var gotBody, gotHost string
+	rc.Wrap("trello", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !fixed {
+			panic("bug")
+		}
+		b, _ := io.ReadAll(r.Body)
+		gotBody, gotHost = string(b), r.Host
+		w.WriteHeader(http.StatusOK)
+	}))
+	ev, _ := s.Add(Event{ID: "e1", Source: "trello", Status: StatusErrored, Method: "POST", Host: "relay.example.com", Path: "/webhook/trello", Body: []byte("payload")})
+
+	fixed = true
+	status, err := rc.Replay(ev.ID, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status != http.StatusOK {
+		t.Errorf("expected 200, got %d", status)
+	}
+	if gotBody != "payload" || gotHost != "relay.example.com" {
+		t.Errorf("replayed request mismatch: body=%q host=%q", gotBody, gotHost)
+	}
+	got, _ := s.Get("e1")
+	if got.Status != StatusReplayed {
+		t.Errorf("expected status replayed, got %s", got.Status)
+	}
+}
+
+func TestReplay_PanicKeepsErrored(t *testing.T) {
+	rc, s := newTestRecovery(t)
+	rc.Wrap("trello", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic("still broken")
+	}))
+	s.Add(Event{ID: "e1", Source: "trello", Status: StatusErrored, Method: "POST", Path: "/webhook/trello"})
+
+	status, err := rc.Replay("e1", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status != http.StatusInternalServerError {
+		t.Errorf("expected 500, got %d", status)
+	}
+	got, _ := s.Get("e1")
+	if got.Status != StatusErrored || got.Error != "still broken" {
+		t.Errorf("unexpected event: %+v", got)
+	}
+}
+
+func TestReplay_UnknownEventOrSource(t *testing.T) {
+	rc, s := newTestRecovery(t)
+	if _, err := rc.Replay("missing", false); err == nil {
+		t.Error("expected error for unknown event")
+	}
+	s.Add(Event{ID: "e1", Source: "jira", Method: "POST", Path: "/webhook/jira"})
+	if _, err := rc.Replay("e1", false); err == nil {
+		t.Error("expected error for unregistered source")
+	}
+}
+
+func TestHandleList_And_Replay(t *testing.T) {
+	rc, s := newTestRecovery(t)
+	rc.Wrap("github", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	s.Add(Event{ID: "e1", Source: "github", Status: StatusErrored, Method: "POST", Path: "/webhook/github"})
+	mux := http.NewServeMux()
+	rc.RegisterRoutes(mux)
+
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest("GET", "/api/events?status=errored", nil))
+	var resp map[string][]Event
+	json.NewDecoder(rec.Body).Decode(&resp)
+	if len(resp["events"]) != 1 {
+		t.Fatalf("expected 1 event, got %d", len(resp["events"]))
+	}
+
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest("POST", "/api/events/replay/e1", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest("POST", "/api/events/replay/missing", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("expected 404, got %d", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest("GET", "/api/events/replay/e1", nil))
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("expected 405, got %d", rec.Code)
+	}
+}
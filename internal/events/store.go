@@ -0,0 +1,159 @@
+package events
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/katalabut/openclaw-relay/internal/atomicfile"
+)
+
+const (
+	// StatusErrored marks a delivery whose handler panicked.
+	StatusErrored = "errored"
+	// StatusReplayed marks an errored delivery that was replayed successfully.
+	StatusReplayed = "replayed"
+
+	defaultMaxEvents = 500
+)
+
+// Event is a stored webhook delivery.
+type Event struct {
+	ID         string      `json:"id"`
+	Source     string      `json:"source"`
+	Status     string      `json:"status"`
+	Error      string      `json:"error,omitempty"`
+	ReceivedAt time.Time   `json:"received_at"`
+	UpdatedAt  time.Time   `json:"updated_at"`
+	Method     string      `json:"method"`
+	Host       string      `json:"host"`
+	Path       string      `json:"path"`
+	Header     http.Header `json:"header,omitempty"`
+	Body       []byte      `json:"body,omitempty"`
+}
+
+// Store persists events to a JSON file, keeping at most maxEvents (oldest dropped first).
+type Store struct {
+	mu        sync.RWMutex
+	filePath  string
+	maxEvents int
+	events    []Event
+}
+
+// NewStore opens (or creates) the event store at filePath.
+// An empty filePath keeps events in memory only.
+func NewStore(filePath string) (*Store, error) {
+	s := &Store{filePath: filePath, maxEvents: defaultMaxEvents}
+	if filePath == "" {
+		return s, nil
+	}
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return s, nil
+		}
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &s.events); err != nil {
+		return nil, fmt.Errorf("parse events: %w", err)
+	}
+	return s, nil
+}
+
+func (s *Store) save() error {
+	if s.filePath == "" {
+		return nil
+	}
+	return atomicfile.WriteJSON(s.filePath, s.events)
+}
+
+func newID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// Add stores a new event, assigning ID and timestamps when missing.
+func (s *Store) Add(e Event) (Event, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if e.ID == "" {
+		e.ID = newID()
+	}
+	if e.ReceivedAt.IsZero() {
+		e.ReceivedAt = time.Now().UTC()
+	}
+	e.UpdatedAt = e.ReceivedAt
+	s.events = append(s.events, e)
+	if len(s.events) > s.maxEvents {
+		s.events = s.events[len(s.events)-s.maxEvents:]
+	}
+	return e, s.save()
+}
+
+// Get returns an event by ID.
+func (s *Store) Get(id string) (Event, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for _, e := range s.events {
+		if e.ID == id {
+			return e, true
+		}
+	}
+	return Event{}, false
+}
+
+// List returns events with the given status (all when empty), newest first.
+func (s *Store) List(status string) []Event {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	out := make([]Event, 0, len(s.events))
+	for i := len(s.events) - 1; i >= 0; i-- {
+		if status == "" || s.events[i].Status == status {
+			out = append(out, s.events[i])
+		}
+	}
+	return out
+}
+
+// Prune drops the events last updated before cutoff and returns them. Paused
+// deliveries wait for their source to resume and are kept. With dryRun it
+// only returns them.
+func (s *Store) Prune(cutoff time.Time, dryRun bool) ([]Event, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var pruned []Event
+	kept := s.events[:0:0]
+	for _, e := range s.events {
+		if e.Status != StatusPaused && e.UpdatedAt.Before(cutoff) {
+			pruned = append(pruned, e)
+			continue
+		}
+		kept = append(kept, e)
+	}
+	if dryRun || len(pruned) == 0 {
+		return pruned, nil
+	}
+	s.events = kept
+	return pruned, s.save()
+}
+
+// SetStatus updates the status and error message of an event.
+func (s *Store) SetStatus(id, status, errMsg string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i := range s.events {
+		if s.events[i].ID == id {
+			s.events[i].Status = status
+			s.events[i].Error = errMsg
+			s.events[i].UpdatedAt = time.Now().UTC()
+			return s.save()
+		}
+	}
+	return fmt.Errorf("event %s not found", id)
+}
@@ -0,0 +1,123 @@
+package events
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestStore_AddGetRoundTrip(t *testing.T) {
+	fp := filepath.Join(t.TempDir(), "events.json")
+	s, err := NewStore(fp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ev, err := s.Add(Event{Source: "trello", Status: StatusErrored, Body: []byte(`{"a":1}`)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ev.ID == "" || ev.ReceivedAt.IsZero() {
+		t.Fatalf("expected ID and timestamp to be set: %+v", ev)
+	}
+
+	s2, err := NewStore(fp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, ok := s2.Get(ev.ID)
+	if !ok {
+		t.Fatal("expected event after reload")
+	}
+	if string(got.Body) != `{"a":1}` || got.Source != "trello" {
+		t.Errorf("unexpected event: %+v", got)
+	}
+}
+
+func TestStore_ListFiltersByStatusNewestFirst(t *testing.T) {
+	s, _ := NewStore(filepath.Join(t.TempDir(), "events.json"))
+	s.Add(Event{ID: "a", Status: StatusErrored})
+	s.Add(Event{ID: "b", Status: StatusReplayed})
+	s.Add(Event{ID: "c", Status: StatusErrored})
+
+	errored := s.List(StatusErrored)
+	if len(errored) != 2 || errored[0].ID != "c" || errored[1].ID != "a" {
+		t.Errorf("unexpected errored list: %+v", errored)
+	}
+	if all := s.List(""); len(all) != 3 {
+		t.Errorf("expected 3 events, got %d", len(all))
+	}
+}
+
+func TestStore_TrimsToMax(t *testing.T) {
+	s, _ := NewStore(filepath.Join(t.TempDir(), "events.json"))
+	s.maxEvents = 2
+	s.Add(Event{ID: "a"})
+	s.Add(Event{ID: "b"})
+	s.Add(Event{ID: "c"})
+	if _, ok := s.Get("a"); ok {
+		t.Error("oldest event should have been dropped")
+	}
+	if len(s.List("")) != 2 {
+		t.Errorf("expected 2 events, got %d", len(s.List("")))
+	}
+}
+
+func TestStore_SetStatus(t *testing.T) {
+	s, _ := NewStore(filepath.Join(t.TempDir(), "events.json"))
+	s.Add(Event{ID: "a", Status: StatusErrored, Error: "boom"})
+	if err := s.SetStatus("a", StatusReplayed, ""); err != nil {
+		t.Fatal(err)
+	}
+	ev, _ := s.Get("a")
+	if ev.Status != StatusReplayed || ev.Error != "" {
+		t.Errorf("unexpected event: %+v", ev)
+	}
+	if err := s.SetStatus("missing", StatusReplayed, ""); err == nil {
+		t.Error("expected error for unknown event")
+	}
+}
+
+func TestStore_Prune(t *testing.T) {
+	fp := filepath.Join(t.TempDir(), "events.json")
+	s, _ := NewStore(fp)
+	old := time.Now().Add(-48 * time.Hour)
+	s.Add(Event{ID: "a", Status: StatusReplayed, ReceivedAt: old})
+	s.Add(Event{ID: "b", Status: StatusPaused, ReceivedAt: old})
+	s.Add(Event{ID: "c", Status: StatusErrored})
+
+	cutoff := time.Now().Add(-24 * time.Hour)
+	pruned, err := s.Prune(cutoff, true)
+	if err != nil || len(pruned) != 1 || pruned[0].ID != "a" || len(s.List("")) != 3 {
+		t.Fatalf("dry run: pruned %+v, err %v", pruned, err)
+	}
+	if pruned, err = s.Prune(cutoff, false); err != nil || len(pruned) != 1 {
+		t.Fatalf("pruned %+v, err %v", pruned, err)
+	}
+	s2, _ := NewStore(fp)
+	if _, ok := s2.Get("a"); ok || len(s2.List("")) != 2 {
+		t.Errorf("expected only a dropped, got %+v", s2.List(""))
+	}
+}
+
+func TestNewStore_CorruptFile(t *testing.T) {
+	fp := filepath.Join(t.TempDir(), "events.json")
+	os.WriteFile(fp, []byte("not json"), 0600)
+	if _, err := NewStore(fp); err == nil {
+		t.Error("expected error for corrupt file")
+	}
+}
+
+func TestNewStore_InMemory(t *testing.T) {
+	s, err := NewStore("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	e, err := s.Add(Event{Source: "trello", Status: StatusErrored})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := s.Get(e.ID); !ok {
+		t.Error("expected event to be kept in memory")
+	}
+}
@@ -0,0 +1,109 @@
+package events
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// Stages of a delivery's timeline. A step for a delivery that matched no rule,
+// was rate limited, sampled out, ignored or stored has that outcome as stage.
+const (
+	StageReceived  = "received"  // the request reached the relay
+	StageVerified  = "verified"  // the source's signature or token check passed
+	StageMatched   = "matched"   // a rule matched
+	StageQueued    = "queued"    // the rule's job was handed to the gateway client
+	StageDelivered = "delivered" // the gateway accepted the job
+	StageFailed    = "failed"    // the gateway did not accept the job
+	StageResponded = "responded" // the relay answered the provider
+
+	// maxSteps bounds the timeline of a delivery carrying many events.
+	maxSteps = 50
+)
+
+// Step is one stage a delivery went through, with how long after it was
+// received.
+type Step struct {
+	Time      time.Time `json:"time"`
+	ElapsedMs int64     `json:"elapsed_ms"`
+	Stage     string    `json:"stage"`
+	Rule      string    `json:"rule,omitempty"`
+	Job       string    `json:"job,omitempty"`    // job name
+	JobID     string    `json:"job_id,omitempty"` // see gateway.JobSpec.ID
+	Detail    string    `json:"detail,omitempty"`
+}
+
+// step appends s to the timeline, timed now. The caller holds a.mu.
+func (a *annotation) step(s Step) {
+	if len(a.steps) >= maxSteps {
+		return
+	}
+	s.Time = a.now().UTC()
+	if len(a.steps) > 0 {
+		s.ElapsedMs = s.Time.Sub(a.steps[0].Time).Milliseconds()
+	}
+	a.steps = append(a.steps, s)
+}
+
+// matched reports whether the latest "matched" step is for rule. The caller
+// holds a.mu.
+func (a *annotation) matched(rule string) bool {
+	for i := len(a.steps) - 1; i >= 0; i-- {
+		if a.steps[i].Stage == StageMatched {
+			return a.steps[i].Rule == rule
+		}
+	}
+	return false
+}
+
+// addStep appends s to the timeline of the delivery ctx belongs to. Outside
+// a delivery recorded by History it does nothing.
+func addStep(ctx context.Context, s Step) {
+	a, _ := ctx.Value(annotationKey{}).(*annotation)
+	if a == nil {
+		return
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.step(s)
+}
+
+// Verified records that the delivery ctx belongs to passed its source's
+// signature or token check. signed is false for a source without a secret
+// configured, which accepts every request.
+func Verified(ctx context.Context, signed bool) {
+	s := Step{Stage: StageVerified}
+	if !signed {
+		s.Detail = "no secret configured"
+	}
+	addStep(ctx, s)
+}
+
+// Queued records that a rule's job is about to be sent to the gateway,
+// after a "matched" step unless the latest one is for the same rule.
+func Queued(ctx context.Context, rule, job, jobID string, delaySeconds int) {
+	a, _ := ctx.Value(annotationKey{}).(*annotation)
+	if a == nil {
+		return
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if !a.matched(rule) {
+		a.step(Step{Stage: StageMatched, Rule: rule})
+	}
+	s := Step{Stage: StageQueued, Rule: rule, Job: job, JobID: jobID}
+	if delaySeconds > 0 {
+		s.Detail = fmt.Sprintf("runs %s after delivery", time.Duration(delaySeconds)*time.Second)
+	}
+	a.step(s)
+}
+
+// Delivered records whether the gateway accepted the job Queued reported.
+func Delivered(ctx context.Context, job, jobID string, err error) {
+	s := Step{Stage: StageDelivered, Job: job, JobID: jobID}
+	if err != nil {
+		s.Stage = StageFailed
+		s.Detail = err.Error()
+	}
+	addStep(ctx, s)
+}
@@ -0,0 +1,130 @@
+package events
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTimeline(t *testing.T) {
+	h, _ := NewHistory("", 0)
+	now := time.Date(2026, 3, 1, 9, 0, 0, 0, time.UTC)
+	h.now = func() time.Time {
+		now = now.Add(time.Second)
+		return now
+	}
+	rc, _ := newTestRecovery(t)
+	rc.SetHistory(h)
+
+	matched := h.Wrap("trello", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		Verified(r.Context(), true)
+		Annotate(r.Context(), "card_moved", "trello.rules[0]", OutcomeSampledOut)
+		Annotate(r.Context(), "", "trello.rules[1]", "")
+		Queued(r.Context(), "trello.rules[1]", "trello card_moved", "job-1", 240)
+		Delivered(r.Context(), "trello card_moved", "job-1", nil)
+		Queued(r.Context(), "trello.rules[2]", "trello card_moved [trello.rules[2]]", "job-2", 0)
+		Delivered(r.Context(), "trello card_moved [trello.rules[2]]", "job-2", errors.New("gateway returned 502"))
+	}))
+	matched.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("POST", "/webhook/trello", nil))
+	unsigned := h.Wrap("jira", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		Verified(r.Context(), false)
+		Annotate(r.Context(), "issue_updated", "", OutcomeNoRule)
+	}))
+	unsigned.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("POST", "/webhook/jira", nil))
+
+	got := h.Query("", time.Time{}, 0)
+	stages := func(d Delivery) string {
+		var out []string
+		for _, s := range d.Timeline {
+			out = append(out, s.Stage)
+		}
+		return strings.Join(out, " ")
+	}
+	if want := "received verified sampled_out matched queued delivered matched queued failed responded"; stages(got[1]) != want {
+		t.Errorf("trello timeline: %s", stages(got[1]))
+	}
+	tl := got[1].Timeline
+	if q := tl[4]; q.Rule != "trello.rules[1]" || q.JobID != "job-1" || q.Detail != "runs 4m0s after delivery" || q.ElapsedMs != 4000 {
+		t.Errorf("queued step: %+v", q)
+	}
+	if f := tl[8]; f.JobID != "job-2" || f.Detail != "gateway returned 502" {
+		t.Errorf("failed step: %+v", f)
+	}
+	if r := tl[9]; r.Detail != "200" {
+		t.Errorf("responded step: %+v", r)
+	}
+	if want := "received verified no_rule responded"; stages(got[0]) != want || got[0].Timeline[1].Detail != "no secret configured" {
+		t.Errorf("jira timeline: %+v", got[0].Timeline)
+	}
+
+	// Outside a recorded delivery the steps are dropped
+	Verified(t.Context(), true)
+	Queued(t.Context(), "r", "job", "id", 0)
+	Delivered(t.Context(), "job", "id", nil)
+
+	mux := http.NewServeMux()
+	rc.RegisterRoutes(mux)
+	get := func(path string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest("GET", path, nil))
+		return rec
+	}
+	rec := get("/api/events/" + got[1].ID)
+	var resp struct {
+		Event Delivery `json:"event"`
+	}
+	json.NewDecoder(rec.Body).Decode(&resp)
+	if rec.Code != http.StatusOK || resp.Event.Source != "trello" || len(resp.Event.Timeline) != 10 {
+		t.Errorf("get: %d %+v", rec.Code, resp)
+	}
+	for _, path := range []string{"/api/events/nope", "/api/events/a/b"} {
+		if rec := get(path); rec.Code != http.StatusNotFound {
+			t.Errorf("%s: %d", path, rec.Code)
+		}
+	}
+}
+
+func TestTimeline_Stored(t *testing.T) {
+	h, _ := NewHistory("", 0)
+	rc, s := newTestRecovery(t)
+	rc.SetHistory(h)
+	panics := h.Wrap("jira", rc.Wrap("jira", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.ReadAll(r.Body)
+		panic("boom")
+	})))
+	panics.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("POST", "/webhook/jira", strings.NewReader("{}")))
+
+	stored := s.List(StatusErrored)[0]
+	mux := http.NewServeMux()
+	rc.RegisterRoutes(mux)
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest("GET", "/api/events/"+stored.ID, nil))
+	var resp struct {
+		Event  Delivery `json:"event"`
+		Stored *Event   `json:"stored"`
+	}
+	json.NewDecoder(rec.Body).Decode(&resp)
+	if rec.Code != http.StatusOK || resp.Stored == nil || resp.Stored.Status != StatusErrored {
+		t.Fatalf("get stored: %d %+v", rec.Code, resp)
+	}
+	if tl := resp.Event.Timeline; len(tl) != 3 || tl[1].Stage != OutcomeErrored || tl[1].Detail != "stored as "+stored.ID {
+		t.Errorf("timeline = %+v", tl)
+	}
+
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest("POST", "/api/events/"+stored.ID, nil))
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("POST: %d", rec.Code)
+	}
+	rc.SetHistory(nil)
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest("GET", "/api/events/"+stored.ID, nil))
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("without history: %d", rec.Code)
+	}
+}
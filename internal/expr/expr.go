@@ -0,0 +1,650 @@
+// Package expr implements the condition language shared by webhook and Gmail rules.
+//
+//	label == 'urgent' && (list == 'ready' || list == 'dev')
+//	!muted && level in ['error', 'fatal']
+//	subject =~ '(?i)invoice' && payload.pull_request.user.login != 'dependabot[bot]'
+//	due < 24h
+//
+// Operands are single- or double-quoted strings, numbers, durations (24h, 1h30m,
+// 7d), true/false, lists ([...]) and field paths (a.b.c, a.items[0].name) looked
+// up in the environment; missing fields are empty. A bare word on the right of
+// == or != is a string, so "status == Done" keeps working. Comparisons against a
+// list field match when any element matches (!= and !~ when none does). A field
+// on its own is true when it is non-empty and not "false" or "0".
+//
+// now.weekday (mon..sun), now.hour (0-23) and now.time ("15:04") give the
+// current local time when the environment doesn't define now, so rules can be
+// limited to working hours:
+//
+//	now.weekday in ['mon', 'tue', 'wed', 'thu', 'fri'] && now.time >= '09:00' && now.time < '18:00'
+package expr
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/katalabut/openclaw-relay/internal/schedule"
+)
+
+// Program is a compiled condition.
+type Program struct {
+	src  string
+	root node
+}
+
+// Compile parses a condition. An empty condition always matches.
+func Compile(src string) (*Program, error) {
+	p := &parser{src: src}
+	if err := p.lex(); err != nil {
+		return nil, err
+	}
+	if len(p.toks) == 0 {
+		return &Program{src: src}, nil
+	}
+	root, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.toks) {
+		return nil, p.errorf("unexpected %q", p.toks[p.pos].text)
+	}
+	return &Program{src: src, root: root}, nil
+}
+
+// Match reports whether the condition holds for env.
+func (p *Program) Match(env map[string]any) bool {
+	if p.root == nil {
+		return true
+	}
+	return truthy(p.root.eval(env))
+}
+
+var cache sync.Map // condition source -> *Program
+
+// Match compiles src (cached) and evaluates it against env.
+func Match(src string, env map[string]any) (bool, error) {
+	if prog, ok := cache.Load(src); ok {
+		return prog.(*Program).Match(env), nil
+	}
+	prog, err := Compile(src)
+	if err != nil {
+		return false, err
+	}
+	cache.Store(src, prog)
+	return prog.Match(env), nil
+}
+
+// --- lexer ---
+
+type tokKind int
+
+const (
+	tokIdent tokKind = iota
+	tokString
+	tokNumber
+	tokDuration
+	tokOp
+)
+
+type token struct {
+	kind tokKind
+	text string
+	pos  int
+}
+
+type parser struct {
+	src  string
+	toks []token
+	pos  int
+}
+
+func (p *parser) errorf(format string, args ...any) error {
+	return fmt.Errorf("condition %q: %s", p.src, fmt.Sprintf(format, args...))
+}
+
+var twoCharOps = []string{"||", "&&", "==", "!=", "<=", ">=", "=~", "!~"}
+
+func isIdentStart(c byte) bool {
+	return c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+func isIdentChar(c byte) bool {
+	return isIdentStart(c) || c >= '0' && c <= '9'
+}
+
+func (p *parser) lex() error {
+	s := p.src
+	for i := 0; i < len(s); {
+		c := s[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case c == '\'' || c == '"':
+			end := strings.IndexByte(s[i+1:], c)
+			if end < 0 {
+				return p.errorf("unterminated string at %d", i)
+			}
+			p.toks = append(p.toks, token{tokString, s[i+1 : i+1+end], i})
+			i += end + 2
+		case c >= '0' && c <= '9' || c == '-' && i+1 < len(s) && s[i+1] >= '0' && s[i+1] <= '9' && p.expectsOperand():
+			start := i
+			i++
+			if n := len(p.toks); n > 0 && p.toks[n-1].kind == tokOp && p.toks[n-1].text == "." {
+				// Array index in a field path (items.0.name)
+				for i < len(s) && s[i] >= '0' && s[i] <= '9' {
+					i++
+				}
+				p.toks = append(p.toks, token{tokNumber, s[start:i], start})
+				continue
+			}
+			for i < len(s) && (s[i] >= '0' && s[i] <= '9' || s[i] == '.') {
+				i++
+			}
+			if i < len(s) && isIdentStart(s[i]) {
+				// Duration such as 24h, 1h30m or 7d
+				for i < len(s) && (isIdentChar(s[i]) || s[i] == '.') {
+					i++
+				}
+				if _, err := schedule.ParseDuration(s[start:i]); err != nil {
+					return p.errorf("invalid duration %q", s[start:i])
+				}
+				p.toks = append(p.toks, token{tokDuration, s[start:i], start})
+				continue
+			}
+			p.toks = append(p.toks, token{tokNumber, s[start:i], start})
+		case isIdentStart(c):
+			start := i
+			for i < len(s) && isIdentChar(s[i]) {
+				i++
+			}
+			p.toks = append(p.toks, token{tokIdent, s[start:i], start})
+		default:
+			op := ""
+			for _, o := range twoCharOps {
+				if strings.HasPrefix(s[i:], o) {
+					op = o
+					break
+				}
+			}
+			if op == "" && strings.ContainsRune("!<>()[],.", rune(c)) {
+				op = string(c)
+			}
+			if op == "" {
+				return p.errorf("unexpected character %q at %d", c, i)
+			}
+			p.toks = append(p.toks, token{tokOp, op, i})
+			i += len(op)
+		}
+	}
+	return nil
+}
+
+// expectsOperand reports whether a '-' here starts a negative number rather than
+// following an operand.
+func (p *parser) expectsOperand() bool {
+	if len(p.toks) == 0 {
+		return true
+	}
+	last := p.toks[len(p.toks)-1]
+	return last.kind == tokOp && last.text != ")" && last.text != "]"
+}
+
+// --- parser ---
+
+func (p *parser) peek() (token, bool) {
+	if p.pos < len(p.toks) {
+		return p.toks[p.pos], true
+	}
+	return token{}, false
+}
+
+func (p *parser) acceptOp(op string) bool {
+	if t, ok := p.peek(); ok && t.kind == tokOp && t.text == op {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *parser) parseOr() (node, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.acceptOp("||") {
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = orNode{left, right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseAnd() (node, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for p.acceptOp("&&") {
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = andNode{left, right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseUnary() (node, error) {
+	if p.acceptOp("!") {
+		inner, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return notNode{inner}, nil
+	}
+	return p.parseComparison()
+}
+
+var comparisonOps = map[string]bool{"==": true, "!=": true, "<": true, "<=": true, ">": true, ">=": true, "=~": true, "!~": true}
+
+func (p *parser) parseComparison() (node, error) {
+	left, err := p.parseOperand(false)
+	if err != nil {
+		return nil, err
+	}
+	t, ok := p.peek()
+	if !ok {
+		return left, nil
+	}
+	op := t.text
+	if t.kind == tokIdent && op == "in" {
+		p.pos++
+		right, err := p.parseOperand(false)
+		if err != nil {
+			return nil, err
+		}
+		return cmpNode{op: "in", left: left, right: right}, nil
+	}
+	if t.kind != tokOp || !comparisonOps[op] {
+		return left, nil
+	}
+	p.pos++
+	right, err := p.parseOperand(op == "==" || op == "!=")
+	if err != nil {
+		return nil, err
+	}
+	n := cmpNode{op: op, left: left, right: right}
+	if op == "=~" || op == "!~" {
+		lit, ok := right.(literal)
+		pattern, isString := lit.v.(string)
+		if !ok || !isString {
+			return nil, p.errorf("%s needs a quoted regular expression", op)
+		}
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, p.errorf("invalid regular expression: %v", err)
+		}
+		n.re = re
+	}
+	return n, nil
+}
+
+// parseOperand parses a literal, list, field path or parenthesized expression.
+// With bareWord, an unquoted identifier is a string rather than a field.
+func (p *parser) parseOperand(bareWord bool) (node, error) {
+	t, ok := p.peek()
+	if !ok {
+		return nil, p.errorf("unexpected end of condition")
+	}
+	p.pos++
+	switch t.kind {
+	case tokString:
+		return literal{t.text}, nil
+	case tokNumber:
+		f, err := strconv.ParseFloat(t.text, 64)
+		if err != nil {
+			return nil, p.errorf("invalid number %q", t.text)
+		}
+		return literal{f}, nil
+	case tokDuration:
+		d, _ := schedule.ParseDuration(t.text)
+		return literal{d}, nil
+	case tokIdent:
+		switch t.text {
+		case "true":
+			return literal{true}, nil
+		case "false":
+			return literal{false}, nil
+		}
+		if bareWord {
+			if next, ok := p.peek(); !ok || next.kind != tokOp || (next.text != "." && next.text != "[") {
+				return literal{t.text}, nil
+			}
+		}
+		return p.parsePath(t.text)
+	}
+	switch t.text {
+	case "(":
+		inner, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if !p.acceptOp(")") {
+			return nil, p.errorf("missing )")
+		}
+		return inner, nil
+	case "[":
+		var items listNode
+		if p.acceptOp("]") {
+			return items, nil
+		}
+		for {
+			item, err := p.parseOperand(false)
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, item)
+			if p.acceptOp("]") {
+				return items, nil
+			}
+			if !p.acceptOp(",") {
+				return nil, p.errorf("expected , or ] in list")
+			}
+		}
+	}
+	return nil, p.errorf("unexpected %q", t.text)
+}
+
+func (p *parser) parsePath(first string) (node, error) {
+	path := pathNode{first}
+	for {
+		switch {
+		case p.acceptOp("."):
+			t, ok := p.peek()
+			if !ok || t.kind != tokIdent && t.kind != tokNumber {
+				return nil, p.errorf("expected field name after .")
+			}
+			p.pos++
+			path = append(path, t.text)
+		case p.acceptOp("["):
+			t, ok := p.peek()
+			if !ok || t.kind != tokNumber && t.kind != tokString {
+				return nil, p.errorf("expected index in [ ]")
+			}
+			p.pos++
+			if !p.acceptOp("]") {
+				return nil, p.errorf("missing ]")
+			}
+			path = append(path, t.text)
+		default:
+			return path, nil
+		}
+	}
+}
+
+// --- evaluation ---
+
+type node interface {
+	eval(env map[string]any) any
+}
+
+type literal struct{ v any }
+
+func (n literal) eval(map[string]any) any { return n.v }
+
+type listNode []node
+
+func (n listNode) eval(env map[string]any) any {
+	out := make([]any, len(n))
+	for i, item := range n {
+		out[i] = item.eval(env)
+	}
+	return out
+}
+
+type pathNode []string
+
+// clock is the current time for now.* paths; tests replace it.
+var clock = time.Now
+
+// nowFields are the now.* fields for t.
+func nowFields(t time.Time) map[string]any {
+	return map[string]any{
+		"weekday": strings.ToLower(t.Weekday().String()[:3]),
+		"hour":    float64(t.Hour()),
+		"time":    t.Format("15:04"),
+	}
+}
+
+func (n pathNode) eval(env map[string]any) any {
+	var v any = env
+	if _, ok := env["now"]; !ok && n[0] == "now" {
+		v = map[string]any{"now": nowFields(clock())}
+	}
+	for _, part := range n {
+		switch node := normalize(v).(type) {
+		case map[string]any:
+			v = node[part]
+		case []any:
+			idx, err := strconv.Atoi(part)
+			if err != nil || idx < 0 || idx >= len(node) {
+				return nil
+			}
+			v = node[idx]
+		default:
+			return nil
+		}
+	}
+	return normalize(v)
+}
+
+type orNode struct{ left, right node }
+
+func (n orNode) eval(env map[string]any) any {
+	return truthy(n.left.eval(env)) || truthy(n.right.eval(env))
+}
+
+type andNode struct{ left, right node }
+
+func (n andNode) eval(env map[string]any) any {
+	return truthy(n.left.eval(env)) && truthy(n.right.eval(env))
+}
+
+type notNode struct{ inner node }
+
+func (n notNode) eval(env map[string]any) any {
+	return !truthy(n.inner.eval(env))
+}
+
+type cmpNode struct {
+	op          string
+	left, right node
+	re          *regexp.Regexp
+}
+
+func (n cmpNode) eval(env map[string]any) any {
+	left, right := n.left.eval(env), n.right.eval(env)
+	switch n.op {
+	case "!=":
+		return !anyOf(left, func(v any) bool { return equal(v, right) })
+	case "!~":
+		return !anyOf(left, func(v any) bool { return n.re.MatchString(toString(v)) })
+	case "=~":
+		return anyOf(left, func(v any) bool { return n.re.MatchString(toString(v)) })
+	case "in":
+		items, _ := right.([]any)
+		return anyOf(left, func(v any) bool {
+			for _, item := range items {
+				if equal(v, item) {
+					return true
+				}
+			}
+			return false
+		})
+	case "==":
+		return anyOf(left, func(v any) bool { return equal(v, right) })
+	}
+	return anyOf(left, func(v any) bool {
+		c, ok := compare(v, right)
+		if !ok {
+			return false
+		}
+		switch n.op {
+		case "<":
+			return c < 0
+		case "<=":
+			return c <= 0
+		case ">":
+			return c > 0
+		default:
+			return c >= 0
+		}
+	})
+}
+
+// anyOf applies f to each element of a list value, or to a scalar value itself.
+func anyOf(v any, f func(any) bool) bool {
+	if list, ok := v.([]any); ok {
+		for _, item := range list {
+			if f(item) {
+				return true
+			}
+		}
+		return false
+	}
+	return f(v)
+}
+
+// normalize converts env values to the evaluator's types: string, float64, bool,
+// time.Duration, []any, map[string]any or nil.
+func normalize(v any) any {
+	switch x := v.(type) {
+	case int:
+		return float64(x)
+	case int64:
+		return float64(x)
+	case float32:
+		return float64(x)
+	case []string:
+		out := make([]any, len(x))
+		for i, s := range x {
+			out[i] = s
+		}
+		return out
+	case map[string]string:
+		out := make(map[string]any, len(x))
+		for k, s := range x {
+			out[k] = s
+		}
+		return out
+	}
+	return v
+}
+
+func truthy(v any) bool {
+	switch x := v.(type) {
+	case nil:
+		return false
+	case bool:
+		return x
+	case string:
+		return x != "" && x != "false" && x != "0"
+	case float64:
+		return x != 0
+	case time.Duration:
+		return x != 0
+	case []any:
+		return len(x) > 0
+	case map[string]any:
+		return len(x) > 0
+	}
+	return true
+}
+
+func toString(v any) string {
+	switch x := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return x
+	case float64:
+		return strconv.FormatFloat(x, 'f', -1, 64)
+	}
+	return fmt.Sprint(v)
+}
+
+func toNumber(v any) (float64, bool) {
+	switch x := v.(type) {
+	case float64:
+		return x, true
+	case string:
+		f, err := strconv.ParseFloat(strings.TrimSpace(x), 64)
+		return f, err == nil
+	}
+	return 0, false
+}
+
+func equal(a, b any) bool {
+	if _, isNum := a.(float64); isNum {
+		if y, ok := toNumber(b); ok {
+			return a.(float64) == y
+		}
+	}
+	if _, isNum := b.(float64); isNum {
+		if x, ok := toNumber(a); ok {
+			return x == b.(float64)
+		}
+	}
+	if da, ok := a.(time.Duration); ok {
+		db, ok := b.(time.Duration)
+		return ok && da == db
+	}
+	if ba, ok := a.(bool); ok {
+		return toString(b) == strconv.FormatBool(ba)
+	}
+	if bb, ok := b.(bool); ok {
+		return toString(a) == strconv.FormatBool(bb)
+	}
+	return toString(a) == toString(b)
+}
+
+// compare orders durations, numbers (including numeric strings) and strings.
+func compare(a, b any) (int, bool) {
+	if da, ok := a.(time.Duration); ok {
+		db, ok := b.(time.Duration)
+		if !ok {
+			return 0, false
+		}
+		return cmp3(float64(da), float64(db)), true
+	}
+	if _, ok := b.(time.Duration); ok {
+		return 0, false
+	}
+	x, okA := toNumber(a)
+	y, okB := toNumber(b)
+	if okA && okB {
+		return cmp3(x, y), true
+	}
+	sa, isStrA := a.(string)
+	sb, isStrB := b.(string)
+	if isStrA && isStrB && !okA && !okB {
+		return strings.Compare(sa, sb), true
+	}
+	return 0, false
+}
+
+func cmp3(x, y float64) int {
+	switch {
+	case x < y:
+		return -1
+	case x > y:
+		return 1
+	}
+	return 0
+}
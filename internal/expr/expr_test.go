@@ -0,0 +1,147 @@
+package expr
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMatch(t *testing.T) {
+	env := map[string]any{
+		"level":  "error",
+		"env":    "prod",
+		"muted":  "false",
+		"count":  "3",
+		"labels": []string{"urgent", "backend"},
+		"bot":    true,
+		"pr":     42,
+		"due":    6 * time.Hour,
+		"payload": map[string]any{
+			"user":  map[string]any{"login": "dependabot[bot]"},
+			"items": []any{map[string]any{"name": "first"}},
+		},
+	}
+	tests := []struct {
+		cond string
+		want bool
+	}{
+		{"", true},
+		{"level == 'error'", true},
+		{`level == "warning"`, false},
+		{"level == error", true},
+		{"level != error", false},
+		{"level == 'error' && env != 'prod'", false},
+		{"level == 'warning' || env == 'prod'", true},
+		{"level == 'warning' || env == 'prod' && count", true},
+		{"(level == 'warning' || env == 'prod') && !count", false},
+		{"!(level == 'warning')", true},
+		{"!!muted", false},
+		{"count", true},
+		{"muted", false},
+		{"missing", false},
+		{"missing == ''", true},
+		{"count > 2 && count <= 3", true},
+		{"count == 3", true},
+		{"count < 2", false},
+		{"pr == 42", true},
+		{"pr >= 50", false},
+		{"pr > -1", true},
+		{"bot", true},
+		{"bot == true", true},
+		{"bot == false", false},
+		{"labels == 'urgent'", true},
+		{"labels != 'urgent'", false},
+		{"labels != 'blocked'", true},
+		{"labels =~ '^back'", true},
+		{"labels !~ '^back'", false},
+		{"level =~ '(?i)ERR'", true},
+		{"level in ['warning', 'error']", true},
+		{"level in []", false},
+		{"labels in ['blocked', 'backend']", true},
+		{"due < 24h", true},
+		{"due < 1h30m", false},
+		{"due > 1d", false},
+		{"missing < 24h", false},
+		{"due < 3", false},
+		{"env > 'dev' && env < 'qa'", true},
+		{"payload.user.login == 'dependabot[bot]'", true},
+		{"payload.user.login =~ '\\[bot\\]$'", true},
+		{"payload.items[0].name == 'first'", true},
+		{"payload.items.0.name == 'first'", true},
+		{"payload.items[5].name == 'first'", false},
+		{"payload.nothing.here", false},
+		{"level == payload.user.login", false},
+	}
+	for _, tt := range tests {
+		got, err := Match(tt.cond, env)
+		if err != nil {
+			t.Errorf("Match(%q) error: %v", tt.cond, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("Match(%q) = %v, want %v", tt.cond, got, tt.want)
+		}
+	}
+}
+
+func TestCompile_Errors(t *testing.T) {
+	for _, cond := range []string{
+		"level == 'error",
+		"(level == 'error'",
+		"level == 'error')",
+		"level ==",
+		"&& level",
+		"level =~ '('",
+		"level =~ other",
+		"level # 'x'",
+		"due < 5x",
+		"level in ['a' 'b']",
+		"payload.",
+		"a.b[c]",
+	} {
+		if _, err := Compile(cond); err == nil {
+			t.Errorf("Compile(%q) should fail", cond)
+		}
+	}
+}
+
+func TestMatch_CachesPrograms(t *testing.T) {
+	const cond = "level == 'cached'"
+	for i := 0; i < 2; i++ {
+		if ok, err := Match(cond, map[string]any{"level": "cached"}); err != nil || !ok {
+			t.Fatalf("Match = %v, %v", ok, err)
+		}
+	}
+	if _, ok := cache.Load(cond); !ok {
+		t.Error("program should be cached")
+	}
+	if _, err := Match("level ==", nil); err == nil {
+		t.Error("invalid condition should return an error")
+	}
+}
+
+func TestMatch_Now(t *testing.T) {
+	defer func(orig func() time.Time) { clock = orig }(clock)
+	clock = func() time.Time { return time.Date(2026, 3, 4, 10, 30, 0, 0, time.UTC) } // a Wednesday
+
+	workingHours := "now.weekday in ['mon', 'tue', 'wed', 'thu', 'fri'] && now.time >= '09:00' && now.time < '18:00'"
+	tests := []struct {
+		cond string
+		env  map[string]any
+		want bool
+	}{
+		{workingHours, nil, true},
+		{"now.hour == 10", nil, true},
+		{"now.weekday == 'sat'", nil, false},
+		{"now == 'fixed'", map[string]any{"now": "fixed"}, true}, // an env field wins
+	}
+	for _, tt := range tests {
+		if got, err := Match(tt.cond, tt.env); err != nil || got != tt.want {
+			t.Errorf("Match(%q) = %v, %v; want %v", tt.cond, got, err, tt.want)
+		}
+	}
+
+	clock = func() time.Time { return time.Date(2026, 3, 7, 10, 30, 0, 0, time.UTC) } // Saturday
+	if ok, _ := Match(workingHours, nil); ok {
+		t.Error("expected working hours not to match on a Saturday")
+	}
+}
@@ -0,0 +1,83 @@
+// Package extract turns attachments such as scanned invoices and PDFs into
+// plain text through an external extraction service, e.g. an Apache Tika
+// server with Tesseract for OCR.
+package extract
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+)
+
+// DefaultMimeTypes are the attachment types sent for extraction by default.
+var DefaultMimeTypes = []string{"application/pdf", "image/*"}
+
+// maxResponse caps the text read back from the service.
+const maxResponse = 1 << 20
+
+// Client calls the extraction service: the attachment is PUT to URL with its
+// MIME type and filename, and the service answers with plain text. That is
+// the protocol of Tika's /tika endpoint.
+type Client struct {
+	URL     string
+	Headers map[string]string // e.g. Authorization for a hosted service
+	HTTP    *http.Client
+}
+
+func NewClient(url string, headers map[string]string, timeout time.Duration) *Client {
+	return &Client{URL: url, Headers: headers, HTTP: &http.Client{Timeout: timeout}}
+}
+
+// Text returns the text the service extracted from data.
+func (c *Client) Text(ctx context.Context, filename, mimeType string, data []byte) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, c.URL, bytes.NewReader(data))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", firstNonEmpty(mimeType, "application/octet-stream"))
+	req.Header.Set("Accept", "text/plain")
+	if filename != "" {
+		req.Header.Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": filename}))
+	}
+	for k, v := range c.Headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := c.HTTP.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("extract %s: %w", filename, err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, maxResponse))
+	if resp.StatusCode >= 300 {
+		return "", fmt.Errorf("extract %s: status %d: %s", filename, resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	return strings.TrimSpace(string(body)), nil
+}
+
+// Supported reports whether mimeType matches one of patterns, which may use
+// globs like "image/*".
+func Supported(patterns []string, mimeType string) bool {
+	mimeType = strings.ToLower(mimeType)
+	for _, p := range patterns {
+		if ok, _ := path.Match(strings.ToLower(p), mimeType); ok {
+			return true
+		}
+	}
+	return false
+}
+
+func firstNonEmpty(vals ...string) string {
+	for _, v := range vals {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
@@ -0,0 +1,67 @@
+package extract
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestClient_Text(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPut || r.Header.Get("Accept") != "text/plain" {
+			t.Errorf("unexpected request %s accept=%q", r.Method, r.Header.Get("Accept"))
+		}
+		if r.Header.Get("Authorization") != "Bearer k" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		if string(body) == "broken" {
+			http.Error(w, "cannot parse", http.StatusUnprocessableEntity)
+			return
+		}
+		w.Write([]byte("\n  " + r.Header.Get("Content-Type") + " " + r.Header.Get("Content-Disposition") + ": " + string(body) + "\n"))
+	}))
+	defer srv.Close()
+
+	c := NewClient(srv.URL, map[string]string{"Authorization": "Bearer k"}, 5*time.Second)
+	ctx := context.Background()
+
+	got, err := c.Text(ctx, "invoice.pdf", "application/pdf", []byte("%PDF"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != `application/pdf attachment; filename=invoice.pdf: %PDF` {
+		t.Errorf("unexpected text %q", got)
+	}
+	if _, err := c.Text(ctx, "bad.pdf", "application/pdf", []byte("broken")); err == nil || !strings.Contains(err.Error(), "422") {
+		t.Errorf("expected status error, got %v", err)
+	}
+
+	c.Headers = nil
+	if _, err := c.Text(ctx, "a.png", "image/png", []byte("x")); err == nil {
+		t.Error("expected unauthorized request to fail")
+	}
+}
+
+func TestSupported(t *testing.T) {
+	tests := []struct {
+		mime string
+		want bool
+	}{
+		{"application/pdf", true},
+		{"image/png", true},
+		{"IMAGE/JPEG", true},
+		{"text/plain", false},
+		{"application/zip", false},
+	}
+	for _, tt := range tests {
+		if got := Supported(DefaultMimeTypes, tt.mime); got != tt.want {
+			t.Errorf("Supported(%q) = %v, want %v", tt.mime, got, tt.want)
+		}
+	}
+}
@@ -8,6 +8,7 @@ import (
 	"log"
 	"net/http"
 	"strings"
+	"text/template"
 	"time"
 )
 
@@ -27,6 +28,15 @@ type Client struct {
 	AgentID string
 	Model   string
 	HTTP    *http.Client
+
+	// SigningSecret, when set, signs every request and adds job metadata
+	// (see Sign), so the gateway can tell relay jobs from other token holders.
+	SigningSecret string
+
+	// JobName renders job names (see ParseJobName); nil means DefaultJobName.
+	JobName *template.Template
+	// Tags are added to every job, e.g. a tenant; see JobSpec for precedence.
+	Tags map[string]string
 }
 
 func NewClient(url, token, agentID, model string) *Client {
@@ -44,6 +54,13 @@ func (c *Client) CreateOneShotJob(name, message string, timeoutSeconds, delaySec
 }
 
 func (c *Client) CreateOneShotJobForAgent(name, message, agentID string, timeoutSeconds, delaySeconds int) error {
+	return c.CreateJob(JobSpec{Name: name, Message: message, AgentID: agentID, TimeoutSeconds: timeoutSeconds, DelaySeconds: delaySeconds})
+}
+
+// CreateJob creates a one-shot cron job tagged with its origin.
+func (c *Client) CreateJob(spec JobSpec) error {
+	name, message, agentID := spec.Name, spec.Message, spec.AgentID
+	timeoutSeconds, delaySeconds := spec.TimeoutSeconds, spec.DelaySeconds
 	if c.URL == "" || c.Token == "" {
 		log.Printf("Gateway not configured, skipping job creation for: %s", name)
 		return nil
@@ -53,9 +70,10 @@ func (c *Client) CreateOneShotJobForAgent(name, message, agentID string, timeout
 		agentID = c.AgentID
 	}
 
+	tags := jobTags(c.Tags, spec)
 	fireAt := time.Now().Add(time.Duration(delaySeconds) * time.Second)
 	job := map[string]interface{}{
-		"name":          fmt.Sprintf("webhook: %s", name),
+		"name":          renderJobName(c.JobName, spec, agentID, tags),
 		"sessionTarget": "isolated",
 		"enabled":       true,
 		"schedule": map[string]interface{}{
@@ -78,6 +96,9 @@ func (c *Client) CreateOneShotJobForAgent(name, message, agentID string, timeout
 	if agentID != "" {
 		job["agentId"] = agentID
 	}
+	if tags != nil {
+		job["tags"] = tags
+	}
 
 	payload := map[string]interface{}{
 		"action": "add",
@@ -91,6 +112,9 @@ func (c *Client) CreateOneShotJobForAgent(name, message, agentID string, timeout
 		"args":       json.RawMessage(body),
 		"sessionKey": fmt.Sprintf("agent:%s:main", agentID),
 	}
+	if c.SigningSecret != "" {
+		reqBody["meta"] = newJobMeta(spec.ID, name, agentID, tags, time.Now())
+	}
 	reqJSON, _ := json.Marshal(reqBody)
 
 	var lastErr error
@@ -123,6 +147,9 @@ func (c *Client) doRequest(reqJSON []byte, agentID, name string) error {
 	}
 	req.Header.Set("Content-Type", "application/json")
 	req.Header.Set("Authorization", "Bearer "+c.Token)
+	if c.SigningSecret != "" {
+		signRequest(req, c.SigningSecret, reqJSON, time.Now())
+	}
 
 	resp, err := c.HTTP.Do(req)
 	if err != nil {
@@ -0,0 +1,136 @@
+package gateway
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"maps"
+	"strings"
+	"text/template"
+
+	"github.com/katalabut/openclaw-relay/internal/config"
+	"github.com/katalabut/openclaw-relay/internal/schedule"
+)
+
+// DefaultJobName is the job name template used when gateway.job_name is unset.
+const DefaultJobName = "webhook: {{.Name}}"
+
+// JobSpec is a one-shot job together with where it came from. Source, Rule
+// and Tags become the job's tags, so dashboards can group agent work by origin.
+type JobSpec struct {
+	// ID identifies the job in the event history and is sent as the job_id
+	// of signed requests; empty gets a random one per request.
+	ID             string
+	Name           string
+	Message        string
+	AgentID        string
+	TimeoutSeconds int
+	DelaySeconds   int
+
+	Source string            // e.g. "github", "gmail"
+	Rule   string            // rule name, or its place in the config, e.g. "trello.rules[2]"
+	Tags   map[string]string // per-rule tags; override the client's static tags
+	// Account is the Google account a gmail, calendar or drive job is about,
+	// so off-boarding the account can drop its dead letters.
+	Account string
+
+	Payload []byte // the webhook delivery behind the job, kept if the job fails
+}
+
+// JobCreator is implemented by gateway clients that keep a job's origin.
+type JobCreator interface {
+	CreateJob(spec JobSpec) error
+}
+
+// CreateJob sends spec through gw, keeping its origin when gw supports it
+// and falling back to CreateOneShotJobForAgent otherwise.
+func CreateJob(gw GatewayClient, spec JobSpec) error {
+	if jc, ok := gw.(JobCreator); ok {
+		return jc.CreateJob(spec)
+	}
+	return gw.CreateOneShotJobForAgent(spec.Name, spec.Message, spec.AgentID, spec.TimeoutSeconds, spec.DelaySeconds)
+}
+
+// jobTags merges the tags of a job: source and rule first, then the static
+// tags, then the per-rule ones. It returns nil when there are none.
+func jobTags(static map[string]string, spec JobSpec) map[string]string {
+	tags := map[string]string{}
+	if spec.Source != "" {
+		tags["source"] = spec.Source
+	}
+	if spec.Rule != "" {
+		tags["rule"] = spec.Rule
+	}
+	maps.Copy(tags, static)
+	maps.Copy(tags, spec.Tags)
+	if len(tags) == 0 {
+		return nil
+	}
+	return tags
+}
+
+// ParseJobName parses a gateway.job_name template. It sees .Name (the event
+// name), .Source, .Rule, .Agent and .Tags.
+func ParseJobName(text string) (*template.Template, error) {
+	if text == "" {
+		text = DefaultJobName
+	}
+	return template.New("job_name").Option("missingkey=zero").Parse(text)
+}
+
+var defaultJobName = template.Must(ParseJobName(""))
+
+func renderJobName(tmpl *template.Template, spec JobSpec, agentID string, tags map[string]string) string {
+	if tmpl == nil {
+		tmpl = defaultJobName
+	}
+	var buf bytes.Buffer
+	err := tmpl.Execute(&buf, map[string]any{
+		"Name":   spec.Name,
+		"Source": spec.Source,
+		"Rule":   spec.Rule,
+		"Agent":  agentID,
+		"Tags":   tags,
+	})
+	if err != nil || buf.Len() == 0 {
+		return fmt.Sprintf("webhook: %s", spec.Name)
+	}
+	return buf.String()
+}
+
+// ActionJob builds the job of a polling source's rule action (Drive,
+// Calendar): the action's message template, tmpl when it has none, rendered
+// with data, and its agent, timeout, tags and delay, or schedule resolved in
+// tz unless the action names a zone.
+func ActionJob(source, rule, name string, a config.RuleAction, tmpl string, data any, tz string) JobSpec {
+	if a.MessageTemplate != "" {
+		tmpl = a.MessageTemplate
+	}
+	return JobSpec{
+		Name:           name,
+		Message:        RenderMessage(source, tmpl, data),
+		AgentID:        a.AgentID,
+		TimeoutSeconds: a.ResolvedTimeout(),
+		DelaySeconds:   schedule.ActionDelay(a.Schedule, a.Timezone, tz, a.ResolvedDelay()),
+		Source:         source,
+		Rule:           rule,
+		Tags:           a.Tags,
+	}
+}
+
+// RenderMessage renders a rule's message template with the event's data. A
+// template that fails to parse or execute is logged under source and sent as
+// written, so a typo still reaches the agent.
+func RenderMessage(source, tmpl string, data any) string {
+	t, err := template.New(source).Parse(tmpl)
+	if err != nil {
+		log.Printf("%s: message template parse error: %v", source, err)
+		return tmpl
+	}
+	var buf strings.Builder
+	if err := t.Execute(&buf, data); err != nil {
+		log.Printf("%s: message template exec error: %v", source, err)
+		return tmpl
+	}
+	return buf.String()
+}
@@ -0,0 +1,130 @@
+package gateway
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/katalabut/openclaw-relay/internal/config"
+)
+
+func TestCreateJob_NameAndTags(t *testing.T) {
+	var job struct {
+		Name string            `json:"name"`
+		Tags map[string]string `json:"tags"`
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		var req struct {
+			Args struct {
+				Job json.RawMessage `json:"job"`
+			} `json:"args"`
+		}
+		json.Unmarshal(body, &req)
+		json.Unmarshal(req.Args.Job, &job)
+	}))
+	defer srv.Close()
+
+	c := NewClient(srv.URL, "tok", "main", "")
+	tmpl, err := ParseJobName("{{.Tags.tenant}}/{{.Source}}: {{.Name}}")
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.JobName = tmpl
+	c.Tags = map[string]string{"tenant": "acme", "env": "prod"}
+
+	err = CreateJob(c, JobSpec{
+		Name: "PR #1", Message: "msg", TimeoutSeconds: 120,
+		Source: "github", Rule: "review",
+		Tags: map[string]string{"env": "staging"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if job.Name != "acme/github: PR #1" {
+		t.Errorf("unexpected name %q", job.Name)
+	}
+	want := map[string]string{"source": "github", "rule": "review", "tenant": "acme", "env": "staging"}
+	if !reflect.DeepEqual(job.Tags, want) {
+		t.Errorf("tags = %v, want %v", job.Tags, want)
+	}
+
+	// Without a template or tags the job looks as it always did
+	c.JobName, c.Tags = nil, nil
+	job.Name, job.Tags = "", nil
+	c.CreateOneShotJob("test", "msg", 120, 2)
+	if job.Name != "webhook: test" || job.Tags != nil {
+		t.Errorf("unexpected default job %+v", job)
+	}
+}
+
+func TestParseJobName(t *testing.T) {
+	if _, err := ParseJobName("{{.Name"); err == nil {
+		t.Error("expected parse error")
+	}
+	tmpl, err := ParseJobName("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := renderJobName(tmpl, JobSpec{Name: "x"}, "", nil); got != "webhook: x" {
+		t.Errorf("default name = %q", got)
+	}
+}
+
+type plainGateway struct{ name string }
+
+func (g *plainGateway) CreateOneShotJob(name, message string, timeoutSeconds, delaySeconds int) error {
+	return g.CreateOneShotJobForAgent(name, message, "", timeoutSeconds, delaySeconds)
+}
+
+func (g *plainGateway) CreateOneShotJobForAgent(name, message, agentID string, timeoutSeconds, delaySeconds int) error {
+	g.name = name
+	return nil
+}
+
+func TestCreateJob_Fallback(t *testing.T) {
+	g := &plainGateway{}
+	CreateJob(g, JobSpec{Name: "job", Source: "slack"})
+	if g.name != "job" {
+		t.Errorf("expected fallback to CreateOneShotJobForAgent, got %q", g.name)
+	}
+
+	s := NewSink()
+	CreateJob(s, JobSpec{Name: "job", Source: "slack", Tags: map[string]string{"team": "core"}})
+	if tags := s.Last().Tags; tags["source"] != "slack" || tags["team"] != "core" {
+		t.Errorf("unexpected sink tags %v", tags)
+	}
+}
+
+func TestRenderMessage(t *testing.T) {
+	data := map[string]any{"Title": "Deploy failed"}
+	if got := RenderMessage("sentry", "Issue: {{.Title}}", data); got != "Issue: Deploy failed" {
+		t.Errorf("got %q", got)
+	}
+	// Broken templates are sent as written
+	for _, tmpl := range []string{"Issue: {{.Title", "Issue: {{.Title.Name}}"} {
+		if got := RenderMessage("sentry", tmpl, data); got != tmpl {
+			t.Errorf("%q: got %q", tmpl, got)
+		}
+	}
+}
+
+func TestActionJob(t *testing.T) {
+	a := config.RuleAction{AgentID: "ops", Tags: map[string]string{"team": "infra"}}
+	job := ActionJob("drive", "contracts", "drive: Q3.pdf", a, "File {{.Name}}", map[string]any{"Name": "Q3.pdf"}, "")
+	if job.Message != "File Q3.pdf" || job.AgentID != "ops" || job.TimeoutSeconds != 120 || job.DelaySeconds != 2 ||
+		job.Source != "drive" || job.Rule != "contracts" || job.Tags["team"] != "infra" {
+		t.Errorf("default job = %+v", job)
+	}
+	a = config.RuleAction{MessageTemplate: "Own {{.Name}}", Timeout: 30, Delay: 30}
+	if job := ActionJob("calendar", "", "x", a, "File {{.Name}}", map[string]any{"Name": "standup"}, "UTC"); job.Message != "Own standup" || job.TimeoutSeconds != 30 || job.DelaySeconds != 30 {
+		t.Errorf("action job = %+v", job)
+	}
+	a = config.RuleAction{Schedule: "in 2h"}
+	if job := ActionJob("drive", "", "x", a, "", nil, "Europe/Berlin"); job.DelaySeconds < 7100 || job.DelaySeconds > 7200 {
+		t.Errorf("scheduled delay = %d", job.DelaySeconds)
+	}
+}
@@ -0,0 +1,99 @@
+package gateway
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSignedJob(t *testing.T) {
+	var header http.Header
+	var body []byte
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header = r.Header.Clone()
+		body, _ = io.ReadAll(r.Body)
+		w.Write([]byte(`{"ok":true}`))
+	}))
+	defer srv.Close()
+
+	c := NewClient(srv.URL, "tok", "agent1", "")
+	c.SigningSecret = "shh"
+	if err := c.CreateOneShotJobForAgent("github CI failed", "hello", "", 120, 2); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := Verify("shh", header, body, time.Now(), time.Minute); err != nil {
+		t.Fatalf("verify: %v", err)
+	}
+	var req struct {
+		Meta JobMeta `json:"meta"`
+	}
+	if err := json.Unmarshal(body, &req); err != nil {
+		t.Fatal(err)
+	}
+	if req.Meta.Origin != "openclaw-relay" || req.Meta.Event != "github CI failed" || req.Meta.Agent != "agent1" || len(req.Meta.JobID) != 24 {
+		t.Errorf("unexpected meta %+v", req.Meta)
+	}
+
+	if err := Verify("other", header, body, time.Now(), time.Minute); err == nil || !strings.Contains(err.Error(), "signature mismatch") {
+		t.Errorf("expected mismatch with wrong secret, got %v", err)
+	}
+	tampered := []byte(strings.Replace(string(body), "hello", "rm -rf", 1))
+	if err := Verify("shh", header, tampered, time.Now(), time.Minute); err == nil {
+		t.Error("expected tampered body rejected")
+	}
+	if err := Verify("shh", header, body, time.Now().Add(time.Hour), time.Minute); err == nil || !strings.Contains(err.Error(), "outside") {
+		t.Errorf("expected stale timestamp rejected, got %v", err)
+	}
+	if err := Verify("shh", http.Header{}, body, time.Now(), time.Minute); err == nil {
+		t.Error("expected missing timestamp rejected")
+	}
+}
+
+func TestSignedJob_ID(t *testing.T) {
+	var body []byte
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ = io.ReadAll(r.Body)
+	}))
+	defer srv.Close()
+
+	c := NewClient(srv.URL, "tok", "agent1", "")
+	c.SigningSecret = "shh"
+	if err := c.CreateJob(JobSpec{ID: "job-1", Name: "trello card moved", Message: "hello"}); err != nil {
+		t.Fatal(err)
+	}
+	var req struct {
+		Meta JobMeta `json:"meta"`
+	}
+	json.Unmarshal(body, &req)
+	if req.Meta.JobID != "job-1" {
+		t.Errorf("job_id = %q, want the spec's ID", req.Meta.JobID)
+	}
+}
+
+func TestUnsignedJob(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if r.Header.Get(SignatureHeader) != "" || strings.Contains(string(body), `"meta"`) {
+			t.Error("expected no signature or meta without a signing secret")
+		}
+	}))
+	defer srv.Close()
+
+	if err := NewClient(srv.URL, "tok", "agent1", "").CreateOneShotJob("test", "hello", 120, 2); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSign(t *testing.T) {
+	// Fixed vector so gateway-side implementations can check theirs
+	got := Sign("secret", time.Unix(1700000000, 0), []byte(`{"tool":"cron"}`))
+	want := "sha256=dbf728cf7a38a6e7c97276ab4b108e10f2f74bbcc7877b54017bd353c2044826"
+	if got != want {
+		t.Errorf("Sign = %s, want %s", got, want)
+	}
+}
@@ -0,0 +1,66 @@
+package gateway
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// Job is a one-shot job captured by a Sink.
+type Job struct {
+	Name           string `json:"name"`
+	Message        string `json:"message"`
+	AgentID        string `json:"agent_id,omitempty"`
+	TimeoutSeconds int    `json:"timeout_seconds"`
+	DelaySeconds   int    `json:"delay_seconds"`
+
+	Tags map[string]string `json:"tags,omitempty"`
+}
+
+// Sink is a GatewayClient that records jobs in memory instead of calling the gateway.
+// It is used by the in-memory mode for load tests and benchmarks.
+type Sink struct {
+	count atomic.Int64
+	mu    sync.Mutex
+	last  *Job
+}
+
+// NewSink creates an empty sink.
+func NewSink() *Sink {
+	return &Sink{}
+}
+
+func (s *Sink) CreateOneShotJob(name, message string, timeoutSeconds, delaySeconds int) error {
+	return s.CreateOneShotJobForAgent(name, message, "", timeoutSeconds, delaySeconds)
+}
+
+func (s *Sink) CreateOneShotJobForAgent(name, message, agentID string, timeoutSeconds, delaySeconds int) error {
+	return s.CreateJob(JobSpec{Name: name, Message: message, AgentID: agentID, TimeoutSeconds: timeoutSeconds, DelaySeconds: delaySeconds})
+}
+
+func (s *Sink) CreateJob(spec JobSpec) error {
+	s.count.Add(1)
+	job := &Job{
+		Name:           spec.Name,
+		Message:        spec.Message,
+		AgentID:        spec.AgentID,
+		TimeoutSeconds: spec.TimeoutSeconds,
+		DelaySeconds:   spec.DelaySeconds,
+		Tags:           jobTags(nil, spec),
+	}
+	s.mu.Lock()
+	s.last = job
+	s.mu.Unlock()
+	return nil
+}
+
+// Count returns the number of jobs received.
+func (s *Sink) Count() int64 {
+	return s.count.Load()
+}
+
+// Last returns the most recent job, or nil.
+func (s *Sink) Last() *Job {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.last
+}
@@ -0,0 +1,32 @@
+package gateway
+
+import (
+	"sync"
+	"testing"
+)
+
+func TestSink(t *testing.T) {
+	s := NewSink()
+	if s.Last() != nil || s.Count() != 0 {
+		t.Fatal("expected empty sink")
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			s.CreateOneShotJob("job", "msg", 120, 2)
+		}()
+	}
+	wg.Wait()
+	s.CreateOneShotJobForAgent("last", "hello", "work", 30, 0)
+
+	if s.Count() != 51 {
+		t.Errorf("expected 51 jobs, got %d", s.Count())
+	}
+	last := s.Last()
+	if last.Name != "last" || last.AgentID != "work" || last.TimeoutSeconds != 30 {
+		t.Errorf("unexpected last job: %+v", last)
+	}
+}
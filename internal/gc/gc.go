@@ -0,0 +1,174 @@
+// Package gc cleans up the relay's data directory: state left behind by
+// removed Gmail accounts, dead letters given up on, event records past
+// retention and temp files from interrupted writes. GET /api/gc reports what
+// a run would remove; POST /api/gc runs it.
+package gc
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"maps"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// tempFileAge is how old a temp file must be before it counts as left
+// behind by a crash rather than a write in progress.
+const tempFileAge = time.Hour
+
+// CollectFunc removes one kind of stale state, or with dryRun only finds it,
+// and returns what it removed.
+type CollectFunc func(now time.Time, dryRun bool) ([]string, error)
+
+// Report is the outcome of a run.
+type Report struct {
+	DryRun  bool                `json:"dry_run"`
+	RanAt   time.Time           `json:"ran_at"`
+	Removed map[string][]string `json:"removed"` // by kind, e.g. "dead_letters"
+	Total   int                 `json:"total"`
+	Errors  map[string]string   `json:"errors,omitempty"`
+}
+
+// GC runs the collectors added to it, periodically and on request.
+type GC struct {
+	interval time.Duration
+
+	mu         sync.Mutex // serializes runs
+	kinds      []string
+	collectors map[string]CollectFunc
+	last       *Report
+	now        func() time.Time
+}
+
+// New returns a GC that runs every interval once started; a zero interval
+// only runs on POST /api/gc.
+func New(interval time.Duration) *GC {
+	return &GC{interval: interval, collectors: map[string]CollectFunc{}, now: time.Now}
+}
+
+// Add registers the collector for kind.
+func (g *GC) Add(kind string, fn CollectFunc) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if _, ok := g.collectors[kind]; !ok {
+		g.kinds = append(g.kinds, kind)
+	}
+	g.collectors[kind] = fn
+}
+
+// Run runs every collector. A failing collector is reported and doesn't stop
+// the others.
+func (g *GC) Run(dryRun bool) Report {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	now := g.now()
+	r := Report{DryRun: dryRun, RanAt: now.UTC(), Removed: map[string][]string{}}
+	for _, kind := range g.kinds {
+		removed, err := g.collectors[kind](now, dryRun)
+		if err != nil {
+			if r.Errors == nil {
+				r.Errors = map[string]string{}
+			}
+			r.Errors[kind] = err.Error()
+		}
+		if len(removed) > 0 {
+			r.Removed[kind] = removed
+			r.Total += len(removed)
+		}
+	}
+	if !dryRun {
+		g.last = &r
+	}
+	return r
+}
+
+// Start runs the collectors every interval until ctx is done.
+func (g *GC) Start(ctx context.Context) {
+	if g.interval <= 0 {
+		return
+	}
+	go func() {
+		ticker := time.NewTicker(g.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				g.logRun(g.Run(false))
+			}
+		}
+	}()
+}
+
+func (g *GC) logRun(r Report) {
+	for kind, err := range r.Errors {
+		log.Printf("GC: %s: %v", kind, err)
+	}
+	if r.Total == 0 {
+		return
+	}
+	var parts []string
+	for _, kind := range slices.Sorted(maps.Keys(r.Removed)) {
+		parts = append(parts, fmt.Sprintf("%s %d", kind, len(r.Removed[kind])))
+	}
+	log.Printf("GC: removed %d item(s): %s", r.Total, strings.Join(parts, ", "))
+}
+
+// HandleGC serves /api/gc: GET reports what a run would remove, with the
+// last run's report; POST runs it now.
+func (g *GC) HandleGC(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		report := g.Run(true)
+		g.mu.Lock()
+		last := g.last
+		g.mu.Unlock()
+		writeJSON(w, http.StatusOK, map[string]any{"report": report, "last_run": last})
+	case http.MethodPost:
+		report := g.Run(false)
+		g.logRun(report)
+		writeJSON(w, http.StatusOK, map[string]any{"report": report})
+	default:
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+	}
+}
+
+func writeJSON(w http.ResponseWriter, code int, data any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(data)
+}
+
+// TempFiles collects the dot-prefixed *.tmp files in dir older than an hour,
+// left behind when the relay died between writing a temp file and renaming
+// it into place.
+func TempFiles(dir string) CollectFunc {
+	return func(now time.Time, dryRun bool) ([]string, error) {
+		matches, err := filepath.Glob(filepath.Join(dir, ".*.tmp"))
+		if err != nil {
+			return nil, err
+		}
+		var removed []string
+		for _, path := range matches {
+			info, err := os.Stat(path)
+			if err != nil || now.Sub(info.ModTime()) < tempFileAge {
+				continue
+			}
+			if !dryRun {
+				if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+					return removed, err
+				}
+			}
+			removed = append(removed, filepath.Base(path))
+		}
+		return removed, nil
+	}
+}
@@ -0,0 +1,98 @@
+package gc
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestGC_Run(t *testing.T) {
+	g := New(0)
+	items := []string{"a", "b"}
+	g.Add("things", func(_ time.Time, dryRun bool) ([]string, error) {
+		removed := items
+		if !dryRun {
+			items = nil
+		}
+		return removed, nil
+	})
+	g.Add("broken", func(time.Time, bool) ([]string, error) {
+		return nil, errors.New("disk on fire")
+	})
+
+	r := g.Run(true)
+	if !r.DryRun || r.Total != 2 || len(items) != 2 || r.Errors["broken"] != "disk on fire" {
+		t.Fatalf("dry run: %+v", r)
+	}
+	if r = g.Run(false); r.Total != 2 || len(r.Removed["things"]) != 2 || items != nil {
+		t.Fatalf("run: %+v", r)
+	}
+	if r = g.Run(false); r.Total != 0 || len(r.Removed) != 0 {
+		t.Errorf("expected nothing left, got %+v", r)
+	}
+}
+
+func TestGC_HandleGC(t *testing.T) {
+	g := New(0)
+	n := 3
+	g.Add("things", func(_ time.Time, dryRun bool) ([]string, error) {
+		removed := make([]string, n)
+		if !dryRun {
+			n = 0
+		}
+		return removed, nil
+	})
+
+	do := func(method string) (int, map[string]*Report) {
+		rec := httptest.NewRecorder()
+		g.HandleGC(rec, httptest.NewRequest(method, "/api/gc", nil))
+		var body map[string]*Report
+		json.Unmarshal(rec.Body.Bytes(), &body)
+		return rec.Code, body
+	}
+	code, body := do("GET")
+	if code != http.StatusOK || !body["report"].DryRun || body["report"].Total != 3 || body["last_run"] != nil || n != 3 {
+		t.Fatalf("GET: %d %+v", code, body)
+	}
+	if code, body = do("POST"); code != http.StatusOK || body["report"].DryRun || body["report"].Total != 3 || n != 0 {
+		t.Fatalf("POST: %d %+v", code, body)
+	}
+	if _, body = do("GET"); body["report"].Total != 0 || body["last_run"] == nil || body["last_run"].Total != 3 {
+		t.Errorf("GET after a run: %+v", body)
+	}
+	if code, _ = do("DELETE"); code != http.StatusMethodNotAllowed {
+		t.Errorf("DELETE: %d", code)
+	}
+}
+
+func TestTempFiles(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Now()
+	for name, age := range map[string]time.Duration{
+		".gmail-state-1.tmp": 2 * time.Hour,
+		".config-2.tmp":      time.Minute, // still being written
+		"events.json":        2 * time.Hour,
+	} {
+		path := filepath.Join(dir, name)
+		os.WriteFile(path, []byte("{}"), 0600)
+		os.Chtimes(path, now.Add(-age), now.Add(-age))
+	}
+
+	collect := TempFiles(dir)
+	removed, err := collect(now, true)
+	if err != nil || len(removed) != 1 || removed[0] != ".gmail-state-1.tmp" {
+		t.Fatalf("dry run: %v, %v", removed, err)
+	}
+	if _, err := collect(now, false); err != nil {
+		t.Fatal(err)
+	}
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 2 {
+		t.Errorf("expected 2 files left, got %v", entries)
+	}
+}
@@ -0,0 +1,292 @@
+// Package github calls the GitHub REST API as a GitHub App, so the agent can act on
+// pull requests and workflow runs through the relay without holding GitHub credentials.
+package github
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/katalabut/openclaw-relay/internal/config"
+)
+
+// API is the set of GitHub operations exposed to the agent.
+type API interface {
+	PullRequestDiff(ctx context.Context, repo string, number int) (string, error)
+	CreateComment(ctx context.Context, repo string, number int, body string) (*Comment, error)
+	RerunWorkflow(ctx context.Context, repo string, runID int64, failedOnly bool) error
+}
+
+// Comment is a created issue or pull request comment.
+type Comment struct {
+	ID      int64  `json:"id"`
+	HTMLURL string `json:"html_url"`
+}
+
+// Client authenticates as a GitHub App and mints installation tokens on demand.
+type Client struct {
+	appID          int64
+	key            *rsa.PrivateKey
+	installationID int64
+	baseURL        string
+	HTTP           *http.Client
+
+	mu       sync.Mutex
+	tokens   map[int64]installationToken // by installation ID
+	installs map[string]int64            // by lowercase owner/name
+	now      func() time.Time
+}
+
+type installationToken struct {
+	Token     string    `json:"token"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// NewAppClient loads the App's private key and returns a client for it.
+func NewAppClient(cfg config.GitHubAppConfig) (*Client, error) {
+	pemData, err := os.ReadFile(cfg.PrivateKeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("read github app key: %w", err)
+	}
+	key, err := parsePrivateKey(pemData)
+	if err != nil {
+		return nil, err
+	}
+	baseURL := strings.TrimRight(cfg.APIURL, "/")
+	if baseURL == "" {
+		baseURL = "https://api.github.com"
+	}
+	return &Client{
+		appID:          cfg.AppID,
+		key:            key,
+		installationID: cfg.InstallationID,
+		baseURL:        baseURL,
+		HTTP:           &http.Client{Timeout: 30 * time.Second},
+		tokens:         make(map[int64]installationToken),
+		installs:       make(map[string]int64),
+		now:            time.Now,
+	}, nil
+}
+
+func parsePrivateKey(data []byte) (*rsa.PrivateKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("github app key: no PEM block found")
+	}
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("github app key: %w", err)
+	}
+	key, ok := parsed.(*rsa.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("github app key: not an RSA key")
+	}
+	return key, nil
+}
+
+// appJWT returns a short-lived RS256 JWT identifying the App itself.
+func (c *Client) appJWT() (string, error) {
+	now := c.now()
+	header := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"RS256","typ":"JWT"}`))
+	claims, _ := json.Marshal(map[string]int64{
+		"iat": now.Add(-60 * time.Second).Unix(), // allow for clock drift
+		"exp": now.Add(9 * time.Minute).Unix(),
+		"iss": c.appID,
+	})
+	signingInput := header + "." + base64.RawURLEncoding.EncodeToString(claims)
+	sum := sha256.Sum256([]byte(signingInput))
+	sig, err := rsa.SignPKCS1v15(rand.Reader, c.key, crypto.SHA256, sum[:])
+	if err != nil {
+		return "", fmt.Errorf("sign github app jwt: %w", err)
+	}
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(sig), nil
+}
+
+// installationFor returns the installation ID for repo, looking it up once when
+// installation_id is not configured.
+func (c *Client) installationFor(ctx context.Context, repo string) (int64, error) {
+	if c.installationID != 0 {
+		return c.installationID, nil
+	}
+	c.mu.Lock()
+	id, ok := c.installs[strings.ToLower(repo)]
+	c.mu.Unlock()
+	if ok {
+		return id, nil
+	}
+
+	jwt, err := c.appJWT()
+	if err != nil {
+		return 0, err
+	}
+	var inst struct {
+		ID int64 `json:"id"`
+	}
+	if err := c.do(ctx, http.MethodGet, "/repos/"+repo+"/installation", "Bearer "+jwt, nil, "", &inst); err != nil {
+		return 0, fmt.Errorf("find installation for %s: %w", repo, err)
+	}
+	c.mu.Lock()
+	c.installs[strings.ToLower(repo)] = inst.ID
+	c.mu.Unlock()
+	return inst.ID, nil
+}
+
+// token returns a cached installation token, minting a new one shortly before expiry.
+func (c *Client) token(ctx context.Context, repo string) (string, error) {
+	id, err := c.installationFor(ctx, repo)
+	if err != nil {
+		return "", err
+	}
+	c.mu.Lock()
+	tok, ok := c.tokens[id]
+	c.mu.Unlock()
+	if ok && c.now().Before(tok.ExpiresAt.Add(-time.Minute)) {
+		return tok.Token, nil
+	}
+
+	jwt, err := c.appJWT()
+	if err != nil {
+		return "", err
+	}
+	path := fmt.Sprintf("/app/installations/%d/access_tokens", id)
+	if err := c.do(ctx, http.MethodPost, path, "Bearer "+jwt, nil, "", &tok); err != nil {
+		return "", fmt.Errorf("create installation token: %w", err)
+	}
+	c.mu.Lock()
+	c.tokens[id] = tok
+	c.mu.Unlock()
+	return tok.Token, nil
+}
+
+// do sends a request and decodes a JSON response into out (if non-nil).
+func (c *Client) do(ctx context.Context, method, path, auth string, body any, accept string, out any) error {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, reader)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", auth)
+	req.Header.Set("X-GitHub-Api-Version", "2022-11-28")
+	if accept == "" {
+		accept = "application/vnd.github+json"
+	}
+	req.Header.Set("Accept", accept)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.HTTP.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	respBody, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode >= 300 {
+		return &APIError{Status: resp.StatusCode, Body: strings.TrimSpace(string(respBody))}
+	}
+	switch v := out.(type) {
+	case nil:
+	case *string:
+		*v = string(respBody)
+	default:
+		if err := json.Unmarshal(respBody, out); err != nil {
+			return fmt.Errorf("decode %s response: %w", path, err)
+		}
+	}
+	return nil
+}
+
+// APIError is a non-2xx response from GitHub.
+type APIError struct {
+	Status int
+	Body   string
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("github api: status %d: %s", e.Status, e.Body)
+}
+
+func (c *Client) repoCall(ctx context.Context, method, repo, path string, body any, accept string, out any) error {
+	tok, err := c.token(ctx, repo)
+	if err != nil {
+		return err
+	}
+	return c.do(ctx, method, "/repos/"+repo+path, "token "+tok, body, accept, out)
+}
+
+// PullRequestDiff returns the unified diff of a pull request.
+func (c *Client) PullRequestDiff(ctx context.Context, repo string, number int) (string, error) {
+	var diff string
+	err := c.repoCall(ctx, http.MethodGet, repo, fmt.Sprintf("/pulls/%d", number), nil, "application/vnd.github.diff", &diff)
+	return diff, err
+}
+
+// CreateComment posts a comment on an issue or pull request.
+func (c *Client) CreateComment(ctx context.Context, repo string, number int, body string) (*Comment, error) {
+	var out Comment
+	err := c.repoCall(ctx, http.MethodPost, repo, fmt.Sprintf("/issues/%d/comments", number), map[string]string{"body": body}, "", &out)
+	if err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// RerunWorkflow re-runs a workflow run, or only its failed jobs.
+func (c *Client) RerunWorkflow(ctx context.Context, repo string, runID int64, failedOnly bool) error {
+	path := fmt.Sprintf("/actions/runs/%d/rerun", runID)
+	if failedOnly {
+		path = fmt.Sprintf("/actions/runs/%d/rerun-failed-jobs", runID)
+	}
+	return c.repoCall(ctx, http.MethodPost, repo, path, nil, "", nil)
+}
+
+// Artifact is an artifact uploaded by a workflow run.
+type Artifact struct {
+	ID          int64  `json:"id"`
+	Name        string `json:"name"`
+	SizeInBytes int64  `json:"size_in_bytes"`
+	Expired     bool   `json:"expired"`
+	DownloadURL string `json:"archive_download_url"` // zip via the API; needs a token
+}
+
+// WorkflowRunArtifacts lists the unexpired artifacts of a workflow run (the first 100).
+func (c *Client) WorkflowRunArtifacts(ctx context.Context, repo string, runID int64) ([]Artifact, error) {
+	var out struct {
+		Artifacts []Artifact `json:"artifacts"`
+	}
+	if err := c.repoCall(ctx, http.MethodGet, repo, fmt.Sprintf("/actions/runs/%d/artifacts?per_page=100", runID), nil, "", &out); err != nil {
+		return nil, err
+	}
+	var artifacts []Artifact
+	for _, a := range out.Artifacts {
+		if a.Expired {
+			continue
+		}
+		artifacts = append(artifacts, a)
+	}
+	return artifacts, nil
+}
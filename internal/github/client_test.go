@@ -0,0 +1,242 @@
+package github
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/katalabut/openclaw-relay/internal/config"
+)
+
+func writeTestKey(t *testing.T, pkcs8 bool) (string, *rsa.PrivateKey) {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	block := &pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)}
+	if pkcs8 {
+		der, _ := x509.MarshalPKCS8PrivateKey(key)
+		block = &pem.Block{Type: "PRIVATE KEY", Bytes: der}
+	}
+	path := filepath.Join(t.TempDir(), "app.pem")
+	if err := os.WriteFile(path, pem.EncodeToMemory(block), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path, key
+}
+
+// verifyJWT checks the RS256 signature and returns the claims.
+func verifyJWT(t *testing.T, token string, pub *rsa.PublicKey) map[string]int64 {
+	t.Helper()
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		t.Fatalf("malformed jwt %q", token)
+	}
+	sig, _ := base64.RawURLEncoding.DecodeString(parts[2])
+	sum := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	if err := rsa.VerifyPKCS1v15(pub, crypto.SHA256, sum[:], sig); err != nil {
+		t.Fatalf("jwt signature: %v", err)
+	}
+	raw, _ := base64.RawURLEncoding.DecodeString(parts[1])
+	var claims map[string]int64
+	json.Unmarshal(raw, &claims)
+	return claims
+}
+
+func newTestClient(t *testing.T, handler http.HandlerFunc, installationID int64) (*Client, *rsa.PrivateKey) {
+	t.Helper()
+	srv := httptest.NewServer(handler)
+	t.Cleanup(srv.Close)
+	path, key := writeTestKey(t, false)
+	c, err := NewAppClient(config.GitHubAppConfig{AppID: 42, PrivateKeyFile: path, InstallationID: installationID, APIURL: srv.URL + "/"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c, key
+}
+
+func TestNewAppClient_KeyFormats(t *testing.T) {
+	for _, pkcs8 := range []bool{false, true} {
+		path, _ := writeTestKey(t, pkcs8)
+		if _, err := NewAppClient(config.GitHubAppConfig{AppID: 1, PrivateKeyFile: path}); err != nil {
+			t.Errorf("pkcs8=%v: %v", pkcs8, err)
+		}
+	}
+
+	bad := filepath.Join(t.TempDir(), "bad.pem")
+	os.WriteFile(bad, []byte("not a key"), 0600)
+	if _, err := NewAppClient(config.GitHubAppConfig{AppID: 1, PrivateKeyFile: bad}); err == nil {
+		t.Error("expected error for invalid PEM")
+	}
+	if _, err := NewAppClient(config.GitHubAppConfig{AppID: 1, PrivateKeyFile: "/nonexistent"}); err == nil {
+		t.Error("expected error for missing key file")
+	}
+}
+
+func TestClient_AppJWT(t *testing.T) {
+	c, key := newTestClient(t, nil, 1)
+	now := time.Unix(1700000000, 0)
+	c.now = func() time.Time { return now }
+
+	tok, err := c.appJWT()
+	if err != nil {
+		t.Fatal(err)
+	}
+	claims := verifyJWT(t, tok, &key.PublicKey)
+	if claims["iss"] != 42 || claims["iat"] != now.Unix()-60 || claims["exp"] != now.Add(9*time.Minute).Unix() {
+		t.Errorf("unexpected claims: %v", claims)
+	}
+}
+
+func TestClient_PullRequestDiff_LooksUpInstallationAndCachesToken(t *testing.T) {
+	var tokenCalls, installCalls int32
+	var key *rsa.PrivateKey
+	c, key := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/repos/acme/api/installation":
+			atomic.AddInt32(&installCalls, 1)
+			verifyJWT(t, strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer "), &key.PublicKey)
+			w.Write([]byte(`{"id":77}`))
+		case r.URL.Path == "/app/installations/77/access_tokens" && r.Method == http.MethodPost:
+			atomic.AddInt32(&tokenCalls, 1)
+			w.Write([]byte(`{"token":"ghs_test","expires_at":"` + time.Now().Add(time.Hour).UTC().Format(time.RFC3339) + `"}`))
+		case r.URL.Path == "/repos/acme/api/pulls/5":
+			if r.Header.Get("Authorization") != "token ghs_test" || r.Header.Get("Accept") != "application/vnd.github.diff" {
+				t.Errorf("unexpected headers: %v", r.Header)
+			}
+			w.Write([]byte("diff --git a/x b/x\n"))
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}, 0)
+
+	for i := 0; i < 2; i++ {
+		diff, err := c.PullRequestDiff(context.Background(), "acme/api", 5)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.HasPrefix(diff, "diff --git") {
+			t.Errorf("unexpected diff %q", diff)
+		}
+	}
+	if installCalls != 1 || tokenCalls != 1 {
+		t.Errorf("expected installation and token to be fetched once, got %d/%d", installCalls, tokenCalls)
+	}
+}
+
+func TestClient_TokenRefreshedNearExpiry(t *testing.T) {
+	var tokenCalls int32
+	c, _ := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/access_tokens") {
+			atomic.AddInt32(&tokenCalls, 1)
+			// Expires in 30s, inside the one-minute refresh margin
+			w.Write([]byte(`{"token":"t","expires_at":"` + time.Now().Add(30*time.Second).UTC().Format(time.RFC3339) + `"}`))
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}, 9)
+
+	c.RerunWorkflow(context.Background(), "acme/api", 1, false)
+	c.RerunWorkflow(context.Background(), "acme/api", 1, false)
+	if tokenCalls != 2 {
+		t.Errorf("expected a fresh token per call near expiry, got %d", tokenCalls)
+	}
+}
+
+func TestClient_CreateCommentAndRerun(t *testing.T) {
+	var paths []string
+	c, _ := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/access_tokens") {
+			w.Write([]byte(`{"token":"t","expires_at":"` + time.Now().Add(time.Hour).UTC().Format(time.RFC3339) + `"}`))
+			return
+		}
+		paths = append(paths, r.Method+" "+r.URL.Path)
+		if strings.HasSuffix(r.URL.Path, "/comments") {
+			var body map[string]string
+			json.NewDecoder(r.Body).Decode(&body)
+			if body["body"] != "LGTM" {
+				t.Errorf("unexpected comment body %v", body)
+			}
+			w.WriteHeader(http.StatusCreated)
+			w.Write([]byte(`{"id":3,"html_url":"https://github.com/acme/api/pull/5#issuecomment-3"}`))
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+	}, 9)
+
+	comment, err := c.CreateComment(context.Background(), "acme/api", 5, "LGTM")
+	if err != nil || comment.ID != 3 {
+		t.Fatalf("comment=%v err=%v", comment, err)
+	}
+	if err := c.RerunWorkflow(context.Background(), "acme/api", 100, true); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.RerunWorkflow(context.Background(), "acme/api", 100, false); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"POST /repos/acme/api/issues/5/comments",
+		"POST /repos/acme/api/actions/runs/100/rerun-failed-jobs",
+		"POST /repos/acme/api/actions/runs/100/rerun",
+	}
+	if strings.Join(paths, "\n") != strings.Join(want, "\n") {
+		t.Errorf("paths = %v, want %v", paths, want)
+	}
+}
+
+func TestClient_APIError(t *testing.T) {
+	c, _ := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/access_tokens") {
+			w.Write([]byte(`{"token":"t","expires_at":"` + time.Now().Add(time.Hour).UTC().Format(time.RFC3339) + `"}`))
+			return
+		}
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"message":"Not Found"}`))
+	}, 9)
+
+	_, err := c.PullRequestDiff(context.Background(), "acme/api", 1)
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.Status != http.StatusNotFound {
+		t.Errorf("expected APIError 404, got %v", err)
+	}
+}
+
+func TestClient_WorkflowRunArtifacts(t *testing.T) {
+	c, _ := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/access_tokens") {
+			w.Write([]byte(`{"token":"t","expires_at":"` + time.Now().Add(time.Hour).UTC().Format(time.RFC3339) + `"}`))
+			return
+		}
+		if r.URL.Path != "/repos/acme/api/actions/runs/100/artifacts" {
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+		w.Write([]byte(`{"total_count":2,"artifacts":[
+			{"id":1,"name":"test-report","size_in_bytes":2048,"expired":false,"archive_download_url":"https://api.github.com/repos/acme/api/actions/artifacts/1/zip"},
+			{"id":2,"name":"old-logs","expired":true}]}`))
+	}, 9)
+
+	artifacts, err := c.WorkflowRunArtifacts(context.Background(), "acme/api", 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(artifacts) != 1 || artifacts[0].Name != "test-report" || artifacts[0].SizeInBytes != 2048 {
+		t.Errorf("expected only the unexpired artifact, got %+v", artifacts)
+	}
+}
@@ -0,0 +1,133 @@
+package github
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// Handler serves /api/github/repos/{owner}/{repo}/... for the agent.
+type Handler struct {
+	client API
+}
+
+func NewHandler(client API) *Handler {
+	return &Handler{client: client}
+}
+
+// RegisterRoutes adds GitHub API routes to the mux.
+func (h *Handler) RegisterRoutes(mux *http.ServeMux) {
+	mux.HandleFunc("/api/github/repos/", h.handleRepo)
+}
+
+func jsonResponse(w http.ResponseWriter, data any) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(data)
+}
+
+func jsonError(w http.ResponseWriter, msg string, code int) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(map[string]string{"error": msg})
+}
+
+// upstreamError maps GitHub errors to a response, passing 4xx statuses through.
+func upstreamError(w http.ResponseWriter, err error) {
+	var apiErr *APIError
+	if errors.As(err, &apiErr) && apiErr.Status >= 400 && apiErr.Status < 500 {
+		jsonError(w, err.Error(), apiErr.Status)
+		return
+	}
+	jsonError(w, err.Error(), http.StatusBadGateway)
+}
+
+// handleRepo routes:
+//
+//	GET  /api/github/repos/{owner}/{repo}/pulls/{n}/diff
+//	POST /api/github/repos/{owner}/{repo}/pulls/{n}/comments  {"body": "..."}
+//	POST /api/github/repos/{owner}/{repo}/actions/runs/{id}/rerun[?failed_only=true]
+func (h *Handler) handleRepo(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/github/repos/"), "/"), "/")
+	if len(parts) < 5 || parts[0] == "" || parts[1] == "" {
+		jsonError(w, "not found", http.StatusNotFound)
+		return
+	}
+	repo := parts[0] + "/" + parts[1]
+	rest := parts[2:]
+
+	switch {
+	case len(rest) == 3 && rest[0] == "pulls" && rest[2] == "diff":
+		h.handleDiff(w, r, repo, rest[1])
+	case len(rest) == 3 && rest[0] == "pulls" && rest[2] == "comments":
+		h.handleComment(w, r, repo, rest[1])
+	case len(rest) == 4 && rest[0] == "actions" && rest[1] == "runs" && rest[3] == "rerun":
+		h.handleRerun(w, r, repo, rest[2])
+	default:
+		jsonError(w, "not found", http.StatusNotFound)
+	}
+}
+
+func (h *Handler) handleDiff(w http.ResponseWriter, r *http.Request, repo, num string) {
+	if r.Method != http.MethodGet {
+		jsonError(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	n, err := strconv.Atoi(num)
+	if err != nil || n <= 0 {
+		jsonError(w, "invalid pull request number", http.StatusBadRequest)
+		return
+	}
+	diff, err := h.client.PullRequestDiff(r.Context(), repo, n)
+	if err != nil {
+		upstreamError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "text/x-diff; charset=utf-8")
+	w.Write([]byte(diff))
+}
+
+func (h *Handler) handleComment(w http.ResponseWriter, r *http.Request, repo, num string) {
+	if r.Method != http.MethodPost {
+		jsonError(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	n, err := strconv.Atoi(num)
+	if err != nil || n <= 0 {
+		jsonError(w, "invalid pull request number", http.StatusBadRequest)
+		return
+	}
+	var req struct {
+		Body string `json:"body"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || strings.TrimSpace(req.Body) == "" {
+		jsonError(w, "body is required", http.StatusBadRequest)
+		return
+	}
+	comment, err := h.client.CreateComment(r.Context(), repo, n, req.Body)
+	if err != nil {
+		upstreamError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusCreated)
+	jsonResponse(w, comment)
+}
+
+func (h *Handler) handleRerun(w http.ResponseWriter, r *http.Request, repo, id string) {
+	if r.Method != http.MethodPost {
+		jsonError(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	runID, err := strconv.ParseInt(id, 10, 64)
+	if err != nil || runID <= 0 {
+		jsonError(w, "invalid run id", http.StatusBadRequest)
+		return
+	}
+	failedOnly := r.URL.Query().Get("failed_only") == "true"
+	if err := h.client.RerunWorkflow(r.Context(), repo, runID, failedOnly); err != nil {
+		upstreamError(w, err)
+		return
+	}
+	jsonResponse(w, map[string]any{"ok": true, "run_id": runID, "failed_only": failedOnly})
+}
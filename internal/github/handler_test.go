@@ -0,0 +1,87 @@
+package github
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type mockAPI struct {
+	calls []string
+	err   error
+}
+
+func (m *mockAPI) PullRequestDiff(ctx context.Context, repo string, number int) (string, error) {
+	m.calls = append(m.calls, "diff "+repo)
+	return "diff --git a/f b/f", m.err
+}
+
+func (m *mockAPI) CreateComment(ctx context.Context, repo string, number int, body string) (*Comment, error) {
+	m.calls = append(m.calls, "comment "+repo+" "+body)
+	if m.err != nil {
+		return nil, m.err
+	}
+	return &Comment{ID: 1}, nil
+}
+
+func (m *mockAPI) RerunWorkflow(ctx context.Context, repo string, runID int64, failedOnly bool) error {
+	if failedOnly {
+		m.calls = append(m.calls, "rerun-failed "+repo)
+	} else {
+		m.calls = append(m.calls, "rerun "+repo)
+	}
+	return m.err
+}
+
+func serve(h *Handler, method, path, body string) *httptest.ResponseRecorder {
+	mux := http.NewServeMux()
+	h.RegisterRoutes(mux)
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestHandler_Routes(t *testing.T) {
+	tests := []struct {
+		method, path, body string
+		wantCode           int
+		wantCall           string
+	}{
+		{"GET", "/api/github/repos/acme/api/pulls/5/diff", "", http.StatusOK, "diff acme/api"},
+		{"POST", "/api/github/repos/acme/api/pulls/5/comments", `{"body":"hi"}`, http.StatusCreated, "comment acme/api hi"},
+		{"POST", "/api/github/repos/acme/api/actions/runs/9/rerun", "", http.StatusOK, "rerun acme/api"},
+		{"POST", "/api/github/repos/acme/api/actions/runs/9/rerun?failed_only=true", "", http.StatusOK, "rerun-failed acme/api"},
+		{"POST", "/api/github/repos/acme/api/pulls/5/diff", "", http.StatusMethodNotAllowed, ""},
+		{"GET", "/api/github/repos/acme/api/pulls/5/comments", "", http.StatusMethodNotAllowed, ""},
+		{"GET", "/api/github/repos/acme/api/pulls/x/diff", "", http.StatusBadRequest, ""},
+		{"POST", "/api/github/repos/acme/api/pulls/5/comments", `{"body":" "}`, http.StatusBadRequest, ""},
+		{"POST", "/api/github/repos/acme/api/actions/runs/abc/rerun", "", http.StatusBadRequest, ""},
+		{"GET", "/api/github/repos/acme/api/issues", "", http.StatusNotFound, ""},
+	}
+	for _, tt := range tests {
+		api := &mockAPI{}
+		rec := serve(NewHandler(api), tt.method, tt.path, tt.body)
+		if rec.Code != tt.wantCode {
+			t.Errorf("%s %s: expected %d, got %d (%s)", tt.method, tt.path, tt.wantCode, rec.Code, rec.Body.String())
+		}
+		got := strings.Join(api.calls, ",")
+		if got != tt.wantCall {
+			t.Errorf("%s %s: expected call %q, got %q", tt.method, tt.path, tt.wantCall, got)
+		}
+	}
+}
+
+func TestHandler_UpstreamErrors(t *testing.T) {
+	rec := serve(NewHandler(&mockAPI{err: &APIError{Status: 404, Body: "Not Found"}}), "GET", "/api/github/repos/acme/api/pulls/5/diff", "")
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("expected GitHub 4xx to pass through, got %d", rec.Code)
+	}
+	rec = serve(NewHandler(&mockAPI{err: errors.New("dial tcp: timeout")}), "POST", "/api/github/repos/acme/api/actions/runs/1/rerun", "")
+	if rec.Code != http.StatusBadGateway {
+		t.Errorf("expected 502 for transport errors, got %d", rec.Code)
+	}
+}
@@ -0,0 +1,40 @@
+package github
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// HookRanges returns the CIDR ranges GitHub sends webhooks from, as published
+// in the "hooks" field of GET /meta. apiURL defaults to https://api.github.com.
+func HookRanges(ctx context.Context, client *http.Client, apiURL string) ([]string, error) {
+	if apiURL == "" {
+		apiURL = "https://api.github.com"
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimRight(apiURL, "/")+"/meta", nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("github meta: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("github meta: status %d", resp.StatusCode)
+	}
+	var meta struct {
+		Hooks []string `json:"hooks"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&meta); err != nil {
+		return nil, fmt.Errorf("github meta: %w", err)
+	}
+	if len(meta.Hooks) == 0 {
+		return nil, fmt.Errorf("github meta: no hook ranges")
+	}
+	return meta.Hooks, nil
+}
@@ -0,0 +1,40 @@
+package github
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestHookRanges(t *testing.T) {
+	empty := false
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/meta" {
+			http.NotFound(w, r)
+			return
+		}
+		if empty {
+			w.Write([]byte(`{"hooks":[]}`))
+			return
+		}
+		w.Write([]byte(`{"hooks":["192.30.252.0/22","2a0a:a440::/29"],"web":["140.82.112.0/20"]}`))
+	}))
+	defer srv.Close()
+
+	ranges, err := HookRanges(context.Background(), srv.Client(), srv.URL+"/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ranges) != 2 || ranges[0] != "192.30.252.0/22" {
+		t.Errorf("unexpected ranges %v", ranges)
+	}
+
+	empty = true
+	if _, err := HookRanges(context.Background(), srv.Client(), srv.URL); err == nil {
+		t.Error("expected an empty hooks list to fail")
+	}
+	if _, err := HookRanges(context.Background(), srv.Client(), srv.URL+"/missing"); err == nil {
+		t.Error("expected a 404 to fail")
+	}
+}
@@ -0,0 +1,95 @@
+package gmail
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/katalabut/openclaw-relay/internal/extract"
+	"github.com/katalabut/openclaw-relay/internal/screening"
+)
+
+// TextExtraction turns a matched message's attachments into text for
+// {{.AttachmentText}}. Attachments are screened before download and again
+// on their content; blocked ones are skipped.
+type TextExtraction struct {
+	Extractor *extract.Client
+	Screener  *screening.Screener
+	MimeTypes []string // default extract.DefaultMimeTypes
+	MaxChars  int      // text kept per message
+}
+
+// SetTextExtraction enables action.extract_text for the poller's rules.
+func (p *Poller) SetTextExtraction(te *TextExtraction) {
+	p.textExtraction = te
+}
+
+// attachmentText returns the text of msgID's supported attachments, each
+// under a "--- filename ---" line. Several rules matching one message share
+// one extraction.
+func (p *Poller) attachmentText(ctx context.Context, msgID string) string {
+	te := p.textExtraction
+	if te == nil || te.Extractor == nil {
+		return ""
+	}
+	if p.extracted.id == msgID {
+		return p.extracted.text
+	}
+	full, err := p.client.GetMessage(ctx, msgID)
+	if err != nil {
+		log.Printf("Gmail: text extraction for %s: %v", msgID, err)
+		return ""
+	}
+	mimeTypes := te.MimeTypes
+	if len(mimeTypes) == 0 {
+		mimeTypes = extract.DefaultMimeTypes
+	}
+
+	var b strings.Builder
+	for _, a := range full.Attachments {
+		if a.ID == "" || !extract.Supported(mimeTypes, a.MimeType) {
+			continue
+		}
+		if res := te.Screener.Check(a.Filename, a.Size); !res.Allowed() {
+			log.Printf("Gmail: not extracting %s from %s: %s", a.Filename, msgID, res.Reason)
+			continue
+		}
+		data, err := p.client.GetAttachment(ctx, msgID, a.ID)
+		if err != nil {
+			log.Printf("Gmail: download %s from %s: %v", a.Filename, msgID, err)
+			continue
+		}
+		if res := te.Screener.Scan(ctx, a.Filename, data); !res.Allowed() {
+			log.Printf("Gmail: not extracting %s from %s: %s %s", a.Filename, msgID, res.Status, res.Reason)
+			continue
+		}
+		text, err := te.Extractor.Text(ctx, a.Filename, a.MimeType, data)
+		if err != nil {
+			log.Printf("Gmail: %v", err)
+			continue
+		}
+		if text == "" {
+			continue
+		}
+		fmt.Fprintf(&b, "--- %s ---\n%s\n", a.Filename, text)
+		if te.MaxChars > 0 && b.Len() >= te.MaxChars {
+			break
+		}
+	}
+	text := truncateRunes(strings.TrimSpace(b.String()), te.MaxChars)
+	p.extracted.id, p.extracted.text = msgID, text
+	return text
+}
+
+// truncateRunes cuts s to at most n characters; n <= 0 keeps all of it.
+func truncateRunes(s string, n int) string {
+	if n <= 0 || len(s) <= n {
+		return s
+	}
+	r := []rune(s)
+	if len(r) <= n {
+		return s
+	}
+	return string(r[:n])
+}
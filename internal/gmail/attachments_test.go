@@ -0,0 +1,84 @@
+package gmail
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/katalabut/openclaw-relay/internal/config"
+	"github.com/katalabut/openclaw-relay/internal/extract"
+	"github.com/katalabut/openclaw-relay/internal/screening"
+)
+
+func TestExecuteCronAction_ExtractText(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		w.Write([]byte("text of " + string(body)))
+	}))
+	defer srv.Close()
+
+	var downloads []string
+	gets := 0
+	mc := &mockGmailClient{
+		getMessageFunc: func(_ context.Context, id string) (*MessageFull, error) {
+			gets++
+			return &MessageFull{ID: id, Attachments: []Attachment{
+				{ID: "A1", Filename: "invoice.pdf", MimeType: "application/pdf", Size: 10},
+				{ID: "A2", Filename: "notes.txt", MimeType: "text/plain", Size: 10},
+				{ID: "A3", Filename: "scan.png", MimeType: "image/png", Size: 1 << 20},
+				{ID: "A4", Filename: "receipt.jpg", MimeType: "image/jpeg", Size: 10},
+			}}, nil
+		},
+		getAttachmentFunc: func(_ context.Context, msgID, attID string) ([]byte, error) {
+			downloads = append(downloads, attID)
+			return []byte(attID), nil
+		},
+	}
+	gw := &mockGW{}
+	p := &Poller{client: mc, gateway: gw}
+	p.SetTextExtraction(&TextExtraction{
+		Extractor: extract.NewClient(srv.URL, nil, 5*time.Second),
+		Screener:  &screening.Screener{MaxBytes: 1000},
+		MaxChars:  1000,
+	})
+
+	rule := config.GmailRule{Name: "invoices", Action: config.GmailAction{
+		ExtractText:     true,
+		MessageTemplate: "{{.Subject}}\n{{.AttachmentText}}",
+	}}
+	msg := HistoryMessage{ID: "m1", Subject: "Invoice"}
+	p.executeCronAction(context.Background(), rule, 0, msg, nil)
+	p.executeCronAction(context.Background(), rule, 0, msg, nil)
+
+	want := "Invoice\n--- invoice.pdf ---\ntext of A1\n--- receipt.jpg ---\ntext of A4"
+	if len(gw.messages) != 2 || gw.messages[0] != want || gw.messages[1] != want {
+		t.Fatalf("unexpected messages %q", gw.messages)
+	}
+	// Text files are not sent and oversized scans are not downloaded
+	if strings.Join(downloads, ",") != "A1,A4" {
+		t.Errorf("unexpected downloads %v", downloads)
+	}
+	if gets != 1 {
+		t.Errorf("expected one extraction per message, got %d", gets)
+	}
+
+	// Without extract_text the message is not fetched
+	rule.Action.ExtractText = false
+	p.executeCronAction(context.Background(), rule, 0, HistoryMessage{ID: "m2", Subject: "Other"}, nil)
+	if gets != 1 || gw.messages[2] != "Other\n" {
+		t.Errorf("unexpected fetch or message %q", gw.messages[2])
+	}
+}
+
+func TestTruncateRunes(t *testing.T) {
+	if got := truncateRunes("Счёт-фактура", 4); got != "Счёт" {
+		t.Errorf("got %q", got)
+	}
+	if got := truncateRunes("short", 0); got != "short" {
+		t.Errorf("got %q", got)
+	}
+}
@@ -0,0 +1,134 @@
+package gmail
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"time"
+
+	"github.com/katalabut/openclaw-relay/internal/config"
+)
+
+// BackfillOptions selects the existing mail Backfill runs through the rules.
+type BackfillOptions struct {
+	Query  string // Gmail search query, e.g. "newer_than:7d"
+	Rule   string // run only this rule; empty runs all rules as the poller does
+	Max    int    // messages to scan (default 100)
+	DryRun bool   // report matches without running actions
+}
+
+// BackfillMatch is a message and the rules it matched.
+type BackfillMatch struct {
+	MessageID string   `json:"messageId"`
+	Subject   string   `json:"subject"`
+	From      string   `json:"from"`
+	Rules     []string `json:"rules"`
+	// Done are the matched rules already backfilled for this message by an
+	// earlier run; they are not run again.
+	Done []string `json:"done,omitempty"`
+}
+
+// BackfillResult is what a Backfill run scanned and matched.
+type BackfillResult struct {
+	Scanned int             `json:"scanned"`
+	Matches []BackfillMatch `json:"matches"`
+}
+
+// Backfill runs the existing messages found by opts.Query through the rules,
+// oldest first, e.g. to try a new rule on last week's mail. A rule that was
+// already backfilled for a message is not run for it again. Label-change and
+// deletion rules only see history, so they are skipped.
+func (p *Poller) Backfill(ctx context.Context, opts BackfillOptions) (*BackfillResult, error) {
+	rules := make([]config.GmailRule, 0, len(p.rules))
+	for _, r := range p.rules {
+		if (opts.Rule == "" || r.Name == opts.Rule) && slices.Contains(r.Match.ResolvedEvents(), EventMessageAdded) {
+			rules = append(rules, r)
+		}
+	}
+	if opts.Rule != "" && len(rules) == 0 {
+		return nil, fmt.Errorf("no message_added rule named %q for %s", opts.Rule, p.accountEmail)
+	}
+	if opts.Rule != "" {
+		rules[0].Continue = false
+	}
+	max := opts.Max
+	if max <= 0 {
+		max = 100
+	}
+	state, err := p.loadState()
+	if errors.Is(err, os.ErrNotExist) {
+		state, err = &GmailState{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var found []MessageMeta
+	pageToken := ""
+	for len(found) < max {
+		page, next, err := p.client.ListMessages(ctx, opts.Query, int64(min(max-len(found), 100)), pageToken)
+		if err != nil {
+			return nil, err
+		}
+		found = append(found, page...)
+		if next == "" {
+			break
+		}
+		pageToken = next
+	}
+	found = found[:min(len(found), max)]
+	slices.Reverse(found) // Gmail lists newest first
+
+	res := &BackfillResult{Scanned: len(found), Matches: []BackfillMatch{}}
+	ran := map[string][]string{} // rule name to message IDs
+	for _, m := range found {
+		if err = ctx.Err(); err != nil {
+			break // keep what ran so far in the log
+		}
+		msg := HistoryMessage{ID: m.ID, ThreadID: m.ThreadID, Labels: m.Labels, Subject: m.Subject, From: m.From, To: m.To, Cc: m.Cc, Snippet: m.Snippet, RFC822ID: m.RFC822ID}
+		look := &messageLookup{client: p.client, msg: msg}
+		matched := p.matchingRules(ctx, rules, msg, look)
+		if len(matched) == 0 {
+			continue
+		}
+		match := BackfillMatch{MessageID: m.ID, Subject: m.Subject, From: m.From}
+		for _, rule := range matched {
+			match.Rules = append(match.Rules, rule.Name)
+			if _, ok := state.Backfilled[rule.Name][m.ID]; ok {
+				match.Done = append(match.Done, rule.Name)
+				continue
+			}
+			if opts.DryRun {
+				continue
+			}
+			p.runRule(ctx, rule, msg, look)
+			ran[rule.Name] = append(ran[rule.Name], m.ID)
+		}
+		res.Matches = append(res.Matches, match)
+	}
+	if len(ran) > 0 {
+		now := time.Now().UTC()
+		serr := p.updateState(func(s *GmailState) {
+			if s.Backfilled == nil {
+				s.Backfilled = map[string]map[string]time.Time{}
+			}
+			hits := map[string]int{}
+			for rule, ids := range ran {
+				if s.Backfilled[rule] == nil {
+					s.Backfilled[rule] = map[string]time.Time{}
+				}
+				for _, id := range ids {
+					s.Backfilled[rule][id] = now
+				}
+				hits[rule] = len(ids)
+			}
+			s.addHits(hits)
+		})
+		if serr != nil {
+			return res, fmt.Errorf("backfill record: %w", serr)
+		}
+	}
+	return res, err
+}
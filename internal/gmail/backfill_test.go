@@ -0,0 +1,124 @@
+package gmail
+
+import (
+	"context"
+	"errors"
+	"os"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/katalabut/openclaw-relay/internal/config"
+)
+
+// backfillMailbox lists msgs newest first, pageSize at a time.
+func backfillMailbox(msgs []MessageMeta, pageSize int, queries *[]string) *mockGmailClient {
+	return &mockGmailClient{
+		listMessagesFunc: func(_ context.Context, query string, max int64, pageToken string) ([]MessageMeta, string, error) {
+			*queries = append(*queries, query)
+			start := 0
+			if pageToken != "" {
+				start = int(pageToken[0] - '0')
+			}
+			end := min(start+pageSize, start+int(max), len(msgs))
+			next := ""
+			if end < len(msgs) {
+				next = string(rune('0' + end))
+			}
+			return msgs[start:end], next, nil
+		},
+	}
+}
+
+func TestBackfill(t *testing.T) {
+	mail := []MessageMeta{
+		{ID: "m4", From: "billing@acme.com", Subject: "Invoice 4"},
+		{ID: "m3", From: "friend@example.com", Subject: "Lunch?"},
+		{ID: "m2", From: "billing@acme.com", Subject: "Invoice 2"},
+		{ID: "m1", From: "billing@acme.com", Subject: "Invoice 1"},
+	}
+	rules := []config.GmailRule{
+		{Name: "invoices", Match: config.GmailMatch{From: []string{"*@acme.com"}}, Continue: true, Action: config.GmailAction{MessageTemplate: "invoice {{.ID}}"}},
+		{Name: "all", Action: config.GmailAction{MessageTemplate: "mail {{.ID}}"}},
+		{Name: "starred", Match: config.GmailMatch{Events: []string{"label_added"}, ChangedLabels: []string{"STARRED"}}, Action: config.GmailAction{MessageTemplate: "starred {{.ID}}"}},
+	}
+	var queries []string
+	gw := &mockGW{}
+	dir := t.TempDir()
+	p := NewPollerForAccount(backfillMailbox(mail, 3, &queries), "me@example.com", "1m", rules, gw, dir, nil)
+	ctx := context.Background()
+
+	// A dry run lists matches oldest first and creates nothing
+	res, err := p.Backfill(ctx, BackfillOptions{Query: "newer_than:7d", DryRun: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Scanned != 4 || len(res.Matches) != 4 || res.Matches[0].MessageID != "m1" || !slices.Equal(res.Matches[0].Rules, []string{"invoices", "all"}) {
+		t.Errorf("unexpected dry run %+v", res)
+	}
+	if len(gw.messages) != 0 {
+		t.Errorf("dry run created %d jobs", len(gw.messages))
+	}
+	if _, err := p.loadState(); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("dry run wrote state: %v", err)
+	}
+	if queries[0] != "newer_than:7d" {
+		t.Errorf("query = %q", queries[0])
+	}
+
+	// --rule runs only that rule, on the newest max messages, and is logged
+	res, err = p.Backfill(ctx, BackfillOptions{Query: "newer_than:7d", Rule: "invoices", Max: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Scanned != 2 || !slices.Equal(gw.messages, []string{"invoice m4"}) {
+		t.Errorf("scanned %d, jobs %v", res.Scanned, gw.messages)
+	}
+
+	// A second run skips what was backfilled already
+	gw.messages = nil
+	res, err = p.Backfill(ctx, BackfillOptions{Query: "newer_than:7d"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"invoice m1", "mail m1", "invoice m2", "mail m2", "mail m3", "mail m4"}
+	if !slices.Equal(gw.messages, want) {
+		t.Errorf("jobs = %v, want %v", gw.messages, want)
+	}
+	if m := res.Matches[3]; m.MessageID != "m4" || !slices.Equal(m.Done, []string{"invoices"}) {
+		t.Errorf("unexpected match %+v", m)
+	}
+	gw.messages = nil
+	p.Backfill(ctx, BackfillOptions{Query: "newer_than:7d"})
+	if len(gw.messages) != 0 {
+		t.Errorf("third run created %v", gw.messages)
+	}
+
+	if s, _ := p.loadState(); s.RuleHits["invoices"] != 3 || s.RuleHits["all"] != 4 || len(s.Backfilled["all"]) != 4 {
+		t.Errorf("unexpected state %+v", s)
+	}
+
+	// Offboarding drops the record with the state
+	RemoveState(dir, "me@example.com")
+	if _, err := p.loadState(); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("backfill record left after RemoveState: %v", err)
+	}
+}
+
+func TestBackfill_Errors(t *testing.T) {
+	var queries []string
+	rules := []config.GmailRule{{Name: "starred", Match: config.GmailMatch{Events: []string{"label_added"}}}}
+	p := NewPollerForAccount(backfillMailbox(nil, 10, &queries), "me@example.com", "1m", rules, &mockGW{}, t.TempDir(), nil)
+	for _, rule := range []string{"missing", "starred"} {
+		if _, err := p.Backfill(context.Background(), BackfillOptions{Query: "in:inbox", Rule: rule}); err == nil || !strings.Contains(err.Error(), "no message_added rule") {
+			t.Errorf("%s: expected an unknown-rule error, got %v", rule, err)
+		}
+	}
+
+	if err := os.WriteFile(stateFilePath(p.stateDir), []byte(`{"version":7}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := p.Backfill(context.Background(), BackfillOptions{Query: "in:inbox"}); err == nil || !strings.Contains(err.Error(), "newer") {
+		t.Errorf("expected a newer-version error, got %v", err)
+	}
+}
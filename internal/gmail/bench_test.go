@@ -0,0 +1,54 @@
+package gmail
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"testing"
+
+	"github.com/katalabut/openclaw-relay/internal/config"
+	"github.com/katalabut/openclaw-relay/internal/gateway"
+)
+
+// BenchmarkEvaluateRules measures Gmail rule matching and dispatch for one message against 20 rules.
+// See docs/benchmarking.md.
+func BenchmarkEvaluateRules(b *testing.B) {
+	log.SetOutput(io.Discard)
+	b.Cleanup(func() { log.SetOutput(os.Stderr) })
+
+	rules := make([]config.GmailRule, 0, 20)
+	for i := 0; i < 19; i++ {
+		rules = append(rules, config.GmailRule{
+			Name:   fmt.Sprintf("r%d", i),
+			Match:  config.GmailMatch{From: []string{fmt.Sprintf("*@vendor%d.com", i)}, Labels: []string{"INBOX"}},
+			Action: config.GmailAction{MessageTemplate: "{{.Subject}}"},
+		})
+	}
+	rules = append(rules, config.GmailRule{
+		Name:   "billing",
+		Match:  config.GmailMatch{From: []string{"billing@", "@stripe.com"}, Labels: []string{"INBOX", "IMPORTANT"}},
+		Action: config.GmailAction{MessageTemplate: "Invoice from {{.From}}: {{.Subject}}\n{{.Snippet}}"},
+	})
+
+	sink := gateway.NewSink()
+	p := NewPollerForAccount(nil, "bench@example.com", "1m", rules, sink, b.TempDir(), nil)
+	msg := HistoryMessage{
+		ID:      "m1",
+		Labels:  []string{"INBOX", "IMPORTANT", "CATEGORY_UPDATES"},
+		From:    "Stripe <receipts@stripe.com>",
+		Subject: "Your invoice is ready",
+		Snippet: "Amount due: $42.00",
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		p.evaluateRules(context.Background(), msg)
+	}
+	b.StopTimer()
+	if sink.Count() != int64(b.N) {
+		b.Fatalf("expected %d jobs, got %d", b.N, sink.Count())
+	}
+}
@@ -0,0 +1,88 @@
+package gmail
+
+import (
+	"strings"
+	"sync"
+	"time"
+)
+
+// maxCacheEntries bounds the response cache; when it is full of live entries
+// it starts over.
+const maxCacheEntries = 500
+
+// responseCache keeps list and label responses for a short TTL, so an agent
+// polling the same query doesn't spend Gmail quota on every call. Keys start
+// with the account, so a modify can drop everything cached for it.
+type responseCache struct {
+	ttl time.Duration
+	now func() time.Time
+
+	mu      sync.Mutex
+	entries map[string]cacheEntry
+}
+
+type cacheEntry struct {
+	value   any
+	expires time.Time
+}
+
+func newResponseCache(ttl time.Duration) *responseCache {
+	return &responseCache{ttl: ttl, now: time.Now, entries: make(map[string]cacheEntry)}
+}
+
+// cacheKey joins an account and the parts of a request that select its
+// response.
+func cacheKey(account string, parts ...string) string {
+	return account + "\n" + strings.Join(parts, "\n")
+}
+
+func (c *responseCache) get(key string) (any, bool) {
+	if c == nil {
+		return nil, false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.entries[key]
+	if !ok || !c.now().Before(e.expires) {
+		return nil, false
+	}
+	return e.value, true
+}
+
+func (c *responseCache) put(key string, value any) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := c.now()
+	if len(c.entries) >= maxCacheEntries {
+		for k, e := range c.entries {
+			if !now.Before(e.expires) {
+				delete(c.entries, k)
+			}
+		}
+		if len(c.entries) >= maxCacheEntries {
+			clear(c.entries)
+		}
+	}
+	c.entries[key] = cacheEntry{value: value, expires: now.Add(c.ttl)}
+}
+
+// bust drops everything cached for account and returns how many entries
+// there were.
+func (c *responseCache) bust(account string) int {
+	if c == nil {
+		return 0
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	n := 0
+	for k := range c.entries {
+		if strings.HasPrefix(k, account+"\n") {
+			delete(c.entries, k)
+			n++
+		}
+	}
+	return n
+}
@@ -0,0 +1,107 @@
+package gmail
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestResponseCache(t *testing.T) {
+	now := time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC)
+	c := newResponseCache(10 * time.Second)
+	c.now = func() time.Time { return now }
+
+	c.put(cacheKey("a@example.com", "labels"), "labels of a")
+	c.put(cacheKey("b@example.com", "labels"), "labels of b")
+	if v, ok := c.get(cacheKey("a@example.com", "labels")); !ok || v != "labels of a" {
+		t.Errorf("get = %v, %v", v, ok)
+	}
+
+	if n := c.bust("a@example.com"); n != 1 {
+		t.Errorf("bust = %d, want 1", n)
+	}
+	if _, ok := c.get(cacheKey("a@example.com", "labels")); ok {
+		t.Error("expected a@example.com busted")
+	}
+	if _, ok := c.get(cacheKey("b@example.com", "labels")); !ok {
+		t.Error("expected b@example.com kept")
+	}
+
+	now = now.Add(10 * time.Second)
+	if _, ok := c.get(cacheKey("b@example.com", "labels")); ok {
+		t.Error("expected the entry to expire")
+	}
+
+	for i := range maxCacheEntries + 1 {
+		c.put(cacheKey("a@example.com", fmt.Sprint(i)), i)
+	}
+	if len(c.entries) > maxCacheEntries {
+		t.Errorf("cache grew to %d entries", len(c.entries))
+	}
+
+	var off *responseCache
+	off.put("k", 1)
+	off.bust("a@example.com")
+	if _, ok := off.get("k"); ok {
+		t.Error("a nil cache must not hit")
+	}
+}
+
+func TestHandler_Cache(t *testing.T) {
+	lists, labels := 0, 0
+	mc := &mockGmailClient{
+		listMessagesFunc: func(_ context.Context, query string, _ int64, _ string) ([]MessageMeta, string, error) {
+			lists++
+			return []MessageMeta{{ID: fmt.Sprintf("m%d", lists)}}, "", nil
+		},
+		listLabelsFunc: func(_ context.Context) ([]LabelInfo, error) {
+			labels++
+			return []LabelInfo{{ID: "INBOX"}}, nil
+		},
+		modifyMessageFunc: func(_ context.Context, _ string, _ ModifyRequest) error {
+			return nil
+		},
+	}
+	h := NewMultiHandler(map[string]GmailClient{"me@example.com": mc})
+	h.SetCacheTTL(time.Minute)
+	mux := http.NewServeMux()
+	h.RegisterRoutes(mux)
+
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest(method, path, strings.NewReader(body)))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("%s %s: %d %s", method, path, rec.Code, rec.Body)
+		}
+		return rec
+	}
+
+	first := do("GET", "/api/gmail/messages?q=is:unread", "")
+	second := do("GET", "/api/gmail/messages?q=is:unread", "")
+	if lists != 1 || second.Body.String() != first.Body.String() || second.Header().Get("X-Relay-Cache") != "hit" {
+		t.Errorf("expected a cache hit, got %d calls, %s", lists, second.Body)
+	}
+	do("GET", "/api/gmail/messages?q=is:starred", "")
+	do("GET", "/api/gmail/labels", "")
+	do("GET", "/api/gmail/labels", "")
+	if lists != 2 || labels != 1 {
+		t.Errorf("list calls %d, label calls %d", lists, labels)
+	}
+
+	do("POST", "/api/gmail/modify/m1", `{"markRead":true}`)
+	do("GET", "/api/gmail/messages?q=is:unread", "")
+	do("GET", "/api/gmail/labels", "")
+	if lists != 3 || labels != 2 {
+		t.Errorf("expected modify to bust the cache, got list calls %d, label calls %d", lists, labels)
+	}
+
+	h.SetCacheTTL(0)
+	do("GET", "/api/gmail/labels", "")
+	if labels != 3 {
+		t.Errorf("expected no cache, got %d label calls", labels)
+	}
+}
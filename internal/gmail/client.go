@@ -3,26 +3,41 @@ package gmail
 import (
 	"context"
 	"encoding/base64"
+	"errors"
 	"fmt"
 	"log"
 	"mime"
+	"net/http"
+	"slices"
 	"strings"
+	"sync"
 
+	"github.com/katalabut/openclaw-relay/internal/config"
+	"github.com/katalabut/openclaw-relay/internal/screening"
 	"github.com/katalabut/openclaw-relay/internal/tokens"
 	"golang.org/x/oauth2"
 	gm "google.golang.org/api/gmail/v1"
 	"google.golang.org/api/option"
 )
 
-// GmailClient is the interface for Gmail operations.
+// GmailClient is the interface for mail operations: the Gmail API client, or
+// another provider's, such as the imap package's. Pollers, rules and the API
+// handler only see this.
 type GmailClient interface {
-	ListMessages(ctx context.Context, query string, maxResults int64) ([]MessageMeta, error)
+	ListMessages(ctx context.Context, query string, maxResults int64, pageToken string) ([]MessageMeta, string, error)
+	ListThreads(ctx context.Context, query string, maxResults int64, pageToken string) ([]ThreadMeta, string, error)
 	GetMessage(ctx context.Context, id string) (*MessageFull, error)
 	ModifyMessage(ctx context.Context, id string, req ModifyRequest) error
 	ListLabels(ctx context.Context) ([]LabelInfo, error)
 	GetThread(ctx context.Context, threadID string) ([]MessageFull, error)
+	GetAttachment(ctx context.Context, messageID, attachmentID string) ([]byte, error)
 	GetCurrentHistoryID(ctx context.Context) (uint64, error)
 	GetHistory(ctx context.Context, startHistoryID uint64) ([]HistoryMessage, uint64, error)
+	ListDrafts(ctx context.Context, maxResults int64) ([]Draft, error)
+	CreateDraft(ctx context.Context, req DraftRequest) (*Draft, error)
+	UpdateDraft(ctx context.Context, id string, req DraftRequest) (*Draft, error)
+	DeleteDraft(ctx context.Context, id string) error
+	ForwardMessage(ctx context.Context, id string, req ForwardRequest) error
 }
 
 // Client wraps Gmail API v1.
@@ -30,30 +45,79 @@ type Client struct {
 	store    *tokens.Store
 	oauthCfg *oauth2.Config
 	email    string
+	endpoint string
+
+	// GetHistory caps per call; the rest is picked up on the next poll
+	maxHistoryPages    int
+	maxHistoryMessages int
+	// historyEvents are the history events GetHistory returns, e.g.
+	// label_added; nil means message_added only
+	historyEvents []string
+	// transport retries rate-limited calls, caps the account's calls in
+	// flight and counts their quota; nil means the default transport
+	transport *retryTransport
 }
 
 func NewClientForAccount(store *tokens.Store, oauthCfg *oauth2.Config, email string) *Client {
 	return &Client{store: store, oauthCfg: oauthCfg, email: email}
 }
 
-func (c *Client) getService(ctx context.Context) (*gm.Service, error) {
-	tok := c.store.GetGoogleOAuth2Token(c.email)
-	if tok == nil {
-		return nil, fmt.Errorf("not authenticated with Google for %s", c.email)
+// SetHistoryLimits caps how many history pages and new messages one GetHistory call
+// processes. Zero means unlimited.
+func (c *Client) SetHistoryLimits(maxPages, maxMessages int) {
+	c.maxHistoryPages = maxPages
+	c.maxHistoryMessages = maxMessages
+}
+
+// SetHistoryEvents sets the history events GetHistory asks Gmail for, from
+// config.GmailEvents. Empty means message_added only.
+func (c *Client) SetHistoryEvents(events []string) {
+	c.historyEvents = events
+}
+
+// SetRetry makes the client retry rate-limited and failing calls with backoff
+// and cap how many it has in flight, per cfg.
+func (c *Client) SetRetry(cfg config.GmailRetryConfig) {
+	var quota *Quota
+	if c.transport != nil {
+		quota = c.transport.quota
+	}
+	c.transport = newRetryTransport(http.DefaultTransport, c.email, cfg)
+	c.transport.quota = quota
+}
+
+// SetQuota charges the client's calls to q, which refuses them with
+// ErrQuotaExhausted once the account's daily budget is used up.
+func (c *Client) SetQuota(q *Quota) {
+	if c.transport == nil {
+		c.transport = newRetryTransport(http.DefaultTransport, c.email, config.GmailRetryConfig{MaxRetries: -1, MaxConcurrent: -1})
 	}
-	ts := c.oauthCfg.TokenSource(ctx, tok)
-	// Get a fresh token (auto-refreshes if expired)
-	newTok, err := ts.Token()
+	c.transport.quota = q
+}
+
+// SetEndpoint points the client at another Gmail API base URL, e.g. a fake
+// server in tests. Empty means Google's default.
+func (c *Client) SetEndpoint(url string) {
+	c.endpoint = url
+}
+
+func (c *Client) getService(ctx context.Context) (*gm.Service, error) {
+	ts, err := c.store.GoogleTokenSource(ctx, c.oauthCfg, c.email)
 	if err != nil {
-		return nil, fmt.Errorf("token refresh: %w", err)
+		return nil, err
 	}
-	// Persist refreshed token
-	if newTok.AccessToken != tok.AccessToken {
-		if err := c.store.UpdateGoogleAccessToken(newTok, c.email); err != nil {
-			log.Printf("Warning: failed to persist refreshed token: %v", err)
-		}
+	// Calls refused for a missing scope fail with a *tokens.ScopeError
+	var base http.RoundTripper
+	if c.transport != nil {
+		base = c.transport
 	}
-	return gm.NewService(ctx, option.WithTokenSource(ts))
+	opts := []option.ClientOption{option.WithHTTPClient(&http.Client{
+		Transport: &oauth2.Transport{Source: ts, Base: c.store.ScopeTransport(c.email, base)},
+	})}
+	if c.endpoint != "" {
+		opts = append(opts, option.WithEndpoint(c.endpoint))
+	}
+	return gm.NewService(ctx, opts...)
 }
 
 // MessageMeta is a lightweight message representation.
@@ -62,8 +126,23 @@ type MessageMeta struct {
 	ThreadID string   `json:"threadId"`
 	Subject  string   `json:"subject"`
 	From     string   `json:"from"`
+	To       string   `json:"to,omitempty"`
+	Cc       string   `json:"cc,omitempty"`
+	Date     string   `json:"date"`
+	Snippet  string   `json:"snippet"`
+	Labels   []string `json:"labels"`
+	RFC822ID string   `json:"rfc822Id,omitempty"`
+}
+
+// ThreadMeta is a lightweight thread representation: the subject and sender
+// of its first message, the date of its last.
+type ThreadMeta struct {
+	ID       string   `json:"id"`
+	Subject  string   `json:"subject"`
+	From     string   `json:"from"`
 	Date     string   `json:"date"`
 	Snippet  string   `json:"snippet"`
+	Messages int      `json:"messages"`
 	Labels   []string `json:"labels"`
 }
 
@@ -78,6 +157,42 @@ type MessageFull struct {
 	Body     string   `json:"body"`
 	Labels   []string `json:"labels"`
 	Snippet  string   `json:"snippet"`
+	// RFC822ID is the Message-ID header, used to run Gmail searches against this message.
+	RFC822ID string `json:"rfc822Id,omitempty"`
+
+	Attachments []Attachment `json:"attachments,omitempty"`
+}
+
+// Attachment is an attachment's metadata. Screening is set by the API
+// handler when attachment screening is configured.
+type Attachment struct {
+	ID        string            `json:"attachmentId"`
+	PartID    string            `json:"partId"`
+	Filename  string            `json:"filename"`
+	MimeType  string            `json:"mimeType"`
+	Size      int64             `json:"size"`
+	Screening *screening.Result `json:"screening,omitempty"`
+}
+
+// extractAttachments lists the parts that carry a filename, depth first.
+func extractAttachments(payload *gm.MessagePart) []Attachment {
+	if payload == nil {
+		return nil
+	}
+	var out []Attachment
+	if payload.Filename != "" && payload.Body != nil {
+		out = append(out, Attachment{
+			ID:       payload.Body.AttachmentId,
+			PartID:   payload.PartId,
+			Filename: decodeRFC2047(payload.Filename),
+			MimeType: payload.MimeType,
+			Size:     payload.Body.Size,
+		})
+	}
+	for _, part := range payload.Parts {
+		out = append(out, extractAttachments(part)...)
+	}
+	return out
 }
 
 func getHeader(headers []*gm.MessagePartHeader, name string) string {
@@ -133,39 +248,133 @@ func decodeRFC2047(s string) string {
 	return result
 }
 
-// ListMessages lists messages matching a query.
-func (c *Client) ListMessages(ctx context.Context, query string, maxResults int64) ([]MessageMeta, error) {
+// ListMessages lists messages matching a query, starting at pageToken ("" for
+// the first page). It returns the token of the next page, "" on the last one.
+func (c *Client) ListMessages(ctx context.Context, query string, maxResults int64, pageToken string) ([]MessageMeta, string, error) {
 	svc, err := c.getService(ctx)
 	if err != nil {
-		return nil, err
+		return nil, "", err
 	}
 	if maxResults <= 0 {
 		maxResults = 20
 	}
-	call := svc.Users.Messages.List("me").Q(query).MaxResults(maxResults)
+	call := svc.Users.Messages.List("me").Q(query).MaxResults(maxResults).Context(ctx)
+	if pageToken != "" {
+		call = call.PageToken(pageToken)
+	}
 	resp, err := call.Do()
 	if err != nil {
-		return nil, fmt.Errorf("list messages: %w", err)
+		return nil, "", fmt.Errorf("list messages: %w", err)
 	}
 
-	var msgs []MessageMeta
-	for _, m := range resp.Messages {
-		msg, err := svc.Users.Messages.Get("me", m.Id).Format("metadata").MetadataHeaders("Subject", "From", "Date").Do()
+	fetched := fetchEach(len(resp.Messages), func(i int) *MessageMeta {
+		id := resp.Messages[i].Id
+		msg, err := svc.Users.Messages.Get("me", id).Format("metadata").MetadataHeaders("Subject", "From", "To", "Cc", "Date", "Message-ID").Context(ctx).Do()
 		if err != nil {
-			log.Printf("Warning: get message %s: %v", m.Id, err)
-			continue
+			log.Printf("Warning: get message %s: %v", id, err)
+			return nil
 		}
-		msgs = append(msgs, MessageMeta{
+		return &MessageMeta{
 			ID:       msg.Id,
 			ThreadID: msg.ThreadId,
 			Subject:  decodeRFC2047(getHeader(msg.Payload.Headers, "Subject")),
 			From:     decodeRFC2047(getHeader(msg.Payload.Headers, "From")),
+			To:       decodeRFC2047(getHeader(msg.Payload.Headers, "To")),
+			Cc:       decodeRFC2047(getHeader(msg.Payload.Headers, "Cc")),
 			Date:     getHeader(msg.Payload.Headers, "Date"),
 			Snippet:  msg.Snippet,
 			Labels:   msg.LabelIds,
-		})
+			RFC822ID: getHeader(msg.Payload.Headers, "Message-ID"),
+		}
+	})
+	var msgs []MessageMeta
+	for _, m := range fetched {
+		if m != nil {
+			msgs = append(msgs, *m)
+		}
 	}
-	return msgs, nil
+	return msgs, resp.NextPageToken, nil
+}
+
+// metadataFetchers caps the metadata requests one list or history call has in
+// flight. The Gmail API client has no batch endpoint, so concurrency is what
+// turns a page of N messages from N+1 sequential round trips into a few.
+const metadataFetchers = 8
+
+// fetchEach calls get for 0..n-1, at most metadataFetchers at a time, and
+// returns the results in order.
+func fetchEach[T any](n int, get func(i int) T) []T {
+	out := make([]T, n)
+	sem := make(chan struct{}, metadataFetchers)
+	var wg sync.WaitGroup
+	for i := range n {
+		sem <- struct{}{}
+		wg.Add(1)
+		go func() {
+			defer func() { <-sem; wg.Done() }()
+			out[i] = get(i)
+		}()
+	}
+	wg.Wait()
+	return out
+}
+
+// ListThreads lists threads matching a query, paged like ListMessages.
+func (c *Client) ListThreads(ctx context.Context, query string, maxResults int64, pageToken string) ([]ThreadMeta, string, error) {
+	svc, err := c.getService(ctx)
+	if err != nil {
+		return nil, "", err
+	}
+	if maxResults <= 0 {
+		maxResults = 20
+	}
+	call := svc.Users.Threads.List("me").Q(query).MaxResults(maxResults).Context(ctx)
+	if pageToken != "" {
+		call = call.PageToken(pageToken)
+	}
+	resp, err := call.Do()
+	if err != nil {
+		return nil, "", fmt.Errorf("list threads: %w", err)
+	}
+
+	fetched := fetchEach(len(resp.Threads), func(i int) *gm.Thread {
+		id := resp.Threads[i].Id
+		thread, err := svc.Users.Threads.Get("me", id).Format("metadata").MetadataHeaders("Subject", "From", "Date").Context(ctx).Do()
+		if err != nil {
+			log.Printf("Warning: get thread %s: %v", id, err)
+			return nil
+		}
+		return thread
+	})
+	var threads []ThreadMeta
+	for _, thread := range fetched {
+		if thread != nil {
+			threads = append(threads, threadMeta(thread))
+		}
+	}
+	return threads, resp.NextPageToken, nil
+}
+
+func threadMeta(thread *gm.Thread) ThreadMeta {
+	meta := ThreadMeta{ID: thread.Id, Messages: len(thread.Messages)}
+	for i, msg := range thread.Messages {
+		var headers []*gm.MessagePartHeader
+		if msg.Payload != nil {
+			headers = msg.Payload.Headers
+		}
+		if i == 0 {
+			meta.Subject = decodeRFC2047(getHeader(headers, "Subject"))
+			meta.From = decodeRFC2047(getHeader(headers, "From"))
+		}
+		meta.Date = getHeader(headers, "Date")
+		meta.Snippet = msg.Snippet
+		for _, l := range msg.LabelIds {
+			if !slices.Contains(meta.Labels, l) {
+				meta.Labels = append(meta.Labels, l)
+			}
+		}
+	}
+	return meta
 }
 
 // GetMessage gets a full message by ID.
@@ -174,7 +383,7 @@ func (c *Client) GetMessage(ctx context.Context, id string) (*MessageFull, error
 	if err != nil {
 		return nil, err
 	}
-	msg, err := svc.Users.Messages.Get("me", id).Format("full").Do()
+	msg, err := svc.Users.Messages.Get("me", id).Format("full").Context(ctx).Do()
 	if err != nil {
 		return nil, fmt.Errorf("get message: %w", err)
 	}
@@ -188,6 +397,9 @@ func (c *Client) GetMessage(ctx context.Context, id string) (*MessageFull, error
 		Body:     extractBody(msg.Payload),
 		Labels:   msg.LabelIds,
 		Snippet:  msg.Snippet,
+		RFC822ID: getHeader(msg.Payload.Headers, "Message-ID"),
+
+		Attachments: extractAttachments(msg.Payload),
 	}, nil
 }
 
@@ -200,26 +412,32 @@ type ModifyRequest struct {
 	Star         bool     `json:"star"`
 }
 
-// ModifyMessage modifies labels on a message.
-func (c *Client) ModifyMessage(ctx context.Context, id string, req ModifyRequest) error {
-	svc, err := c.getService(ctx)
-	if err != nil {
-		return err
-	}
-	mod := &gm.ModifyMessageRequest{
-		AddLabelIds:    req.AddLabels,
-		RemoveLabelIds: req.RemoveLabels,
-	}
+// Labels returns the label IDs the request adds and removes, with archive,
+// markRead and star as INBOX, UNREAD and STARRED.
+func (req ModifyRequest) Labels() (add, remove []string) {
+	add = slices.Clone(req.AddLabels)
+	remove = slices.Clone(req.RemoveLabels)
 	if req.Archive {
-		mod.RemoveLabelIds = append(mod.RemoveLabelIds, "INBOX")
+		remove = append(remove, "INBOX")
 	}
 	if req.MarkRead {
-		mod.RemoveLabelIds = append(mod.RemoveLabelIds, "UNREAD")
+		remove = append(remove, "UNREAD")
 	}
 	if req.Star {
-		mod.AddLabelIds = append(mod.AddLabelIds, "STARRED")
+		add = append(add, "STARRED")
+	}
+	return add, remove
+}
+
+// ModifyMessage modifies labels on a message.
+func (c *Client) ModifyMessage(ctx context.Context, id string, req ModifyRequest) error {
+	svc, err := c.getService(ctx)
+	if err != nil {
+		return err
 	}
-	_, err = svc.Users.Messages.Modify("me", id, mod).Do()
+	add, remove := req.Labels()
+	mod := &gm.ModifyMessageRequest{AddLabelIds: add, RemoveLabelIds: remove}
+	_, err = svc.Users.Messages.Modify("me", id, mod).Context(ctx).Do()
 	return err
 }
 
@@ -236,7 +454,7 @@ func (c *Client) ListLabels(ctx context.Context) ([]LabelInfo, error) {
 	if err != nil {
 		return nil, err
 	}
-	resp, err := svc.Users.Labels.List("me").Do()
+	resp, err := svc.Users.Labels.List("me").Context(ctx).Do()
 	if err != nil {
 		return nil, err
 	}
@@ -253,7 +471,7 @@ func (c *Client) GetThread(ctx context.Context, threadID string) ([]MessageFull,
 	if err != nil {
 		return nil, err
 	}
-	thread, err := svc.Users.Threads.Get("me", threadID).Format("full").Do()
+	thread, err := svc.Users.Threads.Get("me", threadID).Format("full").Context(ctx).Do()
 	if err != nil {
 		return nil, err
 	}
@@ -269,107 +487,234 @@ func (c *Client) GetThread(ctx context.Context, threadID string) ([]MessageFull,
 			Body:     extractBody(msg.Payload),
 			Labels:   msg.LabelIds,
 			Snippet:  msg.Snippet,
+			RFC822ID: getHeader(msg.Payload.Headers, "Message-ID"),
+
+			Attachments: extractAttachments(msg.Payload),
 		})
 	}
 	return msgs, nil
 }
 
+// GetAttachment downloads an attachment's content.
+func (c *Client) GetAttachment(ctx context.Context, messageID, attachmentID string) ([]byte, error) {
+	svc, err := c.getService(ctx)
+	if err != nil {
+		return nil, err
+	}
+	body, err := svc.Users.Messages.Attachments.Get("me", messageID, attachmentID).Context(ctx).Do()
+	if err != nil {
+		return nil, fmt.Errorf("get attachment: %w", err)
+	}
+	return base64.URLEncoding.DecodeString(body.Data)
+}
+
 // GetCurrentHistoryID returns the latest historyId.
 func (c *Client) GetCurrentHistoryID(ctx context.Context) (uint64, error) {
 	svc, err := c.getService(ctx)
 	if err != nil {
 		return 0, err
 	}
-	profile, err := svc.Users.GetProfile("me").Do()
+	profile, err := svc.Users.GetProfile("me").Context(ctx).Do()
 	if err != nil {
 		return 0, err
 	}
 	return profile.HistoryId, nil
 }
 
-// HistoryMessage is a new message from history.
+// ErrHistoryExpired is returned by GetHistory when the start history ID is
+// no longer valid; the poller starts over from the current one.
+var ErrHistoryExpired = errors.New("history ID expired")
+
+// History events, as named in rules' match.events.
+const (
+	EventMessageAdded   = "message_added"
+	EventLabelAdded     = "label_added"
+	EventLabelRemoved   = "label_removed"
+	EventMessageDeleted = "message_deleted"
+)
+
+// historyTypes maps history events to the history.list historyTypes values.
+var historyTypes = map[string]string{
+	EventMessageAdded:   "messageAdded",
+	EventLabelAdded:     "labelAdded",
+	EventLabelRemoved:   "labelRemoved",
+	EventMessageDeleted: "messageDeleted",
+}
+
+// HistoryMessage is a message a history event happened to: a new message,
+// or one whose labels changed or that was deleted. A deleted message has
+// only its IDs.
 type HistoryMessage struct {
-	ID       string   `json:"id"`
-	ThreadID string   `json:"threadId"`
-	Labels   []string `json:"labels"`
-	Subject  string   `json:"subject"`
-	From     string   `json:"from"`
-	Snippet  string   `json:"snippet"`
+	// Event is one of the Event* constants; empty means EventMessageAdded.
+	Event string `json:"event,omitempty"`
+	// ChangedLabels are the labels a label_added or label_removed event
+	// added or removed.
+	ChangedLabels []string `json:"changedLabels,omitempty"`
+	ID            string   `json:"id"`
+	ThreadID      string   `json:"threadId"`
+	Labels        []string `json:"labels"`
+	Subject       string   `json:"subject"`
+	From          string   `json:"from"`
+	To            string   `json:"to"`
+	Cc            string   `json:"cc"`
+	Snippet       string   `json:"snippet"`
+	// RFC822ID is the Message-ID header, used to run Gmail searches against this message.
+	RFC822ID string `json:"rfc822Id,omitempty"`
 }
 
-// GetHistory returns new messages since startHistoryId.
-// Deduplicates by message ID to avoid redundant API calls.
-func (c *Client) GetHistory(ctx context.Context, startHistoryID uint64) ([]HistoryMessage, uint64, error) {
-	svc, err := c.getService(ctx)
-	if err != nil {
-		return nil, 0, err
+// event returns the message's history event, message_added when unset.
+func (m HistoryMessage) event() string {
+	if m.Event == "" {
+		return EventMessageAdded
 	}
+	return m.Event
+}
+
+// key identifies the history entry: its event and message ID.
+func (m HistoryMessage) key() string {
+	return m.event() + "/" + m.ID
+}
+
+// historyMsg is a message ID collected from history before its metadata is fetched.
+type historyMsg struct {
+	Event         string
+	ID            string
+	ThreadID      string
+	Labels        []string
+	ChangedLabels []string
+}
 
-	// Collect unique message IDs and their basic info from history
-	type rawMsg struct {
-		ID       string
-		ThreadID string
-		Labels   []string
+// pageHistory walks history.list pages and collects one entry per message and
+// event, for the events asked for (nil means message_added only). Label
+// changes to the same message are merged into one entry.
+// When maxPages or maxMessages is reached it stops at a history record boundary and
+// returns that record's ID as the next start, so nothing is skipped; otherwise it
+// returns the mailbox's current historyId.
+func pageHistory(fetch func(pageToken string) (*gm.ListHistoryResponse, error), events []string, maxPages, maxMessages int) ([]historyMsg, uint64, bool, error) {
+	if len(events) == 0 {
+		events = []string{EventMessageAdded}
+	}
+	seen := make(map[string]int) // event/ID → index in msgs
+	var msgs []historyMsg
+	add := func(event string, msg *gm.Message, changed []string) {
+		if msg == nil || !slices.Contains(events, event) {
+			return
+		}
+		key := event + "/" + msg.Id
+		if i, ok := seen[key]; ok {
+			for _, l := range changed {
+				if !slices.Contains(msgs[i].ChangedLabels, l) {
+					msgs[i].ChangedLabels = append(msgs[i].ChangedLabels, l)
+				}
+			}
+			if msg.LabelIds != nil {
+				msgs[i].Labels = msg.LabelIds // the latest record has the current labels
+			}
+			return
+		}
+		seen[key] = len(msgs)
+		msgs = append(msgs, historyMsg{Event: event, ID: msg.Id, ThreadID: msg.ThreadId, Labels: msg.LabelIds, ChangedLabels: slices.Clone(changed)})
 	}
-	seen := make(map[string]bool)
-	var rawMsgs []rawMsg
-	var newHistoryID uint64
 	pageToken := ""
 
-	for {
-		call := svc.Users.History.List("me").StartHistoryId(startHistoryID).HistoryTypes("messageAdded")
-		if pageToken != "" {
-			call = call.PageToken(pageToken)
-		}
-		resp, err := call.Do()
+	for pages := 1; ; pages++ {
+		resp, err := fetch(pageToken)
 		if err != nil {
-			return nil, 0, fmt.Errorf("history.list: %w", err)
+			return nil, 0, false, fmt.Errorf("history.list: %w", err)
 		}
-		newHistoryID = resp.HistoryId
 
 		for _, h := range resp.History {
 			for _, ma := range h.MessagesAdded {
-				msg := ma.Message
-				if seen[msg.Id] {
-					continue
-				}
-				seen[msg.Id] = true
-				rawMsgs = append(rawMsgs, rawMsg{
-					ID:       msg.Id,
-					ThreadID: msg.ThreadId,
-					Labels:   msg.LabelIds,
-				})
+				add(EventMessageAdded, ma.Message, nil)
+			}
+			for _, la := range h.LabelsAdded {
+				add(EventLabelAdded, la.Message, la.LabelIds)
+			}
+			for _, lr := range h.LabelsRemoved {
+				add(EventLabelRemoved, lr.Message, lr.LabelIds)
+			}
+			for _, md := range h.MessagesDeleted {
+				add(EventMessageDeleted, md.Message, nil)
+			}
+			if maxMessages > 0 && len(msgs) >= maxMessages {
+				return msgs, h.Id, true, nil
 			}
 		}
 
 		if resp.NextPageToken == "" {
-			break
+			return msgs, resp.HistoryId, false, nil
+		}
+		if maxPages > 0 && pages >= maxPages && len(resp.History) > 0 {
+			return msgs, resp.History[len(resp.History)-1].Id, true, nil
 		}
 		pageToken = resp.NextPageToken
 	}
+}
+
+// GetHistory returns the history events since startHistoryId, new messages
+// and whichever others SetHistoryEvents asked for.
+// Deduplicates by message ID and event to avoid redundant API calls.
+func (c *Client) GetHistory(ctx context.Context, startHistoryID uint64) ([]HistoryMessage, uint64, error) {
+	svc, err := c.getService(ctx)
+	if err != nil {
+		return nil, 0, err
+	}
 
-	// Fetch metadata for each unique message
-	var allMsgs []HistoryMessage
-	for _, rm := range rawMsgs {
-		full, err := svc.Users.Messages.Get("me", rm.ID).Format("metadata").MetadataHeaders("Subject", "From").Do()
+	events := c.historyEvents
+	if len(events) == 0 {
+		events = []string{EventMessageAdded}
+	}
+	var types []string
+	for _, e := range events {
+		types = append(types, historyTypes[e])
+	}
+	fetch := func(pageToken string) (*gm.ListHistoryResponse, error) {
+		call := svc.Users.History.List("me").StartHistoryId(startHistoryID).HistoryTypes(types...).Context(ctx)
+		if pageToken != "" {
+			call = call.PageToken(pageToken)
+		}
+		return call.Do()
+	}
+	rawMsgs, newHistoryID, truncated, err := pageHistory(fetch, events, c.maxHistoryPages, c.maxHistoryMessages)
+	if err != nil {
+		return nil, 0, err
+	}
+	if truncated {
+		log.Printf("Gmail history for %s: stopped at historyId %d after %d messages, continuing next poll", c.email, newHistoryID, len(rawMsgs))
+	}
+
+	// Fetch metadata for each unique message, keeping history order
+	allMsgs := fetchEach(len(rawMsgs), func(i int) HistoryMessage {
+		rm := rawMsgs[i]
+		if rm.Event == EventMessageDeleted {
+			// Gone from the mailbox: there is no metadata to fetch
+			return HistoryMessage{Event: rm.Event, ID: rm.ID, ThreadID: rm.ThreadID}
+		}
+		full, err := svc.Users.Messages.Get("me", rm.ID).Format("metadata").MetadataHeaders("Subject", "From", "To", "Cc", "Message-ID").Context(ctx).Do()
 		if err != nil {
 			log.Printf("Warning: get history message %s: %v", rm.ID, err)
-			allMsgs = append(allMsgs, HistoryMessage{
-				ID:       rm.ID,
-				ThreadID: rm.ThreadID,
-				Labels:   rm.Labels,
-			})
-			continue
+			return HistoryMessage{
+				Event:         rm.Event,
+				ChangedLabels: rm.ChangedLabels,
+				ID:            rm.ID,
+				ThreadID:      rm.ThreadID,
+				Labels:        rm.Labels,
+			}
 		}
-		allMsgs = append(allMsgs, HistoryMessage{
-			ID:       full.Id,
-			ThreadID: full.ThreadId,
-			Labels:   full.LabelIds,
-			Subject:  decodeRFC2047(getHeader(full.Payload.Headers, "Subject")),
-			From:     decodeRFC2047(getHeader(full.Payload.Headers, "From")),
-			Snippet:  full.Snippet,
-		})
-	}
+		return HistoryMessage{
+			Event:         rm.Event,
+			ChangedLabels: rm.ChangedLabels,
+			ID:            full.Id,
+			ThreadID:      full.ThreadId,
+			Labels:        full.LabelIds,
+			Subject:       decodeRFC2047(getHeader(full.Payload.Headers, "Subject")),
+			From:          decodeRFC2047(getHeader(full.Payload.Headers, "From")),
+			To:            decodeRFC2047(getHeader(full.Payload.Headers, "To")),
+			Cc:            decodeRFC2047(getHeader(full.Payload.Headers, "Cc")),
+			Snippet:       full.Snippet,
+			RFC822ID:      getHeader(full.Payload.Headers, "Message-ID"),
+		}
+	})
 
 	return allMsgs, newHistoryID, nil
 }
@@ -1,9 +1,21 @@
 package gmail
 
 import (
+	"context"
 	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync/atomic"
 	"testing"
+	"time"
 
+	"github.com/katalabut/openclaw-relay/internal/tokens"
+	"golang.org/x/oauth2"
 	gm "google.golang.org/api/gmail/v1"
 )
 
@@ -70,6 +82,35 @@ func TestExtractBody_Nil(t *testing.T) {
 	}
 }
 
+func TestExtractAttachments(t *testing.T) {
+	payload := &gm.MessagePart{
+		MimeType: "multipart/mixed",
+		Parts: []*gm.MessagePart{
+			{MimeType: "text/plain", Body: &gm.MessagePartBody{Data: "aGk="}},
+			{
+				MimeType: "multipart/related",
+				Parts: []*gm.MessagePart{
+					{PartId: "1.1", MimeType: "image/png", Filename: "logo.png", Body: &gm.MessagePartBody{AttachmentId: "A1", Size: 2048}},
+				},
+			},
+			{PartId: "2", MimeType: "application/pdf", Filename: "=?UTF-8?B?0YHRh9C10YIucGRm?=", Body: &gm.MessagePartBody{AttachmentId: "A2", Size: 4096}},
+		},
+	}
+	got := extractAttachments(payload)
+	if len(got) != 2 {
+		t.Fatalf("expected 2 attachments, got %+v", got)
+	}
+	if got[0].ID != "A1" || got[0].PartID != "1.1" || got[0].Filename != "logo.png" || got[0].Size != 2048 {
+		t.Errorf("unexpected first attachment %+v", got[0])
+	}
+	if got[1].Filename != "счет.pdf" || got[1].MimeType != "application/pdf" {
+		t.Errorf("unexpected second attachment %+v", got[1])
+	}
+	if extractAttachments(nil) != nil {
+		t.Error("expected no attachments for a nil payload")
+	}
+}
+
 func TestDecodeRFC2047_PlainString(t *testing.T) {
 	result := decodeRFC2047("Hello World")
 	if result != "Hello World" {
@@ -152,3 +193,251 @@ func TestDecodeRFC2047_EncodedSubject(t *testing.T) {
 		t.Errorf("expected 'Привет', got '%s'", result)
 	}
 }
+
+// historyPages fakes history.list: each page holds records with one new message each.
+func historyPages(pages [][]uint64, current uint64) (func(string) (*gm.ListHistoryResponse, error), *int) {
+	calls := 0
+	return func(token string) (*gm.ListHistoryResponse, error) {
+		i := calls
+		calls++
+		resp := &gm.ListHistoryResponse{HistoryId: current}
+		for _, id := range pages[i] {
+			resp.History = append(resp.History, &gm.History{
+				Id:            id,
+				MessagesAdded: []*gm.HistoryMessageAdded{{Message: &gm.Message{Id: fmt.Sprintf("m%d", id)}}},
+			})
+		}
+		if i+1 < len(pages) {
+			resp.NextPageToken = fmt.Sprintf("p%d", i+1)
+		}
+		return resp, nil
+	}, &calls
+}
+
+func TestThreadMeta(t *testing.T) {
+	hdr := func(kv ...string) *gm.MessagePart {
+		var h []*gm.MessagePartHeader
+		for i := 0; i < len(kv); i += 2 {
+			h = append(h, &gm.MessagePartHeader{Name: kv[i], Value: kv[i+1]})
+		}
+		return &gm.MessagePart{Headers: h}
+	}
+	got := threadMeta(&gm.Thread{Id: "t1", Messages: []*gm.Message{
+		{Payload: hdr("Subject", "Invoice 1042", "From", "Billing <billing@example.com>", "Date", "Mon, 14 Sep 2026 08:00:00 +0000"), Snippet: "Please find", LabelIds: []string{"INBOX", "UNREAD"}},
+		{Payload: hdr("Subject", "Re: Invoice 1042", "From", "me@example.com", "Date", "Mon, 14 Sep 2026 09:30:00 +0000"), Snippet: "Thanks, paid", LabelIds: []string{"SENT", "INBOX"}},
+	}})
+	want := ThreadMeta{
+		ID:       "t1",
+		Subject:  "Invoice 1042",
+		From:     "Billing <billing@example.com>",
+		Date:     "Mon, 14 Sep 2026 09:30:00 +0000",
+		Snippet:  "Thanks, paid",
+		Messages: 2,
+		Labels:   []string{"INBOX", "UNREAD", "SENT"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("threadMeta = %+v, want %+v", got, want)
+	}
+}
+
+func TestPageHistory_Unlimited(t *testing.T) {
+	fetch, calls := historyPages([][]uint64{{11, 12}, {13}, {14}}, 20)
+	msgs, next, truncated, err := pageHistory(fetch, nil, 0, 0)
+	if err != nil || truncated {
+		t.Fatalf("unexpected err=%v truncated=%v", err, truncated)
+	}
+	if len(msgs) != 4 || next != 20 || *calls != 3 {
+		t.Errorf("got %d msgs, next=%d, calls=%d", len(msgs), next, *calls)
+	}
+}
+
+func TestPageHistory_MaxPages(t *testing.T) {
+	fetch, calls := historyPages([][]uint64{{11, 12}, {13}, {14}}, 20)
+	msgs, next, truncated, _ := pageHistory(fetch, nil, 2, 0)
+	if !truncated || len(msgs) != 3 || *calls != 2 {
+		t.Fatalf("got %d msgs, truncated=%v, calls=%d", len(msgs), truncated, *calls)
+	}
+	// Continue from the last processed record, not the mailbox's current historyId
+	if next != 13 {
+		t.Errorf("next = %d, want 13", next)
+	}
+}
+
+func TestPageHistory_MaxMessages(t *testing.T) {
+	fetch, _ := historyPages([][]uint64{{11, 12, 13}, {14}}, 20)
+	msgs, next, truncated, _ := pageHistory(fetch, nil, 0, 2)
+	if !truncated || len(msgs) != 2 || next != 12 {
+		t.Errorf("got %d msgs, next=%d, truncated=%v", len(msgs), next, truncated)
+	}
+}
+
+func TestPageHistory_Events(t *testing.T) {
+	msg := func(id string, labels ...string) *gm.Message {
+		return &gm.Message{Id: id, ThreadId: "t" + id, LabelIds: labels}
+	}
+	resp := &gm.ListHistoryResponse{HistoryId: 30, History: []*gm.History{
+		{Id: 21, MessagesAdded: []*gm.HistoryMessageAdded{{Message: msg("m1", "INBOX")}}},
+		{Id: 22, LabelsAdded: []*gm.HistoryLabelAdded{{LabelIds: []string{"IMPORTANT"}, Message: msg("m1", "INBOX", "IMPORTANT")}}},
+		{Id: 23, LabelsAdded: []*gm.HistoryLabelAdded{{LabelIds: []string{"STARRED"}, Message: msg("m1", "INBOX", "IMPORTANT", "STARRED")}}},
+		{Id: 24, LabelsRemoved: []*gm.HistoryLabelRemoved{{LabelIds: []string{"INBOX"}, Message: msg("m2")}}},
+		{Id: 25, MessagesDeleted: []*gm.HistoryMessageDeleted{{Message: msg("m3")}}},
+	}}
+	fetch := func(string) (*gm.ListHistoryResponse, error) { return resp, nil }
+
+	msgs, _, _, _ := pageHistory(fetch, nil, 0, 0)
+	if len(msgs) != 1 || msgs[0].Event != EventMessageAdded {
+		t.Fatalf("default events: got %+v", msgs)
+	}
+
+	msgs, _, _, _ = pageHistory(fetch, []string{EventLabelAdded, EventLabelRemoved, EventMessageDeleted}, 0, 0)
+	want := []historyMsg{
+		{Event: EventLabelAdded, ID: "m1", ThreadID: "tm1", Labels: []string{"INBOX", "IMPORTANT", "STARRED"}, ChangedLabels: []string{"IMPORTANT", "STARRED"}},
+		{Event: EventLabelRemoved, ID: "m2", ThreadID: "tm2", ChangedLabels: []string{"INBOX"}},
+		{Event: EventMessageDeleted, ID: "m3", ThreadID: "tm3"},
+	}
+	if !reflect.DeepEqual(msgs, want) {
+		t.Errorf("pageHistory =\n%+v\nwant\n%+v", msgs, want)
+	}
+}
+
+func TestPageHistory_Error(t *testing.T) {
+	fetch := func(string) (*gm.ListHistoryResponse, error) { return nil, errors.New("404 notFound") }
+	if _, _, _, err := pageHistory(fetch, nil, 0, 0); err == nil || !strings.Contains(err.Error(), "history.list") {
+		t.Errorf("expected wrapped error, got %v", err)
+	}
+}
+
+func TestFetchEach(t *testing.T) {
+	var inFlight, peak atomic.Int32
+	got := fetchEach(50, func(i int) int {
+		n := inFlight.Add(1)
+		for {
+			p := peak.Load()
+			if n <= p || peak.CompareAndSwap(p, n) {
+				break
+			}
+		}
+		time.Sleep(time.Millisecond)
+		inFlight.Add(-1)
+		return i * i
+	})
+	for i, v := range got {
+		if v != i*i {
+			t.Fatalf("result %d = %d, out of order", i, v)
+		}
+	}
+	if p := peak.Load(); p > metadataFetchers || p < 2 {
+		t.Errorf("peak concurrency %d, want 2..%d", p, metadataFetchers)
+	}
+	if got := fetchEach(0, func(int) int { t.Fatal("called for n=0"); return 0 }); len(got) != 0 {
+		t.Errorf("expected no results, got %v", got)
+	}
+}
+
+// TestListMessages_Metadata runs ListMessages against a fake Gmail API whose
+// metadata responses arrive in reverse order, skipping one that fails.
+func TestListMessages_Metadata(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch id := strings.TrimPrefix(r.URL.Path, "/gmail/v1/users/me/messages"); id {
+		case "":
+			if r.URL.Query().Get("pageToken") != "p2" {
+				t.Errorf("pageToken not passed through: %s", r.URL.RawQuery)
+			}
+			fmt.Fprint(w, `{"messages":[{"id":"m1"},{"id":"m2"},{"id":"m3"},{"id":"m4"}],"nextPageToken":"p3"}`)
+		case "/m3":
+			http.Error(w, `{"error":{"code":404,"message":"Not Found"}}`, http.StatusNotFound)
+		default:
+			n := id[len(id)-1] - '0'
+			time.Sleep(time.Duration(5-n) * 10 * time.Millisecond)
+			fmt.Fprintf(w, `{"id":%q,"threadId":"t","payload":{"headers":[{"name":"Subject","value":"Subject %c"}]}}`, id[1:], n+'0')
+		}
+	}))
+	defer srv.Close()
+
+	c := newEndpointClient(t, srv.URL)
+	msgs, next, err := c.ListMessages(context.Background(), "is:unread", 4, "p2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var subjects []string
+	for _, m := range msgs {
+		subjects = append(subjects, m.ID+"="+m.Subject)
+	}
+	if strings.Join(subjects, ",") != "m1=Subject 1,m2=Subject 2,m4=Subject 4" || next != "p3" {
+		t.Errorf("got %v, next %q", subjects, next)
+	}
+}
+
+// newEndpointClient returns a client for me@example.com that talks to the
+// fake Gmail API at url.
+func newEndpointClient(t *testing.T, url string) *Client {
+	t.Helper()
+	store, err := tokens.NewStore(filepath.Join(t.TempDir(), "tokens.json.enc"), strings.Repeat("ab", 32))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := store.SaveGoogle(&oauth2.Token{AccessToken: "tok", RefreshToken: "r", Expiry: time.Now().Add(time.Hour)}, "me@example.com"); err != nil {
+		t.Fatal(err)
+	}
+	c := NewClientForAccount(store, &oauth2.Config{}, "me@example.com")
+	c.SetEndpoint(url + "/")
+	return c
+}
+
+// TestClient_Canceled checks that every call gives up when its context is
+// canceled, so the poller's watchdog can abandon a hung request.
+func TestClient_Canceled(t *testing.T) {
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-release:
+		}
+	}))
+	defer srv.Close()
+	defer close(release)
+	c := newEndpointClient(t, srv.URL)
+
+	calls := map[string]func(ctx context.Context) error{
+		"GetMessage": func(ctx context.Context) error {
+			_, err := c.GetMessage(ctx, "m1")
+			return err
+		},
+		"ModifyMessage": func(ctx context.Context) error {
+			return c.ModifyMessage(ctx, "m1", ModifyRequest{AddLabels: []string{"STARRED"}})
+		},
+		"ListLabels": func(ctx context.Context) error {
+			_, err := c.ListLabels(ctx)
+			return err
+		},
+		"GetThread": func(ctx context.Context) error {
+			_, err := c.GetThread(ctx, "t1")
+			return err
+		},
+		"ListMessages": func(ctx context.Context) error {
+			_, _, err := c.ListMessages(ctx, "is:unread", 10, "")
+			return err
+		},
+		"ListThreads": func(ctx context.Context) error {
+			_, _, err := c.ListThreads(ctx, "is:unread", 10, "")
+			return err
+		},
+	}
+	for name, call := range calls {
+		t.Run(name, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+			defer cancel()
+			done := make(chan error, 1)
+			go func() { done <- call(ctx) }()
+			select {
+			case err := <-done:
+				if !errors.Is(err, context.DeadlineExceeded) {
+					t.Errorf("expected the deadline error, got %v", err)
+				}
+			case <-time.After(5 * time.Second):
+				t.Fatal("call did not stop when its context ended")
+			}
+		})
+	}
+}
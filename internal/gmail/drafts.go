@@ -0,0 +1,300 @@
+package gmail
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
+	gm "google.golang.org/api/gmail/v1"
+)
+
+// Draft is a draft in the mailbox. The agent prepares drafts; the user
+// reviews and sends them from their own mail client.
+type Draft struct {
+	ID        string `json:"id"`
+	MessageID string `json:"messageId"`
+	ThreadID  string `json:"threadId"`
+	To        string `json:"to"`
+	Subject   string `json:"subject"`
+	Snippet   string `json:"snippet"`
+}
+
+// DraftRequest is the content of a new or replaced draft.
+type DraftRequest struct {
+	To      []string `json:"to"`
+	Cc      []string `json:"cc"`
+	Subject string   `json:"subject"`
+	Body    string   `json:"body"` // plain text
+	// ReplyTo is the Gmail ID of a message to answer: the draft joins its
+	// thread, and an empty subject becomes "Re: <original subject>".
+	ReplyTo string `json:"replyTo"`
+}
+
+func (r DraftRequest) validate() error {
+	if len(r.To) == 0 {
+		return errors.New("to is required")
+	}
+	for _, v := range append(append([]string{r.Subject}, r.To...), r.Cc...) {
+		if strings.ContainsAny(v, "\r\n") {
+			return errors.New("header values must not contain line breaks")
+		}
+	}
+	return nil
+}
+
+// replyHeaders are the headers of the message a draft answers.
+type replyHeaders struct {
+	ThreadID   string
+	MessageID  string // Message-ID header
+	References string
+	Subject    string
+}
+
+// buildRaw renders req as an RFC 2822 message, base64url-encoded for the API.
+func buildRaw(req DraftRequest, reply *replyHeaders) string {
+	return base64.URLEncoding.EncodeToString([]byte(buildMessage(req, reply)))
+}
+
+// buildMessage renders req as an RFC 2822 message.
+func buildMessage(req DraftRequest, reply *replyHeaders) string {
+	subject := req.Subject
+	var b strings.Builder
+	b.WriteString("To: " + strings.Join(req.To, ", ") + "\r\n")
+	if len(req.Cc) > 0 {
+		b.WriteString("Cc: " + strings.Join(req.Cc, ", ") + "\r\n")
+	}
+	if reply != nil {
+		if subject == "" {
+			subject = reply.Subject
+			if !strings.HasPrefix(strings.ToLower(subject), "re:") {
+				subject = "Re: " + subject
+			}
+		}
+		if reply.MessageID != "" {
+			b.WriteString("In-Reply-To: " + reply.MessageID + "\r\n")
+			b.WriteString("References: " + strings.TrimSpace(reply.References+" "+reply.MessageID) + "\r\n")
+		}
+	}
+	b.WriteString("Subject: " + mime.QEncoding.Encode("utf-8", subject) + "\r\n")
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=\"UTF-8\"\r\n")
+	b.WriteString("Content-Transfer-Encoding: base64\r\n\r\n")
+	body := base64.StdEncoding.EncodeToString([]byte(req.Body))
+	for len(body) > 76 {
+		b.WriteString(body[:76] + "\r\n")
+		body = body[76:]
+	}
+	b.WriteString(body + "\r\n")
+	return b.String()
+}
+
+// draftMessage builds the API message for req, looking up the answered message
+// when req.ReplyTo is set.
+func draftMessage(ctx context.Context, svc *gm.Service, req DraftRequest) (*gm.Message, error) {
+	var reply *replyHeaders
+	if req.ReplyTo != "" {
+		orig, err := svc.Users.Messages.Get("me", req.ReplyTo).Format("metadata").
+			MetadataHeaders("Message-ID", "References", "Subject").Context(ctx).Do()
+		if err != nil {
+			return nil, fmt.Errorf("get replied message: %w", err)
+		}
+		reply = &replyHeaders{
+			ThreadID:   orig.ThreadId,
+			MessageID:  getHeader(orig.Payload.Headers, "Message-ID"),
+			References: getHeader(orig.Payload.Headers, "References"),
+			Subject:    decodeRFC2047(getHeader(orig.Payload.Headers, "Subject")),
+		}
+	}
+	msg := &gm.Message{Raw: buildRaw(req, reply)}
+	if reply != nil {
+		msg.ThreadId = reply.ThreadID
+	}
+	return msg, nil
+}
+
+func draftFromAPI(d *gm.Draft, req DraftRequest) *Draft {
+	out := &Draft{ID: d.Id, To: strings.Join(req.To, ", "), Subject: req.Subject}
+	if d.Message != nil {
+		out.MessageID = d.Message.Id
+		out.ThreadID = d.Message.ThreadId
+	}
+	return out
+}
+
+// ListDrafts lists up to maxResults drafts with their recipients and subject.
+func (c *Client) ListDrafts(ctx context.Context, maxResults int64) ([]Draft, error) {
+	svc, err := c.getService(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if maxResults <= 0 {
+		maxResults = 20
+	}
+	resp, err := svc.Users.Drafts.List("me").MaxResults(maxResults).Context(ctx).Do()
+	if err != nil {
+		return nil, fmt.Errorf("list drafts: %w", err)
+	}
+	var drafts []Draft
+	for _, d := range resp.Drafts {
+		full, err := svc.Users.Drafts.Get("me", d.Id).Format("metadata").Context(ctx).Do()
+		if err != nil {
+			log.Printf("Warning: get draft %s: %v", d.Id, err)
+			continue
+		}
+		draft := Draft{ID: full.Id}
+		if m := full.Message; m != nil {
+			draft.MessageID, draft.ThreadID, draft.Snippet = m.Id, m.ThreadId, m.Snippet
+			if m.Payload != nil {
+				draft.To = decodeRFC2047(getHeader(m.Payload.Headers, "To"))
+				draft.Subject = decodeRFC2047(getHeader(m.Payload.Headers, "Subject"))
+			}
+		}
+		drafts = append(drafts, draft)
+	}
+	return drafts, nil
+}
+
+// CreateDraft saves a new draft. Nothing is sent.
+func (c *Client) CreateDraft(ctx context.Context, req DraftRequest) (*Draft, error) {
+	svc, err := c.getService(ctx)
+	if err != nil {
+		return nil, err
+	}
+	msg, err := draftMessage(ctx, svc, req)
+	if err != nil {
+		return nil, err
+	}
+	d, err := svc.Users.Drafts.Create("me", &gm.Draft{Message: msg}).Context(ctx).Do()
+	if err != nil {
+		return nil, fmt.Errorf("create draft: %w", err)
+	}
+	return draftFromAPI(d, req), nil
+}
+
+// UpdateDraft replaces the content of draft id.
+func (c *Client) UpdateDraft(ctx context.Context, id string, req DraftRequest) (*Draft, error) {
+	svc, err := c.getService(ctx)
+	if err != nil {
+		return nil, err
+	}
+	msg, err := draftMessage(ctx, svc, req)
+	if err != nil {
+		return nil, err
+	}
+	d, err := svc.Users.Drafts.Update("me", id, &gm.Draft{Id: id, Message: msg}).Context(ctx).Do()
+	if err != nil {
+		return nil, fmt.Errorf("update draft: %w", err)
+	}
+	return draftFromAPI(d, req), nil
+}
+
+// DeleteDraft deletes draft id permanently.
+func (c *Client) DeleteDraft(ctx context.Context, id string) error {
+	svc, err := c.getService(ctx)
+	if err != nil {
+		return err
+	}
+	if err := svc.Users.Drafts.Delete("me", id).Context(ctx).Do(); err != nil {
+		return fmt.Errorf("delete draft: %w", err)
+	}
+	return nil
+}
+
+// handleDrafts serves GET (list) and POST (create) on /api/gmail/drafts.
+func (h *Handler) handleDrafts(w http.ResponseWriter, r *http.Request) {
+	if !draftsAllowed(w, r) {
+		return
+	}
+	client, ok := h.resolveClient(r)
+	if !ok {
+		jsonError(w, "unknown account", http.StatusBadRequest)
+		return
+	}
+	switch r.Method {
+	case http.MethodGet:
+		max := int64(20)
+		if v, err := strconv.ParseInt(r.URL.Query().Get("max"), 10, 64); err == nil && v > 0 {
+			max = v
+		}
+		drafts, err := client.ListDrafts(r.Context(), max)
+		if err != nil {
+			apiError(w, err)
+			return
+		}
+		jsonResponse(w, map[string]any{"drafts": drafts})
+	case http.MethodPost:
+		req, ok := decodeDraftRequest(w, r)
+		if !ok {
+			return
+		}
+		draft, err := client.CreateDraft(r.Context(), req)
+		if err != nil {
+			apiError(w, err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(draft)
+	default:
+		jsonError(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// handleDraft serves PUT (replace) and DELETE on /api/gmail/drafts/{id}.
+func (h *Handler) handleDraft(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPut && r.Method != http.MethodDelete {
+		jsonError(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if !draftsAllowed(w, r) {
+		return
+	}
+	client, ok := h.resolveClient(r)
+	if !ok {
+		jsonError(w, "unknown account", http.StatusBadRequest)
+		return
+	}
+	id := strings.TrimPrefix(r.URL.Path, "/api/gmail/drafts/")
+	if id == "" || strings.Contains(id, "/") {
+		jsonError(w, "missing draft id", http.StatusBadRequest)
+		return
+	}
+	if r.Method == http.MethodDelete {
+		if err := client.DeleteDraft(r.Context(), id); err != nil {
+			apiError(w, err)
+			return
+		}
+		jsonResponse(w, map[string]bool{"ok": true})
+		return
+	}
+	req, ok := decodeDraftRequest(w, r)
+	if !ok {
+		return
+	}
+	draft, err := client.UpdateDraft(r.Context(), id, req)
+	if err != nil {
+		apiError(w, err)
+		return
+	}
+	jsonResponse(w, draft)
+}
+
+func decodeDraftRequest(w http.ResponseWriter, r *http.Request) (DraftRequest, bool) {
+	var req DraftRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		jsonError(w, "invalid request body", http.StatusBadRequest)
+		return req, false
+	}
+	if err := req.validate(); err != nil {
+		jsonError(w, err.Error(), http.StatusBadRequest)
+		return req, false
+	}
+	return req, true
+}
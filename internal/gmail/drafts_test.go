@@ -0,0 +1,160 @@
+package gmail
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/mail"
+	"strings"
+	"testing"
+)
+
+func TestBuildRaw(t *testing.T) {
+	tests := []struct {
+		name        string
+		req         DraftRequest
+		reply       *replyHeaders
+		wantSubject string
+		wantRefs    string
+	}{
+		{
+			name:        "new message",
+			req:         DraftRequest{To: []string{"a@example.com"}, Cc: []string{"b@example.com"}, Subject: "Отчёт", Body: "Привет"},
+			wantSubject: "Отчёт",
+		},
+		{
+			name:        "reply keeps the thread subject",
+			req:         DraftRequest{To: []string{"a@example.com"}, Body: "Thanks"},
+			reply:       &replyHeaders{MessageID: "<m2@example.com>", References: "<m1@example.com>", Subject: "Invoice"},
+			wantSubject: "Re: Invoice",
+			wantRefs:    "<m1@example.com> <m2@example.com>",
+		},
+		{
+			name:        "reply to a reply",
+			req:         DraftRequest{To: []string{"a@example.com"}},
+			reply:       &replyHeaders{MessageID: "<m3@example.com>", Subject: "RE: Invoice"},
+			wantSubject: "RE: Invoice",
+			wantRefs:    "<m3@example.com>",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			raw, err := base64.URLEncoding.DecodeString(buildRaw(tt.req, tt.reply))
+			if err != nil {
+				t.Fatal(err)
+			}
+			msg, err := mail.ReadMessage(bytes.NewReader(raw))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := decodeRFC2047(msg.Header.Get("Subject")); got != tt.wantSubject {
+				t.Errorf("Subject = %q, want %q", got, tt.wantSubject)
+			}
+			if got := msg.Header.Get("References"); got != tt.wantRefs {
+				t.Errorf("References = %q, want %q", got, tt.wantRefs)
+			}
+			if tt.req.Cc != nil && msg.Header.Get("Cc") != "b@example.com" {
+				t.Errorf("unexpected Cc %q", msg.Header.Get("Cc"))
+			}
+			encoded, _ := io.ReadAll(msg.Body)
+			body, _ := base64.StdEncoding.DecodeString(strings.ReplaceAll(string(encoded), "\r\n", ""))
+			if string(body) != tt.req.Body {
+				t.Errorf("body = %q, want %q", body, tt.req.Body)
+			}
+		})
+	}
+}
+
+func TestDraftRequest_Validate(t *testing.T) {
+	tests := []struct {
+		req     DraftRequest
+		wantErr bool
+	}{
+		{DraftRequest{To: []string{"a@example.com"}, Subject: "Hi"}, false},
+		{DraftRequest{Subject: "Hi"}, true},
+		{DraftRequest{To: []string{"a@example.com"}, Subject: "Hi\r\nBcc: evil@example.com"}, true},
+		{DraftRequest{To: []string{"a@example.com"}, Cc: []string{"b@example.com\nX-Injected: 1"}}, true},
+	}
+	for i, tt := range tests {
+		if err := tt.req.validate(); (err != nil) != tt.wantErr {
+			t.Errorf("case %d: validate() = %v, wantErr %v", i, err, tt.wantErr)
+		}
+	}
+}
+
+func TestHandleDrafts(t *testing.T) {
+	var deleted, updated string
+	mc := &mockGmailClient{
+		listDraftsFunc: func(_ context.Context, max int64) ([]Draft, error) {
+			if max != 5 {
+				t.Errorf("expected max 5, got %d", max)
+			}
+			return []Draft{{ID: "d1", Subject: "Invoice"}}, nil
+		},
+		createDraftFunc: func(_ context.Context, req DraftRequest) (*Draft, error) {
+			return &Draft{ID: "d2", To: strings.Join(req.To, ", "), Subject: req.Subject}, nil
+		},
+		updateDraftFunc: func(_ context.Context, id string, req DraftRequest) (*Draft, error) {
+			updated = id
+			return &Draft{ID: id, Subject: req.Subject}, nil
+		},
+		deleteDraftFunc: func(_ context.Context, id string) error {
+			deleted = id
+			if id == "gone" {
+				return fmt.Errorf("not found")
+			}
+			return nil
+		},
+	}
+	mux := http.NewServeMux()
+	NewHandler(mc).RegisterRoutes(mux)
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest(method, path, strings.NewReader(body)))
+		return rec
+	}
+
+	rec := do("GET", "/api/gmail/drafts?max=5", "")
+	var list struct{ Drafts []Draft }
+	json.NewDecoder(rec.Body).Decode(&list)
+	if rec.Code != 200 || len(list.Drafts) != 1 || list.Drafts[0].ID != "d1" {
+		t.Errorf("list: code=%d drafts=%+v", rec.Code, list.Drafts)
+	}
+
+	rec = do("POST", "/api/gmail/drafts", `{"to":["a@example.com"],"subject":"Hi","body":"Hello"}`)
+	var created Draft
+	json.NewDecoder(rec.Body).Decode(&created)
+	if rec.Code != http.StatusCreated || created.ID != "d2" || created.To != "a@example.com" {
+		t.Errorf("create: code=%d draft=%+v", rec.Code, created)
+	}
+	if rec := do("POST", "/api/gmail/drafts", `{"subject":"Hi"}`); rec.Code != http.StatusBadRequest {
+		t.Errorf("create without to: expected 400, got %d", rec.Code)
+	}
+	if rec := do("POST", "/api/gmail/drafts", `not json`); rec.Code != http.StatusBadRequest {
+		t.Errorf("create with bad body: expected 400, got %d", rec.Code)
+	}
+
+	if rec := do("PUT", "/api/gmail/drafts/d2", `{"to":["a@example.com"],"subject":"Hi again"}`); rec.Code != 200 || updated != "d2" {
+		t.Errorf("update: code=%d id=%q", rec.Code, updated)
+	}
+	if rec := do("DELETE", "/api/gmail/drafts/d2", ""); rec.Code != 200 || deleted != "d2" {
+		t.Errorf("delete: code=%d id=%q", rec.Code, deleted)
+	}
+	if rec := do("DELETE", "/api/gmail/drafts/gone", ""); rec.Code != http.StatusInternalServerError {
+		t.Errorf("delete error: expected 500, got %d", rec.Code)
+	}
+	if rec := do("DELETE", "/api/gmail/drafts/", ""); rec.Code != http.StatusBadRequest {
+		t.Errorf("delete without id: expected 400, got %d", rec.Code)
+	}
+	if rec := do("PATCH", "/api/gmail/drafts/d2", ""); rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("patch: expected 405, got %d", rec.Code)
+	}
+	if rec := do("DELETE", "/api/gmail/drafts", ""); rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("delete collection: expected 405, got %d", rec.Code)
+	}
+}
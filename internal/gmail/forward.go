@@ -0,0 +1,69 @@
+package gmail
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	gm "google.golang.org/api/gmail/v1"
+)
+
+// ForwardRequest forwards a message to new recipients.
+type ForwardRequest struct {
+	To   []string
+	Note string // plain text above the forwarded message
+}
+
+// forwardDraft renders orig as the plain-text body of a forward, the way
+// mail clients quote it. Attachments are not carried over.
+func forwardDraft(orig *MessageFull, req ForwardRequest) DraftRequest {
+	subject := orig.Subject
+	if !strings.HasPrefix(strings.ToLower(subject), "fwd:") {
+		subject = "Fwd: " + subject
+	}
+	var b strings.Builder
+	if req.Note != "" {
+		b.WriteString(req.Note + "\n\n")
+	}
+	b.WriteString("---------- Forwarded message ---------\n")
+	b.WriteString("From: " + orig.From + "\n")
+	if orig.Date != "" {
+		b.WriteString("Date: " + orig.Date + "\n")
+	}
+	b.WriteString("Subject: " + orig.Subject + "\n")
+	if orig.To != "" {
+		b.WriteString("To: " + orig.To + "\n")
+	}
+	b.WriteString("\n" + orig.Body)
+	return DraftRequest{To: req.To, Subject: subject, Body: b.String()}
+}
+
+// ForwardRaw renders a forward of orig as an RFC 2822 message without a From
+// header, for providers that send mail themselves.
+func ForwardRaw(orig *MessageFull, req ForwardRequest) ([]byte, error) {
+	draft := forwardDraft(orig, req)
+	if err := draft.validate(); err != nil {
+		return nil, err
+	}
+	return []byte(buildMessage(draft, nil)), nil
+}
+
+// ForwardMessage sends message id to req.To as a new plain-text message.
+func (c *Client) ForwardMessage(ctx context.Context, id string, req ForwardRequest) error {
+	orig, err := c.GetMessage(ctx, id)
+	if err != nil {
+		return fmt.Errorf("get forwarded message: %w", err)
+	}
+	draft := forwardDraft(orig, req)
+	if err := draft.validate(); err != nil {
+		return err
+	}
+	svc, err := c.getService(ctx)
+	if err != nil {
+		return err
+	}
+	if _, err := svc.Users.Messages.Send("me", &gm.Message{Raw: buildRaw(draft, nil)}).Context(ctx).Do(); err != nil {
+		return fmt.Errorf("send forward: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,44 @@
+package gmail
+
+import (
+	"encoding/base64"
+	"strings"
+	"testing"
+)
+
+func TestForwardDraft(t *testing.T) {
+	orig := &MessageFull{
+		From:    "Billing <billing@vendor.example>",
+		To:      "me@example.com",
+		Date:    "Mon, 2 Mar 2026 09:00:00 +0000",
+		Subject: "Invoice 1042",
+		Body:    "Total: 420 EUR",
+	}
+	d := forwardDraft(orig, ForwardRequest{To: []string{"books@example.com"}, Note: "For the March books"})
+	if d.Subject != "Fwd: Invoice 1042" || d.To[0] != "books@example.com" {
+		t.Errorf("unexpected draft %+v", d)
+	}
+	want := "For the March books\n\n---------- Forwarded message ---------\nFrom: Billing <billing@vendor.example>\nDate: Mon, 2 Mar 2026 09:00:00 +0000\nSubject: Invoice 1042\nTo: me@example.com\n\nTotal: 420 EUR"
+	if d.Body != want {
+		t.Errorf("body =\n%s\nwant\n%s", d.Body, want)
+	}
+
+	orig.Subject = "FWD: Invoice 1042"
+	if d := forwardDraft(orig, ForwardRequest{To: []string{"a@example.com"}}); d.Subject != "FWD: Invoice 1042" || strings.HasPrefix(d.Body, "\n") {
+		t.Errorf("already forwarded subject or empty note: %+v", d)
+	}
+
+	raw, _ := base64.URLEncoding.DecodeString(buildRaw(d, nil))
+	if !strings.HasPrefix(string(raw), "To: books@example.com\r\n") {
+		t.Errorf("unexpected raw message:\n%s", raw)
+	}
+
+	msg, err := ForwardRaw(orig, ForwardRequest{To: []string{"a@example.com"}})
+	header, _, _ := strings.Cut(string(msg), "\r\n\r\n")
+	if err != nil || !strings.HasPrefix(header, "To: a@example.com\r\n") || strings.Contains(header, "From:") {
+		t.Errorf("ForwardRaw = %q, %v", msg, err)
+	}
+	if _, err := ForwardRaw(orig, ForwardRequest{}); err == nil {
+		t.Error("ForwardRaw without recipients should fail")
+	}
+}
@@ -2,15 +2,24 @@ package gmail
 
 import (
 	"encoding/json"
+	"errors"
+	"mime"
 	"net/http"
 	"strconv"
 	"strings"
+	"time"
+
+	"github.com/katalabut/openclaw-relay/internal/screening"
+	"github.com/katalabut/openclaw-relay/internal/tokens"
 )
 
 // Handler registers Gmail API HTTP handlers with multi-account support.
 type Handler struct {
 	clients      map[string]GmailClient
 	defaultEmail string
+	screener     *screening.Screener
+	cache        *responseCache // nil when gmail.api_cache is unset
+	quotas       Quotas         // nil when gmail.quota is unset
 }
 
 // NewMultiHandler creates a handler that supports multiple Gmail accounts.
@@ -33,22 +42,93 @@ func NewHandler(client GmailClient) *Handler {
 	}
 }
 
+// SetScreener screens attachment metadata in message and thread responses.
+func (h *Handler) SetScreener(s *screening.Screener) {
+	h.screener = s
+}
+
+// SetCacheTTL reuses message list and label responses for ttl, per account
+// and query. A modify through the handler drops the account's entries.
+func (h *Handler) SetCacheTTL(ttl time.Duration) {
+	h.cache = nil
+	if ttl > 0 {
+		h.cache = newResponseCache(ttl)
+	}
+}
+
+// DropCache drops the responses cached for account, as off-boarding does, and
+// reports whether there were any.
+func (h *Handler) DropCache(account string) bool {
+	return h.cache.bust(account) > 0
+}
+
+// SetQuotas sheds requests for accounts past their quota's throttle level,
+// keeping the rest of the day's budget for the pollers.
+func (h *Handler) SetQuotas(q Quotas) {
+	h.quotas = q
+}
+
+// budgeted answers 429 for accounts whose quota is throttled, until the
+// count starts over at 00:00 UTC.
+func (h *Handler) budgeted(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		account, _, ok := h.resolveAccount(r)
+		if q := h.quotas[account]; ok && q.throttled() {
+			w.Header().Set("Retry-After", strconv.Itoa(int(q.resetIn().Seconds())+1))
+			jsonError(w, "daily Gmail quota for "+account+" is nearly used up", http.StatusTooManyRequests)
+			return
+		}
+		next(w, r)
+	}
+}
+
+// screen attaches a verdict to each attachment of msg.
+func (h *Handler) screen(msg *MessageFull) {
+	if h.screener == nil {
+		return
+	}
+	for i := range msg.Attachments {
+		a := &msg.Attachments[i]
+		res := h.screener.Check(a.Filename, a.Size)
+		a.Screening = &res
+	}
+}
+
+// resolveClient returns the client for ?account=, the default account when
+// it's unset. An API key limited to one account defaults to that one, and
+// other accounts are unknown to it.
 func (h *Handler) resolveClient(r *http.Request) (GmailClient, bool) {
+	_, client, ok := h.resolveAccount(r)
+	return client, ok
+}
+
+// resolveAccount is resolveClient that also returns the account's name.
+func (h *Handler) resolveAccount(r *http.Request) (string, GmailClient, bool) {
 	account := r.URL.Query().Get("account")
+	scope := keyScope(r)
 	if account == "" {
-		return h.clients[h.defaultEmail], true
+		account = h.defaultEmail
+		if scope != nil && len(scope.Accounts) == 1 {
+			account = scope.Accounts[0]
+		}
+	}
+	if scope != nil && !scope.AllowsAccount(account) {
+		return "", nil, false
 	}
 	client, ok := h.clients[account]
-	return client, ok
+	return account, client, ok
 }
 
 // RegisterRoutes adds Gmail API routes to the mux.
 func (h *Handler) RegisterRoutes(mux *http.ServeMux) {
-	mux.HandleFunc("/api/gmail/messages", h.handleListMessages)
-	mux.HandleFunc("/api/gmail/message/", h.handleGetMessage)
-	mux.HandleFunc("/api/gmail/modify/", h.handleModifyMessage)
-	mux.HandleFunc("/api/gmail/labels", h.handleListLabels)
-	mux.HandleFunc("/api/gmail/threads/", h.handleGetThread)
+	mux.HandleFunc("/api/gmail/messages", h.budgeted(h.handleListMessages))
+	mux.HandleFunc("/api/gmail/message/", h.budgeted(h.handleGetMessage))
+	mux.HandleFunc("/api/gmail/modify/", h.budgeted(h.handleModifyMessage))
+	mux.HandleFunc("/api/gmail/labels", h.budgeted(h.handleListLabels))
+	mux.HandleFunc("/api/gmail/threads", h.budgeted(h.handleListThreads))
+	mux.HandleFunc("/api/gmail/threads/", h.budgeted(h.handleGetThread))
+	mux.HandleFunc("/api/gmail/drafts", h.budgeted(h.handleDrafts))
+	mux.HandleFunc("/api/gmail/drafts/", h.budgeted(h.handleDraft))
 }
 
 func jsonResponse(w http.ResponseWriter, data any) {
@@ -56,13 +136,68 @@ func jsonResponse(w http.ResponseWriter, data any) {
 	json.NewEncoder(w).Encode(data)
 }
 
+// cachedResponse writes a response from the cache, marked with X-Relay-Cache.
+func cachedResponse(w http.ResponseWriter, data any) {
+	w.Header().Set("X-Relay-Cache", "hit")
+	jsonResponse(w, data)
+}
+
 func jsonError(w http.ResponseWriter, msg string, code int) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(code)
 	json.NewEncoder(w).Encode(map[string]string{"error": msg})
 }
 
+// apiError answers a failed Google API call: 403 with the page that grants
+// the missing scope when the account's token lacks one, 500 otherwise.
+func apiError(w http.ResponseWriter, err error) {
+	var scopeErr *tokens.ScopeError
+	if !errors.As(err, &scopeErr) {
+		jsonError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusForbidden)
+	json.NewEncoder(w).Encode(map[string]any{
+		"error":           "insufficient_scope",
+		"message":         scopeErr.Error(),
+		"account":         scopeErr.Account,
+		"required_scopes": scopeErr.Required,
+		"granted_scopes":  scopeErr.Granted,
+		"reauth_url":      scopeErr.ReauthURL(),
+	})
+}
+
 func (h *Handler) handleListMessages(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		jsonError(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	account, client, ok := h.resolveAccount(r)
+	if !ok {
+		jsonError(w, "unknown account", http.StatusBadRequest)
+		return
+	}
+	q, max, pageToken := listParams(r)
+	q = scopedQuery(keyScope(r), q)
+	key := cacheKey(account, "messages", q, strconv.FormatInt(max, 10), pageToken)
+	if resp, ok := h.cache.get(key); ok {
+		cachedResponse(w, resp)
+		return
+	}
+	msgs, next, err := client.ListMessages(r.Context(), q, max, pageToken)
+	if err != nil {
+		apiError(w, err)
+		return
+	}
+	resp := listResponse("messages", msgs, next)
+	h.cache.put(key, resp)
+	jsonResponse(w, resp)
+}
+
+// handleListThreads serves GET /api/gmail/threads with the same parameters
+// as /api/gmail/messages.
+func (h *Handler) handleListThreads(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
 		jsonError(w, "method not allowed", http.StatusMethodNotAllowed)
 		return
@@ -72,23 +207,37 @@ func (h *Handler) handleListMessages(w http.ResponseWriter, r *http.Request) {
 		jsonError(w, "unknown account", http.StatusBadRequest)
 		return
 	}
-	q := r.URL.Query().Get("q")
+	q, max, pageToken := listParams(r)
+	threads, next, err := client.ListThreads(r.Context(), scopedQuery(keyScope(r), q), max, pageToken)
+	if err != nil {
+		apiError(w, err)
+		return
+	}
+	jsonResponse(w, listResponse("threads", threads, next))
+}
+
+// listParams reads q (default is:unread), max (default 20) and pageToken.
+func listParams(r *http.Request) (q string, max int64, pageToken string) {
+	q = r.URL.Query().Get("q")
 	if q == "" {
 		q = "is:unread"
 	}
-	maxStr := r.URL.Query().Get("max")
-	max := int64(20)
-	if maxStr != "" {
+	max = 20
+	if maxStr := r.URL.Query().Get("max"); maxStr != "" {
 		if v, err := strconv.ParseInt(maxStr, 10, 64); err == nil && v > 0 {
 			max = v
 		}
 	}
-	msgs, err := client.ListMessages(r.Context(), q, max)
-	if err != nil {
-		jsonError(w, err.Error(), http.StatusInternalServerError)
-		return
+	return q, max, r.URL.Query().Get("pageToken")
+}
+
+// listResponse adds nextPageToken when there are more results.
+func listResponse(key string, items any, next string) map[string]any {
+	resp := map[string]any{key: items}
+	if next != "" {
+		resp["nextPageToken"] = next
 	}
-	jsonResponse(w, map[string]any{"messages": msgs})
+	return resp
 }
 
 func (h *Handler) handleGetMessage(w http.ResponseWriter, r *http.Request) {
@@ -102,16 +251,89 @@ func (h *Handler) handleGetMessage(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	id := strings.TrimPrefix(r.URL.Path, "/api/gmail/message/")
+	if msgID, attID, ok := strings.Cut(id, "/attachments/"); ok {
+		h.handleGetAttachment(w, r, client, msgID, attID)
+		return
+	}
 	if id == "" {
 		jsonError(w, "missing message id", http.StatusBadRequest)
 		return
 	}
+	msg, ok := getScopedMessage(w, r, client, id)
+	if !ok {
+		return
+	}
+	h.screen(msg)
+	jsonResponse(w, msg)
+}
+
+// getScopedMessage fetches a message, answering 404 when the request's API
+// key may not see it.
+func getScopedMessage(w http.ResponseWriter, r *http.Request, client GmailClient, id string) (*MessageFull, bool) {
 	msg, err := client.GetMessage(r.Context(), id)
 	if err != nil {
-		jsonError(w, err.Error(), http.StatusInternalServerError)
+		apiError(w, err)
+		return nil, false
+	}
+	ok, err := inScope(r.Context(), client, keyScope(r), msg)
+	if err != nil {
+		apiError(w, err)
+		return nil, false
+	}
+	if !ok {
+		jsonError(w, "message not found", http.StatusNotFound)
+		return nil, false
+	}
+	return msg, true
+}
+
+// handleGetAttachment serves GET /api/gmail/message/{id}/attachments/{attachmentId}
+// with the attachment's decoded content. The attachment is screened like in
+// the message listing, and its content is scanned before it is sent. Gmail
+// issues a new attachmentId on every fetch, so the stable partId is accepted
+// too.
+func (h *Handler) handleGetAttachment(w http.ResponseWriter, r *http.Request, client GmailClient, msgID, attID string) {
+	if msgID == "" || attID == "" {
+		jsonError(w, "missing message or attachment id", http.StatusBadRequest)
 		return
 	}
-	jsonResponse(w, msg)
+	msg, ok := getScopedMessage(w, r, client, msgID)
+	if !ok {
+		return
+	}
+	var att *Attachment
+	for i, a := range msg.Attachments {
+		if a.ID != "" && (a.ID == attID || a.PartID == attID) {
+			att = &msg.Attachments[i]
+			break
+		}
+	}
+	if att == nil {
+		jsonError(w, "attachment not found", http.StatusNotFound)
+		return
+	}
+	if res := h.screener.Check(att.Filename, att.Size); !res.Allowed() {
+		jsonError(w, "attachment blocked: "+res.Reason, http.StatusForbidden)
+		return
+	}
+	data, err := client.GetAttachment(r.Context(), msgID, att.ID)
+	if err != nil {
+		apiError(w, err)
+		return
+	}
+	if res := h.screener.Scan(r.Context(), att.Filename, data); !res.Allowed() {
+		jsonError(w, "attachment blocked: "+res.Status+" "+res.Reason, http.StatusForbidden)
+		return
+	}
+	mimeType := att.MimeType
+	if mimeType == "" {
+		mimeType = "application/octet-stream"
+	}
+	w.Header().Set("Content-Type", mimeType)
+	w.Header().Set("Content-Length", strconv.Itoa(len(data)))
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": att.Filename}))
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Write(data)
 }
 
 func (h *Handler) handleModifyMessage(w http.ResponseWriter, r *http.Request) {
@@ -119,7 +341,7 @@ func (h *Handler) handleModifyMessage(w http.ResponseWriter, r *http.Request) {
 		jsonError(w, "method not allowed", http.StatusMethodNotAllowed)
 		return
 	}
-	client, ok := h.resolveClient(r)
+	account, client, ok := h.resolveAccount(r)
 	if !ok {
 		jsonError(w, "unknown account", http.StatusBadRequest)
 		return
@@ -134,10 +356,22 @@ func (h *Handler) handleModifyMessage(w http.ResponseWriter, r *http.Request) {
 		jsonError(w, "invalid request body", http.StatusBadRequest)
 		return
 	}
+	if scope := keyScope(r); scope != nil {
+		if label, ok := labelsAllowed(scope, req); !ok {
+			jsonError(w, "label "+label+" is not allowed for this API key", http.StatusForbidden)
+			return
+		}
+		if scope.Query != "" {
+			if _, ok := getScopedMessage(w, r, client, id); !ok {
+				return
+			}
+		}
+	}
 	if err := client.ModifyMessage(r.Context(), id, req); err != nil {
-		jsonError(w, err.Error(), http.StatusInternalServerError)
+		apiError(w, err)
 		return
 	}
+	h.cache.bust(account)
 	jsonResponse(w, map[string]bool{"ok": true})
 }
 
@@ -146,17 +380,24 @@ func (h *Handler) handleListLabels(w http.ResponseWriter, r *http.Request) {
 		jsonError(w, "method not allowed", http.StatusMethodNotAllowed)
 		return
 	}
-	client, ok := h.resolveClient(r)
+	account, client, ok := h.resolveAccount(r)
 	if !ok {
 		jsonError(w, "unknown account", http.StatusBadRequest)
 		return
 	}
+	key := cacheKey(account, "labels")
+	if resp, ok := h.cache.get(key); ok {
+		cachedResponse(w, resp)
+		return
+	}
 	labels, err := client.ListLabels(r.Context())
 	if err != nil {
-		jsonError(w, err.Error(), http.StatusInternalServerError)
+		apiError(w, err)
 		return
 	}
-	jsonResponse(w, map[string]any{"labels": labels})
+	resp := map[string]any{"labels": labels}
+	h.cache.put(key, resp)
+	jsonResponse(w, resp)
 }
 
 func (h *Handler) handleGetThread(w http.ResponseWriter, r *http.Request) {
@@ -176,8 +417,30 @@ func (h *Handler) handleGetThread(w http.ResponseWriter, r *http.Request) {
 	}
 	msgs, err := client.GetThread(r.Context(), threadID)
 	if err != nil {
-		jsonError(w, err.Error(), http.StatusInternalServerError)
+		apiError(w, err)
 		return
 	}
+	// An API key sees the messages of the thread its query matches
+	if scope := keyScope(r); scope != nil && scope.Query != "" {
+		visible := msgs[:0]
+		for _, m := range msgs {
+			ok, err := inScope(r.Context(), client, scope, &m)
+			if err != nil {
+				apiError(w, err)
+				return
+			}
+			if ok {
+				visible = append(visible, m)
+			}
+		}
+		if len(visible) == 0 {
+			jsonError(w, "thread not found", http.StatusNotFound)
+			return
+		}
+		msgs = visible
+	}
+	for i := range msgs {
+		h.screen(&msgs[i])
+	}
 	jsonResponse(w, map[string]any{"messages": msgs})
 }
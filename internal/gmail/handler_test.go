@@ -6,22 +6,36 @@ import (
 	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
 	"strings"
 	"testing"
+
+	"github.com/katalabut/openclaw-relay/internal/screening"
+	"github.com/katalabut/openclaw-relay/internal/tokens"
 )
 
 type mockGmailClient struct {
-	listMessagesFunc  func(ctx context.Context, query string, max int64) ([]MessageMeta, error)
+	listMessagesFunc  func(ctx context.Context, query string, max int64, pageToken string) ([]MessageMeta, string, error)
+	listThreadsFunc   func(ctx context.Context, query string, max int64, pageToken string) ([]ThreadMeta, string, error)
 	getMessageFunc    func(ctx context.Context, id string) (*MessageFull, error)
 	modifyMessageFunc func(ctx context.Context, id string, req ModifyRequest) error
 	listLabelsFunc    func(ctx context.Context) ([]LabelInfo, error)
 	getThreadFunc     func(ctx context.Context, id string) ([]MessageFull, error)
+	getAttachmentFunc func(ctx context.Context, msgID, attID string) ([]byte, error)
 	getCurrentHIDFunc func(ctx context.Context) (uint64, error)
 	getHistoryFunc    func(ctx context.Context, startHID uint64) ([]HistoryMessage, uint64, error)
+	listDraftsFunc    func(ctx context.Context, max int64) ([]Draft, error)
+	createDraftFunc   func(ctx context.Context, req DraftRequest) (*Draft, error)
+	updateDraftFunc   func(ctx context.Context, id string, req DraftRequest) (*Draft, error)
+	deleteDraftFunc   func(ctx context.Context, id string) error
+	forwardFunc       func(ctx context.Context, id string, req ForwardRequest) error
 }
 
-func (m *mockGmailClient) ListMessages(ctx context.Context, query string, max int64) ([]MessageMeta, error) {
-	return m.listMessagesFunc(ctx, query, max)
+func (m *mockGmailClient) ListMessages(ctx context.Context, query string, max int64, pageToken string) ([]MessageMeta, string, error) {
+	return m.listMessagesFunc(ctx, query, max, pageToken)
+}
+func (m *mockGmailClient) ListThreads(ctx context.Context, query string, max int64, pageToken string) ([]ThreadMeta, string, error) {
+	return m.listThreadsFunc(ctx, query, max, pageToken)
 }
 func (m *mockGmailClient) GetMessage(ctx context.Context, id string) (*MessageFull, error) {
 	return m.getMessageFunc(ctx, id)
@@ -35,17 +49,35 @@ func (m *mockGmailClient) ListLabels(ctx context.Context) ([]LabelInfo, error) {
 func (m *mockGmailClient) GetThread(ctx context.Context, id string) ([]MessageFull, error) {
 	return m.getThreadFunc(ctx, id)
 }
+func (m *mockGmailClient) GetAttachment(ctx context.Context, msgID, attID string) ([]byte, error) {
+	return m.getAttachmentFunc(ctx, msgID, attID)
+}
 func (m *mockGmailClient) GetCurrentHistoryID(ctx context.Context) (uint64, error) {
 	return m.getCurrentHIDFunc(ctx)
 }
 func (m *mockGmailClient) GetHistory(ctx context.Context, startHID uint64) ([]HistoryMessage, uint64, error) {
 	return m.getHistoryFunc(ctx, startHID)
 }
+func (m *mockGmailClient) ListDrafts(ctx context.Context, max int64) ([]Draft, error) {
+	return m.listDraftsFunc(ctx, max)
+}
+func (m *mockGmailClient) CreateDraft(ctx context.Context, req DraftRequest) (*Draft, error) {
+	return m.createDraftFunc(ctx, req)
+}
+func (m *mockGmailClient) UpdateDraft(ctx context.Context, id string, req DraftRequest) (*Draft, error) {
+	return m.updateDraftFunc(ctx, id, req)
+}
+func (m *mockGmailClient) DeleteDraft(ctx context.Context, id string) error {
+	return m.deleteDraftFunc(ctx, id)
+}
+func (m *mockGmailClient) ForwardMessage(ctx context.Context, id string, req ForwardRequest) error {
+	return m.forwardFunc(ctx, id, req)
+}
 
 func TestHandleListMessages_OK(t *testing.T) {
 	mc := &mockGmailClient{
-		listMessagesFunc: func(_ context.Context, q string, max int64) ([]MessageMeta, error) {
-			return []MessageMeta{{ID: "msg1", Subject: "Test"}}, nil
+		listMessagesFunc: func(_ context.Context, q string, max int64, _ string) ([]MessageMeta, string, error) {
+			return []MessageMeta{{ID: "msg1", Subject: "Test"}}, "", nil
 		},
 	}
 	h := NewHandler(mc)
@@ -68,8 +100,8 @@ func TestHandleListMessages_OK(t *testing.T) {
 
 func TestHandleListMessages_ClientError(t *testing.T) {
 	mc := &mockGmailClient{
-		listMessagesFunc: func(_ context.Context, _ string, _ int64) ([]MessageMeta, error) {
-			return nil, fmt.Errorf("auth error")
+		listMessagesFunc: func(_ context.Context, _ string, _ int64, _ string) ([]MessageMeta, string, error) {
+			return nil, "", fmt.Errorf("auth error")
 		},
 	}
 	h := NewHandler(mc)
@@ -109,6 +141,95 @@ func TestHandleGetMessage_OK(t *testing.T) {
 	}
 }
 
+func TestHandleGetMessage_ScreensAttachments(t *testing.T) {
+	mc := &mockGmailClient{
+		getMessageFunc: func(_ context.Context, id string) (*MessageFull, error) {
+			return &MessageFull{ID: id, Attachments: []Attachment{
+				{ID: "A1", Filename: "report.pdf", Size: 1000},
+				{ID: "A2", Filename: "setup.exe", Size: 1000},
+				{ID: "A3", Filename: "video.mp4", Size: 1 << 30},
+			}}, nil
+		},
+	}
+	h := NewHandler(mc)
+	h.SetScreener(&screening.Screener{MaxBytes: 25 << 20, DenyExtensions: screening.DefaultDenyExtensions})
+	mux := http.NewServeMux()
+	h.RegisterRoutes(mux)
+
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest("GET", "/api/gmail/message/m1", nil))
+	var msg MessageFull
+	json.NewDecoder(rec.Body).Decode(&msg)
+	want := []string{screening.StatusPassed, screening.StatusBlocked, screening.StatusBlocked}
+	if len(msg.Attachments) != len(want) {
+		t.Fatalf("unexpected attachments %+v", msg.Attachments)
+	}
+	for i, a := range msg.Attachments {
+		if a.Screening == nil || a.Screening.Status != want[i] {
+			t.Errorf("attachment %s: got %+v, want %s", a.ID, a.Screening, want[i])
+		}
+	}
+}
+
+func TestHandleGetAttachment(t *testing.T) {
+	var downloaded []string
+	mc := &mockGmailClient{
+		getMessageFunc: func(_ context.Context, id string) (*MessageFull, error) {
+			return &MessageFull{ID: id, Attachments: []Attachment{
+				{ID: "A1", PartID: "2", Filename: "report.pdf", MimeType: "application/pdf", Size: 7},
+				{ID: "A2", PartID: "3", Filename: "setup.exe", Size: 7},
+			}}, nil
+		},
+		getAttachmentFunc: func(_ context.Context, msgID, attID string) ([]byte, error) {
+			downloaded = append(downloaded, msgID+"/"+attID)
+			return []byte("%PDF-1."), nil
+		},
+	}
+	h := NewHandler(mc)
+	h.SetScreener(&screening.Screener{MaxBytes: 25 << 20, DenyExtensions: screening.DefaultDenyExtensions})
+	mux := http.NewServeMux()
+	h.RegisterRoutes(mux)
+	get := func(path string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest("GET", path, nil))
+		return rec
+	}
+
+	rec := get("/api/gmail/message/m1/attachments/A1")
+	if rec.Code != 200 || rec.Body.String() != "%PDF-1." {
+		t.Fatalf("expected the content, got %d %q", rec.Code, rec.Body.String())
+	}
+	if ct := rec.Header().Get("Content-Type"); ct != "application/pdf" {
+		t.Errorf("unexpected Content-Type %q", ct)
+	}
+	if cd := rec.Header().Get("Content-Disposition"); cd != `attachment; filename=report.pdf` {
+		t.Errorf("unexpected Content-Disposition %q", cd)
+	}
+
+	// The part ID works when the attachment ID changed between fetches
+	if rec := get("/api/gmail/message/m1/attachments/2"); rec.Code != 200 {
+		t.Errorf("expected the part ID to resolve, got %d", rec.Code)
+	}
+	if rec := get("/api/gmail/message/m1/attachments/A2"); rec.Code != http.StatusForbidden {
+		t.Errorf("expected a denied extension to be refused, got %d", rec.Code)
+	}
+	if rec := get("/api/gmail/message/m1/attachments/nope"); rec.Code != http.StatusNotFound {
+		t.Errorf("expected 404 for an unknown attachment, got %d", rec.Code)
+	}
+	if strings.Join(downloaded, ",") != "m1/A1,m1/A1" {
+		t.Errorf("unexpected downloads %v", downloaded)
+	}
+
+	// Content larger than the cap is refused even if the metadata was smaller
+	h.SetScreener(&screening.Screener{MaxBytes: 4})
+	mc.getMessageFunc = func(_ context.Context, id string) (*MessageFull, error) {
+		return &MessageFull{ID: id, Attachments: []Attachment{{ID: "A1", Filename: "report.pdf", Size: 3}}}, nil
+	}
+	if rec := get("/api/gmail/message/m1/attachments/A1"); rec.Code != http.StatusForbidden {
+		t.Errorf("expected oversized content to be refused, got %d", rec.Code)
+	}
+}
+
 func TestHandleGetMessage_NotFound(t *testing.T) {
 	mc := &mockGmailClient{
 		getMessageFunc: func(_ context.Context, _ string) (*MessageFull, error) {
@@ -351,9 +472,9 @@ func TestHandleModifyMessage_MethodNotAllowed(t *testing.T) {
 func TestHandleListMessages_CustomMaxResults(t *testing.T) {
 	var gotMax int64
 	mc := &mockGmailClient{
-		listMessagesFunc: func(_ context.Context, _ string, max int64) ([]MessageMeta, error) {
+		listMessagesFunc: func(_ context.Context, _ string, max int64, _ string) ([]MessageMeta, string, error) {
 			gotMax = max
-			return nil, nil
+			return nil, "", nil
 		},
 	}
 	h := NewHandler(mc)
@@ -368,6 +489,100 @@ func TestHandleListMessages_CustomMaxResults(t *testing.T) {
 	}
 }
 
+func TestHandleListMessages_PageToken(t *testing.T) {
+	var gotToken string
+	mc := &mockGmailClient{
+		listMessagesFunc: func(_ context.Context, _ string, _ int64, pageToken string) ([]MessageMeta, string, error) {
+			gotToken = pageToken
+			if pageToken == "" {
+				return []MessageMeta{{ID: "m1"}}, "page-2", nil
+			}
+			return []MessageMeta{{ID: "m2"}}, "", nil
+		},
+	}
+	mux := http.NewServeMux()
+	NewHandler(mc).RegisterRoutes(mux)
+
+	for _, tt := range []struct {
+		query, wantToken, wantNext string
+	}{
+		{"", "", "page-2"},
+		{"?pageToken=page-2", "page-2", ""},
+	} {
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest("GET", "/api/gmail/messages"+tt.query, nil))
+		var resp map[string]any
+		json.NewDecoder(rec.Body).Decode(&resp)
+		if gotToken != tt.wantToken {
+			t.Errorf("%q: client got pageToken %q, want %q", tt.query, gotToken, tt.wantToken)
+		}
+		next, ok := resp["nextPageToken"]
+		if tt.wantNext == "" && ok {
+			t.Errorf("%q: unexpected nextPageToken %v on the last page", tt.query, next)
+		}
+		if tt.wantNext != "" && next != tt.wantNext {
+			t.Errorf("%q: nextPageToken %v, want %q", tt.query, next, tt.wantNext)
+		}
+	}
+}
+
+func TestHandleListThreads(t *testing.T) {
+	var gotQ, gotToken string
+	var gotMax int64
+	mc := &mockGmailClient{
+		listThreadsFunc: func(_ context.Context, q string, max int64, pageToken string) ([]ThreadMeta, string, error) {
+			gotQ, gotMax, gotToken = q, max, pageToken
+			if q == "fail" {
+				return nil, "", fmt.Errorf("backend error")
+			}
+			return []ThreadMeta{{ID: "t1", Subject: "Invoice", Messages: 3}}, "next", nil
+		},
+	}
+	mux := http.NewServeMux()
+	NewHandler(mc).RegisterRoutes(mux)
+
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest("GET", "/api/gmail/threads?q=from:billing&max=5&pageToken=p1", nil))
+	if rec.Code != 200 {
+		t.Fatalf("expected 200, got %d", rec.Code)
+	}
+	var resp struct {
+		Threads       []ThreadMeta `json:"threads"`
+		NextPageToken string       `json:"nextPageToken"`
+	}
+	json.NewDecoder(rec.Body).Decode(&resp)
+	if len(resp.Threads) != 1 || resp.Threads[0].Messages != 3 || resp.NextPageToken != "next" {
+		t.Errorf("unexpected response %+v", resp)
+	}
+	if gotQ != "from:billing" || gotMax != 5 || gotToken != "p1" {
+		t.Errorf("client got q=%q max=%d pageToken=%q", gotQ, gotMax, gotToken)
+	}
+
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest("GET", "/api/gmail/threads", nil))
+	if gotQ != "is:unread" || gotMax != 20 || gotToken != "" {
+		t.Errorf("defaults: client got q=%q max=%d pageToken=%q", gotQ, gotMax, gotToken)
+	}
+
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest("GET", "/api/gmail/threads?q=fail", nil))
+	if rec.Code != 500 {
+		t.Errorf("expected 500 on client error, got %d", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest("POST", "/api/gmail/threads", nil))
+	if rec.Code != 405 {
+		t.Errorf("expected 405, got %d", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest("GET", "/api/gmail/threads?account=nobody@example.com", nil))
+	if rec.Code != 400 {
+		t.Errorf("expected 400 for an unknown account, got %d", rec.Code)
+	}
+}
+
 func TestHandleGetThread_OK(t *testing.T) {
 	mc := &mockGmailClient{
 		getThreadFunc: func(_ context.Context, id string) ([]MessageFull, error) {
@@ -389,13 +604,13 @@ func TestHandleGetThread_OK(t *testing.T) {
 
 func TestNewMultiHandler_AccountParam(t *testing.T) {
 	mc1 := &mockGmailClient{
-		listMessagesFunc: func(_ context.Context, _ string, _ int64) ([]MessageMeta, error) {
-			return []MessageMeta{{ID: "from-acc1"}}, nil
+		listMessagesFunc: func(_ context.Context, _ string, _ int64, _ string) ([]MessageMeta, string, error) {
+			return []MessageMeta{{ID: "from-acc1"}}, "", nil
 		},
 	}
 	mc2 := &mockGmailClient{
-		listMessagesFunc: func(_ context.Context, _ string, _ int64) ([]MessageMeta, error) {
-			return []MessageMeta{{ID: "from-acc2"}}, nil
+		listMessagesFunc: func(_ context.Context, _ string, _ int64, _ string) ([]MessageMeta, string, error) {
+			return []MessageMeta{{ID: "from-acc2"}}, "", nil
 		},
 	}
 	h := NewMultiHandler(map[string]GmailClient{
@@ -437,8 +652,8 @@ func TestNewMultiHandler_UnknownAccount(t *testing.T) {
 
 func TestNewMultiHandler_DefaultAccount(t *testing.T) {
 	mc := &mockGmailClient{
-		listMessagesFunc: func(_ context.Context, _ string, _ int64) ([]MessageMeta, error) {
-			return []MessageMeta{{ID: "default"}}, nil
+		listMessagesFunc: func(_ context.Context, _ string, _ int64, _ string) ([]MessageMeta, string, error) {
+			return []MessageMeta{{ID: "default"}}, "", nil
 		},
 	}
 	h := NewMultiHandler(map[string]GmailClient{"only@test.com": mc})
@@ -454,3 +669,32 @@ func TestNewMultiHandler_DefaultAccount(t *testing.T) {
 		t.Fatalf("expected 200, got %d", rec.Code)
 	}
 }
+
+func TestHandleListMessages_InsufficientScope(t *testing.T) {
+	scopeErr := &tokens.ScopeError{Account: "me@test.com", Required: []string{"https://www.googleapis.com/auth/gmail.modify"}, Granted: []string{"https://www.googleapis.com/auth/gmail.readonly"}}
+	mc := &mockGmailClient{
+		listMessagesFunc: func(_ context.Context, _ string, _ int64, _ string) ([]MessageMeta, string, error) {
+			return nil, "", &url.Error{Op: "Get", URL: "https://gmail.googleapis.com/", Err: scopeErr}
+		},
+	}
+	h := NewMultiHandler(map[string]GmailClient{"me@test.com": mc})
+	mux := http.NewServeMux()
+	h.RegisterRoutes(mux)
+
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest("GET", "/api/gmail/messages", nil))
+	var resp struct {
+		Error     string   `json:"error"`
+		Account   string   `json:"account"`
+		Required  []string `json:"required_scopes"`
+		Granted   []string `json:"granted_scopes"`
+		ReauthURL string   `json:"reauth_url"`
+	}
+	json.NewDecoder(rec.Body).Decode(&resp)
+	if rec.Code != http.StatusForbidden || resp.Error != "insufficient_scope" || resp.Account != "me@test.com" || len(resp.Required) != 1 || len(resp.Granted) != 1 {
+		t.Errorf("got %d %+v", rec.Code, resp)
+	}
+	if resp.ReauthURL != "/auth/google/login?account=me%40test.com" {
+		t.Errorf("reauth_url = %q", resp.ReauthURL)
+	}
+}
@@ -3,24 +3,24 @@ package gmail
 import (
 	"bytes"
 	"context"
-	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
-	"os"
-	"path/filepath"
+	"math/rand/v2"
+	"net/mail"
+	"slices"
 	"strings"
+	"sync"
 	"text/template"
 	"time"
 
 	"github.com/katalabut/openclaw-relay/internal/config"
+	"github.com/katalabut/openclaw-relay/internal/expr"
 	"github.com/katalabut/openclaw-relay/internal/gateway"
+	"github.com/katalabut/openclaw-relay/internal/lang"
+	"github.com/katalabut/openclaw-relay/internal/schedule"
 )
 
-// GmailState persists the last known historyId.
-type GmailState struct {
-	HistoryID uint64 `json:"history_id"`
-}
-
 // Poller polls Gmail for new messages using historyId.
 type Poller struct {
 	client       GmailClient
@@ -29,11 +29,44 @@ type Poller struct {
 	interval     time.Duration
 	gateway      gateway.GatewayClient
 	stateDir     string
+	timezone     string // default zone for rule schedules (server.timezone)
+	readOnly     bool   // skip modify and forward actions (read_only)
+
+	// attachment text for action.extract_text; extracted is the last
+	// message's result, touched only by the poll loop
+	textExtraction *TextExtraction
+	extracted      struct{ id, text string }
+
+	// quota slows polling when throttled and stops it when used up
+	quota *Quota
 
 	// auth failure tracking
 	lastAuthErr     time.Time
 	authAlertCfg    *config.GmailAuthAlertConfig
 	authErrCooldown time.Duration
+
+	// watchdog state, guarded by mu
+	mu          sync.Mutex
+	parent      context.Context
+	loopCancel  context.CancelFunc
+	lastSuccess time.Time
+	lastErr     string
+	restarts    int
+	restartedAt time.Time
+	stopped     bool           // set by Stop; no loop starts after it
+	loops       sync.WaitGroup // running poll loops, waited for by Stop
+}
+
+// PollerStatus is a snapshot of a poller's health for the watchdog and /api/gmail/pollers.
+type PollerStatus struct {
+	Account     string    `json:"account"`
+	Interval    string    `json:"interval"`
+	LastSuccess time.Time `json:"last_success"`
+	LastError   string    `json:"last_error,omitempty"`
+	Restarts    int       `json:"restarts"`
+	RestartedAt time.Time `json:"-"`
+	Stalled     bool      `json:"stalled"`
+	Stopped     bool      `json:"stopped"` // shut down or off-boarded
 }
 
 func NewPollerForAccount(client GmailClient, accountEmail, pollInterval string, rules []config.GmailRule, gw gateway.GatewayClient, stateDir string, authAlert *config.GmailAuthAlertConfig) *Poller {
@@ -61,30 +94,136 @@ func NewPollerForAccount(client GmailClient, accountEmail, pollInterval string,
 	}
 }
 
-func (p *Poller) stateFile() string {
-	safe := strings.ReplaceAll(p.accountEmail, "/", "_")
-	safe = strings.ReplaceAll(safe, "@", "_at_")
-	return filepath.Join(p.stateDir, fmt.Sprintf("gmail-state-%s.json", safe))
+// SetQuota makes the poller poll every throttledPollEvery intervals once q
+// is throttled, and not at all once it is used up.
+func (p *Poller) SetQuota(q *Quota) {
+	p.quota = q
 }
 
-func (p *Poller) loadState() (*GmailState, error) {
-	data, err := os.ReadFile(p.stateFile())
-	if err != nil {
-		return nil, err
-	}
-	var s GmailState
-	return &s, json.Unmarshal(data, &s)
+// SetTimezone sets the default IANA zone for rule action schedules.
+func (p *Poller) SetTimezone(tz string) {
+	p.timezone = tz
 }
 
-func (p *Poller) saveState(s *GmailState) error {
-	os.MkdirAll(p.stateDir, 0700)
-	data, _ := json.Marshal(s)
-	return os.WriteFile(p.stateFile(), data, 0600)
+// SetReadOnly skips rule actions that change the mailbox or send mail.
+func (p *Poller) SetReadOnly(readOnly bool) {
+	p.readOnly = readOnly
+}
+
+// actionDelay returns the action's schedule resolved to seconds, or its delay.
+func (p *Poller) actionDelay(a config.GmailAction) int {
+	return schedule.ActionDelay(a.Schedule, a.Timezone, p.timezone, a.ResolvedDelay())
 }
 
 // Start begins polling in a goroutine. Cancel ctx to stop.
 func (p *Poller) Start(ctx context.Context) {
+	p.mu.Lock()
+	p.parent = ctx
+	p.lastSuccess = time.Now() // grace period before the first poll
+	p.mu.Unlock()
+	p.startLoop()
+}
+
+// Restart cancels the current poll loop, including any in-flight API call,
+// and starts a fresh one. Used by the watchdog for stuck pollers; a stopped
+// poller stays stopped.
+func (p *Poller) Restart() {
+	p.mu.Lock()
+	if p.stopped {
+		p.mu.Unlock()
+		return
+	}
+	if p.loopCancel != nil {
+		p.loopCancel()
+	}
+	p.restarts++
+	p.restartedAt = time.Now()
+	p.mu.Unlock()
+	log.Printf("Gmail poller restarting (account: %s)", p.accountEmail)
+	p.startLoop()
+}
+
+// Stop cancels the poll loop and waits until it, and any poll it was in the
+// middle of, has returned. The poller does not start again; off-boarding
+// stops an account's poller before dropping its state.
+func (p *Poller) Stop() {
+	p.mu.Lock()
+	p.stopped = true
+	if p.loopCancel != nil {
+		p.loopCancel()
+	}
+	p.mu.Unlock()
+	p.loops.Wait()
+}
+
+// Status reports the poller's health; stalled is computed by the watchdog.
+func (p *Poller) Status() PollerStatus {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return PollerStatus{
+		Account:     p.accountEmail,
+		Interval:    p.interval.String(),
+		LastSuccess: p.lastSuccess,
+		LastError:   p.lastErr,
+		Restarts:    p.restarts,
+		RestartedAt: p.restartedAt,
+		Stopped:     p.stopped || p.parent != nil && p.parent.Err() != nil,
+	}
+}
+
+func (p *Poller) markSuccess() {
+	p.mu.Lock()
+	p.lastSuccess = time.Now()
+	p.lastErr = ""
+	p.mu.Unlock()
+}
+
+// skipForQuota reports whether to skip this poll for the account's quota.
+// Skipped polls count as completed so the watchdog doesn't restart the poller;
+// the reason shows as its last error. skipped counts throttled ticks.
+func (p *Poller) skipForQuota(skipped *int) bool {
+	reason := ""
+	switch {
+	case p.quota.exhausted():
+		reason = ErrQuotaExhausted.Error() + ", polling resumes at 00:00 UTC"
+	case p.quota.throttled():
+		if *skipped++; *skipped%throttledPollEvery == 0 {
+			return false
+		}
+		reason = fmt.Sprintf("daily Gmail quota nearly used up, polling every %d intervals", throttledPollEvery)
+	default:
+		*skipped = 0
+		return false
+	}
+	p.mu.Lock()
+	p.lastSuccess = time.Now()
+	p.lastErr = reason
+	p.mu.Unlock()
+	return true
+}
+
+func (p *Poller) markError(err error) {
+	p.mu.Lock()
+	p.lastErr = err.Error()
+	p.mu.Unlock()
+}
+
+func (p *Poller) startLoop() {
+	p.mu.Lock()
+	if p.stopped {
+		p.mu.Unlock()
+		return
+	}
+	if p.parent == nil {
+		p.parent = context.Background()
+	}
+	ctx, cancel := context.WithCancel(p.parent)
+	p.loopCancel = cancel
+	p.loops.Add(1)
+	p.mu.Unlock()
+
 	go func() {
+		defer p.loops.Done()
 		log.Printf("Gmail poller starting (account: %q, interval: %s, rules: %d)", p.accountEmail, p.interval, len(p.rules))
 
 		// Initialize historyId if needed
@@ -94,10 +233,11 @@ func (p *Poller) Start(ctx context.Context) {
 			hid, err := p.client.GetCurrentHistoryID(ctx)
 			if err != nil {
 				log.Printf("Failed to get initial historyId: %v (will retry)", err)
+				p.markError(err)
 				p.handleAuthError(ctx, err)
 			} else {
-				state = &GmailState{HistoryID: hid}
-				p.saveState(state)
+				p.setHistoryID(hid)
+				p.markSuccess()
 				log.Printf("Gmail poller initialized with historyId: %d", hid)
 			}
 		} else {
@@ -107,12 +247,16 @@ func (p *Poller) Start(ctx context.Context) {
 		ticker := time.NewTicker(p.interval)
 		defer ticker.Stop()
 
+		skipped := 0
 		for {
 			select {
 			case <-ctx.Done():
 				log.Printf("Gmail poller stopped (account: %s)", p.accountEmail)
 				return
 			case <-ticker.C:
+				if p.skipForQuota(&skipped) {
+					continue
+				}
 				p.poll(ctx)
 			}
 		}
@@ -126,83 +270,153 @@ func (p *Poller) poll(ctx context.Context) {
 		hid, err := p.client.GetCurrentHistoryID(ctx)
 		if err != nil {
 			log.Printf("Gmail poll: can't get historyId: %v", err)
+			p.markError(err)
 			p.handleAuthError(ctx, err)
 			return
 		}
 		if state != nil && state.HistoryID > 0 {
 			log.Printf("Gmail poll: WARNING reinitializing from historyId %d → %d, messages in between may be lost", state.HistoryID, hid)
 		}
-		state = &GmailState{HistoryID: hid}
-		p.saveState(state)
+		p.setHistoryID(hid)
+		p.markSuccess()
 		return
 	}
 
 	msgs, newHID, err := p.client.GetHistory(ctx, state.HistoryID)
 	if err != nil {
 		// historyId may be too old — reset
-		if strings.Contains(err.Error(), "404") || strings.Contains(err.Error(), "notFound") {
+		if errors.Is(err, ErrHistoryExpired) || strings.Contains(err.Error(), "404") || strings.Contains(err.Error(), "notFound") {
 			log.Printf("Gmail poll: historyId expired, resetting")
 			hid, err := p.client.GetCurrentHistoryID(ctx)
 			if err == nil {
 				log.Printf("Gmail poll: WARNING historyId reset from %d → %d, messages in between are lost", state.HistoryID, hid)
-				p.saveState(&GmailState{HistoryID: hid})
+				p.setHistoryID(hid)
+				p.markSuccess()
 			}
 			return
 		}
 		log.Printf("Gmail poll error: %v", err)
+		p.markError(err)
 		p.handleAuthError(ctx, err)
 		return
 	}
+	// The poll counts as completed once its messages are handed off
+	defer p.markSuccess()
 
 	if newHID > state.HistoryID {
-		state.HistoryID = newHID
-		p.saveState(state)
+		p.setHistoryID(newHID)
 	}
 
+	// Record the poll, the entries it handled and the rules they ran
+	var processed []string
+	hits := map[string]int{}
+	defer func() {
+		if err := p.updateState(func(s *GmailState) { s.recordPoll(time.Now().UTC(), processed, hits) }); err != nil {
+			log.Printf("Gmail poll: can't save state: %v", err)
+		}
+	}()
+
 	if len(msgs) == 0 {
 		return
 	}
 
-	// Dedup messages by ID (History API can return duplicates)
+	// Dedup by message and event (History API can return duplicates), and
+	// drop what an earlier poll handled already
 	seen := make(map[string]bool, len(msgs))
 	unique := make([]HistoryMessage, 0, len(msgs))
+	handled := 0
 	for _, msg := range msgs {
-		if seen[msg.ID] {
+		if seen[msg.key()] {
+			continue
+		}
+		seen[msg.key()] = true
+		if _, ok := state.Processed[msg.key()]; ok && remembered(msg) {
+			handled++
 			continue
 		}
-		seen[msg.ID] = true
 		unique = append(unique, msg)
 	}
 
-	log.Printf("Gmail poll: %d new messages (%d after dedup)", len(msgs), len(unique))
+	log.Printf("Gmail poll: %d new messages (%d after dedup, %d handled earlier)", len(msgs), len(unique), handled)
 
-	for _, msg := range unique {
+	for i, msg := range unique {
 		// Respect context on shutdown
 		select {
 		case <-ctx.Done():
-			log.Printf("Gmail poll: shutdown during message processing, %d messages remaining", len(unique)-len(seen))
+			log.Printf("Gmail poll: shutdown during message processing, %d messages remaining", len(unique)-i)
 			return
 		default:
 		}
-		p.evaluateRules(ctx, msg)
+		for _, rule := range p.evaluateRules(ctx, msg) {
+			hits[rule]++
+		}
+		if remembered(msg) {
+			processed = append(processed, msg.key())
+		}
+	}
+}
+
+// sampleRoll returns a value in [0, 1) for rule sampling; tests replace it.
+var sampleRoll = rand.Float64
+
+// evaluateRules runs the first rule matching msg, and the matching rules after
+// it for as long as the last one run has continue: true, as webhook rules do.
+// It returns the names of the matching rules.
+func (p *Poller) evaluateRules(ctx context.Context, msg HistoryMessage) []string {
+	look := &messageLookup{client: p.client, msg: msg}
+	var matched []string
+	for _, rule := range p.matchingRules(ctx, p.rules, msg, look) {
+		p.runRule(ctx, rule, msg, look)
+		matched = append(matched, rule.Name)
 	}
+	return matched
 }
 
-func (p *Poller) evaluateRules(ctx context.Context, msg HistoryMessage) {
-	for _, rule := range p.rules {
-		if !p.matchRule(rule.Match, msg) {
+// matchingRules returns the rules of rules that evaluateRules runs for msg.
+func (p *Poller) matchingRules(ctx context.Context, rules []config.GmailRule, msg HistoryMessage, look *messageLookup) []config.GmailRule {
+	var matched []config.GmailRule
+	for _, rule := range rules {
+		if !p.matchRule(rule.Match, msg) || !look.match(ctx, rule.Match) {
 			continue
 		}
-		log.Printf("Gmail rule '%s' matched message %s: %s", rule.Name, msg.ID, msg.Subject)
-		if rule.Action.IsCron() {
-			p.executeCronAction(ctx, rule, msg)
-		} else if rule.Action.Notify != nil {
-			p.executeNotify(ctx, rule.Action.Notify, msg)
+		matched = append(matched, rule)
+		if !rule.Continue {
+			break
+		}
+	}
+	return matched
+}
+
+// runRule runs the actions of a rule that matched msg, unless it's sampled out.
+func (p *Poller) runRule(ctx context.Context, rule config.GmailRule, msg HistoryMessage, look *messageLookup) {
+	log.Printf("Gmail rule '%s' matched message %s: %s", rule.Name, msg.ID, msg.Subject)
+	if !rule.Sample.Keep(sampleRoll()) {
+		log.Printf("Gmail rule '%s' sampled out (sample %v)", rule.Name, float64(rule.Sample))
+		return
+	}
+	for n, action := range rule.ResolvedActions() {
+		switch {
+		case action.Kind == "modify":
+			p.executeModify(ctx, rule, action, msg)
+		case action.Kind == "forward":
+			p.executeForward(ctx, rule, action, msg)
+		case action.IsCron():
+			p.executeCronAction(ctx, rule, n, msg, look)
+		case action.Notify != nil:
+			p.executeNotify(ctx, action.Notify, msg, look)
 		}
 	}
 }
 
 func (p *Poller) matchRule(match config.GmailMatch, msg HistoryMessage) bool {
+	if !slices.Contains(match.ResolvedEvents(), msg.event()) {
+		return false
+	}
+	if len(match.ChangedLabels) > 0 && !slices.ContainsFunc(match.ChangedLabels, func(l string) bool {
+		return slices.Contains(msg.ChangedLabels, l)
+	}) {
+		return false
+	}
 	// Match labels
 	if len(match.Labels) > 0 {
 		msgLabels := make(map[string]bool, len(msg.Labels))
@@ -216,37 +430,159 @@ func (p *Poller) matchRule(match config.GmailMatch, msg HistoryMessage) bool {
 		}
 	}
 	// Match from
-	if len(match.From) > 0 {
-		matched := false
-		fromLower := strings.ToLower(msg.From)
-		for _, pattern := range match.From {
-			pattern = strings.ToLower(pattern)
-			if strings.HasPrefix(pattern, "*") {
-				if strings.HasSuffix(fromLower, pattern[1:]) {
-					matched = true
-					break
-				}
-			} else if strings.Contains(fromLower, pattern) {
-				matched = true
-				break
-			}
-		}
-		if !matched {
+	if len(match.From) > 0 && !config.MatchAddress(match.From, msg.From) {
+		return false
+	}
+	if len(match.To) > 0 && !matchRecipients(match.To, msg.To) {
+		return false
+	}
+	if len(match.Cc) > 0 && !matchRecipients(match.Cc, msg.Cc) {
+		return false
+	}
+	if len(match.Subject) > 0 && !config.MatchPattern(match.Subject, msg.Subject) {
+		return false
+	}
+	if len(match.ExcludeFrom) > 0 && config.MatchAddress(match.ExcludeFrom, msg.From) {
+		return false
+	}
+	for _, l := range msg.Labels {
+		if slices.Contains(match.ExcludeLabels, l) {
 			return false
 		}
 	}
+	if match.Condition != "" {
+		ok, err := expr.Match(match.Condition, p.conditionEnv(msg))
+		if err != nil {
+			log.Printf("Gmail: invalid rule condition: %v", err)
+		}
+		return ok
+	}
 	return true
 }
 
+// matchRecipients matches patterns against each address of a To or Cc header,
+// so "*@example.com" works with several recipients.
+func matchRecipients(patterns []string, header string) bool {
+	list, err := mail.ParseAddressList(header)
+	if err != nil {
+		return config.MatchAddress(patterns, header)
+	}
+	for _, a := range list {
+		if config.MatchAddress(patterns, a.Address) || (a.Name != "" && config.MatchAddress(patterns, a.Name)) {
+			return true
+		}
+	}
+	return false
+}
+
+// messageLookup runs the matchers that need Gmail API calls, once per message
+// however many rules use them. They run after the header matchers pass.
+type messageLookup struct {
+	client  GmailClient
+	msg     HistoryMessage
+	body    *string
+	queries map[string]bool
+}
+
+func (l *messageLookup) match(ctx context.Context, match config.GmailMatch) bool {
+	if len(match.BodyContains) > 0 && !l.bodyContains(ctx, match.BodyContains) {
+		return false
+	}
+	if match.Query != "" && !l.matchesQuery(ctx, match.Query) {
+		return false
+	}
+	if match.NotQuery != "" && l.matchesQuery(ctx, match.NotQuery) {
+		return false
+	}
+	return true
+}
+
+// text fetches the plain-text body the first time a rule or action needs it.
+// A failed fetch is cached as an empty body, so the message matches no
+// body_contains rule.
+func (l *messageLookup) text(ctx context.Context) string {
+	if l.body == nil {
+		var text string
+		if full, err := l.client.GetMessage(ctx, l.msg.ID); err != nil {
+			log.Printf("Gmail: get body of %s: %v", l.msg.ID, err)
+		} else {
+			text = full.Body
+		}
+		l.body = &text
+	}
+	return *l.body
+}
+
+func (l *messageLookup) bodyContains(ctx context.Context, subs []string) bool {
+	body := strings.ToLower(l.text(ctx))
+	for _, s := range subs {
+		if strings.Contains(body, strings.ToLower(s)) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchesQuery reports whether the message is a result of the Gmail search
+// query. When that can't be determined it reports false: query doesn't
+// match, and not_query never drops mail because of an API error.
+func (l *messageLookup) matchesQuery(ctx context.Context, query string) bool {
+	if ok, done := l.queries[query]; done {
+		return ok
+	}
+	ok, err := searchMessage(ctx, l.client, l.msg.ID, l.msg.RFC822ID, query)
+	if err != nil {
+		log.Printf("Gmail: query %q for message %s: %v", query, l.msg.ID, err)
+	}
+	if l.queries == nil {
+		l.queries = make(map[string]bool)
+	}
+	l.queries[query] = ok
+	return ok
+}
+
+// searchMessage reports whether message id is a result of the Gmail search
+// query, by searching for it by its Message-ID header, rfc822ID.
+func searchMessage(ctx context.Context, client GmailClient, id, rfc822ID, query string) (bool, error) {
+	if rfc822ID == "" {
+		return false, fmt.Errorf("message %s has no Message-ID", id)
+	}
+	q := fmt.Sprintf("in:anywhere rfc822msgid:%s (%s)", strings.Trim(rfc822ID, "<>"), query)
+	found, _, err := client.ListMessages(ctx, q, 1, "")
+	if err != nil {
+		return false, err
+	}
+	return slices.ContainsFunc(found, func(m MessageMeta) bool { return m.ID == id }), nil
+}
+
+func (p *Poller) conditionEnv(msg HistoryMessage) map[string]any {
+	return map[string]any{
+		"from":           msg.From,
+		"to":             msg.To,
+		"cc":             msg.Cc,
+		"subject":        msg.Subject,
+		"snippet":        msg.Snippet,
+		"labels":         msg.Labels,
+		"id":             msg.ID,
+		"thread_id":      msg.ThreadID,
+		"account":        p.accountEmail,
+		"event":          msg.event(),
+		"changed_labels": msg.ChangedLabels,
+	}
+}
+
 func (p *Poller) templateData(msg HistoryMessage) map[string]string {
 	return map[string]string{
-		"From":         msg.From,
-		"Subject":      msg.Subject,
-		"Snippet":      msg.Snippet,
-		"ID":           msg.ID,
-		"MessageID":    msg.ID,
-		"ThreadID":     msg.ThreadID,
-		"AccountEmail": p.accountEmail,
+		"From":          msg.From,
+		"Subject":       msg.Subject,
+		"Snippet":       msg.Snippet,
+		"ID":            msg.ID,
+		"MessageID":     msg.ID,
+		"ThreadID":      msg.ThreadID,
+		"AccountEmail":  p.accountEmail,
+		"Event":         msg.event(),
+		"ChangedLabels": strings.Join(msg.ChangedLabels, ", "),
+		"Lang":          lang.Detect(msg.Subject + "\n" + msg.Snippet),
 	}
 }
 
@@ -274,39 +610,106 @@ func jobName(prefix, ruleName string, msg HistoryMessage) string {
 	return fmt.Sprintf("%s: %s", prefix, subject)
 }
 
-// executeCronAction sends a cron-style action directly to the gateway.
-func (p *Poller) executeCronAction(ctx context.Context, rule config.GmailRule, msg HistoryMessage) {
+// executeCronAction sends the n-th cron-style action of rule directly to the
+// gateway. agent_turn actions get the full body in {{.Body}}.
+func (p *Poller) executeCronAction(ctx context.Context, rule config.GmailRule, n int, msg HistoryMessage, look *messageLookup) {
 	// Check context before gateway call
 	select {
 	case <-ctx.Done():
 		return
 	default:
 	}
+	action := rule.ResolvedActions()[n]
 
-	tmplStr := rule.Action.ResolvedTemplate()
+	data := p.templateData(msg)
+	data["AttachmentText"] = ""
+	if action.ExtractText {
+		data["AttachmentText"] = p.attachmentText(ctx, msg.ID)
+	}
+	if action.Kind == "agent_turn" {
+		if look == nil {
+			look = &messageLookup{client: p.client, msg: msg}
+		}
+		data["Body"] = look.text(ctx)
+	}
+	tmplStr := action.LocalizedTemplate(data["Lang"])
 	if tmplStr == "" {
 		tmplStr = "📧 {{.From}}: {{.Subject}}"
+		if action.Kind == "agent_turn" {
+			tmplStr = agentTurnTemplate
+		}
 	}
 
-	message, err := p.renderTemplate("cron", tmplStr, p.templateData(msg))
+	message, err := p.renderTemplate("cron", tmplStr, data)
 	if err != nil {
 		log.Printf("Gmail cron action template error: %v", err)
 		return
 	}
 
 	name := jobName("gmail", rule.Name, msg)
-	if err := p.gateway.CreateOneShotJobForAgent(
-		name,
-		message,
-		rule.Action.ResolvedAgentID(),
-		rule.Action.ResolvedTimeout(),
-		rule.Action.ResolvedDelay(),
-	); err != nil {
+	if n > 0 {
+		name = fmt.Sprintf("%s #%d", name, n+1)
+	}
+	if err := gateway.CreateJob(p.gateway, gateway.JobSpec{
+		Name:           name,
+		Message:        message,
+		AgentID:        action.ResolvedAgentID(),
+		TimeoutSeconds: action.ResolvedTimeout(),
+		DelaySeconds:   p.actionDelay(action),
+		Source:         "gmail",
+		Rule:           rule.Name,
+		Tags:           action.Tags,
+		Account:        p.accountEmail,
+	}); err != nil {
 		log.Printf("Gmail cron action: failed to create gateway job: %v", err)
 	}
 }
 
-func (p *Poller) executeNotify(ctx context.Context, notify *config.GmailNotifyAction, msg HistoryMessage) {
+// agentTurnTemplate is the default message of agent_turn actions.
+const agentTurnTemplate = `New email in {{.AccountEmail}}
+From: {{.From}}
+Subject: {{.Subject}}
+Message ID: {{.ID}}
+
+{{.Body}}`
+
+// executeModify applies a modify action's label changes to msg.
+func (p *Poller) executeModify(ctx context.Context, rule config.GmailRule, action config.GmailAction, msg HistoryMessage) {
+	if p.readOnly {
+		log.Printf("Gmail rule '%s': read-only mode, not modifying message %s", rule.Name, msg.ID)
+		return
+	}
+	req := ModifyRequest{AddLabels: action.AddLabels, RemoveLabels: action.RemoveLabels, Archive: action.Archive, MarkRead: action.MarkRead}
+	if err := p.client.ModifyMessage(ctx, msg.ID, req); err != nil {
+		log.Printf("Gmail rule '%s': modify message %s: %v", rule.Name, msg.ID, err)
+		return
+	}
+	log.Printf("Gmail rule '%s': modified message %s", rule.Name, msg.ID)
+}
+
+// executeForward sends msg on to the action's recipients, with the rendered
+// message_template as a note when there is one.
+func (p *Poller) executeForward(ctx context.Context, rule config.GmailRule, action config.GmailAction, msg HistoryMessage) {
+	if p.readOnly {
+		log.Printf("Gmail rule '%s': read-only mode, not forwarding message %s", rule.Name, msg.ID)
+		return
+	}
+	var note string
+	if tmplStr := action.LocalizedTemplate(lang.Detect(msg.Subject + "\n" + msg.Snippet)); tmplStr != "" {
+		var err error
+		if note, err = p.renderTemplate("forward", tmplStr, p.templateData(msg)); err != nil {
+			log.Printf("Gmail forward note template error: %v", err)
+			return
+		}
+	}
+	if err := p.client.ForwardMessage(ctx, msg.ID, ForwardRequest{To: action.To, Note: note}); err != nil {
+		log.Printf("Gmail rule '%s': forward message %s: %v", rule.Name, msg.ID, err)
+		return
+	}
+	log.Printf("Gmail rule '%s': forwarded message %s to %s", rule.Name, msg.ID, strings.Join(action.To, ", "))
+}
+
+func (p *Poller) executeNotify(ctx context.Context, notify *config.GmailNotifyAction, msg HistoryMessage, look *messageLookup) {
 	// Check context before gateway call
 	select {
 	case <-ctx.Done():
@@ -314,12 +717,20 @@ func (p *Poller) executeNotify(ctx context.Context, notify *config.GmailNotifyAc
 	default:
 	}
 
-	tmplStr := notify.Template
+	data := p.templateData(msg)
+	data["Body"] = ""
+	if notify.IncludeBody {
+		if look == nil {
+			look = &messageLookup{client: p.client, msg: msg}
+		}
+		data["Body"] = notifyBody(look.text(ctx), notify.ResolvedMaxBodyChars())
+	}
+	tmplStr := notify.LocalizedTemplate(data["Lang"])
 	if tmplStr == "" {
 		tmplStr = "📧 {{.From}}: {{.Subject}}"
 	}
 
-	message, err := p.renderTemplate("notify", tmplStr, p.templateData(msg))
+	message, err := p.renderTemplate("notify", tmplStr, data)
 	if err != nil {
 		log.Printf("Gmail notify template error: %v", err)
 		return
@@ -330,11 +741,21 @@ func (p *Poller) executeNotify(ctx context.Context, notify *config.GmailNotifyAc
 		notify.Target, notify.Channel, message)
 
 	name := jobName("gmail-notify", "", msg)
-	if err := p.gateway.CreateOneShotJobForAgent(name, jobMsg, notify.AgentID, 30, 0); err != nil {
+	job := gateway.JobSpec{Name: name, Message: jobMsg, AgentID: notify.AgentID, TimeoutSeconds: 30, Source: "gmail", Account: p.accountEmail}
+	if err := gateway.CreateJob(p.gateway, job); err != nil {
 		log.Printf("Gmail notify: failed to create gateway job: %v", err)
 	}
 }
 
+// notifyBody trims body to max characters, marking a cut with an ellipsis.
+func notifyBody(body string, max int) string {
+	body = strings.TrimSpace(body)
+	if cut := truncateRunes(body, max); cut != body {
+		return strings.TrimSpace(cut) + "…"
+	}
+	return body
+}
+
 // handleAuthError sends an alert if the error looks like an auth failure and cooldown has passed.
 func (p *Poller) handleAuthError(ctx context.Context, err error) {
 	if p.authAlertCfg == nil || !p.authAlertCfg.Enabled {
@@ -386,13 +807,15 @@ func (p *Poller) handleAuthError(ctx context.Context, err error) {
 	}
 
 	log.Printf("Gmail auth alert: sending for %s", p.accountEmail)
-	if alertErr := p.gateway.CreateOneShotJobForAgent(
-		fmt.Sprintf("gmail-auth-alert/%s", p.accountEmail),
-		message,
-		p.authAlertCfg.AgentID,
-		timeout,
-		p.authAlertCfg.Delay,
-	); alertErr != nil {
+	if alertErr := gateway.CreateJob(p.gateway, gateway.JobSpec{
+		Name:           fmt.Sprintf("gmail-auth-alert/%s", p.accountEmail),
+		Message:        message,
+		AgentID:        p.authAlertCfg.AgentID,
+		TimeoutSeconds: timeout,
+		DelaySeconds:   p.authAlertCfg.Delay,
+		Source:         "gmail",
+		Account:        p.accountEmail,
+	}); alertErr != nil {
 		log.Printf("Gmail auth alert: failed to send: %v", alertErr)
 	}
 }
@@ -2,17 +2,51 @@ package gmail
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
-	"os"
-	"path/filepath"
 	"strings"
+	"sync/atomic"
 	"testing"
 	"time"
 
 	"github.com/katalabut/openclaw-relay/internal/config"
 )
 
+func TestPoller_Stop(t *testing.T) {
+	entered := make(chan struct{})
+	var calls int32
+	mc := &mockGmailClient{
+		getCurrentHIDFunc: func(ctx context.Context) (uint64, error) {
+			if atomic.AddInt32(&calls, 1) == 1 {
+				close(entered)
+			}
+			<-ctx.Done()
+			return 0, ctx.Err()
+		},
+	}
+	p := NewPollerForAccount(mc, "gone@example.com", "1h", nil, &mockGW{}, t.TempDir(), nil)
+	p.Start(context.Background())
+	<-entered
+
+	done := make(chan struct{})
+	go func() {
+		p.Stop()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("Stop did not return")
+	}
+	if !p.Status().Stopped {
+		t.Error("expected the poller to report stopped")
+	}
+	p.Restart()
+	time.Sleep(20 * time.Millisecond)
+	if n := atomic.LoadInt32(&calls); n != 1 {
+		t.Errorf("expected no loop after Stop, got %d calls", n)
+	}
+}
+
 func TestMatchRule_LabelMatch(t *testing.T) {
 	p := &Poller{}
 	match := config.GmailMatch{Labels: []string{"INBOX", "UNREAD"}}
@@ -58,6 +92,314 @@ func TestMatchRule_FromContains(t *testing.T) {
 	}
 }
 
+func TestMatchRule_Condition(t *testing.T) {
+	p := &Poller{accountEmail: "me@example.com"}
+	msg := HistoryMessage{From: "billing@vendor.com", Subject: "Invoice #42", Labels: []string{"INBOX"}}
+	tests := []struct {
+		match config.GmailMatch
+		want  bool
+	}{
+		{config.GmailMatch{Condition: "subject =~ '(?i)invoice' && labels == 'INBOX'"}, true},
+		{config.GmailMatch{Condition: "subject =~ 'receipt' || labels == 'STARRED'"}, false},
+		{config.GmailMatch{From: []string{"vendor.com"}, Condition: "account == 'me@example.com'"}, true},
+		{config.GmailMatch{From: []string{"other.com"}, Condition: "account == 'me@example.com'"}, false},
+		{config.GmailMatch{Condition: "subject =="}, false},
+	}
+	for _, tt := range tests {
+		if got := p.matchRule(tt.match, msg); got != tt.want {
+			t.Errorf("matchRule(%+v) = %v, want %v", tt.match, got, tt.want)
+		}
+	}
+}
+
+func TestMatchRule_SubjectAndRecipients(t *testing.T) {
+	p := &Poller{}
+	msg := HistoryMessage{
+		Subject: "Invoice #42 for March",
+		To:      `"Billing" <billing@acme.com>, ops@example.com`,
+		Cc:      "cfo@acme.com",
+	}
+	tests := []struct {
+		match config.GmailMatch
+		want  bool
+	}{
+		{config.GmailMatch{Subject: []string{"invoice*"}}, true},
+		{config.GmailMatch{Subject: []string{"invoice"}}, false}, // globs match the whole subject
+		{config.GmailMatch{Subject: []string{"receipt*", "*#?? for *"}}, true},
+		{config.GmailMatch{Subject: []string{`/#\d+ for (march|april)/`}}, false}, // regexps are case-sensitive
+		{config.GmailMatch{Subject: []string{`/(?i)#\d+ for (march|april)/`}}, true},
+		{config.GmailMatch{To: []string{"*@example.com"}}, true},
+		{config.GmailMatch{To: []string{"billing"}}, true},
+		{config.GmailMatch{To: []string{"*@other.com"}}, false},
+		{config.GmailMatch{Cc: []string{"*@acme.com"}}, true},
+		{config.GmailMatch{Cc: []string{"ops@"}}, false},
+		{config.GmailMatch{Subject: []string{"invoice*"}, To: []string{"*@other.com"}}, false},
+		{config.GmailMatch{Condition: "to =~ 'ops@' && cc == 'cfo@acme.com'"}, true},
+	}
+	for _, tt := range tests {
+		if got := p.matchRule(tt.match, msg); got != tt.want {
+			t.Errorf("matchRule(%+v) = %v, want %v", tt.match, got, tt.want)
+		}
+	}
+}
+
+func TestEvaluateRules_BodyContains(t *testing.T) {
+	fetches := 0
+	mc := &mockGmailClient{
+		getMessageFunc: func(_ context.Context, id string) (*MessageFull, error) {
+			fetches++
+			return &MessageFull{ID: id, Body: "Your payment is OVERDUE by 3 days"}, nil
+		},
+	}
+	gw := &mockGW{}
+	p := &Poller{
+		client: mc,
+		rules: []config.GmailRule{
+			{Name: "no-body", Match: config.GmailMatch{Labels: []string{"STARRED"}, BodyContains: []string{"overdue"}}},
+			{Name: "refund", Match: config.GmailMatch{BodyContains: []string{"refund"}}},
+			{Name: "overdue", Match: config.GmailMatch{BodyContains: []string{"refund", "overdue"}}, Action: config.GmailAction{MessageTemplate: "Chase {{.ID}}"}},
+		},
+		gateway: gw,
+	}
+	p.evaluateRules(context.Background(), HistoryMessage{ID: "m1", Labels: []string{"INBOX"}})
+	if len(gw.messages) != 1 || gw.messages[0] != "Chase m1" {
+		t.Errorf("expected the overdue rule to fire, got %q", gw.messages)
+	}
+	if fetches != 1 {
+		t.Errorf("expected the body to be fetched once, got %d", fetches)
+	}
+
+	fetches = 0
+	p.evaluateRules(context.Background(), HistoryMessage{ID: "m2", Labels: []string{"SPAM"}})
+	if fetches != 1 {
+		t.Errorf("expected one fetch for the label-free body rules, got %d", fetches)
+	}
+	fetches = 0
+	p.rules = p.rules[:1]
+	p.evaluateRules(context.Background(), HistoryMessage{ID: "m3", Labels: []string{"INBOX"}})
+	if fetches != 0 {
+		t.Errorf("label mismatch should skip the body fetch, got %d fetches", fetches)
+	}
+}
+
+func TestEvaluateRules_MailboxActions(t *testing.T) {
+	var modified []ModifyRequest
+	var forwarded []ForwardRequest
+	fetches := 0
+	mc := &mockGmailClient{
+		getMessageFunc: func(_ context.Context, id string) (*MessageFull, error) {
+			fetches++
+			return &MessageFull{ID: id, Body: "Invoice total: 420 EUR"}, nil
+		},
+		modifyMessageFunc: func(_ context.Context, id string, req ModifyRequest) error {
+			modified = append(modified, req)
+			return nil
+		},
+		forwardFunc: func(_ context.Context, id string, req ForwardRequest) error {
+			forwarded = append(forwarded, req)
+			return nil
+		},
+	}
+	gw := &mockGW{}
+	p := &Poller{
+		client:       mc,
+		accountEmail: "me@example.com",
+		rules: []config.GmailRule{{
+			Name:  "invoices",
+			Match: config.GmailMatch{BodyContains: []string{"invoice"}},
+			Actions: []config.GmailAction{
+				{Kind: "modify", AddLabels: []string{"Label_7"}, Archive: true, MarkRead: true},
+				{Kind: "forward", To: []string{"books@example.com"}, MessageTemplate: "From {{.AccountEmail}}"},
+				{Kind: "agent_turn", MessageTemplate: "{{.Subject}}: {{.Body}}"},
+				{Kind: "agent_turn"},
+			},
+		}},
+		gateway: gw,
+	}
+	p.evaluateRules(context.Background(), HistoryMessage{ID: "m1", From: "billing@vendor.example", Subject: "March"})
+
+	if len(modified) != 1 || modified[0].AddLabels[0] != "Label_7" || !modified[0].Archive || !modified[0].MarkRead {
+		t.Errorf("unexpected modify %+v", modified)
+	}
+	if len(forwarded) != 1 || forwarded[0].To[0] != "books@example.com" || forwarded[0].Note != "From me@example.com" {
+		t.Errorf("unexpected forward %+v", forwarded)
+	}
+	if len(gw.messages) != 2 || gw.messages[0] != "March: Invoice total: 420 EUR" {
+		t.Fatalf("unexpected agent turns %q", gw.messages)
+	}
+	if !strings.Contains(gw.messages[1], "Subject: March\nMessage ID: m1\n\nInvoice total: 420 EUR") {
+		t.Errorf("default agent_turn message lacks the body:\n%s", gw.messages[1])
+	}
+	if fetches != 1 {
+		t.Errorf("body fetched %d times, want once for matching and both agent turns", fetches)
+	}
+
+	// read-only mode leaves the mailbox alone but still dispatches
+	modified, forwarded, gw.messages = nil, nil, nil
+	p.SetReadOnly(true)
+	p.evaluateRules(context.Background(), HistoryMessage{ID: "m2"})
+	if len(modified) != 0 || len(forwarded) != 0 || len(gw.messages) != 2 {
+		t.Errorf("read-only: modified %d, forwarded %d, jobs %d", len(modified), len(forwarded), len(gw.messages))
+	}
+}
+
+func TestMatchRule_Exclusions(t *testing.T) {
+	p := &Poller{}
+	msg := HistoryMessage{From: "GitHub <notifications@noreply.github.com>", Labels: []string{"INBOX", "CATEGORY_UPDATES"}}
+	tests := []struct {
+		match config.GmailMatch
+		want  bool
+	}{
+		{config.GmailMatch{Labels: []string{"INBOX"}}, true},
+		{config.GmailMatch{Labels: []string{"INBOX"}, ExcludeFrom: []string{"*@noreply.github.com>"}}, false},
+		{config.GmailMatch{Labels: []string{"INBOX"}, ExcludeFrom: []string{"noreply.github.com"}}, false},
+		{config.GmailMatch{Labels: []string{"INBOX"}, ExcludeFrom: []string{"*@example.com"}}, true},
+		{config.GmailMatch{Labels: []string{"INBOX"}, ExcludeLabels: []string{"CATEGORY_PROMOTIONS", "CATEGORY_UPDATES"}}, false},
+		{config.GmailMatch{Labels: []string{"INBOX"}, ExcludeLabels: []string{"CATEGORY_PROMOTIONS"}}, true},
+	}
+	for _, tt := range tests {
+		if got := p.matchRule(tt.match, msg); got != tt.want {
+			t.Errorf("matchRule(%+v) = %v, want %v", tt.match, got, tt.want)
+		}
+	}
+}
+
+func TestMatchRule_Events(t *testing.T) {
+	p := &Poller{}
+	added := HistoryMessage{ID: "m1", Labels: []string{"INBOX"}}
+	important := HistoryMessage{Event: EventLabelAdded, ChangedLabels: []string{"IMPORTANT"}, ID: "m1", Labels: []string{"INBOX", "IMPORTANT"}}
+	starred := HistoryMessage{Event: EventLabelAdded, ChangedLabels: []string{"STARRED"}, ID: "m1", Labels: []string{"INBOX", "IMPORTANT", "STARRED"}}
+	deleted := HistoryMessage{Event: EventMessageDeleted, ID: "m1"}
+	tests := []struct {
+		name  string
+		match config.GmailMatch
+		msg   HistoryMessage
+		want  bool
+	}{
+		{"default matches new mail", config.GmailMatch{}, added, true},
+		{"default ignores label changes", config.GmailMatch{}, important, false},
+		{"explicit message_added", config.GmailMatch{Events: []string{"message_added"}}, added, true},
+		{"label_added", config.GmailMatch{Events: []string{"label_added"}, ChangedLabels: []string{"IMPORTANT"}}, important, true},
+		{"other label added", config.GmailMatch{Events: []string{"label_added"}, ChangedLabels: []string{"IMPORTANT"}}, starred, false},
+		{"label_removed only", config.GmailMatch{Events: []string{"label_removed"}}, important, false},
+		{"deleted", config.GmailMatch{Events: []string{"message_deleted"}}, deleted, true},
+		{"condition on event", config.GmailMatch{Events: []string{"label_added", "message_deleted"}, Condition: `event == "message_deleted"`}, deleted, true},
+	}
+	for _, tt := range tests {
+		if got := p.matchRule(tt.match, tt.msg); got != tt.want {
+			t.Errorf("%s: matchRule = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestPoll_DedupsPerEvent(t *testing.T) {
+	mc := &mockGmailClient{
+		getHistoryFunc: func(_ context.Context, _ uint64) ([]HistoryMessage, uint64, error) {
+			return []HistoryMessage{
+				{ID: "m1", Subject: "Quarterly report"},
+				{Event: EventLabelAdded, ChangedLabels: []string{"IMPORTANT"}, ID: "m1", Subject: "Quarterly report"},
+				{Event: EventLabelAdded, ChangedLabels: []string{"IMPORTANT"}, ID: "m1", Subject: "Quarterly report"},
+			}, 200, nil
+		},
+	}
+	gw := &mockGW{}
+	rules := []config.GmailRule{{
+		Name:   "important",
+		Match:  config.GmailMatch{Events: []string{"label_added"}, ChangedLabels: []string{"IMPORTANT"}},
+		Action: config.GmailAction{MessageTemplate: "{{.Event}} {{.ChangedLabels}}: {{.Subject}}"},
+	}}
+	p := NewPollerForAccount(mc, "me@example.com", "1m", rules, gw, t.TempDir(), nil)
+	p.setHistoryID(100)
+	p.poll(context.Background())
+	if len(gw.messages) != 1 {
+		t.Fatalf("expected 1 job, got %d", len(gw.messages))
+	}
+	if msg := gw.messages[0]; msg != "label_added IMPORTANT: Quarterly report" {
+		t.Errorf("message = %q", msg)
+	}
+}
+
+func TestEvaluateRules_NotQuery(t *testing.T) {
+	var queries []string
+	mc := &mockGmailClient{
+		listMessagesFunc: func(_ context.Context, query string, max int64, _ string) ([]MessageMeta, string, error) {
+			queries = append(queries, query)
+			if strings.Contains(query, "rfc822msgid:err@x") {
+				return nil, "", fmt.Errorf("invalid query")
+			}
+			if strings.Contains(query, "rfc822msgid:news@x") && strings.Contains(query, "(list:newsletter.example.com)") {
+				return []MessageMeta{{ID: "news"}}, "", nil
+			}
+			return nil, "", nil
+		},
+	}
+	gw := &mockGW{}
+	p := &Poller{
+		client: mc,
+		rules: []config.GmailRule{
+			{Name: "inbox", Match: config.GmailMatch{Labels: []string{"INBOX"}, NotQuery: "list:newsletter.example.com"}, Continue: true, Action: config.GmailAction{MessageTemplate: "inbox {{.ID}}"}},
+			{Name: "also", Match: config.GmailMatch{NotQuery: "list:newsletter.example.com"}, Action: config.GmailAction{MessageTemplate: "also {{.ID}}"}},
+		},
+		gateway: gw,
+	}
+	p.evaluateRules(context.Background(), HistoryMessage{ID: "news", RFC822ID: "<news@x>", Labels: []string{"INBOX"}})
+	if len(gw.messages) != 0 {
+		t.Errorf("newsletter should be excluded, got jobs %q", gw.messages)
+	}
+	if len(queries) != 1 || queries[0] != "in:anywhere rfc822msgid:news@x (list:newsletter.example.com)" {
+		t.Errorf("expected one cached search, got %q", queries)
+	}
+
+	p.evaluateRules(context.Background(), HistoryMessage{ID: "mail", RFC822ID: "<mail@x>", Labels: []string{"INBOX"}})
+	// a failed search or missing Message-ID doesn't exclude
+	p.evaluateRules(context.Background(), HistoryMessage{ID: "err", RFC822ID: "<err@x>", Labels: []string{"INBOX"}})
+	p.evaluateRules(context.Background(), HistoryMessage{ID: "noid", Labels: []string{"INBOX"}})
+	want := []string{"inbox mail", "also mail", "inbox err", "also err", "inbox noid", "also noid"}
+	if strings.Join(gw.messages, ",") != strings.Join(want, ",") {
+		t.Errorf("jobs = %q, want %q", gw.messages, want)
+	}
+}
+
+func TestEvaluateRules_Query(t *testing.T) {
+	var queries []string
+	mc := &mockGmailClient{
+		listMessagesFunc: func(_ context.Context, query string, max int64, _ string) ([]MessageMeta, string, error) {
+			queries = append(queries, query)
+			switch {
+			case strings.Contains(query, "rfc822msgid:err@x"):
+				return nil, "", fmt.Errorf("backend error")
+			case strings.Contains(query, "rfc822msgid:pdf@x") && strings.Contains(query, "(has:attachment filename:pdf)"):
+				return []MessageMeta{{ID: "pdf"}}, "", nil
+			}
+			return nil, "", nil
+		},
+	}
+	gw := &mockGW{}
+	p := &Poller{
+		client: mc,
+		rules: []config.GmailRule{
+			{Name: "pdfs", Match: config.GmailMatch{Labels: []string{"INBOX"}, Query: "has:attachment filename:pdf", NotQuery: "category:promotions"}, Action: config.GmailAction{MessageTemplate: "pdf {{.ID}}"}},
+		},
+		gateway: gw,
+	}
+	for _, msg := range []HistoryMessage{
+		{ID: "pdf", RFC822ID: "<pdf@x>", Labels: []string{"INBOX"}},
+		{ID: "plain", RFC822ID: "<plain@x>", Labels: []string{"INBOX"}},
+		{ID: "err", RFC822ID: "<err@x>", Labels: []string{"INBOX"}},
+		{ID: "noid", Labels: []string{"INBOX"}},
+		{ID: "sent", RFC822ID: "<sent@x>", Labels: []string{"SENT"}},
+	} {
+		p.evaluateRules(context.Background(), msg)
+	}
+	if strings.Join(gw.messages, ",") != "pdf pdf" {
+		t.Errorf("expected only the pdf message to match, got %q", gw.messages)
+	}
+	// pdf: query + not_query; plain and err: query only; noid and sent: none
+	if len(queries) != 4 {
+		t.Errorf("expected 4 searches, got %q", queries)
+	}
+}
+
 func TestEvaluateRules_FirstMatchWins(t *testing.T) {
 	// We can't easily test evaluateRules without a gateway mock,
 	// but we can test matchRule which is the core logic
@@ -90,16 +432,19 @@ func TestMatchRules_NoMatch(t *testing.T) {
 }
 
 type mockGW struct {
-	calls []string
+	calls    []string
+	messages []string
 }
 
 func (m *mockGW) CreateOneShotJob(name, message string, timeout, delay int) error {
 	m.calls = append(m.calls, name)
+	m.messages = append(m.messages, message)
 	return nil
 }
 
 func (m *mockGW) CreateOneShotJobForAgent(name, message, agentID string, timeout, delay int) error {
 	m.calls = append(m.calls, name)
+	m.messages = append(m.messages, message)
 	return nil
 }
 
@@ -211,6 +556,33 @@ func TestEvaluateRules_CronActionDefaultTemplate(t *testing.T) {
 	}
 }
 
+func TestEvaluateRules_MultipleActions(t *testing.T) {
+	gw := &mockGW{}
+	p := &Poller{
+		rules: []config.GmailRule{
+			{
+				Name:  "invoices",
+				Match: config.GmailMatch{Labels: []string{"INBOX"}},
+				Actions: []config.GmailAction{
+					{MessageTemplate: "File {{.Subject}}"},
+					{MessageTemplate: "Pay {{.Subject}}"},
+				},
+			},
+		},
+		gateway: gw,
+	}
+	p.evaluateRules(context.Background(), HistoryMessage{ID: "m1", Labels: []string{"INBOX"}, Subject: "Invoice"})
+	if len(gw.calls) != 2 {
+		t.Fatalf("expected a job per action, got %d", len(gw.calls))
+	}
+	if gw.messages[0] != "File Invoice" || gw.messages[1] != "Pay Invoice" {
+		t.Errorf("unexpected messages %q", gw.messages)
+	}
+	if gw.calls[0] == gw.calls[1] || !strings.HasSuffix(gw.calls[1], " #2") {
+		t.Errorf("expected distinct job names, got %q", gw.calls)
+	}
+}
+
 func TestTemplateData_HasAllFields(t *testing.T) {
 	p := &Poller{accountEmail: "test@test.com"}
 	msg := HistoryMessage{ID: "m1", ThreadID: "t1", From: "a@b.com", Subject: "Hi", Snippet: "snip"}
@@ -252,7 +624,7 @@ func TestExecuteNotify_DefaultTemplate(t *testing.T) {
 	p := &Poller{gateway: gw}
 	notify := &config.GmailNotifyAction{Target: "123", Channel: "telegram"}
 	msg := HistoryMessage{From: "a@b.com", Subject: "Hi"}
-	p.executeNotify(context.Background(), notify, msg)
+	p.executeNotify(context.Background(), notify, msg, nil)
 	if len(gw.calls) != 1 {
 		t.Fatalf("expected 1 call, got %d", len(gw.calls))
 	}
@@ -303,7 +675,7 @@ func TestPoll_WithState_ProcessesMessages(t *testing.T) {
 			},
 		},
 	}
-	p.saveState(&GmailState{HistoryID: 100})
+	p.setHistoryID(100)
 
 	p.poll(context.Background())
 
@@ -325,7 +697,7 @@ func TestPoll_NoNewMessages(t *testing.T) {
 	gw := &mockGW{}
 	dir := t.TempDir()
 	p := &Poller{client: mc, gateway: gw, stateDir: dir}
-	p.saveState(&GmailState{HistoryID: 100})
+	p.setHistoryID(100)
 
 	p.poll(context.Background())
 	if len(gw.calls) != 0 {
@@ -345,7 +717,7 @@ func TestPoll_HistoryError_Resets(t *testing.T) {
 	gw := &mockGW{}
 	dir := t.TempDir()
 	p := &Poller{client: mc, gateway: gw, stateDir: dir}
-	p.saveState(&GmailState{HistoryID: 50})
+	p.setHistoryID(50)
 
 	p.poll(context.Background())
 
@@ -364,7 +736,7 @@ func TestPoll_HistoryError_NonReset(t *testing.T) {
 	gw := &mockGW{}
 	dir := t.TempDir()
 	p := &Poller{client: mc, gateway: gw, stateDir: dir}
-	p.saveState(&GmailState{HistoryID: 50})
+	p.setHistoryID(50)
 
 	p.poll(context.Background())
 
@@ -398,7 +770,7 @@ func TestExecuteNotify_BadTemplate(t *testing.T) {
 	}
 	msg := HistoryMessage{From: "a@b.com", Subject: "Hi"}
 	// Should not panic, just log error
-	p.executeNotify(context.Background(), notify, msg)
+	p.executeNotify(context.Background(), notify, msg, nil)
 	// Gateway should NOT be called when template fails
 	if len(gw.calls) != 0 {
 		t.Errorf("expected 0 calls on bad template, got %d", len(gw.calls))
@@ -414,52 +786,82 @@ func TestExecuteNotify_CustomTemplate(t *testing.T) {
 		Template: "New mail from {{.From}} - {{.Subject}}",
 	}
 	msg := HistoryMessage{From: "test@test.com", Subject: "Hello"}
-	p.executeNotify(context.Background(), notify, msg)
+	p.executeNotify(context.Background(), notify, msg, nil)
 	if len(gw.calls) != 1 {
 		t.Fatalf("expected 1 call, got %d", len(gw.calls))
 	}
 }
 
-func TestMatchRule_EmptyMatch(t *testing.T) {
-	p := &Poller{}
-	match := config.GmailMatch{}
-	msg := HistoryMessage{Labels: []string{"INBOX"}}
-	if !p.matchRule(match, msg) {
-		t.Error("empty match should match everything")
-	}
-}
+func TestExecuteNotify_IncludeBody(t *testing.T) {
+	gw := &mockGW{}
+	fetched := 0
+	client := &mockGmailClient{getMessageFunc: func(ctx context.Context, id string) (*MessageFull, error) {
+		fetched++
+		return &MessageFull{ID: id, Body: "\nPlease pay invoice 1042 by Friday. Thanks!\n"}, nil
+	}}
+	p := &Poller{gateway: gw, client: client}
+	msg := HistoryMessage{ID: "m1", Subject: "Invoice"}
 
-func TestLoadState_NoFile(t *testing.T) {
-	p := &Poller{stateDir: t.TempDir()}
-	_, err := p.loadState()
-	if err == nil {
-		t.Error("expected error for missing file")
+	p.executeNotify(context.Background(), &config.GmailNotifyAction{Template: "{{.Subject}}: {{.Body}}", IncludeBody: true}, msg, nil)
+	p.executeNotify(context.Background(), &config.GmailNotifyAction{Template: "{{.Body}}", IncludeBody: true, MaxBodyChars: 18}, msg, nil)
+	p.executeNotify(context.Background(), &config.GmailNotifyAction{Template: "[{{.Body}}]"}, msg, nil)
+
+	if len(gw.messages) != 3 {
+		t.Fatalf("expected 3 calls, got %d", len(gw.messages))
+	}
+	if !strings.HasSuffix(gw.messages[0], "Invoice: Please pay invoice 1042 by Friday. Thanks!") {
+		t.Errorf("body not included: %q", gw.messages[0])
+	}
+	if !strings.HasSuffix(gw.messages[1], "\n\nPlease pay invoice…") {
+		t.Errorf("body not cut at max_body_chars: %q", gw.messages[1])
+	}
+	if !strings.HasSuffix(gw.messages[2], "\n\n[]") {
+		t.Errorf("body set without include_body: %q", gw.messages[2])
+	}
+	if fetched != 2 {
+		t.Errorf("fetched the message %d times, want 2", fetched)
 	}
 }
 
-func TestSaveLoadState_Roundtrip(t *testing.T) {
-	dir := t.TempDir()
-	p := &Poller{accountEmail: "user@example.com", stateDir: dir}
-
-	state := &GmailState{HistoryID: 12345}
-	if err := p.saveState(state); err != nil {
-		t.Fatal(err)
+func TestExecute_LocalizedTemplates(t *testing.T) {
+	gw := &mockGW{}
+	p := &Poller{gateway: gw}
+	notify := &config.GmailNotifyAction{
+		Target:    "123",
+		Channel:   "telegram",
+		Template:  "Mail: {{.Subject}}",
+		Templates: map[string]string{"ru": "Письмо ({{.Lang}}): {{.Subject}}"},
+	}
+	p.executeNotify(context.Background(), notify, HistoryMessage{Subject: "Счёт за октябрь готов"}, nil)
+	p.executeNotify(context.Background(), notify, HistoryMessage{Subject: "Your invoice is ready for you"}, nil)
+	if len(gw.messages) != 2 {
+		t.Fatalf("expected 2 calls, got %d", len(gw.messages))
+	}
+	if !strings.HasSuffix(gw.messages[0], "Письмо (ru): Счёт за октябрь готов") {
+		t.Errorf("expected Russian template, got %q", gw.messages[0])
+	}
+	if !strings.HasSuffix(gw.messages[1], "Mail: Your invoice is ready for you") {
+		t.Errorf("expected default template, got %q", gw.messages[1])
 	}
 
-	loaded, err := p.loadState()
-	if err != nil {
-		t.Fatal(err)
+	rule := config.GmailRule{Name: "r", Action: config.GmailAction{
+		MessageTemplates: map[string]string{"de": "Neue Mail: {{.Subject}}"},
+	}}
+	if !rule.Action.IsCron() {
+		t.Fatal("message_templates alone should make a cron action")
 	}
-	if loaded.HistoryID != 12345 {
-		t.Errorf("expected 12345, got %d", loaded.HistoryID)
+	p.executeCronAction(context.Background(), rule, 0, HistoryMessage{Subject: "Ihre Rechnung ist da, bitte prüfen"}, nil)
+	if gw.messages[2] != "Neue Mail: Ihre Rechnung ist da, bitte prüfen" {
+		t.Errorf("unexpected cron message %q", gw.messages[2])
 	}
+}
 
-	// Verify file content
-	data, _ := os.ReadFile(filepath.Join(dir, "gmail-state-user_at_example.com.json"))
-	var s GmailState
-	json.Unmarshal(data, &s)
-	if s.HistoryID != 12345 {
-		t.Errorf("file content mismatch")
+func TestMatchRule_EmptyMatch(t *testing.T) {
+	p := &Poller{}
+	match := config.GmailMatch{}
+	msg := HistoryMessage{Labels: []string{"INBOX"}}
+	if !p.matchRule(match, msg) {
+		t.Error("empty match should match everything")
 	}
 }
 
@@ -490,7 +892,7 @@ func TestPoll_DeduplicatesMessages(t *testing.T) {
 			},
 		},
 	}
-	p.saveState(&GmailState{HistoryID: 100})
+	p.setHistoryID(100)
 	p.poll(context.Background())
 
 	// Should only fire 2 times (not 3) due to dedup
@@ -625,7 +1027,7 @@ func TestPoll_RespectsContextDuringProcessing(t *testing.T) {
 			},
 		},
 	}
-	p.saveState(&GmailState{HistoryID: 100})
+	p.setHistoryID(100)
 	p.poll(ctx)
 
 	// With cancelled context, should process 0 messages
@@ -633,3 +1035,69 @@ func TestPoll_RespectsContextDuringProcessing(t *testing.T) {
 		t.Errorf("expected 0 calls with cancelled context, got %d", len(gw.calls))
 	}
 }
+
+func TestPoller_ActionDelay(t *testing.T) {
+	p := &Poller{}
+	p.SetTimezone("Europe/Berlin")
+	if got := p.actionDelay(config.GmailAction{Delay: 7}); got != 7 {
+		t.Errorf("expected delay 7, got %d", got)
+	}
+	if got := p.actionDelay(config.GmailAction{Delay: 7, Schedule: "in 10m"}); got != 600 {
+		t.Errorf("expected schedule to override delay, got %d", got)
+	}
+	if got := p.actionDelay(config.GmailAction{Delay: 7, Schedule: "later"}); got != 7 {
+		t.Errorf("expected fallback to delay, got %d", got)
+	}
+}
+
+func TestEvaluateRules_Sample(t *testing.T) {
+	orig := sampleRoll
+	sampleRoll = func() float64 { return 0.5 }
+	defer func() { sampleRoll = orig }()
+
+	gw := &mockGW{}
+	p := &Poller{
+		accountEmail: "user@test.com",
+		rules: []config.GmailRule{
+			{Name: "trial", Sample: 0.25, Continue: true, Match: config.GmailMatch{Labels: []string{"INBOX"}}, Action: config.GmailAction{MessageTemplate: "trial"}},
+			{Name: "all", Match: config.GmailMatch{Labels: []string{"INBOX"}}, Action: config.GmailAction{MessageTemplate: "all"}},
+		},
+		gateway: gw,
+	}
+	p.evaluateRules(context.Background(), HistoryMessage{ID: "m1", Labels: []string{"INBOX"}})
+	if len(gw.calls) != 1 {
+		t.Errorf("expected only the unsampled rule to fire, got %d calls", len(gw.calls))
+	}
+}
+
+func TestEvaluateRules_Continue(t *testing.T) {
+	inbox := config.GmailMatch{Labels: []string{"INBOX"}}
+	tests := []struct {
+		name                 string
+		continue1, continue2 bool
+		want                 int
+	}{
+		{"first match wins", false, false, 1},
+		{"continue to the next match", true, false, 2},
+		{"continue through all matches", true, true, 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gw := &mockGW{}
+			p := &Poller{
+				accountEmail: "user@test.com",
+				rules: []config.GmailRule{
+					{Name: "first", Continue: tt.continue1, Match: inbox, Action: config.GmailAction{MessageTemplate: "first"}},
+					{Name: "other", Match: config.GmailMatch{Labels: []string{"SPAM"}}, Action: config.GmailAction{MessageTemplate: "other"}},
+					{Name: "second", Continue: tt.continue2, Match: inbox, Action: config.GmailAction{MessageTemplate: "second"}},
+					{Name: "third", Match: inbox, Action: config.GmailAction{MessageTemplate: "third"}},
+				},
+				gateway: gw,
+			}
+			p.evaluateRules(context.Background(), HistoryMessage{ID: "m1", Labels: []string{"INBOX"}})
+			if len(gw.calls) != tt.want {
+				t.Errorf("expected %d jobs, got %d", tt.want, len(gw.calls))
+			}
+		})
+	}
+}
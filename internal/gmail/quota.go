@@ -0,0 +1,195 @@
+package gmail
+
+import (
+	"errors"
+	"log"
+	"maps"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrQuotaExhausted is returned for Gmail calls made after the account's
+// daily unit budget is used up.
+var ErrQuotaExhausted = errors.New("daily Gmail quota used up")
+
+// throttledPollEvery is how many poll intervals a throttled poller waits
+// between polls.
+const throttledPollEvery = 4
+
+// Quota counts the Gmail API units one account spends per day (UTC), using
+// the per-method costs Google documents, and enforces its daily budget.
+// Counts are approximate and start from zero on restart; Google's own
+// counters stay authoritative.
+type Quota struct {
+	account    string
+	budget     int // 0 = unlimited
+	throttleAt int // units
+	now        func() time.Time
+
+	mu    sync.Mutex
+	day   string // "2006-01-02"
+	units int
+	calls int
+}
+
+// QuotaUsage is a snapshot of a Quota for /api/gmail/quota.
+type QuotaUsage struct {
+	Account   string `json:"account"`
+	Day       string `json:"day"`
+	Units     int    `json:"units"`
+	Calls     int    `json:"calls"`
+	Budget    int    `json:"budget,omitempty"`
+	Throttled bool   `json:"throttled"`
+	Exhausted bool   `json:"exhausted"`
+}
+
+// NewQuota tracks account with a daily budget of units (0 = unlimited),
+// throttled from throttlePercent of it.
+func NewQuota(account string, budget, throttlePercent int) *Quota {
+	return &Quota{account: account, budget: budget, throttleAt: budget * throttlePercent / 100, now: time.Now}
+}
+
+// rollover starts a new day's count; q.mu must be held.
+func (q *Quota) rollover() {
+	if day := q.now().UTC().Format(time.DateOnly); day != q.day {
+		q.day, q.units, q.calls = day, 0, 0
+	}
+}
+
+// charge counts a call, or refuses it with ErrQuotaExhausted once the budget
+// is used up.
+func (q *Quota) charge(method, path string) error {
+	if q == nil {
+		return nil
+	}
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.rollover()
+	if q.budget > 0 && q.units >= q.budget {
+		return ErrQuotaExhausted
+	}
+	before := q.units
+	q.units += unitCost(method, path)
+	q.calls++
+	switch {
+	case q.budget > 0 && before < q.budget && q.units >= q.budget:
+		log.Printf("Gmail quota: %s used its daily budget of %d units, calls resume at 00:00 UTC", q.account, q.budget)
+	case q.budget > 0 && before < q.throttleAt && q.units >= q.throttleAt:
+		log.Printf("Gmail quota: %s used %d of %d units today, throttling the poller and API", q.account, q.units, q.budget)
+	}
+	return nil
+}
+
+// Usage returns today's counts.
+func (q *Quota) Usage() QuotaUsage {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.rollover()
+	return QuotaUsage{
+		Account:   q.account,
+		Day:       q.day,
+		Units:     q.units,
+		Calls:     q.calls,
+		Budget:    q.budget,
+		Throttled: q.budget > 0 && q.units >= q.throttleAt,
+		Exhausted: q.budget > 0 && q.units >= q.budget,
+	}
+}
+
+// Reset starts today's count over, as off-boarding does, and reports whether
+// anything was counted.
+func (q *Quota) Reset() bool {
+	if q == nil {
+		return false
+	}
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.rollover()
+	counted := q.calls > 0
+	q.units, q.calls = 0, 0
+	return counted
+}
+
+// throttled reports whether the account is past its throttle level, which
+// includes having used up its budget.
+func (q *Quota) throttled() bool {
+	return q != nil && q.Usage().Throttled
+}
+
+// exhausted reports whether the account has used up its budget.
+func (q *Quota) exhausted() bool {
+	return q != nil && q.Usage().Exhausted
+}
+
+// resetIn returns the time until the count starts over.
+func (q *Quota) resetIn() time.Duration {
+	now := q.now().UTC()
+	return now.Truncate(24 * time.Hour).Add(24 * time.Hour).Sub(now)
+}
+
+// unitCost returns the quota units a Gmail API call costs, from its method
+// and path, e.g. GET /gmail/v1/users/me/messages/{id}. Calls it doesn't know
+// cost 5, the most common price.
+func unitCost(method, path string) int {
+	_, rest, ok := strings.Cut(path, "/users/")
+	if !ok {
+		return 5
+	}
+	parts := strings.Split(strings.Trim(rest, "/"), "/")[1:] // drop the user
+	if len(parts) == 0 {
+		return 5
+	}
+	last := parts[len(parts)-1]
+	switch parts[0] {
+	case "profile":
+		return 1
+	case "history":
+		return 2
+	case "labels":
+		if method == http.MethodGet {
+			return 1
+		}
+		return 5
+	case "threads":
+		return 10
+	case "watch":
+		return 100
+	case "drafts":
+		switch {
+		case last == "send":
+			return 100
+		case method == http.MethodGet:
+			return 5
+		case method == http.MethodPut:
+			return 15
+		}
+		return 10
+	case "messages":
+		switch last {
+		case "send":
+			return 100
+		case "batchModify", "batchDelete":
+			return 50
+		}
+	}
+	return 5
+}
+
+// Quotas are the accounts' quotas by email.
+type Quotas map[string]*Quota
+
+// HandleQuota serves GET /api/gmail/quota, today's usage per account.
+func (qs Quotas) HandleQuota(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		jsonError(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	usage := make([]QuotaUsage, 0, len(qs))
+	for _, account := range slices.Sorted(maps.Keys(qs)) {
+		usage = append(usage, qs[account].Usage())
+	}
+	jsonResponse(w, map[string]any{"accounts": usage})
+}
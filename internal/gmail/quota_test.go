@@ -0,0 +1,191 @@
+package gmail
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/katalabut/openclaw-relay/internal/config"
+)
+
+func TestUnitCost(t *testing.T) {
+	tests := []struct {
+		method, path string
+		want         int
+	}{
+		{"GET", "/gmail/v1/users/me/profile", 1},
+		{"GET", "/gmail/v1/users/me/history", 2},
+		{"GET", "/gmail/v1/users/me/labels", 1},
+		{"POST", "/gmail/v1/users/me/labels", 5},
+		{"GET", "/gmail/v1/users/me/messages", 5},
+		{"GET", "/gmail/v1/users/me/messages/m1", 5},
+		{"POST", "/gmail/v1/users/me/messages/m1/modify", 5},
+		{"GET", "/gmail/v1/users/me/messages/m1/attachments/a1", 5},
+		{"POST", "/gmail/v1/users/me/messages/send", 100},
+		{"POST", "/gmail/v1/users/me/messages/batchModify", 50},
+		{"GET", "/gmail/v1/users/me/threads/t1", 10},
+		{"GET", "/gmail/v1/users/me/drafts", 5},
+		{"POST", "/gmail/v1/users/me/drafts", 10},
+		{"PUT", "/gmail/v1/users/me/drafts/d1", 15},
+		{"POST", "/gmail/v1/users/me/drafts/send", 100},
+		{"GET", "/somewhere/else", 5},
+	}
+	for _, tt := range tests {
+		if got := unitCost(tt.method, tt.path); got != tt.want {
+			t.Errorf("%s %s = %d, want %d", tt.method, tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestQuota(t *testing.T) {
+	now := time.Date(2026, 3, 10, 23, 0, 0, 0, time.UTC)
+	q := NewQuota("me@example.com", 20, 50)
+	q.now = func() time.Time { return now }
+
+	for range 2 {
+		if err := q.charge("GET", "/gmail/v1/users/me/messages/m1"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if u := q.Usage(); u.Units != 10 || u.Calls != 2 || !u.Throttled || u.Exhausted {
+		t.Errorf("usage %+v", u)
+	}
+	q.charge("GET", "/gmail/v1/users/me/threads/t1")
+	if err := q.charge("GET", "/gmail/v1/users/me/profile"); !errors.Is(err, ErrQuotaExhausted) {
+		t.Errorf("expected ErrQuotaExhausted, got %v", err)
+	}
+	if u := q.Usage(); u.Units != 20 || u.Calls != 3 || !u.Exhausted {
+		t.Errorf("usage %+v", u)
+	}
+	if d := q.resetIn(); d != time.Hour {
+		t.Errorf("resetIn = %v", d)
+	}
+
+	now = now.Add(time.Hour)
+	if u := q.Usage(); u.Day != "2026-03-11" || u.Units != 0 || u.Throttled {
+		t.Errorf("expected a new day, got %+v", u)
+	}
+
+	unlimited := NewQuota("me@example.com", 0, 80)
+	for range 100 {
+		unlimited.charge("POST", "/gmail/v1/users/me/messages/send")
+	}
+	if unlimited.throttled() || unlimited.exhausted() || unlimited.Usage().Units != 10000 {
+		t.Errorf("unlimited quota %+v", unlimited.Usage())
+	}
+	if !unlimited.Reset() || unlimited.Usage().Units != 0 || unlimited.Reset() {
+		t.Errorf("expected Reset to clear the count once, got %+v", unlimited.Usage())
+	}
+	var none *Quota
+	if none.charge("GET", "/") != nil || none.throttled() || none.Reset() {
+		t.Error("a nil quota must not limit")
+	}
+}
+
+func TestRetryTransport_ChargesQuota(t *testing.T) {
+	calls := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls++; calls == 1 {
+			w.WriteHeader(http.StatusTooManyRequests)
+		}
+	}))
+	defer srv.Close()
+
+	rt := newRetryTransport(http.DefaultTransport, "me@example.com", config.GmailRetryConfig{BaseDelay: "1ms", MaxDelay: "10ms"})
+	rt.quota = NewQuota("me@example.com", 10, 80)
+	req, _ := http.NewRequest("GET", srv.URL+"/gmail/v1/users/me/messages/m1", nil)
+	resp, err := rt.RoundTrip(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if u := rt.quota.Usage(); u.Units != 10 || u.Calls != 2 {
+		t.Errorf("expected both attempts charged, got %+v", u)
+	}
+	if _, err := rt.RoundTrip(req); !errors.Is(err, ErrQuotaExhausted) || calls != 2 {
+		t.Errorf("expected the call refused, got %v after %d calls", err, calls)
+	}
+}
+
+func TestHandler_Quota(t *testing.T) {
+	mc := &mockGmailClient{
+		listLabelsFunc: func(_ context.Context) ([]LabelInfo, error) {
+			return []LabelInfo{{ID: "INBOX"}}, nil
+		},
+	}
+	quotas := Quotas{
+		"me@example.com":    NewQuota("me@example.com", 100, 80),
+		"other@example.com": NewQuota("other@example.com", 0, 80),
+	}
+	h := NewMultiHandler(map[string]GmailClient{"me@example.com": mc, "other@example.com": mc})
+	h.SetQuotas(quotas)
+	mux := http.NewServeMux()
+	h.RegisterRoutes(mux)
+	mux.HandleFunc("/api/gmail/quota", quotas.HandleQuota)
+
+	get := func(path string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest("GET", path, nil))
+		return rec
+	}
+	if rec := get("/api/gmail/labels?account=me@example.com"); rec.Code != http.StatusOK {
+		t.Fatalf("status %d", rec.Code)
+	}
+	for range 16 {
+		quotas["me@example.com"].charge("GET", "/gmail/v1/users/me/messages/m1")
+	}
+	rec := get("/api/gmail/labels?account=me@example.com")
+	if rec.Code != http.StatusTooManyRequests || rec.Header().Get("Retry-After") == "" {
+		t.Errorf("expected 429 with Retry-After, got %d %v", rec.Code, rec.Header())
+	}
+	if rec := get("/api/gmail/labels?account=other@example.com"); rec.Code != http.StatusOK {
+		t.Errorf("other account: status %d", rec.Code)
+	}
+
+	var body struct{ Accounts []QuotaUsage }
+	if err := json.Unmarshal(get("/api/gmail/quota").Body.Bytes(), &body); err != nil {
+		t.Fatal(err)
+	}
+	if len(body.Accounts) != 2 || body.Accounts[0].Account != "me@example.com" || body.Accounts[0].Units != 80 || !body.Accounts[0].Throttled {
+		t.Errorf("unexpected usage %+v", body.Accounts)
+	}
+}
+
+func TestPoller_SkipForQuota(t *testing.T) {
+	q := NewQuota("me@example.com", 100, 50)
+	p := &Poller{accountEmail: "me@example.com", quota: q}
+
+	skipped := 0
+	if p.skipForQuota(&skipped) {
+		t.Error("an unthrottled poller must poll")
+	}
+	for range 6 {
+		q.charge("GET", "/gmail/v1/users/me/threads/t1") // throttled, not used up
+	}
+	var polls []bool
+	for range 8 {
+		polls = append(polls, !p.skipForQuota(&skipped))
+	}
+	if fmt.Sprint(polls) != "[false false false true false false false true]" {
+		t.Errorf("polls = %v", polls)
+	}
+	if s := p.Status(); s.LastSuccess.IsZero() || !strings.Contains(s.LastError, "nearly used up") {
+		t.Errorf("status %+v", s)
+	}
+
+	q.charge("POST", "/gmail/v1/users/me/watch") // used up
+	for range 8 {
+		if !p.skipForQuota(&skipped) {
+			t.Fatal("an exhausted poller must not poll")
+		}
+	}
+	if s := p.Status(); !strings.Contains(s.LastError, "00:00 UTC") {
+		t.Errorf("status %+v", s)
+	}
+}
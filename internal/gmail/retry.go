@@ -0,0 +1,140 @@
+package gmail
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"log"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/katalabut/openclaw-relay/internal/config"
+)
+
+// retryTransport retries Gmail API requests that hit a rate limit or a server
+// error, with exponential backoff and jitter, and caps the requests one
+// account has in flight. A Retry-After header is honored when it is within
+// maxDelay. Each attempt is charged to the account's quota.
+type retryTransport struct {
+	next       http.RoundTripper
+	account    string
+	maxRetries int
+	baseDelay  time.Duration
+	maxDelay   time.Duration
+	sem        chan struct{} // nil = unlimited
+	quota      *Quota        // charged per attempt; nil = not counted
+}
+
+func newRetryTransport(next http.RoundTripper, account string, cfg config.GmailRetryConfig) *retryTransport {
+	t := &retryTransport{next: next, account: account, maxRetries: cfg.ResolvedMaxRetries()}
+	t.baseDelay, t.maxDelay = cfg.ResolvedDelays()
+	if n := cfg.ResolvedMaxConcurrent(); n > 0 {
+		t.sem = make(chan struct{}, n)
+	}
+	return t
+}
+
+func (t *retryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	for attempt := 0; ; attempt++ {
+		if attempt > 0 && req.Body != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			req = req.Clone(req.Context())
+			req.Body = body
+		}
+		if err := t.quota.charge(req.Method, req.URL.Path); err != nil {
+			return nil, err
+		}
+		release, err := t.acquire(req.Context())
+		if err != nil {
+			return nil, err
+		}
+		resp, err := t.next.RoundTrip(req)
+		if err != nil {
+			release()
+			return nil, err
+		}
+		wait, retry := retryAfter(resp)
+		if wait == 0 {
+			wait = t.backoff(attempt)
+		}
+		canResend := req.Body == nil || req.GetBody != nil
+		if !retry || attempt >= t.maxRetries || wait > t.maxDelay || !canResend {
+			resp.Body = &releasingBody{ReadCloser: resp.Body, release: release}
+			return resp, nil
+		}
+		io.Copy(io.Discard, io.LimitReader(resp.Body, 1<<16))
+		resp.Body.Close()
+		release()
+		log.Printf("Gmail: %s %s for %s answered %d, retry %d/%d in %s", req.Method, req.URL.Path, t.account, resp.StatusCode, attempt+1, t.maxRetries, wait.Round(time.Millisecond))
+		select {
+		case <-req.Context().Done():
+			return nil, req.Context().Err()
+		case <-time.After(wait):
+		}
+	}
+}
+
+// acquire takes a slot of the account's concurrency cap.
+func (t *retryTransport) acquire(ctx context.Context) (func(), error) {
+	if t.sem == nil {
+		return func() {}, nil
+	}
+	select {
+	case t.sem <- struct{}{}:
+		var once sync.Once
+		return func() { once.Do(func() { <-t.sem }) }, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// backoff is baseDelay doubled per attempt, capped at maxDelay, with the
+// upper half jittered so clients don't retry in lockstep.
+func (t *retryTransport) backoff(attempt int) time.Duration {
+	d := t.maxDelay
+	if attempt < 30 {
+		d = min(t.baseDelay<<attempt, t.maxDelay)
+	}
+	return d/2 + rand.N(d/2+1)
+}
+
+// retryAfter reports whether resp is a rate limit or server error worth
+// retrying, and the wait its Retry-After header asks for, if any. A 403 is
+// only retried for rateLimitExceeded and userRateLimitExceeded; the body it
+// reads is put back for the caller.
+func retryAfter(resp *http.Response) (time.Duration, bool) {
+	switch {
+	case resp.StatusCode == http.StatusTooManyRequests, resp.StatusCode >= 500:
+	case resp.StatusCode == http.StatusForbidden:
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<16))
+		resp.Body.Close()
+		resp.Body = io.NopCloser(bytes.NewReader(body))
+		if !bytes.Contains(body, []byte("ateLimitExceeded")) {
+			return 0, false
+		}
+	default:
+		return 0, false
+	}
+	if secs, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && secs > 0 {
+		return time.Duration(secs) * time.Second, true
+	}
+	return 0, true
+}
+
+// releasingBody frees the concurrency slot when the caller closes the body.
+type releasingBody struct {
+	io.ReadCloser
+	release func()
+}
+
+func (b *releasingBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.release()
+	return err
+}
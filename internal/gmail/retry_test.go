@@ -0,0 +1,175 @@
+package gmail
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/katalabut/openclaw-relay/internal/config"
+	"github.com/katalabut/openclaw-relay/internal/tokens"
+	"golang.org/x/oauth2"
+)
+
+func TestRetryTransport(t *testing.T) {
+	tests := []struct {
+		name      string
+		responses []func(w http.ResponseWriter)
+		wantCode  int
+		wantCalls int
+	}{
+		{"429 then ok", []func(http.ResponseWriter){
+			func(w http.ResponseWriter) { w.WriteHeader(http.StatusTooManyRequests) },
+			func(w http.ResponseWriter) { fmt.Fprint(w, "ok") },
+		}, http.StatusOK, 2},
+		{"403 rate limit", []func(http.ResponseWriter){
+			func(w http.ResponseWriter) {
+				http.Error(w, `{"error":{"errors":[{"reason":"userRateLimitExceeded"}]}}`, http.StatusForbidden)
+			},
+			func(w http.ResponseWriter) { fmt.Fprint(w, "ok") },
+		}, http.StatusOK, 2},
+		{"403 other", []func(http.ResponseWriter){
+			func(w http.ResponseWriter) {
+				http.Error(w, `{"error":{"errors":[{"reason":"insufficientPermissions"}]}}`, http.StatusForbidden)
+			},
+		}, http.StatusForbidden, 1},
+		{"server errors until out of retries", []func(http.ResponseWriter){
+			func(w http.ResponseWriter) { w.WriteHeader(http.StatusServiceUnavailable) },
+			func(w http.ResponseWriter) { w.WriteHeader(http.StatusServiceUnavailable) },
+			func(w http.ResponseWriter) { w.WriteHeader(http.StatusServiceUnavailable) },
+		}, http.StatusServiceUnavailable, 3},
+		{"Retry-After past max_delay", []func(http.ResponseWriter){
+			func(w http.ResponseWriter) {
+				w.Header().Set("Retry-After", "60")
+				w.WriteHeader(http.StatusTooManyRequests)
+			},
+		}, http.StatusTooManyRequests, 1},
+		{"not found", []func(http.ResponseWriter){
+			func(w http.ResponseWriter) { w.WriteHeader(http.StatusNotFound) },
+		}, http.StatusNotFound, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls atomic.Int32
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if body, _ := io.ReadAll(r.Body); string(body) != `{"q":1}` {
+					t.Errorf("body = %q", body)
+				}
+				n := int(calls.Add(1)) - 1
+				tt.responses[min(n, len(tt.responses)-1)](w)
+			}))
+			defer srv.Close()
+
+			rt := newRetryTransport(http.DefaultTransport, "me@example.com", config.GmailRetryConfig{
+				MaxRetries: 2, BaseDelay: "1ms", MaxDelay: "10ms",
+			})
+			req, _ := http.NewRequest("POST", srv.URL, strings.NewReader(`{"q":1}`))
+			resp, err := rt.RoundTrip(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			body, _ := io.ReadAll(resp.Body)
+			resp.Body.Close()
+			if resp.StatusCode != tt.wantCode || int(calls.Load()) != tt.wantCalls {
+				t.Errorf("got %d after %d calls (%s), want %d after %d", resp.StatusCode, calls.Load(), body, tt.wantCode, tt.wantCalls)
+			}
+			if resp.StatusCode == http.StatusForbidden && !strings.Contains(string(body), "insufficientPermissions") {
+				t.Errorf("403 body not passed through: %q", body)
+			}
+		})
+	}
+}
+
+func TestRetryTransport_MaxConcurrent(t *testing.T) {
+	var inFlight, peak atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := inFlight.Add(1)
+		for {
+			p := peak.Load()
+			if n <= p || peak.CompareAndSwap(p, n) {
+				break
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+		inFlight.Add(-1)
+		fmt.Fprint(w, "ok")
+	}))
+	defer srv.Close()
+
+	client := &http.Client{Transport: newRetryTransport(http.DefaultTransport, "me@example.com", config.GmailRetryConfig{MaxConcurrent: 2})}
+	var wg sync.WaitGroup
+	for range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			resp, err := client.Get(srv.URL)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+		}()
+	}
+	wg.Wait()
+	if p := peak.Load(); p > 2 {
+		t.Errorf("%d requests in flight, want at most 2", p)
+	}
+}
+
+func TestRetryTransport_ContextCancel(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer srv.Close()
+
+	rt := newRetryTransport(http.DefaultTransport, "me@example.com", config.GmailRetryConfig{BaseDelay: "1m", MaxDelay: "1m"})
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	req, _ := http.NewRequestWithContext(ctx, "GET", srv.URL, nil)
+	if _, err := rt.RoundTrip(req); err == nil {
+		t.Error("expected the backoff to stop with the context")
+	}
+}
+
+func TestClient_SetRetry(t *testing.T) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer tok" {
+			t.Errorf("Authorization = %q", r.Header.Get("Authorization"))
+		}
+		if calls.Add(1) == 1 {
+			http.Error(w, `{"error":{"code":429,"message":"Too Many Requests"}}`, http.StatusTooManyRequests)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"labels":[{"id":"INBOX","name":"INBOX"}]}`)
+	}))
+	defer srv.Close()
+
+	store, err := tokens.NewStore(filepath.Join(t.TempDir(), "tokens.json.enc"), strings.Repeat("ab", 32))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := store.SaveGoogle(&oauth2.Token{AccessToken: "tok", RefreshToken: "r", Expiry: time.Now().Add(time.Hour)}, "me@example.com"); err != nil {
+		t.Fatal(err)
+	}
+	c := NewClientForAccount(store, &oauth2.Config{}, "me@example.com")
+	c.SetEndpoint(srv.URL + "/")
+	c.SetRetry(config.GmailRetryConfig{BaseDelay: "1ms", MaxDelay: "10ms"})
+
+	labels, err := c.ListLabels(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(labels) != 1 || calls.Load() != 2 {
+		t.Errorf("got %v after %d calls", labels, calls.Load())
+	}
+}
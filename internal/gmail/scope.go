@@ -0,0 +1,57 @@
+package gmail
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/katalabut/openclaw-relay/internal/auth"
+	"github.com/katalabut/openclaw-relay/internal/config"
+)
+
+// keyScope returns the Gmail scope of the API key a request was made with,
+// or nil for the internal token.
+func keyScope(r *http.Request) *config.GmailKeyScope {
+	if k := auth.KeyFrom(r.Context()); k != nil {
+		return &k.Gmail
+	}
+	return nil
+}
+
+// scopedQuery narrows a search to the key's query.
+func scopedQuery(scope *config.GmailKeyScope, q string) string {
+	if scope == nil || scope.Query == "" {
+		return q
+	}
+	return "(" + q + ") (" + scope.Query + ")"
+}
+
+// inScope reports whether the key may see msg: whether msg matches its query.
+func inScope(ctx context.Context, client GmailClient, scope *config.GmailKeyScope, msg *MessageFull) (bool, error) {
+	if scope == nil || scope.Query == "" {
+		return true, nil
+	}
+	return searchMessage(ctx, client, msg.ID, msg.RFC822ID, scope.Query)
+}
+
+// labelsAllowed returns the first label of req the key may not change.
+func labelsAllowed(scope *config.GmailKeyScope, req ModifyRequest) (string, bool) {
+	if scope == nil {
+		return "", true
+	}
+	add, remove := req.Labels()
+	for _, l := range append(add, remove...) {
+		if !scope.AllowsLabel(l) {
+			return l, false
+		}
+	}
+	return "", true
+}
+
+// draftsAllowed answers 403 and returns false when the key may not use drafts.
+func draftsAllowed(w http.ResponseWriter, r *http.Request) bool {
+	if scope := keyScope(r); scope != nil && !scope.Drafts {
+		jsonError(w, "drafts are not allowed for this API key", http.StatusForbidden)
+		return false
+	}
+	return true
+}
@@ -0,0 +1,135 @@
+package gmail
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/katalabut/openclaw-relay/internal/auth"
+	"github.com/katalabut/openclaw-relay/internal/config"
+)
+
+func TestHandler_APIKeyScope(t *testing.T) {
+	msgs := map[string]*MessageFull{
+		"m1": {ID: "m1", ThreadID: "t1", RFC822ID: "<invoice@acme.com>", Attachments: []Attachment{{ID: "a1", Filename: "invoice.pdf"}}},
+		"m2": {ID: "m2", ThreadID: "t1", RFC822ID: "<private@example.com>", Attachments: []Attachment{{ID: "a2", Filename: "private.pdf"}}},
+	}
+	var (
+		queries  []string
+		modified []string
+		drafts   int
+	)
+	mc := &mockGmailClient{
+		listMessagesFunc: func(_ context.Context, query string, _ int64, _ string) ([]MessageMeta, string, error) {
+			queries = append(queries, query)
+			if strings.Contains(query, "rfc822msgid:invoice@acme.com (from:acme.com)") {
+				return []MessageMeta{{ID: "m1"}}, "", nil
+			}
+			return nil, "", nil
+		},
+		getMessageFunc: func(_ context.Context, id string) (*MessageFull, error) {
+			return msgs[id], nil
+		},
+		getThreadFunc: func(_ context.Context, _ string) ([]MessageFull, error) {
+			return []MessageFull{*msgs["m1"], *msgs["m2"]}, nil
+		},
+		getAttachmentFunc: func(_ context.Context, _, _ string) ([]byte, error) {
+			return []byte("pdf"), nil
+		},
+		modifyMessageFunc: func(_ context.Context, id string, _ ModifyRequest) error {
+			modified = append(modified, id)
+			return nil
+		},
+		listLabelsFunc: func(_ context.Context) ([]LabelInfo, error) {
+			return nil, nil
+		},
+		listDraftsFunc: func(_ context.Context, _ int64) ([]Draft, error) {
+			drafts++
+			return nil, nil
+		},
+	}
+	keys := []config.APIKey{
+		{Name: "invoices", Key: "invoice-key", Gmail: config.GmailKeyScope{
+			Accounts: []string{"work@example.com"},
+			Query:    "from:acme.com",
+			Labels:   []string{"Label_Paid", "UNREAD"},
+		}},
+		{Name: "drafter", Key: "draft-key", Gmail: config.GmailKeyScope{Drafts: true}},
+	}
+	mux := http.NewServeMux()
+	NewMultiHandler(map[string]GmailClient{"work@example.com": mc, "home@example.com": mc}).RegisterRoutes(mux)
+	mux.HandleFunc("/api/gmail/pollers", func(w http.ResponseWriter, r *http.Request) {})
+	handler := auth.KeyMiddleware("secret", keys, mux)
+
+	tests := []struct {
+		name   string
+		key    string
+		method string
+		path   string
+		body   string
+		want   int
+	}{
+		{"list", "invoice-key", "GET", "/api/gmail/messages?q=is:unread", "", http.StatusOK},
+		{"other account", "invoice-key", "GET", "/api/gmail/messages?account=home@example.com", "", http.StatusBadRequest},
+		{"matching message", "invoice-key", "GET", "/api/gmail/message/m1", "", http.StatusOK},
+		{"other message", "invoice-key", "GET", "/api/gmail/message/m2", "", http.StatusNotFound},
+		{"other attachment", "invoice-key", "GET", "/api/gmail/message/m2/attachments/a2", "", http.StatusNotFound},
+		{"thread", "invoice-key", "GET", "/api/gmail/threads/t1", "", http.StatusOK},
+		{"allowed labels", "invoice-key", "POST", "/api/gmail/modify/m1", `{"addLabels":["Label_Paid"],"markRead":true}`, http.StatusOK},
+		{"other label", "invoice-key", "POST", "/api/gmail/modify/m1", `{"archive":true}`, http.StatusForbidden},
+		{"modify other message", "invoice-key", "POST", "/api/gmail/modify/m2", `{"addLabels":["Label_Paid"]}`, http.StatusNotFound},
+		{"no drafts", "invoice-key", "GET", "/api/gmail/drafts", "", http.StatusForbidden},
+		{"pollers", "invoice-key", "GET", "/api/gmail/pollers", "", http.StatusForbidden},
+		{"other API", "invoice-key", "GET", "/api/status", "", http.StatusForbidden},
+		{"drafts", "draft-key", "GET", "/api/gmail/drafts", "", http.StatusOK},
+		{"no labels", "draft-key", "POST", "/api/gmail/modify/m2", `{"star":true}`, http.StatusForbidden},
+		{"internal token", "secret", "GET", "/api/gmail/message/m2", "", http.StatusOK},
+		{"unknown key", "nope", "GET", "/api/gmail/labels", "", http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+			req.Header.Set("X-Relay-Token", tt.key)
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+			if rec.Code != tt.want {
+				t.Errorf("status = %d, want %d: %s", rec.Code, tt.want, rec.Body)
+			}
+		})
+	}
+
+	if queries[0] != "(is:unread) (from:acme.com)" {
+		t.Errorf("list query = %q", queries[0])
+	}
+	if len(modified) != 1 || modified[0] != "m1" || drafts != 1 {
+		t.Errorf("modified %v, drafts listed %d times", modified, drafts)
+	}
+}
+
+func TestHandler_APIKeyThread(t *testing.T) {
+	mc := &mockGmailClient{
+		listMessagesFunc: func(_ context.Context, query string, _ int64, _ string) ([]MessageMeta, string, error) {
+			if strings.Contains(query, "rfc822msgid:a@x") {
+				return []MessageMeta{{ID: "m1"}}, "", nil
+			}
+			return nil, "", nil
+		},
+		getThreadFunc: func(_ context.Context, _ string) ([]MessageFull, error) {
+			return []MessageFull{{ID: "m1", RFC822ID: "<a@x>"}, {ID: "m2", RFC822ID: "<b@x>"}}, nil
+		},
+	}
+	keys := []config.APIKey{{Name: "k", Key: "key", Gmail: config.GmailKeyScope{Query: "label:shared"}}}
+	mux := http.NewServeMux()
+	NewHandler(mc).RegisterRoutes(mux)
+	handler := auth.KeyMiddleware("secret", keys, mux)
+
+	req := httptest.NewRequest("GET", "/api/gmail/threads/t1", nil)
+	req.Header.Set("X-Relay-Token", "key")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"m1"`) || strings.Contains(rec.Body.String(), `"m2"`) {
+		t.Errorf("expected only m1, got %d %s", rec.Code, rec.Body)
+	}
+}
@@ -0,0 +1,333 @@
+package gmail
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/katalabut/openclaw-relay/internal/atomicfile"
+	"github.com/katalabut/openclaw-relay/internal/migrate"
+)
+
+// stateFileName is the file in the state dir holding every account's poller
+// state.
+const stateFileName = "gmail-state.json"
+
+// processedRetention is how long the key of a handled history entry is kept,
+// and processedLimit how many are kept at most.
+const (
+	processedRetention = 7 * 24 * time.Hour
+	processedLimit     = 20000
+)
+
+// GmailState is what a poller persists for its account.
+type GmailState struct {
+	HistoryID uint64    `json:"history_id"`
+	LastPoll  time.Time `json:"last_poll,omitzero"`
+	// Processed maps the event/ID keys of the handled message_added and
+	// message_deleted entries to when they were handled, so a poll never
+	// handles one twice.
+	Processed map[string]time.Time `json:"processed,omitempty"`
+	// RuleHits counts the messages each rule matched.
+	RuleHits map[string]int `json:"rule_hits,omitempty"`
+	// Backfilled maps a rule name to the IDs of the messages
+	// `relay gmail backfill` ran it for.
+	Backfilled map[string]map[string]time.Time `json:"backfilled,omitempty"`
+}
+
+// stateDoc is the state file.
+type stateDoc struct {
+	Version  int                    `json:"version"`
+	Accounts map[string]*GmailState `json:"accounts"`
+}
+
+// stateSchema versions the state file. Add a migrate.Step when stateDoc or
+// GmailState changes shape.
+var stateSchema = migrate.Schema{Name: "gmail"}
+
+// stateMu serializes reads and writes of the state file, which the pollers of
+// all accounts share, and guards removedAccounts.
+var stateMu sync.Mutex
+
+// removedAccounts holds the state file paths and accounts RemoveState
+// dropped, so a late write can't bring an off-boarded account's state back
+// before the process restarts.
+var removedAccounts = map[[2]string]bool{}
+
+// ErrAccountRemoved is returned when saving the state of an account whose
+// state was removed.
+var ErrAccountRemoved = errors.New("account was off-boarded")
+
+func stateFilePath(stateDir string) string {
+	return filepath.Join(stateDir, stateFileName)
+}
+
+// legacyStateFiles are the per-account state and backfill files written before
+// the state file was shared; their contents are imported on first use.
+func legacyStateFiles(stateDir, accountEmail string) (state, backfill string) {
+	safe := strings.ReplaceAll(accountEmail, "/", "_")
+	safe = strings.ReplaceAll(safe, "@", "_at_")
+	return filepath.Join(stateDir, fmt.Sprintf("gmail-state-%s.json", safe)),
+		filepath.Join(stateDir, fmt.Sprintf("gmail-backfill-%s.json", safe))
+}
+
+func readStateDoc(stateDir string) (*stateDoc, error) {
+	doc := &stateDoc{Accounts: map[string]*GmailState{}}
+	data, err := os.ReadFile(stateFilePath(stateDir))
+	if os.IsNotExist(err) {
+		return doc, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if data, _, err = stateSchema.Apply(data); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, doc); err != nil {
+		return nil, fmt.Errorf("%s: %w", stateFileName, err)
+	}
+	if doc.Accounts == nil {
+		doc.Accounts = map[string]*GmailState{}
+	}
+	return doc, nil
+}
+
+// writeStateDoc replaces the state file atomically, so a crash mid-write
+// leaves the previous state rather than a truncated file.
+func writeStateDoc(stateDir string, doc *stateDoc) error {
+	doc.Version = stateSchema.Current()
+	return atomicfile.WriteJSON(stateFilePath(stateDir), doc)
+}
+
+// importLegacyState reads an account's per-account files into doc. It returns
+// the files read, to be removed once doc is written.
+func importLegacyState(stateDir, accountEmail string, doc *stateDoc) ([]string, error) {
+	statePath, backfillPath := legacyStateFiles(stateDir, accountEmail)
+	var (
+		s     GmailState
+		found []string
+	)
+	if data, err := os.ReadFile(statePath); err == nil {
+		if err := json.Unmarshal(data, &s); err != nil {
+			return nil, fmt.Errorf("%s: %w", filepath.Base(statePath), err)
+		}
+		found = append(found, statePath)
+	} else if !os.IsNotExist(err) {
+		return nil, err
+	}
+	if data, err := os.ReadFile(backfillPath); err == nil {
+		var l struct {
+			Rules map[string]map[string]time.Time `json:"rules"`
+		}
+		if err := json.Unmarshal(data, &l); err != nil {
+			return nil, fmt.Errorf("%s: %w", filepath.Base(backfillPath), err)
+		}
+		s.Backfilled = l.Rules
+		found = append(found, backfillPath)
+	} else if !os.IsNotExist(err) {
+		return nil, err
+	}
+	if len(found) > 0 {
+		doc.Accounts[accountEmail] = &s
+	}
+	return found, nil
+}
+
+// loadAccountState returns an account's state. The error wraps os.ErrNotExist
+// when the account has none yet.
+func loadAccountState(stateDir, accountEmail string) (*GmailState, error) {
+	stateMu.Lock()
+	defer stateMu.Unlock()
+	doc, err := readStateDoc(stateDir)
+	if err != nil {
+		return nil, err
+	}
+	if doc.Accounts[accountEmail] == nil {
+		if _, err := importLegacyState(stateDir, accountEmail, doc); err != nil {
+			return nil, err
+		}
+	}
+	s := doc.Accounts[accountEmail]
+	if s == nil {
+		return nil, fmt.Errorf("no Gmail state for %s: %w", accountEmail, os.ErrNotExist)
+	}
+	return s, nil
+}
+
+// updateAccountState applies fn to an account's state, a zero GmailState if
+// it has none, and writes the state file.
+func updateAccountState(stateDir, accountEmail string, fn func(*GmailState)) error {
+	stateMu.Lock()
+	defer stateMu.Unlock()
+	if removedAccounts[[2]string{stateFilePath(stateDir), accountEmail}] {
+		return fmt.Errorf("%s: %w", accountEmail, ErrAccountRemoved)
+	}
+	doc, err := readStateDoc(stateDir)
+	if err != nil {
+		return err
+	}
+	var legacy []string
+	if doc.Accounts[accountEmail] == nil {
+		if legacy, err = importLegacyState(stateDir, accountEmail, doc); err != nil {
+			return err
+		}
+	}
+	s := doc.Accounts[accountEmail]
+	if s == nil {
+		s = &GmailState{}
+		doc.Accounts[accountEmail] = s
+	}
+	fn(s)
+	if err := writeStateDoc(stateDir, doc); err != nil {
+		return err
+	}
+	for _, path := range legacy {
+		os.Remove(path)
+	}
+	return nil
+}
+
+// RemoveState deletes an account's poller state, including its backfill
+// record. It reports whether the account had any. Until the process
+// restarts, the account's state is not written again.
+func RemoveState(stateDir, accountEmail string) (bool, error) {
+	stateMu.Lock()
+	defer stateMu.Unlock()
+	removedAccounts[[2]string{stateFilePath(stateDir), accountEmail}] = true
+	doc, err := readStateDoc(stateDir)
+	if err != nil {
+		return false, err
+	}
+	_, removed := doc.Accounts[accountEmail]
+	if removed {
+		delete(doc.Accounts, accountEmail)
+		if err := writeStateDoc(stateDir, doc); err != nil {
+			return false, err
+		}
+	}
+	statePath, backfillPath := legacyStateFiles(stateDir, accountEmail)
+	for _, path := range []string{statePath, backfillPath} {
+		err := os.Remove(path)
+		if err == nil {
+			removed = true
+		} else if !os.IsNotExist(err) {
+			return removed, err
+		}
+	}
+	return removed, nil
+}
+
+// recordPoll stamps a poll's time and adds the history entries it handled
+// and the rules they matched, dropping processed keys older than
+// processedRetention.
+func (s *GmailState) recordPoll(now time.Time, processed []string, hits map[string]int) {
+	s.LastPoll = now
+	if s.Processed == nil && len(processed) > 0 {
+		s.Processed = map[string]time.Time{}
+	}
+	for _, key := range processed {
+		s.Processed[key] = now
+	}
+	maps.DeleteFunc(s.Processed, func(_ string, at time.Time) bool {
+		return now.Sub(at) > processedRetention
+	})
+	if extra := len(s.Processed) - processedLimit; extra > 0 {
+		keys := slices.SortedFunc(maps.Keys(s.Processed), func(a, b string) int {
+			return s.Processed[a].Compare(s.Processed[b])
+		})
+		for _, key := range keys[:extra] {
+			delete(s.Processed, key)
+		}
+	}
+	s.addHits(hits)
+}
+
+// remembered reports whether msg's entry is kept in Processed: message_added
+// and message_deleted happen once per message, so seeing one again, e.g.
+// after a historyId reset, means it was handled already. Label changes can
+// repeat and are only deduplicated within a poll.
+func remembered(msg HistoryMessage) bool {
+	return msg.event() == EventMessageAdded || msg.event() == EventMessageDeleted
+}
+
+func (s *GmailState) addHits(hits map[string]int) {
+	if len(hits) == 0 {
+		return
+	}
+	if s.RuleHits == nil {
+		s.RuleHits = map[string]int{}
+	}
+	for rule, n := range hits {
+		s.RuleHits[rule] += n
+	}
+}
+
+func (p *Poller) loadState() (*GmailState, error) {
+	return loadAccountState(p.stateDir, p.accountEmail)
+}
+
+func (p *Poller) updateState(fn func(*GmailState)) error {
+	return updateAccountState(p.stateDir, p.accountEmail, fn)
+}
+
+// setHistoryID saves the historyId the next poll starts from.
+func (p *Poller) setHistoryID(hid uint64) error {
+	return p.updateState(func(s *GmailState) { s.HistoryID = hid })
+}
+
+// PruneState drops the state of accounts not in accounts, including their
+// legacy files, and returns what it dropped: account emails and file names.
+// With dryRun it only returns them.
+func PruneState(stateDir string, accounts []string, dryRun bool) ([]string, error) {
+	stateMu.Lock()
+	defer stateMu.Unlock()
+	doc, err := readStateDoc(stateDir)
+	if err != nil {
+		return nil, err
+	}
+	var pruned []string
+	for _, acct := range slices.Sorted(maps.Keys(doc.Accounts)) {
+		if !slices.Contains(accounts, acct) {
+			pruned = append(pruned, acct)
+			delete(doc.Accounts, acct)
+		}
+	}
+	if len(pruned) > 0 && !dryRun {
+		if err := writeStateDoc(stateDir, doc); err != nil {
+			return nil, err
+		}
+	}
+
+	keep := map[string]bool{}
+	for _, acct := range accounts {
+		statePath, backfillPath := legacyStateFiles(stateDir, acct)
+		keep[statePath], keep[backfillPath] = true, true
+	}
+	var legacy []string
+	for _, pattern := range []string{"gmail-state-*.json", "gmail-backfill-*.json"} {
+		matches, err := filepath.Glob(filepath.Join(stateDir, pattern))
+		if err != nil {
+			return nil, err
+		}
+		legacy = append(legacy, matches...)
+	}
+	for _, path := range legacy {
+		if keep[path] {
+			continue
+		}
+		if !dryRun {
+			if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+				return pruned, err
+			}
+		}
+		pruned = append(pruned, filepath.Base(path))
+	}
+	return pruned, nil
+}
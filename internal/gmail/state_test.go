@@ -0,0 +1,280 @@
+package gmail
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/katalabut/openclaw-relay/internal/config"
+)
+
+func TestState_SharedFile(t *testing.T) {
+	dir := t.TempDir()
+	a := &Poller{accountEmail: "a@example.com", stateDir: dir}
+	b := &Poller{accountEmail: "b@example.com", stateDir: dir}
+
+	if _, err := a.loadState(); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("expected no state, got %v", err)
+	}
+	if err := a.setHistoryID(12345); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.setHistoryID(7); err != nil {
+		t.Fatal(err)
+	}
+	if s, err := a.loadState(); err != nil || s.HistoryID != 12345 {
+		t.Errorf("a: got %+v, %v", s, err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, "gmail-state.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var doc stateDoc
+	if err := json.Unmarshal(data, &doc); err != nil {
+		t.Fatal(err)
+	}
+	if doc.Version != stateSchema.Current() || len(doc.Accounts) != 2 || doc.Accounts["b@example.com"].HistoryID != 7 {
+		t.Errorf("unexpected file %s", data)
+	}
+	if tmps, _ := filepath.Glob(filepath.Join(dir, ".gmail-state-*")); len(tmps) != 0 {
+		t.Errorf("temp files left behind: %v", tmps)
+	}
+
+	if err := os.WriteFile(stateFilePath(dir), []byte(`{"version":9,"accounts":{}}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := a.loadState(); err == nil {
+		t.Error("expected an error for a newer state file")
+	}
+	if err := a.setHistoryID(1); err == nil {
+		t.Error("expected the newer state file to be left alone")
+	}
+}
+
+func TestState_ImportsLegacyFiles(t *testing.T) {
+	dir := t.TempDir()
+	statePath, backfillPath := legacyStateFiles(dir, "user@example.com")
+	if err := os.WriteFile(statePath, []byte(`{"history_id":18446744073709551615}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(backfillPath, []byte(`{"version":0,"rules":{"invoices":{"m1":"2026-01-02T00:00:00Z"}}}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	p := &Poller{accountEmail: "user@example.com", stateDir: dir}
+
+	s, err := p.loadState()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.HistoryID != 18446744073709551615 || len(s.Backfilled["invoices"]) != 1 {
+		t.Errorf("unexpected import %+v", s)
+	}
+	if _, err := os.Stat(statePath); err != nil {
+		t.Errorf("loading removed the legacy file: %v", err)
+	}
+
+	if err := p.setHistoryID(20); err != nil {
+		t.Fatal(err)
+	}
+	for _, path := range []string{statePath, backfillPath} {
+		if _, err := os.Stat(path); !os.IsNotExist(err) {
+			t.Errorf("%s left after import: %v", filepath.Base(path), err)
+		}
+	}
+	if s, _ := p.loadState(); s.HistoryID != 20 || len(s.Backfilled["invoices"]) != 1 {
+		t.Errorf("unexpected state %+v", s)
+	}
+}
+
+func TestRemoveState(t *testing.T) {
+	dir := t.TempDir()
+	p := &Poller{accountEmail: "user@example.com", stateDir: dir}
+	other := &Poller{accountEmail: "other@example.com", stateDir: dir}
+	p.setHistoryID(1)
+	other.setHistoryID(2)
+
+	removed, err := RemoveState(dir, "user@example.com")
+	if err != nil || !removed {
+		t.Fatalf("expected state removed, got removed=%v err=%v", removed, err)
+	}
+	if _, err := p.loadState(); err == nil {
+		t.Error("expected the state to be gone")
+	}
+	if s, err := other.loadState(); err != nil || s.HistoryID != 2 {
+		t.Errorf("other account's state: %+v, %v", s, err)
+	}
+	// A poll finishing after the removal doesn't bring the state back
+	if err := p.setHistoryID(5); !errors.Is(err, ErrAccountRemoved) {
+		t.Errorf("expected ErrAccountRemoved, got %v", err)
+	}
+	if err := other.setHistoryID(3); err != nil {
+		t.Errorf("other account: %v", err)
+	}
+
+	removed, err = RemoveState(dir, "user@example.com")
+	if err != nil || removed {
+		t.Errorf("expected no-op for missing state, got removed=%v err=%v", removed, err)
+	}
+
+	statePath, _ := legacyStateFiles(dir, "user@example.com")
+	os.WriteFile(statePath, []byte(`{"history_id":3}`), 0600)
+	if removed, err = RemoveState(dir, "user@example.com"); err != nil || !removed {
+		t.Errorf("expected the legacy file removed, got removed=%v err=%v", removed, err)
+	}
+	if _, err := os.Stat(statePath); !os.IsNotExist(err) {
+		t.Errorf("legacy file left: %v", err)
+	}
+}
+
+func TestPruneState(t *testing.T) {
+	dir := t.TempDir()
+	(&Poller{accountEmail: "user@example.com", stateDir: dir}).setHistoryID(1)
+	(&Poller{accountEmail: "gone@example.com", stateDir: dir}).setHistoryID(2)
+	legacyKept, _ := legacyStateFiles(dir, "user@example.com")
+	_, legacyGone := legacyStateFiles(dir, "old@example.com")
+	os.WriteFile(legacyKept, []byte(`{"history_id":3}`), 0600)
+	os.WriteFile(legacyGone, []byte(`{}`), 0600)
+
+	accounts := []string{"user@example.com"}
+	pruned, err := PruneState(dir, accounts, true)
+	if err != nil || len(pruned) != 2 || pruned[0] != "gone@example.com" || pruned[1] != filepath.Base(legacyGone) {
+		t.Fatalf("dry run: pruned %v, err %v", pruned, err)
+	}
+	if _, err := os.Stat(legacyGone); err != nil {
+		t.Error("a dry run must not remove files")
+	}
+	if _, err := PruneState(dir, accounts, false); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := (&Poller{accountEmail: "gone@example.com", stateDir: dir}).loadState(); err == nil {
+		t.Error("expected the removed account's state gone")
+	}
+	if s, err := (&Poller{accountEmail: "user@example.com", stateDir: dir}).loadState(); err != nil || s.HistoryID != 1 {
+		t.Errorf("configured account's state: %+v, %v", s, err)
+	}
+	if _, err := os.Stat(legacyGone); !os.IsNotExist(err) {
+		t.Errorf("legacy file left: %v", err)
+	}
+	if _, err := os.Stat(legacyKept); err != nil {
+		t.Errorf("configured account's legacy file removed: %v", err)
+	}
+	if pruned, _ := PruneState(dir, accounts, false); len(pruned) != 0 {
+		t.Errorf("expected nothing left to prune, got %v", pruned)
+	}
+}
+
+func TestGmailState_RecordPoll(t *testing.T) {
+	now := time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC)
+	s := &GmailState{Processed: map[string]time.Time{
+		"message_added/old":    now.Add(-8 * 24 * time.Hour),
+		"message_added/recent": now.Add(-time.Hour),
+	}}
+	s.recordPoll(now, []string{"message_added/m1", "label_added/m1"}, map[string]int{"r1": 2})
+	s.recordPoll(now, nil, map[string]int{"r1": 1, "r2": 1})
+
+	if !s.LastPoll.Equal(now) {
+		t.Errorf("LastPoll = %v", s.LastPoll)
+	}
+	if _, ok := s.Processed["message_added/old"]; ok || len(s.Processed) != 3 {
+		t.Errorf("Processed = %v", s.Processed)
+	}
+	if s.RuleHits["r1"] != 3 || s.RuleHits["r2"] != 1 {
+		t.Errorf("RuleHits = %v", s.RuleHits)
+	}
+}
+
+func TestPoll_RecordsState(t *testing.T) {
+	mc := &mockGmailClient{
+		getHistoryFunc: func(_ context.Context, _ uint64) ([]HistoryMessage, uint64, error) {
+			return []HistoryMessage{
+				{ID: "m1", Labels: []string{"INBOX"}},
+				{ID: "m2", Labels: []string{"SPAM"}},
+			}, 200, nil
+		},
+	}
+	p := &Poller{
+		client:       mc,
+		gateway:      &mockGW{},
+		accountEmail: "user@example.com",
+		stateDir:     t.TempDir(),
+		rules: []config.GmailRule{{
+			Name:   "inbox",
+			Match:  config.GmailMatch{Labels: []string{"INBOX"}},
+			Action: config.GmailAction{MessageTemplate: "mail {{.ID}}"},
+		}},
+	}
+	p.setHistoryID(100)
+
+	p.poll(context.Background())
+
+	s, err := p.loadState()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.HistoryID != 200 || s.LastPoll.IsZero() || len(s.Processed) != 2 || s.RuleHits["inbox"] != 1 {
+		t.Errorf("unexpected state %+v", s)
+	}
+	if _, ok := s.Processed["message_added/m2"]; !ok {
+		t.Errorf("unmatched message not recorded: %v", s.Processed)
+	}
+}
+
+func TestGmailState_ProcessedLimit(t *testing.T) {
+	now := time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC)
+	s := &GmailState{Processed: map[string]time.Time{}}
+	for i := range processedLimit {
+		s.Processed[fmt.Sprintf("message_added/m%d", i)] = now.Add(-time.Duration(processedLimit-i) * time.Second)
+	}
+	s.recordPoll(now, []string{"message_added/new"}, nil)
+	if len(s.Processed) != processedLimit {
+		t.Fatalf("kept %d keys, want %d", len(s.Processed), processedLimit)
+	}
+	if _, ok := s.Processed["message_added/m0"]; ok {
+		t.Error("expected the oldest key to be dropped")
+	}
+	if _, ok := s.Processed["message_added/new"]; !ok {
+		t.Error("expected the new key to be kept")
+	}
+}
+
+func TestPoll_SkipsProcessed(t *testing.T) {
+	history := []HistoryMessage{
+		{ID: "m1", Subject: "Invoice"},
+		{ID: "m1", Event: EventLabelAdded, ChangedLabels: []string{"STARRED"}, Subject: "Invoice"},
+	}
+	mc := &mockGmailClient{
+		getHistoryFunc: func(_ context.Context, _ uint64) ([]HistoryMessage, uint64, error) {
+			return history, 200, nil
+		},
+	}
+	gw := &mockGW{}
+	rules := []config.GmailRule{{
+		Name:     "all",
+		Match:    config.GmailMatch{Events: []string{"message_added", "label_added"}},
+		Continue: true,
+		Action:   config.GmailAction{MessageTemplate: "{{.Event}} {{.ID}}"},
+	}}
+	p := NewPollerForAccount(mc, "me@example.com", "1m", rules, gw, t.TempDir(), nil)
+	p.setHistoryID(100)
+	p.poll(context.Background())
+
+	// After a reset to an older historyId the same entries come back
+	history = append(history, HistoryMessage{ID: "m2", Subject: "Lunch"})
+	p.setHistoryID(100)
+	p.poll(context.Background())
+
+	want := []string{"message_added m1", "label_added m1", "label_added m1", "message_added m2"}
+	if !slices.Equal(gw.messages, want) {
+		t.Errorf("jobs = %v, want %v", gw.messages, want)
+	}
+	if s, _ := p.loadState(); len(s.Processed) != 2 {
+		t.Errorf("Processed = %v", s.Processed)
+	}
+}
@@ -0,0 +1,150 @@
+package gmail
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/katalabut/openclaw-relay/internal/config"
+	"github.com/katalabut/openclaw-relay/internal/gateway"
+)
+
+// Watchdog flags pollers that haven't completed a poll within stall_intervals
+// poll intervals, e.g. because a network call hung. Stalls are always logged;
+// alerting and restarting are opt-in via gmail.watchdog.
+type Watchdog struct {
+	pollers []*Poller
+	cfg     config.GmailWatchdogConfig
+	gateway gateway.GatewayClient
+
+	mu      sync.Mutex
+	stalled map[string]bool // accounts already reported in the current stall
+}
+
+func NewWatchdog(pollers []*Poller, cfg *config.GmailWatchdogConfig, gw gateway.GatewayClient) *Watchdog {
+	w := &Watchdog{pollers: pollers, gateway: gw, stalled: make(map[string]bool)}
+	if cfg != nil {
+		w.cfg = *cfg
+	}
+	if w.cfg.StallIntervals <= 0 {
+		w.cfg.StallIntervals = 3
+	}
+	return w
+}
+
+// Start checks pollers every shortest poll interval until ctx is done.
+func (w *Watchdog) Start(ctx context.Context) {
+	if len(w.pollers) == 0 {
+		return
+	}
+	every := w.pollers[0].interval
+	for _, p := range w.pollers {
+		if p.interval < every {
+			every = p.interval
+		}
+	}
+	go func() {
+		ticker := time.NewTicker(every)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case now := <-ticker.C:
+				w.check(now)
+			}
+		}
+	}()
+}
+
+// Statuses returns each poller's status with the stalled flag set.
+func (w *Watchdog) Statuses(now time.Time) []PollerStatus {
+	out := make([]PollerStatus, 0, len(w.pollers))
+	for _, p := range w.pollers {
+		s := p.Status()
+		s.Stalled = w.isStalled(p, s, now)
+		out = append(out, s)
+	}
+	return out
+}
+
+func (w *Watchdog) isStalled(p *Poller, s PollerStatus, now time.Time) bool {
+	return !s.Stopped && now.Sub(s.LastSuccess) > time.Duration(w.cfg.StallIntervals)*p.interval
+}
+
+func (w *Watchdog) check(now time.Time) {
+	for _, p := range w.pollers {
+		s := p.Status()
+		stalled := w.isStalled(p, s, now)
+
+		w.mu.Lock()
+		reported := w.stalled[s.Account]
+		w.stalled[s.Account] = stalled
+		w.mu.Unlock()
+
+		if !stalled {
+			if reported {
+				log.Printf("Gmail watchdog: poller for %s recovered", s.Account)
+			}
+			continue
+		}
+		// A restarted poller gets the same grace period before it's restarted again
+		restartDue := w.cfg.Restart && now.Sub(s.RestartedAt) > time.Duration(w.cfg.StallIntervals)*p.interval
+		if reported && !restartDue {
+			continue
+		}
+
+		since := now.Sub(s.LastSuccess).Round(time.Second)
+		log.Printf("Gmail watchdog: poller for %s has not completed a poll in %s (last error: %q)", s.Account, since, s.LastError)
+		if !reported && w.cfg.Alert {
+			w.alert(s, since)
+		}
+		if restartDue {
+			p.Restart()
+		}
+	}
+}
+
+func (w *Watchdog) alert(s PollerStatus, since time.Duration) {
+	tmplStr := w.cfg.MessageTemplate
+	if tmplStr == "" {
+		tmplStr = "[Relay Alert] Gmail poller for {{.AccountEmail}} has not completed a poll in {{.Since}}. Last error: {{.Error}}"
+	}
+	data := map[string]string{
+		"AccountEmail": s.Account,
+		"Since":        since.String(),
+		"Error":        s.LastError,
+		"LastSuccess":  s.LastSuccess.UTC().Format(time.RFC3339),
+	}
+	message, err := (&Poller{}).renderTemplate("watchdog", tmplStr, data)
+	if err != nil {
+		log.Printf("Gmail watchdog template error: %v", err)
+		message = fmt.Sprintf("[Relay Alert] Gmail poller for %s has not completed a poll in %s", s.Account, since)
+	}
+	timeout := w.cfg.Timeout
+	if timeout == 0 {
+		timeout = 90
+	}
+	if err := gateway.CreateJob(w.gateway, gateway.JobSpec{
+		Name:           fmt.Sprintf("gmail-watchdog/%s", s.Account),
+		Message:        message,
+		AgentID:        w.cfg.AgentID,
+		TimeoutSeconds: timeout,
+		DelaySeconds:   w.cfg.Delay,
+		Source:         "gmail",
+	}); err != nil {
+		log.Printf("Gmail watchdog: failed to send alert: %v", err)
+	}
+}
+
+// HandlePollers serves GET /api/gmail/pollers.
+func (w *Watchdog) HandlePollers(rw http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		jsonError(rw, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	jsonResponse(rw, map[string]any{"pollers": w.Statuses(time.Now())})
+}
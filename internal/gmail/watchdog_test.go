@@ -0,0 +1,168 @@
+package gmail
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/katalabut/openclaw-relay/internal/config"
+)
+
+func stalledPoller(email string, lastSuccess time.Time) *Poller {
+	return &Poller{accountEmail: email, interval: time.Minute, lastSuccess: lastSuccess, lastErr: "dial tcp: i/o timeout"}
+}
+
+func TestWatchdog_DetectsStall(t *testing.T) {
+	now := time.Now()
+	ok := stalledPoller("ok@example.com", now.Add(-2*time.Minute))
+	stuck := stalledPoller("stuck@example.com", now.Add(-4*time.Minute))
+	w := NewWatchdog([]*Poller{ok, stuck}, nil, &mockGW{})
+
+	st := w.Statuses(now)
+	if st[0].Stalled || !st[1].Stalled {
+		t.Errorf("expected only the second poller stalled (3 intervals default), got %+v", st)
+	}
+	if st[1].LastError == "" {
+		t.Error("expected last error in status")
+	}
+}
+
+func TestWatchdog_AlertsOncePerStall(t *testing.T) {
+	gw := &mockGW{}
+	now := time.Now()
+	p := stalledPoller("stuck@example.com", now.Add(-10*time.Minute))
+	w := NewWatchdog([]*Poller{p}, &config.GmailWatchdogConfig{Alert: true}, gw)
+
+	w.check(now)
+	w.check(now.Add(time.Minute))
+	if len(gw.calls) != 1 || gw.calls[0] != "gmail-watchdog/stuck@example.com" {
+		t.Fatalf("expected a single alert, got %v", gw.calls)
+	}
+
+	// Recovery re-arms the alert
+	p.markSuccess()
+	w.check(time.Now())
+	p.mu.Lock()
+	p.lastSuccess = time.Now().Add(-10 * time.Minute)
+	p.mu.Unlock()
+	w.check(time.Now())
+	if len(gw.calls) != 2 {
+		t.Errorf("expected a second alert after recovery, got %v", gw.calls)
+	}
+}
+
+func TestWatchdog_NoAlertWhenDisabled(t *testing.T) {
+	gw := &mockGW{}
+	now := time.Now()
+	w := NewWatchdog([]*Poller{stalledPoller("stuck@example.com", now.Add(-time.Hour))}, nil, gw)
+	w.check(now)
+	if len(gw.calls) != 0 {
+		t.Errorf("expected no alert by default, got %v", gw.calls)
+	}
+}
+
+func TestWatchdog_RestartsHungPoller(t *testing.T) {
+	entered, hung := make(chan struct{}), make(chan struct{})
+	var calls int32
+	mc := &mockGmailClient{
+		getCurrentHIDFunc: func(ctx context.Context) (uint64, error) {
+			if atomic.AddInt32(&calls, 1) == 1 {
+				// Hangs until the watchdog cancels the loop
+				close(entered)
+				<-ctx.Done()
+				close(hung)
+				return 0, ctx.Err()
+			}
+			return 1, nil
+		},
+	}
+	p := NewPollerForAccount(mc, "stuck@example.com", "1h", nil, &mockGW{}, t.TempDir(), nil)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	p.Start(ctx)
+	<-entered
+
+	w := NewWatchdog([]*Poller{p}, &config.GmailWatchdogConfig{StallIntervals: 1, Restart: true}, &mockGW{})
+	now := time.Now().Add(2 * time.Hour)
+	w.check(now)
+
+	select {
+	case <-hung:
+	case <-time.After(2 * time.Second):
+		t.Fatal("expected restart to cancel the hung call")
+	}
+	if st := p.Status(); st.Restarts != 1 {
+		t.Errorf("expected 1 restart, got %d", st.Restarts)
+	}
+	// Let the restarted loop save its state before the temp dir is removed
+	for deadline := time.Now().Add(2 * time.Second); time.Now().Before(deadline); time.Sleep(5 * time.Millisecond) {
+		if _, err := p.loadState(); err == nil {
+			break
+		}
+	}
+
+	// Still stalled on the next tick, but within the restart grace period
+	w.check(time.Now().Add(30 * time.Minute))
+	if st := p.Status(); st.Restarts != 1 {
+		t.Errorf("expected no immediate second restart, got %d", st.Restarts)
+	}
+}
+
+func TestWatchdog_IgnoresStoppedPoller(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	p := stalledPoller("gone@example.com", time.Now().Add(-time.Hour))
+	p.parent = ctx
+	w := NewWatchdog([]*Poller{p}, nil, &mockGW{})
+	if w.Statuses(time.Now())[0].Stalled {
+		t.Error("expected stopped poller not to be reported as stalled")
+	}
+}
+
+func TestPoll_TracksSuccessAndError(t *testing.T) {
+	fail := true
+	mc := &mockGmailClient{
+		getHistoryFunc: func(_ context.Context, startHID uint64) ([]HistoryMessage, uint64, error) {
+			if fail {
+				return nil, 0, errors.New("connection reset")
+			}
+			return nil, startHID, nil
+		},
+	}
+	p := &Poller{client: mc, gateway: &mockGW{}, stateDir: t.TempDir()}
+	p.setHistoryID(5)
+
+	p.poll(context.Background())
+	if st := p.Status(); st.LastError != "connection reset" || !st.LastSuccess.IsZero() {
+		t.Errorf("expected error recorded without success, got %+v", st)
+	}
+	fail = false
+	p.poll(context.Background())
+	if st := p.Status(); st.LastError != "" || st.LastSuccess.IsZero() {
+		t.Errorf("expected success recorded, got %+v", st)
+	}
+}
+
+func TestWatchdog_HandlePollers(t *testing.T) {
+	w := NewWatchdog([]*Poller{stalledPoller("a@example.com", time.Now())}, nil, &mockGW{})
+	rec := httptest.NewRecorder()
+	w.HandlePollers(rec, httptest.NewRequest("GET", "/api/gmail/pollers", nil))
+	var resp struct {
+		Pollers []PollerStatus `json:"pollers"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil || len(resp.Pollers) != 1 || resp.Pollers[0].Account != "a@example.com" {
+		t.Errorf("unexpected response %q (%v)", rec.Body.String(), err)
+	}
+
+	rec = httptest.NewRecorder()
+	w.HandlePollers(rec, httptest.NewRequest("POST", "/api/gmail/pollers", strings.NewReader("")))
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("expected 405, got %d", rec.Code)
+	}
+}
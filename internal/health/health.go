@@ -0,0 +1,81 @@
+// Package health serves /health with the features the relay runs without and
+// why, e.g. Gmail without RELAY_ENCRYPTION_KEY, so a missing key or client ID
+// shows up there instead of as 404s on the routes it would have added.
+package health
+
+import (
+	"encoding/json"
+	"fmt"
+	"html"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// Feature is a subsystem that is configured but not running.
+type Feature struct {
+	Name   string `json:"feature"` // e.g. "gmail", "calendar", "sealed_secrets"
+	Reason string `json:"reason"`
+}
+
+// Report collects the degraded features while the server starts.
+type Report struct {
+	mu       sync.Mutex
+	degraded []Feature
+}
+
+// Degrade records that feature is disabled for reason and logs it.
+func (r *Report) Degrade(feature, reason string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.degraded = append(r.degraded, Feature{Name: feature, Reason: reason})
+	log.Printf("Degraded: %s disabled: %s", feature, reason)
+}
+
+// Degraded returns the degraded features in the order they were recorded.
+func (r *Report) Degraded() []Feature {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]Feature(nil), r.degraded...)
+}
+
+// Err returns an error listing the degraded features, or nil when there are
+// none; server.strict refuses to start on it.
+func (r *Report) Err() error {
+	degraded := r.Degraded()
+	if len(degraded) == 0 {
+		return nil
+	}
+	parts := make([]string, len(degraded))
+	for i, f := range degraded {
+		parts[i] = f.Name + " (" + f.Reason + ")"
+	}
+	return fmt.Errorf("%d feature(s) degraded: %s", len(degraded), strings.Join(parts, "; "))
+}
+
+// HandleHealth serves GET /health: status "ok" while the relay is serving,
+// and the degraded features when there are any.
+func (r *Report) HandleHealth(w http.ResponseWriter, req *http.Request) {
+	resp := map[string]any{"status": "ok"}
+	if degraded := r.Degraded(); len(degraded) > 0 {
+		resp["degraded"] = degraded
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// WriteHTML writes the degraded features as an HTML list, or nothing when
+// there are none.
+func (r *Report) WriteHTML(w io.Writer) {
+	degraded := r.Degraded()
+	if len(degraded) == 0 {
+		return
+	}
+	fmt.Fprint(w, `<h3>Degraded features</h3><ul>`)
+	for _, f := range degraded {
+		fmt.Fprintf(w, `<li><b>%s</b>: %s</li>`, html.EscapeString(f.Name), html.EscapeString(f.Reason))
+	}
+	fmt.Fprint(w, `</ul>`)
+}
@@ -0,0 +1,49 @@
+package health
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestReport(t *testing.T) {
+	var r Report
+	if err := r.Err(); err != nil {
+		t.Errorf("empty report: %v", err)
+	}
+	rec := httptest.NewRecorder()
+	r.HandleHealth(rec, httptest.NewRequest("GET", "/health", nil))
+	if got := strings.TrimSpace(rec.Body.String()); got != `{"status":"ok"}` {
+		t.Errorf("healthy body = %s", got)
+	}
+	var page strings.Builder
+	r.WriteHTML(&page)
+	if page.Len() != 0 {
+		t.Errorf("healthy page = %q", page.String())
+	}
+
+	r.Degrade("gmail", "RELAY_ENCRYPTION_KEY is not set")
+	r.Degrade("calendar", "Google OAuth is <not> configured")
+
+	rec = httptest.NewRecorder()
+	r.HandleHealth(rec, httptest.NewRequest("GET", "/health", nil))
+	var resp struct {
+		Status   string    `json:"status"`
+		Degraded []Feature `json:"degraded"`
+	}
+	json.NewDecoder(rec.Body).Decode(&resp)
+	if resp.Status != "ok" || len(resp.Degraded) != 2 || resp.Degraded[0].Name != "gmail" || resp.Degraded[1].Reason != "Google OAuth is <not> configured" {
+		t.Errorf("health = %+v", resp)
+	}
+
+	err := r.Err()
+	if err == nil || err.Error() != "2 feature(s) degraded: gmail (RELAY_ENCRYPTION_KEY is not set); calendar (Google OAuth is <not> configured)" {
+		t.Errorf("err = %v", err)
+	}
+
+	r.WriteHTML(&page)
+	if !strings.Contains(page.String(), "<b>gmail</b>: RELAY_ENCRYPTION_KEY is not set") || !strings.Contains(page.String(), "&lt;not&gt;") {
+		t.Errorf("page = %s", page.String())
+	}
+}
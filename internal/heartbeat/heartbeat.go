@@ -0,0 +1,169 @@
+// Package heartbeat sends a periodic job to the gateway confirming the relay
+// is alive and summarizing its pending work, so the agent can notice and
+// report when the relay goes quiet.
+package heartbeat
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/katalabut/openclaw-relay/internal/config"
+	"github.com/katalabut/openclaw-relay/internal/gateway"
+)
+
+// DefaultTemplate is the heartbeat job message when message_template is unset.
+const DefaultTemplate = "[Relay heartbeat] openclaw-relay is alive, up {{.Uptime}}. " +
+	"Since the last heartbeat: {{.Jobs}} jobs created, {{.FailedJobs}} failed. Pending: {{.Pending}}. " +
+	"The next heartbeat is due in {{.Interval}}; if none arrives by then, report that the relay has gone quiet."
+
+// DefaultNotifyTemplate is the message sent to channel and target when
+// message_template is unset.
+const DefaultNotifyTemplate = "✅ openclaw-relay is alive, up {{.Uptime}}: {{.Jobs}} jobs since the last heartbeat ({{.FailedJobs}} failed). Pending: {{.Pending}}."
+
+// Heartbeat counts the jobs created through Gateway and reports them, with
+// the pending counts, every interval.
+type Heartbeat struct {
+	gateway gateway.GatewayClient
+	cfg     config.GatewayHeartbeatConfig
+	started time.Time
+
+	mu      sync.Mutex
+	pending []pending
+	jobs    int
+	failed  int
+}
+
+type pending struct {
+	name  string
+	count func() int
+}
+
+// New returns a heartbeat sending through gw.
+func New(gw gateway.GatewayClient, cfg config.GatewayHeartbeatConfig) *Heartbeat {
+	return &Heartbeat{gateway: gw, cfg: cfg, started: time.Now()}
+}
+
+// AddPending adds a count of pending work to the summary, e.g. dead letters.
+func (h *Heartbeat) AddPending(name string, count func() int) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.pending = append(h.pending, pending{name, count})
+}
+
+// Start sends a heartbeat every interval until ctx is done.
+func (h *Heartbeat) Start(ctx context.Context) {
+	every := h.cfg.ResolvedInterval()
+	if every <= 0 {
+		return
+	}
+	log.Printf("Heartbeat: every %s", every)
+	go func() {
+		ticker := time.NewTicker(every)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case now := <-ticker.C:
+				if err := h.Send(now); err != nil {
+					log.Printf("Heartbeat: %v", err)
+				}
+			}
+		}
+	}()
+}
+
+// Send creates one heartbeat job and starts counting jobs afresh.
+func (h *Heartbeat) Send(now time.Time) error {
+	h.mu.Lock()
+	jobs, failed := h.jobs, h.failed
+	h.jobs, h.failed = 0, 0
+	counts := make(map[string]int, len(h.pending))
+	var parts []string
+	for _, p := range h.pending {
+		n := p.count()
+		counts[p.name] = n
+		if n > 0 {
+			parts = append(parts, fmt.Sprintf("%s: %d", p.name, n))
+		}
+	}
+	h.mu.Unlock()
+
+	summary := "nothing"
+	if len(parts) > 0 {
+		summary = strings.Join(parts, ", ")
+	}
+	tmplStr := h.cfg.MessageTemplate
+	if tmplStr == "" {
+		tmplStr = DefaultTemplate
+		if h.cfg.Channel != "" {
+			tmplStr = DefaultNotifyTemplate
+		}
+	}
+	tmpl, err := template.New("heartbeat").Option("missingkey=zero").Parse(tmplStr)
+	if err != nil {
+		return fmt.Errorf("template: %w", err)
+	}
+	var buf bytes.Buffer
+	err = tmpl.Execute(&buf, map[string]any{
+		"Uptime":     now.Sub(h.started).Round(time.Minute).String(),
+		"Interval":   h.cfg.ResolvedInterval().String(),
+		"Jobs":       jobs,
+		"FailedJobs": failed,
+		"Pending":    summary,
+		"Counts":     counts,
+		"Time":       now.UTC().Format(time.RFC3339),
+	})
+	if err != nil {
+		return fmt.Errorf("template: %w", err)
+	}
+	message := buf.String()
+	if h.cfg.Channel != "" {
+		message = fmt.Sprintf("Send this exact message to %s (target=%s). Just send it, no extra text:\n\n%s",
+			h.cfg.Channel, h.cfg.Target, message)
+	}
+	spec := gateway.JobSpec{Name: "relay heartbeat", Message: message, AgentID: h.cfg.AgentID, TimeoutSeconds: h.cfg.ResolvedTimeout()}
+	if err := gateway.CreateJob(h.gateway, spec); err != nil {
+		return fmt.Errorf("create job: %w", err)
+	}
+	return nil
+}
+
+// Gateway wraps next so the jobs created through it are counted. Jobs
+// without a source are the relay's own notices and are not counted.
+func (h *Heartbeat) Gateway(next gateway.GatewayClient) gateway.GatewayClient {
+	return &countingGateway{hb: h, next: next}
+}
+
+type countingGateway struct {
+	hb   *Heartbeat
+	next gateway.GatewayClient
+}
+
+func (g *countingGateway) CreateOneShotJob(name, message string, timeoutSeconds, delaySeconds int) error {
+	return g.CreateOneShotJobForAgent(name, message, "", timeoutSeconds, delaySeconds)
+}
+
+func (g *countingGateway) CreateOneShotJobForAgent(name, message, agentID string, timeoutSeconds, delaySeconds int) error {
+	return g.CreateJob(gateway.JobSpec{Name: name, Message: message, AgentID: agentID, TimeoutSeconds: timeoutSeconds, DelaySeconds: delaySeconds})
+}
+
+func (g *countingGateway) CreateJob(spec gateway.JobSpec) error {
+	err := gateway.CreateJob(g.next, spec)
+	if spec.Source == "" {
+		return err
+	}
+	g.hb.mu.Lock()
+	g.hb.jobs++
+	if err != nil {
+		g.hb.failed++
+	}
+	g.hb.mu.Unlock()
+	return err
+}
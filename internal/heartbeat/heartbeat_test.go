@@ -0,0 +1,81 @@
+package heartbeat
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/katalabut/openclaw-relay/internal/config"
+	"github.com/katalabut/openclaw-relay/internal/gateway"
+)
+
+type failingGateway struct{ gateway.GatewayClient }
+
+func (failingGateway) CreateJob(spec gateway.JobSpec) error { return errors.New("gateway down") }
+
+func TestHeartbeat_Send(t *testing.T) {
+	sink := gateway.NewSink()
+	h := New(sink, config.GatewayHeartbeatConfig{Interval: "6h", AgentID: "ops"})
+	h.started = time.Date(2026, 10, 15, 6, 0, 0, 0, time.UTC)
+	deadLetters := 2
+	h.AddPending("dead letters", func() int { return deadLetters })
+	h.AddPending("stalled Gmail pollers", func() int { return 0 })
+
+	gw := h.Gateway(sink)
+	gateway.CreateJob(gw, gateway.JobSpec{Name: "github ci", Source: "github"})
+	gateway.CreateJob(gw, gateway.JobSpec{Name: "trello card", Source: "trello"})
+	gateway.CreateJob(gw, gateway.JobSpec{Name: "budget/github"}) // the relay's own notice
+	gateway.CreateJob(h.Gateway(failingGateway{}), gateway.JobSpec{Name: "x", Source: "gmail"})
+
+	now := h.started.Add(26*time.Hour + 20*time.Second)
+	if err := h.Send(now); err != nil {
+		t.Fatal(err)
+	}
+	job := sink.Last()
+	if job.Name != "relay heartbeat" || job.AgentID != "ops" || job.TimeoutSeconds != 60 {
+		t.Errorf("unexpected job %+v", job)
+	}
+	for _, want := range []string{"up 26h0m0s", "3 jobs created, 1 failed", "Pending: dead letters: 2.", "due in 6h0m0s"} {
+		if !strings.Contains(job.Message, want) {
+			t.Errorf("message %q lacks %q", job.Message, want)
+		}
+	}
+
+	// Counts start afresh after each heartbeat
+	deadLetters = 0
+	h.Send(now.Add(6 * time.Hour))
+	if msg := sink.Last().Message; !strings.Contains(msg, "0 jobs created, 0 failed") || !strings.Contains(msg, "Pending: nothing.") {
+		t.Errorf("unexpected second heartbeat %q", msg)
+	}
+}
+
+func TestHeartbeat_Notify(t *testing.T) {
+	sink := gateway.NewSink()
+	h := New(sink, config.GatewayHeartbeatConfig{Interval: "1h", Channel: "telegram", Target: "${TELEGRAM_CHAT_ID}"})
+	if err := h.Send(time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	msg := sink.Last().Message
+	if !strings.HasPrefix(msg, "Send this exact message to telegram (target=${TELEGRAM_CHAT_ID})") || !strings.Contains(msg, "✅ openclaw-relay is alive") {
+		t.Errorf("unexpected notify message %q", msg)
+	}
+
+	h.cfg.MessageTemplate = "alive, {{.Counts}} {{.Pending}}"
+	h.AddPending("dead letters", func() int { return 1 })
+	h.Send(time.Now())
+	if msg := sink.Last().Message; !strings.HasSuffix(msg, "\n\nalive, map[dead letters:1] dead letters: 1") {
+		t.Errorf("unexpected custom message %q", msg)
+	}
+}
+
+func TestHeartbeat_Errors(t *testing.T) {
+	h := New(failingGateway{}, config.GatewayHeartbeatConfig{Interval: "1h"})
+	if err := h.Send(time.Now()); err == nil || !strings.Contains(err.Error(), "gateway down") {
+		t.Errorf("expected the gateway error, got %v", err)
+	}
+	h = New(gateway.NewSink(), config.GatewayHeartbeatConfig{Interval: "1h", MessageTemplate: "{{.Missing.Field}}"})
+	if err := h.Send(time.Now()); err == nil || !strings.Contains(err.Error(), "template") {
+		t.Errorf("expected a template error, got %v", err)
+	}
+}
@@ -0,0 +1,625 @@
+// Package imap reads a mailbox over IMAP and sends mail over SMTP, for
+// gmail.accounts entries with provider: imap. Client implements
+// gmail.GmailClient, so IMAP accounts share the pollers, rules, templates
+// and /api/gmail/* routes of Gmail accounts.
+//
+// Message IDs are IMAP UIDs in the polled mailbox, and the history ID the
+// poller keeps is the mailbox's UIDVALIDITY and next UID in one number.
+package imap
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/katalabut/openclaw-relay/internal/config"
+	"github.com/katalabut/openclaw-relay/internal/gmail"
+)
+
+const (
+	// maxSessions caps one account's open IMAP sessions; servers limit
+	// connections per user.
+	maxSessions = 4
+	// sessionTimeout bounds a session that has no context deadline.
+	sessionTimeout = 2 * time.Minute
+	// defaultListSize is ListMessages' page size when none is given.
+	defaultListSize = 100
+)
+
+var _ gmail.GmailClient = (*Client)(nil)
+
+// Client is an IMAP account. Each call opens its own session.
+type Client struct {
+	account     string
+	cfg         config.MailIMAPConfig
+	maxMessages int
+	sem         chan struct{}
+	now         func() time.Time
+
+	// dial and dialSMTP connect to the servers; tests replace them
+	dial     func(ctx context.Context) (net.Conn, error)
+	dialSMTP func(ctx context.Context) (*smtp.Client, error)
+}
+
+// NewClient returns the client for an account with provider: imap.
+func NewClient(account string, cfg config.MailIMAPConfig) *Client {
+	c := &Client{
+		account: account,
+		cfg:     cfg.Resolved(account),
+		sem:     make(chan struct{}, maxSessions),
+		now:     time.Now,
+	}
+	c.dial = c.dialTLS
+	c.dialSMTP = c.dialSMTPServer
+	return c
+}
+
+// SetHistoryLimits caps how many new messages one GetHistory call returns;
+// the rest come on the next poll. Zero means unlimited.
+func (c *Client) SetHistoryLimits(maxMessages int) {
+	c.maxMessages = maxMessages
+}
+
+func (c *Client) dialTLS(ctx context.Context) (net.Conn, error) {
+	d := &tls.Dialer{Config: &tls.Config{ServerName: c.cfg.Host}}
+	return d.DialContext(ctx, "tcp", net.JoinHostPort(c.cfg.Host, strconv.Itoa(c.cfg.Port)))
+}
+
+// session runs fn in a logged-in session.
+func (c *Client) session(ctx context.Context, fn func(*conn) error) error {
+	select {
+	case c.sem <- struct{}{}:
+		defer func() { <-c.sem }()
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	nc, err := c.dial(ctx)
+	if err != nil {
+		return fmt.Errorf("imap: connect to %s: %w", c.cfg.Host, err)
+	}
+	cn := newConn(nc)
+	defer cn.close()
+	stop := context.AfterFunc(ctx, func() { nc.Close() })
+	defer stop()
+	deadline := time.Now().Add(sessionTimeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	cn.setDeadline(deadline)
+
+	if err := cn.greeting(); err != nil {
+		return err
+	}
+	if _, err := cn.cmd("LOGIN", astring(c.cfg.Username), astring(c.cfg.Password)); err != nil {
+		return err
+	}
+	err = fn(cn)
+	cn.cmd("LOGOUT")
+	return err
+}
+
+var respCode = regexp.MustCompile(`(?i)\[(UIDVALIDITY|UIDNEXT) (\d+)\]`)
+
+// open selects the polled mailbox, read-only with EXAMINE, and returns its
+// UIDVALIDITY and next UID.
+func (c *Client) open(cn *conn, readOnly bool) (validity, next uint32, err error) {
+	command := "SELECT"
+	if readOnly {
+		command = "EXAMINE"
+	}
+	resps, err := cn.cmd(command, astring(encodeMailbox(c.cfg.Mailbox)))
+	if err != nil {
+		return 0, 0, err
+	}
+	for _, r := range resps {
+		for _, m := range respCode.FindAllStringSubmatch(r.text, -1) {
+			n, _ := strconv.ParseUint(m[2], 10, 32)
+			if strings.EqualFold(m[1], "UIDVALIDITY") {
+				validity = uint32(n)
+			} else {
+				next = uint32(n)
+			}
+		}
+	}
+	if next == 0 {
+		// UIDNEXT is optional before IMAP4rev2
+		uids, err := search(cn, []any{"ALL"})
+		if err != nil {
+			return 0, 0, err
+		}
+		next = 1
+		if len(uids) > 0 {
+			next = slices.Max(uids) + 1
+		}
+	}
+	return validity, next, nil
+}
+
+func historyID(validity, uid uint32) uint64 {
+	return uint64(validity)<<32 | uint64(uid)
+}
+
+// search returns the UIDs of the messages matching criteria.
+func search(cn *conn, criteria []any) ([]uint32, error) {
+	args := []any{"UID SEARCH"}
+	if needsUTF8(criteria) {
+		args = append(args, "CHARSET", "UTF-8")
+	}
+	resps, err := cn.cmd(append(args, criteria...)...)
+	if err != nil {
+		return nil, err
+	}
+	var uids []uint32
+	for _, r := range resps {
+		if len(r.fields) == 0 || !strings.EqualFold(fmt.Sprint(r.fields[0]), "SEARCH") {
+			continue
+		}
+		for _, f := range r.fields[1:] {
+			if s, ok := f.(string); ok {
+				if n, err := strconv.ParseUint(s, 10, 32); err == nil {
+					uids = append(uids, uint32(n))
+				}
+			}
+		}
+	}
+	return uids, nil
+}
+
+// fetched is a message's FETCH response.
+type fetched struct {
+	uid   uint32
+	flags []string
+	body  string // the first BODY[...] section
+}
+
+// fetch fetches items, e.g. "(UID FLAGS BODY.PEEK[])", of the messages
+// uids, by UID.
+func fetch(cn *conn, uids []uint32, items string) (map[uint32]fetched, error) {
+	out := make(map[uint32]fetched, len(uids))
+	if len(uids) == 0 {
+		return out, nil
+	}
+	set := make([]string, len(uids))
+	for i, u := range uids {
+		set[i] = strconv.FormatUint(uint64(u), 10)
+	}
+	resps, err := cn.cmd("UID FETCH", strings.Join(set, ","), items)
+	if err != nil {
+		return nil, err
+	}
+	for _, r := range resps {
+		if len(r.fields) < 3 || !strings.EqualFold(fmt.Sprint(r.fields[1]), "FETCH") {
+			continue
+		}
+		attrs, _ := r.fields[2].([]any)
+		var f fetched
+		for i := 0; i+1 < len(attrs); i += 2 {
+			key := strings.ToUpper(fmt.Sprint(attrs[i]))
+			switch {
+			case key == "UID":
+				n, _ := strconv.ParseUint(fmt.Sprint(attrs[i+1]), 10, 32)
+				f.uid = uint32(n)
+			case key == "FLAGS":
+				list, _ := attrs[i+1].([]any)
+				for _, fl := range list {
+					f.flags = append(f.flags, fmt.Sprint(fl))
+				}
+			case strings.HasPrefix(key, "BODY["):
+				if s, ok := attrs[i+1].(string); ok && f.body == "" {
+					f.body = s
+				}
+			}
+		}
+		if f.uid != 0 {
+			out[f.uid] = f
+		}
+	}
+	return out, nil
+}
+
+func parseUID(id string) (uint32, error) {
+	n, err := strconv.ParseUint(id, 10, 32)
+	if err != nil || n == 0 {
+		return 0, fmt.Errorf("imap: bad message ID %q", id)
+	}
+	return uint32(n), nil
+}
+
+func uidString(uid uint32) string {
+	return strconv.FormatUint(uint64(uid), 10)
+}
+
+// GetCurrentHistoryID returns the mailbox's UIDVALIDITY and next UID.
+func (c *Client) GetCurrentHistoryID(ctx context.Context) (uint64, error) {
+	var hid uint64
+	err := c.session(ctx, func(cn *conn) error {
+		validity, next, err := c.open(cn, true)
+		hid = historyID(validity, next)
+		return err
+	})
+	return hid, err
+}
+
+// GetHistory returns the messages that arrived in the mailbox since
+// startHistoryID, all as message_added. Label changes and deletions are not
+// seen over IMAP.
+func (c *Client) GetHistory(ctx context.Context, startHistoryID uint64) ([]gmail.HistoryMessage, uint64, error) {
+	startValidity, from := uint32(startHistoryID>>32), uint32(startHistoryID)
+	var msgs []gmail.HistoryMessage
+	newHID := startHistoryID
+	err := c.session(ctx, func(cn *conn) error {
+		validity, next, err := c.open(cn, true)
+		if err != nil {
+			return err
+		}
+		if validity != startValidity {
+			return fmt.Errorf("imap: UIDVALIDITY of %s changed: %w", c.cfg.Mailbox, gmail.ErrHistoryExpired)
+		}
+		if from >= next {
+			return nil
+		}
+		found, err := search(cn, []any{"UID", fmt.Sprintf("%d:*", from)})
+		if err != nil {
+			return err
+		}
+		// n:* also matches the highest UID when it is below n
+		uids := slices.DeleteFunc(found, func(u uint32) bool { return u < from })
+		slices.Sort(uids)
+		uids = slices.Compact(uids)
+		if c.maxMessages > 0 && len(uids) > c.maxMessages {
+			uids = uids[:c.maxMessages]
+			next = uids[len(uids)-1] + 1
+		} else if len(uids) > 0 {
+			next = max(next, uids[len(uids)-1]+1)
+		}
+		bodies, err := fetch(cn, uids, "(UID FLAGS BODY.PEEK[])")
+		if err != nil {
+			return err
+		}
+		for _, uid := range uids {
+			f, ok := bodies[uid]
+			if !ok {
+				continue // expunged since the search
+			}
+			msg, err := historyMessage(uidString(uid), c.cfg.Mailbox, f.flags, []byte(f.body))
+			if err != nil {
+				log.Printf("IMAP %s: skipping message %d: %v", c.account, uid, err)
+				continue
+			}
+			msgs = append(msgs, msg)
+		}
+		newHID = historyID(validity, next)
+		return nil
+	})
+	return msgs, newHID, err
+}
+
+// ListMessages lists the messages matching a Gmail search query, newest
+// first; see searchCriteria for the operators IMAP supports. Entries have
+// no snippet. The page token is the offset of the page.
+func (c *Client) ListMessages(ctx context.Context, query string, maxResults int64, pageToken string) ([]gmail.MessageMeta, string, error) {
+	criteria, err := searchCriteria(query, c.now())
+	if err != nil {
+		return nil, "", err
+	}
+	offset := 0
+	if pageToken != "" {
+		if offset, err = strconv.Atoi(pageToken); err != nil || offset < 0 {
+			return nil, "", fmt.Errorf("imap: bad page token %q", pageToken)
+		}
+	}
+	if maxResults <= 0 {
+		maxResults = defaultListSize
+	}
+	var metas []gmail.MessageMeta
+	var nextToken string
+	err = c.session(ctx, func(cn *conn) error {
+		if _, _, err := c.open(cn, true); err != nil {
+			return err
+		}
+		uids, err := search(cn, criteria)
+		if err != nil {
+			return err
+		}
+		slices.Sort(uids)
+		slices.Reverse(uids)
+		if offset >= len(uids) {
+			return nil
+		}
+		end := min(offset+int(maxResults), len(uids))
+		if end < len(uids) {
+			nextToken = strconv.Itoa(end)
+		}
+		page := uids[offset:end]
+		headers, err := fetch(cn, page, "(UID FLAGS BODY.PEEK[HEADER])")
+		if err != nil {
+			return err
+		}
+		for _, uid := range page {
+			f, ok := headers[uid]
+			if !ok {
+				continue
+			}
+			m, err := messageMeta(uidString(uid), c.cfg.Mailbox, f.flags, []byte(f.body))
+			if err != nil {
+				log.Printf("IMAP %s: skipping message %d: %v", c.account, uid, err)
+				continue
+			}
+			metas = append(metas, m)
+		}
+		return nil
+	})
+	return metas, nextToken, err
+}
+
+// ListThreads is not supported: IMAP has no thread IDs.
+func (c *Client) ListThreads(ctx context.Context, query string, maxResults int64, pageToken string) ([]gmail.ThreadMeta, string, error) {
+	return nil, "", fmt.Errorf("imap: threads: %w", errors.ErrUnsupported)
+}
+
+// fetchRaw returns a message's flags and raw content.
+func (c *Client) fetchRaw(ctx context.Context, id string) ([]string, []byte, error) {
+	uid, err := parseUID(id)
+	if err != nil {
+		return nil, nil, err
+	}
+	var f fetched
+	err = c.session(ctx, func(cn *conn) error {
+		if _, _, err := c.open(cn, true); err != nil {
+			return err
+		}
+		bodies, err := fetch(cn, []uint32{uid}, "(UID FLAGS BODY.PEEK[])")
+		if err != nil {
+			return err
+		}
+		var ok bool
+		if f, ok = bodies[uid]; !ok {
+			return fmt.Errorf("imap: message %s not found", id)
+		}
+		return nil
+	})
+	return f.flags, []byte(f.body), err
+}
+
+// GetMessage returns a message; attachment IDs are IMAP section numbers.
+func (c *Client) GetMessage(ctx context.Context, id string) (*gmail.MessageFull, error) {
+	flags, raw, err := c.fetchRaw(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return fullMessage(id, c.cfg.Mailbox, flags, raw)
+}
+
+// GetThread returns the message threadID alone: each message is its own
+// thread.
+func (c *Client) GetThread(ctx context.Context, threadID string) ([]gmail.MessageFull, error) {
+	msg, err := c.GetMessage(ctx, threadID)
+	if err != nil {
+		return nil, err
+	}
+	return []gmail.MessageFull{*msg}, nil
+}
+
+// GetAttachment returns the decoded content of an attachment.
+func (c *Client) GetAttachment(ctx context.Context, messageID, attachmentID string) ([]byte, error) {
+	_, raw, err := c.fetchRaw(ctx, messageID)
+	if err != nil {
+		return nil, err
+	}
+	_, parts, err := parseMessage(raw)
+	if err != nil {
+		return nil, err
+	}
+	for _, p := range parts {
+		if p.path == attachmentID && p.filename != "" {
+			return p.data, nil
+		}
+	}
+	return nil, fmt.Errorf("imap: attachment %s of message %s not found", attachmentID, messageID)
+}
+
+// ModifyMessage maps label changes to IMAP: UNREAD and STARRED are the
+// \Seen and \Flagged flags, removing the polled mailbox's label (archive)
+// moves the message to the archive mailbox, and adding another label copies
+// it to the mailbox of that name. Other changes are not supported.
+func (c *Client) ModifyMessage(ctx context.Context, id string, req gmail.ModifyRequest) error {
+	uid, err := parseUID(id)
+	if err != nil {
+		return err
+	}
+	own := mailboxLabel(c.cfg.Mailbox)
+	add, remove := req.Labels()
+	var plus, minus, copies []string
+	archive := false
+	for _, l := range add {
+		switch l {
+		case labelUnread:
+			minus = append(minus, `\Seen`)
+		case labelStarred:
+			plus = append(plus, `\Flagged`)
+		case own:
+		case labelInbox:
+			return fmt.Errorf("imap: adding INBOX: %w", errors.ErrUnsupported)
+		default:
+			copies = append(copies, l)
+		}
+	}
+	for _, l := range remove {
+		switch l {
+		case labelUnread:
+			plus = append(plus, `\Seen`)
+		case labelStarred:
+			minus = append(minus, `\Flagged`)
+		case own:
+			archive = true
+		default:
+			return fmt.Errorf("imap: removing label %s: %w", l, errors.ErrUnsupported)
+		}
+	}
+
+	set := uidString(uid)
+	return c.session(ctx, func(cn *conn) error {
+		if _, _, err := c.open(cn, false); err != nil {
+			return err
+		}
+		if len(plus) > 0 {
+			if _, err := cn.cmd("UID STORE", set, "+FLAGS.SILENT", "("+strings.Join(plus, " ")+")"); err != nil {
+				return err
+			}
+		}
+		if len(minus) > 0 {
+			if _, err := cn.cmd("UID STORE", set, "-FLAGS.SILENT", "("+strings.Join(minus, " ")+")"); err != nil {
+				return err
+			}
+		}
+		for _, mailbox := range copies {
+			if _, err := cn.cmd("UID COPY", set, astring(encodeMailbox(mailbox))); err != nil {
+				return err
+			}
+		}
+		if archive {
+			return c.move(cn, set, c.cfg.ArchiveMailbox)
+		}
+		return nil
+	})
+}
+
+// move moves messages to mailbox with MOVE (RFC 6851), else with COPY and
+// UID EXPUNGE (RFC 4315), which only expunges these messages.
+func (c *Client) move(cn *conn, set, mailbox string) error {
+	caps, err := capabilities(cn)
+	if err != nil {
+		return err
+	}
+	dest := astring(encodeMailbox(mailbox))
+	switch {
+	case caps["MOVE"]:
+		_, err = cn.cmd("UID MOVE", set, dest)
+		return err
+	case caps["UIDPLUS"]:
+		if _, err := cn.cmd("UID COPY", set, dest); err != nil {
+			return err
+		}
+		if _, err := cn.cmd("UID STORE", set, "+FLAGS.SILENT", `(\Deleted)`); err != nil {
+			return err
+		}
+		_, err = cn.cmd("UID EXPUNGE", set)
+		return err
+	}
+	return fmt.Errorf("imap: archive needs a server with MOVE or UIDPLUS: %w", errors.ErrUnsupported)
+}
+
+func capabilities(cn *conn) (map[string]bool, error) {
+	resps, err := cn.cmd("CAPABILITY")
+	if err != nil {
+		return nil, err
+	}
+	caps := map[string]bool{}
+	for _, r := range resps {
+		if len(r.fields) > 0 && strings.EqualFold(fmt.Sprint(r.fields[0]), "CAPABILITY") {
+			for _, f := range r.fields[1:] {
+				caps[strings.ToUpper(fmt.Sprint(f))] = true
+			}
+		}
+	}
+	return caps, nil
+}
+
+// ListLabels lists the account's mailboxes, with UNREAD and STARRED.
+func (c *Client) ListLabels(ctx context.Context) ([]gmail.LabelInfo, error) {
+	labels := []gmail.LabelInfo{
+		{ID: labelUnread, Name: labelUnread, Type: "system"},
+		{ID: labelStarred, Name: labelStarred, Type: "system"},
+	}
+	err := c.session(ctx, func(cn *conn) error {
+		resps, err := cn.cmd("LIST", `""`, `"*"`)
+		if err != nil {
+			return err
+		}
+		for _, r := range resps {
+			if len(r.fields) < 4 || !strings.EqualFold(fmt.Sprint(r.fields[0]), "LIST") {
+				continue
+			}
+			attrs, _ := r.fields[1].([]any)
+			if slices.ContainsFunc(attrs, func(a any) bool { return strings.EqualFold(fmt.Sprint(a), `\Noselect`) }) {
+				continue
+			}
+			name := decodeMailbox(fmt.Sprint(r.fields[3]))
+			typ := "user"
+			if mailboxLabel(name) == labelInbox {
+				typ = "system"
+			}
+			labels = append(labels, gmail.LabelInfo{ID: mailboxLabel(name), Name: name, Type: typ})
+		}
+		return nil
+	})
+	return labels, err
+}
+
+// ListDrafts is not supported for IMAP accounts.
+func (c *Client) ListDrafts(ctx context.Context, maxResults int64) ([]gmail.Draft, error) {
+	return nil, fmt.Errorf("imap: drafts: %w", errors.ErrUnsupported)
+}
+
+// CreateDraft is not supported for IMAP accounts.
+func (c *Client) CreateDraft(ctx context.Context, req gmail.DraftRequest) (*gmail.Draft, error) {
+	return nil, fmt.Errorf("imap: drafts: %w", errors.ErrUnsupported)
+}
+
+// UpdateDraft is not supported for IMAP accounts.
+func (c *Client) UpdateDraft(ctx context.Context, id string, req gmail.DraftRequest) (*gmail.Draft, error) {
+	return nil, fmt.Errorf("imap: drafts: %w", errors.ErrUnsupported)
+}
+
+// DeleteDraft is not supported for IMAP accounts.
+func (c *Client) DeleteDraft(ctx context.Context, id string) error {
+	return fmt.Errorf("imap: drafts: %w", errors.ErrUnsupported)
+}
+
+// ForwardMessage sends message id to req.To through the account's SMTP
+// server, as a new plain-text message.
+func (c *Client) ForwardMessage(ctx context.Context, id string, req gmail.ForwardRequest) error {
+	orig, err := c.GetMessage(ctx, id)
+	if err != nil {
+		return fmt.Errorf("get forwarded message: %w", err)
+	}
+	raw, err := gmail.ForwardRaw(orig, req)
+	if err != nil {
+		return err
+	}
+	return c.sendMail(ctx, req.To, raw)
+}
+
+// historyMessage builds the poller's view of a new message.
+func historyMessage(uid, mailbox string, flags []string, raw []byte) (gmail.HistoryMessage, error) {
+	msg, err := fullMessage(uid, mailbox, flags, raw)
+	if err != nil {
+		return gmail.HistoryMessage{}, err
+	}
+	var cc string
+	if m, err := mail.ReadMessage(strings.NewReader(string(raw))); err == nil {
+		cc = decodeHeader(m.Header.Get("Cc"))
+	}
+	return gmail.HistoryMessage{
+		Event:    gmail.EventMessageAdded,
+		ID:       msg.ID,
+		ThreadID: msg.ThreadID,
+		Labels:   msg.Labels,
+		Subject:  msg.Subject,
+		From:     msg.From,
+		To:       msg.To,
+		Cc:       cc,
+		Snippet:  msg.Snippet,
+		RFC822ID: msg.RFC822ID,
+	}, nil
+}
@@ -0,0 +1,476 @@
+package imap
+
+import (
+	"bufio"
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/smtp"
+	"net/textproto"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/katalabut/openclaw-relay/internal/config"
+	"github.com/katalabut/openclaw-relay/internal/gmail"
+)
+
+type fakeMessage struct {
+	flags []string
+	raw   string
+}
+
+// fakeServer is an IMAP server with one mailbox, enough for Client.
+type fakeServer struct {
+	mu       sync.Mutex
+	validity uint32
+	next     uint32
+	msgs     map[uint32]*fakeMessage
+	caps     string
+	commands []string // received, without tags and literals
+	moved    map[string][]uint32
+}
+
+func newFakeServer() *fakeServer {
+	return &fakeServer{validity: 1, next: 1, msgs: map[uint32]*fakeMessage{}, caps: "IMAP4rev1 MOVE", moved: map[string][]uint32{}}
+}
+
+func (s *fakeServer) add(raw string, flags ...string) uint32 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	uid := s.next
+	s.next++
+	s.msgs[uid] = &fakeMessage{flags: flags, raw: raw}
+	return uid
+}
+
+func (s *fakeServer) sent() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return slices.Clone(s.commands)
+}
+
+func (s *fakeServer) dial(ctx context.Context) (net.Conn, error) {
+	client, server := net.Pipe()
+	go s.serve(server)
+	return client, nil
+}
+
+func (s *fakeServer) serve(nc net.Conn) {
+	defer nc.Close()
+	r := bufio.NewReader(nc)
+	w := bufio.NewWriter(nc)
+	fmt.Fprint(w, "* OK fake ready\r\n")
+	w.Flush()
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		line = strings.TrimRight(line, "\r\n")
+		// Read synchronizing literals into the line
+		for strings.HasSuffix(line, "}") {
+			i := strings.LastIndexByte(line, '{')
+			n, _ := strconv.Atoi(line[i+1 : len(line)-1])
+			fmt.Fprint(w, "+ go on\r\n")
+			w.Flush()
+			buf := make([]byte, n)
+			io.ReadFull(r, buf)
+			rest, _ := r.ReadString('\n')
+			line = line[:i] + `"` + string(buf) + `"` + strings.TrimRight(rest, "\r\n")
+		}
+		tag, command, _ := strings.Cut(line, " ")
+		s.mu.Lock()
+		s.commands = append(s.commands, command)
+		status := s.handle(w, command)
+		s.mu.Unlock()
+		fmt.Fprintf(w, "%s %s\r\n", tag, status)
+		w.Flush()
+		if strings.HasPrefix(command, "LOGOUT") {
+			return
+		}
+	}
+}
+
+func (s *fakeServer) handle(w io.Writer, command string) string {
+	fields := strings.Fields(command)
+	verb := strings.ToUpper(fields[0])
+	if verb == "UID" {
+		verb += " " + strings.ToUpper(fields[1])
+		fields = fields[1:]
+	}
+	switch verb {
+	case "LOGIN":
+		if fields[2] != `"secret"` {
+			return "NO [AUTHENTICATIONFAILED] invalid credentials"
+		}
+	case "SELECT", "EXAMINE":
+		fmt.Fprintf(w, "* %d EXISTS\r\n* OK [UIDVALIDITY %d] ok\r\n* OK [UIDNEXT %d] ok\r\n", len(s.msgs), s.validity, s.next)
+	case "CAPABILITY":
+		fmt.Fprintf(w, "* CAPABILITY %s\r\n", s.caps)
+	case "LIST":
+		fmt.Fprint(w, "* LIST (\\HasNoChildren) \"/\" INBOX\r\n* LIST (\\Noselect) \"/\" \"[Gmail]\"\r\n* LIST () \"/\" \"&BBQEPgQ6BEMEPAQ1BD0EQgRL-\"\r\n")
+	case "UID SEARCH":
+		var uids []string
+		for _, uid := range s.uids() {
+			if len(fields) == 3 && strings.HasSuffix(fields[2], ":*") {
+				from, _ := strconv.Atoi(strings.TrimSuffix(fields[2], ":*"))
+				if uid < uint32(from) && uid != s.next-1 {
+					continue
+				}
+			}
+			if slices.Contains(fields, "UNSEEN") && slices.Contains(s.msgs[uid].flags, `\Seen`) {
+				continue
+			}
+			uids = append(uids, strconv.Itoa(int(uid)))
+		}
+		fmt.Fprintf(w, "* SEARCH %s\r\n", strings.Join(uids, " "))
+	case "UID FETCH":
+		for i, uid := range s.uids() {
+			if !slices.Contains(strings.Split(fields[1], ","), strconv.Itoa(int(uid))) {
+				continue
+			}
+			m := s.msgs[uid]
+			body, section := m.raw, "BODY[]"
+			if strings.Contains(command, "BODY.PEEK[HEADER]") {
+				h, _, _ := strings.Cut(m.raw, "\r\n\r\n")
+				body, section = h+"\r\n\r\n", "BODY[HEADER]"
+			}
+			fmt.Fprintf(w, "* %d FETCH (UID %d FLAGS (%s) %s {%d}\r\n%s)\r\n", i+1, uid, strings.Join(m.flags, " "), section, len(body), body)
+		}
+	case "UID STORE":
+		uid, _ := strconv.Atoi(fields[1])
+		m := s.msgs[uint32(uid)]
+		if m == nil {
+			return "NO no such message"
+		}
+		flags := strings.Fields(strings.Trim(strings.Join(fields[3:], " "), "()"))
+		if strings.HasPrefix(fields[2], "+") {
+			for _, f := range flags {
+				if !slices.Contains(m.flags, f) {
+					m.flags = append(m.flags, f)
+				}
+			}
+		} else {
+			m.flags = slices.DeleteFunc(m.flags, func(f string) bool { return slices.Contains(flags, f) })
+		}
+	case "UID COPY", "UID MOVE":
+		uid, _ := strconv.Atoi(fields[1])
+		mailbox := strings.Trim(fields[2], `"`)
+		s.moved[mailbox] = append(s.moved[mailbox], uint32(uid))
+		if verb == "UID MOVE" {
+			delete(s.msgs, uint32(uid))
+		}
+	case "UID EXPUNGE":
+		for uid, m := range s.msgs {
+			if slices.Contains(m.flags, `\Deleted`) {
+				delete(s.msgs, uid)
+			}
+		}
+	case "LOGOUT":
+		fmt.Fprint(w, "* BYE logging out\r\n")
+	default:
+		return "BAD unknown command"
+	}
+	return "OK done"
+}
+
+func (s *fakeServer) uids() []uint32 {
+	var uids []uint32
+	for uid := range s.msgs {
+		uids = append(uids, uid)
+	}
+	slices.Sort(uids)
+	return uids
+}
+
+func newTestClient(s *fakeServer) *Client {
+	c := NewClient("me@example.com", config.MailIMAPConfig{Host: "imap.example.com", Password: "secret"})
+	c.dial = s.dial
+	c.now = func() time.Time { return time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC) }
+	return c
+}
+
+func testMessage(subject string) string {
+	return "From: Billing <billing@vendor.example>\r\nTo: me@example.com\r\nSubject: " + subject +
+		"\r\nMessage-ID: <" + strings.ReplaceAll(subject, " ", ".") + "@vendor.example>\r\n\r\nTotal: 420 EUR\r\n"
+}
+
+func TestClient_History(t *testing.T) {
+	s := newFakeServer()
+	c := newTestClient(s)
+	ctx := context.Background()
+	s.add(testMessage("old"), `\Seen`)
+
+	hid, err := c.GetCurrentHistoryID(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hid != 1<<32|2 {
+		t.Fatalf("history ID = %x", hid)
+	}
+	msgs, next, err := c.GetHistory(ctx, hid)
+	if err != nil || len(msgs) != 0 || next != hid {
+		t.Fatalf("no new mail: %v, %x, %v", msgs, next, err)
+	}
+
+	s.add(testMessage("Invoice 1"))
+	s.add(testMessage("Invoice 2"))
+	s.add(testMessage("Invoice 3"))
+	c.SetHistoryLimits(2)
+	msgs, next, err = c.GetHistory(ctx, hid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(msgs) != 2 || msgs[0].ID != "2" || msgs[0].Subject != "Invoice 1" || msgs[1].ID != "3" || next != 1<<32|4 {
+		t.Fatalf("capped history = %+v, %x", msgs, next)
+	}
+	if msgs[0].Event != gmail.EventMessageAdded || !slices.Contains(msgs[0].Labels, "UNREAD") {
+		t.Errorf("history message = %+v", msgs[0])
+	}
+	msgs, next, err = c.GetHistory(ctx, next)
+	if err != nil || len(msgs) != 1 || msgs[0].Subject != "Invoice 3" || next != 1<<32|5 {
+		t.Fatalf("rest of history = %+v, %x, %v", msgs, next, err)
+	}
+
+	s.validity = 2
+	if _, _, err := c.GetHistory(ctx, next); !errors.Is(err, gmail.ErrHistoryExpired) {
+		t.Errorf("new UIDVALIDITY: err = %v", err)
+	}
+}
+
+func TestClient_Login(t *testing.T) {
+	s := newFakeServer()
+	c := newTestClient(s)
+	c.cfg.Password = "wrong"
+	_, err := c.GetCurrentHistoryID(context.Background())
+	if err == nil || strings.Contains(err.Error(), "wrong") {
+		t.Errorf("bad password: err = %v", err)
+	}
+	if got := s.sent()[0]; got != `LOGIN "me@example.com" "wrong"` {
+		t.Errorf("login = %q", got)
+	}
+}
+
+func TestClient_ListAndGet(t *testing.T) {
+	s := newFakeServer()
+	c := newTestClient(s)
+	ctx := context.Background()
+	for i := range 3 {
+		s.add(testMessage(fmt.Sprintf("Invoice %d", i+1)))
+	}
+	attached := "Subject: scan\r\nContent-Type: multipart/mixed; boundary=b\r\n\r\n--b\r\nContent-Type: text/plain\r\n\r\nsee scan\r\n--b\r\nContent-Type: image/png\r\nContent-Disposition: attachment; filename=scan.png\r\nContent-Transfer-Encoding: base64\r\n\r\naGVsbG8=\r\n--b--\r\n"
+	s.add(attached, `\Seen`)
+
+	page, token, err := c.ListMessages(ctx, "from:billing@vendor.example", 2, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(page) != 2 || page[0].ID != "4" || page[1].ID != "3" || token != "2" {
+		t.Fatalf("first page = %+v, %q", page, token)
+	}
+	page, token, err = c.ListMessages(ctx, "", 2, token)
+	if err != nil || len(page) != 2 || page[1].Subject != "Invoice 1" || token != "" {
+		t.Fatalf("last page = %+v, %q, %v", page, token, err)
+	}
+	if !slices.Contains(s.sent(), `UID SEARCH FROM "billing@vendor.example"`) {
+		t.Errorf("commands = %q", s.sent())
+	}
+	if _, _, err := c.ListMessages(ctx, "label:work", 10, ""); err == nil {
+		t.Error("unsupported query should fail")
+	}
+
+	msg, err := c.GetMessage(ctx, "4")
+	if err != nil || msg.Body != "see scan" || len(msg.Attachments) != 1 {
+		t.Fatalf("GetMessage = %+v, %v", msg, err)
+	}
+	data, err := c.GetAttachment(ctx, "4", msg.Attachments[0].ID)
+	if err != nil || string(data) != "hello" {
+		t.Errorf("GetAttachment = %q, %v", data, err)
+	}
+	if _, err := c.GetAttachment(ctx, "4", "1"); err == nil {
+		t.Error("the text part is not an attachment")
+	}
+	thread, err := c.GetThread(ctx, "1")
+	if err != nil || len(thread) != 1 || thread[0].Subject != "Invoice 1" {
+		t.Errorf("GetThread = %+v, %v", thread, err)
+	}
+	if _, err := c.GetMessage(ctx, "99"); err == nil {
+		t.Error("missing message should fail")
+	}
+	if _, err := c.GetMessage(ctx, "x"); err == nil {
+		t.Error("bad ID should fail")
+	}
+	for _, cmd := range s.sent() {
+		if strings.HasPrefix(cmd, "SELECT") {
+			t.Errorf("reads should EXAMINE, sent %q", cmd)
+		}
+	}
+}
+
+func TestClient_Modify(t *testing.T) {
+	s := newFakeServer()
+	c := newTestClient(s)
+	ctx := context.Background()
+	uid := uidString(s.add(testMessage("Invoice")))
+
+	if err := c.ModifyMessage(ctx, uid, gmail.ModifyRequest{MarkRead: true, Star: true, AddLabels: []string{"Receipts"}}); err != nil {
+		t.Fatal(err)
+	}
+	if got := s.msgs[1].flags; !slices.Contains(got, `\Seen`) || !slices.Contains(got, `\Flagged`) {
+		t.Errorf("flags = %v", got)
+	}
+	if !slices.Equal(s.moved["Receipts"], []uint32{1}) {
+		t.Errorf("copies = %v", s.moved)
+	}
+
+	if err := c.ModifyMessage(ctx, uid, gmail.ModifyRequest{Archive: true}); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := s.msgs[1]; ok || !slices.Equal(s.moved["Archive"], []uint32{1}) {
+		t.Errorf("archive did not move: %v", s.moved)
+	}
+
+	uid = uidString(s.add(testMessage("Other")))
+	s.caps = "IMAP4rev1 UIDPLUS"
+	if err := c.ModifyMessage(ctx, uid, gmail.ModifyRequest{Archive: true}); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := s.msgs[2]; ok || !slices.Contains(s.sent(), "UID EXPUNGE 2") {
+		t.Errorf("archive without MOVE: %q", s.sent())
+	}
+
+	uid = uidString(s.add(testMessage("Third")))
+	s.caps = "IMAP4rev1"
+	if err := c.ModifyMessage(ctx, uid, gmail.ModifyRequest{Archive: true}); !errors.Is(err, errors.ErrUnsupported) {
+		t.Errorf("archive without MOVE or UIDPLUS: err = %v", err)
+	}
+	if err := c.ModifyMessage(ctx, uid, gmail.ModifyRequest{RemoveLabels: []string{"Receipts"}}); !errors.Is(err, errors.ErrUnsupported) {
+		t.Errorf("removing a label: err = %v", err)
+	}
+}
+
+func TestClient_Labels(t *testing.T) {
+	s := newFakeServer()
+	c := newTestClient(s)
+	labels, err := c.ListLabels(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, l := range labels {
+		names = append(names, l.ID+"/"+l.Type)
+	}
+	want := []string{"UNREAD/system", "STARRED/system", "INBOX/system", "Документы/user"}
+	if !slices.Equal(names, want) {
+		t.Errorf("labels = %v, want %v", names, want)
+	}
+}
+
+func TestClient_Unsupported(t *testing.T) {
+	c := newTestClient(newFakeServer())
+	ctx := context.Background()
+	if _, _, err := c.ListThreads(ctx, "", 10, ""); !errors.Is(err, errors.ErrUnsupported) {
+		t.Errorf("ListThreads: %v", err)
+	}
+	if _, err := c.ListDrafts(ctx, 10); !errors.Is(err, errors.ErrUnsupported) {
+		t.Errorf("ListDrafts: %v", err)
+	}
+	if _, err := c.CreateDraft(ctx, gmail.DraftRequest{}); !errors.Is(err, errors.ErrUnsupported) {
+		t.Errorf("CreateDraft: %v", err)
+	}
+	if _, err := c.UpdateDraft(ctx, "1", gmail.DraftRequest{}); !errors.Is(err, errors.ErrUnsupported) {
+		t.Errorf("UpdateDraft: %v", err)
+	}
+	if err := c.DeleteDraft(ctx, "1"); !errors.Is(err, errors.ErrUnsupported) {
+		t.Errorf("DeleteDraft: %v", err)
+	}
+}
+
+// fakeSMTP accepts one message and returns the commands and data it got.
+func fakeSMTP(t *testing.T) (func(context.Context) (*smtp.Client, error), <-chan []string) {
+	t.Helper()
+	got := make(chan []string, 1)
+	return func(context.Context) (*smtp.Client, error) {
+		client, server := net.Pipe()
+		go func() {
+			defer server.Close()
+			tp := textproto.NewConn(server)
+			var lines []string
+			tp.PrintfLine("220 localhost ESMTP")
+			for {
+				line, err := tp.ReadLine()
+				if err != nil {
+					got <- lines
+					return
+				}
+				lines = append(lines, line)
+				switch {
+				case strings.HasPrefix(line, "EHLO"):
+					tp.PrintfLine("250-localhost\r\n250 AUTH PLAIN")
+				case strings.HasPrefix(line, "AUTH"):
+					tp.PrintfLine("235 ok")
+				case line == "DATA":
+					tp.PrintfLine("354 go on")
+					data, _ := tp.ReadDotLines()
+					lines = append(lines, data...)
+					tp.PrintfLine("250 queued")
+				case line == "QUIT":
+					tp.PrintfLine("221 bye")
+					got <- lines
+					return
+				default:
+					tp.PrintfLine("250 ok")
+				}
+			}
+		}()
+		return smtp.NewClient(client, "localhost")
+	}, got
+}
+
+func TestClient_Forward(t *testing.T) {
+	s := newFakeServer()
+	c := NewClient("me@example.com", config.MailIMAPConfig{
+		Host:     "imap.example.com",
+		Password: "secret",
+		SMTP:     config.MailSMTPConfig{Host: "localhost"},
+	})
+	c.dial = s.dial
+	dial, got := fakeSMTP(t)
+	c.dialSMTP = dial
+	uid := uidString(s.add(testMessage("Invoice 1042")))
+
+	err := c.ForwardMessage(context.Background(), uid, gmail.ForwardRequest{To: []string{"Books <books@example.com>"}, Note: "For the books"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := <-got
+	joined := strings.Join(lines, "\n")
+	for _, want := range []string{
+		"MAIL FROM:<me@example.com>",
+		"RCPT TO:<books@example.com>",
+		"From: <me@example.com>",
+		"To: Books <books@example.com>",
+		"Subject: Fwd: Invoice 1042",
+	} {
+		if !strings.Contains(joined, want) {
+			t.Errorf("SMTP session lacks %q:\n%s", want, joined)
+		}
+	}
+	_, encoded, _ := strings.Cut(joined, "\n\n")
+	body, _ := base64.StdEncoding.DecodeString(strings.ReplaceAll(strings.TrimSuffix(encoded, "\nQUIT"), "\n", ""))
+	if !strings.HasPrefix(string(body), "For the books") || !strings.Contains(string(body), "Total: 420 EUR") {
+		t.Errorf("forwarded body = %q", body)
+	}
+
+	c.cfg.SMTP.Host = ""
+	if err := c.ForwardMessage(context.Background(), uid, gmail.ForwardRequest{To: []string{"a@example.com"}}); err == nil {
+		t.Error("forward without smtp.host should fail")
+	}
+}
@@ -0,0 +1,398 @@
+package imap
+
+import (
+	"bufio"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf16"
+)
+
+// maxLiteral bounds a literal in a server response: a whole message with
+// attachments.
+const maxLiteral = 64 << 20
+
+// mailboxBase64 is the base64 of modified UTF-7 mailbox names, before "/"
+// becomes ",".
+var mailboxBase64 = base64.StdEncoding.WithPadding(base64.NoPadding)
+
+// conn is one IMAP4rev1 session (RFC 3501): commands are sent one at a time
+// and their responses read up to the tagged completion.
+type conn struct {
+	nc  net.Conn
+	r   *bufio.Reader
+	w   *bufio.Writer
+	tag int
+}
+
+// response is a server response line. Untagged data responses keep their
+// fields: atoms, quoted strings and literals as string, NIL as nil and
+// parenthesized lists as []any. Status responses (OK, NO, BAD, BYE,
+// PREAUTH) keep the rest of the line as text.
+type response struct {
+	tag    string // "*" untagged, "+" continuation, else a command tag
+	status string
+	text   string
+	fields []any
+}
+
+// literal is a command argument sent as a synchronizing literal, for
+// strings a quoted string can't hold.
+type literal string
+
+func newConn(nc net.Conn) *conn {
+	return &conn{nc: nc, r: bufio.NewReader(nc), w: bufio.NewWriter(nc)}
+}
+
+// greeting reads the server greeting.
+func (c *conn) greeting() error {
+	resp, err := c.readResponse()
+	if err != nil {
+		return err
+	}
+	if resp.status != "OK" && resp.status != "PREAUTH" {
+		return fmt.Errorf("imap: server refused connection: %s %s", resp.status, resp.text)
+	}
+	return nil
+}
+
+// cmd sends a command built from args, separated by spaces except inside
+// parentheses, strings written as they are and literals as literals. It
+// returns the command's untagged responses; a NO or BAD completion is an
+// error.
+func (c *conn) cmd(args ...any) ([]response, error) {
+	c.tag++
+	tag := fmt.Sprintf("A%03d", c.tag)
+	c.w.WriteString(tag)
+	for i, arg := range args {
+		if i == 0 || (args[i-1] != "(" && arg != ")") {
+			c.w.WriteByte(' ')
+		}
+		switch v := arg.(type) {
+		case literal:
+			fmt.Fprintf(c.w, "{%d}\r\n", len(v))
+			if err := c.w.Flush(); err != nil {
+				return nil, err
+			}
+			resp, err := c.readResponse()
+			if err != nil {
+				return nil, err
+			}
+			if resp.tag != "+" {
+				return nil, fmt.Errorf("imap: %s: literal refused: %s %s", verb(args), resp.status, resp.text)
+			}
+			c.w.WriteString(string(v))
+		default:
+			fmt.Fprint(c.w, v)
+		}
+	}
+	c.w.WriteString("\r\n")
+	if err := c.w.Flush(); err != nil {
+		return nil, err
+	}
+
+	var untagged []response
+	for {
+		resp, err := c.readResponse()
+		if err != nil {
+			return nil, err
+		}
+		switch resp.tag {
+		case tag:
+			if resp.status != "OK" {
+				return untagged, fmt.Errorf("imap: %s: %s %s", verb(args), resp.status, resp.text)
+			}
+			return untagged, nil
+		case "*":
+			if resp.status == "BYE" {
+				return untagged, fmt.Errorf("imap: server closed the session: %s", resp.text)
+			}
+			untagged = append(untagged, resp)
+		}
+	}
+}
+
+// verb names a command in errors, without its arguments: they may hold the
+// password.
+func verb(args []any) string {
+	if len(args) == 0 {
+		return ""
+	}
+	s, _ := args[0].(string)
+	if strings.HasPrefix(s, "UID ") {
+		return s
+	}
+	return strings.Fields(s + " ")[0]
+}
+
+// readResponse reads one response line, with the literals in it.
+func (c *conn) readResponse() (response, error) {
+	tag, err := c.readWord()
+	if err != nil {
+		return response{}, err
+	}
+	resp := response{tag: tag}
+	if tag == "+" {
+		resp.text, err = c.readLine()
+		return resp, err
+	}
+	word, err := c.readWord()
+	if err != nil {
+		return resp, err
+	}
+	switch strings.ToUpper(word) {
+	case "OK", "NO", "BAD", "BYE", "PREAUTH":
+		resp.status = strings.ToUpper(word)
+		resp.text, err = c.readLine()
+		return resp, err
+	}
+	resp.fields = []any{word}
+	for {
+		if err := c.skipSpaces(); err != nil {
+			return resp, err
+		}
+		b, err := c.r.Peek(1)
+		if err != nil {
+			return resp, err
+		}
+		if b[0] == '\r' || b[0] == '\n' {
+			_, err = c.readLine()
+			return resp, err
+		}
+		v, err := c.readValue()
+		if err != nil {
+			return resp, err
+		}
+		resp.fields = append(resp.fields, v)
+	}
+}
+
+// readWord reads up to the next space or line end, consuming the space.
+func (c *conn) readWord() (string, error) {
+	var b strings.Builder
+	for {
+		ch, err := c.r.ReadByte()
+		if err != nil {
+			return "", err
+		}
+		switch ch {
+		case ' ':
+			return b.String(), nil
+		case '\r', '\n':
+			c.r.UnreadByte()
+			return b.String(), nil
+		}
+		b.WriteByte(ch)
+	}
+}
+
+// readLine reads the rest of the line without its line end.
+func (c *conn) readLine() (string, error) {
+	line, err := c.r.ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+func (c *conn) skipSpaces() error {
+	for {
+		b, err := c.r.Peek(1)
+		if err != nil {
+			return err
+		}
+		if b[0] != ' ' {
+			return nil
+		}
+		c.r.ReadByte()
+	}
+}
+
+// readValue reads an atom, quoted string, literal, NIL or list.
+func (c *conn) readValue() (any, error) {
+	b, err := c.r.ReadByte()
+	if err != nil {
+		return nil, err
+	}
+	switch b {
+	case '(':
+		list := []any{}
+		for {
+			if err := c.skipSpaces(); err != nil {
+				return nil, err
+			}
+			next, err := c.r.Peek(1)
+			if err != nil {
+				return nil, err
+			}
+			switch next[0] {
+			case ')':
+				c.r.ReadByte()
+				return list, nil
+			case '\r', '\n':
+				return nil, errors.New("imap: unterminated list in response")
+			}
+			v, err := c.readValue()
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, v)
+		}
+	case '"':
+		var s strings.Builder
+		for {
+			ch, err := c.r.ReadByte()
+			if err != nil {
+				return nil, err
+			}
+			switch ch {
+			case '"':
+				return s.String(), nil
+			case '\\':
+				if ch, err = c.r.ReadByte(); err != nil {
+					return nil, err
+				}
+			case '\r', '\n':
+				return nil, errors.New("imap: unterminated quoted string in response")
+			}
+			s.WriteByte(ch)
+		}
+	case '{':
+		spec, err := c.r.ReadString('}')
+		if err != nil {
+			return nil, err
+		}
+		n, err := strconv.Atoi(strings.TrimSuffix(strings.TrimSuffix(spec, "}"), "+"))
+		if err != nil || n < 0 || n > maxLiteral {
+			return nil, fmt.Errorf("imap: bad literal {%s in response", spec)
+		}
+		if _, err := c.readLine(); err != nil {
+			return nil, err
+		}
+		data := make([]byte, n)
+		if _, err := io.ReadFull(c.r, data); err != nil {
+			return nil, err
+		}
+		return string(data), nil
+	}
+
+	// An atom; a section such as BODY[HEADER.FIELDS (FROM)] may hold spaces
+	var s strings.Builder
+	s.WriteByte(b)
+	inSection := b == '['
+	for {
+		next, err := c.r.Peek(1)
+		if err != nil {
+			return nil, err
+		}
+		ch := next[0]
+		if !inSection && (ch == ' ' || ch == '(' || ch == ')' || ch == '\r' || ch == '\n') {
+			break
+		}
+		if ch == '\r' || ch == '\n' {
+			return nil, errors.New("imap: unterminated section in response")
+		}
+		c.r.ReadByte()
+		s.WriteByte(ch)
+		switch ch {
+		case '[':
+			inSection = true
+		case ']':
+			inSection = false
+		}
+	}
+	if strings.EqualFold(s.String(), "NIL") {
+		return nil, nil
+	}
+	return s.String(), nil
+}
+
+// setDeadline bounds the rest of the session.
+func (c *conn) setDeadline(t time.Time) {
+	c.nc.SetDeadline(t)
+}
+
+func (c *conn) close() error {
+	return c.nc.Close()
+}
+
+// astring is s as a command argument: quoted when it can be, else a
+// literal.
+func astring(s string) any {
+	for i := range len(s) {
+		if s[i] < 0x20 || s[i] > 0x7e {
+			return literal(s)
+		}
+	}
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
+
+// encodeMailbox encodes a mailbox name in modified UTF-7 (RFC 3501 5.1.3).
+func encodeMailbox(name string) string {
+	var b strings.Builder
+	var run []rune
+	flush := func() {
+		if len(run) == 0 {
+			return
+		}
+		var buf []byte
+		for _, u := range utf16.Encode(run) {
+			buf = append(buf, byte(u>>8), byte(u))
+		}
+		b.WriteString("&" + strings.ReplaceAll(mailboxBase64.EncodeToString(buf), "/", ",") + "-")
+		run = nil
+	}
+	for _, r := range name {
+		if r >= 0x20 && r <= 0x7e {
+			flush()
+			if r == '&' {
+				b.WriteString("&-")
+			} else {
+				b.WriteRune(r)
+			}
+			continue
+		}
+		run = append(run, r)
+	}
+	flush()
+	return b.String()
+}
+
+// decodeMailbox decodes a modified UTF-7 mailbox name; names that don't
+// decode are returned as they are.
+func decodeMailbox(name string) string {
+	var b strings.Builder
+	for {
+		i := strings.IndexByte(name, '&')
+		if i < 0 {
+			b.WriteString(name)
+			return b.String()
+		}
+		b.WriteString(name[:i])
+		name = name[i+1:]
+		j := strings.IndexByte(name, '-')
+		if j < 0 {
+			return b.String() + "&" + name
+		}
+		if j == 0 {
+			b.WriteByte('&')
+		} else {
+			buf, err := mailboxBase64.DecodeString(strings.ReplaceAll(name[:j], ",", "/"))
+			if err != nil || len(buf)%2 != 0 {
+				b.WriteString("&" + name[:j+1])
+			} else {
+				u := make([]uint16, len(buf)/2)
+				for k := range u {
+					u[k] = uint16(buf[2*k])<<8 | uint16(buf[2*k+1])
+				}
+				b.WriteString(string(utf16.Decode(u)))
+			}
+		}
+		name = name[j+1:]
+	}
+}
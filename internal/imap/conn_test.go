@@ -0,0 +1,111 @@
+package imap
+
+import (
+	"bufio"
+	"net"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// pipeConn returns a conn whose server side is script: the server writes
+// out and records what the client sends.
+func pipeConn(t *testing.T, out string) (*conn, func() string) {
+	t.Helper()
+	client, server := net.Pipe()
+	got := make(chan string, 1)
+	go func() {
+		defer server.Close()
+		r := bufio.NewReader(server)
+		line, _ := r.ReadString('\n')
+		server.Write([]byte(out))
+		got <- line
+	}()
+	t.Cleanup(func() { client.Close() })
+	return newConn(client), func() string { return <-got }
+}
+
+func TestConn_Cmd(t *testing.T) {
+	c, sent := pipeConn(t, "* 1 FETCH (UID 7 FLAGS (\\Seen $Label) BODY[HEADER.FIELDS (FROM)] {5}\r\nFrom:)\r\n"+
+		"* OK [UIDNEXT 8] next\r\n"+
+		"A001 OK done\r\n")
+	resps, err := c.cmd("UID FETCH", "7", "(", "UID", "FLAGS", ")")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := sent(); s != "A001 UID FETCH 7 (UID FLAGS)\r\n" {
+		t.Errorf("sent %q", s)
+	}
+	if len(resps) != 2 {
+		t.Fatalf("got %d responses", len(resps))
+	}
+	want := []any{"1", "FETCH", []any{"UID", "7", "FLAGS", []any{`\Seen`, "$Label"}, "BODY[HEADER.FIELDS (FROM)]", "From:"}}
+	if !reflect.DeepEqual(resps[0].fields, want) {
+		t.Errorf("fields = %#v", resps[0].fields)
+	}
+	if resps[1].status != "OK" || resps[1].text != "[UIDNEXT 8] next" {
+		t.Errorf("status response = %+v", resps[1])
+	}
+}
+
+func TestConn_CmdErrors(t *testing.T) {
+	c, _ := pipeConn(t, "A001 NO [AUTHENTICATIONFAILED] bad\r\n")
+	_, err := c.cmd("LOGIN", `"me"`, `"secret"`)
+	if err == nil || strings.Contains(err.Error(), "secret") || !strings.Contains(err.Error(), "LOGIN: NO") {
+		t.Errorf("err = %v", err)
+	}
+
+	c, _ = pipeConn(t, "* BYE shutting down\r\n")
+	if _, err := c.cmd("NOOP"); err == nil {
+		t.Error("BYE should fail the command")
+	}
+}
+
+func TestConn_ReadValues(t *testing.T) {
+	c, _ := pipeConn(t, `* LIST (\HasNoChildren) "/" "Work \"Q1\"" NIL`+"\r\nA001 OK\r\n")
+	resps, err := c.cmd("LIST", `""`, `"*"`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []any{"LIST", []any{`\HasNoChildren`}, "/", `Work "Q1"`, nil}
+	if !reflect.DeepEqual(resps[0].fields, want) {
+		t.Errorf("fields = %#v", resps[0].fields)
+	}
+}
+
+func TestAstring(t *testing.T) {
+	tests := []struct {
+		in   string
+		want any
+	}{
+		{"me@example.com", `"me@example.com"`},
+		{`a"b\c`, `"a\"b\\c"`},
+		{"Счёт", literal("Счёт")},
+		{"a\r\nb", literal("a\r\nb")},
+	}
+	for _, tt := range tests {
+		if got := astring(tt.in); got != tt.want {
+			t.Errorf("astring(%q) = %#v, want %#v", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestMailboxNames(t *testing.T) {
+	tests := []struct{ name, encoded string }{
+		{"INBOX", "INBOX"},
+		{"Tom & Jerry", "Tom &- Jerry"},
+		{"Входящие", "&BBIERQQ+BDQETwRJBDgENQ-"},
+		{"日本語/Mail", "&ZeVnLIqe-/Mail"},
+	}
+	for _, tt := range tests {
+		if got := encodeMailbox(tt.name); got != tt.encoded {
+			t.Errorf("encodeMailbox(%q) = %q, want %q", tt.name, got, tt.encoded)
+		}
+		if got := decodeMailbox(tt.encoded); got != tt.name {
+			t.Errorf("decodeMailbox(%q) = %q, want %q", tt.encoded, got, tt.name)
+		}
+	}
+	if got := decodeMailbox("broken&"); got != "broken&" {
+		t.Errorf("decodeMailbox of a bad name = %q", got)
+	}
+}
@@ -0,0 +1,220 @@
+package imap
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"net/textproto"
+	"strings"
+
+	"github.com/katalabut/openclaw-relay/internal/gmail"
+)
+
+// snippetLen is how many characters of the body make a snippet, as in Gmail.
+const snippetLen = 200
+
+// Label IDs the relay's rules and API use for IMAP flags, as in Gmail.
+const (
+	labelInbox   = "INBOX"
+	labelUnread  = "UNREAD"
+	labelStarred = "STARRED"
+)
+
+// part is a leaf MIME part of a message; path is its IMAP section number,
+// e.g. "1.2".
+type part struct {
+	path     string
+	mimeType string
+	filename string
+	data     []byte
+}
+
+// parseMessage parses a raw RFC 822 message into its headers and leaf parts.
+func parseMessage(raw []byte) (mail.Header, []part, error) {
+	m, err := mail.ReadMessage(bytes.NewReader(raw))
+	if err != nil {
+		return nil, nil, fmt.Errorf("parse message: %w", err)
+	}
+	var parts []part
+	walkPart(textproto.MIMEHeader(m.Header), m.Body, "", &parts)
+	return m.Header, parts, nil
+}
+
+// walkPart appends the leaf parts of the entity with header h. Parts that
+// don't parse are skipped, so a malformed attachment doesn't hide the text.
+func walkPart(h textproto.MIMEHeader, body io.Reader, path string, out *[]part) {
+	mediaType, params, err := mime.ParseMediaType(h.Get("Content-Type"))
+	if err != nil {
+		mediaType, params = "text/plain", nil
+	}
+	if strings.HasPrefix(mediaType, "multipart/") && params["boundary"] != "" {
+		mr := multipart.NewReader(body, params["boundary"])
+		for i := 1; ; i++ {
+			p, err := mr.NextRawPart()
+			if err != nil {
+				return
+			}
+			sub := fmt.Sprint(i)
+			if path != "" {
+				sub = path + "." + sub
+			}
+			walkPart(p.Header, p, sub, out)
+		}
+	}
+	if path == "" {
+		path = "1"
+	}
+	data, err := io.ReadAll(decodeTransfer(h.Get("Content-Transfer-Encoding"), body))
+	if err != nil {
+		return
+	}
+	filename := params["name"]
+	if _, dp, err := mime.ParseMediaType(h.Get("Content-Disposition")); err == nil && dp["filename"] != "" {
+		filename = dp["filename"]
+	}
+	*out = append(*out, part{path: path, mimeType: mediaType, filename: decodeHeader(filename), data: data})
+}
+
+func decodeTransfer(encoding string, r io.Reader) io.Reader {
+	switch strings.ToLower(strings.TrimSpace(encoding)) {
+	case "base64":
+		return base64.NewDecoder(base64.StdEncoding, &skipSpace{r})
+	case "quoted-printable":
+		return quotedprintable.NewReader(r)
+	}
+	return r
+}
+
+// skipSpace drops the line breaks base64 bodies are wrapped with.
+type skipSpace struct{ r io.Reader }
+
+func (s *skipSpace) Read(p []byte) (int, error) {
+	n, err := s.r.Read(p)
+	j := 0
+	for _, b := range p[:n] {
+		if b != '\r' && b != '\n' && b != ' ' && b != '\t' {
+			p[j] = b
+			j++
+		}
+	}
+	if j == 0 && n > 0 && err == nil {
+		return s.Read(p)
+	}
+	return j, err
+}
+
+func decodeHeader(s string) string {
+	dec := new(mime.WordDecoder)
+	if out, err := dec.DecodeHeader(s); err == nil {
+		return out
+	}
+	return s
+}
+
+// bodyText returns the first plain text part, else the first HTML part.
+func bodyText(parts []part) string {
+	for _, mt := range []string{"text/plain", "text/html"} {
+		for _, p := range parts {
+			if p.mimeType == mt && p.filename == "" {
+				return string(p.data)
+			}
+		}
+	}
+	return ""
+}
+
+func snippet(body string) string {
+	s := strings.Join(strings.Fields(body), " ")
+	if r := []rune(s); len(r) > snippetLen {
+		return string(r[:snippetLen])
+	}
+	return s
+}
+
+// labels maps a message's flags, in mailbox, to the relay's label IDs.
+func labels(mailbox string, flags []string) []string {
+	out := []string{mailboxLabel(mailbox)}
+	seen, flagged := false, false
+	for _, f := range flags {
+		switch strings.ToLower(f) {
+		case `\seen`:
+			seen = true
+		case `\flagged`:
+			flagged = true
+		}
+	}
+	if !seen {
+		out = append(out, labelUnread)
+	}
+	if flagged {
+		out = append(out, labelStarred)
+	}
+	return out
+}
+
+// mailboxLabel is the label ID of a mailbox: INBOX in any case is INBOX.
+func mailboxLabel(mailbox string) string {
+	if strings.EqualFold(mailbox, labelInbox) {
+		return labelInbox
+	}
+	return mailbox
+}
+
+// fullMessage builds the relay's view of a fetched message. A message is
+// its own thread: IMAP has no thread IDs.
+func fullMessage(uid, mailbox string, flags []string, raw []byte) (*gmail.MessageFull, error) {
+	h, parts, err := parseMessage(raw)
+	if err != nil {
+		return nil, err
+	}
+	body := bodyText(parts)
+	msg := &gmail.MessageFull{
+		ID:       uid,
+		ThreadID: uid,
+		Subject:  decodeHeader(h.Get("Subject")),
+		From:     decodeHeader(h.Get("From")),
+		To:       decodeHeader(h.Get("To")),
+		Date:     h.Get("Date"),
+		Body:     body,
+		Labels:   labels(mailbox, flags),
+		Snippet:  snippet(body),
+		RFC822ID: h.Get("Message-ID"),
+	}
+	for _, p := range parts {
+		if p.filename != "" {
+			msg.Attachments = append(msg.Attachments, gmail.Attachment{
+				ID:       p.path,
+				PartID:   p.path,
+				Filename: p.filename,
+				MimeType: p.mimeType,
+				Size:     int64(len(p.data)),
+			})
+		}
+	}
+	return msg, nil
+}
+
+// messageMeta builds a list entry from a message's header; it has no snippet.
+func messageMeta(uid, mailbox string, flags []string, header []byte) (gmail.MessageMeta, error) {
+	m, err := mail.ReadMessage(bytes.NewReader(append(bytes.TrimRight(header, "\r\n"), "\r\n\r\n"...)))
+	if err != nil {
+		return gmail.MessageMeta{}, fmt.Errorf("parse header: %w", err)
+	}
+	h := m.Header
+	return gmail.MessageMeta{
+		ID:       uid,
+		ThreadID: uid,
+		Subject:  decodeHeader(h.Get("Subject")),
+		From:     decodeHeader(h.Get("From")),
+		To:       decodeHeader(h.Get("To")),
+		Cc:       decodeHeader(h.Get("Cc")),
+		Date:     h.Get("Date"),
+		Labels:   labels(mailbox, flags),
+		RFC822ID: h.Get("Message-ID"),
+	}, nil
+}
@@ -0,0 +1,107 @@
+package imap
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const multipartMessage = "From: =?UTF-8?Q?Bj=C3=B6rn?= <bjorn@vendor.example>\r\n" +
+	"To: me@example.com\r\n" +
+	"Cc: team@example.com\r\n" +
+	"Subject: Invoice 1042\r\n" +
+	"Date: Mon, 2 Mar 2026 09:00:00 +0000\r\n" +
+	"Message-ID: <1042@vendor.example>\r\n" +
+	"MIME-Version: 1.0\r\n" +
+	"Content-Type: multipart/mixed; boundary=outer\r\n" +
+	"\r\n" +
+	"--outer\r\n" +
+	"Content-Type: multipart/alternative; boundary=inner\r\n" +
+	"\r\n" +
+	"--inner\r\n" +
+	"Content-Type: text/plain; charset=utf-8\r\n" +
+	"Content-Transfer-Encoding: quoted-printable\r\n" +
+	"\r\n" +
+	"Total: 420 =E2=82=AC\r\n" +
+	"--inner\r\n" +
+	"Content-Type: text/html\r\n" +
+	"\r\n" +
+	"<p>Total: 420 &euro;</p>\r\n" +
+	"--inner--\r\n" +
+	"--outer\r\n" +
+	"Content-Type: application/pdf; name=\"invoice.pdf\"\r\n" +
+	"Content-Disposition: attachment; filename=\"invoice.pdf\"\r\n" +
+	"Content-Transfer-Encoding: base64\r\n" +
+	"\r\n" +
+	"JVBE\r\nRi0x\r\n" +
+	"--outer--\r\n"
+
+func TestParseMessage(t *testing.T) {
+	_, parts, err := parseMessage([]byte(multipartMessage))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var paths []string
+	for _, p := range parts {
+		paths = append(paths, p.path+" "+p.mimeType)
+	}
+	want := []string{"1.1 text/plain", "1.2 text/html", "2 application/pdf"}
+	if !reflect.DeepEqual(paths, want) {
+		t.Errorf("parts = %v, want %v", paths, want)
+	}
+	if got := string(parts[0].data); got != "Total: 420 €" {
+		t.Errorf("quoted-printable body = %q", got)
+	}
+	if got := string(parts[2].data); got != "%PDF-1" || parts[2].filename != "invoice.pdf" {
+		t.Errorf("attachment = %q %q", parts[2].filename, got)
+	}
+
+	_, parts, err = parseMessage([]byte("Subject: plain\r\n\r\nhello\r\n"))
+	if err != nil || len(parts) != 1 || parts[0].path != "1" || bodyText(parts) != "hello\r\n" {
+		t.Errorf("single part = %+v, %v", parts, err)
+	}
+}
+
+func TestFullMessage(t *testing.T) {
+	msg, err := fullMessage("7", "INBOX", []string{`\Flagged`}, []byte(multipartMessage))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if msg.ID != "7" || msg.ThreadID != "7" || msg.From != "Björn <bjorn@vendor.example>" || msg.Subject != "Invoice 1042" || msg.RFC822ID != "<1042@vendor.example>" {
+		t.Errorf("headers = %+v", msg)
+	}
+	if msg.Snippet != "Total: 420 €" {
+		t.Errorf("snippet = %q", msg.Snippet)
+	}
+	if !reflect.DeepEqual(msg.Labels, []string{"INBOX", "UNREAD", "STARRED"}) {
+		t.Errorf("labels = %v", msg.Labels)
+	}
+	if len(msg.Attachments) != 1 || msg.Attachments[0].ID != "2" || msg.Attachments[0].Size != 6 {
+		t.Errorf("attachments = %+v", msg.Attachments)
+	}
+
+	h, err := historyMessage("7", "inbox", []string{`\Seen`}, []byte(multipartMessage))
+	if err != nil || h.Cc != "team@example.com" || !reflect.DeepEqual(h.Labels, []string{"INBOX"}) {
+		t.Errorf("historyMessage = %+v, %v", h, err)
+	}
+}
+
+func TestMessageMeta(t *testing.T) {
+	header, _, _ := strings.Cut(multipartMessage, "\r\n\r\n")
+	m, err := messageMeta("9", "Receipts", nil, []byte(header+"\r\n\r\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.ID != "9" || m.Cc != "team@example.com" || m.Snippet != "" || !reflect.DeepEqual(m.Labels, []string{"Receipts", "UNREAD"}) {
+		t.Errorf("meta = %+v", m)
+	}
+}
+
+func TestSnippet(t *testing.T) {
+	if got := snippet("  a\r\n\r\n b  "); got != "a b" {
+		t.Errorf("snippet = %q", got)
+	}
+	if got := snippet(strings.Repeat("ж", 300)); len([]rune(got)) != snippetLen {
+		t.Errorf("snippet has %d runes", len([]rune(got)))
+	}
+}
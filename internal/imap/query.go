@@ -0,0 +1,288 @@
+package imap
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// searchCriteria translates a Gmail search query into IMAP SEARCH keys
+// (RFC 3501 6.4.4), for rules' query and not_query, backfill and the API.
+// Supported: from:, to:, cc:, bcc:, subject:, rfc822msgid:, is:unread,
+// is:read, is:starred, has:attachment, after:, before:, newer_than:,
+// older_than:, larger:, smaller:, in:inbox, in:anywhere, free text and
+// quoted phrases, with -negation, OR, {a b} and (groups). Anything else is
+// an error rather than a different search.
+func searchCriteria(query string, now time.Time) ([]any, error) {
+	p := &queryParser{tokens: tokenize(query), now: now}
+	keys, err := p.sequence("")
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.tokens) {
+		return nil, fmt.Errorf("query: unexpected %q", p.tokens[p.pos])
+	}
+	if len(keys) == 0 {
+		return []any{"ALL"}, nil
+	}
+	var out []any
+	for _, k := range keys {
+		out = append(out, k...)
+	}
+	return out, nil
+}
+
+// needsUTF8 reports whether the criteria hold a literal, which is sent with
+// CHARSET UTF-8.
+func needsUTF8(criteria []any) bool {
+	for _, c := range criteria {
+		if _, ok := c.(literal); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// tokenize splits a query into words, quoted phrases (kept with their
+// quotes) and the characters ( ) { }.
+func tokenize(q string) []string {
+	var tokens []string
+	var cur strings.Builder
+	inQuote := false
+	flush := func() {
+		if cur.Len() > 0 {
+			tokens = append(tokens, cur.String())
+			cur.Reset()
+		}
+	}
+	for _, r := range q {
+		switch {
+		case r == '"':
+			cur.WriteRune(r)
+			inQuote = !inQuote
+		case inQuote:
+			cur.WriteRune(r)
+		case r == ' ' || r == '\t' || r == '\n':
+			flush()
+		case strings.ContainsRune("(){}", r):
+			flush()
+			tokens = append(tokens, string(r))
+		default:
+			cur.WriteRune(r)
+		}
+	}
+	flush()
+	return tokens
+}
+
+type queryParser struct {
+	tokens []string
+	pos    int
+	now    time.Time
+}
+
+// sequence parses terms up to end ("" for the end of the query), joining
+// terms around OR. Each returned key is one IMAP search key.
+func (p *queryParser) sequence(end string) ([][]any, error) {
+	var keys [][]any
+	for p.pos < len(p.tokens) && p.tokens[p.pos] != end {
+		tok := p.tokens[p.pos]
+		if tok == ")" || tok == "}" {
+			return nil, fmt.Errorf("query: unexpected %q", tok)
+		}
+		if tok == "OR" && len(keys) > 0 {
+			p.pos++
+			next, err := p.term()
+			if err != nil {
+				return nil, err
+			}
+			if next == nil {
+				return nil, errors.New("query: OR needs a search term on both sides")
+			}
+			keys[len(keys)-1] = append(append([]any{"OR"}, keys[len(keys)-1]...), next...)
+			continue
+		}
+		key, err := p.term()
+		if err != nil {
+			return nil, err
+		}
+		if key != nil {
+			keys = append(keys, key)
+		}
+	}
+	if end != "" {
+		if p.pos >= len(p.tokens) {
+			return nil, fmt.Errorf("query: missing %q", end)
+		}
+		p.pos++
+	}
+	return keys, nil
+}
+
+// group makes keys one search key.
+func group(keys [][]any) []any {
+	if len(keys) == 1 {
+		return keys[0]
+	}
+	out := []any{"("}
+	for _, k := range keys {
+		out = append(out, k...)
+	}
+	return append(out, ")")
+}
+
+// term parses one term. It returns nil for terms that select nothing
+// extra, such as in:anywhere.
+func (p *queryParser) term() ([]any, error) {
+	if p.pos >= len(p.tokens) {
+		return nil, errors.New("query: missing term")
+	}
+	tok := p.tokens[p.pos]
+	p.pos++
+	switch tok {
+	case "(":
+		keys, err := p.sequence(")")
+		if err != nil || len(keys) == 0 {
+			return nil, err
+		}
+		return group(keys), nil
+	case "{":
+		keys, err := p.sequence("}")
+		if err != nil || len(keys) == 0 {
+			return nil, err
+		}
+		key := keys[len(keys)-1]
+		for i := len(keys) - 2; i >= 0; i-- {
+			key = append(append([]any{"OR"}, keys[i]...), key...)
+		}
+		return key, nil
+	}
+	if neg, ok := strings.CutPrefix(tok, "-"); ok {
+		if neg != "" {
+			p.pos--
+			p.tokens[p.pos] = neg
+		}
+		key, err := p.term()
+		if err != nil || key == nil {
+			return nil, err
+		}
+		return append([]any{"NOT"}, key...), nil
+	}
+	return p.operator(tok)
+}
+
+func (p *queryParser) operator(tok string) ([]any, error) {
+	op, arg, ok := strings.Cut(tok, ":")
+	if !ok || strings.HasPrefix(tok, `"`) {
+		return []any{"TEXT", astring(unquote(tok))}, nil
+	}
+	arg = unquote(arg)
+	switch strings.ToLower(op) {
+	case "from", "to", "cc", "bcc", "subject":
+		return []any{strings.ToUpper(op), astring(arg)}, nil
+	case "rfc822msgid":
+		return []any{"HEADER", "Message-ID", astring(arg)}, nil
+	case "is":
+		switch strings.ToLower(arg) {
+		case "unread":
+			return []any{"UNSEEN"}, nil
+		case "read":
+			return []any{"SEEN"}, nil
+		case "starred":
+			return []any{"FLAGGED"}, nil
+		}
+	case "has":
+		if strings.EqualFold(arg, "attachment") {
+			return []any{"HEADER", "Content-Type", `"multipart/mixed"`}, nil
+		}
+	case "in":
+		if strings.EqualFold(arg, "inbox") || strings.EqualFold(arg, "anywhere") {
+			return nil, nil
+		}
+	case "after", "before":
+		t, err := parseQueryDate(arg)
+		if err != nil {
+			return nil, fmt.Errorf("query: %s: %w", tok, err)
+		}
+		key := "SINCE"
+		if strings.EqualFold(op, "before") {
+			key = "BEFORE"
+		}
+		return []any{key, t.Format("2-Jan-2006")}, nil
+	case "newer_than", "older_than":
+		d, err := parseQueryAge(arg)
+		if err != nil {
+			return nil, fmt.Errorf("query: %s: %w", tok, err)
+		}
+		key := "SINCE"
+		if strings.EqualFold(op, "older_than") {
+			key = "BEFORE"
+		}
+		y, m, dd := p.now.Date()
+		return []any{key, time.Date(y-d[0], m-time.Month(d[1]), dd-d[2], 0, 0, 0, 0, time.UTC).Format("2-Jan-2006")}, nil
+	case "larger", "smaller":
+		n, err := parseQuerySize(arg)
+		if err != nil {
+			return nil, fmt.Errorf("query: %s: %w", tok, err)
+		}
+		return []any{strings.ToUpper(op), strconv.FormatInt(n, 10)}, nil
+	}
+	return nil, fmt.Errorf("query: %s is not supported for IMAP accounts", tok)
+}
+
+func unquote(s string) string {
+	if len(s) >= 2 && strings.HasPrefix(s, `"`) && strings.HasSuffix(s, `"`) {
+		return s[1 : len(s)-1]
+	}
+	return strings.Trim(s, `"`)
+}
+
+func parseQueryDate(s string) (time.Time, error) {
+	for _, layout := range []string{"2006/01/02", "2006/1/2", "2006-01-02", "01/02/2006"} {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, nil
+		}
+	}
+	if n, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return time.Unix(n, 0).UTC(), nil
+	}
+	return time.Time{}, fmt.Errorf("bad date %q", s)
+}
+
+// parseQueryAge parses 7d, 2m or 1y into years, months and days.
+func parseQueryAge(s string) ([3]int, error) {
+	if len(s) < 2 {
+		return [3]int{}, fmt.Errorf("bad age %q", s)
+	}
+	n, err := strconv.Atoi(s[:len(s)-1])
+	if err != nil || n < 0 {
+		return [3]int{}, fmt.Errorf("bad age %q", s)
+	}
+	switch s[len(s)-1] {
+	case 'd':
+		return [3]int{0, 0, n}, nil
+	case 'm':
+		return [3]int{0, n, 0}, nil
+	case 'y':
+		return [3]int{n, 0, 0}, nil
+	}
+	return [3]int{}, fmt.Errorf("bad age %q", s)
+}
+
+// parseQuerySize parses a byte count with an optional K or M suffix.
+func parseQuerySize(s string) (int64, error) {
+	mult := int64(1)
+	switch {
+	case strings.HasSuffix(strings.ToUpper(s), "K"):
+		mult, s = 1<<10, s[:len(s)-1]
+	case strings.HasSuffix(strings.ToUpper(s), "M"):
+		mult, s = 1<<20, s[:len(s)-1]
+	}
+	n, err := strconv.ParseInt(s, 10, 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("bad size %q", s)
+	}
+	return n * mult, nil
+}
@@ -0,0 +1,73 @@
+package imap
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSearchCriteria(t *testing.T) {
+	now := time.Date(2026, 3, 10, 15, 0, 0, 0, time.UTC)
+	tests := []struct {
+		query string
+		want  string
+	}{
+		{"", "ALL"},
+		{"in:anywhere", "ALL"},
+		{"from:billing@vendor.example", `FROM "billing@vendor.example"`},
+		{`subject:"Invoice 1042" is:unread`, `SUBJECT "Invoice 1042" UNSEEN`},
+		{"rfc822msgid:<1@x> in:anywhere", `HEADER Message-ID "<1@x>"`},
+		{"in:anywhere rfc822msgid:1@x (from:a is:unread)", `HEADER Message-ID "1@x" ( FROM "a" UNSEEN )`},
+		{"invoice -is:starred", `TEXT "invoice" NOT FLAGGED`},
+		{"from:a OR from:b", `OR FROM "a" FROM "b"`},
+		{"{from:a from:b from:c}", `OR FROM "a" OR FROM "b" FROM "c"`},
+		{"-(from:a to:b)", `NOT ( FROM "a" TO "b" )`},
+		{"after:2026/03/01 before:2026-03-05", "SINCE 1-Mar-2026 BEFORE 5-Mar-2026"},
+		{"newer_than:7d older_than:1m", "SINCE 3-Mar-2026 BEFORE 10-Feb-2026"},
+		{"larger:2M smaller:500", "LARGER 2097152 SMALLER 500"},
+		{"has:attachment", `HEADER Content-Type "multipart/mixed"`},
+		{"subject:счёт", "SUBJECT {счёт}"},
+	}
+	for _, tt := range tests {
+		got, err := searchCriteria(tt.query, now)
+		if err != nil {
+			t.Errorf("searchCriteria(%q): %v", tt.query, err)
+			continue
+		}
+		var words []string
+		for _, k := range got {
+			if l, ok := k.(literal); ok {
+				k = "{" + string(l) + "}"
+			}
+			words = append(words, fmt.Sprint(k))
+		}
+		if s := strings.Join(words, " "); s != tt.want {
+			t.Errorf("searchCriteria(%q) = %s, want %s", tt.query, s, tt.want)
+		}
+	}
+}
+
+func TestSearchCriteria_Errors(t *testing.T) {
+	for _, q := range []string{
+		"label:work",
+		"category:promotions",
+		"(from:a",
+		"from:a)",
+		"from:a -",
+		"from:a OR in:anywhere",
+		"after:yesterday",
+		"newer_than:7w",
+		"larger:big",
+	} {
+		if _, err := searchCriteria(q, time.Now()); err == nil {
+			t.Errorf("searchCriteria(%q) should fail", q)
+		}
+	}
+}
+
+func TestNeedsUTF8(t *testing.T) {
+	if needsUTF8([]any{"FROM", `"a"`}) || !needsUTF8([]any{"FROM", literal("ö")}) {
+		t.Error("needsUTF8 should report literals only")
+	}
+}
@@ -0,0 +1,117 @@
+package imap
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// smtpsPort is the SMTP submission port that uses TLS from the start
+// (RFC 8314); other ports upgrade with STARTTLS.
+const smtpsPort = 465
+
+func (c *Client) dialSMTPServer(ctx context.Context) (*smtp.Client, error) {
+	s := c.cfg.SMTP
+	addr := net.JoinHostPort(s.Host, strconv.Itoa(s.Port))
+	tlsCfg := &tls.Config{ServerName: s.Host}
+	var nc net.Conn
+	var err error
+	if s.Port == smtpsPort {
+		nc, err = (&tls.Dialer{Config: tlsCfg}).DialContext(ctx, "tcp", addr)
+	} else {
+		nc, err = (&net.Dialer{}).DialContext(ctx, "tcp", addr)
+	}
+	if err != nil {
+		return nil, err
+	}
+	deadline := time.Now().Add(sessionTimeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	nc.SetDeadline(deadline)
+	sc, err := smtp.NewClient(nc, s.Host)
+	if err != nil {
+		nc.Close()
+		return nil, err
+	}
+	if s.Port != smtpsPort {
+		// Never send the password in the clear
+		if ok, _ := sc.Extension("STARTTLS"); !ok {
+			sc.Close()
+			return nil, errors.New("server does not offer STARTTLS")
+		}
+		if err := sc.StartTLS(tlsCfg); err != nil {
+			sc.Close()
+			return nil, err
+		}
+	}
+	return sc, nil
+}
+
+// sendMail sends msg, an RFC 2822 message without From, Date and
+// Message-ID headers, to the addresses to.
+func (c *Client) sendMail(ctx context.Context, to []string, msg []byte) error {
+	s := c.cfg.SMTP
+	if s.Host == "" {
+		return errors.New("imap: sending needs imap.smtp.host")
+	}
+	var rcpts []string
+	for _, t := range to {
+		addr, err := mail.ParseAddress(t)
+		if err != nil {
+			return fmt.Errorf("smtp: recipient %q: %w", t, err)
+		}
+		rcpts = append(rcpts, addr.Address)
+	}
+	from, err := mail.ParseAddress(s.From)
+	if err != nil {
+		return fmt.Errorf("smtp: from %q: %w", s.From, err)
+	}
+
+	sc, err := c.dialSMTP(ctx)
+	if err != nil {
+		return fmt.Errorf("smtp: connect to %s: %w", s.Host, err)
+	}
+	defer sc.Close()
+	if err := sc.Auth(smtp.PlainAuth("", s.Username, s.Password, s.Host)); err != nil {
+		return fmt.Errorf("smtp: login: %w", err)
+	}
+	if err := sc.Mail(from.Address); err != nil {
+		return fmt.Errorf("smtp: %w", err)
+	}
+	for _, r := range rcpts {
+		if err := sc.Rcpt(r); err != nil {
+			return fmt.Errorf("smtp: recipient %s: %w", r, err)
+		}
+	}
+	w, err := sc.Data()
+	if err != nil {
+		return fmt.Errorf("smtp: %w", err)
+	}
+	fmt.Fprintf(w, "From: %s\r\nDate: %s\r\nMessage-ID: %s\r\n", from.String(), c.now().Format(time.RFC1123Z), messageID(from.Address))
+	w.Write(msg)
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("smtp: %w", err)
+	}
+	return sc.Quit()
+}
+
+// messageID returns a new Message-ID in the sender's domain.
+func messageID(from string) string {
+	b := make([]byte, 12)
+	rand.Read(b)
+	domain := "localhost"
+	if _, d, ok := strings.Cut(from, "@"); ok {
+		domain = d
+	}
+	return "<" + hex.EncodeToString(b) + "@" + domain + ">"
+}
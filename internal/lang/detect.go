@@ -0,0 +1,131 @@
+// Package lang guesses the language of short texts like email subjects and
+// card titles, so notifications can use a template in the same language.
+package lang
+
+import (
+	"strings"
+	"unicode"
+)
+
+// minWords is how many stopword hits a Latin-script text needs before it is
+// given a language; shorter texts are too ambiguous.
+const minWords = 2
+
+// stopwords are frequent short words that rarely appear in other languages.
+var stopwords = map[string][]string{
+	"en": {"the", "and", "is", "are", "you", "your", "for", "with", "this", "that", "have", "was", "not", "please", "will", "from", "our", "of", "to"},
+	"de": {"der", "die", "das", "und", "ist", "nicht", "sie", "ich", "mit", "für", "auf", "ein", "eine", "bitte", "wir", "ihre", "von", "zu", "den"},
+	"fr": {"le", "la", "les", "et", "est", "vous", "votre", "pour", "avec", "une", "des", "nous", "pas", "merci", "dans", "sur", "du", "au"},
+	"es": {"el", "los", "las", "y", "es", "usted", "su", "para", "con", "una", "por", "que", "gracias", "del", "en", "no", "está", "hola"},
+	"it": {"il", "gli", "e", "è", "per", "con", "una", "non", "che", "grazie", "della", "sono", "questo", "ciao", "del", "di"},
+	"pt": {"o", "os", "as", "e", "é", "você", "para", "com", "uma", "não", "obrigado", "obrigada", "do", "da", "em", "seu", "sua"},
+	"nl": {"de", "het", "een", "en", "is", "niet", "je", "jij", "u", "van", "voor", "met", "op", "dank", "wij", "zijn"},
+	"pl": {"i", "w", "nie", "się", "na", "jest", "z", "że", "do", "dziękuję", "proszę", "pan", "pani", "to"},
+}
+
+var stopwordLangs = func() map[string][]string {
+	m := map[string][]string{}
+	for lang, words := range stopwords {
+		for _, w := range words {
+			m[w] = append(m[w], lang)
+		}
+	}
+	return m
+}()
+
+// Detect returns the ISO 639-1 code of text's language, or "" when unsure.
+// Non-Latin scripts decide on their own (Cyrillic is "ru", or "uk" with
+// Ukrainian letters); Latin-script text is scored by common words.
+func Detect(text string) string {
+	if l := detectScript(text); l != "" {
+		return l
+	}
+	return detectLatin(text)
+}
+
+func detectScript(text string) string {
+	counts := map[string]int{}
+	letters := 0
+	ukrainian := false
+	for _, r := range text {
+		if !unicode.IsLetter(r) {
+			continue
+		}
+		letters++
+		switch {
+		case unicode.Is(unicode.Cyrillic, r):
+			counts["cyrillic"]++
+			if strings.ContainsRune("іїєґІЇЄҐ", r) {
+				ukrainian = true
+			}
+		case unicode.Is(unicode.Greek, r):
+			counts["el"]++
+		case unicode.Is(unicode.Hebrew, r):
+			counts["he"]++
+		case unicode.Is(unicode.Arabic, r):
+			counts["ar"]++
+		case unicode.Is(unicode.Hangul, r):
+			counts["ko"]++
+		case unicode.Is(unicode.Hiragana, r), unicode.Is(unicode.Katakana, r):
+			counts["ja"]++
+		case unicode.Is(unicode.Han, r):
+			counts["han"]++
+		case unicode.Is(unicode.Thai, r):
+			counts["th"]++
+		case unicode.Is(unicode.Devanagari, r):
+			counts["hi"]++
+		}
+	}
+	best, bestN := "", 0
+	for script, n := range counts {
+		if n > bestN || (n == bestN && script < best) {
+			best, bestN = script, n
+		}
+	}
+	// Non-Latin letters must be at least half the text; brand names and
+	// quoted words in another script do not count
+	if bestN == 0 || bestN*2 < letters {
+		return ""
+	}
+	switch best {
+	case "cyrillic":
+		if ukrainian {
+			return "uk"
+		}
+		return "ru"
+	case "han":
+		// Japanese mixes kanji with kana
+		if counts["ja"] > 0 {
+			return "ja"
+		}
+		return "zh"
+	case "ja":
+		return "ja"
+	}
+	return best
+}
+
+func detectLatin(text string) string {
+	scores := map[string]int{}
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && r != '\''
+	})
+	for _, w := range words {
+		for _, l := range stopwordLangs[w] {
+			scores[l]++
+		}
+	}
+	best, bestN, tie := "", 0, false
+	for l, n := range scores {
+		switch {
+		case n > bestN:
+			best, bestN, tie = l, n, false
+		case n == bestN:
+			tie = true
+		}
+	}
+	if bestN < minWords || tie {
+		return ""
+	}
+	return best
+}
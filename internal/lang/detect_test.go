@@ -0,0 +1,34 @@
+package lang
+
+import "testing"
+
+func TestDetect(t *testing.T) {
+	tests := []struct {
+		text string
+		want string
+	}{
+		{"Your invoice for October is ready to download", "en"},
+		{"Please review the attached contract and let me know", "en"},
+		{"Ihre Rechnung ist da, bitte prüfen Sie die Details", "de"},
+		{"Votre facture est disponible, merci pour votre confiance", "fr"},
+		{"Hola, gracias por su pedido con nosotros", "es"},
+		{"Grazie per il tuo ordine, questo è il riepilogo", "it"},
+		{"Obrigado pelo seu pedido, você pode acompanhar a entrega", "pt"},
+		{"Bedankt voor je bestelling, het pakket is onderweg", "nl"},
+		{"Счёт за октябрь готов к оплате", "ru"},
+		{"Рахунок за жовтень готовий, дякуємо", "uk"},
+		{"Ο λογαριασμός σας είναι έτοιμος", "el"},
+		{"您的订单已发货", "zh"},
+		{"ご注文ありがとうございます", "ja"},
+		{"주문해 주셔서 감사합니다", "ko"},
+		{"Deploy failed", ""},
+		{"", ""},
+		{"Re: Meeting — встреча в пятницу", "ru"},
+		{"Invoice from Яндекс for your account and the team", "en"},
+	}
+	for _, tt := range tests {
+		if got := Detect(tt.text); got != tt.want {
+			t.Errorf("Detect(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
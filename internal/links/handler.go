@@ -0,0 +1,104 @@
+package links
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+)
+
+// Handler serves /api/links for the agent to maintain card ↔ PR links.
+type Handler struct {
+	store *Store
+}
+
+func NewHandler(store *Store) *Handler {
+	return &Handler{store: store}
+}
+
+// RegisterRoutes adds the link routes to the mux.
+func (h *Handler) RegisterRoutes(mux *http.ServeMux) {
+	mux.HandleFunc("/api/links", h.handleLinks)
+}
+
+func jsonResponse(w http.ResponseWriter, data any) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(data)
+}
+
+func jsonError(w http.ResponseWriter, msg string, code int) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(map[string]string{"error": msg})
+}
+
+func queryFrom(r *http.Request) (Query, error) {
+	q := Query{
+		CardID: r.URL.Query().Get("card_id"),
+		Repo:   r.URL.Query().Get("repo"),
+		Branch: r.URL.Query().Get("branch"),
+	}
+	if pr := r.URL.Query().Get("pr"); pr != "" {
+		n, err := strconv.Atoi(pr)
+		if err != nil || n <= 0 {
+			return Query{}, strconv.ErrSyntax
+		}
+		q.PR = n
+	}
+	return q, nil
+}
+
+// handleLinks routes:
+//
+//	GET    /api/links?card_id=...|repo=...&pr=...|branch=...
+//	POST   /api/links  {"card_id","card_name","list","repo","pr","branch"}
+//	DELETE /api/links?card_id=...|repo=...&pr=...|branch=...
+func (h *Handler) handleLinks(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		q, err := queryFrom(r)
+		if err != nil {
+			jsonError(w, "pr must be a positive number", http.StatusBadRequest)
+			return
+		}
+		links := h.store.Find(q)
+		if links == nil {
+			links = []Link{}
+		}
+		jsonResponse(w, map[string]any{"links": links})
+	case http.MethodPost:
+		var l Link
+		if err := json.NewDecoder(r.Body).Decode(&l); err != nil {
+			jsonError(w, "invalid JSON", http.StatusBadRequest)
+			return
+		}
+		saved, err := h.store.Put(l)
+		if errors.Is(err, ErrInvalidLink) {
+			jsonError(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err != nil {
+			jsonError(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		jsonResponse(w, map[string]any{"link": saved})
+	case http.MethodDelete:
+		q, err := queryFrom(r)
+		if err != nil {
+			jsonError(w, "pr must be a positive number", http.StatusBadRequest)
+			return
+		}
+		n, err := h.store.Delete(q)
+		if errors.Is(err, ErrEmptyQuery) {
+			jsonError(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err != nil {
+			jsonError(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		jsonResponse(w, map[string]any{"deleted": n})
+	default:
+		jsonError(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
@@ -0,0 +1,66 @@
+package links
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func serve(h *Handler, method, path, body string) *httptest.ResponseRecorder {
+	mux := http.NewServeMux()
+	h.RegisterRoutes(mux)
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(method, path, strings.NewReader(body)))
+	return rec
+}
+
+func TestHandler_Links(t *testing.T) {
+	s, _ := NewStore("")
+	h := NewHandler(s)
+
+	rec := serve(h, "POST", "/api/links", `{"card_id":"c1","card_name":"Fix login","repo":"acme/api","pr":42,"branch":"fix-login"}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("POST: expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	rec = serve(h, "GET", "/api/links?repo=acme/api&pr=42", "")
+	var resp struct {
+		Links []Link `json:"links"`
+	}
+	json.NewDecoder(rec.Body).Decode(&resp)
+	if len(resp.Links) != 1 || resp.Links[0].CardID != "c1" || resp.Links[0].Branch != "fix-login" {
+		t.Fatalf("GET: unexpected links %+v", resp.Links)
+	}
+
+	rec = serve(h, "GET", "/api/links?card_id=none", "")
+	if !strings.Contains(rec.Body.String(), `"links":[]`) {
+		t.Errorf("expected empty list, got %s", rec.Body.String())
+	}
+
+	rec = serve(h, "DELETE", "/api/links?card_id=c1", "")
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"deleted":1`) {
+		t.Errorf("DELETE: unexpected response %d %s", rec.Code, rec.Body.String())
+	}
+}
+
+func TestHandler_LinksErrors(t *testing.T) {
+	h := NewHandler(func() *Store { s, _ := NewStore(""); return s }())
+	tests := []struct {
+		method, path, body string
+		want               int
+	}{
+		{"POST", "/api/links", `{"card_id":"c1"}`, http.StatusBadRequest},
+		{"POST", "/api/links", `not json`, http.StatusBadRequest},
+		{"GET", "/api/links?pr=abc", "", http.StatusBadRequest},
+		{"DELETE", "/api/links?pr=-1", "", http.StatusBadRequest},
+		{"DELETE", "/api/links", "", http.StatusBadRequest},
+		{"PUT", "/api/links", "", http.StatusMethodNotAllowed},
+	}
+	for _, tt := range tests {
+		if rec := serve(h, tt.method, tt.path, tt.body); rec.Code != tt.want {
+			t.Errorf("%s %s: expected %d, got %d", tt.method, tt.path, tt.want, rec.Code)
+		}
+	}
+}
@@ -0,0 +1,179 @@
+// Package links stores the agent's Trello card ↔ GitHub pull request/branch
+// mapping so GitHub jobs can include the linked card without re-deriving it.
+package links
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/katalabut/openclaw-relay/internal/atomicfile"
+)
+
+// Link ties a Trello card to a pull request number and/or branch in a repository.
+type Link struct {
+	CardID    string    `json:"card_id"`
+	CardName  string    `json:"card_name,omitempty"`
+	List      string    `json:"list,omitempty"`
+	Repo      string    `json:"repo"`
+	PR        int       `json:"pr,omitempty"`
+	Branch    string    `json:"branch,omitempty"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+var (
+	// ErrInvalidLink is returned by Put for links missing card_id, repo, or both pr and branch.
+	ErrInvalidLink = errors.New("card_id, repo and pr or branch are required")
+	// ErrEmptyQuery is returned by Delete when no field is set.
+	ErrEmptyQuery = errors.New("card_id, repo, pr or branch is required")
+)
+
+// Query selects links; empty fields match anything, but at least one must be set.
+type Query struct {
+	CardID string
+	Repo   string
+	PR     int
+	Branch string
+}
+
+func (q Query) empty() bool {
+	return q.CardID == "" && q.Repo == "" && q.PR == 0 && q.Branch == ""
+}
+
+func (q Query) match(l Link) bool {
+	return (q.CardID == "" || l.CardID == q.CardID) &&
+		(q.Repo == "" || strings.EqualFold(l.Repo, q.Repo)) &&
+		(q.PR == 0 || l.PR == q.PR) &&
+		(q.Branch == "" || l.Branch == q.Branch)
+}
+
+// Store persists links to a JSON file.
+type Store struct {
+	mu       sync.RWMutex
+	filePath string
+	links    []Link
+}
+
+// NewStore opens (or creates) the link store at filePath.
+// An empty filePath keeps links in memory only.
+func NewStore(filePath string) (*Store, error) {
+	s := &Store{filePath: filePath}
+	if filePath == "" {
+		return s, nil
+	}
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return s, nil
+		}
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &s.links); err != nil {
+		return nil, fmt.Errorf("parse links: %w", err)
+	}
+	return s, nil
+}
+
+func (s *Store) save() error {
+	if s.filePath == "" {
+		return nil
+	}
+	return atomicfile.WriteJSON(s.filePath, s.links)
+}
+
+// Put adds a link, replacing an existing one for the same repo and PR (or, for
+// branch-only links, the same repo and branch).
+func (s *Store) Put(l Link) (Link, error) {
+	if l.CardID == "" || l.Repo == "" || (l.PR == 0 && l.Branch == "") {
+		return Link{}, ErrInvalidLink
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	l.UpdatedAt = time.Now().UTC()
+	for i, old := range s.links {
+		if !strings.EqualFold(old.Repo, l.Repo) {
+			continue
+		}
+		if (l.PR != 0 && old.PR == l.PR) || (l.PR == 0 && old.PR == 0 && old.Branch == l.Branch) {
+			s.links[i] = l
+			return l, s.save()
+		}
+	}
+	s.links = append(s.links, l)
+	return l, s.save()
+}
+
+// Find returns links matching q, most recently updated first.
+func (s *Store) Find(q Query) []Link {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var out []Link
+	for i := len(s.links) - 1; i >= 0; i-- {
+		if q.match(s.links[i]) {
+			out = append(out, s.links[i])
+		}
+	}
+	return out
+}
+
+// Lookup returns the card linked to a repo's pull request, falling back to the branch.
+func (s *Store) Lookup(repo string, pr int, branch string) (Link, bool) {
+	if repo == "" {
+		return Link{}, false
+	}
+	if pr != 0 {
+		if found := s.Find(Query{Repo: repo, PR: pr}); len(found) > 0 {
+			return found[0], true
+		}
+	}
+	if branch != "" {
+		if found := s.Find(Query{Repo: repo, Branch: branch}); len(found) > 0 {
+			return found[0], true
+		}
+	}
+	return Link{}, false
+}
+
+// Delete removes links matching q and returns how many were removed.
+func (s *Store) Delete(q Query) (int, error) {
+	if q.empty() {
+		return 0, ErrEmptyQuery
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	kept := s.links[:0]
+	for _, l := range s.links {
+		if !q.match(l) {
+			kept = append(kept, l)
+		}
+	}
+	n := len(s.links) - len(kept)
+	s.links = kept
+	if n == 0 {
+		return 0, nil
+	}
+	return n, s.save()
+}
+
+// SetList records the card's current list on all of its links, e.g. after a
+// Trello card_moved event. It reports whether any link changed.
+func (s *Store) SetList(cardID, list string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	changed := false
+	for i := range s.links {
+		if s.links[i].CardID == cardID && s.links[i].List != list {
+			s.links[i].List = list
+			s.links[i].UpdatedAt = time.Now().UTC()
+			changed = true
+		}
+	}
+	if !changed {
+		return false, nil
+	}
+	return true, s.save()
+}
@@ -0,0 +1,101 @@
+package links
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestStore_PutLookupPersist(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "links.json")
+	s, err := NewStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Put(Link{CardID: "card1", CardName: "Fix login", Repo: "acme/api", PR: 42, Branch: "fix-login"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Put(Link{CardID: "card2", Repo: "acme/web", Branch: "feature-x"}); err != nil {
+		t.Fatal(err)
+	}
+	// Re-linking the same PR replaces the card
+	if _, err := s.Put(Link{CardID: "card3", Repo: "ACME/api", PR: 42}); err != nil {
+		t.Fatal(err)
+	}
+
+	if l, ok := s.Lookup("acme/api", 42, ""); !ok || l.CardID != "card3" {
+		t.Errorf("Lookup by PR = %+v, %v", l, ok)
+	}
+	if l, ok := s.Lookup("acme/web", 7, "feature-x"); !ok || l.CardID != "card2" {
+		t.Errorf("Lookup by branch fallback = %+v, %v", l, ok)
+	}
+	if _, ok := s.Lookup("acme/web", 0, "main"); ok {
+		t.Error("expected no link for unlinked branch")
+	}
+	if _, ok := s.Lookup("", 42, ""); ok {
+		t.Error("expected no link without a repo")
+	}
+
+	info, err := os.Stat(path)
+	if err != nil || info.Mode().Perm() != 0600 {
+		t.Fatalf("expected 0600 links file, got %v %v", info, err)
+	}
+	reopened, err := NewStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := reopened.Find(Query{Repo: "acme/api"}); len(got) != 1 || got[0].CardID != "card3" {
+		t.Errorf("expected persisted link, got %+v", got)
+	}
+}
+
+func TestStore_InvalidAndDelete(t *testing.T) {
+	s, _ := NewStore("")
+	for _, l := range []Link{{Repo: "acme/api", PR: 1}, {CardID: "c", PR: 1}, {CardID: "c", Repo: "acme/api"}} {
+		if _, err := s.Put(l); !errors.Is(err, ErrInvalidLink) {
+			t.Errorf("Put(%+v): expected ErrInvalidLink, got %v", l, err)
+		}
+	}
+	s.Put(Link{CardID: "c1", Repo: "acme/api", PR: 1})
+	s.Put(Link{CardID: "c1", Repo: "acme/api", PR: 2})
+	s.Put(Link{CardID: "c2", Repo: "acme/api", PR: 3})
+
+	if _, err := s.Delete(Query{}); !errors.Is(err, ErrEmptyQuery) {
+		t.Errorf("expected ErrEmptyQuery, got %v", err)
+	}
+	if n, err := s.Delete(Query{CardID: "c1"}); err != nil || n != 2 {
+		t.Errorf("Delete = %d, %v; want 2", n, err)
+	}
+	if n, _ := s.Delete(Query{CardID: "missing"}); n != 0 {
+		t.Errorf("expected nothing deleted, got %d", n)
+	}
+	if got := s.Find(Query{Repo: "acme/api"}); len(got) != 1 || got[0].CardID != "c2" {
+		t.Errorf("unexpected remaining links: %+v", got)
+	}
+}
+
+func TestStore_SetList(t *testing.T) {
+	s, _ := NewStore("")
+	s.Put(Link{CardID: "c1", Repo: "acme/api", PR: 1, List: "Ready"})
+	if changed, _ := s.SetList("c1", "In Progress"); !changed {
+		t.Error("expected list change")
+	}
+	if changed, _ := s.SetList("c1", "In Progress"); changed {
+		t.Error("expected no change for the same list")
+	}
+	if changed, _ := s.SetList("other", "Done"); changed {
+		t.Error("expected no change for an unlinked card")
+	}
+	if l, _ := s.Lookup("acme/api", 1, ""); l.List != "In Progress" {
+		t.Errorf("expected updated list, got %q", l.List)
+	}
+}
+
+func TestNewStore_Corrupt(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "links.json")
+	os.WriteFile(path, []byte("{"), 0600)
+	if _, err := NewStore(path); err == nil {
+		t.Error("expected parse error")
+	}
+}
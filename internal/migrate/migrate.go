@@ -0,0 +1,105 @@
+// Package migrate upgrades on-disk state documents from the format an older
+// relay wrote to the one the running relay reads. A document is a JSON object
+// with a top-level "version"; files written before versioning have none and
+// count as version 0. Each Step raises the version by one.
+package migrate
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+)
+
+// Doc is a state document's top-level fields, left encoded so steps only
+// decode what they change and large numbers such as Gmail historyIds survive.
+type Doc map[string]json.RawMessage
+
+// Get decodes field into v. It reports false when the field is absent or null.
+func (d Doc) Get(field string, v any) (bool, error) {
+	raw, ok := d[field]
+	if !ok || string(raw) == "null" {
+		return false, nil
+	}
+	if err := json.Unmarshal(raw, v); err != nil {
+		return false, fmt.Errorf("%s: %w", field, err)
+	}
+	return true, nil
+}
+
+// Set encodes v into field.
+func (d Doc) Set(field string, v any) error {
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("%s: %w", field, err)
+	}
+	d[field] = raw
+	return nil
+}
+
+// Step upgrades a document by one version.
+type Step struct {
+	Name string // logged when the step runs
+	Up   func(Doc) error
+}
+
+// Schema is the version history of one kind of state file. Steps[i] upgrades
+// version i to i+1, so the current version is len(Steps). Steps are only ever
+// appended: a released step must keep working on the files it was written for.
+type Schema struct {
+	Name  string // e.g. "tokens", used in logs and errors
+	Steps []Step
+}
+
+// Current returns the version documents are upgraded to.
+func (s Schema) Current() int {
+	return len(s.Steps)
+}
+
+// Version reads a document's version; 0 when it has none.
+func Version(data []byte) (int, error) {
+	var v struct {
+		Version int `json:"version"`
+	}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return 0, err
+	}
+	return v.Version, nil
+}
+
+// Apply upgrades data to the current version and stamps it. It returns data
+// unchanged, and false, when it is already current. A document from a newer
+// relay is an error, so a rollback never rewrites state it can't read.
+func (s Schema) Apply(data []byte) ([]byte, bool, error) {
+	from, err := Version(data)
+	if err != nil {
+		return nil, false, fmt.Errorf("%s: %w", s.Name, err)
+	}
+	if from > s.Current() {
+		return nil, false, fmt.Errorf("%s: version %d is newer than this relay supports (%d)", s.Name, from, s.Current())
+	}
+	if from == s.Current() {
+		return data, false, nil
+	}
+	var doc Doc
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, false, fmt.Errorf("%s: %w", s.Name, err)
+	}
+	if doc == nil {
+		doc = Doc{}
+	}
+	for v := from; v < s.Current(); v++ {
+		step := s.Steps[v]
+		if err := step.Up(doc); err != nil {
+			return nil, false, fmt.Errorf("%s: migrate to version %d (%s): %w", s.Name, v+1, step.Name, err)
+		}
+		log.Printf("Migrated %s state to version %d: %s", s.Name, v+1, step.Name)
+	}
+	if err := doc.Set("version", s.Current()); err != nil {
+		return nil, false, err
+	}
+	out, err := json.Marshal(doc)
+	if err != nil {
+		return nil, false, fmt.Errorf("%s: %w", s.Name, err)
+	}
+	return out, true, nil
+}
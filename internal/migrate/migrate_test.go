@@ -0,0 +1,68 @@
+package migrate
+
+import (
+	"strings"
+	"testing"
+)
+
+func testSchema() Schema {
+	return Schema{Name: "test", Steps: []Step{
+		{Name: "rename id", Up: func(d Doc) error {
+			var id uint64
+			if ok, err := d.Get("id", &id); err != nil || !ok {
+				return err
+			}
+			delete(d, "id")
+			return d.Set("history_id", id)
+		}},
+		{Name: "default interval", Up: func(d Doc) error {
+			if _, ok := d["interval"]; !ok {
+				return d.Set("interval", "1m")
+			}
+			return nil
+		}},
+	}}
+}
+
+func TestApply(t *testing.T) {
+	tests := []struct {
+		name, in, want string
+		changed        bool
+		err            string
+	}{
+		{"unversioned", `{"id":18446744073709551615}`, `{"history_id":18446744073709551615,"interval":"1m","version":2}`, true, ""},
+		{"from version 1", `{"version":1,"history_id":7,"interval":"5m"}`, `{"history_id":7,"interval":"5m","version":2}`, true, ""},
+		{"current", `{"version":2, "history_id":7}`, `{"version":2, "history_id":7}`, false, ""},
+		{"empty object", `{}`, `{"interval":"1m","version":2}`, true, ""},
+		{"newer", `{"version":3}`, "", false, "version 3 is newer than this relay supports (2)"},
+		{"not an object", `[1]`, "", false, "test:"},
+		{"bad field", `{"id":"x"}`, "", false, "migrate to version 1 (rename id): id:"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, changed, err := testSchema().Apply([]byte(tt.in))
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Fatalf("expected error containing %q, got %v", tt.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != tt.want || changed != tt.changed {
+				t.Errorf("got %s (changed=%v), want %s (changed=%v)", got, changed, tt.want, tt.changed)
+			}
+		})
+	}
+}
+
+func TestSchemaWithoutSteps(t *testing.T) {
+	s := Schema{Name: "empty"}
+	if _, changed, err := s.Apply([]byte(`{"a":1}`)); err != nil || changed {
+		t.Errorf("unversioned document under an empty schema: changed=%v err=%v", changed, err)
+	}
+	if _, _, err := s.Apply([]byte(`{"version":1}`)); err == nil {
+		t.Error("expected an error for a document newer than the schema")
+	}
+}
@@ -0,0 +1,238 @@
+// Package notion reads pages from the Notion API so webhook rules can see
+// property values, which Notion's webhook events do not carry.
+package notion
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Version is the Notion-Version header the client speaks.
+const Version = "2022-06-28"
+
+// Client authenticates with an internal integration token.
+type Client struct {
+	token   string
+	baseURL string
+	HTTP    *http.Client
+}
+
+func NewClient(token, apiURL string) *Client {
+	baseURL := strings.TrimRight(apiURL, "/")
+	if baseURL == "" {
+		baseURL = "https://api.notion.com"
+	}
+	return &Client{
+		token:   token,
+		baseURL: baseURL,
+		HTTP:    &http.Client{Timeout: 15 * time.Second},
+	}
+}
+
+// APIError is a non-2xx response from Notion.
+type APIError struct {
+	Status int
+	Body   string
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("notion api: status %d: %s", e.Status, e.Body)
+}
+
+// Page is a Notion page with its properties flattened to strings.
+type Page struct {
+	ID         string
+	URL        string
+	DatabaseID string            // parent database, empty for other parents
+	Title      string            // value of the title property
+	Properties map[string]string // property name -> value
+	PropertyID map[string]string // unescaped property ID -> name, to resolve updated_properties
+}
+
+type rawPage struct {
+	ID     string `json:"id"`
+	URL    string `json:"url"`
+	Parent struct {
+		Type       string `json:"type"`
+		DatabaseID string `json:"database_id"`
+	} `json:"parent"`
+	Properties map[string]rawProperty `json:"properties"`
+}
+
+type richText struct {
+	PlainText string `json:"plain_text"`
+}
+
+type named struct {
+	Name string `json:"name"`
+}
+
+type rawProperty struct {
+	ID          string     `json:"id"`
+	Type        string     `json:"type"`
+	Title       []richText `json:"title"`
+	RichText    []richText `json:"rich_text"`
+	Select      *named     `json:"select"`
+	Status      *named     `json:"status"`
+	MultiSelect []named    `json:"multi_select"`
+	People      []named    `json:"people"`
+	Checkbox    bool       `json:"checkbox"`
+	Number      *float64   `json:"number"`
+	Date        *struct {
+		Start string `json:"start"`
+	} `json:"date"`
+	URL            string `json:"url"`
+	Email          string `json:"email"`
+	PhoneNumber    string `json:"phone_number"`
+	CreatedTime    string `json:"created_time"`
+	LastEditedTime string `json:"last_edited_time"`
+	UniqueID       *struct {
+		Prefix string `json:"prefix"`
+		Number int    `json:"number"`
+	} `json:"unique_id"`
+	Formula *struct {
+		Type    string   `json:"type"`
+		String  string   `json:"string"`
+		Number  *float64 `json:"number"`
+		Boolean bool     `json:"boolean"`
+	} `json:"formula"`
+}
+
+// value renders a property as the string rules compare against: option names
+// for selects and statuses, comma-separated names for multi-selects and people,
+// plain text for text, "true"/"false" for checkboxes and the start for dates.
+func (p rawProperty) value() string {
+	switch p.Type {
+	case "title":
+		return joinText(p.Title)
+	case "rich_text":
+		return joinText(p.RichText)
+	case "select":
+		if p.Select != nil {
+			return p.Select.Name
+		}
+	case "status":
+		if p.Status != nil {
+			return p.Status.Name
+		}
+	case "multi_select":
+		return joinNames(p.MultiSelect)
+	case "people":
+		return joinNames(p.People)
+	case "checkbox":
+		return strconv.FormatBool(p.Checkbox)
+	case "number":
+		return formatNumber(p.Number)
+	case "date":
+		if p.Date != nil {
+			return p.Date.Start
+		}
+	case "url":
+		return p.URL
+	case "email":
+		return p.Email
+	case "phone_number":
+		return p.PhoneNumber
+	case "created_time":
+		return p.CreatedTime
+	case "last_edited_time":
+		return p.LastEditedTime
+	case "unique_id":
+		if p.UniqueID != nil {
+			if p.UniqueID.Prefix != "" {
+				return fmt.Sprintf("%s-%d", p.UniqueID.Prefix, p.UniqueID.Number)
+			}
+			return strconv.Itoa(p.UniqueID.Number)
+		}
+	case "formula":
+		if f := p.Formula; f != nil {
+			switch f.Type {
+			case "string":
+				return f.String
+			case "number":
+				return formatNumber(f.Number)
+			case "boolean":
+				return strconv.FormatBool(f.Boolean)
+			}
+		}
+	}
+	return ""
+}
+
+func joinText(parts []richText) string {
+	var b strings.Builder
+	for _, p := range parts {
+		b.WriteString(p.PlainText)
+	}
+	return b.String()
+}
+
+func joinNames(items []named) string {
+	names := make([]string, len(items))
+	for i, n := range items {
+		names[i] = n.Name
+	}
+	return strings.Join(names, ", ")
+}
+
+func formatNumber(n *float64) string {
+	if n == nil {
+		return ""
+	}
+	return strconv.FormatFloat(*n, 'f', -1, 64)
+}
+
+// GetPage fetches a page and flattens its properties.
+func (c *Client) GetPage(ctx context.Context, pageID string) (*Page, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.baseURL+"/v1/pages/"+url.PathEscape(pageID), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+c.token)
+	req.Header.Set("Notion-Version", Version)
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := c.HTTP.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode >= 300 {
+		return nil, &APIError{Status: resp.StatusCode, Body: strings.TrimSpace(string(body))}
+	}
+	var raw rawPage
+	if err := json.Unmarshal(body, &raw); err != nil {
+		return nil, fmt.Errorf("decode page: %w", err)
+	}
+
+	page := &Page{
+		ID:         raw.ID,
+		URL:        raw.URL,
+		Properties: make(map[string]string, len(raw.Properties)),
+		PropertyID: make(map[string]string, len(raw.Properties)),
+	}
+	if raw.Parent.Type == "database_id" {
+		page.DatabaseID = raw.Parent.DatabaseID
+	}
+	for name, p := range raw.Properties {
+		v := p.value()
+		page.Properties[name] = v
+		id := p.ID
+		if unescaped, err := url.PathUnescape(id); err == nil {
+			id = unescaped
+		}
+		page.PropertyID[id] = name
+		if p.Type == "title" {
+			page.Title = v
+		}
+	}
+	return page, nil
+}
@@ -0,0 +1,72 @@
+package notion
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+const pageJSON = `{
+  "id": "page-1",
+  "url": "https://www.notion.so/Fix-login-page1",
+  "parent": {"type": "database_id", "database_id": "db-1"},
+  "properties": {
+    "Name":     {"id": "title", "type": "title", "title": [{"plain_text": "Fix "}, {"plain_text": "login"}]},
+    "Status":   {"id": "XGe%40", "type": "status", "status": {"name": "In Review"}},
+    "Priority": {"id": "p1", "type": "select", "select": null},
+    "Tags":     {"id": "t1", "type": "multi_select", "multi_select": [{"name": "api"}, {"name": "auth"}]},
+    "Owner":    {"id": "o1", "type": "people", "people": [{"name": "Alice"}]},
+    "Done":     {"id": "d1", "type": "checkbox", "checkbox": true},
+    "Points":   {"id": "n1", "type": "number", "number": 3.5},
+    "Due":      {"id": "du", "type": "date", "date": {"start": "2026-03-01"}},
+    "Notes":    {"id": "rt", "type": "rich_text", "rich_text": [{"plain_text": "see PR"}]},
+    "Link":     {"id": "u1", "type": "url", "url": "https://example.com"},
+    "Ticket":   {"id": "id1", "type": "unique_id", "unique_id": {"prefix": "OPS", "number": 7}},
+    "Score":    {"id": "f1", "type": "formula", "formula": {"type": "number", "number": 10}},
+    "Related":  {"id": "r1", "type": "relation", "relation": [{"id": "x"}]}
+  }
+}`
+
+func TestGetPage(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/pages/page-1" {
+			http.NotFound(w, r)
+			return
+		}
+		if r.Header.Get("Authorization") != "Bearer secret_token" || r.Header.Get("Notion-Version") != Version {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Write([]byte(pageJSON))
+	}))
+	defer srv.Close()
+
+	page, err := NewClient("secret_token", srv.URL).GetPage(context.Background(), "page-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if page.Title != "Fix login" || page.DatabaseID != "db-1" || page.URL == "" {
+		t.Errorf("unexpected page: %+v", page)
+	}
+	want := map[string]string{
+		"Name": "Fix login", "Status": "In Review", "Priority": "", "Tags": "api, auth", "Owner": "Alice",
+		"Done": "true", "Points": "3.5", "Due": "2026-03-01", "Notes": "see PR", "Link": "https://example.com",
+		"Ticket": "OPS-7", "Score": "10", "Related": "",
+	}
+	for name, v := range want {
+		if page.Properties[name] != v {
+			t.Errorf("%s = %q, want %q", name, page.Properties[name], v)
+		}
+	}
+	if page.PropertyID["XGe@"] != "Status" {
+		t.Errorf("expected property ID map, got %v", page.PropertyID)
+	}
+
+	_, err = NewClient("other", srv.URL).GetPage(context.Background(), "page-1")
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.Status != http.StatusUnauthorized {
+		t.Errorf("expected 401 APIError, got %v", err)
+	}
+}
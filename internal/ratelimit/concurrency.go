@@ -0,0 +1,56 @@
+package ratelimit
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Concurrency caps in-flight requests per source so a flood from one provider
+// cannot starve the others. Sources without a limit are not restricted.
+type Concurrency struct {
+	slots map[string]chan struct{}
+	wait  time.Duration
+}
+
+// NewConcurrency creates per-source limits. A request waits up to wait for a free
+// slot before being rejected with 503.
+func NewConcurrency(limits map[string]int, wait time.Duration) *Concurrency {
+	c := &Concurrency{slots: make(map[string]chan struct{}, len(limits)), wait: wait}
+	for source, n := range limits {
+		if n > 0 {
+			c.slots[source] = make(chan struct{}, n)
+		}
+	}
+	return c
+}
+
+// InFlight returns the number of requests currently being processed for source.
+func (c *Concurrency) InFlight(source string) int {
+	return len(c.slots[source])
+}
+
+// Wrap limits next to the configured concurrency for source.
+func (c *Concurrency) Wrap(source string, next http.Handler) http.Handler {
+	slots, ok := c.slots[source]
+	if !ok {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		timer := time.NewTimer(c.wait)
+		defer timer.Stop()
+		select {
+		case slots <- struct{}{}:
+		case <-timer.C:
+			log.Printf("Concurrency: %s at limit (%d in flight), rejecting %s", source, cap(slots), r.URL.Path)
+			w.Header().Set("Retry-After", strconv.Itoa(int(c.wait.Seconds())+1))
+			http.Error(w, "too many in-flight requests", http.StatusServiceUnavailable)
+			return
+		case <-r.Context().Done():
+			return
+		}
+		defer func() { <-slots }()
+		next.ServeHTTP(w, r)
+	})
+}
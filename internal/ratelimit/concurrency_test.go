@@ -0,0 +1,93 @@
+package ratelimit
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestConcurrency_LimitsPerSource(t *testing.T) {
+	c := NewConcurrency(map[string]int{"trello": 1}, 20*time.Millisecond)
+	release := make(chan struct{})
+	started := make(chan struct{})
+	blocking := c.Wrap("trello", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-release
+	}))
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		blocking.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("POST", "/webhook/trello", nil))
+	}()
+	<-started
+	if c.InFlight("trello") != 1 {
+		t.Errorf("expected 1 in flight, got %d", c.InFlight("trello"))
+	}
+
+	// Second trello request times out waiting for a slot
+	rec := httptest.NewRecorder()
+	blocking.ServeHTTP(rec, httptest.NewRequest("POST", "/webhook/trello", nil))
+	if rec.Code != http.StatusServiceUnavailable || rec.Header().Get("Retry-After") == "" {
+		t.Errorf("expected 503 with Retry-After, got %d", rec.Code)
+	}
+
+	// Other sources are unaffected
+	ok := c.Wrap("github", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	rec = httptest.NewRecorder()
+	ok.ServeHTTP(rec, httptest.NewRequest("POST", "/webhook/github", nil))
+	if rec.Code != http.StatusOK {
+		t.Errorf("expected unlimited source to pass, got %d", rec.Code)
+	}
+
+	close(release)
+	wg.Wait()
+	if c.InFlight("trello") != 0 {
+		t.Errorf("expected slot to be released, got %d in flight", c.InFlight("trello"))
+	}
+}
+
+func TestConcurrency_WaitsForSlot(t *testing.T) {
+	c := NewConcurrency(map[string]int{"slack": 1}, time.Second)
+	h := c.Wrap("slack", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(10 * time.Millisecond)
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	var wg sync.WaitGroup
+	codes := make([]int, 3)
+	for i := range codes {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, httptest.NewRequest("POST", "/webhook/slack", nil))
+			codes[i] = rec.Code
+		}(i)
+	}
+	wg.Wait()
+	for i, code := range codes {
+		if code != http.StatusOK {
+			t.Errorf("request %d: expected queued request to succeed, got %d", i, code)
+		}
+	}
+}
+
+func TestConcurrency_ReleasesOnPanic(t *testing.T) {
+	c := NewConcurrency(map[string]int{"jira": 1}, 10*time.Millisecond)
+	h := c.Wrap("jira", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic("boom")
+	}))
+	func() {
+		defer func() { recover() }()
+		h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("POST", "/webhook/jira", nil))
+	}()
+	if c.InFlight("jira") != 0 {
+		t.Error("expected slot to be released after panic")
+	}
+}
@@ -0,0 +1,124 @@
+package ratelimit
+
+import (
+	"container/list"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/katalabut/openclaw-relay/internal/atomicfile"
+)
+
+// DefaultDeliveries is how many delivery IDs are remembered by default.
+const DefaultDeliveries = 5000
+
+// deliveriesFlush is how long a new ID may wait before the file is rewritten.
+const deliveriesFlush = time.Second
+
+// Deliveries remembers recent webhook delivery IDs (GitHub's
+// X-GitHub-Delivery, Trello's action ID) so a redelivery never creates a
+// second job, however late it comes and across restarts. Unlike Limiter it
+// keys on the delivery, not the event, and has no time window: it is an LRU
+// of fixed size persisted to a JSON file, written at most once a second.
+type Deliveries struct {
+	mu    sync.Mutex
+	size  int
+	order *list.List // of string, most recent first
+	index map[string]*list.Element
+	file  *atomicfile.Debounced
+}
+
+// NewDeliveries loads the IDs saved at path. An empty path keeps them in
+// memory only.
+func NewDeliveries(path string, size int) (*Deliveries, error) {
+	if size <= 0 {
+		size = DefaultDeliveries
+	}
+	d := &Deliveries{size: size, order: list.New(), index: map[string]*list.Element{}}
+	d.file = atomicfile.NewDebounced(path, deliveriesFlush, d.snapshot)
+	if path == "" {
+		return d, nil
+	}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return d, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read deliveries: %w", err)
+	}
+	var ids []string // oldest first
+	if err := json.Unmarshal(data, &ids); err != nil {
+		return nil, fmt.Errorf("parse deliveries: %w", err)
+	}
+	for _, id := range ids {
+		d.add(id)
+	}
+	return d, nil
+}
+
+// Seen reports whether id was recorded before. A nil Deliveries has seen
+// nothing.
+func (d *Deliveries) Seen(id string) bool {
+	if d == nil || id == "" {
+		return false
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	el, ok := d.index[id]
+	if ok {
+		d.order.MoveToFront(el)
+	}
+	return ok
+}
+
+// Record remembers id as handled. The file is written within a second; call
+// Flush before exiting.
+func (d *Deliveries) Record(id string) {
+	if d == nil || id == "" {
+		return
+	}
+	d.mu.Lock()
+	d.add(id)
+	d.mu.Unlock()
+	d.file.Mark()
+}
+
+// Flush writes IDs recorded since the last write.
+func (d *Deliveries) Flush() error {
+	if d == nil {
+		return nil
+	}
+	return d.file.Flush()
+}
+
+// Len returns the number of remembered IDs.
+func (d *Deliveries) Len() int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.order.Len()
+}
+
+func (d *Deliveries) add(id string) {
+	if _, ok := d.index[id]; ok {
+		return
+	}
+	d.index[id] = d.order.PushFront(id)
+	for d.order.Len() > d.size {
+		oldest := d.order.Back()
+		d.order.Remove(oldest)
+		delete(d.index, oldest.Value.(string))
+	}
+}
+
+// snapshot returns the IDs oldest first, as the file keeps them.
+func (d *Deliveries) snapshot() any {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	ids := make([]string, 0, d.order.Len())
+	for el := d.order.Back(); el != nil; el = el.Prev() {
+		ids = append(ids, el.Value.(string))
+	}
+	return ids
+}
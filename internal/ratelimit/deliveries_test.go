@@ -0,0 +1,92 @@
+package ratelimit
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDeliveries_SeenAndEviction(t *testing.T) {
+	d, err := NewDeliveries("", 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d.Seen("a") || d.Seen("b") {
+		t.Fatal("first deliveries should not be seen")
+	}
+	if d.Seen("a") {
+		t.Fatal("a delivery is seen only once recorded")
+	}
+	d.Record("a")
+	d.Record("b")
+	if !d.Seen("a") {
+		t.Error("expected redelivery of a to be seen")
+	}
+	// a was just used, so c evicts b
+	d.Record("c")
+	if d.Len() != 2 {
+		t.Errorf("expected 2 IDs, got %d", d.Len())
+	}
+	if !d.Seen("a") {
+		t.Error("expected a to survive eviction")
+	}
+	if d.Seen("b") {
+		t.Error("expected b to be evicted")
+	}
+	d.Record("")
+	if d.Seen("") {
+		t.Error("an empty ID is never a duplicate")
+	}
+
+	var none *Deliveries
+	none.Record("a")
+	if none.Seen("a") || none.Flush() != nil {
+		t.Error("nil Deliveries should see nothing")
+	}
+}
+
+func TestDeliveries_Persisted(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "data", "deliveries.json")
+	d, err := NewDeliveries(path, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	d.Record("github:1")
+	d.Record("trello:2")
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Fatal("expected the write deferred")
+	}
+	if err := d.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("expected 0600, got %v", info.Mode().Perm())
+	}
+
+	reloaded, err := NewDeliveries(path, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reloaded.Seen("github:1") || !reloaded.Seen("trello:2") {
+		t.Error("expected IDs to survive a restart")
+	}
+
+	// A smaller size keeps the most recent IDs
+	small, err := NewDeliveries(path, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !small.Seen("trello:2") || small.Seen("github:1") {
+		t.Error("expected only the newest ID kept")
+	}
+
+	os.WriteFile(path, []byte("{"), 0600)
+	if _, err := NewDeliveries(path, 10); err == nil {
+		t.Error("expected a corrupt file to fail")
+	}
+}
@@ -10,7 +10,7 @@ const maxEntries = 10000
 
 type Limiter struct {
 	mu   sync.Mutex
-	seen map[string]time.Time
+	seen map[string]time.Time // key -> end of its window
 	ttl  time.Duration
 }
 
@@ -20,13 +20,25 @@ func New(ctx context.Context, ttl time.Duration) *Limiter {
 	return l
 }
 
+// Allow reports whether key is outside the default window, and starts a new
+// window for it if so.
 func (l *Limiter) Allow(key string) bool {
+	return l.AllowWithin(key, l.ttl)
+}
+
+// AllowWithin is Allow with a window of ttl, for sources and rules that set
+// their own rate_limit. ttl <= 0 uses the default window.
+func (l *Limiter) AllowWithin(key string, ttl time.Duration) bool {
+	if ttl <= 0 {
+		ttl = l.ttl
+	}
 	l.mu.Lock()
 	defer l.mu.Unlock()
-	if last, ok := l.seen[key]; ok && time.Since(last) < l.ttl {
+	now := time.Now()
+	if until, ok := l.seen[key]; ok && now.Before(until) {
 		return false
 	}
-	l.seen[key] = time.Now()
+	l.seen[key] = now.Add(ttl)
 	if len(l.seen) > maxEntries {
 		l.evictOldest()
 	}
@@ -56,8 +68,9 @@ func (l *Limiter) cleanup(ctx context.Context) {
 			return
 		case <-ticker.C:
 			l.mu.Lock()
+			now := time.Now()
 			for k, v := range l.seen {
-				if time.Since(v) > l.ttl {
+				if !now.Before(v) {
 					delete(l.seen, k)
 				}
 			}
@@ -30,6 +30,25 @@ func TestAllow_AfterTTL(t *testing.T) {
 	}
 }
 
+func TestAllowWithin(t *testing.T) {
+	l := New(context.Background(), time.Minute)
+	l.AllowWithin("ci", 20*time.Millisecond)
+	if l.AllowWithin("ci", 20*time.Millisecond) {
+		t.Error("second call within the short window should be denied")
+	}
+	time.Sleep(30 * time.Millisecond)
+	if !l.AllowWithin("ci", 20*time.Millisecond) {
+		t.Error("call after the short window should be allowed")
+	}
+
+	// Zero falls back to the default window
+	l.AllowWithin("card", 0)
+	time.Sleep(30 * time.Millisecond)
+	if l.Allow("card") {
+		t.Error("expected the default window for a zero ttl")
+	}
+}
+
 func TestAllow_DifferentKeys(t *testing.T) {
 	l := New(context.Background(), time.Minute)
 	if !l.Allow("key1") {
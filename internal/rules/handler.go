@@ -0,0 +1,135 @@
+package rules
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+
+	"github.com/katalabut/openclaw-relay/internal/config"
+	"github.com/katalabut/openclaw-relay/internal/versions"
+)
+
+// maxDocumentBytes caps an imported document.
+const maxDocumentBytes = 4 << 20
+
+// Handler serves /api/rules. Export reads the running config; import
+// rewrites the config file, which takes effect on the next restart like a
+// version rollback.
+type Handler struct {
+	source []byte
+	path   string
+	store  *versions.Store
+}
+
+// NewHandler exports the rules of source, the running config file content;
+// imports are written to path and recorded in store.
+func NewHandler(source []byte, path string, store *versions.Store) *Handler {
+	return &Handler{source: source, path: path, store: store}
+}
+
+// RegisterRoutes adds the rules routes to the mux.
+func (h *Handler) RegisterRoutes(mux *http.ServeMux) {
+	mux.HandleFunc("/api/rules/export", h.handleExport)
+	mux.HandleFunc("/api/rules/import", h.handleImport)
+}
+
+func jsonResponse(w http.ResponseWriter, code int, data any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(data)
+}
+
+func jsonError(w http.ResponseWriter, msg string, code int) {
+	jsonResponse(w, code, map[string]string{"error": msg})
+}
+
+func (h *Handler) handleExport(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		jsonError(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	doc, err := Export(h.source)
+	if err != nil {
+		jsonError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Disposition", `attachment; filename="relay-rules.json"`)
+	jsonResponse(w, http.StatusOK, doc)
+}
+
+// handleImport applies the posted document to the config file:
+//
+//	POST /api/rules/import[?mode=merge|replace][&overwrite=true][&dry_run=true]
+//
+// Conflicts answer 409 and a config that would not validate 422, both
+// leaving the file as it is.
+func (h *Handler) handleImport(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		jsonError(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	q := r.URL.Query()
+	var opts Options
+	switch q.Get("mode") {
+	case "", "merge":
+	case "replace":
+		opts.Replace = true
+	default:
+		jsonError(w, "mode must be merge or replace", http.StatusBadRequest)
+		return
+	}
+	opts.Overwrite, _ = strconv.ParseBool(q.Get("overwrite"))
+	dryRun, _ := strconv.ParseBool(q.Get("dry_run"))
+
+	var doc Document
+	if err := json.NewDecoder(io.LimitReader(r.Body, maxDocumentBytes)).Decode(&doc); err != nil {
+		jsonError(w, "invalid document: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if h.path == "" {
+		jsonError(w, "config path unknown", http.StatusConflict)
+		return
+	}
+	current, err := os.ReadFile(h.path)
+	if err != nil {
+		jsonError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	out, report, err := Import(current, &doc, opts)
+	switch {
+	case errors.Is(err, ErrConflict):
+		jsonResponse(w, http.StatusConflict, map[string]any{"error": err.Error(), "report": report})
+		return
+	case errors.Is(err, ErrInvalid):
+		jsonResponse(w, http.StatusUnprocessableEntity, map[string]any{"error": err.Error(), "report": report})
+		return
+	case err != nil:
+		jsonError(w, fmt.Sprintf("can't read the config file: %v", err), http.StatusInternalServerError)
+		return
+	}
+	if dryRun || !report.Changed() {
+		jsonResponse(w, http.StatusOK, map[string]any{"report": report, "dry_run": dryRun, "restart_required": false})
+		return
+	}
+
+	// The file on disk is snapshotted first, so the import can be rolled back
+	if _, _, err := h.store.Record(current, "on disk before rules import"); err != nil {
+		jsonError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if err := config.WriteFile(h.path, out); err != nil {
+		jsonError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	saved, _, err := h.store.Record(out, "rules import")
+	if err != nil {
+		jsonError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	saved.Content = ""
+	jsonResponse(w, http.StatusOK, map[string]any{"report": report, "version": saved, "restart_required": true})
+}
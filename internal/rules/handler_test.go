@@ -0,0 +1,88 @@
+package rules
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/katalabut/openclaw-relay/internal/versions"
+)
+
+func TestHandler(t *testing.T) {
+	const target = "gateway:\n  url: http://gateway:18789\ntrello:\n  rules:\n    - name: ready\n      list: Doing\n"
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte(target), 0640); err != nil {
+		t.Fatal(err)
+	}
+	store, _ := versions.NewStore("", 0)
+	mux := http.NewServeMux()
+	NewHandler([]byte(sourceConfig), path, store).RegisterRoutes(mux)
+	do := func(method, target, body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest(method, target, strings.NewReader(body)))
+		return rec
+	}
+
+	rec := do("GET", "/api/rules/export", "")
+	var doc Document
+	if err := json.NewDecoder(rec.Body).Decode(&doc); err != nil || rec.Code != http.StatusOK || len(doc.Sources) != 4 {
+		t.Fatalf("export: %d %v %+v", rec.Code, err, doc)
+	}
+	// Only trello applies to the target config
+	delete(doc.Sources, "gmail/me@example.com")
+	delete(doc.Sources, "generic_webhooks/ci")
+	data, _ := json.Marshal(doc)
+
+	for _, tt := range []struct {
+		method, target, body string
+		want                 int
+	}{
+		{"POST", "/api/rules/export", "", http.StatusMethodNotAllowed},
+		{"GET", "/api/rules/import", "", http.StatusMethodNotAllowed},
+		{"POST", "/api/rules/import", "{", http.StatusBadRequest},
+		{"POST", "/api/rules/import?mode=append", string(data), http.StatusBadRequest},
+		{"POST", "/api/rules/import", string(data), http.StatusConflict},
+		{"POST", "/api/rules/import", `{"version":1,"sources":{"trello":[{"name":"x","action":{"delay":"soon"}}]}}`, http.StatusUnprocessableEntity},
+		{"POST", "/api/rules/import?overwrite=true&dry_run=true", string(data), http.StatusOK},
+	} {
+		if rec := do(tt.method, tt.target, tt.body); rec.Code != tt.want {
+			t.Errorf("%s %s: got %d, want %d (%s)", tt.method, tt.target, rec.Code, tt.want, rec.Body)
+		}
+	}
+	if got, _ := os.ReadFile(path); string(got) != target || len(store.List()) != 0 {
+		t.Fatal("a failed or dry-run import must not touch the config file")
+	}
+
+	rec = do("POST", "/api/rules/import?overwrite=true", string(data))
+	var resp struct {
+		Report          Report           `json:"report"`
+		Version         versions.Version `json:"version"`
+		RestartRequired bool             `json:"restart_required"`
+	}
+	json.NewDecoder(rec.Body).Decode(&resp)
+	if rec.Code != http.StatusOK || resp.Report.Replaced != 1 || resp.Report.Added != 2 || !resp.RestartRequired || resp.Version.ID != 2 {
+		t.Fatalf("import: %d %+v", rec.Code, resp)
+	}
+	got, _ := os.ReadFile(path)
+	if !strings.Contains(string(got), "list: Ready") {
+		t.Errorf("config:\n%s", got)
+	}
+	if info, _ := os.Stat(path); info.Mode().Perm() != 0640 {
+		t.Errorf("mode = %v", info.Mode().Perm())
+	}
+	list := store.List()
+	if len(list) != 2 || list[0].Reason != "rules import" || list[1].Reason != "on disk before rules import" {
+		t.Errorf("versions = %+v", list)
+	}
+
+	// Without a config path nothing can be written
+	mux = http.NewServeMux()
+	NewHandler([]byte(sourceConfig), "", store).RegisterRoutes(mux)
+	if rec := do("POST", "/api/rules/import", string(data)); rec.Code != http.StatusConflict {
+		t.Errorf("no path: %d", rec.Code)
+	}
+}
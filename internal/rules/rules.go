@@ -0,0 +1,387 @@
+// Package rules exports the rules of every source as one JSON document and
+// imports such a document back into a config file, for sharing rule sets
+// between relay deployments.
+package rules
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/katalabut/openclaw-relay/internal/config"
+)
+
+// DocumentVersion is the version of the Document format.
+const DocumentVersion = 1
+
+// sections are the top-level config sections with a rules list, in export
+// order. Gmail accounts and generic webhooks hold their own lists, keyed
+// "gmail/<email>" and "generic_webhooks/<name>".
+var sections = []string{
+	"trello", "github", "slack", "discord", "notion", "jira", "sentry",
+	"asana", "bitbucket", "alertmanager", "calendar", "drive",
+}
+
+var (
+	// ErrConflict is returned by Import when the report has conflicts.
+	ErrConflict = errors.New("rules conflict with the config")
+	// ErrInvalid is returned by Import when the document is malformed or
+	// the config it would produce does not validate.
+	ErrInvalid = errors.New("invalid rules")
+)
+
+// Document holds the rules of each source as plain values, in the shape of
+// the config's YAML. Rule groups are kept as they are.
+type Document struct {
+	Version    int              `json:"version"`
+	ExportedAt time.Time        `json:"exported_at"`
+	Sources    map[string][]any `json:"sources"`
+}
+
+// Options control Import.
+type Options struct {
+	// Replace replaces each source's rules with the document's instead of
+	// merging them.
+	Replace bool
+	// Overwrite lets a merged rule replace a different rule of the same name
+	// instead of reporting a conflict.
+	Overwrite bool
+}
+
+// Report is what an import changed, or would change.
+type Report struct {
+	Added     int        `json:"added"`
+	Replaced  int        `json:"replaced"`
+	Removed   int        `json:"removed"`
+	Unchanged int        `json:"unchanged"`
+	Conflicts []Conflict `json:"conflicts,omitempty"`
+}
+
+// Changed reports whether the import changes any rule.
+func (r *Report) Changed() bool {
+	return r.Added+r.Replaced+r.Removed > 0
+}
+
+// Conflict is a rule, or a whole source, an import can't apply.
+type Conflict struct {
+	Source string `json:"source"`
+	Rule   string `json:"rule,omitempty"`
+	Reason string `json:"reason"`
+}
+
+// Export returns the rules in the config file content. It reads the raw
+// file, so ${VAR} placeholders and sealed values are exported as written.
+func Export(content []byte) (*Document, error) {
+	doc := &Document{Version: DocumentVersion, ExportedAt: time.Now().UTC(), Sources: map[string][]any{}}
+	root, err := config.ParseNode(content)
+	if err != nil {
+		return nil, err
+	}
+	top := root.Content[0]
+	add := func(source string, parent *yaml.Node) error {
+		list := value(parent, "rules")
+		if list == nil {
+			return nil
+		}
+		var rules []any
+		if err := list.Decode(&rules); err != nil {
+			return fmt.Errorf("%s rules: %w", source, err)
+		}
+		if len(rules) > 0 {
+			doc.Sources[source] = rules
+		}
+		return nil
+	}
+	for _, s := range sections {
+		if err := add(s, value(top, s)); err != nil {
+			return nil, err
+		}
+	}
+	for _, item := range items(value(value(top, "gmail"), "accounts")) {
+		if err := add("gmail/"+scalar(item, "email"), item); err != nil {
+			return nil, err
+		}
+	}
+	for _, item := range items(value(top, "generic_webhooks")) {
+		if err := add("generic_webhooks/"+scalar(item, "name"), item); err != nil {
+			return nil, err
+		}
+	}
+	// Rules must encode as JSON: YAML allows keys JSON has no form for
+	if _, err := json.Marshal(doc); err != nil {
+		return nil, err
+	}
+	return doc, nil
+}
+
+// Import applies doc to the config file content and returns the new
+// content, which is validated as a config. By default rules are merged:
+// a rule whose name is already in the source is unchanged when equal and a
+// conflict when different, unless opts.Overwrite; unnamed rules are added
+// unless an equal rule exists. With any conflict the content is not
+// changed and err is ErrConflict; the report lists them. The file is edited
+// with config.Edit, which keeps its comments but may change its formatting.
+func Import(content []byte, doc *Document, opts Options) ([]byte, *Report, error) {
+	if doc.Version != DocumentVersion {
+		return nil, nil, fmt.Errorf("%w: unsupported document version %d", ErrInvalid, doc.Version)
+	}
+	report := &Report{}
+	sources := make([]string, 0, len(doc.Sources))
+	for s := range doc.Sources {
+		sources = append(sources, s)
+	}
+	sortSources(sources)
+	out, err := config.Edit(content, func(top *yaml.Node) error {
+		for _, source := range sources {
+			parent, reason := find(top, source)
+			if parent == nil {
+				report.Conflicts = append(report.Conflicts, Conflict{Source: source, Reason: reason})
+				continue
+			}
+			if err := apply(parent, source, doc.Sources[source], opts, report); err != nil {
+				return err
+			}
+		}
+		if len(report.Conflicts) > 0 {
+			return ErrConflict
+		}
+		return nil
+	})
+	switch {
+	case errors.Is(err, ErrConflict):
+		return content, report, err
+	case errors.Is(err, config.ErrInvalid):
+		return nil, report, fmt.Errorf("%w: %v", ErrInvalid, err)
+	case err != nil:
+		return nil, nil, err
+	}
+	return out, report, nil
+}
+
+// apply merges or replaces the rules of one source under parent.
+func apply(parent *yaml.Node, source string, rules []any, opts Options, report *Report) error {
+	nodes := make([]*yaml.Node, len(rules))
+	keys := make([]string, len(rules))
+	for i, r := range rules {
+		if _, ok := r.(map[string]any); !ok {
+			return fmt.Errorf("%w: %s rule %d is not an object", ErrInvalid, source, i)
+		}
+		r = normalize(r)
+		var n yaml.Node
+		if err := n.Encode(r); err != nil {
+			return fmt.Errorf("%w: %s rule %d: %v", ErrInvalid, source, i, err)
+		}
+		nameFirst(&n)
+		nodes[i] = &n
+		keys[i] = canonical(r)
+	}
+	list := rulesNode(parent)
+
+	if opts.Replace {
+		report.Removed += len(list.Content)
+		report.Added += len(nodes)
+		list.Content = nodes
+		return nil
+	}
+
+	existing := make([]string, len(list.Content))
+	names := make([]string, len(list.Content))
+	for i, n := range list.Content {
+		var v any
+		if err := n.Decode(&v); err != nil {
+			return fmt.Errorf("%s rules: %w", source, err)
+		}
+		existing[i] = canonical(v)
+		names[i] = scalar(n, "name")
+	}
+	for i, r := range rules {
+		name, _ := r.(map[string]any)["name"].(string)
+		at := -1
+		for j := range existing {
+			if (name != "" && names[j] == name) || (name == "" && existing[j] == keys[i]) {
+				at = j
+				break
+			}
+		}
+		switch {
+		case at < 0:
+			list.Content = append(list.Content, nodes[i])
+			existing = append(existing, keys[i])
+			names = append(names, name)
+			report.Added++
+		case existing[at] == keys[i]:
+			report.Unchanged++
+		case opts.Overwrite:
+			list.Content[at] = nodes[i]
+			existing[at] = keys[i]
+			report.Replaced++
+		default:
+			report.Conflicts = append(report.Conflicts, Conflict{Source: source, Rule: name, Reason: "a different rule has this name"})
+		}
+	}
+	return nil
+}
+
+// nameFirst moves a rule's name to the top, where the config keeps it;
+// encoded maps are otherwise sorted by key.
+func nameFirst(m *yaml.Node) {
+	for i := 2; i+1 < len(m.Content); i += 2 {
+		if m.Content[i].Value == "name" {
+			pair := slices.Clone(m.Content[i : i+2])
+			m.Content = slices.Insert(slices.Delete(m.Content, i, i+2), 0, pair...)
+			return
+		}
+	}
+}
+
+// find returns the mapping holding source's rules list, creating a missing
+// top-level section; or nil and the reason it can't.
+func find(top *yaml.Node, source string) (*yaml.Node, string) {
+	kind, name, _ := strings.Cut(source, "/")
+	switch kind {
+	case "gmail":
+		for _, item := range items(value(value(top, "gmail"), "accounts")) {
+			if scalar(item, "email") == name {
+				return item, ""
+			}
+		}
+		return nil, "no such account in gmail.accounts"
+	case "generic_webhooks":
+		for _, item := range items(value(top, "generic_webhooks")) {
+			if scalar(item, "name") == name {
+				return item, ""
+			}
+		}
+		return nil, "no such webhook in generic_webhooks"
+	}
+	if slices.Contains(sections, source) {
+		return child(top, source), ""
+	}
+	return nil, "unknown source"
+}
+
+// value returns the value of key in mapping m, following aliases, or nil.
+func value(m *yaml.Node, key string) *yaml.Node {
+	for m != nil && m.Kind == yaml.AliasNode {
+		m = m.Alias
+	}
+	if m == nil || m.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(m.Content); i += 2 {
+		if m.Content[i].Value == key {
+			v := m.Content[i+1]
+			for v.Kind == yaml.AliasNode {
+				v = v.Alias
+			}
+			return v
+		}
+	}
+	return nil
+}
+
+// child returns the mapping at key in m, adding an empty one when missing
+// or null.
+func child(m *yaml.Node, key string) *yaml.Node {
+	if v := value(m, key); v != nil && v.Kind == yaml.MappingNode {
+		return v
+	}
+	v := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+	for i := 0; i+1 < len(m.Content); i += 2 {
+		if m.Content[i].Value == key {
+			m.Content[i+1] = v
+			return v
+		}
+	}
+	set(m, key, v)
+	return v
+}
+
+// rulesNode returns the rules sequence in m, adding an empty one when
+// missing. An aliased list is copied, so the anchor's users are unaffected.
+func rulesNode(m *yaml.Node) *yaml.Node {
+	for i := 0; i+1 < len(m.Content); i += 2 {
+		if m.Content[i].Value != "rules" {
+			continue
+		}
+		v := m.Content[i+1]
+		if v.Kind == yaml.SequenceNode {
+			return v
+		}
+		list := &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq"}
+		if target := value(m, "rules"); target != nil && target.Kind == yaml.SequenceNode {
+			list.Content = append(list.Content, target.Content...)
+		}
+		m.Content[i+1] = list
+		return list
+	}
+	list := &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq"}
+	set(m, "rules", list)
+	return list
+}
+
+func set(m *yaml.Node, key string, v *yaml.Node) {
+	m.Content = append(m.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: key}, v)
+}
+
+func items(seq *yaml.Node) []*yaml.Node {
+	if seq == nil || seq.Kind != yaml.SequenceNode {
+		return nil
+	}
+	return seq.Content
+}
+
+func scalar(m *yaml.Node, key string) string {
+	if v := value(m, key); v != nil && v.Kind == yaml.ScalarNode {
+		return v.Value
+	}
+	return ""
+}
+
+// normalize turns the whole floats JSON decodes numbers to back into
+// integers, so "delay: 30" stays 30 rather than 30.0 and compares equal.
+func normalize(v any) any {
+	switch v := v.(type) {
+	case map[string]any:
+		for k, e := range v {
+			v[k] = normalize(e)
+		}
+	case []any:
+		for i, e := range v {
+			v[i] = normalize(e)
+		}
+	case float64:
+		if v == math.Trunc(v) && math.Abs(v) < 1<<53 {
+			return int64(v)
+		}
+	}
+	return v
+}
+
+// canonical is the JSON form of a rule, for comparing rules; JSON sorts
+// map keys.
+func canonical(v any) string {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(data)
+}
+
+// sortSources orders sources like the config: sections first, then gmail
+// accounts and generic webhooks by name.
+func sortSources(sources []string) {
+	rank := func(s string) string {
+		if i := slices.Index(sections, s); i >= 0 {
+			return fmt.Sprintf("0%02d", i)
+		}
+		return "1" + s
+	}
+	slices.SortFunc(sources, func(a, b string) int { return strings.Compare(rank(a), rank(b)) })
+}
@@ -0,0 +1,204 @@
+package rules
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+)
+
+const sourceConfig = `gateway:
+  url: http://gateway:18789
+trello:
+  rules:
+    - name: ready
+      list: Ready
+      action:
+        delay: 30
+    - when: "board == 'Ops'"
+      rules:
+        - name: urgent
+          label: urgent
+alertmanager:
+  rules:
+    - status: firing
+gmail:
+  accounts:
+    - email: me@example.com
+      rules:
+        - name: invoices
+          match:
+            from: ["*@billing.example"]
+generic_webhooks:
+  - name: ci
+    secret: ${CI_SECRET}
+    rules:
+      - name: failed
+        condition: "status == 'failed'"
+`
+
+// roundTrip exports src and decodes the JSON, as a file would be read back.
+func roundTrip(t *testing.T, src string) *Document {
+	t.Helper()
+	doc, err := Export([]byte(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := json.Marshal(doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out Document
+	if err := json.Unmarshal(data, &out); err != nil {
+		t.Fatal(err)
+	}
+	return &out
+}
+
+func TestExport(t *testing.T) {
+	doc := roundTrip(t, sourceConfig)
+	if doc.Version != DocumentVersion || doc.ExportedAt.IsZero() {
+		t.Errorf("header = %d %v", doc.Version, doc.ExportedAt)
+	}
+	var keys []string
+	for k := range doc.Sources {
+		keys = append(keys, k)
+	}
+	sortSources(keys)
+	if got := strings.Join(keys, " "); got != "trello alertmanager generic_webhooks/ci gmail/me@example.com" {
+		t.Errorf("sources = %s", got)
+	}
+	if len(doc.Sources["trello"]) != 2 {
+		t.Errorf("trello rules = %v", doc.Sources["trello"])
+	}
+	group := doc.Sources["trello"][1].(map[string]any)
+	if group["when"] != "board == 'Ops'" {
+		t.Errorf("group = %v", group)
+	}
+
+	if _, err := Export([]byte("- not a mapping\n")); err == nil {
+		t.Error("expected an error for a config that is not a mapping")
+	}
+}
+
+func TestImport_Merge(t *testing.T) {
+	doc := roundTrip(t, sourceConfig)
+	target := `gateway:
+  url: http://gateway:18789
+# Trello board automation
+trello:
+  rules:
+    - name: ready
+      list: Ready
+      action:
+        delay: 30
+gmail:
+  accounts:
+    - email: me@example.com
+generic_webhooks:
+  - name: ci
+`
+	out, report, err := Import([]byte(target), doc, Options{})
+	if err != nil {
+		t.Fatalf("import: %v (%+v)", err, report)
+	}
+	if report.Added != 4 || report.Unchanged != 1 || report.Replaced != 0 || !report.Changed() {
+		t.Errorf("report = %+v", report)
+	}
+	s := string(out)
+	for _, want := range []string{"# Trello board automation", "    - name: failed\n", "alertmanager:\n  rules:\n    - status: firing", "delay: 30\n"} {
+		if !strings.Contains(s, want) {
+			t.Errorf("config lacks %q:\n%s", want, s)
+		}
+	}
+	if strings.Count(s, "name: ready") != 1 {
+		t.Errorf("rule duplicated:\n%s", s)
+	}
+
+	// Importing again changes nothing
+	_, report, err = Import(out, doc, Options{})
+	if err != nil || report.Changed() || report.Unchanged != 5 {
+		t.Errorf("second import: %v %+v", err, report)
+	}
+}
+
+func TestImport_Conflicts(t *testing.T) {
+	doc := roundTrip(t, sourceConfig)
+	target := "gateway:\n  url: http://gateway:18789\ntrello:\n  rules:\n    - name: ready\n      list: Doing\n"
+
+	out, report, err := Import([]byte(target), doc, Options{})
+	if !errors.Is(err, ErrConflict) || string(out) != target {
+		t.Fatalf("err = %v", err)
+	}
+	var got []string
+	for _, c := range report.Conflicts {
+		got = append(got, c.Source+":"+c.Rule)
+	}
+	if strings.Join(got, " ") != "trello:ready generic_webhooks/ci: gmail/me@example.com:" {
+		t.Errorf("conflicts = %+v", report.Conflicts)
+	}
+
+	// Overwrite replaces the named rule; the unknown account and webhook
+	// still conflict
+	delete(doc.Sources, "gmail/me@example.com")
+	delete(doc.Sources, "generic_webhooks/ci")
+	out, report, err = Import([]byte(target), doc, Options{Overwrite: true})
+	if err != nil || report.Replaced != 1 || !strings.Contains(string(out), "list: Ready") || strings.Contains(string(out), "list: Doing") {
+		t.Errorf("overwrite: %v %+v\n%s", err, report, out)
+	}
+}
+
+func TestImport_Replace(t *testing.T) {
+	doc := &Document{Version: DocumentVersion, Sources: map[string][]any{
+		"trello": {map[string]any{"list": "Done", "name": "done", "action": map[string]any{"timeout": 60.0}}},
+	}}
+	target := "gateway:\n  url: http://gateway:18789\ntrello:\n  rules:\n    - name: ready\n      list: Ready\n    - name: doing\n      list: Doing\n"
+	out, report, err := Import([]byte(target), doc, Options{Replace: true})
+	if err != nil || report.Removed != 2 || report.Added != 1 {
+		t.Fatalf("replace: %v %+v", err, report)
+	}
+	// Name first, whole numbers kept as integers
+	if !strings.Contains(string(out), "rules:\n    - name: done\n      action:\n        timeout: 60\n      list: Done\n") {
+		t.Errorf("config:\n%s", out)
+	}
+}
+
+func TestImport_Invalid(t *testing.T) {
+	target := []byte("gateway:\n  url: http://gateway:18789\n")
+	tests := []struct {
+		name string
+		doc  *Document
+	}{
+		{"version", &Document{Version: 2}},
+		{"not an object", &Document{Version: DocumentVersion, Sources: map[string][]any{"trello": {"ready"}}}},
+		{"bad rule", &Document{Version: DocumentVersion, Sources: map[string][]any{"trello": {map[string]any{"name": "x", "action": map[string]any{"delay": "soon"}}}}}},
+	}
+	for _, tt := range tests {
+		if _, _, err := Import(target, tt.doc, Options{}); !errors.Is(err, ErrInvalid) {
+			t.Errorf("%s: err = %v", tt.name, err)
+		}
+	}
+
+	doc := &Document{Version: DocumentVersion, Sources: map[string][]any{"mailchimp": {map[string]any{"name": "x"}}}}
+	if _, report, err := Import(target, doc, Options{}); !errors.Is(err, ErrConflict) || report.Conflicts[0].Reason != "unknown source" {
+		t.Errorf("unknown source: %v %+v", err, report)
+	}
+}
+
+func TestImport_EmptyConfig(t *testing.T) {
+	doc := &Document{Version: DocumentVersion, Sources: map[string][]any{"drive": {map[string]any{"name": "contracts", "folders": []any{"f1"}}}}}
+	// A null section and no file at all both get the section created
+	for _, target := range []string{"", "gateway:\n  url: http://gateway:18789\ndrive:\n"} {
+		out, _, err := Import([]byte(target), doc, Options{})
+		if target == "" {
+			// Without a gateway the config doesn't validate
+			if !errors.Is(err, ErrInvalid) {
+				t.Errorf("empty config: %v", err)
+			}
+			continue
+		}
+		if err != nil || !strings.Contains(string(out), "drive:\n  rules:\n    - name: contracts\n") {
+			t.Errorf("null section: %v\n%s", err, out)
+		}
+	}
+}
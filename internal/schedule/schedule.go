@@ -0,0 +1,244 @@
+// Package schedule resolves when a delayed job should fire from a human-friendly
+// expression such as "next business day 9am" evaluated in a time zone.
+//
+// Supported expressions (case-insensitive):
+//
+//	90m, in 2h             relative Go duration
+//	9am, 17:30             next occurrence of the time of day
+//	tomorrow 9am           tomorrow at the time of day
+//	business day 9am       today if it is a weekday and the time is still ahead, else the next weekday
+//	next business day 9am  the first weekday after today
+//	monday 9am             next occurrence of the weekday (today if the time is still ahead)
+//	next monday 9am        the first such weekday after today
+//
+// An optional "at" before the time of day is ignored ("tomorrow at 9:30am").
+package schedule
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type dayKind int
+
+const (
+	dayNext         dayKind = iota // next occurrence of the time of day
+	dayTomorrow                    // tomorrow
+	dayBusiness                    // today or the next weekday
+	dayNextBusiness                // first weekday after today
+	dayWeekday                     // today or the next given weekday
+	dayNextWeekday                 // first given weekday after today
+)
+
+// Spec is a parsed schedule expression.
+type Spec struct {
+	isRelative bool // duration expression; the calendar fields are unused
+	relative   time.Duration
+	day        dayKind
+	weekday    time.Weekday
+	hour       int
+	minute     int
+}
+
+var weekdays = map[string]time.Weekday{
+	"sunday": time.Sunday, "monday": time.Monday, "tuesday": time.Tuesday, "wednesday": time.Wednesday,
+	"thursday": time.Thursday, "friday": time.Friday, "saturday": time.Saturday,
+}
+
+// Parse parses a schedule expression.
+func Parse(expr string) (Spec, error) {
+	s := strings.ToLower(strings.Join(strings.Fields(expr), " "))
+	if s == "" {
+		return Spec{}, fmt.Errorf("empty schedule")
+	}
+	if d, err := time.ParseDuration(strings.TrimPrefix(s, "in ")); err == nil {
+		if d < 0 {
+			return Spec{}, fmt.Errorf("schedule %q: negative duration", expr)
+		}
+		return Spec{isRelative: true, relative: d}, nil
+	}
+
+	var spec Spec
+	rest := s
+	switch {
+	case strings.HasPrefix(rest, "tomorrow "):
+		spec.day, rest = dayTomorrow, strings.TrimPrefix(rest, "tomorrow ")
+	case strings.HasPrefix(rest, "next business day "):
+		spec.day, rest = dayNextBusiness, strings.TrimPrefix(rest, "next business day ")
+	case strings.HasPrefix(rest, "business day "):
+		spec.day, rest = dayBusiness, strings.TrimPrefix(rest, "business day ")
+	default:
+		next := false
+		word := rest
+		if strings.HasPrefix(word, "next ") {
+			next, word = true, strings.TrimPrefix(word, "next ")
+		}
+		name, tail, _ := strings.Cut(word, " ")
+		if wd, ok := weekdays[name]; ok {
+			spec.day, spec.weekday, rest = dayWeekday, wd, tail
+			if next {
+				spec.day = dayNextWeekday
+			}
+		} else if next {
+			return Spec{}, fmt.Errorf("schedule %q: expected a weekday or \"business day\" after \"next\"", expr)
+		}
+	}
+
+	rest = strings.TrimPrefix(rest, "at ")
+	h, m, err := parseClock(rest)
+	if err != nil {
+		return Spec{}, fmt.Errorf("schedule %q: %w", expr, err)
+	}
+	spec.hour, spec.minute = h, m
+	return spec, nil
+}
+
+// parseClock parses "9am", "9:30pm", "09:30" or "17:00".
+func parseClock(s string) (hour, minute int, err error) {
+	if s == "" {
+		return 0, 0, fmt.Errorf("missing time of day")
+	}
+	suffix := ""
+	for _, x := range []string{"am", "pm"} {
+		if strings.HasSuffix(s, x) {
+			suffix, s = x, strings.TrimSpace(strings.TrimSuffix(s, x))
+		}
+	}
+	hs, ms, hasMinutes := strings.Cut(s, ":")
+	hour, err = strconv.Atoi(hs)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid time of day %q", s+suffix)
+	}
+	if hasMinutes {
+		if len(ms) != 2 {
+			return 0, 0, fmt.Errorf("invalid minutes in %q", s+suffix)
+		}
+		if minute, err = strconv.Atoi(ms); err != nil || minute > 59 {
+			return 0, 0, fmt.Errorf("invalid minutes in %q", s+suffix)
+		}
+	} else if suffix == "" {
+		return 0, 0, fmt.Errorf("ambiguous time of day %q (use 9am or 09:00)", s)
+	}
+	switch suffix {
+	case "":
+		if hour > 23 {
+			return 0, 0, fmt.Errorf("hour out of range in %q", s)
+		}
+	default:
+		if hour < 1 || hour > 12 {
+			return 0, 0, fmt.Errorf("hour out of range in %q", s+suffix)
+		}
+		hour %= 12
+		if suffix == "pm" {
+			hour += 12
+		}
+	}
+	return hour, minute, nil
+}
+
+// Next returns when the schedule fires after now, with calendar expressions
+// evaluated in loc.
+func (s Spec) Next(now time.Time, loc *time.Location) time.Time {
+	if s.isRelative {
+		return now.Add(s.relative)
+	}
+	local := now.In(loc)
+	at := func(offset int) time.Time {
+		return time.Date(local.Year(), local.Month(), local.Day()+offset, s.hour, s.minute, 0, 0, loc)
+	}
+	switch s.day {
+	case dayTomorrow:
+		return at(1)
+	case dayBusiness, dayNextBusiness:
+		first := 1
+		if s.day == dayBusiness {
+			first = 0
+		}
+		for i := first; ; i++ {
+			t := at(i)
+			if isWeekday(t.Weekday()) && t.After(now) {
+				return t
+			}
+		}
+	case dayWeekday, dayNextWeekday:
+		first := 1
+		if s.day == dayWeekday {
+			first = 0
+		}
+		for i := first; ; i++ {
+			t := at(i)
+			if t.Weekday() == s.weekday && t.After(now) {
+				return t
+			}
+		}
+	default:
+		if t := at(0); t.After(now) {
+			return t
+		}
+		return at(1)
+	}
+}
+
+func isWeekday(d time.Weekday) bool {
+	return d != time.Saturday && d != time.Sunday
+}
+
+// LoadLocation resolves an IANA zone name; empty means UTC.
+func LoadLocation(name string) (*time.Location, error) {
+	if name == "" {
+		return time.UTC, nil
+	}
+	return time.LoadLocation(name)
+}
+
+// ParseDuration parses a Go duration ("90m", "24h"), also accepting whole
+// days ("7d"), as TTLs, retentions and condition literals are written.
+func ParseDuration(s string) (time.Duration, error) {
+	if n, ok := strings.CutSuffix(s, "d"); ok {
+		days, err := strconv.Atoi(n)
+		if err != nil {
+			return 0, fmt.Errorf("invalid duration %q", s)
+		}
+		return time.Duration(days) * 24 * time.Hour, nil
+	}
+	return time.ParseDuration(s)
+}
+
+// DelaySeconds parses expr and returns the whole seconds from now until it fires
+// in time zone tz (rounded up, never negative).
+func DelaySeconds(expr, tz string, now time.Time) (int, error) {
+	spec, err := Parse(expr)
+	if err != nil {
+		return 0, err
+	}
+	loc, err := LoadLocation(tz)
+	if err != nil {
+		return 0, err
+	}
+	d := spec.Next(now, loc).Sub(now)
+	if d <= 0 {
+		return 0, nil
+	}
+	return int((d + time.Second - 1) / time.Second), nil
+}
+
+// ActionDelay returns the delay in seconds of a rule action's job: expr, when
+// set, resolved in tz (defaultTZ when tz is empty, e.g. server.timezone),
+// else delay. An expression that fails is logged and delay used instead.
+func ActionDelay(expr, tz, defaultTZ string, delay int) int {
+	if expr == "" {
+		return delay
+	}
+	if tz == "" {
+		tz = defaultTZ
+	}
+	secs, err := DelaySeconds(expr, tz, time.Now())
+	if err != nil {
+		log.Printf("Invalid action schedule %q, using delay %ds: %v", expr, delay, err)
+		return delay
+	}
+	return secs
+}
@@ -0,0 +1,109 @@
+package schedule
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNext(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Friday 2026-03-27 16:00 in Berlin (15:00 UTC)
+	friday := time.Date(2026, 3, 27, 16, 0, 0, 0, berlin)
+
+	tests := []struct {
+		expr string
+		now  time.Time
+		want time.Time
+	}{
+		{"90m", friday, friday.Add(90 * time.Minute)},
+		{"in 2h", friday, friday.Add(2 * time.Hour)},
+		{"5pm", friday, time.Date(2026, 3, 27, 17, 0, 0, 0, berlin)},
+		{"9am", friday, time.Date(2026, 3, 28, 9, 0, 0, 0, berlin)},
+		{"16:00", friday, time.Date(2026, 3, 28, 16, 0, 0, 0, berlin)},
+		{"tomorrow at 9:30am", friday, time.Date(2026, 3, 28, 9, 30, 0, 0, berlin)},
+		{"next business day 9am", friday, time.Date(2026, 3, 30, 9, 0, 0, 0, berlin)},
+		{"business day 5pm", friday, time.Date(2026, 3, 27, 17, 0, 0, 0, berlin)},
+		{"business day 9am", friday, time.Date(2026, 3, 30, 9, 0, 0, 0, berlin)},
+		{"Friday 6pm", friday, time.Date(2026, 3, 27, 18, 0, 0, 0, berlin)},
+		{"next friday 6pm", friday, time.Date(2026, 4, 3, 18, 0, 0, 0, berlin)},
+		{"monday 12am", friday, time.Date(2026, 3, 30, 0, 0, 0, 0, berlin)},
+		// DST starts in Berlin on 2026-03-29: 9am is still 9am local time
+		{"sunday 9am", friday, time.Date(2026, 3, 29, 9, 0, 0, 0, berlin)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			spec, err := Parse(tt.expr)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := spec.Next(tt.now, berlin); !got.Equal(tt.want) {
+				t.Errorf("Next() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParse_Errors(t *testing.T) {
+	for _, expr := range []string{"", "soon", "9", "25:00", "13pm", "9:5am", "next week 9am", "tomorrow", "-5m", "monday noon"} {
+		if _, err := Parse(expr); err == nil {
+			t.Errorf("Parse(%q): expected error", expr)
+		}
+	}
+}
+
+func TestDelaySeconds(t *testing.T) {
+	now := time.Date(2026, 3, 27, 8, 59, 59, 500_000_000, time.UTC)
+	got, err := DelaySeconds("9am", "", now)
+	if err != nil || got != 1 {
+		t.Errorf("DelaySeconds = %d, %v; want 1 (rounded up)", got, err)
+	}
+	// 9am New York is 13:00 UTC in March after the US DST switch
+	got, err = DelaySeconds("9am", "America/New_York", time.Date(2026, 3, 27, 12, 0, 0, 0, time.UTC))
+	if err != nil || got != 3600 {
+		t.Errorf("DelaySeconds = %d, %v; want 3600", got, err)
+	}
+	if _, err := DelaySeconds("9am", "Mars/Olympus", now); err == nil {
+		t.Error("expected unknown time zone error")
+	}
+	if _, err := DelaySeconds("whenever", "", now); err == nil {
+		t.Error("expected parse error")
+	}
+	if got, _ := DelaySeconds("0s", "", now); got != 0 {
+		t.Errorf("expected 0 for immediate schedule, got %d", got)
+	}
+}
+
+func TestActionDelay(t *testing.T) {
+	if got := ActionDelay("", "", "Europe/Berlin", 5); got != 5 {
+		t.Errorf("expected plain delay 5, got %d", got)
+	}
+	if got := ActionDelay("in 1h", "", "Europe/Berlin", 2); got != 3600 {
+		t.Errorf("expected 3600 for schedule, got %d", got)
+	}
+	if got := ActionDelay("someday", "", "Europe/Berlin", 2); got != 2 {
+		t.Errorf("expected fallback delay for invalid schedule, got %d", got)
+	}
+	if got := ActionDelay("9am", "Mars/Olympus", "", 2); got != 2 {
+		t.Errorf("expected fallback delay for invalid zone, got %d", got)
+	}
+	got := ActionDelay("tomorrow 9am", "Asia/Tokyo", "Europe/Berlin", 2)
+	if got <= 0 || got > 48*3600 {
+		t.Errorf("expected a delay within two days, got %d", got)
+	}
+}
+
+func TestParseDuration(t *testing.T) {
+	for in, want := range map[string]time.Duration{"90m": 90 * time.Minute, "24h": 24 * time.Hour, "7d": 7 * 24 * time.Hour, "0d": 0} {
+		if got, err := ParseDuration(in); err != nil || got != want {
+			t.Errorf("ParseDuration(%q) = %v, %v; want %v", in, got, err, want)
+		}
+	}
+	for _, in := range []string{"", "d", "1.5d", "1h2d", "week"} {
+		if _, err := ParseDuration(in); err == nil {
+			t.Errorf("ParseDuration(%q): expected an error", in)
+		}
+	}
+}
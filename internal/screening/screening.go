@@ -0,0 +1,166 @@
+// Package screening decides whether a mail attachment may be handed out
+// through the API or to an agent: a size cap, an extension denylist and,
+// when a clamd socket is configured, a ClamAV scan of the content.
+package screening
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"path"
+	"strings"
+	"time"
+)
+
+// Result statuses.
+const (
+	StatusPassed  = "passed"  // size and extension checks passed; content not scanned
+	StatusClean   = "clean"   // ClamAV scanned the content and found nothing
+	StatusBlocked = "blocked" // too large, denied extension, or malware found
+	StatusError   = "error"   // the scan could not complete
+)
+
+// DefaultDenyExtensions are executable and script types mail clients
+// commonly block.
+var DefaultDenyExtensions = []string{
+	".exe", ".scr", ".com", ".pif", ".bat", ".cmd", ".msi", ".cpl", ".hta",
+	".js", ".jse", ".vbs", ".vbe", ".wsf", ".ps1", ".jar", ".lnk",
+}
+
+// chunkSize is the INSTREAM chunk length; clamd's default StreamMaxLength
+// applies to the total, not to chunks.
+const chunkSize = 64 << 10
+
+// Result is the screening verdict surfaced in attachment metadata.
+type Result struct {
+	Status  string `json:"status"`
+	Reason  string `json:"reason,omitempty"`
+	Scanner string `json:"scanner,omitempty"` // "clamav" once the content was scanned
+}
+
+// Allowed reports whether the attachment may be exposed. Scan errors are
+// not: with a scanner configured, unscanned content stays back.
+func (r Result) Allowed() bool {
+	return r.Status == StatusPassed || r.Status == StatusClean
+}
+
+// Screener holds the screening policy. The zero value allows everything.
+type Screener struct {
+	MaxBytes       int64         // 0 = no size cap
+	DenyExtensions []string      // lower-case, with the leading dot
+	ClamAV         string        // clamd address: host:port, or a unix socket path
+	Timeout        time.Duration // per scan; 0 = 30s
+}
+
+// Check screens an attachment by name and size, without its content. A nil
+// Screener passes everything.
+func (s *Screener) Check(name string, size int64) Result {
+	if s == nil {
+		return Result{Status: StatusPassed}
+	}
+	if s.MaxBytes > 0 && size > s.MaxBytes {
+		return Result{Status: StatusBlocked, Reason: fmt.Sprintf("size %d exceeds limit %d", size, s.MaxBytes)}
+	}
+	if ext := extension(name); ext != "" {
+		for _, deny := range s.DenyExtensions {
+			if ext == deny {
+				return Result{Status: StatusBlocked, Reason: "extension " + ext + " is denied"}
+			}
+		}
+	}
+	return Result{Status: StatusPassed}
+}
+
+// Scan screens downloaded content: Check on its length, then ClamAV when
+// configured.
+func (s *Screener) Scan(ctx context.Context, name string, data []byte) Result {
+	res := s.Check(name, int64(len(data)))
+	if !res.Allowed() || s == nil || s.ClamAV == "" {
+		return res
+	}
+	found, err := s.clamScan(ctx, data)
+	if err != nil {
+		return Result{Status: StatusError, Reason: err.Error(), Scanner: "clamav"}
+	}
+	if found != "" {
+		return Result{Status: StatusBlocked, Reason: "malware: " + found, Scanner: "clamav"}
+	}
+	return Result{Status: StatusClean, Scanner: "clamav"}
+}
+
+// extension returns the lower-cased final extension. Trailing dots and
+// spaces are dropped first, since Windows ignores them ("a.exe. " runs as
+// a.exe).
+func extension(name string) string {
+	name = strings.TrimRight(strings.TrimSpace(name), ". ")
+	return strings.ToLower(path.Ext(name))
+}
+
+// clamScan streams data to clamd with INSTREAM and returns the signature
+// name if it found one.
+func (s *Screener) clamScan(ctx context.Context, data []byte) (string, error) {
+	timeout := s.Timeout
+	if timeout <= 0 {
+		timeout = 30 * time.Second
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	network := "tcp"
+	if strings.HasPrefix(s.ClamAV, "/") {
+		network = "unix"
+	}
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, network, s.ClamAV)
+	if err != nil {
+		return "", fmt.Errorf("clamav: %w", err)
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	if _, err := conn.Write([]byte("zINSTREAM\x00")); err != nil {
+		return "", fmt.Errorf("clamav: %w", err)
+	}
+	var size [4]byte
+	for len(data) > 0 {
+		n := min(len(data), chunkSize)
+		binary.BigEndian.PutUint32(size[:], uint32(n))
+		if _, err := conn.Write(size[:]); err != nil {
+			return "", fmt.Errorf("clamav: %w", err)
+		}
+		if _, err := conn.Write(data[:n]); err != nil {
+			return "", fmt.Errorf("clamav: %w", err)
+		}
+		data = data[n:]
+	}
+	binary.BigEndian.PutUint32(size[:], 0)
+	if _, err := conn.Write(size[:]); err != nil {
+		return "", fmt.Errorf("clamav: %w", err)
+	}
+
+	reply, err := io.ReadAll(conn)
+	if err != nil && len(reply) == 0 {
+		return "", fmt.Errorf("clamav: %w", err)
+	}
+	return parseReply(string(bytes.TrimRight(reply, "\x00\n")))
+}
+
+// parseReply reads "stream: OK", "stream: <signature> FOUND" or
+// "<message> ERROR".
+func parseReply(reply string) (string, error) {
+	reply = strings.TrimPrefix(reply, "stream: ")
+	switch {
+	case reply == "OK":
+		return "", nil
+	case strings.HasSuffix(reply, " FOUND"):
+		return strings.TrimSuffix(reply, " FOUND"), nil
+	case strings.HasSuffix(reply, " ERROR"):
+		return "", fmt.Errorf("clamav: %s", strings.TrimSuffix(reply, " ERROR"))
+	}
+	return "", fmt.Errorf("clamav: unexpected reply %q", reply)
+}
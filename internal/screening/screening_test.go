@@ -0,0 +1,122 @@
+package screening
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"io"
+	"net"
+	"path/filepath"
+	"testing"
+)
+
+func TestCheck(t *testing.T) {
+	s := &Screener{MaxBytes: 100, DenyExtensions: DefaultDenyExtensions}
+	tests := []struct {
+		name string
+		size int64
+		want string
+	}{
+		{"report.pdf", 10, StatusPassed},
+		{"report.pdf", 101, StatusBlocked},
+		{"setup.EXE", 10, StatusBlocked},
+		{"invoice.pdf.exe", 10, StatusBlocked},
+		{"invoice.exe. ", 10, StatusBlocked},
+		{"README", 10, StatusPassed},
+	}
+	for _, tt := range tests {
+		if got := s.Check(tt.name, tt.size); got.Status != tt.want {
+			t.Errorf("Check(%q, %d) = %+v, want %s", tt.name, tt.size, got, tt.want)
+		}
+	}
+
+	var zero Screener
+	if got := zero.Check("setup.exe", 1<<40); !got.Allowed() {
+		t.Errorf("zero screener should allow everything, got %+v", got)
+	}
+	var none *Screener
+	if got := none.Scan(context.Background(), "setup.exe", []byte("x")); !got.Allowed() {
+		t.Errorf("nil screener should allow everything, got %+v", got)
+	}
+}
+
+// fakeClamd answers one INSTREAM session per connection: FOUND when the
+// stream contains "EICAR", else OK.
+func fakeClamd(ln net.Listener) {
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		go func(conn net.Conn) {
+			defer conn.Close()
+			cmd := make([]byte, len("zINSTREAM\x00"))
+			if _, err := io.ReadFull(conn, cmd); err != nil || string(cmd) != "zINSTREAM\x00" {
+				conn.Write([]byte("UNKNOWN COMMAND\x00"))
+				return
+			}
+			var stream bytes.Buffer
+			for {
+				var size uint32
+				if err := binary.Read(conn, binary.BigEndian, &size); err != nil {
+					return
+				}
+				if size == 0 {
+					break
+				}
+				io.CopyN(&stream, conn, int64(size))
+			}
+			if bytes.Contains(stream.Bytes(), []byte("EICAR")) {
+				conn.Write([]byte("stream: Eicar-Test-Signature FOUND\x00"))
+				return
+			}
+			conn.Write([]byte("stream: OK\x00"))
+		}(conn)
+	}
+}
+
+func TestScan_ClamAV(t *testing.T) {
+	sock := filepath.Join(t.TempDir(), "clamd.sock")
+	ln, err := net.Listen("unix", sock)
+	if err != nil {
+		t.Skipf("unix sockets unavailable: %v", err)
+	}
+	defer ln.Close()
+	go fakeClamd(ln)
+
+	s := &Screener{MaxBytes: 1 << 20, DenyExtensions: DefaultDenyExtensions, ClamAV: sock}
+	ctx := context.Background()
+
+	// Larger than one chunk, so the stream is split
+	if got := s.Scan(ctx, "big.txt", bytes.Repeat([]byte("a"), chunkSize+10)); got.Status != StatusClean || got.Scanner != "clamav" {
+		t.Errorf("expected clean, got %+v", got)
+	}
+	got := s.Scan(ctx, "eicar.txt", []byte("X5O!P%@AP EICAR-STANDARD-ANTIVIRUS-TEST-FILE"))
+	if got.Status != StatusBlocked || got.Reason != "malware: Eicar-Test-Signature" {
+		t.Errorf("expected blocked, got %+v", got)
+	}
+	// Policy checks run before the content is sent
+	if got := s.Scan(ctx, "run.bat", []byte("EICAR")); got.Scanner != "" || got.Status != StatusBlocked {
+		t.Errorf("expected extension block without a scan, got %+v", got)
+	}
+
+	down := &Screener{ClamAV: filepath.Join(t.TempDir(), "missing.sock")}
+	if got := down.Scan(ctx, "a.txt", []byte("x")); got.Status != StatusError || got.Allowed() {
+		t.Errorf("expected error verdict when clamd is down, got %+v", got)
+	}
+}
+
+func TestParseReply(t *testing.T) {
+	if found, err := parseReply("stream: OK"); found != "" || err != nil {
+		t.Errorf("OK: %q %v", found, err)
+	}
+	if found, err := parseReply("stream: Win.Test FOUND"); found != "Win.Test" || err != nil {
+		t.Errorf("FOUND: %q %v", found, err)
+	}
+	if _, err := parseReply("INSTREAM size limit exceeded. ERROR"); err == nil {
+		t.Error("expected error reply to fail")
+	}
+	if _, err := parseReply("garbage"); err == nil {
+		t.Error("expected unexpected reply to fail")
+	}
+}
@@ -0,0 +1,179 @@
+package sealed
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// secretFields are the config keys whose values are secrets.
+var secretFields = map[string]bool{
+	"secret":             true,
+	"signing_secret":     true,
+	"client_secret":      true,
+	"token":              true,
+	"internal_token":     true,
+	"verification_token": true,
+	"api_token":          true,
+	"api_key":            true,
+	"key":                true, // server.api_keys[].key
+	"password":           true, // gmail.accounts[].imap
+}
+
+// Redacted stands in for secrets when there is no key to seal them with.
+const Redacted = `"[redacted]"`
+
+// envRef is a value taken from the environment, which holds no secret itself.
+var envRef = regexp.MustCompile(`^\$\{[^}]+\}$`)
+
+// SealConfig returns config file content with each secret value sealed with
+// key, or replaced by Redacted when key is nil. ${VAR} references and sealed
+// values are kept. The rest of the file, comments included, is unchanged.
+func SealConfig(content string, key []byte) (string, error) {
+	var root yaml.Node
+	if err := yaml.Unmarshal([]byte(content), &root); err != nil {
+		return "", err
+	}
+	var secrets []*yaml.Node
+	collectSecrets(&root, &secrets)
+	if len(secrets) == 0 {
+		return content, nil
+	}
+	display := make(map[*yaml.Node]string, len(secrets))
+	for _, n := range secrets {
+		display[n] = Redacted
+		if key != nil {
+			v, err := Seal(key, n.Value)
+			if err != nil {
+				return "", err
+			}
+			display[n] = v
+		}
+	}
+
+	// Replace each value where it stands, last first so earlier columns on
+	// the same line stay put
+	slices.SortFunc(secrets, func(a, b *yaml.Node) int {
+		if a.Line != b.Line {
+			return b.Line - a.Line
+		}
+		return b.Column - a.Column
+	})
+	lines := strings.Split(content, "\n")
+	for _, n := range secrets {
+		line := lines[n.Line-1]
+		start := n.Column - 1
+		end, ok := tokenEnd(line, start, n)
+		if !ok {
+			return reencode(&root, display)
+		}
+		lines[n.Line-1] = line[:start] + display[n] + line[end:]
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
+// collectSecrets appends the secret scalars under n.
+func collectSecrets(n *yaml.Node, out *[]*yaml.Node) {
+	if n.Kind == yaml.MappingNode {
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			k, v := n.Content[i], n.Content[i+1]
+			if secretFields[k.Value] && v.Kind == yaml.ScalarNode && v.Value != "" && !IsSealed(v.Value) && !envRef.MatchString(v.Value) {
+				*out = append(*out, v)
+			}
+		}
+	}
+	for _, c := range n.Content {
+		collectSecrets(c, out)
+	}
+}
+
+// tokenEnd returns where the scalar n starting at line[start] ends. It fails
+// for scalars it can't place on the line, such as block scalars.
+func tokenEnd(line string, start int, n *yaml.Node) (int, bool) {
+	if start < 0 || start >= len(line) || strings.IndexFunc(line[:start], func(r rune) bool { return r > 127 }) >= 0 {
+		return 0, false
+	}
+	switch n.Style {
+	case 0, yaml.TaggedStyle:
+		end := start + len(n.Value)
+		return end, end <= len(line) && line[start:end] == n.Value
+	case yaml.DoubleQuotedStyle:
+		for i := start + 1; i < len(line); i++ {
+			switch line[i] {
+			case '\\':
+				i++
+			case '"':
+				return i + 1, true
+			}
+		}
+	case yaml.SingleQuotedStyle:
+		for i := start + 1; i < len(line); i++ {
+			if line[i] == '\'' {
+				if i+1 < len(line) && line[i+1] == '\'' {
+					i++
+					continue
+				}
+				return i + 1, true
+			}
+		}
+	}
+	return 0, false
+}
+
+// reencode writes the whole document with the secrets replaced, for files
+// whose secrets can't be replaced in place.
+func reencode(root *yaml.Node, display map[*yaml.Node]string) (string, error) {
+	for n, v := range display {
+		n.Style = 0
+		n.Value = v
+		if v == Redacted {
+			n.Style = yaml.DoubleQuotedStyle
+			n.Value = strings.Trim(v, `"`)
+		}
+	}
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(root); err != nil {
+		return "", err
+	}
+	return buf.String(), enc.Close()
+}
+
+// OpenConfig opens the sealed values in a decoded config document in place.
+// keyHex is only called when the document has a sealed value.
+func OpenConfig(root *yaml.Node, keyHex func() string) error {
+	var (
+		key    []byte
+		keyErr error
+		loaded bool
+	)
+	var walk func(n *yaml.Node) error
+	walk = func(n *yaml.Node) error {
+		if n.Kind == yaml.ScalarNode && IsSealed(n.Value) {
+			if !loaded {
+				key, keyErr = ParseKey(keyHex())
+				loaded = true
+			}
+			if keyErr != nil {
+				return keyErr
+			}
+			v, err := Open(key, n.Value)
+			if err != nil {
+				return fmt.Errorf("line %d: %w", n.Line, err)
+			}
+			n.Value, n.Tag, n.Style = v, "!!str", 0
+		}
+		for _, c := range n.Content {
+			if err := walk(c); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return walk(root)
+}
@@ -0,0 +1,115 @@
+package sealed
+
+import (
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+const secretConfig = `server:
+  internal_token: "s3cret token"  # for the agent
+  api_keys:
+    - name: reader
+      key: 'it''s-a-key'
+github:
+  secret: ${GITHUB_SECRET}
+slack:
+  signing_secret: plainsecret
+trello:
+  api_key: ""
+  token: {a: 1}
+gateway:
+  url: http://gateway:18789
+gmail:
+  accounts:
+    - email: me@fastmail.example
+      imap:
+        password: imap-pass
+`
+
+func TestSealConfig(t *testing.T) {
+	out, err := SealConfig(secretConfig, testKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, secret := range []string{"s3cret token", "it''s-a-key", "plainsecret", "imap-pass"} {
+		if strings.Contains(out, secret) {
+			t.Errorf("%q left in:\n%s", secret, out)
+		}
+	}
+	for _, kept := range []string{"# for the agent", "secret: ${GITHUB_SECRET}", `api_key: ""`, "url: http://gateway:18789"} {
+		if !strings.Contains(out, kept) {
+			t.Errorf("%q changed:\n%s", kept, out)
+		}
+	}
+	if strings.Count(out, "\n") != strings.Count(secretConfig, "\n") {
+		t.Errorf("line count changed:\n%s", out)
+	}
+
+	// The sealed file loads back to the original values
+	var root yaml.Node
+	if err := yaml.Unmarshal([]byte(out), &root); err != nil {
+		t.Fatal(err)
+	}
+	if err := OpenConfig(&root, func() string { return strings.Repeat("ab", 32) }); err != nil {
+		t.Fatal(err)
+	}
+	var cfg struct {
+		Server struct {
+			InternalToken string                 `yaml:"internal_token"`
+			APIKeys       []struct{ Key string } `yaml:"api_keys"`
+		}
+		Slack struct {
+			SigningSecret string `yaml:"signing_secret"`
+		}
+	}
+	if err := root.Decode(&cfg); err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Server.InternalToken != "s3cret token" || cfg.Server.APIKeys[0].Key != "it's-a-key" || cfg.Slack.SigningSecret != "plainsecret" {
+		t.Errorf("round trip: %+v", cfg)
+	}
+
+	again, _ := SealConfig(out, testKey)
+	if again != out {
+		t.Error("expected sealing to be idempotent")
+	}
+
+	redacted, err := SealConfig(secretConfig, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(redacted, "plainsecret") || strings.Count(redacted, Redacted) != 4 {
+		t.Errorf("redacted:\n%s", redacted)
+	}
+}
+
+func TestSealConfig_BlockScalar(t *testing.T) {
+	out, err := SealConfig("github:\n  secret: |\n    multi\n    line\n", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(out, "multi") || !strings.Contains(out, `secret: "[redacted]"`) {
+		t.Errorf("block scalar:\n%s", out)
+	}
+}
+
+func TestOpenConfig_Errors(t *testing.T) {
+	sealedValue, _ := Seal(testKey, "x")
+	var root yaml.Node
+	yaml.Unmarshal([]byte("a:\n  secret: "+sealedValue+"\n"), &root)
+	if err := OpenConfig(&root, func() string { return "" }); err == nil || !strings.Contains(err.Error(), "RELAY_ENCRYPTION_KEY") {
+		t.Errorf("expected a missing key error, got %v", err)
+	}
+	if err := OpenConfig(&root, func() string { return strings.Repeat("cd", 32) }); err == nil || !strings.Contains(err.Error(), "line 2") {
+		t.Errorf("expected a wrong key error, got %v", err)
+	}
+
+	calls := 0
+	yaml.Unmarshal([]byte("a: b\n"), &root)
+	OpenConfig(&root, func() string { calls++; return "" })
+	if calls != 0 {
+		t.Error("expected the key read only for sealed values")
+	}
+}
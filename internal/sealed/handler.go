@@ -0,0 +1,62 @@
+package sealed
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+)
+
+// maxSealBody bounds POST /api/secrets/seal bodies.
+const maxSealBody = 64 << 10
+
+// Handler serves POST /api/secrets/seal, which turns a secret into a sealed
+// value for the config file. The response never contains the plaintext.
+type Handler struct {
+	key []byte
+}
+
+// NewHandler seals with key; without one every request answers 503.
+func NewHandler(key []byte) *Handler {
+	return &Handler{key: key}
+}
+
+// RegisterRoutes adds the seal route to the mux.
+func (h *Handler) RegisterRoutes(mux *http.ServeMux) {
+	mux.HandleFunc("/api/secrets/seal", h.handleSeal)
+}
+
+func jsonError(w http.ResponseWriter, msg string, code int) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(map[string]string{"error": msg})
+}
+
+func (h *Handler) handleSeal(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		jsonError(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	var req struct {
+		Value string `json:"value"`
+	}
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxSealBody)).Decode(&req); err != nil || req.Value == "" {
+		jsonError(w, `body must be {"value": "..."}`, http.StatusBadRequest)
+		return
+	}
+	if IsSealed(req.Value) {
+		jsonError(w, "value is already sealed", http.StatusBadRequest)
+		return
+	}
+	v, err := Seal(h.key, req.Value)
+	if errors.Is(err, ErrNoKey) {
+		jsonError(w, err.Error(), http.StatusServiceUnavailable)
+		return
+	}
+	if err != nil {
+		jsonError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	json.NewEncoder(w).Encode(map[string]string{"sealed": v})
+}
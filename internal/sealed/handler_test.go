@@ -0,0 +1,51 @@
+package sealed
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestHandler_Seal(t *testing.T) {
+	tests := []struct {
+		name   string
+		key    []byte
+		method string
+		body   string
+		want   int
+	}{
+		{"seal", testKey, "POST", `{"value":"hunter2"}`, http.StatusOK},
+		{"empty", testKey, "POST", `{"value":""}`, http.StatusBadRequest},
+		{"not JSON", testKey, "POST", `hunter2`, http.StatusBadRequest},
+		{"already sealed", testKey, "POST", `{"value":"sealed:abc"}`, http.StatusBadRequest},
+		{"GET", testKey, "GET", ``, http.StatusMethodNotAllowed},
+		{"no key", nil, "POST", `{"value":"hunter2"}`, http.StatusServiceUnavailable},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mux := http.NewServeMux()
+			NewHandler(tt.key).RegisterRoutes(mux)
+			rec := httptest.NewRecorder()
+			mux.ServeHTTP(rec, httptest.NewRequest(tt.method, "/api/secrets/seal", strings.NewReader(tt.body)))
+			if rec.Code != tt.want {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.want, rec.Body)
+			}
+			if strings.Contains(rec.Body.String(), "hunter2") {
+				t.Errorf("plaintext in response: %s", rec.Body)
+			}
+			if rec.Code != http.StatusOK {
+				return
+			}
+			var resp struct{ Sealed string }
+			json.NewDecoder(rec.Body).Decode(&resp)
+			if v, err := Open(testKey, resp.Sealed); err != nil || v != "hunter2" {
+				t.Errorf("sealed %q opens to %q, %v", resp.Sealed, v, err)
+			}
+			if rec.Header().Get("Cache-Control") != "no-store" {
+				t.Error("expected Cache-Control: no-store")
+			}
+		})
+	}
+}
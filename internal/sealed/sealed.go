@@ -0,0 +1,114 @@
+// Package sealed encrypts secrets with the relay's RELAY_ENCRYPTION_KEY, so
+// the admin API can show and accept them without the plaintext ever
+// appearing in a response. A sealed value is "sealed:" followed by the
+// base64url AES-256-GCM ciphertext; the relay opens it when it loads the
+// config. Sealing uses keys derived from RELAY_ENCRYPTION_KEY with HKDF and
+// binds the ciphertext to this format, so a sealed value and a token file
+// encrypted with the same key can't be swapped for one another.
+package sealed
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hkdf"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Prefix marks a sealed value.
+const Prefix = "sealed:"
+
+// aad is the additional data every sealed value is bound to.
+const aad = "relay-sealed-v1"
+
+// ErrNoKey is returned when a value must be sealed or opened without a key.
+var ErrNoKey = errors.New("RELAY_ENCRYPTION_KEY is not set")
+
+// ParseKey decodes the hex RELAY_ENCRYPTION_KEY. An empty key gives nil.
+func ParseKey(keyHex string) ([]byte, error) {
+	if keyHex == "" {
+		return nil, nil
+	}
+	key, err := hex.DecodeString(keyHex)
+	if err != nil || len(key) != 32 {
+		return nil, fmt.Errorf("RELAY_ENCRYPTION_KEY must be 32-byte hex (64 chars)")
+	}
+	return key, nil
+}
+
+// IsSealed reports whether s is a sealed value.
+func IsSealed(s string) bool {
+	return strings.HasPrefix(s, Prefix)
+}
+
+// Seal encrypts plaintext with key. The nonce is derived from the plaintext,
+// so the same secret always seals to the same value and config diffs only
+// show secrets that changed.
+func Seal(key []byte, plaintext string) (string, error) {
+	if key == nil {
+		return "", ErrNoKey
+	}
+	nonceKey, gcm, err := subkeys(key)
+	if err != nil {
+		return "", err
+	}
+	mac := hmac.New(sha256.New, nonceKey)
+	mac.Write([]byte(plaintext))
+	nonce := mac.Sum(nil)[:gcm.NonceSize()]
+	out := gcm.Seal(nonce, nonce, []byte(plaintext), []byte(aad))
+	return Prefix + base64.RawURLEncoding.EncodeToString(out), nil
+}
+
+// Open decrypts a sealed value.
+func Open(key []byte, value string) (string, error) {
+	if key == nil {
+		return "", ErrNoKey
+	}
+	data, err := base64.RawURLEncoding.DecodeString(strings.TrimPrefix(value, Prefix))
+	if err != nil {
+		return "", fmt.Errorf("malformed sealed value")
+	}
+	_, gcm, err := subkeys(key)
+	if err != nil {
+		return "", err
+	}
+	ns := gcm.NonceSize()
+	if len(data) < ns {
+		return "", fmt.Errorf("malformed sealed value")
+	}
+	plaintext, err := gcm.Open(nil, data[:ns], data[ns:], []byte(aad))
+	if err != nil {
+		return "", fmt.Errorf("sealed value does not open with this RELAY_ENCRYPTION_KEY")
+	}
+	return string(plaintext), nil
+}
+
+// DeriveKey derives a 32-byte key for purpose from key with HKDF-SHA256, so
+// each use of RELAY_ENCRYPTION_KEY gets a key of its own.
+func DeriveKey(key []byte, purpose string) ([]byte, error) {
+	return hkdf.Key(sha256.New, key, nil, purpose, 32)
+}
+
+// subkeys derives the HMAC key for nonces and the AES-GCM cipher from key,
+// so neither is the raw key other stores encrypt with.
+func subkeys(key []byte) ([]byte, cipher.AEAD, error) {
+	nonceKey, err := DeriveKey(key, aad+" nonce")
+	if err != nil {
+		return nil, nil, err
+	}
+	cipherKey, err := DeriveKey(key, aad+" cipher")
+	if err != nil {
+		return nil, nil, err
+	}
+	block, err := aes.NewCipher(cipherKey)
+	if err != nil {
+		return nil, nil, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	return nonceKey, gcm, err
+}
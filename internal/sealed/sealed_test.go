@@ -0,0 +1,65 @@
+package sealed
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"encoding/base64"
+	"errors"
+	"strings"
+	"testing"
+)
+
+var testKey, _ = ParseKey(strings.Repeat("ab", 32))
+
+func TestSealOpen(t *testing.T) {
+	v, err := Seal(testKey, "hunter2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !IsSealed(v) || strings.Contains(v, "hunter2") {
+		t.Fatalf("sealed value %q", v)
+	}
+	if again, _ := Seal(testKey, "hunter2"); again != v {
+		t.Error("expected the same secret to seal to the same value")
+	}
+	if other, _ := Seal(testKey, "hunter3"); other == v {
+		t.Error("expected different secrets to seal differently")
+	}
+	if got, err := Open(testKey, v); err != nil || got != "hunter2" {
+		t.Errorf("Open = %q, %v", got, err)
+	}
+
+	otherKey, _ := ParseKey(strings.Repeat("cd", 32))
+	if _, err := Open(otherKey, v); err == nil {
+		t.Error("expected another key to fail")
+	}
+	if _, err := Open(testKey, v[:len(v)-4]); err == nil {
+		t.Error("expected a truncated value to fail")
+	}
+	if _, err := Open(testKey, Prefix+"!!"); err == nil {
+		t.Error("expected malformed base64 to fail")
+	}
+	if _, err := Seal(nil, "x"); !errors.Is(err, ErrNoKey) {
+		t.Errorf("expected ErrNoKey, got %v", err)
+	}
+}
+
+func TestOpen_RawKeyCiphertext(t *testing.T) {
+	// The token store's format: AES-GCM under the raw key, nonce first
+	block, _ := aes.NewCipher(testKey)
+	gcm, _ := cipher.NewGCM(block)
+	nonce := make([]byte, gcm.NonceSize())
+	raw := Prefix + base64.RawURLEncoding.EncodeToString(gcm.Seal(nonce, nonce, []byte("hunter2"), nil))
+	if _, err := Open(testKey, raw); err == nil {
+		t.Error("expected a raw-key ciphertext not to open")
+	}
+}
+
+func TestParseKey(t *testing.T) {
+	if k, err := ParseKey(""); k != nil || err != nil {
+		t.Errorf("empty key: %v, %v", k, err)
+	}
+	if _, err := ParseKey("abcd"); err == nil {
+		t.Error("expected a short key to fail")
+	}
+}
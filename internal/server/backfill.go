@@ -0,0 +1,57 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/katalabut/openclaw-relay/internal/auth"
+	"github.com/katalabut/openclaw-relay/internal/config"
+	"github.com/katalabut/openclaw-relay/internal/gmail"
+	"github.com/katalabut/openclaw-relay/internal/imap"
+	"github.com/katalabut/openclaw-relay/internal/tokens"
+)
+
+// GmailBackfill runs an account's existing mail through its rules with the
+// tokens and backfill log in dataDir and the configured gateway, for
+// `relay gmail backfill`. It can run next to the server.
+func GmailBackfill(ctx context.Context, cfg *config.Config, dataDir, account string, opts gmail.BackfillOptions) (*gmail.BackfillResult, error) {
+	var acc *config.GmailAccountConf
+	for _, a := range cfg.Gmail.ResolvedAccounts() {
+		if a.Email == account {
+			acc = &a
+			break
+		}
+	}
+	if acc == nil {
+		return nil, fmt.Errorf("%s is not in gmail.accounts", account)
+	}
+	client, err := backfillClient(cfg, dataDir, *acc)
+	if err != nil {
+		return nil, err
+	}
+
+	poller := gmail.NewPollerForAccount(client, account, acc.PollInterval, acc.Rules, newGatewayClient(cfg), dataDir, nil)
+	poller.SetTimezone(cfg.Server.Timezone)
+	poller.SetReadOnly(cfg.ReadOnly)
+	poller.SetTextExtraction(newTextExtraction(cfg, newScreener(cfg)))
+	return poller.Backfill(ctx, opts)
+}
+
+// backfillClient returns the mail client of acc for a backfill.
+func backfillClient(cfg *config.Config, dataDir string, acc config.GmailAccountConf) (gmail.GmailClient, error) {
+	if acc.IsIMAP() {
+		return imap.NewClient(acc.Email, acc.IMAP), nil
+	}
+	store, err := tokens.NewStore(filepath.Join(dataDir, "tokens.json.enc"), os.Getenv("RELAY_ENCRYPTION_KEY"))
+	if err != nil {
+		return nil, fmt.Errorf("token store: %w", err)
+	}
+	client := gmail.NewClientForAccount(store, auth.NewOAuthConfig(&cfg.Google), acc.Email)
+	client.SetEndpoint(cfg.Gmail.APIURL)
+	client.SetRetry(cfg.Gmail.Retry)
+	// Counted from zero: the server's counts for today aren't shared with this process
+	client.SetQuota(gmail.NewQuota(acc.Email, cfg.Gmail.ResolvedDailyQuota(acc), cfg.Gmail.Quota.ResolvedThrottleAt()))
+	return client, nil
+}
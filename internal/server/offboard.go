@@ -0,0 +1,98 @@
+package server
+
+import (
+	"fmt"
+
+	"github.com/katalabut/openclaw-relay/internal/auth"
+	"github.com/katalabut/openclaw-relay/internal/calendar"
+	"github.com/katalabut/openclaw-relay/internal/deadletter"
+	"github.com/katalabut/openclaw-relay/internal/drive"
+	"github.com/katalabut/openclaw-relay/internal/gmail"
+)
+
+// offboarding holds the per-account stores DELETE /api/auth/accounts/{email}
+// purges. A field is nil when its feature isn't running; its state file is
+// purged anyway, since the feature may have written it before it was
+// turned off.
+type offboarding struct {
+	dataDir        string
+	gmailPollers   map[string]*gmail.Poller
+	gmailAPI       *gmail.Handler
+	gmailQuotas    gmail.Quotas
+	calendarPoller *calendar.Poller
+	drivePoller    *drive.Poller
+	deadLetters    *deadletter.Queue
+}
+
+// register adds a hook per store to g. Each stops what writes the store
+// before purging it, so a poll in progress can't write the account back.
+func (o *offboarding) register(g *auth.GoogleAuth) {
+	g.OnOffboard(o.gmailState)
+	g.OnOffboard(o.gmailAPICache)
+	g.OnOffboard(o.calendarState)
+	g.OnOffboard(o.driveState)
+	if o.deadLetters != nil {
+		g.OnOffboard(o.deadLetterEntries)
+	}
+}
+
+func (o *offboarding) gmailState(email string) (string, error) {
+	if p := o.gmailPollers[email]; p != nil {
+		p.Stop()
+	}
+	removed, err := gmail.RemoveState(o.dataDir, email)
+	if err != nil {
+		return "", fmt.Errorf("poller state: %w", err)
+	}
+	return label(removed, "poller_state"), nil
+}
+
+func (o *offboarding) gmailAPICache(email string) (string, error) {
+	cached := o.gmailAPI != nil && o.gmailAPI.DropCache(email)
+	counted := o.gmailQuotas[email].Reset()
+	return label(cached || counted, "gmail_api_cache"), nil
+}
+
+func (o *offboarding) calendarState(email string) (string, error) {
+	var removed bool
+	var err error
+	if o.calendarPoller != nil {
+		removed, err = o.calendarPoller.RemoveAccount(email)
+	} else {
+		removed, err = calendar.RemoveState(o.dataDir, email)
+	}
+	if err != nil {
+		return "", fmt.Errorf("calendar state: %w", err)
+	}
+	return label(removed, "calendar_state"), nil
+}
+
+func (o *offboarding) driveState(email string) (string, error) {
+	var removed bool
+	var err error
+	if o.drivePoller != nil {
+		removed, err = o.drivePoller.RemoveAccount(email)
+	} else {
+		removed, err = drive.RemoveState(o.dataDir, email)
+	}
+	if err != nil {
+		return "", fmt.Errorf("drive state: %w", err)
+	}
+	return label(removed, "drive_state"), nil
+}
+
+func (o *offboarding) deadLetterEntries(email string) (string, error) {
+	n, err := o.deadLetters.RemoveAccount(email)
+	if err != nil {
+		return "", fmt.Errorf("dead letters: %w", err)
+	}
+	return label(n > 0, "dead_letters"), nil
+}
+
+// label is what an off-boarding hook reports: what, when it removed anything.
+func label(removed bool, what string) string {
+	if removed {
+		return what
+	}
+	return ""
+}
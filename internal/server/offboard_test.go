@@ -0,0 +1,174 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/oauth2"
+
+	"github.com/katalabut/openclaw-relay/internal/auth"
+	"github.com/katalabut/openclaw-relay/internal/calendar"
+	"github.com/katalabut/openclaw-relay/internal/config"
+	"github.com/katalabut/openclaw-relay/internal/deadletter"
+	"github.com/katalabut/openclaw-relay/internal/drive"
+	"github.com/katalabut/openclaw-relay/internal/gateway"
+	"github.com/katalabut/openclaw-relay/internal/gmail"
+	"github.com/katalabut/openclaw-relay/internal/tokens"
+)
+
+// failingGateway refuses every job, so the dead-letter queue keeps them.
+type failingGateway struct{}
+
+func (failingGateway) CreateOneShotJob(name, message string, timeoutSeconds, delaySeconds int) error {
+	return errors.New("gateway down")
+}
+
+func (failingGateway) CreateOneShotJobForAgent(name, message, agentID string, timeoutSeconds, delaySeconds int) error {
+	return errors.New("gateway down")
+}
+
+func TestOffboarding(t *testing.T) {
+	const gone, kept = "gone@example.com", "kept@example.com"
+	dataDir := t.TempDir()
+	key := strings.Repeat("ab", 32)
+
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/labels"):
+			fmt.Fprint(w, `{"labels":[{"id":"INBOX","name":"INBOX"}]}`)
+		case strings.HasSuffix(r.URL.Path, "/profile"):
+			fmt.Fprint(w, `{"historyId":"7"}`)
+		default:
+			fmt.Fprint(w, `{}`)
+		}
+	}))
+	defer api.Close()
+
+	store, err := tokens.NewStore(filepath.Join(dataDir, "tokens.json.enc"), key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	clients := map[string]gmail.GmailClient{}
+	quotas := gmail.Quotas{}
+	pollers := map[string]*gmail.Poller{}
+	for _, email := range []string{gone, kept} {
+		if err := store.SaveGoogle(&oauth2.Token{AccessToken: "tok", RefreshToken: "r", Expiry: time.Now().Add(time.Hour)}, email); err != nil {
+			t.Fatal(err)
+		}
+		quotas[email] = gmail.NewQuota(email, 0, 80)
+		c := gmail.NewClientForAccount(store, &oauth2.Config{}, email)
+		c.SetEndpoint(api.URL + "/")
+		c.SetQuota(quotas[email])
+		clients[email] = c
+		pollers[email] = gmail.NewPollerForAccount(c, email, "1h", nil, failingGateway{}, dataDir, nil)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	for _, p := range pollers {
+		p.Start(ctx)
+	}
+	handler := gmail.NewMultiHandler(clients)
+	handler.SetCacheTTL(time.Hour)
+	handler.SetQuotas(quotas)
+	mux := http.NewServeMux()
+	handler.RegisterRoutes(mux)
+	for _, email := range []string{gone, kept} {
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest("GET", "/api/gmail/labels?account="+email, nil))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("labels for %s: status %d", email, rec.Code)
+		}
+	}
+
+	// Per-account state written by earlier polls
+	states := map[string]string{
+		"gmail-state.json":    fmt.Sprintf(`{"accounts":{%q:{"history_id":5},%q:{"history_id":5}}}`, gone, kept),
+		"calendar-state.json": fmt.Sprintf(`{"fired":{"%s|e1|10":"2026-01-01T00:00:00Z","%s|e1|10":"2026-01-01T00:00:00Z"}}`, gone, kept),
+		"drive-state.json":    fmt.Sprintf(`{"accounts":{%q:"p1",%q:"p1"}}`, gone, kept),
+	}
+	for name, data := range states {
+		if err := os.WriteFile(filepath.Join(dataDir, name), []byte(data), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	dlq, err := deadletter.New(failingGateway{}, filepath.Join(dataDir, "deadletters.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, email := range []string{gone, kept} {
+		dlq.CreateJob(gateway.JobSpec{Name: "gmail: new mail", Message: "hi", Account: email})
+	}
+
+	o := &offboarding{
+		dataDir:        dataDir,
+		gmailPollers:   pollers,
+		gmailAPI:       handler,
+		gmailQuotas:    quotas,
+		calendarPoller: calendar.NewPoller(nil, []string{gone, kept}, nil, time.Hour, failingGateway{}, dataDir),
+		drivePoller:    drive.NewPoller(nil, config.DriveConfig{}, failingGateway{}, nil, dataDir),
+		deadLetters:    dlq,
+	}
+	g := auth.NewGoogleAuth(context.Background(), &config.GoogleConfig{ClientID: "id", ClientSecret: "secret"}, store, key, nil)
+	o.register(g)
+
+	rec := httptest.NewRecorder()
+	g.HandleDeleteAccount(rec, httptest.NewRequest("DELETE", "/api/auth/accounts/"+gone, nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", rec.Code, rec.Body)
+	}
+	for _, what := range []string{"tokens", "poller_state", "gmail_api_cache", "calendar_state", "drive_state", "dead_letters"} {
+		if !strings.Contains(rec.Body.String(), `"`+what+`"`) {
+			t.Errorf("expected %s in %s", what, rec.Body)
+		}
+	}
+
+	if !pollers[gone].Status().Stopped {
+		t.Error("expected the account's poller stopped")
+	}
+	if pollers[kept].Status().Stopped {
+		t.Error("expected other pollers left running")
+	}
+	if store.GetGoogle(gone) != nil {
+		t.Error("expected the tokens removed")
+	}
+	if handler.DropCache(gone) {
+		t.Error("expected the Gmail API cache empty")
+	}
+	if quotas[gone].Usage().Calls != 0 {
+		t.Error("expected the quota count reset")
+	}
+	if removed, err := gmail.RemoveState(dataDir, gone); removed || err != nil {
+		t.Errorf("gmail state left: %v %v", removed, err)
+	}
+	if removed, err := calendar.RemoveState(dataDir, gone); removed || err != nil {
+		t.Errorf("calendar state left: %v %v", removed, err)
+	}
+	if removed, err := drive.RemoveState(dataDir, gone); removed || err != nil {
+		t.Errorf("drive state left: %v %v", removed, err)
+	}
+	if n, err := dlq.RemoveAccount(gone); n != 0 || err != nil {
+		t.Errorf("dead letters left: %d %v", n, err)
+	}
+
+	// The other account keeps everything
+	if !handler.DropCache(kept) || quotas[kept].Usage().Calls == 0 {
+		t.Error("expected the other account's cache and quota kept")
+	}
+	for _, remove := range []func(string, string) (bool, error){gmail.RemoveState, calendar.RemoveState, drive.RemoveState} {
+		if removed, _ := remove(dataDir, kept); !removed {
+			t.Error("expected the other account's state kept")
+		}
+	}
+	if n, _ := dlq.RemoveAccount(kept); n != 1 {
+		t.Errorf("expected the other account's dead letter kept, got %d", n)
+	}
+}
@@ -2,84 +2,487 @@ package server
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"log"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
 	"syscall"
 	"time"
 
+	"github.com/katalabut/openclaw-relay/internal/asana"
 	"github.com/katalabut/openclaw-relay/internal/audit"
 	"github.com/katalabut/openclaw-relay/internal/auth"
+	"github.com/katalabut/openclaw-relay/internal/budget"
+	"github.com/katalabut/openclaw-relay/internal/calendar"
+	"github.com/katalabut/openclaw-relay/internal/chaos"
 	"github.com/katalabut/openclaw-relay/internal/config"
+	"github.com/katalabut/openclaw-relay/internal/control"
+	"github.com/katalabut/openclaw-relay/internal/dataset"
+	"github.com/katalabut/openclaw-relay/internal/deadletter"
+	"github.com/katalabut/openclaw-relay/internal/drive"
+	"github.com/katalabut/openclaw-relay/internal/events"
+	"github.com/katalabut/openclaw-relay/internal/extract"
 	"github.com/katalabut/openclaw-relay/internal/gateway"
+	"github.com/katalabut/openclaw-relay/internal/gc"
+	"github.com/katalabut/openclaw-relay/internal/github"
 	"github.com/katalabut/openclaw-relay/internal/gmail"
+	"github.com/katalabut/openclaw-relay/internal/health"
+	"github.com/katalabut/openclaw-relay/internal/heartbeat"
+	"github.com/katalabut/openclaw-relay/internal/imap"
+	"github.com/katalabut/openclaw-relay/internal/links"
+	"github.com/katalabut/openclaw-relay/internal/notion"
 	"github.com/katalabut/openclaw-relay/internal/ratelimit"
+	"github.com/katalabut/openclaw-relay/internal/rules"
+	"github.com/katalabut/openclaw-relay/internal/screening"
+	"github.com/katalabut/openclaw-relay/internal/sealed"
+	"github.com/katalabut/openclaw-relay/internal/state"
+	"github.com/katalabut/openclaw-relay/internal/tasks"
 	"github.com/katalabut/openclaw-relay/internal/tokens"
+	"github.com/katalabut/openclaw-relay/internal/trello"
+	"github.com/katalabut/openclaw-relay/internal/versions"
 	"github.com/katalabut/openclaw-relay/internal/webhook"
 )
 
+// Run starts the relay and blocks until SIGINT or SIGTERM.
 func Run(cfg *config.Config) error {
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+	return RunContext(ctx, cfg)
+}
+
+// RunContext starts the relay and blocks until ctx is done, then shuts down
+// gracefully.
+func RunContext(ctx context.Context, cfg *config.Config) error {
 	if err := cfg.Validate(); err != nil {
 		return fmt.Errorf("config validation: %w", err)
 	}
 
-	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	ctx, cancel := context.WithCancel(ctx)
 	defer cancel()
 
-	gw := gateway.NewClient(cfg.Gateway.URL, cfg.Gateway.Token, cfg.Gateway.AgentID, cfg.Gateway.Model)
-	limiter := ratelimit.New(ctx, 5*time.Minute)
+	limiter := ratelimit.New(ctx, cfg.Server.ResolvedRateLimit())
 
 	mux := http.NewServeMux()
 
-	// Health
-	mux.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
-		w.Header().Set("Content-Type", "application/json")
-		w.Write([]byte(`{"status":"ok"}`))
+	// In-memory mode sends jobs to a sink instead of the gateway, for load tests
+	var gw gateway.GatewayClient
+	var gwClient *gateway.Client
+	if cfg.InMemory {
+		sink := gateway.NewSink()
+		gw = sink
+		mux.HandleFunc("/api/sink", func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(map[string]any{"jobs": sink.Count(), "last": sink.Last()})
+		})
+		log.Println("In-memory mode: no disk state, gateway jobs go to /api/sink")
+	} else {
+		gwClient = newGatewayClient(cfg)
+		gw = gwClient
+	}
+
+	// Fault injection for staging (RELAY_CHAOS); never set this in production
+	faults, err := chaos.FromEnv()
+	if err != nil {
+		return fmt.Errorf("RELAY_CHAOS: %w", err)
+	}
+	if faults != nil {
+		log.Printf("WARNING: chaos fault injection enabled (%s)", faults)
+		if gwClient != nil {
+			gwClient.HTTP.Transport = faults.Transport(gwClient.HTTP.Transport)
+		}
+		mux.HandleFunc("/api/chaos", faults.HandleChaos)
+	}
+
+	// Jobs the gateway does not accept are kept and retried
+	var dlq *deadletter.Queue
+	if !cfg.InMemory {
+		dlq, err = deadletter.New(gw, "data/deadletter.json")
+		if err != nil {
+			log.Printf("Warning: dead letter queue unreadable, starting empty: %v", err)
+			dlq, _ = deadletter.New(gw, "")
+		}
+		dlq.RetryInterval = cfg.Gateway.DeadLetter.ResolvedRetryInterval()
+		dlq.MaxAttempts = cfg.Gateway.DeadLetter.ResolvedMaxAttempts()
+		dlq.Start(ctx)
+		dlq.RegisterRoutes(mux)
+		gw = dlq
+	}
+
+	// Monthly job caps per source and rule. The budget wraps the dead letter
+	// queue, so jobs it drops over a cap are never dead letters.
+	if cfg.Budget.Enabled() {
+		budgetPath := "data/budget.json"
+		if cfg.InMemory {
+			budgetPath = ""
+		}
+		caps := budget.Caps{Sources: cfg.Budget.Sources, Rules: cfg.Budget.Rules}
+		alert := budget.Alert{AgentID: cfg.Budget.AgentID, Timeout: cfg.Budget.Timeout}
+		b, err := budget.New(gw, caps, alert, budgetPath)
+		if err != nil {
+			log.Printf("Warning: budget usage unreadable, counting from zero: %v", err)
+			b, _ = budget.New(gw, caps, alert, "")
+		}
+		gw = b
+		mux.HandleFunc("/api/budget", b.HandleUsage)
+		log.Printf("Budget: monthly caps on %d sources and %d rules", len(caps.Sources), len(caps.Rules))
+	}
+
+	// Anonymized event → prompt pairs for prompt evaluation
+	if cfg.Dataset.Path != "" {
+		if cfg.InMemory {
+			log.Printf("Dataset: recording disabled in in-memory mode")
+		} else {
+			// Patterns were validated with the config
+			redactor, _ := dataset.NewRedactor(cfg.Dataset.RedactFields, cfg.Dataset.Redact)
+			gw = dataset.New(gw, cfg.Dataset.Path, dataset.Options{
+				Sample:   float64(cfg.Dataset.Sample),
+				Sources:  cfg.Dataset.Sources,
+				Redactor: redactor,
+			})
+			log.Printf("Dataset: recording jobs to %s", cfg.Dataset.Path)
+		}
+	}
+
+	// Matched events for scripts long-polling GET /api/events/wait
+	feed := events.NewFeed(0)
+	gw = feed.Gateway(gw)
+	mux.HandleFunc("/api/events/wait", feed.HandleWait)
+
+	// Periodic "relay is alive" job with a summary of pending work
+	heartbeats := heartbeat.New(gw, cfg.Gateway.Heartbeat)
+	gw = heartbeats.Gateway(gw)
+	if dlq != nil {
+		heartbeats.AddPending("dead letters", func() int { return len(dlq.List()) })
+	}
+	heartbeats.Start(ctx)
+
+	// Health, with the configured features that can't run and why
+	report := &health.Report{}
+	mux.HandleFunc("/health", report.HandleHealth)
+
+	// Event store: deliveries whose handler panicked are kept for replay
+	eventsPath := "data/events.json"
+	if cfg.InMemory {
+		eventsPath = ""
+	}
+	eventStore, err := events.NewStore(eventsPath)
+	if err != nil {
+		report.Degrade("event_store", fmt.Sprintf("event store init failed, errored deliveries will not be preserved: %v", err))
+	}
+	recovery := events.NewRecovery(eventStore)
+	if eventStore != nil {
+		heartbeats.AddPending("errored deliveries", func() int { return len(eventStore.List(events.StatusErrored)) })
+	}
+	recovery.RegisterRoutes(mux)
+
+	// Every webhook delivery with its outcome, listed by GET /api/events
+	historyPath := "data/history.json"
+	if cfg.InMemory {
+		historyPath = ""
+	}
+	history, err := events.NewHistory(historyPath, cfg.Server.EventHistory)
+	if err != nil {
+		log.Printf("Warning: event history init failed, starting empty: %v", err)
+		history, _ = events.NewHistory("", cfg.Server.EventHistory)
+	}
+	recovery.SetHistory(history)
+
+	// Deliveries older than event_ttl are dropped and kept as "expired"
+	eventTTL := cfg.Server.ResolvedEventTTL()
+	recovery.SetTTL(eventTTL)
+	expiry := events.NewExpiry(eventStore, eventTTL, webhook.EventTime)
+	if eventTTL > 0 {
+		log.Printf("Event TTL: deliveries older than %s are dropped", eventTTL)
+	}
+
+	// Old deliveries, spent dead letters and orphaned state, collected by GC
+	collector := gc.New(cfg.GC.ResolvedInterval())
+	eventRetention, deadLetterRetention := cfg.GC.ResolvedRetention()
+	if eventStore != nil {
+		collector.Add("events", func(now time.Time, dryRun bool) ([]string, error) {
+			pruned, err := eventStore.Prune(now.Add(-eventRetention), dryRun)
+			var ids []string
+			for _, e := range pruned {
+				ids = append(ids, e.ID)
+			}
+			return ids, err
+		})
+	}
+	collector.Add("history", func(now time.Time, dryRun bool) ([]string, error) {
+		pruned, err := history.Prune(now.Add(-eventRetention), dryRun)
+		var ids []string
+		for _, d := range pruned {
+			ids = append(ids, d.ID)
+		}
+		return ids, err
 	})
+	if dlq != nil {
+		collector.Add("dead_letters", func(now time.Time, dryRun bool) ([]string, error) {
+			var ids []string
+			for _, e := range dlq.Prune(now.Add(-deadLetterRetention), dryRun) {
+				ids = append(ids, e.ID)
+			}
+			return ids, nil
+		})
+	}
+	if !cfg.InMemory {
+		collector.Add("temp_files", gc.TempFiles("data"))
+	}
 
-	// Webhooks
-	mux.Handle("/webhook/trello", &webhook.TrelloHandler{Config: cfg, Gateway: gw, Limiter: limiter})
-	mux.Handle("/webhook/github", &webhook.GitHubHandler{Config: cfg, Gateway: gw, Limiter: limiter})
+	// Card ↔ PR links maintained by the agent, used in GitHub job prompts
+	linksPath := "data/links.json"
+	if cfg.InMemory {
+		linksPath = ""
+	}
+	linkStore, err := links.NewStore(linksPath)
+	if err != nil {
+		log.Printf("Warning: link store init failed, starting empty: %v", err)
+		linkStore, _ = links.NewStore("")
+	}
+	links.NewHandler(linkStore).RegisterRoutes(mux)
+
+	// Config file history: snapshot what we started with, serve diff and rollback
+	versionsPath := "data/config_versions.json"
+	if cfg.InMemory {
+		versionsPath = ""
+	}
+	versionStore, err := versions.NewStore(versionsPath, versions.DefaultMax)
+	if err != nil {
+		log.Printf("Warning: config version store init failed, starting empty: %v", err)
+		versionStore, _ = versions.NewStore("", versions.DefaultMax)
+	}
+	var running versions.Version
+	if cfg.Source != nil {
+		var created bool
+		running, created, err = versionStore.Record(cfg.Source, "startup")
+		if err != nil {
+			log.Printf("Warning: config version snapshot failed: %v", err)
+		} else if created {
+			log.Printf("Config version %d recorded", running.ID)
+		}
+	}
+	// Secrets in the admin API are sealed with the relay key, never shown in plaintext
+	secretKey, err := sealed.ParseKey(os.Getenv("RELAY_ENCRYPTION_KEY"))
+	if secretKey == nil {
+		if err == nil {
+			err = sealed.ErrNoKey
+		}
+		report.Degrade("sealed_secrets", err.Error()+"; config secrets are redacted and /api/secrets/seal is unavailable")
+	}
+	versionHandler := versions.NewHandler(versionStore, cfg.Path, running)
+	versionHandler.SetSecretKey(secretKey)
+	versionHandler.RegisterRoutes(mux)
+	sealed.NewHandler(secretKey).RegisterRoutes(mux)
+	// Rule export reads the running config with its secrets sealed too;
+	// imports rewrite the file and are recorded as versions
+	rulesSource, err := sealed.SealConfig(string(cfg.Source), secretKey)
+	if err != nil {
+		log.Printf("Warning: can't seal secrets for rule export: %v", err)
+	}
+	rules.NewHandler([]byte(rulesSource), cfg.Path, versionStore).RegisterRoutes(mux)
+
+	// Delivery IDs already handled, so redelivered webhooks create no second job
+	deliveriesPath := "data/deliveries.json"
+	if cfg.InMemory {
+		deliveriesPath = ""
+	}
+	deliveries, err := ratelimit.NewDeliveries(deliveriesPath, cfg.Server.DeliveryHistory)
+	if err != nil {
+		log.Printf("Warning: delivery history init failed, starting empty: %v", err)
+		deliveries, _ = ratelimit.NewDeliveries("", cfg.Server.DeliveryHistory)
+	}
+
+	// Namespaced key-value state shared between agent jobs
+	statePath := "data/state.json"
+	if cfg.InMemory {
+		statePath = ""
+	}
+	stateStore, err := state.NewStore(statePath)
+	if err != nil {
+		log.Printf("Warning: state store init failed, starting empty: %v", err)
+		stateStore, _ = state.NewStore("")
+	}
+	state.NewHandler(stateStore).RegisterRoutes(mux)
+
+	// Webhooks: per-source in-flight limits go outermost, so a delivery turned
+	// away is neither read (panic recovery reads the whole body) nor recorded.
+	// Replays are not limited. The history sees every delivery let in,
+	// including handshakes and panics turned into 500s. Paused sources (see the
+	// control channel) hold deliveries in the event store.
+	pauses := events.NewPauses(eventStore)
+	inflight := ratelimit.NewConcurrency(cfg.Server.MaxInFlight, cfg.Server.ResolvedInFlightWait())
+	webhookHandler := func(source string, h http.Handler) http.Handler {
+		return inflight.Wrap(source, history.Wrap(source, webhook.Handshakes(source, recovery.Wrap(source, pauses.Wrap(source, expiry.Wrap(source, h))))))
+	}
+	if cfg.Server.DevSkipSignatures {
+		log.Println("WARNING: server.dev_skip_signatures is on: webhook signatures are not checked for direct loopback requests. Never enable this in production.")
+		wrap := webhookHandler
+		webhookHandler = func(source string, h http.Handler) http.Handler {
+			return webhook.LoopbackSignatureBypass(wrap(source, h))
+		}
+	}
+	if allow := cfg.Server.IPAllowlist; allow.Enabled() {
+		// Ranges were validated with the config
+		allowlist, _ := webhook.NewIPAllowlist(allow.Default, allow.Sources, allow.TrustedProxies)
+		if allow.GitHubMeta {
+			// Closed until GitHub's ranges are fetched, unless github has ranges of its own
+			configured, _ := webhook.ParseCIDRs(allow.Sources["github"])
+			allowlist.SetRanges("github", configured)
+			go refreshGitHubRanges(ctx, allowlist, configured, cfg.GitHub.App.APIURL, allow.ResolvedRefresh())
+		}
+		wrap := webhookHandler
+		webhookHandler = func(source string, h http.Handler) http.Handler {
+			return allowlist.Wrap(source, wrap(source, h))
+		}
+		log.Printf("IP allowlist: webhooks restricted for %d sources (default ranges: %d)", len(allow.Sources), len(allow.Default))
+	}
+	// Timestamped signatures (Slack, Discord): tolerance and drift tracking
+	skew := &webhook.ClockSkew{Tolerance: cfg.Server.ClockSkew.ResolvedTolerance(), Warn: cfg.Server.ClockSkew.ResolvedWarn()}
+	mux.HandleFunc("/api/skew", skew.HandleStatus)
+
+	// List, label and member names of trello.boards for message templates
+	// and trello.list_names resolved to list IDs; commented cards' lists
+	var trelloClient *trello.Client
+	var trelloBoards *trello.BoardCache
+	var trelloLists *trello.ListDirectory
+	var trelloCards *trello.CardLists
+	if cfg.Trello.APIKey != "" {
+		trelloClient = trello.NewClient(cfg.Trello.APIKey, cfg.Trello.Token, cfg.Trello.APIURL)
+		trelloCards = trello.NewCardLists(trelloClient)
+		if len(cfg.Trello.Boards) > 0 {
+			trelloBoards = trello.NewBoardCache(trelloClient, cfg.Trello.Boards)
+		}
+	}
+	if trelloBoards != nil && len(cfg.Trello.ListNames) > 0 {
+		listsPath := "data/trello_lists.json"
+		if cfg.InMemory {
+			listsPath = ""
+		}
+		trelloLists, err = trello.NewListDirectory(listsPath, cfg.Trello.ListNames, trelloBoards)
+		if err != nil {
+			log.Printf("Warning: Trello list IDs init failed, resolving list names from scratch: %v", err)
+			trelloLists, _ = trello.NewListDirectory("", cfg.Trello.ListNames, trelloBoards)
+		}
+	}
+
+	// Cards in trello.watch_lists, whose comments get the list they sit in
+	var trelloWatches *trello.Watches
+	if len(cfg.Trello.WatchLists) > 0 {
+		watchesPath := "data/trello_watches.json"
+		if cfg.InMemory {
+			watchesPath = ""
+		}
+		trelloWatches, err = trello.NewWatches(watchesPath, cfg.Trello.ResolvedWatchTTL())
+		if err != nil {
+			log.Printf("Warning: Trello watches init failed, starting empty: %v", err)
+			trelloWatches, _ = trello.NewWatches("", cfg.Trello.ResolvedWatchTTL())
+		}
+		collector.Add("trello_watches", trelloWatches.Prune)
+		mux.HandleFunc("/api/trello/watches", trelloWatches.HandleList)
+	}
+
+	// The GitHub App client also lists workflow run artifacts for the webhook prompts
+	var ghClient *github.Client
+	githubHandler := &webhook.GitHubHandler{Config: cfg, Gateway: gw, Limiter: limiter, Deliveries: deliveries, Links: linkStore}
+	if cfg.GitHub.App != nil {
+		ghClient, err = github.NewAppClient(*cfg.GitHub.App)
+		if err != nil {
+			return fmt.Errorf("github app: %w", err)
+		}
+		githubHandler.Artifacts = ghClient
+	}
+
+	mux.Handle("/webhook/trello", webhookHandler("trello", &webhook.TrelloHandler{Config: cfg, Gateway: gw, Limiter: limiter, Deliveries: deliveries, Links: linkStore, Boards: trelloBoards, Lists: trelloLists, Watches: trelloWatches, Cards: trelloCards}))
+	mux.Handle("/webhook/github", webhookHandler("github", githubHandler))
+	if len(cfg.Slack.Rules) > 0 {
+		mux.Handle("/webhook/slack", webhookHandler("slack", &webhook.SlackHandler{Config: cfg, Gateway: gw, Limiter: limiter, Skew: skew}))
+	}
+	if len(cfg.Discord.Rules) > 0 {
+		mux.Handle("/webhook/discord", webhookHandler("discord", &webhook.DiscordHandler{Config: cfg, Gateway: gw, Limiter: limiter, Skew: skew}))
+	}
+	if len(cfg.Notion.Rules) > 0 {
+		notionHandler := &webhook.NotionHandler{Config: cfg, Gateway: gw, Limiter: limiter, Pages: stateStore}
+		if cfg.Notion.APIToken != "" {
+			notionHandler.Client = notion.NewClient(cfg.Notion.APIToken, cfg.Notion.APIURL)
+		}
+		mux.Handle("/webhook/notion", webhookHandler("notion", notionHandler))
+	}
+	if len(cfg.Jira.Rules) > 0 {
+		mux.Handle("/webhook/jira", webhookHandler("jira", &webhook.JiraHandler{Config: cfg, Gateway: gw, Limiter: limiter}))
+	}
+	if len(cfg.Sentry.Rules) > 0 {
+		mux.Handle("/webhook/sentry", webhookHandler("sentry", &webhook.SentryHandler{Config: cfg, Gateway: gw, Limiter: limiter}))
+	}
+	if len(cfg.Alertmanager.Rules) > 0 {
+		mux.Handle("/webhook/alertmanager", webhookHandler("alertmanager", &webhook.AlertmanagerHandler{Config: cfg, Gateway: gw, Limiter: limiter}))
+	}
+	if len(cfg.Bitbucket.Rules) > 0 {
+		mux.Handle("/webhook/bitbucket", webhookHandler("bitbucket", &webhook.BitbucketHandler{Config: cfg, Gateway: gw, Limiter: limiter}))
+	}
+	if len(cfg.Asana.Rules) > 0 {
+		// Hook secrets live in their own file, not the /api/state store agents can read
+		hooksPath := "data/asana-hooks.json"
+		if cfg.InMemory {
+			hooksPath = ""
+		}
+		hookSecrets, err := state.NewStore(hooksPath)
+		if err != nil {
+			return fmt.Errorf("asana hook secrets: %w", err)
+		}
+		asanaHandler := &webhook.AsanaHandler{Config: cfg, Gateway: gw, Limiter: limiter, Secrets: hookSecrets}
+		if cfg.Asana.APIToken != "" {
+			asanaHandler.Client = asana.NewClient(cfg.Asana.APIToken, cfg.Asana.APIURL)
+		}
+		mux.Handle("/webhook/asana", webhookHandler("asana", asanaHandler))
+		mux.Handle("/webhook/asana/", webhookHandler("asana", asanaHandler))
+	}
+	if len(cfg.GenericWebhooks) > 0 {
+		mux.Handle("/webhook/custom/", webhookHandler("custom", &webhook.GenericHandler{Config: cfg, Gateway: gw, Limiter: limiter}))
+	}
 
 	// Token store + Google OAuth
 	var googleAuth *auth.GoogleAuth
+	var store *tokens.Store
+	var gmailClients map[string]gmail.GmailClient
 	var auditLogger *audit.Logger
+	offboard := &offboarding{dataDir: "data", deadLetters: dlq}
 	encKey := os.Getenv("RELAY_ENCRYPTION_KEY")
-	if encKey != "" && cfg.Google.ClientID != "" {
-		store, err := tokens.NewStore("data/tokens.json.enc", encKey)
+	if cfg.InMemory && cfg.Google.ClientID != "" {
+		log.Println("In-memory mode: Google OAuth and Gmail are disabled (they need on-disk tokens and state)")
+	}
+	// Why the Google features below can't run, when they can't
+	googleUnavailable := ""
+	switch {
+	case cfg.Google.ClientID == "":
+		googleUnavailable = "Google OAuth is not configured (google.client_id)"
+	case encKey == "":
+		googleUnavailable = "RELAY_ENCRYPTION_KEY is not set; Google tokens can't be stored"
+	}
+	if googleUnavailable != "" && cfg.Google.ClientID != "" && !cfg.InMemory {
+		report.Degrade("google_oauth", googleUnavailable)
+	}
+	if encKey != "" && cfg.Google.ClientID != "" && !cfg.InMemory {
+		var err error
+		store, err = tokens.NewStore("data/tokens.json.enc", encKey)
 		if err != nil {
-			log.Printf("Warning: token store init failed: %v", err)
+			googleUnavailable = fmt.Sprintf("token store init failed: %v", err)
+			report.Degrade("google_oauth", googleUnavailable)
 		} else {
 			googleAuth = auth.NewGoogleAuth(ctx, &cfg.Google, store, encKey, cfg)
+			if err := googleAuth.LoadAllowedOverrides("data/allowed-emails.json"); err != nil {
+				log.Printf("Warning: allowed-email overrides not loaded: %v", err)
+			}
+			googleAuth.SetDegraded(report.Degraded)
 			googleAuth.RegisterRoutes(mux)
 
 			// Auth status API
 			mux.HandleFunc("/api/auth/status", googleAuth.HandleAuthStatus)
-
-			// Gmail
-			if cfg.Gmail.Enabled {
-				accounts := cfg.Gmail.ResolvedAccounts()
-				if len(accounts) > 0 {
-					// Build client map for multi-account API
-					clients := make(map[string]gmail.GmailClient, len(accounts))
-					for _, acc := range accounts {
-						clients[acc.Email] = gmail.NewClientForAccount(store, googleAuth.OAuthConfig(), acc.Email)
-					}
-					gmailHandler := gmail.NewMultiHandler(clients)
-					gmailHandler.RegisterRoutes(mux)
-
-					for _, acc := range accounts {
-						client := clients[acc.Email]
-						poller := gmail.NewPollerForAccount(client, acc.Email, acc.PollInterval, acc.Rules, gw, "data", cfg.Gmail.AuthAlert)
-						poller.Start(ctx)
-					}
-					log.Printf("Gmail integration enabled for %d account(s)", len(accounts))
-				} else {
-					log.Println("Gmail enabled but no accounts configured")
-				}
-			}
+			mux.HandleFunc("/api/auth/accounts/", googleAuth.HandleAccount)
+			mux.HandleFunc("/api/auth/allowed-emails", googleAuth.HandleAllowedEmails)
+			mux.HandleFunc("/api/auth/allowed-emails/", googleAuth.HandleAllowedEmails)
 		}
 	} else {
 		// Default root page
@@ -89,29 +492,255 @@ func Run(cfg *config.Config) error {
 				return
 			}
 			w.Header().Set("Content-Type", "text/html; charset=utf-8")
-			fmt.Fprint(w, `<!DOCTYPE html><html><body><h2>openclaw-relay</h2><p>Google OAuth not configured.</p></body></html>`)
+			fmt.Fprint(w, `<!DOCTYPE html><html><body><h2>openclaw-relay</h2><p>Google OAuth not configured.</p>`)
+			report.WriteHTML(w)
+			fmt.Fprint(w, `</body></html>`)
 		})
 	}
 
+	// Gmail, and the IMAP accounts under gmail.accounts
+	if cfg.Gmail.Enabled && !cfg.InMemory {
+		accounts := cfg.Gmail.ResolvedAccounts()
+		if len(accounts) > 0 {
+			// Build client map for multi-account API
+			clients := make(map[string]gmail.GmailClient, len(accounts))
+			quotas := make(gmail.Quotas, len(accounts))
+			for _, acc := range accounts {
+				var client gmail.GmailClient
+				switch {
+				case acc.IsIMAP():
+					c := imap.NewClient(acc.Email, acc.IMAP)
+					_, maxMessages := cfg.Gmail.ResolvedHistoryLimits()
+					c.SetHistoryLimits(maxMessages)
+					client = c
+				case googleAuth != nil:
+					quotas[acc.Email] = gmail.NewQuota(acc.Email, cfg.Gmail.ResolvedDailyQuota(acc), cfg.Gmail.Quota.ResolvedThrottleAt())
+					c := gmail.NewClientForAccount(store, googleAuth.OAuthConfig(), acc.Email)
+					c.SetHistoryLimits(cfg.Gmail.ResolvedHistoryLimits())
+					c.SetHistoryEvents(acc.HistoryEvents())
+					c.SetEndpoint(cfg.Gmail.APIURL)
+					c.SetRetry(cfg.Gmail.Retry)
+					c.SetQuota(quotas[acc.Email])
+					client = c
+				default:
+					report.Degrade("gmail/"+acc.Email, googleUnavailable)
+					continue
+				}
+				if faults != nil {
+					client = faults.WrapGmail(client)
+				}
+				clients[acc.Email] = client
+			}
+			gmailClients = clients
+			screener := newScreener(cfg)
+			gmailHandler := gmail.NewMultiHandler(clients)
+			gmailHandler.SetScreener(screener)
+			gmailHandler.SetCacheTTL(cfg.Gmail.ResolvedAPICache())
+			gmailHandler.SetQuotas(quotas)
+			gmailHandler.RegisterRoutes(mux)
+			offboard.gmailAPI, offboard.gmailQuotas = gmailHandler, quotas
+			mux.HandleFunc("/api/gmail/quota", quotas.HandleQuota)
+
+			// Attachment text for rules with action.extract_text
+			textExtraction := newTextExtraction(cfg, screener)
+
+			offboard.gmailPollers = make(map[string]*gmail.Poller, len(accounts))
+			pollers := make([]*gmail.Poller, 0, len(accounts))
+			for _, acc := range accounts {
+				client, ok := clients[acc.Email]
+				if !ok {
+					continue
+				}
+				poller := gmail.NewPollerForAccount(client, acc.Email, acc.PollInterval, acc.Rules, gw, "data", cfg.Gmail.AuthAlert)
+				poller.SetTimezone(cfg.Server.Timezone)
+				poller.SetReadOnly(cfg.ReadOnly)
+				poller.SetTextExtraction(textExtraction)
+				poller.SetQuota(quotas[acc.Email])
+				poller.Start(ctx)
+				offboard.gmailPollers[acc.Email] = poller
+				pollers = append(pollers, poller)
+			}
+
+			watchdog := gmail.NewWatchdog(pollers, cfg.Gmail.Watchdog, gw)
+			watchdog.Start(ctx)
+			mux.HandleFunc("/api/gmail/pollers", watchdog.HandlePollers)
+			heartbeats.AddPending("stalled Gmail pollers", func() int {
+				n := 0
+				for _, s := range watchdog.Statuses(time.Now()) {
+					if s.Stalled {
+						n++
+					}
+				}
+				return n
+			})
+
+			// State of accounts removed from the config
+			configured := make([]string, 0, len(accounts))
+			for _, acc := range accounts {
+				configured = append(configured, acc.Email)
+			}
+			collector.Add("gmail_state", func(_ time.Time, dryRun bool) ([]string, error) {
+				return gmail.PruneState("data", configured, dryRun)
+			})
+			log.Printf("Gmail integration enabled for %d account(s)", len(clients))
+		} else {
+			log.Println("Gmail enabled but no accounts configured")
+		}
+	}
+
+	// Google Calendar: the API for the agent, and upcoming-event rules
+	if cfg.Calendar.Enabled && !cfg.InMemory {
+		if googleAuth == nil {
+			report.Degrade("calendar", googleUnavailable)
+		} else {
+			clients := make(map[string]calendar.CalendarClient, len(cfg.Calendar.Accounts))
+			for _, acc := range cfg.Calendar.Accounts {
+				c := calendar.NewClientForAccount(store, googleAuth.OAuthConfig(), acc)
+				c.SetEndpoint(cfg.Calendar.APIURL)
+				clients[acc] = c
+			}
+			calendarHandler := calendar.NewHandler(cfg.Calendar.Accounts, clients)
+			calendarHandler.SetWrite(cfg.Calendar.Write)
+			calendarHandler.RegisterRoutes(mux)
+			if len(cfg.Calendar.Rules) > 0 {
+				poller := calendar.NewPoller(clients, cfg.Calendar.Accounts, cfg.Calendar.Rules, cfg.Calendar.ResolvedPollInterval(), gw, "data")
+				poller.SetTimezone(cfg.Server.Timezone)
+				poller.Start(ctx)
+				offboard.calendarPoller = poller
+			}
+			log.Printf("Calendar integration enabled for %d account(s), %d rule(s), write %v", len(clients), len(cfg.Calendar.Rules), cfg.Calendar.Write)
+		}
+	}
+
+	// Google Drive: file content for the agent, and changed-file rules
+	if cfg.Drive.Enabled && !cfg.InMemory {
+		if googleAuth == nil {
+			report.Degrade("drive", googleUnavailable)
+		} else {
+			clients := make(map[string]drive.DriveClient, len(cfg.Drive.Accounts))
+			for _, acc := range cfg.Drive.Accounts {
+				c := drive.NewClientForAccount(store, googleAuth.OAuthConfig(), acc)
+				c.SetEndpoint(cfg.Drive.APIURL)
+				clients[acc] = c
+			}
+			// Downloads are screened like mail attachments, up to the Drive cap
+			screener := newScreener(cfg)
+			screener.MaxBytes = cfg.Drive.ResolvedMaxDownloadBytes()
+			driveHandler := drive.NewHandler(cfg.Drive.Accounts, clients, cfg.Drive.ResolvedMaxDownloadBytes())
+			driveHandler.SetScreener(screener)
+			driveHandler.RegisterRoutes(mux)
+			if len(cfg.Drive.Rules) > 0 {
+				poller := drive.NewPoller(clients, cfg.Drive, gw, limiter, "data")
+				poller.SetTimezone(cfg.Server.Timezone)
+				poller.Start(ctx)
+				offboard.drivePoller = poller
+			}
+			log.Printf("Drive integration enabled for %d account(s), %d rule(s)", len(clients), len(cfg.Drive.Rules))
+		}
+	}
+
+	// Google Tasks: read-only task lists for the agent
+	if cfg.Tasks.Enabled && !cfg.InMemory {
+		if googleAuth == nil {
+			report.Degrade("tasks", googleUnavailable)
+		} else {
+			clients := make(map[string]tasks.TasksClient, len(cfg.Tasks.Accounts))
+			for _, acc := range cfg.Tasks.Accounts {
+				c := tasks.NewClientForAccount(store, googleAuth.OAuthConfig(), acc)
+				c.SetEndpoint(cfg.Tasks.APIURL)
+				clients[acc] = c
+			}
+			tasksHandler := tasks.NewHandler(cfg.Tasks.Accounts, clients)
+			tasksHandler.SetTimezone(cfg.Server.Timezone)
+			tasksHandler.RegisterRoutes(mux)
+			log.Printf("Tasks integration enabled for %d account(s)", len(clients))
+		}
+	}
+
+	// Off-boarding purges every per-account store, whichever Google features
+	// are enabled
+	if googleAuth != nil {
+		offboard.register(googleAuth)
+	}
+
+	// GitHub App API for the agent
+	if ghClient != nil {
+		github.NewHandler(ghClient).RegisterRoutes(mux)
+		log.Printf("GitHub App API enabled (app %d)", cfg.GitHub.App.AppID)
+	}
+
+	// Trello REST API for the agent, and webhook registration for trello.boards
+	var trelloHooks *trello.WebhookManager
+	if trelloClient != nil {
+		trello.NewHandler(trelloClient, cfg.Trello.Lists, trelloLists).RegisterRoutes(mux)
+		log.Println("Trello API enabled")
+		if len(cfg.Trello.Boards) > 0 && !cfg.InMemory {
+			trelloHooks = trello.NewWebhookManager(trelloClient, cfg.Trello.Boards, cfg.Trello.CallbackURL)
+		}
+	}
+	if trelloBoards != nil {
+		mux.HandleFunc("/api/trello/boards", trelloBoards.HandleList)
+		switch {
+		case cfg.InMemory:
+		case trelloLists != nil:
+			go trelloLists.Run(ctx, cfg.Trello.ResolvedBoardRefresh())
+		default:
+			go trelloBoards.Run(ctx, cfg.Trello.ResolvedBoardRefresh())
+		}
+	}
+
+	// Control channel: the gateway pushes commands over a long poll the relay opens
+	if cfg.Gateway.Control.Enabled && !cfg.InMemory {
+		channel := control.NewChannel(cfg.Gateway.URL, cfg.Gateway.Token, cfg.Gateway.Control.ResolvedWait())
+		channel.Pauses = pauses
+		channel.Events = eventStore
+		channel.Recovery = recovery
+		channel.Gmail = gmailClients
+		channel.ReadOnly = cfg.ReadOnly
+		channel.Start(ctx)
+		mux.HandleFunc("/api/control", channel.HandleStatus)
+	}
+
+	// GC: GET reports what a run would remove, POST runs it
+	collector.Start(ctx)
+	mux.HandleFunc("/api/gc", collector.HandleGC)
+
 	// API status
 	mux.HandleFunc("/api/status", func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "application/json")
 		w.Write([]byte(`{"status":"ok","service":"openclaw-relay"}`))
 	})
 
-	// Wrap with auth middleware
+	// Read-only mode blocks mutating API calls
 	var handler http.Handler = mux
+	if cfg.ReadOnly {
+		log.Println("Read-only mode enabled: mutating /api/* requests are rejected")
+		handler = auth.ReadOnly(handler)
+	}
+
+	// Wrap with auth middleware; API keys only reach the Gmail API
 	if cfg.Server.InternalToken != "" {
-		handler = auth.Middleware(cfg.Server.InternalToken, handler)
+		handler = auth.KeyMiddleware(cfg.Server.InternalToken, cfg.Server.APIKeys, handler)
+	}
+
+	// CORS for browser apps on other origins; preflights skip the token check
+	if len(cfg.Server.CORS) > 0 {
+		handler = auth.CORS(cfg.Server.CORS, handler)
 	}
 
 	// Wrap with audit middleware
-	var err error
-	auditLogger, err = audit.NewLogger(cfg.Audit.LogPath)
-	if err != nil {
-		log.Printf("Warning: audit log disabled: %v", err)
-	} else {
-		handler = audit.Middleware(auditLogger, handler)
+	if !cfg.InMemory {
+		auditLogger, err = audit.FromConfig(cfg.Audit)
+		if err != nil {
+			report.Degrade("audit_log", err.Error())
+		} else {
+			handler = audit.Middleware(auditLogger, handler)
+		}
+	}
+
+	// Strict mode would rather not start than run without a configured feature
+	if err := report.Err(); err != nil && cfg.Server.Strict {
+		return fmt.Errorf("server.strict: %w", err)
 	}
 
 	srv := &http.Server{
@@ -119,20 +748,37 @@ func Run(cfg *config.Config) error {
 		Handler: handler,
 	}
 
+	// Listen before serving so webhook registration can reach the callback URL
+	ln, err := net.Listen("tcp", srv.Addr)
+	if err != nil {
+		return err
+	}
+
 	// Start server in goroutine
 	errCh := make(chan error, 1)
 	go func() {
 		log.Printf("openclaw-relay starting on %s", srv.Addr)
 		log.Printf("Agent: %s, Gateway: %s", cfg.Gateway.AgentID, cfg.Gateway.URL)
-		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		if err := srv.Serve(ln); err != nil && err != http.ErrServerClosed {
 			errCh <- err
 		}
 	}()
 
+	// Trello verifies a new webhook with a HEAD request to the callback URL
+	if trelloHooks != nil {
+		go func() {
+			syncCtx, syncCancel := context.WithTimeout(ctx, time.Minute)
+			defer syncCancel()
+			if err := trelloHooks.Sync(syncCtx); err != nil {
+				log.Printf("Trello webhook registration failed: %v", err)
+			}
+		}()
+	}
+
 	// Wait for shutdown signal or server error
 	select {
 	case <-ctx.Done():
-		log.Println("Shutdown signal received")
+		log.Println("Shutdown requested")
 	case err := <-errCh:
 		return err
 	}
@@ -141,10 +787,24 @@ func Run(cfg *config.Config) error {
 	shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer shutdownCancel()
 
+	if trelloHooks != nil {
+		if err := trelloHooks.Remove(shutdownCtx); err != nil {
+			log.Printf("Trello webhook removal failed: %v", err)
+		}
+	}
+
 	if err := srv.Shutdown(shutdownCtx); err != nil {
 		log.Printf("HTTP server shutdown error: %v", err)
 	}
 
+	// Write state whose writes are batched
+	if err := history.Flush(); err != nil {
+		log.Printf("Failed to save event history: %v", err)
+	}
+	if err := deliveries.Flush(); err != nil {
+		log.Printf("Failed to save delivery IDs: %v", err)
+	}
+
 	// Close audit logger
 	if auditLogger != nil {
 		auditLogger.Close()
@@ -153,3 +813,71 @@ func Run(cfg *config.Config) error {
 	log.Println("Server stopped")
 	return nil
 }
+
+// refreshGitHubRanges loads GitHub's published hook ranges into the allowlist
+// now and every interval, on top of the configured ones. A failed fetch keeps
+// the previous ranges.
+func refreshGitHubRanges(ctx context.Context, allowlist *webhook.IPAllowlist, configured []*net.IPNet, apiURL string, interval time.Duration) {
+	client := &http.Client{Timeout: 30 * time.Second}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		ranges, err := github.HookRanges(ctx, client, apiURL)
+		if err == nil {
+			var nets []*net.IPNet
+			if nets, err = webhook.ParseCIDRs(ranges); err == nil {
+				allowlist.SetRanges("github", append(append([]*net.IPNet{}, configured...), nets...))
+				log.Printf("IP allowlist: loaded %d GitHub hook ranges", len(nets))
+			}
+		}
+		if err != nil {
+			log.Printf("IP allowlist: failed to load GitHub hook ranges: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// newGatewayClient returns the gateway client the config describes.
+func newGatewayClient(cfg *config.Config) *gateway.Client {
+	c := gateway.NewClient(cfg.Gateway.URL, cfg.Gateway.Token, cfg.Gateway.AgentID, cfg.Gateway.Model)
+	c.SigningSecret = cfg.Gateway.SigningSecret
+	c.Tags = cfg.Gateway.Tags
+	if cfg.Gateway.JobName != "" {
+		// Validated with the config
+		c.JobName, _ = gateway.ParseJobName(cfg.Gateway.JobName)
+	}
+	return c
+}
+
+// newScreener returns the attachment screener of gmail.attachments.
+func newScreener(cfg *config.Config) *screening.Screener {
+	denyExt := cfg.Gmail.Attachments.ResolvedDenyExtensions()
+	if denyExt == nil {
+		denyExt = screening.DefaultDenyExtensions
+	}
+	return &screening.Screener{
+		MaxBytes:       cfg.Gmail.Attachments.ResolvedMaxBytes(),
+		DenyExtensions: denyExt,
+		ClamAV:         cfg.Gmail.Attachments.ClamAV,
+		Timeout:        cfg.Gmail.Attachments.ResolvedClamAVTimeout(),
+	}
+}
+
+// newTextExtraction returns the attachment text extraction of
+// gmail.text_extraction, or nil when it has no URL.
+func newTextExtraction(cfg *config.Config, screener *screening.Screener) *gmail.TextExtraction {
+	te := cfg.Gmail.TextExtraction
+	if te.URL == "" {
+		return nil
+	}
+	return &gmail.TextExtraction{
+		Extractor: extract.NewClient(te.URL, te.Headers, te.ResolvedTimeout()),
+		Screener:  screener,
+		MimeTypes: te.MimeTypes,
+		MaxChars:  te.ResolvedMaxChars(),
+	}
+}
@@ -0,0 +1,319 @@
+// Package simulate builds realistic, correctly signed webhook deliveries
+// for end-to-end testing of rules against a running relay.
+package simulate
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/katalabut/openclaw-relay/internal/config"
+)
+
+// Options describe the event to simulate. Only the fields relevant to Source/Event are used.
+type Options struct {
+	Source string // trello, github, slack, jira
+	Event  string
+	URL    string // relay base URL, e.g. http://localhost:8080
+
+	Card       string // trello card name
+	List       string // trello list name (key in trello.lists)
+	From       string // trello previous list, or jira previous status
+	Text       string // comment or message text
+	User       string // trello member, slack user or jira account ID
+	Repo       string // github owner/name
+	PR         int    // github pull request number
+	Conclusion string // github check/workflow conclusion
+	Channel    string // slack channel ID
+	Issue      string // jira issue key
+	Status     string // jira status
+}
+
+// Events lists the supported events per source.
+var Events = map[string][]string{
+	"trello": {"card_moved", "comment_added"},
+	"github": {"check_run", "workflow_run", "pull_request_review"},
+	"slack":  {"app_mention", "message"},
+	"jira":   {"issue_created", "issue_updated", "comment_created"},
+}
+
+// BuildRequest crafts the delivery the source would send, signed with the secrets in cfg.
+func BuildRequest(cfg *config.Config, opts Options) (*http.Request, error) {
+	known := false
+	for _, e := range Events[opts.Source] {
+		if e == opts.Event {
+			known = true
+		}
+	}
+	if !known {
+		if _, ok := Events[opts.Source]; !ok {
+			return nil, fmt.Errorf("unknown source %q (trello, github, slack, jira)", opts.Source)
+		}
+		return nil, fmt.Errorf("unknown %s event %q (%s)", opts.Source, opts.Event, strings.Join(Events[opts.Source], ", "))
+	}
+
+	base, err := url.Parse(strings.TrimRight(opts.URL, "/"))
+	if err != nil || base.Host == "" {
+		return nil, fmt.Errorf("invalid relay URL %q", opts.URL)
+	}
+	target := base.String() + "/webhook/" + opts.Source
+
+	var body []byte
+	header := http.Header{}
+	switch opts.Source {
+	case "trello":
+		body, err = trelloPayload(cfg, opts)
+		if err != nil {
+			return nil, err
+		}
+		if cfg.Trello.Secret != "" {
+			// The relay signs against the callback URL it derives from Host and path
+			mac := hmac.New(sha1.New, []byte(cfg.Trello.Secret))
+			mac.Write(body)
+			mac.Write([]byte("https://" + base.Host + "/webhook/trello"))
+			header.Set("X-Trello-Webhook", base64.StdEncoding.EncodeToString(mac.Sum(nil)))
+		}
+	case "github":
+		body = githubPayload(opts)
+		header.Set("X-GitHub-Event", opts.Event)
+		header.Set("X-GitHub-Delivery", randomHex(16))
+		if cfg.GitHub.Secret != "" {
+			header.Set("X-Hub-Signature-256", "sha256="+hmacSHA256Hex(cfg.GitHub.Secret, body))
+		}
+	case "slack":
+		body = slackPayload(opts)
+		if cfg.Slack.SigningSecret != "" {
+			ts := strconv.FormatInt(time.Now().Unix(), 10)
+			header.Set("X-Slack-Request-Timestamp", ts)
+			header.Set("X-Slack-Signature", "v0="+hmacSHA256Hex(cfg.Slack.SigningSecret, []byte("v0:"+ts+":"+string(body))))
+		}
+	case "jira":
+		body = jiraPayload(opts)
+		if cfg.Jira.Secret != "" {
+			header.Set("X-Hub-Signature", "sha256="+hmacSHA256Hex(cfg.Jira.Secret, body))
+		}
+	}
+
+	req, err := http.NewRequest(http.MethodPost, target, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header = header
+	req.Header.Set("Content-Type", "application/json")
+	return req, nil
+}
+
+// Send builds the delivery, posts it to the relay and returns the response status and body.
+func Send(cfg *config.Config, opts Options) (int, string, error) {
+	req, err := BuildRequest(cfg, opts)
+	if err != nil {
+		return 0, "", err
+	}
+	resp, err := (&http.Client{Timeout: 30 * time.Second}).Do(req)
+	if err != nil {
+		return 0, "", err
+	}
+	defer resp.Body.Close()
+	respBody, _ := io.ReadAll(resp.Body)
+	return resp.StatusCode, string(respBody), nil
+}
+
+// LoadResult summarizes a Load run.
+type LoadResult struct {
+	Sent     int
+	Failed   int // transport errors and non-2xx responses
+	Duration time.Duration
+}
+
+// PerSecond returns the achieved request rate.
+func (r LoadResult) PerSecond() float64 {
+	if r.Duration <= 0 {
+		return 0
+	}
+	return float64(r.Sent) / r.Duration.Seconds()
+}
+
+// Load sends count deliveries with the given concurrency. Each delivery is built
+// fresh, so IDs differ and only sources keyed on user-supplied values (e.g. GitHub PR) are deduplicated.
+func Load(cfg *config.Config, opts Options, count, concurrency int) (LoadResult, error) {
+	if _, err := BuildRequest(cfg, opts); err != nil {
+		return LoadResult{}, err
+	}
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	client := &http.Client{Timeout: 30 * time.Second}
+	jobs := make(chan struct{})
+	var mu sync.Mutex
+	var res LoadResult
+	var wg sync.WaitGroup
+
+	start := time.Now()
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range jobs {
+				ok := false
+				if req, err := BuildRequest(cfg, opts); err == nil {
+					if resp, err := client.Do(req); err == nil {
+						io.Copy(io.Discard, resp.Body)
+						resp.Body.Close()
+						ok = resp.StatusCode < 300
+					}
+				}
+				mu.Lock()
+				res.Sent++
+				if !ok {
+					res.Failed++
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+	for i := 0; i < count; i++ {
+		jobs <- struct{}{}
+	}
+	close(jobs)
+	wg.Wait()
+	res.Duration = time.Since(start)
+	return res, nil
+}
+
+func trelloPayload(cfg *config.Config, opts Options) ([]byte, error) {
+	card := map[string]string{"id": randomHex(12), "name": opts.Card}
+	member := opts.User
+	if member == "" {
+		member = "simulator"
+	}
+	data := map[string]any{"card": card}
+	actionType := "commentCard"
+
+	if opts.Event == "card_moved" {
+		listID, ok := cfg.Trello.Lists[opts.List]
+		if !ok {
+			return nil, fmt.Errorf("list %q is not in trello.lists", opts.List)
+		}
+		actionType = "updateCard"
+		data["listAfter"] = map[string]string{"id": listID, "name": opts.List}
+		if opts.From != "" {
+			data["listBefore"] = map[string]string{"id": cfg.Trello.Lists[opts.From], "name": opts.From}
+		}
+	} else {
+		data["text"] = opts.Text
+	}
+
+	return json.Marshal(map[string]any{
+		"action": map[string]any{
+			"id":            randomHex(12),
+			"type":          actionType,
+			"date":          time.Now().UTC().Format(time.RFC3339),
+			"data":          data,
+			"memberCreator": map[string]string{"id": randomHex(12), "username": member},
+		},
+	})
+}
+
+func githubPayload(opts Options) []byte {
+	repo := opts.Repo
+	if repo == "" {
+		repo = "example/repo"
+	}
+	conclusion := opts.Conclusion
+	if conclusion == "" {
+		conclusion = "failure"
+	}
+	prs := []map[string]int{{"number": opts.PR}}
+	p := map[string]any{
+		"action":     "completed",
+		"repository": map[string]string{"full_name": repo},
+	}
+	switch opts.Event {
+	case "check_run":
+		p["check_run"] = map[string]any{"name": "ci", "conclusion": conclusion, "pull_requests": prs}
+	case "workflow_run":
+		p["workflow_run"] = map[string]any{"name": "ci", "conclusion": conclusion, "pull_requests": prs}
+	case "pull_request_review":
+		p["action"] = "submitted"
+		p["pull_request"] = map[string]any{"number": opts.PR, "title": opts.Text}
+		p["review"] = map[string]any{"state": "changes_requested", "body": opts.Text}
+	}
+	body, _ := json.Marshal(p)
+	return body
+}
+
+func slackPayload(opts Options) []byte {
+	user := opts.User
+	if user == "" {
+		user = "USIMULATOR"
+	}
+	body, _ := json.Marshal(map[string]any{
+		"type":     "event_callback",
+		"team_id":  "TSIMULATOR",
+		"event_id": "Ev" + strings.ToUpper(randomHex(5)),
+		"event": map[string]any{
+			"type":    opts.Event,
+			"channel": opts.Channel,
+			"user":    user,
+			"text":    opts.Text,
+			"ts":      fmt.Sprintf("%d.000100", time.Now().Unix()),
+		},
+	})
+	return body
+}
+
+func jiraPayload(opts Options) []byte {
+	project, _, _ := strings.Cut(opts.Issue, "-")
+	user := map[string]string{"accountId": opts.User, "displayName": "Simulator"}
+	p := map[string]any{
+		"webhookEvent": opts.Event,
+		"user":         user,
+		"issue": map[string]any{
+			"key":  opts.Issue,
+			"self": "https://jira.example.com/rest/api/2/issue/" + randomHex(4),
+			"fields": map[string]any{
+				"summary": opts.Card,
+				"status":  map[string]string{"name": opts.Status},
+				"project": map[string]string{"key": project},
+			},
+		},
+	}
+	switch opts.Event {
+	case "issue_created", "issue_updated":
+		p["webhookEvent"] = "jira:" + opts.Event
+		if opts.Event == "issue_updated" {
+			p["changelog"] = map[string]any{"items": []map[string]string{
+				{"field": "status", "fromString": opts.From, "toString": opts.Status},
+			}}
+		}
+	case "comment_created":
+		p["comment"] = map[string]any{"id": randomHex(4), "body": opts.Text, "author": user}
+	}
+	body, _ := json.Marshal(p)
+	return body
+}
+
+func hmacSHA256Hex(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func randomHex(n int) string {
+	b := make([]byte, n)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
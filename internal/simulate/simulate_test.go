@@ -0,0 +1,171 @@
+package simulate
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/katalabut/openclaw-relay/internal/config"
+	"github.com/katalabut/openclaw-relay/internal/ratelimit"
+	"github.com/katalabut/openclaw-relay/internal/webhook"
+)
+
+type mockGateway struct {
+	messages []string
+}
+
+func (m *mockGateway) CreateOneShotJob(name, message string, timeoutSeconds, delaySeconds int) error {
+	m.messages = append(m.messages, message)
+	return nil
+}
+
+func (m *mockGateway) CreateOneShotJobForAgent(name, message, agentID string, timeoutSeconds, delaySeconds int) error {
+	m.messages = append(m.messages, message)
+	return nil
+}
+
+func testConfig() *config.Config {
+	return &config.Config{
+		Trello: config.TrelloConfig{
+			Secret: "trello-secret",
+			Lists:  map[string]string{"ready": "list-ready", "backlog": "list-backlog"},
+			Rules: []config.TrelloRule{
+				{Event: "card_moved", Condition: "list == 'ready'", Action: config.RuleAction{MessageTemplate: "moved {{.CardName}} from {{.ListBeforeName}}"}},
+				{Event: "comment_added", Action: config.RuleAction{MessageTemplate: "comment on {{.CardName}}"}},
+			},
+		},
+		GitHub: config.GitHubConfig{Secret: "gh-secret", MessageTemplate: "{{.Event}} {{.Repository}}#{{.PRNumber}} {{.Conclusion}}"},
+		Slack: config.SlackConfig{
+			SigningSecret: "slack-secret",
+			Rules:         []config.SlackRule{{Event: "app_mention", Action: config.RuleAction{MessageTemplate: "{{.Channel}}: {{.Text}}"}}},
+		},
+		Jira: config.JiraConfig{
+			Secret: "jira-secret",
+			Rules: []config.JiraRule{
+				{Event: "issue_updated", Action: config.RuleAction{MessageTemplate: "{{.IssueKey}} {{.FromStatus}}->{{.Status}}"}},
+				{Event: "comment_created", Action: config.RuleAction{MessageTemplate: "{{.IssueKey}}: {{.Comment}}"}},
+			},
+		},
+	}
+}
+
+func newMux(cfg *config.Config, gw *mockGateway) *http.ServeMux {
+	limiter := ratelimit.New(context.Background(), 5*time.Minute)
+	mux := http.NewServeMux()
+	mux.Handle("/webhook/trello", &webhook.TrelloHandler{Config: cfg, Gateway: gw, Limiter: limiter})
+	mux.Handle("/webhook/github", &webhook.GitHubHandler{Config: cfg, Gateway: gw, Limiter: limiter})
+	mux.Handle("/webhook/slack", &webhook.SlackHandler{Config: cfg, Gateway: gw, Limiter: limiter})
+	mux.Handle("/webhook/jira", &webhook.JiraHandler{Config: cfg, Gateway: gw, Limiter: limiter})
+	return mux
+}
+
+// Deliveries built by the simulator must pass the real handlers' signature checks and rules.
+func TestBuildRequest_AcceptedByHandlers(t *testing.T) {
+	tests := []struct {
+		opts Options
+		want string
+	}{
+		{Options{Source: "trello", Event: "card_moved", Card: "Fix login", List: "ready", From: "backlog"}, "moved Fix login from backlog"},
+		{Options{Source: "trello", Event: "comment_added", Card: "Fix login", Text: "ping"}, "comment on Fix login"},
+		{Options{Source: "github", Event: "check_run", Repo: "acme/api", PR: 42, Conclusion: "failure"}, "check_run acme/api#42 failure"},
+		{Options{Source: "github", Event: "pull_request_review", Repo: "acme/api", PR: 7}, "pull_request_review acme/api#7 "},
+		{Options{Source: "slack", Event: "app_mention", Channel: "C1", Text: "deploy"}, "C1: deploy"},
+		{Options{Source: "jira", Event: "issue_updated", Issue: "OPS-3", From: "To Do", Status: "Done"}, "OPS-3 To Do->Done"},
+		{Options{Source: "jira", Event: "comment_created", Issue: "OPS-3", Text: "lgtm"}, "OPS-3: lgtm"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.opts.Source+"/"+tt.opts.Event, func(t *testing.T) {
+			cfg := testConfig()
+			gw := &mockGateway{}
+			tt.opts.URL = "http://relay.local:8080"
+
+			req, err := BuildRequest(cfg, tt.opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			rec := httptest.NewRecorder()
+			newMux(cfg, gw).ServeHTTP(rec, req)
+
+			if rec.Code != http.StatusOK {
+				t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+			}
+			if len(gw.messages) != 1 || gw.messages[0] != tt.want {
+				t.Errorf("expected message %q, got %v", tt.want, gw.messages)
+			}
+		})
+	}
+}
+
+func TestBuildRequest_Errors(t *testing.T) {
+	cfg := testConfig()
+	tests := []struct {
+		opts Options
+		want string
+	}{
+		{Options{Source: "gitlab", Event: "push", URL: "http://localhost:8080"}, "unknown source"},
+		{Options{Source: "trello", Event: "card_deleted", URL: "http://localhost:8080"}, "unknown trello event"},
+		{Options{Source: "trello", Event: "card_moved", List: "nope", URL: "http://localhost:8080"}, "not in trello.lists"},
+		{Options{Source: "slack", Event: "message", URL: "::"}, "invalid relay URL"},
+	}
+	for _, tt := range tests {
+		if _, err := BuildRequest(cfg, tt.opts); err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%+v: expected error containing %q, got %v", tt.opts, tt.want, err)
+		}
+	}
+}
+
+func TestSend(t *testing.T) {
+	cfg := testConfig()
+	gw := &mockGateway{}
+	srv := httptest.NewServer(newMux(cfg, gw))
+	defer srv.Close()
+
+	status, body, err := Send(cfg, Options{Source: "slack", Event: "app_mention", Channel: "C9", Text: "hi", URL: srv.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status != http.StatusOK || body != `{"ok":true}` || len(gw.messages) != 1 {
+		t.Errorf("unexpected result: %d %s (calls=%d)", status, body, len(gw.messages))
+	}
+}
+
+func TestLoad(t *testing.T) {
+	cfg := testConfig()
+	gw := &countingGateway{}
+	limiter := ratelimit.New(context.Background(), 5*time.Minute)
+	srv := httptest.NewServer(&webhook.TrelloHandler{Config: cfg, Gateway: gw, Limiter: limiter})
+	defer srv.Close()
+
+	opts := Options{Source: "trello", Event: "card_moved", Card: "Load", List: "ready", URL: srv.URL}
+	res, err := Load(cfg, opts, 20, 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Sent != 20 || res.Failed != 0 || res.PerSecond() <= 0 {
+		t.Errorf("unexpected result: %+v", res)
+	}
+	// Random card IDs mean no delivery is deduplicated
+	if gw.n.Load() != 20 {
+		t.Errorf("expected 20 jobs, got %d", gw.n.Load())
+	}
+
+	if _, err := Load(cfg, Options{Source: "nope"}, 1, 1); err == nil {
+		t.Error("expected error for invalid options")
+	}
+}
+
+type countingGateway struct{ n atomic.Int64 }
+
+func (g *countingGateway) CreateOneShotJob(name, message string, timeoutSeconds, delaySeconds int) error {
+	g.n.Add(1)
+	return nil
+}
+
+func (g *countingGateway) CreateOneShotJobForAgent(name, message, agentID string, timeoutSeconds, delaySeconds int) error {
+	g.n.Add(1)
+	return nil
+}
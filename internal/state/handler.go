@@ -0,0 +1,116 @@
+package state
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/katalabut/openclaw-relay/internal/schedule"
+)
+
+// Handler serves /api/state for agent jobs to share workflow state.
+type Handler struct {
+	store *Store
+}
+
+func NewHandler(store *Store) *Handler {
+	return &Handler{store: store}
+}
+
+// RegisterRoutes adds the state routes to the mux.
+func (h *Handler) RegisterRoutes(mux *http.ServeMux) {
+	mux.HandleFunc("/api/state/", h.handleState)
+}
+
+func jsonResponse(w http.ResponseWriter, data any) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(data)
+}
+
+func jsonError(w http.ResponseWriter, msg string, code int) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(map[string]string{"error": msg})
+}
+
+// parseTTL parses a PUT's ttl: empty for none, else a duration such as 90m or 7d.
+func parseTTL(s string) (time.Duration, error) {
+	if s == "" {
+		return 0, nil
+	}
+	d, err := schedule.ParseDuration(s)
+	if err != nil || d < 0 {
+		return 0, fmt.Errorf("invalid ttl %q (use e.g. 90m, 24h or 7d)", s)
+	}
+	return d, nil
+}
+
+// handleState routes:
+//
+//	GET    /api/state/{ns}         list live entries
+//	GET    /api/state/{ns}/{key}
+//	PUT    /api/state/{ns}/{key}   {"value": <any JSON>, "ttl": "24h"}
+//	DELETE /api/state/{ns}/{key}
+func (h *Handler) handleState(w http.ResponseWriter, r *http.Request) {
+	ns, key, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/api/state/"), "/")
+	if ns == "" {
+		jsonError(w, "namespace is required", http.StatusBadRequest)
+		return
+	}
+
+	if key == "" {
+		if r.Method != http.MethodGet {
+			jsonError(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		jsonResponse(w, map[string]any{"entries": h.store.List(ns)})
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		e, ok := h.store.Get(ns, key)
+		if !ok {
+			jsonError(w, "not found", http.StatusNotFound)
+			return
+		}
+		jsonResponse(w, e)
+	case http.MethodPut:
+		var req struct {
+			Value json.RawMessage `json:"value"`
+			TTL   string          `json:"ttl"`
+		}
+		r.Body = http.MaxBytesReader(w, r.Body, MaxValueBytes+1024)
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			jsonError(w, "invalid JSON", http.StatusBadRequest)
+			return
+		}
+		ttl, err := parseTTL(req.TTL)
+		if err != nil {
+			jsonError(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		e, err := h.store.Put(ns, key, req.Value, ttl)
+		if errors.Is(err, ErrInvalidName) || errors.Is(err, ErrInvalidValue) || errors.Is(err, ErrValueTooLarge) {
+			jsonError(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err != nil {
+			jsonError(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		jsonResponse(w, e)
+	case http.MethodDelete:
+		deleted, err := h.store.Delete(ns, key)
+		if err != nil {
+			jsonError(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		jsonResponse(w, map[string]any{"deleted": deleted})
+	default:
+		jsonError(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
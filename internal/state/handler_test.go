@@ -0,0 +1,81 @@
+package state
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func serve(h *Handler, method, path, body string) *httptest.ResponseRecorder {
+	mux := http.NewServeMux()
+	h.RegisterRoutes(mux)
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(method, path, strings.NewReader(body)))
+	return rec
+}
+
+func TestHandler_State(t *testing.T) {
+	s, _ := NewStore("")
+	h := NewHandler(s)
+
+	rec := serve(h, "PUT", "/api/state/deploy/api", `{"value":{"sha":"abc","attempt":2},"ttl":"1d"}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("PUT: expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var put Entry
+	json.NewDecoder(rec.Body).Decode(&put)
+	if put.ExpiresAt == nil || put.ExpiresAt.Sub(put.UpdatedAt) != 24*time.Hour {
+		t.Errorf("expected 1d TTL, got %+v", put)
+	}
+
+	rec = serve(h, "GET", "/api/state/deploy/api", "")
+	var got Entry
+	json.NewDecoder(rec.Body).Decode(&got)
+	if rec.Code != http.StatusOK || string(got.Value) != `{"sha":"abc","attempt":2}` {
+		t.Fatalf("GET: unexpected %d %+v", rec.Code, got)
+	}
+
+	rec = serve(h, "GET", "/api/state/deploy", "")
+	if !strings.Contains(rec.Body.String(), `"key":"api"`) {
+		t.Errorf("list: unexpected %s", rec.Body.String())
+	}
+
+	rec = serve(h, "DELETE", "/api/state/deploy/api", "")
+	if !strings.Contains(rec.Body.String(), `"deleted":true`) {
+		t.Errorf("DELETE: unexpected %s", rec.Body.String())
+	}
+	if rec = serve(h, "GET", "/api/state/deploy/api", ""); rec.Code != http.StatusNotFound {
+		t.Errorf("GET after delete: expected 404, got %d", rec.Code)
+	}
+	rec = serve(h, "GET", "/api/state/deploy", "")
+	if !strings.Contains(rec.Body.String(), `"entries":[]`) {
+		t.Errorf("expected empty list, got %s", rec.Body.String())
+	}
+}
+
+func TestHandler_StateErrors(t *testing.T) {
+	s, _ := NewStore("")
+	h := NewHandler(s)
+	tests := []struct {
+		method, path, body string
+		want               int
+	}{
+		{"GET", "/api/state/", "", http.StatusBadRequest},
+		{"PUT", "/api/state/ns", `{"value":1}`, http.StatusMethodNotAllowed},
+		{"POST", "/api/state/ns/k", `{"value":1}`, http.StatusMethodNotAllowed},
+		{"PUT", "/api/state/ns/k", `not json`, http.StatusBadRequest},
+		{"PUT", "/api/state/ns/k", `{}`, http.StatusBadRequest},
+		{"PUT", "/api/state/ns/k", `{"value":1,"ttl":"soon"}`, http.StatusBadRequest},
+		{"PUT", "/api/state/ns/k", `{"value":1,"ttl":"-5m"}`, http.StatusBadRequest},
+		{"PUT", "/api/state/ns/a%20b", `{"value":1}`, http.StatusBadRequest},
+		{"PUT", "/api/state/ns/k", `{"value":"` + strings.Repeat("x", MaxValueBytes+2048) + `"}`, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		if rec := serve(h, tt.method, tt.path, tt.body); rec.Code != tt.want {
+			t.Errorf("%s %s: expected %d, got %d: %s", tt.method, tt.path, tt.want, rec.Code, rec.Body.String())
+		}
+	}
+}
@@ -0,0 +1,177 @@
+// Package state is a small namespaced key-value store that lets agent jobs
+// spawned by different webhooks share workflow state through /api/state.
+package state
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/katalabut/openclaw-relay/internal/atomicfile"
+)
+
+// MaxValueBytes caps the encoded size of a single value.
+const MaxValueBytes = 64 << 10
+
+var (
+	// ErrInvalidName is returned for namespaces or keys outside [A-Za-z0-9_.:-]{1,128}.
+	ErrInvalidName = errors.New("namespace and key must be 1-128 characters of letters, digits, '_', '-', '.' or ':'")
+	// ErrValueTooLarge is returned by Put for values over MaxValueBytes.
+	ErrValueTooLarge = fmt.Errorf("value must be at most %d bytes", MaxValueBytes)
+	// ErrInvalidValue is returned by Put for a missing or malformed JSON value.
+	ErrInvalidValue = errors.New("value must be valid JSON")
+)
+
+var namePattern = regexp.MustCompile(`^[A-Za-z0-9_.:-]{1,128}$`)
+
+// Entry is a stored value. ExpiresAt is nil for entries without a TTL.
+type Entry struct {
+	Namespace string          `json:"namespace"`
+	Key       string          `json:"key"`
+	Value     json.RawMessage `json:"value"`
+	UpdatedAt time.Time       `json:"updated_at"`
+	ExpiresAt *time.Time      `json:"expires_at,omitempty"`
+}
+
+func (e Entry) expired(now time.Time) bool {
+	return e.ExpiresAt != nil && !now.Before(*e.ExpiresAt)
+}
+
+// Store persists entries to a JSON file. Expired entries are never returned and
+// are dropped on the next write.
+type Store struct {
+	mu       sync.RWMutex
+	filePath string
+	entries  map[string]map[string]Entry // namespace -> key -> entry
+	now      func() time.Time
+}
+
+// NewStore opens (or creates) the state store at filePath.
+// An empty filePath keeps state in memory only.
+func NewStore(filePath string) (*Store, error) {
+	s := &Store{filePath: filePath, entries: make(map[string]map[string]Entry), now: time.Now}
+	if filePath == "" {
+		return s, nil
+	}
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return s, nil
+		}
+		return nil, err
+	}
+	var list []Entry
+	if err := json.Unmarshal(data, &list); err != nil {
+		return nil, fmt.Errorf("parse state: %w", err)
+	}
+	for _, e := range list {
+		s.set(e)
+	}
+	return s, nil
+}
+
+func (s *Store) set(e Entry) {
+	ns := s.entries[e.Namespace]
+	if ns == nil {
+		ns = make(map[string]Entry)
+		s.entries[e.Namespace] = ns
+	}
+	ns[e.Key] = e
+}
+
+// purge drops expired entries; the caller holds the write lock.
+func (s *Store) purge(now time.Time) {
+	for name, ns := range s.entries {
+		for key, e := range ns {
+			if e.expired(now) {
+				delete(ns, key)
+			}
+		}
+		if len(ns) == 0 {
+			delete(s.entries, name)
+		}
+	}
+}
+
+func (s *Store) save() error {
+	if s.filePath == "" {
+		return nil
+	}
+	list := make([]Entry, 0)
+	for _, ns := range s.entries {
+		for _, e := range ns {
+			list = append(list, e)
+		}
+	}
+	return atomicfile.WriteJSON(s.filePath, list)
+}
+
+// Get returns the live entry for namespace/key.
+func (s *Store) Get(namespace, key string) (Entry, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	e, ok := s.entries[namespace][key]
+	if !ok || e.expired(s.now()) {
+		return Entry{}, false
+	}
+	return e, true
+}
+
+// List returns the live entries in a namespace, sorted by key.
+func (s *Store) List(namespace string) []Entry {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	now := s.now()
+	out := make([]Entry, 0, len(s.entries[namespace]))
+	for _, e := range s.entries[namespace] {
+		if !e.expired(now) {
+			out = append(out, e)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Key < out[j].Key })
+	return out
+}
+
+// Put stores value under namespace/key, replacing any existing entry. A ttl of
+// zero keeps the entry until it is deleted.
+func (s *Store) Put(namespace, key string, value json.RawMessage, ttl time.Duration) (Entry, error) {
+	if !namePattern.MatchString(namespace) || !namePattern.MatchString(key) {
+		return Entry{}, ErrInvalidName
+	}
+	if len(value) == 0 || !json.Valid(value) {
+		return Entry{}, ErrInvalidValue
+	}
+	if len(value) > MaxValueBytes {
+		return Entry{}, ErrValueTooLarge
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.now().UTC()
+	e := Entry{Namespace: namespace, Key: key, Value: value, UpdatedAt: now}
+	if ttl > 0 {
+		exp := now.Add(ttl)
+		e.ExpiresAt = &exp
+	}
+	s.purge(now)
+	s.set(e)
+	return e, s.save()
+}
+
+// Delete removes namespace/key and reports whether a live entry existed.
+func (s *Store) Delete(namespace, key string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.now()
+	e, ok := s.entries[namespace][key]
+	if !ok {
+		return false, nil
+	}
+	delete(s.entries[namespace], key)
+	s.purge(now)
+	return !e.expired(now), s.save()
+}
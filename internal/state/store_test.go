@@ -0,0 +1,102 @@
+package state
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestStore_PutGetPersist(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+	s, err := NewStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Put("deploy", "api", json.RawMessage(`{"sha":"abc"}`), 0); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Put("deploy", "web", json.RawMessage(`"pending"`), time.Hour); err != nil {
+		t.Fatal(err)
+	}
+
+	e, ok := s.Get("deploy", "api")
+	if !ok || string(e.Value) != `{"sha":"abc"}` || e.ExpiresAt != nil {
+		t.Errorf("Get = %+v, %v", e, ok)
+	}
+	if _, ok := s.Get("other", "api"); ok {
+		t.Error("namespaces should be separate")
+	}
+	if got := s.List("deploy"); len(got) != 2 || got[0].Key != "api" || got[1].Key != "web" {
+		t.Errorf("List = %+v", got)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil || info.Mode().Perm() != 0600 {
+		t.Fatalf("expected 0600 state file, got %v %v", info, err)
+	}
+	reopened, err := NewStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e, ok := reopened.Get("deploy", "web"); !ok || string(e.Value) != `"pending"` || e.ExpiresAt == nil {
+		t.Errorf("expected persisted entry with expiry, got %+v %v", e, ok)
+	}
+}
+
+func TestStore_TTL(t *testing.T) {
+	s, _ := NewStore("")
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	s.now = func() time.Time { return now }
+
+	s.Put("ns", "short", json.RawMessage(`1`), time.Minute)
+	s.Put("ns", "long", json.RawMessage(`2`), time.Hour)
+	now = now.Add(2 * time.Minute)
+
+	if _, ok := s.Get("ns", "short"); ok {
+		t.Error("expired entry should not be returned")
+	}
+	if got := s.List("ns"); len(got) != 1 || got[0].Key != "long" {
+		t.Errorf("List should skip expired entries, got %+v", got)
+	}
+	if deleted, _ := s.Delete("ns", "short"); deleted {
+		t.Error("deleting an expired entry should report false")
+	}
+
+	// Writes purge expired entries
+	s.Put("ns", "gone", json.RawMessage(`3`), time.Minute)
+	now = now.Add(time.Hour)
+	s.Put("other", "k", json.RawMessage(`4`), 0)
+	if _, ok := s.entries["ns"]; ok {
+		t.Errorf("expected expired namespace to be purged, got %+v", s.entries["ns"])
+	}
+}
+
+func TestStore_Invalid(t *testing.T) {
+	s, _ := NewStore("")
+	tests := []struct {
+		ns, key, value string
+		want           error
+	}{
+		{"", "k", `1`, ErrInvalidName},
+		{"ns", "a/b", `1`, ErrInvalidName},
+		{"ns", strings.Repeat("k", 129), `1`, ErrInvalidName},
+		{"ns", "k", ``, ErrInvalidValue},
+		{"ns", "k", `{bad`, ErrInvalidValue},
+		{"ns", "k", `"` + strings.Repeat("x", MaxValueBytes) + `"`, ErrValueTooLarge},
+	}
+	for _, tt := range tests {
+		if _, err := s.Put(tt.ns, tt.key, json.RawMessage(tt.value), 0); !errors.Is(err, tt.want) {
+			t.Errorf("Put(%q, %q): expected %v, got %v", tt.ns, tt.key[:min(len(tt.key), 10)], tt.want, err)
+		}
+	}
+
+	path := filepath.Join(t.TempDir(), "state.json")
+	os.WriteFile(path, []byte("not json"), 0600)
+	if _, err := NewStore(path); err == nil {
+		t.Error("expected parse error for corrupt state file")
+	}
+}
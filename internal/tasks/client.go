@@ -0,0 +1,165 @@
+// Package tasks reads Google Tasks for the accounts signed in with Google
+// OAuth, for the /api/tasks/* routes.
+package tasks
+
+import (
+	"context"
+	"time"
+
+	"github.com/katalabut/openclaw-relay/internal/tokens"
+	"golang.org/x/oauth2"
+	"google.golang.org/api/option"
+	gtasks "google.golang.org/api/tasks/v1"
+)
+
+// TasksClient is the interface the API handler uses, so tests can fake
+// Google Tasks.
+type TasksClient interface {
+	ListTaskLists(ctx context.Context) ([]TaskList, error)
+	ListTasks(ctx context.Context, listID string, q TaskQuery) ([]Task, string, error)
+}
+
+// Client wraps Google Tasks API v1 for one account.
+type Client struct {
+	store    *tokens.Store
+	oauthCfg *oauth2.Config
+	email    string
+	endpoint string
+}
+
+func NewClientForAccount(store *tokens.Store, oauthCfg *oauth2.Config, email string) *Client {
+	return &Client{store: store, oauthCfg: oauthCfg, email: email}
+}
+
+// SetEndpoint points the client at another Tasks API base URL, e.g. a fake
+// server in tests. Empty means Google's default.
+func (c *Client) SetEndpoint(url string) {
+	c.endpoint = url
+}
+
+func (c *Client) getService(ctx context.Context) (*gtasks.Service, error) {
+	ts, err := c.store.GoogleTokenSource(ctx, c.oauthCfg, c.email)
+	if err != nil {
+		return nil, err
+	}
+	opts := []option.ClientOption{option.WithTokenSource(ts)}
+	if c.endpoint != "" {
+		opts = append(opts, option.WithEndpoint(c.endpoint))
+	}
+	return gtasks.NewService(ctx, opts...)
+}
+
+// TaskList is one of the account's task lists.
+type TaskList struct {
+	ID      string    `json:"id"`
+	Title   string    `json:"title"`
+	Updated time.Time `json:"updated"`
+}
+
+// Task is a task of a list. Due is a date (YYYY-MM-DD): Google Tasks keeps
+// no time of day for it.
+type Task struct {
+	ID        string     `json:"id"`
+	ListID    string     `json:"listId"`
+	Title     string     `json:"title"`
+	Notes     string     `json:"notes,omitempty"`
+	Status    string     `json:"status"` // needsAction or completed
+	Due       string     `json:"due,omitempty"`
+	Overdue   bool       `json:"overdue,omitempty"` // set by /api/tasks/due
+	Completed *time.Time `json:"completed,omitempty"`
+	Updated   time.Time  `json:"updated"`
+	Parent    string     `json:"parent,omitempty"` // the parent task of a subtask
+	URL       string     `json:"url,omitempty"`    // the task in the Google Tasks web UI
+}
+
+// TaskQuery narrows ListTasks. Zero fields are unbounded.
+type TaskQuery struct {
+	DueFrom       time.Time // tasks due on or after
+	DueTo         time.Time // tasks due before
+	ShowCompleted bool      // include completed tasks, hidden ones too
+	MaxResults    int64
+	PageToken     string
+}
+
+// ListTaskLists lists the account's task lists.
+func (c *Client) ListTaskLists(ctx context.Context) ([]TaskList, error) {
+	svc, err := c.getService(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var out []TaskList
+	err = svc.Tasklists.List().MaxResults(100).Pages(ctx, func(resp *gtasks.TaskLists) error {
+		for _, l := range resp.Items {
+			out = append(out, TaskList{ID: l.Id, Title: l.Title, Updated: parseTime(l.Updated)})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// ListTasks lists a list's tasks matching q in Google's order, and the next
+// page token.
+func (c *Client) ListTasks(ctx context.Context, listID string, q TaskQuery) ([]Task, string, error) {
+	svc, err := c.getService(ctx)
+	if err != nil {
+		return nil, "", err
+	}
+	call := svc.Tasks.List(listID).ShowCompleted(q.ShowCompleted).Context(ctx)
+	if q.ShowCompleted {
+		call = call.ShowHidden(true)
+	}
+	if !q.DueFrom.IsZero() {
+		call = call.DueMin(q.DueFrom.Format(time.RFC3339))
+	}
+	if !q.DueTo.IsZero() {
+		call = call.DueMax(q.DueTo.Format(time.RFC3339))
+	}
+	if q.MaxResults > 0 {
+		call = call.MaxResults(q.MaxResults)
+	}
+	if q.PageToken != "" {
+		call = call.PageToken(q.PageToken)
+	}
+	resp, err := call.Do()
+	if err != nil {
+		return nil, "", err
+	}
+	out := make([]Task, 0, len(resp.Items))
+	for _, t := range resp.Items {
+		out = append(out, convertTask(listID, t))
+	}
+	return out, resp.NextPageToken, nil
+}
+
+func convertTask(listID string, t *gtasks.Task) Task {
+	task := Task{
+		ID:      t.Id,
+		ListID:  listID,
+		Title:   t.Title,
+		Notes:   t.Notes,
+		Status:  t.Status,
+		Updated: parseTime(t.Updated),
+		Parent:  t.Parent,
+		URL:     t.WebViewLink,
+	}
+	if due := parseTime(t.Due); !due.IsZero() {
+		task.Due = due.UTC().Format(time.DateOnly)
+	}
+	if t.Completed != nil {
+		if done := parseTime(*t.Completed); !done.IsZero() {
+			task.Completed = &done
+		}
+	}
+	return task
+}
+
+func parseTime(s string) time.Time {
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return time.Time{}
+	}
+	return t
+}
@@ -0,0 +1,98 @@
+package tasks
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/katalabut/openclaw-relay/internal/tokens"
+	"golang.org/x/oauth2"
+)
+
+// newTestClient returns a client for me@example.com against a fake Tasks
+// API served by h.
+func newTestClient(t *testing.T, h http.HandlerFunc) *Client {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		h(w, r)
+	}))
+	t.Cleanup(srv.Close)
+	store, err := tokens.NewStore(filepath.Join(t.TempDir(), "tokens.json.enc"), strings.Repeat("ab", 32))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := store.SaveGoogle(&oauth2.Token{AccessToken: "tok", RefreshToken: "r", Expiry: time.Now().Add(time.Hour)}, "me@example.com"); err != nil {
+		t.Fatal(err)
+	}
+	c := NewClientForAccount(store, &oauth2.Config{}, "me@example.com")
+	c.SetEndpoint(srv.URL + "/")
+	return c
+}
+
+func TestListTaskLists(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/tasks/v1/users/@me/lists" {
+			t.Errorf("path = %s", r.URL.Path)
+		}
+		if r.URL.Query().Get("pageToken") == "" {
+			fmt.Fprint(w, `{"items":[{"id":"l1","title":"My Tasks","updated":"2026-03-01T08:00:00.000Z"}],"nextPageToken":"p2"}`)
+			return
+		}
+		fmt.Fprint(w, `{"items":[{"id":"l2","title":"Groceries"}]}`)
+	})
+	lists, err := c.ListTaskLists(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(lists) != 2 || lists[0].Title != "My Tasks" || lists[0].Updated.IsZero() || lists[1].ID != "l2" {
+		t.Errorf("lists = %+v", lists)
+	}
+}
+
+func TestListTasks(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		if r.URL.Path != "/tasks/v1/lists/l1/tasks" || q.Get("showCompleted") != "true" || q.Get("showHidden") != "true" ||
+			q.Get("dueMax") != "2026-03-02T23:59:59Z" || q.Get("pageToken") != "p1" || q.Get("maxResults") != "20" {
+			t.Errorf("request = %s?%s", r.URL.Path, r.URL.RawQuery)
+		}
+		fmt.Fprint(w, `{"nextPageToken":"p2","items":[
+			{"id":"t1","title":"Pay rent","notes":"IBAN in 1Password","status":"needsAction","due":"2026-03-02T00:00:00.000Z",
+			 "updated":"2026-03-01T08:00:00.000Z","webViewLink":"https://tasks.google.com/task/t1"},
+			{"id":"t2","title":"Call bank","status":"completed","completed":"2026-03-01T09:30:00.000Z","parent":"t1"}]}`)
+	})
+	tasks, next, err := c.ListTasks(context.Background(), "l1", TaskQuery{
+		DueTo:         time.Date(2026, 3, 2, 23, 59, 59, 0, time.UTC),
+		ShowCompleted: true,
+		MaxResults:    20,
+		PageToken:     "p1",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tasks) != 2 || next != "p2" {
+		t.Fatalf("tasks = %+v, next %q", tasks, next)
+	}
+	if t1 := tasks[0]; t1.ListID != "l1" || t1.Due != "2026-03-02" || t1.Notes == "" || t1.URL == "" || t1.Completed != nil {
+		t.Errorf("t1 = %+v", t1)
+	}
+	if t2 := tasks[1]; t2.Due != "" || t2.Completed == nil || !t2.Completed.Equal(time.Date(2026, 3, 1, 9, 30, 0, 0, time.UTC)) || t2.Parent != "t1" {
+		t.Errorf("t2 = %+v", t2)
+	}
+}
+
+func TestListTasks_Error(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+		fmt.Fprint(w, `{"error":{"code":403,"message":"Request had insufficient authentication scopes."}}`)
+	})
+	if _, _, err := c.ListTasks(context.Background(), "@default", TaskQuery{}); err == nil || !strings.Contains(err.Error(), "insufficient") {
+		t.Errorf("err = %v", err)
+	}
+}
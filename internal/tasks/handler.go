@@ -0,0 +1,224 @@
+package tasks
+
+import (
+	"encoding/json"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// maxTasks caps ?max= on /api/tasks, Google's page size limit.
+	maxTasks = 100
+	// maxDueTasks caps the tasks /api/tasks/due collects across lists.
+	maxDueTasks = 500
+)
+
+// Handler serves the read-only Tasks API for the agent.
+type Handler struct {
+	clients        map[string]TasksClient
+	defaultAccount string
+	loc            *time.Location // server.timezone, which decides what "today" is
+	now            func() time.Time
+}
+
+// NewHandler serves clients, keyed by account; ?account= defaults to the
+// first of accounts that has one.
+func NewHandler(accounts []string, clients map[string]TasksClient) *Handler {
+	h := &Handler{clients: clients, loc: time.UTC, now: time.Now}
+	for _, acc := range accounts {
+		if _, ok := clients[acc]; ok {
+			h.defaultAccount = acc
+			break
+		}
+	}
+	return h
+}
+
+// SetTimezone sets the IANA zone in which due dates are compared with
+// today; empty or invalid means UTC.
+func (h *Handler) SetTimezone(tz string) {
+	if loc, err := time.LoadLocation(tz); err == nil && tz != "" {
+		h.loc = loc
+	}
+}
+
+// RegisterRoutes adds the Tasks API routes to the mux.
+func (h *Handler) RegisterRoutes(mux *http.ServeMux) {
+	mux.HandleFunc("/api/tasks/lists", h.handleLists)
+	mux.HandleFunc("/api/tasks", h.handleTasks)
+	mux.HandleFunc("/api/tasks/due", h.handleDue)
+}
+
+// resolveClient returns the client for ?account=, the default account when
+// it's unset.
+func (h *Handler) resolveClient(r *http.Request) (TasksClient, bool) {
+	account := r.URL.Query().Get("account")
+	if account == "" {
+		account = h.defaultAccount
+	}
+	client, ok := h.clients[account]
+	return client, ok
+}
+
+func jsonResponse(w http.ResponseWriter, data any) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(data)
+}
+
+func jsonError(w http.ResponseWriter, msg string, code int) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(map[string]string{"error": msg})
+}
+
+// handleLists serves GET /api/tasks/lists.
+func (h *Handler) handleLists(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		jsonError(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	client, ok := h.resolveClient(r)
+	if !ok {
+		jsonError(w, "unknown account", http.StatusBadRequest)
+		return
+	}
+	lists, err := client.ListTaskLists(r.Context())
+	if err != nil {
+		jsonError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	jsonResponse(w, map[string]any{"lists": lists})
+}
+
+// handleTasks serves GET /api/tasks: ?list= (default @default, the user's
+// default list), due_from and due_to as YYYY-MM-DD dates (both inclusive),
+// completed=true to include completed tasks, max (default 100) and
+// pageToken.
+func (h *Handler) handleTasks(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		jsonError(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	client, ok := h.resolveClient(r)
+	if !ok {
+		jsonError(w, "unknown account", http.StatusBadRequest)
+		return
+	}
+	params := r.URL.Query()
+	q := TaskQuery{MaxResults: maxTasks, PageToken: params.Get("pageToken")}
+	q.ShowCompleted, _ = strconv.ParseBool(params.Get("completed"))
+	for name, t := range map[string]*time.Time{"due_from": &q.DueFrom, "due_to": &q.DueTo} {
+		if s := params.Get(name); s != "" {
+			v, err := time.Parse(time.DateOnly, s)
+			if err != nil {
+				jsonError(w, name+" must be a YYYY-MM-DD date", http.StatusBadRequest)
+				return
+			}
+			*t = v
+		}
+	}
+	if !q.DueTo.IsZero() {
+		// Due dates are kept as midnight UTC; take in the whole day
+		q.DueTo = q.DueTo.Add(24*time.Hour - time.Second)
+	}
+	if s := params.Get("max"); s != "" {
+		if v, err := strconv.ParseInt(s, 10, 64); err == nil && v > 0 {
+			q.MaxResults = min(v, maxTasks)
+		}
+	}
+	tasks, next, err := client.ListTasks(r.Context(), listParam(r), q)
+	if err != nil {
+		jsonError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	jsonResponse(w, map[string]any{"tasks": tasks, "nextPageToken": next})
+}
+
+// handleDue serves GET /api/tasks/due: the open tasks due today or within
+// ?days= (default 0) in server.timezone, overdue ones included, from every
+// list or only ?list=, soonest first.
+func (h *Handler) handleDue(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		jsonError(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	client, ok := h.resolveClient(r)
+	if !ok {
+		jsonError(w, "unknown account", http.StatusBadRequest)
+		return
+	}
+	days := 0
+	if s := r.URL.Query().Get("days"); s != "" {
+		v, err := strconv.Atoi(s)
+		if err != nil || v < 0 || v > 366 {
+			jsonError(w, "days must be between 0 and 366", http.StatusBadRequest)
+			return
+		}
+		days = v
+	}
+	now := h.now().In(h.loc)
+	today := now.Format(time.DateOnly)
+	last := now.AddDate(0, 0, days).Format(time.DateOnly)
+	dueTo, _ := time.Parse(time.DateOnly, last)
+
+	var listIDs []string
+	if id := r.URL.Query().Get("list"); id != "" {
+		listIDs = []string{id}
+	} else {
+		lists, err := client.ListTaskLists(r.Context())
+		if err != nil {
+			jsonError(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		for _, l := range lists {
+			listIDs = append(listIDs, l.ID)
+		}
+	}
+
+	due := []Task{}
+	truncated := false
+collect:
+	for _, id := range listIDs {
+		q := TaskQuery{DueTo: dueTo.Add(24*time.Hour - time.Second), MaxResults: maxTasks}
+		for {
+			tasks, next, err := client.ListTasks(r.Context(), id, q)
+			if err != nil {
+				jsonError(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			for _, t := range tasks {
+				if t.Due == "" || t.Due > last || t.Status == "completed" {
+					continue
+				}
+				if len(due) == maxDueTasks {
+					truncated = true
+					break collect
+				}
+				t.Overdue = t.Due < today
+				due = append(due, t)
+			}
+			if next == "" {
+				break
+			}
+			q.PageToken = next
+		}
+	}
+	slices.SortStableFunc(due, func(a, b Task) int {
+		if c := strings.Compare(a.Due, b.Due); c != 0 {
+			return c
+		}
+		return strings.Compare(a.Title, b.Title)
+	})
+	jsonResponse(w, map[string]any{"today": today, "tasks": due, "truncated": truncated})
+}
+
+// listParam returns ?list=, or @default.
+func listParam(r *http.Request) string {
+	if id := r.URL.Query().Get("list"); id != "" {
+		return id
+	}
+	return "@default"
+}
@@ -0,0 +1,180 @@
+package tasks
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeClient serves task lists from memory, pageSize tasks a page.
+type fakeClient struct {
+	lists    []TaskList
+	tasks    map[string][]Task
+	pageSize int
+	err      error
+
+	queries []TaskQuery
+}
+
+func (f *fakeClient) ListTaskLists(ctx context.Context) ([]TaskList, error) {
+	return f.lists, f.err
+}
+
+func (f *fakeClient) ListTasks(ctx context.Context, listID string, q TaskQuery) ([]Task, string, error) {
+	if f.err != nil {
+		return nil, "", f.err
+	}
+	f.queries = append(f.queries, q)
+	all := f.tasks[listID]
+	start := 0
+	if q.PageToken != "" {
+		start = len(q.PageToken)
+	}
+	end := len(all)
+	if f.pageSize > 0 && start+f.pageSize < end {
+		end = start + f.pageSize
+	}
+	next := ""
+	if end < len(all) {
+		next = strings.Repeat("p", end)
+	}
+	return all[start:end], next, nil
+}
+
+func serve(h *Handler, method, url string) *httptest.ResponseRecorder {
+	mux := http.NewServeMux()
+	h.RegisterRoutes(mux)
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(method, url, nil))
+	return rec
+}
+
+func TestHandler_Lists(t *testing.T) {
+	fc := &fakeClient{lists: []TaskList{{ID: "l1", Title: "My Tasks"}}}
+	h := NewHandler([]string{"me@example.com"}, map[string]TasksClient{"me@example.com": fc})
+
+	rec := serve(h, "GET", "/api/tasks/lists")
+	var resp struct {
+		Lists []TaskList `json:"lists"`
+	}
+	json.NewDecoder(rec.Body).Decode(&resp)
+	if rec.Code != http.StatusOK || len(resp.Lists) != 1 || resp.Lists[0].Title != "My Tasks" {
+		t.Errorf("lists: %d %+v", rec.Code, resp)
+	}
+
+	for _, tt := range []struct {
+		method, url string
+		code        int
+	}{
+		{"POST", "/api/tasks/lists", http.StatusMethodNotAllowed},
+		{"GET", "/api/tasks/lists?account=other@example.com", http.StatusBadRequest},
+		{"DELETE", "/api/tasks", http.StatusMethodNotAllowed},
+		{"GET", "/api/tasks?due_from=tomorrow", http.StatusBadRequest},
+		{"GET", "/api/tasks?account=other@example.com", http.StatusBadRequest},
+		{"POST", "/api/tasks/due", http.StatusMethodNotAllowed},
+		{"GET", "/api/tasks/due?days=-1", http.StatusBadRequest},
+		{"GET", "/api/tasks/due?account=other@example.com", http.StatusBadRequest},
+	} {
+		if rec := serve(h, tt.method, tt.url); rec.Code != tt.code {
+			t.Errorf("%s %s: %d, want %d", tt.method, tt.url, rec.Code, tt.code)
+		}
+	}
+
+	fc.err = errors.New("quota exceeded")
+	for _, url := range []string{"/api/tasks/lists", "/api/tasks", "/api/tasks/due"} {
+		if rec := serve(h, "GET", url); rec.Code != http.StatusInternalServerError {
+			t.Errorf("%s with failing client: %d", url, rec.Code)
+		}
+	}
+}
+
+func TestHandler_Tasks(t *testing.T) {
+	fc := &fakeClient{tasks: map[string][]Task{"@default": {{ID: "t1", Title: "Pay rent"}}, "l2": {{ID: "t2"}}}}
+	h := NewHandler([]string{"me@example.com"}, map[string]TasksClient{"me@example.com": fc})
+
+	rec := serve(h, "GET", "/api/tasks?due_from=2026-03-01&due_to=2026-03-02&completed=true&max=500")
+	var resp struct {
+		Tasks []Task `json:"tasks"`
+	}
+	json.NewDecoder(rec.Body).Decode(&resp)
+	if rec.Code != http.StatusOK || len(resp.Tasks) != 1 || resp.Tasks[0].ID != "t1" {
+		t.Fatalf("tasks: %d %+v", rec.Code, resp)
+	}
+	q := fc.queries[0]
+	if !q.DueFrom.Equal(time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)) || !q.DueTo.Equal(time.Date(2026, 3, 2, 23, 59, 59, 0, time.UTC)) ||
+		!q.ShowCompleted || q.MaxResults != maxTasks {
+		t.Errorf("query = %+v", q)
+	}
+
+	rec = serve(h, "GET", "/api/tasks?list=l2")
+	json.NewDecoder(rec.Body).Decode(&resp)
+	if len(resp.Tasks) != 1 || resp.Tasks[0].ID != "t2" || fc.queries[1].ShowCompleted {
+		t.Errorf("list l2: %+v %+v", resp, fc.queries[1])
+	}
+}
+
+func TestHandler_Due(t *testing.T) {
+	fc := &fakeClient{
+		lists: []TaskList{{ID: "l1"}, {ID: "l2"}},
+		tasks: map[string][]Task{
+			"l1": {
+				{ID: "rent", Title: "Pay rent", Due: "2026-03-02", Status: "needsAction"},
+				{ID: "late", Title: "Renew passport", Due: "2026-02-20", Status: "needsAction"},
+				{ID: "done", Title: "File taxes", Due: "2026-03-01", Status: "completed"},
+				{ID: "someday", Title: "Learn Go", Status: "needsAction"},
+			},
+			"l2": {
+				{ID: "milk", Title: "Milk", Due: "2026-03-03", Status: "needsAction"},
+				{ID: "later", Title: "Dentist", Due: "2026-03-10", Status: "needsAction"},
+			},
+		},
+		pageSize: 2,
+	}
+	h := NewHandler([]string{"me@example.com"}, map[string]TasksClient{"me@example.com": fc})
+	// 23:30 UTC on March 1 is already March 2 in Berlin
+	h.now = func() time.Time { return time.Date(2026, 3, 1, 23, 30, 0, 0, time.UTC) }
+	h.SetTimezone("Europe/Berlin")
+
+	var resp struct {
+		Today     string `json:"today"`
+		Tasks     []Task `json:"tasks"`
+		Truncated bool   `json:"truncated"`
+	}
+	ids := func() string {
+		var out []string
+		for _, t := range resp.Tasks {
+			if t.Overdue {
+				out = append(out, t.ID+"!")
+			} else {
+				out = append(out, t.ID)
+			}
+		}
+		return strings.Join(out, " ")
+	}
+
+	rec := serve(h, "GET", "/api/tasks/due")
+	json.NewDecoder(rec.Body).Decode(&resp)
+	if rec.Code != http.StatusOK || resp.Today != "2026-03-02" || ids() != "late! rent" || resp.Truncated {
+		t.Errorf("due today: %d %s %s", rec.Code, resp.Today, ids())
+	}
+	if q := fc.queries[0]; !q.DueTo.Equal(time.Date(2026, 3, 2, 23, 59, 59, 0, time.UTC)) || q.ShowCompleted {
+		t.Errorf("query = %+v", q)
+	}
+
+	resp.Tasks = nil
+	json.NewDecoder(serve(h, "GET", "/api/tasks/due?days=1").Body).Decode(&resp)
+	if ids() != "late! rent milk" {
+		t.Errorf("due within a day: %s", ids())
+	}
+
+	resp.Tasks = nil
+	json.NewDecoder(serve(h, "GET", "/api/tasks/due?days=30&list=l2").Body).Decode(&resp)
+	if ids() != "milk later" {
+		t.Errorf("due in l2: %s", ids())
+	}
+}
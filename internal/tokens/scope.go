@@ -0,0 +1,93 @@
+package tokens
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"golang.org/x/oauth2"
+)
+
+// GrantedScopes returns the scopes Google granted with token, from the
+// "scope" field of the token response. With granular consent the user can
+// leave some of the requested ones out. A response without the field grants
+// requested.
+func GrantedScopes(token *oauth2.Token, requested []string) []string {
+	if s, _ := token.Extra("scope").(string); strings.TrimSpace(s) != "" {
+		return strings.Fields(s)
+	}
+	return requested
+}
+
+// ScopeError is returned for a Google API call refused because the account's
+// token lacks a scope the call needs. The account must sign in again to
+// grant it.
+type ScopeError struct {
+	Account  string
+	Required []string // the scopes Google named for the call, when it did
+	Granted  []string // the scopes the account granted, when recorded
+}
+
+func (e *ScopeError) Error() string {
+	return fmt.Sprintf("insufficient OAuth scope for %s, re-auth at %s", e.Account, e.ReauthURL())
+}
+
+// ReauthURL is the relay path that reconnects the account.
+func (e *ScopeError) ReauthURL() string {
+	return "/auth/google/login?account=" + url.QueryEscape(e.Account)
+}
+
+// scopeParam is the scope="..." parameter of a WWW-Authenticate header.
+var scopeParam = regexp.MustCompile(`scope="([^"]*)"`)
+
+// ScopeTransport wraps next (http.DefaultTransport when nil), the transport
+// under the OAuth2 one, so Google API calls that a 403 refuses for a missing
+// scope fail with a *ScopeError for email instead.
+func (s *Store) ScopeTransport(email string, next http.RoundTripper) http.RoundTripper {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	return &scopeTransport{store: s, email: email, next: next}
+}
+
+type scopeTransport struct {
+	store *Store
+	email string
+	next  http.RoundTripper
+}
+
+func (t *scopeTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.next.RoundTrip(req)
+	if err != nil || resp.StatusCode != http.StatusForbidden {
+		return resp, err
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<16))
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	auth := resp.Header.Get("WWW-Authenticate")
+	if !strings.Contains(auth, "insufficient_scope") && !insufficientScopeBody(body) {
+		resp.Body = io.NopCloser(bytes.NewReader(body))
+		return resp, nil
+	}
+	e := &ScopeError{Account: t.email}
+	if m := scopeParam.FindStringSubmatch(auth); m != nil {
+		e.Required = strings.Fields(m[1])
+	}
+	if g := t.store.GetGoogle(t.email); g != nil {
+		e.Granted = g.Scopes
+	}
+	return nil, e
+}
+
+// insufficientScopeBody reports whether a Google API error body refuses the
+// call for a missing scope.
+func insufficientScopeBody(body []byte) bool {
+	s := string(body)
+	return strings.Contains(s, "ACCESS_TOKEN_SCOPE_INSUFFICIENT") || strings.Contains(s, "insufficient authentication scopes")
+}
@@ -0,0 +1,79 @@
+package tokens
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"golang.org/x/oauth2"
+)
+
+func TestGrantedScopes(t *testing.T) {
+	requested := []string{"openid", "gmail.readonly", "calendar.readonly"}
+	tok := (&oauth2.Token{AccessToken: "a"}).WithExtra(map[string]any{"scope": "openid gmail.readonly"})
+	if got := GrantedScopes(tok, requested); !slices.Equal(got, []string{"openid", "gmail.readonly"}) {
+		t.Errorf("with scope field: %v", got)
+	}
+	if got := GrantedScopes(&oauth2.Token{AccessToken: "a"}, requested); !slices.Equal(got, requested) {
+		t.Errorf("without scope field: %v", got)
+	}
+}
+
+func TestScopeTransport(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/header":
+			w.Header().Set("WWW-Authenticate", `Bearer realm="https://accounts.google.com/", error="insufficient_scope", scope="https://www.googleapis.com/auth/gmail.modify"`)
+			w.WriteHeader(http.StatusForbidden)
+		case "/body":
+			w.WriteHeader(http.StatusForbidden)
+			io.WriteString(w, `{"error":{"code":403,"message":"Request had insufficient authentication scopes.","status":"PERMISSION_DENIED"}}`)
+		case "/denied":
+			w.WriteHeader(http.StatusForbidden)
+			io.WriteString(w, `{"error":{"code":403,"message":"The caller does not have permission"}}`)
+		default:
+			io.WriteString(w, "ok")
+		}
+	}))
+	defer srv.Close()
+
+	s, _ := NewStore(filepath.Join(t.TempDir(), "tokens.json.enc"), strings.Repeat("ab", 32))
+	s.SaveGoogleScopes(&oauth2.Token{AccessToken: "a"}, "me@example.com", []string{"gmail.readonly"})
+	client := &http.Client{Transport: s.ScopeTransport("me@example.com", nil)}
+
+	_, err := client.Get(srv.URL + "/header")
+	var se *ScopeError
+	if !errors.As(err, &se) {
+		t.Fatalf("header: err = %v", err)
+	}
+	if se.Account != "me@example.com" || !slices.Equal(se.Required, []string{"https://www.googleapis.com/auth/gmail.modify"}) || !slices.Equal(se.Granted, []string{"gmail.readonly"}) {
+		t.Errorf("header: %+v", se)
+	}
+	if !strings.Contains(err.Error(), "re-auth at /auth/google/login?account=me%40example.com") {
+		t.Errorf("message: %v", err)
+	}
+
+	_, err = client.Get(srv.URL + "/body")
+	if !errors.As(err, &se) || se.Required != nil {
+		t.Errorf("body: err = %v", err)
+	}
+
+	// Other 403s reach the caller with their body intact
+	resp, err := client.Get(srv.URL + "/denied")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusForbidden || !strings.Contains(string(body), "does not have permission") {
+		t.Errorf("denied: %d %s", resp.StatusCode, body)
+	}
+	if resp, err := client.Get(srv.URL + "/"); err != nil || resp.StatusCode != http.StatusOK {
+		t.Errorf("ok: %v", err)
+	}
+}
@@ -1,6 +1,7 @@
 package tokens
 
 import (
+	"context"
 	"crypto/aes"
 	"crypto/cipher"
 	"crypto/rand"
@@ -8,11 +9,15 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"log"
 	"os"
-	"path/filepath"
+	"slices"
+	"sort"
 	"sync"
 	"time"
 
+	"github.com/katalabut/openclaw-relay/internal/atomicfile"
+	"github.com/katalabut/openclaw-relay/internal/migrate"
 	"golang.org/x/oauth2"
 )
 
@@ -23,21 +28,70 @@ type GoogleToken struct {
 	TokenType    string    `json:"token_type"`
 	Expiry       time.Time `json:"expiry"`
 	Email        string    `json:"email"`
+	// DeactivatedAt marks a disconnected account. The token is kept, but not
+	// used, until it is restored or purged after the grace period.
+	DeactivatedAt *time.Time `json:"deactivated_at,omitempty"`
+	// Scopes are the OAuth scopes Google granted when the account was
+	// connected; empty for tokens saved before they were recorded.
+	Scopes []string `json:"scopes,omitempty"`
+}
+
+// Active reports whether the token is usable (not deactivated).
+func (t *GoogleToken) Active() bool {
+	return t.DeactivatedAt == nil
+}
+
+// MissingScopes returns the scopes of want the account was not granted. A
+// token without recorded scopes is assumed to have them all.
+func (t *GoogleToken) MissingScopes(want []string) []string {
+	if len(t.Scopes) == 0 {
+		return nil
+	}
+	var missing []string
+	for _, s := range want {
+		if !slices.Contains(t.Scopes, s) {
+			missing = append(missing, s)
+		}
+	}
+	return missing
 }
 
 // TokenData is the top-level structure persisted to disk.
 type TokenData struct {
+	Version       int                     `json:"version"`
 	GoogleByEmail map[string]*GoogleToken `json:"google_by_email,omitempty"`
-	// Legacy single-account field (kept for backward compatibility on load).
-	Google *GoogleToken `json:"google,omitempty"`
 }
 
+// schema upgrades token files written by older relays; see internal/migrate.
+var schema = migrate.Schema{Name: "tokens", Steps: []migrate.Step{
+	{Name: "move the single-account google token into google_by_email", Up: func(d migrate.Doc) error {
+		var legacy *GoogleToken
+		if ok, err := d.Get("google", &legacy); err != nil || !ok {
+			return err
+		}
+		delete(d, "google")
+		if legacy.Email == "" {
+			return nil // no account to file it under; it was never usable
+		}
+		byEmail := map[string]*GoogleToken{}
+		if _, err := d.Get("google_by_email", &byEmail); err != nil {
+			return err
+		}
+		if byEmail == nil {
+			byEmail = map[string]*GoogleToken{}
+		}
+		byEmail[legacy.Email] = legacy
+		return d.Set("google_by_email", byEmail)
+	}},
+}}
+
 // Store provides encrypted token persistence.
 type Store struct {
 	mu       sync.RWMutex
 	filePath string
 	key      []byte
 	data     TokenData
+	now      func() time.Time
 }
 
 // NewStore creates a token store. encKeyHex is a 32-byte hex-encoded AES key.
@@ -46,7 +100,7 @@ func NewStore(filePath, encKeyHex string) (*Store, error) {
 	if err != nil || len(key) != 32 {
 		return nil, fmt.Errorf("RELAY_ENCRYPTION_KEY must be 32-byte hex (64 chars)")
 	}
-	s := &Store{filePath: filePath, key: key}
+	s := &Store{filePath: filePath, key: key, now: time.Now}
 	if err := s.load(); err != nil && !os.IsNotExist(err) {
 		return nil, fmt.Errorf("load tokens: %w", err)
 	}
@@ -94,24 +148,24 @@ func (s *Store) load() error {
 	if err != nil {
 		return fmt.Errorf("decrypt: %w", err)
 	}
+	plaintext, migrated, err := schema.Apply(plaintext)
+	if err != nil {
+		return err
+	}
 	if err := json.Unmarshal(plaintext, &s.data); err != nil {
 		return err
 	}
 	if s.data.GoogleByEmail == nil {
 		s.data.GoogleByEmail = map[string]*GoogleToken{}
 	}
-	// Migrate legacy single-account payload on first load.
-	if s.data.Google != nil && s.data.Google.Email != "" {
-		s.data.GoogleByEmail[s.data.Google.Email] = s.data.Google
-		s.data.Google = nil
+	if migrated {
+		return s.save()
 	}
 	return nil
 }
 
 func (s *Store) save() error {
-	if err := os.MkdirAll(filepath.Dir(s.filePath), 0700); err != nil {
-		return err
-	}
+	s.data.Version = schema.Current()
 	plaintext, err := json.Marshal(s.data)
 	if err != nil {
 		return err
@@ -120,11 +174,18 @@ func (s *Store) save() error {
 	if err != nil {
 		return err
 	}
-	return os.WriteFile(s.filePath, encrypted, 0600)
+	return atomicfile.WriteFile(s.filePath, encrypted, 0600)
 }
 
 // SaveGoogle stores a Google OAuth token for a specific email account.
 func (s *Store) SaveGoogle(token *oauth2.Token, email string) error {
+	return s.SaveGoogleScopes(token, email, nil)
+}
+
+// SaveGoogleScopes stores a Google OAuth token with the scopes it grants
+// (see GrantedScopes), so accounts can be asked to reconnect when
+// google.scopes asks for more.
+func (s *Store) SaveGoogleScopes(token *oauth2.Token, email string, scopes []string) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 	if s.data.GoogleByEmail == nil {
@@ -136,12 +197,13 @@ func (s *Store) SaveGoogle(token *oauth2.Token, email string) error {
 		TokenType:    token.TokenType,
 		Expiry:       token.Expiry,
 		Email:        email,
+		Scopes:       slices.Clone(scopes),
 	}
-	s.data.Google = nil
 	return s.save()
 }
 
-// GetGoogle returns a stored Google token by email, or nil.
+// GetGoogle returns an active Google token by email, or nil.
+// Deactivated tokens are treated as absent.
 func (s *Store) GetGoogle(email ...string) *GoogleToken {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
@@ -151,20 +213,36 @@ func (s *Store) GetGoogle(email ...string) *GoogleToken {
 	}
 	if account == "" {
 		for _, t := range s.data.GoogleByEmail {
-			return t
+			if t.Active() {
+				return t
+			}
 		}
 		return nil
 	}
-	return s.data.GoogleByEmail[account]
+	if t := s.data.GoogleByEmail[account]; t != nil && t.Active() {
+		return t
+	}
+	return nil
 }
 
-// ListGoogle returns all stored Google tokens keyed by email.
+// ListGoogle returns all active Google tokens keyed by email.
 func (s *Store) ListGoogle() map[string]*GoogleToken {
+	return s.list(true)
+}
+
+// ListDeactivatedGoogle returns the deactivated Google tokens keyed by email.
+func (s *Store) ListDeactivatedGoogle() map[string]*GoogleToken {
+	return s.list(false)
+}
+
+func (s *Store) list(active bool) map[string]*GoogleToken {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 	out := make(map[string]*GoogleToken, len(s.data.GoogleByEmail))
 	for k, v := range s.data.GoogleByEmail {
-		out[k] = v
+		if v.Active() == active {
+			out[k] = v
+		}
 	}
 	return out
 }
@@ -195,13 +273,15 @@ func (s *Store) UpdateGoogleAccessToken(token *oauth2.Token, email ...string) er
 		account = email[0]
 	}
 	if account == "" {
-		for k := range s.data.GoogleByEmail {
-			account = k
-			break
+		for k, t := range s.data.GoogleByEmail {
+			if t.Active() {
+				account = k
+				break
+			}
 		}
 	}
 	g := s.data.GoogleByEmail[account]
-	if g == nil {
+	if g == nil || !g.Active() {
 		return fmt.Errorf("no google token to update")
 	}
 	g.AccessToken = token.AccessToken
@@ -212,7 +292,79 @@ func (s *Store) UpdateGoogleAccessToken(token *oauth2.Token, email ...string) er
 	return s.save()
 }
 
-// ClearGoogle removes stored Google token for one account (or all when email empty).
+// GoogleTokenSource returns a token source for the account's stored token
+// under cfg, refreshing it first when it has expired. A refreshed access
+// token is persisted; failing to do so is only logged.
+func (s *Store) GoogleTokenSource(ctx context.Context, cfg *oauth2.Config, email string) (oauth2.TokenSource, error) {
+	tok := s.GetGoogleOAuth2Token(email)
+	if tok == nil {
+		return nil, fmt.Errorf("not authenticated with Google for %s", email)
+	}
+	ts := cfg.TokenSource(ctx, tok)
+	newTok, err := ts.Token()
+	if err != nil {
+		return nil, fmt.Errorf("token refresh: %w", err)
+	}
+	if newTok.AccessToken != tok.AccessToken {
+		if err := s.UpdateGoogleAccessToken(newTok, email); err != nil {
+			log.Printf("Warning: failed to persist refreshed token: %v", err)
+		}
+	}
+	return ts, nil
+}
+
+// DeactivateGoogle soft-deletes an account's token: it stops being returned by
+// GetGoogle and ListGoogle but can be restored until PurgeDeactivatedGoogle
+// removes it.
+func (s *Store) DeactivateGoogle(email string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	g := s.data.GoogleByEmail[email]
+	if g == nil {
+		return fmt.Errorf("no google token for %s", email)
+	}
+	if !g.Active() {
+		return nil
+	}
+	now := s.now()
+	g.DeactivatedAt = &now
+	return s.save()
+}
+
+// RestoreGoogle reactivates a deactivated account token.
+func (s *Store) RestoreGoogle(email string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	g := s.data.GoogleByEmail[email]
+	if g == nil || g.Active() {
+		return fmt.Errorf("no deactivated google token for %s", email)
+	}
+	g.DeactivatedAt = nil
+	return s.save()
+}
+
+// PurgeDeactivatedGoogle deletes tokens deactivated more than grace ago and
+// returns the purged emails.
+func (s *Store) PurgeDeactivatedGoogle(grace time.Duration) ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	cutoff := s.now().Add(-grace)
+	var purged []string
+	for email, g := range s.data.GoogleByEmail {
+		if !g.Active() && !g.DeactivatedAt.After(cutoff) {
+			delete(s.data.GoogleByEmail, email)
+			purged = append(purged, email)
+		}
+	}
+	if len(purged) == 0 {
+		return nil, nil
+	}
+	sort.Strings(purged)
+	return purged, s.save()
+}
+
+// ClearGoogle removes stored Google token for one account (or all when email empty),
+// whether active or deactivated.
 func (s *Store) ClearGoogle(email ...string) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
@@ -222,7 +374,6 @@ func (s *Store) ClearGoogle(email ...string) error {
 	}
 	if account == "" {
 		s.data.GoogleByEmail = map[string]*GoogleToken{}
-		s.data.Google = nil
 		return s.save()
 	}
 	delete(s.data.GoogleByEmail, account)
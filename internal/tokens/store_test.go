@@ -1,11 +1,16 @@
 package tokens
 
 import (
+	"context"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/katalabut/openclaw-relay/internal/migrate"
 	"golang.org/x/oauth2"
 )
 
@@ -42,6 +47,31 @@ func TestStoreRoundTrip(t *testing.T) {
 	}
 }
 
+func TestSaveGoogleScopes(t *testing.T) {
+	fp := filepath.Join(t.TempDir(), "tokens.json.enc")
+	key := strings.Repeat("ab", 32)
+	s, _ := NewStore(fp, key)
+	if err := s.SaveGoogleScopes(&oauth2.Token{AccessToken: "a"}, "me@example.com", []string{"gmail.readonly", "userinfo.email"}); err != nil {
+		t.Fatal(err)
+	}
+	// Refreshes keep the recorded scopes
+	s.UpdateGoogleAccessToken(&oauth2.Token{AccessToken: "b"}, "me@example.com")
+
+	s2, _ := NewStore(fp, key)
+	g := s2.GetGoogle("me@example.com")
+	if got := g.MissingScopes([]string{"gmail.modify", "userinfo.email"}); len(got) != 1 || got[0] != "gmail.modify" {
+		t.Errorf("missing = %v (scopes %v)", got, g.Scopes)
+	}
+	if got := g.MissingScopes([]string{"gmail.readonly"}); got != nil {
+		t.Errorf("missing = %v", got)
+	}
+	// Tokens saved without scopes are not flagged
+	s2.SaveGoogle(&oauth2.Token{AccessToken: "c"}, "old@example.com")
+	if got := s2.GetGoogle("old@example.com").MissingScopes([]string{"gmail.modify"}); got != nil {
+		t.Errorf("legacy token missing = %v", got)
+	}
+}
+
 func TestStoreInvalidKey(t *testing.T) {
 	_, err := NewStore("/tmp/test.enc", "short")
 	if err == nil {
@@ -151,3 +181,162 @@ func TestUpdateGoogleAccessToken_NoToken(t *testing.T) {
 		t.Error("expected error when no token exists")
 	}
 }
+
+func TestGoogleTokenSource(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"access_token":"fresh","token_type":"Bearer","expires_in":3600}`))
+	}))
+	defer srv.Close()
+	cfg := &oauth2.Config{Endpoint: oauth2.Endpoint{TokenURL: srv.URL}}
+
+	s, _ := NewStore(filepath.Join(t.TempDir(), "tokens.json.enc"), "0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef")
+	if _, err := s.GoogleTokenSource(context.Background(), cfg, "a@b.com"); err == nil || !strings.Contains(err.Error(), "not authenticated") {
+		t.Errorf("expected not authenticated, got %v", err)
+	}
+
+	s.SaveGoogle(&oauth2.Token{AccessToken: "stale", RefreshToken: "ref", Expiry: time.Now().Add(-time.Minute)}, "a@b.com")
+	ts, err := s.GoogleTokenSource(context.Background(), cfg, "a@b.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tok, _ := ts.Token(); tok.AccessToken != "fresh" {
+		t.Errorf("token = %q", tok.AccessToken)
+	}
+	if g := s.GetGoogle("a@b.com"); g.AccessToken != "fresh" || g.RefreshToken != "ref" {
+		t.Errorf("stored token = %+v", g)
+	}
+}
+
+func TestDeactivateRestoreGoogle(t *testing.T) {
+	dir := t.TempDir()
+	fp := filepath.Join(dir, "tokens.json.enc")
+	key := "0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef"
+
+	s, _ := NewStore(fp, key)
+	tok := &oauth2.Token{AccessToken: "acc", RefreshToken: "ref", Expiry: time.Now().Add(time.Hour)}
+	s.SaveGoogle(tok, "a@b.com")
+
+	if err := s.DeactivateGoogle("a@b.com"); err != nil {
+		t.Fatal(err)
+	}
+	if s.GetGoogle("a@b.com") != nil || s.GetGoogle() != nil || s.GetGoogleOAuth2Token("a@b.com") != nil {
+		t.Error("deactivated token must not be returned")
+	}
+	if len(s.ListGoogle()) != 0 || len(s.ListDeactivatedGoogle()) != 1 {
+		t.Errorf("unexpected lists: active=%v deactivated=%v", s.ListGoogle(), s.ListDeactivatedGoogle())
+	}
+	if err := s.UpdateGoogleAccessToken(&oauth2.Token{AccessToken: "new"}, "a@b.com"); err == nil {
+		t.Error("expected error updating a deactivated token")
+	}
+
+	// The tombstone survives a reload
+	s2, err := NewStore(fp, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s2.RestoreGoogle("a@b.com"); err != nil {
+		t.Fatal(err)
+	}
+	if g := s2.GetGoogle("a@b.com"); g == nil || g.RefreshToken != "ref" {
+		t.Fatalf("expected restored token, got %+v", g)
+	}
+	if err := s2.RestoreGoogle("a@b.com"); err == nil {
+		t.Error("expected error restoring an active token")
+	}
+	if err := s2.DeactivateGoogle("missing@b.com"); err == nil {
+		t.Error("expected error deactivating an unknown account")
+	}
+}
+
+func TestPurgeDeactivatedGoogle(t *testing.T) {
+	dir := t.TempDir()
+	fp := filepath.Join(dir, "tokens.json.enc")
+	key := "0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef"
+
+	s, _ := NewStore(fp, key)
+	now := time.Date(2025, 1, 10, 12, 0, 0, 0, time.UTC)
+	s.now = func() time.Time { return now }
+	tok := &oauth2.Token{AccessToken: "x", RefreshToken: "y", Expiry: now.Add(time.Hour)}
+	s.SaveGoogle(tok, "old@b.com")
+	s.SaveGoogle(tok, "new@b.com")
+	s.SaveGoogle(tok, "active@b.com")
+
+	s.DeactivateGoogle("old@b.com")
+	now = now.Add(5 * 24 * time.Hour)
+	s.DeactivateGoogle("new@b.com")
+	now = now.Add(3 * 24 * time.Hour)
+
+	purged, err := s.PurgeDeactivatedGoogle(7 * 24 * time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(purged) != 1 || purged[0] != "old@b.com" {
+		t.Fatalf("expected only old@b.com purged, got %v", purged)
+	}
+	if err := s.RestoreGoogle("old@b.com"); err == nil {
+		t.Error("purged token must not be restorable")
+	}
+	if err := s.RestoreGoogle("new@b.com"); err != nil {
+		t.Errorf("token within grace period should restore: %v", err)
+	}
+	if s.GetGoogle("active@b.com") == nil {
+		t.Error("active token must not be purged")
+	}
+}
+
+// writeRaw encrypts plaintext into fp the way the store does.
+func writeRaw(t *testing.T, fp, key, plaintext string) {
+	t.Helper()
+	s, err := NewStore(filepath.Join(t.TempDir(), "scratch"), key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	enc, err := s.encrypt([]byte(plaintext))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(fp, enc, 0600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestStoreMigratesLegacyToken(t *testing.T) {
+	fp := filepath.Join(t.TempDir(), "tokens.json.enc")
+	key := "0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef"
+	writeRaw(t, fp, key, `{"google":{"access_token":"old","refresh_token":"r","email":"legacy@example.com"},
+		"google_by_email":{"new@example.com":{"access_token":"new","email":"new@example.com"}}}`)
+
+	s, err := NewStore(fp, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if g := s.GetGoogle("legacy@example.com"); g == nil || g.AccessToken != "old" {
+		t.Fatalf("legacy token not migrated: %+v", g)
+	}
+	if g := s.GetGoogle("new@example.com"); g == nil || g.AccessToken != "new" {
+		t.Fatalf("existing token lost: %+v", g)
+	}
+
+	// The upgraded file is written back, so the step runs once
+	data, _ := os.ReadFile(fp)
+	plain, err := s.decrypt(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v, err := migrate.Version(plain); err != nil || v != schema.Current() {
+		t.Errorf("file version = %d (%v), want %d", v, err, schema.Current())
+	}
+	if strings.Contains(string(plain), `"google":`) {
+		t.Errorf("legacy field left in file: %s", plain)
+	}
+}
+
+func TestStoreRejectsNewerVersion(t *testing.T) {
+	fp := filepath.Join(t.TempDir(), "tokens.json.enc")
+	key := "0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef"
+	writeRaw(t, fp, key, `{"version":99,"google_by_email":{}}`)
+	if _, err := NewStore(fp, key); err == nil || !strings.Contains(err.Error(), "newer") {
+		t.Errorf("expected a newer-version error, got %v", err)
+	}
+}
@@ -0,0 +1,203 @@
+package trello
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Board is a snapshot of a board's lists, labels and members.
+type Board struct {
+	ID      string   `json:"id"`
+	Name    string   `json:"name"`
+	Lists   []List   `json:"lists"`
+	Labels  []Label  `json:"labels"`
+	Members []Member `json:"members"`
+}
+
+type List struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+type Label struct {
+	ID    string `json:"id"`
+	Name  string `json:"name"`
+	Color string `json:"color"`
+}
+
+type Member struct {
+	ID       string `json:"id"`
+	Username string `json:"username"`
+	FullName string `json:"fullName"`
+}
+
+// Board fetches a board with its open lists, labels and members in one request.
+func (c *Client) Board(ctx context.Context, id string) (*Board, error) {
+	var out Board
+	params := url.Values{
+		"fields":        {"name"},
+		"lists":         {"open"},
+		"list_fields":   {"name"},
+		"labels":        {"all"},
+		"label_fields":  {"name,color"},
+		"members":       {"all"},
+		"member_fields": {"username,fullName"},
+	}
+	if err := c.do(ctx, http.MethodGet, "/boards/"+url.PathEscape(id), params, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// BoardCache keeps the metadata of the configured boards so templates can show
+// list and member names for the IDs in a payload. Lookups miss (and callers
+// fall back to the ID) until the first refresh succeeds.
+type BoardCache struct {
+	client *Client
+	boards []string
+
+	mu      sync.RWMutex
+	fetched map[string]Board // board ID -> last good snapshot
+	lists   map[string]string
+	listIDs map[string]string // lower-cased list name -> ID, first board wins
+	labels  map[string]string
+	members map[string]string // member ID and username -> display name
+}
+
+func NewBoardCache(client *Client, boards []string) *BoardCache {
+	return &BoardCache{client: client, boards: boards, fetched: make(map[string]Board)}
+}
+
+// Refresh re-fetches every board. A board that fails keeps its previous
+// snapshot; the first error is returned after the others are tried.
+func (c *BoardCache) Refresh(ctx context.Context) error {
+	var firstErr error
+	for _, id := range c.boards {
+		b, err := c.client.Board(ctx, id)
+		if err != nil {
+			if firstErr == nil {
+				firstErr = fmt.Errorf("fetch board %s: %w", id, err)
+			}
+			continue
+		}
+		c.mu.Lock()
+		c.fetched[id] = *b
+		c.mu.Unlock()
+	}
+	c.index()
+	return firstErr
+}
+
+// index rebuilds the lookup maps from the board snapshots.
+func (c *BoardCache) index() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.lists = make(map[string]string)
+	c.listIDs = make(map[string]string)
+	c.labels = make(map[string]string)
+	c.members = make(map[string]string)
+	for _, id := range c.boards {
+		b, ok := c.fetched[id]
+		if !ok {
+			continue
+		}
+		for _, l := range b.Lists {
+			c.lists[l.ID] = l.Name
+			key := strings.ToLower(strings.TrimSpace(l.Name))
+			if _, dup := c.listIDs[key]; !dup {
+				c.listIDs[key] = l.ID
+			}
+		}
+		for _, l := range b.Labels {
+			name := l.Name
+			if name == "" {
+				name = l.Color
+			}
+			c.labels[l.ID] = name
+		}
+		for _, m := range b.Members {
+			name := m.FullName
+			if name == "" {
+				name = m.Username
+			}
+			c.members[m.ID] = name
+			if m.Username != "" {
+				c.members[m.Username] = name
+			}
+		}
+	}
+}
+
+// Run refreshes the cache now and every interval until ctx is done.
+func (c *BoardCache) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if err := c.Refresh(ctx); err != nil {
+			log.Printf("Trello: board cache refresh failed: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// ListName returns the name of list id, or "" when it is not cached.
+func (c *BoardCache) ListName(id string) string {
+	return c.lookup(func() map[string]string { return c.lists }, id)
+}
+
+// ListID returns the ID of the open list called name (case-insensitive) on
+// the configured boards, searched in order, or "" when none is cached.
+func (c *BoardCache) ListID(name string) string {
+	return c.lookup(func() map[string]string { return c.listIDs }, strings.ToLower(strings.TrimSpace(name)))
+}
+
+// LabelName returns the name (or color, for unnamed labels) of label id.
+func (c *BoardCache) LabelName(id string) string {
+	return c.lookup(func() map[string]string { return c.labels }, id)
+}
+
+// MemberName returns the full name of a member given by ID or username.
+func (c *BoardCache) MemberName(idOrUsername string) string {
+	return c.lookup(func() map[string]string { return c.members }, idOrUsername)
+}
+
+func (c *BoardCache) lookup(m func() map[string]string, key string) string {
+	if c == nil {
+		return ""
+	}
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return m()[key]
+}
+
+// Boards returns the cached snapshots in configured order.
+func (c *BoardCache) Boards() []Board {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	out := []Board{}
+	for _, id := range c.boards {
+		if b, ok := c.fetched[id]; ok {
+			out = append(out, b)
+		}
+	}
+	return out
+}
+
+// HandleList serves GET /api/trello/boards with the cached snapshots.
+func (c *BoardCache) HandleList(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		jsonError(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	jsonResponse(w, map[string]any{"boards": c.Boards()})
+}
@@ -0,0 +1,83 @@
+package trello
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestBoardCache_Refresh(t *testing.T) {
+	failB2 := false
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/1/boards/b1":
+			if r.URL.Query().Get("lists") != "open" || r.URL.Query().Get("members") != "all" {
+				t.Errorf("expected nested lists and members, got %s", r.URL.RawQuery)
+			}
+			w.Write([]byte(`{"id":"b1","name":"Dev","lists":[{"id":"l1","name":"Ready"}],
+				"labels":[{"id":"lab1","name":"bug","color":"red"},{"id":"lab2","name":"","color":"green"}],
+				"members":[{"id":"m1","username":"alice","fullName":"Alice A."},{"id":"m2","username":"bob","fullName":""}]}`))
+		case "/1/boards/b2":
+			if failB2 {
+				http.Error(w, "boom", http.StatusInternalServerError)
+				return
+			}
+			w.Write([]byte(`{"id":"b2","name":"Ops","lists":[{"id":"l2","name":"Doing"}]}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	c := NewBoardCache(NewClient("k", "t", srv.URL), []string{"b1", "b2"})
+	if c.ListName("l1") != "" {
+		t.Error("expected a miss before the first refresh")
+	}
+	if err := c.Refresh(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	lookups := []struct {
+		got, want string
+	}{
+		{c.ListName("l1"), "Ready"},
+		{c.ListName("l2"), "Doing"},
+		{c.ListName("l3"), ""},
+		{c.LabelName("lab1"), "bug"},
+		{c.LabelName("lab2"), "green"},
+		{c.MemberName("m1"), "Alice A."},
+		{c.MemberName("alice"), "Alice A."},
+		{c.MemberName("bob"), "bob"},
+	}
+	for i, l := range lookups {
+		if l.got != l.want {
+			t.Errorf("lookup %d = %q, want %q", i, l.got, l.want)
+		}
+	}
+
+	// A failed board keeps its previous snapshot
+	failB2 = true
+	if err := c.Refresh(context.Background()); err == nil {
+		t.Error("expected the b2 failure reported")
+	}
+	if c.ListName("l2") != "Doing" {
+		t.Error("expected b2 lists kept after a failed refresh")
+	}
+
+	rec := httptest.NewRecorder()
+	c.HandleList(rec, httptest.NewRequest("GET", "/api/trello/boards", nil))
+	var resp struct{ Boards []Board }
+	json.NewDecoder(rec.Body).Decode(&resp)
+	if len(resp.Boards) != 2 || resp.Boards[0].Name != "Dev" || resp.Boards[1].Name != "Ops" {
+		t.Errorf("unexpected boards %+v", resp.Boards)
+	}
+}
+
+func TestBoardCache_Nil(t *testing.T) {
+	var c *BoardCache
+	if c.ListName("l1") != "" || c.MemberName("m1") != "" || c.LabelName("lab1") != "" {
+		t.Error("expected a nil cache to miss")
+	}
+}
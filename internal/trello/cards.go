@@ -0,0 +1,103 @@
+package trello
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+)
+
+const (
+	// cardListTTL is how long a looked-up card list is trusted. Moves the
+	// relay hears of update it sooner.
+	cardListTTL = 5 * time.Minute
+	// maxCardLists caps the cards whose list is kept.
+	maxCardLists = 1000
+)
+
+// CardList returns the ID of the list a card is in.
+func (c *Client) CardList(ctx context.Context, cardID string) (string, error) {
+	var out struct {
+		IDList string `json:"idList"`
+	}
+	if err := c.do(ctx, http.MethodGet, cardPath(cardID, ""), url.Values{"fields": {"idList"}}, &out); err != nil {
+		return "", err
+	}
+	return out.IDList, nil
+}
+
+// CardLists caches the list each card is in, for actions whose payload has
+// no list, such as comments.
+type CardLists struct {
+	client *Client
+
+	mu    sync.Mutex
+	cards map[string]cardList
+	now   func() time.Time
+}
+
+type cardList struct {
+	listID string
+	at     time.Time
+}
+
+func NewCardLists(client *Client) *CardLists {
+	return &CardLists{client: client, cards: make(map[string]cardList), now: time.Now}
+}
+
+// ListID returns the ID of the list the card is in: cached, or fetched from
+// Trello. When the fetch fails, the last known list is used, or "".
+func (c *CardLists) ListID(ctx context.Context, cardID string) string {
+	if c == nil || cardID == "" {
+		return ""
+	}
+	c.mu.Lock()
+	cached, ok := c.cards[cardID]
+	c.mu.Unlock()
+	if ok && c.now().Sub(cached.at) < cardListTTL {
+		return cached.listID
+	}
+	listID, err := c.client.CardList(ctx, cardID)
+	if err != nil {
+		log.Printf("Trello: failed to look up the list of card %s: %v", cardID, err)
+		return cached.listID
+	}
+	c.Set(cardID, listID)
+	return listID
+}
+
+// Set records the list a card moved to.
+func (c *CardLists) Set(cardID, listID string) {
+	if c == nil || cardID == "" {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.cards[cardID]; !ok && len(c.cards) >= maxCardLists {
+		c.evictOldest()
+	}
+	c.cards[cardID] = cardList{listID: listID, at: c.now()}
+}
+
+// Forget drops a deleted card.
+func (c *CardLists) Forget(cardID string) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.cards, cardID)
+}
+
+func (c *CardLists) evictOldest() {
+	var oldest string
+	var oldestAt time.Time
+	for id, e := range c.cards {
+		if oldest == "" || e.at.Before(oldestAt) {
+			oldest, oldestAt = id, e.at
+		}
+	}
+	delete(c.cards, oldest)
+}
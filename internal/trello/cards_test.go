@@ -0,0 +1,71 @@
+package trello
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestCardLists(t *testing.T) {
+	listID, status := "l1", http.StatusOK
+	var lookups int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lookups++
+		w.WriteHeader(status)
+		w.Write([]byte(`{"idList":"` + listID + `"}`))
+	}))
+	defer srv.Close()
+	c := NewCardLists(NewClient("k", "t", srv.URL))
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	c.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	if got := c.ListID(ctx, "c1"); got != "l1" || lookups != 1 {
+		t.Fatalf("first lookup = %q, %d requests", got, lookups)
+	}
+	if got := c.ListID(ctx, "c1"); got != "l1" || lookups != 1 {
+		t.Errorf("cached lookup = %q, %d requests", got, lookups)
+	}
+
+	// Moves update the cache without a request
+	c.Set("c1", "l2")
+	if got := c.ListID(ctx, "c1"); got != "l2" || lookups != 1 {
+		t.Errorf("after move = %q, %d requests", got, lookups)
+	}
+
+	// Past the TTL it is fetched again; a failure keeps the last known list
+	now = now.Add(cardListTTL)
+	status = http.StatusInternalServerError
+	if got := c.ListID(ctx, "c1"); got != "l2" || lookups != 2 {
+		t.Errorf("failed refresh = %q, %d requests", got, lookups)
+	}
+	c.Forget("c1")
+	if got := c.ListID(ctx, "c1"); got != "" {
+		t.Errorf("forgotten card = %q", got)
+	}
+
+	var nilCards *CardLists
+	if nilCards.ListID(ctx, "c1") != "" {
+		t.Error("nil cache returned a list")
+	}
+	nilCards.Set("c1", "l1")
+	nilCards.Forget("c1")
+}
+
+func TestCardLists_Evicts(t *testing.T) {
+	c := NewCardLists(nil)
+	start := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	for i := range maxCardLists + 1 {
+		c.now = func() time.Time { return start.Add(time.Duration(i) * time.Second) }
+		c.Set(fmt.Sprintf("c%d", i), "l1")
+	}
+	if len(c.cards) != maxCardLists {
+		t.Errorf("cards = %d", len(c.cards))
+	}
+	if _, ok := c.cards["c0"]; ok {
+		t.Error("oldest card kept")
+	}
+}
@@ -0,0 +1,160 @@
+// Package trello calls the Trello REST API on behalf of the agent, so write-back
+// actions go through the relay instead of embedding Trello credentials elsewhere.
+package trello
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// API is the set of Trello operations exposed to the agent.
+type API interface {
+	MoveCard(ctx context.Context, cardID, listID string) error
+	AddComment(ctx context.Context, cardID, text string) (*Comment, error)
+	AddLabel(ctx context.Context, cardID, labelID string) error
+	SetDue(ctx context.Context, cardID string, due *time.Time) error
+}
+
+// Comment is a created card comment.
+type Comment struct {
+	ID string `json:"id"`
+}
+
+// Client authenticates with an API key and member token.
+type Client struct {
+	key     string
+	token   string
+	baseURL string
+	HTTP    *http.Client
+}
+
+func NewClient(key, token, apiURL string) *Client {
+	baseURL := strings.TrimRight(apiURL, "/")
+	if baseURL == "" {
+		baseURL = "https://api.trello.com"
+	}
+	return &Client{
+		key:     key,
+		token:   token,
+		baseURL: baseURL,
+		HTTP:    &http.Client{Timeout: 15 * time.Second},
+	}
+}
+
+// APIError is a non-2xx response from Trello.
+type APIError struct {
+	Status int
+	Body   string
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("trello api: status %d: %s", e.Status, e.Body)
+}
+
+// do sends a request with params as the query string. Credentials go in the
+// Authorization header so they never show up in URLs or proxy logs.
+func (c *Client) do(ctx context.Context, method, path string, params url.Values, out any) error {
+	u := c.baseURL + "/1" + path
+	if len(params) > 0 {
+		u += "?" + params.Encode()
+	}
+	req, err := http.NewRequestWithContext(ctx, method, u, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", fmt.Sprintf(`OAuth oauth_consumer_key="%s", oauth_token="%s"`, c.key, c.token))
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := c.HTTP.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode >= 300 {
+		return &APIError{Status: resp.StatusCode, Body: strings.TrimSpace(string(body))}
+	}
+	if out != nil {
+		if err := json.Unmarshal(body, out); err != nil {
+			return fmt.Errorf("decode %s response: %w", path, err)
+		}
+	}
+	return nil
+}
+
+func cardPath(cardID string, suffix string) string {
+	return "/cards/" + url.PathEscape(cardID) + suffix
+}
+
+// MoveCard moves a card to another list.
+func (c *Client) MoveCard(ctx context.Context, cardID, listID string) error {
+	return c.do(ctx, http.MethodPut, cardPath(cardID, ""), url.Values{"idList": {listID}}, nil)
+}
+
+// AddComment posts a comment on a card.
+func (c *Client) AddComment(ctx context.Context, cardID, text string) (*Comment, error) {
+	var out Comment
+	if err := c.do(ctx, http.MethodPost, cardPath(cardID, "/actions/comments"), url.Values{"text": {text}}, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// AddLabel adds an existing board label to a card.
+func (c *Client) AddLabel(ctx context.Context, cardID, labelID string) error {
+	return c.do(ctx, http.MethodPost, cardPath(cardID, "/idLabels"), url.Values{"value": {labelID}}, nil)
+}
+
+// Webhook is a Trello webhook registered with the client's token.
+type Webhook struct {
+	ID          string `json:"id"`
+	Description string `json:"description"`
+	IDModel     string `json:"idModel"`
+	CallbackURL string `json:"callbackURL"`
+	Active      bool   `json:"active"`
+}
+
+// ListWebhooks returns the webhooks owned by the token.
+func (c *Client) ListWebhooks(ctx context.Context) ([]Webhook, error) {
+	var out []Webhook
+	if err := c.do(ctx, http.MethodGet, "/tokens/"+url.PathEscape(c.token)+"/webhooks", nil, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// CreateWebhook registers callbackURL for a model (board) ID. Trello verifies the
+// callback with a HEAD request before accepting it, so the relay must be listening.
+func (c *Client) CreateWebhook(ctx context.Context, idModel, callbackURL, description string) (*Webhook, error) {
+	var out Webhook
+	params := url.Values{"idModel": {idModel}, "callbackURL": {callbackURL}, "description": {description}}
+	if err := c.do(ctx, http.MethodPost, "/webhooks", params, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// ActivateWebhook re-enables a webhook Trello disabled after failed deliveries.
+func (c *Client) ActivateWebhook(ctx context.Context, id string) error {
+	return c.do(ctx, http.MethodPut, "/webhooks/"+url.PathEscape(id), url.Values{"active": {"true"}}, nil)
+}
+
+// DeleteWebhook removes a webhook.
+func (c *Client) DeleteWebhook(ctx context.Context, id string) error {
+	return c.do(ctx, http.MethodDelete, "/webhooks/"+url.PathEscape(id), nil, nil)
+}
+
+// SetDue sets the card's due date; nil clears it.
+func (c *Client) SetDue(ctx context.Context, cardID string, due *time.Time) error {
+	value := ""
+	if due != nil {
+		value = due.UTC().Format(time.RFC3339)
+	}
+	return c.do(ctx, http.MethodPut, cardPath(cardID, ""), url.Values{"due": {value}}, nil)
+}
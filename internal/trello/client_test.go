@@ -0,0 +1,93 @@
+package trello
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+type recorded struct {
+	method, path, auth string
+	query              map[string]string
+}
+
+func newTestClient(t *testing.T, status int, body string) (*Client, *[]recorded) {
+	var reqs []recorded
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		q := map[string]string{}
+		for k := range r.URL.Query() {
+			q[k] = r.URL.Query().Get(k)
+		}
+		reqs = append(reqs, recorded{r.Method, r.URL.Path, r.Header.Get("Authorization"), q})
+		w.WriteHeader(status)
+		w.Write([]byte(body))
+	}))
+	t.Cleanup(srv.Close)
+	return NewClient("k1", "t1", srv.URL+"/"), &reqs
+}
+
+func TestClient_Operations(t *testing.T) {
+	c, reqs := newTestClient(t, http.StatusOK, `{"id":"c9"}`)
+	ctx := context.Background()
+	due := time.Date(2026, 1, 2, 9, 0, 0, 0, time.FixedZone("CET", 3600))
+
+	if err := c.MoveCard(ctx, "card1", "list1"); err != nil {
+		t.Fatal(err)
+	}
+	comment, err := c.AddComment(ctx, "card1", "done")
+	if err != nil || comment.ID != "c9" {
+		t.Fatalf("AddComment = %+v, %v", comment, err)
+	}
+	if err := c.AddLabel(ctx, "card1", "lab1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.SetDue(ctx, "card1", &due); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.SetDue(ctx, "card1", nil); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []struct {
+		method, path, key, value string
+	}{
+		{"PUT", "/1/cards/card1", "idList", "list1"},
+		{"POST", "/1/cards/card1/actions/comments", "text", "done"},
+		{"POST", "/1/cards/card1/idLabels", "value", "lab1"},
+		{"PUT", "/1/cards/card1", "due", "2026-01-02T08:00:00Z"},
+		{"PUT", "/1/cards/card1", "due", ""},
+	}
+	if len(*reqs) != len(want) {
+		t.Fatalf("expected %d requests, got %d", len(want), len(*reqs))
+	}
+	for i, w := range want {
+		got := (*reqs)[i]
+		if got.method != w.method || got.path != w.path || got.query[w.key] != w.value {
+			t.Errorf("request %d = %+v, want %+v", i, got, w)
+		}
+		if got.auth != `OAuth oauth_consumer_key="k1", oauth_token="t1"` {
+			t.Errorf("request %d auth = %q", i, got.auth)
+		}
+		if _, leaked := got.query["token"]; leaked {
+			t.Errorf("request %d leaked token in query", i)
+		}
+	}
+}
+
+func TestClient_APIError(t *testing.T) {
+	c, _ := newTestClient(t, http.StatusNotFound, "card not found\n")
+	err := c.MoveCard(context.Background(), "nope", "list1")
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.Status != http.StatusNotFound || apiErr.Body != "card not found" {
+		t.Fatalf("expected 404 APIError, got %v", err)
+	}
+}
+
+func TestNewClient_DefaultURL(t *testing.T) {
+	if c := NewClient("k", "t", ""); c.baseURL != "https://api.trello.com" {
+		t.Errorf("unexpected base URL %q", c.baseURL)
+	}
+}
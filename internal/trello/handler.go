@@ -0,0 +1,158 @@
+package trello
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Handler serves /api/trello/cards/{id}/... for the agent.
+type Handler struct {
+	client API
+	lists  map[string]string // trello.lists: name -> list ID
+	named  *ListDirectory    // optional; trello.list_names resolved to IDs
+}
+
+func NewHandler(client API, lists map[string]string, named *ListDirectory) *Handler {
+	return &Handler{client: client, lists: lists, named: named}
+}
+
+// RegisterRoutes adds Trello API routes to the mux.
+func (h *Handler) RegisterRoutes(mux *http.ServeMux) {
+	mux.HandleFunc("/api/trello/cards/", h.handleCard)
+}
+
+func jsonResponse(w http.ResponseWriter, data any) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(data)
+}
+
+func jsonError(w http.ResponseWriter, msg string, code int) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(map[string]string{"error": msg})
+}
+
+// upstreamError maps Trello errors to a response, passing 4xx statuses through.
+func upstreamError(w http.ResponseWriter, err error) {
+	var apiErr *APIError
+	if errors.As(err, &apiErr) && apiErr.Status >= 400 && apiErr.Status < 500 {
+		jsonError(w, err.Error(), apiErr.Status)
+		return
+	}
+	jsonError(w, err.Error(), http.StatusBadGateway)
+}
+
+// handleCard routes:
+//
+//	POST /api/trello/cards/{id}/move      {"list": "ready"}  (trello.lists or trello.list_names alias, or list ID)
+//	POST /api/trello/cards/{id}/comments  {"text": "..."}
+//	POST /api/trello/cards/{id}/labels    {"label_id": "..."}
+//	PUT  /api/trello/cards/{id}/due       {"due": "2026-01-02T09:00:00Z"}  (null clears)
+func (h *Handler) handleCard(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/trello/cards/"), "/"), "/")
+	if len(parts) != 2 || parts[0] == "" {
+		jsonError(w, "not found", http.StatusNotFound)
+		return
+	}
+	cardID, op := parts[0], parts[1]
+
+	switch op {
+	case "move":
+		h.handleMove(w, r, cardID)
+	case "comments":
+		h.handleComment(w, r, cardID)
+	case "labels":
+		h.handleLabel(w, r, cardID)
+	case "due":
+		h.handleDue(w, r, cardID)
+	default:
+		jsonError(w, "not found", http.StatusNotFound)
+	}
+}
+
+func (h *Handler) handleMove(w http.ResponseWriter, r *http.Request, cardID string) {
+	if r.Method != http.MethodPost {
+		jsonError(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	var req struct {
+		List string `json:"list"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.List == "" {
+		jsonError(w, "list is required", http.StatusBadRequest)
+		return
+	}
+	listID := req.List
+	if id, ok := h.lists[req.List]; ok {
+		listID = id
+	} else if id := h.named.ID(req.List); id != "" {
+		listID = id
+	}
+	if err := h.client.MoveCard(r.Context(), cardID, listID); err != nil {
+		upstreamError(w, err)
+		return
+	}
+	jsonResponse(w, map[string]any{"ok": true, "list_id": listID})
+}
+
+func (h *Handler) handleComment(w http.ResponseWriter, r *http.Request, cardID string) {
+	if r.Method != http.MethodPost {
+		jsonError(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	var req struct {
+		Text string `json:"text"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || strings.TrimSpace(req.Text) == "" {
+		jsonError(w, "text is required", http.StatusBadRequest)
+		return
+	}
+	comment, err := h.client.AddComment(r.Context(), cardID, req.Text)
+	if err != nil {
+		upstreamError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusCreated)
+	jsonResponse(w, comment)
+}
+
+func (h *Handler) handleLabel(w http.ResponseWriter, r *http.Request, cardID string) {
+	if r.Method != http.MethodPost {
+		jsonError(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	var req struct {
+		LabelID string `json:"label_id"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.LabelID == "" {
+		jsonError(w, "label_id is required", http.StatusBadRequest)
+		return
+	}
+	if err := h.client.AddLabel(r.Context(), cardID, req.LabelID); err != nil {
+		upstreamError(w, err)
+		return
+	}
+	jsonResponse(w, map[string]any{"ok": true})
+}
+
+func (h *Handler) handleDue(w http.ResponseWriter, r *http.Request, cardID string) {
+	if r.Method != http.MethodPut {
+		jsonError(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	var req struct {
+		Due *time.Time `json:"due"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		jsonError(w, "due must be an RFC 3339 timestamp or null", http.StatusBadRequest)
+		return
+	}
+	if err := h.client.SetDue(r.Context(), cardID, req.Due); err != nil {
+		upstreamError(w, err)
+		return
+	}
+	jsonResponse(w, map[string]any{"ok": true, "due": req.Due})
+}
@@ -0,0 +1,111 @@
+package trello
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+type mockAPI struct {
+	calls []string
+	err   error
+}
+
+func (m *mockAPI) MoveCard(ctx context.Context, cardID, listID string) error {
+	m.calls = append(m.calls, "move "+cardID+" "+listID)
+	return m.err
+}
+
+func (m *mockAPI) AddComment(ctx context.Context, cardID, text string) (*Comment, error) {
+	m.calls = append(m.calls, "comment "+cardID+" "+text)
+	if m.err != nil {
+		return nil, m.err
+	}
+	return &Comment{ID: "c1"}, nil
+}
+
+func (m *mockAPI) AddLabel(ctx context.Context, cardID, labelID string) error {
+	m.calls = append(m.calls, "label "+cardID+" "+labelID)
+	return m.err
+}
+
+func (m *mockAPI) SetDue(ctx context.Context, cardID string, due *time.Time) error {
+	if due == nil {
+		m.calls = append(m.calls, "due "+cardID+" clear")
+	} else {
+		m.calls = append(m.calls, "due "+cardID+" "+due.Format(time.RFC3339))
+	}
+	return m.err
+}
+
+func serve(h *Handler, method, path, body string) *httptest.ResponseRecorder {
+	mux := http.NewServeMux()
+	h.RegisterRoutes(mux)
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestHandler_Routes(t *testing.T) {
+	tests := []struct {
+		method, path, body string
+		wantCode           int
+		wantCall           string
+	}{
+		{"POST", "/api/trello/cards/abc/move", `{"list":"ready"}`, http.StatusOK, "move abc list-ready"},
+		{"POST", "/api/trello/cards/abc/move", `{"list":"5f00"}`, http.StatusOK, "move abc 5f00"},
+		{"POST", "/api/trello/cards/abc/move", `{"list":"doing"}`, http.StatusOK, "move abc list-doing"},
+		{"POST", "/api/trello/cards/abc/comments", `{"text":"on it"}`, http.StatusCreated, "comment abc on it"},
+		{"POST", "/api/trello/cards/abc/labels", `{"label_id":"lab"}`, http.StatusOK, "label abc lab"},
+		{"PUT", "/api/trello/cards/abc/due", `{"due":"2026-01-02T09:00:00Z"}`, http.StatusOK, "due abc 2026-01-02T09:00:00Z"},
+		{"PUT", "/api/trello/cards/abc/due", `{"due":null}`, http.StatusOK, "due abc clear"},
+		{"POST", "/api/trello/cards/abc/move", `{}`, http.StatusBadRequest, ""},
+		{"POST", "/api/trello/cards/abc/comments", `{"text":" "}`, http.StatusBadRequest, ""},
+		{"POST", "/api/trello/cards/abc/labels", `{}`, http.StatusBadRequest, ""},
+		{"PUT", "/api/trello/cards/abc/due", `{"due":"tomorrow"}`, http.StatusBadRequest, ""},
+		{"GET", "/api/trello/cards/abc/move", "", http.StatusMethodNotAllowed, ""},
+		{"GET", "/api/trello/cards/abc/comments", "", http.StatusMethodNotAllowed, ""},
+		{"GET", "/api/trello/cards/abc/labels", "", http.StatusMethodNotAllowed, ""},
+		{"POST", "/api/trello/cards/abc/due", "", http.StatusMethodNotAllowed, ""},
+		{"POST", "/api/trello/cards/abc/archive", "", http.StatusNotFound, ""},
+		{"POST", "/api/trello/cards/abc", "", http.StatusNotFound, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.method+" "+tt.path+" "+tt.body, func(t *testing.T) {
+			m := &mockAPI{}
+			named := &ListDirectory{ids: map[string]string{"doing": "list-doing"}}
+			rec := serve(NewHandler(m, map[string]string{"ready": "list-ready"}, named), tt.method, tt.path, tt.body)
+			if rec.Code != tt.wantCode {
+				t.Fatalf("expected %d, got %d: %s", tt.wantCode, rec.Code, rec.Body.String())
+			}
+			if tt.wantCall == "" {
+				if len(m.calls) != 0 {
+					t.Errorf("unexpected calls: %v", m.calls)
+				}
+				return
+			}
+			if len(m.calls) != 1 || m.calls[0] != tt.wantCall {
+				t.Errorf("expected call %q, got %v", tt.wantCall, m.calls)
+			}
+		})
+	}
+}
+
+func TestHandler_UpstreamErrors(t *testing.T) {
+	rec := serve(NewHandler(&mockAPI{err: &APIError{Status: http.StatusUnauthorized, Body: "invalid token"}}, nil, nil),
+		"POST", "/api/trello/cards/abc/labels", `{"label_id":"lab"}`)
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("expected Trello 401 passed through, got %d", rec.Code)
+	}
+
+	rec = serve(NewHandler(&mockAPI{err: errors.New("connection refused")}, nil, nil),
+		"POST", "/api/trello/cards/abc/comments", `{"text":"hi"}`)
+	if rec.Code != http.StatusBadGateway {
+		t.Errorf("expected 502, got %d", rec.Code)
+	}
+}
@@ -0,0 +1,167 @@
+package trello
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"maps"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/katalabut/openclaw-relay/internal/atomicfile"
+)
+
+// missRefreshInterval is how often an unknown list ID may trigger a refresh.
+const missRefreshInterval = time.Minute
+
+// ListDirectory resolves trello.list_names (alias -> list name) to list IDs
+// through the board cache. The last resolved IDs are saved to a JSON file,
+// so aliases keep working when Trello is unreachable at startup, and a name
+// that disappears from the boards keeps its previous ID.
+type ListDirectory struct {
+	cache *BoardCache
+	names map[string]string // alias -> list name
+	path  string
+
+	mu          sync.RWMutex
+	ids         map[string]string // alias -> list ID
+	lastRefresh time.Time
+	now         func() time.Time
+}
+
+// NewListDirectory loads the IDs saved at path. An empty path keeps them in
+// memory only.
+func NewListDirectory(path string, names map[string]string, cache *BoardCache) (*ListDirectory, error) {
+	d := &ListDirectory{cache: cache, names: names, path: path, ids: make(map[string]string), now: time.Now}
+	if path == "" {
+		return d, nil
+	}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return d, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read list IDs: %w", err)
+	}
+	if err := json.Unmarshal(data, &d.ids); err != nil {
+		return nil, fmt.Errorf("parse list IDs: %w", err)
+	}
+	// Aliases removed from the config are forgotten
+	maps.DeleteFunc(d.ids, func(alias, _ string) bool { _, ok := names[alias]; return !ok })
+	return d, nil
+}
+
+func (d *ListDirectory) save() error {
+	if d.path == "" {
+		return nil
+	}
+	data, err := json.MarshalIndent(d.ids, "", "  ")
+	if err != nil {
+		return err
+	}
+	return atomicfile.WriteFile(d.path, data, 0600)
+}
+
+// Refresh re-fetches the boards and resolves every name again.
+func (d *ListDirectory) Refresh(ctx context.Context) error {
+	d.mu.Lock()
+	d.lastRefresh = d.now()
+	d.mu.Unlock()
+	err := d.cache.Refresh(ctx)
+	d.resolve()
+	return err
+}
+
+// resolve maps each name to its list ID from the cache, saving when an ID
+// changed. Names the cache doesn't know keep their previous ID.
+func (d *ListDirectory) resolve() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	changed := false
+	for alias, name := range d.names {
+		id := d.cache.ListID(name)
+		if id == "" {
+			if prev, ok := d.ids[alias]; ok {
+				log.Printf("Trello: no list named %q on the boards for alias %s, keeping %s", name, alias, prev)
+			} else {
+				log.Printf("Trello: no list named %q on the boards for alias %s", name, alias)
+			}
+			continue
+		}
+		if d.ids[alias] != id {
+			if prev, ok := d.ids[alias]; ok {
+				log.Printf("Trello: list %q (alias %s) is now %s, was %s", name, alias, id, prev)
+			}
+			d.ids[alias] = id
+			changed = true
+		}
+	}
+	if changed {
+		if err := d.save(); err != nil {
+			log.Printf("Trello: failed to save list IDs: %v", err)
+		}
+	}
+}
+
+// Run refreshes the directory now and every interval until ctx is done. It
+// replaces BoardCache.Run when list names are configured.
+func (d *ListDirectory) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if err := d.Refresh(ctx); err != nil {
+			log.Printf("Trello: board cache refresh failed: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// ID returns the list ID resolved for alias, or "".
+func (d *ListDirectory) ID(alias string) string {
+	if d == nil {
+		return ""
+	}
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	return d.ids[alias]
+}
+
+// Alias returns the alias resolved to list id, or "".
+func (d *ListDirectory) Alias(id string) string {
+	if d == nil || id == "" {
+		return ""
+	}
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	for alias, lid := range d.ids {
+		if lid == id {
+			return alias
+		}
+	}
+	return ""
+}
+
+// Lookup is Alias, but an unknown ID refreshes the boards first (at most once
+// a minute), so a list recreated under a configured name is picked up by the
+// webhook that first mentions it.
+func (d *ListDirectory) Lookup(ctx context.Context, id string) string {
+	if alias := d.Alias(id); alias != "" || id == "" || d == nil {
+		return alias
+	}
+	d.mu.RLock()
+	stale := d.now().Sub(d.lastRefresh) >= missRefreshInterval
+	d.mu.RUnlock()
+	if !stale {
+		return ""
+	}
+	if err := d.Refresh(ctx); err != nil {
+		log.Printf("Trello: board refresh for unknown list %s failed: %v", id, err)
+	}
+	return d.Alias(id)
+}
@@ -0,0 +1,74 @@
+package trello
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestListDirectory(t *testing.T) {
+	readyID := "l1"
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if readyID == "" {
+			w.Write([]byte(`{"id":"b1","lists":[{"id":"l2","name":"Doing"}]}`))
+			return
+		}
+		w.Write([]byte(`{"id":"b1","lists":[{"id":"` + readyID + `","name":"Ready for Dev"},{"id":"l2","name":"Doing"}]}`))
+	}))
+	defer srv.Close()
+
+	path := filepath.Join(t.TempDir(), "trello_lists.json")
+	names := map[string]string{"ready": "ready for dev", "doing": "Doing", "review": "Review"}
+	cache := NewBoardCache(NewClient("k", "t", srv.URL), []string{"b1"})
+	d, err := NewListDirectory(path, names, cache)
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	d.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	if err := d.Refresh(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if d.ID("ready") != "l1" || d.ID("doing") != "l2" || d.ID("review") != "" {
+		t.Errorf("unexpected IDs ready=%q doing=%q review=%q", d.ID("ready"), d.ID("doing"), d.ID("review"))
+	}
+	if d.Alias("l2") != "doing" {
+		t.Errorf("expected l2 to be doing, got %q", d.Alias("l2"))
+	}
+
+	// The list is recreated: an unknown ID refreshes, at most once a minute
+	readyID = "l9"
+	if got := d.Lookup(ctx, "l9"); got != "" {
+		t.Errorf("expected no refresh right after the last one, got %q", got)
+	}
+	now = now.Add(2 * time.Minute)
+	if got := d.Lookup(ctx, "l9"); got != "ready" {
+		t.Errorf("expected the recreated list resolved to ready, got %q", got)
+	}
+
+	// A name that disappears keeps its last ID
+	readyID = ""
+	d.Refresh(ctx)
+	if d.ID("ready") != "l9" {
+		t.Errorf("expected ready to keep l9, got %q", d.ID("ready"))
+	}
+
+	// The saved IDs work without reaching Trello; removed aliases are dropped
+	reloaded, err := NewListDirectory(path, map[string]string{"ready": "Ready for Dev"}, NewBoardCache(nil, nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reloaded.ID("ready") != "l9" || reloaded.ID("doing") != "" {
+		t.Errorf("unexpected reloaded IDs ready=%q doing=%q", reloaded.ID("ready"), reloaded.ID("doing"))
+	}
+
+	var nilDir *ListDirectory
+	if nilDir.Lookup(ctx, "l1") != "" || nilDir.ID("ready") != "" {
+		t.Error("expected a nil directory to miss")
+	}
+}
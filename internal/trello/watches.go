@@ -0,0 +1,166 @@
+package trello
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/katalabut/openclaw-relay/internal/atomicfile"
+	"github.com/katalabut/openclaw-relay/internal/migrate"
+)
+
+// Watch is a card whose comments are relayed while it sits in a watched list
+// (trello.watch_lists). Comment payloads carry no list, so the list comes
+// from here.
+type Watch struct {
+	CardID   string    `json:"card_id"`
+	CardName string    `json:"card_name,omitempty"`
+	List     string    `json:"list"` // alias from trello.lists or list_names
+	Since    time.Time `json:"since"`
+}
+
+// watchDoc is the watch file.
+type watchDoc struct {
+	Version int              `json:"version"`
+	Watches map[string]Watch `json:"watches"`
+}
+
+// watchSchema versions the watch file. Add a migrate.Step when watchDoc or
+// Watch changes shape.
+var watchSchema = migrate.Schema{Name: "trello-watches"}
+
+// Watches persists the watched cards to a JSON file. A watch ends when the
+// card moves to another list, is archived or deleted, or after ttl, for
+// cards whose leaving the relay never heard of.
+type Watches struct {
+	mu       sync.Mutex
+	filePath string
+	ttl      time.Duration
+	watches  map[string]Watch
+	now      func() time.Time
+}
+
+// NewWatches opens (or creates) the watch file at filePath. An empty
+// filePath keeps watches in memory only; a zero ttl never expires them.
+func NewWatches(filePath string, ttl time.Duration) (*Watches, error) {
+	w := &Watches{filePath: filePath, ttl: ttl, watches: map[string]Watch{}, now: time.Now}
+	if filePath == "" {
+		return w, nil
+	}
+	data, err := os.ReadFile(filePath)
+	if os.IsNotExist(err) {
+		return w, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if data, _, err = watchSchema.Apply(data); err != nil {
+		return nil, err
+	}
+	var doc watchDoc
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("parse watches: %w", err)
+	}
+	for id, wa := range doc.Watches {
+		w.watches[id] = wa
+	}
+	return w, nil
+}
+
+// Watch starts watching a card in list, or moves its watch there, and
+// reports whether it did. A card already watched in list keeps its start.
+func (w *Watches) Watch(cardID, cardName, list string) (bool, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if cur, ok := w.watches[cardID]; ok && cur.List == list && !w.expired(cur) {
+		return false, nil
+	}
+	w.watches[cardID] = Watch{CardID: cardID, CardName: cardName, List: list, Since: w.now().UTC()}
+	return true, w.save()
+}
+
+// Unwatch ends a card's watch and reports whether there was one.
+func (w *Watches) Unwatch(cardID string) (bool, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if _, ok := w.watches[cardID]; !ok {
+		return false, nil
+	}
+	delete(w.watches, cardID)
+	return true, w.save()
+}
+
+// Lookup returns a card's watch, unless it expired.
+func (w *Watches) Lookup(cardID string) (Watch, bool) {
+	if w == nil {
+		return Watch{}, false
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	wa, ok := w.watches[cardID]
+	if !ok || w.expired(wa) {
+		return Watch{}, false
+	}
+	return wa, true
+}
+
+// List returns the current watches, oldest first.
+func (w *Watches) List() []Watch {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	out := make([]Watch, 0, len(w.watches))
+	for _, wa := range w.watches {
+		if !w.expired(wa) {
+			out = append(out, wa)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Since.Before(out[j].Since) })
+	return out
+}
+
+// HandleList serves GET /api/trello/watches.
+func (w *Watches) HandleList(rw http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		jsonError(rw, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	jsonResponse(rw, map[string]any{"watches": w.List()})
+}
+
+// Prune drops the expired watches as of now and returns their card IDs. With
+// dryRun it only finds them.
+func (w *Watches) Prune(now time.Time, dryRun bool) ([]string, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	var ids []string
+	for id, wa := range w.watches {
+		if w.ttl > 0 && !now.Before(wa.Since.Add(w.ttl)) {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+	if dryRun || len(ids) == 0 {
+		return ids, nil
+	}
+	for _, id := range ids {
+		delete(w.watches, id)
+	}
+	return ids, w.save()
+}
+
+func (w *Watches) expired(wa Watch) bool {
+	return w.ttl > 0 && !w.now().Before(wa.Since.Add(w.ttl))
+}
+
+// save replaces the watch file atomically, so a crash mid-write leaves the
+// previous watches rather than a truncated file.
+func (w *Watches) save() error {
+	if w.filePath == "" {
+		return nil
+	}
+	return atomicfile.WriteJSON(w.filePath, watchDoc{Version: watchSchema.Current(), Watches: w.watches})
+}
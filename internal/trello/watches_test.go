@@ -0,0 +1,98 @@
+package trello
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWatches(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "trello_watches.json")
+	w, err := NewWatches(path, 24*time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	w.now = func() time.Time { return now }
+
+	if started, err := w.Watch("c1", "Login bug", "questions"); !started || err != nil {
+		t.Fatalf("watch: %v %v", started, err)
+	}
+	now = now.Add(time.Hour)
+	if started, _ := w.Watch("c1", "Login bug", "questions"); started {
+		t.Error("re-watching in the same list restarted the watch")
+	}
+	w.Watch("c2", "Signup", "review")
+	if got, ok := w.Lookup("c1"); !ok || got.List != "questions" || !got.Since.Equal(now.Add(-time.Hour)) {
+		t.Errorf("lookup c1 = %+v %v", got, ok)
+	}
+
+	// Reopened from disk
+	w2, err := NewWatches(path, 24*time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w2.now = w.now
+	if list := w2.List(); len(list) != 2 || list[0].CardID != "c1" {
+		t.Errorf("reopened = %+v", list)
+	}
+	data, _ := os.ReadFile(path)
+	if !strings.Contains(string(data), `"version":0`) {
+		t.Errorf("file = %s", data)
+	}
+
+	if ended, _ := w.Unwatch("c2"); !ended {
+		t.Error("unwatch c2 reported no watch")
+	}
+	if ended, _ := w.Unwatch("c2"); ended {
+		t.Error("second unwatch reported a watch")
+	}
+
+	// Past the TTL a watch no longer counts and is pruned
+	now = now.Add(23 * time.Hour)
+	if _, ok := w.Lookup("c1"); ok {
+		t.Error("expired watch still found")
+	}
+	if ids, _ := w.Prune(now, true); len(ids) != 1 || ids[0] != "c1" || len(w.watches) != 1 {
+		t.Errorf("dry run = %v", ids)
+	}
+	w.Prune(now, false)
+	if len(w.watches) != 0 {
+		t.Errorf("after prune: %+v", w.watches)
+	}
+}
+
+func TestWatches_Corrupt(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "trello_watches.json")
+	os.WriteFile(path, []byte("{"), 0600)
+	if _, err := NewWatches(path, time.Hour); err == nil {
+		t.Error("expected an error for a corrupt file")
+	}
+	os.WriteFile(path, []byte(`{"version":99,"watches":{}}`), 0600)
+	if _, err := NewWatches(path, time.Hour); err == nil {
+		t.Error("expected an error for a newer version")
+	}
+}
+
+func TestWatches_HandleList(t *testing.T) {
+	w, _ := NewWatches("", 0)
+	w.Watch("c1", "Login bug", "questions")
+
+	rec := httptest.NewRecorder()
+	w.HandleList(rec, httptest.NewRequest("GET", "/api/trello/watches", nil))
+	var resp struct{ Watches []Watch }
+	json.NewDecoder(rec.Body).Decode(&resp)
+	if rec.Code != http.StatusOK || len(resp.Watches) != 1 || resp.Watches[0].CardName != "Login bug" {
+		t.Errorf("list: %d %+v", rec.Code, resp)
+	}
+	rec = httptest.NewRecorder()
+	w.HandleList(rec, httptest.NewRequest("POST", "/api/trello/watches", nil))
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("POST: %d", rec.Code)
+	}
+}
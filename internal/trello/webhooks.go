@@ -0,0 +1,87 @@
+package trello
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+)
+
+// WebhookManager keeps one active webhook per configured board pointing at the
+// relay's callback URL, so boards don't have to be registered by hand.
+type WebhookManager struct {
+	client      *Client
+	boards      []string
+	callbackURL string
+}
+
+func NewWebhookManager(client *Client, boards []string, callbackURL string) *WebhookManager {
+	return &WebhookManager{client: client, boards: boards, callbackURL: callbackURL}
+}
+
+// owned returns the existing webhooks for our callback URL, keyed by board ID.
+func (m *WebhookManager) owned(ctx context.Context) (map[string]Webhook, error) {
+	hooks, err := m.client.ListWebhooks(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("list webhooks: %w", err)
+	}
+	out := make(map[string]Webhook)
+	for _, wh := range hooks {
+		if wh.CallbackURL == m.callbackURL {
+			out[wh.IDModel] = wh
+		}
+	}
+	return out, nil
+}
+
+// Sync creates missing board webhooks and re-activates disabled ones. It is
+// idempotent: boards that already have an active webhook are left alone.
+func (m *WebhookManager) Sync(ctx context.Context) error {
+	existing, err := m.owned(ctx)
+	if err != nil {
+		return err
+	}
+	var errs []error
+	for _, board := range m.boards {
+		wh, ok := existing[board]
+		switch {
+		case ok && wh.Active:
+			log.Printf("Trello: webhook %s for board %s already registered", wh.ID, board)
+		case ok:
+			if err := m.client.ActivateWebhook(ctx, wh.ID); err != nil {
+				errs = append(errs, fmt.Errorf("activate webhook for board %s: %w", board, err))
+				continue
+			}
+			log.Printf("Trello: re-activated webhook %s for board %s", wh.ID, board)
+		default:
+			created, err := m.client.CreateWebhook(ctx, board, m.callbackURL, "openclaw-relay")
+			if err != nil {
+				errs = append(errs, fmt.Errorf("create webhook for board %s: %w", board, err))
+				continue
+			}
+			log.Printf("Trello: registered webhook %s for board %s", created.ID, board)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Remove deletes the configured boards' webhooks for our callback URL.
+func (m *WebhookManager) Remove(ctx context.Context) error {
+	existing, err := m.owned(ctx)
+	if err != nil {
+		return err
+	}
+	var errs []error
+	for _, board := range m.boards {
+		wh, ok := existing[board]
+		if !ok {
+			continue
+		}
+		if err := m.client.DeleteWebhook(ctx, wh.ID); err != nil {
+			errs = append(errs, fmt.Errorf("delete webhook for board %s: %w", board, err))
+			continue
+		}
+		log.Printf("Trello: deleted webhook %s for board %s", wh.ID, board)
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,114 @@
+package trello
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// fakeTrello keeps webhooks in memory and records mutating calls.
+type fakeTrello struct {
+	hooks []Webhook
+	calls []string
+}
+
+func (f *fakeTrello) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	switch {
+	case r.Method == "GET" && strings.HasPrefix(r.URL.Path, "/1/tokens/"):
+		json.NewEncoder(w).Encode(f.hooks)
+		return
+	case r.Method == "POST" && r.URL.Path == "/1/webhooks":
+		wh := Webhook{ID: "new-" + q.Get("idModel"), IDModel: q.Get("idModel"), CallbackURL: q.Get("callbackURL"), Active: true}
+		f.hooks = append(f.hooks, wh)
+		f.calls = append(f.calls, "create "+wh.IDModel)
+		json.NewEncoder(w).Encode(wh)
+		return
+	case r.Method == "PUT" && q.Get("active") == "true":
+		f.calls = append(f.calls, "activate "+strings.TrimPrefix(r.URL.Path, "/1/webhooks/"))
+	case r.Method == "DELETE":
+		f.calls = append(f.calls, "delete "+strings.TrimPrefix(r.URL.Path, "/1/webhooks/"))
+	default:
+		http.Error(w, "unexpected", http.StatusBadRequest)
+		return
+	}
+	w.Write([]byte(`{}`))
+}
+
+func newTestManager(t *testing.T, f *fakeTrello, boards ...string) *WebhookManager {
+	srv := httptest.NewServer(f)
+	t.Cleanup(srv.Close)
+	return NewWebhookManager(NewClient("k", "t", srv.URL), boards, "https://relay.example.com/webhook/trello")
+}
+
+func TestWebhookManager_Sync(t *testing.T) {
+	f := &fakeTrello{hooks: []Webhook{
+		{ID: "h1", IDModel: "board1", CallbackURL: "https://relay.example.com/webhook/trello", Active: true},
+		{ID: "h2", IDModel: "board2", CallbackURL: "https://relay.example.com/webhook/trello", Active: false},
+		{ID: "h3", IDModel: "board3", CallbackURL: "https://other.example.com/hook", Active: true},
+	}}
+	m := newTestManager(t, f, "board1", "board2", "board3")
+
+	if err := m.Sync(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"activate h2", "create board3"}
+	if strings.Join(f.calls, ",") != strings.Join(want, ",") {
+		t.Errorf("calls = %v, want %v", f.calls, want)
+	}
+
+	// Second sync is a no-op once everything is registered and active
+	f.hooks[1].Active = true
+	f.calls = nil
+	if err := m.Sync(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if len(f.calls) != 0 {
+		t.Errorf("expected idempotent sync, got %v", f.calls)
+	}
+}
+
+func TestWebhookManager_Remove(t *testing.T) {
+	f := &fakeTrello{hooks: []Webhook{
+		{ID: "h1", IDModel: "board1", CallbackURL: "https://relay.example.com/webhook/trello"},
+		{ID: "h2", IDModel: "board2", CallbackURL: "https://relay.example.com/webhook/trello"},
+		{ID: "h3", IDModel: "board1", CallbackURL: "https://other.example.com/hook"},
+		{ID: "h4", IDModel: "unmanaged", CallbackURL: "https://relay.example.com/webhook/trello"},
+	}}
+	m := newTestManager(t, f, "board1", "board2", "board9")
+
+	if err := m.Remove(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	sort.Strings(f.calls)
+	if strings.Join(f.calls, ",") != "delete h1,delete h2" {
+		t.Errorf("unexpected calls: %v", f.calls)
+	}
+}
+
+func TestWebhookManager_Errors(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "GET" {
+			w.Write([]byte(`[]`))
+			return
+		}
+		http.Error(w, "URL (https://relay.example.com/webhook/trello) did not return 200 status code", http.StatusBadRequest)
+	}))
+	defer srv.Close()
+	m := NewWebhookManager(NewClient("k", "t", srv.URL), []string{"board1"}, "https://relay.example.com/webhook/trello")
+	if err := m.Sync(context.Background()); err == nil || !strings.Contains(err.Error(), "board1") {
+		t.Errorf("expected create error naming the board, got %v", err)
+	}
+
+	broken := NewWebhookManager(NewClient("k", "t", "http://127.0.0.1:1"), []string{"board1"}, "x")
+	if err := broken.Sync(context.Background()); err == nil {
+		t.Error("expected list error")
+	}
+	if err := broken.Remove(context.Background()); err == nil {
+		t.Error("expected list error")
+	}
+}
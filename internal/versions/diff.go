@@ -0,0 +1,143 @@
+package versions
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	// diffContext is the number of unchanged lines shown around each change.
+	diffContext = 3
+	// maxDiffCells bounds the LCS table; larger inputs diff as a full replacement.
+	maxDiffCells = 4 << 20
+)
+
+type diffOp struct {
+	kind byte // ' ', '-' or '+'
+	line string
+}
+
+// Diff renders a unified diff from a to b, labelled with the given names.
+// It returns "" when the contents are equal.
+func Diff(fromName, toName, a, b string) string {
+	if a == b {
+		return ""
+	}
+	ops := diffLines(splitLines(a), splitLines(b))
+	var out strings.Builder
+	fmt.Fprintf(&out, "--- %s\n+++ %s\n", fromName, toName)
+	for _, h := range hunks(ops) {
+		out.WriteString(h)
+	}
+	return out.String()
+}
+
+func splitLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(s, "\n"), "\n")
+}
+
+// diffLines aligns a and b on their longest common subsequence.
+func diffLines(a, b []string) []diffOp {
+	var ops []diffOp
+	if len(a)*len(b) > maxDiffCells {
+		for _, l := range a {
+			ops = append(ops, diffOp{'-', l})
+		}
+		for _, l := range b {
+			ops = append(ops, diffOp{'+', l})
+		}
+		return ops
+	}
+	// lcs[i][j] is the LCS length of a[i:] and b[j:]
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] == b[j]:
+			ops = append(ops, diffOp{' ', a[i]})
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			ops = append(ops, diffOp{'-', a[i]})
+			i++
+		default:
+			ops = append(ops, diffOp{'+', b[j]})
+			j++
+		}
+	}
+	for ; i < len(a); i++ {
+		ops = append(ops, diffOp{'-', a[i]})
+	}
+	for ; j < len(b); j++ {
+		ops = append(ops, diffOp{'+', b[j]})
+	}
+	return ops
+}
+
+// hunks groups ops into "@@ -l,n +l,n @@" hunks with diffContext lines around changes.
+func hunks(ops []diffOp) []string {
+	var out []string
+	for start := 0; start < len(ops); {
+		// find the next change
+		first := start
+		for first < len(ops) && ops[first].kind == ' ' {
+			first++
+		}
+		if first == len(ops) {
+			break
+		}
+		from := max(first-diffContext, start)
+		// extend while changes are within 2*diffContext unchanged lines of each other
+		end, unchanged := first, 0
+		for k := first; k < len(ops) && unchanged <= 2*diffContext; k++ {
+			if ops[k].kind == ' ' {
+				unchanged++
+			} else {
+				unchanged = 0
+				end = k + 1
+			}
+		}
+		to := min(end+diffContext, len(ops))
+
+		aLine, bLine := 1, 1
+		for _, op := range ops[:from] {
+			if op.kind != '+' {
+				aLine++
+			}
+			if op.kind != '-' {
+				bLine++
+			}
+		}
+		var body strings.Builder
+		aCount, bCount := 0, 0
+		for _, op := range ops[from:to] {
+			if op.kind != '+' {
+				aCount++
+			}
+			if op.kind != '-' {
+				bCount++
+			}
+			body.WriteByte(op.kind)
+			body.WriteString(op.line)
+			body.WriteByte('\n')
+		}
+		out = append(out, fmt.Sprintf("@@ -%d,%d +%d,%d @@\n", aLine, aCount, bLine, bCount)+body.String())
+		start = to
+	}
+	return out
+}
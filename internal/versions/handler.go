@@ -0,0 +1,162 @@
+package versions
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/katalabut/openclaw-relay/internal/config"
+	"github.com/katalabut/openclaw-relay/internal/sealed"
+)
+
+// Handler serves /api/config/versions. Running is the version the process
+// started with; the relay has no live reload, so a rollback rewrites the
+// config file and takes effect on the next restart. Secrets in the content it
+// serves are sealed with key, or redacted without one.
+type Handler struct {
+	store   *Store
+	path    string
+	running Version
+	key     []byte
+}
+
+// NewHandler serves versions from store; path is the config file rollbacks write.
+func NewHandler(store *Store, path string, running Version) *Handler {
+	return &Handler{store: store, path: path, running: running}
+}
+
+// SetSecretKey seals the secrets in served config content with key, the
+// parsed RELAY_ENCRYPTION_KEY, instead of redacting them.
+func (h *Handler) SetSecretKey(key []byte) {
+	h.key = key
+}
+
+// RegisterRoutes adds the version routes to the mux.
+func (h *Handler) RegisterRoutes(mux *http.ServeMux) {
+	mux.HandleFunc("/api/config/versions", h.handleList)
+	mux.HandleFunc("/api/config/versions/", h.handleVersion)
+}
+
+func jsonResponse(w http.ResponseWriter, data any) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(data)
+}
+
+func jsonError(w http.ResponseWriter, msg string, code int) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(map[string]string{"error": msg})
+}
+
+func (h *Handler) handleList(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		jsonError(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	jsonResponse(w, map[string]any{"running": h.running.ID, "versions": h.store.List()})
+}
+
+// handleVersion routes:
+//
+//	GET  /api/config/versions/{id}
+//	GET  /api/config/versions/{id}/diff[?from=N]  (default: the running version)
+//	POST /api/config/versions/{id}/rollback
+func (h *Handler) handleVersion(w http.ResponseWriter, r *http.Request) {
+	rest := strings.TrimPrefix(r.URL.Path, "/api/config/versions/")
+	idStr, action, _ := strings.Cut(rest, "/")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		jsonError(w, "invalid version id", http.StatusBadRequest)
+		return
+	}
+	v, ok := h.store.Get(id)
+	if !ok {
+		jsonError(w, "version not found", http.StatusNotFound)
+		return
+	}
+
+	switch {
+	case action == "" && r.Method == http.MethodGet:
+		if v.Content, err = sealed.SealConfig(v.Content, h.key); err != nil {
+			jsonError(w, fmt.Sprintf("can't seal secrets in version %d: %v", v.ID, err), http.StatusInternalServerError)
+			return
+		}
+		jsonResponse(w, v)
+	case action == "diff" && r.Method == http.MethodGet:
+		h.diff(w, r, v)
+	case action == "rollback" && r.Method == http.MethodPost:
+		h.rollback(w, v)
+	case action == "" || action == "diff" || action == "rollback":
+		jsonError(w, "method not allowed", http.StatusMethodNotAllowed)
+	default:
+		jsonError(w, "not found", http.StatusNotFound)
+	}
+}
+
+func (h *Handler) diff(w http.ResponseWriter, r *http.Request, v Version) {
+	from := h.running
+	if s := r.URL.Query().Get("from"); s != "" {
+		id, err := strconv.Atoi(s)
+		if err != nil {
+			jsonError(w, "invalid from version", http.StatusBadRequest)
+			return
+		}
+		var ok bool
+		if from, ok = h.store.Get(id); !ok {
+			jsonError(w, "from version not found", http.StatusNotFound)
+			return
+		}
+	} else if from.ID != 0 {
+		// h.running has no content; fetch it
+		from, _ = h.store.Get(from.ID)
+	}
+	// Secrets are sealed on both sides, so a changed secret shows as a
+	// changed sealed value
+	a, err := sealed.SealConfig(from.Content, h.key)
+	if err == nil {
+		v.Content, err = sealed.SealConfig(v.Content, h.key)
+	}
+	if err != nil {
+		jsonError(w, fmt.Sprintf("can't seal secrets: %v", err), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	fmt.Fprint(w, Diff(fmt.Sprintf("version %d", from.ID), fmt.Sprintf("version %d", v.ID), a, v.Content))
+}
+
+// rollback validates v and writes it over the config file. The file on disk is
+// snapshotted first, so hand edits made since startup can be recovered.
+func (h *Handler) rollback(w http.ResponseWriter, v Version) {
+	if h.path == "" {
+		jsonError(w, "config path unknown", http.StatusConflict)
+		return
+	}
+	if err := config.ValidateContent([]byte(v.Content)); err != nil {
+		jsonError(w, fmt.Sprintf("version %d is not a valid config: %v", v.ID, err), http.StatusUnprocessableEntity)
+		return
+	}
+
+	current, err := os.ReadFile(h.path)
+	if err != nil {
+		jsonError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if _, _, err := h.store.Record(current, "on disk before rollback"); err != nil {
+		jsonError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if err := config.WriteFile(h.path, []byte(v.Content)); err != nil {
+		jsonError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	saved, _, err := h.store.Record([]byte(v.Content), fmt.Sprintf("rollback to %d", v.ID))
+	if err != nil {
+		jsonError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	saved.Content = ""
+	jsonResponse(w, map[string]any{"version": saved, "restart_required": true})
+}
@@ -0,0 +1,138 @@
+package versions
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const (
+	goodConfig = "gateway:\n  url: http://gateway:18789\ntrello:\n  rules:\n    - name: ready\n      list: Ready\n"
+	newConfig  = "gateway:\n  url: http://gateway:18789\ntrello:\n  rules:\n    - name: ready\n      list: Doing\n"
+	badConfig  = "trello:\n  rules:\n    - name: ready\n      list: Ready\n"
+)
+
+func TestHandler(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte(newConfig+"# hand edit\n"), 0640); err != nil {
+		t.Fatal(err)
+	}
+	store, _ := NewStore("", 0)
+	store.Record([]byte(goodConfig), "startup")
+	store.Record([]byte(badConfig), "startup")
+	running, _, _ := store.Record([]byte(newConfig), "startup")
+
+	mux := http.NewServeMux()
+	NewHandler(store, path, running).RegisterRoutes(mux)
+	do := func(method, target string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest(method, target, nil))
+		return rec
+	}
+
+	rec := do("GET", "/api/config/versions")
+	var list struct {
+		Running  int       `json:"running"`
+		Versions []Version `json:"versions"`
+	}
+	json.NewDecoder(rec.Body).Decode(&list)
+	if rec.Code != 200 || list.Running != 3 || len(list.Versions) != 3 {
+		t.Fatalf("list: code=%d body=%+v", rec.Code, list)
+	}
+
+	rec = do("GET", "/api/config/versions/1")
+	var v Version
+	json.NewDecoder(rec.Body).Decode(&v)
+	if rec.Code != 200 || v.Content != goodConfig {
+		t.Errorf("get: code=%d version=%+v", rec.Code, v)
+	}
+
+	rec = do("GET", "/api/config/versions/1/diff")
+	if body := rec.Body.String(); !strings.Contains(body, "--- version 3\n+++ version 1\n") || !strings.Contains(body, "-      list: Doing\n+      list: Ready\n") {
+		t.Errorf("diff against running:\n%s", body)
+	}
+	rec = do("GET", "/api/config/versions/3/diff?from=1")
+	if body := rec.Body.String(); !strings.Contains(body, "--- version 1\n+++ version 3\n") {
+		t.Errorf("diff from 1:\n%s", body)
+	}
+
+	for _, tt := range []struct {
+		method, target string
+		want           int
+	}{
+		{"GET", "/api/config/versions/x", http.StatusBadRequest},
+		{"GET", "/api/config/versions/9", http.StatusNotFound},
+		{"GET", "/api/config/versions/1/diff?from=9", http.StatusNotFound},
+		{"GET", "/api/config/versions/1/other", http.StatusNotFound},
+		{"GET", "/api/config/versions/1/rollback", http.StatusMethodNotAllowed},
+		{"POST", "/api/config/versions", http.StatusMethodNotAllowed},
+		{"POST", "/api/config/versions/2/rollback", http.StatusUnprocessableEntity},
+	} {
+		if rec := do(tt.method, tt.target); rec.Code != tt.want {
+			t.Errorf("%s %s: got %d, want %d", tt.method, tt.target, rec.Code, tt.want)
+		}
+	}
+	if data, _ := os.ReadFile(path); string(data) != newConfig+"# hand edit\n" {
+		t.Fatal("invalid rollback must not touch the config file")
+	}
+
+	rec = do("POST", "/api/config/versions/1/rollback")
+	var resp struct {
+		Version         Version `json:"version"`
+		RestartRequired bool    `json:"restart_required"`
+	}
+	json.NewDecoder(rec.Body).Decode(&resp)
+	if rec.Code != 200 || !resp.RestartRequired || resp.Version.ID != 5 || resp.Version.Reason != "rollback to 1" {
+		t.Fatalf("rollback: code=%d resp=%+v", rec.Code, resp)
+	}
+	data, _ := os.ReadFile(path)
+	if string(data) != goodConfig {
+		t.Errorf("config file = %q, want version 1", data)
+	}
+	if info, _ := os.Stat(path); info.Mode().Perm() != 0640 {
+		t.Errorf("config file mode = %v, want 0640", info.Mode().Perm())
+	}
+	if edited, ok := store.Get(4); !ok || edited.Reason != "on disk before rollback" || !strings.Contains(edited.Content, "# hand edit") {
+		t.Errorf("hand-edited file should be snapshotted before rollback, got %+v", edited)
+	}
+}
+
+func TestHandler_SealsSecrets(t *testing.T) {
+	const secretV1 = "server:\n  internal_token: hunter2\ngithub:\n  secret: ${GITHUB_SECRET}\n"
+	const secretV2 = "server:\n  internal_token: hunter3\ngithub:\n  secret: ${GITHUB_SECRET}\n"
+	store, _ := NewStore("", 0)
+	store.Record([]byte(secretV1), "startup")
+	running, _, _ := store.Record([]byte(secretV2), "startup")
+
+	for _, key := range [][]byte{nil, []byte(strings.Repeat("k", 32))} {
+		mux := http.NewServeMux()
+		h := NewHandler(store, "", running)
+		h.SetSecretKey(key)
+		h.RegisterRoutes(mux)
+
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest("GET", "/api/config/versions/1", nil))
+		var v Version
+		json.NewDecoder(rec.Body).Decode(&v)
+		if rec.Code != 200 || strings.Contains(v.Content, "hunter2") || !strings.Contains(v.Content, "${GITHUB_SECRET}") {
+			t.Errorf("key %v: code=%d content=%q", key != nil, rec.Code, v.Content)
+		}
+		if key != nil && !strings.Contains(v.Content, "internal_token: sealed:") {
+			t.Errorf("expected a sealed token, got %q", v.Content)
+		}
+
+		rec = httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest("GET", "/api/config/versions/1/diff", nil))
+		body := rec.Body.String()
+		if strings.Contains(body, "hunter") {
+			t.Errorf("plaintext in diff:\n%s", body)
+		}
+		if changed := strings.Contains(body, "-  internal_token:"); changed != (key != nil) {
+			t.Errorf("key %v: diff shows the changed token: %v\n%s", key != nil, changed, body)
+		}
+	}
+}
@@ -0,0 +1,126 @@
+// Package versions keeps a history of the config file the relay ran with, so a
+// broken rule edit can be diffed against and rolled back to a known-good version.
+package versions
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/katalabut/openclaw-relay/internal/atomicfile"
+)
+
+// DefaultMax is the number of versions kept when NewStore gets max <= 0.
+const DefaultMax = 50
+
+// Version is one snapshot of the config file, taken before ${VAR} substitution
+// so secrets referenced from the environment are not copied into it.
+type Version struct {
+	ID        int       `json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	SHA256    string    `json:"sha256"`
+	Reason    string    `json:"reason"` // "startup" or "rollback to N"
+	Content   string    `json:"content,omitempty"`
+}
+
+// Store persists versions to a JSON file, oldest first.
+type Store struct {
+	mu       sync.RWMutex
+	filePath string
+	max      int
+	versions []Version
+}
+
+// NewStore opens (or creates) the version store at filePath, keeping the
+// newest max versions. An empty filePath keeps versions in memory only.
+func NewStore(filePath string, max int) (*Store, error) {
+	if max <= 0 {
+		max = DefaultMax
+	}
+	s := &Store{filePath: filePath, max: max}
+	if filePath == "" {
+		return s, nil
+	}
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return s, nil
+		}
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &s.versions); err != nil {
+		return nil, fmt.Errorf("parse config versions: %w", err)
+	}
+	return s, nil
+}
+
+func (s *Store) save() error {
+	if s.filePath == "" {
+		return nil
+	}
+	return atomicfile.WriteJSON(s.filePath, s.versions)
+}
+
+func checksum(content []byte) string {
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:])
+}
+
+// Record snapshots content unless it matches the latest version, and returns
+// the version now describing it. created reports whether a new one was added.
+func (s *Store) Record(content []byte, reason string) (v Version, created bool, err error) {
+	sum := checksum(content)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if n := len(s.versions); n > 0 && s.versions[n-1].SHA256 == sum {
+		return s.versions[n-1], false, nil
+	}
+	v = Version{ID: 1, CreatedAt: time.Now().UTC(), SHA256: sum, Reason: reason, Content: string(content)}
+	if n := len(s.versions); n > 0 {
+		v.ID = s.versions[n-1].ID + 1
+	}
+	s.versions = append(s.versions, v)
+	if len(s.versions) > s.max {
+		s.versions = append([]Version(nil), s.versions[len(s.versions)-s.max:]...)
+	}
+	return v, true, s.save()
+}
+
+// List returns all versions newest first, without their content.
+func (s *Store) List() []Version {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	out := make([]Version, 0, len(s.versions))
+	for i := len(s.versions) - 1; i >= 0; i-- {
+		v := s.versions[i]
+		v.Content = ""
+		out = append(out, v)
+	}
+	return out
+}
+
+// Get returns version id with its content.
+func (s *Store) Get(id int) (Version, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for _, v := range s.versions {
+		if v.ID == id {
+			return v, true
+		}
+	}
+	return Version{}, false
+}
+
+// Latest returns the newest version.
+func (s *Store) Latest() (Version, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if len(s.versions) == 0 {
+		return Version{}, false
+	}
+	return s.versions[len(s.versions)-1], true
+}
@@ -0,0 +1,90 @@
+package versions
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestStore_Record(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "versions.json")
+	s, err := NewStore(path, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	v1, created, err := s.Record([]byte("a: 1\n"), "startup")
+	if err != nil || !created || v1.ID != 1 {
+		t.Fatalf("first record: v=%+v created=%v err=%v", v1, created, err)
+	}
+	again, created, _ := s.Record([]byte("a: 1\n"), "startup")
+	if created || again.ID != 1 {
+		t.Errorf("unchanged content should not add a version, got %+v created=%v", again, created)
+	}
+	for _, c := range []string{"a: 2\n", "a: 3\n", "a: 4\n"} {
+		s.Record([]byte(c), "startup")
+	}
+
+	reopened, err := NewStore(path, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	list := reopened.List()
+	if len(list) != 3 || list[0].ID != 4 || list[2].ID != 2 {
+		t.Fatalf("expected versions 4..2 newest first, got %+v", list)
+	}
+	if list[0].Content != "" {
+		t.Error("List should omit content")
+	}
+	if _, ok := reopened.Get(1); ok {
+		t.Error("version 1 should have been pruned")
+	}
+	v, ok := reopened.Get(3)
+	if !ok || v.Content != "a: 3\n" || v.SHA256 == "" {
+		t.Errorf("Get(3) = %+v, %v", v, ok)
+	}
+	if latest, _ := reopened.Latest(); latest.ID != 4 {
+		t.Errorf("Latest = %d, want 4", latest.ID)
+	}
+}
+
+func TestDiff(t *testing.T) {
+	a := "server:\n  port: 8080\ntrello:\n  rules:\n    - name: ready\n      list: Ready\n"
+	b := "server:\n  port: 8080\ntrello:\n  rules:\n    - name: ready\n      list: In Progress\n    - name: done\n"
+	got := Diff("version 1", "version 2", a, b)
+	want := `--- version 1
++++ version 2
+@@ -3,4 +3,5 @@
+ trello:
+   rules:
+     - name: ready
+-      list: Ready
++      list: In Progress
++    - name: done
+`
+	if got != want {
+		t.Errorf("Diff =\n%s\nwant\n%s", got, want)
+	}
+	if d := Diff("a", "b", a, a); d != "" {
+		t.Errorf("equal contents should diff empty, got %q", d)
+	}
+}
+
+func TestDiff_SeparateHunks(t *testing.T) {
+	var a, b []string
+	for i := 0; i < 30; i++ {
+		line := strings.Repeat("x", i+1)
+		a = append(a, line)
+		if i == 2 || i == 25 {
+			line += " changed"
+		}
+		b = append(b, line)
+	}
+	got := Diff("a", "b", strings.Join(a, "\n"), strings.Join(b, "\n"))
+	if n := strings.Count(got, "@@ -"); n != 2 {
+		t.Errorf("expected 2 hunks, got %d:\n%s", n, got)
+	}
+	if !strings.Contains(got, "@@ -1,6 +1,6 @@") || !strings.Contains(got, "@@ -23,7 +23,7 @@") {
+		t.Errorf("unexpected hunk headers:\n%s", got)
+	}
+}
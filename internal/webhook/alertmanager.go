@@ -0,0 +1,178 @@
+package webhook
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/katalabut/openclaw-relay/internal/config"
+	"github.com/katalabut/openclaw-relay/internal/events"
+	"github.com/katalabut/openclaw-relay/internal/gateway"
+	"github.com/katalabut/openclaw-relay/internal/ratelimit"
+)
+
+// AlertmanagerHandler serves Prometheus Alertmanager webhook notifications
+// (webhook_configs) at /webhook/alertmanager.
+type AlertmanagerHandler struct {
+	Config  *config.Config
+	Gateway gateway.GatewayClient
+	Limiter *ratelimit.Limiter
+}
+
+type alertmanagerAlert struct {
+	Status       string            `json:"status"`
+	Labels       map[string]string `json:"labels"`
+	Annotations  map[string]string `json:"annotations"`
+	StartsAt     time.Time         `json:"startsAt"`
+	EndsAt       time.Time         `json:"endsAt"`
+	GeneratorURL string            `json:"generatorURL"`
+	Fingerprint  string            `json:"fingerprint"`
+}
+
+type alertmanagerPayload struct {
+	Receiver          string              `json:"receiver"`
+	Status            string              `json:"status"` // firing or resolved
+	GroupKey          string              `json:"groupKey"`
+	GroupLabels       map[string]string   `json:"groupLabels"`
+	CommonLabels      map[string]string   `json:"commonLabels"`
+	CommonAnnotations map[string]string   `json:"commonAnnotations"`
+	ExternalURL       string              `json:"externalURL"`
+	Alerts            []alertmanagerAlert `json:"alerts"`
+}
+
+func (h *AlertmanagerHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// Alertmanager cannot sign payloads; it sends the token configured in its
+	// webhook_configs http_config.authorization.
+	if token := h.Config.Alertmanager.Token; token != "" && !signatureBypassed(r, "Alertmanager") &&
+		subtle.ConstantTimeCompare([]byte(r.Header.Get("Authorization")), []byte("Bearer "+token)) != 1 {
+		log.Printf("Alertmanager token verification failed")
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	events.Verified(r.Context(), h.Config.Alertmanager.Token != "")
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, "bad request", http.StatusBadRequest)
+		return
+	}
+
+	var p alertmanagerPayload
+	if err := json.Unmarshal(body, &p); err != nil {
+		log.Printf("Failed to parse Alertmanager payload: %v", err)
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	if len(p.Alerts) == 0 {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	alertName := firstNonEmpty(p.CommonLabels["alertname"], p.GroupLabels["alertname"])
+	severity := p.CommonLabels["severity"]
+	rules := h.findRules(p, alertName, severity)
+	if len(rules) == 0 {
+		log.Printf("Alertmanager: no matching rule for %s status=%s", alertName, p.Status)
+		events.Annotate(r.Context(), p.Status, "", events.OutcomeNoRule)
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	for i, rule := range rules {
+		ref := ruleRef("alertmanager", h.Config.Alertmanager.Rules, rule)
+		events.Annotate(r.Context(), p.Status, ref, "")
+
+		data := map[string]any{
+			"Status":            p.Status,
+			"Receiver":          p.Receiver,
+			"AlertName":         alertName,
+			"Severity":          severity,
+			"Summary":           firstNonEmpty(p.CommonAnnotations["summary"], p.CommonAnnotations["description"]),
+			"Count":             len(p.Alerts),
+			"Alerts":            p.Alerts,
+			"GroupLabels":       p.GroupLabels,
+			"CommonLabels":      p.CommonLabels,
+			"CommonAnnotations": p.CommonAnnotations,
+			"ExternalURL":       p.ExternalURL,
+		}
+
+		if sampledOut("Alertmanager", alertName, rule.Sample) {
+			events.Annotate(r.Context(), "", ref, events.OutcomeSampledOut)
+			continue
+		}
+		// The same group is re-sent every group_interval while it fires; the key
+		// changes when alerts join, leave or resolve.
+		key := continued(dedupKey("alertmanager", rule.DedupKey, data, "alertmanager:"+alertmanagerFingerprint(p)), i, ref)
+		if !h.Limiter.AllowWithin(key, config.RateWindow(rule.RateLimit, h.Config.Alertmanager.RateLimit)) {
+			log.Printf("Alertmanager: rate limited group %s (%s)", p.GroupKey, p.Status)
+			events.Annotate(r.Context(), "", ref, events.OutcomeRateLimited)
+			continue
+		}
+
+		log.Printf("Alertmanager: processing %s %s (%d alerts)", p.Status, alertName, len(p.Alerts))
+
+		tmplStr := rule.Action.MessageTemplate
+		if tmplStr == "" {
+			tmplStr = config.DefaultAlertmanagerMessageTemplate()
+		}
+		msg := gateway.RenderMessage("alertmanager", tmplStr, data)
+
+		timeout, delay := jobTiming(h.Config, rule.Action, 0, 0)
+
+		eventName := continued(fmt.Sprintf("alertmanager %s: %s", p.Status, alertName), i, ref)
+		createJob(r.Context(), h.Gateway, ruleJob("alertmanager", ref, rule.Action, eventName, msg, timeout, delay, body), ref)
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(`{"ok":true}`))
+}
+
+// alertmanagerFingerprint identifies a notification by group, status and the
+// status of each alert in it.
+func alertmanagerFingerprint(p alertmanagerPayload) string {
+	parts := make([]string, 0, len(p.Alerts))
+	for _, a := range p.Alerts {
+		parts = append(parts, a.Fingerprint+"="+a.Status)
+	}
+	sort.Strings(parts)
+	sum := sha256.Sum256([]byte(p.GroupKey + "\n" + p.Status + "\n" + strings.Join(parts, ",")))
+	return hex.EncodeToString(sum[:12])
+}
+
+func (h *AlertmanagerHandler) findRules(p alertmanagerPayload, alertName, severity string) []*config.AlertmanagerRule {
+	env := map[string]any{
+		"status":      p.Status,
+		"receiver":    p.Receiver,
+		"alertname":   alertName,
+		"severity":    severity,
+		"count":       len(p.Alerts),
+		"labels":      p.CommonLabels,
+		"annotations": p.CommonAnnotations,
+	}
+	var matched []*config.AlertmanagerRule
+	for i, rule := range h.Config.Alertmanager.Rules {
+		if rule.Status != "" && rule.Status != p.Status {
+			continue
+		}
+		if rule.Condition != "" && !evalCondition(rule.Condition, env) {
+			continue
+		}
+		matched = append(matched, &h.Config.Alertmanager.Rules[i])
+		if !rule.Continue {
+			return matched
+		}
+	}
+	return matched
+}
@@ -0,0 +1,129 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/katalabut/openclaw-relay/internal/config"
+	"github.com/katalabut/openclaw-relay/internal/ratelimit"
+)
+
+func newTestAlertmanagerHandler(gw *mockGateway) *AlertmanagerHandler {
+	cfg := &config.Config{
+		Alertmanager: config.AlertmanagerConfig{
+			Token: "am-token",
+			Rules: []config.AlertmanagerRule{
+				{Status: "firing", Condition: "severity == 'critical'"},
+				{Status: "resolved", Action: config.RuleAction{MessageTemplate: "resolved {{.AlertName}} ({{.Count}})"}},
+			},
+		},
+	}
+	return &AlertmanagerHandler{
+		Config:  cfg,
+		Gateway: gw,
+		Limiter: ratelimit.New(context.Background(), 5*time.Minute),
+	}
+}
+
+func alertmanagerNotification(status, severity string, fingerprints ...string) map[string]any {
+	alerts := make([]map[string]any, 0, len(fingerprints))
+	for _, fp := range fingerprints {
+		alerts = append(alerts, map[string]any{
+			"status":      status,
+			"labels":      map[string]string{"alertname": "HighErrorRate", "instance": fp},
+			"annotations": map[string]string{"summary": "5xx above 5%"},
+			"fingerprint": fp,
+		})
+	}
+	return map[string]any{
+		"receiver":          "relay",
+		"status":            status,
+		"groupKey":          `{}:{alertname="HighErrorRate"}`,
+		"groupLabels":       map[string]string{"alertname": "HighErrorRate"},
+		"commonLabels":      map[string]string{"alertname": "HighErrorRate", "severity": severity},
+		"commonAnnotations": map[string]string{"summary": "5xx above 5%"},
+		"alerts":            alerts,
+	}
+}
+
+func postAlertmanager(h *AlertmanagerHandler, token string, payload map[string]any) *httptest.ResponseRecorder {
+	body, _ := json.Marshal(payload)
+	req := httptest.NewRequest("POST", "/webhook/alertmanager", bytes.NewReader(body))
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestServeHTTP_Alertmanager_Firing(t *testing.T) {
+	gw := &mockGateway{}
+	h := newTestAlertmanagerHandler(gw)
+
+	rec := postAlertmanager(h, "am-token", alertmanagerNotification("firing", "critical", "a1", "a2"))
+	if rec.Code != http.StatusOK || len(gw.calls) != 1 {
+		t.Fatalf("expected 1 job, got %d (%d)", len(gw.calls), rec.Code)
+	}
+	msg := gw.calls[0].Message
+	for _, want := range []string{"Alert: HighErrorRate", "Severity: critical", "Summary: 5xx above 5%", "Alerts: 2", "instance=a1"} {
+		if !strings.Contains(msg, want) {
+			t.Errorf("default template missing %q:\n%s", want, msg)
+		}
+	}
+	if gw.calls[0].Name != "alertmanager firing: HighErrorRate" {
+		t.Errorf("unexpected job name %q", gw.calls[0].Name)
+	}
+	if got := gw.calls[0].Tags["rule"]; got != "alertmanager.rules[0]" {
+		t.Errorf("rule tag = %q", got)
+	}
+
+	// Re-sent group is collapsed; a group that changed is not
+	postAlertmanager(h, "am-token", alertmanagerNotification("firing", "critical", "a2", "a1"))
+	if len(gw.calls) != 1 {
+		t.Errorf("expected repeat notification to be deduped, got %d jobs", len(gw.calls))
+	}
+	postAlertmanager(h, "am-token", alertmanagerNotification("firing", "critical", "a1", "a2", "a3"))
+	if len(gw.calls) != 2 {
+		t.Errorf("expected changed group to dispatch, got %d jobs", len(gw.calls))
+	}
+
+	postAlertmanager(h, "am-token", alertmanagerNotification("resolved", "critical", "a1"))
+	if len(gw.calls) != 3 || gw.calls[2].Message != "resolved HighErrorRate (1)" {
+		t.Errorf("expected resolved rule, got %+v", gw.calls)
+	}
+}
+
+func TestServeHTTP_Alertmanager_NoMatch(t *testing.T) {
+	gw := &mockGateway{}
+	h := newTestAlertmanagerHandler(gw)
+
+	postAlertmanager(h, "am-token", alertmanagerNotification("firing", "warning", "a1"))
+	postAlertmanager(h, "am-token", alertmanagerNotification("firing", "critical"))
+	if len(gw.calls) != 0 {
+		t.Errorf("expected no jobs, got %d", len(gw.calls))
+	}
+}
+
+func TestServeHTTP_Alertmanager_Auth(t *testing.T) {
+	h := newTestAlertmanagerHandler(&mockGateway{})
+
+	for _, token := range []string{"", "wrong"} {
+		rec := postAlertmanager(h, token, alertmanagerNotification("firing", "critical", "a1"))
+		if rec.Code != http.StatusUnauthorized {
+			t.Errorf("token %q: expected 401, got %d", token, rec.Code)
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest("GET", "/webhook/alertmanager", nil))
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("expected 405, got %d", rec.Code)
+	}
+}
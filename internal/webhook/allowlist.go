@@ -0,0 +1,143 @@
+package webhook
+
+import (
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// IPAllowlist restricts webhook endpoints to the CIDR ranges their providers
+// send from. Sources without ranges of their own use the default ranges;
+// with neither, the source is open.
+type IPAllowlist struct {
+	mu       sync.RWMutex
+	defaults []*net.IPNet
+	sources  map[string][]*net.IPNet
+	proxies  []*net.IPNet
+}
+
+// NewIPAllowlist parses the default and per-source ranges. Requests arriving
+// from a trusted proxy are checked against the client address in
+// X-Forwarded-For instead.
+func NewIPAllowlist(defaults []string, sources map[string][]string, trustedProxies []string) (*IPAllowlist, error) {
+	a := &IPAllowlist{sources: map[string][]*net.IPNet{}}
+	var err error
+	if a.defaults, err = ParseCIDRs(defaults); err != nil {
+		return nil, err
+	}
+	if a.proxies, err = ParseCIDRs(trustedProxies); err != nil {
+		return nil, err
+	}
+	for source, ranges := range sources {
+		if len(ranges) == 0 {
+			continue
+		}
+		nets, err := ParseCIDRs(ranges)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", source, err)
+		}
+		a.sources[source] = nets
+	}
+	return a, nil
+}
+
+// ParseCIDRs parses CIDR ranges; a bare address is taken as a single host.
+func ParseCIDRs(ranges []string) ([]*net.IPNet, error) {
+	nets := make([]*net.IPNet, 0, len(ranges))
+	for _, r := range ranges {
+		r = strings.TrimSpace(r)
+		if !strings.Contains(r, "/") {
+			ip := net.ParseIP(r)
+			if ip == nil {
+				return nil, fmt.Errorf("invalid IP range %q", r)
+			}
+			bits := 32
+			if ip.To4() == nil {
+				bits = 128
+			}
+			r = fmt.Sprintf("%s/%d", r, bits)
+		}
+		_, n, err := net.ParseCIDR(r)
+		if err != nil {
+			return nil, fmt.Errorf("invalid IP range %q", r)
+		}
+		nets = append(nets, n)
+	}
+	return nets, nil
+}
+
+// SetRanges replaces the ranges of source, e.g. with GitHub's published hook
+// ranges. Empty nets rejects every request for source until ranges are set.
+func (a *IPAllowlist) SetRanges(source string, nets []*net.IPNet) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.sources[source] = nets
+}
+
+// Allowed reports whether ip may deliver source's webhooks.
+func (a *IPAllowlist) Allowed(source string, ip net.IP) bool {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	nets, ok := a.sources[source]
+	if !ok {
+		if len(a.defaults) == 0 {
+			return true
+		}
+		nets = a.defaults
+	}
+	return ip != nil && containsIP(nets, ip)
+}
+
+// Wrap answers 403 to requests for source from outside its ranges.
+func (a *IPAllowlist) Wrap(source string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ip := a.clientIP(r)
+		if !a.Allowed(source, ip) {
+			log.Printf("IP allowlist: rejected %s webhook from %s", source, ip)
+			http.Error(w, "forbidden", http.StatusForbidden)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// clientIP returns the peer address or, when the peer is a trusted proxy, the
+// rightmost X-Forwarded-For entry that is not one. Entries left of that are
+// client-supplied and never trusted.
+func (a *IPAllowlist) clientIP(r *http.Request) net.IP {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	ip := net.ParseIP(host)
+	if ip == nil || !containsIP(a.proxies, ip) {
+		return ip
+	}
+	xff := r.Header.Values("X-Forwarded-For")
+	if len(xff) == 0 {
+		return ip
+	}
+	hops := strings.Split(strings.Join(xff, ","), ",")
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop := net.ParseIP(strings.TrimSpace(hops[i]))
+		if hop == nil {
+			return nil
+		}
+		if !containsIP(a.proxies, hop) {
+			return hop
+		}
+	}
+	return ip
+}
+
+func containsIP(nets []*net.IPNet, ip net.IP) bool {
+	for _, n := range nets {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,87 @@
+package webhook
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestIPAllowlist_Wrap(t *testing.T) {
+	a, err := NewIPAllowlist(
+		[]string{"10.0.0.0/8"},
+		map[string][]string{"github": {"192.30.252.0/22", "2a0a:a440::/29"}, "slack": {}},
+		[]string{"172.16.0.1"},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusOK) })
+
+	tests := []struct {
+		name   string
+		source string
+		remote string
+		xff    string
+		want   int
+	}{
+		{"github range", "github", "192.30.252.10:443", "", http.StatusOK},
+		{"github ipv6", "github", "[2a0a:a440::1]:443", "", http.StatusOK},
+		{"github outside", "github", "10.1.2.3:443", "", http.StatusForbidden},
+		{"default ranges", "trello", "10.1.2.3:443", "", http.StatusOK},
+		{"default outside", "trello", "8.8.8.8:443", "", http.StatusForbidden},
+		{"empty source list uses default", "slack", "8.8.8.8:443", "", http.StatusForbidden},
+		{"via trusted proxy", "github", "172.16.0.1:5000", "192.30.252.10", http.StatusOK},
+		{"spoofed hop left of client", "github", "172.16.0.1:5000", "192.30.252.10, 8.8.8.8", http.StatusForbidden},
+		{"untrusted peer ignores header", "github", "8.8.8.8:5000", "192.30.252.10", http.StatusForbidden},
+		{"garbage header", "github", "172.16.0.1:5000", "not-an-ip", http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest("POST", "/webhook/"+tt.source, nil)
+		req.RemoteAddr = tt.remote
+		if tt.xff != "" {
+			req.Header.Set("X-Forwarded-For", tt.xff)
+		}
+		rec := httptest.NewRecorder()
+		a.Wrap(tt.source, ok).ServeHTTP(rec, req)
+		if rec.Code != tt.want {
+			t.Errorf("%s: expected %d, got %d", tt.name, tt.want, rec.Code)
+		}
+	}
+}
+
+func TestIPAllowlist_SetRanges(t *testing.T) {
+	a, err := NewIPAllowlist(nil, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ip := net.ParseIP("140.82.115.1")
+	if !a.Allowed("github", ip) {
+		t.Error("sources without ranges should be open")
+	}
+	a.SetRanges("github", nil)
+	if a.Allowed("github", ip) {
+		t.Error("expected github closed until ranges are set")
+	}
+	nets, _ := ParseCIDRs([]string{"140.82.112.0/20"})
+	a.SetRanges("github", nets)
+	if !a.Allowed("github", ip) {
+		t.Error("expected fetched ranges to allow the address")
+	}
+}
+
+func TestParseCIDRs(t *testing.T) {
+	nets, err := ParseCIDRs([]string{"192.168.1.5", "::1", " 10.0.0.0/8 "})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(nets) != 3 || nets[0].String() != "192.168.1.5/32" || nets[1].String() != "::1/128" {
+		t.Errorf("unexpected ranges %v", nets)
+	}
+	if _, err := ParseCIDRs([]string{"10.0.0.0/33"}); err == nil {
+		t.Error("expected an invalid range to fail")
+	}
+	if _, err := NewIPAllowlist(nil, map[string][]string{"github": {"nope"}}, nil); err == nil {
+		t.Error("expected an invalid source range to fail")
+	}
+}
@@ -0,0 +1,325 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/katalabut/openclaw-relay/internal/asana"
+	"github.com/katalabut/openclaw-relay/internal/config"
+	"github.com/katalabut/openclaw-relay/internal/events"
+	"github.com/katalabut/openclaw-relay/internal/gateway"
+	"github.com/katalabut/openclaw-relay/internal/ratelimit"
+	"github.com/katalabut/openclaw-relay/internal/state"
+)
+
+// asanaSecretNS is the namespace of the hook secrets captured from handshakes,
+// keyed by webhook name.
+const asanaSecretNS = "asana-hooks"
+
+// AsanaHandler serves Asana webhooks at /webhook/asana and /webhook/asana/<name>.
+// Each path is one webhook with its own secret: Asana sends it once in the
+// X-Hook-Secret handshake and signs every later delivery with it.
+type AsanaHandler struct {
+	Config  *config.Config
+	Gateway gateway.GatewayClient
+	Limiter *ratelimit.Limiter
+	Client  *asana.Client // reads names and comment text; nil without asana.api_token
+	Secrets *state.Store  // hook secrets; keep it out of the shared /api/state store
+}
+
+type asanaResource struct {
+	GID             string `json:"gid"`
+	ResourceType    string `json:"resource_type"`
+	ResourceSubtype string `json:"resource_subtype"`
+}
+
+type asanaEvent struct {
+	Action    string         `json:"action"`
+	CreatedAt time.Time      `json:"created_at"`
+	User      *asanaResource `json:"user"`
+	Resource  asanaResource  `json:"resource"`
+	Parent    *asanaResource `json:"parent"`
+}
+
+// asanaTask is what Asana rules and templates see of one event.
+type asanaTask struct {
+	Event     string
+	TaskID    string
+	TaskName  string
+	TaskURL   string
+	SectionID string
+	Section   string // asana.sections name, else the name from the API
+	CommentID string
+	Comment   string
+	UserID    string
+	UserName  string
+}
+
+func (h *AsanaHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	name, ok := asanaHookName(r.URL.Path)
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+
+	if secret := r.Header.Get("X-Hook-Secret"); secret != "" {
+		h.acceptHookSecret(w, r, name, secret)
+		return
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, "bad request", http.StatusBadRequest)
+		return
+	}
+
+	if !signatureBypassed(r, "Asana") {
+		secret := h.secret(name)
+		if secret == "" {
+			log.Printf("Asana: delivery for webhook %q before its handshake; re-create the webhook", name)
+			http.Error(w, "forbidden", http.StatusForbidden)
+			return
+		}
+		if !VerifyHMACSHA256(body, r.Header.Get("X-Hook-Signature"), "", secret) {
+			log.Printf("Asana signature verification failed")
+			http.Error(w, "forbidden", http.StatusForbidden)
+			return
+		}
+	}
+	events.Verified(r.Context(), true)
+
+	var payload struct {
+		Events []asanaEvent `json:"events"`
+	}
+	if err := json.Unmarshal(body, &payload); err != nil {
+		log.Printf("Failed to parse Asana payload: %v", err)
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	// Asana batches events, and sends an empty batch as a heartbeat
+	for _, ev := range payload.Events {
+		h.handleEvent(r.Context(), ev)
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(`{"ok":true}`))
+}
+
+// asanaHookName maps /webhook/asana to "default" and /webhook/asana/<name> to name.
+func asanaHookName(path string) (string, bool) {
+	rest, ok := strings.CutPrefix(path, "/webhook/asana")
+	if !ok {
+		return "", false
+	}
+	rest = strings.Trim(rest, "/")
+	if rest == "" {
+		return "default", true
+	}
+	return rest, !strings.Contains(rest, "/")
+}
+
+// acceptHookSecret answers webhook creation by echoing X-Hook-Secret and
+// keeps the secret. It is trust on first use: once a webhook name has a
+// secret, another handshake for it is refused, so nobody can swap in their
+// own secret.
+func (h *AsanaHandler) acceptHookSecret(w http.ResponseWriter, r *http.Request, name, secret string) {
+	if h.Secrets == nil {
+		markHandshake(r, HandshakeHookSecret, events.OutcomeErrored)
+		http.Error(w, "hook secrets unavailable", http.StatusServiceUnavailable)
+		return
+	}
+	if h.secret(name) != "" {
+		log.Printf("Asana: refusing handshake for webhook %q, which already has a secret; use a new /webhook/asana/<name> path", name)
+		markHandshake(r, HandshakeHookSecret, events.OutcomeRejected)
+		http.Error(w, "forbidden", http.StatusForbidden)
+		return
+	}
+	value, _ := json.Marshal(secret)
+	if _, err := h.Secrets.Put(asanaSecretNS, name, value, 0); err != nil {
+		log.Printf("Asana: failed to store hook secret for %q: %v", name, err)
+		markHandshake(r, HandshakeHookSecret, events.OutcomeErrored)
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+	handshake(w, r, "Asana", HandshakeHookSecret, func(w http.ResponseWriter) {
+		w.Header().Set("X-Hook-Secret", secret)
+		w.WriteHeader(http.StatusOK)
+	})
+}
+
+func (h *AsanaHandler) secret(name string) string {
+	if h.Secrets == nil {
+		return ""
+	}
+	e, ok := h.Secrets.Get(asanaSecretNS, name)
+	if !ok {
+		return ""
+	}
+	var secret string
+	json.Unmarshal(e.Value, &secret)
+	return secret
+}
+
+// asanaEventType classifies an event: a task added to a section is a move,
+// a comment_added story on a task is a comment.
+func asanaEventType(ev asanaEvent) string {
+	if ev.Action != "added" || ev.Parent == nil {
+		return ""
+	}
+	switch {
+	case ev.Resource.ResourceType == "task" && ev.Parent.ResourceType == "section":
+		return "section_moved"
+	case ev.Resource.ResourceType == "story" && ev.Resource.ResourceSubtype == "comment_added" && ev.Parent.ResourceType == "task":
+		return "comment_added"
+	}
+	return ""
+}
+
+func (h *AsanaHandler) handleEvent(ctx context.Context, ev asanaEvent) {
+	eventType := asanaEventType(ev)
+	if eventType == "" {
+		return
+	}
+	userID := ""
+	if ev.User != nil {
+		userID = ev.User.GID
+	}
+	if containsString(h.Config.Asana.IgnoreUsers, userID) {
+		log.Printf("Asana: ignoring %s by user %s", eventType, userID)
+		return
+	}
+
+	task := h.loadTask(ctx, eventType, ev)
+	rules := h.findRules(task)
+	if len(rules) == 0 {
+		log.Printf("Asana: no matching rule for %s on task %s", eventType, task.TaskID)
+		events.Annotate(ctx, eventType, "", events.OutcomeNoRule)
+		return
+	}
+	for i, rule := range rules {
+		ref := ruleRef("asana", h.Config.Asana.Rules, rule)
+		events.Annotate(ctx, eventType, ref, "")
+
+		data := map[string]any{
+			"Event":     task.Event,
+			"TaskID":    task.TaskID,
+			"TaskName":  task.TaskName,
+			"TaskURL":   task.TaskURL,
+			"SectionID": task.SectionID,
+			"Section":   task.Section,
+			"CommentID": task.CommentID,
+			"Comment":   task.Comment,
+			"UserID":    task.UserID,
+			"UserName":  task.UserName,
+		}
+		if sampledOut("Asana", eventType, rule.Sample) {
+			events.Annotate(ctx, "", ref, events.OutcomeSampledOut)
+			continue
+		}
+		key := continued(dedupKey("asana", rule.DedupKey, data, fmt.Sprintf("asana:%s:%s:%s", ev.Resource.GID, ev.Parent.GID, eventType)), i, ref)
+		if !h.Limiter.AllowWithin(key, config.RateWindow(rule.RateLimit, h.Config.Asana.RateLimit)) {
+			log.Printf("Asana: rate limited %s", key)
+			events.Annotate(ctx, "", ref, events.OutcomeRateLimited)
+			continue
+		}
+
+		log.Printf("Asana: processing %s for task %s", eventType, task.TaskID)
+
+		tmplStr := rule.Action.MessageTemplate
+		if tmplStr == "" {
+			tmplStr = config.DefaultAsanaMessageTemplate()
+		}
+		msg := gateway.RenderMessage("asana", tmplStr, data)
+
+		timeout, delay := jobTiming(h.Config, rule.Action, 0, 0)
+
+		eventName := continued(fmt.Sprintf("asana %s: %s", eventType, firstNonEmpty(task.TaskName, task.TaskID)), i, ref)
+		payload, _ := json.Marshal(ev) // the one event of the batch
+		createJob(ctx, h.Gateway, ruleJob("asana", ref, rule.Action, eventName, msg, timeout, delay, payload), ref)
+	}
+
+}
+
+// loadTask fills in names and comment text from the API when a client is
+// configured; without one, templates get GIDs and a task link.
+func (h *AsanaHandler) loadTask(ctx context.Context, eventType string, ev asanaEvent) asanaTask {
+	t := asanaTask{Event: eventType}
+	if ev.User != nil {
+		t.UserID = ev.User.GID
+	}
+	switch eventType {
+	case "section_moved":
+		t.TaskID, t.SectionID = ev.Resource.GID, ev.Parent.GID
+		t.Section = h.Config.AsanaSectionName(t.SectionID)
+	case "comment_added":
+		t.TaskID, t.CommentID = ev.Parent.GID, ev.Resource.GID
+	}
+	t.TaskURL = "https://app.asana.com/0/0/" + t.TaskID
+
+	if h.Client == nil {
+		return t
+	}
+	if task, err := h.Client.GetTask(ctx, t.TaskID); err != nil {
+		log.Printf("Asana: failed to read task %s: %v", t.TaskID, err)
+	} else {
+		t.TaskName = task.Name
+		t.TaskURL = firstNonEmpty(task.PermalinkURL, t.TaskURL)
+	}
+	if t.SectionID != "" && t.Section == "" {
+		if section, err := h.Client.GetSection(ctx, t.SectionID); err != nil {
+			log.Printf("Asana: failed to read section %s: %v", t.SectionID, err)
+		} else {
+			t.Section = section.Name
+		}
+	}
+	if t.CommentID != "" {
+		if story, err := h.Client.GetStory(ctx, t.CommentID); err != nil {
+			log.Printf("Asana: failed to read comment %s: %v", t.CommentID, err)
+		} else {
+			t.Comment = story.Text
+			if story.CreatedBy != nil {
+				t.UserName = story.CreatedBy.Name
+			}
+		}
+	}
+	return t
+}
+
+func (h *AsanaHandler) findRules(t asanaTask) []*config.AsanaRule {
+	env := map[string]any{
+		"event":   t.Event,
+		"section": t.Section,
+		"task":    t.TaskName,
+		"comment": t.Comment,
+		"user":    t.UserID,
+	}
+	var matched []*config.AsanaRule
+	for i, rule := range h.Config.Asana.Rules {
+		if rule.Event != t.Event {
+			continue
+		}
+		if rule.Section != "" && rule.Section != t.Section && rule.Section != t.SectionID {
+			continue
+		}
+		if rule.Condition != "" && !evalCondition(rule.Condition, env) {
+			continue
+		}
+		matched = append(matched, &h.Config.Asana.Rules[i])
+		if !rule.Continue {
+			return matched
+		}
+	}
+	return matched
+}
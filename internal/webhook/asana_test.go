@@ -0,0 +1,187 @@
+package webhook
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/katalabut/openclaw-relay/internal/asana"
+	"github.com/katalabut/openclaw-relay/internal/config"
+	"github.com/katalabut/openclaw-relay/internal/ratelimit"
+	"github.com/katalabut/openclaw-relay/internal/state"
+)
+
+func fakeAsanaAPI(w http.ResponseWriter, r *http.Request) {
+	switch r.URL.Path {
+	case "/tasks/T1":
+		w.Write([]byte(`{"data":{"gid":"T1","name":"Fix login","permalink_url":"https://app.asana.com/0/P1/T1"}}`))
+	case "/sections/S9":
+		w.Write([]byte(`{"data":{"gid":"S9","name":"Blocked"}}`))
+	case "/stories/C1":
+		w.Write([]byte(`{"data":{"gid":"C1","text":"please take a look","created_by":{"gid":"U1","name":"Alice"}}}`))
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func newTestAsanaHandler(t *testing.T, gw *mockGateway, withAPI bool) *AsanaHandler {
+	t.Helper()
+	secrets, err := state.NewStore("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := &AsanaHandler{
+		Config: &config.Config{Asana: config.AsanaConfig{
+			Sections:    map[string]string{"review": "S1"},
+			IgnoreUsers: []string{"U_BOT"},
+			Rules: []config.AsanaRule{
+				{Event: "section_moved", Section: "review", Action: config.RuleAction{MessageTemplate: "review {{.TaskID}} {{.TaskName}} {{.TaskURL}}"}},
+				{Event: "section_moved", Condition: "section == 'Blocked'", Action: config.RuleAction{MessageTemplate: "blocked {{.TaskName}}"}},
+				{Event: "comment_added", Action: config.RuleAction{MessageTemplate: "{{.UserName}}: {{.Comment}}"}},
+			},
+		}},
+		Gateway: gw,
+		Limiter: ratelimit.New(context.Background(), 5*time.Minute),
+		Secrets: secrets,
+	}
+	if withAPI {
+		srv := httptest.NewServer(http.HandlerFunc(fakeAsanaAPI))
+		t.Cleanup(srv.Close)
+		h.Client = asana.NewClient("pat", srv.URL)
+	}
+	return h
+}
+
+func signAsana(body, secret string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(body))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func postAsana(h http.Handler, path, body, sig string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest("POST", path, strings.NewReader(body))
+	if sig != "" {
+		req.Header.Set("X-Hook-Signature", sig)
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return rec
+}
+
+func asanaHandshake(h http.Handler, path, secret string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest("POST", path, nil)
+	req.Header.Set("X-Hook-Secret", secret)
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestAsana_Handshake(t *testing.T) {
+	h := newTestAsanaHandler(t, &mockGateway{}, false)
+
+	// Deliveries before a handshake cannot be verified
+	if rec := postAsana(h, "/webhook/asana", `{"events":[]}`, signAsana(`{"events":[]}`, "s1")); rec.Code != http.StatusForbidden {
+		t.Errorf("expected 403 before handshake, got %d", rec.Code)
+	}
+
+	rec := asanaHandshake(h, "/webhook/asana", "s1")
+	if rec.Code != http.StatusOK || rec.Header().Get("X-Hook-Secret") != "s1" {
+		t.Fatalf("expected echoed secret, got %d %q", rec.Code, rec.Header().Get("X-Hook-Secret"))
+	}
+	if rec := asanaHandshake(h, "/webhook/asana", "attacker"); rec.Code != http.StatusForbidden {
+		t.Errorf("expected second handshake refused, got %d", rec.Code)
+	}
+	// Another webhook name gets its own secret
+	if rec := asanaHandshake(h, "/webhook/asana/design", "s2"); rec.Code != http.StatusOK {
+		t.Errorf("expected handshake for a new name, got %d", rec.Code)
+	}
+
+	body := `{"events":[]}`
+	if rec := postAsana(h, "/webhook/asana", body, signAsana(body, "s1")); rec.Code != http.StatusOK {
+		t.Errorf("expected heartbeat accepted, got %d", rec.Code)
+	}
+	if rec := postAsana(h, "/webhook/asana/design", body, signAsana(body, "s1")); rec.Code != http.StatusForbidden {
+		t.Errorf("expected the other webhook's secret rejected, got %d", rec.Code)
+	}
+	if rec := postAsana(h, "/webhook/asana", body, "bad"); rec.Code != http.StatusForbidden {
+		t.Errorf("expected bad signature rejected, got %d", rec.Code)
+	}
+	if rec := postAsana(h, "/webhook/asana/a/b", body, ""); rec.Code != http.StatusNotFound {
+		t.Errorf("expected nested path 404, got %d", rec.Code)
+	}
+}
+
+const asanaMoveEvents = `{"events":[
+  {"action":"added","user":{"gid":"U1","resource_type":"user"},"resource":{"gid":"T1","resource_type":"task"},"parent":{"gid":"S1","resource_type":"section"}},
+  {"action":"removed","user":{"gid":"U1","resource_type":"user"},"resource":{"gid":"T1","resource_type":"task"},"parent":{"gid":"S0","resource_type":"section"}},
+  {"action":"changed","user":{"gid":"U1","resource_type":"user"},"resource":{"gid":"T1","resource_type":"task"}}
+]}`
+
+func TestAsana_SectionMoved(t *testing.T) {
+	gw := &mockGateway{}
+	h := newTestAsanaHandler(t, gw, false)
+	asanaHandshake(h, "/webhook/asana", "s1")
+
+	rec := postAsana(h, "/webhook/asana", asanaMoveEvents, signAsana(asanaMoveEvents, "s1"))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rec.Code)
+	}
+	if len(gw.calls) != 1 {
+		t.Fatalf("expected 1 job, got %d", len(gw.calls))
+	}
+	if got := gw.calls[0].Message; got != "review T1  https://app.asana.com/0/0/T1" {
+		t.Errorf("unexpected message %q", got)
+	}
+
+	// Asana retries deliveries; the same move collapses in the rate limiter
+	postAsana(h, "/webhook/asana", asanaMoveEvents, signAsana(asanaMoveEvents, "s1"))
+	if len(gw.calls) != 1 {
+		t.Errorf("expected duplicate move deduplicated, got %d calls", len(gw.calls))
+	}
+}
+
+func TestAsana_WithAPI(t *testing.T) {
+	gw := &mockGateway{}
+	h := newTestAsanaHandler(t, gw, true)
+	asanaHandshake(h, "/webhook/asana", "s1")
+
+	body := `{"events":[
+	  {"action":"added","user":{"gid":"U1"},"resource":{"gid":"T1","resource_type":"task"},"parent":{"gid":"S9","resource_type":"section"}},
+	  {"action":"added","user":{"gid":"U1"},"resource":{"gid":"C1","resource_type":"story","resource_subtype":"comment_added"},"parent":{"gid":"T1","resource_type":"task"}},
+	  {"action":"added","user":{"gid":"U_BOT"},"resource":{"gid":"C2","resource_type":"story","resource_subtype":"comment_added"},"parent":{"gid":"T1","resource_type":"task"}},
+	  {"action":"added","user":{"gid":"U1"},"resource":{"gid":"C3","resource_type":"story","resource_subtype":"assigned"},"parent":{"gid":"T1","resource_type":"task"}}
+	]}`
+	postAsana(h, "/webhook/asana", body, signAsana(body, "s1"))
+	if len(gw.calls) != 2 {
+		t.Fatalf("expected 2 jobs, got %d: %+v", len(gw.calls), gw.calls)
+	}
+	if gw.calls[0].Message != "blocked Fix login" {
+		t.Errorf("unexpected move message %q", gw.calls[0].Message)
+	}
+	if gw.calls[1].Message != "Alice: please take a look" || gw.calls[1].Name != "asana comment_added: Fix login" {
+		t.Errorf("unexpected comment job %+v", gw.calls[1])
+	}
+}
+
+func TestAsanaEventType(t *testing.T) {
+	tests := []struct {
+		ev   asanaEvent
+		want string
+	}{
+		{asanaEvent{Action: "added", Resource: asanaResource{ResourceType: "task"}, Parent: &asanaResource{ResourceType: "section"}}, "section_moved"},
+		{asanaEvent{Action: "added", Resource: asanaResource{ResourceType: "task"}, Parent: &asanaResource{ResourceType: "project"}}, ""},
+		{asanaEvent{Action: "added", Resource: asanaResource{ResourceType: "story", ResourceSubtype: "comment_added"}, Parent: &asanaResource{ResourceType: "task"}}, "comment_added"},
+		{asanaEvent{Action: "changed", Resource: asanaResource{ResourceType: "task"}}, ""},
+	}
+	for i, tt := range tests {
+		if got := asanaEventType(tt.ev); got != tt.want {
+			t.Errorf("%d: got %q, want %q", i, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,125 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log"
+	"net/http/httptest"
+	"os"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/katalabut/openclaw-relay/internal/config"
+	"github.com/katalabut/openclaw-relay/internal/gateway"
+	"github.com/katalabut/openclaw-relay/internal/ratelimit"
+)
+
+// Benchmarks for handler throughput and rule evaluation. See docs/benchmarking.md.
+//
+//	go test ./internal/webhook/ -run '^$' -bench . -benchmem
+
+func quietLogs(b *testing.B) {
+	log.SetOutput(io.Discard)
+	b.Cleanup(func() { log.SetOutput(os.Stderr) })
+}
+
+func benchTrelloConfig() *config.Config {
+	rules := make([]config.TrelloRule, 0, 10)
+	for i := 0; i < 9; i++ {
+		rules = append(rules, config.TrelloRule{Event: "card_moved", Condition: fmt.Sprintf("list == 'other-%d'", i)})
+	}
+	rules = append(rules, config.TrelloRule{
+		Event:     "card_moved",
+		Condition: "list == 'review' || list == 'ready'",
+		Action:    config.RuleAction{MessageTemplate: "Card {{.CardName}} moved from {{.ListBeforeName}} to {{.ListAfterName}}"},
+	})
+	return &config.Config{Trello: config.TrelloConfig{
+		Secret: "bench-secret",
+		Lists:  map[string]string{"ready": "list-ready-id"},
+		Rules:  rules,
+	}}
+}
+
+// BenchmarkTrelloHandler measures the full path: signature check, parse, rate limit, rule match (last of 10), render, dispatch.
+func BenchmarkTrelloHandler(b *testing.B) {
+	quietLogs(b)
+	cfg := benchTrelloConfig()
+	sink := gateway.NewSink()
+	h := &TrelloHandler{Config: cfg, Gateway: sink, Limiter: ratelimit.New(context.Background(), 5*time.Minute)}
+
+	var n atomic.Int64
+	b.ReportAllocs()
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			body := []byte(fmt.Sprintf(`{"action":{"type":"updateCard","data":{"card":{"id":"c%d","name":"Card"},"listAfter":{"id":"list-ready-id","name":"Ready"},"listBefore":{"id":"x","name":"Backlog"}}}}`, n.Add(1)))
+			mac := hmac.New(sha1.New, []byte(cfg.Trello.Secret))
+			mac.Write(body)
+			mac.Write([]byte("https://relay.local/webhook/trello"))
+			req := httptest.NewRequest("POST", "https://relay.local/webhook/trello", bytes.NewReader(body))
+			req.Header.Set("X-Trello-Webhook", base64.StdEncoding.EncodeToString(mac.Sum(nil)))
+			h.ServeHTTP(httptest.NewRecorder(), req)
+		}
+	})
+	b.StopTimer()
+	if sink.Count() != int64(b.N) {
+		b.Fatalf("expected %d jobs, got %d", b.N, sink.Count())
+	}
+}
+
+// BenchmarkGitHubHandler measures a signed check_run delivery through to dispatch.
+func BenchmarkGitHubHandler(b *testing.B) {
+	quietLogs(b)
+	cfg := &config.Config{GitHub: config.GitHubConfig{Secret: "bench-secret"}}
+	sink := gateway.NewSink()
+	h := &GitHubHandler{Config: cfg, Gateway: sink, Limiter: ratelimit.New(context.Background(), 5*time.Minute)}
+
+	var n atomic.Int64
+	b.ReportAllocs()
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			body := []byte(fmt.Sprintf(`{"action":"completed","repository":{"full_name":"acme/api"},"check_run":{"conclusion":"failure","pull_requests":[{"number":%d}]}}`, n.Add(1)))
+			mac := hmac.New(sha256.New, []byte(cfg.GitHub.Secret))
+			mac.Write(body)
+			req := httptest.NewRequest("POST", "/webhook/github", bytes.NewReader(body))
+			req.Header.Set("X-GitHub-Event", "check_run")
+			req.Header.Set("X-Hub-Signature-256", "sha256="+hex.EncodeToString(mac.Sum(nil)))
+			h.ServeHTTP(httptest.NewRecorder(), req)
+		}
+	})
+}
+
+// BenchmarkGenericHandler measures field extraction, condition evaluation and templating for config-driven webhooks.
+func BenchmarkGenericHandler(b *testing.B) {
+	quietLogs(b)
+	sink := gateway.NewSink()
+	h := newTestGenericHandler(nil)
+	h.Gateway = sink
+	h.Config.GenericWebhooks[0].DedupField = ""
+	body := sentryPayload("1", "fatal")
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("POST", "/webhook/custom/sentry", bytes.NewReader(body)))
+	}
+}
+
+// BenchmarkEvalGenericCondition isolates the rule-engine condition evaluator.
+func BenchmarkEvalGenericCondition(b *testing.B) {
+	fields := map[string]string{"level": "error", "env": "prod", "team": "payments", "muted": "false"}
+	cond := "level == 'warning' || level == 'error' && env == 'prod' && !muted || team == 'infra'"
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		evalGenericCondition(cond, fields)
+	}
+}
@@ -0,0 +1,315 @@
+package webhook
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"path"
+	"strconv"
+
+	"github.com/katalabut/openclaw-relay/internal/config"
+	"github.com/katalabut/openclaw-relay/internal/events"
+	"github.com/katalabut/openclaw-relay/internal/gateway"
+	"github.com/katalabut/openclaw-relay/internal/ratelimit"
+)
+
+// BitbucketHandler serves Bitbucket Cloud webhooks at /webhook/bitbucket:
+// pull requests created and approved, and pushes.
+type BitbucketHandler struct {
+	Config  *config.Config
+	Gateway gateway.GatewayClient
+	Limiter *ratelimit.Limiter
+}
+
+type bitbucketUser struct {
+	UUID        string `json:"uuid"`
+	Nickname    string `json:"nickname"`
+	DisplayName string `json:"display_name"`
+}
+
+type bitbucketLinks struct {
+	HTML struct {
+		Href string `json:"href"`
+	} `json:"html"`
+}
+
+type bitbucketCommit struct {
+	Hash    string `json:"hash"`
+	Message string `json:"message"`
+	Author  struct {
+		Raw string `json:"raw"`
+	} `json:"author"`
+}
+
+type bitbucketPayload struct {
+	Actor      bitbucketUser `json:"actor"`
+	Repository struct {
+		FullName string         `json:"full_name"`
+		Links    bitbucketLinks `json:"links"`
+	} `json:"repository"`
+	PullRequest *struct {
+		ID          int            `json:"id"`
+		Title       string         `json:"title"`
+		Description string         `json:"description"`
+		Author      bitbucketUser  `json:"author"`
+		Links       bitbucketLinks `json:"links"`
+		Source      struct {
+			Branch struct {
+				Name string `json:"name"`
+			} `json:"branch"`
+		} `json:"source"`
+		Destination struct {
+			Branch struct {
+				Name string `json:"name"`
+			} `json:"branch"`
+		} `json:"destination"`
+	} `json:"pullrequest"`
+	Approval *struct {
+		User bitbucketUser `json:"user"`
+	} `json:"approval"`
+	Push *struct {
+		Changes []struct {
+			New *struct {
+				Type   string          `json:"type"` // branch or tag
+				Name   string          `json:"name"`
+				Target bitbucketCommit `json:"target"`
+			} `json:"new"`
+			Commits []bitbucketCommit `json:"commits"`
+			Links   struct {
+				HTML struct {
+					Href string `json:"href"`
+				} `json:"html"`
+			} `json:"links"`
+		} `json:"changes"`
+	} `json:"push"`
+}
+
+// bitbucketEvent is one thing rules match: a pull request event, or one
+// branch updated by a push.
+type bitbucketEvent struct {
+	Event        string
+	Repo         string
+	RepoURL      string
+	Actor        string
+	Branch       string // PR destination, or the pushed branch
+	SourceBranch string
+	PRID         int
+	Title        string
+	Description  string
+	Author       string
+	URL          string
+	Commit       string
+	Commits      []bitbucketCommit
+	DedupKey     string
+}
+
+func (h *BitbucketHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// Bitbucket Cloud webhooks are configured with a URL only, so the shared
+	// secret travels as ?token= on that URL.
+	if token := h.Config.Bitbucket.Token; token != "" && !signatureBypassed(r, "Bitbucket") &&
+		subtle.ConstantTimeCompare([]byte(r.URL.Query().Get("token")), []byte(token)) != 1 {
+		log.Printf("Bitbucket token verification failed")
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	events.Verified(r.Context(), h.Config.Bitbucket.Token != "")
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, "bad request", http.StatusBadRequest)
+		return
+	}
+
+	eventKey := r.Header.Get("X-Event-Key")
+	var p bitbucketPayload
+	if err := json.Unmarshal(body, &p); err != nil {
+		log.Printf("Failed to parse Bitbucket payload: %v", err)
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	if containsString(h.Config.Bitbucket.IgnoreUsers, p.Actor.UUID) || containsString(h.Config.Bitbucket.IgnoreUsers, p.Actor.Nickname) {
+		log.Printf("Bitbucket: ignoring %s by %s", eventKey, p.Actor.Nickname)
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	events := bitbucketEvents(eventKey, &p)
+	if len(events) == 0 {
+		log.Printf("Bitbucket: ignoring %s", firstNonEmpty(eventKey, "delivery without X-Event-Key"))
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	for _, ev := range events {
+		h.dispatch(r.Context(), ev, body)
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(`{"ok":true}`))
+}
+
+// bitbucketEvents normalizes a delivery. A push yields one event per branch
+// it updated; tag pushes and branch deletions are skipped.
+func bitbucketEvents(eventKey string, p *bitbucketPayload) []bitbucketEvent {
+	base := bitbucketEvent{
+		Event:   eventKey,
+		Repo:    p.Repository.FullName,
+		RepoURL: p.Repository.Links.HTML.Href,
+		Actor:   firstNonEmpty(p.Actor.DisplayName, p.Actor.Nickname),
+	}
+	switch eventKey {
+	case "pullrequest:created", "pullrequest:approved":
+		pr := p.PullRequest
+		if pr == nil {
+			return nil
+		}
+		ev := base
+		ev.PRID = pr.ID
+		ev.Title = pr.Title
+		ev.Description = pr.Description
+		ev.Author = firstNonEmpty(pr.Author.DisplayName, pr.Author.Nickname)
+		ev.URL = pr.Links.HTML.Href
+		ev.Branch = pr.Destination.Branch.Name
+		ev.SourceBranch = pr.Source.Branch.Name
+		ev.DedupKey = fmt.Sprintf("bitbucket:%s:pr:%d:%s", ev.Repo, pr.ID, eventKey)
+		if eventKey == "pullrequest:approved" && p.Approval != nil {
+			// Each approver counts once
+			ev.Actor = firstNonEmpty(p.Approval.User.DisplayName, p.Approval.User.Nickname, ev.Actor)
+			ev.DedupKey += ":" + firstNonEmpty(p.Approval.User.UUID, p.Approval.User.Nickname)
+		}
+		return []bitbucketEvent{ev}
+	case "repo:push":
+		if p.Push == nil {
+			return nil
+		}
+		var out []bitbucketEvent
+		for _, c := range p.Push.Changes {
+			if c.New == nil || c.New.Type != "branch" {
+				continue
+			}
+			ev := base
+			ev.Branch = c.New.Name
+			ev.Commit = c.New.Target.Hash
+			ev.Commits = c.Commits
+			ev.URL = c.Links.HTML.Href
+			ev.DedupKey = fmt.Sprintf("bitbucket:%s:push:%s:%s", ev.Repo, ev.Branch, ev.Commit)
+			out = append(out, ev)
+		}
+		return out
+	}
+	return nil
+}
+
+func (h *BitbucketHandler) dispatch(ctx context.Context, ev bitbucketEvent, body []byte) {
+	rules := h.findRules(ev)
+	if len(rules) == 0 {
+		log.Printf("Bitbucket: no matching rule for %s in %s", ev.Event, ev.Repo)
+		events.Annotate(ctx, ev.Event, "", events.OutcomeNoRule)
+		return
+	}
+	for i, rule := range rules {
+		ref := ruleRef("bitbucket", h.Config.Bitbucket.Rules, rule)
+		events.Annotate(ctx, ev.Event, ref, "")
+		prID := ""
+		if ev.PRID > 0 {
+			prID = strconv.Itoa(ev.PRID)
+		}
+		data := map[string]any{
+			"Event":        ev.Event,
+			"Repo":         ev.Repo,
+			"RepoURL":      ev.RepoURL,
+			"Actor":        ev.Actor,
+			"Branch":       ev.Branch,
+			"SourceBranch": ev.SourceBranch,
+			"PRID":         prID,
+			"Title":        ev.Title,
+			"Description":  ev.Description,
+			"Author":       ev.Author,
+			"URL":          ev.URL,
+			"Commit":       ev.Commit,
+			"Commits":      ev.Commits,
+		}
+		if sampledOut("Bitbucket", ev.Event, rule.Sample) {
+			events.Annotate(ctx, "", ref, events.OutcomeSampledOut)
+			continue
+		}
+		key := dedupKey("bitbucket", rule.DedupKey, data, ev.DedupKey)
+		if !h.Limiter.AllowWithin(continued(key, i, ref), config.RateWindow(rule.RateLimit, h.Config.Bitbucket.RateLimit)) {
+			log.Printf("Bitbucket: rate limited %s", key)
+			events.Annotate(ctx, "", ref, events.OutcomeRateLimited)
+			continue
+		}
+
+		log.Printf("Bitbucket: processing %s in %s", ev.Event, ev.Repo)
+
+		tmplStr := rule.Action.MessageTemplate
+		if tmplStr == "" {
+			tmplStr = config.DefaultBitbucketMessageTemplate()
+		}
+		msg := gateway.RenderMessage("bitbucket", tmplStr, data)
+
+		timeout, delay := jobTiming(h.Config, rule.Action, 0, 0)
+
+		eventName := fmt.Sprintf("bitbucket %s: %s", ev.Event, ev.Repo)
+		if ev.PRID > 0 {
+			eventName += fmt.Sprintf("#%d", ev.PRID)
+		} else if ev.Branch != "" {
+			eventName += "@" + ev.Branch
+		}
+		createJob(ctx, h.Gateway, ruleJob("bitbucket", ref, rule.Action, continued(eventName, i, ref), msg, timeout, delay, body), ref)
+	}
+
+}
+
+func (h *BitbucketHandler) findRules(ev bitbucketEvent) []*config.BitbucketRule {
+	env := map[string]any{
+		"event":         ev.Event,
+		"repo":          ev.Repo,
+		"actor":         ev.Actor,
+		"branch":        ev.Branch,
+		"source_branch": ev.SourceBranch,
+		"pr":            ev.PRID,
+		"title":         ev.Title,
+		"author":        ev.Author,
+		"commits":       len(ev.Commits),
+	}
+	var matched []*config.BitbucketRule
+	for i, rule := range h.Config.Bitbucket.Rules {
+		if rule.Event != ev.Event {
+			continue
+		}
+		if len(rule.Repos) > 0 && !matchRepo(rule.Repos, ev.Repo) {
+			continue
+		}
+		if len(rule.Branches) > 0 && !matchBranch(rule.Branches, ev.Branch) {
+			continue
+		}
+		if rule.Condition != "" && !evalCondition(rule.Condition, env) {
+			continue
+		}
+		matched = append(matched, &h.Config.Bitbucket.Rules[i])
+		if !rule.Continue {
+			return matched
+		}
+	}
+	return matched
+}
+
+// matchBranch matches a branch against names or globs like "release/*".
+func matchBranch(patterns []string, branch string) bool {
+	for _, p := range patterns {
+		if ok, _ := path.Match(p, branch); ok {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,127 @@
+package webhook
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/katalabut/openclaw-relay/internal/config"
+	"github.com/katalabut/openclaw-relay/internal/ratelimit"
+)
+
+const bitbucketPRPayload = `{
+  "actor": {"uuid": "{u-1}", "nickname": "alice", "display_name": "Alice"},
+  "repository": {"full_name": "acme/api", "links": {"html": {"href": "https://bitbucket.org/acme/api"}}},
+  "pullrequest": {
+    "id": 42, "title": "Add retries", "description": "closes #7",
+    "author": {"uuid": "{u-1}", "nickname": "alice", "display_name": "Alice"},
+    "links": {"html": {"href": "https://bitbucket.org/acme/api/pull-requests/42"}},
+    "source": {"branch": {"name": "feature/retries"}},
+    "destination": {"branch": {"name": "main"}}
+  },
+  "approval": {"user": {"uuid": "{u-2}", "nickname": "bob", "display_name": "Bob"}}
+}`
+
+const bitbucketPushPayload = `{
+  "actor": {"uuid": "{u-1}", "nickname": "alice", "display_name": "Alice"},
+  "repository": {"full_name": "acme/api"},
+  "push": {"changes": [
+    {"new": {"type": "branch", "name": "release/1.2", "target": {"hash": "abc123"}},
+     "commits": [{"hash": "abc123", "message": "bump version"}],
+     "links": {"html": {"href": "https://bitbucket.org/acme/api/branches/compare/abc123"}}},
+    {"new": {"type": "tag", "name": "v1.2.0", "target": {"hash": "abc123"}}},
+    {"new": null}
+  ]}
+}`
+
+func newTestBitbucketHandler(gw *mockGateway) *BitbucketHandler {
+	return &BitbucketHandler{
+		Config: &config.Config{Bitbucket: config.BitbucketConfig{
+			Token:       "s3cret",
+			IgnoreUsers: []string{"relay-bot"},
+			Rules: []config.BitbucketRule{
+				{Event: "pullrequest:created", Repos: []string{"acme/*"}, Branches: []string{"main"}, Action: config.RuleAction{MessageTemplate: "review #{{.PRID}} {{.Title}} {{.SourceBranch}}->{{.Branch}}"}},
+				{Event: "pullrequest:approved", Action: config.RuleAction{MessageTemplate: "{{.Actor}} approved #{{.PRID}}"}},
+				{Event: "repo:push", Branches: []string{"release/*"}, Condition: "commits > 0", Action: config.RuleAction{MessageTemplate: "{{.Branch}} {{.Commit}}{{range .Commits}} [{{.Message}}]{{end}}"}},
+			},
+		}},
+		Gateway: gw,
+		Limiter: ratelimit.New(context.Background(), 5*time.Minute),
+	}
+}
+
+func postBitbucket(h http.Handler, event, token, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest("POST", "/webhook/bitbucket?token="+token, strings.NewReader(body))
+	req.Header.Set("X-Event-Key", event)
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestBitbucket_Token(t *testing.T) {
+	gw := &mockGateway{}
+	h := newTestBitbucketHandler(gw)
+	for _, token := range []string{"", "wrong"} {
+		if rec := postBitbucket(h, "pullrequest:created", token, bitbucketPRPayload); rec.Code != http.StatusUnauthorized {
+			t.Errorf("token %q: expected 401, got %d", token, rec.Code)
+		}
+	}
+	if len(gw.calls) != 0 {
+		t.Errorf("expected no jobs, got %d", len(gw.calls))
+	}
+}
+
+func TestBitbucket_PullRequests(t *testing.T) {
+	gw := &mockGateway{}
+	h := newTestBitbucketHandler(gw)
+
+	if rec := postBitbucket(h, "pullrequest:created", "s3cret", bitbucketPRPayload); rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rec.Code)
+	}
+	postBitbucket(h, "pullrequest:created", "s3cret", bitbucketPRPayload) // redelivery
+	postBitbucket(h, "pullrequest:approved", "s3cret", bitbucketPRPayload)
+
+	if len(gw.calls) != 2 {
+		t.Fatalf("expected 2 jobs, got %d: %+v", len(gw.calls), gw.calls)
+	}
+	if gw.calls[0].Message != "review #42 Add retries feature/retries->main" || gw.calls[0].Name != "bitbucket pullrequest:created: acme/api#42" {
+		t.Errorf("unexpected created job %+v", gw.calls[0])
+	}
+	if gw.calls[1].Message != "Bob approved #42" {
+		t.Errorf("unexpected approved message %q", gw.calls[1].Message)
+	}
+
+	// A PR into another branch matches no rule
+	other := strings.Replace(bitbucketPRPayload, `"id": 42`, `"id": 43`, 1)
+	other = strings.Replace(other, `{"name": "main"}`, `{"name": "develop"}`, 1)
+	postBitbucket(h, "pullrequest:created", "s3cret", other)
+	if len(gw.calls) != 2 {
+		t.Errorf("expected PR into develop to be ignored, got %d calls", len(gw.calls))
+	}
+}
+
+func TestBitbucket_Push(t *testing.T) {
+	gw := &mockGateway{}
+	h := newTestBitbucketHandler(gw)
+	postBitbucket(h, "repo:push", "s3cret", bitbucketPushPayload)
+	if len(gw.calls) != 1 {
+		t.Fatalf("expected 1 job for the branch change, got %d", len(gw.calls))
+	}
+	if gw.calls[0].Message != "release/1.2 abc123 [bump version]" || gw.calls[0].Name != "bitbucket repo:push: acme/api@release/1.2" {
+		t.Errorf("unexpected push job %+v", gw.calls[0])
+	}
+}
+
+func TestBitbucket_Skips(t *testing.T) {
+	gw := &mockGateway{}
+	h := newTestBitbucketHandler(gw)
+	postBitbucket(h, "repo:fork", "s3cret", `{"repository":{"full_name":"acme/api"}}`)
+	postBitbucket(h, "pullrequest:created", "s3cret", strings.ReplaceAll(bitbucketPRPayload, `"alice"`, `"relay-bot"`))
+	postBitbucket(h, "pullrequest:created", "s3cret", `not json`)
+	if len(gw.calls) != 0 {
+		t.Errorf("expected no jobs, got %+v", gw.calls)
+	}
+}
@@ -0,0 +1,50 @@
+package webhook
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestLoopbackSignatureBypass(t *testing.T) {
+	tests := []struct {
+		name       string
+		remoteAddr string
+		header     string
+		wrap       bool
+		wantCode   int
+	}{
+		{"loopback with flag", "127.0.0.1:5555", "", true, http.StatusOK},
+		{"ipv6 loopback with flag", "[::1]:5555", "", true, http.StatusOK},
+		{"loopback without flag", "127.0.0.1:5555", "", false, http.StatusForbidden},
+		{"remote with flag", "203.0.113.7:5555", "", true, http.StatusForbidden},
+		{"proxied loopback with flag", "127.0.0.1:5555", "X-Forwarded-For", true, http.StatusForbidden},
+		{"forwarded loopback with flag", "127.0.0.1:5555", "Forwarded", true, http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		gw := &mockGateway{}
+		h := newTestTrelloHandler(gw)
+		h.Config.Trello.Secret = "trello-secret"
+		var handler http.Handler = h
+		if tt.wrap {
+			handler = LoopbackSignatureBypass(h)
+		}
+
+		body := makeTrelloPayload("updateCard", "card1", "My Card", "list-ready-id", "Ready", "list-other", "Other")
+		req := httptest.NewRequest("POST", "/webhook/trello", bytes.NewReader(body))
+		req.RemoteAddr = tt.remoteAddr
+		if tt.header != "" {
+			req.Header.Set(tt.header, "198.51.100.1")
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+
+		if rec.Code != tt.wantCode {
+			t.Errorf("%s: expected %d, got %d", tt.name, tt.wantCode, rec.Code)
+		}
+		if wantCalls := map[bool]int{true: 1, false: 0}[tt.wantCode == http.StatusOK]; len(gw.calls) != wantCalls {
+			t.Errorf("%s: expected %d gateway calls, got %d", tt.name, wantCalls, len(gw.calls))
+		}
+	}
+}
@@ -0,0 +1,55 @@
+package webhook
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/katalabut/openclaw-relay/internal/events"
+	"github.com/katalabut/openclaw-relay/internal/ratelimit"
+)
+
+// redelivered reports whether the delivery with key id was handled before.
+// Otherwise it returns w wrapped to record id once the handler answers with
+// a 2xx, so a delivery that fails (or panics) is handled again when the
+// provider retries it. Replays of stored events are never dropped as
+// redeliveries, but their ID is recorded the same way.
+func redelivered(w http.ResponseWriter, r *http.Request, d *ratelimit.Deliveries, source, id string) (http.ResponseWriter, bool) {
+	if id == "" || d == nil {
+		return w, false
+	}
+	key := source + ":" + id
+	if d.Seen(key) && !events.IsReplay(r.Context()) {
+		log.Printf("Dropping %s redelivery %s", source, id)
+		return w, true
+	}
+	return &deliveryWriter{ResponseWriter: w, deliveries: d, key: key}, false
+}
+
+// deliveryWriter records its delivery as handled when a 2xx status is
+// written through it.
+type deliveryWriter struct {
+	http.ResponseWriter
+	deliveries *ratelimit.Deliveries
+	key        string
+	wrote      bool
+}
+
+func (d *deliveryWriter) WriteHeader(code int) {
+	if !d.wrote {
+		d.wrote = true
+		if code >= 200 && code < 300 {
+			d.deliveries.Record(d.key)
+		}
+	}
+	d.ResponseWriter.WriteHeader(code)
+}
+
+func (d *deliveryWriter) Write(b []byte) (int, error) {
+	if !d.wrote {
+		d.WriteHeader(http.StatusOK)
+	}
+	return d.ResponseWriter.Write(b)
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer.
+func (d *deliveryWriter) Unwrap() http.ResponseWriter { return d.ResponseWriter }
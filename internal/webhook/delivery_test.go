@@ -0,0 +1,47 @@
+package webhook
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/katalabut/openclaw-relay/internal/ratelimit"
+)
+
+func TestRedelivered_RecordsOnSuccess(t *testing.T) {
+	d, _ := ratelimit.NewDeliveries("", 10)
+	serve := func(id string, status int) bool {
+		rec := httptest.NewRecorder()
+		w, dup := redelivered(rec, httptest.NewRequest("POST", "/webhook/github", nil), d, "github", id)
+		if !dup {
+			w.WriteHeader(status)
+		}
+		return dup
+	}
+	if serve("d-1", http.StatusInternalServerError) {
+		t.Fatal("first delivery dropped")
+	}
+	if serve("d-1", http.StatusOK) {
+		t.Fatal("a delivery that failed must be handled again on retry")
+	}
+	if !serve("d-1", http.StatusOK) {
+		t.Error("expected the redelivery of a handled delivery dropped")
+	}
+
+	// A panic before anything is written leaves the ID unrecorded
+	func() {
+		defer func() { recover() }()
+		redelivered(httptest.NewRecorder(), httptest.NewRequest("POST", "/webhook/trello", nil), d, "trello", "a1")
+		panic("boom")
+	}()
+	if d.Seen("trello:a1") {
+		t.Error("a delivery that panicked must not be recorded")
+	}
+
+	// A body without WriteHeader is an implicit 200
+	w, _ := redelivered(httptest.NewRecorder(), httptest.NewRequest("POST", "/webhook/trello", nil), d, "trello", "a2")
+	w.Write([]byte(`{"ok":true}`))
+	if !d.Seen("trello:a2") {
+		t.Error("expected a delivery answered with a body recorded")
+	}
+}
@@ -0,0 +1,302 @@
+package webhook
+
+import (
+	"crypto/ed25519"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/katalabut/openclaw-relay/internal/config"
+	"github.com/katalabut/openclaw-relay/internal/events"
+	"github.com/katalabut/openclaw-relay/internal/gateway"
+	"github.com/katalabut/openclaw-relay/internal/ratelimit"
+)
+
+// Interaction and response types from the Discord API.
+const (
+	discordPing               = 1
+	discordApplicationCommand = 2
+	discordPong               = 1
+	discordChannelMessage     = 4
+	discordEphemeral          = 1 << 6
+	discordMessageCommand     = 3 // data.type of a "right-click → Apps" command on a message
+	discordSubcommand         = 1
+	discordSubcommandGroup    = 2
+)
+
+const defaultDiscordReply = "Sent to the agent."
+
+// DiscordHandler serves Discord interaction webhooks (the application's
+// Interactions Endpoint URL) at /webhook/discord.
+type DiscordHandler struct {
+	Config  *config.Config
+	Gateway gateway.GatewayClient
+	Limiter *ratelimit.Limiter
+	Skew    *ClockSkew // optional; nil uses DefaultSkewTolerance
+}
+
+type discordUser struct {
+	ID       string `json:"id"`
+	Username string `json:"username"`
+	Bot      bool   `json:"bot"`
+}
+
+type discordOption struct {
+	Name    string          `json:"name"`
+	Type    int             `json:"type"`
+	Value   any             `json:"value"`
+	Options []discordOption `json:"options"`
+}
+
+type discordInteraction struct {
+	ID        string `json:"id"`
+	Type      int    `json:"type"`
+	GuildID   string `json:"guild_id"`
+	ChannelID string `json:"channel_id"`
+	Member    *struct {
+		User discordUser `json:"user"`
+	} `json:"member"`
+	User *discordUser `json:"user"`
+	Data struct {
+		Name     string          `json:"name"`
+		Type     int             `json:"type"`
+		Options  []discordOption `json:"options"`
+		TargetID string          `json:"target_id"`
+		Resolved struct {
+			Messages map[string]struct {
+				Content string      `json:"content"`
+				Author  discordUser `json:"author"`
+			} `json:"messages"`
+		} `json:"resolved"`
+	} `json:"data"`
+}
+
+// user returns the invoking user: member.user in a guild, user in DMs.
+func (i *discordInteraction) user() discordUser {
+	if i.Member != nil {
+		return i.Member.User
+	}
+	if i.User != nil {
+		return *i.User
+	}
+	return discordUser{}
+}
+
+// VerifyDiscordSignature checks Discord's Ed25519 signature over timestamp+body
+// with the application's hex public key. Requests whose timestamp is further
+// than tolerance from now are rejected; 0 means DefaultSkewTolerance.
+func VerifyDiscordSignature(body []byte, timestamp, signature, publicKey string, now time.Time, tolerance time.Duration) bool {
+	key, err := hex.DecodeString(publicKey)
+	if err != nil || len(key) != ed25519.PublicKeySize {
+		return false
+	}
+	sig, err := hex.DecodeString(signature)
+	if err != nil || len(sig) != ed25519.SignatureSize {
+		return false
+	}
+	ts, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return false
+	}
+	if tolerance <= 0 {
+		tolerance = DefaultSkewTolerance
+	}
+	if now.Sub(time.Unix(ts, 0)).Abs() > tolerance {
+		return false
+	}
+	msg := append([]byte(timestamp), body...)
+	return ed25519.Verify(ed25519.PublicKey(key), msg, sig)
+}
+
+func (h *DiscordHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, "bad request", http.StatusBadRequest)
+		return
+	}
+
+	// Discord requires verification and probes the endpoint with bad signatures
+	timestamp, now := r.Header.Get("X-Signature-Timestamp"), time.Now()
+	h.Skew.Observe("Discord", timestamp, now)
+	if !signatureBypassed(r, "Discord") && !VerifyDiscordSignature(body, timestamp,
+		r.Header.Get("X-Signature-Ed25519"), h.Config.Discord.PublicKey, now, h.Skew.ResolvedTolerance()) {
+		log.Printf("Discord signature verification failed")
+		http.Error(w, "invalid request signature", http.StatusUnauthorized)
+		return
+	}
+	events.Verified(r.Context(), true)
+
+	var in discordInteraction
+	if err := json.Unmarshal(body, &in); err != nil {
+		log.Printf("Failed to parse Discord interaction: %v", err)
+		http.Error(w, "bad request", http.StatusBadRequest)
+		return
+	}
+
+	switch in.Type {
+	case discordPing:
+		handshake(w, r, "Discord", HandshakePing, func(w http.ResponseWriter) {
+			discordRespond(w, map[string]any{"type": discordPong})
+		})
+		return
+	case discordApplicationCommand:
+	default:
+		log.Printf("Discord: ignoring interaction type %d", in.Type)
+		discordReply(w, "This interaction is not handled by the relay.")
+		return
+	}
+
+	user := in.user()
+	if user.Bot || h.isIgnoredUser(user.ID) {
+		log.Printf("Discord: ignoring /%s from user %s", in.Data.Name, user.ID)
+		discordReply(w, "Ignored.")
+		return
+	}
+
+	rules := h.findRules(in.Data.Name, in.ChannelID)
+	if len(rules) == 0 {
+		log.Printf("Discord: no matching rule for command=%s channel=%s", in.Data.Name, in.ChannelID)
+		events.Annotate(r.Context(), in.Data.Name, "", events.OutcomeNoRule)
+		discordReply(w, "No rule handles this command here.")
+		return
+	}
+
+	subcommand, options := flattenDiscordOptions(in.Data.Options)
+	data := map[string]any{
+		"Command":    in.Data.Name,
+		"Subcommand": subcommand,
+		"Options":    options,
+		"Guild":      in.GuildID,
+		"Channel":    in.ChannelID,
+		"User":       user.ID,
+		"Username":   user.Username,
+		"Text":       joinDiscordOptions(options),
+	}
+	if in.Data.Type == discordMessageCommand {
+		if m, ok := in.Data.Resolved.Messages[in.Data.TargetID]; ok {
+			data["Text"] = m.Content
+			data["TargetAuthor"] = m.Author.Username
+		}
+		data["TargetID"] = in.Data.TargetID
+	}
+
+	// The user gets one reply: the first rule's, unless a job failed
+	reply := rules[0].Reply
+	if reply == "" {
+		reply = defaultDiscordReply
+	}
+	for i, rule := range rules {
+		ref := ruleRef("discord", h.Config.Discord.Rules, rule)
+		events.Annotate(r.Context(), in.Data.Name, ref, "")
+		if sampledOut("Discord", in.Data.Name, rule.Sample) {
+			events.Annotate(r.Context(), "", ref, events.OutcomeSampledOut)
+			continue
+		}
+		key := dedupKey("discord", rule.DedupKey, data, "discord:"+in.ID)
+		if !h.Limiter.AllowWithin(continued(key, i, ref), config.RateWindow(rule.RateLimit, h.Config.Discord.RateLimit)) {
+			log.Printf("Discord: rate limited interaction %s", in.ID)
+			events.Annotate(r.Context(), "", ref, events.OutcomeRateLimited)
+			if i == 0 {
+				reply = "Already received."
+			}
+			continue
+		}
+
+		log.Printf("Discord: processing /%s in %s", in.Data.Name, in.ChannelID)
+
+		tmplStr := rule.Action.MessageTemplate
+		if tmplStr == "" {
+			tmplStr = config.DefaultDiscordMessageTemplate()
+		}
+		msg := gateway.RenderMessage("discord", tmplStr, data)
+
+		timeout, delay := jobTiming(h.Config, rule.Action, 0, 0)
+
+		eventName := continued(fmt.Sprintf("discord /%s: %s", in.Data.Name, in.ChannelID), i, ref)
+		if err := createJob(r.Context(), h.Gateway, ruleJob("discord", ref, rule.Action, eventName, msg, timeout, delay, body), ref); err != nil {
+			reply = "The relay could not reach the agent."
+		}
+	}
+
+	discordReply(w, reply)
+}
+
+func (h *DiscordHandler) findRules(command, channel string) []*config.DiscordRule {
+	var matched []*config.DiscordRule
+	for i, rule := range h.Config.Discord.Rules {
+		if rule.Command != command {
+			continue
+		}
+		if len(rule.Channels) == 0 || containsString(rule.Channels, channel) {
+			matched = append(matched, &h.Config.Discord.Rules[i])
+			if !rule.Continue {
+				return matched
+			}
+		}
+	}
+	return matched
+}
+
+func (h *DiscordHandler) isIgnoredUser(user string) bool {
+	return containsString(h.Config.Discord.IgnoreUsers, user)
+}
+
+// flattenDiscordOptions returns the subcommand path ("group sub") and the
+// leaf option values by name.
+func flattenDiscordOptions(opts []discordOption) (string, map[string]string) {
+	values := make(map[string]string)
+	var path []string
+	for len(opts) > 0 {
+		next := []discordOption(nil)
+		for _, o := range opts {
+			switch o.Type {
+			case discordSubcommand, discordSubcommandGroup:
+				path = append(path, o.Name)
+				next = o.Options
+			default:
+				values[o.Name] = stringifyValue(o.Value)
+			}
+		}
+		opts = next
+	}
+	return strings.Join(path, " "), values
+}
+
+// joinDiscordOptions joins option values in name order, the free text of a command like /ask.
+func joinDiscordOptions(options map[string]string) string {
+	names := make([]string, 0, len(options))
+	for name := range options {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	parts := make([]string, 0, len(names))
+	for _, name := range names {
+		parts = append(parts, options[name])
+	}
+	return strings.Join(parts, " ")
+}
+
+// discordReply answers the interaction with a message only the invoking user sees.
+func discordReply(w http.ResponseWriter, content string) {
+	discordRespond(w, map[string]any{
+		"type": discordChannelMessage,
+		"data": map[string]any{"content": content, "flags": discordEphemeral},
+	})
+}
+
+func discordRespond(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
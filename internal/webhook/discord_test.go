@@ -0,0 +1,228 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/katalabut/openclaw-relay/internal/config"
+	"github.com/katalabut/openclaw-relay/internal/ratelimit"
+)
+
+// testDiscordKey is a fixed key pair so tests are deterministic.
+var testDiscordKey = ed25519.NewKeyFromSeed(bytes.Repeat([]byte{7}, ed25519.SeedSize))
+
+func testDiscordPublicKey() string {
+	return hex.EncodeToString(testDiscordKey.Public().(ed25519.PublicKey))
+}
+
+func signDiscord(body []byte, ts string) string {
+	return hex.EncodeToString(ed25519.Sign(testDiscordKey, append([]byte(ts), body...)))
+}
+
+func TestVerifyDiscordSignature(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	ts := strconv.FormatInt(now.Unix(), 10)
+	body := []byte(`{"type":1}`)
+	sig := signDiscord(body, ts)
+	pub := testDiscordPublicKey()
+	otherKey := hex.EncodeToString(make([]byte, ed25519.PublicKeySize))
+
+	tests := []struct {
+		name      string
+		body      []byte
+		ts        string
+		sig       string
+		key       string
+		now       time.Time
+		tolerance time.Duration
+		want      bool
+	}{
+		{"valid", body, ts, sig, pub, now, 0, true},
+		{"tampered body", []byte(`{"type":2}`), ts, sig, pub, now, 0, false},
+		{"wrong key", body, ts, sig, otherKey, now, 0, false},
+		{"empty key", body, ts, sig, "", now, 0, false},
+		{"bad signature hex", body, ts, "zz", pub, now, 0, false},
+		{"bad timestamp", body, "abc", sig, pub, now, 0, false},
+		{"stale", body, ts, sig, pub, now.Add(6 * time.Minute), 0, false},
+		{"within skew", body, ts, sig, pub, now.Add(4 * time.Minute), 0, true},
+		{"within configured tolerance", body, ts, sig, pub, now.Add(8 * time.Minute), 10 * time.Minute, true},
+		{"outside configured tolerance", body, ts, sig, pub, now.Add(2 * time.Minute), time.Minute, false},
+	}
+	for _, tt := range tests {
+		if got := VerifyDiscordSignature(tt.body, tt.ts, tt.sig, tt.key, tt.now, tt.tolerance); got != tt.want {
+			t.Errorf("%s: got %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func newTestDiscordHandler(gw *mockGateway) *DiscordHandler {
+	cfg := &config.Config{
+		Discord: config.DiscordConfig{
+			PublicKey:   testDiscordPublicKey(),
+			IgnoreUsers: []string{"U_IGNORED"},
+			Rules: []config.DiscordRule{
+				{
+					Command:  "ask",
+					Channels: []string{"C_OPS"},
+					Reply:    "On it.",
+					Action:   config.RuleAction{MessageTemplate: "{{.Username}} /{{.Command}} {{.Subcommand}}: {{.Text}}"},
+				},
+				{Command: "summarize"},
+			},
+		},
+	}
+	return &DiscordHandler{
+		Config:  cfg,
+		Gateway: gw,
+		Limiter: ratelimit.New(context.Background(), 5*time.Minute),
+	}
+}
+
+func postDiscord(h *DiscordHandler, payload map[string]any) (*httptest.ResponseRecorder, map[string]any) {
+	body, _ := json.Marshal(payload)
+	ts := strconv.FormatInt(time.Now().Unix(), 10)
+	req := httptest.NewRequest("POST", "/webhook/discord", bytes.NewReader(body))
+	req.Header.Set("X-Signature-Timestamp", ts)
+	req.Header.Set("X-Signature-Ed25519", signDiscord(body, ts))
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	var resp map[string]any
+	json.Unmarshal(rec.Body.Bytes(), &resp)
+	return rec, resp
+}
+
+func discordCommand(id, name, channel, userID string, options []map[string]any) map[string]any {
+	return map[string]any{
+		"id":         id,
+		"type":       2,
+		"guild_id":   "G1",
+		"channel_id": channel,
+		"member":     map[string]any{"user": map[string]any{"id": userID, "username": "alice"}},
+		"data":       map[string]any{"name": name, "type": 1, "options": options},
+	}
+}
+
+func replyContent(resp map[string]any) string {
+	data, _ := resp["data"].(map[string]any)
+	content, _ := data["content"].(string)
+	return content
+}
+
+func TestServeHTTP_Discord_Ping(t *testing.T) {
+	h := newTestDiscordHandler(&mockGateway{})
+	rec, resp := postDiscord(h, map[string]any{"id": "1", "type": 1})
+	if rec.Code != http.StatusOK || resp["type"] != float64(1) {
+		t.Errorf("expected PONG, got %d %v", rec.Code, resp)
+	}
+}
+
+func TestServeHTTP_Discord_BadSignature(t *testing.T) {
+	h := newTestDiscordHandler(&mockGateway{})
+	req := httptest.NewRequest("POST", "/webhook/discord", strings.NewReader(`{"type":1}`))
+	req.Header.Set("X-Signature-Timestamp", strconv.FormatInt(time.Now().Unix(), 10))
+	req.Header.Set("X-Signature-Ed25519", hex.EncodeToString(make([]byte, ed25519.SignatureSize)))
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("expected 401, got %d", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest("GET", "/webhook/discord", nil))
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("expected 405, got %d", rec.Code)
+	}
+}
+
+func TestServeHTTP_Discord_SlashCommand(t *testing.T) {
+	gw := &mockGateway{}
+	h := newTestDiscordHandler(gw)
+
+	options := []map[string]any{{
+		"name": "deploy", "type": 1,
+		"options": []map[string]any{{"name": "question", "type": 3, "value": "is api healthy?"}},
+	}}
+	rec, resp := postDiscord(h, discordCommand("I1", "ask", "C_OPS", "U1", options))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rec.Code)
+	}
+	if len(gw.calls) != 1 {
+		t.Fatalf("expected 1 gateway call, got %d", len(gw.calls))
+	}
+	if got := gw.calls[0].Message; got != "alice /ask deploy: is api healthy?" {
+		t.Errorf("unexpected message: %q", got)
+	}
+	if gw.calls[0].Name != "discord /ask: C_OPS" || gw.calls[0].Timeout != 120 || gw.calls[0].Delay != 2 {
+		t.Errorf("unexpected job: %+v", gw.calls[0])
+	}
+	data, _ := resp["data"].(map[string]any)
+	if resp["type"] != float64(4) || replyContent(resp) != "On it." || data["flags"] != float64(64) {
+		t.Errorf("expected ephemeral rule reply, got %v", resp)
+	}
+
+	// Discord retries deliver the same interaction ID
+	postDiscord(h, discordCommand("I1", "ask", "C_OPS", "U1", options))
+	if len(gw.calls) != 1 {
+		t.Errorf("expected duplicate interaction to be dropped, got %d calls", len(gw.calls))
+	}
+}
+
+func TestServeHTTP_Discord_MessageCommand(t *testing.T) {
+	gw := &mockGateway{}
+	h := newTestDiscordHandler(gw)
+
+	payload := map[string]any{
+		"id":         "I2",
+		"type":       2,
+		"channel_id": "C_ANY",
+		"user":       map[string]any{"id": "U2", "username": "bob"},
+		"data": map[string]any{
+			"name": "summarize", "type": 3, "target_id": "M1",
+			"resolved": map[string]any{"messages": map[string]any{
+				"M1": map[string]any{"content": "the build is red", "author": map[string]any{"id": "U3", "username": "carol"}},
+			}},
+		},
+	}
+	_, resp := postDiscord(h, payload)
+	if len(gw.calls) != 1 {
+		t.Fatalf("expected 1 gateway call, got %d", len(gw.calls))
+	}
+	msg := gw.calls[0].Message
+	for _, want := range []string{"Command: /summarize", "User: bob (U2)", "Message by: carol", "the build is red"} {
+		if !strings.Contains(msg, want) {
+			t.Errorf("default message missing %q:\n%s", want, msg)
+		}
+	}
+	if replyContent(resp) != defaultDiscordReply {
+		t.Errorf("expected default reply, got %v", resp)
+	}
+}
+
+func TestServeHTTP_Discord_Skips(t *testing.T) {
+	gw := &mockGateway{}
+	h := newTestDiscordHandler(gw)
+
+	postDiscord(h, discordCommand("I3", "ask", "C_OTHER", "U1", nil))      // channel filter
+	postDiscord(h, discordCommand("I4", "unknown", "C_OPS", "U1", nil))    // no rule
+	postDiscord(h, discordCommand("I5", "ask", "C_OPS", "U_IGNORED", nil)) // ignored user
+	bot := discordCommand("I6", "ask", "C_OPS", "U9", nil)
+	bot["member"] = map[string]any{"user": map[string]any{"id": "U9", "bot": true}}
+	postDiscord(h, bot)
+	_, resp := postDiscord(h, map[string]any{"id": "I7", "type": 3}) // component interaction
+
+	if len(gw.calls) != 0 {
+		t.Errorf("expected no gateway calls, got %d", len(gw.calls))
+	}
+	if resp["type"] != float64(4) || replyContent(resp) == "" {
+		t.Errorf("unhandled interactions should still get a reply, got %v", resp)
+	}
+}
@@ -0,0 +1,80 @@
+package webhook
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// EventTime returns when the source produced a delivery, read from the payload
+// (Trello action.date, Slack event_time, Jira timestamp, Notion timestamp, GitHub object timestamps).
+// It reports false when the payload carries no usable timestamp, e.g. custom webhooks.
+func EventTime(source string, body []byte) (time.Time, bool) {
+	switch source {
+	case "trello":
+		var p struct {
+			Action struct {
+				Date time.Time `json:"date"`
+			} `json:"action"`
+		}
+		if json.Unmarshal(body, &p) == nil && !p.Action.Date.IsZero() {
+			return p.Action.Date, true
+		}
+	case "slack":
+		var p struct {
+			EventTime int64 `json:"event_time"`
+		}
+		if json.Unmarshal(body, &p) == nil && p.EventTime > 0 {
+			return time.Unix(p.EventTime, 0), true
+		}
+	case "jira":
+		var p struct {
+			Timestamp int64 `json:"timestamp"` // milliseconds
+		}
+		if json.Unmarshal(body, &p) == nil && p.Timestamp > 0 {
+			return time.UnixMilli(p.Timestamp), true
+		}
+	case "notion":
+		var p struct {
+			Timestamp time.Time `json:"timestamp"`
+		}
+		if json.Unmarshal(body, &p) == nil && !p.Timestamp.IsZero() {
+			return p.Timestamp, true
+		}
+	case "github":
+		return githubEventTime(body)
+	}
+	return time.Time{}, false
+}
+
+// githubEventTime picks the timestamp of the object the event is about.
+func githubEventTime(body []byte) (time.Time, bool) {
+	type stamped struct {
+		UpdatedAt   time.Time `json:"updated_at"`
+		CompletedAt time.Time `json:"completed_at"`
+		SubmittedAt time.Time `json:"submitted_at"`
+		CreatedAt   time.Time `json:"created_at"`
+	}
+	var p struct {
+		CheckRun    *stamped `json:"check_run"`
+		WorkflowRun *stamped `json:"workflow_run"`
+		Review      *stamped `json:"review"`
+		Comment     *stamped `json:"comment"`
+		PullRequest *stamped `json:"pull_request"`
+		Issue       *stamped `json:"issue"`
+	}
+	if json.Unmarshal(body, &p) != nil {
+		return time.Time{}, false
+	}
+	// Most specific object first: a review or comment on a PR, a run's completion
+	for _, s := range []*stamped{p.Review, p.Comment, p.CheckRun, p.WorkflowRun, p.PullRequest, p.Issue} {
+		if s == nil {
+			continue
+		}
+		for _, t := range []time.Time{s.SubmittedAt, s.CompletedAt, s.UpdatedAt, s.CreatedAt} {
+			if !t.IsZero() {
+				return t, true
+			}
+		}
+	}
+	return time.Time{}, false
+}
@@ -0,0 +1,36 @@
+package webhook
+
+import (
+	"testing"
+	"time"
+)
+
+func TestEventTime(t *testing.T) {
+	want := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		source, body string
+		ok           bool
+	}{
+		{"trello", `{"action":{"date":"2026-03-01T12:00:00.000Z"}}`, true},
+		{"slack", `{"event_time":1772366400}`, true},
+		{"jira", `{"timestamp":1772366400000}`, true},
+		{"notion", `{"timestamp":"2026-03-01T12:00:00.000Z"}`, true},
+		{"github", `{"check_run":{"completed_at":"2026-03-01T12:00:00Z","updated_at":"2026-03-01T13:00:00Z"}}`, true},
+		{"github", `{"review":{"submitted_at":"2026-03-01T12:00:00Z"},"pull_request":{"updated_at":"2026-03-02T00:00:00Z"}}`, true},
+		{"github", `{"comment":{"created_at":"2026-03-01T12:00:00Z"},"issue":{"updated_at":"2026-03-02T00:00:00Z"}}`, true},
+		{"github", `{"zen":"ping"}`, false},
+		{"trello", `{"action":{}}`, false},
+		{"custom", `{"time":"2026-03-01T12:00:00Z"}`, false},
+		{"slack", `not json`, false},
+	}
+	for _, tt := range tests {
+		got, ok := EventTime(tt.source, []byte(tt.body))
+		if ok != tt.ok {
+			t.Errorf("%s %s: ok = %v, want %v", tt.source, tt.body, ok, tt.ok)
+			continue
+		}
+		if ok && !got.Equal(want) {
+			t.Errorf("%s %s: got %v, want %v", tt.source, tt.body, got, want)
+		}
+	}
+}
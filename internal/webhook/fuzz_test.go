@@ -0,0 +1,177 @@
+package webhook
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/katalabut/openclaw-relay/internal/config"
+	"github.com/katalabut/openclaw-relay/internal/gateway"
+)
+
+// The fuzz targets below run their seed corpus with go test ./...; to search
+// for new inputs, run one at a time:
+//
+//	go test ./internal/webhook -run '^$' -fuzz FuzzTrelloWebhook -fuzztime 1m
+
+// addPayloadSeeds adds the golden payload fixtures of source to the corpus.
+func addPayloadSeeds(f *testing.F, source string, add func(body []byte)) {
+	paths, _ := filepath.Glob(filepath.Join("testdata", "payloads", source, "*.json"))
+	for _, p := range paths {
+		if strings.HasSuffix(p, ".golden.json") {
+			continue
+		}
+		body, err := os.ReadFile(p)
+		if err != nil {
+			f.Fatal(err)
+		}
+		add(body)
+	}
+}
+
+// serveWithin fails the fuzz run when the handler panics or takes longer than
+// a second for a single delivery.
+func serveWithin(t *testing.T, h http.Handler, req *http.Request) *httptest.ResponseRecorder {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	done := make(chan any, 1)
+	go func() {
+		defer func() { done <- recover() }()
+		h.ServeHTTP(rec, req)
+	}()
+	select {
+	case p := <-done:
+		if p != nil {
+			t.Fatalf("handler panicked: %v", p)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("handler did not return within a second")
+	}
+	if rec.Code >= 500 {
+		t.Fatalf("status %d", rec.Code)
+	}
+	return rec
+}
+
+func FuzzTrelloWebhook(f *testing.F) {
+	addPayloadSeeds(f, "trello", func(body []byte) { f.Add(body) })
+	f.Add(makeTrelloPayload("updateCard", "c1", "Fix {{.CardName}}", "list-ready-id", "Ready", "l0", "Backlog"))
+	f.Add(makeTrelloPayload("commentCard", "c1", "", "", "", "", ""))
+	f.Add([]byte(`{"action":{"type":"updateCard","data":{"card":{"due":"not a time"}}}}`))
+	f.Add([]byte(`{"action":null,"model":[]}`))
+	f.Fuzz(func(t *testing.T, body []byte) {
+		h := newTestTrelloHandler(&mockGateway{})
+		req := httptest.NewRequest(http.MethodPost, "/webhook/trello", bytes.NewReader(body))
+		serveWithin(t, h, req)
+	})
+}
+
+func FuzzGitHubWebhook(f *testing.F) {
+	for _, s := range []struct{ event, fixture string }{
+		{"pull_request", "pull_request_opened"},
+		{"issue_comment", "issue_comment_on_pr"},
+		{"pull_request_review", "pull_request_review_submitted"},
+		{"workflow_run", "workflow_run_completed"},
+		{"check_run", "check_run_completed"},
+	} {
+		body, err := os.ReadFile(filepath.Join("testdata", "payloads", "github", s.fixture+".json"))
+		if err != nil {
+			f.Fatal(err)
+		}
+		f.Add(s.event, body)
+	}
+	f.Add("workflow_run", []byte(`{"action":"completed","repository":{"full_name":"acme/x"},"workflow_run":{"conclusion":"failure","pull_requests":[{}]}}`))
+	f.Add("issue_comment", []byte(`{"issue":{"pull_request":null},"comment":{"body":"\u0000"}}`))
+	f.Add("push", []byte(`[]`))
+	f.Fuzz(func(t *testing.T, event string, body []byte) {
+		h := newTestGitHubRulesHandler(&mockGateway{})
+		req := httptest.NewRequest(http.MethodPost, "/webhook/github", bytes.NewReader(body))
+		req.Header.Set("X-GitHub-Event", event)
+		serveWithin(t, h, req)
+
+		ev := parseGitHubEvent(event, body)
+		gateway.RenderMessage("github", config.DefaultGitHubMessageTemplate(), ev.templateData())
+	})
+}
+
+// FuzzVerifySignatures checks that each verifier accepts the signature it
+// should compute and nothing else, and never panics on malformed headers.
+func FuzzVerifySignatures(f *testing.F) {
+	f.Add([]byte(`{"action":{}}`), "sha256=00", "secret", "1700000000")
+	f.Add([]byte{}, "", "s", "")
+	f.Add([]byte("body"), "v0=", "secret", "-9223372036854775808")
+	f.Add([]byte("body"), "sha256=zz", "secret", "9223372036854775807")
+	f.Fuzz(func(t *testing.T, body []byte, signature, secret, timestamp string) {
+		if secret == "" {
+			return // verification is off without a secret
+		}
+		const callback = "https://relay.example.com/webhook/trello"
+		mac := hmac.New(sha1.New, []byte(secret))
+		mac.Write(body)
+		mac.Write([]byte(callback))
+		trelloSig := base64.StdEncoding.EncodeToString(mac.Sum(nil))
+		if !VerifyTrelloSignature(body, trelloSig, secret, callback) {
+			t.Error("Trello rejected its own signature")
+		}
+		if signature != trelloSig && VerifyTrelloSignature(body, signature, secret, callback) {
+			t.Errorf("Trello accepted %q", signature)
+		}
+
+		mac = hmac.New(sha256.New, []byte(secret))
+		mac.Write(body)
+		githubSig := "sha256=" + hex.EncodeToString(mac.Sum(nil))
+		if !VerifyGitHubSignature(body, githubSig, secret) || !VerifyHMACSHA256(body, githubSig, "sha256=", secret) {
+			t.Error("GitHub rejected its own signature")
+		}
+		if signature != githubSig && (VerifyGitHubSignature(body, signature, secret) || VerifyHMACSHA256(body, signature, "sha256=", secret)) {
+			t.Errorf("GitHub accepted %q", signature)
+		}
+
+		now := time.Unix(1700000000, 0)
+		if VerifySlackSignature(body, timestamp, signature, secret, now, 0) {
+			mac = hmac.New(sha256.New, []byte(secret))
+			mac.Write([]byte("v0:" + timestamp + ":"))
+			mac.Write(body)
+			if signature != "v0="+hex.EncodeToString(mac.Sum(nil)) {
+				t.Errorf("Slack accepted %q at %q", signature, timestamp)
+			}
+		}
+		VerifyDiscordSignature(body, timestamp, signature, secret, now, 0)
+	})
+}
+
+// FuzzRenderMessage feeds payload-controlled text through the default
+// templates; only config supplies templates, so the template itself is fixed.
+func FuzzRenderMessage(f *testing.F) {
+	f.Add("Fix login redirect")
+	f.Add("{{.CardName}}")
+	f.Add("\x00\xff<script>")
+	f.Add("{{range 1000000000}}x{{end}}")
+	f.Fuzz(func(t *testing.T, value string) {
+		h := &TrelloHandler{Config: &config.Config{}}
+		data := map[string]string{"CardName": value, "ListAfterName": value, "ListAfterID": value, "MemberCreatorID": value}
+		if got := h.renderMessage(`{{.CardName}} → {{listNameByID .ListAfterID}} by {{memberName .MemberCreatorID}}`, data); got == "" {
+			t.Error("empty render")
+		}
+
+		anyData := map[string]any{}
+		for _, k := range []string{"Event", "EventType", "Action", "Repo", "Title", "Summary", "IssueKey", "Text", "Channel", "User", "Status", "Level", "URL"} {
+			anyData[k] = value
+		}
+		gateway.RenderMessage("slack", config.DefaultSlackMessageTemplate(), anyData)
+		gateway.RenderMessage("sentry", config.DefaultSentryMessageTemplate(), anyData)
+		gateway.RenderMessage("jira", config.DefaultJiraMessageTemplate(), anyData)
+		gateway.RenderMessage("bitbucket", config.DefaultBitbucketMessageTemplate(), anyData)
+		gateway.RenderMessage("alertmanager", config.DefaultAlertmanagerMessageTemplate(), anyData)
+	})
+}
@@ -0,0 +1,224 @@
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"maps"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/katalabut/openclaw-relay/internal/config"
+	"github.com/katalabut/openclaw-relay/internal/events"
+	"github.com/katalabut/openclaw-relay/internal/expr"
+	"github.com/katalabut/openclaw-relay/internal/gateway"
+	"github.com/katalabut/openclaw-relay/internal/ratelimit"
+)
+
+// GenericHandler serves config-driven webhooks at /webhook/custom/<name>.
+type GenericHandler struct {
+	Config  *config.Config
+	Gateway gateway.GatewayClient
+	Limiter *ratelimit.Limiter
+}
+
+// VerifyHMACSHA256 checks a hex HMAC-SHA256 signature of body, with an optional prefix (e.g. "sha256=").
+func VerifyHMACSHA256(body []byte, signature, prefix, secret string) bool {
+	if secret == "" {
+		return true
+	}
+	if !strings.HasPrefix(signature, prefix) {
+		return false
+	}
+	sig := strings.TrimPrefix(signature, prefix)
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	expected := hex.EncodeToString(mac.Sum(nil))
+	return hmac.Equal([]byte(sig), []byte(expected))
+}
+
+func (h *GenericHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	name := strings.TrimPrefix(r.URL.Path, "/webhook/custom/")
+	hook := h.Config.GenericWebhook(name)
+	if hook == nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, "bad request", http.StatusBadRequest)
+		return
+	}
+
+	if hook.Secret != "" && !signatureBypassed(r, "Generic webhook "+name) {
+		header := hook.SignatureHeader
+		if header == "" {
+			header = "X-Signature-256"
+		}
+		if !VerifyHMACSHA256(body, r.Header.Get(header), hook.SignaturePrefix, hook.Secret) {
+			log.Printf("Generic webhook %s: signature verification failed", name)
+			http.Error(w, "forbidden", http.StatusForbidden)
+			return
+		}
+	}
+	events.Verified(r.Context(), hook.Secret != "")
+
+	var payload any
+	if err := json.Unmarshal(body, &payload); err != nil {
+		log.Printf("Generic webhook %s: failed to parse payload: %v", name, err)
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	fields := make(map[string]string, len(hook.Fields))
+	for field, path := range hook.Fields {
+		fields[field] = stringifyValue(lookupPath(payload, path))
+	}
+
+	rules := findGenericRules(hook.Rules, fields, payload)
+	if len(rules) == 0 {
+		log.Printf("Generic webhook %s: no matching rule", name)
+		events.Annotate(r.Context(), name, "", events.OutcomeNoRule)
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	for i, rule := range rules {
+		ref := rule.Name
+		if ref == "" {
+			ref = ruleRef(name, hook.Rules, rule)
+		}
+		events.Annotate(r.Context(), name, ref, "")
+
+		data := make(map[string]any, len(fields)+3)
+		for k, v := range fields {
+			data[k] = v
+		}
+		data["Webhook"] = name
+		data["Rule"] = rule.Name
+		data["Payload"] = payload
+
+		if sampledOut("Generic webhook "+name, rule.Name, rule.Sample) {
+			events.Annotate(r.Context(), "", ref, events.OutcomeSampledOut)
+			continue
+		}
+
+		// Deliveries are only deduplicated by a dedup_field or a rule's dedup_key
+		defaultKey := ""
+		if hook.DedupField != "" {
+			defaultKey = fmt.Sprintf("custom:%s:%s", name, fields[hook.DedupField])
+		}
+		if key := dedupKey("custom:"+name, rule.DedupKey, data, defaultKey); key != "" {
+			key = continued(key, i, ref)
+			if !h.Limiter.AllowWithin(key, config.RateWindow(rule.RateLimit, hook.RateLimit)) {
+				log.Printf("Generic webhook %s: rate limited %s", name, key)
+				events.Annotate(r.Context(), "", ref, events.OutcomeRateLimited)
+				continue
+			}
+		}
+
+		log.Printf("Generic webhook %s: rule %q matched", name, rule.Name)
+
+		msg := gateway.RenderMessage("generic", rule.Action.MessageTemplate, data)
+
+		timeout, delay := jobTiming(h.Config, rule.Action, 0, 0)
+
+		eventName := continued(fmt.Sprintf("custom %s: %s", name, rule.Name), i, ref)
+		job := ruleJob("custom", rule.Name, rule.Action, eventName, msg, timeout, delay, body)
+		job.Tags = map[string]string{"webhook": name}
+		maps.Copy(job.Tags, rule.Action.Tags)
+		createJob(r.Context(), h.Gateway, job, ref)
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(`{"ok":true}`))
+}
+
+func findGenericRules(rules []config.GenericRule, fields map[string]string, payload any) []*config.GenericRule {
+	env := genericEnv(fields)
+	if _, ok := env["payload"]; !ok {
+		env["payload"] = payload
+	}
+	var matched []*config.GenericRule
+	for i, rule := range rules {
+		if evalCondition(rule.Condition, env) {
+			matched = append(matched, &rules[i])
+			if !rule.Continue {
+				return matched
+			}
+		}
+	}
+	return matched
+}
+
+// evalGenericCondition evaluates a condition (see internal/expr) against extracted string fields.
+func evalGenericCondition(condition string, fields map[string]string) bool {
+	return evalCondition(condition, genericEnv(fields))
+}
+
+func genericEnv(fields map[string]string) map[string]any {
+	env := make(map[string]any, len(fields)+1)
+	for k, v := range fields {
+		env[k] = v
+	}
+	return env
+}
+
+// evalCondition evaluates a rule condition. Conditions are compiled when the
+// config is loaded, so an invalid one here is logged and never matches.
+func evalCondition(condition string, env map[string]any) bool {
+	ok, err := expr.Match(condition, env)
+	if err != nil {
+		log.Printf("Invalid rule condition: %v", err)
+	}
+	return ok
+}
+
+// lookupPath resolves a dot path like "data.items.0.name" (or "data.items[0].name") in decoded JSON.
+func lookupPath(v any, path string) any {
+	path = strings.NewReplacer("[", ".", "]", "").Replace(path)
+	for _, part := range strings.Split(path, ".") {
+		if part == "" {
+			continue
+		}
+		switch node := v.(type) {
+		case map[string]any:
+			v = node[part]
+		case []any:
+			idx, err := strconv.Atoi(part)
+			if err != nil || idx < 0 || idx >= len(node) {
+				return nil
+			}
+			v = node[idx]
+		default:
+			return nil
+		}
+	}
+	return v
+}
+
+func stringifyValue(v any) string {
+	switch val := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return val
+	case float64:
+		return strconv.FormatFloat(val, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(val)
+	default:
+		b, _ := json.Marshal(val)
+		return string(b)
+	}
+}
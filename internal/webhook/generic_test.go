@@ -0,0 +1,234 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/katalabut/openclaw-relay/internal/config"
+	"github.com/katalabut/openclaw-relay/internal/ratelimit"
+)
+
+func newTestGenericHandler(gw *mockGateway) *GenericHandler {
+	cfg := &config.Config{
+		GenericWebhooks: []config.GenericWebhookConfig{
+			{
+				Name: "sentry",
+				Fields: map[string]string{
+					"title":   "data.issue.title",
+					"level":   "data.issue.level",
+					"issue":   "data.issue.id",
+					"culprit": "data.issue.tags[0].value",
+				},
+				DedupField: "issue",
+				Rules: []config.GenericRule{
+					{
+						Name:      "errors",
+						Condition: "level == 'error' || level == 'fatal'",
+						Action: config.RuleAction{
+							MessageTemplate: "[{{.Webhook}}/{{.Rule}}] {{.title}} in {{.culprit}}",
+							Tags:            map[string]string{"team": "core"},
+						},
+					},
+				},
+			},
+		},
+	}
+	return &GenericHandler{
+		Config:  cfg,
+		Gateway: gw,
+		Limiter: ratelimit.New(context.Background(), 5*time.Minute),
+	}
+}
+
+func sentryPayload(id, level string) []byte {
+	body, _ := json.Marshal(map[string]any{
+		"data": map[string]any{
+			"issue": map[string]any{
+				"id":    id,
+				"title": "NullPointer",
+				"level": level,
+				"tags":  []map[string]string{{"key": "fn", "value": "handler.go"}},
+			},
+		},
+	})
+	return body
+}
+
+func TestGenericHandler_MatchDispatches(t *testing.T) {
+	gw := &mockGateway{}
+	h := newTestGenericHandler(gw)
+
+	req := httptest.NewRequest("POST", "/webhook/custom/sentry", bytes.NewReader(sentryPayload("1", "error")))
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rec.Code)
+	}
+	if len(gw.calls) != 1 {
+		t.Fatalf("expected 1 gateway call, got %d", len(gw.calls))
+	}
+	if gw.calls[0].Message != "[sentry/errors] NullPointer in handler.go" {
+		t.Errorf("unexpected message: %q", gw.calls[0].Message)
+	}
+	if gw.calls[0].Timeout != 120 || gw.calls[0].Delay != 2 {
+		t.Errorf("expected default timeout/delay, got %d/%d", gw.calls[0].Timeout, gw.calls[0].Delay)
+	}
+	want := map[string]string{"source": "custom", "rule": "errors", "webhook": "sentry", "team": "core"}
+	if !reflect.DeepEqual(gw.calls[0].Tags, want) {
+		t.Errorf("tags = %v, want %v", gw.calls[0].Tags, want)
+	}
+}
+
+func TestGenericHandler_NoMatch(t *testing.T) {
+	gw := &mockGateway{}
+	h := newTestGenericHandler(gw)
+
+	req := httptest.NewRequest("POST", "/webhook/custom/sentry", bytes.NewReader(sentryPayload("1", "warning")))
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+
+	if len(gw.calls) != 0 {
+		t.Errorf("expected no gateway calls, got %d", len(gw.calls))
+	}
+}
+
+func TestGenericHandler_DedupField(t *testing.T) {
+	gw := &mockGateway{}
+	h := newTestGenericHandler(gw)
+
+	for i := 0; i < 2; i++ {
+		req := httptest.NewRequest("POST", "/webhook/custom/sentry", bytes.NewReader(sentryPayload("7", "error")))
+		h.ServeHTTP(httptest.NewRecorder(), req)
+	}
+	req := httptest.NewRequest("POST", "/webhook/custom/sentry", bytes.NewReader(sentryPayload("8", "error")))
+	h.ServeHTTP(httptest.NewRecorder(), req)
+
+	if len(gw.calls) != 2 {
+		t.Errorf("expected 2 calls (one deduped), got %d", len(gw.calls))
+	}
+}
+
+func TestGenericHandler_DedupKey(t *testing.T) {
+	gw := &mockGateway{}
+	h := newTestGenericHandler(gw)
+	hook := &h.Config.GenericWebhooks[0]
+	hook.DedupField = ""
+	hook.Rules[0].DedupKey = "{{.culprit}}"
+
+	// Both issues are in handler.go, so the second one is deduped
+	for _, id := range []string{"7", "8"} {
+		req := httptest.NewRequest("POST", "/webhook/custom/sentry", bytes.NewReader(sentryPayload(id, "error")))
+		h.ServeHTTP(httptest.NewRecorder(), req)
+	}
+	if len(gw.calls) != 1 {
+		t.Errorf("expected 1 call, got %d", len(gw.calls))
+	}
+}
+
+func TestGenericHandler_UnknownName(t *testing.T) {
+	h := newTestGenericHandler(&mockGateway{})
+	req := httptest.NewRequest("POST", "/webhook/custom/stripe", strings.NewReader(`{}`))
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("expected 404, got %d", rec.Code)
+	}
+}
+
+func TestGenericHandler_Signature(t *testing.T) {
+	gw := &mockGateway{}
+	h := newTestGenericHandler(gw)
+	h.Config.GenericWebhooks[0].Secret = "s3cret"
+	h.Config.GenericWebhooks[0].SignaturePrefix = "sha256="
+
+	body := sentryPayload("1", "error")
+	req := httptest.NewRequest("POST", "/webhook/custom/sentry", bytes.NewReader(body))
+	req.Header.Set("X-Signature-256", "sha256=bad")
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if rec.Code != http.StatusForbidden {
+		t.Fatalf("expected 403, got %d", rec.Code)
+	}
+
+	mac := hmac.New(sha256.New, []byte("s3cret"))
+	mac.Write(body)
+	req = httptest.NewRequest("POST", "/webhook/custom/sentry", bytes.NewReader(body))
+	req.Header.Set("X-Signature-256", "sha256="+hex.EncodeToString(mac.Sum(nil)))
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK || len(gw.calls) != 1 {
+		t.Errorf("expected valid signature to dispatch, code=%d calls=%d", rec.Code, len(gw.calls))
+	}
+}
+
+func TestGenericHandler_MethodAndBadJSON(t *testing.T) {
+	gw := &mockGateway{}
+	h := newTestGenericHandler(gw)
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest("GET", "/webhook/custom/sentry", nil))
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("expected 405, got %d", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest("POST", "/webhook/custom/sentry", strings.NewReader("not json")))
+	if rec.Code != http.StatusOK || len(gw.calls) != 0 {
+		t.Errorf("expected 200 without dispatch for bad JSON, code=%d calls=%d", rec.Code, len(gw.calls))
+	}
+}
+
+func TestEvalGenericCondition(t *testing.T) {
+	fields := map[string]string{"level": "error", "env": "prod", "muted": "false", "count": "3"}
+	tests := []struct {
+		cond string
+		want bool
+	}{
+		{"", true},
+		{"level == 'error'", true},
+		{"level == \"warning\"", false},
+		{"level == 'error' && env == 'prod'", true},
+		{"level == 'error' && env != 'prod'", false},
+		{"level == 'warning' || env == 'prod'", true},
+		{"count", true},
+		{"muted", false},
+		{"!muted && level == error", true},
+		{"missing", false},
+	}
+	for _, tt := range tests {
+		if got := evalGenericCondition(tt.cond, fields); got != tt.want {
+			t.Errorf("evalGenericCondition(%q) = %v, want %v", tt.cond, got, tt.want)
+		}
+	}
+}
+
+func TestLookupPath(t *testing.T) {
+	var payload any
+	json.Unmarshal([]byte(`{"a":{"b":[{"c":1.5},{"c":true}],"d":{"e":"x"}}}`), &payload)
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"a.b.0.c", "1.5"},
+		{"a.b[1].c", "true"},
+		{"a.d", `{"e":"x"}`},
+		{"a.b.5.c", ""},
+		{"a.missing.c", ""},
+	}
+	for _, tt := range tests {
+		if got := stringifyValue(lookupPath(payload, tt.path)); got != tt.want {
+			t.Errorf("lookupPath(%q) = %q, want %q", tt.path, got, tt.want)
+		}
+	}
+}
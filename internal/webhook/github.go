@@ -1,7 +1,7 @@
 package webhook
 
 import (
-	"bytes"
+	"context"
 	"crypto/hmac"
 	"crypto/sha256"
 	"encoding/hex"
@@ -10,18 +10,40 @@ import (
 	"io"
 	"log"
 	"net/http"
+	"path"
+	"strconv"
 	"strings"
-	"text/template"
+	"time"
+	"unicode/utf8"
 
 	"github.com/katalabut/openclaw-relay/internal/config"
+	"github.com/katalabut/openclaw-relay/internal/events"
 	"github.com/katalabut/openclaw-relay/internal/gateway"
+	"github.com/katalabut/openclaw-relay/internal/github"
+	"github.com/katalabut/openclaw-relay/internal/links"
 	"github.com/katalabut/openclaw-relay/internal/ratelimit"
 )
 
 type GitHubHandler struct {
-	Config  *config.Config
-	Gateway gateway.GatewayClient
-	Limiter *ratelimit.Limiter
+	Config     *config.Config
+	Gateway    gateway.GatewayClient
+	Limiter    *ratelimit.Limiter
+	Deliveries *ratelimit.Deliveries // optional; drops redeliveries by X-GitHub-Delivery
+	Links      *links.Store          // optional card ↔ PR links for {{.CardID}} and friends
+	Artifacts  ArtifactLister        // optional; fills {{.Artifacts}} for completed workflow runs
+}
+
+// ArtifactLister lists the artifacts of a workflow run; *github.Client implements it.
+type ArtifactLister interface {
+	WorkflowRunArtifacts(ctx context.Context, repo string, runID int64) ([]github.Artifact, error)
+}
+
+// githubArtifact is a workflow run artifact as seen by templates.
+type githubArtifact struct {
+	Name        string
+	Size        int64  // bytes
+	URL         string // artifact page in the run
+	DownloadURL string // zip via the API; needs a token
 }
 
 func VerifyGitHubSignature(body []byte, signature, secret string) bool {
@@ -38,6 +60,266 @@ func VerifyGitHubSignature(body []byte, signature, secret string) bool {
 	return hmac.Equal([]byte(sig), []byte(expected))
 }
 
+type githubPayload struct {
+	Action     string `json:"action"`
+	Repository struct {
+		FullName string `json:"full_name"`
+	} `json:"repository"`
+	Sender struct {
+		Login string `json:"login"`
+		Type  string `json:"type"`
+	} `json:"sender"`
+	PullRequest struct {
+		Number  int    `json:"number"`
+		Title   string `json:"title"`
+		HTMLURL string `json:"html_url"`
+		Merged  bool   `json:"merged"`
+		Head    struct {
+			SHA string `json:"sha"`
+			Ref string `json:"ref"`
+		} `json:"head"`
+	} `json:"pull_request"`
+	Issue struct {
+		Number      int       `json:"number"`
+		Title       string    `json:"title"`
+		HTMLURL     string    `json:"html_url"`
+		PullRequest *struct{} `json:"pull_request"` // present when the issue is a pull request
+	} `json:"issue"`
+	Comment struct {
+		ID      int64  `json:"id"`
+		Body    string `json:"body"`
+		HTMLURL string `json:"html_url"`
+		Path    string `json:"path"` // pull_request_review_comment
+		User    struct {
+			Login string `json:"login"`
+		} `json:"user"`
+	} `json:"comment"`
+	Review struct {
+		State   string `json:"state"`
+		Body    string `json:"body"`
+		HTMLURL string `json:"html_url"`
+		User    struct {
+			Login string `json:"login"`
+		} `json:"user"`
+	} `json:"review"`
+	CheckRun    githubRun `json:"check_run"`
+	WorkflowRun githubRun `json:"workflow_run"`
+}
+
+type githubRun struct {
+	ID         int64  `json:"id"`
+	Name       string `json:"name"`
+	Conclusion string `json:"conclusion"`
+	HeadSHA    string `json:"head_sha"`
+	HeadBranch string `json:"head_branch"` // workflow_run
+	HTMLURL    string `json:"html_url"`
+	CheckSuite struct {
+		HeadBranch string `json:"head_branch"`
+	} `json:"check_suite"` // check_run
+	PullRequests []struct {
+		Number int `json:"number"`
+	} `json:"pull_requests"`
+}
+
+// githubEvent is the normalized view of a delivery used for filtering and templates.
+type githubEvent struct {
+	Event      string
+	Action     string
+	Repository string
+	Sender     string
+	SenderBot  bool
+	PRNumber   int
+	PRTitle    string
+	Merged     bool
+	Conclusion string
+	Name       string
+	HeadSHA    string
+	Branch     string
+	URL        string
+	RunID      int64 // workflow_run
+
+	IssueNumber   int
+	IssueTitle    string
+	CommentID     int64
+	Comment       string
+	CommentAuthor string
+	CommentPath   string
+
+	ReviewState  string // approved, changes_requested or commented
+	ReviewBody   string
+	ReviewAuthor string
+
+	Payload any    `json:"-"` // decoded body, for rule conditions
+	Body    []byte `json:"-"` // raw delivery
+}
+
+func parseGitHubEvent(event string, body []byte) githubEvent {
+	var p githubPayload
+	json.Unmarshal(body, &p)
+	var raw any
+	json.Unmarshal(body, &raw)
+
+	ev := githubEvent{
+		Event:      event,
+		Action:     p.Action,
+		Repository: p.Repository.FullName,
+		Sender:     p.Sender.Login,
+		SenderBot:  p.Sender.Type == "Bot",
+		PRNumber:   p.PullRequest.Number,
+		PRTitle:    p.PullRequest.Title,
+		Merged:     p.PullRequest.Merged,
+		HeadSHA:    p.PullRequest.Head.SHA,
+		Branch:     p.PullRequest.Head.Ref,
+		URL:        p.PullRequest.HTMLURL,
+		RunID:      p.WorkflowRun.ID,
+
+		IssueNumber:   p.Issue.Number,
+		IssueTitle:    p.Issue.Title,
+		CommentID:     p.Comment.ID,
+		Comment:       p.Comment.Body,
+		CommentAuthor: p.Comment.User.Login,
+		CommentPath:   p.Comment.Path,
+
+		ReviewState:  p.Review.State,
+		ReviewBody:   truncateText(strings.TrimSpace(p.Review.Body), maxReviewBodyChars),
+		ReviewAuthor: p.Review.User.Login,
+
+		Payload: raw,
+	}
+	if event == "pull_request_review_comment" {
+		ev.Comment = truncateText(ev.Comment, maxReviewBodyChars)
+	}
+	// Comments on pull requests arrive as issue_comment with issue.pull_request set
+	if p.Issue.PullRequest != nil && ev.PRNumber == 0 {
+		ev.PRNumber = p.Issue.Number
+		ev.PRTitle = p.Issue.Title
+	}
+	if ev.URL == "" {
+		ev.URL = p.Comment.HTMLURL
+	}
+	if ev.URL == "" {
+		ev.URL = p.Review.HTMLURL
+	}
+	if ev.URL == "" {
+		ev.URL = p.Issue.HTMLURL
+	}
+	for _, run := range []githubRun{p.CheckRun, p.WorkflowRun} {
+		if ev.PRNumber == 0 && len(run.PullRequests) > 0 {
+			ev.PRNumber = run.PullRequests[0].Number
+		}
+		if ev.Conclusion == "" {
+			ev.Conclusion = run.Conclusion
+		}
+		if ev.Name == "" {
+			ev.Name = run.Name
+		}
+		if ev.HeadSHA == "" {
+			ev.HeadSHA = run.HeadSHA
+		}
+		if ev.Branch == "" {
+			ev.Branch = firstNonEmpty(run.HeadBranch, run.CheckSuite.HeadBranch)
+		}
+		if ev.URL == "" {
+			ev.URL = run.HTMLURL
+		}
+	}
+	return ev
+}
+
+func (e githubEvent) templateData() map[string]interface{} {
+	return map[string]interface{}{
+		"Event":      e.Event,
+		"Action":     e.Action,
+		"Repository": e.Repository,
+		"Sender":     e.Sender,
+		"PRNumber":   e.PRNumber,
+		"PRTitle":    e.PRTitle,
+		"Merged":     e.Merged,
+		"Conclusion": e.Conclusion,
+		"Name":       e.Name,
+		"HeadSHA":    e.HeadSHA,
+		"Branch":     e.Branch,
+		"URL":        e.URL,
+
+		"IssueNumber":   e.IssueNumber,
+		"IssueTitle":    e.IssueTitle,
+		"Comment":       e.Comment,
+		"CommentAuthor": e.CommentAuthor,
+		"CommentPath":   e.CommentPath,
+
+		"ReviewState":  e.ReviewState,
+		"ReviewBody":   e.ReviewBody,
+		"ReviewAuthor": e.ReviewAuthor,
+	}
+}
+
+// templateData adds the linked Trello card (CardID, CardName, CardList) and the
+// run's artifacts to the event's template data; the fields are empty when no
+// link exists or the artifacts can't be listed.
+func (h *GitHubHandler) templateData(ctx context.Context, ev githubEvent) map[string]interface{} {
+	data := ev.templateData()
+	var link links.Link
+	if h.Links != nil {
+		link, _ = h.Links.Lookup(ev.Repository, ev.PRNumber, ev.Branch)
+	}
+	data["CardID"] = link.CardID
+	data["CardName"] = link.CardName
+	data["CardList"] = link.List
+	data["Artifacts"] = h.artifacts(ctx, ev)
+	return data
+}
+
+// artifacts lists the artifacts of a completed workflow run through the GitHub
+// App. A failed call only leaves them out of the prompt.
+func (h *GitHubHandler) artifacts(ctx context.Context, ev githubEvent) []githubArtifact {
+	if h.Artifacts == nil || ev.Event != "workflow_run" || ev.Action != "completed" || ev.RunID == 0 {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	list, err := h.Artifacts.WorkflowRunArtifacts(ctx, ev.Repository, ev.RunID)
+	if err != nil {
+		log.Printf("GitHub: list artifacts for %s run %d failed: %v", ev.Repository, ev.RunID, err)
+		return nil
+	}
+	var out []githubArtifact
+	for _, a := range list {
+		ga := githubArtifact{Name: a.Name, Size: a.SizeInBytes, DownloadURL: a.DownloadURL}
+		if ev.URL != "" {
+			ga.URL = fmt.Sprintf("%s/artifacts/%d", strings.TrimRight(ev.URL, "/"), a.ID)
+		}
+		out = append(out, ga)
+	}
+	return out
+}
+
+// rateKey returns the limiter key for the delivery. Issue, comment and pull request
+// events get their own key shapes so they never collide with CI events on the same PR.
+func (e githubEvent) rateKey() string {
+	switch e.Event {
+	case "issues":
+		return fmt.Sprintf("github:issues:%s#%d:%s", e.Repository, e.IssueNumber, e.Action)
+	case "issue_comment", "pull_request_review_comment":
+		return fmt.Sprintf("github:%s:%s:%d", e.Event, e.Repository, e.CommentID)
+	case "pull_request":
+		// synchronize fires once per push, so the head SHA tells pushes apart
+		return fmt.Sprintf("github:pull_request:%s#%d:%s:%s", e.Repository, e.PRNumber, e.Action, e.HeadSHA)
+	}
+	ref := strconv.Itoa(e.PRNumber)
+	if e.PRNumber == 0 && e.HeadSHA != "" {
+		ref = e.HeadSHA
+	}
+	return fmt.Sprintf("github:%s:%s:%s", e.Event, e.Repository, ref)
+}
+
+// jobName is the gateway job name for the delivery.
+func (e githubEvent) jobName() string {
+	if e.Event == "issues" || (e.Event == "issue_comment" && e.PRNumber == 0) {
+		return fmt.Sprintf("github %s/%s #%d", e.Event, e.Action, e.IssueNumber)
+	}
+	return fmt.Sprintf("github %s/%s PR#%d", e.Event, e.Action, e.PRNumber)
+}
+
 func (h *GitHubHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
 		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
@@ -51,13 +333,185 @@ func (h *GitHubHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	}
 
 	sig := r.Header.Get("X-Hub-Signature-256")
-	if h.Config.GitHub.Secret != "" && !VerifyGitHubSignature(body, sig, h.Config.GitHub.Secret) {
+	if h.Config.GitHub.Secret != "" && !signatureBypassed(r, "GitHub") && !VerifyGitHubSignature(body, sig, h.Config.GitHub.Secret) {
 		log.Printf("GitHub signature verification failed")
 		http.Error(w, "forbidden", http.StatusForbidden)
 		return
 	}
+	events.Verified(r.Context(), h.Config.GitHub.Secret != "")
 
-	ghEvent := r.Header.Get("X-GitHub-Event")
+	if r.Header.Get("X-GitHub-Event") == "ping" {
+		handshake(w, r, "GitHub", HandshakePing, nil)
+		return
+	}
+
+	w, dup := redelivered(w, r, h.Deliveries, "github", r.Header.Get("X-GitHub-Delivery"))
+	if dup {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	ev := parseGitHubEvent(r.Header.Get("X-GitHub-Event"), body)
+	ev.Body = body
+	eventType := ev.Event
+	if ev.Action != "" {
+		eventType += "/" + ev.Action
+	}
+	events.Annotate(r.Context(), eventType, "", "")
+	if len(h.Config.GitHub.Rules) > 0 {
+		h.serveRules(r.Context(), w, ev)
+		return
+	}
+	h.serveLegacy(r.Context(), w, ev)
+}
+
+// serveRules dispatches the matching github.rules entries: the first match,
+// and the ones after it while the previous has continue: true.
+func (h *GitHubHandler) serveRules(ctx context.Context, w http.ResponseWriter, ev githubEvent) {
+	rules := h.findRules(ev)
+	if len(rules) == 0 {
+		log.Printf("GitHub: no matching rule for %s/%s on %s", ev.Event, ev.Action, ev.Repository)
+		events.Annotate(ctx, "", "", events.OutcomeNoRule)
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	if ev.Event == "issue_comment" && ev.SenderBot {
+		log.Printf("GitHub: ignoring bot comment by %s on %s", ev.Sender, ev.Repository)
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	data := h.templateData(ctx, ev)
+	for i, rule := range rules {
+		ref := ruleRef("github", h.Config.GitHub.Rules, rule)
+		if sampledOut("GitHub", ev.Event+"/"+ev.Action, rule.Sample) {
+			events.Annotate(ctx, "", ref, events.OutcomeSampledOut)
+			continue
+		}
+
+		key := continued(dedupKey("github", rule.DedupKey, data, ev.rateKey()), i, ref)
+		if !h.Limiter.AllowWithin(key, config.RateWindow(rule.RateLimit, h.Config.GitHub.RateLimit)) {
+			log.Printf("GitHub: rate limited %s", key)
+			events.Annotate(ctx, "", ref, events.OutcomeRateLimited)
+			continue
+		}
+
+		log.Printf("GitHub: rule matched %s/%s for %s PR#%d", ev.Event, ev.Action, ev.Repository, ev.PRNumber)
+
+		for n, action := range rule.ResolvedActions() {
+			tmplStr := action.MessageTemplate
+			if tmplStr == "" {
+				tmplStr = h.Config.GitHub.ResolvedTemplate(ev.Event)
+			}
+			msg := gateway.RenderMessage("github", tmplStr, data)
+
+			timeout, delay := jobTiming(h.Config, action, h.Config.GitHub.Timeout, h.Config.GitHub.Delay)
+			agentID := action.AgentID
+			if agentID == "" {
+				agentID = h.Config.GitHub.AgentID
+			}
+
+			job := ruleJob("github", ref, action, actionJobName(continued(ev.jobName(), i, ref), n), msg, timeout, delay, ev.Body)
+			job.AgentID = agentID
+			createJob(ctx, h.Gateway, job, ref)
+		}
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(`{"ok":true}`))
+}
+
+func (h *GitHubHandler) findRules(ev githubEvent) []*config.GitHubRule {
+	var matched []*config.GitHubRule
+	for i, rule := range h.Config.GitHub.Rules {
+		if rule.Event != ev.Event {
+			continue
+		}
+		if len(rule.Actions) > 0 && !containsString(rule.Actions, ev.Action) {
+			continue
+		}
+		if len(rule.Conclusions) > 0 && !containsString(rule.Conclusions, ev.Conclusion) {
+			continue
+		}
+		if len(rule.Repos) > 0 && !matchRepo(rule.Repos, ev.Repository) {
+			continue
+		}
+		if rule.Condition != "" && !evalCondition(rule.Condition, ev.conditionEnv()) {
+			continue
+		}
+		matched = append(matched, &h.Config.GitHub.Rules[i])
+		if !rule.Continue {
+			return matched
+		}
+	}
+	return matched
+}
+
+// conditionEnv exposes the event to rule conditions; payload is the raw body
+// (payload.pull_request.user.login).
+func (ev githubEvent) conditionEnv() map[string]any {
+	return map[string]any{
+		"event":          ev.Event,
+		"action":         ev.Action,
+		"repo":           ev.Repository,
+		"sender":         ev.Sender,
+		"sender_bot":     ev.SenderBot,
+		"pr":             ev.PRNumber,
+		"pr_title":       ev.PRTitle,
+		"merged":         ev.Merged,
+		"conclusion":     ev.Conclusion,
+		"name":           ev.Name,
+		"branch":         ev.Branch,
+		"head_sha":       ev.HeadSHA,
+		"issue":          ev.IssueNumber,
+		"issue_title":    ev.IssueTitle,
+		"comment":        ev.Comment,
+		"comment_author": ev.CommentAuthor,
+		"review_state":   ev.ReviewState,
+		"review_body":    ev.ReviewBody,
+		"payload":        ev.Payload,
+	}
+}
+
+// matchRepo matches owner/name against exact names or globs like "acme/*".
+func matchRepo(patterns []string, repo string) bool {
+	for _, p := range patterns {
+		if ok, _ := path.Match(strings.ToLower(p), strings.ToLower(repo)); ok {
+			return true
+		}
+	}
+	return false
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+func firstNonEmpty(vals ...string) string {
+	for _, v := range vals {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// serveLegacy keeps the built-in behavior used when github.rules is empty:
+// completed check/workflow runs and submitted reviews, filtered by notify_mode,
+// plus the optional events enabled in github.events.
+func (h *GitHubHandler) serveLegacy(ctx context.Context, w http.ResponseWriter, ev githubEvent) {
+	ghEvent := ev.Event
+
+	if _, optional := config.OptionalGitHubEvents[ghEvent]; optional {
+		h.serveOptional(ctx, w, ev)
+		return
+	}
 
 	relevantEvents := map[string]bool{
 		"check_run":           true,
@@ -70,130 +524,102 @@ func (h *GitHubHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	var payload struct {
-		Action     string `json:"action"`
-		Repository struct {
-			FullName string `json:"full_name"`
-		} `json:"repository"`
-		PullRequest struct {
-			Number int    `json:"number"`
-			Title  string `json:"title"`
-		} `json:"pull_request"`
-		CheckRun struct {
-			Conclusion   string `json:"conclusion"`
-			PullRequests []struct {
-				Number int `json:"number"`
-			} `json:"pull_requests"`
-		} `json:"check_run"`
-		WorkflowRun struct {
-			Conclusion   string `json:"conclusion"`
-			PullRequests []struct {
-				Number int `json:"number"`
-			} `json:"pull_requests"`
-		} `json:"workflow_run"`
-	}
-	json.Unmarshal(body, &payload)
-
 	switch ghEvent {
 	case "check_run":
-		if payload.Action != "completed" {
+		if ev.Action != "completed" {
 			w.WriteHeader(http.StatusOK)
 			return
 		}
 	case "workflow_run":
-		if payload.Action != "completed" {
+		if ev.Action != "completed" {
 			w.WriteHeader(http.StatusOK)
 			return
 		}
 	case "pull_request_review":
-		if payload.Action != "submitted" {
+		if ev.Action != "submitted" {
 			w.WriteHeader(http.StatusOK)
 			return
 		}
 	}
 
-	prNumber := payload.PullRequest.Number
-	prTitle := payload.PullRequest.Title
-	if prNumber == 0 && len(payload.CheckRun.PullRequests) > 0 {
-		prNumber = payload.CheckRun.PullRequests[0].Number
-	}
-	if prNumber == 0 && len(payload.WorkflowRun.PullRequests) > 0 {
-		prNumber = payload.WorkflowRun.PullRequests[0].Number
-	}
-
-	conclusion := payload.CheckRun.Conclusion
-	if conclusion == "" {
-		conclusion = payload.WorkflowRun.Conclusion
-	}
+	prNumber := ev.PRNumber
 
 	// notify_mode filtering: "failures" skips successful CI runs
-	if h.Config.GitHub.NotifyMode == "failures" && conclusion == "success" {
+	if h.Config.GitHub.NotifyMode == "failures" && ev.Conclusion == "success" {
 		log.Printf("GitHub: skipping successful %s PR#%d (notify_mode=failures)", ghEvent, prNumber)
 		w.WriteHeader(http.StatusOK)
 		return
 	}
 
 	key := fmt.Sprintf("github:%s:%d", ghEvent, prNumber)
-	if !h.Limiter.Allow(key) {
+	if !h.Limiter.AllowWithin(key, config.RateWindow(h.Config.GitHub.RateLimit)) {
 		log.Printf("GitHub: rate limited %s PR#%d", ghEvent, prNumber)
+		events.Annotate(ctx, "", "", events.OutcomeRateLimited)
 		w.WriteHeader(http.StatusOK)
 		return
 	}
 
-	log.Printf("GitHub: processing %s/%s for %s PR#%d", ghEvent, payload.Action, payload.Repository.FullName, prNumber)
+	log.Printf("GitHub: processing %s/%s for %s PR#%d", ghEvent, ev.Action, ev.Repository, prNumber)
 
 	// Render message from template
-	tmplStr := h.Config.GitHub.MessageTemplate
-	if tmplStr == "" {
-		tmplStr = config.DefaultGitHubMessageTemplate()
-	}
+	msg := gateway.RenderMessage("github", h.Config.GitHub.ResolvedTemplate(ghEvent), h.templateData(ctx, ev))
+	eventName := fmt.Sprintf("github %s/%s PR#%d", ghEvent, ev.Action, prNumber)
 
-	data := map[string]interface{}{
-		"Event":      ghEvent,
-		"Action":     payload.Action,
-		"Repository": payload.Repository.FullName,
-		"PRNumber":   prNumber,
-		"PRTitle":    prTitle,
-		"Conclusion": conclusion,
-	}
+	timeout, delay := jobTiming(h.Config, config.RuleAction{}, h.Config.GitHub.Timeout, h.Config.GitHub.Delay)
 
-	msg := renderGitHubMessage(tmplStr, data)
-	eventName := fmt.Sprintf("github %s/%s PR#%d", ghEvent, payload.Action, prNumber)
+	job := gateway.JobSpec{Name: eventName, Message: msg, AgentID: h.Config.GitHub.AgentID, TimeoutSeconds: timeout, DelaySeconds: delay, Source: "github", Payload: ev.Body}
+	createJob(ctx, h.Gateway, job, "")
+
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(`{"ok":true}`))
+}
 
-	timeout := h.Config.GitHub.Timeout
-	if timeout == 0 {
-		timeout = 120
+// serveOptional handles issues, issue_comment and pull_request when enabled in github.events.
+func (h *GitHubHandler) serveOptional(ctx context.Context, w http.ResponseWriter, ev githubEvent) {
+	if !h.Config.GitHub.EventEnabled(ev.Event) {
+		log.Printf("GitHub: ignoring event %s (not in github.events)", ev.Event)
+		w.WriteHeader(http.StatusOK)
+		return
 	}
-	delay := h.Config.GitHub.Delay
-	if delay == 0 {
-		delay = 2
+	if !containsString(config.OptionalGitHubEvents[ev.Event], ev.Action) {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	// Don't feed the agent its own comments back
+	if ev.Event == "issue_comment" && ev.SenderBot {
+		log.Printf("GitHub: ignoring bot comment by %s on %s", ev.Sender, ev.Repository)
+		w.WriteHeader(http.StatusOK)
+		return
 	}
 
-	agentID := h.Config.GitHub.AgentID
-	if agentID != "" {
-		if err := h.Gateway.CreateOneShotJobForAgent(eventName, msg, agentID, timeout, delay); err != nil {
-			log.Printf("Failed to create job: %v", err)
-		}
-	} else {
-		if err := h.Gateway.CreateOneShotJob(eventName, msg, timeout, delay); err != nil {
-			log.Printf("Failed to create job: %v", err)
-		}
+	key := ev.rateKey()
+	if !h.Limiter.AllowWithin(key, config.RateWindow(h.Config.GitHub.RateLimit)) {
+		log.Printf("GitHub: rate limited %s", key)
+		events.Annotate(ctx, "", "", events.OutcomeRateLimited)
+		w.WriteHeader(http.StatusOK)
+		return
 	}
 
+	log.Printf("GitHub: processing %s/%s for %s", ev.Event, ev.Action, ev.Repository)
+
+	msg := gateway.RenderMessage("github", h.Config.GitHub.ResolvedTemplate(ev.Event), h.templateData(ctx, ev))
+	timeout, delay := jobTiming(h.Config, config.RuleAction{}, h.Config.GitHub.Timeout, h.Config.GitHub.Delay)
+
+	job := gateway.JobSpec{Name: ev.jobName(), Message: msg, AgentID: h.Config.GitHub.AgentID, TimeoutSeconds: timeout, DelaySeconds: delay, Source: "github", Payload: ev.Body}
+	createJob(ctx, h.Gateway, job, "")
+
 	w.WriteHeader(http.StatusOK)
 	w.Write([]byte(`{"ok":true}`))
 }
 
-func renderGitHubMessage(tmplStr string, data map[string]interface{}) string {
-	tmpl, err := template.New("github").Parse(tmplStr)
-	if err != nil {
-		log.Printf("GitHub message template parse error: %v", err)
-		return tmplStr
-	}
-	var buf bytes.Buffer
-	if err := tmpl.Execute(&buf, data); err != nil {
-		log.Printf("GitHub message template exec error: %v", err)
-		return tmplStr
+// maxReviewBodyChars caps review and review comment bodies in the prompt; the
+// full text stays in the payload.
+const maxReviewBodyChars = 2000
+
+// truncateText cuts s to n characters, marking the cut with an ellipsis.
+func truncateText(s string, n int) string {
+	if utf8.RuneCountInString(s) <= n {
+		return s
 	}
-	return buf.String()
+	return string([]rune(s)[:n]) + "…"
 }
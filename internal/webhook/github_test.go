@@ -7,12 +7,18 @@ import (
 	"crypto/sha256"
 	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 	"time"
+	"unicode/utf8"
 
 	"github.com/katalabut/openclaw-relay/internal/config"
+	"github.com/katalabut/openclaw-relay/internal/events"
+	"github.com/katalabut/openclaw-relay/internal/github"
+	"github.com/katalabut/openclaw-relay/internal/links"
 	"github.com/katalabut/openclaw-relay/internal/ratelimit"
 )
 
@@ -102,6 +108,31 @@ func TestServeHTTP_GitHub_PullRequestReview(t *testing.T) {
 	}
 }
 
+func TestServeHTTP_GitHub_PullRequestReviewBody(t *testing.T) {
+	gw := &mockGateway{}
+	h := newTestGitHubHandler(gw)
+
+	postGitHub(h, "pull_request_review", map[string]interface{}{
+		"action":       "submitted",
+		"repository":   map[string]string{"full_name": "user/repo"},
+		"pull_request": map[string]interface{}{"number": 42, "title": "Fix bug"},
+		"review": map[string]interface{}{
+			"state": "changes_requested",
+			"body":  "Please add a test for the nil case.",
+			"user":  map[string]string{"login": "alice"},
+		},
+	})
+	if len(gw.calls) != 1 {
+		t.Fatalf("expected 1 gateway call, got %d", len(gw.calls))
+	}
+	msg := gw.calls[0].Message
+	for _, want := range []string{"Review: changes_requested by alice", "Please add a test for the nil case."} {
+		if !strings.Contains(msg, want) {
+			t.Errorf("expected %q in message, got:\n%s", want, msg)
+		}
+	}
+}
+
 func TestServeHTTP_GitHub_WorkflowRun(t *testing.T) {
 	gw := &mockGateway{}
 	h := newTestGitHubHandler(gw)
@@ -265,6 +296,147 @@ func TestServeHTTP_GitHub_RateLimited(t *testing.T) {
 	}
 }
 
+func TestServeHTTP_GitHub_Redelivery(t *testing.T) {
+	gw := &mockGateway{}
+	h := newTestGitHubHandler(gw)
+	// A limiter window that has always passed, so only delivery IDs dedup
+	h.Limiter = ratelimit.New(context.Background(), time.Nanosecond)
+	h.Deliveries, _ = ratelimit.NewDeliveries("", 10)
+
+	body, _ := json.Marshal(map[string]interface{}{
+		"action":       "submitted",
+		"repository":   map[string]string{"full_name": "user/repo"},
+		"pull_request": map[string]interface{}{"number": 7, "title": "Test"},
+	})
+	send := func(delivery string) {
+		req := httptest.NewRequest("POST", "/webhook/github", bytes.NewReader(body))
+		req.Header.Set("X-GitHub-Event", "pull_request_review")
+		req.Header.Set("X-GitHub-Delivery", delivery)
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		if rec.Code != http.StatusOK {
+			t.Errorf("expected 200 for %s, got %d", delivery, rec.Code)
+		}
+		time.Sleep(time.Millisecond)
+	}
+	send("d-1")
+	send("d-1")
+	if len(gw.calls) != 1 {
+		t.Fatalf("expected the redelivery dropped, got %d calls", len(gw.calls))
+	}
+	send("d-2")
+	if len(gw.calls) != 2 {
+		t.Errorf("expected a new delivery to pass, got %d calls", len(gw.calls))
+	}
+}
+
+func TestServeHTTP_GitHub_RuleRateLimit(t *testing.T) {
+	gw := &mockGateway{}
+	h := newTestGitHubRulesHandler(gw)
+	h.Config.GitHub.RateLimit = "1h"
+	h.Config.GitHub.Rules[0].RateLimit = "10ms"
+
+	// The workflow_run rule's own window overrides the source's hour
+	postGitHub(h, "workflow_run", workflowRun("acme/api", "failure", "abc"))
+	postGitHub(h, "workflow_run", workflowRun("acme/api", "failure", "abc"))
+	if len(gw.calls) != 1 {
+		t.Fatalf("expected the repeat rate limited, got %d calls", len(gw.calls))
+	}
+	time.Sleep(20 * time.Millisecond)
+	postGitHub(h, "workflow_run", workflowRun("acme/api", "failure", "abc"))
+	if len(gw.calls) != 2 {
+		t.Errorf("expected the repeat to pass after the rule window, got %d calls", len(gw.calls))
+	}
+}
+
+func TestServeHTTP_GitHub_RuleMultipleActions(t *testing.T) {
+	gw := &mockGateway{}
+	h := newTestGitHubRulesHandler(gw)
+	h.Config.GitHub.Rules[0].Jobs = []config.RuleAction{
+		{MessageTemplate: "notify {{.Name}} {{.Conclusion}}"},
+		{AgentID: "triage", Delay: 60, MessageTemplate: "follow up on {{.HeadSHA}}", Tags: map[string]string{"kind": "followup"}},
+	}
+
+	postGitHub(h, "workflow_run", workflowRun("acme/api", "failure", "abc"))
+	if len(gw.calls) != 2 {
+		t.Fatalf("expected a job per action, got %d", len(gw.calls))
+	}
+	first, second := gw.calls[0], gw.calls[1]
+	if first.Message != "notify CI failure" || second.Message != "follow up on abc" {
+		t.Errorf("unexpected messages %q, %q", first.Message, second.Message)
+	}
+	if second.Name != first.Name+" #2" || second.Delay != 60 || second.Tags["kind"] != "followup" || first.Tags["kind"] != "" {
+		t.Errorf("unexpected jobs %+v", gw.calls)
+	}
+}
+
+func TestServeHTTP_GitHub_RuleContinue(t *testing.T) {
+	gw := &mockGateway{}
+	h := newTestGitHubRulesHandler(gw)
+	h.Config.GitHub.Rules[0].Continue = true
+	h.Config.GitHub.Rules = append(h.Config.GitHub.Rules, config.GitHubRule{
+		Event:  "workflow_run",
+		Action: config.RuleAction{AgentID: "audit", MessageTemplate: "audit {{.Repository}}"},
+	})
+
+	postGitHub(h, "workflow_run", workflowRun("acme/api", "failure", "abc"))
+	if len(gw.calls) != 2 || gw.calls[1].Message != "audit acme/api" {
+		t.Fatalf("expected the failure rule and the audit rule to fire, got %+v", gw.calls)
+	}
+
+	// Each rule that fired dedups the redelivery on its own
+	postGitHub(h, "workflow_run", workflowRun("acme/api", "failure", "abc"))
+	if len(gw.calls) != 2 {
+		t.Errorf("expected the redelivery rate limited for both rules, got %d jobs", len(gw.calls))
+	}
+
+	// A success only matches the catch-all rule
+	postGitHub(h, "workflow_run", workflowRun("acme/api", "success", "def"))
+	if len(gw.calls) != 3 || gw.calls[2].Message != "audit acme/api" {
+		t.Errorf("expected only the audit rule to fire, got %+v", gw.calls)
+	}
+}
+
+func TestServeHTTP_GitHub_History(t *testing.T) {
+	gw := &mockGateway{}
+	history, _ := events.NewHistory("", 0)
+	h := history.Wrap("github", newTestGitHubRulesHandler(gw))
+	post := func(payload map[string]interface{}) {
+		body, _ := json.Marshal(payload)
+		req := httptest.NewRequest("POST", "/webhook/github", bytes.NewReader(body))
+		req.Header.Set("X-GitHub-Event", "workflow_run")
+		h.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	post(workflowRun("acme/api", "failure", "abc"))
+	post(workflowRun("acme/api", "failure", "abc"))
+	post(workflowRun("acme/api", "success", "def"))
+
+	got := history.Query("github", time.Time{}, 0)
+	want := []string{events.OutcomeNoRule, events.OutcomeRateLimited, events.OutcomeMatched}
+	if len(got) != len(want) {
+		t.Fatalf("expected %d deliveries, got %+v", len(want), got)
+	}
+	for i, outcome := range want {
+		if got[i].Outcome != outcome || got[i].EventType != "workflow_run/completed" {
+			t.Errorf("delivery %d: expected %s, got %+v", i, outcome, got[i])
+		}
+	}
+	if got[2].Rule != "github.rules[0]" || got[1].Rule != "github.rules[0]" || got[0].Rule != "" {
+		t.Errorf("unexpected rules %+v", got)
+	}
+	var stages []string
+	for _, s := range got[2].Timeline {
+		stages = append(stages, s.Stage)
+	}
+	if strings.Join(stages, " ") != "received verified matched queued delivered responded" {
+		t.Fatalf("timeline = %+v", got[2].Timeline)
+	}
+	if tl := got[2].Timeline; tl[1].Detail != "no secret configured" || tl[3].JobID == "" || tl[4].JobID != tl[3].JobID {
+		t.Errorf("timeline = %+v", tl)
+	}
+}
+
 func TestServeHTTP_GitHub_MethodNotAllowed(t *testing.T) {
 	gw := &mockGateway{}
 	h := newTestGitHubHandler(gw)
@@ -277,3 +449,375 @@ func TestServeHTTP_GitHub_MethodNotAllowed(t *testing.T) {
 		t.Errorf("expected 405, got %d", rec.Code)
 	}
 }
+
+func newTestGitHubRulesHandler(gw *mockGateway) *GitHubHandler {
+	h := newTestGitHubHandler(gw)
+	h.Config.GitHub.AgentID = "ci"
+	h.Config.GitHub.Rules = []config.GitHubRule{
+		{
+			Event:       "workflow_run",
+			Actions:     []string{"completed"},
+			Repos:       []string{"acme/*"},
+			Conclusions: []string{"failure", "timed_out"},
+			Action: config.RuleAction{
+				Timeout:         300,
+				MessageTemplate: "{{.Name}} {{.Conclusion}} on {{.Repository}} ({{.HeadSHA}}) by {{.Sender}}",
+			},
+		},
+		{
+			Event:   "pull_request_review",
+			Actions: []string{"submitted"},
+			Action:  config.RuleAction{AgentID: "reviewer"},
+		},
+	}
+	return h
+}
+
+func postGitHub(h *GitHubHandler, event string, payload map[string]interface{}) *httptest.ResponseRecorder {
+	body, _ := json.Marshal(payload)
+	req := httptest.NewRequest("POST", "/webhook/github", bytes.NewReader(body))
+	req.Header.Set("X-GitHub-Event", event)
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return rec
+}
+
+func workflowRun(repo, conclusion, sha string) map[string]interface{} {
+	return map[string]interface{}{
+		"action":     "completed",
+		"repository": map[string]string{"full_name": repo},
+		"sender":     map[string]string{"login": "octocat"},
+		"workflow_run": map[string]interface{}{
+			"name":       "CI",
+			"conclusion": conclusion,
+			"head_sha":   sha,
+		},
+	}
+}
+
+type fakeArtifacts struct {
+	runID int64
+	err   error
+}
+
+func (f *fakeArtifacts) WorkflowRunArtifacts(ctx context.Context, repo string, runID int64) ([]github.Artifact, error) {
+	f.runID = runID
+	if f.err != nil {
+		return nil, f.err
+	}
+	return []github.Artifact{{ID: 7, Name: "test-report", SizeInBytes: 2048}}, nil
+}
+
+func TestServeHTTP_GitHub_WorkflowRunArtifacts(t *testing.T) {
+	gw := &mockGateway{}
+	h := newTestGitHubHandler(gw)
+	lister := &fakeArtifacts{}
+	h.Artifacts = lister
+
+	payload := workflowRun("acme/api", "failure", "abc123")
+	run := payload["workflow_run"].(map[string]interface{})
+	run["id"] = 100
+	run["html_url"] = "https://github.com/acme/api/actions/runs/100"
+	postGitHub(h, "workflow_run", payload)
+
+	if lister.runID != 100 {
+		t.Errorf("expected artifacts listed for run 100, got %d", lister.runID)
+	}
+	if len(gw.calls) != 1 {
+		t.Fatalf("expected 1 gateway call, got %d", len(gw.calls))
+	}
+	if want := "- test-report: https://github.com/acme/api/actions/runs/100/artifacts/7"; !strings.Contains(gw.calls[0].Message, want) {
+		t.Errorf("expected %q in message, got:\n%s", want, gw.calls[0].Message)
+	}
+
+	// A failed listing still creates the job, without artifacts
+	gw = &mockGateway{}
+	h = newTestGitHubHandler(gw)
+	h.Artifacts = &fakeArtifacts{err: errors.New("boom")}
+	postGitHub(h, "workflow_run", payload)
+	if len(gw.calls) != 1 || strings.Contains(gw.calls[0].Message, "Artifacts") {
+		t.Errorf("expected a job without artifacts, got %+v", gw.calls)
+	}
+}
+
+func TestServeHTTP_GitHub_RulesMatch(t *testing.T) {
+	gw := &mockGateway{}
+	h := newTestGitHubRulesHandler(gw)
+
+	rec := postGitHub(h, "workflow_run", workflowRun("acme/api", "failure", "abc123"))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rec.Code)
+	}
+	if len(gw.calls) != 1 {
+		t.Fatalf("expected 1 gateway call, got %d", len(gw.calls))
+	}
+	if gw.calls[0].Message != "CI failure on acme/api (abc123) by octocat" {
+		t.Errorf("unexpected message: %q", gw.calls[0].Message)
+	}
+	if gw.calls[0].Timeout != 300 || gw.calls[0].Delay != 2 {
+		t.Errorf("expected rule timeout and default delay, got %d/%d", gw.calls[0].Timeout, gw.calls[0].Delay)
+	}
+
+	// Without a PR, different commits are not deduplicated against each other
+	postGitHub(h, "workflow_run", workflowRun("acme/api", "timed_out", "def456"))
+	if len(gw.calls) != 2 {
+		t.Errorf("expected second commit to dispatch, got %d calls", len(gw.calls))
+	}
+}
+
+func TestServeHTTP_GitHub_RulesFilters(t *testing.T) {
+	gw := &mockGateway{}
+	h := newTestGitHubRulesHandler(gw)
+
+	postGitHub(h, "workflow_run", workflowRun("acme/api", "success", "1"))  // conclusion filter
+	postGitHub(h, "workflow_run", workflowRun("other/api", "failure", "2")) // repo filter
+	postGitHub(h, "check_run", workflowRun("acme/api", "failure", "3"))     // no rule for event
+	inProgress := workflowRun("acme/api", "failure", "4")
+	inProgress["action"] = "in_progress"
+	postGitHub(h, "workflow_run", inProgress) // action filter
+
+	if len(gw.calls) != 0 {
+		t.Errorf("expected no gateway calls, got %d", len(gw.calls))
+	}
+}
+
+func TestServeHTTP_GitHub_RulesCondition(t *testing.T) {
+	gw := &mockGateway{}
+	h := newTestGitHubRulesHandler(gw)
+	h.Config.GitHub.Rules[0].Condition = "branch == 'main' && payload.sender.login !~ '\\[bot\\]$'"
+
+	run := func(branch, sender, sha string) map[string]interface{} {
+		p := workflowRun("acme/api", "failure", sha)
+		p["workflow_run"].(map[string]interface{})["head_branch"] = branch
+		p["sender"] = map[string]string{"login": sender}
+		return p
+	}
+	postGitHub(h, "workflow_run", run("feature", "octocat", "1"))
+	postGitHub(h, "workflow_run", run("main", "dependabot[bot]", "2"))
+	if len(gw.calls) != 0 {
+		t.Fatalf("expected condition to filter both runs, got %d calls", len(gw.calls))
+	}
+	postGitHub(h, "workflow_run", run("main", "octocat", "3"))
+	if len(gw.calls) != 1 {
+		t.Errorf("expected main-branch run to dispatch, got %d calls", len(gw.calls))
+	}
+}
+
+func TestServeHTTP_GitHub_RulesDefaultTemplate(t *testing.T) {
+	gw := &mockGateway{}
+	h := newTestGitHubRulesHandler(gw)
+
+	postGitHub(h, "pull_request_review", map[string]interface{}{
+		"action":       "submitted",
+		"repository":   map[string]string{"full_name": "user/repo"},
+		"pull_request": map[string]interface{}{"number": 42, "title": "Fix bug"},
+	})
+	if len(gw.calls) != 1 {
+		t.Fatalf("expected 1 gateway call, got %d", len(gw.calls))
+	}
+	if !strings.Contains(gw.calls[0].Message, "PR: #42") || !strings.Contains(gw.calls[0].Message, "Title: Fix bug") {
+		t.Errorf("expected default template, got %q", gw.calls[0].Message)
+	}
+	if gw.calls[0].Name != "github pull_request_review/submitted PR#42" {
+		t.Errorf("unexpected job name: %q", gw.calls[0].Name)
+	}
+}
+
+func TestMatchRepo(t *testing.T) {
+	tests := []struct {
+		patterns []string
+		repo     string
+		want     bool
+	}{
+		{[]string{"acme/api"}, "acme/api", true},
+		{[]string{"Acme/API"}, "acme/api", true},
+		{[]string{"acme/*"}, "acme/web", true},
+		{[]string{"acme/*"}, "other/web", false},
+		{[]string{"x/y", "*/web"}, "acme/web", true},
+	}
+	for _, tt := range tests {
+		if got := matchRepo(tt.patterns, tt.repo); got != tt.want {
+			t.Errorf("matchRepo(%v, %q) = %v, want %v", tt.patterns, tt.repo, got, tt.want)
+		}
+	}
+}
+
+func issueComment(id int, body, senderType string, onPR bool) map[string]interface{} {
+	issue := map[string]interface{}{"number": 7, "title": "Crash on start", "html_url": "https://github.com/acme/api/issues/7"}
+	if onPR {
+		issue["pull_request"] = map[string]string{"url": "https://api.github.com/repos/acme/api/pulls/7"}
+	}
+	return map[string]interface{}{
+		"action":     "created",
+		"repository": map[string]string{"full_name": "acme/api"},
+		"sender":     map[string]string{"login": "octocat", "type": senderType},
+		"issue":      issue,
+		"comment":    map[string]interface{}{"id": id, "body": body, "user": map[string]string{"login": "octocat"}},
+	}
+}
+
+func TestServeHTTP_GitHub_OptionalEventsDisabled(t *testing.T) {
+	gw := &mockGateway{}
+	h := newTestGitHubHandler(gw)
+
+	postGitHub(h, "issue_comment", issueComment(1, "hi", "User", false))
+	postGitHub(h, "issues", map[string]interface{}{"action": "opened", "issue": map[string]interface{}{"number": 1}})
+	if len(gw.calls) != 0 {
+		t.Errorf("expected optional events to be ignored by default, got %d calls", len(gw.calls))
+	}
+}
+
+func TestServeHTTP_GitHub_IssueComment(t *testing.T) {
+	gw := &mockGateway{}
+	h := newTestGitHubHandler(gw)
+	h.Config.GitHub.Events = []string{"issue_comment"}
+
+	postGitHub(h, "issue_comment", issueComment(100, "Still broken", "User", false))
+	if len(gw.calls) != 1 {
+		t.Fatalf("expected 1 gateway call, got %d", len(gw.calls))
+	}
+	msg := gw.calls[0].Message
+	if !strings.Contains(msg, "Issue: #7 Crash on start") || !strings.Contains(msg, "Comment by octocat:\nStill broken") {
+		t.Errorf("expected issue template, got %q", msg)
+	}
+	if gw.calls[0].Name != "github issue_comment/created #7" {
+		t.Errorf("unexpected job name: %q", gw.calls[0].Name)
+	}
+
+	// Each comment has its own key; the same delivery again is deduplicated
+	postGitHub(h, "issue_comment", issueComment(101, "And again", "User", true))
+	postGitHub(h, "issue_comment", issueComment(101, "And again", "User", true))
+	if len(gw.calls) != 2 {
+		t.Fatalf("expected 2 gateway calls, got %d", len(gw.calls))
+	}
+	if gw.calls[1].Name != "github issue_comment/created PR#7" {
+		t.Errorf("expected PR comment job name, got %q", gw.calls[1].Name)
+	}
+
+	// Bot comments (e.g. the agent's own replies) are skipped
+	postGitHub(h, "issue_comment", issueComment(102, "Done", "Bot", false))
+	if len(gw.calls) != 2 {
+		t.Errorf("expected bot comment to be ignored, got %d calls", len(gw.calls))
+	}
+}
+
+func TestServeHTTP_GitHub_PullRequestEvents(t *testing.T) {
+	gw := &mockGateway{}
+	h := newTestGitHubHandler(gw)
+	h.Config.GitHub.Events = []string{"pull_request"}
+	h.Config.GitHub.Templates = map[string]string{"pull_request": "{{.Action}} #{{.PRNumber}} merged={{.Merged}}"}
+
+	pr := func(action, sha string, merged bool) map[string]interface{} {
+		return map[string]interface{}{
+			"action":       action,
+			"repository":   map[string]string{"full_name": "acme/api"},
+			"pull_request": map[string]interface{}{"number": 9, "merged": merged, "head": map[string]string{"sha": sha}},
+		}
+	}
+	postGitHub(h, "pull_request", pr("opened", "a1", false))
+	postGitHub(h, "pull_request", pr("synchronize", "b2", false))
+	postGitHub(h, "pull_request", pr("synchronize", "c3", false)) // new push, new key
+	postGitHub(h, "pull_request", pr("labeled", "c3", false))     // action not handled
+	postGitHub(h, "pull_request", pr("closed", "c3", true))
+
+	want := []string{"opened #9 merged=false", "synchronize #9 merged=false", "synchronize #9 merged=false", "closed #9 merged=true"}
+	if len(gw.calls) != len(want) {
+		t.Fatalf("expected %d gateway calls, got %d", len(want), len(gw.calls))
+	}
+	for i, w := range want {
+		if gw.calls[i].Message != w {
+			t.Errorf("call %d: expected %q, got %q", i, w, gw.calls[i].Message)
+		}
+	}
+}
+
+func TestGitHubEvent_RateKey(t *testing.T) {
+	tests := []struct {
+		ev   githubEvent
+		want string
+	}{
+		{githubEvent{Event: "issues", Action: "opened", Repository: "a/b", IssueNumber: 3}, "github:issues:a/b#3:opened"},
+		{githubEvent{Event: "issue_comment", Repository: "a/b", CommentID: 55}, "github:issue_comment:a/b:55"},
+		{githubEvent{Event: "pull_request_review_comment", Repository: "a/b", PRNumber: 4, CommentID: 56}, "github:pull_request_review_comment:a/b:56"},
+		{githubEvent{Event: "pull_request", Action: "synchronize", Repository: "a/b", PRNumber: 4, HeadSHA: "f00"}, "github:pull_request:a/b#4:synchronize:f00"},
+		{githubEvent{Event: "check_run", Repository: "a/b", PRNumber: 4}, "github:check_run:a/b:4"},
+		{githubEvent{Event: "workflow_run", Repository: "a/b", HeadSHA: "f00"}, "github:workflow_run:a/b:f00"},
+	}
+	for _, tt := range tests {
+		if got := tt.ev.rateKey(); got != tt.want {
+			t.Errorf("rateKey() = %q, want %q", got, tt.want)
+		}
+	}
+}
+
+func TestServeHTTP_GitHub_LinkedTrelloCard(t *testing.T) {
+	store, _ := links.NewStore("")
+	store.Put(links.Link{CardID: "card1", CardName: "Fix login", List: "In Review", Repo: "acme/api", Branch: "fix-login"})
+	store.Put(links.Link{CardID: "card2", CardName: "Crash", Repo: "acme/api", PR: 7})
+
+	gw := &mockGateway{}
+	h := newTestGitHubHandler(gw)
+	h.Links = store
+	h.Config.GitHub.Events = []string{"issue_comment"}
+
+	// workflow_run carries only the branch
+	run := workflowRun("acme/api", "failure", "abc")
+	run["workflow_run"].(map[string]interface{})["head_branch"] = "fix-login"
+	postGitHub(h, "workflow_run", run)
+	// PR comments resolve by PR number
+	postGitHub(h, "issue_comment", issueComment(1, "please fix", "User", true))
+	// Unlinked branches render without a card line
+	other := workflowRun("acme/api", "failure", "def")
+	other["workflow_run"].(map[string]interface{})["head_branch"] = "main"
+	other["workflow_run"].(map[string]interface{})["pull_requests"] = []map[string]int{{"number": 99}}
+	postGitHub(h, "workflow_run", other)
+
+	if len(gw.calls) != 3 {
+		t.Fatalf("expected 3 gateway calls, got %d", len(gw.calls))
+	}
+	if !strings.Contains(gw.calls[0].Message, "Trello card: Fix login (card1) in In Review") {
+		t.Errorf("expected linked card in CI prompt, got:\n%s", gw.calls[0].Message)
+	}
+	if !strings.Contains(gw.calls[1].Message, "Trello card: Crash (card2)") || strings.Contains(gw.calls[1].Message, " in ") {
+		t.Errorf("expected linked card without list in comment prompt, got:\n%s", gw.calls[1].Message)
+	}
+	if strings.Contains(gw.calls[2].Message, "Trello card") {
+		t.Errorf("expected no card for unlinked branch, got:\n%s", gw.calls[2].Message)
+	}
+}
+
+func TestParseGitHubEvent_Review(t *testing.T) {
+	long := strings.Repeat("é", maxReviewBodyChars+10)
+	ev := parseGitHubEvent("pull_request_review", []byte(`{"action":"submitted","pull_request":{"number":7},
+		"review":{"state":"approved","body":"  `+long+`  ","html_url":"https://github.com/a/b/pull/7#pullrequestreview-1","user":{"login":"bob"}}}`))
+	if ev.ReviewState != "approved" || ev.ReviewAuthor != "bob" {
+		t.Errorf("unexpected review state=%q author=%q", ev.ReviewState, ev.ReviewAuthor)
+	}
+	if want := strings.Repeat("é", maxReviewBodyChars) + "…"; ev.ReviewBody != want {
+		t.Errorf("expected the body truncated to %d characters, got %d", maxReviewBodyChars, utf8.RuneCountInString(ev.ReviewBody))
+	}
+	if ev.conditionEnv()["review_state"] != "approved" {
+		t.Error("expected review_state in the condition env")
+	}
+
+	ev = parseGitHubEvent("pull_request_review_comment", []byte(`{"action":"created","pull_request":{"number":7},
+		"comment":{"id":9,"body":"`+long+`","path":"main.go","user":{"login":"bob"}}}`))
+	if ev.CommentPath != "main.go" || utf8.RuneCountInString(ev.Comment) != maxReviewBodyChars+1 {
+		t.Errorf("unexpected review comment path=%q length=%d", ev.CommentPath, utf8.RuneCountInString(ev.Comment))
+	}
+}
+
+func TestParseGitHubEvent_Branch(t *testing.T) {
+	tests := []struct {
+		event, body, want string
+	}{
+		{"pull_request", `{"pull_request":{"number":1,"head":{"ref":"feature-x"}}}`, "feature-x"},
+		{"workflow_run", `{"workflow_run":{"head_branch":"main"}}`, "main"},
+		{"check_run", `{"check_run":{"check_suite":{"head_branch":"release"}}}`, "release"},
+	}
+	for _, tt := range tests {
+		if got := parseGitHubEvent(tt.event, []byte(tt.body)).Branch; got != tt.want {
+			t.Errorf("%s: Branch = %q, want %q", tt.event, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,156 @@
+package webhook
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+var updateGolden = flag.Bool("update", false, "rewrite testdata/payloads/**/*.golden.json from the current parsers")
+
+// goldenParsers decode a provider payload into what the handler goes on to
+// match rules and render templates with. key is the event header the provider
+// sends alongside the body (X-GitHub-Event, X-Event-Key, Sentry-Hook-Resource).
+var goldenParsers = map[string]func(t *testing.T, key string, body []byte) any{
+	"github": func(t *testing.T, key string, body []byte) any {
+		return parseGitHubEvent(key, body)
+	},
+	"trello": func(t *testing.T, _ string, body []byte) any {
+		var p trelloPayload
+		mustDecode(t, body, &p)
+		d := p.Action.Data
+		return map[string]any{
+			"ActionID":   p.Action.ID,
+			"Type":       p.Action.Type,
+			"CardID":     d.Card.ID,
+			"Card":       p.card(d.ListAfter.Name),
+			"ListBefore": d.ListBefore,
+			"ListAfter":  d.ListAfter,
+			"Member":     p.Action.MemberCreator,
+			"Text":       d.Text,
+		}
+	},
+	"bitbucket": func(t *testing.T, key string, body []byte) any {
+		var p bitbucketPayload
+		mustDecode(t, body, &p)
+		return bitbucketEvents(key, &p)
+	},
+	"sentry": func(t *testing.T, key string, body []byte) any {
+		var p sentryWebhook
+		mustDecode(t, body, &p)
+		a, ok := parseSentryAlert(key, &p)
+		if !ok {
+			t.Fatal("not a Sentry issue alert")
+		}
+		return a
+	},
+	"alertmanager": func(t *testing.T, _ string, body []byte) any {
+		var p alertmanagerPayload
+		mustDecode(t, body, &p)
+		return map[string]any{"Payload": p, "Fingerprint": alertmanagerFingerprint(p)}
+	},
+	"jira": func(t *testing.T, _ string, body []byte) any {
+		var p jiraPayload
+		mustDecode(t, body, &p)
+		return p
+	},
+	"slack": func(t *testing.T, _ string, body []byte) any {
+		var p slackPayload
+		mustDecode(t, body, &p)
+		return p
+	},
+}
+
+// TestGoldenPayloads decodes real provider payloads and compares the result,
+// and the delivery's event time, with a checked-in golden file, so a provider
+// renaming or moving a field shows up as a diff instead of silently empty
+// template fields. Run with -update after an intended parser change.
+func TestGoldenPayloads(t *testing.T) {
+	tests := []struct {
+		source  string
+		fixture string
+		key     string
+	}{
+		{"github", "pull_request_opened", "pull_request"},
+		{"github", "issue_comment_on_pr", "issue_comment"},
+		{"github", "pull_request_review_submitted", "pull_request_review"},
+		{"github", "workflow_run_completed", "workflow_run"},
+		{"github", "check_run_completed", "check_run"},
+		{"trello", "card_moved", ""},
+		{"trello", "comment_card", ""},
+		{"bitbucket", "repo_push", "repo:push"},
+		{"bitbucket", "pullrequest_created", "pullrequest:created"},
+		{"bitbucket", "pullrequest_approved", "pullrequest:approved"},
+		{"sentry", "event_alert", "event_alert"},
+		{"sentry", "issue_created", "issue"},
+		{"sentry", "legacy_plugin", ""},
+		{"alertmanager", "firing", ""},
+		{"jira", "issue_updated", ""},
+		{"jira", "comment_created", ""},
+		{"slack", "message", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.source+"/"+tt.fixture, func(t *testing.T) {
+			dir := filepath.Join("testdata", "payloads", tt.source)
+			body, err := os.ReadFile(filepath.Join(dir, tt.fixture+".json"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := map[string]any{"Event": goldenParsers[tt.source](t, tt.key, body)}
+			if ts, ok := EventTime(tt.source, body); ok {
+				got["Time"] = ts.UTC().Format(time.RFC3339Nano)
+			}
+			data, err := json.MarshalIndent(got, "", "  ")
+			if err != nil {
+				t.Fatal(err)
+			}
+			data = append(data, '\n')
+
+			golden := filepath.Join(dir, tt.fixture+".golden.json")
+			if *updateGolden {
+				if err := os.WriteFile(golden, data, 0644); err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			want, err := os.ReadFile(golden)
+			if err != nil {
+				t.Fatalf("%v (run go test ./internal/webhook -run TestGoldenPayloads -update)", err)
+			}
+			if !bytes.Equal(data, want) {
+				t.Errorf("parsed %s differs from %s:\n%s", tt.fixture, golden, lineDiff(string(want), string(data)))
+			}
+		})
+	}
+}
+
+func mustDecode(t *testing.T, body []byte, v any) {
+	t.Helper()
+	if err := json.Unmarshal(body, v); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+}
+
+// lineDiff lists the lines that differ between want and got, by position.
+func lineDiff(want, got string) string {
+	w, g := strings.Split(want, "\n"), strings.Split(got, "\n")
+	var b strings.Builder
+	for i := 0; i < len(w) || i < len(g); i++ {
+		var wl, gl string
+		if i < len(w) {
+			wl = w[i]
+		}
+		if i < len(g) {
+			gl = g[i]
+		}
+		if wl != gl {
+			b.WriteString("- " + wl + "\n+ " + gl + "\n")
+		}
+	}
+	return b.String()
+}
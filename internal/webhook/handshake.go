@@ -0,0 +1,55 @@
+package webhook
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/katalabut/openclaw-relay/internal/audit"
+	"github.com/katalabut/openclaw-relay/internal/events"
+)
+
+// Handshake kinds: requests a provider sends to verify an endpoint rather
+// than to deliver an event.
+const (
+	HandshakeHead              = "head"               // Trello checks a callback URL with HEAD
+	HandshakePing              = "ping"               // GitHub's ping event, Discord's PING interaction
+	HandshakeURLVerification   = "url_verification"   // Slack's challenge, echoed back
+	HandshakeHookSecret        = "hook_secret"        // Asana's X-Hook-Secret, echoed back and kept
+	HandshakeVerificationToken = "verification_token" // Notion's subscription token
+)
+
+// Handshakes answers HEAD requests to source's endpoint before next sees
+// them: Trello sends one to check a callback URL before it creates a
+// webhook, and other providers probe with them. It goes inside History,
+// outside the pause and expiry checks, so a paused source still passes the
+// check. Handshakes a provider signs or that carry a secret are answered by
+// the source's handler with handshake, after its signature check.
+func Handshakes(source string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodHead {
+			handshake(w, r, source, HandshakeHead, nil)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// handshake answers a verification request of the given kind with reply,
+// or an empty 200 when reply is nil, and records it: as outcome handshake
+// in the delivery history, in the request's audit entry and in the log.
+func handshake(w http.ResponseWriter, r *http.Request, source, kind string, reply func(http.ResponseWriter)) {
+	markHandshake(r, kind, events.OutcomeHandshake)
+	log.Printf("%s: %s handshake answered", source, kind)
+	if reply == nil {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	reply(w)
+}
+
+// markHandshake records a handshake with outcome, for handshakes that are
+// refused rather than answered.
+func markHandshake(r *http.Request, kind, outcome string) {
+	events.Annotate(r.Context(), kind, "", outcome)
+	audit.MarkHandshake(r.Context(), kind)
+}
@@ -0,0 +1,95 @@
+package webhook
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/katalabut/openclaw-relay/internal/events"
+)
+
+func TestHandshakes(t *testing.T) {
+	history, _ := events.NewHistory("", 0)
+	reached := 0
+	h := history.Wrap("jira", Handshakes("jira", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		reached++
+		w.WriteHeader(http.StatusAccepted)
+	})))
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest("HEAD", "/webhook/jira", nil))
+	if rec.Code != http.StatusOK || reached != 0 {
+		t.Errorf("HEAD: %d, handler reached %d times", rec.Code, reached)
+	}
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest("POST", "/webhook/jira", nil))
+	if rec.Code != http.StatusAccepted || reached != 1 {
+		t.Errorf("POST: %d, handler reached %d times", rec.Code, reached)
+	}
+
+	got := history.Query("jira", time.Time{}, 0) // newest first
+	if len(got) != 2 || got[1].Outcome != events.OutcomeHandshake || got[1].EventType != HandshakeHead || got[0].Outcome == events.OutcomeHandshake {
+		t.Errorf("history = %+v", got)
+	}
+}
+
+func TestHandshake_GitHubPing(t *testing.T) {
+	gw := &mockGateway{}
+	history, _ := events.NewHistory("", 0)
+	gh := newTestGitHubHandler(gw)
+	gh.Config.GitHub.Secret = "secret"
+	h := history.Wrap("github", gh)
+	ping := func(sig string) int {
+		body := []byte(`{"zen":"Keep it logically awesome.","hook_id":1}`)
+		req := httptest.NewRequest("POST", "/webhook/github", bytes.NewReader(body))
+		req.Header.Set("X-GitHub-Event", "ping")
+		if sig == "" {
+			mac := hmac.New(sha256.New, []byte("secret"))
+			mac.Write(body)
+			sig = "sha256=" + hex.EncodeToString(mac.Sum(nil))
+		}
+		req.Header.Set("X-Hub-Signature-256", sig)
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	if code := ping(""); code != http.StatusOK {
+		t.Errorf("signed ping: %d", code)
+	}
+	if code := ping("sha256=bad"); code != http.StatusForbidden {
+		t.Errorf("unsigned ping: %d", code)
+	}
+	got := history.Query("github", time.Time{}, 0) // newest first
+	if len(got) != 2 || got[1].Outcome != events.OutcomeHandshake || got[1].EventType != HandshakePing || got[0].Outcome != events.OutcomeRejected {
+		t.Errorf("history = %+v", got)
+	}
+	if len(gw.calls) != 0 {
+		t.Errorf("ping created jobs: %+v", gw.calls)
+	}
+}
+
+func TestHandshake_SlackHistory(t *testing.T) {
+	history, _ := events.NewHistory("", 0)
+	sh := newTestSlackHandler(&mockGateway{})
+	sh.Config.Slack.SigningSecret = "secret"
+	h := history.Wrap("slack", sh)
+
+	body := []byte(`{"type":"url_verification","challenge":"abc123"}`)
+	ts := strconv.FormatInt(time.Now().Unix(), 10)
+	req := httptest.NewRequest("POST", "/webhook/slack", bytes.NewReader(body))
+	req.Header.Set("X-Slack-Request-Timestamp", ts)
+	req.Header.Set("X-Slack-Signature", signSlack(body, ts, "secret"))
+	h.ServeHTTP(httptest.NewRecorder(), req)
+
+	got := history.Query("slack", time.Time{}, 0)
+	if len(got) != 1 || got[0].Outcome != events.OutcomeHandshake || got[0].EventType != HandshakeURLVerification {
+		t.Errorf("history = %+v", got)
+	}
+}
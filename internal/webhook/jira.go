@@ -0,0 +1,234 @@
+package webhook
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/katalabut/openclaw-relay/internal/config"
+	"github.com/katalabut/openclaw-relay/internal/events"
+	"github.com/katalabut/openclaw-relay/internal/gateway"
+	"github.com/katalabut/openclaw-relay/internal/ratelimit"
+)
+
+type JiraHandler struct {
+	Config  *config.Config
+	Gateway gateway.GatewayClient
+	Limiter *ratelimit.Limiter
+}
+
+type jiraUser struct {
+	AccountID   string `json:"accountId"`
+	DisplayName string `json:"displayName"`
+}
+
+type jiraPayload struct {
+	WebhookEvent string   `json:"webhookEvent"`
+	User         jiraUser `json:"user"`
+	Issue        struct {
+		Key    string `json:"key"`
+		Self   string `json:"self"`
+		Fields struct {
+			Summary string `json:"summary"`
+			Status  struct {
+				Name string `json:"name"`
+			} `json:"status"`
+			Project struct {
+				Key string `json:"key"`
+			} `json:"project"`
+			IssueType struct {
+				Name string `json:"name"`
+			} `json:"issuetype"`
+			Priority struct {
+				Name string `json:"name"`
+			} `json:"priority"`
+			Assignee *jiraUser `json:"assignee"`
+		} `json:"fields"`
+	} `json:"issue"`
+	Changelog struct {
+		Items []struct {
+			Field      string `json:"field"`
+			FromString string `json:"fromString"`
+			ToString   string `json:"toString"`
+		} `json:"items"`
+	} `json:"changelog"`
+	Comment struct {
+		ID     string   `json:"id"`
+		Body   string   `json:"body"`
+		Author jiraUser `json:"author"`
+	} `json:"comment"`
+}
+
+func (h *JiraHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, "bad request", http.StatusBadRequest)
+		return
+	}
+
+	if h.Config.Jira.Secret != "" && !signatureBypassed(r, "Jira") && !VerifyGitHubSignature(body, r.Header.Get("X-Hub-Signature"), h.Config.Jira.Secret) {
+		log.Printf("Jira signature verification failed")
+		http.Error(w, "forbidden", http.StatusForbidden)
+		return
+	}
+	events.Verified(r.Context(), h.Config.Jira.Secret != "")
+
+	var payload jiraPayload
+	if err := json.Unmarshal(body, &payload); err != nil {
+		log.Printf("Failed to parse Jira payload: %v", err)
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	issue := payload.Issue
+	status := issue.Fields.Status.Name
+	actor := payload.User
+
+	var eventType, fromStatus, dedup string
+	switch payload.WebhookEvent {
+	case "jira:issue_created":
+		eventType = "issue_created"
+	case "jira:issue_updated":
+		changed := false
+		for _, item := range payload.Changelog.Items {
+			if item.Field == "status" {
+				changed = true
+				fromStatus = item.FromString
+				status = item.ToString
+			}
+		}
+		if !changed {
+			log.Printf("Jira: ignoring issue_updated without status change for %s", issue.Key)
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+		eventType = "issue_updated"
+		dedup = status
+	case "comment_created":
+		eventType = "comment_created"
+		actor = payload.Comment.Author
+		dedup = payload.Comment.ID
+	default:
+		log.Printf("Jira: ignoring event %s", payload.WebhookEvent)
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	if h.isIgnoredUser(actor.AccountID) {
+		log.Printf("Jira: ignoring %s on %s from user %s", eventType, issue.Key, actor.DisplayName)
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	assignee := ""
+	if issue.Fields.Assignee != nil {
+		assignee = issue.Fields.Assignee.DisplayName
+	}
+	fields := map[string]string{
+		"project":     issue.Fields.Project.Key,
+		"status":      status,
+		"from_status": fromStatus,
+		"issue_type":  issue.Fields.IssueType.Name,
+		"priority":    issue.Fields.Priority.Name,
+		"assignee":    assignee,
+	}
+
+	rules := h.findRules(eventType, fields)
+	if len(rules) == 0 {
+		log.Printf("Jira: no matching rule for event=%s project=%s status=%s", eventType, fields["project"], status)
+		events.Annotate(r.Context(), eventType, "", events.OutcomeNoRule)
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	for i, rule := range rules {
+		ref := ruleRef("jira", h.Config.Jira.Rules, rule)
+		events.Annotate(r.Context(), eventType, ref, "")
+		data := map[string]interface{}{
+			"Event":         eventType,
+			"IssueKey":      issue.Key,
+			"Summary":       issue.Fields.Summary,
+			"Project":       fields["project"],
+			"Status":        status,
+			"FromStatus":    fromStatus,
+			"IssueType":     fields["issue_type"],
+			"Priority":      fields["priority"],
+			"Assignee":      assignee,
+			"User":          actor.DisplayName,
+			"Comment":       payload.Comment.Body,
+			"CommentAuthor": payload.Comment.Author.DisplayName,
+			"URL":           jiraBrowseURL(issue.Self, issue.Key),
+		}
+		if sampledOut("Jira", eventType, rule.Sample) {
+			events.Annotate(r.Context(), "", ref, events.OutcomeSampledOut)
+			continue
+		}
+		key := continued(dedupKey("jira", rule.DedupKey, data, fmt.Sprintf("jira:%s:%s:%s", issue.Key, eventType, dedup)), i, ref)
+		if !h.Limiter.AllowWithin(key, config.RateWindow(rule.RateLimit, h.Config.Jira.RateLimit)) {
+			log.Printf("Jira: rate limited %s", key)
+			events.Annotate(r.Context(), "", ref, events.OutcomeRateLimited)
+			continue
+		}
+
+		log.Printf("Jira: processing %s for %s", eventType, issue.Key)
+
+		tmplStr := rule.Action.MessageTemplate
+		if tmplStr == "" {
+			tmplStr = config.DefaultJiraMessageTemplate()
+		}
+		msg := gateway.RenderMessage("jira", tmplStr, data)
+
+		timeout, delay := jobTiming(h.Config, rule.Action, 0, 0)
+
+		eventName := continued(fmt.Sprintf("jira %s: %s", eventType, issue.Key), i, ref)
+		createJob(r.Context(), h.Gateway, ruleJob("jira", ref, rule.Action, eventName, msg, timeout, delay, body), ref)
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(`{"ok":true}`))
+}
+
+func (h *JiraHandler) findRules(eventType string, fields map[string]string) []*config.JiraRule {
+	var matched []*config.JiraRule
+	for i, rule := range h.Config.Jira.Rules {
+		if rule.Event != eventType {
+			continue
+		}
+		if rule.Project != "" && !strings.EqualFold(rule.Project, fields["project"]) {
+			continue
+		}
+		if evalGenericCondition(rule.Condition, fields) {
+			matched = append(matched, &h.Config.Jira.Rules[i])
+			if !rule.Continue {
+				return matched
+			}
+		}
+	}
+	return matched
+}
+
+func (h *JiraHandler) isIgnoredUser(accountID string) bool {
+	for _, ignored := range h.Config.Jira.IgnoreUsers {
+		if ignored == accountID {
+			return true
+		}
+	}
+	return false
+}
+
+// jiraBrowseURL turns the issue's REST "self" link into the human-facing /browse/ URL.
+func jiraBrowseURL(self, key string) string {
+	u, err := url.Parse(self)
+	if err != nil || u.Host == "" || key == "" {
+		return ""
+	}
+	return fmt.Sprintf("%s://%s/browse/%s", u.Scheme, u.Host, key)
+}
@@ -0,0 +1,193 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/katalabut/openclaw-relay/internal/config"
+	"github.com/katalabut/openclaw-relay/internal/ratelimit"
+)
+
+func newTestJiraHandler(gw *mockGateway) *JiraHandler {
+	cfg := &config.Config{
+		Jira: config.JiraConfig{
+			IgnoreUsers: []string{"bot-account"},
+			Rules: []config.JiraRule{
+				{
+					Event:     "issue_updated",
+					Project:   "OPS",
+					Condition: "status == 'In Review'",
+					Action:    config.RuleAction{MessageTemplate: "{{.IssueKey}}: {{.FromStatus}} -> {{.Status}} ({{.URL}})"},
+				},
+				{Event: "issue_created", Project: "OPS"},
+				{Event: "comment_created", Action: config.RuleAction{MessageTemplate: "{{.CommentAuthor}} on {{.IssueKey}}: {{.Comment}}"}},
+			},
+		},
+	}
+	return &JiraHandler{
+		Config:  cfg,
+		Gateway: gw,
+		Limiter: ratelimit.New(context.Background(), 5*time.Minute),
+	}
+}
+
+func jiraEvent(event, project, status string, extra map[string]any) []byte {
+	p := map[string]any{
+		"webhookEvent": event,
+		"user":         map[string]any{"accountId": "u1", "displayName": "Alice"},
+		"issue": map[string]any{
+			"key":  project + "-7",
+			"self": "https://acme.atlassian.net/rest/api/2/issue/10007",
+			"fields": map[string]any{
+				"summary":   "Fix login",
+				"status":    map[string]any{"name": status},
+				"project":   map[string]any{"key": project},
+				"issuetype": map[string]any{"name": "Bug"},
+			},
+		},
+	}
+	for k, v := range extra {
+		p[k] = v
+	}
+	body, _ := json.Marshal(p)
+	return body
+}
+
+func statusChange(from, to string) map[string]any {
+	return map[string]any{"changelog": map[string]any{"items": []map[string]any{
+		{"field": "status", "fromString": from, "toString": to},
+	}}}
+}
+
+func serveJira(h *JiraHandler, body []byte) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest("POST", "/webhook/jira", bytes.NewReader(body)))
+	return rec
+}
+
+func TestJiraHandler_StatusTransition(t *testing.T) {
+	gw := &mockGateway{}
+	h := newTestJiraHandler(gw)
+
+	rec := serveJira(h, jiraEvent("jira:issue_updated", "OPS", "In Review", statusChange("In Progress", "In Review")))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rec.Code)
+	}
+	if len(gw.calls) != 1 {
+		t.Fatalf("expected 1 gateway call, got %d", len(gw.calls))
+	}
+	if gw.calls[0].Message != "OPS-7: In Progress -> In Review (https://acme.atlassian.net/browse/OPS-7)" {
+		t.Errorf("unexpected message: %q", gw.calls[0].Message)
+	}
+	if gw.calls[0].Name != "jira issue_updated: OPS-7" {
+		t.Errorf("unexpected job name: %q", gw.calls[0].Name)
+	}
+
+	// Non-matching status, other project, and updates without a status change are ignored
+	serveJira(h, jiraEvent("jira:issue_updated", "OPS", "Done", statusChange("In Review", "Done")))
+	serveJira(h, jiraEvent("jira:issue_updated", "WEB", "In Review", statusChange("In Progress", "In Review")))
+	serveJira(h, jiraEvent("jira:issue_updated", "OPS", "In Review", map[string]any{"changelog": map[string]any{"items": []map[string]any{{"field": "summary"}}}}))
+	if len(gw.calls) != 1 {
+		t.Errorf("expected no further calls, got %d", len(gw.calls))
+	}
+}
+
+func TestJiraHandler_IssueCreatedDefaultTemplate(t *testing.T) {
+	gw := &mockGateway{}
+	h := newTestJiraHandler(gw)
+
+	serveJira(h, jiraEvent("jira:issue_created", "ops", "To Do", nil))
+	if len(gw.calls) != 1 {
+		t.Fatalf("expected 1 gateway call, got %d", len(gw.calls))
+	}
+	msg := gw.calls[0].Message
+	if !strings.Contains(msg, "Source: jira") || !strings.Contains(msg, "Issue: ops-7 Fix login") {
+		t.Errorf("expected default template, got %q", msg)
+	}
+	if gw.calls[0].Timeout != 120 || gw.calls[0].Delay != 2 {
+		t.Errorf("expected default timeout/delay, got %d/%d", gw.calls[0].Timeout, gw.calls[0].Delay)
+	}
+}
+
+func TestJiraHandler_Comments(t *testing.T) {
+	gw := &mockGateway{}
+	h := newTestJiraHandler(gw)
+
+	comment := func(id, author string) map[string]any {
+		return map[string]any{"comment": map[string]any{
+			"id": id, "body": "Looks good",
+			"author": map[string]any{"accountId": author, "displayName": "Bob"},
+		}}
+	}
+	serveJira(h, jiraEvent("comment_created", "WEB", "To Do", comment("c1", "u2")))
+	serveJira(h, jiraEvent("comment_created", "WEB", "To Do", comment("c1", "u2")))
+	serveJira(h, jiraEvent("comment_created", "WEB", "To Do", comment("c2", "bot-account")))
+
+	if len(gw.calls) != 1 {
+		t.Fatalf("expected duplicate and bot comments to be skipped, got %d calls", len(gw.calls))
+	}
+	if gw.calls[0].Message != "Bob on WEB-7: Looks good" {
+		t.Errorf("unexpected message: %q", gw.calls[0].Message)
+	}
+}
+
+func TestJiraHandler_Signature(t *testing.T) {
+	gw := &mockGateway{}
+	h := newTestJiraHandler(gw)
+	h.Config.Jira.Secret = "s3cret"
+	body := jiraEvent("jira:issue_created", "OPS", "To Do", nil)
+
+	req := httptest.NewRequest("POST", "/webhook/jira", bytes.NewReader(body))
+	req.Header.Set("X-Hub-Signature", "sha256=bad")
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if rec.Code != http.StatusForbidden {
+		t.Fatalf("expected 403, got %d", rec.Code)
+	}
+
+	mac := hmac.New(sha256.New, []byte("s3cret"))
+	mac.Write(body)
+	req = httptest.NewRequest("POST", "/webhook/jira", bytes.NewReader(body))
+	req.Header.Set("X-Hub-Signature", "sha256="+hex.EncodeToString(mac.Sum(nil)))
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK || len(gw.calls) != 1 {
+		t.Errorf("expected valid signature to dispatch, code=%d calls=%d", rec.Code, len(gw.calls))
+	}
+}
+
+func TestJiraHandler_IgnoredRequests(t *testing.T) {
+	gw := &mockGateway{}
+	h := newTestJiraHandler(gw)
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest("GET", "/webhook/jira", nil))
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("expected 405, got %d", rec.Code)
+	}
+	if rec := serveJira(h, []byte("nope")); rec.Code != http.StatusOK {
+		t.Errorf("expected 200 for bad JSON, got %d", rec.Code)
+	}
+	serveJira(h, jiraEvent("jira:issue_deleted", "OPS", "To Do", nil))
+	if len(gw.calls) != 0 {
+		t.Errorf("expected no gateway calls, got %d", len(gw.calls))
+	}
+}
+
+func TestJiraBrowseURL(t *testing.T) {
+	if got := jiraBrowseURL("https://acme.atlassian.net/rest/api/2/issue/1", "OPS-1"); got != "https://acme.atlassian.net/browse/OPS-1" {
+		t.Errorf("unexpected URL: %s", got)
+	}
+	if got := jiraBrowseURL("", "OPS-1"); got != "" {
+		t.Errorf("expected empty URL, got %s", got)
+	}
+}
@@ -0,0 +1,127 @@
+package webhook
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"text/template"
+
+	"github.com/katalabut/openclaw-relay/internal/config"
+	"github.com/katalabut/openclaw-relay/internal/events"
+	"github.com/katalabut/openclaw-relay/internal/gateway"
+	"github.com/katalabut/openclaw-relay/internal/schedule"
+)
+
+// ruleJob builds the job for a matched rule, tagged with the source, the
+// rule's name (when rules have names) and the action's tags. payload is the
+// delivery the job came from.
+func ruleJob(source, rule string, a config.RuleAction, name, msg string, timeout, delay int, payload []byte) gateway.JobSpec {
+	return gateway.JobSpec{
+		Name:           name,
+		Message:        msg,
+		AgentID:        a.AgentID,
+		TimeoutSeconds: timeout,
+		DelaySeconds:   delay,
+		Source:         source,
+		Rule:           rule,
+		Tags:           a.Tags,
+		Payload:        payload,
+	}
+}
+
+// jobTiming returns the timeout and delay in seconds of a rule action's job:
+// the action's own, else the source's (0 when the source has none), else the
+// RuleAction defaults. The action's schedule, when it has one, replaces the
+// delay.
+func jobTiming(cfg *config.Config, a config.RuleAction, timeout, delay int) (int, int) {
+	a.Timeout = firstNonZero(a.Timeout, timeout)
+	a.Delay = firstNonZero(a.Delay, delay)
+	return a.ResolvedTimeout(), schedule.ActionDelay(a.Schedule, a.Timezone, cfg.Server.Timezone, a.ResolvedDelay())
+}
+
+func firstNonZero(vals ...int) int {
+	for _, v := range vals {
+		if v != 0 {
+			return v
+		}
+	}
+	return 0
+}
+
+// actionJobName is the job name for the n-th action of a matched rule: the
+// first keeps name, later ones get "#2", "#3", ... so the gateway can tell
+// the jobs of one event apart.
+func actionJobName(name string, n int) string {
+	if n == 0 {
+		return name
+	}
+	return fmt.Sprintf("%s #%d", name, n+1)
+}
+
+// continued scopes a rate-limit key or job name to the i-th rule that fired
+// for one event. The first rule keeps s; rules reached through continue: true
+// get their ref appended, so they neither dedup against nor overwrite the
+// first rule's job.
+func continued(s string, i int, ref string) string {
+	if i == 0 {
+		return s
+	}
+	return fmt.Sprintf("%s [%s]", s, ref)
+}
+
+// dedupKey returns the rate-limit key of an event for a rule: the rule's
+// dedup_key template rendered with the event's message data and scoped to
+// source, or def, the source's built-in key, when the rule sets none. A
+// template that fails, names a missing field or renders empty falls back to
+// def too, so a typo never collapses unrelated events into one key.
+func dedupKey(source, tmpl string, data any, def string) string {
+	if tmpl == "" {
+		return def
+	}
+	t, err := template.New("dedup_key").Option("missingkey=error").Parse(tmpl)
+	if err != nil {
+		log.Printf("%s: dedup_key parse error: %v", source, err)
+		return def
+	}
+	var buf strings.Builder
+	if err := t.Execute(&buf, data); err != nil {
+		log.Printf("%s: dedup_key exec error: %v", source, err)
+		return def
+	}
+	key := strings.TrimSpace(buf.String())
+	if key == "" {
+		log.Printf("%s: dedup_key %q rendered empty, using %s", source, tmpl, def)
+		return def
+	}
+	return source + ":" + key
+}
+
+// ruleRef names a matched rule in the event history by its position in the
+// config, e.g. "trello.rules[2]", since most rules have no name.
+func ruleRef[T any](section string, rules []T, rule *T) string {
+	for i := range rules {
+		if &rules[i] == rule {
+			return fmt.Sprintf("%s.rules[%d]", section, i)
+		}
+	}
+	return ""
+}
+
+// createJob creates a matched rule's job and reports the outcome to the event
+// history of the delivery ctx belongs to, with the job's ID in its timeline.
+func createJob(ctx context.Context, gw gateway.GatewayClient, spec gateway.JobSpec, rule string) error {
+	if spec.ID == "" {
+		spec.ID = gateway.NewJobID()
+	}
+	events.Queued(ctx, rule, spec.Name, spec.ID, spec.DelaySeconds)
+	err := gateway.CreateJob(gw, spec)
+	events.Delivered(ctx, spec.Name, spec.ID, err)
+	outcome := events.OutcomeMatched
+	if err != nil {
+		log.Printf("Failed to create job: %v", err)
+		outcome = events.OutcomeJobFailed
+	}
+	events.Annotate(ctx, "", rule, outcome)
+	return err
+}
@@ -0,0 +1,28 @@
+package webhook
+
+import (
+	"testing"
+
+	"github.com/katalabut/openclaw-relay/internal/config"
+)
+
+func TestJobTiming(t *testing.T) {
+	cfg := &config.Config{}
+	tests := []struct {
+		name           string
+		action         config.RuleAction
+		timeout, delay int
+		wantT, wantD   int
+	}{
+		{"defaults", config.RuleAction{}, 0, 0, 120, 2},
+		{"source", config.RuleAction{}, 300, 10, 300, 10},
+		{"action", config.RuleAction{Timeout: 60, Delay: 5}, 300, 10, 60, 5},
+		{"schedule", config.RuleAction{Delay: 5, Schedule: "in 1h"}, 0, 0, 120, 3600},
+	}
+	for _, tt := range tests {
+		timeout, delay := jobTiming(cfg, tt.action, tt.timeout, tt.delay)
+		if timeout != tt.wantT || delay != tt.wantD {
+			t.Errorf("%s: got %d/%d, want %d/%d", tt.name, timeout, delay, tt.wantT, tt.wantD)
+		}
+	}
+}
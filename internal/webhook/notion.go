@@ -0,0 +1,276 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/katalabut/openclaw-relay/internal/config"
+	"github.com/katalabut/openclaw-relay/internal/events"
+	"github.com/katalabut/openclaw-relay/internal/gateway"
+	"github.com/katalabut/openclaw-relay/internal/notion"
+	"github.com/katalabut/openclaw-relay/internal/ratelimit"
+	"github.com/katalabut/openclaw-relay/internal/state"
+)
+
+// notionSnapshotNS is the state namespace holding each page's last seen
+// properties, so property transitions have a "from" value.
+const notionSnapshotNS = "notion-pages"
+
+// notionSnapshotTTL drops snapshots of pages that stop changing.
+const notionSnapshotTTL = 90 * 24 * time.Hour
+
+// NotionHandler serves Notion webhook subscriptions at /webhook/notion.
+type NotionHandler struct {
+	Config  *config.Config
+	Gateway gateway.GatewayClient
+	Limiter *ratelimit.Limiter
+	Client  *notion.Client // reads page properties; nil without notion.api_token
+	Pages   *state.Store   // previous property values; nil disables "from" matching
+}
+
+type notionEvent struct {
+	ID                string `json:"id"`
+	Type              string `json:"type"`
+	VerificationToken string `json:"verification_token"`
+	Authors           []struct {
+		ID   string `json:"id"`
+		Type string `json:"type"` // person, bot or agent
+	} `json:"authors"`
+	Entity struct {
+		ID   string `json:"id"`
+		Type string `json:"type"`
+	} `json:"entity"`
+	Data struct {
+		Parent struct {
+			ID   string `json:"id"`
+			Type string `json:"type"`
+		} `json:"parent"`
+		UpdatedProperties []string `json:"updated_properties"`
+	} `json:"data"`
+}
+
+// notionPage is what Notion rules see of the event's page.
+type notionPage struct {
+	ID       string
+	URL      string
+	Title    string
+	Database string
+	Props    map[string]string
+	Prev     map[string]string // nil when the page has not been seen before
+	Changed  []string
+}
+
+func (h *NotionHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, "bad request", http.StatusBadRequest)
+		return
+	}
+
+	var ev notionEvent
+	if err := json.Unmarshal(body, &ev); err != nil {
+		log.Printf("Failed to parse Notion payload: %v", err)
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	// The subscription handshake delivers the token that signs later events; it
+	// has to be pasted back into Notion, so it is logged until it is configured.
+	if ev.VerificationToken != "" && ev.Type == "" {
+		if h.Config.Notion.VerificationToken == "" {
+			log.Printf("Notion: subscription verification token received: %s (paste it into the integration's Webhooks tab and set notion.verification_token)", ev.VerificationToken)
+		} else {
+			log.Printf("Notion: subscription verification request received; notion.verification_token is already set")
+		}
+		handshake(w, r, "Notion", HandshakeVerificationToken, nil)
+		return
+	}
+
+	if h.Config.Notion.VerificationToken != "" && !signatureBypassed(r, "Notion") &&
+		!VerifyHMACSHA256(body, r.Header.Get("X-Notion-Signature"), "sha256=", h.Config.Notion.VerificationToken) {
+		log.Printf("Notion signature verification failed")
+		http.Error(w, "forbidden", http.StatusForbidden)
+		return
+	}
+	events.Verified(r.Context(), h.Config.Notion.VerificationToken != "")
+
+	for _, a := range ev.Authors {
+		if a.Type != "person" || containsString(h.Config.Notion.IgnoreUsers, a.ID) {
+			log.Printf("Notion: ignoring %s by %s %s", ev.Type, a.Type, a.ID)
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+	}
+
+	page := h.loadPage(r.Context(), ev)
+	rules := h.findRules(ev.Type, page)
+	if len(rules) == 0 {
+		log.Printf("Notion: no matching rule for event=%s page=%s", ev.Type, page.ID)
+		events.Annotate(r.Context(), ev.Type, "", events.OutcomeNoRule)
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	author := ""
+	if len(ev.Authors) > 0 {
+		author = ev.Authors[0].ID
+	}
+	for i, rule := range rules {
+		ref := ruleRef("notion", h.Config.Notion.Rules, rule)
+		events.Annotate(r.Context(), ev.Type, ref, "")
+
+		data := map[string]any{
+			"Event":      ev.Type,
+			"EventID":    ev.ID,
+			"PageID":     page.ID,
+			"PageURL":    page.URL,
+			"Title":      page.Title,
+			"Database":   page.Database,
+			"Author":     author,
+			"Properties": page.Props,
+			"Previous":   page.Prev,
+			"Changed":    strings.Join(page.Changed, ", "),
+			"Property":   rule.Property,
+			"From":       page.Prev[rule.Property],
+			"To":         page.Props[rule.Property],
+		}
+		if sampledOut("Notion", ev.Type, rule.Sample) {
+			events.Annotate(r.Context(), "", ref, events.OutcomeSampledOut)
+			continue
+		}
+		key := dedupKey("notion", rule.DedupKey, data, "notion:"+ev.ID)
+		if !h.Limiter.AllowWithin(continued(key, i, ref), config.RateWindow(rule.RateLimit, h.Config.Notion.RateLimit)) {
+			log.Printf("Notion: rate limited event %s", ev.ID)
+			events.Annotate(r.Context(), "", ref, events.OutcomeRateLimited)
+			continue
+		}
+
+		log.Printf("Notion: processing %s for page %s", ev.Type, page.ID)
+
+		tmplStr := rule.Action.MessageTemplate
+		if tmplStr == "" {
+			tmplStr = config.DefaultNotionMessageTemplate()
+		}
+		msg := gateway.RenderMessage("notion", tmplStr, data)
+
+		timeout, delay := jobTiming(h.Config, rule.Action, 0, 0)
+
+		eventName := continued(fmt.Sprintf("notion %s: %s", ev.Type, firstNonEmpty(page.Title, page.ID)), i, ref)
+		createJob(r.Context(), h.Gateway, ruleJob("notion", ref, rule.Action, eventName, msg, timeout, delay, body), ref)
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(`{"ok":true}`))
+}
+
+// loadPage reads the event's page from the API (when configured) and compares
+// it with the last snapshot to find the previous values and changed properties.
+func (h *NotionHandler) loadPage(ctx context.Context, ev notionEvent) notionPage {
+	page := notionPage{ID: ev.Entity.ID}
+	if ev.Data.Parent.Type == "database" {
+		page.Database = ev.Data.Parent.ID
+	}
+	if ev.Entity.Type != "page" || h.Client == nil || ev.Type == "page.deleted" {
+		return page
+	}
+
+	p, err := h.Client.GetPage(ctx, ev.Entity.ID)
+	if err != nil {
+		log.Printf("Notion: failed to read page %s: %v", ev.Entity.ID, err)
+		return page
+	}
+	page.URL, page.Title, page.Props = p.URL, p.Title, p.Properties
+	if p.DatabaseID != "" {
+		page.Database = p.DatabaseID
+	}
+
+	if h.Pages != nil {
+		if e, ok := h.Pages.Get(notionSnapshotNS, page.ID); ok {
+			json.Unmarshal(e.Value, &page.Prev)
+		}
+		if raw, err := json.Marshal(page.Props); err == nil {
+			if _, err := h.Pages.Put(notionSnapshotNS, page.ID, raw, notionSnapshotTTL); err != nil {
+				log.Printf("Notion: failed to save page snapshot: %v", err)
+			}
+		}
+	}
+
+	for _, id := range ev.Data.UpdatedProperties {
+		if unescaped, err := url.PathUnescape(id); err == nil {
+			id = unescaped
+		}
+		if name, ok := p.PropertyID[id]; ok {
+			page.Changed = appendUnique(page.Changed, name)
+		}
+	}
+	if len(page.Changed) == 0 && page.Prev != nil {
+		for name, v := range page.Props {
+			if page.Prev[name] != v {
+				page.Changed = append(page.Changed, name)
+			}
+		}
+	}
+	sort.Strings(page.Changed)
+	return page
+}
+
+func (h *NotionHandler) findRules(eventType string, page notionPage) []*config.NotionRule {
+	var matched []*config.NotionRule
+	for i, rule := range h.Config.Notion.Rules {
+		if rule.Event != eventType {
+			continue
+		}
+		if rule.Database != "" && normalizeNotionID(rule.Database) != normalizeNotionID(page.Database) {
+			continue
+		}
+		if rule.Property != "" {
+			if !containsString(page.Changed, rule.Property) {
+				continue
+			}
+			if rule.To != "" && page.Props[rule.Property] != rule.To {
+				continue
+			}
+			// Without a snapshot the previous value is unknown, so from never matches
+			if rule.From != "" && (page.Prev == nil || page.Prev[rule.Property] != rule.From) {
+				continue
+			}
+		}
+		if rule.Condition != "" && !evalCondition(rule.Condition, page.conditionEnv(eventType)) {
+			continue
+		}
+		matched = append(matched, &h.Config.Notion.Rules[i])
+		if !rule.Continue {
+			return matched
+		}
+	}
+	return matched
+}
+
+func (p notionPage) conditionEnv(eventType string) map[string]any {
+	return map[string]any{
+		"event":    eventType,
+		"page":     p.ID,
+		"title":    p.Title,
+		"database": p.Database,
+		"props":    p.Props,
+		"prev":     p.Prev,
+		"changed":  p.Changed,
+	}
+}
+
+// normalizeNotionID drops dashes and case, since Notion IDs appear both ways.
+func normalizeNotionID(id string) string {
+	return strings.ToLower(strings.ReplaceAll(id, "-", ""))
+}
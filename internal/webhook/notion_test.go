@@ -0,0 +1,237 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/katalabut/openclaw-relay/internal/config"
+	"github.com/katalabut/openclaw-relay/internal/notion"
+	"github.com/katalabut/openclaw-relay/internal/ratelimit"
+	"github.com/katalabut/openclaw-relay/internal/state"
+)
+
+const testNotionDB = "1f2e3d4c-5b6a-4798-8a9b-0c1d2e3f4a5b"
+
+// fakeNotionAPI serves a single page whose Status can be changed between events.
+type fakeNotionAPI struct {
+	status string
+}
+
+func (f *fakeNotionAPI) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !strings.HasPrefix(r.URL.Path, "/v1/pages/") {
+		http.NotFound(w, r)
+		return
+	}
+	json.NewEncoder(w).Encode(map[string]any{
+		"id":     strings.TrimPrefix(r.URL.Path, "/v1/pages/"),
+		"url":    "https://www.notion.so/Fix-login-p1",
+		"parent": map[string]any{"type": "database_id", "database_id": testNotionDB},
+		"properties": map[string]any{
+			"Name":   map[string]any{"id": "title", "type": "title", "title": []any{map[string]any{"plain_text": "Fix login"}}},
+			"Status": map[string]any{"id": "s%3Ft", "type": "status", "status": map[string]any{"name": f.status}},
+		},
+	})
+}
+
+func newTestNotionHandler(t *testing.T, gw *mockGateway, api *fakeNotionAPI) *NotionHandler {
+	srv := httptest.NewServer(api)
+	t.Cleanup(srv.Close)
+	cfg := &config.Config{
+		Notion: config.NotionConfig{
+			VerificationToken: "secret_verify",
+			APIToken:          "ntn_test",
+			IgnoreUsers:       []string{"U_IGNORED"},
+			Rules: []config.NotionRule{
+				{
+					Event:    "page.properties_updated",
+					Database: strings.ReplaceAll(testNotionDB, "-", ""),
+					Property: "Status",
+					From:     "In progress",
+					To:       "Review",
+					Action:   config.RuleAction{MessageTemplate: "{{.Title}}: {{.From}} -> {{.To}} ({{.Changed}})"},
+				},
+				{
+					Event:     "page.properties_updated",
+					Condition: "props.Status == 'Blocked'",
+					Action:    config.RuleAction{MessageTemplate: "blocked {{.PageID}}"},
+				},
+				{Event: "page.created"},
+			},
+		},
+	}
+	pages, err := state.NewStore("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &NotionHandler{
+		Config:  cfg,
+		Gateway: gw,
+		Limiter: ratelimit.New(context.Background(), 5*time.Minute),
+		Client:  notion.NewClient("ntn_test", srv.URL),
+		Pages:   pages,
+	}
+}
+
+func notionEventPayload(id, eventType, authorType string, updated ...string) map[string]any {
+	return map[string]any{
+		"id":        id,
+		"timestamp": "2025-06-01T12:00:00.000Z",
+		"type":      eventType,
+		"authors":   []any{map[string]any{"id": "U_ALICE", "type": authorType}},
+		"entity":    map[string]any{"id": "p1", "type": "page"},
+		"data": map[string]any{
+			"parent":             map[string]any{"id": testNotionDB, "type": "database"},
+			"updated_properties": updated,
+		},
+	}
+}
+
+func signNotion(body []byte, token string) string {
+	mac := hmac.New(sha256.New, []byte(token))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func postNotion(h *NotionHandler, payload map[string]any) *httptest.ResponseRecorder {
+	body, _ := json.Marshal(payload)
+	req := httptest.NewRequest("POST", "/webhook/notion", bytes.NewReader(body))
+	req.Header.Set("X-Notion-Signature", signNotion(body, h.Config.Notion.VerificationToken))
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestServeHTTP_Notion_Verification(t *testing.T) {
+	gw := &mockGateway{}
+	h := newTestNotionHandler(t, gw, &fakeNotionAPI{})
+	h.Config.Notion.VerificationToken = ""
+
+	// The handshake is unsigned: the token is not known yet
+	req := httptest.NewRequest("POST", "/webhook/notion", strings.NewReader(`{"verification_token":"secret_abc"}`))
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Errorf("expected 200, got %d", rec.Code)
+	}
+	if len(gw.calls) != 0 {
+		t.Errorf("handshake must not create jobs, got %d", len(gw.calls))
+	}
+}
+
+func TestServeHTTP_Notion_BadSignature(t *testing.T) {
+	h := newTestNotionHandler(t, &mockGateway{}, &fakeNotionAPI{})
+	body, _ := json.Marshal(notionEventPayload("e1", "page.created", "person"))
+
+	req := httptest.NewRequest("POST", "/webhook/notion", bytes.NewReader(body))
+	req.Header.Set("X-Notion-Signature", signNotion(body, "wrong"))
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("expected 403, got %d", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest("GET", "/webhook/notion", nil))
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("expected 405, got %d", rec.Code)
+	}
+}
+
+func TestServeHTTP_Notion_StatusTransition(t *testing.T) {
+	gw := &mockGateway{}
+	api := &fakeNotionAPI{status: "In progress"}
+	h := newTestNotionHandler(t, gw, api)
+
+	// First sighting: no snapshot yet, so "from" cannot match
+	postNotion(h, notionEventPayload("e1", "page.properties_updated", "person", "s%3Ft"))
+	if len(gw.calls) != 0 {
+		t.Fatalf("expected no job without a previous value, got %d", len(gw.calls))
+	}
+
+	api.status = "Review"
+	rec := postNotion(h, notionEventPayload("e2", "page.properties_updated", "person", "s%3Ft"))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rec.Code)
+	}
+	if len(gw.calls) != 1 {
+		t.Fatalf("expected 1 job, got %d", len(gw.calls))
+	}
+	if want := "Fix login: In progress -> Review (Status)"; gw.calls[0].Message != want {
+		t.Errorf("message = %q, want %q", gw.calls[0].Message, want)
+	}
+	if gw.calls[0].Name != "notion page.properties_updated: Fix login" {
+		t.Errorf("unexpected job name %q", gw.calls[0].Name)
+	}
+
+	// Same event redelivered
+	postNotion(h, notionEventPayload("e2", "page.properties_updated", "person", "s%3Ft"))
+	if len(gw.calls) != 1 {
+		t.Errorf("expected duplicate delivery to be dropped, got %d jobs", len(gw.calls))
+	}
+}
+
+func TestServeHTTP_Notion_Condition(t *testing.T) {
+	gw := &mockGateway{}
+	h := newTestNotionHandler(t, gw, &fakeNotionAPI{status: "Blocked"})
+
+	postNotion(h, notionEventPayload("e1", "page.properties_updated", "person", "s%3Ft"))
+	if len(gw.calls) != 1 || gw.calls[0].Message != "blocked p1" {
+		t.Fatalf("expected condition rule to fire, got %+v", gw.calls)
+	}
+}
+
+func TestServeHTTP_Notion_Skips(t *testing.T) {
+	tests := []struct {
+		name    string
+		payload map[string]any
+	}{
+		{"bot author", notionEventPayload("e1", "page.created", "bot")},
+		{"ignored user", func() map[string]any {
+			p := notionEventPayload("e2", "page.created", "person")
+			p["authors"] = []any{map[string]any{"id": "U_IGNORED", "type": "person"}}
+			return p
+		}()},
+		{"no rule", notionEventPayload("e3", "comment.created", "person")},
+		{"other database", func() map[string]any {
+			p := notionEventPayload("e4", "page.properties_updated", "person", "s%3Ft")
+			p["data"].(map[string]any)["parent"] = map[string]any{"id": "other", "type": "database"}
+			return p
+		}()},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gw := &mockGateway{}
+			h := newTestNotionHandler(t, gw, &fakeNotionAPI{status: "Review"})
+			h.Client = nil // keep the event's parent as the database
+			rec := postNotion(h, tt.payload)
+			if rec.Code != http.StatusOK {
+				t.Errorf("expected 200, got %d", rec.Code)
+			}
+			if len(gw.calls) != 0 {
+				t.Errorf("expected no job, got %d", len(gw.calls))
+			}
+		})
+	}
+}
+
+func TestServeHTTP_Notion_PageCreated(t *testing.T) {
+	gw := &mockGateway{}
+	h := newTestNotionHandler(t, gw, &fakeNotionAPI{status: "Todo"})
+
+	postNotion(h, notionEventPayload("e1", "page.created", "person"))
+	if len(gw.calls) != 1 {
+		t.Fatalf("expected 1 job, got %d", len(gw.calls))
+	}
+	if !strings.Contains(gw.calls[0].Message, "Page: Fix login (p1)") {
+		t.Errorf("default template missing page line: %q", gw.calls[0].Message)
+	}
+}
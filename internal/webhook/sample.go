@@ -0,0 +1,21 @@
+package webhook
+
+import (
+	"log"
+	"math/rand/v2"
+
+	"github.com/katalabut/openclaw-relay/internal/config"
+)
+
+// sampleRoll returns a value in [0, 1); tests replace it.
+var sampleRoll = rand.Float64
+
+// sampledOut reports (and logs) whether a matched rule skips this event because
+// of its sample rate.
+func sampledOut(source, rule string, s config.Sample) bool {
+	if s.Keep(sampleRoll()) {
+		return false
+	}
+	log.Printf("%s: rule %s sampled out (sample %v)", source, rule, float64(s))
+	return true
+}
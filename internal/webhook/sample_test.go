@@ -0,0 +1,72 @@
+package webhook
+
+import (
+	"bytes"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/katalabut/openclaw-relay/internal/config"
+)
+
+func withSampleRoll(t *testing.T, roll float64) {
+	orig := sampleRoll
+	sampleRoll = func() float64 { return roll }
+	t.Cleanup(func() { sampleRoll = orig })
+}
+
+func TestSampledOut(t *testing.T) {
+	withSampleRoll(t, 0.5)
+	tests := []struct {
+		sample config.Sample
+		want   bool
+	}{
+		{0, false},   // unset: every event
+		{1, false},   // all events
+		{0.6, false}, // roll below rate
+		{0.5, true},  // roll at rate is out
+		{0.1, true},
+	}
+	for _, tt := range tests {
+		if got := sampledOut("Test", "rule", tt.sample); got != tt.want {
+			t.Errorf("sampledOut(%v) = %v, want %v", tt.sample, got, tt.want)
+		}
+	}
+}
+
+func TestTrelloHandler_Sample(t *testing.T) {
+	gw := &mockGateway{}
+	h := newTestTrelloHandler(gw)
+	h.Config.Trello.Rules[0].Sample = 0.1
+	post := func(card string) {
+		body := makeTrelloPayload("updateCard", card, "My Card", "list-ready-id", "Ready", "", "Dev")
+		h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("POST", "/webhook/trello", bytes.NewReader(body)))
+	}
+
+	withSampleRoll(t, 0.9)
+	post("card1")
+	if len(gw.calls) != 0 {
+		t.Fatalf("expected event to be sampled out, got %d calls", len(gw.calls))
+	}
+	// Sampled out before the rate limit, so the same card still dispatches
+	withSampleRoll(t, 0.05)
+	post("card1")
+	if len(gw.calls) != 1 {
+		t.Fatalf("expected sampled-in event to dispatch, got %d calls", len(gw.calls))
+	}
+}
+
+func TestGitHubHandler_Sample(t *testing.T) {
+	gw := &mockGateway{}
+	h := newTestGitHubHandler(gw)
+	h.Config.GitHub.Rules = []config.GitHubRule{{Event: "workflow_run", Sample: 0.2}}
+	withSampleRoll(t, 0.3)
+	postGitHub(h, "workflow_run", workflowRun("acme/api", "failure", "abc"))
+	if len(gw.calls) != 0 {
+		t.Errorf("expected sampled-out workflow_run, got %d calls", len(gw.calls))
+	}
+	withSampleRoll(t, 0.1)
+	postGitHub(h, "workflow_run", workflowRun("acme/api", "failure", "def"))
+	if len(gw.calls) != 1 {
+		t.Errorf("expected sampled-in workflow_run to dispatch, got %d calls", len(gw.calls))
+	}
+}
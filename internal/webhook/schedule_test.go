@@ -0,0 +1,18 @@
+package webhook
+
+import (
+	"bytes"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestTrelloHandler_ScheduledAction(t *testing.T) {
+	gw := &mockGateway{}
+	h := newTestTrelloHandler(gw)
+	h.Config.Trello.Rules[0].Action.Schedule = "in 30m"
+	body := makeTrelloPayload("updateCard", "card1", "My Card", "list-ready-id", "Ready", "", "Dev")
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("POST", "/webhook/trello", bytes.NewReader(body)))
+	if len(gw.calls) != 1 || gw.calls[0].Delay != 1800 {
+		t.Fatalf("expected one job delayed 1800s, got %+v", gw.calls)
+	}
+}
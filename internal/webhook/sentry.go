@@ -0,0 +1,318 @@
+package webhook
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/katalabut/openclaw-relay/internal/config"
+	"github.com/katalabut/openclaw-relay/internal/events"
+	"github.com/katalabut/openclaw-relay/internal/gateway"
+	"github.com/katalabut/openclaw-relay/internal/ratelimit"
+)
+
+// SentryHandler serves Sentry issue alerts at /webhook/sentry: internal
+// integration webhooks (event_alert and issue resources) and the legacy
+// Webhooks plugin.
+type SentryHandler struct {
+	Config  *config.Config
+	Gateway gateway.GatewayClient
+	Limiter *ratelimit.Limiter
+}
+
+type sentryEvent struct {
+	EventID     string     `json:"event_id"`
+	IssueID     string     `json:"issue_id"`
+	Title       string     `json:"title"`
+	Culprit     string     `json:"culprit"`
+	Level       string     `json:"level"`
+	Message     string     `json:"message"`
+	Environment string     `json:"environment"`
+	Fingerprint []string   `json:"fingerprint"`
+	Tags        [][]string `json:"tags"`
+	WebURL      string     `json:"web_url"`
+	URL         string     `json:"url"`
+}
+
+type sentryIssue struct {
+	ID        string `json:"id"`
+	ShortID   string `json:"shortId"`
+	Title     string `json:"title"`
+	Culprit   string `json:"culprit"`
+	Level     string `json:"level"`
+	Count     string `json:"count"`
+	UserCount int    `json:"userCount"`
+	Permalink string `json:"permalink"`
+	WebURL    string `json:"web_url"`
+	Project   struct {
+		Slug string `json:"slug"`
+	} `json:"project"`
+}
+
+type sentryWebhook struct {
+	// Internal integration webhooks
+	Action string `json:"action"`
+	Data   struct {
+		Event         *sentryEvent `json:"event"`
+		TriggeredRule string       `json:"triggered_rule"`
+		Issue         *sentryIssue `json:"issue"`
+	} `json:"data"`
+
+	// Legacy Webhooks plugin
+	ID              string       `json:"id"`
+	Project         string       `json:"project"`
+	ProjectSlug     string       `json:"project_slug"`
+	Culprit         string       `json:"culprit"`
+	Level           string       `json:"level"`
+	URL             string       `json:"url"`
+	Message         string       `json:"message"`
+	TriggeringRules []string     `json:"triggering_rules"`
+	Event           *sentryEvent `json:"event"`
+}
+
+// sentryAlert is the normalized alert rules and templates see.
+type sentryAlert struct {
+	Resource    string
+	Action      string
+	IssueID     string
+	ShortID     string
+	Title       string
+	Culprit     string
+	Level       string
+	Project     string
+	Environment string
+	URL         string
+	Count       int // events in the issue; 0 when the payload does not say
+	Users       int
+	Fingerprint string
+	Rule        string
+	Tags        map[string]string
+}
+
+// sentryProjectURL pulls the project slug out of an event's API URL
+// (.../projects/<org>/<project>/events/<id>/), since event alerts carry only
+// the numeric project ID.
+var sentryProjectURL = regexp.MustCompile(`/projects/[^/]+/([^/]+)/events/`)
+
+func (h *SentryHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, "bad request", http.StatusBadRequest)
+		return
+	}
+
+	if h.Config.Sentry.ClientSecret != "" && !signatureBypassed(r, "Sentry") &&
+		!VerifyHMACSHA256(body, r.Header.Get("Sentry-Hook-Signature"), "", h.Config.Sentry.ClientSecret) {
+		log.Printf("Sentry signature verification failed")
+		http.Error(w, "forbidden", http.StatusForbidden)
+		return
+	}
+	events.Verified(r.Context(), h.Config.Sentry.ClientSecret != "")
+
+	var payload sentryWebhook
+	if err := json.Unmarshal(body, &payload); err != nil {
+		log.Printf("Failed to parse Sentry payload: %v", err)
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	resource := r.Header.Get("Sentry-Hook-Resource")
+	alert, ok := parseSentryAlert(resource, &payload)
+	if !ok {
+		log.Printf("Sentry: ignoring %s %s", firstNonEmpty(resource, "delivery"), payload.Action)
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	rules := h.findRules(alert)
+	if len(rules) == 0 {
+		log.Printf("Sentry: no matching rule for project=%s level=%s", alert.Project, alert.Level)
+		events.Annotate(r.Context(), resource, "", events.OutcomeNoRule)
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	for i, rule := range rules {
+		ref := ruleRef("sentry", h.Config.Sentry.Rules, rule)
+		events.Annotate(r.Context(), resource, ref, "")
+
+		// Sentry groups events into issues by fingerprint; repeated alerts for the
+		// same group collapse in the rate limiter.
+		key := "sentry:" + alert.Project + ":" + alert.Fingerprint
+		if alert.Resource == "issue" {
+			key += ":" + alert.Action
+		}
+		count := ""
+		if alert.Count > 0 {
+			count = strconv.Itoa(alert.Count)
+		}
+		data := map[string]any{
+			"Resource":    alert.Resource,
+			"Action":      alert.Action,
+			"IssueID":     alert.IssueID,
+			"ShortID":     alert.ShortID,
+			"Title":       alert.Title,
+			"Culprit":     alert.Culprit,
+			"Level":       alert.Level,
+			"Project":     alert.Project,
+			"Environment": alert.Environment,
+			"URL":         alert.URL,
+			"Count":       count,
+			"Users":       alert.Users,
+			"Fingerprint": alert.Fingerprint,
+			"Rule":        alert.Rule,
+			"Tags":        alert.Tags,
+		}
+		if sampledOut("Sentry", alert.Project, rule.Sample) {
+			events.Annotate(r.Context(), "", ref, events.OutcomeSampledOut)
+			continue
+		}
+		key = dedupKey("sentry", rule.DedupKey, data, key)
+		if !h.Limiter.AllowWithin(continued(key, i, ref), config.RateWindow(rule.RateLimit, h.Config.Sentry.RateLimit)) {
+			log.Printf("Sentry: rate limited %s", key)
+			events.Annotate(r.Context(), "", ref, events.OutcomeRateLimited)
+			continue
+		}
+
+		log.Printf("Sentry: processing %s alert for %s", alert.Level, firstNonEmpty(alert.ShortID, alert.IssueID))
+
+		tmplStr := rule.Action.MessageTemplate
+		if tmplStr == "" {
+			tmplStr = config.DefaultSentryMessageTemplate()
+		}
+		msg := gateway.RenderMessage("sentry", tmplStr, data)
+
+		timeout, delay := jobTiming(h.Config, rule.Action, 0, 0)
+
+		eventName := continued(fmt.Sprintf("sentry %s: %s", alert.Project, alert.Title), i, ref)
+		createJob(r.Context(), h.Gateway, ruleJob("sentry", ref, rule.Action, eventName, msg, timeout, delay, body), ref)
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(`{"ok":true}`))
+}
+
+// parseSentryAlert normalizes the three payload shapes. It reports false for
+// deliveries that are not issue alerts (installation, metric alerts, ...).
+func parseSentryAlert(resource string, p *sentryWebhook) (sentryAlert, bool) {
+	var a sentryAlert
+	switch {
+	case resource == "event_alert" && p.Data.Event != nil:
+		a = alertFromSentryEvent(p.Data.Event)
+		a.Resource, a.Action, a.Rule = resource, p.Action, p.Data.TriggeredRule
+	case resource == "issue" && p.Data.Issue != nil:
+		is := p.Data.Issue
+		a = sentryAlert{
+			Resource:    resource,
+			Action:      p.Action,
+			IssueID:     is.ID,
+			ShortID:     is.ShortID,
+			Title:       is.Title,
+			Culprit:     is.Culprit,
+			Level:       is.Level,
+			Project:     is.Project.Slug,
+			URL:         firstNonEmpty(is.WebURL, is.Permalink),
+			Users:       is.UserCount,
+			Fingerprint: is.ID,
+		}
+		a.Count, _ = strconv.Atoi(is.Count)
+	case resource == "" && p.ID != "" && p.Event != nil:
+		a = alertFromSentryEvent(p.Event)
+		a.Resource = "legacy"
+		a.IssueID = p.ID
+		a.Project = firstNonEmpty(p.ProjectSlug, p.Project)
+		a.Title = firstNonEmpty(a.Title, p.Message)
+		a.Culprit = firstNonEmpty(a.Culprit, p.Culprit)
+		a.Level = firstNonEmpty(a.Level, p.Level)
+		a.URL = p.URL
+		a.Rule = strings.Join(p.TriggeringRules, ", ")
+		if a.Fingerprint == "" {
+			a.Fingerprint = p.ID
+		}
+	default:
+		return a, false
+	}
+	if a.Tags == nil {
+		a.Tags = map[string]string{}
+	}
+	return a, true
+}
+
+func alertFromSentryEvent(ev *sentryEvent) sentryAlert {
+	a := sentryAlert{
+		IssueID:     ev.IssueID,
+		Title:       firstNonEmpty(ev.Title, ev.Message),
+		Culprit:     ev.Culprit,
+		Level:       ev.Level,
+		Environment: ev.Environment,
+		URL:         ev.WebURL,
+		Tags:        make(map[string]string, len(ev.Tags)),
+	}
+	for _, t := range ev.Tags {
+		if len(t) == 2 {
+			a.Tags[t[0]] = t[1]
+		}
+	}
+	a.Environment = firstNonEmpty(a.Environment, a.Tags["environment"])
+	a.Level = firstNonEmpty(a.Level, a.Tags["level"])
+	if m := sentryProjectURL.FindStringSubmatch(ev.URL); m != nil {
+		a.Project = m[1]
+	}
+	a.Fingerprint = sentryFingerprint(ev.Fingerprint, ev.IssueID)
+	return a
+}
+
+// sentryFingerprint returns a custom fingerprint when the event has one, else
+// the issue ID: "{{ default }}" alone means Sentry grouped the event itself,
+// and the issue is that group.
+func sentryFingerprint(parts []string, issueID string) string {
+	for _, p := range parts {
+		if p != "{{ default }}" && p != "{{default}}" {
+			return strings.Join(parts, "|")
+		}
+	}
+	return issueID
+}
+
+func (h *SentryHandler) findRules(a sentryAlert) []*config.SentryRule {
+	env := a.conditionEnv()
+	var matched []*config.SentryRule
+	for i, rule := range h.Config.Sentry.Rules {
+		if rule.Project != "" && rule.Project != a.Project {
+			continue
+		}
+		if rule.Condition != "" && !evalCondition(rule.Condition, env) {
+			continue
+		}
+		matched = append(matched, &h.Config.Sentry.Rules[i])
+		if !rule.Continue {
+			return matched
+		}
+	}
+	return matched
+}
+
+func (a sentryAlert) conditionEnv() map[string]any {
+	return map[string]any{
+		"resource":    a.Resource,
+		"action":      a.Action,
+		"project":     a.Project,
+		"level":       a.Level,
+		"title":       a.Title,
+		"culprit":     a.Culprit,
+		"environment": a.Environment,
+		"count":       a.Count,
+		"users":       a.Users,
+		"rule":        a.Rule,
+		"tags":        a.Tags,
+	}
+}
@@ -0,0 +1,181 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/katalabut/openclaw-relay/internal/config"
+	"github.com/katalabut/openclaw-relay/internal/ratelimit"
+)
+
+func newTestSentryHandler(gw *mockGateway) *SentryHandler {
+	cfg := &config.Config{
+		Sentry: config.SentryConfig{
+			ClientSecret: "s3cret",
+			Rules: []config.SentryRule{
+				{Project: "api", Condition: "level == 'fatal'", Action: config.RuleAction{AgentID: "oncall", MessageTemplate: "FATAL {{.Title}}"}},
+				{Project: "api", Action: config.RuleAction{MessageTemplate: "{{.Title}} in {{.Culprit}} x{{.Count}} [{{.Environment}}]"}},
+			},
+		},
+	}
+	return &SentryHandler{
+		Config:  cfg,
+		Gateway: gw,
+		Limiter: ratelimit.New(context.Background(), 5*time.Minute),
+	}
+}
+
+func postSentry(h *SentryHandler, resource string, payload map[string]any) *httptest.ResponseRecorder {
+	body, _ := json.Marshal(payload)
+	mac := hmac.New(sha256.New, []byte(h.Config.Sentry.ClientSecret))
+	mac.Write(body)
+	req := httptest.NewRequest("POST", "/webhook/sentry", bytes.NewReader(body))
+	req.Header.Set("Sentry-Hook-Signature", hex.EncodeToString(mac.Sum(nil)))
+	if resource != "" {
+		req.Header.Set("Sentry-Hook-Resource", resource)
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return rec
+}
+
+func sentryEventAlert(issueID, level string, fingerprint ...string) map[string]any {
+	if len(fingerprint) == 0 {
+		fingerprint = []string{"{{ default }}"}
+	}
+	return map[string]any{
+		"action": "triggered",
+		"data": map[string]any{
+			"triggered_rule": "Notify agent",
+			"event": map[string]any{
+				"event_id":    "ev-" + issueID,
+				"issue_id":    issueID,
+				"title":       "TypeError: x is undefined",
+				"culprit":     "handlers.login",
+				"level":       level,
+				"fingerprint": fingerprint,
+				"tags":        [][]string{{"environment", "prod"}},
+				"web_url":     "https://sentry.io/organizations/acme/issues/" + issueID + "/events/ev/",
+				"url":         "https://sentry.io/api/0/projects/acme/api/events/ev/",
+			},
+		},
+	}
+}
+
+func TestServeHTTP_Sentry_EventAlert(t *testing.T) {
+	gw := &mockGateway{}
+	h := newTestSentryHandler(gw)
+
+	rec := postSentry(h, "event_alert", sentryEventAlert("101", "error"))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rec.Code)
+	}
+	if len(gw.calls) != 1 {
+		t.Fatalf("expected 1 job, got %d", len(gw.calls))
+	}
+	if want := "TypeError: x is undefined in handlers.login x [prod]"; gw.calls[0].Message != want {
+		t.Errorf("message = %q, want %q", gw.calls[0].Message, want)
+	}
+
+	// Another event in the same issue is collapsed
+	postSentry(h, "event_alert", sentryEventAlert("101", "error"))
+	if len(gw.calls) != 1 {
+		t.Errorf("expected repeat alert to be deduped, got %d jobs", len(gw.calls))
+	}
+
+	// A fatal event matches the first rule
+	postSentry(h, "event_alert", sentryEventAlert("102", "fatal"))
+	if len(gw.calls) != 2 || gw.calls[1].Message != "FATAL TypeError: x is undefined" {
+		t.Errorf("expected fatal rule, got %+v", gw.calls)
+	}
+}
+
+func TestServeHTTP_Sentry_CustomFingerprint(t *testing.T) {
+	gw := &mockGateway{}
+	h := newTestSentryHandler(gw)
+
+	// Different issues sharing a custom fingerprint dedupe together
+	postSentry(h, "event_alert", sentryEventAlert("201", "error", "db-timeout"))
+	postSentry(h, "event_alert", sentryEventAlert("202", "error", "db-timeout"))
+	if len(gw.calls) != 1 {
+		t.Errorf("expected 1 job for a shared fingerprint, got %d", len(gw.calls))
+	}
+}
+
+func TestServeHTTP_Sentry_IssueResource(t *testing.T) {
+	gw := &mockGateway{}
+	h := newTestSentryHandler(gw)
+	h.Config.Sentry.Rules[1].Action.MessageTemplate = ""
+
+	rec := postSentry(h, "issue", map[string]any{
+		"action": "created",
+		"data": map[string]any{"issue": map[string]any{
+			"id": "301", "shortId": "API-7", "title": "KeyError: 'user'", "culprit": "views.profile",
+			"level": "error", "count": "42", "userCount": 3, "web_url": "https://sentry.io/issues/301/",
+			"project": map[string]any{"slug": "api"},
+		}},
+	})
+	if rec.Code != http.StatusOK || len(gw.calls) != 1 {
+		t.Fatalf("expected 1 job, got %d (%d)", len(gw.calls), rec.Code)
+	}
+	msg := gw.calls[0].Message
+	for _, want := range []string{"Issue: KeyError: 'user' (API-7)", "Culprit: views.profile", "Events: 42 (3 users)"} {
+		if !strings.Contains(msg, want) {
+			t.Errorf("default template missing %q:\n%s", want, msg)
+		}
+	}
+}
+
+func TestServeHTTP_Sentry_LegacyPlugin(t *testing.T) {
+	gw := &mockGateway{}
+	h := newTestSentryHandler(gw)
+
+	postSentry(h, "", map[string]any{
+		"id": "401", "project": "api", "culprit": "worker.run", "level": "error",
+		"message": "boom", "url": "https://sentry.io/acme/api/issues/401/",
+		"event": map[string]any{"title": "RuntimeError: boom", "tags": [][]string{{"environment", "staging"}}},
+	})
+	if len(gw.calls) != 1 {
+		t.Fatalf("expected 1 job, got %d", len(gw.calls))
+	}
+	if want := "RuntimeError: boom in worker.run x [staging]"; gw.calls[0].Message != want {
+		t.Errorf("message = %q, want %q", gw.calls[0].Message, want)
+	}
+}
+
+func TestServeHTTP_Sentry_Rejects(t *testing.T) {
+	gw := &mockGateway{}
+	h := newTestSentryHandler(gw)
+
+	req := httptest.NewRequest("POST", "/webhook/sentry", strings.NewReader(`{}`))
+	req.Header.Set("Sentry-Hook-Signature", "00")
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("expected 403, got %d", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest("GET", "/webhook/sentry", nil))
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("expected 405, got %d", rec.Code)
+	}
+
+	// Other resources and other projects do not create jobs
+	postSentry(h, "installation", map[string]any{"action": "created"})
+	other := sentryEventAlert("501", "error")
+	other["data"].(map[string]any)["event"].(map[string]any)["url"] = "https://sentry.io/api/0/projects/acme/web/events/ev/"
+	postSentry(h, "event_alert", other)
+	if len(gw.calls) != 0 {
+		t.Errorf("expected no jobs, got %d", len(gw.calls))
+	}
+}
@@ -0,0 +1,106 @@
+package webhook
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// DefaultSkewTolerance is how far a signed request's timestamp may be from
+// the local clock before it is rejected as a replay.
+const DefaultSkewTolerance = 5 * time.Minute
+
+// ClockSkew tracks how far the timestamps of signed requests (Slack,
+// Discord) are from the local clock. A container whose clock drifts sees
+// skew grow on every source at once, long before requests start failing
+// with 403s.
+type ClockSkew struct {
+	Tolerance time.Duration // max accepted skew; 0 = DefaultSkewTolerance
+	Warn      time.Duration // skew above this is logged and counted; 0 = never
+
+	mu      sync.Mutex
+	sources map[string]*SkewStats
+}
+
+// SkewStats is the skew seen on one source. Positive skew means the request
+// was signed in the past by the local clock's reckoning.
+type SkewStats struct {
+	LastMillis  int64     `json:"last_ms"`
+	MaxMillis   int64     `json:"max_ms"` // largest absolute skew seen
+	Warnings    int       `json:"warnings"`
+	Outside     int       `json:"outside_tolerance"` // requests further off than the tolerance
+	LastWarning time.Time `json:"last_warning,omitzero"`
+}
+
+// ResolvedTolerance returns Tolerance with its default. A nil ClockSkew
+// uses the default.
+func (c *ClockSkew) ResolvedTolerance() time.Duration {
+	if c == nil || c.Tolerance <= 0 {
+		return DefaultSkewTolerance
+	}
+	return c.Tolerance
+}
+
+// Observe records the skew of a request timestamp (unix seconds) on source,
+// and logs a warning when it exceeds Warn. Unparsable timestamps are left to
+// signature verification.
+func (c *ClockSkew) Observe(source, timestamp string, now time.Time) {
+	if c == nil {
+		return
+	}
+	ts, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return
+	}
+	skew := now.Sub(time.Unix(ts, 0))
+	abs := skew.Abs()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.sources == nil {
+		c.sources = map[string]*SkewStats{}
+	}
+	s := c.sources[source]
+	if s == nil {
+		s = &SkewStats{}
+		c.sources[source] = s
+	}
+	s.LastMillis = skew.Milliseconds()
+	s.MaxMillis = max(s.MaxMillis, abs.Milliseconds())
+	if abs > c.ResolvedTolerance() {
+		s.Outside++
+	}
+	if c.Warn > 0 && abs > c.Warn {
+		s.Warnings++
+		s.LastWarning = now
+		log.Printf("WARNING: %s request timestamp is %s off the local clock (tolerance %s); check NTP on this host", source, skew.Round(time.Second), c.ResolvedTolerance())
+	}
+}
+
+// Stats returns a copy of the per-source skew.
+func (c *ClockSkew) Stats() map[string]SkewStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	out := make(map[string]SkewStats, len(c.sources))
+	for k, s := range c.sources {
+		out[k] = *s
+	}
+	return out
+}
+
+// HandleStatus serves GET /api/skew.
+func (c *ClockSkew) HandleStatus(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{
+		"tolerance_ms": c.ResolvedTolerance().Milliseconds(),
+		"warn_ms":      c.Warn.Milliseconds(),
+		"sources":      c.Stats(),
+	})
+}
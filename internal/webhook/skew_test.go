@@ -0,0 +1,61 @@
+package webhook
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestClockSkew_Observe(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	c := &ClockSkew{Tolerance: 2 * time.Minute, Warn: 30 * time.Second}
+
+	c.Observe("Slack", strconv.FormatInt(now.Add(-10*time.Second).Unix(), 10), now)
+	c.Observe("Slack", strconv.FormatInt(now.Add(-45*time.Second).Unix(), 10), now)
+	c.Observe("Discord", strconv.FormatInt(now.Add(3*time.Minute).Unix(), 10), now)
+	c.Observe("Discord", "not-a-number", now)
+
+	stats := c.Stats()
+	slack := stats["Slack"]
+	if slack.LastMillis != 45000 || slack.MaxMillis != 45000 || slack.Warnings != 1 || slack.Outside != 0 {
+		t.Errorf("unexpected Slack stats %+v", slack)
+	}
+	discord := stats["Discord"]
+	if discord.LastMillis != -180000 || discord.MaxMillis != 180000 || discord.Warnings != 1 || discord.Outside != 1 {
+		t.Errorf("unexpected Discord stats %+v", discord)
+	}
+
+	var none *ClockSkew
+	none.Observe("Slack", "1", now)
+	if none.ResolvedTolerance() != DefaultSkewTolerance {
+		t.Error("nil ClockSkew should use the default tolerance")
+	}
+}
+
+func TestClockSkew_HandleStatus(t *testing.T) {
+	c := &ClockSkew{Warn: time.Minute}
+	now := time.Now()
+	c.Observe("Slack", strconv.FormatInt(now.Unix(), 10), now)
+
+	rec := httptest.NewRecorder()
+	c.HandleStatus(rec, httptest.NewRequest("GET", "/api/skew", nil))
+	var got struct {
+		ToleranceMS int64                `json:"tolerance_ms"`
+		Sources     map[string]SkewStats `json:"sources"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&got); err != nil {
+		t.Fatal(err)
+	}
+	if got.ToleranceMS != DefaultSkewTolerance.Milliseconds() || len(got.Sources) != 1 {
+		t.Errorf("unexpected status %+v", got)
+	}
+
+	rec = httptest.NewRecorder()
+	c.HandleStatus(rec, httptest.NewRequest("POST", "/api/skew", nil))
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("expected 405, got %d", rec.Code)
+	}
+}
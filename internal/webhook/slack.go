@@ -0,0 +1,201 @@
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/katalabut/openclaw-relay/internal/config"
+	"github.com/katalabut/openclaw-relay/internal/events"
+	"github.com/katalabut/openclaw-relay/internal/gateway"
+	"github.com/katalabut/openclaw-relay/internal/ratelimit"
+)
+
+type SlackHandler struct {
+	Config  *config.Config
+	Gateway gateway.GatewayClient
+	Limiter *ratelimit.Limiter
+	Skew    *ClockSkew // optional; nil uses DefaultSkewTolerance
+}
+
+type slackPayload struct {
+	Type      string `json:"type"`
+	Challenge string `json:"challenge"`
+	TeamID    string `json:"team_id"`
+	EventID   string `json:"event_id"`
+	Event     struct {
+		Type     string `json:"type"`
+		Subtype  string `json:"subtype"`
+		Channel  string `json:"channel"`
+		User     string `json:"user"`
+		BotID    string `json:"bot_id"`
+		Text     string `json:"text"`
+		TS       string `json:"ts"`
+		ThreadTS string `json:"thread_ts"`
+	} `json:"event"`
+}
+
+// VerifySlackSignature checks Slack's v0 signature: HMAC-SHA256 over "v0:<timestamp>:<body>".
+// Requests whose timestamp is further than tolerance from now are rejected; 0
+// means DefaultSkewTolerance.
+func VerifySlackSignature(body []byte, timestamp, signature, secret string, now time.Time, tolerance time.Duration) bool {
+	if secret == "" {
+		return true
+	}
+	ts, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return false
+	}
+	if tolerance <= 0 {
+		tolerance = DefaultSkewTolerance
+	}
+	if now.Sub(time.Unix(ts, 0)).Abs() > tolerance {
+		return false
+	}
+	mac := hmac.New(sha256.New, []byte(secret))
+	fmt.Fprintf(mac, "v0:%s:", timestamp)
+	mac.Write(body)
+	expected := "v0=" + hex.EncodeToString(mac.Sum(nil))
+	return hmac.Equal([]byte(signature), []byte(expected))
+}
+
+func (h *SlackHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, "bad request", http.StatusBadRequest)
+		return
+	}
+
+	timestamp, now := r.Header.Get("X-Slack-Request-Timestamp"), time.Now()
+	h.Skew.Observe("Slack", timestamp, now)
+	if h.Config.Slack.SigningSecret != "" && !signatureBypassed(r, "Slack") && !VerifySlackSignature(body,
+		timestamp, r.Header.Get("X-Slack-Signature"), h.Config.Slack.SigningSecret, now, h.Skew.ResolvedTolerance()) {
+		log.Printf("Slack signature verification failed")
+		http.Error(w, "forbidden", http.StatusForbidden)
+		return
+	}
+	events.Verified(r.Context(), h.Config.Slack.SigningSecret != "")
+
+	var payload slackPayload
+	if err := json.Unmarshal(body, &payload); err != nil {
+		log.Printf("Failed to parse Slack payload: %v", err)
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	switch payload.Type {
+	case "url_verification":
+		handshake(w, r, "Slack", HandshakeURLVerification, func(w http.ResponseWriter) {
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(map[string]string{"challenge": payload.Challenge})
+		})
+		return
+	case "event_callback":
+	default:
+		log.Printf("Slack: ignoring payload type %s", payload.Type)
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	ev := payload.Event
+	// Never react to bot messages, including our own agent's replies
+	if ev.BotID != "" || ev.Subtype == "bot_message" {
+		log.Printf("Slack: ignoring bot %s in %s", ev.Type, ev.Channel)
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	if h.isIgnoredUser(ev.User) {
+		log.Printf("Slack: ignoring %s from user %s", ev.Type, ev.User)
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	// Slack redelivers when we answer slowly; the event ID stays the same
+	key := fmt.Sprintf("slack:%s", payload.EventID)
+	if payload.EventID == "" {
+		key = fmt.Sprintf("slack:%s:%s:%s", ev.Type, ev.Channel, ev.TS)
+	}
+	rules := h.findRules(ev.Type, ev.Channel)
+	if len(rules) == 0 {
+		log.Printf("Slack: no matching rule for event=%s channel=%s", ev.Type, ev.Channel)
+		events.Annotate(r.Context(), ev.Type, "", events.OutcomeNoRule)
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	log.Printf("Slack: processing %s in %s", ev.Type, ev.Channel)
+	events.Annotate(r.Context(), ev.Type, "", "")
+	data := map[string]interface{}{
+		"EventType": ev.Type,
+		"EventID":   payload.EventID,
+		"Team":      payload.TeamID,
+		"Channel":   ev.Channel,
+		"User":      ev.User,
+		"Text":      ev.Text,
+		"TS":        ev.TS,
+		"ThreadTS":  ev.ThreadTS,
+	}
+	for i, rule := range rules {
+		ref := ruleRef("slack", h.Config.Slack.Rules, rule)
+		if sampledOut("Slack", ev.Type, rule.Sample) {
+			events.Annotate(r.Context(), "", ref, events.OutcomeSampledOut)
+			continue
+		}
+		ruleKey := dedupKey("slack", rule.DedupKey, data, key)
+		if !h.Limiter.AllowWithin(continued(ruleKey, i, ref), config.RateWindow(rule.RateLimit, h.Config.Slack.RateLimit)) {
+			log.Printf("Slack: rate limited %s (retry %s)", ruleKey, r.Header.Get("X-Slack-Retry-Num"))
+			events.Annotate(r.Context(), "", ref, events.OutcomeRateLimited)
+			continue
+		}
+
+		tmplStr := rule.Action.MessageTemplate
+		if tmplStr == "" {
+			tmplStr = config.DefaultSlackMessageTemplate()
+		}
+		msg := gateway.RenderMessage("slack", tmplStr, data)
+
+		timeout, delay := jobTiming(h.Config, rule.Action, 0, 0)
+
+		eventName := continued(fmt.Sprintf("slack %s: %s", ev.Type, ev.Channel), i, ref)
+		createJob(r.Context(), h.Gateway, ruleJob("slack", ref, rule.Action, eventName, msg, timeout, delay, body), ref)
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(`{"ok":true}`))
+}
+
+func (h *SlackHandler) findRules(eventType, channel string) []*config.SlackRule {
+	var matched []*config.SlackRule
+	for i, rule := range h.Config.Slack.Rules {
+		if rule.Event != eventType {
+			continue
+		}
+		if len(rule.Channels) == 0 || containsString(rule.Channels, channel) {
+			matched = append(matched, &h.Config.Slack.Rules[i])
+			if !rule.Continue {
+				return matched
+			}
+		}
+	}
+	return matched
+}
+
+func (h *SlackHandler) isIgnoredUser(user string) bool {
+	for _, ignored := range h.Config.Slack.IgnoreUsers {
+		if ignored == user {
+			return true
+		}
+	}
+	return false
+}
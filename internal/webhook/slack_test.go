@@ -0,0 +1,205 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/katalabut/openclaw-relay/internal/config"
+	"github.com/katalabut/openclaw-relay/internal/ratelimit"
+)
+
+func signSlack(body []byte, ts, secret string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte("v0:" + ts + ":"))
+	mac.Write(body)
+	return "v0=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func TestVerifySlackSignature(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	ts := strconv.FormatInt(now.Unix(), 10)
+	body := []byte(`{"type":"event_callback"}`)
+	sig := signSlack(body, ts, "secret")
+
+	tests := []struct {
+		name      string
+		ts        string
+		sig       string
+		secret    string
+		now       time.Time
+		tolerance time.Duration
+		want      bool
+	}{
+		{"valid", ts, sig, "secret", now, 0, true},
+		{"empty secret", "", "", "", now, 0, true},
+		{"wrong secret", ts, sig, "other", now, 0, false},
+		{"bad timestamp", "abc", sig, "secret", now, 0, false},
+		{"stale", ts, sig, "secret", now.Add(6 * time.Minute), 0, false},
+		{"future", ts, sig, "secret", now.Add(-6 * time.Minute), 0, false},
+		{"within skew", ts, sig, "secret", now.Add(4 * time.Minute), 0, true},
+		{"within configured tolerance", ts, sig, "secret", now.Add(8 * time.Minute), 10 * time.Minute, true},
+		{"outside configured tolerance", ts, sig, "secret", now.Add(-2 * time.Minute), time.Minute, false},
+	}
+	for _, tt := range tests {
+		if got := VerifySlackSignature(body, tt.ts, tt.sig, tt.secret, tt.now, tt.tolerance); got != tt.want {
+			t.Errorf("%s: got %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func newTestSlackHandler(gw *mockGateway) *SlackHandler {
+	cfg := &config.Config{
+		Slack: config.SlackConfig{
+			IgnoreUsers: []string{"U_IGNORED"},
+			Rules: []config.SlackRule{
+				{
+					Event:    "app_mention",
+					Channels: []string{"C_OPS"},
+					Action:   config.RuleAction{MessageTemplate: "{{.User}} in {{.Channel}}: {{.Text}}"},
+				},
+				{Event: "app_mention"},
+			},
+		},
+	}
+	return &SlackHandler{
+		Config:  cfg,
+		Gateway: gw,
+		Limiter: ratelimit.New(context.Background(), 5*time.Minute),
+	}
+}
+
+func slackEvent(eventID, evType, channel, user string) []byte {
+	body, _ := json.Marshal(map[string]any{
+		"type":     "event_callback",
+		"team_id":  "T1",
+		"event_id": eventID,
+		"event": map[string]any{
+			"type":    evType,
+			"channel": channel,
+			"user":    user,
+			"text":    "<@U_BOT> deploy please",
+			"ts":      "1700000000.000100",
+		},
+	})
+	return body
+}
+
+func TestSlackHandler_URLVerification(t *testing.T) {
+	h := newTestSlackHandler(&mockGateway{})
+	h.Config.Slack.SigningSecret = "secret"
+
+	body := []byte(`{"type":"url_verification","challenge":"abc123"}`)
+	ts := strconv.FormatInt(time.Now().Unix(), 10)
+	req := httptest.NewRequest("POST", "/webhook/slack", bytes.NewReader(body))
+	req.Header.Set("X-Slack-Request-Timestamp", ts)
+	req.Header.Set("X-Slack-Signature", signSlack(body, ts, "secret"))
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+
+	var resp map[string]string
+	json.NewDecoder(rec.Body).Decode(&resp)
+	if rec.Code != http.StatusOK || resp["challenge"] != "abc123" {
+		t.Errorf("expected challenge echo, got %d %v", rec.Code, resp)
+	}
+}
+
+func TestSlackHandler_InvalidSignature(t *testing.T) {
+	gw := &mockGateway{}
+	h := newTestSlackHandler(gw)
+	h.Config.Slack.SigningSecret = "secret"
+
+	req := httptest.NewRequest("POST", "/webhook/slack", bytes.NewReader(slackEvent("Ev1", "app_mention", "C_OPS", "U1")))
+	req.Header.Set("X-Slack-Request-Timestamp", strconv.FormatInt(time.Now().Unix(), 10))
+	req.Header.Set("X-Slack-Signature", "v0=bad")
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusForbidden || len(gw.calls) != 0 {
+		t.Errorf("expected 403 without dispatch, got %d (calls=%d)", rec.Code, len(gw.calls))
+	}
+}
+
+func TestSlackHandler_RuleMatching(t *testing.T) {
+	gw := &mockGateway{}
+	h := newTestSlackHandler(gw)
+
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("POST", "/webhook/slack", bytes.NewReader(slackEvent("Ev1", "app_mention", "C_OPS", "U1"))))
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("POST", "/webhook/slack", bytes.NewReader(slackEvent("Ev2", "app_mention", "C_OTHER", "U1"))))
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("POST", "/webhook/slack", bytes.NewReader(slackEvent("Ev3", "reaction_added", "C_OPS", "U1"))))
+
+	if len(gw.calls) != 2 {
+		t.Fatalf("expected 2 gateway calls, got %d", len(gw.calls))
+	}
+	if gw.calls[0].Message != "U1 in C_OPS: <@U_BOT> deploy please" {
+		t.Errorf("unexpected message: %q", gw.calls[0].Message)
+	}
+	if gw.calls[0].Name != "slack app_mention: C_OPS" {
+		t.Errorf("unexpected job name: %q", gw.calls[0].Name)
+	}
+	// Catch-all rule falls back to the default template
+	if !strings.Contains(gw.calls[1].Message, "Source: slack") || !strings.Contains(gw.calls[1].Message, "Channel: C_OTHER") {
+		t.Errorf("expected default template, got %q", gw.calls[1].Message)
+	}
+	if gw.calls[1].Timeout != 120 || gw.calls[1].Delay != 2 {
+		t.Errorf("expected default timeout/delay, got %d/%d", gw.calls[1].Timeout, gw.calls[1].Delay)
+	}
+}
+
+func TestSlackHandler_IgnoresBotsUsersAndRetries(t *testing.T) {
+	gw := &mockGateway{}
+	h := newTestSlackHandler(gw)
+
+	bot, _ := json.Marshal(map[string]any{
+		"type": "event_callback", "event_id": "EvBot",
+		"event": map[string]any{"type": "app_mention", "channel": "C_OPS", "bot_id": "B1"},
+	})
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("POST", "/webhook/slack", bytes.NewReader(bot)))
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("POST", "/webhook/slack", bytes.NewReader(slackEvent("EvIgn", "app_mention", "C_OPS", "U_IGNORED"))))
+	if len(gw.calls) != 0 {
+		t.Fatalf("expected bot and ignored user to be skipped, got %d calls", len(gw.calls))
+	}
+
+	for i := 0; i < 2; i++ {
+		req := httptest.NewRequest("POST", "/webhook/slack", bytes.NewReader(slackEvent("EvDup", "app_mention", "C_OPS", "U1")))
+		if i > 0 {
+			req.Header.Set("X-Slack-Retry-Num", "1")
+		}
+		h.ServeHTTP(httptest.NewRecorder(), req)
+	}
+	if len(gw.calls) != 1 {
+		t.Errorf("expected redelivery to be deduplicated, got %d calls", len(gw.calls))
+	}
+}
+
+func TestSlackHandler_MethodAndBadJSON(t *testing.T) {
+	gw := &mockGateway{}
+	h := newTestSlackHandler(gw)
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest("GET", "/webhook/slack", nil))
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("expected 405, got %d", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest("POST", "/webhook/slack", strings.NewReader("nope")))
+	if rec.Code != http.StatusOK || len(gw.calls) != 0 {
+		t.Errorf("expected 200 without dispatch, got %d (calls=%d)", rec.Code, len(gw.calls))
+	}
+
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest("POST", "/webhook/slack", strings.NewReader(`{"type":"app_rate_limited"}`)))
+	if rec.Code != http.StatusOK || len(gw.calls) != 0 {
+		t.Errorf("expected unknown payload type to be ignored, got %d (calls=%d)", rec.Code, len(gw.calls))
+	}
+}
@@ -1,6 +1,7 @@
 package webhook
 
 import (
+	"context"
 	"crypto/hmac"
 	"crypto/sha1"
 	"encoding/base64"
@@ -11,26 +12,62 @@ import (
 	"net/http"
 	"strings"
 	"text/template"
+	"time"
 
 	"github.com/katalabut/openclaw-relay/internal/config"
+	"github.com/katalabut/openclaw-relay/internal/events"
 	"github.com/katalabut/openclaw-relay/internal/gateway"
+	"github.com/katalabut/openclaw-relay/internal/lang"
+	"github.com/katalabut/openclaw-relay/internal/links"
 	"github.com/katalabut/openclaw-relay/internal/ratelimit"
+	"github.com/katalabut/openclaw-relay/internal/trello"
 )
 
 type TrelloHandler struct {
-	Config  *config.Config
-	Gateway gateway.GatewayClient
-	Limiter *ratelimit.Limiter
+	Config     *config.Config
+	Gateway    gateway.GatewayClient
+	Limiter    *ratelimit.Limiter
+	Deliveries *ratelimit.Deliveries // optional; drops redeliveries by action ID
+	Links      *links.Store          // optional; linked cards get their list updated on moves
+	Boards     *trello.BoardCache    // optional; list, label and member names for templates
+	Lists      *trello.ListDirectory // optional; trello.list_names aliases
+	Watches    *trello.Watches       // optional; cards in trello.watch_lists, for comments
+	Cards      *trello.CardLists     // optional; the current list of commented cards
+}
+
+// trelloCardFields are the card attributes rules can match on. Trello includes
+// them in action.data.card when they changed, and in model for card webhooks.
+type trelloCardFields struct {
+	ID     string `json:"id"`
+	Labels []struct {
+		ID    string `json:"id"`
+		Name  string `json:"name"`
+		Color string `json:"color"`
+	} `json:"labels"`
+	IDLabels  []string `json:"idLabels"`
+	IDMembers []string `json:"idMembers"`
+	Members   []struct {
+		ID       string `json:"id"`
+		Username string `json:"username"`
+	} `json:"members"`
+	Due         *time.Time `json:"due"`
+	DueComplete bool       `json:"dueComplete"`
 }
 
 type trelloPayload struct {
+	Model  trelloCardFields `json:"model"`
 	Action struct {
+		ID   string `json:"id"`
 		Type string `json:"type"`
 		Data struct {
 			Card struct {
-				ID   string `json:"id"`
-				Name string `json:"name"`
+				trelloCardFields
+				Name   string `json:"name"`
+				Closed bool   `json:"closed"`
 			} `json:"card"`
+			Old struct {
+				Closed *bool `json:"closed"` // set when the change archived or restored the card
+			} `json:"old"`
 			ListAfter struct {
 				ID   string `json:"id"`
 				Name string `json:"name"`
@@ -39,6 +76,7 @@ type trelloPayload struct {
 				ID   string `json:"id"`
 				Name string `json:"name"`
 			} `json:"listBefore"`
+			Text string `json:"text"` // comment text
 		} `json:"data"`
 		MemberCreator struct {
 			ID       string `json:"id"`
@@ -63,8 +101,9 @@ func VerifyTrelloSignature(body []byte, signature, secret, callbackURL string) b
 }
 
 func (h *TrelloHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	// Also answered by Handshakes, which the server wraps every source in
 	if r.Method == http.MethodHead {
-		w.WriteHeader(http.StatusOK)
+		handshake(w, r, "Trello", HandshakeHead, nil)
 		return
 	}
 	if r.Method != http.MethodPost {
@@ -80,11 +119,12 @@ func (h *TrelloHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 
 	sig := r.Header.Get("X-Trello-Webhook")
 	callbackURL := "https://" + r.Host + r.URL.Path
-	if h.Config.Trello.Secret != "" && !VerifyTrelloSignature(body, sig, h.Config.Trello.Secret, callbackURL) {
+	if h.Config.Trello.Secret != "" && !signatureBypassed(r, "Trello") && !VerifyTrelloSignature(body, sig, h.Config.Trello.Secret, callbackURL) {
 		log.Printf("Trello signature verification failed")
 		http.Error(w, "forbidden", http.StatusForbidden)
 		return
 	}
+	events.Verified(r.Context(), h.Config.Trello.Secret != "")
 
 	var payload trelloPayload
 	if err := json.Unmarshal(body, &payload); err != nil {
@@ -92,6 +132,11 @@ func (h *TrelloHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusOK)
 		return
 	}
+	w, dup := redelivered(w, r, h.Deliveries, "trello", payload.Action.ID)
+	if dup {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
 
 	actionType := payload.Action.Type
 	cardID := payload.Action.Data.Card.ID
@@ -100,6 +145,20 @@ func (h *TrelloHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	listAfterName := payload.Action.Data.ListAfter.Name
 	listBeforeName := payload.Action.Data.ListBefore.Name
 
+	// Keep linked cards' list current, even for moves no rule acts on
+	if h.Links != nil && actionType == "updateCard" && listAfterName != "" {
+		if _, err := h.Links.SetList(cardID, listAfterName); err != nil {
+			log.Printf("Trello: failed to update linked card %s: %v", cardID, err)
+		}
+	}
+	h.updateWatch(r.Context(), &payload)
+	switch {
+	case actionType == "deleteCard":
+		h.Cards.Forget(cardID)
+	case actionType == "updateCard" && listAfterID != "":
+		h.Cards.Set(cardID, listAfterID)
+	}
+
 	var eventType string
 	switch actionType {
 	case "updateCard":
@@ -108,7 +167,7 @@ func (h *TrelloHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 			w.WriteHeader(http.StatusOK)
 			return
 		}
-		listName := h.Config.ListIDToName(listAfterID)
+		listName := h.listAlias(r.Context(), listAfterID)
 		if listName == "" {
 			log.Printf("Trello: ignoring move to unwatched list %s for %s", listAfterName, cardName)
 			w.WriteHeader(http.StatusOK)
@@ -141,86 +200,226 @@ func (h *TrelloHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Rate limit
-	rateLimitKey := fmt.Sprintf("trello:%s:%s", cardID, actionType)
-	if !h.Limiter.Allow(rateLimitKey) {
-		log.Printf("Trello: rate limited card %s (%s) action %s", cardName, cardID, actionType)
-		w.WriteHeader(http.StatusOK)
-		return
-	}
-
 	log.Printf("Trello: processing %s for card %s", eventType, cardName)
+	events.Annotate(r.Context(), eventType, "", "")
 
-	// Find matching rule
-	listName := h.Config.ListIDToName(listAfterID)
-	rule := h.findRule(eventType, listName)
-	if rule == nil {
-		log.Printf("Trello: no matching rule for event=%s list=%s", eventType, listName)
+	// Find matching rules
+	card := payload.card(h.listAlias(r.Context(), listAfterID))
+	if eventType == "comment_added" {
+		// Comments carry no list: a watched card's comes from its watch,
+		// another's is looked up on Trello
+		if watch, ok := h.Watches.Lookup(cardID); ok {
+			card.List, card.Watched = watch.List, true
+		} else if id := h.Cards.ListID(r.Context(), cardID); id != "" {
+			listAfterID, listAfterName = id, h.Boards.ListName(id)
+			card.List = h.listAlias(r.Context(), id)
+		}
+	}
+	rules := h.findRules(eventType, card, time.Now())
+	if len(rules) == 0 {
+		log.Printf("Trello: no matching rule for event=%s list=%s", eventType, card.List)
+		events.Annotate(r.Context(), "", "", events.OutcomeNoRule)
 		w.WriteHeader(http.StatusOK)
 		return
 	}
 
-	// Render message
-	msg := h.renderMessage(rule.Action.MessageTemplate, map[string]string{
+	due := ""
+	if card.Due != nil {
+		due = card.Due.UTC().Format(time.RFC3339)
+	}
+
+	cardLang := lang.Detect(cardName + "\n" + payload.Action.Data.Text)
+	data := map[string]string{
 		"CardID":         cardID,
 		"CardName":       cardName,
 		"ListAfterID":    listAfterID,
 		"ListAfterName":  listAfterName,
+		"ListBeforeID":   payload.Action.Data.ListBefore.ID,
 		"ListBeforeName": listBeforeName,
 		"ListName":       listAfterName,
-	})
+		"Labels":         strings.Join(card.Labels, ", "),
+		"Members":        strings.Join(card.Members, ", "),
+		"Due":            due,
+		"Lang":           cardLang,
 
-	timeout := rule.Action.Timeout
-	if timeout == 0 {
-		timeout = 120
-	}
-	delay := rule.Action.Delay
-	if delay == 0 {
-		delay = 2
+		"MemberCreatorID":       payload.Action.MemberCreator.ID,
+		"MemberCreatorUsername": payload.Action.MemberCreator.Username,
 	}
 
-	eventName := fmt.Sprintf("%s: %s", eventType, cardName)
-	if err := h.Gateway.CreateOneShotJobForAgent(eventName, msg, rule.Action.AgentID, timeout, delay); err != nil {
-		log.Printf("Failed to create job: %v", err)
+	defaultKey := fmt.Sprintf("trello:%s:%s", cardID, actionType)
+	for i, rule := range rules {
+		ref := ruleRef("trello", h.Config.Trello.Rules, rule)
+
+		if sampledOut("Trello", eventType, rule.Sample) {
+			events.Annotate(r.Context(), "", ref, events.OutcomeSampledOut)
+			continue
+		}
+
+		// Rate limit
+		rateLimitKey := dedupKey("trello", rule.DedupKey, data, defaultKey)
+		if !h.Limiter.AllowWithin(continued(rateLimitKey, i, ref), config.RateWindow(rule.RateLimit, h.Config.Trello.RateLimit)) {
+			log.Printf("Trello: rate limited card %s (%s) action %s", cardName, cardID, actionType)
+			events.Annotate(r.Context(), "", ref, events.OutcomeRateLimited)
+			continue
+		}
+
+		eventName := continued(fmt.Sprintf("%s: %s", eventType, cardName), i, ref)
+		for n, action := range rule.ResolvedActions() {
+			// Render message, in the card's language when the action has a variant for it
+			msg := h.renderMessage(action.LocalizedTemplate(cardLang), data)
+
+			timeout, delay := jobTiming(h.Config, action, 0, 0)
+
+			createJob(r.Context(), h.Gateway, ruleJob("trello", ref, action, actionJobName(eventName, n), msg, timeout, delay, body), ref)
+		}
 	}
 
 	w.WriteHeader(http.StatusOK)
 	w.Write([]byte(`{"ok":true}`))
 }
 
-func (h *TrelloHandler) findRule(eventType, listName string) *config.TrelloRule {
+// trelloCard is what Trello rule conditions see of a card.
+type trelloCard struct {
+	Name        string
+	List        string   // alias from trello.lists
+	Labels      []string // label names (color for unnamed labels)
+	LabelIDs    []string
+	Members     []string // usernames when present, else member IDs
+	MemberIDs   []string
+	Due         *time.Time
+	DueComplete bool
+	Watched     bool // a comment on a card in trello.watch_lists
+}
+
+// card collects the card's labels, members and due date from action.data.card
+// and, for webhooks registered on the card itself, from model.
+func (p *trelloPayload) card(listName string) trelloCard {
+	c := trelloCard{Name: p.Action.Data.Card.Name, List: listName}
+	sources := []trelloCardFields{p.Action.Data.Card.trelloCardFields}
+	if p.Model.ID != "" && p.Model.ID == p.Action.Data.Card.ID {
+		sources = append(sources, p.Model)
+	}
+	for _, f := range sources {
+		for _, l := range f.Labels {
+			name := l.Name
+			if name == "" {
+				name = l.Color
+			}
+			c.Labels = appendUnique(c.Labels, name)
+			c.LabelIDs = appendUnique(c.LabelIDs, l.ID)
+		}
+		for _, id := range f.IDLabels {
+			c.LabelIDs = appendUnique(c.LabelIDs, id)
+		}
+		for _, m := range f.Members {
+			c.Members = appendUnique(c.Members, m.Username)
+			c.MemberIDs = appendUnique(c.MemberIDs, m.ID)
+		}
+		for _, id := range f.IDMembers {
+			c.MemberIDs = appendUnique(c.MemberIDs, id)
+		}
+		if c.Due == nil && f.Due != nil {
+			c.Due, c.DueComplete = f.Due, f.DueComplete
+		}
+	}
+	if len(c.Members) == 0 {
+		c.Members = c.MemberIDs
+	}
+	return c
+}
+
+func appendUnique(list []string, v string) []string {
+	if v == "" || containsString(list, v) {
+		return list
+	}
+	return append(list, v)
+}
+
+func (h *TrelloHandler) findRules(eventType string, card trelloCard, now time.Time) []*config.TrelloRule {
+	var matched []*config.TrelloRule
 	for i, rule := range h.Config.Trello.Rules {
 		if rule.Event != eventType {
 			continue
 		}
-		if h.matchCondition(rule.Condition, listName) {
-			return &h.Config.Trello.Rules[i]
+		if h.matchCondition(rule.Condition, card, now) {
+			matched = append(matched, &h.Config.Trello.Rules[i])
+			if !rule.Continue {
+				return matched
+			}
 		}
 	}
-	return nil
+	return matched
 }
 
-func (h *TrelloHandler) matchCondition(condition, listName string) bool {
-	if condition == "" {
-		return true
+// matchCondition evaluates a rule condition (see internal/expr) with the fields:
+//
+//	list     list alias from trello.lists
+//	label    label names and IDs (label == 'urgent' matches any label)
+//	member   member usernames and IDs
+//	due      time until an open due date (due < 24h); absent when unset or complete
+//	overdue  true when an open due date has passed
+//	card     card name
+//	watched  true for a comment on a card watched in trello.watch_lists
+func (h *TrelloHandler) matchCondition(condition string, card trelloCard, now time.Time) bool {
+	return evalCondition(condition, trelloEnv(card, now))
+}
+
+func trelloEnv(card trelloCard, now time.Time) map[string]any {
+	labels := append(append([]string{}, card.Labels...), card.LabelIDs...)
+	members := append(append([]string{}, card.Members...), card.MemberIDs...)
+	env := map[string]any{
+		"list":    card.List,
+		"label":   labels,
+		"member":  members,
+		"overdue": false,
+		"card":    card.Name,
+		"watched": card.Watched,
+	}
+	if card.Due != nil && !card.DueComplete {
+		left := card.Due.Sub(now)
+		env["due"] = left
+		env["overdue"] = left < 0
+	}
+	return env
+}
+
+// updateWatch starts a card's comment watch when it moves into a list of
+// trello.watch_lists, and ends it when the card moves elsewhere, is archived
+// or is deleted.
+func (h *TrelloHandler) updateWatch(ctx context.Context, payload *trelloPayload) {
+	if h.Watches == nil {
+		return
 	}
-	// Simple condition parser: "list == 'ready'" or "list == 'x' || list == 'y'"
-	parts := strings.Split(condition, "||")
-	for _, part := range parts {
-		part = strings.TrimSpace(part)
-		if strings.Contains(part, "list ==") {
-			// Extract quoted value
-			start := strings.Index(part, "'")
-			end := strings.LastIndex(part, "'")
-			if start >= 0 && end > start {
-				val := part[start+1 : end]
-				if val == listName {
-					return true
-				}
+	d := payload.Action.Data
+	cardID := d.Card.ID
+	if cardID == "" {
+		return
+	}
+	var (
+		ended bool
+		err   error
+	)
+	switch {
+	case payload.Action.Type == "deleteCard",
+		payload.Action.Type == "updateCard" && d.Card.Closed && d.Old.Closed != nil:
+		ended, err = h.Watches.Unwatch(cardID)
+	case payload.Action.Type == "updateCard" && d.ListAfter.ID != "":
+		alias := h.listAlias(ctx, d.ListAfter.ID)
+		if alias != "" && containsString(h.Config.Trello.WatchLists, alias) {
+			var started bool
+			if started, err = h.Watches.Watch(cardID, d.Card.Name, alias); started {
+				log.Printf("Trello: watching comments on %s (%s) in %s", d.Card.Name, cardID, alias)
 			}
+			break
 		}
+		ended, err = h.Watches.Unwatch(cardID)
+	}
+	if err != nil {
+		log.Printf("Trello: failed to update comment watch for %s: %v", cardID, err)
+	}
+	if ended {
+		log.Printf("Trello: stopped watching comments on %s (%s)", d.Card.Name, cardID)
 	}
-	return false
 }
 
 func (h *TrelloHandler) isIgnoredMember(memberID, username string) bool {
@@ -232,8 +431,42 @@ func (h *TrelloHandler) isIgnoredMember(memberID, username string) bool {
 	return false
 }
 
+// listAlias returns the trello.lists or trello.list_names alias of a list ID.
+// An ID neither knows makes the list directory refresh the boards once, in
+// case the list was recreated under a configured name.
+func (h *TrelloHandler) listAlias(ctx context.Context, id string) string {
+	if alias := h.Config.ListIDToName(id); alias != "" {
+		return alias
+	}
+	return h.Lists.Lookup(ctx, id)
+}
+
+// templateFuncs resolves Trello IDs to names from the board cache. Names that
+// aren't cached fall back to the trello.lists or list_names alias, then to the ID.
+func (h *TrelloHandler) templateFuncs() template.FuncMap {
+	orID := func(name, id string) string {
+		if name == "" {
+			return id
+		}
+		return name
+	}
+	return template.FuncMap{
+		"listNameByID": func(id string) string {
+			if name := h.Boards.ListName(id); name != "" {
+				return name
+			}
+			if alias := h.Config.ListIDToName(id); alias != "" {
+				return alias
+			}
+			return orID(h.Lists.Alias(id), id)
+		},
+		"memberName": func(idOrUsername string) string { return orID(h.Boards.MemberName(idOrUsername), idOrUsername) },
+		"labelName":  func(id string) string { return orID(h.Boards.LabelName(id), id) },
+	}
+}
+
 func (h *TrelloHandler) renderMessage(tmpl string, data map[string]string) string {
-	t, err := template.New("msg").Parse(tmpl)
+	t, err := template.New("msg").Funcs(h.templateFuncs()).Parse(tmpl)
 	if err != nil {
 		log.Printf("Template parse error: %v", err)
 		return tmpl
@@ -7,13 +7,18 @@ import (
 	"crypto/sha1"
 	"encoding/base64"
 	"encoding/json"
+	"maps"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/katalabut/openclaw-relay/internal/config"
+	"github.com/katalabut/openclaw-relay/internal/gateway"
+	"github.com/katalabut/openclaw-relay/internal/links"
 	"github.com/katalabut/openclaw-relay/internal/ratelimit"
+	"github.com/katalabut/openclaw-relay/internal/trello"
 )
 
 type mockGateway struct {
@@ -25,15 +30,24 @@ type mockGatewayCall struct {
 	Message string
 	Timeout int
 	Delay   int
+	Tags    map[string]string
 }
 
 func (m *mockGateway) CreateOneShotJob(name, message string, timeoutSeconds, delaySeconds int) error {
-	m.calls = append(m.calls, mockGatewayCall{name, message, timeoutSeconds, delaySeconds})
-	return nil
+	return m.CreateOneShotJobForAgent(name, message, "", timeoutSeconds, delaySeconds)
 }
 
 func (m *mockGateway) CreateOneShotJobForAgent(name, message, agentID string, timeoutSeconds, delaySeconds int) error {
-	m.calls = append(m.calls, mockGatewayCall{name, message, timeoutSeconds, delaySeconds})
+	return m.CreateJob(gateway.JobSpec{Name: name, Message: message, AgentID: agentID, TimeoutSeconds: timeoutSeconds, DelaySeconds: delaySeconds})
+}
+
+func (m *mockGateway) CreateJob(spec gateway.JobSpec) error {
+	tags := map[string]string{"source": spec.Source}
+	if spec.Rule != "" {
+		tags["rule"] = spec.Rule
+	}
+	maps.Copy(tags, spec.Tags)
+	m.calls = append(m.calls, mockGatewayCall{spec.Name, spec.Message, spec.TimeoutSeconds, spec.DelaySeconds, tags})
 	return nil
 }
 
@@ -77,7 +91,7 @@ func TestMatchCondition(t *testing.T) {
 		{"", "anything", true},
 	}
 	for _, tt := range tests {
-		got := h.matchCondition(tt.cond, tt.list)
+		got := h.matchCondition(tt.cond, trelloCard{List: tt.list}, time.Now())
 		if got != tt.want {
 			t.Errorf("matchCondition(%q, %q) = %v, want %v", tt.cond, tt.list, got, tt.want)
 		}
@@ -138,6 +152,28 @@ func makeTrelloPayload(actionType, cardID, cardName, listAfterID, listAfterName,
 	return b
 }
 
+func TestServeHTTP_Trello_Redelivery(t *testing.T) {
+	gw := &mockGateway{}
+	h := newTestTrelloHandler(gw)
+	h.Limiter = ratelimit.New(context.Background(), time.Nanosecond)
+	h.Deliveries, _ = ratelimit.NewDeliveries("", 10)
+
+	send := func(actionID string) {
+		body := []byte(`{"action":{"id":"` + actionID + `","type":"updateCard","data":{"card":{"id":"card1","name":"My Card"},"listAfter":{"id":"list-ready-id","name":"Ready"}}}}`)
+		h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("POST", "/webhook/trello", bytes.NewReader(body)))
+		time.Sleep(time.Millisecond)
+	}
+	send("a1")
+	send("a1")
+	if len(gw.calls) != 1 {
+		t.Fatalf("expected the redelivery dropped, got %d calls", len(gw.calls))
+	}
+	send("a2")
+	if len(gw.calls) != 2 {
+		t.Errorf("expected a new action to pass, got %d calls", len(gw.calls))
+	}
+}
+
 func TestServeHTTP_InvalidSignature(t *testing.T) {
 	gw := &mockGateway{}
 	h := newTestTrelloHandler(gw)
@@ -261,25 +297,138 @@ func TestServeHTTP_Comment_OtherColumn(t *testing.T) {
 	}
 }
 
+func TestServeHTTP_Comment_Watch(t *testing.T) {
+	gw := &mockGateway{}
+	h := newTestTrelloHandler(gw)
+	h.Limiter = ratelimit.New(context.Background(), time.Nanosecond)
+	h.Config.Trello.WatchLists = []string{"questions"}
+	h.Watches, _ = trello.NewWatches("", time.Hour)
+
+	send := func(body string) {
+		h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("POST", "/webhook/trello", strings.NewReader(body)))
+		time.Sleep(time.Millisecond)
+	}
+	// Real comment payloads have no listAfter
+	comment := `{"action":{"type":"commentCard","data":{"card":{"id":"card1","name":"My Card"},"text":"answered"}}}`
+
+	send(comment)
+	if len(gw.calls) != 0 {
+		t.Fatalf("comment before the card entered questions: %d calls", len(gw.calls))
+	}
+	send(string(makeTrelloPayload("updateCard", "card1", "My Card", "list-questions-id", "Questions", "list-ready-id", "Ready")))
+	if w, ok := h.Watches.Lookup("card1"); !ok || w.List != "questions" || len(gw.calls) != 0 {
+		t.Fatalf("move to questions: watch %+v %v, %d calls", w, ok, len(gw.calls))
+	}
+	send(comment)
+	if len(gw.calls) != 1 || gw.calls[0].Message != "Comment on My Card" {
+		t.Fatalf("comment on watched card: %+v", gw.calls)
+	}
+
+	// Leaving the list ends the watch
+	send(string(makeTrelloPayload("updateCard", "card1", "My Card", "list-ready-id", "Ready", "list-questions-id", "Questions")))
+	send(comment)
+	if _, ok := h.Watches.Lookup("card1"); ok || len(gw.calls) != 2 {
+		t.Errorf("after leaving questions: %d calls", len(gw.calls))
+	}
+
+	// So does archiving the card
+	h.Watches.Watch("card1", "My Card", "questions")
+	send(`{"action":{"type":"updateCard","data":{"card":{"id":"card1","name":"My Card","closed":true},"old":{"closed":false}}}}`)
+	if _, ok := h.Watches.Lookup("card1"); ok {
+		t.Error("archived card still watched")
+	}
+	h.Watches.Watch("card1", "My Card", "questions")
+	send(`{"action":{"type":"deleteCard","data":{"card":{"id":"card1"}}}}`)
+	if _, ok := h.Watches.Lookup("card1"); ok {
+		t.Error("deleted card still watched")
+	}
+}
+
+func TestServeHTTP_Comment_CardList(t *testing.T) {
+	var lookups int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lookups++
+		if r.URL.Path != "/1/cards/card1" || r.URL.Query().Get("fields") != "idList" {
+			t.Errorf("request = %s?%s", r.URL.Path, r.URL.RawQuery)
+		}
+		w.Write([]byte(`{"id":"card1","idList":"list-questions-id"}`))
+	}))
+	defer srv.Close()
+
+	gw := &mockGateway{}
+	h := newTestTrelloHandler(gw)
+	h.Limiter = ratelimit.New(context.Background(), time.Nanosecond)
+	h.Cards = trello.NewCardLists(trello.NewClient("k", "t", srv.URL))
+	send := func(body string) {
+		h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("POST", "/webhook/trello", strings.NewReader(body)))
+		time.Sleep(time.Millisecond)
+	}
+	comment := `{"action":{"type":"commentCard","data":{"card":{"id":"card1","name":"My Card"},"text":"answered"}}}`
+
+	// The card's list comes from Trello, then from the cache
+	send(comment)
+	send(comment)
+	if len(gw.calls) != 2 || gw.calls[0].Message != "Comment on My Card" || lookups != 1 {
+		t.Fatalf("comments: %+v, %d lookups", gw.calls, lookups)
+	}
+
+	// A move the relay hears of updates the cached list
+	send(string(makeTrelloPayload("updateCard", "card1", "My Card", "list-ready-id", "Ready", "list-questions-id", "Questions")))
+	send(comment)
+	if len(gw.calls) != 3 || lookups != 1 {
+		t.Errorf("comment after leaving questions: %d calls, %d lookups", len(gw.calls), lookups)
+	}
+}
+
 func TestFindRule_MatchFirst(t *testing.T) {
 	h := newTestTrelloHandler(&mockGateway{})
-	rule := h.findRule("card_moved", "ready")
-	if rule == nil {
-		t.Fatal("expected to find rule")
+	rules := h.findRules("card_moved", trelloCard{List: "ready"}, time.Now())
+	if len(rules) != 1 {
+		t.Fatalf("expected to find one rule, got %d", len(rules))
 	}
-	if rule.Action.Timeout != 120 {
+	if rules[0].Action.Timeout != 120 {
 		t.Errorf("wrong rule matched")
 	}
 }
 
 func TestFindRule_NoMatch(t *testing.T) {
 	h := newTestTrelloHandler(&mockGateway{})
-	rule := h.findRule("card_moved", "nonexistent")
-	if rule != nil {
+	rules := h.findRules("card_moved", trelloCard{List: "nonexistent"}, time.Now())
+	if len(rules) != 0 {
 		t.Error("expected no match")
 	}
 }
 
+func TestServeHTTP_Trello_Continue(t *testing.T) {
+	gw := &mockGateway{}
+	h := newTestTrelloHandler(gw)
+	catchAll := config.TrelloRule{Event: "card_moved", Action: config.RuleAction{MessageTemplate: "Log {{.CardName}}"}}
+	h.Config.Trello.Rules = append(h.Config.Trello.Rules, catchAll, catchAll)
+
+	body := makeTrelloPayload("updateCard", "card1", "Fix login", "list-ready-id", "Ready", "", "Dev")
+	post := func() {
+		h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("POST", "/webhook/trello", bytes.NewReader(body)))
+	}
+
+	// Without continue the first match wins
+	post()
+	if len(gw.calls) != 1 || gw.calls[0].Message != "Card Fix login moved to Ready" {
+		t.Fatalf("expected only the first rule to fire, got %+v", gw.calls)
+	}
+
+	// continue: true lets evaluation go on to the next match, and stops after it
+	gw.calls = nil
+	h.Limiter = ratelimit.New(context.Background(), 5*time.Minute)
+	h.Config.Trello.Rules[0].Continue = true
+	post()
+	if len(gw.calls) != 2 || gw.calls[1].Message != "Log Fix login" {
+		t.Fatalf("expected the first and the catch-all rule to fire, got %+v", gw.calls)
+	}
+	if gw.calls[1].Name != gw.calls[0].Name+" [trello.rules[2]]" {
+		t.Errorf("expected distinct job names, got %q and %q", gw.calls[0].Name, gw.calls[1].Name)
+	}
+}
+
 func TestRenderMessage_AllVars(t *testing.T) {
 	h := &TrelloHandler{}
 	msg := h.renderMessage("Card {{.CardName}} to {{.ListAfterName}}", map[string]string{
@@ -300,6 +449,54 @@ func TestRenderMessage_InvalidTemplate(t *testing.T) {
 	}
 }
 
+func TestRenderMessage_BoardLookups(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"id":"b1","lists":[{"id":"list-dev-id","name":"In Progress"}],
+			"labels":[{"id":"lab1","name":"urgent"}],"members":[{"id":"m1","username":"alice","fullName":"Alice A."}]}`))
+	}))
+	defer srv.Close()
+	boards := trello.NewBoardCache(trello.NewClient("k", "t", srv.URL), []string{"b1"})
+	if err := boards.Refresh(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	gw := &mockGateway{}
+	h := newTestTrelloHandler(gw)
+	h.Boards = boards
+	h.Config.Trello.Rules[0].Action.MessageTemplate = `{{listNameByID .ListBeforeID}} -> {{listNameByID .ListAfterID}}, {{memberName "alice"}}, {{labelName "lab1"}}, {{memberName "m9"}}`
+
+	body := makeTrelloPayload("updateCard", "card1", "Fix login", "list-ready-id", "", "list-dev-id", "")
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("POST", "/webhook/trello", bytes.NewReader(body)))
+	if len(gw.calls) != 1 {
+		t.Fatalf("expected 1 job, got %d", len(gw.calls))
+	}
+	// Lists the cache doesn't know fall back to the trello.lists alias; unknown members to the ID
+	if want := "In Progress -> ready, Alice A., urgent, m9"; gw.calls[0].Message != want {
+		t.Errorf("expected %q, got %q", want, gw.calls[0].Message)
+	}
+}
+
+func TestServeHTTP_CardMoved_NamedList(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"id":"b1","lists":[{"id":"list-review-id","name":"Code Review"}]}`))
+	}))
+	defer srv.Close()
+	boards := trello.NewBoardCache(trello.NewClient("k", "t", srv.URL), []string{"b1"})
+	lists, _ := trello.NewListDirectory("", map[string]string{"review": "code review"}, boards)
+
+	gw := &mockGateway{}
+	h := newTestTrelloHandler(gw)
+	h.Lists = lists
+	h.Config.Trello.Rules[0].Condition = "list == 'review'"
+
+	// The first move to the unknown ID resolves list_names from the board
+	body := makeTrelloPayload("updateCard", "card1", "Fix login", "list-review-id", "Code Review", "", "")
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("POST", "/webhook/trello", bytes.NewReader(body)))
+	if len(gw.calls) != 1 || gw.calls[0].Message != "Card Fix login moved to Code Review" {
+		t.Fatalf("expected the review rule to fire, got %+v", gw.calls)
+	}
+}
+
 func TestServeHTTP_RateLimited(t *testing.T) {
 	gw := &mockGateway{}
 	h := newTestTrelloHandler(gw)
@@ -323,6 +520,37 @@ func TestServeHTTP_RateLimited(t *testing.T) {
 	}
 }
 
+func TestServeHTTP_DedupKey(t *testing.T) {
+	send := func(dedupKey string) int {
+		gw := &mockGateway{}
+		h := newTestTrelloHandler(gw)
+		h.Config.Trello.Rules = []config.TrelloRule{
+			{Event: "card_moved", DedupKey: dedupKey, Action: config.RuleAction{MessageTemplate: "moved"}},
+			{Event: "comment_added", DedupKey: dedupKey, Action: config.RuleAction{MessageTemplate: "comment"}},
+		}
+		for _, action := range []string{"updateCard", "commentCard"} {
+			body := makeTrelloPayload(action, "card1", "My Card", "list-ready-id", "Ready", "", "Dev")
+			h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("POST", "/webhook/trello", bytes.NewReader(body)))
+		}
+		return len(gw.calls)
+	}
+
+	// The default key includes the action type, so a move and a comment both fire
+	if n := send(""); n != 2 {
+		t.Errorf("default key: expected 2 calls, got %d", n)
+	}
+	if n := send("{{.CardID}}"); n != 1 {
+		t.Errorf("card key: expected 1 call, got %d", n)
+	}
+	// Keys naming a missing field or rendering empty fall back to the default
+	if n := send("{{.Missing}}"); n != 2 {
+		t.Errorf("missing field: expected 2 calls, got %d", n)
+	}
+	if n := send("{{.Due}}"); n != 2 {
+		t.Errorf("empty key: expected 2 calls, got %d", n)
+	}
+}
+
 func TestServeHTTP_MethodNotAllowed(t *testing.T) {
 	gw := &mockGateway{}
 	h := newTestTrelloHandler(gw)
@@ -436,3 +664,168 @@ func TestServeHTTP_HeadRequest(t *testing.T) {
 		t.Errorf("HEAD should return 200, got %d", rec.Code)
 	}
 }
+
+func TestMatchCondition_LabelsMembersDue(t *testing.T) {
+	h := &TrelloHandler{}
+	now := time.Date(2026, 3, 27, 12, 0, 0, 0, time.UTC)
+	soon := now.Add(6 * time.Hour)
+	past := now.Add(-time.Hour)
+	card := trelloCard{
+		List:      "ready",
+		Labels:    []string{"Urgent", "backend"},
+		LabelIDs:  []string{"lab1", "lab2"},
+		Members:   []string{"alice"},
+		MemberIDs: []string{"mem1"},
+		Due:       &soon,
+	}
+	tests := []struct {
+		cond string
+		card trelloCard
+		want bool
+	}{
+		{"label == 'Urgent' && list == 'ready'", card, true},
+		{"label == 'urgent'", card, false},
+		{"label =~ '(?i)^urgent$'", card, true},
+		{"label == 'urgent' && list == 'dev'", card, false},
+		{"label == 'lab2'", card, true},
+		{"label != 'blocked'", card, true},
+		{"label != 'backend'", card, false},
+		{"member == 'alice'", card, true},
+		{"member == 'mem1'", card, true},
+		{"member == 'bob' || label == 'backend'", card, true},
+		{"list != 'ready'", card, false},
+		{"due < 24h", card, true},
+		{"due < 1h", card, false},
+		{"due > 2d", card, false},
+		{"due > 1h && !overdue", card, true},
+		{"overdue", trelloCard{Due: &past}, true},
+		{"overdue", trelloCard{Due: &past, DueComplete: true}, false},
+		{"due < 24h", trelloCard{Due: &past, DueComplete: true}, false},
+		{"due < 24h", trelloCard{}, false},
+		{"due < soon", card, false},
+		{"priority == 'high'", card, false},
+		{"list", card, true},
+		{"(label == 'backend' || member == 'bob') && !overdue", card, true},
+		{"label in ['blocked', 'lab1']", card, true},
+		{"card =~ '^Fix'", trelloCard{Name: "Fix login"}, true},
+		{"label < 3", card, false},
+	}
+	for _, tt := range tests {
+		if got := h.matchCondition(tt.cond, tt.card, now); got != tt.want {
+			t.Errorf("matchCondition(%q) = %v, want %v", tt.cond, got, tt.want)
+		}
+	}
+}
+
+func TestTrelloPayload_Card(t *testing.T) {
+	body := `{
+		"model": {"id": "card1", "idMembers": ["mem1"], "members": [{"id": "mem1", "username": "alice"}],
+			"labels": [{"id": "lab1", "name": "Urgent"}, {"id": "lab2", "name": "", "color": "red"}],
+			"due": "2026-03-28T09:00:00.000Z", "dueComplete": false},
+		"action": {"type": "updateCard", "data": {"card": {"id": "card1", "name": "Fix login", "idLabels": ["lab3"]}}}
+	}`
+	var p trelloPayload
+	if err := json.Unmarshal([]byte(body), &p); err != nil {
+		t.Fatal(err)
+	}
+	card := p.card("ready")
+	if card.List != "ready" || strings.Join(card.Labels, ",") != "Urgent,red" || strings.Join(card.LabelIDs, ",") != "lab3,lab1,lab2" {
+		t.Errorf("unexpected labels: %+v", card)
+	}
+	if strings.Join(card.Members, ",") != "alice" || strings.Join(card.MemberIDs, ",") != "mem1" {
+		t.Errorf("unexpected members: %+v", card)
+	}
+	if card.Due == nil || card.Due.Day() != 28 {
+		t.Errorf("unexpected due: %v", card.Due)
+	}
+
+	// A board webhook's model is the board, not the card
+	p.Model.ID = "board1"
+	if card := p.card(""); len(card.Labels) != 0 || card.Due != nil || len(card.LabelIDs) != 1 {
+		t.Errorf("expected only action card fields, got %+v", card)
+	}
+}
+
+func TestServeHTTP_LabelRuleAndTemplate(t *testing.T) {
+	gw := &mockGateway{}
+	h := newTestTrelloHandler(gw)
+	h.Config.Trello.Rules = []config.TrelloRule{{
+		Event:     "card_moved",
+		Condition: "label == 'urgent' && list == 'ready'",
+		Action:    config.RuleAction{MessageTemplate: "{{.CardName}} [{{.Labels}}] @{{.Members}} due {{.Due}}"},
+	}}
+	body := `{"action": {"type": "updateCard", "data": {
+		"card": {"id": "card1", "name": "Fix login", "labels": [{"id": "l1", "name": "urgent"}], "idMembers": ["m1"], "due": "2026-03-28T09:00:00Z"},
+		"listAfter": {"id": "list-ready-id", "name": "Ready"}}}}`
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("POST", "/webhook/trello", strings.NewReader(body)))
+	if len(gw.calls) != 1 {
+		t.Fatalf("expected 1 gateway call, got %d", len(gw.calls))
+	}
+	if want := "Fix login [urgent] @m1 due 2026-03-28T09:00:00Z"; gw.calls[0].Message != want {
+		t.Errorf("message = %q, want %q", gw.calls[0].Message, want)
+	}
+
+	unlabeled := makeTrelloPayload("updateCard", "card2", "Other", "list-ready-id", "Ready", "", "Dev")
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("POST", "/webhook/trello", bytes.NewReader(unlabeled)))
+	if len(gw.calls) != 1 {
+		t.Errorf("expected unlabeled card to be skipped, got %d calls", len(gw.calls))
+	}
+}
+
+func TestServeHTTP_LocalizedTemplate(t *testing.T) {
+	gw := &mockGateway{}
+	h := newTestTrelloHandler(gw)
+	h.Config.Trello.Rules[0].Action.MessageTemplates = map[string]string{
+		"ru": "Карточка {{.CardName}} ({{.Lang}})",
+	}
+
+	for _, name := range []string{"Исправить вход в личный кабинет", "Fix login"} {
+		body := makeTrelloPayload("updateCard", "card-"+name, name, "list-ready-id", "Ready", "", "Dev")
+		h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("POST", "/webhook/trello", bytes.NewReader(body)))
+	}
+	if len(gw.calls) != 2 {
+		t.Fatalf("expected 2 gateway calls, got %d", len(gw.calls))
+	}
+	if want := "Карточка Исправить вход в личный кабинет (ru)"; gw.calls[0].Message != want {
+		t.Errorf("message = %q, want %q", gw.calls[0].Message, want)
+	}
+	if want := "Card Fix login moved to Ready"; gw.calls[1].Message != want {
+		t.Errorf("message = %q, want %q", gw.calls[1].Message, want)
+	}
+}
+
+func TestServeHTTP_MultipleActions(t *testing.T) {
+	gw := &mockGateway{}
+	h := newTestTrelloHandler(gw)
+	h.Config.Trello.Rules[0].Actions = []config.RuleAction{
+		{MessageTemplate: "Start {{.CardName}}"},
+		{MessageTemplate: "Review {{.CardName}}", Timeout: 300},
+	}
+
+	body := makeTrelloPayload("updateCard", "card1", "Fix login", "list-ready-id", "Ready", "", "Dev")
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("POST", "/webhook/trello", bytes.NewReader(body)))
+	if len(gw.calls) != 2 {
+		t.Fatalf("expected a job per action, got %d", len(gw.calls))
+	}
+	if gw.calls[0].Message != "Start Fix login" || gw.calls[1].Message != "Review Fix login" || gw.calls[1].Timeout != 300 {
+		t.Errorf("unexpected jobs %+v", gw.calls)
+	}
+	if gw.calls[1].Name != gw.calls[0].Name+" #2" {
+		t.Errorf("expected distinct job names, got %q and %q", gw.calls[0].Name, gw.calls[1].Name)
+	}
+}
+
+func TestServeHTTP_CardMoved_UpdatesLinkedList(t *testing.T) {
+	store, _ := links.NewStore("")
+	store.Put(links.Link{CardID: "card1", Repo: "acme/api", PR: 42, List: "Ready"})
+	h := newTestTrelloHandler(&mockGateway{})
+	h.Links = store
+
+	// Moves to unwatched lists still update the link
+	body := makeTrelloPayload("updateCard", "card1", "My Card", "list-unwatched", "Done", "list-ready-id", "Ready")
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("POST", "/webhook/trello", bytes.NewReader(body)))
+
+	if l, _ := store.Lookup("acme/api", 42, ""); l.List != "Done" {
+		t.Errorf("expected linked card list Done, got %q", l.List)
+	}
+}